@@ -0,0 +1,98 @@
+// Package apiclient is a client of the management API that the CLI server of a node
+// serves under /api/v1. The operations are generated from the OpenAPI document of the
+// API in doc/api/openapi.json
+package apiclient
+
+//go:generate go run gen.go -spec ../doc/api/openapi.json -out operations.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client is a client of the management API of a node
+type Client struct {
+	// BaseURL is the URL of the API, e.g. http://localhost:7935/api/v1
+	BaseURL string
+	// Token is presented as a bearer token if set
+	Token string
+	// HTTPClient is used to send the requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+}
+
+// New returns a client of the management API at baseURL
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Error is an error returned by the API
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %v", e.StatusCode, e.Message)
+}
+
+// do sends a request to the API and decodes the JSON response into result, if not nil
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body map[string]interface{}, result interface{}) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			msg = apiErr.Error
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: msg}
+	}
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
@@ -0,0 +1,85 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var req *http.Request
+	var reqBody string
+	status, respBody := http.StatusOK, ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.Nil(err)
+		req, reqBody = r, string(body)
+		w.WriteHeader(status)
+		w.Write([]byte(respBody))
+	}))
+	defer ts.Close()
+
+	c := New(ts.URL + "/api/v1/")
+	c.Token = "secret"
+	ctx := context.Background()
+
+	// JSON results
+	respBody = `{"Version":"0.5.9"}`
+	res, err := c.GetStatus(ctx)
+	require.Nil(err)
+	assert.JSONEq(respBody, string(res))
+	assert.Equal("GET", req.Method)
+	assert.Equal("/api/v1/status", req.URL.Path)
+	assert.Equal("Bearer secret", req.Header.Get("Authorization"))
+
+	// String results
+	respBody = `"0xabcd"`
+	sig, err := c.SignMessage(ctx, &SignMessageParams{Message: "hello"})
+	require.Nil(err)
+	assert.Equal("0xabcd", sig)
+	assert.Equal("POST", req.Method)
+	assert.Equal("application/json", req.Header.Get("Content-Type"))
+	assert.JSONEq(`{"message":"hello"}`, reqBody)
+
+	// Amounts are sent as strings and unset optional parameters are omitted
+	status, respBody = http.StatusNoContent, ""
+	amount, _ := new(big.Int).SetString("1000000000000000000000", 10)
+	require.Nil(c.Bond(ctx, &BondParams{Amount: amount, ToAddr: "0x1"}))
+	assert.JSONEq(`{"amount":"1000000000000000000000","toAddr":"0x1"}`, reqBody)
+	require.Nil(c.ActivateOrchestrator(ctx, &ActivateOrchestratorParams{Amount: amount, ServiceURI: "https://127.0.0.1:8935"}))
+	var body map[string]interface{}
+	require.Nil(json.Unmarshal([]byte(reqBody), &body))
+	assert.NotContains(body, "unbondingLockId")
+	assert.Equal("1000000000000000000000", body["amount"])
+
+	// Parameters of GET operations are sent in the query
+	status, respBody = http.StatusOK, "[]"
+	withdrawable := true
+	_, err = c.ListUnbondingLocks(ctx, &ListUnbondingLocksParams{Withdrawable: &withdrawable})
+	require.Nil(err)
+	assert.Equal("withdrawable=true", req.URL.RawQuery)
+	assert.Empty(reqBody)
+	_, err = c.ListUnbondingLocks(ctx, &ListUnbondingLocksParams{})
+	require.Nil(err)
+	assert.Empty(req.URL.RawQuery)
+
+	// Errors
+	status, respBody = http.StatusInternalServerError, `{"error":"could not execute unlock: nope"}`
+	err = c.Unlock(ctx)
+	require.IsType(&Error{}, err)
+	assert.Equal(&Error{StatusCode: http.StatusInternalServerError, Message: "could not execute unlock: nope"}, err)
+	assert.Equal("500: could not execute unlock: nope", err.Error())
+	status, respBody = http.StatusUnauthorized, "Unauthorized\n"
+	_, err = c.GetStatus(ctx)
+	assert.Equal(&Error{StatusCode: http.StatusUnauthorized, Message: "Unauthorized"}, err)
+}
@@ -0,0 +1,248 @@
+// +build ignore
+
+// gen generates the operations of the client from the OpenAPI document of the API
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+type schema struct {
+	Type        string             `json:"type"`
+	Format      string             `json:"format"`
+	Description string             `json:"description"`
+	Properties  map[string]*schema `json:"properties"`
+	Required    []string           `json:"required"`
+}
+
+type operation struct {
+	OperationID string `json:"operationId"`
+	Summary     string `json:"summary"`
+	Parameters  []struct {
+		Name        string  `json:"name"`
+		Description string  `json:"description"`
+		Required    bool    `json:"required"`
+		Schema      *schema `json:"schema"`
+	} `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]struct {
+			Schema *schema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]struct {
+		Content map[string]struct {
+			Schema *schema `json:"schema"`
+		} `json:"content"`
+	} `json:"responses"`
+}
+
+type spec struct {
+	Paths map[string]map[string]*operation `json:"paths"`
+}
+
+type param struct {
+	Name     string
+	Field    string
+	Desc     string
+	GoType   string
+	Optional bool
+	// Expression of the value of the parameter
+	Value string
+}
+
+type method struct {
+	Name    string
+	Summary string
+	Method  string
+	Path    string
+	Query   bool
+	Params  []param
+	// Go type of the result, empty if the operation returns nothing
+	Result string
+}
+
+func exported(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func newParam(name, desc string, required bool, s *schema) param {
+	p := param{Name: name, Field: exported(name), Desc: desc, Optional: !required}
+	if p.Desc == "" {
+		p.Desc = s.Description
+	}
+	switch {
+	case s.Format == "bigint":
+		p.GoType, p.Value = "*big.Int", "params."+p.Field+".String()"
+		// nil is unset for both required and optional amounts
+		p.Optional = true
+		return p
+	case s.Type == "integer":
+		p.GoType = "int64"
+	case s.Type == "number":
+		p.GoType = "float64"
+	case s.Type == "boolean":
+		p.GoType = "bool"
+	default:
+		p.GoType, p.Value = "string", "params."+p.Field
+		return p
+	}
+	p.Value = "params." + p.Field
+	if p.Optional {
+		p.GoType, p.Value = "*"+p.GoType, "*params."+p.Field
+	}
+	return p
+}
+
+var tmpl = template.Must(template.New("operations").Parse(`// Code generated by gen.go from the OpenAPI document of the API. DO NOT EDIT.
+
+package apiclient
+
+import (
+{{- range .Imports}}
+	"{{.}}"
+{{- end}}
+)
+{{range .Methods}}
+{{- if .Params}}
+// {{.Name}}Params are the parameters of {{.Name}}
+type {{.Name}}Params struct {
+{{- range .Params}}
+	// {{.Desc}}
+	{{.Field}} {{.GoType}}
+{{- end}}
+}
+{{end}}
+// {{.Name}} calls {{.Method}} {{.Path}}: {{.Summary}}
+func (c *Client) {{.Name}}(ctx context.Context{{if .Params}}, params *{{.Name}}Params{{end}}) {{if .Result}}({{.Result}}, error){{else}}error{{end}} {
+{{- if .Query}}
+	query := url.Values{}
+{{- range .Params}}
+{{- if .Optional}}
+	if params.{{.Field}} != {{if eq .GoType "string"}}""{{else}}nil{{end}} {
+		query.Set("{{.Name}}", fmt.Sprint({{.Value}}))
+	}
+{{- else}}
+	query.Set("{{.Name}}", fmt.Sprint({{.Value}}))
+{{- end}}
+{{- end}}
+{{- else if .Params}}
+	body := map[string]interface{}{}
+{{- range .Params}}
+{{- if .Optional}}
+	if params.{{.Field}} != {{if eq .GoType "string"}}""{{else}}nil{{end}} {
+		body["{{.Name}}"] = {{.Value}}
+	}
+{{- else}}
+	body["{{.Name}}"] = {{.Value}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .Result}}
+	var result {{.Result}}
+	err := c.do(ctx, "{{.Method}}", "{{.Path}}", {{if .Query}}query{{else}}nil{{end}}, {{if and .Params (not .Query)}}body{{else}}nil{{end}}, &result)
+	return result, err
+{{- else}}
+	return c.do(ctx, "{{.Method}}", "{{.Path}}", {{if .Query}}query{{else}}nil{{end}}, {{if and .Params (not .Query)}}body{{else}}nil{{end}}, nil)
+{{- end}}
+}
+{{end}}`))
+
+func main() {
+	specFile := flag.String("spec", "", "OpenAPI document")
+	out := flag.String("out", "", "Output file")
+	flag.Parse()
+
+	specData, err := ioutil.ReadFile(*specFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var s spec
+	if err := json.Unmarshal(specData, &s); err != nil {
+		log.Fatal(err)
+	}
+
+	var methods []method
+	for path, item := range s.Paths {
+		for m, op := range item {
+			mt := method{
+				Name:    exported(op.OperationID),
+				Summary: op.Summary,
+				Method:  strings.ToUpper(m),
+				Path:    path,
+			}
+			for _, p := range op.Parameters {
+				mt.Query = true
+				mt.Params = append(mt.Params, newParam(p.Name, p.Description, p.Required, p.Schema))
+			}
+			if op.RequestBody != nil {
+				body := op.RequestBody.Content["application/json"].Schema
+				required := make(map[string]bool)
+				for _, name := range body.Required {
+					required[name] = true
+				}
+				var names []string
+				for name := range body.Properties {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					mt.Params = append(mt.Params, newParam(name, "", required[name], body.Properties[name]))
+				}
+			}
+			if resp, ok := op.Responses["200"]; ok {
+				mt.Result = "json.RawMessage"
+				if resp.Content["application/json"].Schema.Type == "string" {
+					mt.Result = "string"
+				}
+			}
+			methods = append(methods, mt)
+		}
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Name < methods[j].Name })
+
+	imports := map[string]bool{"context": true}
+	for _, mt := range methods {
+		if mt.Result == "json.RawMessage" {
+			imports["encoding/json"] = true
+		}
+		if mt.Query {
+			imports["fmt"] = true
+			imports["net/url"] = true
+		}
+		for _, p := range mt.Params {
+			if p.GoType == "*big.Int" {
+				imports["math/big"] = true
+			}
+		}
+	}
+	var importList []string
+	for imp := range imports {
+		importList = append(importList, imp)
+	}
+	sort.Strings(importList)
+
+	var buf bytes.Buffer
+	data := struct {
+		Imports []string
+		Methods []method
+	}{importList, methods}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Fatal(err)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(fmt.Errorf("%v\n%s", err, buf.Bytes()))
+	}
+	if err := ioutil.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
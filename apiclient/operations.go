@@ -0,0 +1,871 @@
+// Code generated by gen.go from the OpenAPI document of the API. DO NOT EDIT.
+
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+)
+
+// ActivateOrchestratorParams are the parameters of ActivateOrchestrator
+type ActivateOrchestratorParams struct {
+	// Amount of LPT in base units to bond to self
+	Amount *big.Int
+	// Percentage of the block rewards kept by the orchestrator
+	BlockRewardCut float64
+	// Percentage of the fees shared with delegators
+	FeeShare float64
+	// Number of pixels priced at pricePerUnit
+	PixelsPerUnit int64
+	// Price in Wei per pixelsPerUnit pixels
+	PricePerUnit int64
+	// Service URI of the orchestrator
+	ServiceURI string
+	// Register the service URI without checking that it reaches the node
+	SkipServiceURICheck *bool
+	// Unbonding lock to rebond with instead of bonding amount
+	UnbondingLockId *big.Int
+}
+
+// ActivateOrchestrator calls POST /orchestrator/activate: Register the node as an orchestrator
+func (c *Client) ActivateOrchestrator(ctx context.Context, params *ActivateOrchestratorParams) error {
+	body := map[string]interface{}{}
+	if params.Amount != nil {
+		body["amount"] = params.Amount.String()
+	}
+	body["blockRewardCut"] = params.BlockRewardCut
+	body["feeShare"] = params.FeeShare
+	body["pixelsPerUnit"] = params.PixelsPerUnit
+	body["pricePerUnit"] = params.PricePerUnit
+	body["serviceURI"] = params.ServiceURI
+	if params.SkipServiceURICheck != nil {
+		body["skipServiceURICheck"] = *params.SkipServiceURICheck
+	}
+	if params.UnbondingLockId != nil {
+		body["unbondingLockId"] = params.UnbondingLockId.String()
+	}
+	return c.do(ctx, "POST", "/orchestrator/activate", nil, body, nil)
+}
+
+// BondParams are the parameters of Bond
+type BondParams struct {
+	// Amount of LPT in base units
+	Amount *big.Int
+	// Address of the orchestrator
+	ToAddr string
+}
+
+// Bond calls POST /delegator/bond: Bond LPT to an orchestrator
+func (c *Client) Bond(ctx context.Context, params *BondParams) error {
+	body := map[string]interface{}{}
+	if params.Amount != nil {
+		body["amount"] = params.Amount.String()
+	}
+	body["toAddr"] = params.ToAddr
+	return c.do(ctx, "POST", "/delegator/bond", nil, body, nil)
+}
+
+// CancelUnlock calls POST /broadcaster/sender/cancelUnlock: Cancel the unlock of the deposit and reserve
+func (c *Client) CancelUnlock(ctx context.Context) error {
+	return c.do(ctx, "POST", "/broadcaster/sender/cancelUnlock", nil, nil, nil)
+}
+
+// CheckServiceURIParams are the parameters of CheckServiceURI
+type CheckServiceURIParams struct {
+	// Service URI to check, the current service URI of the node by default
+	ServiceURI string
+}
+
+// CheckServiceURI calls GET /orchestrator/serviceURI: Check that a service URI is public and reaches the node
+func (c *Client) CheckServiceURI(ctx context.Context, params *CheckServiceURIParams) (json.RawMessage, error) {
+	query := url.Values{}
+	if params.ServiceURI != "" {
+		query.Set("serviceURI", fmt.Sprint(params.ServiceURI))
+	}
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/orchestrator/serviceURI", query, nil, &result)
+	return result, err
+}
+
+// ClaimEarningsParams are the parameters of ClaimEarnings
+type ClaimEarningsParams struct {
+	// Round to claim up to
+	EndRound *big.Int
+}
+
+// ClaimEarnings calls POST /delegator/claimEarnings: Claim rewards and fees
+func (c *Client) ClaimEarnings(ctx context.Context, params *ClaimEarningsParams) error {
+	body := map[string]interface{}{}
+	if params.EndRound != nil {
+		body["endRound"] = params.EndRound.String()
+	}
+	return c.do(ctx, "POST", "/delegator/claimEarnings", nil, body, nil)
+}
+
+// ClipParams are the parameters of Clip
+type ClipParams struct {
+	// End of the clip, in seconds from the start of the recording
+	End float64
+	// Comma separated list of transcoding profiles
+	Presets string
+	// JSON array of custom profiles, as returned by the auth webhook
+	Profiles string
+	// Start of the clip, in seconds from the start of the recording
+	Start float64
+	// URL of the HLS media playlist of the recorded stream
+	Url string
+}
+
+// Clip calls POST /broadcaster/clips: Transcode a frame-accurate clip of a recorded stream
+func (c *Client) Clip(ctx context.Context, params *ClipParams) (json.RawMessage, error) {
+	body := map[string]interface{}{}
+	body["end"] = params.End
+	if params.Presets != "" {
+		body["presets"] = params.Presets
+	}
+	if params.Profiles != "" {
+		body["profiles"] = params.Profiles
+	}
+	body["start"] = params.Start
+	body["url"] = params.Url
+	var result json.RawMessage
+	err := c.do(ctx, "POST", "/broadcaster/clips", nil, body, &result)
+	return result, err
+}
+
+// DiscardUnsignedTransactionParams are the parameters of DiscardUnsignedTransaction
+type DiscardUnsignedTransactionParams struct {
+	// ID of the queued transaction
+	Id string
+}
+
+// DiscardUnsignedTransaction calls POST /account/unsignedTransactions/discard: Remove a transaction queued to be signed offline
+func (c *Client) DiscardUnsignedTransaction(ctx context.Context, params *DiscardUnsignedTransactionParams) error {
+	body := map[string]interface{}{}
+	body["id"] = params.Id
+	return c.do(ctx, "POST", "/account/unsignedTransactions/discard", nil, body, nil)
+}
+
+// EnsureDepositParams are the parameters of EnsureDeposit
+type EnsureDepositParams struct {
+	// Minimum deposit in Wei
+	DepositAmount *big.Int
+	// Minimum reserve in Wei
+	ReserveAmount *big.Int
+}
+
+// EnsureDeposit calls POST /onboarding/deposit: Top up the deposit and reserve of the broadcaster to at least the given amounts, funding only what is missing
+func (c *Client) EnsureDeposit(ctx context.Context, params *EnsureDepositParams) (json.RawMessage, error) {
+	body := map[string]interface{}{}
+	if params.DepositAmount != nil {
+		body["depositAmount"] = params.DepositAmount.String()
+	}
+	if params.ReserveAmount != nil {
+		body["reserveAmount"] = params.ReserveAmount.String()
+	}
+	var result json.RawMessage
+	err := c.do(ctx, "POST", "/onboarding/deposit", nil, body, &result)
+	return result, err
+}
+
+// EnsureOrchestratorParams are the parameters of EnsureOrchestrator
+type EnsureOrchestratorParams struct {
+	// Minimum stake in LPT base units bonded to the orchestrator, bonding only what is missing
+	Amount *big.Int
+	// Percentage of the block rewards kept by the orchestrator
+	BlockRewardCut float64
+	// Percentage of the fees shared with delegators
+	FeeShare float64
+	// Number of pixels priced at pricePerUnit
+	PixelsPerUnit int64
+	// Price in Wei per pixelsPerUnit pixels
+	PricePerUnit int64
+	// Service URI of the orchestrator
+	ServiceURI string
+	// Register the service URI without checking that it reaches the node
+	SkipServiceURICheck *bool
+}
+
+// EnsureOrchestrator calls POST /onboarding/orchestrator: Register the node as an orchestrator, or update the settings that differ if it is registered
+func (c *Client) EnsureOrchestrator(ctx context.Context, params *EnsureOrchestratorParams) (json.RawMessage, error) {
+	body := map[string]interface{}{}
+	if params.Amount != nil {
+		body["amount"] = params.Amount.String()
+	}
+	body["blockRewardCut"] = params.BlockRewardCut
+	body["feeShare"] = params.FeeShare
+	body["pixelsPerUnit"] = params.PixelsPerUnit
+	body["pricePerUnit"] = params.PricePerUnit
+	body["serviceURI"] = params.ServiceURI
+	if params.SkipServiceURICheck != nil {
+		body["skipServiceURICheck"] = *params.SkipServiceURICheck
+	}
+	var result json.RawMessage
+	err := c.do(ctx, "POST", "/onboarding/orchestrator", nil, body, &result)
+	return result, err
+}
+
+// FundDepositAndReserveParams are the parameters of FundDepositAndReserve
+type FundDepositAndReserveParams struct {
+	// Deposit amount in Wei
+	DepositAmount *big.Int
+	// Reserve amount in Wei
+	ReserveAmount *big.Int
+}
+
+// FundDepositAndReserve calls POST /broadcaster/sender/fund: Fund the deposit and reserve
+func (c *Client) FundDepositAndReserve(ctx context.Context, params *FundDepositAndReserveParams) error {
+	body := map[string]interface{}{}
+	if params.DepositAmount != nil {
+		body["depositAmount"] = params.DepositAmount.String()
+	}
+	if params.ReserveAmount != nil {
+		body["reserveAmount"] = params.ReserveAmount.String()
+	}
+	return c.do(ctx, "POST", "/broadcaster/sender/fund", nil, body, nil)
+}
+
+// GetBandwidth calls GET /stats/bandwidth: Get the ingress and egress bytes of the node
+func (c *Client) GetBandwidth(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/stats/bandwidth", nil, nil, &result)
+	return result, err
+}
+
+// GetBroadcastConfig calls GET /broadcaster/config: Get the maximum price and transcoding options
+func (c *Client) GetBroadcastConfig(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/broadcaster/config", nil, nil, &result)
+	return result, err
+}
+
+// GetCanary calls GET /canary: Get the results of the self-test canary
+func (c *Client) GetCanary(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/canary", nil, nil, &result)
+	return result, err
+}
+
+// GetConfigSnapshot calls GET /config/snapshot: Export the effective configuration of the node as a YAML config file, without secrets
+func (c *Client) GetConfigSnapshot(ctx context.Context) (string, error) {
+	var result string
+	err := c.do(ctx, "GET", "/config/snapshot", nil, nil, &result)
+	return result, err
+}
+
+// GetContractAddresses calls GET /protocol/contracts: Get the addresses of the protocol contracts
+func (c *Client) GetContractAddresses(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/protocol/contracts", nil, nil, &result)
+	return result, err
+}
+
+// GetCurrentRound calls GET /protocol/round: Get the current round
+func (c *Client) GetCurrentRound(ctx context.Context) (string, error) {
+	var result string
+	err := c.do(ctx, "GET", "/protocol/round", nil, nil, &result)
+	return result, err
+}
+
+// GetDelegator calls GET /delegator: Get the delegator info of the node
+func (c *Client) GetDelegator(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/delegator", nil, nil, &result)
+	return result, err
+}
+
+// GetEarningsParams are the parameters of GetEarnings
+type GetEarningsParams struct {
+	// Only return the earnings of this kind: fees, reward or bond
+	Kind string
+	// RFC 3339 time of the oldest earnings
+	Since string
+	// RFC 3339 time after the most recent earnings
+	Until string
+	// Also sum the earnings per day, month or year
+	Interval string
+	// Also list the indexed earnings
+	Events *bool
+}
+
+// GetEarnings calls GET /stats/earnings: Get the fees, rewards and bonds of the node indexed from the chain
+func (c *Client) GetEarnings(ctx context.Context, params *GetEarningsParams) (json.RawMessage, error) {
+	query := url.Values{}
+	if params.Kind != "" {
+		query.Set("kind", fmt.Sprint(params.Kind))
+	}
+	if params.Since != "" {
+		query.Set("since", fmt.Sprint(params.Since))
+	}
+	if params.Until != "" {
+		query.Set("until", fmt.Sprint(params.Until))
+	}
+	if params.Interval != "" {
+		query.Set("interval", fmt.Sprint(params.Interval))
+	}
+	if params.Events != nil {
+		query.Set("events", fmt.Sprint(*params.Events))
+	}
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/stats/earnings", query, nil, &result)
+	return result, err
+}
+
+// GetEthAddress calls GET /account/address: Get the Ethereum address of the node
+func (c *Client) GetEthAddress(ctx context.Context) (string, error) {
+	var result string
+	err := c.do(ctx, "GET", "/account/address", nil, nil, &result)
+	return result, err
+}
+
+// GetEthBalance calls GET /account/ethBalance: Get the ETH balance in Wei
+func (c *Client) GetEthBalance(ctx context.Context) (string, error) {
+	var result string
+	err := c.do(ctx, "GET", "/account/ethBalance", nil, nil, &result)
+	return result, err
+}
+
+// GetFleetStatus calls GET /broadcaster/fleet: Get the members, streams and orchestrator performance of the fleet of a coordinator
+func (c *Client) GetFleetStatus(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/broadcaster/fleet", nil, nil, &result)
+	return result, err
+}
+
+// GetGasPrice calls GET /gasPrice: Get the gas price in Wei, 0 if automatic
+func (c *Client) GetGasPrice(ctx context.Context) (string, error) {
+	var result string
+	err := c.do(ctx, "GET", "/gasPrice", nil, nil, &result)
+	return result, err
+}
+
+// GetLogLevel calls GET /logLevel: Get the log verbosity
+func (c *Client) GetLogLevel(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/logLevel", nil, nil, &result)
+	return result, err
+}
+
+// GetOrchestratorInfo calls GET /orchestrator: Get the on-chain info and price of the orchestrator
+func (c *Client) GetOrchestratorInfo(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/orchestrator", nil, nil, &result)
+	return result, err
+}
+
+// GetPixelChecks calls GET /verification/pixelChecks: Get the results of the pixel count checks
+func (c *Client) GetPixelChecks(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/verification/pixelChecks", nil, nil, &result)
+	return result, err
+}
+
+// GetProtocolParameters calls GET /protocol/parameters: Get the protocol parameters
+func (c *Client) GetProtocolParameters(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/protocol/parameters", nil, nil, &result)
+	return result, err
+}
+
+// GetReady calls GET /ready: Check that the node serves requests and is not shutting down
+func (c *Client) GetReady(ctx context.Context) (string, error) {
+	var result string
+	err := c.do(ctx, "GET", "/ready", nil, nil, &result)
+	return result, err
+}
+
+// GetSenderInfo calls GET /broadcaster/sender: Get the deposit and reserve of the broadcaster
+func (c *Client) GetSenderInfo(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/broadcaster/sender", nil, nil, &result)
+	return result, err
+}
+
+// GetSenderStatsParams are the parameters of GetSenderStats
+type GetSenderStatsParams struct {
+	// Only return the analytics of this broadcaster
+	Sender string
+}
+
+// GetSenderStats calls GET /stats/senders: Get the analytics of the broadcasters of an orchestrator
+func (c *Client) GetSenderStats(ctx context.Context, params *GetSenderStatsParams) (json.RawMessage, error) {
+	query := url.Values{}
+	if params.Sender != "" {
+		query.Set("sender", fmt.Sprint(params.Sender))
+	}
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/stats/senders", query, nil, &result)
+	return result, err
+}
+
+// GetStatus calls GET /status: Get the status of the node
+func (c *Client) GetStatus(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/status", nil, nil, &result)
+	return result, err
+}
+
+// GetTokenBalance calls GET /account/tokenBalance: Get the LPT balance in base units
+func (c *Client) GetTokenBalance(ctx context.Context) (string, error) {
+	var result string
+	err := c.do(ctx, "GET", "/account/tokenBalance", nil, nil, &result)
+	return result, err
+}
+
+// GetTranscoderPool calls GET /transcoders: Get the load and the performance of the transcoders connected to an orchestrator
+func (c *Client) GetTranscoderPool(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/transcoders", nil, nil, &result)
+	return result, err
+}
+
+// GetVerifierStatus calls GET /verification/status: Get the status of the verifiers and orchestrators
+func (c *Client) GetVerifierStatus(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/verification/status", nil, nil, &result)
+	return result, err
+}
+
+// InitializeRound calls POST /protocol/round: Initialize the current round
+func (c *Client) InitializeRound(ctx context.Context) error {
+	return c.do(ctx, "POST", "/protocol/round", nil, nil, nil)
+}
+
+// ListFeatures calls GET /features: List the experimental features and whether they are enabled
+func (c *Client) ListFeatures(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/features", nil, nil, &result)
+	return result, err
+}
+
+// ListOrchestrators calls GET /protocol/orchestrators: List the registered orchestrators
+func (c *Client) ListOrchestrators(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/protocol/orchestrators", nil, nil, &result)
+	return result, err
+}
+
+// ListTranscodeReceiptsParams are the parameters of ListTranscodeReceipts
+type ListTranscodeReceiptsParams struct {
+	// Only return the receipts of this stream
+	ManifestID string
+	// Only return the receipts of this orchestrator
+	Orchestrator string
+	// Only return the receipts that cover this segment, with its Merkle proof
+	SeqNo *int64
+	// Maximum number of receipts
+	Limit *int64
+}
+
+// ListTranscodeReceipts calls GET /receipts: List the stored transcode receipts, most recent first
+func (c *Client) ListTranscodeReceipts(ctx context.Context, params *ListTranscodeReceiptsParams) (json.RawMessage, error) {
+	query := url.Values{}
+	if params.ManifestID != "" {
+		query.Set("manifestID", fmt.Sprint(params.ManifestID))
+	}
+	if params.Orchestrator != "" {
+		query.Set("orchestrator", fmt.Sprint(params.Orchestrator))
+	}
+	if params.SeqNo != nil {
+		query.Set("seqNo", fmt.Sprint(*params.SeqNo))
+	}
+	if params.Limit != nil {
+		query.Set("limit", fmt.Sprint(*params.Limit))
+	}
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/receipts", query, nil, &result)
+	return result, err
+}
+
+// ListUnbondingLocksParams are the parameters of ListUnbondingLocks
+type ListUnbondingLocksParams struct {
+	// Only list the locks that can be withdrawn
+	Withdrawable *bool
+}
+
+// ListUnbondingLocks calls GET /delegator/unbondingLocks: List the unbonding locks
+func (c *Client) ListUnbondingLocks(ctx context.Context, params *ListUnbondingLocksParams) (json.RawMessage, error) {
+	query := url.Values{}
+	if params.Withdrawable != nil {
+		query.Set("withdrawable", fmt.Sprint(*params.Withdrawable))
+	}
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/delegator/unbondingLocks", query, nil, &result)
+	return result, err
+}
+
+// ListUnsignedTransactions calls GET /account/unsignedTransactions: List the transactions queued to be signed offline, with the nonces to sign them with
+func (c *Client) ListUnsignedTransactions(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/account/unsignedTransactions", nil, nil, &result)
+	return result, err
+}
+
+// ListVerificationResultsParams are the parameters of ListVerificationResults
+type ListVerificationResultsParams struct {
+	// Only return the results of this stream
+	ManifestID string
+	// Only return the results of this orchestrator
+	Orchestrator string
+	// RFC 3339 time of the oldest result
+	Since string
+	// RFC 3339 time of the most recent result
+	Until string
+	// Maximum number of results
+	Limit *int64
+}
+
+// ListVerificationResults calls GET /verification/results: List the verification results, most recent first
+func (c *Client) ListVerificationResults(ctx context.Context, params *ListVerificationResultsParams) (json.RawMessage, error) {
+	query := url.Values{}
+	if params.ManifestID != "" {
+		query.Set("manifestID", fmt.Sprint(params.ManifestID))
+	}
+	if params.Orchestrator != "" {
+		query.Set("orchestrator", fmt.Sprint(params.Orchestrator))
+	}
+	if params.Since != "" {
+		query.Set("since", fmt.Sprint(params.Since))
+	}
+	if params.Until != "" {
+		query.Set("until", fmt.Sprint(params.Until))
+	}
+	if params.Limit != nil {
+		query.Set("limit", fmt.Sprint(*params.Limit))
+	}
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/verification/results", query, nil, &result)
+	return result, err
+}
+
+// ListWebhooks calls GET /webhooks: Get the delivery counters and dead letters of the outbound webhooks
+func (c *Client) ListWebhooks(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/webhooks", nil, nil, &result)
+	return result, err
+}
+
+// MoveStakeParams are the parameters of MoveStake
+type MoveStakeParams struct {
+	// Address of the orchestrator
+	ToAddr string
+}
+
+// MoveStake calls POST /delegator/moveStake: Move the whole stake to another orchestrator
+func (c *Client) MoveStake(ctx context.Context, params *MoveStakeParams) (json.RawMessage, error) {
+	body := map[string]interface{}{}
+	body["toAddr"] = params.ToAddr
+	var result json.RawMessage
+	err := c.do(ctx, "POST", "/delegator/moveStake", nil, body, &result)
+	return result, err
+}
+
+// PreviewStakeParams are the parameters of PreviewStake
+type PreviewStakeParams struct {
+	// Transaction to preview: bond, rebond, unbond, withdrawStake or moveStake
+	Action string
+	// Amount of LPT in base units to bond or unbond
+	Amount *big.Int
+	// Address of the orchestrator to bond, rebond or move the stake to
+	ToAddr string
+	// ID of the unbonding lock to rebond or withdraw
+	UnbondingLockId *big.Int
+}
+
+// PreviewStake calls GET /delegator/preview: Preview the stake after a bonding transaction without sending it
+func (c *Client) PreviewStake(ctx context.Context, params *PreviewStakeParams) (json.RawMessage, error) {
+	query := url.Values{}
+	query.Set("action", fmt.Sprint(params.Action))
+	if params.Amount != nil {
+		query.Set("amount", fmt.Sprint(params.Amount.String()))
+	}
+	if params.ToAddr != "" {
+		query.Set("toAddr", fmt.Sprint(params.ToAddr))
+	}
+	if params.UnbondingLockId != nil {
+		query.Set("unbondingLockId", fmt.Sprint(params.UnbondingLockId.String()))
+	}
+	var result json.RawMessage
+	err := c.do(ctx, "GET", "/delegator/preview", query, nil, &result)
+	return result, err
+}
+
+// RebondParams are the parameters of Rebond
+type RebondParams struct {
+	// Address of the orchestrator to rebond to, if unbonded
+	ToAddr string
+	// ID of the unbonding lock
+	UnbondingLockId *big.Int
+}
+
+// Rebond calls POST /delegator/rebond: Rebond the stake of an unbonding lock
+func (c *Client) Rebond(ctx context.Context, params *RebondParams) error {
+	body := map[string]interface{}{}
+	if params.ToAddr != "" {
+		body["toAddr"] = params.ToAddr
+	}
+	if params.UnbondingLockId != nil {
+		body["unbondingLockId"] = params.UnbondingLockId.String()
+	}
+	return c.do(ctx, "POST", "/delegator/rebond", nil, body, nil)
+}
+
+// Reload calls POST /reload: Reload the settings that can be changed without a restart
+func (c *Client) Reload(ctx context.Context) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.do(ctx, "POST", "/reload", nil, nil, &result)
+	return result, err
+}
+
+// Reward calls POST /orchestrator/reward: Call reward for the current round
+func (c *Client) Reward(ctx context.Context) error {
+	return c.do(ctx, "POST", "/orchestrator/reward", nil, nil, nil)
+}
+
+// SendSignedTransactionParams are the parameters of SendSignedTransaction
+type SendSignedTransactionParams struct {
+	// Hex encoded signed raw transaction
+	Tx string
+}
+
+// SendSignedTransaction calls POST /account/unsignedTransactions/send: Send an offline signed queued transaction, and get its hash
+func (c *Client) SendSignedTransaction(ctx context.Context, params *SendSignedTransactionParams) (string, error) {
+	body := map[string]interface{}{}
+	body["tx"] = params.Tx
+	var result string
+	err := c.do(ctx, "POST", "/account/unsignedTransactions/send", nil, body, &result)
+	return result, err
+}
+
+// SetBroadcastConfigParams are the parameters of SetBroadcastConfig
+type SetBroadcastConfigParams struct {
+	// Maximum price in Wei per pixelsPerUnit pixels, 0 for no maximum
+	MaxPricePerUnit *int64
+	// Number of pixels priced at maxPricePerUnit
+	PixelsPerUnit *int64
+	// Comma separated list of transcoding profiles
+	TranscodingOptions string
+}
+
+// SetBroadcastConfig calls POST /broadcaster/config: Set the maximum price and transcoding options
+func (c *Client) SetBroadcastConfig(ctx context.Context, params *SetBroadcastConfigParams) error {
+	body := map[string]interface{}{}
+	if params.MaxPricePerUnit != nil {
+		body["maxPricePerUnit"] = *params.MaxPricePerUnit
+	}
+	if params.PixelsPerUnit != nil {
+		body["pixelsPerUnit"] = *params.PixelsPerUnit
+	}
+	if params.TranscodingOptions != "" {
+		body["transcodingOptions"] = params.TranscodingOptions
+	}
+	return c.do(ctx, "POST", "/broadcaster/config", nil, body, nil)
+}
+
+// SetFeatureParams are the parameters of SetFeature
+type SetFeatureParams struct {
+	// Whether the feature is enabled
+	Enabled bool
+	// Name of the feature
+	Name string
+}
+
+// SetFeature calls POST /features: Enable or disable an experimental feature until the node restarts
+func (c *Client) SetFeature(ctx context.Context, params *SetFeatureParams) error {
+	body := map[string]interface{}{}
+	body["enabled"] = params.Enabled
+	body["name"] = params.Name
+	return c.do(ctx, "POST", "/features", nil, body, nil)
+}
+
+// SetGasPriceParams are the parameters of SetGasPrice
+type SetGasPriceParams struct {
+	// Gas price in Wei, 0 for automatic
+	Amount *big.Int
+}
+
+// SetGasPrice calls POST /gasPrice: Set the gas price
+func (c *Client) SetGasPrice(ctx context.Context, params *SetGasPriceParams) error {
+	body := map[string]interface{}{}
+	if params.Amount != nil {
+		body["amount"] = params.Amount.String()
+	}
+	return c.do(ctx, "POST", "/gasPrice", nil, body, nil)
+}
+
+// SetLogLevelParams are the parameters of SetLogLevel
+type SetLogLevelParams struct {
+	// Verbosity from 0 to 6
+	Loglevel int64
+}
+
+// SetLogLevel calls POST /logLevel: Set the log verbosity
+func (c *Client) SetLogLevel(ctx context.Context, params *SetLogLevelParams) error {
+	body := map[string]interface{}{}
+	body["loglevel"] = params.Loglevel
+	return c.do(ctx, "POST", "/logLevel", nil, body, nil)
+}
+
+// SetOrchestratorConfigParams are the parameters of SetOrchestratorConfig
+type SetOrchestratorConfigParams struct {
+	// Percentage of the block rewards kept by the orchestrator
+	BlockRewardCut *float64
+	// Percentage of the fees shared with delegators
+	FeeShare *float64
+	// Number of pixels priced at pricePerUnit
+	PixelsPerUnit *int64
+	// Price in Wei per pixelsPerUnit pixels
+	PricePerUnit *int64
+	// Service URI of the orchestrator
+	ServiceURI string
+	// Register the service URI without checking that it reaches the node
+	SkipServiceURICheck *bool
+}
+
+// SetOrchestratorConfig calls POST /orchestrator/config: Update the orchestrator configuration
+func (c *Client) SetOrchestratorConfig(ctx context.Context, params *SetOrchestratorConfigParams) error {
+	body := map[string]interface{}{}
+	if params.BlockRewardCut != nil {
+		body["blockRewardCut"] = *params.BlockRewardCut
+	}
+	if params.FeeShare != nil {
+		body["feeShare"] = *params.FeeShare
+	}
+	if params.PixelsPerUnit != nil {
+		body["pixelsPerUnit"] = *params.PixelsPerUnit
+	}
+	if params.PricePerUnit != nil {
+		body["pricePerUnit"] = *params.PricePerUnit
+	}
+	if params.ServiceURI != "" {
+		body["serviceURI"] = params.ServiceURI
+	}
+	if params.SkipServiceURICheck != nil {
+		body["skipServiceURICheck"] = *params.SkipServiceURICheck
+	}
+	return c.do(ctx, "POST", "/orchestrator/config", nil, body, nil)
+}
+
+// SetStreamProfilesParams are the parameters of SetStreamProfiles
+type SetStreamProfilesParams struct {
+	// Manifest ID of the stream
+	ManifestID string
+	// Comma separated list of transcoding profiles
+	Presets string
+	// JSON array of custom profiles, as returned by the auth webhook
+	Profiles string
+}
+
+// SetStreamProfiles calls POST /broadcaster/streams/profiles: Replace the transcoding profiles of a live stream from its next segment
+func (c *Client) SetStreamProfiles(ctx context.Context, params *SetStreamProfilesParams) (json.RawMessage, error) {
+	body := map[string]interface{}{}
+	body["manifestID"] = params.ManifestID
+	if params.Presets != "" {
+		body["presets"] = params.Presets
+	}
+	if params.Profiles != "" {
+		body["profiles"] = params.Profiles
+	}
+	var result json.RawMessage
+	err := c.do(ctx, "POST", "/broadcaster/streams/profiles", nil, body, &result)
+	return result, err
+}
+
+// SignMessageParams are the parameters of SignMessage
+type SignMessageParams struct {
+	// Message to sign
+	Message string
+}
+
+// SignMessage calls POST /account/sign: Sign a message with the Ethereum account of the node
+func (c *Client) SignMessage(ctx context.Context, params *SignMessageParams) (string, error) {
+	body := map[string]interface{}{}
+	body["message"] = params.Message
+	var result string
+	err := c.do(ctx, "POST", "/account/sign", nil, body, &result)
+	return result, err
+}
+
+// TransferTokensParams are the parameters of TransferTokens
+type TransferTokensParams struct {
+	// Amount of LPT in base units
+	Amount *big.Int
+	// Address of the recipient
+	To string
+}
+
+// TransferTokens calls POST /account/transfer: Transfer LPT
+func (c *Client) TransferTokens(ctx context.Context, params *TransferTokensParams) error {
+	body := map[string]interface{}{}
+	if params.Amount != nil {
+		body["amount"] = params.Amount.String()
+	}
+	body["to"] = params.To
+	return c.do(ctx, "POST", "/account/transfer", nil, body, nil)
+}
+
+// UnbondParams are the parameters of Unbond
+type UnbondParams struct {
+	// Amount of LPT in base units
+	Amount *big.Int
+}
+
+// Unbond calls POST /delegator/unbond: Unbond LPT
+func (c *Client) Unbond(ctx context.Context, params *UnbondParams) error {
+	body := map[string]interface{}{}
+	if params.Amount != nil {
+		body["amount"] = params.Amount.String()
+	}
+	return c.do(ctx, "POST", "/delegator/unbond", nil, body, nil)
+}
+
+// Unlock calls POST /broadcaster/sender/unlock: Start the unlock period of the deposit and reserve
+func (c *Client) Unlock(ctx context.Context) error {
+	return c.do(ctx, "POST", "/broadcaster/sender/unlock", nil, nil, nil)
+}
+
+// VoteParams are the parameters of Vote
+type VoteParams struct {
+	// ID of the choice to vote for
+	ChoiceID int64
+	// Address of the poll contract
+	Poll string
+}
+
+// Vote calls POST /protocol/vote: Vote in a poll
+func (c *Client) Vote(ctx context.Context, params *VoteParams) (string, error) {
+	body := map[string]interface{}{}
+	body["choiceID"] = params.ChoiceID
+	body["poll"] = params.Poll
+	var result string
+	err := c.do(ctx, "POST", "/protocol/vote", nil, body, &result)
+	return result, err
+}
+
+// Withdraw calls POST /broadcaster/sender/withdraw: Withdraw the unlocked deposit and reserve
+func (c *Client) Withdraw(ctx context.Context) error {
+	return c.do(ctx, "POST", "/broadcaster/sender/withdraw", nil, nil, nil)
+}
+
+// WithdrawFees calls POST /delegator/withdrawFees: Withdraw fees
+func (c *Client) WithdrawFees(ctx context.Context) error {
+	return c.do(ctx, "POST", "/delegator/withdrawFees", nil, nil, nil)
+}
+
+// WithdrawStakeParams are the parameters of WithdrawStake
+type WithdrawStakeParams struct {
+	// ID of the unbonding lock
+	UnbondingLockId *big.Int
+}
+
+// WithdrawStake calls POST /delegator/withdrawStake: Withdraw the stake of an unbonding lock
+func (c *Client) WithdrawStake(ctx context.Context, params *WithdrawStakeParams) error {
+	body := map[string]interface{}{}
+	if params.UnbondingLockId != nil {
+		body["unbondingLockId"] = params.UnbondingLockId.String()
+	}
+	return c.do(ctx, "POST", "/delegator/withdrawStake", nil, body, nil)
+}
@@ -0,0 +1,222 @@
+// Package audit implements an append-only, hash-chained log of payment relevant events
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Types of the events recorded in the audit log
+const (
+	EventTicketsReceived           = "TicketsReceived"
+	EventTicketRedeemed            = "TicketRedeemed"
+	EventTicketRedemptionFailed    = "TicketRedemptionFailed"
+	EventTicketRedemptionAbandoned = "TicketRedemptionAbandoned"
+	EventPriceChanged              = "PriceChanged"
+	EventMaxPriceChanged           = "MaxPriceChanged"
+	EventWithdrawal                = "Withdrawal"
+	EventPixelsOverReported        = "PixelsOverReported"
+)
+
+// GenesisHash is the previous hash of the first entry of a log
+var GenesisHash = strings.Repeat("0", 64)
+
+// maxEntrySize bounds the size of a single entry when reading a log
+const maxEntrySize = 1024 * 1024
+
+var errEmptyEventType = errors.New("empty event type")
+
+// Entry is a single event of the audit log
+type Entry struct {
+	Seq      uint64            `json:"seq"`
+	Time     time.Time         `json:"time"`
+	Type     string            `json:"type"`
+	Data     map[string]string `json:"data,omitempty"`
+	PrevHash string            `json:"prevHash"`
+	Hash     string            `json:"hash,omitempty"`
+}
+
+// computeHash returns the hex encoded SHA-256 hash of the entry without its hash
+func (e *Entry) computeHash() string {
+	c := *e
+	c.Hash = ""
+	// Marshalling a struct of strings, a time and a map of strings does not fail,
+	// and the keys of maps are sorted so the encoding is deterministic
+	b, _ := json.Marshal(&c)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyError is returned when an entry of a log fails verification
+type VerifyError struct {
+	Line   int
+	Reason string
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("audit log verification failed at line %d: %s", e.Line, e.Reason)
+}
+
+// Log is an append-only audit log backed by a file. Every entry includes the hash of the
+// previous entry so that any modification, removal or reordering of entries is detected
+// when the log is verified. All methods are safe to call on a nil log, in which case
+// events are discarded
+type Log struct {
+	mu       sync.Mutex
+	path     string
+	f        *os.File
+	seq      uint64
+	lastHash string
+	now      func() time.Time
+}
+
+// Open opens the audit log at path, creating it if it does not exist. The existing
+// entries are verified so that new entries extend a valid chain
+func Open(path string) (*Log, error) {
+	l := &Log{path: path, lastHash: GenesisHash, now: time.Now}
+
+	f, err := os.Open(path)
+	if err == nil {
+		n, last, err := verify(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			l.seq = last.Seq + 1
+			l.lastHash = last.Hash
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	l.f, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Append records an event. The entry is synced to disk before returning. Errors are
+// logged rather than returned so that recording an event never interrupts payments
+func (l *Log) Append(typ string, data map[string]string) {
+	if l == nil {
+		return
+	}
+	if err := l.append(typ, data); err != nil {
+		glog.Errorf("Error writing to audit log type=%s err=%v", typ, err)
+	}
+}
+
+func (l *Log) append(typ string, data map[string]string) error {
+	if typ == "" {
+		return errEmptyEventType
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := &Entry{
+		Seq:      l.seq,
+		Time:     l.now().UTC().Round(0),
+		Type:     typ,
+		Data:     data,
+		PrevHash: l.lastHash,
+	}
+	e.Hash = e.computeHash()
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := l.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	if err := l.f.Sync(); err != nil {
+		return err
+	}
+
+	l.seq++
+	l.lastHash = e.Hash
+	return nil
+}
+
+// Export writes all the entries of the log to w
+func (l *Log) Export(w io.Writer) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Close closes the file backing the log
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.f.Close()
+}
+
+// Verify checks that the entries read from r form a valid chain and returns the
+// number of entries. A *VerifyError is returned for the first invalid entry
+func Verify(r io.Reader) (int, error) {
+	n, _, err := verify(r)
+	return n, err
+}
+
+func verify(r io.Reader) (int, *Entry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), maxEntrySize)
+
+	var last *Entry
+	prevHash := GenesisHash
+	n := 0
+	for scanner.Scan() {
+		line := n + 1
+		e := &Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			return n, last, &VerifyError{Line: line, Reason: fmt.Sprintf("invalid entry: %v", err)}
+		}
+		if e.Seq != uint64(n) {
+			return n, last, &VerifyError{Line: line, Reason: fmt.Sprintf("expected sequence number %d, got %d", n, e.Seq)}
+		}
+		if e.PrevHash != prevHash {
+			return n, last, &VerifyError{Line: line, Reason: "previous hash does not match the previous entry"}
+		}
+		if e.Hash != e.computeHash() {
+			return n, last, &VerifyError{Line: line, Reason: "hash does not match the entry"}
+		}
+		prevHash = e.Hash
+		last = e
+		n++
+	}
+	if err := scanner.Err(); err != nil {
+		return n, last, err
+	}
+	return n, last, nil
+}
@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tempLogPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.Nil(t, err)
+	return filepath.Join(dir, "audit.log"), func() { os.RemoveAll(dir) }
+}
+
+func TestLog_AppendAndVerify(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	path, cleanup := tempLogPath(t)
+	defer cleanup()
+
+	l, err := Open(path)
+	require.Nil(err)
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+	l.Append(EventPriceChanged, map[string]string{"pricePerPixel": "1/1"})
+	l.Append(EventTicketsReceived, map[string]string{"sender": "0xfoo", "tickets": "2"})
+	// Events without a type are dropped
+	l.Append("", nil)
+	require.Nil(l.Close())
+
+	data, err := ioutil.ReadFile(path)
+	require.Nil(err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(lines, 2)
+	assert.Contains(lines[0], `"seq":0`)
+	assert.Contains(lines[0], `"prevHash":"`+GenesisHash+`"`)
+	assert.Contains(lines[1], `"type":"TicketsReceived"`)
+
+	n, err := Verify(bytes.NewReader(data))
+	assert.Nil(err)
+	assert.Equal(2, n)
+
+	// Reopening the log extends the chain
+	l, err = Open(path)
+	require.Nil(err)
+	assert.Equal(uint64(2), l.seq)
+	l.Append(EventWithdrawal, map[string]string{"type": "fees"})
+
+	var buf bytes.Buffer
+	require.Nil(l.Export(&buf))
+	require.Nil(l.Close())
+	n, err = Verify(&buf)
+	assert.Nil(err)
+	assert.Equal(3, n)
+}
+
+func TestVerify_Tampered(t *testing.T) {
+	path, cleanup := tempLogPath(t)
+	defer cleanup()
+
+	l, err := Open(path)
+	require.Nil(t, err)
+	l.Append(EventTicketsReceived, map[string]string{"ev": "100"})
+	l.Append(EventTicketsReceived, map[string]string{"ev": "200"})
+	l.Append(EventTicketsReceived, map[string]string{"ev": "300"})
+	l.Close()
+
+	data, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	tests := []struct {
+		name   string
+		lines  []string
+		line   int
+		reason string
+	}{
+		{
+			name:   "modified entry",
+			lines:  []string{lines[0], strings.Replace(lines[1], `"200"`, `"2000"`, 1), lines[2]},
+			line:   2,
+			reason: "hash does not match the entry",
+		},
+		{
+			name:   "removed entry",
+			lines:  []string{lines[0], lines[2]},
+			line:   2,
+			reason: "expected sequence number 1, got 2",
+		},
+		{
+			name:   "reordered entries",
+			lines:  []string{lines[1], lines[0], lines[2]},
+			line:   1,
+			reason: "expected sequence number 0, got 1",
+		},
+		{
+			name:   "invalid entry",
+			lines:  []string{lines[0], "foo"},
+			line:   2,
+			reason: "invalid entry",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := Verify(strings.NewReader(strings.Join(tt.lines, "\n")))
+			require.IsType(t, &VerifyError{}, err)
+			verr := err.(*VerifyError)
+			assert.Equal(t, tt.line, verr.Line)
+			assert.Contains(t, verr.Reason, tt.reason)
+			assert.Equal(t, tt.line-1, n)
+		})
+	}
+
+	// A rewritten chain is detected through the previous hash
+	e := &Entry{Seq: 1, Time: time.Now().UTC(), Type: EventTicketsReceived, PrevHash: GenesisHash}
+	e.Hash = e.computeHash()
+	_, err = Verify(strings.NewReader(lines[0] + "\n" + string(mustMarshal(t, e))))
+	require.IsType(t, &VerifyError{}, err)
+	assert.Equal(t, "previous hash does not match the previous entry", err.(*VerifyError).Reason)
+}
+
+func TestOpen_InvalidLog(t *testing.T) {
+	path, cleanup := tempLogPath(t)
+	defer cleanup()
+
+	require.Nil(t, ioutil.WriteFile(path, []byte("foo\n"), 0600))
+	_, err := Open(path)
+	assert.IsType(t, &VerifyError{}, err)
+}
+
+func TestLog_Nil(t *testing.T) {
+	var l *Log
+	assert.NotPanics(t, func() {
+		l.Append(EventWithdrawal, nil)
+	})
+	assert.Nil(t, l.Export(ioutil.Discard))
+	assert.Nil(t, l.Close())
+}
+
+func mustMarshal(t *testing.T, e *Entry) []byte {
+	b, err := json.Marshal(e)
+	require.Nil(t, err)
+	return b
+}
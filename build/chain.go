@@ -17,9 +17,11 @@ const (
 // ChainSupported returns whether the node can connect to the chain with the given ID
 func ChainSupported(chainID int64) bool {
 	switch chainID {
-	case 4:
+	// Rinkeby and Arbitrum Rinkeby
+	case 4, 421611:
 		return Rinkeby <= HighestChain
-	case 1:
+	// Mainnet and Arbitrum One
+	case 1, 42161:
 		return Mainnet <= HighestChain
 	default:
 		return Dev <= HighestChain
@@ -159,12 +159,18 @@ func ethSetup(ethAcctAddr, keystoreDir string, isBroadcaster bool) {
 	}
 	glog.Infof("Using controller address %s", ethController)
 
-	client, err := eth.NewClient(ethcommon.HexToAddress(ethAcctAddr), keystoreDir, backend,
-		ethcommon.HexToAddress(ethController), ethTxTimeout)
+	client, err := eth.NewClient(eth.LivepeerEthClientConfig{
+		AccountAddr:    ethcommon.HexToAddress(ethAcctAddr),
+		KeystoreDir:    keystoreDir,
+		EthClient:      backend,
+		ControllerAddr: ethcommon.HexToAddress(ethController),
+		TxTimeout:      ethTxTimeout,
+	})
 	if err != nil {
 		glog.Errorf("Failed to create client: %v", err)
 		return
 	}
+	defer client.Close()
 
 	err = client.Setup(passphrase, uint64(0), nil)
 	if err != nil {
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/eth"
+)
+
+// accountCommand strips the `account create`, `account import` or `account sign-tx` command
+// from the arguments, if present, and returns its name
+func accountCommand(args []string) ([]string, string) {
+	if len(args) >= 2 && args[0] == "account" && (args[1] == "create" || args[1] == "import" || args[1] == "sign-tx") {
+		return args[2:], args[1]
+	}
+	return args, ""
+}
+
+// keystoreDirectory returns the keystore of the node, which is the directory of
+// -ethKeystorePath if it exists and the keystore directory of the data directory otherwise
+func keystoreDirectory(ethKeystorePath, datadir string) string {
+	if _, err := os.Stat(ethKeystorePath); !os.IsNotExist(err) {
+		dir, _ := filepath.Split(ethKeystorePath)
+		return dir
+	}
+	return filepath.Join(datadir, "keystore")
+}
+
+// runAccountCommand creates the Ethereum account of the node, or imports it from keyFile,
+// without prompting for the passphrase, for `livepeer account create` and
+// `livepeer account import`. Existing accounts are kept, so that provisioning systems
+// can run the command every time they set up a node
+func runAccountCommand(command, keystoreDir, ethPassword, keyFile string) error {
+	if keystoreDir == "" {
+		return errors.New("cannot find keystore directory")
+	}
+	passphrase, err := common.GetPass(ethPassword)
+	if err != nil {
+		return err
+	}
+	if command == "sign-tx" {
+		if keyFile == "" {
+			return errors.New("usage: livepeer account sign-tx -ethPassword <passphrase> <unsigned transactions file>")
+		}
+		return signTxs(keystoreDir, passphrase, keyFile)
+	}
+	if passphrase == "" {
+		return errors.New("-ethPassword is required to encrypt the account")
+	}
+
+	var (
+		acct    accounts.Account
+		changed bool
+	)
+	switch command {
+	case "create":
+		acct, changed, err = eth.CreateAccount(keystoreDir, passphrase)
+	case "import":
+		if keyFile == "" {
+			return errors.New("usage: livepeer account import -ethPassword <passphrase> <key file>")
+		}
+		var key []byte
+		if key, err = ioutil.ReadFile(keyFile); err == nil {
+			acct, changed, err = eth.ImportAccount(keystoreDir, key, passphrase)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		fmt.Printf("Stored Ethereum account %v in %v\n", acct.Address.Hex(), keystoreDir)
+	} else {
+		fmt.Printf("Ethereum account %v is already in %v\n", acct.Address.Hex(), keystoreDir)
+	}
+	return nil
+}
+
+// signTxs signs the unsigned transactions of fname, exported by a node in offline signing mode
+// as a JSON array or object, and prints their signed raw transactions in order, for
+// `livepeer account sign-tx` on an air-gapped machine
+func signTxs(keystoreDir, passphrase, fname string) error {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+
+	var txs []*eth.UnsignedTx
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '{' {
+		var tx eth.UnsignedTx
+		err = json.Unmarshal(data, &tx)
+		txs = append(txs, &tx)
+	} else {
+		err = json.Unmarshal(data, &txs)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid unsigned transactions %v: %v", fname, err)
+	}
+
+	for _, u := range txs {
+		tx, err := eth.SignUnsignedTx(keystoreDir, passphrase, u)
+		if err != nil {
+			return err
+		}
+		raw, err := eth.EncodeRawTx(tx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Signed transaction id=%v method=%v nonce=%v\n", u.ID, u.Method, u.Nonce)
+		fmt.Println(raw)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// configEnvPrefix is the prefix of the environment variables that set flags, e.g.
+// LP_ETHURL sets -ethUrl
+const configEnvPrefix = "LP_"
+
+// configEnvVar returns the name of the environment variable that sets a flag
+func configEnvVar(name string) string {
+	return configEnvPrefix + strings.ToUpper(name)
+}
+
+// configEnv returns the value of a flag from the environment and the name of the variable
+// it was read from. If the _FILE variable of the flag is set, the value is read from the
+// file it names instead, so that secrets mounted as files can be used
+func configEnv(env map[string]string, name string) (string, string, bool, error) {
+	key := configEnvVar(name)
+	v, ok := env[key]
+	fname, fromFile := env[key+"_FILE"]
+	if !fromFile {
+		return v, key, ok, nil
+	}
+	if ok {
+		return "", key, false, fmt.Errorf("both %v and %v_FILE are set", key, key)
+	}
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return "", key + "_FILE", false, err
+	}
+	return strings.TrimRight(string(data), "\r\n"), key + "_FILE", true, nil
+}
+
+// configCommand strips the `config validate` or `config import` command from the
+// arguments, if present, and returns its name
+func configCommand(args []string) ([]string, string) {
+	if len(args) >= 2 && args[0] == "config" && (args[1] == "validate" || args[1] == "import") {
+		return args[2:], args[1]
+	}
+	return args, ""
+}
+
+// configEntry is the value of a flag from the environment or the config file
+type configEntry struct {
+	value string
+	// Environment variable or config file the value is read from
+	source string
+}
+
+// configValues returns the values of the flags that are set in the environment or in the
+// config file, keyed by flag name. Environment variables take precedence over the file
+func configValues(fs *flag.FlagSet, fname string, environ []string) (map[string]configEntry, error) {
+	env := make(map[string]string)
+	for _, kv := range environ {
+		if i := strings.Index(kv, "="); i > 0 && strings.HasPrefix(kv, configEnvPrefix) {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+
+	if fname == "" {
+		fname = env[configEnvVar("config")]
+	}
+	file := make(map[string]string)
+	if fname != "" {
+		var err error
+		file, err = readConfigFile(fname)
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(file))
+		for k := range file {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if fs.Lookup(k) == nil || k == "config" {
+				return nil, fmt.Errorf("unknown flag %v in %v", k, fname)
+			}
+		}
+	}
+
+	values := make(map[string]configEntry)
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || f.Name == "config" {
+			return
+		}
+		v, key, ok, eerr := configEnv(env, f.Name)
+		if eerr != nil {
+			err = eerr
+			return
+		}
+		if ok {
+			values[f.Name] = configEntry{value: v, source: key}
+		} else if v, ok := file[f.Name]; ok {
+			values[f.Name] = configEntry{value: v, source: fmt.Sprintf("%v in %v", f.Name, fname)}
+		}
+	})
+	return values, err
+}
+
+// applyConfig sets the flags that were not set on the command line from the environment
+// and then from the config file. Flags take precedence over environment variables, which
+// take precedence over the config file
+func applyConfig(fs *flag.FlagSet, fname string, environ []string) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	values, err := configValues(fs, fname, environ)
+	if err != nil {
+		return err
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		v, ok := values[f.Name]
+		if err != nil || set[f.Name] || !ok {
+			return
+		}
+		if serr := fs.Set(f.Name, v.value); serr != nil {
+			err = fmt.Errorf("invalid value %q for %v: %v", v.value, v.source, serr)
+		}
+	})
+	return err
+}
+
+// readConfigFile reads the flag values of a YAML or TOML config file. Only top level
+// keys are supported, and lists are joined with commas
+func readConfigFile(fname string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".yaml", ".yml":
+		return parseYAMLConfig(data)
+	case ".toml":
+		return parseTOMLConfig(data)
+	}
+	return nil, fmt.Errorf("unsupported config file format %v, expected .yaml, .yml or .toml", fname)
+}
+
+func parseYAMLConfig(data []byte) (map[string]string, error) {
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return configDocument(values)
+}
+
+// configDocument returns the flag values of the top level keys of a config document
+func configDocument(values map[string]interface{}) (map[string]string, error) {
+	config := make(map[string]string)
+	for k, v := range values {
+		s, err := configValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %v: %v", k, err)
+		}
+		config[k] = s
+	}
+	return config, nil
+}
+
+func configValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := configValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	case map[interface{}]interface{}, map[string]interface{}, []map[string]interface{}:
+		return "", fmt.Errorf("nested values are not supported")
+	}
+	return fmt.Sprint(v), nil
+}
+
+func parseTOMLConfig(data []byte) (map[string]string, error) {
+	var values map[string]interface{}
+	if _, err := toml.Decode(string(data), &values); err != nil {
+		return nil, err
+	}
+	return configDocument(values)
+}
+
+// validateConfig reports whether the configuration is valid, for `livepeer config validate`
+func validateConfig(fs *flag.FlagSet, fname string) error {
+	if fname == "" && fs.NArg() > 0 {
+		fname = fs.Arg(0)
+	}
+	if err := applyConfig(fs, fname, os.Environ()); err != nil {
+		return err
+	}
+	fmt.Println("Config is valid")
+	return nil
+}
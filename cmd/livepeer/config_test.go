@@ -0,0 +1,174 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	fname := filepath.Join(dir, name)
+	require.Nil(t, ioutil.WriteFile(fname, []byte(content), 0644))
+	return fname
+}
+
+func TestReadConfigFile(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "TestReadConfigFile")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	expected := map[string]string{
+		"network":      "rinkeby",
+		"orchestrator": "true",
+		"maxSessions":  "20",
+		"orchAddr":     "127.0.0.1:8935,127.0.0.1:8936",
+		"serviceAddr":  "orch.example.com:8935 # not a comment",
+	}
+
+	fname := writeConfigFile(t, dir, "livepeer.yaml", `
+# Orchestrator config
+network: rinkeby
+orchestrator: true
+maxSessions: 20
+orchAddr:
+  - 127.0.0.1:8935
+  - 127.0.0.1:8936
+serviceAddr: "orch.example.com:8935 # not a comment"
+`)
+	config, err := readConfigFile(fname)
+	require.Nil(err)
+	assert.Equal(expected, config)
+
+	fname = writeConfigFile(t, dir, "livepeer.toml", `
+# Orchestrator config
+network = "rinkeby" # trailing comment
+orchestrator = true
+maxSessions = 20
+orchAddr = ["127.0.0.1:8935", '127.0.0.1:8936']
+serviceAddr = "orch.example.com:8935 # not a comment"
+`)
+	config, err = readConfigFile(fname)
+	require.Nil(err)
+	assert.Equal(expected, config)
+
+	// Unsupported documents
+	_, err = readConfigFile(writeConfigFile(t, dir, "livepeer.json", `{}`))
+	assert.Contains(err.Error(), "unsupported config file format")
+	_, err = readConfigFile(writeConfigFile(t, dir, "nested.yaml", "s3:\n  bucket: foo\n"))
+	assert.EqualError(err, "invalid value for s3: nested values are not supported")
+	_, err = readConfigFile(writeConfigFile(t, dir, "table.toml", "[s3]\nbucket = 'foo'\n"))
+	assert.EqualError(err, "invalid value for s3: nested values are not supported")
+	_, err = readConfigFile(writeConfigFile(t, dir, "unquoted.toml", "network = rinkeby mainnet\n"))
+	require.NotNil(err)
+	assert.Contains(err.Error(), "line 1")
+	_, err = readConfigFile(writeConfigFile(t, dir, "dup.toml", "network = 'a'\nnetwork = 'b'\n"))
+	require.NotNil(err)
+	assert.Contains(err.Error(), "already been defined")
+	_, err = readConfigFile(writeConfigFile(t, dir, "novalue.toml", "network\n"))
+	assert.NotNil(err)
+	_, err = readConfigFile(filepath.Join(dir, "missing.yaml"))
+	assert.True(os.IsNotExist(err))
+}
+
+func TestApplyConfig(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "TestApplyConfig")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	newFlags := func() (*flag.FlagSet, *string, *string, *int, *time.Duration) {
+		fs := flag.NewFlagSet("livepeer", flag.ContinueOnError)
+		fs.String("config", "", "")
+		network := fs.String("network", "offchain", "")
+		ethURL := fs.String("ethUrl", "", "")
+		maxSessions := fs.Int("maxSessions", 10, "")
+		timeout := fs.Duration("verifierTimeout", 30*time.Second, "")
+		return fs, network, ethURL, maxSessions, timeout
+	}
+	fname := writeConfigFile(t, dir, "livepeer.yaml", "network: rinkeby\nethUrl: http://file\nmaxSessions: 20\n")
+
+	// Flags take precedence over the environment, which takes precedence over the file
+	fs, network, ethURL, maxSessions, timeout := newFlags()
+	require.Nil(fs.Parse([]string{"-network", "mainnet"}))
+	env := []string{"LP_ETHURL=http://env", "LP_NETWORK=env", "HOME=/root"}
+	require.Nil(applyConfig(fs, fname, env))
+	assert.Equal("mainnet", *network)
+	assert.Equal("http://env", *ethURL)
+	assert.Equal(20, *maxSessions)
+	assert.Equal(30*time.Second, *timeout)
+
+	// Values from the config are reported as set
+	isFlagSet := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { isFlagSet[f.Name] = true })
+	assert.Equal(map[string]bool{"network": true, "ethUrl": true, "maxSessions": true}, isFlagSet)
+
+	// The config file can be set from the environment
+	fs, network, _, _, _ = newFlags()
+	require.Nil(applyConfig(fs, "", []string{"LP_CONFIG=" + fname}))
+	assert.Equal("rinkeby", *network)
+
+	// Values can be read from files named by _FILE variables
+	secret := writeConfigFile(t, dir, "ethurl", "http://secret\n")
+	fs, _, ethURL, _, _ = newFlags()
+	require.Nil(applyConfig(fs, fname, []string{"LP_ETHURL_FILE=" + secret}))
+	assert.Equal("http://secret", *ethURL)
+
+	fs, _, _, _, _ = newFlags()
+	err = applyConfig(fs, "", []string{"LP_ETHURL_FILE=" + secret, "LP_ETHURL=http://env"})
+	assert.EqualError(err, "both LP_ETHURL and LP_ETHURL_FILE are set")
+
+	fs, _, _, _, _ = newFlags()
+	err = applyConfig(fs, "", []string{"LP_ETHURL_FILE=" + filepath.Join(dir, "missing")})
+	assert.True(os.IsNotExist(err))
+
+	fs, _, _, maxSessions, _ = newFlags()
+	err = applyConfig(fs, "", []string{"LP_MAXSESSIONS_FILE=" + secret})
+	assert.Contains(err.Error(), `invalid value "http://secret" for LP_MAXSESSIONS_FILE`)
+
+	// Invalid values
+	fs, _, _, _, _ = newFlags()
+	err = applyConfig(fs, "", []string{"LP_VERIFIERTIMEOUT=foo"})
+	assert.Contains(err.Error(), `invalid value "foo" for LP_VERIFIERTIMEOUT`)
+
+	fs, _, _, _, _ = newFlags()
+	err = applyConfig(fs, writeConfigFile(t, dir, "invalid.yaml", "maxSessions: many\n"), nil)
+	assert.Contains(err.Error(), `invalid value "many" for maxSessions`)
+
+	// Unknown flags are rejected
+	fs, _, _, _, _ = newFlags()
+	err = applyConfig(fs, writeConfigFile(t, dir, "unknown.yaml", "maxSesions: 20\n"), nil)
+	assert.Contains(err.Error(), "unknown flag maxSesions")
+	err = applyConfig(fs, writeConfigFile(t, dir, "config.yaml", "config: other.yaml\n"), nil)
+	assert.Contains(err.Error(), "unknown flag config")
+}
+
+func TestConfigCommand(t *testing.T) {
+	assert := assert.New(t)
+
+	args, cmd := configCommand([]string{"config", "validate", "-config", "livepeer.yaml"})
+	assert.Equal("validate", cmd)
+	assert.Equal([]string{"-config", "livepeer.yaml"}, args)
+
+	args, cmd = configCommand([]string{"config", "import", "-config", "livepeer.yaml", "snapshot.yaml"})
+	assert.Equal("import", cmd)
+	assert.Equal([]string{"-config", "livepeer.yaml", "snapshot.yaml"}, args)
+
+	args, cmd = configCommand([]string{"-config", "livepeer.yaml"})
+	assert.Empty(cmd)
+	assert.Equal([]string{"-config", "livepeer.yaml"}, args)
+
+	args, cmd = configCommand([]string{"config"})
+	assert.Empty(cmd)
+	assert.Equal([]string{"config"}, args)
+}
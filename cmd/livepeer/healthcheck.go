@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// healthcheckTimeout bounds a health check, since a deadlocked node accepts connections
+// without ever responding
+const healthcheckTimeout = 10 * time.Second
+
+// healthcheckCommand strips the `healthcheck` command from the arguments, if present
+func healthcheckCommand(args []string) ([]string, bool) {
+	if len(args) >= 1 && args[0] == "healthcheck" {
+		return args[1:], true
+	}
+	return args, false
+}
+
+// healthcheckURL returns the URL of the readiness endpoint of the CLI server listening on
+// cliAddr, on the loopback interface if it listens on all interfaces
+func healthcheckURL(cliAddr string, https bool) string {
+	addr := defaultAddr(cliAddr, "127.0.0.1", CliPort)
+	if host, port, err := net.SplitHostPort(addr); err == nil {
+		if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+			addr = net.JoinHostPort("127.0.0.1", port)
+		}
+	}
+	scheme := "http"
+	if https {
+		scheme = "https"
+	}
+	return scheme + "://" + addr + "/ready"
+}
+
+// healthcheckClient returns a client of the CLI server of the node. The CLI server is
+// trusted if it presents the -cliCert certificate, which is then also presented to the
+// server if it requires client certificates with -cliClientCA
+func healthcheckClient(cliCert, cliKey, cliClientCA string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cliCert != "" {
+		cert, err := tls.LoadX509KeyPair(cliCert, cliKey)
+		if err != nil {
+			return nil, err
+		}
+		config := &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			// The address of the probe is not necessarily a name of the certificate, so
+			// the certificate is pinned instead
+			InsecureSkipVerify: true,
+			VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+				if len(rawCerts) == 0 || !bytes.Equal(rawCerts[0], cert.Certificate[0]) {
+					return errors.New("the CLI server does not present the -cliCert certificate")
+				}
+				return nil
+			},
+		}
+		if cliClientCA != "" {
+			config.Certificates = []tls.Certificate{cert}
+		}
+		transport.TLSClientConfig = config
+	}
+	return &http.Client{Transport: transport, Timeout: healthcheckTimeout}, nil
+}
+
+// healthcheck probes the readiness endpoint of the node, for `livepeer healthcheck`. It
+// returns an error if the node doesn't respond or is shutting down
+func healthcheck(client *http.Client, url, token string) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v returned %v: %v", url, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
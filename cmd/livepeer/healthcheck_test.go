@@ -0,0 +1,130 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthcheckCommand(t *testing.T) {
+	assert := assert.New(t)
+
+	args, ok := healthcheckCommand([]string{"healthcheck", "-cliAddr", ":7936"})
+	assert.True(ok)
+	assert.Equal([]string{"-cliAddr", ":7936"}, args)
+	args, ok = healthcheckCommand([]string{"-cliAddr", ":7936"})
+	assert.False(ok)
+	assert.Equal([]string{"-cliAddr", ":7936"}, args)
+
+	assert.Equal("http://127.0.0.1:7935/ready", healthcheckURL("", false))
+	assert.Equal("http://127.0.0.1:7936/ready", healthcheckURL(":7936", false))
+	assert.Equal("https://127.0.0.1:7936/ready", healthcheckURL("0.0.0.0:7936", true))
+	assert.Equal("http://10.0.0.1:7935/ready", healthcheckURL("10.0.0.1", false))
+}
+
+func TestHealthcheck(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ready := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !ready {
+			http.Error(w, "node is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ready"))
+	}))
+	defer srv.Close()
+
+	client, err := healthcheckClient("", "", "")
+	require.Nil(err)
+	assert.Nil(healthcheck(client, srv.URL+"/ready", "secret"))
+	assert.EqualError(healthcheck(client, srv.URL+"/ready", ""), srv.URL+"/ready returned 401 Unauthorized: Unauthorized")
+	ready = false
+	assert.EqualError(healthcheck(client, srv.URL+"/ready", "secret"), srv.URL+"/ready returned 503 Service Unavailable: node is shutting down")
+	srv.Close()
+	assert.NotNil(healthcheck(client, srv.URL+"/ready", "secret"))
+}
+
+// writeCert writes a self-signed certificate and its key to dir
+func writeCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "livepeer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"livepeer.example.com"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.Nil(t, err)
+
+	certFile, keyFile := filepath.Join(dir, "cli.crt"), filepath.Join(dir, "cli.key")
+	require.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func TestHealthcheck_TLS(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "TestHealthcheck_TLS")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCert(t, dir)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.Nil(err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ready"))
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	// The certificate doesn't name 127.0.0.1 but is the pinned one
+	client, err := healthcheckClient(certFile, keyFile, "")
+	require.Nil(err)
+	assert.Nil(healthcheck(client, srv.URL+"/ready", ""))
+
+	other := httptest.NewTLSServer(srv.Config.Handler)
+	defer other.Close()
+	err = healthcheck(client, other.URL+"/ready", "")
+	require.NotNil(err)
+	assert.Contains(err.Error(), "the CLI server does not present the -cliCert certificate")
+
+	// The certificate is presented to a CLI server that requires client certificates
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	srv.TLS.ClientCAs = pool
+	srv.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+	client, err = healthcheckClient(certFile, keyFile, certFile)
+	require.Nil(err)
+	assert.Nil(healthcheck(client, srv.URL+"/ready", ""))
+
+	_, err = healthcheckClient(certFile, "", "")
+	assert.NotNil(err)
+}
@@ -8,13 +8,13 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/big"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
-	"os/signal"
 	"os/user"
 
 	"path/filepath"
@@ -25,11 +25,16 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/livepeer/go-livepeer/build"
 	"github.com/livepeer/go-livepeer/pm"
+	"github.com/livepeer/go-livepeer/sdnotify"
+	"github.com/livepeer/go-livepeer/secrets"
 	"github.com/livepeer/go-livepeer/server"
+	"github.com/livepeer/go-livepeer/webhook"
 
+	"github.com/ethereum/go-ethereum/accounts"
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/audit"
 	"github.com/livepeer/go-livepeer/common"
 	"github.com/livepeer/go-livepeer/core"
 	"github.com/livepeer/go-livepeer/discovery"
@@ -38,7 +43,9 @@ import (
 	"github.com/livepeer/go-livepeer/eth/blockwatch"
 	"github.com/livepeer/go-livepeer/eth/eventservices"
 	"github.com/livepeer/go-livepeer/eth/watchers"
+	"github.com/livepeer/go-livepeer/features"
 	"github.com/livepeer/go-livepeer/verification"
+	"github.com/livepeer/lpms/ffmpeg"
 
 	lpmon "github.com/livepeer/go-livepeer/monitor"
 )
@@ -53,7 +60,10 @@ var (
 	blockWatcherRetentionLimit = 20
 
 	// Estimate of the gas required to redeem a PM ticket
-	redeemGas = 250000
+	defaultRedeemGas = 250000
+	// The percentage added to the gas estimates of L2 chains, which include the cost of posting
+	// transactions to L1 at its gas price at the time of the estimate
+	l2EstimateGasMargin = uint64(20)
 	// The multiplier on the transaction cost to use for PM ticket faceValue
 	txCostMultiplier = 100
 
@@ -61,6 +71,9 @@ var (
 	cleanupInterval = 1 * time.Minute
 	// The time to live for cached max float values for PM senders (else they will be cleaned up) in seconds
 	smTTL = 60 // 1 minute
+
+	// The number of bytes of recent log output retained for diagnostic bundles
+	recentLogsSize = 4 * 1024 * 1024
 )
 
 const RtmpPort = "1935"
@@ -81,6 +94,7 @@ var defaultCapabilities = []core.Capability{
 	core.Capability_ProfileH264High,
 	core.Capability_ProfileH264ConstrainedHigh,
 	core.Capability_GOP,
+	core.Capability_AudioOnly,
 }
 
 // Add to this list as certain features become mandatory. Orchestrator only
@@ -88,10 +102,18 @@ var defaultCapabilities = []core.Capability{
 var mandatoryCapabilities = []core.Capability{}
 
 func main() {
+	flag.Set("logtostderr", "true")
+	if len(os.Args) > 1 && os.Args[1] == "service" {
+		serviceCommand(os.Args[2:], runNode)
+		return
+	}
+	runNode()
+}
+
+func runNode() {
 	// Override the default flag set since there are dependencies that
 	// incorrectly add their own flags (specifically, due to the 'testing'
 	// package being linked)
-	flag.Set("logtostderr", "true")
 	usr, err := user.Current()
 	if err != nil {
 		glog.Fatalf("Cannot find current user: %v", err)
@@ -102,16 +124,48 @@ func main() {
 
 	// Network & Addresses:
 	network := flag.String("network", "offchain", "Network to connect to")
+	networkConfig := flag.String("networkConfig", "", "JSON file of the chain ID, the Controller address and the protocol contract address overrides of -network custom")
 	rtmpAddr := flag.String("rtmpAddr", "127.0.0.1:"+RtmpPort, "Address to bind for RTMP commands")
 	cliAddr := flag.String("cliAddr", "127.0.0.1:"+CliPort, "Address to bind for  CLI commands")
+	cliToken := flag.String("cliToken", "", "Bearer token that every request to the CLI server must present")
+	cliCert := flag.String("cliCert", "", "Certificate file to serve the CLI server over HTTPS")
+	cliKey := flag.String("cliKey", "", "Private key file of -cliCert")
+	cliClientCA := flag.String("cliClientCA", "", "CA certificates file; CLI clients must present a certificate signed by one of these CAs. Requires -cliCert")
 	httpAddr := flag.String("httpAddr", "", "Address to bind for HTTP commands")
 	serviceAddr := flag.String("serviceAddr", "", "Orchestrator only. Overrides the on-chain serviceURI that broadcasters can use to contact this node; may be an IP or hostname.")
+	altServiceAddrs := flag.String("altServiceAddrs", "", "Orchestrator only. Comma separated list of additional addresses, e.g. per region, that broadcasters fail over to if -serviceAddr is unreachable")
 	orchAddr := flag.String("orchAddr", "", "Orchestrator to connect to as a standalone transcoder")
-	verifierURL := flag.String("verifierUrl", "", "URL of the verifier to use")
+	verifierURL := flag.String("verifierUrl", "", "Comma separated list of URLs of the verifiers to use. Verifiers are used in order, failing over to the next healthy verifier")
+	verifierTimeout := flag.Duration("verifierTimeout", 30*time.Second, "Timeout of a request to a verifier")
+	verifierRetries := flag.Int("verifierRetries", 1, "Number of retries of a request to a verifier before failing over to the next verifier")
+	verifierHealthCheckInterval := flag.Duration("verifierHealthCheckInterval", 10*time.Second, "How often unhealthy verifiers are checked for recovery")
 
 	verifierPath := flag.String("verifierPath", "", "Path to verifier shared volume")
 	localVerify := flag.Bool("localVerify", true, "Set to true to enable local verification i.e. pixel count and signature verification.")
+	verifySampleRate := flag.Float64("verifySampleRate", 1, "Fraction of segments, between 0 and 1, that are verified")
+	verifyUntrustedSampleRate := flag.Float64("verifyUntrustedSampleRate", 1, "Fraction of segments, between 0 and 1, that are verified for new orchestrators and orchestrators that recently failed verification")
+	verifySampleKey := flag.String("verifySampleKey", "", "Secret key, or path to a file containing it, of the sampling of the segments that are verified or checked, so that orchestrators can't predict the sampled segments. Set it to sample the same segments across restarts. Generated at startup if not set")
+	verifyTrustThreshold := flag.Int("verifyTrustThreshold", 10, "Number of consecutive successful verifications after which an orchestrator is verified at -verifySampleRate")
+	verifyStoreResults := flag.Bool("verifyStoreResults", true, "Set to true to store the outcome of every verification in the DB, queryable from the /verificationResults endpoint")
+	verifyResultsRetention := flag.Duration("verifyResultsRetention", 7*24*time.Hour, "How long the stored outcomes of verifications are kept in the DB. Kept forever if 0")
+	verifyFailureThreshold := flag.Int("verifyFailureThreshold", server.VerificationFailureThreshold, "Number of consecutive failed verifications after which an orchestrator is suspended. Set to 0 to never suspend orchestrators")
+	verifyPenalty := flag.Int("verifyPenalty", server.VerificationPenalty, "Number of session refreshes an orchestrator that repeatedly fails verification is suspended for")
+	verifyWebhookURL := flag.String("verifyWebhookUrl", "", "URL that receives an event when an orchestrator is suspended for failing verification")
+	verifyRenditions := flag.Bool("verifyRenditions", false, "Set to true to decode the renditions returned by orchestrators and check their resolution, duration and pixel counts without an external verifier")
+	verifyRetranscode := flag.Bool("verifyRetranscode", false, "Set to true to verify sampled segments by transcoding them again with the same profiles and comparing the perceptual hashes of the frames. Requires the ffmpeg binary in the PATH")
+	verifyRetranscodeNvidia := flag.String("verifyRetranscodeNvidia", "", "Comma-separated list of Nvidia GPU device IDs to use for -verifyRetranscode. Uses the CPU if not set")
+	verifyRetranscodeMaxDistance := flag.Float64("verifyRetranscodeMaxDistance", verification.DefaultMaxHashDistance, "Maximum average Hamming distance, out of 64 bits, between the frame hashes of a rendition and of its re-transcode")
+	qualityMetric := flag.String("qualityMetric", "", "Quality metric used to score sampled renditions against their source: ssim or vmaf. Requires the ffmpeg binary in the PATH")
+	qualitySampleRate := flag.Float64("qualitySampleRate", 0.1, "Fraction of segments, between 0 and 1, that are scored with -qualityMetric")
+	qualityMinScore := flag.Float64("qualityMinScore", 0, "Average -qualityMetric score below which an orchestrator is suspended. Set to 0 to never suspend orchestrators for their quality")
+	pixelCheckSampleRate := flag.Float64("pixelCheckSampleRate", 0, "Fraction of segments, between 0 and 1, whose renditions are decoded to cross-check the pixel counts that orchestrators charge for. Set to 0 to disable")
+	videoSignatureSampleRate := flag.Float64("videoSignatureSampleRate", 0, "Fraction of segments, between 0 and 1, whose renditions are checked against their source with MPEG-7 video signatures. Requires the ffmpeg binary in the PATH with the signature filter. Set to 0 to disable")
+	adaptiveBitrate := flag.String("adaptiveBitrate", "", "Comma separated minimum and maximum percentages of the bitrates of the profiles, e.g. 50,120, that the bitrates of the renditions are scaled within to the complexity of each segment. Disabled if not set")
+	alignKeyframes := flag.Duration("alignKeyframes", 0, "GOP that all the renditions are encoded with, so that their keyframes are aligned at the start of every segment and every GOP. Results whose keyframes aren't aligned are rejected. Disabled if 0")
+	parallelOrchestrators := flag.Int("parallelOrchestrators", server.ParallelOrchestrators, "Number of orchestrators that the segments of a stream are dispatched to in turn, to transcode them in parallel when transcoding a segment takes longer than its duration, e.g. with many renditions. Renditions are still published in order")
+	storeReceipts := flag.Bool("storeReceipts", false, "Set to true to check the transcode receipts sent by orchestrators and store them in the DB, queryable from the /transcodeReceipts endpoint")
 	httpIngest := flag.Bool("httpIngest", true, "Set to true to enable HTTP ingest")
+	vodHosts := flag.String("vodHosts", "", "Comma-separated list of the hosts of the object stores that the /vod/ endpoint of HTTP ingest fetches files from, e.g. bucket.storage.example.com. Files can only be pushed in the body of requests if empty")
 
 	// Transcoding:
 	orchestrator := flag.Bool("orchestrator", false, "Set to true to be an orchestrator")
@@ -119,44 +173,133 @@ func main() {
 	broadcaster := flag.Bool("broadcaster", false, "Set to true to be a broadcaster")
 	orchSecret := flag.String("orchSecret", "", "Shared secret with the orchestrator as a standalone transcoder")
 	transcodingOptions := flag.String("transcodingOptions", "P240p30fps16x9,P360p30fps16x9", "Transcoding options for broadcast job, or path to json config")
+	requiredCapabilities := flag.String("requiredCapabilities", "", "Broadcaster only. Comma separated list of capabilities that orchestrators must support in addition to those needed by the transcoding options, e.g. mp4,gop. Orchestrators that don't advertise them are not selected")
 	maxAttempts := flag.Int("maxAttempts", 3, "Maximum transcode attempts")
 	maxSessions := flag.Int("maxSessions", 10, "Maximum number of concurrent transcoding sessions for Orchestrator, maximum number or RTMP streams for Broadcaster, or maximum capacity for transcoder")
 	currentManifest := flag.Bool("currentManifest", false, "Expose the currently active ManifestID as \"/stream/current.m3u8\"")
-	nvidia := flag.String("nvidia", "", "Comma-separated list of Nvidia GPU device IDs to use for transcoding")
+	canaryInterval := flag.Duration("canaryInterval", 0, "Interval at which to push a self-test segment through the broadcaster pipeline. Disabled if 0")
+	canarySegment := flag.String("canarySegment", "", "Path to a MPEG-TS segment to push instead of the built-in self-test canary segment")
+	canarySegmentDuration := flag.Duration("canarySegmentDuration", 2*time.Second, "Duration of the segment given by -canarySegment")
+	// Broadcaster fleets
+	coordinator := flag.Bool("coordinator", false, "Set to true to serve the coordinator API of a fleet of broadcasters on the CLI server")
+	coordinatorURL := flag.String("coordinatorUrl", "", "URL of the CLI server of the coordinator of the fleet of broadcasters to join")
+	coordinatorToken := flag.String("coordinatorToken", "", "Bearer token of the CLI server of the fleet coordinator, if set with -cliToken")
+	fleetSecret := flag.String("fleetSecret", "", "Secret shared by the coordinator and the broadcasters of a fleet, required by the coordinator to register them")
+	coordinatorInterval := flag.Duration("coordinatorInterval", 10*time.Second, "Interval at which the broadcasters of a fleet send a heartbeat to the coordinator")
+	fleetAddr := flag.String("fleetAddr", "", "Address at which the other broadcasters of the fleet reach the HTTP ingest of this node. Defaults to -httpAddr")
+	nvidia := flag.String("nvidia", "", "Comma-separated list of Nvidia GPU device IDs to use for transcoding, or \"all\" for every GPU listed by nvidia-smi")
+	nvidiaMaxSessions := flag.Int("nvidiaMaxSessions", 0, "Maximum number of concurrent transcoding sessions per Nvidia GPU of -nvidia, which also caps -maxSessions. Unlimited if 0")
 	testTranscoder := flag.Bool("testTranscoder", true, "Test Nvidia GPU transcoding at startup")
+	// Limits of the public endpoints of orchestrators
+	ipRateLimit := flag.Float64("ipRateLimit", server.IPRateLimit, "Orchestrator only. Requests per second accepted from an IP address, over HTTP and gRPC. Disabled if 0")
+	senderRateLimit := flag.Float64("senderRateLimit", server.SenderRateLimit, "Orchestrator only. Segments per second accepted from a broadcaster. Disabled if 0")
+	maxDiscoveryRequests := flag.Int("maxDiscoveryRequests", server.MaxDiscoveryRequests, "Orchestrator only. Maximum number of discovery requests served at the same time. Disabled if 0")
+	maxSegmentSize := flag.Int64("maxSegmentSize", server.MaxSegmentSize, "Orchestrator only. Largest segment, in bytes, accepted from broadcasters. Disabled if 0")
+	shutdownTimeout := flag.Duration("shutdownTimeout", 30*time.Second, "Maximum time to finish the segments in flight, end the streams and complete the ticket redemptions in progress on SIGTERM or SIGINT before exiting. The node exits immediately if 0")
 
 	// Onchain:
 	ethAcctAddr := flag.String("ethAcctAddr", "", "Existing Eth account address")
 	ethPassword := flag.String("ethPassword", "", "Password for existing Eth account address")
 	ethKeystorePath := flag.String("ethKeystorePath", "", "Path for the Eth Key")
+	ethUsbWallet := flag.Bool("ethUsbWallet", false, "Set to true to use the account of a Ledger or Trezor wallet plugged in over USB instead of the keystore. Transactions are confirmed on the device, but messages can't be signed, so it is only supported by nodes that don't run -broadcaster or -orchestrator, e.g. -redeemer or -reward nodes")
+	ethDerivationPath := flag.String("ethDerivationPath", accounts.DefaultBaseDerivationPath.String(), "HD derivation path of the account of the USB wallet when -ethUsbWallet is set")
+	ethRemoteSigner := flag.String("ethRemoteSigner", "", "HTTP(S) URL or IPC path of the RPC API of a remote signer, e.g. Clef or Web3Signer, holding the account of the node instead of the keystore")
+	ethRemoteSignerAPI := flag.String("ethRemoteSignerAPI", eth.RemoteSignerClef, "API of the remote signer of -ethRemoteSigner: clef or web3signer")
+	ethRemoteSignerTimeout := flag.Duration("ethRemoteSignerTimeout", eth.DefaultRemoteSignerTimeout, "Time to wait for the response of the remote signer to a request, including its approval in Clef")
+	ethRemoteSignerHealthInterval := flag.Duration("ethRemoteSignerHealthInterval", time.Minute, "Interval at which the remote signer is checked. Set to 0 to disable")
+	ethOfflineSigning := flag.Bool("ethOfflineSigning", false, "Set to true to queue the transactions of the account of -ethAcctAddr unsigned instead of sending them, to be signed on an air-gapped machine with `livepeer account sign-tx` and sent with the sendSignedTransaction CLI command. Messages can't be signed, so it is only supported by nodes that don't run -broadcaster or -orchestrator, e.g. -redeemer or -reward nodes")
+	ethUsbConfirmTimeout := flag.Duration("ethUsbConfirmTimeout", eth.DefaultUsbConfirmTimeout, "Time to wait for a transaction to be confirmed on the USB wallet when -ethUsbWallet is set")
 	ethOrchAddr := flag.String("ethOrchAddr", "", "ETH address of an on-chain registered orchestrator")
-	ethUrl := flag.String("ethUrl", "", "Ethereum node JSON-RPC URL")
+	ethUrl := flag.String("ethUrl", "", "Ethereum node JSON-RPC URL. Comma separated list of HTTP(S) URLs of several nodes to fail over between")
+	ethUrlTimeout := flag.Duration("ethUrlTimeout", eth.DefaultRPCFailoverTimeout, "Time to wait for the response of an Ethereum node before failing over to the next node of -ethUrl")
+	ethUrlHealthInterval := flag.Duration("ethUrlHealthInterval", 30*time.Second, "Interval at which the health and the latency of the Ethereum nodes of -ethUrl are checked, to prefer the healthy node with the lowest latency. Set to 0 to disable")
 	ethController := flag.String("ethController", "", "Protocol smart contract address")
 	gasLimit := flag.Int("gasLimit", 0, "Gas limit for ETH transactions")
-	gasPrice := flag.Int("gasPrice", 0, "Gas price for ETH transactions")
+	gasPrice := flag.Int("gasPrice", 0, "Deprecated, use -minGasPrice and -maxGasPrice. Gas price for ETH transactions, which sets both the floor and the ceiling of the gas price")
+	minGasPrice := flag.String("minGasPrice", "", "Minimum gas price in Wei for ETH transactions. No floor if not set")
+	maxGasPrice := flag.String("maxGasPrice", "", "Maximum gas price in Wei for ETH transactions, which are legacy transactions priced from the base fee of the latest block or the gas price oracle. The node fails to price transactions while the base fee is above it. No cap if not set")
+	gasPriceOracleURL := flag.String("gasPriceOracleURL", "", "URL of an external gas price oracle to get the gas price of ETH transactions from, falling back to the Ethereum node if the oracle fails")
+	gasPriceTip := flag.String("gasPriceTip", "", "Tip in Wei added to the base fee of the latest block in the gas price of ETH transactions, which are legacy transactions that pay their whole gas price. Estimated by the Ethereum node if not set")
+	ethTxStuckTimeout := flag.Duration("ethTxStuckTimeout", eth.DefaultTxStuckTimeout, "Time after which a pending ETH transaction is replaced with the same transaction at a bumped gas price. Set to 0 to disable")
 	initializeRound := flag.Bool("initializeRound", false, "Set to true if running as a transcoder and the node should automatically initialize new rounds")
+	initializeRoundFirst := flag.Bool("initializeRoundFirst", false, "Set to true with -initializeRound for the node to initialize new rounds as soon as it sees them uninitialized, rather than in the epochs in which it is selected among the upcoming active set")
+	redeemGas := flag.Int("redeemGas", 0, "Orchestrator only. Estimate of the gas required to redeem a PM ticket, which the ticket face value covers the cost of. Defaults to the estimate of the network")
 	ticketEV := flag.String("ticketEV", "1000000000000", "The expected value for PM tickets")
+	ticketOverhead := flag.String("ticketOverhead", "", "Orchestrator only. Target share of the face value of PM tickets spent on redeeming them, e.g. 0.01. If set, the face value and win probability of tickets are computed from it, the price and the gas price instead of -ticketEV")
+	pixelsPerTicket := flag.Int64("pixelsPerTicket", 0, "Orchestrator only. The number of pixels paid for by each PM ticket when -ticketOverhead is set")
 	// Broadcaster max acceptable ticket EV
 	maxTicketEV := flag.String("maxTicketEV", "100000000000000", "The maximum acceptable expected value for PM tickets")
 	// Broadcaster deposit multiplier to determine max acceptable ticket faceValue
 	depositMultiplier := flag.Int("depositMultiplier", 1, "The deposit multiplier used to determine max acceptable faceValue for PM tickets")
 	// Orchestrator base pricing info
 	pricePerUnit := flag.Int("pricePerUnit", 0, "The price per 'pixelsPerUnit' amount pixels")
+	capabilityPrices := flag.String("capabilityPrices", "av1_encode=400", "Orchestrator only. Comma separated capability=percent pairs pricing the pixels of the renditions that need a capability as a percentage of -pricePerUnit, e.g. for codecs that are slower to encode")
+	receiptInterval := flag.Int("receiptInterval", core.ReceiptInterval, "Number of segments of a stream covered by each signed transcode receipt sent by an orchestrator. Set to 0 to disable receipts")
 	// Broadcaster max acceptable price
 	maxPricePerUnit := flag.Int("maxPricePerUnit", 0, "The maximum transcoding price (in wei) per 'pixelsPerUnit' a broadcaster is willing to accept. If not set explicitly, broadcaster is willing to accept ANY price")
 	// Unit of pixels for both O's basePriceInfo and B's MaxBroadcastPrice
 	pixelsPerUnit := flag.Int("pixelsPerUnit", 1, "Amount of pixels per unit. Set to '> 1' to have smaller price granularity than 1 wei / pixel")
 	// Interval to poll for blocks
 	blockPollingInterval := flag.Int("blockPollingInterval", 5, "Interval in seconds at which different blockchain event services poll for blocks")
+	// Earnings accounting
+	indexEarnings := flag.Bool("indexEarnings", false, "Set to true to index the fees, rewards and bonds of the node's address from the chain into the local DB, for the /earnings endpoint")
+	earningsStartBlock := flag.Uint64("earningsStartBlock", 0, "Block from which the earnings of the node are backfilled with -indexEarnings, e.g. the block of the first transaction of the node's address. The earnings are indexed from the latest block if not set")
 	// Redemption service
 	redeemer := flag.Bool("redeemer", false, "Set to true to run a ticket redemption service")
 	redeemerAddr := flag.String("redeemerAddr", "", "URL of the ticket redemption service to use")
+	redeemBatchSize := flag.Int("redeemBatchSize", 1, "Number of winning tickets of a broadcaster to redeem with a single transaction. Set to 1 to redeem tickets one at a time")
+	redeemBatchInterval := flag.Duration("redeemBatchInterval", 10*time.Minute, "Longest time that redeemable winning tickets wait for a full batch of -redeemBatchSize tickets")
+	redeemRetryBackoff := flag.Duration("redeemRetryBackoff", time.Minute, "Time that a winning ticket waits before its redemption is retried after the first failed attempt, doubled with every failed attempt")
+	redeemMaxRetryBackoff := flag.Duration("redeemMaxRetryBackoff", time.Hour, "Longest time that a winning ticket waits before its redemption is retried")
+	redeemMaxAttempts := flag.Int("redeemMaxAttempts", 0, "Number of failed redemption attempts after which a winning ticket is given up on. Set to 0 to retry redemptions until tickets expire")
+	// Payout split with the transcoder pool operator
+	payoutSplitAddr := flag.String("payoutSplitAddr", "", "Orchestrator only. ETH address of the operator of the transcoder pool of the orchestrator, entitled to -payoutSplitShare of the winnings of tickets")
+	payoutSplitShare := flag.Float64("payoutSplitShare", 0, "Orchestrator only. Percentage of the face value of winning tickets owed to -payoutSplitAddr, e.g. 12.5")
+	contractSigs := flag.Bool("contractSigs", false, "Orchestrator only. Accept the ERC-1271 signatures of broadcasters whose address is a smart contract wallet, which calls the Ethereum node for signatures that aren't ECDSA signatures. The tickets of contract wallets can only be redeemed by a TicketBroker that verifies ERC-1271 signatures")
+	ticketVersion := flag.Uint("ticketVersion", uint(pm.TicketVersionLegacy), "Orchestrator only. Version of the tickets accepted from broadcasters: 0 for tickets signed as personal messages, 1 for tickets signed as EIP-712 typed data. Version 1 tickets can only be redeemed by a TicketBroker that verifies typed data signatures")
+	// RecipientRand rotation
+	recipientRandMaxTickets := flag.Int("recipientRandMaxTickets", 0, "Orchestrator only. Number of tickets accepted with the same ticket params, after which broadcasters must use new params. Set to 0 to disable")
+	recipientRandTTL := flag.Duration("recipientRandTTL", 0, "Orchestrator only. Time during which tickets are accepted with the same ticket params, after which broadcasters must use new params. Set to 0 to disable")
+	// Free tier
+	freeTickets := flag.Bool("freeTickets", false, "Set to true to enable the free tier. Orchestrators accept zero face value tickets without checking the deposit and reserve of broadcasters, and broadcasters send a zero face value ticket with every segment to orchestrators with a price of 0")
+	// Payment batching
+	paymentSegments := flag.Int("paymentSegments", 1, "Broadcaster only. Number of segments covered by a payment to an orchestrator. Segments are sent without tickets while the credit of the last payment covers them, so that high FPS streams make fewer payments")
+	// Deposit and reserve top-ups
+	topUpDepositMin := flag.String("topUpDepositMin", "", "Broadcaster only. Deposit in Wei below which the deposit is topped up to -topUpDepositTarget from the wallet of the broadcaster. No top-ups of the deposit if not set")
+	topUpDepositTarget := flag.String("topUpDepositTarget", "", "Broadcaster only. Deposit in Wei that the deposit is topped up to once it falls below -topUpDepositMin")
+	topUpReserveMin := flag.String("topUpReserveMin", "", "Broadcaster only. Reserve in Wei below which the reserve is topped up to -topUpReserveTarget from the wallet of the broadcaster. No top-ups of the reserve if not set")
+	topUpReserveTarget := flag.String("topUpReserveTarget", "", "Broadcaster only. Reserve in Wei that the reserve is topped up to once it falls below -topUpReserveMin")
+	topUpDailyCap := flag.String("topUpDailyCap", "", "Broadcaster only. Max amount in Wei spent on deposit and reserve top-ups in 24 hours. No cap if not set")
+	topUpInterval := flag.Duration("topUpInterval", 10*time.Minute, "Broadcaster only. Interval at which the deposit and the reserve are checked for top-ups, in addition to the checks after the TicketBroker events of the broadcaster")
+	topUpWebhook := flag.String("topUpWebhook", "", "Broadcaster only. URL to POST the top-ups of the deposit and the reserve, and the top-ups that could not be made, to")
+	// Sender blacklist
+	maxSenderDoubleSpends := flag.Int64("maxSenderDoubleSpends", 0, "Orchestrator only. Number of tickets reusing a nonce after which a broadcaster is blacklisted. Set to 0 to disable")
+	maxSenderInsufficientFunds := flag.Int64("maxSenderInsufficientFunds", 0, "Orchestrator only. Number of ticket params requests from a broadcaster with an insufficient reserve after which it is blacklisted. Set to 0 to disable")
+	maxSenderInvalidSignatures := flag.Int64("maxSenderInvalidSignatures", 0, "Orchestrator only. Number of tickets with an invalid signature after which a broadcaster is blacklisted. Set to 0 to disable")
+	freezeUnlockedSenders := flag.Bool("freezeUnlockedSenders", true, "Orchestrator only. Refuse the tickets of broadcasters as soon as they unlock their deposit and reserve, rather than in the last round of the unlock period")
+	// Relay service
+	relay := flag.Bool("relay", false, "Set to true to run a relay for orchestrators without public ingress")
+	relayAddr := flag.String("relayAddr", "", "Orchestrator only. Address of the relay to serve broadcasters through when the node has no public ingress; -serviceAddr must point to the relay")
+	relaySecret := flag.String("relaySecret", "", "Secret shared by a relay and the orchestrators using it")
 	// Reward service
 	reward := flag.Bool("reward", false, "Set to true to run a reward service")
+	rewardMaxGasPrice := flag.String("rewardMaxGasPrice", "", "Orchestrator only. Highest gas price in Wei at which the reward service calls reward. Reward is called once the gas price goes below it, or is missed if the round ends first. No cap if not set")
+	rewardRetryInterval := flag.Duration("rewardRetryInterval", time.Minute, "Orchestrator only. Interval between the attempts of the reward service to call reward in a round")
+	rewardMaxAttempts := flag.Int("rewardMaxAttempts", 0, "Orchestrator only. Number of failed attempts of the reward service to call reward in a round before it gives up on the round. No limit if 0")
+	rewardMissedWebhook := flag.String("rewardMissedWebhook", "", "Orchestrator only. URL to POST the rounds in which the reward service did not call reward to")
 	// Metrics & logging:
 	monitor := flag.Bool("monitor", false, "Set to true to send performance metrics")
+	metricGroups := flag.String("metricGroups", "", "Comma separated list of metric groups to collect. Collects all groups if empty")
+	metricSampleRates := flag.String("metricSampleRates", "", "Comma separated list of sample rates between 0 and 1 for metric groups, e.g. segment=0.1")
+	statsdAddr := flag.String("statsdAddr", "", "UDP address of a StatsD server or Datadog agent to send metrics to, e.g. 127.0.0.1:8125. Requires -monitor")
+	statsdPrefix := flag.String("statsdPrefix", "livepeer.", "Prefix of the metric names sent to StatsD")
+	statsdTags := flag.String("statsdTags", "", "Comma separated list of key:value tags attached to the metrics sent to StatsD")
+	statsdFormat := flag.String("statsdFormat", lpmon.StatsdFormatDogstatsd, "StatsD line format, either dogstatsd (with tags) or statsd (without tags)")
 	version := flag.Bool("version", false, "Print out the version")
 	verbosity := flag.String("v", "", "Log verbosity.  {4|5|6}")
+	crashReportSentryDSN := flag.String("crashReportSentryDsn", "", "Sentry DSN to report panics and fatal errors to")
+	crashReportWebhook := flag.String("crashReportWebhook", "", "URL to POST reports of panics and fatal errors to")
+	diagnosticsToken := flag.String("diagnosticsToken", "", "Enables the pprof, expvar and diagnostic bundle endpoints on the CLI server, protected by this token")
 
 	// Storage:
 	datadir := flag.String("datadir", "", "data directory")
@@ -164,14 +307,80 @@ func main() {
 	s3creds := flag.String("s3creds", "", "S3 credentials (in form ACCESSKEYID/ACCESSKEY)")
 	gsBucket := flag.String("gsbucket", "", "Google storage bucket")
 	gsKey := flag.String("gskey", "", "Google Storage private key file name (in json format)")
+	inlineSegmentMaxSize := flag.Uint64("inlineSegmentMaxSize", core.InlineSegmentMaxSize, "Largest segment, in bytes, sent to orchestrators in the body of requests rather than through the object storage of the broadcaster. The smaller size of the broadcaster and the orchestrator is used. Set to 0 to always use the object storage")
+
+	// Audit log
+	auditLog := flag.Bool("auditLog", false, "Set to true to record ticket receipts, redemptions, price changes and withdrawals in a hash-chained audit log in the data directory")
+	verifyAuditLog := flag.String("verifyAuditLog", "", "Path to an audit log to verify. The node exits after verification")
 
 	// API
 	authWebhookURL := flag.String("authWebhookUrl", "", "RTMP authentication webhook URL")
 	orchWebhookURL := flag.String("orchWebhookUrl", "", "Orchestrator discovery callback URL")
-
-	flag.Parse()
+	webhookAttempts := flag.Int("webhookAttempts", webhook.DefaultOptions.MaxAttempts, "Number of attempts of an outbound webhook delivery before it is added to the dead-letter queue in the data directory")
+	webhookDeadLetters := flag.Int("webhookDeadLetters", 1000, "Maximum number of failed webhook deliveries kept in the dead-letter queue, the oldest ones are dropped when the queue is full")
+
+	// Experimental features
+	featureList := flag.String("features", "", "Comma separated list of experimental features to enable, see `livepeer_cli` or the /features endpoint of the CLI server")
+
+	// Secrets
+	secretsRefresh := flag.Duration("secretsRefresh", 0, "Interval at which the secrets referenced by flags are read again from Vault or AWS Secrets Manager, applying the rotated ones. Disabled if 0")
+
+	// Config
+	configFile := flag.String("config", "", "Path to a YAML or TOML config file setting any of the flags. Flags take precedence over LP_ prefixed environment variables, which take precedence over the config file")
+
+	args, command := configCommand(os.Args[1:])
+	args, accountCmd := accountCommand(args)
+	args, isHealthcheck := healthcheckCommand(args)
+	flag.CommandLine.Parse(args)
+	switch command {
+	case "validate":
+		if err := validateConfig(flag.CommandLine, *configFile); err != nil {
+			glog.Fatalf("Config is invalid: %v", err)
+		}
+		return
+	case "import":
+		if err := importConfig(flag.CommandLine, *configFile, flag.Arg(0)); err != nil {
+			glog.Fatalf("Error importing config snapshot: %v", err)
+		}
+		return
+	}
+	cliFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { cliFlags[f.Name] = true })
+	if err := applyConfig(flag.CommandLine, *configFile, os.Environ()); err != nil {
+		glog.Fatalf("Error loading config: %v", err)
+	}
+	secretFlags, err := resolveSecretFlags(flag.CommandLine, secrets.NewResolver(os.Getenv))
+	if err != nil {
+		glog.Fatalf("Error loading secrets: %v", err)
+	}
 	vFlag.Value.Set(*verbosity)
 
+	if isHealthcheck {
+		client, err := healthcheckClient(*cliCert, *cliKey, *cliClientCA)
+		if err == nil {
+			err = healthcheck(client, healthcheckURL(*cliAddr, *cliCert != ""), *cliToken)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Node is not healthy: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Node is healthy")
+		return
+	}
+
+	// Settings that can be reloaded on SIGHUP or from the /reload endpoint
+	configReloader := newReloader(flag.CommandLine, *configFile, cliFlags, os.Environ)
+	configReloader.resolve = secretFlags.resolve
+	configReloader.add(func() error { return vFlag.Value.Set(*verbosity) }, "v")
+
+	if err := features.Configure(*featureList); err != nil {
+		glog.Fatalf("Error enabling experimental features: %v", err)
+	}
+	if *featureList != "" {
+		glog.Infof("Enabled experimental features: %v", features.EnabledList())
+	}
+	configReloader.add(func() error { return features.Configure(*featureList) }, "features")
+
 	isFlagSet := make(map[string]bool)
 	flag.Visit(func(f *flag.Flag) { isFlagSet[f.Name] = true })
 
@@ -185,6 +394,13 @@ func main() {
 		return
 	}
 
+	if *verifyAuditLog != "" {
+		if err := verifyAuditLogFile(*verifyAuditLog); err != nil {
+			glog.Fatalf("Audit log %v is invalid: %v", *verifyAuditLog, err)
+		}
+		return
+	}
+
 	if *maxSessions <= 0 {
 		glog.Fatal("-maxSessions must be greater than zero")
 		return
@@ -192,6 +408,13 @@ func main() {
 
 	type NetworkConfig struct {
 		ethController string
+		chainID       int64
+		// redeemGas is the estimate of the gas required to redeem a ticket, if it is not the
+		// default estimate
+		redeemGas int
+		// l2 is set for rollups, whose gas estimates include the cost of posting transactions to
+		// L1 and whose contracts count blocks in L1 blocks
+		l2 bool
 	}
 
 	ctx := context.Background()
@@ -199,28 +422,54 @@ func main() {
 	configOptions := map[string]*NetworkConfig{
 		"rinkeby": {
 			ethController: "0xA268AEa9D048F8d3A592dD7f1821297972D4C8Ea",
+			chainID:       4,
 		},
 		"mainnet": {
 			ethController: "0xf96d54e490317c557a967abfa5d6e33006be69b3",
+			chainID:       1,
+		},
+		"arbitrum-one-rinkeby": {
+			ethController: "0x9ceC649179e2C7Ab91688271bcD09fb707b3E574",
+			chainID:       421611,
+			redeemGas:     1200000,
+			l2:            true,
+		},
+		"arbitrum-one-mainnet": {
+			ethController: "0xD8E8328501E9645d16Cf49539efC04f734606ee4",
+			chainID:       42161,
+			redeemGas:     1200000,
+			l2:            true,
 		},
 	}
 
 	// If multiple orchAddr specified, ensure other necessary flags present and clean up list
 	var orchURLs []*url.URL
 	if len(*orchAddr) > 0 {
-		for _, addr := range strings.Split(*orchAddr, ",") {
-			addr = strings.TrimSpace(addr)
-			addr = defaultAddr(addr, "127.0.0.1", RpcPort)
-			if !strings.HasPrefix(addr, "http") {
-				addr = "https://" + addr
-			}
-			uri, err := url.ParseRequestURI(addr)
-			if err != nil {
-				glog.Error("Could not parse orchestrator URI: ", err)
-				continue
-			}
-			orchURLs = append(orchURLs, uri)
+		orchURLs = parseOrchAddrs(*orchAddr)
+	}
+
+	// The custom network is a private deployment of the protocol
+	var contractAddrs map[string]ethcommon.Address
+	if *network == customNetwork {
+		if *networkConfig == "" {
+			glog.Errorf("-networkConfig is required for -network %v", customNetwork)
+			return
+		}
+		cfg, addrs, err := readNetworkConfig(*networkConfig)
+		if err != nil {
+			glog.Error(err)
+			return
 		}
+		configOptions[customNetwork] = &NetworkConfig{
+			ethController: cfg.Controller,
+			chainID:       cfg.ChainID,
+			redeemGas:     cfg.RedeemGas,
+			l2:            cfg.L2,
+		}
+		contractAddrs = addrs
+	} else if *networkConfig != "" {
+		glog.Errorf("-networkConfig is only supported for -network %v", customNetwork)
+		return
 	}
 
 	// Setting config options based on specified network
@@ -241,6 +490,13 @@ func main() {
 		*datadir = filepath.Join(homedir, ".lpData", *network)
 	}
 
+	if accountCmd != "" {
+		if err := runAccountCommand(accountCmd, keystoreDirectory(*ethKeystorePath, *datadir), *ethPassword, flag.Arg(0)); err != nil {
+			glog.Fatalf("Error running account %v: %v", accountCmd, err)
+		}
+		return
+	}
+
 	//Make sure datadir is present
 	if _, err := os.Stat(*datadir); os.IsNotExist(err) {
 		glog.Infof("Creating data dir: %v", *datadir)
@@ -262,27 +518,100 @@ func main() {
 		glog.Errorf("Error creating livepeer node: %v", err)
 	}
 
+	if *auditLog {
+		auditLogPath := filepath.Join(*datadir, "audit.log")
+		n.AuditLog, err = audit.Open(auditLogPath)
+		if err != nil {
+			glog.Errorf("Error opening audit log, use -verifyAuditLog %v to inspect it: %v", auditLogPath, err)
+			return
+		}
+		defer n.AuditLog.Close()
+		glog.Infof("Recording payment events in audit log %v", auditLogPath)
+	}
+
+	webhookDLQ, err := webhook.NewDeadLetterQueue(filepath.Join(*datadir, "webhooks"), *webhookDeadLetters)
+	if err != nil {
+		glog.Errorf("Error creating webhook dead-letter queue: %v", err)
+		return
+	}
+	webhookOpts := webhook.DefaultOptions
+	webhookOpts.MaxAttempts = *webhookAttempts
+	server.Webhooks = webhook.NewDispatcher(&http.Client{Timeout: common.HTTPTimeout}, webhookOpts, webhookDLQ)
+
 	if *orchSecret != "" {
 		n.OrchSecret, _ = common.GetPass(*orchSecret)
 	}
 
+	// Capabilities of the local transcoder that depend on the FFmpeg build
+	var transcoderCaps []core.Capability
 	if *transcoder {
 		core.WorkDir = *datadir
 		if *nvidia != "" {
+			devices, err := core.ParseNvidiaDevices(*nvidia)
+			if err != nil {
+				glog.Fatalf("Invalid -nvidia %v: %v", *nvidia, err)
+			}
+			if *nvidiaMaxSessions < 0 {
+				glog.Fatal("-nvidiaMaxSessions must not be negative")
+			}
 			if *testTranscoder {
-				err := core.TestNvidiaTranscoder(*nvidia)
+				err := core.TestNvidiaTranscoder(strings.Join(devices, ","))
 				if err != nil {
 					glog.Fatalf("Unable to transcode using Nvidia gpu=%s err=%v", *nvidia, err)
 				}
 			}
-			n.Transcoder = core.NewLoadBalancingTranscoder(*nvidia, core.NewNvidiaTranscoder)
+			lb := core.NewDeviceLoadBalancer(devices, *nvidiaMaxSessions, core.NewNvidiaTranscoder)
+			glog.Infof("Transcoding on Nvidia devices=%v maxSessionsPerDevice=%v", strings.Join(devices, ","), *nvidiaMaxSessions)
+			if capacity := lb.Capacity(); capacity > 0 && capacity < *maxSessions {
+				glog.Infof("Lowering -maxSessions from %v to the %v sessions of the Nvidia devices", *maxSessions, capacity)
+				*maxSessions = capacity
+			}
+			n.Transcoder = lb
 		} else {
 			n.Transcoder = core.NewLocalTranscoder(*datadir)
+			if _, err := core.TestSoftwareEncoder(common.ProfileH265Main, ffmpeg.FormatMPEGTS); err != nil {
+				glog.Infof("Not advertising HEVC: unable to transcode H265 renditions err=%v", err)
+			} else {
+				transcoderCaps = append(transcoderCaps, core.Capability_HEVC)
+			}
+			if av1Seg, err := core.TestSoftwareEncoder(common.ProfileAV1Main, ffmpeg.FormatMP4); err != nil {
+				glog.Infof("Not advertising AV1: unable to transcode AV1 renditions err=%v", err)
+			} else {
+				transcoderCaps = append(transcoderCaps, core.Capability_AV1Encode)
+				if err := core.TestSoftwareDecoder(av1Seg); err != nil {
+					glog.Infof("Not advertising AV1 decoding: unable to transcode AV1 segments err=%v", err)
+				} else {
+					transcoderCaps = append(transcoderCaps, core.Capability_AV1Decode)
+				}
+			}
+			webm := false
+			if _, err := core.TestSoftwareEncoder(common.ProfileVP8, common.FormatWebM); err != nil {
+				glog.Infof("Not advertising VP8: unable to transcode VP8 renditions err=%v", err)
+			} else {
+				transcoderCaps = append(transcoderCaps, core.Capability_VP8)
+				webm = true
+			}
+			if _, err := core.TestSoftwareEncoder(common.ProfileVP9, common.FormatWebM); err != nil {
+				glog.Infof("Not advertising VP9: unable to transcode VP9 renditions err=%v", err)
+			} else {
+				transcoderCaps = append(transcoderCaps, core.Capability_VP9)
+				webm = true
+			}
+			if webm {
+				transcoderCaps = append(transcoderCaps, core.Capability_WebM)
+			}
+		}
+		if core.ClippingAvailable() {
+			transcoderCaps = append(transcoderCaps, core.Capability_Clip)
+		} else {
+			glog.Infof("Not advertising clipping: unable to find the %v binary", core.FFmpegCommand)
 		}
 	}
 
 	if *redeemer {
 		n.NodeType = core.RedeemerNode
+	} else if *relay {
+		n.NodeType = core.RelayNode
 	} else if *orchestrator {
 		n.NodeType = core.OrchestratorNode
 		if !*transcoder {
@@ -294,7 +623,7 @@ func main() {
 	} else if *broadcaster {
 		n.NodeType = core.BroadcasterNode
 	} else if !*reward && !*initializeRound {
-		glog.Fatalf("No services enabled; must be at least one of -broadcaster, -transcoder, -orchestrator, -redeemer, -reward or -initializeRound")
+		glog.Fatalf("No services enabled; must be at least one of -broadcaster, -transcoder, -orchestrator, -redeemer, -relay, -reward or -initializeRound")
 	}
 
 	if *monitor {
@@ -304,35 +633,88 @@ func main() {
 			hn, _ := os.Hostname()
 			nodeID = hn
 		}
-		nodeType := "dflt"
-		switch n.NodeType {
-		case core.BroadcasterNode:
-			nodeType = "bctr"
-		case core.OrchestratorNode:
-			nodeType = "orch"
-		case core.TranscoderNode:
-			nodeType = "trcr"
-		case core.RedeemerNode:
-			nodeType = "rdmr"
+		metricsConfig, err := lpmon.ParseMetricsConfig(*metricGroups, *metricSampleRates)
+		if err != nil {
+			glog.Fatalf("Error parsing metrics configuration err=%v", err)
 		}
-		lpmon.InitCensus(nodeType, nodeID, core.LivepeerVersion)
+		lpmon.InitMetricsConfig(metricsConfig)
+		lpmon.InitCensus(nodeTypeName(n.NodeType), nodeID, core.LivepeerVersion)
+		pm.SetMetrics(lpmon.PaymentMetrics{})
+
+		if *statsdAddr != "" {
+			var tags []string
+			if *statsdTags != "" {
+				tags = strings.Split(*statsdTags, ",")
+			}
+			exporter, err := lpmon.NewStatsdExporter(lpmon.StatsdOptions{
+				Addr:   *statsdAddr,
+				Prefix: *statsdPrefix,
+				Tags:   tags,
+				Format: *statsdFormat,
+			})
+			if err != nil {
+				glog.Fatalf("Error setting up StatsD exporter err=%v", err)
+			}
+			lpmon.InitStatsd(exporter)
+			glog.Infof("Sending metrics to StatsD at %v", *statsdAddr)
+		}
+	} else if *statsdAddr != "" {
+		glog.Fatal("Sending metrics to StatsD requires -monitor")
 	}
 
+	if *crashReportSentryDSN != "" || *crashReportWebhook != "" {
+		cr, err := lpmon.NewCrashReporter(*crashReportSentryDSN, *crashReportWebhook, map[string]string{
+			"version":  core.LivepeerVersion,
+			"nodeType": nodeTypeName(n.NodeType),
+			"network":  *network,
+		}, server.Webhooks)
+		if err != nil {
+			glog.Fatalf("Error setting up crash reporting err=%v", err)
+		}
+		lpmon.InitCrashReporter(cr)
+		defer lpmon.RecoverAndReport()
+	}
+
+	if *diagnosticsToken != "" {
+		server.DiagnosticsToken = *diagnosticsToken
+		server.RecentLogs = common.NewLogBuffer(recentLogsSize)
+		if err := teeStderr(server.RecentLogs); err != nil {
+			glog.Errorf("Unable to capture recent logs for diagnostics err=%v", err)
+		}
+	}
+
+	watchdog := newWatchdog()
+	lpmon.Go(func() { watchdog.Run(ctx) })
+
 	if n.NodeType == core.TranscoderNode {
 		glog.Info("***Livepeer is in transcoder mode ***")
 		if n.OrchSecret == "" {
-			glog.Fatal("Missing -orchSecret")
+			lpmon.Fatal("Missing -orchSecret")
 		}
-		if len(orchURLs) > 0 {
+		if len(orchURLs) == 0 {
+			lpmon.Fatal("Missing -orchAddr")
+		}
+		done := make(chan struct{})
+		go func() {
+			defer lpmon.RecoverAndReport()
 			server.RunTranscoder(n, orchURLs[0].Host, *maxSessions)
-		} else {
-			glog.Fatal("Missing -orchAddr")
+			close(done)
+		}()
+		c := shutdownSignals()
+		select {
+		case <-done:
+		case sig := <-c:
+			glog.Infof("Exiting Livepeer Transcoder: %v", sig)
+			sd := &shutdown{timeout: *shutdownTimeout}
+			sd.run(c)
 		}
 		return
 	}
 
+	sd := &shutdown{timeout: *shutdownTimeout}
 	watcherErr := make(chan error)
 	redeemerErr := make(chan error)
+	relayErr := make(chan error)
 	var timeWatcher *watchers.TimeWatcher
 	if *network == "offchain" {
 		glog.Infof("***Livepeer is in off-chain mode***")
@@ -343,13 +725,7 @@ func main() {
 		}
 
 	} else {
-		var keystoreDir string
-		if _, err := os.Stat(*ethKeystorePath); !os.IsNotExist(err) {
-			keystoreDir, _ = filepath.Split(*ethKeystorePath)
-		} else {
-			keystoreDir = filepath.Join(*datadir, "keystore")
-		}
-
+		keystoreDir := keystoreDirectory(*ethKeystorePath, *datadir)
 		if keystoreDir == "" {
 			glog.Errorf("Cannot find keystore directory")
 			return
@@ -357,15 +733,80 @@ func main() {
 
 		//Get the Eth client connection information
 		if *ethUrl == "" {
-			glog.Fatal("Need to specify an Ethereum node JSON-RPC URL using -ethUrl")
+			lpmon.Fatal("Need to specify an Ethereum node JSON-RPC URL using -ethUrl")
 		}
 
 		//Set up eth client
-		backend, err := ethclient.Dial(*ethUrl)
+		ethUrls := strings.Split(*ethUrl, ",")
+		for i := range ethUrls {
+			ethUrls[i] = strings.TrimSpace(ethUrls[i])
+		}
+		// The health checks of the Ethereum nodes stop when the node shuts down
+		rpcCtx, cancelRPC := context.WithCancel(ctx)
+		defer cancelRPC()
+		rpcClient, err := eth.DialRPCFailover(rpcCtx, eth.RPCFailoverConfig{
+			URLs:                ethUrls,
+			Timeout:             *ethUrlTimeout,
+			HealthCheckInterval: *ethUrlHealthInterval,
+		})
 		if err != nil {
 			glog.Errorf("Failed to connect to Ethereum client: %v", err)
 			return
 		}
+		backend := ethclient.NewClient(rpcClient)
+
+		var feeCap, tip *big.Int
+		if *maxGasPrice != "" {
+			var ok bool
+			if feeCap, ok = new(big.Int).SetString(*maxGasPrice, 10); !ok || feeCap.Sign() <= 0 {
+				glog.Errorf("-maxGasPrice must be a positive integer, provided %v", *maxGasPrice)
+				return
+			}
+		}
+		if *gasPriceTip != "" {
+			var ok bool
+			if tip, ok = new(big.Int).SetString(*gasPriceTip, 10); !ok || tip.Sign() < 0 {
+				glog.Errorf("-gasPriceTip must be a non-negative integer, provided %v", *gasPriceTip)
+				return
+			}
+		}
+		feeOracle := eth.NewFeeOracle(rpcClient, feeCap, tip)
+
+		floor, err := parseWei("minGasPrice", *minGasPrice)
+		if err != nil {
+			glog.Error(err)
+			return
+		}
+		if *gasPrice > 0 {
+			glog.Warningf("-gasPrice is deprecated, use -minGasPrice and -maxGasPrice")
+			if floor == nil {
+				floor = big.NewInt(int64(*gasPrice))
+			}
+			if feeCap == nil {
+				feeCap = big.NewInt(int64(*gasPrice))
+			}
+		}
+		if floor != nil && feeCap != nil && floor.Cmp(feeCap) > 0 {
+			glog.Errorf("-minGasPrice must be at most -maxGasPrice, provided %v and %v", floor, feeCap)
+			return
+		}
+
+		var gpo eth.GasPriceOracle = feeOracle
+		if *gasPriceOracleURL != "" {
+			if _, err := validateURL(*gasPriceOracleURL); err != nil {
+				glog.Errorf("Invalid -gasPriceOracleURL: %v", err)
+				return
+			}
+			gpo = eth.NewHTTPGasPriceOracle(*gasPriceOracleURL, 0, feeOracle)
+		}
+		// Transactions, ticket redemption costs and the reward service use the gas price of the
+		// monitor, which is kept between the min gas price and the max fee
+		gpm := eth.NewGasPriceMonitor(gpo, blockPollingTime, floor, feeCap)
+		if _, err := gpm.Start(ctx); err != nil {
+			glog.Errorf("error starting gas price monitor: %v", err)
+			return
+		}
+		defer gpm.Stop()
 
 		chainID, err := backend.ChainID(ctx)
 		if err != nil {
@@ -383,33 +824,135 @@ func main() {
 			return
 		}
 
-		client, err := eth.NewClient(ethcommon.HexToAddress(*ethAcctAddr), keystoreDir, backend, ethcommon.HexToAddress(*ethController), EthTxTimeout)
-		if err != nil {
-			glog.Errorf("Failed to create client: %v", err)
+		// The contracts of networks that are not preset are looked up by the chain ID of the
+		// Ethereum node
+		netw, ok := configOptions[*network]
+		if !ok {
+			for _, n := range configOptions {
+				if n.chainID == chainID.Int64() {
+					netw = n
+				}
+			}
+			if netw != nil && *ethController == "" {
+				*ethController = netw.ethController
+				glog.Infof("Using the protocol contracts of chainID=%v: %v", chainID, *ethController)
+			}
+		} else if netw.chainID != chainID.Int64() {
+			glog.Errorf("-network %v expects chainID = %v, but the Ethereum node is on chainID = %v", *network, netw.chainID, chainID)
 			return
 		}
 
-		var bigGasPrice *big.Int
-		if *gasPrice > 0 {
-			bigGasPrice = big.NewInt(int64(*gasPrice))
+		ticketRedeemGas := defaultRedeemGas
+		var estimateGasMargin uint64
+		if netw != nil {
+			if netw.redeemGas > 0 {
+				ticketRedeemGas = netw.redeemGas
+			}
+			if netw.l2 {
+				estimateGasMargin = l2EstimateGasMargin
+			}
+		}
+		if *redeemGas < 0 {
+			glog.Errorf("-redeemGas must be positive, provided %v", *redeemGas)
+			return
+		} else if *redeemGas > 0 {
+			ticketRedeemGas = *redeemGas
+		}
+
+		// Hardware wallets and offline signing only sign transactions, so they can't hold
+		// the account of broadcasters, which sign tickets, or of orchestrators, which sign
+		// their results, receipts and pings
+		signsMessages := *broadcaster || *orchestrator
+
+		var usbWallet *eth.UsbWalletConfig
+		if *ethUsbWallet {
+			if signsMessages {
+				glog.Errorf("-ethUsbWallet is not supported for broadcasters and orchestrators, which sign messages")
+				return
+			}
+			path, err := accounts.ParseDerivationPath(*ethDerivationPath)
+			if err != nil {
+				glog.Errorf("Invalid -ethDerivationPath %v: %v", *ethDerivationPath, err)
+				return
+			}
+			usbWallet = &eth.UsbWalletConfig{
+				DerivationPath: path,
+				ConfirmTimeout: *ethUsbConfirmTimeout,
+			}
+		}
+
+		var remoteSigner *eth.RemoteSignerConfig
+		if *ethRemoteSigner != "" {
+			if *ethUsbWallet {
+				glog.Errorf("-ethRemoteSigner and -ethUsbWallet can't be used together")
+				return
+			}
+			remoteSigner = &eth.RemoteSignerConfig{
+				URL:                 *ethRemoteSigner,
+				API:                 *ethRemoteSignerAPI,
+				Timeout:             *ethRemoteSignerTimeout,
+				HealthCheckInterval: *ethRemoteSignerHealthInterval,
+			}
+		}
+
+		var offlineSigner *eth.OfflineAccountManager
+		if *ethOfflineSigning {
+			if signsMessages {
+				glog.Errorf("-ethOfflineSigning is not supported for broadcasters and orchestrators, which sign messages")
+				return
+			}
+			if *ethUsbWallet || *ethRemoteSigner != "" {
+				glog.Errorf("-ethOfflineSigning can't be used with -ethUsbWallet or -ethRemoteSigner")
+				return
+			}
+			offlineSigner, err = eth.NewOfflineAccountManager(ethcommon.HexToAddress(*ethAcctAddr), chainID)
+			if err != nil {
+				glog.Errorf("Error setting up offline signing: %v", err)
+				return
+			}
+			// Replacements of stuck transactions would be queued, so that they are replaced
+			// by resigning them offline instead
+			*ethTxStuckTimeout = 0
 		}
 
-		err = client.Setup(*ethPassword, uint64(*gasLimit), bigGasPrice)
+		client, err := eth.NewClient(eth.LivepeerEthClientConfig{
+			AccountAddr:       ethcommon.HexToAddress(*ethAcctAddr),
+			KeystoreDir:       keystoreDir,
+			UsbWallet:         usbWallet,
+			RemoteSigner:      remoteSigner,
+			OfflineSigner:     offlineSigner,
+			EthClient:         backend,
+			GasPriceOracle:    gpm,
+			EstimateGasMargin: estimateGasMargin,
+			ControllerAddr:    ethcommon.HexToAddress(*ethController),
+			ContractAddrs:     contractAddrs,
+			TxTimeout:         EthTxTimeout,
+			TxStuckTimeout:    *ethTxStuckTimeout,
+			MaxGasPrice:       feeCap,
+		})
+		if err != nil {
+			glog.Errorf("Failed to create client: %v", err)
+			return
+		}
+		defer client.Close()
+
+		err = client.Setup(*ethPassword, uint64(*gasLimit), nil)
 		if err != nil {
 			glog.Errorf("Failed to setup client: %v", err)
 			return
 		}
 
 		n.Eth = client
+		n.OfflineSigner = offlineSigner
 
 		addrMap := n.Eth.ContractAddresses()
 
+		// Typed data tickets are bound to the chain and the TicketBroker
+		pm.TicketDomain.ChainID = chainID
+		pm.TicketDomain.VerifyingContract = addrMap["TicketBroker"]
+
 		// Initialize block watcher that will emit logs used by event watchers
-		blockWatcherClient, err := blockwatch.NewRPCClient(*ethUrl, ethRPCTimeout)
-		if err != nil {
-			glog.Errorf("Failed to setup blockwatch client: %v", err)
-			return
-		}
+		blockWatcherClient := blockwatch.NewRPCClient(rpcClient, ethRPCTimeout)
 		topics := watchers.FilterTopics()
 
 		// Determine backfilling start block
@@ -438,6 +981,7 @@ func main() {
 			WithLogs:            true,
 			Topics:              topics,
 			Client:              blockWatcherClient,
+			Heartbeat:           watchdog.Register("blockwatch", blockPollingTime),
 		}
 		// Wait until all event watchers have been initialized before starting the block watcher
 		blockWatcher := blockwatch.New(blockWatcherCfg)
@@ -450,6 +994,7 @@ func main() {
 
 		timeWatcherErr := make(chan error, 1)
 		go func() {
+			defer lpmon.RecoverAndReport()
 			if err := timeWatcher.Watch(); err != nil {
 				timeWatcherErr <- fmt.Errorf("roundswatcher failed to start watching for events: %v", err)
 			}
@@ -463,7 +1008,7 @@ func main() {
 			return
 		}
 		// Start unbonding watcher (logs will not be received until the block watcher is started)
-		go unbondingWatcher.Watch()
+		lpmon.Go(func() { unbondingWatcher.Watch() })
 		defer unbondingWatcher.Stop()
 
 		senderWatcher, err := watchers.NewSenderWatcher(addrMap["TicketBroker"], blockWatcher, n.Eth, timeWatcher)
@@ -471,7 +1016,7 @@ func main() {
 			glog.Errorf("Failed to setup senderwatcher: %v", err)
 			return
 		}
-		go senderWatcher.Watch()
+		lpmon.Go(func() { senderWatcher.Watch() })
 		defer senderWatcher.Stop()
 
 		orchWatcher, err := watchers.NewOrchestratorWatcher(addrMap["BondingManager"], blockWatcher, dbh, n.Eth, timeWatcher)
@@ -479,7 +1024,7 @@ func main() {
 			glog.Errorf("Failed to setup orchestrator watcher: %v", err)
 			return
 		}
-		go orchWatcher.Watch()
+		lpmon.Go(func() { orchWatcher.Watch() })
 		defer orchWatcher.Stop()
 
 		serviceRegistryWatcher, err := watchers.NewServiceRegistryWatcher(addrMap["ServiceRegistry"], blockWatcher, dbh, n.Eth)
@@ -487,9 +1032,28 @@ func main() {
 			glog.Errorf("Failed to set up service registry watcher: %v", err)
 			return
 		}
-		go serviceRegistryWatcher.Watch()
+		lpmon.Go(func() { serviceRegistryWatcher.Watch() })
 		defer serviceRegistryWatcher.Stop()
 
+		if *indexEarnings {
+			backend, err := n.Eth.Backend()
+			if err != nil {
+				glog.Errorf("Failed to set up earnings indexer: %v", err)
+				return
+			}
+			var startBlock *big.Int
+			if isFlagSet["earningsStartBlock"] {
+				startBlock = new(big.Int).SetUint64(*earningsStartBlock)
+			}
+			earningsIndexer, err := watchers.NewEarningsIndexer(n.Eth.Account().Address, addrMap["TicketBroker"], addrMap["BondingManager"], startBlock, blockWatcher, backend, dbh)
+			if err != nil {
+				glog.Errorf("Failed to set up earnings indexer: %v", err)
+				return
+			}
+			lpmon.Go(func() { earningsIndexer.Watch() })
+			defer earningsIndexer.Stop()
+		}
+
 		n.Balances = core.NewAddressBalances(cleanupInterval)
 		defer n.Balances.StopCleanup()
 
@@ -504,9 +1068,17 @@ func main() {
 			Claimant:        recipientAddr,
 			CleanupInterval: cleanupInterval,
 			TTL:             smTTL,
-			RedeemGas:       redeemGas,
+			RedeemGas:       ticketRedeemGas,
 			SuggestGasPrice: backend.SuggestGasPrice,
 			RPCTimeout:      ethRPCTimeout,
+			AuditLog:        n.AuditLog,
+
+			RedeemBatchSize:     *redeemBatchSize,
+			RedeemBatchInterval: *redeemBatchInterval,
+
+			RedeemRetryBackoff:    *redeemRetryBackoff,
+			RedeemMaxRetryBackoff: *redeemMaxRetryBackoff,
+			RedeemMaxAttempts:     *redeemMaxAttempts,
 		}
 
 		if *orchestrator {
@@ -536,6 +1108,54 @@ func main() {
 				return
 			}
 
+			var tuner *pm.TicketParamsTuner
+			if *ticketOverhead != "" {
+				overhead, ok := new(big.Rat).SetString(*ticketOverhead)
+				if !ok {
+					glog.Errorf("-ticketOverhead must be a valid rational number, but %v provided. Restart the node with a different valid value for -ticketOverhead", *ticketOverhead)
+					return
+				}
+				tuner, err = pm.NewTicketParamsTuner(overhead, *pixelsPerTicket)
+				if err != nil {
+					glog.Errorf("Invalid -ticketOverhead or -pixelsPerTicket err=%v. Restart the node with different valid values", err)
+					return
+				}
+				glog.Infof("Computing PM ticket params for a redemption overhead of %v%% and %v pixels per ticket", new(big.Rat).Mul(overhead, big.NewRat(100, 1)).FloatString(2), *pixelsPerTicket)
+			}
+
+			if *receiptInterval < 0 {
+				glog.Errorf("-receiptInterval must not be negative, but %v provided. Restart the node with a different valid value for -receiptInterval", *receiptInterval)
+				return
+			}
+			core.ReceiptInterval = *receiptInterval
+
+			var payoutSplit *pm.PayoutSplit
+			if *payoutSplitAddr != "" || *payoutSplitShare != 0 {
+				if !ethcommon.IsHexAddress(*payoutSplitAddr) {
+					glog.Errorf("-payoutSplitAddr must be a valid ETH address, but %v provided. Restart the node with a different valid value for -payoutSplitAddr", *payoutSplitAddr)
+					return
+				}
+				payoutSplit = &pm.PayoutSplit{
+					Recipient: ethcommon.HexToAddress(*payoutSplitAddr),
+					Share:     eth.FromPerc(*payoutSplitShare).Int64(),
+				}
+				if err := payoutSplit.Validate(); err != nil {
+					glog.Errorf("Invalid -payoutSplitAddr or -payoutSplitShare err=%v. Restart the node with different valid values", err)
+					return
+				}
+				glog.Infof("Paying out %v%% of the winnings of tickets to %v", *payoutSplitShare, payoutSplit.Recipient.Hex())
+			}
+
+			if *ticketVersion != uint(pm.TicketVersionLegacy) && *ticketVersion != uint(pm.TicketVersionTypedData) {
+				glog.Errorf("-ticketVersion must be %v or %v, but %v provided. Restart the node with a different valid value for -ticketVersion", pm.TicketVersionLegacy, pm.TicketVersionTypedData, *ticketVersion)
+				return
+			}
+
+			if *recipientRandMaxTickets < 0 || *recipientRandTTL < 0 {
+				glog.Errorf("-recipientRandMaxTickets and -recipientRandTTL must not be negative. Restart the node with different valid values")
+				return
+			}
+
 			orchSetupCtx, cancel := context.WithCancel(ctx)
 			defer cancel()
 
@@ -544,17 +1164,23 @@ func main() {
 				return
 			}
 
-			sigVerifier := &pm.DefaultSigVerifier{}
-			validator := pm.NewValidator(sigVerifier, timeWatcher)
-			gpm := eth.NewGasPriceMonitor(backend, blockPollingTime)
-			// Start gas price monitor
-			_, err := gpm.Start(ctx)
-			if err != nil {
-				glog.Errorf("error starting gas price monitor: %v", err)
-				return
+			var sigVerifier pm.SigVerifier = &pm.DefaultSigVerifier{}
+			if *contractSigs {
+				// Accept the signatures of smart contract wallets
+				backend, err := n.Eth.Backend()
+				if err != nil {
+					glog.Errorf("Failed to get ETH backend: %v", err)
+					return
+				}
+				contractSigVerifier, err := pm.NewContractSigVerifier(backend)
+				if err != nil {
+					glog.Errorf("Error creating signature verifier: %v", err)
+					return
+				}
+				sigVerifier = contractSigVerifier
+				n.SigVerifier = contractSigVerifier
 			}
-			defer gpm.Stop()
-
+			validator := pm.NewValidator(sigVerifier, timeWatcher)
 			var sm pm.SenderMonitor
 			if *redeemerAddr != "" {
 				*redeemerAddr = defaultAddr(*redeemerAddr, "127.0.0.1", RpcPort)
@@ -565,17 +1191,42 @@ func main() {
 				}
 				sm = rc
 			} else {
-				sm = pm.NewSenderMonitor(smCfg, n.Eth, senderWatcher, timeWatcher, n.Database)
+				sd.sm = pm.NewSenderMonitor(smCfg, n.Eth, senderWatcher, timeWatcher, n.Database)
+				failed := sd.sm.FailedRedemptions()
+				lpmon.Go(func() { logFailedRedemptions(failed) })
+				sm = sd.sm
 			}
 
 			// Start sender monitor
 			sm.Start()
 			defer sm.Stop()
 
+			var unlocks *pm.UnlockWatcher
+			if *freezeUnlockedSenders {
+				unlocks = pm.NewUnlockWatcher(senderWatcher, senderWatcher)
+				lpmon.Go(func() { unlocks.Watch() })
+				defer unlocks.Stop()
+			}
+
+			n.SenderReputation = pm.NewSenderReputationTracker(pm.SenderReputationConfig{
+				MaxDoubleSpends:      *maxSenderDoubleSpends,
+				MaxInsufficientFunds: *maxSenderInsufficientFunds,
+				MaxInvalidSignatures: *maxSenderInvalidSignatures,
+			})
+
 			cfg := pm.TicketParamsConfig{
-				EV:               ev,
-				RedeemGas:        redeemGas,
-				TxCostMultiplier: txCostMultiplier,
+				EV:                      ev,
+				RedeemGas:               ticketRedeemGas,
+				TxCostMultiplier:        txCostMultiplier,
+				PayoutSplit:             payoutSplit,
+				TicketVersion:           uint32(*ticketVersion),
+				Reputation:              n.SenderReputation,
+				Unlocks:                 unlocks,
+				RecipientRandMaxTickets: *recipientRandMaxTickets,
+				RecipientRandTTL:        *recipientRandTTL,
+				FreeTickets:             *freeTickets,
+				SeedSource:              pm.NewRoundSeedSource(timeWatcher),
+				Tuner:                   tuner,
 			}
 			n.Recipient, err = pm.NewRecipient(
 				recipientAddr,
@@ -615,7 +1266,53 @@ func main() {
 			glog.Info("Broadcaster Deposit: ", eth.FormatUnits(info.Deposit, "ETH"))
 			glog.Info("Broadcaster Reserve: ", eth.FormatUnits(info.Reserve.FundsRemaining, "ETH"))
 
-			n.Sender = pm.NewSender(n.Eth, timeWatcher, senderWatcher, ev, *depositMultiplier)
+			n.Sender = pm.NewSender(n.Eth, timeWatcher, senderWatcher, ev, *depositMultiplier, server.BroadcastCfg)
+			server.BroadcastCfg.SetFreeTickets(*freeTickets)
+
+			if *topUpDepositMin != "" || *topUpReserveMin != "" {
+				cfg := eventservices.TopUpServiceConfig{
+					CheckInterval: *topUpInterval,
+					AlertWebhook:  *topUpWebhook,
+					Webhooks:      server.Webhooks,
+				}
+				for _, f := range []struct {
+					name, value string
+					amount      **big.Int
+				}{
+					{"topUpDepositMin", *topUpDepositMin, &cfg.MinDeposit},
+					{"topUpDepositTarget", *topUpDepositTarget, &cfg.TargetDeposit},
+					{"topUpReserveMin", *topUpReserveMin, &cfg.MinReserve},
+					{"topUpReserveTarget", *topUpReserveTarget, &cfg.TargetReserve},
+					{"topUpDailyCap", *topUpDailyCap, &cfg.DailyCap},
+				} {
+					if *f.amount, err = parseWei(f.name, f.value); err != nil {
+						glog.Error(err)
+						return
+					}
+				}
+				if cfg.MinDeposit != nil && (cfg.TargetDeposit == nil || cfg.TargetDeposit.Cmp(cfg.MinDeposit) < 0) {
+					glog.Errorf("-topUpDepositTarget must be at least -topUpDepositMin, provided %v", *topUpDepositTarget)
+					return
+				}
+				if cfg.MinReserve != nil && (cfg.TargetReserve == nil || cfg.TargetReserve.Cmp(cfg.MinReserve) < 0) {
+					glog.Errorf("-topUpReserveTarget must be at least -topUpReserveMin, provided %v", *topUpReserveTarget)
+					return
+				}
+				if *topUpInterval <= 0 {
+					glog.Errorf("-topUpInterval must be positive, provided %v", *topUpInterval)
+					return
+				}
+
+				ts := eventservices.NewTopUpService(n.Eth, senderWatcher, cfg)
+				ts.Start(ctx)
+				defer ts.Stop()
+			}
+
+			if *paymentSegments < 1 {
+				glog.Errorf("-paymentSegments must be at least 1, provided %v", *paymentSegments)
+				return
+			}
+			server.BroadcastCfg.SetPaymentSegments(*paymentSegments)
 
 			if *pixelsPerUnit <= 0 {
 				// Can't divide by 0
@@ -627,13 +1324,31 @@ func main() {
 				glog.Infof("Maximum transcoding price per pixel is not greater than 0: %v, broadcaster is currently set to accept ANY price.\n", *maxPricePerUnit)
 				glog.Infoln("To update the broadcaster's maximum acceptable transcoding price per pixel, use the CLI or restart the broadcaster with the appropriate 'maxPricePerUnit' and 'pixelsPerUnit' values")
 			}
+			configReloader.add(func() error {
+				if *pixelsPerUnit <= 0 {
+					return fmt.Errorf("-pixelsPerUnit must be > 0, provided %d", *pixelsPerUnit)
+				}
+				var price *big.Rat
+				maxPrice := "0"
+				if *maxPricePerUnit > 0 {
+					price = big.NewRat(int64(*maxPricePerUnit), int64(*pixelsPerUnit))
+					maxPrice = price.RatString()
+				}
+				server.BroadcastCfg.SetMaxPrice(price)
+				n.AuditLog.Append(audit.EventMaxPriceChanged, map[string]string{"maxPricePerPixel": maxPrice})
+				glog.Infof("Maximum transcoding price: %d per %d pixels", *maxPricePerUnit, *pixelsPerUnit)
+				return nil
+			}, "maxPricePerUnit", "pixelsPerUnit")
 		}
 
 		if n.NodeType == core.RedeemerNode {
+			sd.sm = pm.NewSenderMonitor(smCfg, n.Eth, senderWatcher, timeWatcher, n.Database)
+			failed := sd.sm.FailedRedemptions()
+			lpmon.Go(func() { logFailedRedemptions(failed) })
 			r, err := server.NewRedeemer(
 				recipientAddr,
 				n.Eth,
-				pm.NewSenderMonitor(smCfg, n.Eth, senderWatcher, timeWatcher, n.Database),
+				sd.sm,
 			)
 			if err != nil {
 				glog.Errorf("Unable to create redeemer: %v", err)
@@ -648,6 +1363,7 @@ func main() {
 			}
 
 			go func() {
+				defer lpmon.RecoverAndReport()
 				if err := r.Start(url, n.WorkDir); err != nil {
 					redeemerErr <- err
 					return
@@ -672,16 +1388,36 @@ func main() {
 		if *reward {
 			// Start reward service
 			// The node will only call reward if it is active in the current round
-			rs := eventservices.NewRewardService(n.Eth, blockPollingTime)
+			var maxGasPrice *big.Int
+			if *rewardMaxGasPrice != "" {
+				var ok bool
+				if maxGasPrice, ok = new(big.Int).SetString(*rewardMaxGasPrice, 10); !ok || maxGasPrice.Sign() <= 0 {
+					glog.Errorf("-rewardMaxGasPrice must be a positive integer, provided %v", *rewardMaxGasPrice)
+					return
+				}
+			}
+			if *rewardRetryInterval <= 0 {
+				glog.Errorf("-rewardRetryInterval must be positive, provided %v", *rewardRetryInterval)
+				return
+			}
+			rs := eventservices.NewRewardService(n.Eth, timeWatcher, eventservices.RewardServiceConfig{
+				RetryInterval:   *rewardRetryInterval,
+				MaxAttempts:     *rewardMaxAttempts,
+				MaxGasPrice:     maxGasPrice,
+				SuggestGasPrice: gpm.SuggestGasPrice,
+				MissedWebhook:   *rewardMissedWebhook,
+				Webhooks:        server.Webhooks,
+			})
 			rs.Start(ctx)
 			defer rs.Stop()
 		}
 
 		if *initializeRound {
 			// Start round initializer
-			// The node will only initialize rounds if it in the upcoming active set for the round
-			initializer := eth.NewRoundInitializer(n.Eth, n.Database, timeWatcher, blockPollingTime)
-			go initializer.Start()
+			// The node will only initialize rounds if it in the upcoming active set for the round,
+			// unless -initializeRoundFirst is set
+			initializer := eth.NewRoundInitializer(n.Eth, n.Database, timeWatcher, blockPollingTime, *initializeRoundFirst)
+			lpmon.Go(func() { initializer.Start() })
 			defer initializer.Stop()
 		}
 
@@ -690,6 +1426,7 @@ func main() {
 
 		// Backfill events that the node has missed since its last seen block. This method will block
 		// and the node will not continue setup until it finishes
+		notifySystemd(sdnotify.Status("Backfilling missed events"))
 		if err := blockWatcher.BackfillEventsIfNeeded(blockWatchCtx); err != nil {
 			glog.Errorf("Failed to backfill events: %v", err)
 			return
@@ -697,12 +1434,14 @@ func main() {
 
 		blockWatcherErr := make(chan error, 1)
 		go func() {
+			defer lpmon.RecoverAndReport()
 			if err := blockWatcher.Watch(blockWatchCtx); err != nil {
 				blockWatcherErr <- fmt.Errorf("block watcher error: %v", err)
 			}
 		}()
 
 		go func() {
+			defer lpmon.RecoverAndReport()
 			var err error
 			select {
 			case err = <-timeWatcherErr:
@@ -731,6 +1470,14 @@ func main() {
 		br := strings.Split(*s3bucket, "/")
 		cr := strings.Split(*s3creds, "/")
 		drivers.NodeStorage = drivers.NewS3Driver(br[0], br[1], cr[0], cr[1])
+		s3Storage := drivers.NodeStorage
+		configReloader.add(func() error {
+			cr := strings.Split(*s3creds, "/")
+			if len(cr) != 2 {
+				return errors.New("S3 credentials must be in form ACCESSKEYID/ACCESSKEY")
+			}
+			return drivers.UpdateS3Credentials(s3Storage, cr[0], cr[1])
+		}, "s3creds")
 	}
 
 	if *gsBucket != "" && *gsKey != "" {
@@ -742,9 +1489,27 @@ func main() {
 		}
 	}
 
-	core.MaxSessions = *maxSessions
+	core.InlineSegmentMaxSize = *inlineSegmentMaxSize
+
+	core.SetMaxSessions(*maxSessions)
 	if lpmon.Enabled {
-		lpmon.MaxSessions(core.MaxSessions)
+		lpmon.MaxSessions(core.MaxSessions())
+	}
+	if n.NodeType != core.TranscoderNode {
+		// The capacity of a transcoder is registered with its orchestrator
+		configReloader.add(func() error {
+			if *maxSessions <= 0 {
+				return errors.New("-maxSessions must be greater than zero")
+			}
+			if lb, ok := n.Transcoder.(*core.LoadBalancingTranscoder); ok && lb.Capacity() > 0 && lb.Capacity() < *maxSessions {
+				return fmt.Errorf("-maxSessions must not exceed the %v sessions of the Nvidia devices", lb.Capacity())
+			}
+			core.SetMaxSessions(*maxSessions)
+			if lpmon.Enabled {
+				lpmon.MaxSessions(core.MaxSessions())
+			}
+			return nil
+		}, "maxSessions")
 	}
 
 	if n.NodeType == core.BroadcasterNode {
@@ -755,6 +1520,12 @@ func main() {
 
 		bcast := core.NewBroadcaster(n)
 
+		caps, err := core.ParseCapabilities(*requiredCapabilities)
+		if err != nil {
+			lpmon.Fatalf("Invalid -requiredCapabilities: %v", err)
+		}
+		server.BroadcastCfg.SetRequiredCapabilities(caps)
+
 		// When the node is on-chain mode always cache the on-chain orchestrators and poll for updates
 		// Right now we rely on the DBOrchestratorPoolCache constructor to do this. Consider separating the logic
 		// caching/polling from the logic for fetching orchestrators during discovery
@@ -770,85 +1541,293 @@ func main() {
 		}
 
 		// Set up orchestrator discovery
-		if *orchWebhookURL != "" {
-			whurl, err := validateURL(*orchWebhookURL)
-			if err != nil {
-				glog.Fatal("Error setting orch webhook URL ", err)
+		chainPool := n.OrchestratorPool
+		orchestratorPool := func() (common.OrchestratorPool, error) {
+			if *orchWebhookURL != "" {
+				whurl, err := validateURL(*orchWebhookURL)
+				if err != nil {
+					return nil, err
+				}
+				glog.Info("Using orchestrator webhook URL ", whurl)
+				return discovery.NewWebhookPool(bcast, whurl), nil
+			} else if *orchAddr != "" {
+				if orchURLs := parseOrchAddrs(*orchAddr); len(orchURLs) > 0 {
+					return discovery.NewOrchestratorPool(bcast, orchURLs), nil
+				}
 			}
-			glog.Info("Using orchestrator webhook URL ", whurl)
-			n.OrchestratorPool = discovery.NewWebhookPool(bcast, whurl)
-		} else if len(orchURLs) > 0 {
-			n.OrchestratorPool = discovery.NewOrchestratorPool(bcast, orchURLs)
+			return chainPool, nil
+		}
+		pool, err := orchestratorPool()
+		if err != nil {
+			lpmon.Fatal("Error setting orch webhook URL ", err)
 		}
 
-		if n.OrchestratorPool == nil {
+		if pool == nil {
 			// Not a fatal error; may continue operating in segment-only mode
 			glog.Error("No orchestrator specified; transcoding will not happen")
 		}
+		// Orchestrators can be reloaded, in which case new sessions use the new pool
+		reloadablePool := discovery.NewReloadablePool(pool)
+		n.OrchestratorPool = reloadablePool
+		configReloader.add(func() error {
+			pool, err := orchestratorPool()
+			if err != nil {
+				return fmt.Errorf("invalid orchestrator webhook URL: %v", err)
+			}
+			reloadablePool.SetPool(pool)
+			return nil
+		}, "orchAddr", "orchWebhookUrl")
+
 		if *authWebhookURL != "" {
 			_, err := validateURL(*authWebhookURL)
 			if err != nil {
-				glog.Fatal("Error setting auth webhook URL ", err)
+				lpmon.Fatal("Error setting auth webhook URL ", err)
 			}
 			glog.Info("Using auth webhook URL ", *authWebhookURL)
-			server.AuthWebhookURL = *authWebhookURL
+			server.SetAuthWebhookURL(*authWebhookURL)
 		}
+		configReloader.add(func() error {
+			// HTTP ingest may have been enabled on a public -httpAddr because of the webhook,
+			// clearing it would leave the ingest open without authentication
+			if *authWebhookURL == "" {
+				return errors.New("the auth webhook URL can't be removed without a restart")
+			}
+			if _, err := validateURL(*authWebhookURL); err != nil {
+				return fmt.Errorf("invalid auth webhook URL: %v", err)
+			}
+			server.SetAuthWebhookURL(*authWebhookURL)
+			return nil
+		}, "authWebhookUrl")
 
 		isLocalHTTP, err := isLocalURL("https://" + *httpAddr)
 		if err != nil {
 			glog.Errorf("Error checking for local -httpAddr: %v", err)
 			return
 		}
-		if !isFlagSet["httpIngest"] && !isLocalHTTP && server.AuthWebhookURL == "" {
+		if !isFlagSet["httpIngest"] && !isLocalHTTP && server.AuthWebhookURL() == "" {
 			glog.Warning("HTTP ingest is disabled because -httpAddr is publicly accessible. To enable, configure -authWebhookUrl or use the -httpIngest flag")
 			*httpIngest = false
 		}
 
+		if *vodHosts != "" {
+			server.VODHosts = strings.Split(*vodHosts, ",")
+		}
+
 		// Disable local verification when running in off-chain mode
-		// To enable, set -localVerify or -verifierURL
+		// To enable, set -localVerify, -verifyRenditions, -verifyRetranscode or -verifierURL
 		if !isFlagSet["localVerify"] && *network == "offchain" {
 			*localVerify = false
 		}
 
 		if *verifierURL != "" {
-			_, err := validateURL(*verifierURL)
-			if err != nil {
-				glog.Fatal("Error setting verifier URL ", err)
+			verifierURLs := strings.Split(*verifierURL, ",")
+			for _, u := range verifierURLs {
+				if _, err := validateURL(u); err != nil {
+					lpmon.Fatal("Error setting verifier URL ", err)
+				}
+			}
+			if *verifierTimeout <= 0 || *verifierHealthCheckInterval <= 0 || *verifierRetries < 0 {
+				lpmon.Fatal("-verifierTimeout and -verifierHealthCheckInterval must be positive and -verifierRetries must not be negative")
 			}
 			glog.Info("Using the Epic Labs classifier for verification at ", *verifierURL)
-			server.Policy = &verification.Policy{Retries: 2, Verifier: &verification.EpicClassifier{Addr: *verifierURL}}
+			verifier := verification.NewFailoverVerifier(verifierURLs, verification.FailoverOptions{
+				Timeout:             *verifierTimeout,
+				Retries:             *verifierRetries,
+				HealthCheckInterval: *verifierHealthCheckInterval,
+			})
+			go func() {
+				defer lpmon.RecoverAndReport()
+				verifier.StartHealthChecks(ctx)
+			}()
+			server.Policy = &verification.Policy{Retries: 2, Verifier: verifier}
 
 			// Set the verifier path. Remove once [1] is implemented!
 			// [1] https://github.com/livepeer/verification-classifier/issues/64
 			if drivers.NodeStorage == nil && *verifierPath == "" {
-				glog.Fatal("Requires a path to the verifier shared volume when local storage is in use; use -verifierPath, S3 or GCS")
+				lpmon.Fatal("Requires a path to the verifier shared volume when local storage is in use; use -verifierPath, S3 or GCS")
 			}
 			verification.VerifierPath = *verifierPath
+		} else if *verifyRetranscode {
+			if *verifyRetranscodeMaxDistance <= 0 || *verifyRetranscodeMaxDistance > 64 {
+				lpmon.Fatal("-verifyRetranscodeMaxDistance must be between 0 and 64")
+			}
+			transcoder := core.NewLocalTranscoder(n.WorkDir)
+			if *verifyRetranscodeNvidia != "" {
+				transcoder = core.NewLoadBalancingTranscoder(*verifyRetranscodeNvidia, core.NewNvidiaTranscoder)
+			}
+			glog.Info("Re-transcode verification of renditions enabled")
+			server.Policy = &verification.Policy{Retries: 2, Verifier: verification.NewRetranscodeVerifier(transcoder, *verifyRetranscodeMaxDistance)}
+		} else if *verifyRenditions {
+			glog.Info("Local verification of renditions enabled")
+			server.Policy = &verification.Policy{Retries: 2, Verifier: verification.NewLocalVerifier(verification.DefaultDurationTolerance)}
 		} else if *localVerify {
 			glog.Info("Local verification enabled")
 			server.Policy = &verification.Policy{Retries: 2}
 		}
 
+		if *verifySampleKey != "" {
+			key, _ := common.GetPass(*verifySampleKey)
+			verification.SetSampleKey([]byte(key))
+		}
+
+		if server.Policy != nil {
+			if *verifySampleRate <= 0 || *verifySampleRate > 1 || *verifyUntrustedSampleRate <= 0 || *verifyUntrustedSampleRate > 1 {
+				lpmon.Fatal("Verification sample rates must be greater than 0 and at most 1")
+			}
+			server.Policy.SampleRate = *verifySampleRate
+			server.Policy.UntrustedSampleRate = *verifyUntrustedSampleRate
+			server.Policy.TrustThreshold = *verifyTrustThreshold
+			if *verifySampleRate < 1 {
+				glog.Infof("Verifying %v of segments, %v for untrusted orchestrators", *verifySampleRate, *verifyUntrustedSampleRate)
+			}
+
+			if *verifyFailureThreshold < 0 || *verifyPenalty <= 0 {
+				lpmon.Fatal("-verifyFailureThreshold must not be negative and -verifyPenalty must be positive")
+			}
+			if *verifyResultsRetention < 0 {
+				lpmon.Fatal("-verifyResultsRetention must not be negative")
+			}
+			if *verifyStoreResults {
+				server.Policy.Store = n.Database
+				server.Policy.Retention = *verifyResultsRetention
+			}
+			server.VerificationFailureThreshold = *verifyFailureThreshold
+			server.VerificationPenalty = *verifyPenalty
+			if *verifyWebhookURL != "" {
+				if _, err := validateURL(*verifyWebhookURL); err != nil {
+					lpmon.Fatal("Error setting verification webhook URL ", err)
+				}
+				glog.Info("Using verification webhook URL ", *verifyWebhookURL)
+				server.VerificationWebhookURL = *verifyWebhookURL
+			}
+		}
+
+		if *qualityMetric != "" {
+			if *qualitySampleRate <= 0 || *qualitySampleRate > 1 {
+				lpmon.Fatal("-qualitySampleRate must be greater than 0 and at most 1")
+			}
+			scorer, err := verification.NewQualityScorer(verification.QualityMetric(*qualityMetric), *qualitySampleRate, *qualityMinScore)
+			if err != nil {
+				lpmon.Fatalf("Error setting quality metric %v: %v", *qualityMetric, err)
+			}
+			glog.Infof("Scoring %v of segments with %v", *qualitySampleRate, *qualityMetric)
+			server.Quality = scorer
+		}
+
+		if *pixelCheckSampleRate < 0 || *pixelCheckSampleRate > 1 {
+			lpmon.Fatal("-pixelCheckSampleRate must be between 0 and 1")
+		}
+		if *pixelCheckSampleRate > 0 {
+			glog.Infof("Cross-checking the pixel counts of %v of segments", *pixelCheckSampleRate)
+			server.PixelChecker = verification.NewPixelChecker(*pixelCheckSampleRate)
+		}
+
+		if *videoSignatureSampleRate < 0 || *videoSignatureSampleRate > 1 {
+			lpmon.Fatal("-videoSignatureSampleRate must be between 0 and 1")
+		}
+		if *videoSignatureSampleRate > 0 {
+			if !core.VideoSignaturesAvailable() {
+				lpmon.Fatalf("-videoSignatureSampleRate requires the %v binary with the signature filter", core.FFmpegCommand)
+			}
+			glog.Infof("Checking the video signatures of %v of segments", *videoSignatureSampleRate)
+			server.VideoSignatures = verification.NewSignatureChecker(*videoSignatureSampleRate)
+		}
+
+		if *adaptiveBitrate != "" {
+			server.AdaptiveBitrate, err = core.ParseAdaptiveBitrate(*adaptiveBitrate)
+			if err != nil {
+				lpmon.Fatalf("Error parsing -adaptiveBitrate: %v", err)
+			}
+			glog.Infof("Adapting the bitrates of the renditions to the segments within %d%% and %d%% of the profiles", server.AdaptiveBitrate.MinPercent, server.AdaptiveBitrate.MaxPercent)
+		}
+
+		if *alignKeyframes < 0 {
+			lpmon.Fatal("-alignKeyframes must not be negative")
+		}
+		if *alignKeyframes > 0 {
+			if server.SegLen%*alignKeyframes != 0 {
+				glog.Warningf("-alignKeyframes=%v does not divide the segment length of %v, so GOPs are shorter at the end of segments", *alignKeyframes, server.SegLen)
+			}
+			glog.Infof("Aligning the keyframes of the renditions every %v", *alignKeyframes)
+			server.KeyframeAlignment = &core.KeyframeAlignment{GOP: *alignKeyframes}
+		}
+
+		if *storeReceipts {
+			glog.Info("Storing transcode receipts sent by orchestrators")
+			server.ReceiptStore = n.Database
+		}
+
+		if *parallelOrchestrators > 1 {
+			glog.Infof("Transcoding the segments of streams on %d orchestrators in parallel", *parallelOrchestrators)
+		}
+		server.ParallelOrchestrators = *parallelOrchestrators
+
 		// Set max transcode attempts. <=0 is OK; it just means "don't transcode"
 		server.MaxAttempts = *maxAttempts
 
 	} else if n.NodeType == core.OrchestratorNode {
 		suri, err := getServiceURI(n, *serviceAddr)
 		if err != nil {
-			glog.Fatal("Error getting service URI: ", err)
+			lpmon.Fatal("Error getting service URI: ", err)
 		}
 		n.SetServiceURI(suri)
+		var altURIs []*url.URL
+		for _, addr := range strings.Split(*altServiceAddrs, ",") {
+			if addr = strings.TrimSpace(addr); addr == "" {
+				continue
+			}
+			uri, err := url.ParseRequestURI("https://" + addr)
+			if err != nil {
+				lpmon.Fatalf("Invalid -altServiceAddrs address %v err=%v", addr, err)
+			}
+			altURIs = append(altURIs, uri)
+		}
+		n.SetAltServiceURIs(altURIs)
+		server.IPRateLimit = *ipRateLimit
+		server.SenderRateLimit = *senderRateLimit
+		server.MaxDiscoveryRequests = *maxDiscoveryRequests
+		server.MaxSegmentSize = *maxSegmentSize
+		if *relayAddr != "" {
+			server.RelayURI, err = url.ParseRequestURI("https://" + defaultAddr(*relayAddr, "127.0.0.1", RpcPort))
+			if err != nil {
+				lpmon.Fatal("Invalid -relayAddr: ", err)
+			}
+			server.RelaySecret, _ = common.GetPass(*relaySecret)
+			if server.RelaySecret == "" {
+				lpmon.Fatal("Missing -relaySecret")
+			}
+		}
 		// if http addr is not provided, listen to all ifaces
 		// take the port to listen to from the service URI
 		*httpAddr = defaultAddr(*httpAddr, "", n.GetServiceURI().Port())
 
-		n.Capabilities = core.NewCapabilities(defaultCapabilities, mandatoryCapabilities)
+		caps := append(append([]core.Capability{}, defaultCapabilities...), transcoderCaps...)
+		n.Capabilities = core.NewCapabilities(caps, mandatoryCapabilities)
+		n.CapabilityPrices, err = core.ParseCapabilityPrices(*capabilityPrices)
+		if err != nil {
+			lpmon.Fatalf("Invalid -capabilityPrices: %v", err)
+		}
 
 		if !*transcoder && n.OrchSecret == "" {
-			glog.Fatal("Running an orchestrator requires an -orchSecret for standalone mode or -transcoder for orchestrator+transcoder mode")
+			lpmon.Fatal("Running an orchestrator requires an -orchSecret for standalone mode or -transcoder for orchestrator+transcoder mode")
 		}
 	}
 	*cliAddr = defaultAddr(*cliAddr, "127.0.0.1", CliPort)
+	server.CliToken = *cliToken
+	if *cliCert != "" || *cliKey != "" {
+		if *cliCert == "" || *cliKey == "" {
+			lpmon.Fatal("Serving the CLI over HTTPS requires both -cliCert and -cliKey")
+		}
+		server.CliTLS, err = server.NewCliTLSConfig(*cliCert, *cliKey, *cliClientCA)
+		if err != nil {
+			lpmon.Fatal("Error loading CLI TLS config err=", err)
+		}
+	} else if *cliClientCA != "" {
+		lpmon.Fatal("-cliClientCA requires -cliCert and -cliKey")
+	}
+	if server.CliToken == "" && *cliClientCA == "" && !isLoopbackAddr(*cliAddr) {
+		glog.Warningf("The CLI server listens on %v without authentication; set -cliToken or -cliClientCA to protect it", *cliAddr)
+	}
 
 	if drivers.NodeStorage == nil {
 		// base URI will be empty for broadcasters; that's OK
@@ -860,8 +1839,9 @@ func main() {
 	//Set up the media server
 	s, err := server.NewLivepeerServer(*rtmpAddr, n, *httpIngest, *transcodingOptions)
 	if err != nil {
-		glog.Fatal("Error creating Livepeer server err=", err)
+		lpmon.Fatal("Error creating Livepeer server err=", err)
 	}
+	sd.s = s
 
 	ec := make(chan error)
 	tc := make(chan struct{})
@@ -874,17 +1854,121 @@ func main() {
 		s.ExposeCurrentManifest = *currentManifest
 	}
 
+	if *canaryInterval > 0 {
+		if n.NodeType != core.BroadcasterNode {
+			lpmon.Fatal("The self-test canary requires -broadcaster")
+		}
+		if !*httpIngest {
+			lpmon.Fatal("The self-test canary requires HTTP ingest; use -httpIngest")
+		}
+		seg, err := core.TestSegment()
+		segDuration := core.TestSegmentDuration
+		if *canarySegment != "" {
+			seg, err = ioutil.ReadFile(*canarySegment)
+			segDuration = *canarySegmentDuration
+		}
+		if err != nil {
+			lpmon.Fatal("Error reading self-test canary segment err=", err)
+		}
+		s.Canary = server.NewCanary(defaultAddr(*httpAddr, "127.0.0.1", RpcPort), seg, segDuration, *canaryInterval)
+	}
+
+	if *coordinator || *coordinatorURL != "" {
+		if n.NodeType != core.BroadcasterNode {
+			lpmon.Fatal("A fleet of broadcasters requires -broadcaster")
+		}
+		if *coordinatorInterval <= 0 {
+			lpmon.Fatal("-coordinatorInterval must be greater than 0")
+		}
+		*fleetSecret, _ = common.GetPass(*fleetSecret)
+		if *fleetSecret == "" {
+			lpmon.Fatal("Missing -fleetSecret")
+		}
+	}
+	if *coordinator {
+		server.FleetCoordinator, err = server.NewCoordinator(*coordinatorInterval, *fleetSecret)
+		if err != nil {
+			lpmon.Fatal("Error starting the fleet coordinator err=", err)
+		}
+		if server.CliToken == "" {
+			glog.Warning("The fleet coordinator API is served without authentication; set -cliToken to protect it")
+		}
+	}
+	if *coordinatorURL != "" {
+		addr := *fleetAddr
+		if addr == "" {
+			addr = *httpAddr
+			if isLoopbackAddr(addr) || isUnspecifiedAddr(addr) {
+				lpmon.Fatalf("The other broadcasters of the fleet can't reach the HTTP ingest on %v; set -fleetAddr", addr)
+			}
+		}
+		server.Fleet, err = server.NewFleetMember(*coordinatorURL, *coordinatorToken, *fleetSecret, "http://"+defaultAddr(addr, "127.0.0.1", RpcPort), *coordinatorInterval)
+		if err != nil {
+			lpmon.Fatal("Error joining the fleet err=", err)
+		}
+		glog.Infof("Joining the fleet of the coordinator %v as %v", *coordinatorURL, server.Fleet.Node())
+	}
+
+	server.Reload = configReloader.reload
+	server.ConfigSnapshot = func() ([]byte, error) {
+		runtime := runtimeConfig(n)
+		for name, ref := range secretFlags.references() {
+			runtime[name] = ref
+		}
+		return configSnapshot(flag.CommandLine, runtime, time.Now())
+	}
+	configReloader.reloadOnSignal()
+	if *secretsRefresh > 0 {
+		lpmon.Go(func() { secretFlags.refreshEvery(ctx, *secretsRefresh, configReloader.update) })
+	}
+
 	go func() {
+		defer lpmon.RecoverAndReport()
 		s.StartCliWebserver(*cliAddr)
 		close(wc)
 	}()
-	if n.NodeType != core.RedeemerNode {
+	if n.NodeType != core.RedeemerNode && n.NodeType != core.RelayNode {
 		go func() {
+			defer lpmon.RecoverAndReport()
 			ec <- s.StartMediaServer(msCtx, *httpAddr)
 		}()
 	}
+	if n.NodeType == core.RelayNode {
+		secret, _ := common.GetPass(*relaySecret)
+		r, err := server.NewRelay(secret)
+		if err != nil {
+			lpmon.Fatal("Unable to create relay: ", err)
+		}
+		*httpAddr = defaultAddr(*httpAddr, "", RpcPort)
+		uri, err := url.ParseRequestURI("https://" + *httpAddr)
+		if err != nil {
+			lpmon.Fatal("Could not parse relay URI: ", err)
+		}
+		go func() {
+			defer lpmon.RecoverAndReport()
+			relayErr <- r.Start(uri, n.WorkDir)
+		}()
+		defer r.Stop()
+		glog.Infof("Relay started on %v", *httpAddr)
+	}
+	if s.Canary != nil {
+		go func() {
+			defer lpmon.RecoverAndReport()
+			s.Canary.Start(msCtx)
+		}()
+	}
+	if watchdog != nil {
+		lpmon.Go(func() { probeServer(msCtx, watchdog, s) })
+	}
+	if server.Fleet != nil {
+		go func() {
+			defer lpmon.RecoverAndReport()
+			server.Fleet.Run(msCtx)
+		}()
+	}
 
 	go func() {
+		defer lpmon.RecoverAndReport()
 		if core.OrchestratorNode != n.NodeType {
 			return
 		}
@@ -892,6 +1976,7 @@ func main() {
 		orch := core.NewOrchestrator(s.LivepeerNode, timeWatcher)
 
 		go func() {
+			defer lpmon.RecoverAndReport()
 			server.StartTranscodeServer(orch, *httpAddr, s.HTTPMux, n.WorkDir, n.TranscoderManager != nil)
 			tc <- struct{}{}
 		}()
@@ -903,7 +1988,9 @@ func main() {
 			// shut down orchestrator
 			glog.Infof("Orchestrator not available at %v; shutting down", orch.ServiceURI())
 			tc <- struct{}{}
+			return
 		}
+		notifySystemd(sdnotify.StateReady, sdnotify.Status("Running in orchestrator mode"))
 
 	}()
 
@@ -913,14 +2000,18 @@ func main() {
 	case core.BroadcasterNode:
 		glog.Infof("***Livepeer Running in Broadcaster Mode***")
 		glog.Infof("Video Ingest Endpoint - rtmp://%v", *rtmpAddr)
+		notifySystemd(sdnotify.StateReady, sdnotify.Status("Running in broadcaster mode"))
 	case core.TranscoderNode:
 		glog.Infof("**Liveepeer Running in Transcoder Mode***")
 	case core.RedeemerNode:
 		glog.Infof("**Livepeer Running in Redeemer Mode**")
+		notifySystemd(sdnotify.StateReady, sdnotify.Status("Running in redeemer mode"))
+	case core.RelayNode:
+		glog.Infof("**Livepeer Running in Relay Mode**")
+		notifySystemd(sdnotify.StateReady, sdnotify.Status("Running in relay mode"))
 	}
 
-	c := make(chan os.Signal)
-	signal.Notify(c, os.Interrupt)
+	c := shutdownSignals()
 	select {
 	case err := <-watcherErr:
 		glog.Error(err)
@@ -930,8 +2021,11 @@ func main() {
 		return
 	case err := <-redeemerErr:
 		if err != nil {
-			glog.Fatalf("Error starting redemption service: %v", err)
+			lpmon.Fatalf("Error starting redemption service: %v", err)
 		}
+	case err := <-relayErr:
+		glog.Errorf("Relay shut down: %v", err)
+		return
 	case <-msCtx.Done():
 		glog.Infof("MediaServer Done()")
 		return
@@ -942,11 +2036,40 @@ func main() {
 		return
 	case sig := <-c:
 		glog.Infof("Exiting Livepeer: %v", sig)
-		time.Sleep(time.Millisecond * 500) //Give time for other processes to shut down completely
+		sd.run(c)
 		return
 	}
 }
 
+// nodeTypeName returns the short name of a node type used in metrics and crash reports
+func nodeTypeName(nodeType core.NodeType) string {
+	switch nodeType {
+	case core.BroadcasterNode:
+		return "bctr"
+	case core.OrchestratorNode:
+		return "orch"
+	case core.TranscoderNode:
+		return "trcr"
+	case core.RedeemerNode:
+		return "rdmr"
+	case core.RelayNode:
+		return "rlay"
+	}
+	return "dflt"
+}
+
+// teeStderr copies everything written to stderr, which includes glog output, into w
+func teeStderr(w io.Writer) error {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	stderr := os.Stderr
+	os.Stderr = pw
+	go io.Copy(io.MultiWriter(stderr, w), pr)
+	return nil
+}
+
 func validateURL(u string) (*url.URL, error) {
 	if u == "" {
 		return nil, nil
@@ -1050,6 +2173,72 @@ func setupOrchestrator(ctx context.Context, n *core.LivepeerNode, ethOrchAddr et
 	return nil
 }
 
+// parseOrchAddrs parses a comma separated list of orchestrator addresses
+func parseOrchAddrs(orchAddr string) []*url.URL {
+	var orchURLs []*url.URL
+	for _, addr := range strings.Split(orchAddr, ",") {
+		addr = strings.TrimSpace(addr)
+		addr = defaultAddr(addr, "127.0.0.1", RpcPort)
+		if !strings.HasPrefix(addr, "http") {
+			addr = "https://" + addr
+		}
+		uri, err := url.ParseRequestURI(addr)
+		if err != nil {
+			glog.Error("Could not parse orchestrator URI: ", err)
+			continue
+		}
+		orchURLs = append(orchURLs, uri)
+	}
+	return orchURLs
+}
+
+func verifyAuditLogFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := audit.Verify(f)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Audit log %v is valid with %d entries\n", path, n)
+	return nil
+}
+
+// isLoopbackAddr returns whether a host:port address can only be reached from the local host
+// logFailedRedemptions logs the winning tickets that are given up on after failing the max
+// redemption attempts, which are lost unless they are redeemed by hand
+func logFailedRedemptions(failed <-chan *pm.FailedRedemption) {
+	for f := range failed {
+		glog.Errorf("Gave up on redeeming winning ticket sender=%v faceValue=%v senderNonce=%v recipientRandHash=%x attempts=%v err=%v",
+			f.Ticket.Sender.Hex(), f.Ticket.FaceValue, f.Ticket.SenderNonce, f.Ticket.RecipientRandHash, f.Attempts, f.Err)
+	}
+}
+
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// isUnspecifiedAddr returns whether addr listens on all interfaces
+func isUnspecifiedAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return host == "" || (ip != nil && ip.IsUnspecified())
+}
+
 func defaultAddr(addr, defaultHost, defaultPort string) string {
 	if addr == "" {
 		return defaultHost + ":" + defaultPort
@@ -1064,6 +2253,18 @@ func defaultAddr(addr, defaultHost, defaultPort string) string {
 	return addr
 }
 
+// parseWei parses the amount in Wei of the flag name, or returns nil if it is not set
+func parseWei(name, value string) (*big.Int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	amount, ok := new(big.Int).SetString(value, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("-%v must be a non-negative integer, provided %v", name, value)
+	}
+	return amount, nil
+}
+
 func checkOrStoreChainID(dbh *common.DB, chainID *big.Int) error {
 	expectedChainID, err := dbh.ChainID()
 	if err != nil {
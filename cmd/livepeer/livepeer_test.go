@@ -85,3 +85,22 @@ func TestIsLocalURL(t *testing.T) {
 	assert.Nil(err)
 	assert.False(isLocal)
 }
+
+func TestParseWei(t *testing.T) {
+	assert := assert.New(t)
+
+	// Test unset flags
+	amount, err := parseWei("topUpDailyCap", "")
+	assert.Nil(err)
+	assert.Nil(amount)
+
+	amount, err = parseWei("topUpDailyCap", "1000000000000000000")
+	assert.Nil(err)
+	assert.Equal(big.NewInt(1000000000000000000), amount)
+
+	// Test invalid amounts
+	_, err = parseWei("topUpDailyCap", "1.5")
+	assert.EqualError(err, "-topUpDailyCap must be a non-negative integer, provided 1.5")
+	_, err = parseWei("topUpDailyCap", "-1")
+	assert.EqualError(err, "-topUpDailyCap must be a non-negative integer, provided -1")
+}
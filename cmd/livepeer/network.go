@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/livepeer/go-livepeer/eth"
+)
+
+// customNetwork is the name of the network whose chain ID and protocol contracts are read from
+// the -networkConfig file, e.g. for private deployments
+const customNetwork = "custom"
+
+// customNetworkConfig is the JSON config of a custom network
+type customNetworkConfig struct {
+	ChainID    int64  `json:"chainId"`
+	Controller string `json:"controller"`
+	// Contracts are addresses of protocol contracts, keyed by contract name, which override
+	// the addresses registered in the Controller
+	Contracts map[string]string `json:"contracts"`
+	RedeemGas int               `json:"redeemGas"`
+	L2        bool              `json:"l2"`
+}
+
+// readNetworkConfig reads the custom network config of fname, and returns it with the
+// addresses of the contracts that it overrides
+func readNetworkConfig(fname string) (*customNetworkConfig, map[string]ethcommon.Address, error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var cfg customNetworkConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, nil, fmt.Errorf("invalid network config %v: %v", fname, err)
+	}
+
+	if cfg.ChainID <= 0 {
+		return nil, nil, fmt.Errorf("the chainId of network config %v must be positive", fname)
+	}
+	if !validAddress(cfg.Controller) {
+		return nil, nil, fmt.Errorf("the controller of network config %v must be an address, got %q", fname, cfg.Controller)
+	}
+	if cfg.RedeemGas < 0 {
+		return nil, nil, fmt.Errorf("the redeemGas of network config %v must not be negative", fname)
+	}
+
+	contracts := make(map[string]ethcommon.Address)
+	for name, addr := range cfg.Contracts {
+		if !validAddress(addr) {
+			return nil, nil, fmt.Errorf("the %v contract of network config %v must be an address, got %q", name, fname, addr)
+		}
+		contracts[name] = ethcommon.HexToAddress(addr)
+	}
+	if err := eth.ValidContractAddrs(contracts); err != nil {
+		return nil, nil, fmt.Errorf("invalid network config %v: %v", fname, err)
+	}
+
+	return &cfg, contracts, nil
+}
+
+func validAddress(addr string) bool {
+	return ethcommon.IsHexAddress(addr) && ethcommon.HexToAddress(addr) != ethcommon.Address{}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadNetworkConfig(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "TestReadNetworkConfig")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	fname := writeConfigFile(t, dir, "network.json", `{
+		"chainId": 1337,
+		"controller": "0x1111111111111111111111111111111111111111",
+		"contracts": {"TicketBroker": "0x2222222222222222222222222222222222222222"},
+		"redeemGas": 300000,
+		"l2": true
+	}`)
+	cfg, contracts, err := readNetworkConfig(fname)
+	require.Nil(err)
+	assert.Equal(int64(1337), cfg.ChainID)
+	assert.Equal("0x1111111111111111111111111111111111111111", cfg.Controller)
+	assert.Equal(300000, cfg.RedeemGas)
+	assert.True(cfg.L2)
+	assert.Equal(map[string]ethcommon.Address{"TicketBroker": ethcommon.HexToAddress("0x2222222222222222222222222222222222222222")}, contracts)
+
+	// Test the contract overrides are optional
+	fname = writeConfigFile(t, dir, "network.json", `{"chainId": 1337, "controller": "0x1111111111111111111111111111111111111111"}`)
+	_, contracts, err = readNetworkConfig(fname)
+	require.Nil(err)
+	assert.Empty(contracts)
+
+	invalid := map[string]string{
+		`{"chainId": 1337`: "invalid network config",
+		`{"controller": "0x1111111111111111111111111111111111111111"}`:                                                                                      "the chainId of network config",
+		`{"chainId": 1337, "controller": "0x0000000000000000000000000000000000000000"}`:                                                                     "the controller of network config",
+		`{"chainId": 1337, "controller": "foo"}`:                                                                                                            "the controller of network config",
+		`{"chainId": 1337, "controller": "0x1111111111111111111111111111111111111111", "redeemGas": -1}`:                                                    "the redeemGas of network config",
+		`{"chainId": 1337, "controller": "0x1111111111111111111111111111111111111111", "contracts": {"Minter": ""}}`:                                        "the Minter contract of network config",
+		`{"chainId": 1337, "controller": "0x1111111111111111111111111111111111111111", "contracts": {"Foo": "0x1111111111111111111111111111111111111111"}}`: "unknown contract Foo",
+	}
+	for content, expected := range invalid {
+		fname = writeConfigFile(t, dir, "network.json", content)
+		_, _, err = readNetworkConfig(fname)
+		if assert.NotNil(err, content) {
+			assert.Contains(err.Error(), expected)
+		}
+	}
+
+	_, _, err = readNetworkConfig(dir + "/missing.json")
+	assert.NotNil(err)
+}
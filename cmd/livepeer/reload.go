@@ -0,0 +1,176 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/golang/glog"
+)
+
+// reloadGroup is a group of flags that can be changed without restarting the node, along
+// with the function that applies their values once any of them changed
+type reloadGroup struct {
+	flags []string
+	apply func() error
+}
+
+// reloader reloads the flags of the node that can be changed without a restart from the
+// environment and the config file. Flags set on the command line are never reloaded
+type reloader struct {
+	mu       sync.Mutex
+	fs       *flag.FlagSet
+	config   string
+	environ  func() []string
+	cliFlags map[string]bool
+	// Values of the flags in the environment or the config file when they were last
+	// loaded, or their defaults
+	loaded map[string]string
+	groups []reloadGroup
+	// resolve returns the value a flag is set to from its value in the environment or the
+	// config file, e.g. the secret it references
+	resolve func(name, value string) (string, error)
+}
+
+// newReloader creates a reloader for the flags of fs, which must already be loaded
+func newReloader(fs *flag.FlagSet, config string, cliFlags map[string]bool, environ func() []string) *reloader {
+	r := &reloader{
+		fs:       fs,
+		config:   config,
+		environ:  environ,
+		cliFlags: cliFlags,
+		loaded:   make(map[string]string),
+	}
+	values, _ := configValues(fs, config, environ())
+	fs.VisitAll(func(f *flag.Flag) { r.loaded[f.Name] = configTarget(values, f) })
+	return r
+}
+
+// configTarget returns the value of a flag in the environment or the config file, or its default
+func configTarget(values map[string]configEntry, f *flag.Flag) string {
+	if v, ok := values[f.Name]; ok {
+		return v.value
+	}
+	return f.DefValue
+}
+
+// add registers a group of reloadable flags
+func (r *reloader) add(apply func() error, flags ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.groups = append(r.groups, reloadGroup{flags: flags, apply: apply})
+}
+
+// reload sets the reloadable flags to their values in the environment or the config file,
+// or to their defaults if they were removed, and applies the groups of flags that changed.
+// Returns the names of the flags that changed
+func (r *reloader) reload() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	values, err := configValues(r.fs, r.config, r.environ())
+	if err != nil {
+		return nil, err
+	}
+	target := func(f *flag.Flag) string { return configTarget(values, f) }
+
+	reloadable := make(map[string]bool)
+	for _, g := range r.groups {
+		for _, name := range g.flags {
+			reloadable[name] = true
+		}
+	}
+	r.fs.VisitAll(func(f *flag.Flag) {
+		if !reloadable[f.Name] && !r.cliFlags[f.Name] && f.Name != "config" && target(f) != r.loaded[f.Name] {
+			glog.Warningf("Ignoring change of -%v, which requires a restart", f.Name)
+		}
+	})
+
+	var changed []string
+	for _, g := range r.groups {
+		prev := make(map[string]string)
+		restore := func() {
+			for name, v := range prev {
+				r.fs.Set(name, v)
+			}
+		}
+		for _, name := range g.flags {
+			f := r.fs.Lookup(name)
+			if f == nil || r.cliFlags[name] || target(f) == r.loaded[name] {
+				continue
+			}
+			v := target(f)
+			if r.resolve != nil {
+				var err error
+				if v, err = r.resolve(name, v); err != nil {
+					restore()
+					return changed, err
+				}
+			}
+			prev[name] = f.Value.String()
+			if err := r.fs.Set(name, v); err != nil {
+				restore()
+				return changed, fmt.Errorf("invalid value %q for %v: %v", target(f), name, err)
+			}
+		}
+		if len(prev) == 0 {
+			continue
+		}
+		if err := g.apply(); err != nil {
+			restore()
+			return changed, err
+		}
+		for name := range prev {
+			r.loaded[name] = target(r.fs.Lookup(name))
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// update sets a flag to a value that changed outside of the environment and the config
+// file, e.g. a secret that was rotated, and applies its group. Returns false if the flag
+// can't be changed without a restart
+func (r *reloader) update(name, value string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, g := range r.groups {
+		for _, n := range g.flags {
+			if n != name {
+				continue
+			}
+			prev := r.fs.Lookup(name).Value.String()
+			if err := r.fs.Set(name, value); err != nil {
+				// The error would quote the value, which may be a secret
+				return true, fmt.Errorf("invalid value for %v", name)
+			}
+			if err := g.apply(); err != nil {
+				r.fs.Set(name, prev)
+				return true, err
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// reloadOnSignal reloads the flags whenever the node receives SIGHUP
+func (r *reloader) reloadOnSignal() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	go func() {
+		for range c {
+			changed, err := r.reload()
+			if err != nil {
+				glog.Errorf("Error reloading settings, applied changes=%v err=%v", changed, err)
+				continue
+			}
+			glog.Infof("Reloaded settings, changed=%v", changed)
+		}
+	}()
+}
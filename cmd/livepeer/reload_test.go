@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloader(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "TestReloader")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	fs := flag.NewFlagSet("livepeer", flag.ContinueOnError)
+	maxSessions := fs.Int("maxSessions", 10, "")
+	maxPrice := fs.Int("maxPricePerUnit", 0, "")
+	pixels := fs.Int("pixelsPerUnit", 1, "")
+	orchAddr := fs.String("orchAddr", "", "")
+	network := fs.String("network", "offchain", "")
+	fs.String("v", "", "")
+
+	fname := writeConfigFile(t, dir, "livepeer.yaml", "maxSessions: 20\nnetwork: rinkeby\n")
+	env := []string{}
+	environ := func() []string { return env }
+	require.Nil(fs.Parse([]string{"-v", "6"}))
+	cliFlags := map[string]bool{"v": true}
+	require.Nil(applyConfig(fs, fname, environ()))
+	assert.Equal(20, *maxSessions)
+
+	r := newReloader(fs, fname, cliFlags, environ)
+	applied := make(map[string]int)
+	var applyErr error
+	add := func(name string, flags ...string) {
+		r.add(func() error {
+			if applyErr != nil {
+				return applyErr
+			}
+			applied[name]++
+			return nil
+		}, flags...)
+	}
+	add("sessions", "maxSessions")
+	add("price", "maxPricePerUnit", "pixelsPerUnit")
+	add("orchs", "orchAddr")
+	add("v", "v")
+
+	// Nothing changed
+	changed, err := r.reload()
+	assert.Nil(err)
+	assert.Empty(changed)
+	assert.Empty(applied)
+
+	// Groups are applied once if any of their flags changed
+	writeConfigFile(t, dir, "livepeer.yaml", "maxSessions: 20\nmaxPricePerUnit: 100\npixelsPerUnit: 2\nv: 4\nnetwork: mainnet\n")
+	changed, err = r.reload()
+	assert.Nil(err)
+	assert.Equal([]string{"maxPricePerUnit", "pixelsPerUnit"}, changed)
+	assert.Equal(map[string]int{"price": 1}, applied)
+	assert.Equal(100, *maxPrice)
+	assert.Equal(2, *pixels)
+	// Flags that require a restart and flags set on the command line are not changed
+	assert.Equal("rinkeby", *network)
+	assert.Equal("6", fs.Lookup("v").Value.String())
+
+	// Environment variables take precedence and removed flags are reset to their defaults
+	env = []string{"LP_ORCHADDR=127.0.0.1:8935"}
+	writeConfigFile(t, dir, "livepeer.yaml", "maxPricePerUnit: 100\npixelsPerUnit: 2\norchAddr: 127.0.0.1:8936\n")
+	changed, err = r.reload()
+	assert.Nil(err)
+	assert.Equal([]string{"maxSessions", "orchAddr"}, changed)
+	assert.Equal(10, *maxSessions)
+	assert.Equal("127.0.0.1:8935", *orchAddr)
+	assert.Equal(map[string]int{"price": 1, "sessions": 1, "orchs": 1}, applied)
+
+	// Invalid values are rejected
+	writeConfigFile(t, dir, "livepeer.yaml", "maxPricePerUnit: 100\npixelsPerUnit: many\n")
+	_, err = r.reload()
+	assert.Contains(err.Error(), `invalid value "many" for pixelsPerUnit`)
+	assert.Equal(2, *pixels)
+
+	writeConfigFile(t, dir, "livepeer.yaml", "maxSesions: 1\n")
+	_, err = r.reload()
+	assert.Contains(err.Error(), "unknown flag maxSesions")
+
+	// Flags are restored if they can't be applied
+	applyErr = errors.New("apply error")
+	writeConfigFile(t, dir, "livepeer.yaml", "maxPricePerUnit: 200\npixelsPerUnit: 2\n")
+	_, err = r.reload()
+	assert.Equal(applyErr, err)
+	assert.Equal(100, *maxPrice)
+
+	// and applied on the next reload
+	applyErr = nil
+	changed, err = r.reload()
+	assert.Nil(err)
+	assert.Equal([]string{"maxPricePerUnit"}, changed)
+	assert.Equal(200, *maxPrice)
+	assert.Equal(2, applied["price"])
+}
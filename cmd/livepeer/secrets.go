@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/secrets"
+)
+
+// secretFlags keeps track of the flags whose values are references to secrets in Vault or
+// AWS Secrets Manager, e.g. -ethPassword=vault://secret/data/livepeer#ethPassword, which
+// are set to the secrets themselves
+type secretFlags struct {
+	mu       sync.Mutex
+	fs       *flag.FlagSet
+	resolver *secrets.Resolver
+	// References of the flags, and the values of the secrets when they were last read
+	refs   map[string]string
+	values map[string]string
+}
+
+// resolveSecretFlags sets the flags of fs that reference secrets to the secrets
+func resolveSecretFlags(fs *flag.FlagSet, resolver *secrets.Resolver) (*secretFlags, error) {
+	s := &secretFlags{
+		fs:       fs,
+		resolver: resolver,
+		refs:     make(map[string]string),
+		values:   make(map[string]string),
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		if secrets.IsReference(f.Value.String()) {
+			s.refs[f.Name] = f.Value.String()
+		}
+	})
+	if len(s.refs) == 0 {
+		return s, nil
+	}
+	values, err := resolver.Resolve(s.refs)
+	if err != nil {
+		return nil, err
+	}
+	for name, v := range values {
+		if err := fs.Set(name, v); err != nil {
+			// The error would quote the secret
+			return nil, fmt.Errorf("invalid value of the secret of %v", name)
+		}
+		s.values[name] = v
+	}
+	return s, nil
+}
+
+// resolve returns the value a flag is set to when it is reloaded: the secret if value is a
+// reference, or value itself
+func (s *secretFlags) resolve(name, value string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !secrets.IsReference(value) {
+		delete(s.refs, name)
+		delete(s.values, name)
+		return value, nil
+	}
+	values, err := s.resolver.Resolve(map[string]string{name: value})
+	if err != nil {
+		return "", err
+	}
+	s.refs[name], s.values[name] = value, values[name]
+	return values[name], nil
+}
+
+// references returns the references of the flags, which config snapshots keep instead of
+// the secrets
+func (s *secretFlags) references() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	refs := make(map[string]string)
+	for name, ref := range s.refs {
+		refs[name] = ref
+	}
+	return refs
+}
+
+// refresh reads the secrets again and sets the flags whose secrets changed with update,
+// which returns false if the flag requires a restart. Returns the names of the flags that
+// changed
+func (s *secretFlags) refresh(update func(name, value string) (bool, error)) ([]string, error) {
+	refs := s.references()
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	values, err := s.resolver.Resolve(refs)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for name, v := range values {
+		s.mu.Lock()
+		prev, ok := s.values[name]
+		s.mu.Unlock()
+		if !ok || prev == v {
+			continue
+		}
+		applied, err := update(name, v)
+		if err != nil {
+			return changed, err
+		}
+		s.mu.Lock()
+		s.values[name] = v
+		s.mu.Unlock()
+		if !applied {
+			glog.Warningf("Ignoring change of the secret of -%v, which requires a restart", name)
+			continue
+		}
+		changed = append(changed, name)
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// refreshEvery refreshes the secrets at every interval until ctx is done
+func (s *secretFlags) refreshEvery(ctx context.Context, interval time.Duration, update func(name, value string) (bool, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		changed, err := s.refresh(update)
+		if err != nil {
+			glog.Errorf("Error refreshing secrets, applied changes=%v err=%v", changed, err)
+			continue
+		}
+		if len(changed) > 0 {
+			glog.Infof("Refreshed secrets, changed=%v", changed)
+		}
+	}
+}
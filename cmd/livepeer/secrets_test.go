@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/livepeer/go-livepeer/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretFlags(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "TestSecretFlags")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	secret := map[string]string{"ethPassword": "pass", "s3creds": "KEY/SECRET", "auth": "https://auth.example.com/?token=a"}
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": secret, "metadata": nil}})
+	}))
+	defer vault.Close()
+	env := map[string]string{"VAULT_ADDR": vault.URL, "VAULT_TOKEN": "token"}
+	resolver := secrets.NewResolver(func(k string) string { return env[k] })
+
+	fs := flag.NewFlagSet("livepeer", flag.ContinueOnError)
+	ethPassword := fs.String("ethPassword", "", "")
+	s3creds := fs.String("s3creds", "", "")
+	authWebhookURL := fs.String("authWebhookUrl", "", "")
+	orchAddr := fs.String("orchAddr", "", "")
+	fname := writeConfigFile(t, dir, "livepeer.yaml", "s3creds: vault://secret/data/livepeer#s3creds\nauthWebhookUrl: vault://secret/data/livepeer#auth\norchAddr: 127.0.0.1:8935\n")
+	require.Nil(fs.Parse([]string{"-ethPassword", "vault://secret/data/livepeer#ethPassword"}))
+	require.Nil(applyConfig(fs, fname, nil))
+
+	s, err := resolveSecretFlags(fs, resolver)
+	require.Nil(err)
+	assert.Equal("pass", *ethPassword)
+	assert.Equal("KEY/SECRET", *s3creds)
+	assert.Equal("https://auth.example.com/?token=a", *authWebhookURL)
+	assert.Equal("127.0.0.1:8935", *orchAddr)
+	assert.Equal(map[string]string{
+		"ethPassword":    "vault://secret/data/livepeer#ethPassword",
+		"s3creds":        "vault://secret/data/livepeer#s3creds",
+		"authWebhookUrl": "vault://secret/data/livepeer#auth",
+	}, s.references())
+
+	r := newReloader(fs, fname, map[string]bool{"ethPassword": true}, func() []string { return nil })
+	r.resolve = s.resolve
+	applied := make(map[string]string)
+	r.add(func() error { applied["s3creds"] = *s3creds; return nil }, "s3creds")
+	r.add(func() error { applied["authWebhookUrl"] = *authWebhookURL; return nil }, "authWebhookUrl")
+
+	// Nothing changed
+	changed, err := s.refresh(r.update)
+	assert.Nil(err)
+	assert.Empty(changed)
+
+	// Rotated secrets are applied, unless they require a restart
+	secret = map[string]string{"ethPassword": "rotated", "s3creds": "KEY/ROTATED", "auth": "https://auth.example.com/?token=a"}
+	changed, err = s.refresh(r.update)
+	assert.Nil(err)
+	assert.Equal([]string{"s3creds"}, changed)
+	assert.Equal(map[string]string{"s3creds": "KEY/ROTATED"}, applied)
+	assert.Equal("pass", *ethPassword)
+
+	// Reloaded flags referencing secrets are set to the secrets
+	writeConfigFile(t, dir, "livepeer.yaml", "s3creds: vault://secret/data/livepeer#s3creds\nauthWebhookUrl: vault://secret/data/other#auth\n")
+	secret["auth"] = "https://auth.example.com/?token=b"
+	changed, err = r.reload()
+	assert.Nil(err)
+	assert.Equal([]string{"authWebhookUrl"}, changed)
+	assert.Equal("https://auth.example.com/?token=b", applied["authWebhookUrl"])
+	assert.Equal("vault://secret/data/other#auth", s.references()["authWebhookUrl"])
+
+	// and to plain values otherwise
+	writeConfigFile(t, dir, "livepeer.yaml", "s3creds: vault://secret/data/livepeer#s3creds\nauthWebhookUrl: https://auth.example.com\n")
+	_, err = r.reload()
+	assert.Nil(err)
+	assert.Equal("https://auth.example.com", *authWebhookURL)
+	assert.NotContains(s.references(), "authWebhookUrl")
+
+	// Secrets that can't be read are not applied
+	env["VAULT_TOKEN"] = ""
+	secret["s3creds"] = "KEY/AGAIN"
+	_, err = s.refresh(r.update)
+	assert.NotNil(err)
+	assert.Equal("KEY/ROTATED", *s3creds)
+	writeConfigFile(t, dir, "livepeer.yaml", "s3creds: vault://secret/data/livepeer#s3creds\nauthWebhookUrl: vault://secret/data/livepeer#auth\n")
+	_, err = r.reload()
+	assert.NotNil(err)
+	assert.Equal("https://auth.example.com", *authWebhookURL)
+}
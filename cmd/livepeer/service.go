@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"os"
+)
+
+// serviceStops receives the requests of the Windows service manager to stop the node,
+// which shut down the node like a signal does
+var serviceStops = make(chan os.Signal, 1)
+
+// eventLogger is the event log of a Windows service
+type eventLogger interface {
+	Info(eid uint32, msg string) error
+	Warning(eid uint32, msg string) error
+	Error(eid uint32, msg string) error
+}
+
+// eventID is the ID of the events logged by the node
+const eventID = 1
+
+// eventLogWriter writes every line of the logs of the node to an event log, with the
+// severity of the glog header of the line
+type eventLogWriter struct {
+	log eventLogger
+	// buf is the incomplete line written last
+	buf []byte
+}
+
+func (w *eventLogWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(w.buf[:i])
+		w.buf = w.buf[i+1:]
+		if line == "" {
+			continue
+		}
+		// The event log is the only output of a service, so errors can't be reported
+		switch line[0] {
+		case 'E', 'F':
+			w.log.Error(eventID, line)
+		case 'W':
+			w.log.Warning(eventID, line)
+		default:
+			w.log.Info(eventID, line)
+		}
+	}
+	return len(p), nil
+}
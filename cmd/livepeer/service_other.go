@@ -0,0 +1,14 @@
+// +build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// serviceCommand runs the service subcommand, which is only supported on Windows
+func serviceCommand(args []string, run func()) {
+	fmt.Fprintln(os.Stderr, "livepeer service is only supported on Windows, see doc/config.md to run the node under systemd")
+	os.Exit(1)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubEventLog struct {
+	events []string
+}
+
+func (l *stubEventLog) report(severity string, eid uint32, msg string) error {
+	l.events = append(l.events, fmt.Sprintf("%v %v %v", severity, eid, msg))
+	return nil
+}
+
+func (l *stubEventLog) Info(eid uint32, msg string) error    { return l.report("info", eid, msg) }
+func (l *stubEventLog) Warning(eid uint32, msg string) error { return l.report("warning", eid, msg) }
+func (l *stubEventLog) Error(eid uint32, msg string) error   { return l.report("error", eid, msg) }
+
+func TestEventLogWriter(t *testing.T) {
+	assert := assert.New(t)
+	elog := &stubEventLog{}
+	w := &eventLogWriter{log: elog}
+
+	logs := []byte("I1014 10:00:00.000000 1 livepeer.go:1] Started\n\nW1014 10:00:00.000000 1 livepeer.go:2] Slow")
+	n, err := w.Write(logs)
+	assert.Nil(err)
+	assert.Equal(len(logs), n)
+	// Incomplete lines are logged once complete
+	assert.Equal([]string{"info 1 I1014 10:00:00.000000 1 livepeer.go:1] Started"}, elog.events)
+
+	w.Write([]byte("\nE1014 10:00:00.000000 1 livepeer.go:3] Failed\nF1014 10:00:00.000000 1 livepeer.go:4] Fatal\n"))
+	assert.Equal([]string{
+		"info 1 I1014 10:00:00.000000 1 livepeer.go:1] Started",
+		"warning 1 W1014 10:00:00.000000 1 livepeer.go:2] Slow",
+		"error 1 E1014 10:00:00.000000 1 livepeer.go:3] Failed",
+		"error 1 F1014 10:00:00.000000 1 livepeer.go:4] Fatal",
+	}, elog.events)
+	assert.Empty(w.buf)
+}
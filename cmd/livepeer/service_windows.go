@@ -0,0 +1,176 @@
+// +build windows
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const serviceUsage = "Usage: livepeer service install <name> [flags] | uninstall <name> | run <name> [flags]"
+
+// serviceCommand runs the service subcommand, which installs, uninstalls or runs the node
+// as a Windows service. The service manager starts an installed service with
+// `service run <name>` followed by the flags it was installed with
+func serviceCommand(args []string, run func()) {
+	if len(args) < 2 {
+		exit(serviceUsage)
+	}
+	name, flags := args[1], args[2:]
+	var err error
+	switch args[0] {
+	case "install":
+		err = installService(name, flags)
+	case "uninstall":
+		err = uninstallService(name)
+	case "run":
+		err = runService(name, flags, run)
+	default:
+		exit(serviceUsage)
+	}
+	if err != nil {
+		exit(fmt.Sprintf("Error running service %v command for service %v: %v", args[0], name, err))
+	}
+}
+
+// exit prints msg and exits, the flags are not parsed yet so the logs can't be used
+func exit(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+	os.Exit(1)
+}
+
+func installService(name string, flags []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	if s, err := m.OpenService(name); err == nil {
+		s.Close()
+		return fmt.Errorf("service %v already exists", name)
+	}
+
+	config := mgr.Config{
+		DisplayName: name,
+		Description: "Livepeer node",
+		StartType:   mgr.StartAutomatic,
+	}
+	s, err := m.CreateService(name, exe, config, append([]string{"service", "run", name}, flags...)...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	// Restart the node if it crashes, unless it crashes repeatedly within a day
+	restart := mgr.RecoveryAction{Type: mgr.ServiceRestart, Delay: 10 * time.Second}
+	if err := s.SetRecoveryActions([]mgr.RecoveryAction{restart, restart, restart}, 24*60*60); err != nil {
+		s.Delete()
+		return err
+	}
+	if err := eventlog.InstallAsEventCreate(name, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		s.Delete()
+		return err
+	}
+	fmt.Printf("Installed service %v, start it with `sc start %v`\n", name, name)
+	return nil
+}
+
+func uninstallService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %v is not installed", name)
+	}
+	defer s.Close()
+	// A running service is removed once it stops
+	if err := s.Delete(); err != nil {
+		return err
+	}
+	if err := eventlog.Remove(name); err != nil {
+		return err
+	}
+	fmt.Printf("Uninstalled service %v\n", name)
+	return nil
+}
+
+// runService runs the node with flags as the service name, logging to the event log
+func runService(name string, flags []string, run func()) error {
+	elog, err := eventlog.Open(name)
+	if err != nil {
+		return err
+	}
+	defer elog.Close()
+	// Services have no console, so the logs written to stderr go to the event log
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	os.Stderr = pw
+	go io.Copy(&eventLogWriter{log: elog}, pr)
+
+	// Services start in the system directory, resolve relative paths from the executable instead
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(filepath.Dir(exe)); err != nil {
+		return err
+	}
+	os.Args = append([]string{os.Args[0]}, flags...)
+	return svc.Run(name, &nodeService{run: run})
+}
+
+// nodeService runs the node as a Windows service
+type nodeService struct {
+	run func()
+}
+
+// Execute runs the node until it stops. Stop and shutdown requests shut down the node
+// gracefully, like SIGTERM does
+func (ns *nodeService) Execute(args []string, reqs <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ns.run()
+	}()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	stopping := false
+	for {
+		select {
+		case <-done:
+			if !stopping {
+				// The node exited on its own
+				return true, 1
+			}
+			return false, 0
+		case r := <-reqs:
+			switch r.Cmd {
+			case svc.Interrogate:
+				status <- r.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				if !stopping {
+					stopping = true
+					status <- svc.Status{State: svc.StopPending}
+					serviceStops <- syscall.SIGTERM
+				}
+			}
+		}
+	}
+}
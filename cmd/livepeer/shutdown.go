@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/pm"
+	"github.com/livepeer/go-livepeer/sdnotify"
+	"github.com/livepeer/go-livepeer/server"
+)
+
+// shutdownSignals returns a channel that receives the signals that shut down the node,
+// and the requests of the Windows service manager to stop it
+func shutdownSignals() chan os.Signal {
+	c := make(chan os.Signal, 2)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for sig := range serviceStops {
+			select {
+			case c <- sig:
+			default:
+			}
+		}
+	}()
+	return c
+}
+
+// shutdown shuts down a node gracefully within a timeout
+type shutdown struct {
+	timeout time.Duration
+	// s is the media server of the node, nil for a transcoder
+	s *server.LivepeerServer
+	// sm redeems the winning tickets of an orchestrator or a redeemer, nil otherwise
+	sm *pm.LocalSenderMonitor
+}
+
+// run refuses new streams, segments and sessions, waits for the segments in flight,
+// ends the streams and waits for the ticket redemptions in progress. It returns once
+// done, after the timeout, or when another signal is received on sigs. Winning tickets
+// are stored when they are received, so the ones that are not redeemed yet are redeemed
+// after a restart. The node is not drained if the timeout is 0
+func (sd *shutdown) run(sigs <-chan os.Signal) {
+	notifySystemd(sdnotify.StateStopping)
+	if sd.timeout <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sd.timeout)
+	defer cancel()
+	go func() {
+		select {
+		case sig := <-sigs:
+			glog.Infof("Exiting Livepeer without draining: %v", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	glog.Infof("Draining for up to %v", sd.timeout)
+	start := time.Now()
+	server.Drain()
+	if err := server.WaitSegments(ctx); err != nil {
+		glog.Errorf("Exiting before the segments in flight are done: %v", err)
+	}
+	if sd.s != nil {
+		sd.s.EndStreams()
+	}
+	if sd.sm != nil {
+		if err := sd.sm.Shutdown(ctx); err != nil {
+			glog.Errorf("Exiting before the ticket redemptions in progress are done; the tickets will be redeemed after a restart err=%v", err)
+		}
+	}
+	glog.Infof("Drained in %v", time.Since(start))
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/features"
+	"github.com/livepeer/go-livepeer/server"
+	"github.com/livepeer/lpms/ffmpeg"
+	"gopkg.in/yaml.v2"
+)
+
+// snapshotExcluded are the flags that are not part of a config snapshot, since they are
+// secrets or are specific to a node
+var snapshotExcluded = map[string]bool{
+	// Secrets
+	"orchSecret":           true,
+	"relaySecret":          true,
+	"ethPassword":          true,
+	"cliToken":             true,
+	"diagnosticsToken":     true,
+	"s3creds":              true,
+	"gskey":                true,
+	"crashReportSentryDsn": true,
+	// May embed the API key of the Ethereum node provider
+	"ethUrl": true,
+
+	// Specific to a node
+	"config":          true,
+	"datadir":         true,
+	"ethAcctAddr":     true,
+	"ethKeystorePath": true,
+	"serviceAddr":     true,
+	"altServiceAddrs": true,
+	"cliCert":         true,
+	"cliKey":          true,
+	"cliClientCA":     true,
+	"auditLog":        true,
+	"verifyAuditLog":  true,
+	"version":         true,
+}
+
+// runtimeConfig returns the values of the flags that were changed on the running node,
+// e.g. the prices set with livepeer_cli
+func runtimeConfig(n *core.LivepeerNode) map[string]string {
+	values := map[string]string{"features": features.EnabledList()}
+	switch n.NodeType {
+	case core.OrchestratorNode:
+		if price := n.GetBasePrice(); price != nil {
+			values["pricePerUnit"] = price.Num().String()
+			values["pixelsPerUnit"] = price.Denom().String()
+		}
+	case core.BroadcasterNode:
+		if price := server.BroadcastCfg.MaxPrice(); price != nil {
+			values["maxPricePerUnit"] = price.Num().String()
+			values["pixelsPerUnit"] = price.Denom().String()
+		} else {
+			values["maxPricePerUnit"] = "0"
+		}
+		// Profiles loaded from a JSON file can't be named, the flag keeps the file instead
+		names := make([]string, 0, len(server.BroadcastJobVideoProfiles))
+		for _, p := range server.BroadcastJobVideoProfiles {
+			if _, ok := ffmpeg.VideoProfileLookup[p.Name]; !ok {
+				return values
+			}
+			names = append(names, p.Name)
+		}
+		values["transcodingOptions"] = strings.Join(names, ",")
+	}
+	return values
+}
+
+// configSnapshot returns the effective configuration of the node as a YAML config file:
+// the flags of fs that differ from their defaults, overridden by the values of runtime,
+// without the secrets and the flags that are specific to a node
+func configSnapshot(fs *flag.FlagSet, runtime map[string]string, now time.Time) ([]byte, error) {
+	var config yaml.MapSlice
+	fs.VisitAll(func(f *flag.Flag) {
+		if snapshotExcluded[f.Name] {
+			return
+		}
+		v := f.Value.String()
+		if rv, ok := runtime[f.Name]; ok {
+			v = rv
+		}
+		if v != f.DefValue {
+			config = append(config, yaml.MapItem{Key: f.Name, Value: configFileValue(f, v)})
+		}
+	})
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Config snapshot of a Livepeer %v node, exported at %v\n", core.LivepeerVersion, now.UTC().Format(time.RFC3339))
+	fmt.Fprintln(&buf, "# Secrets and node specific flags are not included, see `livepeer config import`")
+	buf.Write(data)
+	return buf.Bytes(), nil
+}
+
+// configFileValue returns the value of a flag with the type of the flag, so that config
+// files don't quote numbers and booleans
+func configFileValue(f *flag.Flag, v string) interface{} {
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return v
+	}
+	switch getter.Get().(type) {
+	case bool:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	case int, int64, uint, uint64:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i
+		}
+	case float64:
+		if x, err := strconv.ParseFloat(v, 64); err == nil {
+			return x
+		}
+	}
+	return v
+}
+
+// importConfig merges the flags of a config snapshot into the config file of the node,
+// for `livepeer config import`. The flags of the config file that the snapshot doesn't
+// set, such as secrets, are kept
+func importConfig(fs *flag.FlagSet, fname, snapshot string) error {
+	if fname == "" {
+		fname = os.Getenv(configEnvVar("config"))
+	}
+	if fname == "" || snapshot == "" {
+		return errors.New("usage: livepeer config import -config <config file> <snapshot>")
+	}
+
+	values, err := readConfigFile(snapshot)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f := fs.Lookup(k)
+		if f == nil {
+			return fmt.Errorf("unknown flag %v in %v", k, snapshot)
+		}
+		if snapshotExcluded[k] {
+			return fmt.Errorf("%v in %v is a secret or specific to a node, set it in %v instead", k, snapshot, fname)
+		}
+		if err := f.Value.Set(values[k]); err != nil {
+			return fmt.Errorf("invalid value %q for %v in %v: %v", values[k], k, snapshot, err)
+		}
+	}
+
+	config := make(map[string]string)
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(fname); err == nil {
+		mode = info.Mode()
+		if config, err = readConfigFile(fname); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	for k, v := range values {
+		config[k] = v
+	}
+	data, err := marshalConfigFile(fs, fname, config)
+	if err != nil {
+		return err
+	}
+
+	// Replace the config file at once so that a node reloading it never reads half of it
+	tmp, err := ioutil.TempFile(filepath.Dir(fname), filepath.Base(fname))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), fname); err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d flags from %v into %v\n", len(values), snapshot, fname)
+	return nil
+}
+
+// marshalConfigFile returns a YAML or TOML config file, depending on the extension of
+// fname, that sets the flags of fs to the values of config
+func marshalConfigFile(fs *flag.FlagSet, fname string, config map[string]string) ([]byte, error) {
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	value := func(k string) interface{} {
+		if f := fs.Lookup(k); f != nil {
+			return configFileValue(f, config[k])
+		}
+		return config[k]
+	}
+
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".yaml", ".yml":
+		var items yaml.MapSlice
+		for _, k := range keys {
+			items = append(items, yaml.MapItem{Key: k, Value: value(k)})
+		}
+		return yaml.Marshal(items)
+	case ".toml":
+		var buf bytes.Buffer
+		for _, k := range keys {
+			if s, ok := value(k).(string); ok {
+				fmt.Fprintf(&buf, "%v = %v\n", k, strconv.Quote(s))
+			} else {
+				fmt.Fprintf(&buf, "%v = %v\n", k, value(k))
+			}
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported config file format %v, expected .yaml, .yml or .toml", fname)
+}
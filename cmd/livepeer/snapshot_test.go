@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/features"
+	"github.com/livepeer/go-livepeer/server"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func snapshotFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet("livepeer", flag.ContinueOnError)
+	fs.String("network", "offchain", "")
+	fs.Bool("orchestrator", false, "")
+	fs.Int("maxSessions", 10, "")
+	fs.Int("pricePerUnit", 0, "")
+	fs.Int("pixelsPerUnit", 1, "")
+	fs.Float64("verifySampleRate", 1, "")
+	fs.Duration("shutdownTimeout", 30*time.Second, "")
+	fs.String("orchAddr", "", "")
+	fs.String("orchSecret", "", "")
+	fs.String("datadir", "", "")
+	fs.String("config", "", "")
+	return fs
+}
+
+func TestConfigSnapshot(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fs := snapshotFlags()
+	require.Nil(fs.Parse([]string{
+		"-network", "rinkeby", "-orchestrator", "-maxSessions", "10", "-pricePerUnit", "100",
+		"-verifySampleRate", "0.5", "-shutdownTimeout", "1m", "-orchAddr", "127.0.0.1:8935,127.0.0.1:8936",
+		"-orchSecret", "secret", "-datadir", "/data", "-config", "livepeer.yaml",
+	}))
+
+	// Defaults, secrets and node specific flags are not exported, runtime values are
+	now := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	data, err := configSnapshot(fs, map[string]string{"pricePerUnit": "200", "pixelsPerUnit": "3"}, now)
+	require.Nil(err)
+	assert.Equal(`# Config snapshot of a Livepeer `+core.LivepeerVersion+` node, exported at 2020-03-01T12:00:00Z
+# Secrets and node specific flags are not included, see `+"`livepeer config import`"+`
+network: rinkeby
+orchAddr: 127.0.0.1:8935,127.0.0.1:8936
+orchestrator: true
+pixelsPerUnit: 3
+pricePerUnit: 200
+shutdownTimeout: 1m0s
+verifySampleRate: 0.5
+`, string(data))
+
+	// The snapshot is a valid config file
+	dir, err := ioutil.TempDir("", "TestConfigSnapshot")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+	fname := writeConfigFile(t, dir, "snapshot.yaml", string(data))
+	other := snapshotFlags()
+	require.Nil(applyConfig(other, fname, nil))
+	assert.Equal("200", other.Lookup("pricePerUnit").Value.String())
+	assert.Equal("1m0s", other.Lookup("shutdownTimeout").Value.String())
+	assert.Equal("", other.Lookup("orchSecret").Value.String())
+}
+
+var _ = features.Register("snapshotTest", "Feature of the snapshot tests")
+
+func TestRuntimeConfig(t *testing.T) {
+	assert := assert.New(t)
+	defer func(profiles []ffmpeg.VideoProfile) { server.BroadcastJobVideoProfiles = profiles }(server.BroadcastJobVideoProfiles)
+	defer server.BroadcastCfg.SetMaxPrice(server.BroadcastCfg.MaxPrice())
+
+	n, _ := core.NewLivepeerNode(nil, "", nil)
+	n.NodeType = core.OrchestratorNode
+	n.SetBasePrice(big.NewRat(10, 4))
+	assert.Equal(map[string]string{"features": "", "pricePerUnit": "5", "pixelsPerUnit": "2"}, runtimeConfig(n))
+
+	n.NodeType = core.BroadcasterNode
+	server.BroadcastCfg.SetMaxPrice(nil)
+	server.BroadcastJobVideoProfiles = []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9, ffmpeg.P720p30fps16x9}
+	assert.Equal(map[string]string{"features": "", "maxPricePerUnit": "0", "transcodingOptions": "P144p30fps16x9,P720p30fps16x9"}, runtimeConfig(n))
+
+	// Profiles from a JSON file are kept in the flag
+	server.BroadcastCfg.SetMaxPrice(big.NewRat(1, 3))
+	server.BroadcastJobVideoProfiles = []ffmpeg.VideoProfile{{Name: "custom"}}
+	assert.Equal(map[string]string{"features": "", "maxPricePerUnit": "1", "pixelsPerUnit": "3"}, runtimeConfig(n))
+
+	// Features toggled at runtime are exported with their current state
+	defer features.Set("snapshotTest", false)
+	features.Set("snapshotTest", true)
+	assert.Equal("snapshotTest", runtimeConfig(n)["features"])
+}
+
+func TestImportConfig(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "TestImportConfig")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	snapshot := writeConfigFile(t, dir, "snapshot.yaml", "# Config snapshot\nnetwork: rinkeby\nmaxSessions: 20\norchAddr: 127.0.0.1:8935\nshutdownTimeout: 1m0s\n")
+	assert.EqualError(importConfig(snapshotFlags(), "", snapshot), "usage: livepeer config import -config <config file> <snapshot>")
+
+	// The flags of the config file that the snapshot doesn't set are kept
+	fname := writeConfigFile(t, dir, "livepeer.yaml", "network: offchain\norchSecret: secret\n")
+	require.Nil(os.Chmod(fname, 0640))
+	require.Nil(importConfig(snapshotFlags(), fname, snapshot))
+	data, err := ioutil.ReadFile(fname)
+	require.Nil(err)
+	assert.Equal("maxSessions: 20\nnetwork: rinkeby\norchAddr: 127.0.0.1:8935\norchSecret: secret\nshutdownTimeout: 1m0s\n", string(data))
+	info, err := os.Stat(fname)
+	require.Nil(err)
+	assert.Equal(os.FileMode(0640), info.Mode())
+
+	// New TOML config file
+	fname = filepath.Join(dir, "livepeer.toml")
+	require.Nil(importConfig(snapshotFlags(), fname, snapshot))
+	data, err = ioutil.ReadFile(fname)
+	require.Nil(err)
+	assert.Equal("maxSessions = 20\nnetwork = \"rinkeby\"\norchAddr = \"127.0.0.1:8935\"\nshutdownTimeout = \"1m0s\"\n", string(data))
+	info, err = os.Stat(fname)
+	require.Nil(err)
+	assert.Equal(os.FileMode(0600), info.Mode())
+	fs := snapshotFlags()
+	require.Nil(applyConfig(fs, fname, nil))
+	assert.Equal("20", fs.Lookup("maxSessions").Value.String())
+
+	// Invalid snapshots are not imported
+	before, err := ioutil.ReadFile(fname)
+	require.Nil(err)
+	err = importConfig(snapshotFlags(), fname, writeConfigFile(t, dir, "unknown.yaml", "maxSession: 20\n"))
+	assert.EqualError(err, "unknown flag maxSession in "+filepath.Join(dir, "unknown.yaml"))
+	err = importConfig(snapshotFlags(), fname, writeConfigFile(t, dir, "secret.yaml", "orchSecret: secret\n"))
+	assert.EqualError(err, "orchSecret in "+filepath.Join(dir, "secret.yaml")+" is a secret or specific to a node, set it in "+fname+" instead")
+	err = importConfig(snapshotFlags(), fname, writeConfigFile(t, dir, "invalid.yaml", "maxSessions: many\n"))
+	assert.Contains(err.Error(), `invalid value "many" for maxSessions`)
+	after, err := ioutil.ReadFile(fname)
+	require.Nil(err)
+	assert.Equal(before, after)
+}
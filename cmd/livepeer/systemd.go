@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/sdnotify"
+	"github.com/livepeer/go-livepeer/server"
+)
+
+// newWatchdog returns a watchdog if systemd enabled one for the node, nil otherwise
+func newWatchdog() *sdnotify.Watchdog {
+	timeout, err := sdnotify.WatchdogTimeout()
+	if err != nil {
+		glog.Errorf("Not feeding the systemd watchdog: %v", err)
+		return nil
+	}
+	if timeout == 0 {
+		return nil
+	}
+	glog.Infof("Feeding the systemd watchdog with a timeout of %v", timeout)
+	return sdnotify.NewWatchdog(timeout)
+}
+
+// probeServer gets the status of the node, which locks its streams and transcoders, at
+// every half of the watchdog timeout so that the watchdog is not fed anymore if the
+// server is deadlocked
+func probeServer(ctx context.Context, watchdog *sdnotify.Watchdog, s *server.LivepeerServer) {
+	period := watchdog.Timeout() / 2
+	beat := watchdog.Register("server", period)
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		s.GetNodeStatus()
+		beat()
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// notifySystemd sends states to systemd, if it supervises the node
+func notifySystemd(states ...string) {
+	if err := sdnotify.Notify(states...); err != nil {
+		glog.Errorf("Error notifying systemd err=%v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/urfave/cli"
+)
+
+// tokenTransport adds the bearer token of the node's CLI webserver to every request
+type tokenTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the request
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(req)
+}
+
+// nodeClient returns the client of the node's CLI webserver, which presents the token if
+// set and verifies the node with the CAs of caFile if set. A client certificate is
+// presented if certFile and keyFile are set
+func nodeClient(token, caFile, certFile, keyFile string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caFile != "" || certFile != "" || keyFile != "" {
+		config := &tls.Config{MinVersion: tls.VersionTLS12}
+		if caFile != "" {
+			pem, err := ioutil.ReadFile(caFile)
+			if err != nil {
+				return nil, err
+			}
+			config.RootCAs = x509.NewCertPool()
+			if !config.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %v", caFile)
+			}
+		}
+		if certFile != "" || keyFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return nil, err
+			}
+			config.Certificates = []tls.Certificate{cert}
+		}
+		transport.TLSClientConfig = config
+	}
+
+	client := &http.Client{Transport: transport}
+	if token != "" {
+		client.Transport = &tokenTransport{token: token, base: transport}
+	}
+	return client, nil
+}
+
+// nodeScheme returns the scheme of the node's CLI webserver
+func nodeScheme(c *cli.Context) string {
+	if c.GlobalBool("https") || c.GlobalString("cacert") != "" || c.GlobalString("cert") != "" {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	lpcommon "github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/eth"
+	"github.com/livepeer/go-livepeer/eth/types"
+	"github.com/urfave/cli"
+)
+
+// commandParam is a flag of a command that is sent to the node as a form value
+type commandParam struct {
+	flag  string
+	form  string
+	usage string
+	// The flag is not required
+	optional bool
+	// parse validates the value of the flag and converts it to the form value
+	parse func(v string) (string, error)
+}
+
+// nodeCommand is a single action against the node's CLI webserver that can be run
+// without the interactive wizard
+type nodeCommand struct {
+	name   string
+	usage  string
+	method string
+	path   string
+	params []commandParam
+	// The response is binary data that is printed as hex, e.g. a signature or a tx hash
+	hex bool
+}
+
+// commandResult is the machine-readable output of a command
+type commandResult struct {
+	Command string          `json:"command"`
+	OK      bool            `json:"ok"`
+	Status  int             `json:"status"`
+	Result  json.RawMessage `json:"result,omitempty"`
+}
+
+func parseBigInt(v string) (string, error) {
+	i, err := lpcommon.ParseBigInt(v)
+	if err != nil {
+		return "", err
+	}
+	return i.String(), nil
+}
+
+func parseBaseAmount(v string) (string, error) {
+	i, err := eth.ToBaseAmount(v)
+	if err != nil {
+		return "", err
+	}
+	return i.String(), nil
+}
+
+func parseAddress(v string) (string, error) {
+	if !ethcommon.IsHexAddress(v) {
+		return "", fmt.Errorf("invalid hex address %v", v)
+	}
+	return ethcommon.HexToAddress(v).Hex(), nil
+}
+
+func parseVoteChoice(v string) (string, error) {
+	choice, err := strconv.Atoi(v)
+	if err != nil || !types.VoteChoice(choice).IsValid() {
+		return "", fmt.Errorf("invalid choice %v", v)
+	}
+	return strconv.Itoa(choice), nil
+}
+
+func parsePercentage(v string) (string, error) {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 || f > 100 {
+		return "", fmt.Errorf("invalid percentage %v", v)
+	}
+	return v, nil
+}
+
+func parseSeconds(v string) (string, error) {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return "", fmt.Errorf("invalid number of seconds %v", v)
+	}
+	return v, nil
+}
+
+func parseString(v string) (string, error) {
+	return v, nil
+}
+
+func parseBool(v string) (string, error) {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return "", fmt.Errorf("invalid boolean %v", v)
+	}
+	return strconv.FormatBool(b), nil
+}
+
+var nodeCommands = []nodeCommand{
+	{name: "status", usage: "Get node status", method: "GET", path: "/status"},
+	{name: "exportConfig", usage: "Export the configuration of the node as a YAML config file, without secrets", method: "GET", path: "/configSnapshot"},
+	{name: "features", usage: "List the experimental features and whether they are enabled", method: "GET", path: "/features"},
+	{name: "setFeature", usage: "Enable or disable an experimental feature until the node restarts", method: "POST", path: "/setFeature", params: []commandParam{
+		{flag: "name", form: "name", usage: "name of the feature", parse: parseString},
+		{flag: "enabled", form: "enabled", usage: "true to enable the feature, false to disable it", parse: parseBool},
+	}},
+	{name: "transcoderPool", usage: "Get the load and the performance of the transcoders connected to the orchestrator", method: "GET", path: "/transcoderPool"},
+	{name: "fleetStatus", usage: "Get the members, streams and orchestrator performance of the fleet of a coordinator", method: "GET", path: "/coordinator"},
+	{name: "setStreamProfiles", usage: "Replace the transcoding profiles of a live stream from its next segment", method: "POST", path: "/setStreamProfiles", params: []commandParam{
+		{flag: "manifestID", form: "manifestID", usage: "manifest ID of the stream", parse: parseString},
+		{flag: "presets", form: "presets", usage: "comma separated list of transcoding profiles", parse: parseString, optional: true},
+		{flag: "profiles", form: "profiles", usage: "JSON array of custom profiles, as returned by the auth webhook", parse: parseString, optional: true},
+	}},
+	{name: "clip", usage: "Transcode a frame-accurate clip of a recorded stream", method: "POST", path: "/clip", params: []commandParam{
+		{flag: "url", form: "url", usage: "URL of the HLS media playlist of the recorded stream", parse: parseString},
+		{flag: "start", form: "start", usage: "start of the clip, in seconds from the start of the recording", parse: parseSeconds},
+		{flag: "end", form: "end", usage: "end of the clip, in seconds from the start of the recording", parse: parseSeconds},
+		{flag: "presets", form: "presets", usage: "comma separated list of transcoding profiles", parse: parseString, optional: true},
+		{flag: "profiles", form: "profiles", usage: "JSON array of custom profiles, as returned by the auth webhook", parse: parseString, optional: true},
+	}},
+	{name: "bond", usage: "Bond LPT to an orchestrator", method: "POST", path: "/bond", params: []commandParam{
+		{flag: "amount", form: "amount", usage: "amount of LPT to bond, in base units", parse: parseBigInt},
+		{flag: "to", form: "toAddr", usage: "address of the orchestrator", parse: parseAddress},
+	}},
+	{name: "unbond", usage: "Unbond LPT", method: "POST", path: "/unbond", params: []commandParam{
+		{flag: "amount", form: "amount", usage: "amount of LPT to unbond, in base units", parse: parseBigInt},
+	}},
+	{name: "rebond", usage: "Rebond with an unbonding lock", method: "POST", path: "/rebond", params: []commandParam{
+		{flag: "lock", form: "unbondingLockId", usage: "ID of the unbonding lock", parse: parseBigInt},
+		{flag: "to", form: "toAddr", usage: "address of the orchestrator to rebond to, if unbonded", parse: parseAddress, optional: true},
+	}},
+	{name: "withdrawStake", usage: "Withdraw the stake of an unbonding lock", method: "POST", path: "/withdrawStake", params: []commandParam{
+		{flag: "lock", form: "unbondingLockId", usage: "ID of the unbonding lock", parse: parseBigInt},
+	}},
+	{name: "moveStake", usage: "Move the whole stake to another orchestrator", method: "POST", path: "/moveStake", params: []commandParam{
+		{flag: "to", form: "toAddr", usage: "address of the orchestrator", parse: parseAddress},
+	}},
+	{name: "previewStake", usage: "Preview the stake after a bond, rebond, unbond, withdrawStake or moveStake without sending it", method: "GET", path: "/previewStake", params: []commandParam{
+		{flag: "action", form: "action", usage: "transaction to preview: bond, rebond, unbond, withdrawStake or moveStake", parse: parseString},
+		{flag: "amount", form: "amount", usage: "amount of LPT to bond or unbond, in base units", parse: parseBigInt, optional: true},
+		{flag: "to", form: "toAddr", usage: "address of the orchestrator to bond, rebond or move the stake to", parse: parseAddress, optional: true},
+		{flag: "lock", form: "unbondingLockId", usage: "ID of the unbonding lock to rebond or withdraw", parse: parseBigInt, optional: true},
+	}},
+	{name: "withdrawFees", usage: "Withdraw fees (ETH)", method: "POST", path: "/withdrawFees"},
+	{name: "claim", usage: "Claim rewards and fees", method: "POST", path: "/claimEarnings", params: []commandParam{
+		{flag: "endRound", form: "endRound", usage: "round to claim up to", parse: parseBigInt},
+	}},
+	{name: "transfer", usage: "Transfer LPT", method: "POST", path: "/transferTokens", params: []commandParam{
+		{flag: "to", form: "to", usage: "address of the recipient", parse: parseAddress},
+		{flag: "amount", form: "amount", usage: "amount of LPT to transfer, in base units", parse: parseBigInt},
+	}},
+	{name: "registerOrchestrator", usage: "Register the node as an orchestrator, after checking that the service URI reaches it", method: "POST", path: "/activateOrchestrator", params: []commandParam{
+		{flag: "rewardCut", form: "blockRewardCut", usage: "percentage of the block rewards kept by the orchestrator", parse: parsePercentage},
+		{flag: "feeShare", form: "feeShare", usage: "percentage of the fees shared with delegators", parse: parsePercentage},
+		{flag: "pricePerUnit", form: "pricePerUnit", usage: "price in Wei per pixelsPerUnit pixels", parse: parseBigInt},
+		{flag: "pixelsPerUnit", form: "pixelsPerUnit", usage: "number of pixels priced at pricePerUnit", parse: parseBigInt},
+		{flag: "serviceURI", form: "serviceURI", usage: "service URI of the orchestrator, e.g. https://1.2.3.4:8935", parse: parseString},
+		{flag: "amount", form: "amount", usage: "amount of LPT to bond to self, in base units", parse: parseBigInt, optional: true},
+		{flag: "skipServiceURICheck", form: "skipServiceURICheck", usage: "true to register the service URI without checking it", parse: parseBool, optional: true},
+	}},
+	{name: "setOrchestratorConfig", usage: "Update the commission rates, price or service URI of the orchestrator", method: "POST", path: "/setOrchestratorConfig", params: []commandParam{
+		{flag: "rewardCut", form: "blockRewardCut", usage: "percentage of the block rewards kept by the orchestrator, along with --feeShare", parse: parsePercentage, optional: true},
+		{flag: "feeShare", form: "feeShare", usage: "percentage of the fees shared with delegators, along with --rewardCut", parse: parsePercentage, optional: true},
+		{flag: "pricePerUnit", form: "pricePerUnit", usage: "price in Wei per pixelsPerUnit pixels, along with --pixelsPerUnit", parse: parseBigInt, optional: true},
+		{flag: "pixelsPerUnit", form: "pixelsPerUnit", usage: "number of pixels priced at pricePerUnit, along with --pricePerUnit", parse: parseBigInt, optional: true},
+		{flag: "serviceURI", form: "serviceURI", usage: "service URI of the orchestrator", parse: parseString, optional: true},
+		{flag: "skipServiceURICheck", form: "skipServiceURICheck", usage: "true to register the service URI without checking it", parse: parseBool, optional: true},
+	}},
+	{name: "checkServiceURI", usage: "Check that a service URI is public and reaches the node", method: "GET", path: "/checkServiceURI", params: []commandParam{
+		{flag: "serviceURI", form: "serviceURI", usage: "service URI to check, the current service URI of the node by default", parse: parseString, optional: true},
+	}},
+	{name: "earnings", usage: "Get the fees, rewards and bonds of the node indexed from the chain", method: "GET", path: "/earnings", params: []commandParam{
+		{flag: "since", form: "since", usage: "RFC 3339 time of the oldest earnings", parse: parseString, optional: true},
+		{flag: "until", form: "until", usage: "RFC 3339 time after the most recent earnings", parse: parseString, optional: true},
+		{flag: "interval", form: "interval", usage: "also sum the earnings per day, month or year", parse: parseString, optional: true},
+		{flag: "events", form: "events", usage: "true to also list the indexed earnings", parse: parseBool, optional: true},
+	}},
+	{name: "initializeRound", usage: "Initialize the current round", method: "POST", path: "/initializeRound"},
+	{name: "reward", usage: "Call reward for the current round", method: "GET", path: "/reward"},
+	{name: "deposit", usage: "Deposit broadcasting funds (ETH)", method: "POST", path: "/fundDepositAndReserve", params: []commandParam{
+		{flag: "deposit", form: "depositAmount", usage: "deposit amount in ETH", parse: parseBaseAmount},
+		{flag: "reserve", form: "reserveAmount", usage: "reserve amount in ETH", parse: parseBaseAmount},
+	}},
+	{name: "unlock", usage: "Unlock broadcasting funds", method: "POST", path: "/unlock"},
+	{name: "cancelUnlock", usage: "Cancel the unlock of broadcasting funds", method: "POST", path: "/cancelUnlock"},
+	{name: "withdraw", usage: "Withdraw broadcasting funds", method: "POST", path: "/withdraw"},
+	{name: "setGasPrice", usage: "Set the Eth gas price", method: "POST", path: "/setGasPrice", params: []commandParam{
+		{flag: "amount", form: "amount", usage: "gas price in Wei, 0 for automatic", parse: parseBigInt},
+	}},
+	{name: "sign", usage: "Sign a message", method: "POST", path: "/signMessage", hex: true, params: []commandParam{
+		{flag: "message", form: "message", usage: "message to sign", parse: parseString},
+	}},
+	{name: "unsignedTransactions", usage: "List the transactions queued to be signed offline, with the nonces to sign them with", method: "GET", path: "/unsignedTransactions"},
+	{name: "sendSignedTransaction", usage: "Send an offline signed queued transaction", method: "POST", path: "/sendSignedTransaction", params: []commandParam{
+		{flag: "tx", form: "tx", usage: "hex encoded signed raw transaction", parse: parseString},
+	}},
+	{name: "discardUnsignedTransaction", usage: "Remove a transaction queued to be signed offline", method: "POST", path: "/discardUnsignedTransaction", params: []commandParam{
+		{flag: "id", form: "id", usage: "ID of the queued transaction", parse: parseString},
+	}},
+	{name: "vote", usage: "Vote in a poll", method: "POST", path: "/vote", hex: true, params: []commandParam{
+		{flag: "poll", form: "poll", usage: "contract address of the poll", parse: parseAddress},
+		{flag: "choice", form: "choiceID", usage: "ID of the choice to vote for", parse: parseVoteChoice},
+	}},
+}
+
+// cliCommands returns the subcommands that run a single action and exit
+func cliCommands() []cli.Command {
+	var cmds []cli.Command
+	for _, nc := range nodeCommands {
+		nc := nc
+		flags := []cli.Flag{cli.BoolFlag{Name: "json", Usage: "print the result as JSON"}}
+		for _, p := range nc.params {
+			flags = append(flags, cli.StringFlag{Name: p.flag, Usage: p.usage})
+		}
+		cmds = append(cmds, cli.Command{
+			Name:  nc.name,
+			Usage: nc.usage,
+			Flags: flags,
+			Action: func(c *cli.Context) error {
+				values := make(map[string]string)
+				for _, p := range nc.params {
+					if c.IsSet(p.flag) {
+						values[p.flag] = c.String(p.flag)
+					}
+				}
+				base := fmt.Sprintf("%v://%v:%v", nodeScheme(c), c.GlobalString("host"), c.GlobalString("http"))
+				res, err := nc.run(http.DefaultClient, base, values)
+				if err != nil {
+					return cli.NewExitError(err.Error(), 1)
+				}
+				printResult(res, c.Bool("json"))
+				if !res.OK {
+					return cli.NewExitError("", 1)
+				}
+				return nil
+			},
+		})
+	}
+	return cmds
+}
+
+// form validates the flag values of a command and returns the form values of its request
+func (nc nodeCommand) form(values map[string]string) (url.Values, error) {
+	form := url.Values{}
+	for _, p := range nc.params {
+		v, ok := values[p.flag]
+		if !ok && p.optional {
+			continue
+		}
+		if !ok {
+			return nil, fmt.Errorf("missing --%v", p.flag)
+		}
+		fv, err := p.parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%v: %v", p.flag, err)
+		}
+		form.Set(p.form, fv)
+	}
+	return form, nil
+}
+
+// run sends the request of a command to the node at base
+func (nc nodeCommand) run(client *http.Client, base string, values map[string]string) (*commandResult, error) {
+	form, err := nc.form(values)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *http.Response
+	if nc.method == "GET" {
+		u := base + nc.path
+		if len(form) > 0 {
+			u += "?" + form.Encode()
+		}
+		resp, err = client.Get(u)
+	} else {
+		resp, err = client.Post(base+nc.path, "application/x-www-form-urlencoded", bytes.NewBufferString(form.Encode()))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot reach the node: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &commandResult{
+		Command: nc.name,
+		OK:      resp.StatusCode >= 200 && resp.StatusCode < 300,
+		Status:  resp.StatusCode,
+	}
+	switch {
+	case len(body) == 0:
+	case nc.hex && res.OK:
+		res.Result, _ = json.Marshal(fmt.Sprintf("0x%x", body))
+	case json.Valid(body):
+		res.Result = body
+	default:
+		res.Result, _ = json.Marshal(strings.TrimSpace(string(body)))
+	}
+	return res, nil
+}
+
+func printResult(res *commandResult, asJSON bool) {
+	if asJSON {
+		data, _ := json.Marshal(res)
+		fmt.Println(string(data))
+		return
+	}
+	var s string
+	if json.Unmarshal(res.Result, &s) != nil {
+		var buf bytes.Buffer
+		if json.Indent(&buf, res.Result, "", "  ") == nil {
+			s = buf.String()
+		}
+	}
+	if !res.OK {
+		fmt.Fprintf(os.Stderr, "Error running %v: %v %v\n", res.Command, res.Status, s)
+		return
+	}
+	if s == "" {
+		s = "OK"
+	}
+	fmt.Println(s)
+}
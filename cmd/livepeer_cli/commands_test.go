@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func findCommand(name string) nodeCommand {
+	for _, nc := range nodeCommands {
+		if nc.name == name {
+			return nc
+		}
+	}
+	panic("unknown command " + name)
+}
+
+func TestNodeCommand(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var form url.Values
+	var path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Nil(r.ParseForm())
+		form, path = r.Form, r.URL.Path
+		switch r.URL.Path {
+		case "/status":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"Version":"0.5.9"}`))
+		case "/signMessage":
+			w.Write([]byte{0xab, 0xcd})
+		case "/unlock":
+			http.Error(w, "not enough funds", http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	// JSON responses are included as is
+	res, err := findCommand("status").run(ts.Client(), ts.URL, nil)
+	require.Nil(err)
+	assert.True(res.OK)
+	assert.Equal(`{"Version":"0.5.9"}`, string(res.Result))
+	data, err := json.Marshal(res)
+	require.Nil(err)
+	assert.Equal(`{"command":"status","ok":true,"status":200,"result":{"Version":"0.5.9"}}`, string(data))
+
+	// Flags are validated and sent as form values
+	to := "0x0000000000000000000000000000000000000001"
+	res, err = findCommand("bond").run(ts.Client(), ts.URL, map[string]string{"amount": "100", "to": to})
+	require.Nil(err)
+	assert.True(res.OK)
+	assert.Nil(res.Result)
+	assert.Equal("/bond", path)
+	assert.Equal(url.Values{"amount": {"100"}, "toAddr": {to}}, form)
+
+	_, err = findCommand("bond").run(ts.Client(), ts.URL, map[string]string{"amount": "100"})
+	assert.EqualError(err, "missing --to")
+	_, err = findCommand("bond").run(ts.Client(), ts.URL, map[string]string{"amount": "100", "to": "foo"})
+	assert.EqualError(err, "invalid --to: invalid hex address foo")
+	_, err = findCommand("bond").run(ts.Client(), ts.URL, map[string]string{"amount": "many", "to": to})
+	assert.Contains(err.Error(), "invalid --amount")
+
+	// Optional flags
+	_, err = findCommand("rebond").run(ts.Client(), ts.URL, map[string]string{"lock": "2"})
+	require.Nil(err)
+	assert.Equal(url.Values{"unbondingLockId": {"2"}}, form)
+
+	_, err = findCommand("setFeature").run(ts.Client(), ts.URL, map[string]string{"name": "llhls", "enabled": "1"})
+	require.Nil(err)
+	assert.Equal(url.Values{"name": {"llhls"}, "enabled": {"true"}}, form)
+	_, err = findCommand("setFeature").run(ts.Client(), ts.URL, map[string]string{"name": "llhls", "enabled": "maybe"})
+	assert.EqualError(err, "invalid --enabled: invalid boolean maybe")
+
+	// ETH amounts are converted to base units
+	_, err = findCommand("deposit").run(ts.Client(), ts.URL, map[string]string{"deposit": "1.5", "reserve": "0.5"})
+	require.Nil(err)
+	assert.Equal(url.Values{"depositAmount": {"1500000000000000000"}, "reserveAmount": {"500000000000000000"}}, form)
+
+	// Binary responses are printed as hex
+	res, err = findCommand("sign").run(ts.Client(), ts.URL, map[string]string{"message": "hello"})
+	require.Nil(err)
+	assert.Equal(`"0xabcd"`, string(res.Result))
+
+	// Errors are reported with the status of the response
+	res, err = findCommand("unlock").run(ts.Client(), ts.URL, nil)
+	require.Nil(err)
+	assert.False(res.OK)
+	assert.Equal(http.StatusInternalServerError, res.Status)
+	assert.Equal(`"not enough funds"`, string(res.Result))
+
+	ts.Close()
+	_, err = findCommand("status").run(ts.Client(), ts.URL, nil)
+	assert.Contains(err.Error(), "cannot reach the node")
+}
+
+func TestNodeClient(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var auth string
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "TestNodeClient")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	// The node is verified with the CA file
+	caFile := filepath.Join(dir, "ca.pem")
+	require.Nil(ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0644))
+	client, err := nodeClient("foo", caFile, "", "")
+	require.Nil(err)
+	res, err := findCommand("status").run(client, ts.URL, nil)
+	require.Nil(err)
+	assert.True(res.OK)
+	assert.Equal("Bearer foo", auth)
+
+	client, err = nodeClient("", "", "", "")
+	require.Nil(err)
+	_, err = findCommand("status").run(client, ts.URL, nil)
+	assert.Contains(err.Error(), "certificate")
+
+	_, err = nodeClient("", filepath.Join(dir, "missing.pem"), "", "")
+	assert.True(os.IsNotExist(err))
+}
@@ -34,7 +34,38 @@ func main() {
 			Value: 4,
 			Usage: "log level to emit to the screen",
 		},
+		cli.StringFlag{
+			Name:   "token",
+			Usage:  "bearer token of the node's CLI server, if set with -cliToken",
+			EnvVar: "LIVEPEER_CLI_TOKEN",
+		},
+		cli.BoolFlag{
+			Name:  "https",
+			Usage: "connect to the node over HTTPS, if served with -cliCert",
+		},
+		cli.StringFlag{
+			Name:  "cacert",
+			Usage: "CA certificates file to verify the node's certificate; implies --https",
+		},
+		cli.StringFlag{
+			Name:  "cert",
+			Usage: "client certificate file, if the node is started with -cliClientCA; implies --https",
+		},
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "private key file of --cert",
+		},
 	}
+	app.Before = func(c *cli.Context) error {
+		client, err := nodeClient(c.String("token"), c.String("cacert"), c.String("cert"), c.String("key"))
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Error configuring the node client: %v", err), 1)
+		}
+		http.DefaultClient = client
+		return nil
+	}
+	// Subcommands run a single action against the node and exit
+	app.Commands = cliCommands()
 	app.Action = func(c *cli.Context) error {
 		if c.Bool("version") {
 			fmt.Println("Version: " + core.LivepeerVersion)
@@ -46,11 +77,12 @@ func main() {
 
 		// Start the wizard and relinquish control
 		w := &wizard{
-			endpoint: fmt.Sprintf("http://%v:%v/status", c.String("host"), c.String("http")),
+			scheme:   nodeScheme(c),
 			httpPort: c.String("http"),
 			host:     c.String("host"),
 			in:       bufio.NewReader(os.Stdin),
 		}
+		w.endpoint = w.nodeURL("/status")
 		w.orchestrator = w.isOrchestrator()
 		w.checkNet()
 		w.run()
@@ -64,6 +96,7 @@ func main() {
 
 type wizard struct {
 	endpoint     string // Local livepeer node
+	scheme       string
 	httpPort     string
 	host         string
 	orchestrator bool
@@ -102,6 +135,7 @@ func (w *wizard) initializeOptions() []wizardOpt {
 		{desc: "Invoke \"withdraw broadcasting funds\"", invoke: w.withdraw, notOrchestrator: true},
 		{desc: "Set broadcast config", invoke: w.setBroadcastConfig, notOrchestrator: true},
 		{desc: "Set Eth gas price", invoke: w.setGasPrice},
+		{desc: "Export audit log", invoke: w.exportAuditLog},
 		{desc: "Get test LPT", invoke: w.requestTokens, testnet: true},
 		{desc: "Get test ETH", invoke: func() {
 			fmt.Print("For Rinkeby Eth, go to the Rinkeby faucet (https://faucet.rinkeby.io/).")
@@ -113,6 +147,11 @@ func (w *wizard) initializeOptions() []wizardOpt {
 	return options
 }
 
+// nodeURL returns the URL of an endpoint of the node's CLI server
+func (w *wizard) nodeURL(path string) string {
+	return fmt.Sprintf("%v://%v:%v%v", w.scheme, w.host, w.httpPort, path)
+}
+
 func (w *wizard) filterOptions(options []wizardOpt) []wizardOpt {
 	filtered := make([]wizardOpt, 0, len(options))
 	for _, opt := range options {
@@ -172,7 +211,7 @@ var RinkebyChainID = "4"
 var DevenvChainID = "54321"
 
 func (w *wizard) checkNet() {
-	nID := httpGet(fmt.Sprintf("http://%v:%v/EthChainID", w.host, w.httpPort))
+	nID := httpGet(w.nodeURL("/EthChainID"))
 	w.testnet = nID == RinkebyChainID || nID == DevenvChainID
 	w.offchain = nID == "0"
 }
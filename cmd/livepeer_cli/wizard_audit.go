@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+func (w *wizard) exportAuditLog() {
+	fmt.Printf("Enter the path of the file to export the audit log to - ")
+	path := w.readString()
+
+	resp, err := http.Get(w.nodeURL("/auditLog"))
+	if err != nil {
+		fmt.Printf("Error exporting audit log: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		fmt.Printf("Error exporting audit log: %v\n", resp.Status)
+		return
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		fmt.Printf("Error creating %v: %v\n", path, err)
+		return
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		fmt.Printf("Error writing audit log to %v: %v\n", path, err)
+		return
+	}
+	fmt.Printf("Audit log exported to %v\n", path)
+
+	var res struct {
+		Entries int
+		Valid   bool
+		Error   string
+	}
+	data := httpGet(w.nodeURL("/verifyAuditLog"))
+	if err := json.Unmarshal([]byte(data), &res); err != nil {
+		fmt.Printf("Error verifying audit log: %v\n", err)
+		return
+	}
+	if res.Valid {
+		fmt.Printf("The audit log is valid with %d entries\n", res.Entries)
+	} else {
+		fmt.Printf("The audit log is invalid: %v\n", res.Error)
+	}
+}
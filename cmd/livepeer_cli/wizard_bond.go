@@ -58,7 +58,7 @@ func (w *wizard) registeredOrchestratorStats() map[int]common.Address {
 }
 
 func (w *wizard) getRegisteredOrchestrators() ([]lpTypes.Transcoder, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%v:%v/registeredOrchestrators", w.host, w.httpPort))
+	resp, err := http.Get(w.nodeURL("/registeredOrchestrators"))
 	if err != nil {
 		return nil, err
 	}
@@ -117,9 +117,9 @@ func (w *wizard) unbondingLockStats(withdrawable bool) map[int64]bool {
 func (w *wizard) getUnbondingLocks(withdrawable bool) ([]lpcommon.DBUnbondingLock, error) {
 	var url string
 	if withdrawable {
-		url = fmt.Sprintf("http://%v:%v/unbondingLocks?withdrawable=true", w.host, w.httpPort)
+		url = w.nodeURL("/unbondingLocks?withdrawable=true")
 	} else {
-		url = fmt.Sprintf("http://%v:%v/unbondingLocks", w.host, w.httpPort)
+		url = w.nodeURL("/unbondingLocks")
 	}
 	resp, err := http.Get(url)
 	if err != nil {
@@ -181,7 +181,7 @@ func (w *wizard) bond() {
 		"toAddr": {fmt.Sprintf("%v", tAddr.Hex())},
 	}
 
-	httpPostWithParams(fmt.Sprintf("http://%v:%v/bond", w.host, w.httpPort), val)
+	httpPostWithParams(w.nodeURL("/bond"), val)
 }
 
 func (w *wizard) rebond() {
@@ -241,7 +241,7 @@ func (w *wizard) rebond() {
 		val["toAddr"] = []string{fmt.Sprintf("%v", toAddr.Hex())}
 	}
 
-	httpPostWithParams(fmt.Sprintf("http://%v:%v/rebond", w.host, w.httpPort), val)
+	httpPostWithParams(w.nodeURL("/rebond"), val)
 }
 
 func (w *wizard) unbond() {
@@ -289,7 +289,7 @@ func (w *wizard) unbond() {
 		"amount": {fmt.Sprintf("%v", amount.String())},
 	}
 
-	httpPostWithParams(fmt.Sprintf("http://%v:%v/unbond", w.host, w.httpPort), val)
+	httpPostWithParams(w.nodeURL("/unbond"), val)
 }
 
 func (w *wizard) withdrawStake() {
@@ -326,11 +326,11 @@ func (w *wizard) withdrawStake() {
 		"unbondingLockId": {fmt.Sprintf("%v", strconv.FormatInt(unbondingLockID, 10))},
 	}
 
-	httpPostWithParams(fmt.Sprintf("http://%v:%v/withdrawStake", w.host, w.httpPort), val)
+	httpPostWithParams(w.nodeURL("/withdrawStake"), val)
 }
 
 func (w *wizard) withdrawFees() {
-	httpPost(fmt.Sprintf("http://%v:%v/withdrawFees", w.host, w.httpPort))
+	httpPost(w.nodeURL("/withdrawFees"))
 }
 
 func (w *wizard) claimRewardsAndFees() {
@@ -356,5 +356,5 @@ func (w *wizard) claimRewardsAndFees() {
 		"endRound": {fmt.Sprintf("%v", endRound.String())},
 	}
 
-	httpPostWithParams(fmt.Sprintf("http://%v:%v/claimEarnings", w.host, w.httpPort), val)
+	httpPostWithParams(w.nodeURL("/claimEarnings"), val)
 }
@@ -17,7 +17,7 @@ import (
 )
 
 func (w *wizard) allTranscodingOptions() map[int]string {
-	resp, err := http.Get(fmt.Sprintf("http://%v:%v/getAvailableTranscodingOptions", w.host, w.httpPort))
+	resp, err := http.Get(w.nodeURL("/getAvailableTranscodingOptions"))
 	if err != nil {
 		glog.Errorf("Error getting all transcoding options: %v", err)
 		return nil
@@ -82,7 +82,7 @@ func (w *wizard) setBroadcastConfig() {
 		"transcodingOptions": {fmt.Sprintf("%v", transOpts)},
 	}
 
-	httpPostWithParams(fmt.Sprintf("http://%v:%v/setBroadcastConfig", w.host, w.httpPort), val)
+	httpPostWithParams(w.nodeURL("/setBroadcastConfig"), val)
 }
 
 func (w *wizard) idListToVideoProfileList(idList string, opts map[int]string) (string, error) {
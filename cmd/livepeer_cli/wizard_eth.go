@@ -14,7 +14,7 @@ func (w *wizard) setGasPrice() {
 		"amount": {fmt.Sprintf("%v", amount.String())},
 	}
 
-	httpPostWithParams(fmt.Sprintf("http://%v:%v/setGasPrice", w.host, w.httpPort), val)
+	httpPostWithParams(w.nodeURL("/setGasPrice"), val)
 }
 
 func (w *wizard) signMessage() {
@@ -23,7 +23,7 @@ func (w *wizard) signMessage() {
 	val := url.Values{
 		"message": {msg},
 	}
-	result, ok := httpPostWithParams(fmt.Sprintf("http://%v:%v/signMessage", w.host, w.httpPort), val)
+	result, ok := httpPostWithParams(w.nodeURL("/signMessage"), val)
 	if !ok {
 		fmt.Printf("Error signing message: %v\n", result)
 	}
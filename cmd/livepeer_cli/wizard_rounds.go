@@ -2,14 +2,13 @@ package main
 
 import (
 	"errors"
-	"fmt"
 	"io/ioutil"
 	"math/big"
 	"net/http"
 )
 
 func (w *wizard) currentRound() (*big.Int, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%v:%v/currentRound", w.host, w.httpPort))
+	resp, err := http.Get(w.nodeURL("/currentRound"))
 	if err != nil {
 		return nil, err
 	}
@@ -29,5 +28,5 @@ func (w *wizard) currentRound() (*big.Int, error) {
 }
 
 func (w *wizard) initializeRound() {
-	httpPost(fmt.Sprintf("http://%v:%v/initializeRound", w.host, w.httpPort))
+	httpPost(w.nodeURL("/initializeRound"))
 }
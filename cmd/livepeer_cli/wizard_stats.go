@@ -267,7 +267,7 @@ func (w *wizard) delegatorStats() {
 }
 
 func (w *wizard) getProtocolParameters() (lpTypes.ProtocolParameters, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%v:%v/protocolParameters", w.host, w.httpPort))
+	resp, err := http.Get(w.nodeURL("/protocolParameters"))
 	if err != nil {
 		return lpTypes.ProtocolParameters{}, err
 	}
@@ -289,7 +289,7 @@ func (w *wizard) getProtocolParameters() (lpTypes.ProtocolParameters, error) {
 }
 
 func (w *wizard) getContractAddresses() (map[string]common.Address, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%v:%v/contractAddresses", w.host, w.httpPort))
+	resp, err := http.Get(w.nodeURL("/contractAddresses"))
 	if err != nil {
 		return nil, err
 	}
@@ -310,7 +310,7 @@ func (w *wizard) getContractAddresses() (map[string]common.Address, error) {
 }
 
 func (w *wizard) getEthAddr() string {
-	addr := httpGet(fmt.Sprintf("http://%v:%v/ethAddr", w.host, w.httpPort))
+	addr := httpGet(w.nodeURL("/ethAddr"))
 	if addr == "" {
 		addr = "Unknown"
 	}
@@ -318,7 +318,7 @@ func (w *wizard) getEthAddr() string {
 }
 
 func (w *wizard) getTokenBalance() string {
-	b := httpGet(fmt.Sprintf("http://%v:%v/tokenBalance", w.host, w.httpPort))
+	b := httpGet(w.nodeURL("/tokenBalance"))
 	if b == "" {
 		b = "Unknown"
 	}
@@ -326,7 +326,7 @@ func (w *wizard) getTokenBalance() string {
 }
 
 func (w *wizard) getEthBalance() string {
-	e := httpGet(fmt.Sprintf("http://%v:%v/ethBalance", w.host, w.httpPort))
+	e := httpGet(w.nodeURL("/ethBalance"))
 	if e == "" {
 		e = "Unknown"
 	}
@@ -334,7 +334,7 @@ func (w *wizard) getEthBalance() string {
 }
 
 func (w *wizard) getBroadcastConfig() (*big.Rat, string) {
-	resp, err := http.Get(fmt.Sprintf("http://%v:%v/getBroadcastConfig", w.host, w.httpPort))
+	resp, err := http.Get(w.nodeURL("/getBroadcastConfig"))
 	if err != nil {
 		glog.Errorf("Error getting broadcast config: %v", err)
 		return nil, ""
@@ -361,7 +361,7 @@ func (w *wizard) getBroadcastConfig() (*big.Rat, string) {
 }
 
 func (w *wizard) getOrchestratorInfo() (*lpTypes.Transcoder, *big.Rat, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%v:%v/orchestratorInfo", w.host, w.httpPort))
+	resp, err := http.Get(w.nodeURL("/orchestratorInfo"))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -386,7 +386,7 @@ func (w *wizard) getOrchestratorInfo() (*lpTypes.Transcoder, *big.Rat, error) {
 }
 
 func (w *wizard) getDelegatorInfo() (lpTypes.Delegator, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%v:%v/delegatorInfo", w.host, w.httpPort))
+	resp, err := http.Get(w.nodeURL("/delegatorInfo"))
 	if err != nil {
 		return lpTypes.Delegator{}, err
 	}
@@ -408,7 +408,7 @@ func (w *wizard) getDelegatorInfo() (lpTypes.Delegator, error) {
 }
 
 func (w *wizard) getGasPrice() string {
-	g := httpGet(fmt.Sprintf("http://%v:%v/gasPrice", w.host, w.httpPort))
+	g := httpGet(w.nodeURL("/gasPrice"))
 	if g == "" {
 		g = "Unknown"
 	} else if g == "0" {
@@ -418,7 +418,7 @@ func (w *wizard) getGasPrice() string {
 }
 
 func (w *wizard) currentBlock() (*big.Int, error) {
-	resp, err := http.Get(fmt.Sprintf("http://%v:%v/currentBlock", w.host, w.httpPort))
+	resp, err := http.Get(w.nodeURL("/currentBlock"))
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +59,7 @@ func (w *wizard) deposit() {
 		"depositAmount": {depositAmount.String()},
 		"reserveAmount": {reserveAmount.String()},
 	}
-	fmt.Println(httpPostWithParams(fmt.Sprintf("http://%v:%v/fundDepositAndReserve", w.host, w.httpPort), form))
+	fmt.Println(httpPostWithParams(w.nodeURL("/fundDepositAndReserve"), form))
 
 	return
 }
@@ -104,7 +104,7 @@ func (w *wizard) unlock() {
 		return
 	}
 
-	fmt.Println(httpPost(fmt.Sprintf("http://%v:%v/unlock", w.host, w.httpPort)))
+	fmt.Println(httpPost(w.nodeURL("/unlock")))
 }
 
 func (w *wizard) cancelUnlock() {
@@ -138,7 +138,7 @@ func (w *wizard) cancelUnlock() {
 		return
 	}
 
-	fmt.Println(httpPost(fmt.Sprintf("http://%v:%v/cancelUnlock", w.host, w.httpPort)))
+	fmt.Println(httpPost(w.nodeURL("/cancelUnlock")))
 }
 
 func (w *wizard) withdraw() {
@@ -173,12 +173,12 @@ func (w *wizard) withdraw() {
 		return
 	}
 
-	fmt.Println(httpPost(fmt.Sprintf("http://%v:%v/withdraw", w.host, w.httpPort)))
+	fmt.Println(httpPost(w.nodeURL("/withdraw")))
 }
 
 func (w *wizard) senderInfo() (info pm.SenderInfo, err error) {
 	var resp *http.Response
-	resp, err = http.Get(fmt.Sprintf("http://%v:%v/senderInfo", w.host, w.httpPort))
+	resp, err = http.Get(w.nodeURL("/senderInfo"))
 	if err != nil {
 		return
 	}
@@ -212,7 +212,7 @@ func (w *wizard) ticketBrokerParams() (params struct {
 	UnlockPeriod *big.Int
 }, err error) {
 	var resp *http.Response
-	resp, err = http.Get(fmt.Sprintf("http://%v:%v/ticketBrokerParams", w.host, w.httpPort))
+	resp, err = http.Get(w.nodeURL("/ticketBrokerParams"))
 	if err != nil {
 		return
 	}
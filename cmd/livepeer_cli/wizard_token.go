@@ -19,9 +19,9 @@ func (w *wizard) transferTokens() {
 		"amount": {fmt.Sprintf("%v", amount.String())},
 	}
 
-	httpPostWithParams(fmt.Sprintf("http://%v:%v/transferTokens", w.host, w.httpPort), val)
+	httpPostWithParams(w.nodeURL("/transferTokens"), val)
 }
 
 func (w *wizard) requestTokens() {
-	httpPost(fmt.Sprintf("http://%v:%v/requestTokens", w.host, w.httpPort))
+	httpPost(w.nodeURL("/requestTokens"))
 }
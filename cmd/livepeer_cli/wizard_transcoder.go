@@ -19,7 +19,7 @@ import (
 const defaultRPCPort = "8935"
 
 func (w *wizard) isOrchestrator() bool {
-	isT := httpGet(fmt.Sprintf("http://%v:%v/IsOrchestrator", w.host, w.httpPort))
+	isT := httpGet(w.nodeURL("/IsOrchestrator"))
 	return isT == "true"
 }
 
@@ -154,7 +154,7 @@ func (w *wizard) activateOrchestrator() {
 		}
 	}
 
-	result, ok := httpPostWithParams(fmt.Sprintf("http://%v:%v/activateOrchestrator", w.host, w.httpPort), val)
+	result, ok := httpPostWithParams(w.nodeURL("/activateOrchestrator"), val)
 	if !ok {
 		fmt.Printf("Error activating orchestrator: %v\n", result)
 		return
@@ -168,7 +168,7 @@ func (w *wizard) setOrchestratorConfig() {
 
 	val := w.getOrchestratorConfigFormValues()
 
-	result, ok := httpPostWithParams(fmt.Sprintf("http://%v:%v/setOrchestratorConfig", w.host, w.httpPort), val)
+	result, ok := httpPostWithParams(w.nodeURL("/setOrchestratorConfig"), val)
 
 	if !ok {
 		fmt.Printf("Error applying configuration: %s\n", result)
@@ -207,7 +207,7 @@ func (w *wizard) callReward() {
 	}
 
 	fmt.Printf("Calling reward for round %v\n", c)
-	httpGet(fmt.Sprintf("http://%v:%v/reward", w.host, w.httpPort))
+	httpGet(w.nodeURL("/reward"))
 }
 
 func (w *wizard) vote() {
@@ -251,7 +251,7 @@ func (w *wizard) vote() {
 		"choiceID": {fmt.Sprintf("%v", int(choice))},
 	}
 
-	result, ok := httpPostWithParams(fmt.Sprintf("http://%v:%v/vote", w.host, w.httpPort), data)
+	result, ok := httpPostWithParams(w.nodeURL("/vote"), data)
 
 	if !ok {
 		fmt.Printf("Error voting: %s\n", result)
@@ -0,0 +1,205 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+
+	ffmpeg "github.com/livepeer/lpms/ffmpeg"
+)
+
+// Codec profiles that LPMS doesn't enumerate. LPMS only knows the H264 encoder
+// profiles, so these are numbered clear of its values and the transcoder names
+// the encoder of their codec explicitly.
+const (
+	ProfileH265Main ffmpeg.Profile = iota + 100
+	ProfileAV1Main
+	ProfileVP8
+	ProfileVP9
+	// ProfileAudioOnly renditions have no video, and pass the audio of the source through
+	ProfileAudioOnly
+)
+
+// Output formats that LPMS doesn't enumerate, likewise numbered clear of its
+// values. The transcoder names their muxer explicitly.
+const (
+	FormatWebM ffmpeg.Format = iota + 100
+)
+
+const webmExtension = ".webm"
+
+type VideoCodec int
+
+const (
+	H264 VideoCodec = iota
+	H265
+	AV1
+	VP8
+	VP9
+	NoVideo
+)
+
+// ErrCodecFormat is returned for profiles whose codec can't be muxed into their format
+var ErrCodecFormat = fmt.Errorf("codec not supported by the output format")
+
+func (c VideoCodec) String() string {
+	switch c {
+	case H264:
+		return "h264"
+	case H265:
+		return "h265"
+	case AV1:
+		return "av1"
+	case VP8:
+		return "vp8"
+	case VP9:
+		return "vp9"
+	case NoVideo:
+		return "none"
+	}
+	return fmt.Sprintf("codec_%d", int(c))
+}
+
+// ProfileCodec returns the codec of the renditions of an encoder profile
+func ProfileCodec(profile ffmpeg.Profile) VideoCodec {
+	switch profile {
+	case ProfileH265Main:
+		return H265
+	case ProfileAV1Main:
+		return AV1
+	case ProfileVP8:
+		return VP8
+	case ProfileVP9:
+		return VP9
+	case ProfileAudioOnly:
+		return NoVideo
+	}
+	return H264
+}
+
+// CodecFormat returns the only format that renditions of an encoder profile can
+// be muxed into, or FormatNone if they can be muxed into any of the formats.
+// AV1 renditions are only muxed into MP4, as MPEG-TS has no mapping for AV1, and
+// VP8 and VP9 renditions are only muxed into WebM.
+func CodecFormat(profile ffmpeg.Profile) ffmpeg.Format {
+	switch ProfileCodec(profile) {
+	case AV1:
+		return ffmpeg.FormatMP4
+	case VP8, VP9:
+		return FormatWebM
+	}
+	return ffmpeg.FormatNone
+}
+
+// ValidateProfileCodec checks that the codec of a profile can be muxed into its
+// format. WebM only takes VP8 and VP9 renditions, so audio-only renditions, whose
+// AAC audio isn't re-encoded, are muxed into MPEG-TS or MP4.
+func ValidateProfileCodec(profile ffmpeg.VideoProfile) error {
+	format := CodecFormat(profile.Profile)
+	if format != ffmpeg.FormatNone && profile.Format != format {
+		return ErrCodecFormat
+	}
+	if profile.Format == FormatWebM && format != FormatWebM {
+		return ErrCodecFormat
+	}
+	return nil
+}
+
+// IsAV1MP4 checks whether an MP4 segment has an AV1 track, from the av01 sample
+// entry that follows the header and the entry count of the sample description box
+func IsAV1MP4(data []byte) bool {
+	for i := 0; ; {
+		j := bytes.Index(data[i:], []byte("stsd"))
+		if j < 0 {
+			return false
+		}
+		entry := i + j + len("stsd") + 8 + 4
+		if entry+4 <= len(data) && bytes.Equal(data[entry:entry+4], []byte("av01")) {
+			return true
+		}
+		i += j + len("stsd")
+	}
+}
+
+type codecLevel struct {
+	idc        int
+	pictureMax int64
+	rateMax    int64
+}
+
+// HEVC levels, as the level_idc values of the codec string (30 times the level)
+// with the largest picture size and luma sample rate of each
+var hevcLevels = []codecLevel{
+	{30, 36864, 552960},
+	{60, 122880, 3686400},
+	{63, 245760, 7372800},
+	{90, 552960, 16588800},
+	{93, 983040, 33177600},
+	{120, 2228224, 66846720},
+	{123, 2228224, 133693440},
+	{150, 8912896, 267386880},
+	{153, 8912896, 534773760},
+	{156, 8912896, 1069547520},
+	{180, 35651584, 1069547520},
+	{183, 35651584, 2139095040},
+	{186, 35651584, 4278190080},
+}
+
+// AV1 levels, as the seq_level_idx values of the codec string with the largest
+// picture size and luma sample rate of each
+var av1Levels = []codecLevel{
+	{0, 147456, 4423680},
+	{1, 278784, 8363520},
+	{4, 665856, 19975680},
+	{5, 1065024, 31950720},
+	{8, 2359296, 70778880},
+	{9, 2359296, 141557760},
+	{12, 8912896, 267386880},
+	{13, 8912896, 534773760},
+	{14, 8912896, 1069547520},
+	{16, 35651584, 1069547520},
+	{17, 35651584, 2139095040},
+	{18, 35651584, 4278190080},
+}
+
+// HLSCodecs returns the CODECS attribute of the master playlist entry of a
+// rendition. It's empty for H264 renditions, which players assume by default.
+// Audio is copied from the source, so it's listed as AAC-LC. Audio-only
+// renditions only list the audio. WebM renditions aren't listed in HLS playlists.
+func HLSCodecs(profile ffmpeg.VideoProfile) string {
+	audio := "mp4a.40.2"
+	switch ProfileCodec(profile.Profile) {
+	case H265:
+		// Main profile, progressive frames; parameter sets are sent in-band (hev1)
+		return fmt.Sprintf("hev1.1.6.L%d.90,%s", codecLevelIdc(hevcLevels, profile), audio)
+	case AV1:
+		// Main profile, 8 bit
+		return fmt.Sprintf("av01.0.%02dM.08,%s", codecLevelIdc(av1Levels, profile), audio)
+	case NoVideo:
+		return audio
+	}
+	return ""
+}
+
+// codecLevelIdc returns the lowest of the levels that fits the resolution and the
+// frame rate of a profile
+func codecLevelIdc(levels []codecLevel, profile ffmpeg.VideoProfile) int {
+	w, h, err := ffmpeg.VideoProfileResolution(profile)
+	if err != nil {
+		return levels[len(levels)-1].idc
+	}
+	fps := int64(profile.Framerate)
+	if profile.FramerateDen > 1 {
+		fps = (fps + int64(profile.FramerateDen) - 1) / int64(profile.FramerateDen)
+	}
+	if fps <= 0 {
+		// passthrough frame rate, so assume a common source rate
+		fps = 30
+	}
+	picture := int64(w) * int64(h)
+	for _, l := range levels {
+		if picture <= l.pictureMax && picture*fps <= l.rateMax {
+			return l.idc
+		}
+	}
+	return levels[len(levels)-1].idc
+}
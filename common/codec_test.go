@@ -0,0 +1,111 @@
+package common
+
+import (
+	"testing"
+
+	ffmpeg "github.com/livepeer/lpms/ffmpeg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileCodec(t *testing.T) {
+	assert := assert.New(t)
+	for p := range ffmpeg.ProfileParameters {
+		assert.Equal(H264, ProfileCodec(p))
+	}
+	assert.Equal(H265, ProfileCodec(ProfileH265Main))
+	assert.Equal("h265", H265.String())
+	assert.Equal(AV1, ProfileCodec(ProfileAV1Main))
+	assert.Equal("av1", AV1.String())
+	assert.Equal(VP8, ProfileCodec(ProfileVP8))
+	assert.Equal(VP9, ProfileCodec(ProfileVP9))
+	assert.Equal("vp9", VP9.String())
+	assert.Equal(NoVideo, ProfileCodec(ProfileAudioOnly))
+}
+
+func TestCodecFormat(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(ffmpeg.FormatNone, CodecFormat(ffmpeg.ProfileH264Main))
+	assert.Equal(ffmpeg.FormatNone, CodecFormat(ProfileH265Main))
+	assert.Equal(ffmpeg.FormatMP4, CodecFormat(ProfileAV1Main))
+	assert.Equal(FormatWebM, CodecFormat(ProfileVP8))
+	assert.Equal(FormatWebM, CodecFormat(ProfileVP9))
+}
+
+func TestValidateProfileCodec(t *testing.T) {
+	assert := assert.New(t)
+	p := ffmpeg.P240p30fps16x9
+	assert.Nil(ValidateProfileCodec(p))
+	p.Profile = ProfileH265Main
+	assert.Nil(ValidateProfileCodec(p))
+
+	// AV1 is only muxed into MP4
+	p.Profile = ProfileAV1Main
+	assert.Equal(ErrCodecFormat, ValidateProfileCodec(p))
+	p.Format = ffmpeg.FormatMP4
+	assert.Nil(ValidateProfileCodec(p))
+
+	// VP8 and VP9 are only muxed into WebM, which only takes them
+	p.Profile = ProfileVP8
+	assert.Equal(ErrCodecFormat, ValidateProfileCodec(p))
+	p.Format = FormatWebM
+	assert.Nil(ValidateProfileCodec(p))
+	p.Profile = ProfileVP9
+	assert.Nil(ValidateProfileCodec(p))
+	p.Profile = ffmpeg.ProfileH264Main
+	assert.Equal(ErrCodecFormat, ValidateProfileCodec(p))
+
+	// Audio-only renditions aren't muxed into WebM
+	p.Profile = ProfileAudioOnly
+	assert.Equal(ErrCodecFormat, ValidateProfileCodec(p))
+	p.Format = ffmpeg.FormatMP4
+	assert.Nil(ValidateProfileCodec(p))
+}
+
+func TestIsAV1MP4(t *testing.T) {
+	assert := assert.New(t)
+	stsd := func(entry string) []byte {
+		// box size and type, version and flags, entry count, then the sample entry
+		b := append([]byte("\x00\x00\x00\x10stsd"), 0, 0, 0, 0, 0, 0, 0, 1)
+		return append(append(b, 0, 0, 0, 0x10), entry...)
+	}
+	assert.True(IsAV1MP4(stsd("av01")))
+	assert.True(IsAV1MP4(append(stsd("mp4a"), stsd("av01")...)))
+	assert.False(IsAV1MP4(stsd("avc1")))
+	assert.False(IsAV1MP4([]byte("stsd")))
+	assert.False(IsAV1MP4(nil))
+}
+
+func TestHLSCodecs(t *testing.T) {
+	assert := assert.New(t)
+
+	// H264 renditions have no codecs
+	assert.Empty(HLSCodecs(ffmpeg.P720p30fps16x9))
+
+	hevc := func(p ffmpeg.VideoProfile) ffmpeg.VideoProfile {
+		p.Profile = ProfileH265Main
+		return p
+	}
+	assert.Equal("hev1.1.6.L60.90,mp4a.40.2", HLSCodecs(hevc(ffmpeg.P240p30fps16x9)))
+	assert.Equal("hev1.1.6.L93.90,mp4a.40.2", HLSCodecs(hevc(ffmpeg.P720p30fps16x9)))
+	assert.Equal("hev1.1.6.L120.90,mp4a.40.2", HLSCodecs(hevc(ffmpeg.P720p60fps16x9)))
+
+	// 1080p60 needs level 4.1; fractional rates round up
+	p := hevc(ffmpeg.VideoProfile{Resolution: "1920x1080", Bitrate: "6000k", Framerate: 60000, FramerateDen: 1001})
+	assert.Equal("hev1.1.6.L123.90,mp4a.40.2", HLSCodecs(p))
+
+	// passthrough frame rate is taken as 30fps
+	p.Framerate, p.FramerateDen = 0, 0
+	assert.Equal("hev1.1.6.L120.90,mp4a.40.2", HLSCodecs(p))
+
+	av1 := func(p ffmpeg.VideoProfile) ffmpeg.VideoProfile {
+		p.Profile = ProfileAV1Main
+		return p
+	}
+	assert.Equal("av01.0.00M.08,mp4a.40.2", HLSCodecs(av1(ffmpeg.P240p30fps16x9)))
+	assert.Equal("av01.0.01M.08,mp4a.40.2", HLSCodecs(av1(ffmpeg.P360p30fps16x9)))
+	assert.Equal("av01.0.08M.08,mp4a.40.2", HLSCodecs(av1(ffmpeg.P720p60fps16x9)))
+	assert.Equal("av01.0.09M.08,mp4a.40.2", HLSCodecs(av1(ffmpeg.VideoProfile{Resolution: "1920x1080", Bitrate: "6000k", Framerate: 60})))
+
+	// Audio-only renditions only list the audio
+	assert.Equal("mp4a.40.2", HLSCodecs(ffmpeg.VideoProfile{Resolution: "0x0", Bitrate: "128k", Profile: ProfileAudioOnly}))
+}
@@ -9,13 +9,14 @@ import (
 	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/golang/glog"
 	"github.com/livepeer/go-livepeer/eth/blockwatch"
 	"github.com/livepeer/go-livepeer/pm"
-	_ "github.com/mattn/go-sqlite3"
+	sqlite3 "github.com/mattn/go-sqlite3"
 	"github.com/pkg/errors"
 )
 
@@ -34,13 +35,22 @@ type DB struct {
 	withdrawableUnbondingLocks       *sql.Stmt
 	insertWinningTicket              *sql.Stmt
 	selectEarliestWinningTicket      *sql.Stmt
+	selectEarliestWinningTickets     *sql.Stmt
 	winningTicketCount               *sql.Stmt
+	winningTickets                   *sql.Stmt
 	markWinningTicketRedeemed        *sql.Stmt
+	markWinningTicketFailed          *sql.Stmt
 	removeWinningTicket              *sql.Stmt
+	winningTicketSenders             *sql.Stmt
+	winningTicketFaceValues          *sql.Stmt
 	insertMiniHeader                 *sql.Stmt
 	findLatestMiniHeader             *sql.Stmt
 	findAllMiniHeadersSortedByNumber *sql.Stmt
 	deleteMiniHeader                 *sql.Stmt
+	insertVerificationResult         *sql.Stmt
+	deleteVerificationResults        *sql.Stmt
+	insertTranscodeReceipt           *sql.Stmt
+	insertEarning                    *sql.Stmt
 }
 
 // DBOrch is the type binding for a row result from the orchestrators table
@@ -61,6 +71,84 @@ type DBUnbondingLock struct {
 	WithdrawRound int64
 }
 
+// DBVerificationResult is the type binding for a row result from the verificationResults table
+type DBVerificationResult struct {
+	CreatedAt    time.Time
+	ManifestID   string
+	SeqNo        uint64
+	Orchestrator string // Service URI of the orchestrator
+	Score        float64
+	Error        string   `json:",omitempty"`
+	URIs         []string // Rendition locations
+}
+
+// DBVerificationFilter is an object used to attach a filter to a verification results query
+type DBVerificationFilter struct {
+	ManifestID   string
+	Orchestrator string
+	Since        time.Time // Inclusive, ignored if zero
+	Until        time.Time // Exclusive, ignored if zero
+	Limit        int       // Maximum number of results, all results if zero
+}
+
+// DBTranscodeReceipt is the type binding for a row result from the transcodeReceipts table
+type DBTranscodeReceipt struct {
+	CreatedAt    time.Time
+	ManifestID   string
+	Orchestrator string // Service URI of the orchestrator
+	StartSeq     int64
+	EndSeq       int64
+	Root         []byte // Merkle root signed by the orchestrator
+	Sig          []byte
+	// Segments received by the broadcaster since the previous receipt
+	Segments []DBReceiptSegment
+	// Whether the signature is valid and the root matches the segments received
+	Verified bool
+}
+
+// DBReceiptSegment is a segment covered by a transcode receipt
+type DBReceiptSegment struct {
+	SeqNo int64
+	Hash  []byte // Hash of the concatenated rendition hashes
+}
+
+// DBTranscodeReceiptFilter is an object used to attach a filter to a transcode receipts query
+type DBTranscodeReceiptFilter struct {
+	ManifestID   string
+	Orchestrator string
+	SeqNo        *int64 // Only receipts that cover the segment, ignored if nil
+	Limit        int    // Maximum number of results, all results if zero
+}
+
+// Kinds of the earnings of a node
+const (
+	// EarningFees are the face values of the winning tickets redeemed by the node
+	EarningFees = "fees"
+	// EarningReward are the LPT rewards minted for the orchestrator and its delegators
+	EarningReward = "reward"
+	// EarningBond are the LPT bonded by the node
+	EarningBond = "bond"
+)
+
+// DBEarning is the type binding for a row result from the earnings table
+type DBEarning struct {
+	Kind   string
+	Amount *big.Int
+	// Sender of the tickets of fees, or new delegate of bonds
+	Counterparty ethcommon.Address
+	BlockNumber  int64
+	BlockTime    time.Time
+	TxHash       ethcommon.Hash
+	LogIndex     int64
+}
+
+// DBEarningsFilter is an object used to attach a filter to an earnings query
+type DBEarningsFilter struct {
+	Kind  string
+	Since time.Time // Inclusive, ignored if zero
+	Until time.Time // Exclusive, ignored if zero
+}
+
 // DBOrchFilter is an object used to attach a filter to a selectOrch query
 type DBOrchFilter struct {
 	MaxPrice     *big.Rat
@@ -68,7 +156,7 @@ type DBOrchFilter struct {
 	Addresses    []ethcommon.Address
 }
 
-var LivepeerDBVersion = 1
+var LivepeerDBVersion = 5
 
 var ErrDBTooNew = errors.New("DB Too New")
 
@@ -131,7 +219,12 @@ var schema = `
 		creationRoundBlockHash STRING,
 		paramsExpirationBlock int64,
 		redeemedAt DATETIME,
-		txHash STRING
+		txHash STRING,
+		payoutRecipient STRING,
+		payoutShare int64,
+		ticketVersion int64 DEFAULT 0,
+		redeemAttempts int64 DEFAULT 0,
+		redeemRetryAt int64 DEFAULT 0
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_ticketqueue_sender ON ticketQueue(sender);
@@ -140,12 +233,73 @@ var schema = `
 		number int64,
 		parent STRING,
 		hash STRING PRIMARY KEY,
-		logs BLOB
+		logs BLOB,
+		l1BlockNumber int64
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_blockheaders_number ON blockheaders(number);
+
+	CREATE TABLE IF NOT EXISTS verificationResults (
+		createdAt DATETIME,
+		manifestID STRING,
+		seqNo int64,
+		orchestrator STRING,
+		score REAL,
+		error STRING,
+		uris STRING
+	);
+	CREATE INDEX IF NOT EXISTS idx_verificationresults_createdat ON verificationResults(createdAt);
+	CREATE INDEX IF NOT EXISTS idx_verificationresults_manifestid ON verificationResults(manifestID);
+	CREATE INDEX IF NOT EXISTS idx_verificationresults_orchestrator ON verificationResults(orchestrator);
+
+	CREATE TABLE IF NOT EXISTS transcodeReceipts (
+		createdAt DATETIME,
+		manifestID STRING,
+		orchestrator STRING,
+		startSeq int64,
+		endSeq int64,
+		root BLOB,
+		sig BLOB,
+		segments STRING,
+		verified int
+	);
+	CREATE INDEX IF NOT EXISTS idx_transcodereceipts_manifestid ON transcodeReceipts(manifestID, startSeq, endSeq);
+
+	CREATE TABLE IF NOT EXISTS earnings (
+		kind STRING,
+		amount TEXT,
+		counterparty STRING,
+		blockNumber int64,
+		blockTime DATETIME,
+		txHash STRING,
+		logIndex int64,
+		PRIMARY KEY(txHash, logIndex)
+	);
+	CREATE INDEX IF NOT EXISTS idx_earnings_blocktime ON earnings(blockTime);
 `
 
+// migrations upgrade the schema of an existing DB to each version from the previous one
+var migrations = map[int]string{
+	// Payout splits of winning tickets
+	2: `
+	ALTER TABLE ticketQueue ADD COLUMN payoutRecipient STRING;
+	ALTER TABLE ticketQueue ADD COLUMN payoutShare int64;
+	`,
+	// Versions of winning tickets
+	3: `
+	ALTER TABLE ticketQueue ADD COLUMN ticketVersion int64 DEFAULT 0;
+	`,
+	// Redemption retries of winning tickets
+	4: `
+	ALTER TABLE ticketQueue ADD COLUMN redeemAttempts int64 DEFAULT 0;
+	ALTER TABLE ticketQueue ADD COLUMN redeemRetryAt int64 DEFAULT 0;
+	`,
+	// L1 block numbers of the headers of L2 chains
+	5: `
+	ALTER TABLE blockheaders ADD COLUMN l1BlockNumber int64;
+	`,
+}
+
 func NewDBOrch(ethereumAddr string, serviceURI string, pricePerPixel int64, activationRound int64, deactivationRound int64, stake int64) *DBOrch {
 	return &DBOrch{
 		ServiceURI:        serviceURI,
@@ -197,6 +351,19 @@ func InitDB(dbPath string) (*DB, error) {
 	} else if dbVersion < LivepeerDBVersion {
 		// Upgrade stepwise up to the correct version using the migration
 		// procedure for each version
+		for v := dbVersion + 1; v <= LivepeerDBVersion; v++ {
+			if _, err := db.Exec(migrations[v]); err != nil {
+				glog.Errorf("Unable to upgrade DB to version=%v err=%v", v, err)
+				d.Close()
+				return nil, err
+			}
+			if _, err := db.Exec("UPDATE kv SET value=? WHERE key='dbVersion'", v); err != nil {
+				glog.Errorf("Unable to update DB version to version=%v err=%v", v, err)
+				d.Close()
+				return nil, err
+			}
+			glog.Infof("Upgraded DB to version=%v", v)
+		}
 	} else if dbVersion == LivepeerDBVersion {
 		// all good; nothing to do
 	}
@@ -292,8 +459,8 @@ func InitDB(dbPath string) (*DB, error) {
 
 	// Winning tickets prepared statements
 	stmt, err = db.Prepare(`
-	INSERT INTO ticketQueue(sender, recipient, faceValue, winProb, senderNonce, recipientRand, recipientRandHash, sig, creationRound, creationRoundBlockHash, paramsExpirationBlock)
-	VALUES(:sender, :recipient, :faceValue, :winProb, :senderNonce, :recipientRand, :recipientRandHash, :sig, :creationRound, :creationRoundBlockHash, :paramsExpirationBlock)
+	INSERT INTO ticketQueue(sender, recipient, faceValue, winProb, senderNonce, recipientRand, recipientRandHash, sig, creationRound, creationRoundBlockHash, paramsExpirationBlock, payoutRecipient, payoutShare, ticketVersion)
+	VALUES(:sender, :recipient, :faceValue, :winProb, :senderNonce, :recipientRand, :recipientRandHash, :sig, :creationRound, :creationRoundBlockHash, :paramsExpirationBlock, :payoutRecipient, :payoutShare, :ticketVersion)
 	`)
 	if err != nil {
 		glog.Error("Unable to prepare insertWinningTicket ", err)
@@ -303,7 +470,7 @@ func InitDB(dbPath string) (*DB, error) {
 	d.insertWinningTicket = stmt
 
 	// Select earliest ticket
-	stmt, err = db.Prepare("SELECT sender, recipient, faceValue, winProb, senderNonce, recipientRand, recipientRandHash, sig, creationRound, creationRoundBlockHash, paramsExpirationBlock FROM ticketQueue WHERE sender=? AND redeemedAt IS NULL AND txHash IS NULL ORDER BY createdAt ASC LIMIT 1")
+	stmt, err = db.Prepare("SELECT sender, recipient, faceValue, winProb, senderNonce, recipientRand, recipientRandHash, sig, creationRound, creationRoundBlockHash, paramsExpirationBlock, payoutRecipient, payoutShare, ticketVersion, redeemAttempts FROM ticketQueue WHERE sender=? AND redeemedAt IS NULL AND txHash IS NULL AND redeemRetryAt <= ? ORDER BY createdAt ASC LIMIT 1")
 	if err != nil {
 		glog.Error("Unable to prepare selectEarliestWinningTicket ", err)
 		d.Close()
@@ -311,6 +478,23 @@ func InitDB(dbPath string) (*DB, error) {
 	}
 	d.selectEarliestWinningTicket = stmt
 
+	// Select earliest tickets
+	stmt, err = db.Prepare("SELECT sender, recipient, faceValue, winProb, senderNonce, recipientRand, recipientRandHash, sig, creationRound, creationRoundBlockHash, paramsExpirationBlock, payoutRecipient, payoutShare, ticketVersion, redeemAttempts FROM ticketQueue WHERE sender=? AND redeemedAt IS NULL AND txHash IS NULL AND paramsExpirationBlock <= ? AND redeemRetryAt <= ? ORDER BY createdAt ASC LIMIT ?")
+	if err != nil {
+		glog.Error("Unable to prepare selectEarliestWinningTickets ", err)
+		d.Close()
+		return nil, err
+	}
+	d.selectEarliestWinningTickets = stmt
+
+	stmt, err = db.Prepare("SELECT sender, recipient, faceValue, winProb, senderNonce, recipientRand, recipientRandHash, sig, creationRound, creationRoundBlockHash, paramsExpirationBlock, payoutRecipient, payoutShare, ticketVersion, redeemAttempts FROM ticketQueue WHERE sender=? AND redeemedAt IS NULL AND txHash IS NULL ORDER BY createdAt ASC")
+	if err != nil {
+		glog.Error("Unable to prepare winningTickets ", err)
+		d.Close()
+		return nil, err
+	}
+	d.winningTickets = stmt
+
 	stmt, err = db.Prepare("SELECT count(sig) FROM ticketQueue WHERE sender=? AND redeemedAt IS NULL AND txHash IS NULL")
 	if err != nil {
 		glog.Error("Unable to prepare winningTicketCount ", err)
@@ -337,8 +521,35 @@ func InitDB(dbPath string) (*DB, error) {
 	}
 	d.markWinningTicketRedeemed = stmt
 
+	// Mark ticket redemption failed
+	stmt, err = db.Prepare("UPDATE ticketQueue SET redeemAttempts=?, redeemRetryAt=? WHERE sig=?")
+	if err != nil {
+		glog.Error("Unable to prepare markWinningTicketFailed ", err)
+		d.Close()
+		return nil, err
+	}
+	d.markWinningTicketFailed = stmt
+
+	// Senders with unredeemed tickets
+	stmt, err = db.Prepare("SELECT DISTINCT sender FROM ticketQueue WHERE redeemedAt IS NULL AND txHash IS NULL")
+	if err != nil {
+		glog.Error("Unable to prepare winningTicketSenders ", err)
+		d.Close()
+		return nil, err
+	}
+	d.winningTicketSenders = stmt
+
+	// Face values of unredeemed tickets
+	stmt, err = db.Prepare("SELECT faceValue FROM ticketQueue WHERE sender=? AND redeemedAt IS NULL AND txHash IS NULL")
+	if err != nil {
+		glog.Error("Unable to prepare winningTicketFaceValues ", err)
+		d.Close()
+		return nil, err
+	}
+	d.winningTicketFaceValues = stmt
+
 	// Insert block header
-	stmt, err = db.Prepare("INSERT INTO blockheaders(number, parent, hash, logs) VALUES(?, ?, ?, ?)")
+	stmt, err = db.Prepare("INSERT INTO blockheaders(number, parent, hash, logs, l1BlockNumber) VALUES(?, ?, ?, ?, ?)")
 	if err != nil {
 		glog.Error("Unable to prepare insertMiniHeader ", err)
 		d.Close()
@@ -347,7 +558,7 @@ func InitDB(dbPath string) (*DB, error) {
 	d.insertMiniHeader = stmt
 
 	// Find the latest block header
-	stmt, err = db.Prepare("SELECT * FROM blockheaders ORDER BY number DESC LIMIT 1")
+	stmt, err = db.Prepare("SELECT number, parent, hash, logs, l1BlockNumber FROM blockheaders ORDER BY number DESC LIMIT 1")
 	if err != nil {
 		glog.Error("Unable to prepare findLatestMiniHeader ", err)
 		d.Close()
@@ -356,7 +567,7 @@ func InitDB(dbPath string) (*DB, error) {
 	d.findLatestMiniHeader = stmt
 
 	// Find all block headers sorted by number
-	stmt, err = db.Prepare("SELECT * FROM blockheaders ORDER BY number DESC")
+	stmt, err = db.Prepare("SELECT number, parent, hash, logs, l1BlockNumber FROM blockheaders ORDER BY number DESC")
 	if err != nil {
 		glog.Error("Unable to prepare findAllMiniHeadersSortedByNumber ", err)
 		d.Close()
@@ -373,6 +584,42 @@ func InitDB(dbPath string) (*DB, error) {
 	}
 	d.deleteMiniHeader = stmt
 
+	// Insert verification result
+	stmt, err = db.Prepare("INSERT INTO verificationResults(createdAt, manifestID, seqNo, orchestrator, score, error, uris) VALUES(?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		glog.Error("Unable to prepare insertVerificationResult ", err)
+		d.Close()
+		return nil, err
+	}
+	d.insertVerificationResult = stmt
+
+	// Delete verification results
+	stmt, err = db.Prepare("DELETE FROM verificationResults WHERE createdAt < ?")
+	if err != nil {
+		glog.Error("Unable to prepare deleteVerificationResults ", err)
+		d.Close()
+		return nil, err
+	}
+	d.deleteVerificationResults = stmt
+
+	// Insert transcode receipt
+	stmt, err = db.Prepare("INSERT INTO transcodeReceipts(createdAt, manifestID, orchestrator, startSeq, endSeq, root, sig, segments, verified) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		glog.Error("Unable to prepare insertTranscodeReceipt ", err)
+		d.Close()
+		return nil, err
+	}
+	d.insertTranscodeReceipt = stmt
+
+	// Insert earning, ignoring the logs that are indexed again
+	stmt, err = db.Prepare("INSERT OR IGNORE INTO earnings(kind, amount, counterparty, blockNumber, blockTime, txHash, logIndex) VALUES(?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		glog.Error("Unable to prepare insertEarning ", err)
+		d.Close()
+		return nil, err
+	}
+	d.insertEarning = stmt
+
 	glog.V(DEBUG).Info("Initialized DB node")
 	return &d, nil
 }
@@ -409,15 +656,30 @@ func (db *DB) Close() {
 	if db.selectEarliestWinningTicket != nil {
 		db.selectEarliestWinningTicket.Close()
 	}
+	if db.selectEarliestWinningTickets != nil {
+		db.selectEarliestWinningTickets.Close()
+	}
 	if db.winningTicketCount != nil {
 		db.winningTicketCount.Close()
 	}
+	if db.winningTickets != nil {
+		db.winningTickets.Close()
+	}
 	if db.markWinningTicketRedeemed != nil {
 		db.markWinningTicketRedeemed.Close()
 	}
+	if db.markWinningTicketFailed != nil {
+		db.markWinningTicketFailed.Close()
+	}
 	if db.removeWinningTicket != nil {
 		db.removeWinningTicket.Close()
 	}
+	if db.winningTicketSenders != nil {
+		db.winningTicketSenders.Close()
+	}
+	if db.winningTicketFaceValues != nil {
+		db.winningTicketFaceValues.Close()
+	}
 	if db.insertMiniHeader != nil {
 		db.insertMiniHeader.Close()
 	}
@@ -430,6 +692,18 @@ func (db *DB) Close() {
 	if db.deleteMiniHeader != nil {
 		db.deleteMiniHeader.Close()
 	}
+	if db.insertVerificationResult != nil {
+		db.insertVerificationResult.Close()
+	}
+	if db.deleteVerificationResults != nil {
+		db.deleteVerificationResults.Close()
+	}
+	if db.insertTranscodeReceipt != nil {
+		db.insertTranscodeReceipt.Close()
+	}
+	if db.insertEarning != nil {
+		db.insertEarning.Close()
+	}
 	if db.dbh != nil {
 		db.dbh.Close()
 	}
@@ -448,6 +722,23 @@ func (db *DB) LastSeenBlock() (*big.Int, error) {
 	return header.Number, nil
 }
 
+// LastSeenL1Block returns the last L1 block number stored by the DB, which is the number of the
+// last block on L1 chains
+func (db *DB) LastSeenL1Block() (*big.Int, error) {
+	header, err := db.FindLatestMiniHeader()
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, nil
+	}
+	if header.L1BlockNumber != nil {
+		return header.L1BlockNumber, nil
+	}
+
+	return header.Number, nil
+}
+
 func (db *DB) ChainID() (*big.Int, error) {
 	idString, err := db.selectKVStore("chainID")
 	if err != nil {
@@ -684,6 +975,13 @@ func (db *DB) StoreWinningTicket(ticket *pm.SignedTicket) error {
 		return errors.New("cannot store nil recipientRand")
 	}
 
+	var payoutRecipient sql.NullString
+	var payoutShare sql.NullInt64
+	if ticket.PayoutSplit != nil {
+		payoutRecipient = sql.NullString{String: ticket.PayoutSplit.Recipient.Hex(), Valid: true}
+		payoutShare = sql.NullInt64{Int64: ticket.PayoutSplit.Share, Valid: true}
+	}
+
 	_, err := db.insertWinningTicket.Exec(
 		sql.Named("sender", ticket.Sender.Hex()),
 		sql.Named("recipient", ticket.Recipient.Hex()),
@@ -696,8 +994,14 @@ func (db *DB) StoreWinningTicket(ticket *pm.SignedTicket) error {
 		sql.Named("creationRound", ticket.CreationRound),
 		sql.Named("creationRoundBlockHash", ticket.CreationRoundBlockHash.Hex()),
 		sql.Named("paramsExpirationBlock", ticket.ParamsExpirationBlock.Int64()),
+		sql.Named("payoutRecipient", payoutRecipient),
+		sql.Named("payoutShare", payoutShare),
+		sql.Named("ticketVersion", ticket.Version),
 	)
 
+	if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+		return pm.ErrTicketStored
+	}
 	if err != nil {
 		return errors.Wrapf(err, "failed inserting winning ticket sender=%v", ticket.Sender.Hex())
 	}
@@ -728,6 +1032,30 @@ func (db *DB) MarkWinningTicketRedeemed(ticket *pm.SignedTicket, txHash ethcommo
 	return nil
 }
 
+// MarkWinningTicketFailed stores the number of failed redemption attempts of a ticket and
+// the time before which its redemption is not retried
+func (db *DB) MarkWinningTicketFailed(ticket *pm.SignedTicket, attempts int, retryAt time.Time) error {
+	if ticket == nil || ticket.Ticket == nil {
+		return errors.New("cannot update nil ticket")
+	}
+	if ticket.Sig == nil {
+		return errors.New("cannot update nil sig")
+	}
+
+	res, err := db.markWinningTicketFailed.Exec(attempts, retryAt.Unix(), ticket.Sig)
+	if err != nil {
+		return errors.Wrapf(err, "failed marking winning ticket redemption as failed sender=%v", ticket.Sender.Hex())
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("no record found for sig=0x%x", ticket.Sig)
+	}
+	return nil
+}
+
 // RemoveWinningTicket removes a ticket
 func (db *DB) RemoveWinningTicket(ticket *pm.SignedTicket) error {
 	if ticket == nil || ticket.Ticket == nil {
@@ -747,9 +1075,59 @@ func (db *DB) RemoveWinningTicket(ticket *pm.SignedTicket) error {
 }
 
 // SelectEarliestWinningTicket selects the earliest stored winning ticket for a 'sender'
-// which is not yet redeemed
+// which is not yet redeemed and not waiting to retry a failed redemption
 func (db *DB) SelectEarliestWinningTicket(sender ethcommon.Address) (*pm.SignedTicket, error) {
-	row := db.selectEarliestWinningTicket.QueryRow(sender.Hex())
+	row := db.selectEarliestWinningTicket.QueryRow(sender.Hex(), time.Now().Unix())
+	ticket, err := scanWinningTicket(row)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("could not retrieve earliest ticket err=%v", err)
+		}
+		// If there is no result return no error, just nil value
+		return nil, nil
+	}
+	return ticket, nil
+}
+
+// SelectEarliestWinningTickets selects up to 'limit' of the earliest stored winning tickets
+// for a 'sender' which are not yet redeemed, not waiting to retry a failed redemption and
+// whose params expired at or before 'block'
+func (db *DB) SelectEarliestWinningTickets(sender ethcommon.Address, block *big.Int, limit int) ([]*pm.SignedTicket, error) {
+	rows, err := db.selectEarliestWinningTickets.Query(sender.Hex(), block.Int64(), time.Now().Unix(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve earliest tickets err=%v", err)
+	}
+	defer rows.Close()
+	var tickets []*pm.SignedTicket
+	for rows.Next() {
+		ticket, err := scanWinningTicket(rows)
+		if err != nil {
+			return nil, fmt.Errorf("could not retrieve earliest tickets err=%v", err)
+		}
+		tickets = append(tickets, ticket)
+	}
+	return tickets, rows.Err()
+}
+
+// WinningTickets returns the non-redeemed winning tickets for a 'sender', from the earliest to the latest
+func (db *DB) WinningTickets(sender ethcommon.Address) ([]*pm.SignedTicket, error) {
+	rows, err := db.winningTickets.Query(sender.Hex())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed selecting winning tickets")
+	}
+	defer rows.Close()
+	var tickets []*pm.SignedTicket
+	for rows.Next() {
+		ticket, err := scanWinningTicket(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed selecting winning tickets")
+		}
+		tickets = append(tickets, ticket)
+	}
+	return tickets, rows.Err()
+}
+
+func scanWinningTicket(row interface{ Scan(...interface{}) error }) (*pm.SignedTicket, error) {
 	var (
 		senderString           string
 		recipient              string
@@ -762,18 +1140,26 @@ func (db *DB) SelectEarliestWinningTicket(sender ethcommon.Address) (*pm.SignedT
 		creationRound          int64
 		creationRoundBlockHash string
 		paramsExpirationBlock  int64
+		payoutRecipient        sql.NullString
+		payoutShare            sql.NullInt64
+		ticketVersion          int64
+		redeemAttempts         int64
 	)
-	if err := row.Scan(&senderString, &recipient, &faceValue, &winProb, &senderNonce, &recipientRand, &recipientRandHash, &sig, &creationRound, &creationRoundBlockHash, &paramsExpirationBlock); err != nil {
-		if err.Error() != "sql: no rows in result set" {
-			return nil, fmt.Errorf("could not retrieve earliest ticket err=%v", err)
+	if err := row.Scan(&senderString, &recipient, &faceValue, &winProb, &senderNonce, &recipientRand, &recipientRandHash, &sig, &creationRound, &creationRoundBlockHash, &paramsExpirationBlock, &payoutRecipient, &payoutShare, &ticketVersion, &redeemAttempts); err != nil {
+		return nil, err
+	}
+
+	var payoutSplit *pm.PayoutSplit
+	if payoutRecipient.Valid {
+		payoutSplit = &pm.PayoutSplit{
+			Recipient: ethcommon.HexToAddress(payoutRecipient.String),
+			Share:     payoutShare.Int64,
 		}
-		// If there is no result return no error, just nil value
-		return nil, nil
 	}
 
 	return &pm.SignedTicket{
 		Ticket: &pm.Ticket{
-			Sender:                 sender,
+			Sender:                 ethcommon.HexToAddress(senderString),
 			Recipient:              ethcommon.HexToAddress(recipient),
 			FaceValue:              new(big.Int).SetBytes(faceValue),
 			WinProb:                new(big.Int).SetBytes(winProb),
@@ -782,9 +1168,12 @@ func (db *DB) SelectEarliestWinningTicket(sender ethcommon.Address) (*pm.SignedT
 			CreationRound:          creationRound,
 			CreationRoundBlockHash: ethcommon.HexToHash(creationRoundBlockHash),
 			ParamsExpirationBlock:  big.NewInt(paramsExpirationBlock),
+			PayoutSplit:            payoutSplit,
+			Version:                uint32(ticketVersion),
 		},
-		Sig:           sig,
-		RecipientRand: new(big.Int).SetBytes(recipientRand),
+		Sig:            sig,
+		RecipientRand:  new(big.Int).SetBytes(recipientRand),
+		RedeemAttempts: int(redeemAttempts),
 	}, nil
 }
 
@@ -803,6 +1192,42 @@ func (db *DB) WinningTicketCount(sender ethcommon.Address) (int, error) {
 	return int(count64), nil
 }
 
+// WinningTicketSenders returns the senders with non-redeemed winning tickets
+func (db *DB) WinningTicketSenders() ([]ethcommon.Address, error) {
+	rows, err := db.winningTicketSenders.Query()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed selecting winning ticket senders")
+	}
+	defer rows.Close()
+	var senders []ethcommon.Address
+	for rows.Next() {
+		var sender string
+		if err := rows.Scan(&sender); err != nil {
+			return nil, errors.Wrap(err, "failed selecting winning ticket senders")
+		}
+		senders = append(senders, ethcommon.HexToAddress(sender))
+	}
+	return senders, rows.Err()
+}
+
+// WinningTicketFaceValue returns the sum of the face values of the non-redeemed winning tickets for a 'sender'
+func (db *DB) WinningTicketFaceValue(sender ethcommon.Address) (*big.Int, error) {
+	rows, err := db.winningTicketFaceValues.Query(sender.Hex())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed selecting winning ticket face values")
+	}
+	defer rows.Close()
+	total := big.NewInt(0)
+	for rows.Next() {
+		var faceValue []byte
+		if err := rows.Scan(&faceValue); err != nil {
+			return nil, errors.Wrap(err, "failed selecting winning ticket face values")
+		}
+		total.Add(total, new(big.Int).SetBytes(faceValue))
+	}
+	return total, rows.Err()
+}
+
 func buildSelectOrchsQuery(filter *DBOrchFilter) (string, error) {
 	query := "SELECT ethereumAddr, serviceURI, pricePerPixel, activationRound, deactivationRound, stake FROM orchestrators "
 	fil, err := buildFilterOrchsQuery(filter)
@@ -848,16 +1273,317 @@ func buildFilterOrchsQuery(filter *DBOrchFilter) (string, error) {
 	return qry, nil
 }
 
+// InsertVerificationResult stores the outcome of the verification of a segment
+func (db *DB) InsertVerificationResult(res *DBVerificationResult) error {
+	if db == nil {
+		return nil
+	}
+	if res == nil {
+		return errors.New("cannot insert nil verification result")
+	}
+
+	uris, err := json.Marshal(res.URIs)
+	if err != nil {
+		return err
+	}
+	createdAt := res.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	_, err = db.insertVerificationResult.Exec(createdAt.UTC(), res.ManifestID, res.SeqNo, res.Orchestrator, res.Score, res.Error, string(uris))
+	if err != nil {
+		glog.Errorf("db: Unable to insert verification result manifestID=%v seqNo=%v err=%v", res.ManifestID, res.SeqNo, err)
+	}
+	return err
+}
+
+// DeleteVerificationResults deletes the verification results stored before a time and
+// returns how many were deleted
+func (db *DB) DeleteVerificationResults(before time.Time) (int64, error) {
+	if db == nil {
+		return 0, nil
+	}
+
+	res, err := db.deleteVerificationResults.Exec(before.UTC())
+	if err != nil {
+		glog.Errorf("db: Unable to delete verification results before=%v err=%v", before, err)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// VerificationResults returns the stored verification results matching the filter, most recent first
+func (db *DB) VerificationResults(filter *DBVerificationFilter) ([]*DBVerificationResult, error) {
+	if db == nil {
+		return nil, nil
+	}
+
+	qry, args := buildVerificationResultsQuery(filter)
+	rows, err := db.dbh.Query(qry, args...)
+	if err != nil {
+		glog.Error("db: Unable to get verification results ", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*DBVerificationResult{}
+	for rows.Next() {
+		var (
+			res  DBVerificationResult
+			uris string
+		)
+		if err := rows.Scan(&res.CreatedAt, &res.ManifestID, &res.SeqNo, &res.Orchestrator, &res.Score, &res.Error, &uris); err != nil {
+			glog.Error("db: Unable to fetch verification result ", err)
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(uris), &res.URIs); err != nil {
+			glog.Error("db: Unable to decode verification result URIs ", err)
+			return nil, err
+		}
+		results = append(results, &res)
+	}
+	return results, rows.Err()
+}
+
+func buildVerificationResultsQuery(filter *DBVerificationFilter) (string, []interface{}) {
+	qry := "SELECT createdAt, manifestID, seqNo, orchestrator, score, error, uris FROM verificationResults"
+	var (
+		conds []string
+		args  []interface{}
+	)
+	limit := -1
+	if filter != nil {
+		if filter.ManifestID != "" {
+			conds = append(conds, "manifestID = ?")
+			args = append(args, filter.ManifestID)
+		}
+		if filter.Orchestrator != "" {
+			conds = append(conds, "orchestrator = ?")
+			args = append(args, filter.Orchestrator)
+		}
+		if !filter.Since.IsZero() {
+			conds = append(conds, "createdAt >= ?")
+			args = append(args, filter.Since.UTC())
+		}
+		if !filter.Until.IsZero() {
+			conds = append(conds, "createdAt < ?")
+			args = append(args, filter.Until.UTC())
+		}
+		if filter.Limit > 0 {
+			limit = filter.Limit
+		}
+	}
+	if len(conds) > 0 {
+		qry += " WHERE " + strings.Join(conds, " AND ")
+	}
+	qry += " ORDER BY createdAt DESC LIMIT ?"
+	args = append(args, limit)
+	return qry, args
+}
+
+// InsertTranscodeReceipt stores a transcode receipt sent by an orchestrator
+func (db *DB) InsertTranscodeReceipt(r *DBTranscodeReceipt) error {
+	if db == nil {
+		return nil
+	}
+	if r == nil {
+		return errors.New("cannot insert nil transcode receipt")
+	}
+
+	segments, err := json.Marshal(r.Segments)
+	if err != nil {
+		return err
+	}
+	createdAt := r.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	_, err = db.insertTranscodeReceipt.Exec(createdAt.UTC(), r.ManifestID, r.Orchestrator, r.StartSeq, r.EndSeq, r.Root, r.Sig, string(segments), r.Verified)
+	if err != nil {
+		glog.Errorf("db: Unable to insert transcode receipt manifestID=%v startSeq=%v endSeq=%v err=%v", r.ManifestID, r.StartSeq, r.EndSeq, err)
+	}
+	return err
+}
+
+// TranscodeReceipts returns the stored transcode receipts matching the filter, most recent first
+func (db *DB) TranscodeReceipts(filter *DBTranscodeReceiptFilter) ([]*DBTranscodeReceipt, error) {
+	if db == nil {
+		return nil, nil
+	}
+
+	qry, args := buildTranscodeReceiptsQuery(filter)
+	rows, err := db.dbh.Query(qry, args...)
+	if err != nil {
+		glog.Error("db: Unable to get transcode receipts ", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	receipts := []*DBTranscodeReceipt{}
+	for rows.Next() {
+		var (
+			r        DBTranscodeReceipt
+			segments string
+		)
+		if err := rows.Scan(&r.CreatedAt, &r.ManifestID, &r.Orchestrator, &r.StartSeq, &r.EndSeq, &r.Root, &r.Sig, &segments, &r.Verified); err != nil {
+			glog.Error("db: Unable to fetch transcode receipt ", err)
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(segments), &r.Segments); err != nil {
+			glog.Error("db: Unable to decode transcode receipt segments ", err)
+			return nil, err
+		}
+		receipts = append(receipts, &r)
+	}
+	return receipts, rows.Err()
+}
+
+func buildTranscodeReceiptsQuery(filter *DBTranscodeReceiptFilter) (string, []interface{}) {
+	qry := "SELECT createdAt, manifestID, orchestrator, startSeq, endSeq, root, sig, segments, verified FROM transcodeReceipts"
+	var (
+		conds []string
+		args  []interface{}
+	)
+	limit := -1
+	if filter != nil {
+		if filter.ManifestID != "" {
+			conds = append(conds, "manifestID = ?")
+			args = append(args, filter.ManifestID)
+		}
+		if filter.Orchestrator != "" {
+			conds = append(conds, "orchestrator = ?")
+			args = append(args, filter.Orchestrator)
+		}
+		if filter.SeqNo != nil {
+			conds = append(conds, "startSeq <= ? AND endSeq >= ?")
+			args = append(args, *filter.SeqNo, *filter.SeqNo)
+		}
+		if filter.Limit > 0 {
+			limit = filter.Limit
+		}
+	}
+	if len(conds) > 0 {
+		qry += " WHERE " + strings.Join(conds, " AND ")
+	}
+	qry += " ORDER BY createdAt DESC LIMIT ?"
+	args = append(args, limit)
+	return qry, args
+}
+
+// LastIndexedEarningsBlock returns the last block whose earnings are stored by the DB, or nil if
+// no block was indexed
+func (db *DB) LastIndexedEarningsBlock() (*big.Int, error) {
+	if db == nil {
+		return nil, nil
+	}
+
+	value, err := db.selectKVStore("lastEarningsBlock")
+	if err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return nil, nil
+	}
+	block, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid last earnings block %v", value)
+	}
+	return block, nil
+}
+
+// InsertEarnings stores the earnings of the blocks up to lastBlock and records lastBlock as the
+// last indexed block. Earnings that are already stored are ignored, so that the blocks after
+// the last indexed block can be indexed again after a failure
+func (db *DB) InsertEarnings(earnings []*DBEarning, lastBlock *big.Int) error {
+	if db == nil {
+		return nil
+	}
+
+	for _, e := range earnings {
+		_, err := db.insertEarning.Exec(e.Kind, e.Amount.String(), e.Counterparty.Hex(), e.BlockNumber, e.BlockTime.UTC(), e.TxHash.Hex(), e.LogIndex)
+		if err != nil {
+			glog.Errorf("db: Unable to insert earning txHash=%v logIndex=%v err=%v", e.TxHash.Hex(), e.LogIndex, err)
+			return err
+		}
+	}
+	return db.updateKVStore("lastEarningsBlock", lastBlock.String())
+}
+
+// Earnings returns the stored earnings matching the filter, oldest first
+func (db *DB) Earnings(filter *DBEarningsFilter) ([]*DBEarning, error) {
+	if db == nil {
+		return nil, nil
+	}
+
+	qry, args := buildEarningsQuery(filter)
+	rows, err := db.dbh.Query(qry, args...)
+	if err != nil {
+		glog.Error("db: Unable to get earnings ", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	earnings := []*DBEarning{}
+	for rows.Next() {
+		var (
+			e                            DBEarning
+			amount, counterparty, txHash string
+		)
+		if err := rows.Scan(&e.Kind, &amount, &counterparty, &e.BlockNumber, &e.BlockTime, &txHash, &e.LogIndex); err != nil {
+			glog.Error("db: Unable to fetch earning ", err)
+			return nil, err
+		}
+		var ok bool
+		if e.Amount, ok = new(big.Int).SetString(amount, 10); !ok {
+			return nil, fmt.Errorf("invalid earning amount %v", amount)
+		}
+		e.Counterparty = ethcommon.HexToAddress(counterparty)
+		e.TxHash = ethcommon.HexToHash(txHash)
+		earnings = append(earnings, &e)
+	}
+	return earnings, rows.Err()
+}
+
+func buildEarningsQuery(filter *DBEarningsFilter) (string, []interface{}) {
+	qry := "SELECT kind, amount, counterparty, blockNumber, blockTime, txHash, logIndex FROM earnings"
+	var (
+		conds []string
+		args  []interface{}
+	)
+	if filter != nil {
+		if filter.Kind != "" {
+			conds = append(conds, "kind = ?")
+			args = append(args, filter.Kind)
+		}
+		if !filter.Since.IsZero() {
+			conds = append(conds, "blockTime >= ?")
+			args = append(args, filter.Since.UTC())
+		}
+		if !filter.Until.IsZero() {
+			conds = append(conds, "blockTime < ?")
+			args = append(args, filter.Until.UTC())
+		}
+	}
+	if len(conds) > 0 {
+		qry += " WHERE " + strings.Join(conds, " AND ")
+	}
+	qry += " ORDER BY blockNumber ASC, logIndex ASC"
+	return qry, args
+}
+
 // FindLatestMiniHeader returns the MiniHeader with the highest blocknumber in the DB
 func (db *DB) FindLatestMiniHeader() (*blockwatch.MiniHeader, error) {
 	row := db.findLatestMiniHeader.QueryRow()
 	var (
-		number  int64
-		parent  string
-		hash    string
-		logsEnc []byte
+		number        int64
+		parent        string
+		hash          string
+		logsEnc       []byte
+		l1BlockNumber sql.NullInt64
 	)
-	if err := row.Scan(&number, &parent, &hash, &logsEnc); err != nil {
+	if err := row.Scan(&number, &parent, &hash, &logsEnc, &l1BlockNumber); err != nil {
 		if err.Error() != "sql: no rows in result set" {
 			return nil, fmt.Errorf("could not retrieve latest header: %v", err)
 		}
@@ -870,10 +1596,11 @@ func (db *DB) FindLatestMiniHeader() (*blockwatch.MiniHeader, error) {
 		return nil, err
 	}
 	return &blockwatch.MiniHeader{
-		Number: big.NewInt(number),
-		Parent: ethcommon.HexToHash(parent),
-		Hash:   ethcommon.HexToHash(hash),
-		Logs:   logs,
+		Number:        big.NewInt(number),
+		Parent:        ethcommon.HexToHash(parent),
+		Hash:          ethcommon.HexToHash(hash),
+		L1BlockNumber: nullBigInt(l1BlockNumber),
+		Logs:          logs,
 	}, nil
 }
 
@@ -887,12 +1614,13 @@ func (db *DB) FindAllMiniHeadersSortedByNumber() ([]*blockwatch.MiniHeader, erro
 	}
 	for rows.Next() {
 		var (
-			number  int64
-			parent  string
-			hash    string
-			logsEnc []byte
+			number        int64
+			parent        string
+			hash          string
+			logsEnc       []byte
+			l1BlockNumber sql.NullInt64
 		)
-		if err := rows.Scan(&number, &parent, &hash, &logsEnc); err != nil {
+		if err := rows.Scan(&number, &parent, &hash, &logsEnc, &l1BlockNumber); err != nil {
 			return nil, err
 		}
 		logs, err := decodeLogsJSON(logsEnc)
@@ -900,10 +1628,11 @@ func (db *DB) FindAllMiniHeadersSortedByNumber() ([]*blockwatch.MiniHeader, erro
 			return nil, err
 		}
 		headers = append(headers, &blockwatch.MiniHeader{
-			Number: big.NewInt(number),
-			Parent: ethcommon.HexToHash(parent),
-			Hash:   ethcommon.HexToHash(hash),
-			Logs:   logs,
+			Number:        big.NewInt(number),
+			Parent:        ethcommon.HexToHash(parent),
+			Hash:          ethcommon.HexToHash(hash),
+			L1BlockNumber: nullBigInt(l1BlockNumber),
+			Logs:          logs,
 		})
 	}
 	return headers, nil
@@ -921,7 +1650,11 @@ func (db *DB) InsertMiniHeader(header *blockwatch.MiniHeader) error {
 	if err != nil {
 		return err
 	}
-	_, err = db.insertMiniHeader.Exec(header.Number.Int64(), header.Parent.Hex(), header.Hash.Hex(), logsEnc)
+	var l1BlockNumber sql.NullInt64
+	if header.L1BlockNumber != nil {
+		l1BlockNumber = sql.NullInt64{Int64: header.L1BlockNumber.Int64(), Valid: true}
+	}
+	_, err = db.insertMiniHeader.Exec(header.Number.Int64(), header.Parent.Hex(), header.Hash.Hex(), logsEnc, l1BlockNumber)
 	if err != nil {
 		return err
 	}
@@ -937,6 +1670,13 @@ func (db *DB) DeleteMiniHeader(hash ethcommon.Hash) error {
 	return nil
 }
 
+func nullBigInt(n sql.NullInt64) *big.Int {
+	if !n.Valid {
+		return nil
+	}
+	return big.NewInt(n.Int64)
+}
+
 func encodeLogsJSON(logs []types.Log) ([]byte, error) {
 	logsEnc, err := json.Marshal(logs)
 	if err != nil {
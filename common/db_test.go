@@ -149,6 +149,50 @@ func TestDBLastSeenBlock(t *testing.T) {
 	assert.Equal(h1.Number, blk)
 }
 
+func TestDBLastSeenL1Block(t *testing.T) {
+	dbh, dbraw, err := TempDB(t)
+	if err != nil {
+		return
+	}
+	defer dbh.Close()
+	defer dbraw.Close()
+
+	assert := assert.New(t)
+	require := require.New(t)
+
+	blk, err := dbh.LastSeenL1Block()
+	assert.Nil(err)
+	assert.Nil(blk)
+
+	// Headers of L1 chains have no L1 block number
+	h0 := defaultMiniHeader()
+	h0.Number = big.NewInt(100)
+	require.Nil(dbh.InsertMiniHeader(h0))
+
+	blk, err = dbh.LastSeenL1Block()
+	assert.Nil(err)
+	assert.Equal(h0.Number, blk)
+
+	// Headers of L2 chains
+	h1 := defaultMiniHeader()
+	h1.Number = big.NewInt(1000)
+	h1.L1BlockNumber = big.NewInt(101)
+	require.Nil(dbh.InsertMiniHeader(h1))
+
+	blk, err = dbh.LastSeenL1Block()
+	assert.Nil(err)
+	assert.Equal(h1.L1BlockNumber, blk)
+	blk, err = dbh.LastSeenBlock()
+	assert.Nil(err)
+	assert.Equal(h1.Number, blk)
+
+	headers, err := dbh.FindAllMiniHeadersSortedByNumber()
+	require.Nil(err)
+	require.Len(headers, 2)
+	assert.Equal(h1.L1BlockNumber, headers[0].L1BlockNumber)
+	assert.Nil(headers[1].L1BlockNumber)
+}
+
 func TestDBVersion(t *testing.T) {
 	dbh, dbraw, err := TempDB(t)
 	if err != nil {
@@ -183,6 +227,66 @@ func TestDBVersion(t *testing.T) {
 	}
 }
 
+func TestDBUpgrade(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Version 1 of the schema, before payout splits
+	dbraw, err := sql.Open("sqlite3", dbPath(t))
+	require.Nil(err)
+	defer dbraw.Close()
+	_, err = dbraw.Exec(`
+	CREATE TABLE kv (key STRING PRIMARY KEY, value STRING, updatedAt STRING DEFAULT CURRENT_TIMESTAMP);
+	INSERT INTO kv(key, value) VALUES('dbVersion', '1');
+	CREATE TABLE ticketQueue (
+		createdAt DATETIME DEFAULT CURRENT_TIMESTAMP,
+		sender STRING,
+		recipient STRING,
+		faceValue BLOB,
+		winProb BLOB,
+		senderNonce INTEGER,
+		recipientRand BLOB,
+		recipientRandHash STRING,
+		sig BLOB PRIMARY KEY,
+		creationRound int64,
+		creationRoundBlockHash STRING,
+		paramsExpirationBlock int64,
+		redeemedAt DATETIME,
+		txHash STRING
+	);
+	INSERT INTO ticketQueue(sender, recipient, faceValue, winProb, senderNonce, recipientRand, recipientRandHash, sig, creationRound, creationRoundBlockHash, paramsExpirationBlock)
+	VALUES('0x0000000000000000000000000000000000000001', '0x0000000000000000000000000000000000000002', x'01', x'01', 1, x'01', '0x01', x'02', 1, '0x01', 1);
+	CREATE TABLE blockheaders (number int64, parent STRING, hash STRING PRIMARY KEY, logs BLOB);
+	INSERT INTO blockheaders(number, parent, hash, logs) VALUES(5, '0x01', '0x02', '[]');
+	`)
+	require.Nil(err)
+
+	dbh, err := InitDB(dbPath(t))
+	require.Nil(err)
+	defer dbh.Close()
+
+	var dbVersion int
+	require.Nil(dbraw.QueryRow("SELECT value FROM kv WHERE key = 'dbVersion'").Scan(&dbVersion))
+	assert.Equal(LivepeerDBVersion, dbVersion)
+
+	// Tickets stored before the upgrade have no payout split, are legacy tickets and have no
+	// failed redemption attempts
+	ticket, err := dbh.SelectEarliestWinningTicket(ethcommon.HexToAddress("0x0000000000000000000000000000000000000001"))
+	require.Nil(err)
+	require.NotNil(ticket)
+	assert.Equal([]byte{2}, ticket.Sig)
+	assert.Nil(ticket.PayoutSplit)
+	assert.Equal(pm.TicketVersionLegacy, ticket.Version)
+	assert.Equal(0, ticket.RedeemAttempts)
+
+	// Headers stored before the upgrade have no L1 block number
+	header, err := dbh.FindLatestMiniHeader()
+	require.Nil(err)
+	require.NotNil(header)
+	assert.Equal(big.NewInt(5), header.Number)
+	assert.Nil(header.L1BlockNumber)
+}
+
 func profilesMatch(j1 []ffmpeg.VideoProfile, j2 []ffmpeg.VideoProfile) bool {
 	if len(j1) != len(j2) {
 		return false
@@ -833,6 +937,16 @@ func TestInsertWinningTicket_GivenValidInputs_InsertsOneRowCorrectly(t *testing.
 
 	ticketsCount := getRowCountOrFatal("SELECT count(*) FROM ticketQueue", dbraw, t)
 	assert.Equal(1, ticketsCount)
+
+	// A ticket with the same sig is already stored
+	err = dbh.StoreWinningTicket(&pm.SignedTicket{
+		Ticket:        ticket,
+		Sig:           sig,
+		RecipientRand: recipientRand,
+	})
+	assert.Equal(pm.ErrTicketStored, err)
+	ticketsCount = getRowCountOrFatal("SELECT count(*) FROM ticketQueue", dbraw, t)
+	assert.Equal(1, ticketsCount)
 }
 
 func TestInsertWinningTicket_GivenMaxValueInputs_InsertsOneRowCorrectly(t *testing.T) {
@@ -976,6 +1090,8 @@ func TestSelectEarliestWinningTicket(t *testing.T) {
 
 	_, ticket, sig, recipientRand = defaultWinningTicket(t)
 	ticket.Sender = ethcommon.HexToAddress("charizard")
+	ticket.PayoutSplit = &pm.PayoutSplit{Recipient: pm.RandAddress(), Share: 100000}
+	ticket.Version = pm.TicketVersionTypedData
 	signedTicket2 := &pm.SignedTicket{
 		Ticket:        ticket,
 		Sig:           pm.RandBytes(32),
@@ -996,6 +1112,52 @@ func TestSelectEarliestWinningTicket(t *testing.T) {
 	assert.Equal(earliest, signedTicket2)
 }
 
+func TestSelectEarliestWinningTickets(t *testing.T) {
+	assert := assert.New(t)
+	dbh, dbraw, err := TempDB(t)
+	defer dbh.Close()
+	defer dbraw.Close()
+	require := require.New(t)
+	require.Nil(err)
+
+	sender := ethcommon.HexToAddress("charizard")
+	var tickets []*pm.SignedTicket
+	for i := 0; i < 4; i++ {
+		_, ticket, _, recipientRand := defaultWinningTicket(t)
+		ticket.Sender = sender
+		ticket.ParamsExpirationBlock = big.NewInt(int64(10 * (i + 1)))
+		signedTicket := &pm.SignedTicket{
+			Ticket:        ticket,
+			Sig:           pm.RandBytes(32),
+			RecipientRand: recipientRand,
+		}
+		require.Nil(dbh.StoreWinningTicket(signedTicket))
+		tickets = append(tickets, signedTicket)
+	}
+
+	// no tickets found
+	earliest, err := dbh.SelectEarliestWinningTickets(ethcommon.HexToAddress("pikachu"), big.NewInt(100), 10)
+	assert.Nil(err)
+	assert.Empty(earliest)
+	earliest, err = dbh.SelectEarliestWinningTickets(sender, big.NewInt(9), 10)
+	assert.Nil(err)
+	assert.Empty(earliest)
+
+	// Only tickets with expired params are selected, up to the limit
+	earliest, err = dbh.SelectEarliestWinningTickets(sender, big.NewInt(30), 10)
+	assert.Nil(err)
+	assert.Equal(tickets[:3], earliest)
+	earliest, err = dbh.SelectEarliestWinningTickets(sender, big.NewInt(100), 2)
+	assert.Nil(err)
+	assert.Equal(tickets[:2], earliest)
+
+	// Test excluding submitted tickets
+	require.Nil(dbh.MarkWinningTicketRedeemed(tickets[0], pm.RandHash()))
+	earliest, err = dbh.SelectEarliestWinningTickets(sender, big.NewInt(100), 10)
+	assert.Nil(err)
+	assert.Equal(tickets[1:], earliest)
+}
+
 func TestMarkWinningTicketRedeemed_GivenNilTicket_ReturnsError(t *testing.T) {
 	dbh, dbraw, err := TempDB(t)
 	defer dbh.Close()
@@ -1069,6 +1231,113 @@ func TestMarkWinningTicketRedeemed_Update_TxHash_And_SubmittedAt(t *testing.T) {
 	assert.InDelta(redeemedAt.Day(), time.Now().Day(), 1)
 }
 
+func TestMarkWinningTicketFailed(t *testing.T) {
+	assert := assert.New(t)
+	dbh, dbraw, err := TempDB(t)
+	defer dbh.Close()
+	defer dbraw.Close()
+	require := require.New(t)
+	require.Nil(err)
+
+	err = dbh.MarkWinningTicketFailed(nil, 1, time.Now())
+	assert.Contains(err.Error(), "nil ticket")
+	err = dbh.MarkWinningTicketFailed(&pm.SignedTicket{Ticket: &pm.Ticket{}}, 1, time.Now())
+	assert.Contains(err.Error(), "nil sig")
+
+	sender := ethcommon.HexToAddress("charizard")
+	var tickets []*pm.SignedTicket
+	for i := 0; i < 2; i++ {
+		_, ticket, _, recipientRand := defaultWinningTicket(t)
+		ticket.Sender = sender
+		ticket.ParamsExpirationBlock = big.NewInt(10)
+		signedTicket := &pm.SignedTicket{
+			Ticket:        ticket,
+			Sig:           pm.RandBytes(32),
+			RecipientRand: recipientRand,
+		}
+		tickets = append(tickets, signedTicket)
+	}
+
+	// test no record found
+	err = dbh.MarkWinningTicketFailed(tickets[0], 1, time.Now())
+	assert.EqualError(err, fmt.Sprintf("no record found for sig=0x%x", tickets[0].Sig))
+
+	for _, ticket := range tickets {
+		require.Nil(dbh.StoreWinningTicket(ticket))
+	}
+
+	// Tickets waiting to retry a failed redemption are not selected
+	require.Nil(dbh.MarkWinningTicketFailed(tickets[0], 2, time.Now().Add(time.Hour)))
+	earliest, err := dbh.SelectEarliestWinningTicket(sender)
+	require.Nil(err)
+	assert.Equal(tickets[1].Sig, earliest.Sig)
+	batch, err := dbh.SelectEarliestWinningTickets(sender, big.NewInt(10), 10)
+	require.Nil(err)
+	require.Len(batch, 1)
+	assert.Equal(tickets[1].Sig, batch[0].Sig)
+
+	// but they are still queued
+	count, err := dbh.WinningTicketCount(sender)
+	require.Nil(err)
+	assert.Equal(2, count)
+
+	// Tickets are selected with their attempts once their retry time passed
+	require.Nil(dbh.MarkWinningTicketFailed(tickets[0], 3, time.Now().Add(-time.Second)))
+	earliest, err = dbh.SelectEarliestWinningTicket(sender)
+	require.Nil(err)
+	assert.Equal(tickets[0].Sig, earliest.Sig)
+	assert.Equal(3, earliest.RedeemAttempts)
+	batch, err = dbh.SelectEarliestWinningTickets(sender, big.NewInt(10), 10)
+	require.Nil(err)
+	require.Len(batch, 2)
+	assert.Equal(3, batch[0].RedeemAttempts)
+	assert.Equal(0, batch[1].RedeemAttempts)
+}
+
+func TestWinningTickets(t *testing.T) {
+	assert := assert.New(t)
+	dbh, dbraw, err := TempDB(t)
+	defer dbh.Close()
+	defer dbraw.Close()
+	require := require.New(t)
+	require.Nil(err)
+
+	sender := ethcommon.HexToAddress("charizard")
+	tickets, err := dbh.WinningTickets(sender)
+	require.Nil(err)
+	assert.Empty(tickets)
+
+	var stored []*pm.SignedTicket
+	for i := 0; i < 3; i++ {
+		_, ticket, _, recipientRand := defaultWinningTicket(t)
+		ticket.Sender = sender
+		ticket.CreationRound = int64(i)
+		signedTicket := &pm.SignedTicket{
+			Ticket:        ticket,
+			Sig:           pm.RandBytes(32),
+			RecipientRand: recipientRand,
+		}
+		require.Nil(dbh.StoreWinningTicket(signedTicket))
+		stored = append(stored, signedTicket)
+	}
+	// Tickets of other senders are not returned
+	_, other, _, recipientRand := defaultWinningTicket(t)
+	require.Nil(dbh.StoreWinningTicket(&pm.SignedTicket{Ticket: other, Sig: pm.RandBytes(32), RecipientRand: recipientRand}))
+
+	// Redeemed tickets are not returned but tickets waiting to retry are
+	require.Nil(dbh.MarkWinningTicketRedeemed(stored[1], pm.RandHash()))
+	require.Nil(dbh.MarkWinningTicketFailed(stored[2], 1, time.Now().Add(time.Hour)))
+
+	tickets, err = dbh.WinningTickets(sender)
+	require.Nil(err)
+	require.Len(tickets, 2)
+	assert.Equal(stored[0].Sig, tickets[0].Sig)
+	assert.Equal(stored[0].RecipientRand, tickets[0].RecipientRand)
+	assert.Equal(stored[0].FaceValue, tickets[0].FaceValue)
+	assert.Equal(stored[2].Sig, tickets[1].Sig)
+	assert.Equal(1, tickets[1].RedeemAttempts)
+}
+
 func TestRemoveWinningTicket(t *testing.T) {
 	assert := assert.New(t)
 	dbh, dbraw, err := TempDB(t)
@@ -1110,6 +1379,93 @@ func TestRemoveWinningTicket(t *testing.T) {
 	require.Equal(count, 0)
 }
 
+func TestWinningTicketSenders(t *testing.T) {
+	assert := assert.New(t)
+	dbh, dbraw, err := TempDB(t)
+	defer dbh.Close()
+	defer dbraw.Close()
+	require := require.New(t)
+	require.Nil(err)
+
+	senders, err := dbh.WinningTicketSenders()
+	assert.Nil(err)
+	assert.Empty(senders)
+
+	_, ticket, sig, recipientRand := defaultWinningTicket(t)
+	signedTicket := &pm.SignedTicket{
+		Ticket:        ticket,
+		Sig:           sig,
+		RecipientRand: recipientRand,
+	}
+	require.Nil(dbh.StoreWinningTicket(signedTicket))
+	// Senders are listed once however many tickets they have
+	signedTicketDup := *signedTicket
+	signedTicketDup.Sig = pm.RandBytes(32)
+	require.Nil(dbh.StoreWinningTicket(&signedTicketDup))
+
+	otherTicket := *ticket
+	otherTicket.Sender = pm.RandAddress()
+	signedTicket2 := &pm.SignedTicket{
+		Ticket:        &otherTicket,
+		Sig:           pm.RandBytes(32),
+		RecipientRand: recipientRand,
+	}
+	require.Nil(dbh.StoreWinningTicket(signedTicket2))
+
+	senders, err = dbh.WinningTicketSenders()
+	assert.Nil(err)
+	assert.ElementsMatch([]ethcommon.Address{ticket.Sender, otherTicket.Sender}, senders)
+
+	// Senders whose tickets are all redeemed are not listed
+	require.Nil(dbh.MarkWinningTicketRedeemed(signedTicket2, pm.RandHash()))
+	senders, err = dbh.WinningTicketSenders()
+	assert.Nil(err)
+	assert.Equal([]ethcommon.Address{ticket.Sender}, senders)
+}
+
+func TestWinningTicketFaceValue(t *testing.T) {
+	assert := assert.New(t)
+	dbh, dbraw, err := TempDB(t)
+	defer dbh.Close()
+	defer dbraw.Close()
+	require := require.New(t)
+	require.Nil(err)
+
+	_, ticket, sig, recipientRand := defaultWinningTicket(t)
+	faceValue, err := dbh.WinningTicketFaceValue(ticket.Sender)
+	assert.Nil(err)
+	assert.Zero(faceValue.Sign())
+
+	signedTicket := &pm.SignedTicket{
+		Ticket:        ticket,
+		Sig:           sig,
+		RecipientRand: recipientRand,
+	}
+	require.Nil(dbh.StoreWinningTicket(signedTicket))
+	signedTicketDup := *signedTicket
+	signedTicketDup.Sig = pm.RandBytes(32)
+	require.Nil(dbh.StoreWinningTicket(&signedTicketDup))
+
+	// Tickets of other senders are not included
+	otherTicket := *ticket
+	otherTicket.Sender = pm.RandAddress()
+	require.Nil(dbh.StoreWinningTicket(&pm.SignedTicket{
+		Ticket:        &otherTicket,
+		Sig:           pm.RandBytes(32),
+		RecipientRand: recipientRand,
+	}))
+
+	faceValue, err = dbh.WinningTicketFaceValue(ticket.Sender)
+	assert.Nil(err)
+	assert.Zero(faceValue.Cmp(new(big.Int).Mul(ticket.FaceValue, big.NewInt(2))))
+
+	// Redeemed tickets are not included
+	require.Nil(dbh.MarkWinningTicketRedeemed(signedTicket, pm.RandHash()))
+	faceValue, err = dbh.WinningTicketFaceValue(ticket.Sender)
+	assert.Nil(err)
+	assert.Zero(faceValue.Cmp(ticket.FaceValue))
+}
+
 func TestInsertMiniHeader_ReturnsFindLatestMiniHeader(t *testing.T) {
 	dbh, dbraw, err := TempDB(t)
 	defer dbh.Close()
@@ -1234,6 +1590,219 @@ func TestDeleteMiniHeader(t *testing.T) {
 	assert.Equal(headers[0].Hash, h1.Hash)
 }
 
+func TestVerificationResults(t *testing.T) {
+	dbh, dbraw, err := TempDB(t)
+	defer dbh.Close()
+	defer dbraw.Close()
+	assert := assert.New(t)
+	require := require.New(t)
+	require.Nil(err)
+
+	results, err := dbh.VerificationResults(nil)
+	require.Nil(err)
+	assert.Empty(results)
+
+	err = dbh.InsertVerificationResult(nil)
+	assert.EqualError(err, "cannot insert nil verification result")
+
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	insert := func(mid string, seqNo uint64, orch string, offset time.Duration, verr string) *DBVerificationResult {
+		res := &DBVerificationResult{
+			CreatedAt:    start.Add(offset),
+			ManifestID:   mid,
+			SeqNo:        seqNo,
+			Orchestrator: orch,
+			Score:        float64(seqNo) / 10,
+			Error:        verr,
+			URIs:         []string{fmt.Sprintf("/stream/%v/P240p30fps16x9/%v.ts", mid, seqNo)},
+		}
+		require.Nil(dbh.InsertVerificationResult(res))
+		return res
+	}
+	r0 := insert("mid1", 0, "https://o1", 0, "")
+	r1 := insert("mid1", 1, "https://o2", time.Minute, "Tampered")
+	r2 := insert("mid2", 0, "https://o1", 2*time.Minute, "")
+	r3 := insert("mid2", 1, "https://o1", 3*time.Minute, "PixelMismatch")
+
+	// Most recent first
+	results, err = dbh.VerificationResults(nil)
+	require.Nil(err)
+	assert.Equal([]*DBVerificationResult{r3, r2, r1, r0}, results)
+
+	results, err = dbh.VerificationResults(&DBVerificationFilter{ManifestID: "mid1"})
+	require.Nil(err)
+	assert.Equal([]*DBVerificationResult{r1, r0}, results)
+
+	results, err = dbh.VerificationResults(&DBVerificationFilter{Orchestrator: "https://o1", ManifestID: "mid2"})
+	require.Nil(err)
+	assert.Equal([]*DBVerificationResult{r3, r2}, results)
+
+	// Since is inclusive and until is exclusive
+	results, err = dbh.VerificationResults(&DBVerificationFilter{Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)})
+	require.Nil(err)
+	assert.Equal([]*DBVerificationResult{r2, r1}, results)
+
+	results, err = dbh.VerificationResults(&DBVerificationFilter{Orchestrator: "https://o1", Limit: 1})
+	require.Nil(err)
+	assert.Equal([]*DBVerificationResult{r3}, results)
+
+	results, err = dbh.VerificationResults(&DBVerificationFilter{Orchestrator: "https://o3"})
+	require.Nil(err)
+	assert.Empty(results)
+
+	// Results stored before the time are deleted
+	deleted, err := dbh.DeleteVerificationResults(start.Add(2 * time.Minute))
+	require.Nil(err)
+	assert.Equal(int64(2), deleted)
+	results, err = dbh.VerificationResults(nil)
+	require.Nil(err)
+	assert.Equal([]*DBVerificationResult{r3, r2}, results)
+
+	// Nil DB is a no-op
+	var nilDB *DB
+	assert.Nil(nilDB.InsertVerificationResult(r0))
+	results, err = nilDB.VerificationResults(nil)
+	assert.Nil(err)
+	assert.Nil(results)
+	deleted, err = nilDB.DeleteVerificationResults(start)
+	assert.Nil(err)
+	assert.Zero(deleted)
+}
+
+func TestTranscodeReceipts(t *testing.T) {
+	dbh, dbraw, err := TempDB(t)
+	defer dbh.Close()
+	defer dbraw.Close()
+	assert := assert.New(t)
+	require := require.New(t)
+	require.Nil(err)
+
+	receipts, err := dbh.TranscodeReceipts(nil)
+	require.Nil(err)
+	assert.Empty(receipts)
+
+	err = dbh.InsertTranscodeReceipt(nil)
+	assert.EqualError(err, "cannot insert nil transcode receipt")
+
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	insert := func(mid string, orch string, startSeq, endSeq int64, offset time.Duration) *DBTranscodeReceipt {
+		r := &DBTranscodeReceipt{
+			CreatedAt:    start.Add(offset),
+			ManifestID:   mid,
+			Orchestrator: orch,
+			StartSeq:     startSeq,
+			EndSeq:       endSeq,
+			Root:         []byte("root"),
+			Sig:          []byte("sig"),
+			Verified:     orch == "https://o1",
+		}
+		for seq := startSeq; seq <= endSeq; seq++ {
+			r.Segments = append(r.Segments, DBReceiptSegment{SeqNo: seq, Hash: []byte(fmt.Sprint(seq))})
+		}
+		require.Nil(dbh.InsertTranscodeReceipt(r))
+		return r
+	}
+	r0 := insert("mid1", "https://o1", 0, 9, 0)
+	r1 := insert("mid1", "https://o2", 10, 19, time.Minute)
+	r2 := insert("mid2", "https://o1", 0, 9, 2*time.Minute)
+
+	// Most recent first
+	receipts, err = dbh.TranscodeReceipts(nil)
+	require.Nil(err)
+	assert.Equal([]*DBTranscodeReceipt{r2, r1, r0}, receipts)
+
+	receipts, err = dbh.TranscodeReceipts(&DBTranscodeReceiptFilter{ManifestID: "mid1"})
+	require.Nil(err)
+	assert.Equal([]*DBTranscodeReceipt{r1, r0}, receipts)
+
+	receipts, err = dbh.TranscodeReceipts(&DBTranscodeReceiptFilter{Orchestrator: "https://o1", Limit: 1})
+	require.Nil(err)
+	assert.Equal([]*DBTranscodeReceipt{r2}, receipts)
+
+	// Only the receipts that cover the segment
+	seqNo := int64(10)
+	receipts, err = dbh.TranscodeReceipts(&DBTranscodeReceiptFilter{ManifestID: "mid1", SeqNo: &seqNo})
+	require.Nil(err)
+	assert.Equal([]*DBTranscodeReceipt{r1}, receipts)
+	seqNo = 20
+	receipts, err = dbh.TranscodeReceipts(&DBTranscodeReceiptFilter{ManifestID: "mid1", SeqNo: &seqNo})
+	require.Nil(err)
+	assert.Empty(receipts)
+
+	// Nil DB is a no-op
+	var nilDB *DB
+	assert.Nil(nilDB.InsertTranscodeReceipt(r0))
+	receipts, err = nilDB.TranscodeReceipts(nil)
+	assert.Nil(err)
+	assert.Nil(receipts)
+}
+
+func TestEarnings(t *testing.T) {
+	dbh, dbraw, err := TempDB(t)
+	defer dbh.Close()
+	defer dbraw.Close()
+	assert := assert.New(t)
+	require := require.New(t)
+	require.Nil(err)
+
+	last, err := dbh.LastIndexedEarningsBlock()
+	require.Nil(err)
+	assert.Nil(last)
+	earnings, err := dbh.Earnings(nil)
+	require.Nil(err)
+	assert.Empty(earnings)
+
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	earning := func(kind string, amount int64, block int64, logIndex int64) *DBEarning {
+		return &DBEarning{
+			Kind:         kind,
+			Amount:       big.NewInt(amount),
+			Counterparty: pm.RandAddress(),
+			BlockNumber:  block,
+			BlockTime:    start.Add(time.Duration(block) * time.Hour),
+			TxHash:       ethcommon.BigToHash(big.NewInt(block)),
+			LogIndex:     logIndex,
+		}
+	}
+	e0 := earning(EarningFees, 100, 1, 0)
+	e1 := earning(EarningReward, 200, 1, 1)
+	e2 := earning(EarningFees, 300, 3, 0)
+	require.Nil(dbh.InsertEarnings([]*DBEarning{e2, e0, e1}, big.NewInt(5)))
+
+	last, err = dbh.LastIndexedEarningsBlock()
+	require.Nil(err)
+	assert.Equal(big.NewInt(5), last)
+
+	// Oldest first
+	earnings, err = dbh.Earnings(nil)
+	require.Nil(err)
+	assert.Equal([]*DBEarning{e0, e1, e2}, earnings)
+
+	// Earnings that are indexed again are ignored
+	require.Nil(dbh.InsertEarnings([]*DBEarning{e2}, big.NewInt(6)))
+	earnings, err = dbh.Earnings(nil)
+	require.Nil(err)
+	assert.Len(earnings, 3)
+
+	earnings, err = dbh.Earnings(&DBEarningsFilter{Kind: EarningFees})
+	require.Nil(err)
+	assert.Equal([]*DBEarning{e0, e2}, earnings)
+
+	earnings, err = dbh.Earnings(&DBEarningsFilter{Since: start.Add(2 * time.Hour)})
+	require.Nil(err)
+	assert.Equal([]*DBEarning{e2}, earnings)
+	earnings, err = dbh.Earnings(&DBEarningsFilter{Until: start.Add(3 * time.Hour)})
+	require.Nil(err)
+	assert.Equal([]*DBEarning{e0, e1}, earnings)
+
+	// Nil DB is a no-op
+	var nilDB *DB
+	assert.Nil(nilDB.InsertEarnings([]*DBEarning{e0}, big.NewInt(1)))
+	earnings, err = nilDB.Earnings(nil)
+	assert.Nil(err)
+	assert.Nil(earnings)
+}
+
 func defaultWinningTicket(t *testing.T) (sessionID string, ticket *pm.Ticket, sig []byte, recipientRand *big.Int) {
 	sessionID = "foo bar"
 	ticket = &pm.Ticket{
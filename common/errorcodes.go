@@ -0,0 +1,184 @@
+package common
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ErrorCode is a numbered identifier for a failure in the segment pipeline.
+// Codes are grouped by the stage of the pipeline that failed:
+//
+//	1xx - ingest
+//	2xx - payment
+//	3xx - transcode
+//	4xx - verification
+//	5xx - storage
+//
+// Codes are sent between nodes in HTTP responses and are used as metric labels,
+// so existing values must never be renumbered
+type ErrorCode int
+
+const (
+	ErrCodeUnknown ErrorCode = 0
+
+	ErrCodeIngest                ErrorCode = 100
+	ErrCodeIngestSegCreds        ErrorCode = 101
+	ErrCodeIngestReadBody        ErrorCode = 102
+	ErrCodeIngestDownload        ErrorCode = 103
+	ErrCodeIngestDownloadTimeout ErrorCode = 104
+	ErrCodeIngestHashMismatch    ErrorCode = 105
+	ErrCodeIngestTimeout         ErrorCode = 106
+	ErrCodeIngestSessionEnded    ErrorCode = 107
+	ErrCodeIngestShuttingDown    ErrorCode = 108
+	ErrCodeIngestProtocol        ErrorCode = 109
+	ErrCodeIngestAuthToken       ErrorCode = 110
+	ErrCodeIngestRateLimited     ErrorCode = 111
+	ErrCodeIngestTooLarge        ErrorCode = 112
+
+	ErrCodePayment                    ErrorCode = 200
+	ErrCodePaymentParse               ErrorCode = 201
+	ErrCodePaymentProcess             ErrorCode = 202
+	ErrCodePaymentInsufficientBalance ErrorCode = 203
+	ErrCodePaymentGenCreds            ErrorCode = 204
+	ErrCodePaymentOrchestratorInfo    ErrorCode = 205
+
+	ErrCodeTranscode                   ErrorCode = 300
+	ErrCodeTranscodeFailed             ErrorCode = 301
+	ErrCodeTranscodeOrchestratorBusy   ErrorCode = 302
+	ErrCodeTranscodeOrchestratorCapped ErrorCode = 303
+	ErrCodeTranscodeUnknownResponse    ErrorCode = 304
+	ErrCodeTranscodeParseResponse      ErrorCode = 305
+	ErrCodeTranscodeReadBody           ErrorCode = 306
+	ErrCodeTranscodeNoOrchestrators    ErrorCode = 307
+	ErrCodeTranscodeSessionEnded       ErrorCode = 308
+
+	ErrCodeVerification             ErrorCode = 400
+	ErrCodeVerificationTampered     ErrorCode = 401
+	ErrCodeVerificationPixels       ErrorCode = 402
+	ErrCodeVerificationAudio        ErrorCode = 403
+	ErrCodeVerificationSignature    ErrorCode = 404
+	ErrCodeVerificationMissingInput ErrorCode = 405
+
+	ErrCodeStorage         ErrorCode = 500
+	ErrCodeStorageSaveData ErrorCode = 501
+	ErrCodeStorageDownload ErrorCode = 502
+	ErrCodeStoragePlaylist ErrorCode = 503
+)
+
+var errorCodeNames = map[ErrorCode]string{
+	ErrCodeUnknown: "Unknown",
+
+	ErrCodeIngest:                "Ingest",
+	ErrCodeIngestSegCreds:        "IngestSegCreds",
+	ErrCodeIngestReadBody:        "IngestReadBody",
+	ErrCodeIngestDownload:        "IngestDownload",
+	ErrCodeIngestDownloadTimeout: "IngestDownloadTimeout",
+	ErrCodeIngestHashMismatch:    "IngestHashMismatch",
+	ErrCodeIngestTimeout:         "IngestTimeout",
+	ErrCodeIngestSessionEnded:    "IngestSessionEnded",
+	ErrCodeIngestShuttingDown:    "IngestShuttingDown",
+	ErrCodeIngestProtocol:        "IngestProtocol",
+	ErrCodeIngestAuthToken:       "IngestAuthToken",
+	ErrCodeIngestRateLimited:     "IngestRateLimited",
+	ErrCodeIngestTooLarge:        "IngestTooLarge",
+
+	ErrCodePayment:                    "Payment",
+	ErrCodePaymentParse:               "PaymentParse",
+	ErrCodePaymentProcess:             "PaymentProcess",
+	ErrCodePaymentInsufficientBalance: "PaymentInsufficientBalance",
+	ErrCodePaymentGenCreds:            "PaymentGenCreds",
+	ErrCodePaymentOrchestratorInfo:    "PaymentOrchestratorInfo",
+
+	ErrCodeTranscode:                   "Transcode",
+	ErrCodeTranscodeFailed:             "TranscodeFailed",
+	ErrCodeTranscodeOrchestratorBusy:   "TranscodeOrchestratorBusy",
+	ErrCodeTranscodeOrchestratorCapped: "TranscodeOrchestratorCapped",
+	ErrCodeTranscodeUnknownResponse:    "TranscodeUnknownResponse",
+	ErrCodeTranscodeParseResponse:      "TranscodeParseResponse",
+	ErrCodeTranscodeReadBody:           "TranscodeReadBody",
+	ErrCodeTranscodeNoOrchestrators:    "TranscodeNoOrchestrators",
+	ErrCodeTranscodeSessionEnded:       "TranscodeSessionEnded",
+
+	ErrCodeVerification:             "Verification",
+	ErrCodeVerificationTampered:     "VerificationTampered",
+	ErrCodeVerificationPixels:       "VerificationPixels",
+	ErrCodeVerificationAudio:        "VerificationAudio",
+	ErrCodeVerificationSignature:    "VerificationSignature",
+	ErrCodeVerificationMissingInput: "VerificationMissingInput",
+
+	ErrCodeStorage:         "Storage",
+	ErrCodeStorageSaveData: "StorageSaveData",
+	ErrCodeStorageDownload: "StorageDownload",
+	ErrCodeStoragePlaylist: "StoragePlaylist",
+}
+
+var errorCategoryNames = map[ErrorCode]string{
+	ErrCodeIngest:       "ingest",
+	ErrCodePayment:      "payment",
+	ErrCodeTranscode:    "transcode",
+	ErrCodeVerification: "verification",
+	ErrCodeStorage:      "storage",
+}
+
+// String returns the name of the code. The name is stable and is used as a metric label
+func (c ErrorCode) String() string {
+	if name, ok := errorCodeNames[c]; ok {
+		return name
+	}
+	// Codes from a newer node that we do not know about are labelled by their category
+	if name, ok := errorCodeNames[c.category()]; ok {
+		return name
+	}
+	return errorCodeNames[ErrCodeUnknown]
+}
+
+// Category returns the pipeline stage that the code belongs to
+func (c ErrorCode) Category() string {
+	if name, ok := errorCategoryNames[c.category()]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func (c ErrorCode) category() ErrorCode {
+	return c - c%100
+}
+
+// ParseErrorCode parses a numeric error code as sent in a HTTP response.
+// Empty or malformed values yield ErrCodeUnknown
+func ParseErrorCode(s string) ErrorCode {
+	code, err := strconv.Atoi(s)
+	if err != nil || code < 0 {
+		return ErrCodeUnknown
+	}
+	return ErrorCode(code)
+}
+
+// CodedError is an error tagged with an ErrorCode
+type CodedError struct {
+	Code ErrorCode
+	err  error
+}
+
+// NewCodedError returns an error that wraps err with the provided code
+func NewCodedError(code ErrorCode, err error) *CodedError {
+	return &CodedError{Code: code, err: err}
+}
+
+func (e *CodedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *CodedError) Unwrap() error {
+	return e.err
+}
+
+// ErrorCodeOf returns the code of the first CodedError in the chain of err
+// or ErrCodeUnknown if there is none
+func ErrorCodeOf(err error) ErrorCode {
+	var cerr *CodedError
+	if errors.As(err, &cerr) {
+		return cerr.Code
+	}
+	return ErrCodeUnknown
+}
@@ -0,0 +1,104 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/livepeer/go-livepeer/monitor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorCode_String(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("Unknown", ErrCodeUnknown.String())
+	assert.Equal("PaymentInsufficientBalance", ErrCodePaymentInsufficientBalance.String())
+	assert.Equal("TranscodeOrchestratorBusy", ErrCodeTranscodeOrchestratorBusy.String())
+
+	// Unknown codes fall back to their category
+	assert.Equal("Verification", ErrorCode(499).String())
+	assert.Equal("Unknown", ErrorCode(999).String())
+}
+
+func TestErrorCode_Category(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("ingest", ErrCodeIngestHashMismatch.Category())
+	assert.Equal("payment", ErrCodePaymentParse.Category())
+	assert.Equal("transcode", ErrCodeTranscodeFailed.Category())
+	assert.Equal("verification", ErrCodeVerificationTampered.Category())
+	assert.Equal("storage", ErrCodeStorageSaveData.Category())
+	assert.Equal("unknown", ErrCodeUnknown.Category())
+	assert.Equal("unknown", ErrorCode(999).Category())
+}
+
+func TestErrorCode_NamesUnique(t *testing.T) {
+	seen := make(map[string]ErrorCode)
+	for code, name := range errorCodeNames {
+		if other, ok := seen[name]; ok {
+			t.Errorf("duplicate name %v for codes %d and %d", name, code, other)
+		}
+		seen[name] = code
+	}
+}
+
+func TestParseErrorCode(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(ErrCodePaymentParse, ParseErrorCode("201"))
+	assert.Equal(ErrorCode(999), ParseErrorCode("999"))
+	assert.Equal(ErrCodeUnknown, ParseErrorCode(""))
+	assert.Equal(ErrCodeUnknown, ParseErrorCode("foo"))
+	assert.Equal(ErrCodeUnknown, ParseErrorCode("-1"))
+}
+
+func TestCodedError(t *testing.T) {
+	assert := assert.New(t)
+
+	inner := errors.New("some error")
+	err := NewCodedError(ErrCodeStorageSaveData, inner)
+	assert.EqualError(err, "some error")
+	assert.True(errors.Is(err, inner))
+	assert.Equal(ErrCodeStorageSaveData, ErrorCodeOf(err))
+
+	// Wrapped coded errors keep their code
+	assert.Equal(ErrCodeStorageSaveData, ErrorCodeOf(fmt.Errorf("wrapped: %w", err)))
+
+	assert.Equal(ErrCodeUnknown, ErrorCodeOf(inner))
+	assert.Equal(ErrCodeUnknown, ErrorCodeOf(nil))
+}
+
+func TestErrorCode_MatchesMonitorLabels(t *testing.T) {
+	assert := assert.New(t)
+
+	uploadErrors := map[monitor.SegmentUploadError]ErrorCode{
+		monitor.SegmentUploadErrorUnknown:             ErrCodeUnknown,
+		monitor.SegmentUploadErrorGenCreds:            ErrCodePaymentGenCreds,
+		monitor.SegmentUploadErrorOS:                  ErrCodeStorageSaveData,
+		monitor.SegmentUploadErrorSessionEnded:        ErrCodeIngestSessionEnded,
+		monitor.SegmentUploadErrorInsufficientBalance: ErrCodePaymentInsufficientBalance,
+		monitor.SegmentUploadErrorTimeout:             ErrCodeIngestTimeout,
+	}
+	for label, code := range uploadErrors {
+		assert.Equal(string(label), code.String())
+	}
+
+	transcodeErrors := map[monitor.SegmentTranscodeError]ErrorCode{
+		monitor.SegmentTranscodeErrorUnknown:            ErrCodeUnknown,
+		monitor.SegmentTranscodeErrorUnknownResponse:    ErrCodeTranscodeUnknownResponse,
+		monitor.SegmentTranscodeErrorTranscode:          ErrCodeTranscodeFailed,
+		monitor.SegmentTranscodeErrorOrchestratorBusy:   ErrCodeTranscodeOrchestratorBusy,
+		monitor.SegmentTranscodeErrorOrchestratorCapped: ErrCodeTranscodeOrchestratorCapped,
+		monitor.SegmentTranscodeErrorParseResponse:      ErrCodeTranscodeParseResponse,
+		monitor.SegmentTranscodeErrorReadBody:           ErrCodeTranscodeReadBody,
+		monitor.SegmentTranscodeErrorNoOrchestrators:    ErrCodeTranscodeNoOrchestrators,
+		monitor.SegmentTranscodeErrorDownload:           ErrCodeStorageDownload,
+		monitor.SegmentTranscodeErrorSaveData:           ErrCodeStorageSaveData,
+		monitor.SegmentTranscodeErrorSessionEnded:       ErrCodeTranscodeSessionEnded,
+		monitor.SegmentTranscodeErrorPlaylist:           ErrCodeStoragePlaylist,
+	}
+	for label, code := range transcodeErrors {
+		assert.Equal(string(label), code.String())
+	}
+}
@@ -0,0 +1,48 @@
+package common
+
+import (
+	"sync"
+)
+
+// LogBuffer is an io.Writer that retains the most recent bytes written to it.
+// It is used to keep a window of recent log output in memory for diagnostics
+type LogBuffer struct {
+	mu   sync.Mutex
+	buf  []byte
+	size int
+}
+
+// NewLogBuffer creates a LogBuffer that retains up to size bytes
+func NewLogBuffer(size int) *LogBuffer {
+	return &LogBuffer{
+		buf:  make([]byte, 0, size),
+		size: size,
+	}
+}
+
+// Write appends p to the buffer, discarding the oldest data if the buffer is full
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := len(p)
+	if n >= b.size {
+		b.buf = append(b.buf[:0], p[n-b.size:]...)
+		return n, nil
+	}
+	if overflow := len(b.buf) + n - b.size; overflow > 0 {
+		b.buf = append(b.buf[:0], b.buf[overflow:]...)
+	}
+	b.buf = append(b.buf, p...)
+	return n, nil
+}
+
+// Bytes returns a copy of the retained data
+func (b *LogBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	res := make([]byte, len(b.buf))
+	copy(res, b.buf)
+	return res
+}
@@ -0,0 +1,38 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogBuffer(t *testing.T) {
+	assert := assert.New(t)
+
+	b := NewLogBuffer(8)
+	assert.Empty(b.Bytes())
+
+	n, err := b.Write([]byte("abc"))
+	assert.Nil(err)
+	assert.Equal(3, n)
+	assert.Equal("abc", string(b.Bytes()))
+
+	// Fill up to capacity
+	b.Write([]byte("defgh"))
+	assert.Equal("abcdefgh", string(b.Bytes()))
+
+	// Oldest data is discarded on overflow
+	b.Write([]byte("ij"))
+	assert.Equal("cdefghij", string(b.Bytes()))
+
+	// Writes larger than the buffer keep only the tail
+	n, err = b.Write([]byte("0123456789"))
+	assert.Nil(err)
+	assert.Equal(10, n)
+	assert.Equal("23456789", string(b.Bytes()))
+
+	// Returned bytes are a copy
+	res := b.Bytes()
+	res[0] = 'x'
+	assert.Equal("23456789", string(b.Bytes()))
+}
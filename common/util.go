@@ -15,9 +15,11 @@ import (
 	"testing"
 	"time"
 
+	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/golang/glog"
 	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/go-livepeer/pm"
 	ffmpeg "github.com/livepeer/lpms/ffmpeg"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc/peer"
@@ -43,8 +45,9 @@ var (
 	ErrProfName    = fmt.Errorf("unknown VideoProfile profile name")
 
 	ext2mime = map[string]string{
-		".ts":  "video/mp2t",
-		".mp4": "video/mp4",
+		".ts":   "video/mp2t",
+		".mp4":  "video/mp4",
+		".webm": "video/webm",
 	}
 )
 
@@ -176,6 +179,8 @@ func FFmpegProfiletoNetProfile(ffmpegProfiles []ffmpeg.VideoProfile) ([]*net.Vid
 		case ffmpeg.FormatMPEGTS:
 		case ffmpeg.FormatMP4:
 			format = net.VideoProfile_MP4
+		case FormatWebM:
+			format = net.VideoProfile_WEBM
 		default:
 			return nil, ErrFormatProto
 		}
@@ -190,6 +195,16 @@ func FFmpegProfiletoNetProfile(ffmpegProfiles []ffmpeg.VideoProfile) ([]*net.Vid
 			encoderProf = net.VideoProfile_H264_HIGH
 		case ffmpeg.ProfileH264ConstrainedHigh:
 			encoderProf = net.VideoProfile_H264_CONSTRAINED_HIGH
+		case ProfileH265Main:
+			encoderProf = net.VideoProfile_H265_MAIN
+		case ProfileAV1Main:
+			encoderProf = net.VideoProfile_AV1_MAIN
+		case ProfileVP8:
+			encoderProf = net.VideoProfile_VP8
+		case ProfileVP9:
+			encoderProf = net.VideoProfile_VP9
+		case ProfileAudioOnly:
+			encoderProf = net.VideoProfile_AUDIO_ONLY
 		default:
 			return nil, ErrProfProto
 		}
@@ -244,6 +259,12 @@ func EncoderProfileNameToValue(profile string) (ffmpeg.Profile, error) {
 		"h264main":            ffmpeg.ProfileH264Main,
 		"h264high":            ffmpeg.ProfileH264High,
 		"h264constrainedhigh": ffmpeg.ProfileH264ConstrainedHigh,
+		"h265main":            ProfileH265Main,
+		"hevcmain":            ProfileH265Main,
+		"av1main":             ProfileAV1Main,
+		"vp8":                 ProfileVP8,
+		"vp9":                 ProfileVP9,
+		"audioonly":           ProfileAudioOnly,
 	}
 	p, ok := EncoderProfileLookup[strings.ToLower(profile)]
 	if !ok {
@@ -261,6 +282,9 @@ func ProfileExtensionFormat(ext string) ffmpeg.Format {
 }
 
 func ProfileFormatExtension(f ffmpeg.Format) (string, error) {
+	if f == FormatWebM {
+		return webmExtension, nil
+	}
 	ext, ok := ffmpeg.FormatExtensions[f]
 	if !ok {
 		return "", ErrFormatExt
@@ -372,3 +396,27 @@ func RatPriceInfo(priceInfo *net.PriceInfo) (*big.Rat, error) {
 
 	return big.NewRat(priceInfo.PricePerUnit, pixelsPerUnit), nil
 }
+
+// PmPayoutSplit converts the payout split of ticket params sent over the wire
+func PmPayoutSplit(split *net.PayoutSplit) *pm.PayoutSplit {
+	if split == nil {
+		return nil
+	}
+
+	return &pm.PayoutSplit{
+		Recipient: ethcommon.BytesToAddress(split.Recipient),
+		Share:     split.Share,
+	}
+}
+
+// ProtoPayoutSplit converts the payout split of ticket params to send it over the wire
+func ProtoPayoutSplit(split *pm.PayoutSplit) *net.PayoutSplit {
+	if split == nil {
+		return nil
+	}
+
+	return &net.PayoutSplit{
+		Recipient: split.Recipient.Bytes(),
+		Share:     split.Share,
+	}
+}
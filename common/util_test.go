@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/go-livepeer/pm"
 	"github.com/livepeer/lpms/ffmpeg"
 	"github.com/stretchr/testify/assert"
 )
@@ -130,6 +131,35 @@ func TestFFmpegProfiletoNetProfile(t *testing.T) {
 	assert.Equal(fullProfiles[0].Gop, int32(123))
 	assert.Equal(fullProfiles[1].Gop, int32(-100))
 
+	// Verify H265 profile
+	profiles[1].Profile = ProfileH265Main
+	fullProfiles, err = FFmpegProfiletoNetProfile(profiles)
+	assert.Nil(err)
+	assert.Equal(net.VideoProfile_H265_MAIN, fullProfiles[1].Profile)
+
+	// Verify AV1 profile
+	profiles[1].Profile = ProfileAV1Main
+	fullProfiles, err = FFmpegProfiletoNetProfile(profiles)
+	assert.Nil(err)
+	assert.Equal(net.VideoProfile_AV1_MAIN, fullProfiles[1].Profile)
+
+	// Verify WebM format and VP9 profile
+	profiles[1].Profile = ProfileVP9
+	profiles[1].Format = FormatWebM
+	fullProfiles, err = FFmpegProfiletoNetProfile(profiles)
+	assert.Nil(err)
+	assert.Equal(net.VideoProfile_VP9, fullProfiles[1].Profile)
+	assert.Equal(net.VideoProfile_WEBM, fullProfiles[1].Format)
+	profiles[1].Profile = ffmpeg.ProfileNone
+	profiles[1].Format = ffmpeg.FormatNone
+
+	// Verify audio-only profile
+	profiles[1].Profile = ProfileAudioOnly
+	fullProfiles, err = FFmpegProfiletoNetProfile(profiles)
+	assert.Nil(err)
+	assert.Equal(net.VideoProfile_AUDIO_ONLY, fullProfiles[1].Profile)
+	profiles[1].Profile = ffmpeg.ProfileNone
+
 	// Invalid format should return error
 	profiles[1].Format = -1
 	fullProfiles, err = FFmpegProfiletoNetProfile(profiles)
@@ -183,6 +213,11 @@ func TestVideoProfile_FormatMimeType(t *testing.T) {
 	if _, ok := ffmpeg.FormatExtensions[-1]; ok {
 		t.Error("Sanity check failed; did not clean up extension")
 	}
+
+	// formats that LPMS doesn't enumerate
+	if m, err := ProfileFormatMimeType(FormatWebM); m != "video/webm" || err != nil {
+		t.Error("Mismatched format; expected video/webm got ", m)
+	}
 }
 
 func TestVideoProfile_FormatExtension(t *testing.T) {
@@ -200,6 +235,9 @@ func TestVideoProfile_FormatExtension(t *testing.T) {
 	if _, err := ProfileFormatExtension(-1); err != ErrFormatExt {
 		t.Error("Did not get expected error")
 	}
+	if m, err := ProfileFormatExtension(FormatWebM); m != ".webm" || err != nil {
+		t.Error("Mismatched format; expected .webm got ", m)
+	}
 }
 
 func TestVideoProfile_ProfileNameToValue(t *testing.T) {
@@ -215,6 +253,18 @@ func TestVideoProfile_ProfileNameToValue(t *testing.T) {
 	}
 	p, _ := EncoderProfileNameToValue("none")
 	assert.Equal(ffmpeg.ProfileNone, p)
+	p, _ = EncoderProfileNameToValue("H265Main")
+	assert.Equal(ProfileH265Main, p)
+	p, _ = EncoderProfileNameToValue("hevcmain")
+	assert.Equal(ProfileH265Main, p)
+	p, _ = EncoderProfileNameToValue("AV1Main")
+	assert.Equal(ProfileAV1Main, p)
+	p, _ = EncoderProfileNameToValue("VP8")
+	assert.Equal(ProfileVP8, p)
+	p, _ = EncoderProfileNameToValue("vp9")
+	assert.Equal(ProfileVP9, p)
+	p, _ = EncoderProfileNameToValue("AudioOnly")
+	assert.Equal(ProfileAudioOnly, p)
 	_, err := EncoderProfileNameToValue("invalid")
 	assert.Equal(ErrProfName, err, "Could not get profile value")
 }
@@ -332,3 +382,16 @@ func TestRatPriceInfo(t *testing.T) {
 	assert.Nil(err)
 	assert.Zero(priceInfo.Cmp(big.NewRat(7, 2)))
 }
+
+func TestPayoutSplitConversion(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(PmPayoutSplit(nil))
+	assert.Nil(ProtoPayoutSplit(nil))
+
+	split := &pm.PayoutSplit{Recipient: pm.RandAddress(), Share: 125000}
+	protoSplit := ProtoPayoutSplit(split)
+	assert.Equal(split.Recipient.Bytes(), protoSplit.Recipient)
+	assert.Equal(int64(125000), protoSplit.Share)
+	assert.Equal(split, PmPayoutSplit(protoSplit))
+}
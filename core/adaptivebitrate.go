@@ -0,0 +1,102 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/lpms/ffmpeg"
+)
+
+// ReferenceBitsPerPixel is the bits per pixel of each frame of a source of average
+// complexity, which the renditions are encoded at the bitrates of their profiles for
+const ReferenceBitsPerPixel = 0.1
+
+// AdaptiveBitrate scales the bitrates of the renditions of a segment with the
+// complexity of its content, within bounds set by the operator as percentages of
+// the bitrates of the profiles
+type AdaptiveBitrate struct {
+	MinPercent int
+	MaxPercent int
+}
+
+// ParseAdaptiveBitrate parses the bounds of adaptive bitrates as a comma separated
+// pair of percentages, e.g. 50,120
+func ParseAdaptiveBitrate(s string) (*AdaptiveBitrate, error) {
+	bounds := strings.Split(s, ",")
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("adaptive bitrate bounds %q are not of the form min,max", s)
+	}
+	min, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil || min <= 0 {
+		return nil, fmt.Errorf("invalid minimum percent of adaptive bitrate bounds %q", s)
+	}
+	max, err := strconv.Atoi(strings.TrimSpace(bounds[1]))
+	if err != nil || max < min {
+		return nil, fmt.Errorf("invalid maximum percent of adaptive bitrate bounds %q", s)
+	}
+	return &AdaptiveBitrate{MinPercent: min, MaxPercent: max}, nil
+}
+
+// SegmentComplexity estimates the complexity of the content of a source segment
+// from the bits that the source encoder spent on each pixel, relative to
+// ReferenceBitsPerPixel. Sources at a constant bitrate look equally complex
+// throughout. It returns 0 if the resolution or the duration are unknown.
+func SegmentComplexity(source ffmpeg.VideoProfile, size int, duration float64) float64 {
+	w, h, err := ffmpeg.VideoProfileResolution(source)
+	if err != nil || w <= 0 || h <= 0 || duration <= 0 {
+		return 0
+	}
+	fps := float64(source.Framerate)
+	if source.FramerateDen > 1 {
+		fps /= float64(source.FramerateDen)
+	}
+	if fps <= 0 {
+		// unknown frame rate, so assume a common source rate
+		fps = 30
+	}
+	bpp := float64(size) * 8 / (duration * fps * float64(w) * float64(h))
+	return bpp / ReferenceBitsPerPixel
+}
+
+// Percent returns the percentage of the bitrates of the profiles that renditions
+// of a segment are encoded at, given its complexity
+func (a *AdaptiveBitrate) Percent(complexity float64) int {
+	percent := int(math.Round(complexity * 100))
+	if percent < a.MinPercent {
+		return a.MinPercent
+	}
+	if percent > a.MaxPercent {
+		return a.MaxPercent
+	}
+	return percent
+}
+
+// Profiles returns copies of the profiles with their bitrates scaled to the
+// complexity of a segment. Audio-only profiles, and profiles whose bitrate can't be
+// parsed, are kept as is.
+func (a *AdaptiveBitrate) Profiles(profiles []ffmpeg.VideoProfile, complexity float64) []ffmpeg.VideoProfile {
+	percent := a.Percent(complexity)
+	res := make([]ffmpeg.VideoProfile, len(profiles))
+	copy(res, profiles)
+	if percent == 100 {
+		return res
+	}
+	for i, p := range profiles {
+		if common.ProfileCodec(p.Profile) == common.NoVideo {
+			continue
+		}
+		bitrate, err := strconv.Atoi(strings.Replace(p.Bitrate, "k", "000", 1))
+		if err != nil || bitrate <= 0 {
+			continue
+		}
+		kbps := int64(bitrate) * int64(percent) / 100 / 1000
+		if kbps < 1 {
+			kbps = 1
+		}
+		res[i].Bitrate = fmt.Sprintf("%dk", kbps)
+	}
+	return res
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAdaptiveBitrate(t *testing.T) {
+	assert := assert.New(t)
+
+	ab, err := ParseAdaptiveBitrate("50, 120")
+	assert.Nil(err)
+	assert.Equal(&AdaptiveBitrate{MinPercent: 50, MaxPercent: 120}, ab)
+
+	ab, err = ParseAdaptiveBitrate("100,100")
+	assert.Nil(err)
+	assert.Equal(&AdaptiveBitrate{MinPercent: 100, MaxPercent: 100}, ab)
+
+	for _, s := range []string{"", "50", "50,", "0,100", "-10,100", "120,50", "a,100", "50,b", "50,100,150"} {
+		_, err = ParseAdaptiveBitrate(s)
+		assert.Error(err, s)
+	}
+}
+
+func TestSegmentComplexity(t *testing.T) {
+	assert := assert.New(t)
+	source := ffmpeg.VideoProfile{Resolution: "1280x720", Framerate: 30}
+
+	// 0.1 bits per pixel of each frame is of average complexity
+	size := int(0.1 * 1280 * 720 * 30 * 2 / 8)
+	assert.InDelta(1.0, SegmentComplexity(source, size, 2.0), 0.001)
+	assert.InDelta(0.5, SegmentComplexity(source, size/2, 2.0), 0.001)
+
+	// the frame rate of the source is assumed if unknown
+	source.Framerate = 0
+	assert.InDelta(1.0, SegmentComplexity(source, size, 2.0), 0.001)
+	source.Framerate = 60000
+	source.FramerateDen = 1001
+	assert.InDelta(0.5, SegmentComplexity(source, size, 2.0), 0.001)
+
+	// unknown resolution or duration
+	assert.Zero(SegmentComplexity(ffmpeg.VideoProfile{}, size, 2.0))
+	assert.Zero(SegmentComplexity(ffmpeg.VideoProfile{Resolution: "0x0"}, size, 2.0))
+	assert.Zero(SegmentComplexity(source, size, 0))
+}
+
+func TestAdaptiveBitrate_Profiles(t *testing.T) {
+	assert := assert.New(t)
+	ab := &AdaptiveBitrate{MinPercent: 50, MaxPercent: 120}
+
+	assert.Equal(50, ab.Percent(0.2))
+	assert.Equal(75, ab.Percent(0.75))
+	assert.Equal(120, ab.Percent(3))
+
+	audio := ffmpeg.VideoProfile{Name: "audio", Bitrate: "128k", Profile: common.ProfileAudioOnly}
+	profiles := []ffmpeg.VideoProfile{ffmpeg.P720p30fps16x9, ffmpeg.P144p30fps16x9, audio}
+	adapted := ab.Profiles(profiles, 0.75)
+	assert.Equal("3000k", adapted[0].Bitrate)
+	assert.Equal("300k", adapted[1].Bitrate)
+	assert.Equal("128k", adapted[2].Bitrate)
+	assert.Equal(ffmpeg.P720p30fps16x9.Name, adapted[0].Name)
+	// the profiles are copied
+	assert.Equal("4000k", profiles[0].Bitrate)
+
+	adapted = ab.Profiles(profiles, 10)
+	assert.Equal("4800k", adapted[0].Bitrate)
+
+	// bitrates in bits per second are parsed too
+	adapted = ab.Profiles([]ffmpeg.VideoProfile{{Bitrate: "1000000"}, {Bitrate: "invalid"}}, 0.5)
+	assert.Equal("500k", adapted[0].Bitrate)
+	assert.Equal("invalid", adapted[1].Bitrate)
+}
@@ -0,0 +1,220 @@
+package core
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// BandwidthRateWindow is the window over which bandwidth rates are computed
+var BandwidthRateWindow = 60 * time.Second
+
+// BandwidthStats holds a snapshot of the bytes transferred for a stream or a peer
+type BandwidthStats struct {
+	ID           string
+	IngressBytes int64
+	EgressBytes  int64
+	IngressRate  float64 // bytes per second over BandwidthRateWindow
+	EgressRate   float64 // bytes per second over BandwidthRateWindow
+	LastActivity time.Time
+}
+
+type bandwidthSample struct {
+	time    time.Time
+	ingress int64
+	egress  int64
+}
+
+type bandwidthCounter struct {
+	ingress      int64
+	egress       int64
+	samples      []bandwidthSample
+	lastActivity time.Time
+}
+
+// BandwidthTracker aggregates the ingress and egress bytes of a node per stream and per peer.
+// A peer is an orchestrator URI on a broadcaster and a broadcaster address on an orchestrator.
+// All methods are safe to call on a nil tracker, in which case records are discarded
+type BandwidthTracker struct {
+	mu      sync.Mutex
+	streams map[ManifestID]*bandwidthCounter
+	peers   map[string]*bandwidthCounter
+	total   bandwidthCounter
+	now     func() time.Time
+}
+
+// NewBandwidthTracker creates a new BandwidthTracker instance
+func NewBandwidthTracker() *BandwidthTracker {
+	return &BandwidthTracker{
+		streams: make(map[ManifestID]*bandwidthCounter),
+		peers:   make(map[string]*bandwidthCounter),
+		now:     time.Now,
+	}
+}
+
+// RecordIngress records bytes received for a stream from a peer. The peer may be empty
+// if the bytes did not come from another node, e.g. for RTMP ingest
+func (t *BandwidthTracker) RecordIngress(manifestID ManifestID, peer string, bytes int64) {
+	t.record(manifestID, peer, bytes, 0)
+}
+
+// RecordEgress records bytes sent for a stream to a peer. The peer may be empty
+// if the bytes were not sent to another node, e.g. for HLS playback
+func (t *BandwidthTracker) RecordEgress(manifestID ManifestID, peer string, bytes int64) {
+	t.record(manifestID, peer, 0, bytes)
+}
+
+func (t *BandwidthTracker) record(manifestID ManifestID, peer string, ingress, egress int64) {
+	if t == nil || (ingress <= 0 && egress <= 0) {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.total.add(now, ingress, egress)
+	if manifestID != "" {
+		c, ok := t.streams[manifestID]
+		if !ok {
+			c = &bandwidthCounter{}
+			t.streams[manifestID] = c
+		}
+		c.add(now, ingress, egress)
+	}
+	if peer != "" {
+		c, ok := t.peers[peer]
+		if !ok {
+			c = &bandwidthCounter{}
+			t.peers[peer] = c
+		}
+		c.add(now, ingress, egress)
+	}
+}
+
+// RemoveStream drops the stats of a stream. Stats of the peers of the stream and totals are kept
+func (t *BandwidthTracker) RemoveStream(manifestID ManifestID) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.streams, manifestID)
+}
+
+// StreamStats returns a snapshot of the stats for all streams ordered by most recent activity
+func (t *BandwidthTracker) StreamStats() []*BandwidthStats {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	now := t.now()
+	res := make([]*BandwidthStats, 0, len(t.streams))
+	for mid, c := range t.streams {
+		res = append(res, c.snapshot(string(mid), now))
+	}
+	t.mu.Unlock()
+
+	sortBandwidthStats(res)
+	return res
+}
+
+// PeerStats returns a snapshot of the stats for all peers ordered by most recent activity
+func (t *BandwidthTracker) PeerStats() []*BandwidthStats {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	now := t.now()
+	res := make([]*BandwidthStats, 0, len(t.peers))
+	for peer, c := range t.peers {
+		res = append(res, c.snapshot(peer, now))
+	}
+	t.mu.Unlock()
+
+	sortBandwidthStats(res)
+	return res
+}
+
+// Totals returns a snapshot of the bytes transferred by the node
+func (t *BandwidthTracker) Totals() *BandwidthStats {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.total.snapshot("total", t.now())
+}
+
+// Caller of this function should hold the lock
+func (c *bandwidthCounter) add(now time.Time, ingress, egress int64) {
+	c.ingress += ingress
+	c.egress += egress
+	c.lastActivity = now
+	c.prune(now)
+
+	// Samples are bucketed per second to bound memory use
+	sec := now.Truncate(time.Second)
+	if n := len(c.samples); n > 0 && c.samples[n-1].time.Equal(sec) {
+		c.samples[n-1].ingress += ingress
+		c.samples[n-1].egress += egress
+		return
+	}
+	c.samples = append(c.samples, bandwidthSample{time: sec, ingress: ingress, egress: egress})
+}
+
+// Caller of this function should hold the lock
+func (c *bandwidthCounter) prune(now time.Time) {
+	cutoff := now.Add(-BandwidthRateWindow)
+	i := 0
+	for i < len(c.samples) && !c.samples[i].time.After(cutoff) {
+		i++
+	}
+	c.samples = c.samples[i:]
+}
+
+// Caller of this function should hold the lock
+func (c *bandwidthCounter) snapshot(id string, now time.Time) *BandwidthStats {
+	c.prune(now)
+
+	var ingress, egress int64
+	for _, s := range c.samples {
+		ingress += s.ingress
+		egress += s.egress
+	}
+	window := BandwidthRateWindow.Seconds()
+	return &BandwidthStats{
+		ID:           id,
+		IngressBytes: c.ingress,
+		EgressBytes:  c.egress,
+		IngressRate:  float64(ingress) / window,
+		EgressRate:   float64(egress) / window,
+		LastActivity: c.lastActivity,
+	}
+}
+
+// BandwidthPeer returns the bandwidth peer of a stream on an orchestrator, which is the
+// address of the broadcaster that pays for the stream. Returns an empty string if the
+// sender of the stream is unknown
+func (n *LivepeerNode) BandwidthPeer(manifestID ManifestID) string {
+	if n.SenderStats == nil {
+		return ""
+	}
+	sender, ok := n.SenderStats.Sender(manifestID)
+	if !ok {
+		return ""
+	}
+	return sender.Hex()
+}
+
+func sortBandwidthStats(stats []*BandwidthStats) {
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].LastActivity.After(stats[j].LastActivity)
+	})
+}
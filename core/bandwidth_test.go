@@ -0,0 +1,132 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/livepeer/go-livepeer/pm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBandwidthTracker_Record(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(1000, 0)
+	bt := NewBandwidthTracker()
+	bt.now = func() time.Time { return now }
+
+	bt.RecordIngress("foo", "", 100)
+	bt.RecordEgress("foo", "orchA", 60)
+	bt.RecordIngress("foo", "orchA", 40)
+	bt.RecordEgress("bar", "orchB", 10)
+	// Empty records are dropped
+	bt.RecordEgress("baz", "orchC", 0)
+
+	streams := bt.StreamStats()
+	assert.Len(streams, 2)
+	byID := make(map[string]*BandwidthStats)
+	for _, s := range streams {
+		byID[s.ID] = s
+	}
+	assert.Equal(int64(140), byID["foo"].IngressBytes)
+	assert.Equal(int64(60), byID["foo"].EgressBytes)
+	assert.Equal(int64(0), byID["bar"].IngressBytes)
+	assert.Equal(int64(10), byID["bar"].EgressBytes)
+
+	peers := bt.PeerStats()
+	assert.Len(peers, 2)
+	byID = make(map[string]*BandwidthStats)
+	for _, s := range peers {
+		byID[s.ID] = s
+	}
+	assert.Equal(int64(40), byID["orchA"].IngressBytes)
+	assert.Equal(int64(60), byID["orchA"].EgressBytes)
+	assert.Equal(int64(10), byID["orchB"].EgressBytes)
+
+	total := bt.Totals()
+	assert.Equal(int64(140), total.IngressBytes)
+	assert.Equal(int64(70), total.EgressBytes)
+	assert.Equal(now, total.LastActivity)
+}
+
+func TestBandwidthTracker_Rates(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(1000, 0)
+	bt := NewBandwidthTracker()
+	bt.now = func() time.Time { return now }
+	window := BandwidthRateWindow.Seconds()
+
+	bt.RecordIngress("foo", "", 600)
+	now = now.Add(500 * time.Millisecond)
+	bt.RecordEgress("foo", "", 300)
+
+	s := bt.StreamStats()[0]
+	assert.Equal(600/window, s.IngressRate)
+	assert.Equal(300/window, s.EgressRate)
+
+	// Samples older than the window no longer count towards the rate
+	now = now.Add(BandwidthRateWindow / 2)
+	bt.RecordIngress("foo", "", 60)
+	now = now.Add(BandwidthRateWindow / 2)
+	s = bt.StreamStats()[0]
+	assert.Equal(int64(660), s.IngressBytes)
+	assert.Equal(int64(300), s.EgressBytes)
+	assert.Equal(60/window, s.IngressRate)
+	assert.Equal(0.0, s.EgressRate)
+}
+
+func TestBandwidthTracker_Ordering(t *testing.T) {
+	now := time.Unix(1000, 0)
+	bt := NewBandwidthTracker()
+	bt.now = func() time.Time { return now }
+
+	bt.RecordIngress("foo", "orchA", 1)
+	now = now.Add(time.Second)
+	bt.RecordIngress("bar", "orchB", 1)
+
+	streams := bt.StreamStats()
+	assert.Equal(t, "bar", streams[0].ID)
+	assert.Equal(t, "foo", streams[1].ID)
+	peers := bt.PeerStats()
+	assert.Equal(t, "orchB", peers[0].ID)
+	assert.Equal(t, "orchA", peers[1].ID)
+}
+
+func TestBandwidthTracker_RemoveStream(t *testing.T) {
+	assert := assert.New(t)
+
+	bt := NewBandwidthTracker()
+	bt.RecordIngress("foo", "orchA", 10)
+	bt.RemoveStream("foo")
+
+	assert.Empty(bt.StreamStats())
+	assert.Len(bt.PeerStats(), 1)
+	assert.Equal(int64(10), bt.Totals().IngressBytes)
+}
+
+func TestBandwidthTracker_Nil(t *testing.T) {
+	var bt *BandwidthTracker
+	assert.NotPanics(t, func() {
+		bt.RecordIngress("foo", "bar", 1)
+		bt.RecordEgress("foo", "bar", 1)
+		bt.RemoveStream("foo")
+	})
+	assert.Nil(t, bt.StreamStats())
+	assert.Nil(t, bt.PeerStats())
+	assert.Nil(t, bt.Totals())
+}
+
+func TestBandwidthPeer(t *testing.T) {
+	assert := assert.New(t)
+
+	n, _ := NewLivepeerNode(nil, "", nil)
+	assert.Equal("", n.BandwidthPeer("foo"))
+
+	sender := pm.RandAddress()
+	n.SenderStats.RecordSession(sender, "foo")
+	assert.Equal(sender.Hex(), n.BandwidthPeer("foo"))
+
+	n.SenderStats = nil
+	assert.Equal("", n.BandwidthPeer("foo"))
+}
@@ -2,7 +2,10 @@ package core
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/livepeer/go-livepeer/common"
 	"github.com/livepeer/go-livepeer/drivers"
 	"github.com/livepeer/go-livepeer/net"
 	"github.com/livepeer/lpms/ffmpeg"
@@ -33,8 +36,73 @@ const (
 	Capability_ProfileH264High
 	Capability_ProfileH264ConstrainedHigh
 	Capability_GOP
+	Capability_HEVC
+	Capability_AV1Decode
+	Capability_AV1Encode
+	Capability_WebM
+	Capability_VP8
+	Capability_VP9
+	Capability_AudioOnly
+	Capability_Clip
 )
 
+// capabilityNames are the names with which capabilities are configured and reported
+var capabilityNames = map[Capability]string{
+	Capability_H264:                       "h264",
+	Capability_MPEGTS:                     "mpegts",
+	Capability_MP4:                        "mp4",
+	Capability_FractionalFramerates:       "fractional_framerates",
+	Capability_StorageDirect:              "storage_direct",
+	Capability_StorageS3:                  "storage_s3",
+	Capability_StorageGCS:                 "storage_gcs",
+	Capability_ProfileH264Baseline:        "h264_baseline",
+	Capability_ProfileH264Main:            "h264_main",
+	Capability_ProfileH264High:            "h264_high",
+	Capability_ProfileH264ConstrainedHigh: "h264_constrained_high",
+	Capability_GOP:                        "gop",
+	Capability_HEVC:                       "hevc",
+	Capability_AV1Decode:                  "av1_decode",
+	Capability_AV1Encode:                  "av1_encode",
+	Capability_WebM:                       "webm",
+	Capability_VP8:                        "vp8",
+	Capability_VP9:                        "vp9",
+	Capability_AudioOnly:                  "audio_only",
+	Capability_Clip:                       "clip",
+}
+
+func (c Capability) String() string {
+	if name, ok := capabilityNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("capability_%d", int(c))
+}
+
+// ParseCapabilities parses a comma separated list of capability names
+func ParseCapabilities(names string) ([]Capability, error) {
+	caps := []Capability{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		c, err := capabilityFromName(name)
+		if err != nil {
+			return nil, err
+		}
+		caps = append(caps, c)
+	}
+	return caps, nil
+}
+
+func capabilityFromName(name string) (Capability, error) {
+	for c, n := range capabilityNames {
+		if n == name {
+			return c, nil
+		}
+	}
+	return Capability_Invalid, fmt.Errorf("capability: unknown capability %q", name)
+}
+
 var capFormatConv = errors.New("capability: unknown format")
 var capStorageConv = errors.New("capability: unknown storage")
 var capProfileConv = errors.New("capability: unknown profile")
@@ -70,12 +138,33 @@ func (c1 CapabilityString) CompatibleWith(c2 CapabilityString) bool {
 	return true
 }
 
+// Capabilities returns the capabilities set in the bitstring, in ascending order
+func (c CapabilityString) Capabilities() []Capability {
+	caps := []Capability{}
+	for i, bits := range c {
+		for j := 0; j < 64; j++ {
+			if bits&(1<<uint(j)) != 0 {
+				caps = append(caps, Capability(i*64+j))
+			}
+		}
+	}
+	return caps
+}
+
 func JobCapabilities(params *StreamParameters) (*Capabilities, error) {
 	caps := make(map[Capability]bool)
 
 	// Define any default capabilities (especially ones that may be mandatory)
 	caps[Capability_H264] = true
 
+	// capabilities explicitly required by the broadcaster
+	for _, c := range params.RequiredCapabilities {
+		if c <= Capability_Unused {
+			return nil, fmt.Errorf("capability: invalid required capability %v", int(c))
+		}
+		caps[c] = true
+	}
+
 	// capabilities based on requested output
 	for _, v := range params.Profiles {
 		// set format
@@ -145,6 +234,18 @@ func (bcast *Capabilities) CompatibleWith(orch *net.Capabilities) bool {
 	return bcast.bitstring.CompatibleWith(orch.Bitstring)
 }
 
+// Names returns the names of the capabilities of the bitstring, for status and logs
+func (c *Capabilities) Names() []string {
+	if c == nil {
+		return nil
+	}
+	var names []string
+	for _, capability := range c.bitstring.Capabilities() {
+		names = append(names, capability.String())
+	}
+	return names
+}
+
 func (c *Capabilities) ToNetCapabilities() *net.Capabilities {
 	if c == nil {
 		return nil
@@ -181,6 +282,8 @@ func formatToCapability(format ffmpeg.Format) (Capability, error) {
 		return Capability_MPEGTS, nil
 	case ffmpeg.FormatMP4:
 		return Capability_MP4, nil
+	case common.FormatWebM:
+		return Capability_WebM, nil
 	}
 	return Capability_Invalid, capFormatConv
 }
@@ -212,6 +315,16 @@ func profileToCapability(profile ffmpeg.Profile) (Capability, error) {
 		return Capability_ProfileH264High, nil
 	case ffmpeg.ProfileH264ConstrainedHigh:
 		return Capability_ProfileH264ConstrainedHigh, nil
+	case common.ProfileH265Main:
+		return Capability_HEVC, nil
+	case common.ProfileAV1Main:
+		return Capability_AV1Encode, nil
+	case common.ProfileVP8:
+		return Capability_VP8, nil
+	case common.ProfileVP9:
+		return Capability_VP9, nil
+	case common.ProfileAudioOnly:
+		return Capability_AudioOnly, nil
 	}
 	return Capability_Invalid, capProfileConv
 }
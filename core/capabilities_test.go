@@ -4,6 +4,7 @@ import (
 	"sort"
 	"testing"
 
+	"github.com/livepeer/go-livepeer/common"
 	"github.com/livepeer/go-livepeer/drivers"
 	"github.com/livepeer/go-livepeer/net"
 	"github.com/livepeer/lpms/ffmpeg"
@@ -149,6 +150,51 @@ func TestCapability_JobCapabilities(t *testing.T) {
 	params.OS = &stubOS{storageType: -1}
 	_, err = JobCapabilities(params)
 	assert.Equal(capStorageConv, err)
+
+	// check required capabilities
+	params.OS = nil
+	params.RequiredCapabilities = []Capability{Capability_MP4, Capability_GOP}
+	assert.True(checkSuccess(params, []Capability{
+		Capability_H264,
+		Capability_MP4,
+		Capability_GOP,
+	}), "failed with required capabilities")
+
+	// check error case with required capabilities
+	params.RequiredCapabilities = []Capability{Capability_Invalid}
+	_, err = JobCapabilities(params)
+	assert.EqualError(err, "capability: invalid required capability -2")
+}
+
+func TestCapability_Names(t *testing.T) {
+	assert := assert.New(t)
+
+	caps, err := ParseCapabilities(" mp4, gop,,h264_high ")
+	assert.Nil(err)
+	assert.Equal([]Capability{Capability_MP4, Capability_GOP, Capability_ProfileH264High}, caps)
+	caps, err = ParseCapabilities("")
+	assert.Nil(err)
+	assert.Empty(caps)
+	_, err = ParseCapabilities("mp4,foo")
+	assert.EqualError(err, `capability: unknown capability "foo"`)
+
+	// every capability has a name that parses back to it
+	for c, name := range capabilityNames {
+		caps, err := ParseCapabilities(c.String())
+		assert.Nil(err)
+		assert.Equal([]Capability{c}, caps, name)
+	}
+	assert.Equal("capability_1000", Capability(1000).String())
+
+	// names are listed in ascending order
+	assert.Equal([]string{"h264", "mp4", "gop", "capability_100"},
+		NewCapabilities([]Capability{100, Capability_GOP, Capability_MP4, Capability_H264}, nil).Names())
+	assert.Empty(NewCapabilities(nil, nil).Names())
+	var nilCaps *Capabilities
+	assert.Nil(nilCaps.Names())
+	for _, c := range legacyCapabilities {
+		assert.Contains(capabilityNames, c)
+	}
 }
 
 func TestCapability_CompatibleWithNetCap(t *testing.T) {
@@ -240,7 +286,11 @@ func TestCapability_FormatToCapability(t *testing.T) {
 		assert.Nil(err)
 	}
 	// ensure error is triggered for unrepresented values
-	c, err := formatToCapability(-100)
+	c, err := formatToCapability(common.FormatWebM)
+	assert.Nil(err)
+	assert.Equal(Capability_WebM, c)
+	// ensure error is triggered for unrepresented values
+	c, err = formatToCapability(-100)
 	assert.Equal(Capability_Invalid, c)
 	assert.Equal(capFormatConv, err)
 }
@@ -302,8 +352,26 @@ func TestCapability_ProfileToCapability(t *testing.T) {
 		assert.Equal(caps[i], c)
 	}
 
+	c, err := profileToCapability(common.ProfileH265Main)
+	assert.Nil(err)
+	assert.Equal(Capability_HEVC, c)
+
+	c, err = profileToCapability(common.ProfileAV1Main)
+	assert.Nil(err)
+	assert.Equal(Capability_AV1Encode, c)
+
+	c, err = profileToCapability(common.ProfileVP8)
+	assert.Nil(err)
+	assert.Equal(Capability_VP8, c)
+	c, err = profileToCapability(common.ProfileVP9)
+	assert.Nil(err)
+	assert.Equal(Capability_VP9, c)
+	c, err = profileToCapability(common.ProfileAudioOnly)
+	assert.Nil(err)
+	assert.Equal(Capability_AudioOnly, c)
+
 	// check invalid profile handling
-	c, err := profileToCapability(-1)
+	c, err = profileToCapability(-1)
 	assert.Equal(Capability_Invalid, c)
 	assert.Equal(capProfileConv, err)
 }
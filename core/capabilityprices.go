@@ -0,0 +1,120 @@
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/lpms/ffmpeg"
+)
+
+// CapabilityPrices are the prices of the pixels of the renditions that need a
+// capability, as percentages of the price per pixel. Renditions that need none of
+// the capabilities are charged the price per pixel.
+type CapabilityPrices map[Capability]uint32
+
+// MaxCapabilityPercent is the highest price of a capability, as a percentage of the
+// price per pixel
+const MaxCapabilityPercent = 1000
+
+// pricedCapabilities are the capabilities that can be priced: the codecs that are
+// slower to encode than H264. The other capabilities, e.g. the H264 profiles, are
+// charged the price per pixel
+var pricedCapabilities = map[Capability]bool{
+	Capability_HEVC:      true,
+	Capability_AV1Encode: true,
+	Capability_VP8:       true,
+	Capability_VP9:       true,
+}
+
+// ParseCapabilityPrices parses a comma separated list of capability=percent pairs,
+// e.g. av1_encode=400
+func ParseCapabilityPrices(s string) (CapabilityPrices, error) {
+	prices := CapabilityPrices{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("capability price %q is not of the form capability=percent", pair)
+		}
+		c, err := capabilityFromName(strings.TrimSpace(kv[0]))
+		if err != nil {
+			return nil, err
+		}
+		if !pricedCapabilities[c] {
+			return nil, fmt.Errorf("capability %v of capability price %q can't be priced", c, pair)
+		}
+		percent, err := strconv.ParseUint(strings.TrimSpace(kv[1]), 10, 32)
+		if err != nil || percent == 0 || percent > MaxCapabilityPercent {
+			return nil, fmt.Errorf("invalid percent of capability price %q, must be between 1 and %v", pair, MaxCapabilityPercent)
+		}
+		prices[c] = uint32(percent)
+	}
+	return prices, nil
+}
+
+// CapabilityPricesFromNet returns the capability prices advertised by an orchestrator.
+// The prices of the capabilities that can't be priced are ignored, and the percents
+// above MaxCapabilityPercent are kept so that the orchestrator can be refused
+func CapabilityPricesFromNet(prices []*net.CapabilityPrice) CapabilityPrices {
+	res := CapabilityPrices{}
+	for _, p := range prices {
+		if p.Percent > 0 && pricedCapabilities[Capability(p.Capability)] {
+			res[Capability(p.Capability)] = p.Percent
+		}
+	}
+	return res
+}
+
+// ToNet returns the capability prices to advertise to broadcasters, by capability
+func (p CapabilityPrices) ToNet() []*net.CapabilityPrice {
+	if len(p) == 0 {
+		return nil
+	}
+	res := make([]*net.CapabilityPrice, 0, len(p))
+	for c, percent := range p {
+		res = append(res, &net.CapabilityPrice{Capability: uint32(c), Percent: percent})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Capability < res[j].Capability })
+	return res
+}
+
+// ProfilePercent returns the price of the pixels of a profile as a percentage of
+// the price per pixel, from the capability of its encoder profile
+func (p CapabilityPrices) ProfilePercent(profile ffmpeg.VideoProfile) uint32 {
+	if c, err := profileToCapability(profile.Profile); err == nil && pricedCapabilities[c] {
+		if percent, ok := p[c]; ok {
+			return percent
+		}
+	}
+	return 100
+}
+
+// MaxPercent returns the highest price of the profiles, as a percentage of the price
+// per pixel
+func (p CapabilityPrices) MaxPercent(profiles []ffmpeg.VideoProfile) uint32 {
+	max := uint32(100)
+	for _, profile := range profiles {
+		if percent := p.ProfilePercent(profile); percent > max {
+			max = percent
+		}
+	}
+	return max
+}
+
+// PricedPixels returns the pixels of a rendition of a profile, scaled by the price
+// of the profile, to charge at the price per pixel
+func (p CapabilityPrices) PricedPixels(profile ffmpeg.VideoProfile, pixels int64) int64 {
+	percent := p.ProfilePercent(profile)
+	if percent == 100 {
+		return pixels
+	}
+	priced := new(big.Int).Mul(big.NewInt(pixels), big.NewInt(int64(percent)))
+	return priced.Div(priced, big.NewInt(100)).Int64()
+}
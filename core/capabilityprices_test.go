@@ -0,0 +1,79 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCapabilityPrices(t *testing.T) {
+	assert := assert.New(t)
+
+	prices, err := ParseCapabilityPrices("")
+	assert.Nil(err)
+	assert.Empty(prices)
+
+	prices, err = ParseCapabilityPrices("av1_encode=400, hevc = 150")
+	assert.Nil(err)
+	assert.Equal(CapabilityPrices{Capability_AV1Encode: 400, Capability_HEVC: 150}, prices)
+
+	for _, s := range []string{"av1_encode", "av1_encode=", "av1_encode=0", "av1_encode=-1", "av1_encode=abc", "invalid=400", "av1_encode=1001", "h264_main=400"} {
+		_, err = ParseCapabilityPrices(s)
+		assert.Error(err, s)
+	}
+}
+
+func TestCapabilityPrices_Net(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(CapabilityPrices(nil).ToNet())
+	assert.Empty(CapabilityPricesFromNet(nil))
+
+	prices := CapabilityPrices{Capability_AV1Encode: 400, Capability_HEVC: 150}
+	netPrices := prices.ToNet()
+	assert.Equal([]*net.CapabilityPrice{
+		{Capability: uint32(Capability_HEVC), Percent: 150},
+		{Capability: uint32(Capability_AV1Encode), Percent: 400},
+	}, netPrices)
+	assert.Equal(prices, CapabilityPricesFromNet(netPrices))
+
+	// zero percents are ignored
+	netPrices = append(netPrices, &net.CapabilityPrice{Capability: uint32(Capability_VP9), Percent: 0})
+	assert.Equal(prices, CapabilityPricesFromNet(netPrices))
+
+	// the prices of the capabilities that can't be priced are ignored, but percents above
+	// the max are kept so that the orchestrator is refused
+	netPrices = append(netPrices, &net.CapabilityPrice{Capability: uint32(Capability_ProfileH264Main), Percent: 100000})
+	assert.Equal(prices, CapabilityPricesFromNet(netPrices))
+	netPrices = append(netPrices, &net.CapabilityPrice{Capability: uint32(Capability_VP8), Percent: 100000})
+	assert.Equal(uint32(100000), CapabilityPricesFromNet(netPrices)[Capability_VP8])
+}
+
+func TestCapabilityPrices_PricedPixels(t *testing.T) {
+	assert := assert.New(t)
+	prices := CapabilityPrices{Capability_AV1Encode: 400, Capability_HEVC: 150}
+
+	av1 := ffmpeg.P144p30fps16x9
+	av1.Profile = common.ProfileAV1Main
+	hevc := ffmpeg.P144p30fps16x9
+	hevc.Profile = common.ProfileH265Main
+
+	assert.Equal(uint32(400), prices.ProfilePercent(av1))
+	assert.Equal(int64(4000), prices.PricedPixels(av1, 1000))
+	assert.Equal(int64(1501), prices.PricedPixels(hevc, 1001))
+
+	// renditions without a priced capability are charged the price per pixel
+	assert.Equal(uint32(100), prices.ProfilePercent(ffmpeg.P144p30fps16x9))
+	assert.Equal(int64(1000), prices.PricedPixels(ffmpeg.P144p30fps16x9, 1000))
+	assert.Equal(int64(1000), CapabilityPrices(nil).PricedPixels(av1, 1000))
+	main := ffmpeg.P144p30fps16x9
+	main.Profile = ffmpeg.ProfileH264Main
+	assert.Equal(uint32(100), CapabilityPrices{Capability_ProfileH264Main: 400}.ProfilePercent(main))
+
+	assert.Equal(uint32(400), prices.MaxPercent([]ffmpeg.VideoProfile{hevc, av1, main}))
+	assert.Equal(uint32(100), prices.MaxPercent([]ffmpeg.VideoProfile{main}))
+	assert.Equal(uint32(100), prices.MaxPercent(nil))
+}
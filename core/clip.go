@@ -0,0 +1,140 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/common"
+)
+
+// FFmpegCommand is the ffmpeg binary that cuts the source segments of clips before they are
+// transcoded, and that segments and muxes VOD files. It is looked up in the PATH if it
+// isn't a path.
+var FFmpegCommand = "ffmpeg"
+
+// clipTimeout bounds the time it takes to cut a source segment
+var clipTimeout = 30 * time.Second
+
+var ErrClipRange = errors.New("ClipRange")
+
+// ClipRange is the part of a segment that a clip keeps, relative to the start of the
+// segment. A zero To keeps the segment until its end.
+type ClipRange struct {
+	From time.Duration
+	To   time.Duration
+}
+
+// Validate checks that a clip range keeps part of a segment of the given duration
+func (c *ClipRange) Validate(duration time.Duration) error {
+	if c.From < 0 || c.To < 0 || c.From >= duration || (c.To != 0 && c.To <= c.From) {
+		return ErrClipRange
+	}
+	return nil
+}
+
+// Duration returns the duration of the part of a segment that the clip keeps, given
+// the duration of the segment, in seconds
+func (c *ClipRange) Duration(segDuration float64) float64 {
+	end := segDuration
+	if c.To > 0 && c.To.Seconds() < segDuration {
+		end = c.To.Seconds()
+	}
+	return math.Max(end-c.From.Seconds(), 0)
+}
+
+// ClippingAvailable checks if the ffmpeg binary that cuts the source segments of clips
+// is installed, so that the node can advertise the clip capability
+func ClippingAvailable() bool {
+	_, err := exec.LookPath(FFmpegCommand)
+	return err == nil
+}
+
+// ClipSegment is a segment of a recorded stream that is part of a clip
+type ClipSegment struct {
+	// Index of the segment in the recording
+	Index int
+	// Part of the segment that the clip keeps, nil if the clip keeps all of it
+	Clip *ClipRange
+}
+
+// ClipSegments returns the segments of a recording that a clip from start to end
+// overlaps, given the durations of the segments of the recording, in seconds. The first
+// and the last segments are clipped to the bounds of the clip.
+func ClipSegments(durations []float64, start, end float64) ([]ClipSegment, error) {
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("invalid clip from %v to %v", start, end)
+	}
+	var segs []ClipSegment
+	t := 0.0
+	for i, dur := range durations {
+		segStart, segEnd := t, t+dur
+		t = segEnd
+		// skip the segments that the clip overlaps by less than the precision of the bounds
+		if segEnd-start < 0.001 || end-segStart < 0.001 {
+			continue
+		}
+		seg := ClipSegment{Index: i}
+		if start > segStart || end < segEnd {
+			seg.Clip = &ClipRange{}
+			if start > segStart {
+				seg.Clip.From = toDuration(start - segStart)
+			}
+			if end < segEnd {
+				seg.Clip.To = toDuration(end - segStart)
+			}
+		}
+		segs = append(segs, seg)
+	}
+	if len(segs) == 0 {
+		return nil, fmt.Errorf("clip from %v to %v is outside of the recording of %v seconds", start, end, t)
+	}
+	return segs, nil
+}
+
+func toDuration(sec float64) time.Duration {
+	return time.Duration(math.Round(sec*1000)) * time.Millisecond
+}
+
+// clipInput cuts the part of the source segment of a clip that the broadcaster asked
+// for into a new file of the work dir, and returns the name of the file. Segments
+// that aren't clipped are transcoded as they are.
+func clipInput(workDir string, md *SegTranscodingMetadata) (string, error) {
+	if md.Clip == nil {
+		return md.Fname, nil
+	}
+	out := filepath.Join(workDir, common.RandName()+".ts")
+	ctx, cancel := context.WithTimeout(context.Background(), clipTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, FFmpegCommand, clipArgs(md.Fname, out, md.Clip)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		glog.Errorf("Error clipping segment manifestID=%s seqNo=%d from=%v to=%v err=%v output=%s",
+			md.ManifestID, md.Seq, md.Clip.From, md.Clip.To, err, output)
+		os.Remove(out)
+		return "", fmt.Errorf("clip: %v", err)
+	}
+	return out, nil
+}
+
+// clipArgs are the arguments of ffmpeg to cut a segment. The segment is decoded from
+// the keyframe ahead of the start of the clip and the frames before the start are
+// dropped, so the clip starts at the exact frame. The video is re-encoded at a quality
+// high enough not to degrade the renditions, and the timestamps of the segment are kept
+// so that the clipped segments play back to back with the segments that aren't clipped.
+func clipArgs(in, out string, clip *ClipRange) []string {
+	args := []string{"-hide_banner", "-loglevel", "error", "-y",
+		"-ss", fmt.Sprintf("%.3f", clip.From.Seconds())}
+	if clip.To > 0 {
+		args = append(args, "-t", fmt.Sprintf("%.3f", (clip.To-clip.From).Seconds()))
+	}
+	return append(args, "-copyts", "-i", in,
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "12",
+		"-c:a", "aac", "-f", "mpegts", out)
+}
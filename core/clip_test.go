@@ -0,0 +1,118 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClipSegments(t *testing.T) {
+	assert := assert.New(t)
+	durations := []float64{2, 2, 2, 1.5}
+
+	segs, err := ClipSegments(durations, 1.25, 5.5)
+	assert.Nil(err)
+	assert.Equal([]ClipSegment{
+		{Index: 0, Clip: &ClipRange{From: 1250 * time.Millisecond}},
+		{Index: 1},
+		{Index: 2, Clip: &ClipRange{To: 1500 * time.Millisecond}},
+	}, segs)
+
+	// clips within a segment are cut at both ends
+	segs, err = ClipSegments(durations, 6.2, 6.9)
+	assert.Nil(err)
+	assert.Equal([]ClipSegment{{Index: 3, Clip: &ClipRange{From: 200 * time.Millisecond, To: 900 * time.Millisecond}}}, segs)
+
+	// clips on the bounds of segments keep whole segments
+	segs, err = ClipSegments(durations, 2, 4)
+	assert.Nil(err)
+	assert.Equal([]ClipSegment{{Index: 1}}, segs)
+	segs, err = ClipSegments(durations, 0, 100)
+	assert.Nil(err)
+	assert.Len(segs, 4)
+	assert.Nil(segs[3].Clip)
+
+	_, err = ClipSegments(durations, 3, 3)
+	assert.EqualError(err, "invalid clip from 3 to 3")
+	_, err = ClipSegments(durations, -1, 3)
+	assert.NotNil(err)
+	_, err = ClipSegments(durations, 7.5, 9)
+	assert.EqualError(err, "clip from 7.5 to 9 is outside of the recording of 7.5 seconds")
+}
+
+func TestClipRange(t *testing.T) {
+	assert := assert.New(t)
+
+	clip := &ClipRange{From: 500 * time.Millisecond, To: 1500 * time.Millisecond}
+	assert.Nil(clip.Validate(2 * time.Second))
+	assert.Equal(1.0, clip.Duration(2))
+	// clips end with their segment
+	assert.Equal(0.5, clip.Duration(1))
+	assert.Equal(1.5, (&ClipRange{From: 500 * time.Millisecond}).Duration(2))
+
+	assert.Equal(ErrClipRange, (&ClipRange{From: 2 * time.Second}).Validate(2*time.Second))
+	assert.Equal(ErrClipRange, (&ClipRange{From: time.Second, To: time.Second}).Validate(2*time.Second))
+	assert.Equal(ErrClipRange, (&ClipRange{From: -time.Second}).Validate(2*time.Second))
+}
+
+func TestClipArgs(t *testing.T) {
+	assert := assert.New(t)
+
+	args := clipArgs("in.ts", "out.ts", &ClipRange{From: 1250 * time.Millisecond, To: 1500 * time.Millisecond})
+	assert.Equal([]string{"-hide_banner", "-loglevel", "error", "-y", "-ss", "1.250", "-t", "0.250", "-copyts", "-i", "in.ts",
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "12", "-c:a", "aac", "-f", "mpegts", "out.ts"}, args)
+
+	// clips until the end of the segment have no duration
+	args = clipArgs("in.ts", "out.ts", &ClipRange{From: 1250 * time.Millisecond})
+	assert.NotContains(args, "-t")
+}
+
+func TestClipInput(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	tmpdir, err := ioutil.TempDir("", "")
+	require.Nil(err)
+	defer os.RemoveAll(tmpdir)
+
+	// segments that aren't clipped are transcoded as they are
+	md := &SegTranscodingMetadata{Fname: "in.ts"}
+	fname, err := clipInput(tmpdir, md)
+	assert.Nil(err)
+	assert.Equal("in.ts", fname)
+
+	defer func(cmd string) { FFmpegCommand = cmd }(FFmpegCommand)
+	FFmpegCommand = "false"
+	md.Clip = &ClipRange{From: time.Second}
+	_, err = clipInput(tmpdir, md)
+	assert.EqualError(err, "clip: exit status 1")
+	files, err := ioutil.ReadDir(tmpdir)
+	require.Nil(err)
+	assert.Empty(files)
+
+	FFmpegCommand = "nonexistent-ffmpeg"
+	assert.False(ClippingAvailable())
+}
+
+func TestNetSegData_Clip(t *testing.T) {
+	assert := assert.New(t)
+
+	md := &SegTranscodingMetadata{ManifestID: "mid", Seq: 1}
+	segData, err := NetSegData(md)
+	assert.Nil(err)
+	assert.Zero(segData.ClipFrom)
+	assert.Zero(segData.ClipTo)
+	flat := md.Flatten()
+
+	md.Clip = &ClipRange{From: 250 * time.Millisecond, To: time.Second}
+	segData, err = NetSegData(md)
+	assert.Nil(err)
+	assert.Equal(int64(250), segData.ClipFrom)
+	assert.Equal(int64(1000), segData.ClipTo)
+	// the bounds of clips are flattened after the fields of other segments
+	assert.Equal(flat, md.Flatten()[:len(flat)])
+	assert.Len(md.Flatten(), len(flat)+64)
+}
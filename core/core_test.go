@@ -146,6 +146,27 @@ func TestTranscodeLoop_GivenNoSegmentsPastTimeout_CleansSegmentChan(t *testing.T
 	assert.Nil(segChan)
 }
 
+func TestTranscodeLoop_GivenNoSegmentsPastTimeout_RemovesBandwidthStats(t *testing.T) {
+	drivers.NodeStorage = drivers.NewMemoryDriver(nil)
+	n, _ := NewLivepeerNode(nil, "", nil)
+	md := &SegTranscodingMetadata{ManifestID: ManifestID("mid")}
+
+	defer func(timeout time.Duration) { transcodeLoopTimeout = timeout }(transcodeLoopTimeout)
+	transcodeLoopTimeout = 100 * time.Millisecond
+	assert := assert.New(t)
+	require := require.New(t)
+
+	_, err := n.getSegmentChan(md)
+	require.Nil(err)
+	n.Bandwidth.RecordIngress(md.ManifestID, "peer", 100)
+	require.Len(n.Bandwidth.StreamStats(), 1)
+
+	waitForTranscoderLoopTimeout(n, md.ManifestID)
+
+	assert.Nil(getSegChan(n, md.ManifestID))
+	assert.Empty(n.Bandwidth.StreamStats())
+}
+
 func waitForTranscoderLoopTimeout(n *LivepeerNode, m ManifestID) {
 	for i := 0; i < 3; i++ {
 		time.Sleep(transcodeLoopTimeout * 2)
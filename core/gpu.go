@@ -0,0 +1,47 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// AllNvidiaDevices is the -nvidia value that selects every Nvidia GPU of the host
+const AllNvidiaDevices = "all"
+
+// queryNvidiaDevices returns the indexes of the Nvidia GPUs of the host, one per line
+var queryNvidiaDevices = func() ([]byte, error) {
+	return exec.Command("nvidia-smi", "--query-gpu=index", "--format=csv,noheader").Output()
+}
+
+// ParseNvidiaDevices returns the device IDs of a comma separated list of Nvidia GPU device
+// IDs, or of every GPU of the host, as enumerated by nvidia-smi, for AllNvidiaDevices
+func ParseNvidiaDevices(devices string) ([]string, error) {
+	if strings.TrimSpace(devices) == AllNvidiaDevices {
+		out, err := queryNvidiaDevices()
+		if err != nil {
+			return nil, fmt.Errorf("could not enumerate Nvidia devices with nvidia-smi: %v", err)
+		}
+		devices = strings.Join(strings.Fields(string(out)), ",")
+		if devices == "" {
+			return nil, errors.New("no Nvidia devices found")
+		}
+	}
+
+	var ids []string
+	seen := make(map[string]bool)
+	for _, id := range strings.Split(devices, ",") {
+		id = strings.TrimSpace(id)
+		if _, err := strconv.ParseUint(id, 10, 32); err != nil {
+			return nil, fmt.Errorf("invalid Nvidia device ID %q", id)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate Nvidia device ID %v", id)
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
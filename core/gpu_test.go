@@ -0,0 +1,39 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNvidiaDevices(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(q func() ([]byte, error)) { queryNvidiaDevices = q }(queryNvidiaDevices)
+	queryNvidiaDevices = func() ([]byte, error) { return []byte("0\n1\n2\n"), nil }
+
+	devices, err := ParseNvidiaDevices("0, 2")
+	assert.Nil(err)
+	assert.Equal([]string{"0", "2"}, devices)
+
+	// Test every GPU is enumerated with all
+	devices, err = ParseNvidiaDevices("all")
+	assert.Nil(err)
+	assert.Equal([]string{"0", "1", "2"}, devices)
+
+	_, err = ParseNvidiaDevices("0,foo")
+	assert.EqualError(err, `invalid Nvidia device ID "foo"`)
+	_, err = ParseNvidiaDevices("0,,1")
+	assert.EqualError(err, `invalid Nvidia device ID ""`)
+	_, err = ParseNvidiaDevices("1,1")
+	assert.EqualError(err, "duplicate Nvidia device ID 1")
+
+	queryNvidiaDevices = func() ([]byte, error) { return nil, nil }
+	_, err = ParseNvidiaDevices("all")
+	assert.EqualError(err, "no Nvidia devices found")
+
+	queryNvidiaDevices = func() ([]byte, error) { return nil, errors.New("not found") }
+	_, err = ParseNvidiaDevices("all")
+	assert.EqualError(err, "could not enumerate Nvidia devices with nvidia-smi: not found")
+}
@@ -0,0 +1,29 @@
+package core
+
+import (
+	"time"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/lpms/ffmpeg"
+)
+
+// KeyframeAlignment encodes all the renditions of a stream with the same GOP, so
+// that their keyframes fall at the same timestamps: at the start of every segment
+// and every GOP within it. Packagers can then switch between the renditions at any
+// keyframe.
+type KeyframeAlignment struct {
+	GOP time.Duration
+}
+
+// Profiles returns copies of the profiles with the GOP of the alignment.
+// Audio-only profiles have no keyframes, so they are kept as is.
+func (ka *KeyframeAlignment) Profiles(profiles []ffmpeg.VideoProfile) []ffmpeg.VideoProfile {
+	res := make([]ffmpeg.VideoProfile, len(profiles))
+	copy(res, profiles)
+	for i, p := range profiles {
+		if common.ProfileCodec(p.Profile) != common.NoVideo {
+			res[i].GOP = ka.GOP
+		}
+	}
+	return res
+}
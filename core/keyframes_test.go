@@ -0,0 +1,33 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyframeAlignment_Profiles(t *testing.T) {
+	assert := assert.New(t)
+	ka := &KeyframeAlignment{GOP: time.Second}
+
+	intra := ffmpeg.P240p30fps16x9
+	intra.GOP = ffmpeg.GOPIntraOnly
+	audio := ffmpeg.VideoProfile{Name: "audio", Profile: common.ProfileAudioOnly}
+	profiles := []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9, intra, audio}
+
+	aligned := ka.Profiles(profiles)
+	assert.Equal(time.Second, aligned[0].GOP)
+	assert.Equal(time.Second, aligned[1].GOP)
+	assert.Equal(time.Duration(0), aligned[2].GOP)
+	assert.Equal(ffmpeg.P144p30fps16x9.Name, aligned[0].Name)
+	// the profiles are copied
+	assert.Equal(ffmpeg.GOPIntraOnly, profiles[1].GOP)
+
+	// aligned keyframes need the GOP capability of orchestrators
+	caps, err := JobCapabilities(&StreamParameters{Profiles: aligned})
+	assert.Nil(err)
+	assert.Contains(caps.Names(), "gop")
+}
@@ -23,28 +23,68 @@ type TranscoderSession interface {
 
 type newTranscoderFn func(device string) TranscoderSession
 
+// LoadBalancingTranscoder assigns each stream to a transcode session on one of several
+// devices, e.g. GPUs, and transcodes all the segments of the stream on that session. New
+// sessions go to the device with the least load, skipping the devices that already run
+// their maximum number of concurrent sessions
 type LoadBalancingTranscoder struct {
 	transcoders []string // Slice of device IDs
 	newT        newTranscoderFn
+	maxSessions int // Maximum number of concurrent sessions per device, unlimited if 0
 
 	// The following fields need to be protected by the mutex `mu`
 	mu       *sync.RWMutex
 	load     map[string]int
+	count    map[string]int // Number of sessions per device
 	sessions map[string]*transcoderSession
 	idx      int // Ensures a non-tapered work distribution
 }
 
+// DeviceLoad is the load of a device of a LoadBalancingTranscoder
+type DeviceLoad struct {
+	Device      string
+	Sessions    int
+	MaxSessions int
+	// Cost is the estimated pixels per second of the sessions of the device
+	Cost int
+}
+
 func NewLoadBalancingTranscoder(devices string, newTranscoderFn newTranscoderFn) Transcoder {
-	d := strings.Split(devices, ",")
+	return NewDeviceLoadBalancer(strings.Split(devices, ","), 0, newTranscoderFn)
+}
+
+// NewDeviceLoadBalancer creates a LoadBalancingTranscoder for devices which runs at most
+// maxSessions concurrent sessions per device, or any number of sessions if maxSessions is 0
+func NewDeviceLoadBalancer(devices []string, maxSessions int, newTranscoderFn newTranscoderFn) *LoadBalancingTranscoder {
 	return &LoadBalancingTranscoder{
-		transcoders: d,
+		transcoders: devices,
 		newT:        newTranscoderFn,
+		maxSessions: maxSessions,
 		mu:          &sync.RWMutex{},
 		load:        make(map[string]int),
+		count:       make(map[string]int),
 		sessions:    make(map[string]*transcoderSession),
 	}
 }
 
+// Capacity returns the maximum number of concurrent sessions of all the devices, 0 if it is
+// unlimited
+func (lb *LoadBalancingTranscoder) Capacity() int {
+	return lb.maxSessions * len(lb.transcoders)
+}
+
+// DeviceLoads returns the load of each device, in the order of the devices
+func (lb *LoadBalancingTranscoder) DeviceLoads() []DeviceLoad {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	loads := make([]DeviceLoad, len(lb.transcoders))
+	for i, d := range lb.transcoders {
+		loads[i] = DeviceLoad{Device: d, Sessions: lb.count[d], MaxSessions: lb.maxSessions, Cost: lb.load[d]}
+	}
+	return loads
+}
+
 func (lb *LoadBalancingTranscoder) Transcode(md *SegTranscodingMetadata) (*TranscodeData, error) {
 
 	lb.mu.RLock()
@@ -75,6 +115,10 @@ func (lb *LoadBalancingTranscoder) createSession(md *SegTranscodingMetadata) (*t
 
 	glog.V(common.DEBUG).Info("LB: Creating transcode session for ", job)
 	transcoder := lb.leastLoaded()
+	if transcoder == "" {
+		glog.V(common.DEBUG).Infof("LB: All devices run their maximum of %v sessions; rejecting %v", lb.maxSessions, job)
+		return nil, ErrTranscoderBusy
+	}
 
 	// Acquire transcode session. Map to job id + assigned transcoder
 	key := job + "_" + transcoder
@@ -87,6 +131,7 @@ func (lb *LoadBalancingTranscoder) createSession(md *SegTranscodingMetadata) (*t
 	}
 	lb.sessions[job] = session
 	lb.load[transcoder] += costEstimate
+	lb.count[transcoder]++
 	lb.idx = (lb.idx + 1) % len(lb.transcoders)
 
 	// Local cleanup function
@@ -99,6 +144,7 @@ func (lb *LoadBalancingTranscoder) createSession(md *SegTranscodingMetadata) (*t
 		}
 		delete(lb.sessions, job)
 		lb.load[transcoder] -= costEstimate
+		lb.count[transcoder]--
 		glog.V(common.DEBUG).Info("LB: Deleted transcode session for ", session.key)
 	}
 
@@ -111,17 +157,23 @@ func (lb *LoadBalancingTranscoder) createSession(md *SegTranscodingMetadata) (*t
 	return session, nil
 }
 
-// Find the lowest loaded transcoder.
+// Find the lowest loaded transcoder with room for another session, or "" if there is none.
 // Expects the mutex `lb.mu` to be locked by the caller.
 func (lb *LoadBalancingTranscoder) leastLoaded() string {
-	min, idx := math.MaxInt64, 0
+	min, idx := math.MaxInt64, -1
 	for i := 0; i < len(lb.transcoders); i++ {
 		k := (i + lb.idx) % len(lb.transcoders)
+		if lb.maxSessions > 0 && lb.count[lb.transcoders[k]] >= lb.maxSessions {
+			continue
+		}
 		if lb.load[lb.transcoders[k]] < min {
 			min = lb.load[lb.transcoders[k]]
 			idx = k
 		}
 	}
+	if idx < 0 {
+		return ""
+	}
 	return lb.transcoders[idx]
 }
 
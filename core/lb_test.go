@@ -100,6 +100,44 @@ func TestLB_LoadAssignment(t *testing.T) {
 	})
 }
 
+func TestLB_MaxSessionsPerDevice(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	lb := NewDeviceLoadBalancer([]string{"0", "1"}, 2, newStubTranscoder)
+	assert.Equal(4, lb.Capacity())
+	assert.Equal(0, NewDeviceLoadBalancer([]string{"0", "1"}, 0, newStubTranscoder).Capacity())
+
+	// Test the sessions are spread over the devices up to their maximum
+	for _, sess := range []string{"a", "b", "c", "d"} {
+		_, err := lb.Transcode(stubMetadata(sess, ffmpeg.P144p30fps16x9))
+		require.Nil(err)
+	}
+	assert.Equal([]DeviceLoad{
+		{Device: "0", Sessions: 2, MaxSessions: 2, Cost: 2 * calculateCost([]ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9})},
+		{Device: "1", Sessions: 2, MaxSessions: 2, Cost: 2 * calculateCost([]ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9})},
+	}, lb.DeviceLoads())
+
+	// Test a new session is refused once every device is full, but existing sessions go on
+	_, err := lb.Transcode(stubMetadata("e", ffmpeg.P144p30fps16x9))
+	assert.Equal(ErrTranscoderBusy, err)
+	_, err = lb.Transcode(stubMetadata("a", ffmpeg.P144p30fps16x9))
+	assert.Nil(err)
+
+	// Test a full device is skipped even if it has the least load
+	lb = NewDeviceLoadBalancer([]string{"0", "1"}, 2, newStubTranscoder)
+	_, err = lb.Transcode(stubMetadata("a", ffmpeg.P720p60fps16x9))
+	require.Nil(err)
+	for _, sess := range []string{"b", "c", "d"} {
+		_, err = lb.Transcode(stubMetadata(sess, ffmpeg.P144p30fps16x9))
+		require.Nil(err)
+	}
+	loads := lb.DeviceLoads()
+	assert.Equal(2, loads[0].Sessions)
+	assert.Equal(2, loads[1].Sessions)
+	assert.Contains(lb.sessions["d"].key, "_0")
+}
+
 func TestLB_SessionCancel(t *testing.T) {
 	// One-off test for session cancellation to work around thread safety issues
 	stubCtx, stubCancel := context.WithCancel(context.Background())
@@ -350,6 +388,11 @@ func (m *lbMachine) Check(t *rapid.T) {
 	defer m.lb.mu.RUnlock()
 	assert.Equal(len(m.states), len(m.lb.sessions), "Mismatch in number of sessions")
 	assert.Equal(m.totalLoad, accumLoad(m.lb), "Mismatch in load calculation")
+	sessions := 0
+	for _, c := range m.lb.count {
+		sessions += c
+	}
+	assert.Equal(len(m.lb.sessions), sessions, "Mismatch in number of device sessions")
 
 	for name, sess := range m.lb.sessions {
 		transcoder, ok := sess.transcoder.(*StubTranscoder)
@@ -16,8 +16,10 @@ import (
 	"math/rand"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/livepeer/go-livepeer/audit"
 	"github.com/livepeer/go-livepeer/pm"
 
 	"github.com/livepeer/go-livepeer/common"
@@ -33,7 +35,19 @@ var ErrTranscode = errors.New("ErrTranscode")
 // output of the `git describe` command.
 var LivepeerVersion = "undefined"
 
-var MaxSessions = 10
+// maxSessions is the maximum number of concurrent transcoding sessions. It can be changed
+// while the node runs, so it is accessed atomically
+var maxSessions int64 = 10
+
+// MaxSessions returns the maximum number of concurrent transcoding sessions
+func MaxSessions() int {
+	return int(atomic.LoadInt64(&maxSessions))
+}
+
+// SetMaxSessions sets the maximum number of concurrent transcoding sessions
+func SetMaxSessions(n int) {
+	atomic.StoreInt64(&maxSessions, int64(n))
+}
 
 type NodeType int
 
@@ -43,16 +57,22 @@ const (
 	OrchestratorNode
 	TranscoderNode
 	RedeemerNode
+	RelayNode
 )
 
 //LivepeerNode handles videos going in and coming out of the Livepeer network.
 type LivepeerNode struct {
 
 	// Common fields
-	Eth      eth.LivepeerEthClient
-	WorkDir  string
-	NodeType NodeType
-	Database *common.DB
+	Eth       eth.LivepeerEthClient
+	WorkDir   string
+	NodeType  NodeType
+	Database  *common.DB
+	Bandwidth *BandwidthTracker
+	AuditLog  *audit.Log
+	// OfflineSigner holds the transactions queued to be signed offline, if the account is
+	// signed for offline
+	OfflineSigner *eth.OfflineAccountManager
 
 	// Transcoder public fields
 	SegmentChans      map[ManifestID]SegmentChan
@@ -63,6 +83,15 @@ type LivepeerNode struct {
 	TranscoderManager *RemoteTranscoderManager
 	Balances          *AddressBalances
 	Capabilities      *Capabilities
+	SenderStats       *SenderStatsTracker
+	SenderReputation  *pm.SenderReputationTracker
+	// SigVerifier verifies the signatures of broadcasters, if set, e.g. to accept the
+	// signatures of smart contract wallets
+	SigVerifier pm.SigVerifier
+	Receipts    *ReceiptTracker
+	// CapabilityPrices are the prices of the renditions that need capabilities which
+	// cost more to transcode
+	CapabilityPrices CapabilityPrices
 
 	// Broadcaster public fields
 	Sender pm.Sender
@@ -73,6 +102,8 @@ type LivepeerNode struct {
 	priceInfo    *big.Rat
 	serviceURI   url.URL
 	segmentMutex *sync.RWMutex
+	// Additional service URIs, e.g. per region or per provider
+	altServiceURIs []*url.URL
 }
 
 //NewLivepeerNode creates a new Livepeer Node. Eth can be nil.
@@ -83,6 +114,9 @@ func NewLivepeerNode(e eth.LivepeerEthClient, wd string, dbh *common.DB) (*Livep
 		WorkDir:      wd,
 		Database:     dbh,
 		SegmentChans: make(map[ManifestID]SegmentChan),
+		SenderStats:  NewSenderStatsTracker(),
+		Receipts:     NewReceiptTracker(),
+		Bandwidth:    NewBandwidthTracker(),
 		segmentMutex: &sync.RWMutex{},
 	}, nil
 }
@@ -99,11 +133,30 @@ func (n *LivepeerNode) SetServiceURI(newUrl *url.URL) {
 	n.serviceURI = *newUrl
 }
 
+// GetAltServiceURIs returns the additional service URIs of an orchestrator
+func (n *LivepeerNode) GetAltServiceURIs() []*url.URL {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return append([]*url.URL(nil), n.altServiceURIs...)
+}
+
+// SetAltServiceURIs sets the additional service URIs of an orchestrator, which
+// broadcasters fail over to if the service URI is unreachable
+func (n *LivepeerNode) SetAltServiceURIs(uris []*url.URL) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.altServiceURIs = append([]*url.URL(nil), uris...)
+}
+
 // SetBasePrice sets the base price for an orchestrator on the node
 func (n *LivepeerNode) SetBasePrice(price *big.Rat) {
 	n.mu.Lock()
-	defer n.mu.Unlock()
 	n.priceInfo = price
+	n.mu.Unlock()
+
+	if price != nil {
+		n.AuditLog.Append(audit.EventPriceChanged, map[string]string{"pricePerPixel": price.RatString()})
+	}
 }
 
 // GetBasePrice gets the base price for an orchestrator
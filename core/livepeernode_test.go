@@ -1,14 +1,18 @@
 package core
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/big"
 	"net/url"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/audit"
 	"github.com/livepeer/go-livepeer/drivers"
 	"github.com/livepeer/lpms/ffmpeg"
 	"github.com/stretchr/testify/assert"
@@ -164,3 +168,26 @@ func TestSetAndGetBasePrice(t *testing.T) {
 	assert.Zero(n.priceInfo.Cmp(price))
 	assert.Zero(n.GetBasePrice().Cmp(price))
 }
+
+func TestSetBasePrice_AuditLog(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "audit")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	n, err := NewLivepeerNode(nil, "", nil)
+	require.Nil(err)
+	n.AuditLog, err = audit.Open(filepath.Join(dir, "audit.log"))
+	require.Nil(err)
+	defer n.AuditLog.Close()
+
+	n.SetBasePrice(big.NewRat(2, 4))
+
+	var buf bytes.Buffer
+	require.Nil(n.AuditLog.Export(&buf))
+	var e audit.Entry
+	require.Nil(json.Unmarshal(buf.Bytes(), &e))
+	assert.Equal(t, audit.EventPriceChanged, e.Type)
+	assert.Equal(t, "1/2", e.Data["pricePerPixel"])
+}
@@ -1,7 +1,9 @@
 package core
 
 import (
+	"bytes"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -9,6 +11,8 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"sort"
 	"sync"
 	"testing"
 	"time"
@@ -18,6 +22,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/livepeer/go-livepeer/audit"
 	"github.com/livepeer/go-livepeer/pm"
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
@@ -418,6 +423,107 @@ func TestTranscoderManagerTranscoding(t *testing.T) {
 	s.WithholdResults = false
 }
 
+func TestTranscoderManagerRedispatch(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	m := NewRemoteTranscoderManager()
+	s1 := &StubTranscoderServer{manager: m, TranscodeError: fmt.Errorf("TranscodeError")}
+	s2 := &StubTranscoderServer{manager: m}
+	go m.Manage(s1, 5)
+	go m.Manage(s2, 2)
+	time.Sleep(1 * time.Millisecond)
+	require.Len(m.liveTranscoders, 2)
+	setLoad := func(strm *StubTranscoderServer, load int) {
+		m.RTmutex.Lock()
+		defer m.RTmutex.Unlock()
+		m.liveTranscoders[strm].load = load
+		sort.Sort(byLoadFactor(m.remoteTranscoders))
+	}
+	totalFailures := func() int {
+		failures := 0
+		for _, ts := range m.PoolStatus().Transcoders {
+			failures += ts.Failures
+		}
+		return failures
+	}
+
+	// Test a segment is not dispatched again to a transcoder at capacity
+	setLoad(s2, 2)
+	_, err := m.Transcode(&SegTranscodingMetadata{})
+	assert.Equal(s1.TranscodeError, err)
+	assert.Equal(1, m.liveTranscoders[s1].failures)
+
+	// Test a failed segment is dispatched again to another transcoder
+	setLoad(s2, 1)
+	res, err := m.Transcode(&SegTranscodingMetadata{})
+	require.Nil(err)
+	assert.Equal("asdf", string(res.Segments[0].Data))
+	assert.Equal(0, m.liveTranscoders[s1].load)
+	assert.Equal(1, m.liveTranscoders[s2].load)
+
+	status := m.PoolStatus()
+	assert.Equal(1, status.Load)
+	assert.Equal(7, status.Capacity)
+	require.Len(status.Transcoders, 2)
+	for _, ts := range status.Transcoders {
+		assert.False(ts.ConnectedAt.IsZero())
+	}
+	assert.Equal(2, m.liveTranscoders[s1].failures)
+	assert.Equal(0, m.liveTranscoders[s1].segments)
+	assert.Equal(1, m.liveTranscoders[s2].segments)
+
+	// Test a segment is dispatched to at most maxRemoteTranscodeAttempts transcoders
+	s3 := &StubTranscoderServer{manager: m, TranscodeError: fmt.Errorf("TranscodeError")}
+	go m.Manage(s3, 5)
+	time.Sleep(1 * time.Millisecond)
+	s2.TranscodeError = fmt.Errorf("TranscodeError")
+	_, err = m.Transcode(&SegTranscodingMetadata{})
+	assert.NotNil(err)
+	assert.Equal(2+maxRemoteTranscodeAttempts, totalFailures())
+	assert.Equal(0, m.liveTranscoders[s2].failures)
+
+	// Test errors caused by the source segment are returned without dispatching
+	// the segment again, nor counted as failures of the transcoder
+	for _, strm := range []*StubTranscoderServer{s1, s2, s3} {
+		strm.TranscodeError = errors.New(ffmpeg.ErrTranscoderInp.Error())
+	}
+	failures, load := totalFailures(), m.PoolStatus().Load
+	_, err = m.Transcode(&SegTranscodingMetadata{})
+	assert.EqualError(err, ffmpeg.ErrTranscoderInp.Error())
+	assert.Equal(failures, totalFailures())
+	assert.Equal(load, m.PoolStatus().Load)
+
+	// Test fatal errors are counted as failures of the lost transcoder
+	s4 := &StubTranscoderServer{manager: m, SendError: errors.New("SendError")}
+	go m.Manage(s4, 10)
+	time.Sleep(1 * time.Millisecond)
+	m.RTmutex.Lock()
+	tc4 := m.liveTranscoders[s4]
+	m.RTmutex.Unlock()
+	require.NotNil(tc4)
+	m.Transcode(&SegTranscodingMetadata{})
+	m.RTmutex.Lock()
+	assert.Equal(1, tc4.failures)
+	m.RTmutex.Unlock()
+}
+
+func TestRecordTranscode(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewRemoteTranscoderManager()
+	tc := NewRemoteTranscoder(m, &StubTranscoderServer{}, 5)
+
+	m.recordTranscode(tc, 10*time.Second, nil)
+	assert.Equal(10*time.Second, tc.latency)
+	m.recordTranscode(tc, 20*time.Second, nil)
+	assert.Equal(12*time.Second, tc.latency)
+	m.recordTranscode(tc, time.Second, errors.New("TranscodeError"))
+	assert.Equal(12*time.Second, tc.latency)
+	assert.Equal(2, tc.segments)
+	assert.Equal(1, tc.failures)
+}
+
 func TestTaskChan(t *testing.T) {
 	n := NewRemoteTranscoderManager()
 	// Sanity check task ID
@@ -536,8 +642,8 @@ func TestGetSegmentChan(t *testing.T) {
 	}
 
 	// Test max sessions
-	oldTranscodeSessions := MaxSessions
-	MaxSessions = 0
+	oldTranscodeSessions := MaxSessions()
+	SetMaxSessions(0)
 	if _, err := n.getSegmentChan(segData); err != nil {
 		t.Error("Existing mid should continue processing even when O is at capacity: ", err)
 	}
@@ -545,7 +651,7 @@ func TestGetSegmentChan(t *testing.T) {
 	if _, err := n.getSegmentChan(segData); err != ErrOrchCap {
 		t.Error("Didn't fail when orch cap hit: ", err)
 	}
-	MaxSessions = oldTranscodeSessions
+	SetMaxSessions(oldTranscodeSessions)
 
 	// Test what happens when invoking the transcode loop fails
 	drivers.NodeStorage = nil // will make the transcode loop fail
@@ -579,24 +685,40 @@ func TestOrchCheckCapacity(t *testing.T) {
 	n, _ := NewLivepeerNode(nil, "", nil)
 	o := NewOrchestrator(n, nil)
 	md := StubSegTranscodingMetadata()
-	cap := MaxSessions
+	cap := MaxSessions()
 	assert := assert.New(t)
 
 	// happy case
 	assert.Nil(o.CheckCapacity(md.ManifestID))
 
 	// capped case
-	MaxSessions = 0
+	SetMaxSessions(0)
 	assert.Equal(ErrOrchCap, o.CheckCapacity(md.ManifestID))
 
 	// ensure existing segment chans pass while cap is active
-	MaxSessions = cap
+	SetMaxSessions(cap)
 	_, err := n.getSegmentChan(md) // store md into segment chans
 	assert.Nil(err)
-	MaxSessions = 0
+	SetMaxSessions(0)
 	assert.Nil(o.CheckCapacity(md.ManifestID))
 }
 
+func TestOrchAuthToken(t *testing.T) {
+	assert := assert.New(t)
+	n, _ := NewLivepeerNode(nil, "", nil)
+	o := NewOrchestrator(n, nil)
+
+	token := o.AuthToken("sess", 100)
+	assert.Equal("sess", token.SessionId)
+	assert.Equal(int64(100), token.Expiration)
+	assert.Equal(token.Token, o.AuthToken("sess", 100).Token)
+	assert.NotEqual(token.Token, o.AuthToken("sess", 101).Token)
+	assert.NotEqual(token.Token, o.AuthToken("other", 100).Token)
+
+	// Tokens of other orchestrators, or issued before a restart, are different
+	assert.NotEqual(token.Token, NewOrchestrator(n, nil).AuthToken("sess", 100).Token)
+}
+
 func TestProcessPayment_GivenRecipientError_ReturnsNil(t *testing.T) {
 	addr := defaultRecipient
 	dbh, dbraw := tempDBWithOrch(t, &common.DBOrch{
@@ -781,6 +903,51 @@ func TestProcessPayment_GivenLosingTicket_DoesNotRedeem(t *testing.T) {
 	recipient.AssertNotCalled(t, "RedeemWinningTicket", mock.Anything, mock.Anything, mock.Anything)
 }
 
+func TestProcessPayment_AuditLog(t *testing.T) {
+	addr := defaultRecipient
+	dbh, dbraw := tempDBWithOrch(t, &common.DBOrch{
+		EthereumAddr:      addr.Hex(),
+		ActivationRound:   1,
+		DeactivationRound: 999,
+	})
+	defer dbh.Close()
+	defer dbraw.Close()
+
+	dir, err := ioutil.TempDir("", "audit")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	n, _ := NewLivepeerNode(nil, "", dbh)
+	n.AuditLog, err = audit.Open(filepath.Join(dir, "audit.log"))
+	require.Nil(t, err)
+	defer n.AuditLog.Close()
+	n.Balances = NewAddressBalances(5 * time.Second)
+	recipient := new(pm.MockRecipient)
+	n.Recipient = recipient
+	orch := NewOrchestrator(n, &stubRoundsManager{round: big.NewInt(10)})
+	orch.address = addr
+
+	recipient.On("TxCostMultiplier", mock.Anything).Return(big.NewRat(1, 1), nil)
+	recipient.On("ReceiveTicket", mock.Anything, mock.Anything, mock.Anything).Return("some sessionID", false, nil)
+
+	payment := defaultPayment(t)
+	err = orch.ProcessPayment(payment, ManifestID("some manifest"))
+	require.Nil(t, err)
+
+	var buf bytes.Buffer
+	require.Nil(t, n.AuditLog.Export(&buf))
+	var e audit.Entry
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &e))
+
+	assert := assert.New(t)
+	assert.Equal(audit.EventTicketsReceived, e.Type)
+	assert.Equal(ethcommon.BytesToAddress(payment.Sender).Hex(), e.Data["sender"])
+	assert.Equal("some manifest", e.Data["manifestID"])
+	assert.Equal("1", e.Data["tickets"])
+	assert.Equal("0", e.Data["winningTickets"])
+	assert.Equal(new(big.Int).SetBytes(payment.TicketParams.FaceValue).String(), e.Data["faceValue"])
+}
+
 func TestProcessPayment_GivenWinningTicket_RedeemError(t *testing.T) {
 	addr := defaultRecipient
 	dbh, dbraw := tempDBWithOrch(t, &common.DBOrch{
@@ -1090,6 +1257,87 @@ func TestProcessPayment_PaymentError_DoesNotIncreaseCreditBalance(t *testing.T)
 	err := orch.ProcessPayment(payment, manifestID)
 	assert.Error(err)
 	assert.Nil(orch.node.Balances.Balance(ethcommon.BytesToAddress(payment.Sender), manifestID))
+	// The session isn't associated with a sender whose tickets are all refused, and the
+	// sender isn't tracked
+	_, ok := n.SenderStats.Sender(manifestID)
+	assert.False(ok)
+	assert.Nil(n.SenderStats.Stats(ethcommon.BytesToAddress(payment.Sender)))
+}
+
+// Check that the tickets of a payment are credited individually
+func TestProcessPayment_MultipleTickets_CreditsValidTickets(t *testing.T) {
+	addr := defaultRecipient
+	dbh, dbraw := tempDBWithOrch(t, &common.DBOrch{
+		EthereumAddr:      addr.Hex(),
+		ActivationRound:   1,
+		DeactivationRound: 999,
+	})
+	defer dbh.Close()
+	defer dbraw.Close()
+
+	n, _ := NewLivepeerNode(nil, "", dbh)
+	n.Balances = NewAddressBalances(5 * time.Second)
+	recipient := new(pm.MockRecipient)
+	n.Recipient = recipient
+	rm := &stubRoundsManager{
+		round: big.NewInt(10),
+	}
+	orch := NewOrchestrator(n, rm)
+	orch.address = addr
+	orch.node.SetBasePrice(big.NewRat(0, 1))
+
+	manifestID := ManifestID("some manifest")
+	paymentError := errors.New("ReceiveTicket error")
+
+	var senderParams []*net.TicketSenderParams
+	for i := 0; i < 3; i++ {
+		senderParams = append(
+			senderParams,
+			&net.TicketSenderParams{SenderNonce: 456 + uint32(i), Sig: pm.RandBytes(123)},
+		)
+	}
+	payment := defaultPaymentWithTickets(t, senderParams)
+	sender := ethcommon.BytesToAddress(payment.Sender)
+
+	// The tickets of the payment are received in order with differing nonces
+	var nonces []uint32
+	recipient.On("ReceiveTicket", mock.Anything, mock.Anything, mock.Anything).Return("", false, nil).Run(func(args mock.Arguments) {
+		nonces = append(nonces, args.Get(0).(*pm.Ticket).SenderNonce)
+	}).Once()
+	recipient.On("ReceiveTicket", mock.Anything, mock.Anything, mock.Anything).Return("", false, paymentError).Once()
+	recipient.On("ReceiveTicket", mock.Anything, mock.Anything, mock.Anything).Return("", false, nil).Run(func(args mock.Arguments) {
+		nonces = append(nonces, args.Get(0).(*pm.Ticket).SenderNonce)
+	}).Once()
+
+	assert := assert.New(t)
+
+	err := orch.ProcessPayment(*payment, manifestID)
+	assert.EqualError(err, paymentError.Error())
+	assert.Equal([]uint32{456, 458}, nonces)
+
+	// The tickets before and after the refused ticket are credited
+	ticketEV := pm.NewTicket(
+		&pm.TicketParams{
+			FaceValue: new(big.Int).SetBytes(payment.TicketParams.FaceValue),
+			WinProb:   new(big.Int).SetBytes(payment.TicketParams.WinProb),
+		},
+		&pm.TicketExpirationParams{},
+		sender,
+		0,
+	).EV()
+	expCredit := new(big.Rat).Mul(ticketEV, big.NewRat(2, 1))
+	assert.Zero(expCredit.Cmp(orch.node.Balances.Balance(sender, manifestID)))
+	mSender, ok := n.SenderStats.Sender(manifestID)
+	assert.True(ok)
+	assert.Equal(sender, mSender)
+
+	// The tickets after a fatal error are not received
+	recipient.On("ReceiveTicket", mock.Anything, mock.Anything, mock.Anything).Return("", false, pm.NewFatalReceiveErr(paymentError)).Once()
+	err = orch.ProcessPayment(*payment, manifestID)
+	_, ok = err.(*pm.FatalReceiveErr)
+	assert.True(ok)
+	recipient.AssertNumberOfCalls(t, "ReceiveTicket", 4)
+	assert.Zero(expCredit.Cmp(orch.node.Balances.Balance(sender, manifestID)))
 }
 
 func TestIsActive(t *testing.T) {
@@ -1154,7 +1402,7 @@ func TestSufficientBalance_IsSufficient_ReturnsTrue(t *testing.T) {
 
 	err := orch.ProcessPayment(payment, manifestID)
 	assert.Nil(err)
-	recipient.On("EV").Return(big.NewRat(100, 1))
+	recipient.On("EV", mock.Anything).Return(big.NewRat(100, 1))
 	assert.True(orch.SufficientBalance(ethcommon.BytesToAddress(payment.Sender), manifestID))
 }
 
@@ -1195,7 +1443,7 @@ func TestSufficientBalance_IsNotSufficient_ReturnsFalse(t *testing.T) {
 
 	err := orch.ProcessPayment(payment, manifestID)
 	assert.Nil(err)
-	recipient.On("EV").Return(big.NewRat(10000, 1))
+	recipient.On("EV", mock.Anything).Return(big.NewRat(10000, 1))
 	assert.False(orch.SufficientBalance(ethcommon.BytesToAddress(payment.Sender), manifestID))
 }
 
@@ -1591,3 +1839,26 @@ func tempDBWithOrchs(t *testing.T, orchs []*common.DBOrch) (*common.DB, *sql.DB)
 
 	return dbh, dbraw
 }
+
+func TestShouldRotateTicketParams(t *testing.T) {
+	assert := assert.New(t)
+
+	n, _ := NewLivepeerNode(nil, "", nil)
+	orch := NewOrchestrator(n, nil)
+	payment := defaultPayment(t)
+
+	// Off-chain
+	assert.False(orch.ShouldRotateTicketParams(payment))
+
+	recipient := new(pm.MockRecipient)
+	n.Recipient = recipient
+	recipientRandHash := ethcommon.BytesToHash(payment.TicketParams.RecipientRandHash)
+	recipient.On("ShouldRotateTicketParams", recipientRandHash).Return(true).Once()
+	assert.True(orch.ShouldRotateTicketParams(payment))
+	recipient.On("ShouldRotateTicketParams", recipientRandHash).Return(false).Once()
+	assert.False(orch.ShouldRotateTicketParams(payment))
+
+	// Payments without tickets
+	assert.False(orch.ShouldRotateTicketParams(net.Payment{}))
+	recipient.AssertExpectations(t)
+}
@@ -2,6 +2,8 @@ package core
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -10,6 +12,7 @@ import (
 	"os"
 	"path"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -17,6 +20,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/golang/glog"
 
+	"github.com/livepeer/go-livepeer/audit"
 	"github.com/livepeer/go-livepeer/common"
 	"github.com/livepeer/go-livepeer/drivers"
 	"github.com/livepeer/go-livepeer/eth"
@@ -26,11 +30,15 @@ import (
 
 	lpcrypto "github.com/livepeer/go-livepeer/crypto"
 	lpmon "github.com/livepeer/go-livepeer/monitor"
+	"github.com/livepeer/lpms/ffmpeg"
 	"github.com/livepeer/lpms/stream"
 )
 
 var transcodeLoopTimeout = 1 * time.Minute
 
+// AuthTokenValidPeriod is how long the auth tokens issued by the orchestrator are valid for
+var AuthTokenValidPeriod = 30 * time.Minute
+
 // Gives us more control of "timeout" / cancellation behavior during testing
 var transcodeLoopContext = func() (context.Context, context.CancelFunc) {
 	return context.WithTimeout(context.Background(), transcodeLoopTimeout)
@@ -41,12 +49,18 @@ type orchestrator struct {
 	address ethcommon.Address
 	node    *LivepeerNode
 	rm      common.RoundsManager
+	// Key of the auth tokens issued by the orchestrator
+	authTokenKey []byte
 }
 
 func (orch *orchestrator) ServiceURI() *url.URL {
 	return orch.node.GetServiceURI()
 }
 
+func (orch *orchestrator) AltServiceURIs() []*url.URL {
+	return orch.node.GetAltServiceURIs()
+}
+
 func (orch *orchestrator) CurrentBlock() *big.Int {
 	if orch.node == nil || orch.node.Database == nil {
 		return nil
@@ -66,6 +80,9 @@ func (orch *orchestrator) VerifySig(addr ethcommon.Address, msg string, sig []by
 	if orch.node == nil || orch.node.Eth == nil {
 		return true
 	}
+	if orch.node.SigVerifier != nil {
+		return orch.node.SigVerifier.Verify(addr, crypto.Keccak256([]byte(msg)), sig)
+	}
 	return lpcrypto.VerifySig(addr, crypto.Keccak256([]byte(msg)), sig)
 }
 
@@ -77,20 +94,43 @@ func (orch *orchestrator) TranscoderSecret() string {
 	return orch.node.OrchSecret
 }
 
+// AuthToken returns a token authorizing the broadcaster session sessionID until
+// expiration, a unix timestamp
+func (orch *orchestrator) AuthToken(sessionID string, expiration int64) *net.AuthToken {
+	mac := hmac.New(sha256.New, orch.authTokenKey)
+	mac.Write([]byte(fmt.Sprintf("%v|%v", sessionID, expiration)))
+	return &net.AuthToken{Token: mac.Sum(nil), SessionId: sessionID, Expiration: expiration}
+}
+
 func (orch *orchestrator) CheckCapacity(mid ManifestID) error {
 	orch.node.segmentMutex.RLock()
 	defer orch.node.segmentMutex.RUnlock()
 	if _, ok := orch.node.SegmentChans[mid]; ok {
 		return nil
 	}
-	if len(orch.node.SegmentChans) >= MaxSessions {
+	if len(orch.node.SegmentChans) >= MaxSessions() {
 		return ErrOrchCap
 	}
 	return nil
 }
 
 func (orch *orchestrator) TranscodeSeg(md *SegTranscodingMetadata, seg *stream.HLSSegment) (*TranscodeResult, error) {
-	return orch.node.sendToTranscodeLoop(md, seg)
+	peer := orch.node.BandwidthPeer(md.ManifestID)
+	orch.node.Bandwidth.RecordIngress(md.ManifestID, peer, int64(len(seg.Data)))
+
+	res, err := orch.node.sendToTranscodeLoop(md, seg)
+	if err != nil && orch.node.SenderStats != nil {
+		orch.node.SenderStats.RecordTranscodeError(md.ManifestID)
+	}
+	// Results saved to local storage are counted when they are downloaded by the broadcaster
+	if err == nil && res.TranscodeData != nil && res.OS != nil && res.OS.IsExternal() {
+		var bytes int64
+		for _, s := range res.TranscodeData.Segments {
+			bytes += int64(len(s.Data))
+		}
+		orch.node.Bandwidth.RecordEgress(md.ManifestID, peer, bytes)
+	}
+	return res, err
 }
 
 func (orch *orchestrator) ServeTranscoder(stream net.Transcoder_RegisterTranscoderServer, capacity int) {
@@ -145,6 +185,8 @@ func (orch *orchestrator) ProcessPayment(payment net.Payment, manifestID Manifes
 		Seed:              seed,
 		ExpirationBlock:   new(big.Int).SetBytes(payment.TicketParams.ExpirationBlock),
 		PricePerPixel:     priceInfoRat,
+		PayoutSplit:       common.PmPayoutSplit(payment.TicketParams.PayoutSplit),
+		Version:           payment.TicketParams.Version,
 	}
 
 	ticketExpirationParams := &pm.TicketExpirationParams{
@@ -156,37 +198,38 @@ func (orch *orchestrator) ProcessPayment(payment net.Payment, manifestID Manifes
 	totalTickets := 0
 	totalWinningTickets := 0
 
-	var receiveErr error
-
+	batch := &pm.TicketBatch{
+		TicketParams:           ticketParams,
+		TicketExpirationParams: ticketExpirationParams,
+		Sender:                 sender,
+	}
 	for _, tsp := range payment.TicketSenderParams {
+		batch.SenderParams = append(batch.SenderParams, &pm.TicketSenderParams{SenderNonce: tsp.SenderNonce, Sig: tsp.Sig})
+	}
 
-		ticket := pm.NewTicket(
-			ticketParams,
-			ticketExpirationParams,
-			sender,
-			tsp.SenderNonce,
-		)
+	glog.V(common.DEBUG).Infof("Receiving payment manifestID=%v tickets=%v faceValue=%v winProb=%v", manifestID, len(batch.SenderParams), eth.FormatUnits(ticketParams.FaceValue, "ETH"), ticketParams.WinProbRat().FloatString(10))
 
-		glog.V(common.DEBUG).Infof("Receiving ticket manifestID=%v faceValue=%v winProb=%v ev=%v", manifestID, eth.FormatUnits(ticket.FaceValue, "ETH"), ticket.WinProbRat().FloatString(10), ticket.EV().FloatString(2))
+	// Each ticket of the payment is credited on its own, so that a refused ticket doesn't
+	// refuse the other tickets of the payment unless its error is fatal
+	var receiveErr error
 
-		_, won, err := orch.node.Recipient.ReceiveTicket(
-			ticket,
-			tsp.Sig,
-			seed,
-		)
-		if err != nil {
-			glog.Errorf("Error receiving ticket manifestID=%v recipientRandHash=%x senderNonce=%v: %v", manifestID, ticket.RecipientRandHash, ticket.SenderNonce, err)
+	for _, res := range orch.node.Recipient.ReceivePayment(batch, seed) {
+		ticket := res.Ticket
+
+		if res.Err != nil {
+			glog.Errorf("Error receiving ticket manifestID=%v recipientRandHash=%x senderNonce=%v: %v", manifestID, ticket.RecipientRandHash, ticket.SenderNonce, res.Err)
 
 			if monitor.Enabled {
-				monitor.PaymentRecvError(sender.String(), string(manifestID), err.Error())
+				monitor.PaymentRecvError(sender.String(), string(manifestID), res.Err.Error())
 			}
-			if _, ok := err.(*pm.FatalReceiveErr); ok {
-				return err
+			if orch.node.SenderStats != nil {
+				orch.node.SenderStats.RecordPaymentError(sender)
 			}
-			receiveErr = err
-		}
-
-		if receiveErr == nil {
+			if pm.Errors.IsFatal(res.Err) {
+				return res.Err
+			}
+			receiveErr = res.Err
+		} else {
 			// Add ticket EV to credit
 			ev := ticket.EV()
 			orch.node.Balances.Credit(sender, manifestID, ev)
@@ -194,7 +237,7 @@ func (orch *orchestrator) ProcessPayment(payment net.Payment, manifestID Manifes
 			totalTickets++
 		}
 
-		if won {
+		if res.Won {
 			glog.V(common.DEBUG).Infof("Received winning ticket manifestID=%v recipientRandHash=%x senderNonce=%v", manifestID, ticket.RecipientRandHash, ticket.SenderNonce)
 
 			totalWinningTickets++
@@ -203,17 +246,35 @@ func (orch *orchestrator) ProcessPayment(payment net.Payment, manifestID Manifes
 				if err := orch.node.Recipient.RedeemWinningTicket(ticket, sig, seed); err != nil {
 					glog.Errorf("error redeeming ticket manifestID=%v recipientRandHash=%x senderNonce=%v err=%v", manifestID, ticket.RecipientRandHash, ticket.SenderNonce, err)
 				}
-			}(ticket, tsp.Sig, seed)
+			}(ticket, res.Sig, seed)
 		}
 	}
 
+	// The session is only associated with the sender once a ticket of the sender is valid,
+	// so that a payment can't claim the session of another sender
+	if totalTickets > 0 && orch.node.SenderStats != nil {
+		orch.node.SenderStats.RecordSession(sender, manifestID)
+	}
+
 	if monitor.Enabled {
 		senderStr := sender.String()
 		mid := string(manifestID)
 
 		monitor.TicketValueRecv(senderStr, mid, totalEV)
 		monitor.TicketsRecv(senderStr, mid, totalTickets)
-		monitor.WinningTicketsRecv(senderStr, totalWinningTickets)
+	}
+
+	if totalTickets > 0 {
+		orch.node.AuditLog.Append(audit.EventTicketsReceived, map[string]string{
+			"sender":         sender.Hex(),
+			"manifestID":     string(manifestID),
+			"tickets":        strconv.Itoa(totalTickets),
+			"winningTickets": strconv.Itoa(totalWinningTickets),
+			"faceValue":      ticketParams.FaceValue.String(),
+			"winProb":        ticketParams.WinProb.String(),
+			"ev":             totalEV.RatString(),
+			"pricePerPixel":  priceInfoRat.RatString(),
+		})
 	}
 
 	if receiveErr != nil {
@@ -244,6 +305,8 @@ func (orch *orchestrator) TicketParams(sender ethcommon.Address, priceInfo *net.
 			CreationRound:          params.ExpirationParams.CreationRound,
 			CreationRoundBlockHash: params.ExpirationParams.CreationRoundBlockHash.Bytes(),
 		},
+		PayoutSplit: common.ProtoPayoutSplit(params.PayoutSplit),
+		Version:     params.Version,
 	}, nil
 }
 
@@ -297,20 +360,49 @@ func (orch *orchestrator) SufficientBalance(addr ethcommon.Address, manifestID M
 	}
 
 	balance := orch.node.Balances.Balance(addr, manifestID)
-	if balance == nil || balance.Cmp(orch.node.Recipient.EV()) < 0 {
+	if balance == nil {
+		return false
+	}
+	// The balance must cover the EV of the tickets of the sender, which depends on the
+	// price of its ticket params if they are tuned
+	price, err := orch.priceInfo(addr)
+	if err != nil {
+		glog.Errorf("Error getting price for sender=%v err=%v", addr.Hex(), err)
 		return false
 	}
-	return true
+	return balance.Cmp(orch.node.Recipient.EV(price)) >= 0
 }
 
 // DebitFees debits the balance for a ManifestID based on the amount of output pixels * price
 func (orch *orchestrator) DebitFees(addr ethcommon.Address, manifestID ManifestID, price *net.PriceInfo, pixels int64) {
+	if orch.node == nil {
+		return
+	}
+	if monitor.Enabled {
+		monitor.SenderPixelsTranscoded(addr.String(), pixels)
+	}
 	// Don't debit in offchain mode
-	if orch.node == nil || orch.node.Balances == nil {
+	if orch.node.Balances == nil {
+		if orch.node.SenderStats != nil {
+			orch.node.SenderStats.RecordSegment(addr, manifestID, pixels, nil)
+		}
 		return
 	}
 	priceRat := big.NewRat(price.GetPricePerUnit(), price.GetPixelsPerUnit())
-	orch.node.Balances.Debit(addr, manifestID, priceRat.Mul(priceRat, big.NewRat(pixels, 1)))
+	fees := priceRat.Mul(priceRat, big.NewRat(pixels, 1))
+	orch.node.Balances.Debit(addr, manifestID, fees)
+	if orch.node.SenderStats != nil {
+		orch.node.SenderStats.RecordSegment(addr, manifestID, pixels, fees)
+	}
+}
+
+// ShouldRotateTicketParams returns true if the broadcaster should replace the ticket params
+// of a payment, because their recipientRand is about to expire
+func (orch *orchestrator) ShouldRotateTicketParams(payment net.Payment) bool {
+	if orch.node == nil || orch.node.Recipient == nil || payment.TicketParams == nil {
+		return false
+	}
+	return orch.node.Recipient.ShouldRotateTicketParams(ethcommon.BytesToHash(payment.TicketParams.RecipientRandHash))
 }
 
 func (orch *orchestrator) Capabilities() *net.Capabilities {
@@ -320,6 +412,13 @@ func (orch *orchestrator) Capabilities() *net.Capabilities {
 	return orch.node.Capabilities.ToNetCapabilities()
 }
 
+func (orch *orchestrator) CapabilityPrices() CapabilityPrices {
+	if orch.node == nil {
+		return nil
+	}
+	return orch.node.CapabilityPrices
+}
+
 func (orch *orchestrator) isActive(addr ethcommon.Address) (bool, error) {
 	filter := &common.DBOrchFilter{
 		CurrentRound: orch.rm.LastInitializedRound(),
@@ -342,6 +441,8 @@ func NewOrchestrator(n *LivepeerNode, rm common.RoundsManager) *orchestrator {
 		node:    n,
 		address: addr,
 		rm:      rm,
+		// Tokens issued before a restart are rejected
+		authTokenKey: pm.RandBytes(32),
 	}
 }
 
@@ -355,18 +456,21 @@ type TranscodeResult struct {
 	Sig           []byte
 	TranscodeData *TranscodeData
 	OS            drivers.OSSession
+	Receipt       *net.TranscodeReceipt
 }
 
 // TranscodeData contains the transcoding output for an input segment
 type TranscodeData struct {
 	Segments []*TranscodedSegmentData
 	Pixels   int64 // Decoded pixels
+	Frames   int   // Decoded frames, 0 if unknown
 }
 
 // TranscodedSegmentData contains encoded data for a profile
 type TranscodedSegmentData struct {
 	Data   []byte
 	Pixels int64 // Encoded pixels
+	Frames int   // Encoded frames, 0 if unknown
 }
 
 type SegChanData struct {
@@ -428,7 +532,7 @@ func (n *LivepeerNode) getSegmentChan(md *SegTranscodingMetadata) (SegmentChan,
 	if sc, ok := n.SegmentChans[md.ManifestID]; ok {
 		return sc, nil
 	}
-	if len(n.SegmentChans) >= MaxSessions {
+	if len(n.SegmentChans) >= MaxSessions() {
 		return nil, ErrOrchCap
 	}
 	sc := make(SegmentChan, 1)
@@ -568,7 +672,18 @@ func (n *LivepeerNode) transcodeSeg(config transcodeConfig, seg *stream.HLSSegme
 	tr.Sig, tr.Err = n.Eth.Sign(segHash)
 	if tr.Err != nil {
 		glog.Error("Unable to sign hash of transcoded segment hashes: ", tr.Err)
+		return &tr
+	}
+	// Broadcasters that don't know about receipts don't get any
+	if !HasProtocolFeature(md.Protocol, ProtocolFeatureReceipts) {
+		return &tr
+	}
+	receipt, err := n.receipt(md.ManifestID, int64(seg.SeqNo), segHash)
+	if err != nil {
+		// Receipts are best effort and do not fail the segment
+		glog.Errorf("Unable to sign transcode receipt manifestID=%s seqNo=%d err=%v", md.ManifestID, seg.SeqNo, err)
 	}
+	tr.Receipt = receipt
 	return &tr
 }
 
@@ -603,6 +718,10 @@ func (n *LivepeerNode) transcodeSegmentLoop(md *SegTranscodingMetadata, segChan
 				os.EndSession()
 				los.EndSession()
 				glog.V(common.DEBUG).Info("Segment loop timed out; closing ", md.ManifestID)
+				if n.Receipts != nil {
+					n.Receipts.Flush(string(md.ManifestID))
+				}
+				n.Bandwidth.RemoveStream(md.ManifestID)
 				n.segmentMutex.Lock()
 				if _, ok := n.SegmentChans[md.ManifestID]; ok {
 					close(n.SegmentChans[md.ManifestID])
@@ -643,6 +762,35 @@ type RemoteTranscoder struct {
 	addr     string
 	capacity int
 	load     int
+
+	// The remaining fields track the performance of the transcoder, and are
+	// protected by the RTmutex of the manager
+	connectedAt time.Time
+	segments    int
+	failures    int
+	latency     time.Duration
+}
+
+// RemoteTranscoderStatus is the load and the performance of a transcoder of the pool
+type RemoteTranscoderStatus struct {
+	Address     string
+	Capacity    int
+	Load        int
+	ConnectedAt time.Time
+	// Segments and Failures are the numbers of segments that the transcoder
+	// transcoded and failed to transcode
+	Segments int
+	Failures int
+	// Latency is the moving average of the time taken by the transcoder to
+	// return the results of a segment
+	Latency time.Duration
+}
+
+// TranscoderPoolStatus is the status of the transcoders connected to an orchestrator
+type TranscoderPoolStatus struct {
+	Load        int
+	Capacity    int
+	Transcoders []RemoteTranscoderStatus
 }
 
 // RemoteTranscoderFatalError wraps error to indicate that error is fatal
@@ -658,6 +806,44 @@ func NewRemoteTranscoderFatalError(err error) error {
 
 var ErrRemoteTranscoderTimeout = errors.New("Remote transcoder took too long")
 
+// Errors returned by remote transcoders when the source segment doesn't match the
+// hash or the signature of the broadcaster
+var (
+	ErrSourceSegmentHash = errors.New("Source segment hash mismatch")
+	ErrSourceSegmentSig  = errors.New("Source segment signature mismatch")
+)
+
+// remoteInputErrs are the errors of remote transcoders caused by the source segment
+// itself, which any other transcoder would fail to transcode too
+var remoteInputErrs = []error{
+	ErrSourceSegmentHash,
+	ErrSourceSegmentSig,
+	ffmpeg.ErrTranscoderInp,
+	ffmpeg.ErrTranscoderFmt,
+}
+
+// isRemoteInputError returns whether err is caused by the source segment. Remote
+// transcoders return their errors as strings, so the messages are compared
+func isRemoteInputError(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, e := range remoteInputErrs {
+		if err.Error() == e.Error() {
+			return true
+		}
+	}
+	return false
+}
+
+// maxRemoteTranscodeAttempts is the number of transcoders that a segment is
+// dispatched to if they fail to transcode it
+const maxRemoteTranscodeAttempts = 2
+
+// remoteTranscoderLatencyWeight is the weight of the latest segment in the
+// moving average of the latency of a transcoder
+const remoteTranscoderLatencyWeight = 0.2
+
 func (rt *RemoteTranscoder) done() {
 	// select so we don't block indefinitely if there's no listener
 	select {
@@ -677,11 +863,11 @@ func (rt *RemoteTranscoder) Transcode(md *SegTranscodingMetadata) (*TranscodeDat
 		return nil, RemoteTranscoderFatalError{err}
 	}
 
-	// Copy and remove some fields to minimize unneeded transfer
+	// Copy and remove some fields to minimize unneeded transfer. The hash, the
+	// sequence number and the signature of the broadcaster are kept so that the
+	// transcoder can check the source segment before transcoding it
 	mdCopy := *md
 	mdCopy.OS = nil // remote transcoders currently upload directly back to O
-	mdCopy.Hash = ethcommon.Hash{}
-	mdCopy.Seq = 0
 	segData, err := NetSegData(&mdCopy)
 	if err != nil {
 		return nil, err
@@ -694,6 +880,9 @@ func (rt *RemoteTranscoder) Transcode(md *SegTranscodingMetadata) (*TranscodeDat
 		// Triggers failure on Os that don't know how to use SegData
 		Profiles: []byte("invalid"),
 	}
+	if (md.Sender != ethcommon.Address{}) {
+		msg.Sender = md.Sender.Bytes()
+	}
 	err = rt.stream.Send(msg)
 
 	if err != nil {
@@ -725,6 +914,8 @@ func NewRemoteTranscoder(m *RemoteTranscoderManager, stream net.Transcoder_Regis
 		eof:      make(chan struct{}, 1),
 		capacity: capacity,
 		addr:     common.GetConnectionAddr(stream.Context()),
+
+		connectedAt: time.Now(),
 	}
 }
 
@@ -780,6 +971,32 @@ func (rtm *RemoteTranscoderManager) RegisteredTranscodersInfo() []net.RemoteTran
 	return res
 }
 
+// PoolStatus returns the load, the capacity and the performance of the live transcoders,
+// sorted by address
+func (rtm *RemoteTranscoderManager) PoolStatus() TranscoderPoolStatus {
+	rtm.RTmutex.Lock()
+	defer rtm.RTmutex.Unlock()
+
+	var res TranscoderPoolStatus
+	res.Load, res.Capacity, _ = rtm.totalLoadAndCapacity()
+	res.Transcoders = make([]RemoteTranscoderStatus, 0, len(rtm.liveTranscoders))
+	for _, t := range rtm.liveTranscoders {
+		res.Transcoders = append(res.Transcoders, RemoteTranscoderStatus{
+			Address:     t.addr,
+			Capacity:    t.capacity,
+			Load:        t.load,
+			ConnectedAt: t.connectedAt,
+			Segments:    t.segments,
+			Failures:    t.failures,
+			Latency:     t.latency,
+		})
+	}
+	sort.Slice(res.Transcoders, func(i, j int) bool {
+		return res.Transcoders[i].Address < res.Transcoders[j].Address
+	})
+	return res
+}
+
 // Manage adds transcoder to list of live transcoders. Doesn't return untill transcoder disconnects
 func (rtm *RemoteTranscoderManager) Manage(stream net.Transcoder_RegisterTranscoderServer, capacity int) {
 	from := common.GetConnectionAddr(stream.Context())
@@ -820,23 +1037,29 @@ func (rtm *RemoteTranscoderManager) Manage(stream net.Transcoder_RegisterTransco
 }
 
 func (rtm *RemoteTranscoderManager) selectTranscoder() *RemoteTranscoder {
+	return rtm.selectTranscoderExcept(nil)
+}
+
+// selectTranscoderExcept selects the least loaded live transcoder that is not in
+// tried, e.g. the transcoders that already failed to transcode a segment
+func (rtm *RemoteTranscoderManager) selectTranscoderExcept(tried map[*RemoteTranscoder]bool) *RemoteTranscoder {
 	rtm.RTmutex.Lock()
 	defer rtm.RTmutex.Unlock()
 
-	checkTranscoders := func(rtm *RemoteTranscoderManager) bool {
-		return len(rtm.remoteTranscoders) > 0
-	}
-
-	for checkTranscoders(rtm) {
-		last := len(rtm.remoteTranscoders) - 1
-		currentTranscoder := rtm.remoteTranscoders[last]
+	for i := len(rtm.remoteTranscoders) - 1; i >= 0; i-- {
+		currentTranscoder := rtm.remoteTranscoders[i]
 		if _, ok := rtm.liveTranscoders[currentTranscoder.stream]; !ok {
-			// transcoder does not exist in table; remove and retry
-			rtm.remoteTranscoders = rtm.remoteTranscoders[:last]
+			if i == len(rtm.remoteTranscoders)-1 {
+				// transcoder does not exist in table; remove and retry
+				rtm.remoteTranscoders = rtm.remoteTranscoders[:i]
+			}
+			continue
+		}
+		if tried[currentTranscoder] {
 			continue
 		}
 		if currentTranscoder.load == currentTranscoder.capacity {
-			// Head of queue is at capacity, so the rest must be too. Exit early
+			// Least loaded transcoder is at capacity, so the rest must be too. Exit early
 			return nil
 		}
 		currentTranscoder.load++
@@ -859,6 +1082,24 @@ func (rtm *RemoteTranscoderManager) completeTranscoders(trans *RemoteTranscoder)
 	sort.Sort(byLoadFactor(rtm.remoteTranscoders))
 }
 
+// recordTranscode updates the performance of a transcoder after it returned the
+// results of a segment in took
+func (rtm *RemoteTranscoderManager) recordTranscode(trans *RemoteTranscoder, took time.Duration, err error) {
+	rtm.RTmutex.Lock()
+	defer rtm.RTmutex.Unlock()
+
+	if err != nil {
+		trans.failures++
+		return
+	}
+	if trans.segments == 0 {
+		trans.latency = took
+	} else {
+		trans.latency += time.Duration(remoteTranscoderLatencyWeight * float64(took-trans.latency))
+	}
+	trans.segments++
+}
+
 // Caller of this function should hold RTmutex lock
 func (rtm *RemoteTranscoderManager) totalLoadAndCapacity() (int, int, int) {
 	var load, capacity int
@@ -869,22 +1110,50 @@ func (rtm *RemoteTranscoderManager) totalLoadAndCapacity() (int, int, int) {
 	return load, capacity, len(rtm.liveTranscoders)
 }
 
-// Transcode does actual transcoding using remote transcoder from the pool
+// Transcode does actual transcoding using remote transcoder from the pool. A
+// segment is dispatched again to another transcoder if the transcoder is lost,
+// and up to maxRemoteTranscodeAttempts times if the transcoder fails to
+// transcode it, unless the source segment itself is bad
 func (rtm *RemoteTranscoderManager) Transcode(md *SegTranscodingMetadata) (*TranscodeData, error) {
-	currentTranscoder := rtm.selectTranscoder()
-	if currentTranscoder == nil {
-		return nil, errors.New("No transcoders available")
-	}
-	res, err := currentTranscoder.Transcode(md)
-	_, fatal := err.(RemoteTranscoderFatalError)
-	if fatal {
-		// Don't retry if we've timed out; broadcaster likely to have moved on
-		// XXX problematic for VOD when we *should* retry
-		if err.(RemoteTranscoderFatalError).error == ErrRemoteTranscoderTimeout {
+	tried := make(map[*RemoteTranscoder]bool)
+	var res *TranscodeData
+	var err error
+	for {
+		currentTranscoder := rtm.selectTranscoderExcept(tried)
+		if currentTranscoder == nil {
+			if len(tried) > 0 {
+				// No other transcoder to dispatch the segment to
+				return res, err
+			}
+			return nil, errors.New("No transcoders available")
+		}
+		start := time.Now()
+		res, err = currentTranscoder.Transcode(md)
+		_, fatal := err.(RemoteTranscoderFatalError)
+		if fatal {
+			// The transcoder is lost, but it still failed to transcode the segment
+			rtm.recordTranscode(currentTranscoder, time.Since(start), err)
+			// Don't retry if we've timed out; broadcaster likely to have moved on
+			// XXX problematic for VOD when we *should* retry
+			if err.(RemoteTranscoderFatalError).error == ErrRemoteTranscoderTimeout {
+				return res, err
+			}
+			continue
+		}
+		if isRemoteInputError(err) {
+			// Not the fault of the transcoder, and other transcoders would fail too
+			rtm.completeTranscoders(currentTranscoder)
+			return res, err
+		}
+		rtm.recordTranscode(currentTranscoder, time.Since(start), err)
+		rtm.completeTranscoders(currentTranscoder)
+		if err == nil {
+			return res, nil
+		}
+		tried[currentTranscoder] = true
+		if len(tried) >= maxRemoteTranscodeAttempts {
 			return res, err
 		}
-		return rtm.Transcode(md)
+		glog.Errorf("Dispatching segment again after error with remote transcoder=%s fname=%s err=%v", currentTranscoder.addr, md.Fname, err)
 	}
-	rtm.completeTranscoders(currentTranscoder)
-	return res, err
 }
@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/common"
 	"github.com/livepeer/go-livepeer/drivers"
 	ffmpeg "github.com/livepeer/lpms/ffmpeg"
 	"github.com/livepeer/m3u8"
@@ -16,15 +17,24 @@ const LIVE_LIST_LENGTH uint = 6
 type PlaylistManager interface {
 	ManifestID() ManifestID
 	// Implicitly creates master and media playlists
-	// Inserts in media playlist given a link to a segment
+	// Inserts in media playlist given a link to a segment. WebM segments, which
+	// aren't valid HLS segments, are skipped
 	InsertHLSSegment(profile *ffmpeg.VideoProfile, seqNo uint64, uri string, duration float64) error
 
 	GetHLSMasterPlaylist() *m3u8.MasterPlaylist
 
 	GetHLSMediaPlaylist(rendition string) *m3u8.MediaPlaylist
 
+	// Removes a rendition from the master playlist, e.g. after its profile was
+	// removed from the stream
+	RemoveHLSRendition(rendition string)
+
 	GetOSSession() drivers.OSSession
 
+	// Ends the media playlists once the stream gets no more segments, e.g. after
+	// the last segment of a VOD file
+	EndHLSPlaylists()
+
 	Cleanup()
 }
 
@@ -35,6 +45,9 @@ type BasicPlaylistManager struct {
 	masterPList *m3u8.MasterPlaylist
 	mediaLists  map[string]*m3u8.MediaPlaylist
 	mapSync     *sync.RWMutex
+	// number of segments that the media playlists keep
+	length uint
+	vod    bool
 }
 
 // NewBasicPlaylistManager create new BasicPlaylistManager struct
@@ -47,7 +60,21 @@ func NewBasicPlaylistManager(manifestID ManifestID,
 		masterPList:    m3u8.NewMasterPlaylist(),
 		mediaLists:     make(map[string]*m3u8.MediaPlaylist),
 		mapSync:        &sync.RWMutex{},
+		length:         LIVE_LIST_LENGTH,
+	}
+	return bplm
+}
+
+// NewVODPlaylistManager creates a BasicPlaylistManager whose media playlists keep
+// all the segments of a VOD file, rather than a sliding window of the last ones
+func NewVODPlaylistManager(manifestID ManifestID, storageSession drivers.OSSession,
+	segments int) *BasicPlaylistManager {
+
+	bplm := NewBasicPlaylistManager(manifestID, storageSession)
+	if segments > 0 {
+		bplm.length = uint(segments)
 	}
+	bplm.vod = true
 	return bplm
 }
 
@@ -76,13 +103,20 @@ func (mgr *BasicPlaylistManager) getOrCreatePL(profile *ffmpeg.VideoProfile) (*m
 	if pl, ok := mgr.mediaLists[profile.Name]; ok {
 		return pl, nil
 	}
-	mpl, err := m3u8.NewMediaPlaylist(LIVE_LIST_LENGTH, LIVE_LIST_LENGTH)
+	mpl, err := m3u8.NewMediaPlaylist(mgr.length, mgr.length)
 	if err != nil {
 		glog.Error(err)
 		return nil, err
 	}
+	if mgr.vod {
+		mpl.MediaType = m3u8.VOD
+	}
 	mgr.mediaLists[profile.Name] = mpl
 	vParams := ffmpeg.VideoProfileToVariantParams(*profile)
+	vParams.Codecs = common.HLSCodecs(*profile)
+	if common.ProfileCodec(profile.Profile) == common.NoVideo {
+		vParams.Resolution = ""
+	}
 	url := fmt.Sprintf("%v/%v.m3u8", mgr.manifestID, profile.Name)
 	mgr.masterPList.Append(url, mpl, vParams)
 	return mpl, nil
@@ -91,6 +125,10 @@ func (mgr *BasicPlaylistManager) getOrCreatePL(profile *ffmpeg.VideoProfile) (*m
 func (mgr *BasicPlaylistManager) InsertHLSSegment(profile *ffmpeg.VideoProfile, seqNo uint64, uri string,
 	duration float64) error {
 
+	if profile.Format == common.FormatWebM {
+		// WebM renditions are only uploaded and returned to the client
+		return nil
+	}
 	mpl, err := mgr.getOrCreatePL(profile)
 	if err != nil {
 		return err
@@ -106,6 +144,16 @@ func (mgr *BasicPlaylistManager) InsertHLSSegment(profile *ffmpeg.VideoProfile,
 	return mpl.InsertSegment(seqNo, mseg)
 }
 
+// EndHLSPlaylists marks the media playlists as complete, so that players stop
+// polling them for new segments
+func (mgr *BasicPlaylistManager) EndHLSPlaylists() {
+	mgr.mapSync.RLock()
+	defer mgr.mapSync.RUnlock()
+	for _, mpl := range mgr.mediaLists {
+		mpl.Close()
+	}
+}
+
 // GetHLSMasterPlaylist ..
 func (mgr *BasicPlaylistManager) GetHLSMasterPlaylist() *m3u8.MasterPlaylist {
 	return mgr.masterPList
@@ -116,6 +164,27 @@ func (mgr *BasicPlaylistManager) GetHLSMediaPlaylist(rendition string) *m3u8.Med
 	return mgr.getPL(rendition)
 }
 
+// RemoveHLSRendition removes the media playlist of a rendition and its entry in the
+// master playlist. The rendition is added again by its next segment.
+func (mgr *BasicPlaylistManager) RemoveHLSRendition(rendition string) {
+	mgr.mapSync.Lock()
+	defer mgr.mapSync.Unlock()
+	if _, ok := mgr.mediaLists[rendition]; !ok {
+		return
+	}
+	delete(mgr.mediaLists, rendition)
+	url := fmt.Sprintf("%v/%v.m3u8", mgr.manifestID, rendition)
+	// Copy the variants, as the master playlist may be encoded concurrently
+	variants := make([]*m3u8.Variant, 0, len(mgr.masterPList.Variants))
+	for _, v := range mgr.masterPList.Variants {
+		if v.URI != url {
+			variants = append(variants, v)
+		}
+	}
+	mgr.masterPList.Variants = variants
+	mgr.masterPList.ResetCache()
+}
+
 func newMediaSegment(uri string, duration float64) *m3u8.MediaSegment {
 	return &m3u8.MediaSegment{
 		URI:      uri,
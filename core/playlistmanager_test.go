@@ -2,9 +2,12 @@ package core
 
 import (
 	"bytes"
+	"fmt"
 	"net/url"
+	"strings"
 	"testing"
 
+	"github.com/livepeer/go-livepeer/common"
 	"github.com/livepeer/go-livepeer/drivers"
 	ffmpeg "github.com/livepeer/lpms/ffmpeg"
 	"github.com/livepeer/m3u8"
@@ -83,6 +86,30 @@ func TestGetOrCreatePL(t *testing.T) {
 	if len(masterPL.Variants) != 2 || masterPL.Variants[1].Resolution != vProfile.Resolution {
 		t.Error("Master PL had some unexpected variants or properties")
 	}
+	if masterPL.Variants[1].Codecs != "" {
+		t.Error("H264 variant had unexpected codecs ", masterPL.Variants[1].Codecs)
+	}
+
+	// H265 profiles should list their codecs
+	hevcProfile := ffmpeg.P360p30fps16x9
+	hevcProfile.Name = "hevc"
+	hevcProfile.Profile = common.ProfileH265Main
+	if _, err := c.getOrCreatePL(&hevcProfile); err != nil {
+		t.Error("Unexpected error ", err)
+	}
+	if len(masterPL.Variants) != 3 || masterPL.Variants[2].Codecs != common.HLSCodecs(hevcProfile) {
+		t.Error("H265 variant had unexpected codecs")
+	}
+
+	// Audio-only profiles should list the audio codec without a resolution
+	audioProfile := ffmpeg.VideoProfile{Name: "audio", Resolution: "0x0", Bitrate: "128k", Profile: common.ProfileAudioOnly}
+	if _, err := c.getOrCreatePL(&audioProfile); err != nil {
+		t.Error("Unexpected error ", err)
+	}
+	if len(masterPL.Variants) != 4 || masterPL.Variants[3].Codecs != "mp4a.40.2" ||
+		masterPL.Variants[3].Resolution != "" || masterPL.Variants[3].Bandwidth != 128000 {
+		t.Error("Audio-only variant had unexpected properties")
+	}
 }
 
 func TestPlaylists(t *testing.T) {
@@ -153,6 +180,20 @@ func TestPlaylists(t *testing.T) {
 		t.Error("Unexpected seg properties in new playlist")
 	}
 
+	// WebM segments aren't inserted into the HLS playlists
+	webmProfile := ffmpeg.P240p30fps16x9
+	webmProfile.Name = "webm"
+	webmProfile.Profile = common.ProfileVP9
+	webmProfile.Format = common.FormatWebM
+	if err := c.InsertHLSSegment(&webmProfile, newSeg.SeqId, newSeg.URI, newSeg.Duration); err != nil {
+		t.Error("HLS insertion")
+	}
+	if pl := c.GetHLSMediaPlaylist(webmProfile.Name); pl != nil {
+		t.Error("Unexpected WebM playlist")
+	}
+	if len(c.GetHLSMasterPlaylist().Variants) != 2 {
+		t.Error("Unexpected WebM variant")
+	}
 }
 
 func TestCleanup(t *testing.T) {
@@ -186,3 +227,74 @@ func TestCleanup(t *testing.T) {
 		t.Fatal("Data should be cleaned up")
 	}
 }
+
+func TestRemoveHLSRendition(t *testing.T) {
+	c := NewBasicPlaylistManager(RandomManifestID(), nil)
+	p144, p240 := ffmpeg.P144p30fps16x9, ffmpeg.P240p30fps16x9
+	if err := c.InsertHLSSegment(&p144, 1, "144/1.ts", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.InsertHLSSegment(&p240, 1, "240/1.ts", 2); err != nil {
+		t.Fatal(err)
+	}
+	before := c.GetHLSMasterPlaylist().String()
+
+	c.RemoveHLSRendition(p144.Name)
+	masterPL := c.GetHLSMasterPlaylist()
+	if len(masterPL.Variants) != 1 || masterPL.Variants[0].Resolution != p240.Resolution {
+		t.Error("Unexpected variants after removing a rendition ", masterPL.Variants)
+	}
+	if masterPL.String() == before {
+		t.Error("Master playlist was not encoded again")
+	}
+	if c.GetHLSMediaPlaylist(p144.Name) != nil {
+		t.Error("Media playlist of a removed rendition")
+	}
+
+	// unknown renditions are ignored
+	c.RemoveHLSRendition("unknown")
+	if len(c.GetHLSMasterPlaylist().Variants) != 1 {
+		t.Error("Unexpected variants after removing an unknown rendition")
+	}
+
+	// the rendition comes back with its next segment
+	if err := c.InsertHLSSegment(&p144, 2, "144/2.ts", 2); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.GetHLSMasterPlaylist().Variants) != 2 || len(c.GetHLSMediaPlaylist(p144.Name).Segments) == 0 {
+		t.Error("Rendition was not added again")
+	}
+}
+
+func TestVODPlaylists(t *testing.T) {
+	c := NewVODPlaylistManager(RandomManifestID(), nil, 10)
+	vProfile := ffmpeg.P144p30fps16x9
+	for i := 0; i < 10; i++ {
+		if err := c.InsertHLSSegment(&vProfile, uint64(i), fmt.Sprintf("%d.ts", i), 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// VOD playlists keep all of their segments
+	mpl := c.GetHLSMediaPlaylist(vProfile.Name)
+	if mpl.Count() != 10 || mpl.Segments[0].URI != "0.ts" {
+		t.Errorf("Expected all the segments, got %v from %v", mpl.Count(), mpl.Segments[0].URI)
+	}
+	if strings.Contains(mpl.String(), "#EXT-X-ENDLIST") {
+		t.Error("Playlist ended before its last segment")
+	}
+	c.EndHLSPlaylists()
+	if !strings.Contains(mpl.String(), "#EXT-X-PLAYLIST-TYPE:VOD") || !strings.Contains(mpl.String(), "#EXT-X-ENDLIST") {
+		t.Error("Expected an ended VOD playlist, got ", mpl.String())
+	}
+
+	// live playlists keep a sliding window
+	live := NewBasicPlaylistManager(RandomManifestID(), nil)
+	for i := 0; i < 10; i++ {
+		if err := live.InsertHLSSegment(&vProfile, uint64(i), fmt.Sprintf("%d.ts", i), 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if live.GetHLSMediaPlaylist(vProfile.Name).Count() != LIVE_LIST_LENGTH {
+		t.Error("Unexpected length of a live playlist")
+	}
+}
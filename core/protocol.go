@@ -0,0 +1,112 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/livepeer/go-livepeer/net"
+)
+
+// Version of the protocol between broadcasters and orchestrators. Bump ProtocolVersion
+// when a release changes what nodes send each other, and MinProtocolVersion once the
+// node can no longer work with nodes speaking an older version. Nodes that predate
+// versioning speak version 0
+const (
+	ProtocolVersion    = 1
+	MinProtocolVersion = 0
+)
+
+// Optional features of the protocol, used only if both nodes support them
+const (
+	// The orchestrator periodically sends signed transcode receipts
+	ProtocolFeatureReceipts = "receipts"
+)
+
+// InlineSegmentMaxSize is the largest segment, in bytes, that the node sends in the body
+// of segment requests when the broadcaster has object storage configured. Larger
+// segments are uploaded to the object storage and the orchestrator downloads them from
+// there. Nodes always use the object storage if 0
+var InlineSegmentMaxSize uint64
+
+// protocolFeatures are the optional protocol features supported by the node
+var protocolFeatures = []string{ProtocolFeatureReceipts}
+
+// IncompatibleProtocolError is returned when a node speaks a protocol version that the
+// other node can't work with
+type IncompatibleProtocolError struct {
+	// Versions spoken by this node and the other node, and the oldest version each of
+	// them is compatible with
+	Version, MinVersion         uint32
+	PeerVersion, PeerMinVersion uint32
+}
+
+func (e *IncompatibleProtocolError) Error() string {
+	if e.PeerVersion < e.MinVersion {
+		return fmt.Sprintf("incompatible protocol version: the other node speaks version %v but this node requires at least version %v, the other node needs to be upgraded",
+			e.PeerVersion, e.MinVersion)
+	}
+	return fmt.Sprintf("incompatible protocol version: this node speaks version %v but the other node requires at least version %v, this node needs to be upgraded",
+		e.Version, e.PeerMinVersion)
+}
+
+// NewProtocolInfo returns the protocol versions and features supported by the node
+func NewProtocolInfo() *net.ProtocolInfo {
+	return &net.ProtocolInfo{
+		Version:       ProtocolVersion,
+		MinVersion:    MinProtocolVersion,
+		Features:      append([]string(nil), protocolFeatures...),
+		InlineMaxSize: InlineSegmentMaxSize,
+	}
+}
+
+// NegotiateProtocol returns the protocol spoken with a node supporting peer, which is nil
+// if the node predates versioning: the lowest of the two versions, the features
+// supported by both nodes and the smaller inline segment size. Returns an IncompatibleProtocolError if either node is too old
+// for the other
+func NegotiateProtocol(peer *net.ProtocolInfo) (*net.ProtocolInfo, error) {
+	if peer.GetVersion() < MinProtocolVersion || ProtocolVersion < peer.GetMinVersion() {
+		return nil, &IncompatibleProtocolError{
+			Version:        ProtocolVersion,
+			MinVersion:     MinProtocolVersion,
+			PeerVersion:    peer.GetVersion(),
+			PeerMinVersion: peer.GetMinVersion(),
+		}
+	}
+
+	version := uint32(ProtocolVersion)
+	if peer.GetVersion() < version {
+		version = peer.GetVersion()
+	}
+	var features []string
+	for _, f := range protocolFeatures {
+		if HasProtocolFeature(peer, f) {
+			features = append(features, f)
+		}
+	}
+	inlineMaxSize := InlineSegmentMaxSize
+	if peer.GetInlineMaxSize() < inlineMaxSize {
+		inlineMaxSize = peer.GetInlineMaxSize()
+	}
+	return &net.ProtocolInfo{
+		Version:       version,
+		MinVersion:    MinProtocolVersion,
+		Features:      features,
+		InlineMaxSize: inlineMaxSize,
+	}, nil
+}
+
+// SendInline returns true if a segment of size bytes is sent in the body of segment
+// requests under protocol, rather than through object storage
+func SendInline(protocol *net.ProtocolInfo, size int) bool {
+	max := protocol.GetInlineMaxSize()
+	return max > 0 && size >= 0 && uint64(size) <= max
+}
+
+// HasProtocolFeature returns true if feature is one of the features of protocol
+func HasProtocolFeature(protocol *net.ProtocolInfo, feature string) bool {
+	for _, f := range protocol.GetFeatures() {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateProtocol(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Nodes that predate versioning speak version 0 without any feature
+	p, err := NegotiateProtocol(nil)
+	require.Nil(err)
+	assert.Equal(uint32(0), p.Version)
+	assert.False(HasProtocolFeature(p, ProtocolFeatureReceipts))
+
+	p, err = NegotiateProtocol(NewProtocolInfo())
+	require.Nil(err)
+	assert.Equal(uint32(ProtocolVersion), p.Version)
+	assert.Equal(uint32(MinProtocolVersion), p.MinVersion)
+	assert.True(HasProtocolFeature(p, ProtocolFeatureReceipts))
+
+	// The lowest version and the common features are used
+	p, err = NegotiateProtocol(&net.ProtocolInfo{Version: ProtocolVersion + 1, Features: []string{"foo", ProtocolFeatureReceipts}})
+	require.Nil(err)
+	assert.Equal(uint32(ProtocolVersion), p.Version)
+	assert.Equal([]string{ProtocolFeatureReceipts}, p.Features)
+
+	// Newer nodes that can't work with this node are rejected
+	_, err = NegotiateProtocol(&net.ProtocolInfo{Version: ProtocolVersion + 2, MinVersion: ProtocolVersion + 1})
+	require.IsType(&IncompatibleProtocolError{}, err)
+	assert.Contains(err.Error(), "this node needs to be upgraded")
+
+	// and so are older nodes that this node can't work with
+	err = &IncompatibleProtocolError{Version: 3, MinVersion: 2, PeerVersion: 1}
+	assert.EqualError(err, "incompatible protocol version: the other node speaks version 1 but this node requires at least version 2, the other node needs to be upgraded")
+}
+
+func TestNegotiateProtocol_InlineMaxSize(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	defer func() { InlineSegmentMaxSize = 0 }()
+
+	// Segments always go through object storage by default, and with nodes that predate
+	// the negotiation
+	p, err := NegotiateProtocol(&net.ProtocolInfo{InlineMaxSize: 1000})
+	require.Nil(err)
+	assert.False(SendInline(p, 10))
+	InlineSegmentMaxSize = 1000
+	assert.Equal(uint64(1000), NewProtocolInfo().InlineMaxSize)
+	p, err = NegotiateProtocol(nil)
+	require.Nil(err)
+	assert.False(SendInline(p, 10))
+
+	// The smaller size of both nodes is used
+	p, err = NegotiateProtocol(&net.ProtocolInfo{InlineMaxSize: 500})
+	require.Nil(err)
+	assert.Equal(uint64(500), p.InlineMaxSize)
+	assert.True(SendInline(p, 500))
+	assert.False(SendInline(p, 501))
+	p, err = NegotiateProtocol(&net.ProtocolInfo{InlineMaxSize: 2000})
+	require.Nil(err)
+	assert.Equal(uint64(1000), p.InlineMaxSize)
+}
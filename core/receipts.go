@@ -0,0 +1,253 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	lpcrypto "github.com/livepeer/go-livepeer/crypto"
+	"github.com/livepeer/go-livepeer/net"
+)
+
+// ReceiptInterval is the number of segments of a stream covered by each transcode
+// receipt signed by an orchestrator. Set to 0 to disable receipts
+var ReceiptInterval = 10
+
+// ReceiptSegment is a transcoded segment covered by a receipt
+type ReceiptSegment struct {
+	SeqNo int64
+	// Hash of the concatenated rendition hashes, which is also the hash that is
+	// signed for every segment
+	Hash []byte
+}
+
+// Leaf returns the Merkle leaf of the segment
+func (s ReceiptSegment) Leaf() []byte {
+	seq := make([]byte, 8)
+	binary.BigEndian.PutUint64(seq, uint64(s.SeqNo))
+	return crypto.Keccak256(seq, s.Hash)
+}
+
+// SegmentHash returns the hash of the concatenated hashes of the renditions of a segment
+func SegmentHash(renditions [][]byte) []byte {
+	hashes := make([][]byte, len(renditions))
+	for i, data := range renditions {
+		hashes[i] = crypto.Keccak256(data)
+	}
+	return crypto.Keccak256(hashes...)
+}
+
+// sortedLeaves returns the leaves of the segments sorted by sequence number, so that
+// the orchestrator and the broadcaster compute the same root regardless of the order
+// in which the segments were transcoded
+func sortedLeaves(segs []ReceiptSegment) ([]ReceiptSegment, [][]byte) {
+	sorted := make([]ReceiptSegment, len(segs))
+	copy(sorted, segs)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].SeqNo != sorted[j].SeqNo {
+			return sorted[i].SeqNo < sorted[j].SeqNo
+		}
+		return bytes.Compare(sorted[i].Hash, sorted[j].Hash) < 0
+	})
+	leaves := make([][]byte, len(sorted))
+	for i, s := range sorted {
+		leaves[i] = s.Leaf()
+	}
+	return sorted, leaves
+}
+
+// ReceiptRoot returns the Merkle root of the segments
+func ReceiptRoot(segs []ReceiptSegment) []byte {
+	_, leaves := sortedLeaves(segs)
+	return lpcrypto.MerkleRoot(leaves)
+}
+
+// ReceiptProof returns the Merkle leaf of the segment with the given sequence number
+// and the proof that it is part of the root of the segments
+func ReceiptProof(segs []ReceiptSegment, seqNo int64) ([]byte, [][]byte, error) {
+	sorted, leaves := sortedLeaves(segs)
+	for i, s := range sorted {
+		if s.SeqNo == seqNo {
+			proof, err := lpcrypto.MerkleProof(leaves, i)
+			return leaves[i], proof, err
+		}
+	}
+	return nil, nil, lpcrypto.ErrMerkleIndex
+}
+
+// NewReceipt returns an unsigned receipt for the segments of a stream
+func NewReceipt(mid ManifestID, segs []ReceiptSegment) *net.TranscodeReceipt {
+	r := &net.TranscodeReceipt{
+		ManifestId: string(mid),
+		Segments:   int64(len(segs)),
+		Root:       ReceiptRoot(segs),
+	}
+	for i, s := range segs {
+		if i == 0 || s.SeqNo < r.StartSeq {
+			r.StartSeq = s.SeqNo
+		}
+		if i == 0 || s.SeqNo > r.EndSeq {
+			r.EndSeq = s.SeqNo
+		}
+	}
+	return r
+}
+
+// FlattenReceipt returns the bytes of a receipt that are hashed and signed by the orchestrator
+func FlattenReceipt(r *net.TranscodeReceipt) []byte {
+	buf := make([]byte, 24)
+	binary.BigEndian.PutUint64(buf[0:], uint64(r.StartSeq))
+	binary.BigEndian.PutUint64(buf[8:], uint64(r.EndSeq))
+	binary.BigEndian.PutUint64(buf[16:], uint64(r.Segments))
+	return bytes.Join([][]byte{[]byte(r.ManifestId), buf, r.Root}, nil)
+}
+
+// receiptMaxSegments is the most segments and the most pending receipts that a ReceiptTracker
+// holds for a key. The oldest ones are dropped beyond it, e.g. when an orchestrator stops sending
+// receipts, or sends receipts for segments that are never delivered
+var receiptMaxSegments = 1000
+
+// ReceiptTracker accumulates the segments of every stream that are not covered by a receipt
+// yet, keyed by sequence number, along with the receipts still waiting for some of their segments
+type ReceiptTracker struct {
+	mu       sync.Mutex
+	segments map[string]map[int64]ReceiptSegment
+	pending  map[string][]*net.TranscodeReceipt
+}
+
+// ReceiptBatch is a receipt along with the segments recorded for it
+type ReceiptBatch struct {
+	Receipt  *net.TranscodeReceipt
+	Segments []ReceiptSegment
+}
+
+// NewReceiptTracker creates a new ReceiptTracker instance
+func NewReceiptTracker() *ReceiptTracker {
+	return &ReceiptTracker{
+		segments: make(map[string]map[int64]ReceiptSegment),
+		pending:  make(map[string][]*net.TranscodeReceipt),
+	}
+}
+
+// Add records a segment and returns the number of segments recorded under key since the last flush
+func (rt *ReceiptTracker) Add(key string, seg ReceiptSegment) int {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if rt.segments[key] == nil {
+		rt.segments[key] = make(map[int64]ReceiptSegment)
+	}
+	rt.segments[key][seg.SeqNo] = seg
+	if len(rt.segments[key]) > receiptMaxSegments {
+		min := rt.minSeqNo(key, receiptMaxSegments)
+		rt.take(key, func(s ReceiptSegment) bool { return s.SeqNo < min })
+	}
+	return len(rt.segments[key])
+}
+
+// minSeqNo returns the lowest sequence number of the newest n segments recorded under key.
+// Caller of this function should hold the lock
+func (rt *ReceiptTracker) minSeqNo(key string, n int) int64 {
+	seqNos := make([]int64, 0, len(rt.segments[key]))
+	for seqNo := range rt.segments[key] {
+		seqNos = append(seqNos, seqNo)
+	}
+	sort.Slice(seqNos, func(i, j int) bool { return seqNos[i] > seqNos[j] })
+	if n > len(seqNos) {
+		n = len(seqNos)
+	}
+	return seqNos[n-1]
+}
+
+// Flush returns the segments recorded under key, sorted by sequence number, and starts a new batch
+func (rt *ReceiptTracker) Flush(key string) []ReceiptSegment {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	segs := rt.take(key, func(ReceiptSegment) bool { return true })
+	delete(rt.segments, key)
+	delete(rt.pending, key)
+	return segs
+}
+
+// Settle records a receipt received under key, if any, and returns the receipts whose segments
+// have all been recorded, along with those segments. Segments complete concurrently, so a receipt
+// may arrive before some of the segments it covers and is held until they are recorded. Receipts
+// older than a settled receipt are settled with the segments recorded for them so far, since the
+// segments still missing are not going to be delivered
+func (rt *ReceiptTracker) Settle(key string, r *net.TranscodeReceipt) []ReceiptBatch {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	pending := rt.pending[key]
+	if r != nil {
+		pending = append(pending, r)
+		sort.SliceStable(pending, func(i, j int) bool { return pending[i].StartSeq < pending[j].StartSeq })
+		if len(pending) > receiptMaxSegments {
+			pending = pending[len(pending)-receiptMaxSegments:]
+		}
+	}
+
+	last := -1
+	for i, p := range pending {
+		n := 0
+		for seqNo := range rt.segments[key] {
+			if seqNo >= p.StartSeq && seqNo <= p.EndSeq {
+				n++
+			}
+		}
+		if int64(n) >= p.Segments {
+			last = i
+		}
+	}
+
+	var batches []ReceiptBatch
+	for _, p := range pending[:last+1] {
+		// Segments preceding the receipt aren't covered by any receipt and are dropped
+		segs := rt.take(key, func(s ReceiptSegment) bool { return s.SeqNo <= p.EndSeq })
+		var covered []ReceiptSegment
+		for _, s := range segs {
+			if s.SeqNo >= p.StartSeq {
+				covered = append(covered, s)
+			}
+		}
+		batches = append(batches, ReceiptBatch{Receipt: p, Segments: covered})
+	}
+	rt.pending[key] = pending[last+1:]
+	if len(rt.pending[key]) == 0 {
+		delete(rt.pending, key)
+	}
+	return batches
+}
+
+// take removes and returns the segments recorded under key that match, sorted by sequence number
+func (rt *ReceiptTracker) take(key string, match func(ReceiptSegment) bool) []ReceiptSegment {
+	var segs []ReceiptSegment
+	for seqNo, s := range rt.segments[key] {
+		if match(s) {
+			segs = append(segs, s)
+			delete(rt.segments[key], seqNo)
+		}
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i].SeqNo < segs[j].SeqNo })
+	return segs
+}
+
+// receipt records a transcoded segment and returns a signed receipt once
+// ReceiptInterval segments of the stream have been transcoded
+func (n *LivepeerNode) receipt(mid ManifestID, seqNo int64, segHash []byte) (*net.TranscodeReceipt, error) {
+	if ReceiptInterval <= 0 || n.Receipts == nil || n.Eth == nil {
+		return nil, nil
+	}
+	if n.Receipts.Add(string(mid), ReceiptSegment{SeqNo: seqNo, Hash: segHash}) < ReceiptInterval {
+		return nil, nil
+	}
+	r := NewReceipt(mid, n.Receipts.Flush(string(mid)))
+	sig, err := n.Eth.Sign(crypto.Keccak256(FlattenReceipt(r)))
+	if err != nil {
+		return nil, err
+	}
+	r.Sig = sig
+	return r, nil
+}
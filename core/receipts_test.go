@@ -0,0 +1,189 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	lpcrypto "github.com/livepeer/go-livepeer/crypto"
+	"github.com/livepeer/go-livepeer/eth"
+)
+
+func receiptSegments(seqs ...int64) []ReceiptSegment {
+	var segs []ReceiptSegment
+	for _, seq := range seqs {
+		segs = append(segs, ReceiptSegment{SeqNo: seq, Hash: SegmentHash([][]byte{[]byte(fmt.Sprint(seq))})})
+	}
+	return segs
+}
+
+func TestReceiptRoot(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// The root doesn't depend on the order of the segments
+	segs := receiptSegments(3, 1, 2, 0)
+	root := ReceiptRoot(segs)
+	assert.Equal(root, ReceiptRoot(receiptSegments(0, 1, 2, 3)))
+	assert.NotEqual(root, ReceiptRoot(receiptSegments(0, 1, 2)))
+
+	// The sequence number is part of the leaf
+	swapped := []ReceiptSegment{{SeqNo: 1, Hash: segs[0].Hash}, {SeqNo: 3, Hash: segs[1].Hash}, segs[2], segs[3]}
+	assert.NotEqual(root, ReceiptRoot(swapped))
+
+	for _, s := range segs {
+		leaf, proof, err := ReceiptProof(segs, s.SeqNo)
+		require.Nil(err)
+		assert.Equal(s.Leaf(), leaf)
+		assert.True(lpcrypto.VerifyMerkleProof(root, leaf, proof))
+	}
+	_, _, err := ReceiptProof(segs, 4)
+	assert.Equal(lpcrypto.ErrMerkleIndex, err)
+
+	r := NewReceipt(ManifestID("mid"), segs)
+	assert.Equal("mid", r.ManifestId)
+	assert.Equal(int64(0), r.StartSeq)
+	assert.Equal(int64(3), r.EndSeq)
+	assert.Equal(int64(4), r.Segments)
+	assert.Equal(root, r.Root)
+
+	// Every field is signed
+	flat := FlattenReceipt(r)
+	r.EndSeq = 4
+	assert.NotEqual(flat, FlattenReceipt(r))
+}
+
+func TestReceiptTracker(t *testing.T) {
+	assert := assert.New(t)
+
+	rt := NewReceiptTracker()
+	segs := receiptSegments(0, 1)
+	assert.Equal(1, rt.Add("a", segs[0]))
+	assert.Equal(2, rt.Add("a", segs[1]))
+	assert.Equal(1, rt.Add("b", segs[0]))
+
+	assert.Equal(segs, rt.Flush("a"))
+	assert.Empty(rt.Flush("a"))
+	assert.Equal(1, rt.Add("a", segs[0]))
+	assert.Equal(segs[:1], rt.Flush("b"))
+	// Segments are keyed and returned by sequence number
+	rt.Flush("a")
+	assert.Equal(1, rt.Add("a", segs[1]))
+	assert.Equal(2, rt.Add("a", segs[0]))
+	assert.Equal(2, rt.Add("a", segs[1]))
+	assert.Equal(segs, rt.Flush("a"))
+}
+
+func TestReceiptTracker_MaxSegments(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(max int) { receiptMaxSegments = max }(receiptMaxSegments)
+	receiptMaxSegments = 2
+
+	// The oldest segments are dropped
+	rt := NewReceiptTracker()
+	segs := receiptSegments(0, 1, 2, 3)
+	rt.Add("a", segs[3])
+	rt.Add("a", segs[0])
+	assert.Equal(2, rt.Add("a", segs[2]))
+	assert.Equal(2, rt.Add("a", segs[1]))
+	assert.Equal(segs[2:], rt.Flush("a"))
+
+	// The oldest pending receipts are dropped
+	r1 := NewReceipt("mid", segs[:1])
+	r2 := NewReceipt("mid", segs[1:2])
+	r3 := NewReceipt("mid", segs[2:3])
+	assert.Empty(rt.Settle("a", r3))
+	assert.Empty(rt.Settle("a", r2))
+	assert.Empty(rt.Settle("a", r1))
+	rt.Add("a", segs[0])
+	rt.Add("a", segs[1])
+	rt.Add("a", segs[2])
+	assert.Equal([]ReceiptBatch{
+		{Receipt: r2, Segments: segs[1:2]},
+		{Receipt: r3, Segments: segs[2:3]},
+	}, rt.Settle("a", nil))
+}
+
+func TestReceiptTracker_Settle(t *testing.T) {
+	assert := assert.New(t)
+
+	rt := NewReceiptTracker()
+	segs := receiptSegments(0, 1, 2, 3, 4, 5, 6)
+	r1 := NewReceipt("mid", segs[1:3])
+	r2 := NewReceipt("mid", segs[3:5])
+	r3 := NewReceipt("mid", segs[5:])
+
+	// Receipts wait for the segments they cover, regardless of the order in which they complete
+	rt.Add("a", segs[0])
+	rt.Add("a", segs[2])
+	assert.Empty(rt.Settle("a", r1))
+	rt.Add("a", segs[1])
+	assert.Equal([]ReceiptBatch{{Receipt: r1, Segments: segs[1:3]}}, rt.Settle("a", nil))
+	assert.Empty(rt.Settle("a", nil))
+
+	// Older receipts are settled along with newer ones, with the segments recorded so far
+	rt.Add("a", segs[3])
+	assert.Empty(rt.Settle("a", r2))
+	rt.Add("a", segs[5])
+	rt.Add("a", segs[6])
+	assert.Equal([]ReceiptBatch{
+		{Receipt: r2, Segments: segs[3:4]},
+		{Receipt: r3, Segments: segs[5:]},
+	}, rt.Settle("a", r3))
+	assert.Empty(rt.Flush("a"))
+}
+
+func TestLivepeerNode_Receipt(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	defer func(interval int) { ReceiptInterval = interval }(ReceiptInterval)
+	ReceiptInterval = 3
+
+	n, _ := NewLivepeerNode(nil, "", nil)
+	segs := receiptSegments(0, 1, 2, 3)
+
+	// Receipts are only signed on-chain
+	r, err := n.receipt("mid", 0, segs[0].Hash)
+	assert.Nil(err)
+	assert.Nil(r)
+
+	seth := &eth.StubClient{}
+	n.Eth = seth
+	for i := 0; i < 2; i++ {
+		r, err = n.receipt("mid", segs[i].SeqNo, segs[i].Hash)
+		assert.Nil(err)
+		assert.Nil(r)
+	}
+	r, err = n.receipt("mid", segs[2].SeqNo, segs[2].Hash)
+	require.Nil(err)
+	require.NotNil(r)
+	assert.Equal(int64(3), r.Segments)
+	assert.Equal(ReceiptRoot(segs[:3]), r.Root)
+	// The stub client returns the signed message
+	assert.Equal(crypto.Keccak256(FlattenReceipt(r)), r.Sig)
+
+	// A new batch is started
+	r, err = n.receipt("mid", segs[3].SeqNo, segs[3].Hash)
+	assert.Nil(err)
+	assert.Nil(r)
+
+	// Signing errors are returned
+	seth.Err = errors.New("Sign error")
+	n.receipt("mid", 4, segs[0].Hash)
+	_, err = n.receipt("mid", 5, segs[0].Hash)
+	assert.Equal(seth.Err, err)
+
+	// Receipts can be disabled
+	ReceiptInterval = 0
+	seth.Err = nil
+	for i := 0; i < 3; i++ {
+		r, err = n.receipt("mid2", segs[i].SeqNo, segs[i].Hash)
+		assert.Nil(r)
+	}
+}
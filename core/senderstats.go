@@ -0,0 +1,251 @@
+package core
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// SenderStats holds a snapshot of the activity of a single broadcaster (ticket sender)
+// as observed by an orchestrator
+type SenderStats struct {
+	Sender           ethcommon.Address
+	Sessions         int
+	Segments         int64
+	PixelsTranscoded int64
+	FeesEarned       *big.Rat
+	PaymentErrors    int64
+	TranscodeErrors  int64
+	LastActivity     time.Time
+}
+
+// ErrorRate returns the fraction of segments from the sender that errored
+func (s *SenderStats) ErrorRate() float64 {
+	if s.Segments == 0 {
+		return 0
+	}
+	return float64(s.PaymentErrors+s.TranscodeErrors) / float64(s.Segments)
+}
+
+// senderStatsManifestTTL is the time after which a ManifestID without activity is no longer
+// associated with its sender, and maxSenderStatsManifests caps the number of associated
+// ManifestIDs, beyond which the least recently active one is dropped
+var senderStatsManifestTTL = 24 * time.Hour
+var maxSenderStatsManifests = 10000
+
+// maxSenderStats caps the number of senders with stats, beyond which the least recently
+// active sender is dropped
+var maxSenderStats = 10000
+
+type senderStats struct {
+	sessions         int
+	segments         int64
+	pixelsTranscoded int64
+	feesEarned       *big.Rat
+	paymentErrors    int64
+	transcodeErrors  int64
+	lastActivity     time.Time
+}
+
+// SenderStatsTracker aggregates per-sender analytics for an orchestrator
+type SenderStatsTracker struct {
+	mu        sync.RWMutex
+	senders   map[ethcommon.Address]*senderStats
+	manifests map[ManifestID]*manifestSender
+}
+
+type manifestSender struct {
+	sender       ethcommon.Address
+	lastActivity time.Time
+}
+
+// NewSenderStatsTracker creates a new SenderStatsTracker instance
+func NewSenderStatsTracker() *SenderStatsTracker {
+	return &SenderStatsTracker{
+		senders:   make(map[ethcommon.Address]*senderStats),
+		manifests: make(map[ManifestID]*manifestSender),
+	}
+}
+
+// RecordSession associates a ManifestID with a sender, which should only be recorded once a
+// payment of the sender is validated
+func (t *SenderStatsTracker) RecordSession(sender ethcommon.Address, manifestID ManifestID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.recordManifest(sender, manifestID)
+	t.statsForSender(sender).lastActivity = time.Now()
+}
+
+// RecordSegment records a processed segment for a sender along with the pixels transcoded
+// and the fees debited for the segment
+func (t *SenderStatsTracker) RecordSegment(sender ethcommon.Address, manifestID ManifestID, pixels int64, fees *big.Rat) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.recordManifest(sender, manifestID)
+	s := t.statsForSender(sender)
+	s.segments++
+	s.pixelsTranscoded += pixels
+	if fees != nil {
+		s.feesEarned.Add(s.feesEarned, fees)
+	}
+	s.lastActivity = time.Now()
+}
+
+// RecordPaymentError records a payment error for a sender. The sender of a payment is not
+// validated until one of its tickets is, so errors of senders without a session are dropped,
+// and any peer can't add senders by sending invalid payments
+func (t *SenderStatsTracker) RecordPaymentError(sender ethcommon.Address) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.senders[sender]
+	if !ok {
+		return
+	}
+	s.paymentErrors++
+	s.lastActivity = time.Now()
+}
+
+// RecordTranscodeError records a transcode error for the sender that owns a ManifestID.
+// If the ManifestID is not associated with a known sender the error is dropped
+func (t *SenderStatsTracker) RecordTranscodeError(manifestID ManifestID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sender, ok := t.sender(manifestID)
+	if !ok {
+		return
+	}
+	s := t.statsForSender(sender)
+	s.transcodeErrors++
+	s.lastActivity = time.Now()
+}
+
+// Sender returns the sender that owns a ManifestID
+func (t *SenderStatsTracker) Sender(manifestID ManifestID) (ethcommon.Address, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.sender(manifestID)
+}
+
+// Stats returns a snapshot of the stats for a sender or nil if the sender is unknown
+func (t *SenderStatsTracker) Stats(sender ethcommon.Address) *SenderStats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	s, ok := t.senders[sender]
+	if !ok {
+		return nil
+	}
+	return s.snapshot(sender)
+}
+
+// AllStats returns a snapshot of the stats for all known senders ordered by most recent activity
+func (t *SenderStatsTracker) AllStats() []*SenderStats {
+	t.mu.RLock()
+	res := make([]*SenderStats, 0, len(t.senders))
+	for addr, s := range t.senders {
+		res = append(res, s.snapshot(addr))
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].LastActivity.After(res[j].LastActivity)
+	})
+	return res
+}
+
+// Caller of this function should hold the lock
+func (t *SenderStatsTracker) sender(manifestID ManifestID) (ethcommon.Address, bool) {
+	m, ok := t.manifests[manifestID]
+	if !ok || time.Since(m.lastActivity) > senderStatsManifestTTL {
+		return ethcommon.Address{}, false
+	}
+	return m.sender, true
+}
+
+// recordManifest associates a ManifestID with a sender, counting a new session of the sender
+// if the ManifestID was not associated with it
+// Caller of this function should hold the lock
+func (t *SenderStatsTracker) recordManifest(sender ethcommon.Address, manifestID ManifestID) {
+	now := time.Now()
+	if m, ok := t.sender(manifestID); ok && m == sender {
+		t.manifests[manifestID].lastActivity = now
+		return
+	}
+
+	if _, ok := t.manifests[manifestID]; !ok && len(t.manifests) >= maxSenderStatsManifests {
+		t.evictManifests(now)
+	}
+	t.manifests[manifestID] = &manifestSender{sender: sender, lastActivity: now}
+	t.statsForSender(sender).sessions++
+}
+
+// evictManifests drops the expired ManifestIDs, and the least recently active one if none
+// expired
+// Caller of this function should hold the lock
+func (t *SenderStatsTracker) evictManifests(now time.Time) {
+	var oldest ManifestID
+	var oldestActivity time.Time
+	for mid, m := range t.manifests {
+		if now.Sub(m.lastActivity) > senderStatsManifestTTL {
+			delete(t.manifests, mid)
+			continue
+		}
+		if oldestActivity.IsZero() || m.lastActivity.Before(oldestActivity) {
+			oldest, oldestActivity = mid, m.lastActivity
+		}
+	}
+	if len(t.manifests) >= maxSenderStatsManifests {
+		delete(t.manifests, oldest)
+	}
+}
+
+// Caller of this function should hold the lock
+func (t *SenderStatsTracker) statsForSender(sender ethcommon.Address) *senderStats {
+	s, ok := t.senders[sender]
+	if !ok {
+		if len(t.senders) >= maxSenderStats {
+			t.evictSender()
+		}
+		s = &senderStats{
+			feesEarned: big.NewRat(0, 1),
+		}
+		t.senders[sender] = s
+	}
+	return s
+}
+
+// evictSender drops the least recently active sender
+// Caller of this function should hold the lock
+func (t *SenderStatsTracker) evictSender() {
+	var oldest ethcommon.Address
+	var oldestActivity time.Time
+	first := true
+	for addr, s := range t.senders {
+		if first || s.lastActivity.Before(oldestActivity) {
+			oldest, oldestActivity = addr, s.lastActivity
+			first = false
+		}
+	}
+	delete(t.senders, oldest)
+}
+
+func (s *senderStats) snapshot(sender ethcommon.Address) *SenderStats {
+	return &SenderStats{
+		Sender:           sender,
+		Sessions:         s.sessions,
+		Segments:         s.segments,
+		PixelsTranscoded: s.pixelsTranscoded,
+		FeesEarned:       new(big.Rat).Set(s.feesEarned),
+		PaymentErrors:    s.paymentErrors,
+		TranscodeErrors:  s.transcodeErrors,
+		LastActivity:     s.lastActivity,
+	}
+}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSenderStatsTracker_RecordSegment(t *testing.T) {
+	assert := assert.New(t)
+	tracker := NewSenderStatsTracker()
+	sender := ethcommon.BytesToAddress([]byte("foo"))
+
+	assert.Nil(tracker.Stats(sender))
+
+	tracker.RecordSession(sender, ManifestID("a"))
+	tracker.RecordSegment(sender, ManifestID("a"), 100, big.NewRat(5, 1))
+	tracker.RecordSegment(sender, ManifestID("b"), 50, nil)
+
+	stats := tracker.Stats(sender)
+	assert.Equal(sender, stats.Sender)
+	assert.Equal(2, stats.Sessions)
+	assert.Equal(int64(2), stats.Segments)
+	assert.Equal(int64(150), stats.PixelsTranscoded)
+	assert.Zero(stats.FeesEarned.Cmp(big.NewRat(5, 1)))
+	assert.False(stats.LastActivity.IsZero())
+	assert.Equal(float64(0), stats.ErrorRate())
+
+	// Snapshot should not be modified by later updates
+	tracker.RecordSegment(sender, ManifestID("a"), 100, big.NewRat(5, 1))
+	assert.Zero(stats.FeesEarned.Cmp(big.NewRat(5, 1)))
+	assert.Zero(tracker.Stats(sender).FeesEarned.Cmp(big.NewRat(10, 1)))
+}
+
+func TestSenderStatsTracker_Errors(t *testing.T) {
+	assert := assert.New(t)
+	tracker := NewSenderStatsTracker()
+	sender := ethcommon.BytesToAddress([]byte("foo"))
+
+	// Unknown manifestID is dropped
+	tracker.RecordTranscodeError(ManifestID("a"))
+	assert.Nil(tracker.Stats(sender))
+
+	// Payment errors of senders without a session are dropped
+	tracker.RecordPaymentError(sender)
+	assert.Nil(tracker.Stats(sender))
+	assert.Empty(tracker.AllStats())
+
+	tracker.RecordSession(sender, ManifestID("a"))
+	tracker.RecordSegment(sender, ManifestID("a"), 100, nil)
+	tracker.RecordSegment(sender, ManifestID("a"), 100, nil)
+	tracker.RecordSegment(sender, ManifestID("a"), 100, nil)
+	tracker.RecordSegment(sender, ManifestID("a"), 100, nil)
+	tracker.RecordTranscodeError(ManifestID("a"))
+	tracker.RecordPaymentError(sender)
+
+	stats := tracker.Stats(sender)
+	assert.Equal(int64(1), stats.TranscodeErrors)
+	assert.Equal(int64(1), stats.PaymentErrors)
+	assert.Equal(0.5, stats.ErrorRate())
+}
+
+func TestSenderStatsTracker_Manifests(t *testing.T) {
+	assert := assert.New(t)
+	tracker := NewSenderStatsTracker()
+	foo := ethcommon.BytesToAddress([]byte("foo"))
+	bar := ethcommon.BytesToAddress([]byte("bar"))
+
+	defer func(max int, ttl time.Duration) {
+		maxSenderStatsManifests = max
+		senderStatsManifestTTL = ttl
+	}(maxSenderStatsManifests, senderStatsManifestTTL)
+	maxSenderStatsManifests = 2
+
+	// The least recently active ManifestID is dropped beyond the cap
+	tracker.RecordSession(foo, ManifestID("a"))
+	tracker.RecordSession(foo, ManifestID("b"))
+	tracker.RecordSegment(foo, ManifestID("a"), 100, nil)
+	tracker.RecordSession(bar, ManifestID("c"))
+	assert.Len(tracker.manifests, 2)
+	_, ok := tracker.Sender(ManifestID("b"))
+	assert.False(ok)
+	sender, ok := tracker.Sender(ManifestID("a"))
+	assert.True(ok)
+	assert.Equal(foo, sender)
+	assert.Equal(2, tracker.Stats(foo).Sessions)
+	assert.Equal(1, tracker.Stats(bar).Sessions)
+
+	// ManifestIDs without activity expire
+	senderStatsManifestTTL = 0
+	_, ok = tracker.Sender(ManifestID("a"))
+	assert.False(ok)
+	tracker.RecordTranscodeError(ManifestID("a"))
+	assert.Zero(tracker.Stats(foo).TranscodeErrors)
+	tracker.RecordSession(bar, ManifestID("d"))
+	assert.Len(tracker.manifests, 1)
+}
+
+func TestSenderStatsTracker_MaxSenders(t *testing.T) {
+	assert := assert.New(t)
+	tracker := NewSenderStatsTracker()
+	foo := ethcommon.BytesToAddress([]byte("foo"))
+	bar := ethcommon.BytesToAddress([]byte("bar"))
+	baz := ethcommon.BytesToAddress([]byte("baz"))
+
+	defer func(max int) { maxSenderStats = max }(maxSenderStats)
+	maxSenderStats = 2
+
+	// The least recently active sender is dropped beyond the cap
+	tracker.RecordSession(foo, ManifestID("a"))
+	time.Sleep(time.Millisecond)
+	tracker.RecordSession(bar, ManifestID("b"))
+	time.Sleep(time.Millisecond)
+	tracker.RecordSegment(foo, ManifestID("a"), 100, nil)
+	tracker.RecordSession(baz, ManifestID("c"))
+	assert.Len(tracker.AllStats(), 2)
+	assert.Nil(tracker.Stats(bar))
+	assert.NotNil(tracker.Stats(foo))
+	assert.NotNil(tracker.Stats(baz))
+}
+
+func TestSenderStatsTracker_AllStats(t *testing.T) {
+	assert := assert.New(t)
+	tracker := NewSenderStatsTracker()
+	foo := ethcommon.BytesToAddress([]byte("foo"))
+	bar := ethcommon.BytesToAddress([]byte("bar"))
+
+	assert.Empty(tracker.AllStats())
+
+	tracker.RecordSession(foo, ManifestID("a"))
+	time.Sleep(time.Millisecond)
+	tracker.RecordSession(bar, ManifestID("b"))
+
+	stats := tracker.AllStats()
+	assert.Len(stats, 2)
+	// Most recently active sender first
+	assert.Equal(bar, stats[0].Sender)
+	assert.Equal(foo, stats[1].Sender)
+}
+
+func TestDebitFees_RecordsSenderStats(t *testing.T) {
+	assert := assert.New(t)
+	n, _ := NewLivepeerNode(nil, "", nil)
+	orch := NewOrchestrator(n, nil)
+	addr := ethcommon.BytesToAddress([]byte("foo"))
+	manifestID := ManifestID("some manifest")
+	price := &net.PriceInfo{
+		PricePerUnit:  1,
+		PixelsPerUnit: 5,
+	}
+
+	// Off-chain: pixels are tracked but no fees are earned
+	orch.DebitFees(addr, manifestID, price, 100)
+	stats := n.SenderStats.Stats(addr)
+	assert.Equal(int64(100), stats.PixelsTranscoded)
+	assert.Zero(stats.FeesEarned.Sign())
+
+	n.Balances = NewAddressBalances(5 * time.Second)
+	defer n.Balances.StopCleanup()
+	orch.DebitFees(addr, manifestID, price, 100)
+	stats = n.SenderStats.Stats(addr)
+	assert.Equal(int64(200), stats.PixelsTranscoded)
+	assert.Equal(int64(2), stats.Segments)
+	assert.Zero(stats.FeesEarned.Cmp(big.NewRat(20, 1)))
+}
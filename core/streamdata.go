@@ -30,6 +30,15 @@ type StreamParameters struct {
 	Format       ffmpeg.Format
 	OS           drivers.OSSession
 	Capabilities *Capabilities
+	// RequiredCapabilities are the capabilities that orchestrators must support in
+	// addition to those needed by the profiles, e.g. set by the auth webhook
+	RequiredCapabilities []Capability
+	// Clip is the part of the segment that is kept by clipping jobs. It is set on
+	// copies of the parameters of the stream for each segment of a clip.
+	Clip *ClipRange
+	// VODSegments is the number of segments of VOD files and clips, whose playlists
+	// keep all of their segments. It is zero for live streams.
+	VODSegments int
 }
 
 func (s *StreamParameters) StreamID() string {
@@ -45,6 +54,22 @@ type SegTranscodingMetadata struct {
 	OS         *net.OSInfo
 	Duration   time.Duration
 	Caps       *Capabilities
+
+	// Signature of the broadcaster over the flattened metadata and the address of
+	// the broadcaster, so that remote transcoders can check the source segment
+	Sig    []byte
+	Sender ethcommon.Address
+
+	// Protocol spoken with the other node: the protocol supported by the broadcaster
+	// when it sends the segment, and the negotiated protocol once the orchestrator
+	// received it
+	Protocol *net.ProtocolInfo
+
+	// Auth token of the broadcaster session
+	AuthToken *net.AuthToken
+
+	// Part of the segment kept by clipping jobs, nil if the segment isn't clipped
+	Clip *ClipRange
 }
 
 func (md *SegTranscodingMetadata) Flatten() []byte {
@@ -56,6 +81,14 @@ func (md *SegTranscodingMetadata) Flatten() []byte {
 	i += copy(buf[i:], md.Hash.Bytes())
 	i += copy(buf[i:], []byte(profiles))
 	// i += copy(buf[i:], []byte(s.OS))
+	// The bounds of clips are signed too, but leave the signatures of other
+	// segments unchanged for older orchestrators
+	if md.Clip != nil {
+		from := big.NewInt(int64(md.Clip.From / time.Millisecond)).Bytes()
+		to := big.NewInt(int64(md.Clip.To / time.Millisecond)).Bytes()
+		buf = append(buf, ethcommon.LeftPadBytes(from, 32)...)
+		buf = append(buf, ethcommon.LeftPadBytes(to, 32)...)
+	}
 	return buf
 }
 
@@ -78,10 +111,18 @@ func NetSegData(md *SegTranscodingMetadata) (*net.SegData, error) {
 		Storage:      storage,
 		Duration:     int32(md.Duration / time.Millisecond),
 		Capabilities: md.Caps.ToNetCapabilities(),
+		Sig:          md.Sig,
+		Protocol:     md.Protocol,
+		AuthToken:    md.AuthToken,
 		// Triggers failure on Os that don't know how to use FullProfiles/2/3
 		Profiles: []byte("invalid"),
 	}
 
+	if md.Clip != nil {
+		segData.ClipFrom = int64(md.Clip.From / time.Millisecond)
+		segData.ClipTo = int64(md.Clip.To / time.Millisecond)
+	}
+
 	// If all outputs are mpegts, use the older SegData.FullProfiles field
 	// for compatibility with older orchestrators
 	allTS := true
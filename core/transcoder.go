@@ -30,9 +30,17 @@ type LocalTranscoder struct {
 var WorkDir string
 
 func (lt *LocalTranscoder) Transcode(md *SegTranscodingMetadata) (*TranscodeData, error) {
+	fname, err := clipInput(lt.workDir, md)
+	if err != nil {
+		return nil, err
+	}
+	if fname != md.Fname {
+		defer os.Remove(fname)
+	}
+
 	// Set up in / out config
 	in := &ffmpeg.TranscodeOptionsIn{
-		Fname: md.Fname,
+		Fname: fname,
 		Accel: ffmpeg.Software,
 	}
 	profiles := md.Profiles
@@ -64,15 +72,31 @@ func NewLocalTranscoder(workDir string) Transcoder {
 type NvidiaTranscoder struct {
 	device  string
 	session *ffmpeg.Transcoder
+	// LPMS sessions keep the outputs and the encoders of their first segment,
+	// so the session is replaced when the profiles of the stream change
+	profiles []ffmpeg.VideoProfile
 }
 
 func (nv *NvidiaTranscoder) Transcode(md *SegTranscodingMetadata) (*TranscodeData, error) {
+	fname, err := clipInput(WorkDir, md)
+	if err != nil {
+		return nil, err
+	}
+	if fname != md.Fname {
+		defer os.Remove(fname)
+	}
 
 	in := &ffmpeg.TranscodeOptionsIn{
-		Fname:  md.Fname,
+		Fname:  fname,
 		Accel:  ffmpeg.Nvidia,
 		Device: nv.device,
 	}
+	if nv.profiles != nil && !sameProfiles(nv.profiles, md.Profiles) {
+		glog.V(common.DEBUG).Infof("Restarting transcode session for new profiles manifestID=%s device=%s", md.ManifestID, nv.device)
+		nv.session.StopTranscoder()
+		nv.session = ffmpeg.NewTranscoder()
+	}
+	nv.profiles = md.Profiles
 	out := profilesToTranscodeOptions(WorkDir, ffmpeg.Nvidia, md.Profiles)
 	res, err := nv.session.Transcode(in, out)
 	if err != nil {
@@ -81,21 +105,37 @@ func (nv *NvidiaTranscoder) Transcode(md *SegTranscodingMetadata) (*TranscodeDat
 	return resToTranscodeData(res, out)
 }
 
-// TestNvidiaTranscoder tries to transcode test segment on all the devices
-func TestNvidiaTranscoder(gpu string) error {
-	devices := strings.Split(gpu, ",")
-	b := bytes.NewReader(testSegment)
-	z, err := gzip.NewReader(b)
+// TestSegmentDuration is the duration of the built-in test segment: 5 frames at 25 fps
+const TestSegmentDuration = 200 * time.Millisecond
+
+// TestSegment returns the built-in MPEG-TS test segment
+func TestSegment() ([]byte, error) {
+	z, err := gzip.NewReader(bytes.NewReader(testSegment))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	mp4testSeg, err := ioutil.ReadAll(z)
-	z.Close()
+	defer z.Close()
+	return ioutil.ReadAll(z)
+}
+
+// writeTestSegment writes the test segment to the work dir and returns its name
+func writeTestSegment() (string, error) {
+	mp4testSeg, err := TestSegment()
 	if err != nil {
-		return err
+		return "", err
 	}
 	fname := filepath.Join(WorkDir, "testseg.tempfile")
 	err = ioutil.WriteFile(fname, mp4testSeg, 0644)
+	if err != nil {
+		return "", err
+	}
+	return fname, nil
+}
+
+// TestNvidiaTranscoder tries to transcode test segment on all the devices
+func TestNvidiaTranscoder(gpu string) error {
+	devices := strings.Split(gpu, ",")
+	fname, err := writeTestSegment()
 	if err != nil {
 		return err
 	}
@@ -119,6 +159,42 @@ func TestNvidiaTranscoder(gpu string) error {
 	return nil
 }
 
+// TestSoftwareEncoder tries to transcode test segment into a rendition of an
+// encoder profile in software, to check that FFmpeg was built with its encoder.
+// It returns the rendition.
+func TestSoftwareEncoder(profile ffmpeg.Profile, format ffmpeg.Format) ([]byte, error) {
+	fname, err := writeTestSegment()
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(fname)
+	return testSoftwareTranscode(fname, ffmpeg.VideoProfile{Resolution: "256x144", Bitrate: "1k", Format: format, Profile: profile})
+}
+
+// TestSoftwareDecoder tries to transcode a segment into an H264 rendition in
+// software, to check that FFmpeg was built with the decoder of the segment
+func TestSoftwareDecoder(seg []byte) error {
+	fname := filepath.Join(WorkDir, "testdecode.tempfile")
+	if err := ioutil.WriteFile(fname, seg, 0644); err != nil {
+		return err
+	}
+	defer os.Remove(fname)
+	_, err := testSoftwareTranscode(fname, ffmpeg.VideoProfile{Resolution: "256x144", Bitrate: "1k", Format: ffmpeg.FormatMPEGTS})
+	return err
+}
+
+func testSoftwareTranscode(fname string, p ffmpeg.VideoProfile) ([]byte, error) {
+	md := &SegTranscodingMetadata{Fname: fname, Profiles: []ffmpeg.VideoProfile{p}}
+	td, err := NewLocalTranscoder(WorkDir).Transcode(md)
+	if err != nil {
+		return nil, err
+	}
+	if len(td.Segments) == 0 || td.Pixels == 0 {
+		return nil, errors.New("Empty transcoded segment")
+	}
+	return td.Segments[0].Data, nil
+}
+
 func NewNvidiaTranscoder(gpu string) TranscoderSession {
 	return &NvidiaTranscoder{
 		device:  gpu,
@@ -130,6 +206,24 @@ func (nv *NvidiaTranscoder) Stop() {
 	nv.session.StopTranscoder()
 }
 
+// sameProfiles returns whether the profiles of a Nvidia session can be reused for b. The
+// bitrates are not compared: adaptive bitrates change them with every segment, and restarting
+// the session for each segment would lose the benefit of keeping the GPU session. The encoders
+// of the session keep the bitrates that they were opened with
+func sameProfiles(a, b []ffmpeg.VideoProfile) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		x, y := a[i], b[i]
+		x.Bitrate, y.Bitrate = "", ""
+		if x != y {
+			return false
+		}
+	}
+	return true
+}
+
 func parseURI(uri string) (string, uint64, error) {
 	var mid string
 	var seqNo uint64
@@ -157,13 +251,14 @@ func resToTranscodeData(res *ffmpeg.TranscodeResults, opts []ffmpeg.TranscodeOpt
 			glog.Error("Cannot read transcoded output for ", oname)
 			return nil, err
 		}
-		segments[i] = &TranscodedSegmentData{Data: o, Pixels: res.Encoded[i].Pixels}
+		segments[i] = &TranscodedSegmentData{Data: o, Pixels: res.Encoded[i].Pixels, Frames: res.Encoded[i].Frames}
 		os.Remove(oname)
 	}
 
 	return &TranscodeData{
 		Segments: segments,
 		Pixels:   res.Decoded.Pixels,
+		Frames:   res.Decoded.Frames,
 	}, nil
 }
 
@@ -176,6 +271,48 @@ func profilesToTranscodeOptions(workDir string, accel ffmpeg.Acceleration, profi
 			Accel:        accel,
 			AudioEncoder: ffmpeg.ComponentOptions{Name: "copy"},
 		}
+		// LPMS picks the H264 encoder itself, so name the encoder of other codecs.
+		// A named encoder gets the software scaler, so only software transcodes
+		// them; LPMS rejects the profile on other accelerations.
+		if accel == ffmpeg.Software {
+			switch common.ProfileCodec(profiles[i].Profile) {
+			case common.H265:
+				o.VideoEncoder = ffmpeg.ComponentOptions{
+					Name: "libx265",
+					Opts: map[string]string{"forced-idr": "1", "profile": "main"},
+				}
+			case common.AV1:
+				// the fastest preset, without lookahead, to keep up with live streams
+				o.VideoEncoder = ffmpeg.ComponentOptions{
+					Name: "libaom-av1",
+					Opts: map[string]string{"cpu-used": "8", "row-mt": "1", "lag-in-frames": "0"},
+				}
+			case common.VP8:
+				o.VideoEncoder = ffmpeg.ComponentOptions{
+					Name: "libvpx",
+					Opts: map[string]string{"deadline": "realtime", "cpu-used": "8"},
+				}
+			case common.VP9:
+				o.VideoEncoder = ffmpeg.ComponentOptions{
+					Name: "libvpx-vp9",
+					Opts: map[string]string{"deadline": "realtime", "cpu-used": "8", "row-mt": "1"},
+				}
+			}
+		}
+		if common.ProfileCodec(profiles[i].Profile) == common.NoVideo {
+			// Drop the video on any acceleration; the audio is copied as usual.
+			// Without video, there are no keyframes to place.
+			o.VideoEncoder = ffmpeg.ComponentOptions{Name: "drop"}
+			o.Profile.GOP = 0
+		}
+		// LPMS only muxes into the formats that it enumerates, or else into the
+		// named muxer. It resamples audio to 44.1kHz, which Opus doesn't take, so
+		// WebM renditions carry Vorbis.
+		if profiles[i].Format == common.FormatWebM {
+			o.Profile.Format = ffmpeg.FormatNone
+			o.Muxer = ffmpeg.ComponentOptions{Name: "webm"}
+			o.AudioEncoder = ffmpeg.ComponentOptions{Name: "libvorbis"}
+		}
 		opts[i] = o
 	}
 	return opts
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/livepeer/go-livepeer/common"
 	"github.com/livepeer/lpms/ffmpeg"
@@ -199,6 +200,58 @@ func TestProfilesToTranscodeOptions(t *testing.T) {
 		assert.Equal(p, opts[i].Profile)
 		assert.Equal("copy", opts[i].AudioEncoder.Name)
 	}
+
+	// Test H265 profile names the encoder in software only
+	hevc := ffmpeg.P144p30fps16x9
+	hevc.Profile = common.ProfileH265Main
+	profiles = []ffmpeg.VideoProfile{hevc, ffmpeg.P240p30fps16x9}
+	opts = profilesToTranscodeOptions(workDir, ffmpeg.Software, profiles)
+	assert.Equal("libx265", opts[0].VideoEncoder.Name)
+	assert.Equal("main", opts[0].VideoEncoder.Opts["profile"])
+	assert.Empty(opts[1].VideoEncoder.Name)
+
+	opts = profilesToTranscodeOptions(workDir, ffmpeg.Nvidia, profiles)
+	assert.Empty(opts[0].VideoEncoder.Name)
+
+	// Test AV1 profile names the encoder in software only
+	av1 := ffmpeg.P144p30fps16x9
+	av1.Profile = common.ProfileAV1Main
+	av1.Format = ffmpeg.FormatMP4
+	profiles = []ffmpeg.VideoProfile{av1}
+	opts = profilesToTranscodeOptions(workDir, ffmpeg.Software, profiles)
+	assert.Equal("libaom-av1", opts[0].VideoEncoder.Name)
+	assert.Equal("0", opts[0].VideoEncoder.Opts["lag-in-frames"])
+
+	opts = profilesToTranscodeOptions(workDir, ffmpeg.Nvidia, profiles)
+	assert.Empty(opts[0].VideoEncoder.Name)
+
+	// Test VP8 and VP9 profiles are muxed into WebM with Vorbis audio
+	vp8 := ffmpeg.P144p30fps16x9
+	vp8.Profile = common.ProfileVP8
+	vp8.Format = common.FormatWebM
+	vp9 := vp8
+	vp9.Profile = common.ProfileVP9
+	profiles = []ffmpeg.VideoProfile{vp8, vp9, ffmpeg.P240p30fps16x9}
+	opts = profilesToTranscodeOptions(workDir, ffmpeg.Software, profiles)
+	assert.Equal("libvpx", opts[0].VideoEncoder.Name)
+	assert.Equal("libvpx-vp9", opts[1].VideoEncoder.Name)
+	for i := 0; i < 2; i++ {
+		assert.Equal(ffmpeg.FormatNone, opts[i].Profile.Format)
+		assert.Equal("webm", opts[i].Muxer.Name)
+		assert.Equal("libvorbis", opts[i].AudioEncoder.Name)
+	}
+	assert.Equal(ffmpeg.P240p30fps16x9, opts[2].Profile)
+	assert.Empty(opts[2].Muxer.Name)
+	assert.Equal("copy", opts[2].AudioEncoder.Name)
+
+	// Test audio-only profile drops the video on any acceleration
+	audio := ffmpeg.VideoProfile{Resolution: "0x0", Bitrate: "128k", Profile: common.ProfileAudioOnly, GOP: time.Second}
+	for _, accel := range []ffmpeg.Acceleration{ffmpeg.Software, ffmpeg.Nvidia} {
+		opts = profilesToTranscodeOptions(workDir, accel, []ffmpeg.VideoProfile{audio})
+		assert.Equal("drop", opts[0].VideoEncoder.Name)
+		assert.Equal("copy", opts[0].AudioEncoder.Name)
+		assert.Zero(opts[0].Profile.GOP)
+	}
 }
 
 func TestAudioCopy(t *testing.T) {
@@ -263,3 +316,34 @@ func TestTranscoder_Formats(t *testing.T) {
 	// sanity check the base format wasn't overwritten (has happened before!)
 	assert.Equal(ffmpeg.FormatNone, ffmpeg.P144p30fps16x9.Format)
 }
+
+func TestSameProfiles(t *testing.T) {
+	assert := assert.New(t)
+	profiles := []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9, ffmpeg.P240p30fps16x9}
+
+	assert.True(sameProfiles(profiles, append([]ffmpeg.VideoProfile(nil), profiles...)))
+	assert.False(sameProfiles(profiles, profiles[:1]))
+	assert.False(sameProfiles(profiles, []ffmpeg.VideoProfile{ffmpeg.P240p30fps16x9, ffmpeg.P144p30fps16x9}))
+
+	changed := append([]ffmpeg.VideoProfile(nil), profiles...)
+	changed[1].Resolution = "1x1"
+	assert.False(sameProfiles(profiles, changed))
+
+	// adaptive bitrates don't restart the session
+	changed = append([]ffmpeg.VideoProfile(nil), profiles...)
+	changed[1].Bitrate = "1k"
+	assert.True(sameProfiles(profiles, changed))
+}
+
+func TestTestSegment(t *testing.T) {
+	assert := assert.New(t)
+
+	seg, err := TestSegment()
+	assert.Nil(err)
+	// MPEG-TS packets are 188 bytes and start with a sync byte
+	assert.NotEmpty(seg)
+	assert.Zero(len(seg) % 188)
+	for i := 0; i < len(seg); i += 188 {
+		assert.Equal(byte(0x47), seg[i])
+	}
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/livepeer/go-livepeer/common"
+)
+
+// videoSignatureTimeout bounds the time it takes to compute the signature of a segment
+var videoSignatureTimeout = 30 * time.Second
+
+// VideoSignaturesAvailable checks if the ffmpeg binary has the signature filter, so
+// that the node can check the video signatures of renditions
+func VideoSignaturesAvailable() bool {
+	out, err := exec.Command(FFmpegCommand, "-hide_banner", "-h", "filter=signature").CombinedOutput()
+	return err == nil && bytes.Contains(out, []byte("Filter signature"))
+}
+
+// VideoSignature computes the MPEG-7 video signature of a segment, in the binary format
+// of the signature filter of ffmpeg. Video signatures are robust to scaling and
+// re-encoding, so the renditions of a segment have signatures close to its own.
+func VideoSignature(workDir string, data []byte) ([]byte, error) {
+	name := filepath.Join(workDir, common.RandName())
+	in, out := name+".seg", name+".sig"
+	defer os.Remove(in)
+	defer os.Remove(out)
+	if err := ioutil.WriteFile(in, data, 0644); err != nil {
+		return nil, err
+	}
+	if err := runFFmpeg(videoSignatureTimeout, videoSignatureArgs(in, out)); err != nil {
+		return nil, fmt.Errorf("video signature: %v", err)
+	}
+	return ioutil.ReadFile(out)
+}
+
+// videoSignatureArgs are the arguments of ffmpeg to write the video signature of a
+// segment without encoding it
+func videoSignatureArgs(in, out string) []string {
+	return []string{"-hide_banner", "-loglevel", "error", "-y", "-i", in, "-an",
+		"-vf", "signature=format=binary:filename=" + out, "-f", "null", "-"}
+}
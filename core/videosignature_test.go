@@ -0,0 +1,59 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubSignature stands in for ffmpeg: it writes the input as the signature of a segment
+const stubSignature = `#!/bin/sh
+while [ $# -gt 1 ]; do
+	case "$1" in
+	-i) in="$2" ;;
+	-vf) out="${2#*filename=}" ;;
+	esac
+	shift
+done
+printf sig: > "$out"
+cat "$in" >> "$out"
+`
+
+func TestVideoSignature(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	tmpdir, err := ioutil.TempDir("", "")
+	require.Nil(err)
+	defer os.RemoveAll(tmpdir)
+
+	defer func(cmd string) { FFmpegCommand = cmd }(FFmpegCommand)
+	FFmpegCommand = filepath.Join(tmpdir, "ffmpeg")
+	require.Nil(ioutil.WriteFile(FFmpegCommand, []byte(stubSignature), 0755))
+	workDir := filepath.Join(tmpdir, "work")
+	require.Nil(os.Mkdir(workDir, 0755))
+
+	sig, err := VideoSignature(workDir, []byte("seg"))
+	assert.Nil(err)
+	assert.Equal("sig:seg", string(sig))
+
+	// the files of the work dir are removed
+	files, err := ioutil.ReadDir(workDir)
+	require.Nil(err)
+	assert.Empty(files)
+
+	assert.False(VideoSignaturesAvailable())
+	FFmpegCommand = "false"
+	_, err = VideoSignature(workDir, []byte("seg"))
+	assert.EqualError(err, "video signature: exit status 1")
+	assert.False(VideoSignaturesAvailable())
+}
+
+func TestVideoSignatureArgs(t *testing.T) {
+	args := videoSignatureArgs("in.ts", "out.sig")
+	assert.Equal(t, []string{"-hide_banner", "-loglevel", "error", "-y", "-i", "in.ts", "-an",
+		"-vf", "signature=format=binary:filename=out.sig", "-f", "null", "-"}, args)
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/livepeer/lpms/stream"
+	"github.com/livepeer/m3u8"
+
+	"github.com/livepeer/go-livepeer/common"
+)
+
+// vodTimeout bounds the time it takes to segment or mux a VOD file
+var vodTimeout = 10 * time.Minute
+
+// SegmentVOD splits a VOD file into MPEG-TS segments of about segLen, and returns the
+// segments in order. The segments are cut at the keyframes of the file without
+// re-encoding it, so they are as long as the GOPs of the file when those are longer.
+func SegmentVOD(workDir string, data []byte, segLen time.Duration) ([]*stream.HLSSegment, error) {
+	dir, err := ioutil.TempDir(workDir, "vod")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	in := filepath.Join(dir, "source")
+	if err := ioutil.WriteFile(in, data, 0644); err != nil {
+		return nil, err
+	}
+	playlist := filepath.Join(dir, "index.m3u8")
+	if err := runFFmpeg(vodTimeout, segmentArgs(in, dir, playlist, segLen)); err != nil {
+		return nil, fmt.Errorf("segment: %v", err)
+	}
+
+	f, err := os.Open(playlist)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	pl, listType, err := m3u8.DecodeFrom(f, true)
+	if err != nil {
+		return nil, err
+	}
+	mpl, ok := pl.(*m3u8.MediaPlaylist)
+	if listType != m3u8.MEDIA || !ok {
+		return nil, fmt.Errorf("segment: unexpected playlist")
+	}
+	var segs []*stream.HLSSegment
+	for _, seg := range mpl.Segments {
+		if seg == nil {
+			break
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.Base(seg.URI)))
+		if err != nil {
+			return nil, err
+		}
+		segs = append(segs, &stream.HLSSegment{
+			Data:     data,
+			Name:     seg.URI,
+			SeqNo:    uint64(len(segs)),
+			Duration: seg.Duration,
+		})
+	}
+	if len(segs) == 0 {
+		return nil, fmt.Errorf("segment: no segments")
+	}
+	return segs, nil
+}
+
+// segmentArgs are the arguments of ffmpeg to split a file into the numbered MPEG-TS
+// segments of an HLS playlist of the given dir
+func segmentArgs(in, dir, playlist string, segLen time.Duration) []string {
+	return []string{"-hide_banner", "-loglevel", "error", "-y", "-i", in,
+		"-c", "copy", "-f", "hls",
+		"-hls_time", fmt.Sprintf("%.3f", segLen.Seconds()),
+		"-hls_list_size", "0", "-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(dir, "%d.ts"), playlist}
+}
+
+// MuxMP4 joins the MPEG-TS segments of a rendition into an MP4 file, without
+// re-encoding them
+func MuxMP4(workDir string, segs [][]byte) ([]byte, error) {
+	name := filepath.Join(workDir, common.RandName())
+	in, out := name+".ts", name+".mp4"
+	defer os.Remove(in)
+	defer os.Remove(out)
+	if err := ioutil.WriteFile(in, bytes.Join(segs, nil), 0644); err != nil {
+		return nil, err
+	}
+	if err := runFFmpeg(vodTimeout, mp4Args(in, out)); err != nil {
+		return nil, fmt.Errorf("mux: %v", err)
+	}
+	return ioutil.ReadFile(out)
+}
+
+// mp4Args are the arguments of ffmpeg to remux MPEG-TS into MP4. The moov atom is
+// moved ahead of the media so that the file plays while it downloads.
+func mp4Args(in, out string) []string {
+	return []string{"-hide_banner", "-loglevel", "error", "-y", "-i", in,
+		"-c", "copy", "-bsf:a", "aac_adtstoasc", "-movflags", "+faststart", "-f", "mp4", out}
+}
+
+func runFFmpeg(timeout time.Duration, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, FFmpegCommand, args...).CombinedOutput()
+	if output = bytes.TrimSpace(output); err != nil && len(output) > 0 {
+		return fmt.Errorf("%v: %s", err, output)
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubFFmpeg is a script that stands in for ffmpeg: it writes two segments and their
+// playlist when it segments a file, and copies its input otherwise
+const stubFFmpeg = `#!/bin/sh
+while [ $# -gt 1 ]; do
+	case "$1" in
+	-i) in="$2" ;;
+	-hls_segment_filename) segs="$2" ;;
+	esac
+	shift
+done
+if [ -n "$segs" ]; then
+	dir=$(dirname "$1")
+	printf seg0 > "$dir/0.ts"
+	printf seg1 > "$dir/1.ts"
+	printf '#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:2\n#EXT-X-MEDIA-SEQUENCE:0\n#EXTINF:2.000,\n0.ts\n#EXTINF:1.500,\n1.ts\n#EXT-X-ENDLIST\n' > "$1"
+else
+	cat "$in" > "$1"
+fi
+`
+
+func TestSegmentVOD(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	tmpdir, err := ioutil.TempDir("", "")
+	require.Nil(err)
+	defer os.RemoveAll(tmpdir)
+
+	defer func(cmd string) { FFmpegCommand = cmd }(FFmpegCommand)
+	FFmpegCommand = filepath.Join(tmpdir, "ffmpeg")
+	require.Nil(ioutil.WriteFile(FFmpegCommand, []byte(stubFFmpeg), 0755))
+	workDir := filepath.Join(tmpdir, "work")
+	require.Nil(os.Mkdir(workDir, 0755))
+
+	segs, err := SegmentVOD(workDir, []byte("file"), 2*time.Second)
+	require.Nil(err)
+	require.Len(segs, 2)
+	assert.Equal("seg0", string(segs[0].Data))
+	assert.Equal(uint64(1), segs[1].SeqNo)
+	assert.Equal(1.5, segs[1].Duration)
+
+	data, err := MuxMP4(workDir, [][]byte{segs[0].Data, segs[1].Data})
+	assert.Nil(err)
+	assert.Equal("seg0seg1", string(data))
+
+	// the files of the work dir are removed
+	files, err := ioutil.ReadDir(workDir)
+	require.Nil(err)
+	assert.Empty(files)
+
+	FFmpegCommand = "false"
+	_, err = SegmentVOD(workDir, []byte("file"), 2*time.Second)
+	assert.EqualError(err, "segment: exit status 1")
+	_, err = MuxMP4(workDir, nil)
+	assert.EqualError(err, "mux: exit status 1")
+}
+
+func TestSegmentArgs(t *testing.T) {
+	assert := assert.New(t)
+
+	args := segmentArgs("in.mp4", "dir", "dir/index.m3u8", 2*time.Second)
+	assert.Equal([]string{"-hide_banner", "-loglevel", "error", "-y", "-i", "in.mp4", "-c", "copy", "-f", "hls",
+		"-hls_time", "2.000", "-hls_list_size", "0", "-hls_playlist_type", "vod",
+		"-hls_segment_filename", "dir/%d.ts", "dir/index.m3u8"}, args)
+
+	args = mp4Args("in.ts", "out.mp4")
+	assert.Equal([]string{"-hide_banner", "-loglevel", "error", "-y", "-i", "in.ts",
+		"-c", "copy", "-bsf:a", "aac_adtstoasc", "-movflags", "+faststart", "-f", "mp4", "out.mp4"}, args)
+}
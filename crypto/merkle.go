@@ -0,0 +1,72 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var ErrMerkleIndex = errors.New("leaf index out of range")
+
+// MerkleRoot returns the root of the Merkle tree built over leaves, in order.
+// Pairs of nodes are sorted before they are hashed with Keccak256 so that proofs
+// can be verified without the position of the leaf, which is how Merkle proofs
+// are commonly verified on-chain. A node without a sibling is promoted to the next
+// level as is. Returns nil if there are no leaves
+func MerkleRoot(leaves [][]byte) []byte {
+	if len(leaves) == 0 {
+		return nil
+	}
+	level := leaves
+	for len(level) > 1 {
+		level = merkleLevel(level)
+	}
+	return level[0]
+}
+
+// MerkleProof returns the sibling hashes from the leaf at index up to the root
+func MerkleProof(leaves [][]byte, index int) ([][]byte, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, ErrMerkleIndex
+	}
+	var proof [][]byte
+	level := leaves
+	for len(level) > 1 {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			proof = append(proof, level[sibling])
+		}
+		level = merkleLevel(level)
+		index /= 2
+	}
+	return proof, nil
+}
+
+// VerifyMerkleProof checks that leaf is part of the Merkle tree with the given root
+func VerifyMerkleProof(root, leaf []byte, proof [][]byte) bool {
+	node := leaf
+	for _, sibling := range proof {
+		node = hashPair(node, sibling)
+	}
+	return len(root) > 0 && bytes.Equal(node, root)
+}
+
+func merkleLevel(nodes [][]byte) [][]byte {
+	next := make([][]byte, 0, (len(nodes)+1)/2)
+	for i := 0; i < len(nodes); i += 2 {
+		if i+1 == len(nodes) {
+			next = append(next, nodes[i])
+			break
+		}
+		next = append(next, hashPair(nodes[i], nodes[i+1]))
+	}
+	return next
+}
+
+func hashPair(a, b []byte) []byte {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	return crypto.Keccak256(a, b)
+}
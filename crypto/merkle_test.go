@@ -0,0 +1,52 @@
+package crypto
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerkleRoot(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(MerkleRoot(nil))
+
+	a, b, c := crypto.Keccak256([]byte("a")), crypto.Keccak256([]byte("b")), crypto.Keccak256([]byte("c"))
+	assert.Equal(a, MerkleRoot([][]byte{a}))
+	assert.Equal(hashPair(a, b), MerkleRoot([][]byte{a, b}))
+	// Pairs are sorted before hashing
+	assert.Equal(MerkleRoot([][]byte{a, b}), MerkleRoot([][]byte{b, a}))
+	// The odd node is promoted
+	assert.Equal(hashPair(hashPair(a, b), c), MerkleRoot([][]byte{a, b, c}))
+	// Duplicating the last leaf changes the root
+	assert.NotEqual(MerkleRoot([][]byte{a, b, c}), MerkleRoot([][]byte{a, b, c, c}))
+}
+
+func TestMerkleProof(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	for n := 1; n <= 9; n++ {
+		var leaves [][]byte
+		for i := 0; i < n; i++ {
+			leaves = append(leaves, crypto.Keccak256([]byte(fmt.Sprint(i))))
+		}
+		root := MerkleRoot(leaves)
+		for i, leaf := range leaves {
+			proof, err := MerkleProof(leaves, i)
+			require.Nil(err)
+			assert.True(VerifyMerkleProof(root, leaf, proof), "leaves=%d index=%d", n, i)
+			assert.False(VerifyMerkleProof(root, crypto.Keccak256([]byte("x")), proof))
+		}
+	}
+
+	_, err := MerkleProof(nil, 0)
+	assert.Equal(ErrMerkleIndex, err)
+	_, err = MerkleProof([][]byte{{1}}, 1)
+	assert.Equal(ErrMerkleIndex, err)
+
+	assert.False(VerifyMerkleProof(nil, nil, nil))
+}
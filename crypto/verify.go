@@ -25,7 +25,23 @@ func VerifySig(addr ethcommon.Address, msg, sig []byte) bool {
 	return recovered == addr
 }
 
+// VerifyHashSig verifies that a ETH ECDSA signature over a given hash, which
+// is signed as is rather than as a personal message, e.g. the EIP-712 digest of
+// typed data, is produced by a given ETH address
+func VerifyHashSig(addr ethcommon.Address, hash, sig []byte) bool {
+	recovered, err := ecrecoverHash(hash, sig)
+	if err != nil {
+		return false
+	}
+
+	return recovered == addr
+}
+
 func ecrecover(msg, sig []byte) (ethcommon.Address, error) {
+	return ecrecoverHash(accounts.TextHash(msg), sig)
+}
+
+func ecrecoverHash(hash, sig []byte) (ethcommon.Address, error) {
 	if len(sig) != 65 {
 		return ethcommon.Address{}, errors.New("invalid signature length")
 	}
@@ -46,8 +62,7 @@ func ecrecover(msg, sig []byte) (ethcommon.Address, error) {
 	copy(ethSig[:], sig[:])
 	ethSig[64] -= 27
 
-	ethMsg := accounts.TextHash(msg)
-	pubkey, err := crypto.SigToPub(ethMsg, ethSig)
+	pubkey, err := crypto.SigToPub(hash, ethSig)
 	if err != nil {
 		return ethcommon.Address{}, err
 	}
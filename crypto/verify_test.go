@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -50,3 +51,22 @@ func TestVerifySig(t *testing.T) {
 	// Check that verification fails for a different message
 	assert.False(VerifySig(addr, ethcommon.FromHex("foo"), sig))
 }
+
+func TestVerifyHashSig(t *testing.T) {
+	assert := assert.New(t)
+
+	key, err := crypto.GenerateKey()
+	assert.Nil(err)
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	hash := crypto.Keccak256([]byte("foo"))
+	sig, err := crypto.Sign(hash, key)
+	assert.Nil(err)
+	sig[64] += 27
+
+	// Check that verification passes for the hash signed as is
+	assert.True(VerifyHashSig(addr, hash, sig))
+	// Check that verification fails for a different hash
+	assert.False(VerifyHashSig(addr, crypto.Keccak256([]byte("bar")), sig))
+	// Check that the signature is not valid for the hash as a personal message
+	assert.False(VerifySig(addr, hash, sig))
+}
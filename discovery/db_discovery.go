@@ -98,7 +98,7 @@ func (dbo *DBOrchestratorPoolCache) GetOrchestrators(numOrchestrators int, suspe
 
 	pred := func(info *net.OrchestratorInfo) bool {
 
-		if err := dbo.ticketParamsValidator.ValidateTicketParams(pmTicketParams(info.TicketParams)); err != nil {
+		if err := dbo.ticketParamsValidator.ValidateTicketParams(pmTicketParams(info.TicketParams, info.PriceInfo)); err != nil {
 			glog.V(common.DEBUG).Infof("invalid ticket params - orch=%v err=%v",
 				info.GetTranscoder(),
 				err,
@@ -316,11 +316,16 @@ func ethOrchToDBOrch(orch *lpTypes.Transcoder) *common.DBOrch {
 	return dbo
 }
 
-func pmTicketParams(params *net.TicketParams) *pm.TicketParams {
+// pmTicketParams converts the ticket params of an orchestrator, which are created for the price
+// per pixel it advertises
+func pmTicketParams(params *net.TicketParams, priceInfo *net.PriceInfo) *pm.TicketParams {
 	if params == nil {
 		return nil
 	}
 
+	// An invalid price is left unset, it is refused by the checks of the orchestrator price
+	price, _ := common.RatPriceInfo(priceInfo)
+
 	return &pm.TicketParams{
 		Recipient:         ethcommon.BytesToAddress(params.Recipient),
 		FaceValue:         new(big.Int).SetBytes(params.FaceValue),
@@ -328,6 +333,7 @@ func pmTicketParams(params *net.TicketParams) *pm.TicketParams {
 		RecipientRandHash: ethcommon.BytesToHash(params.RecipientRandHash),
 		Seed:              new(big.Int).SetBytes(params.Seed),
 		ExpirationBlock:   new(big.Int).SetBytes(params.ExpirationBlock),
+		PricePerPixel:     price,
 		ExpirationParams: &pm.TicketExpirationParams{
 			CreationRound:          params.ExpirationParams.GetCreationRound(),
 			CreationRoundBlockHash: ethcommon.BytesToHash(params.ExpirationParams.GetCreationRoundBlockHash()),
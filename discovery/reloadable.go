@@ -0,0 +1,62 @@
+package discovery
+
+import (
+	"errors"
+	"net/url"
+	"sync"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/net"
+)
+
+var errNoPool = errors.New("no orchestrators configured")
+
+// ReloadablePool is an orchestrator pool backed by a pool that can be replaced while the
+// node is running, e.g. when the list of orchestrators is reloaded
+type ReloadablePool struct {
+	mu   sync.RWMutex
+	pool common.OrchestratorPool
+}
+
+// NewReloadablePool creates a ReloadablePool backed by pool, which may be nil
+func NewReloadablePool(pool common.OrchestratorPool) *ReloadablePool {
+	return &ReloadablePool{pool: pool}
+}
+
+// Pool returns the current backing pool
+func (p *ReloadablePool) Pool() common.OrchestratorPool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.pool
+}
+
+// SetPool replaces the backing pool. Requests in progress complete with the previous pool
+func (p *ReloadablePool) SetPool(pool common.OrchestratorPool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pool = pool
+}
+
+func (p *ReloadablePool) GetURLs() []*url.URL {
+	pool := p.Pool()
+	if pool == nil {
+		return nil
+	}
+	return pool.GetURLs()
+}
+
+func (p *ReloadablePool) GetOrchestrators(numOrchestrators int, suspender common.Suspender, caps common.CapabilityComparator) ([]*net.OrchestratorInfo, error) {
+	pool := p.Pool()
+	if pool == nil {
+		return nil, errNoPool
+	}
+	return pool.GetOrchestrators(numOrchestrators, suspender, caps)
+}
+
+func (p *ReloadablePool) Size() int {
+	pool := p.Pool()
+	if pool == nil {
+		return 0
+	}
+	return pool.Size()
+}
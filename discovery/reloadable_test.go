@@ -0,0 +1,54 @@
+package discovery
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/net"
+)
+
+type fixedPool struct {
+	uris []*url.URL
+}
+
+func (p *fixedPool) GetURLs() []*url.URL { return p.uris }
+func (p *fixedPool) GetOrchestrators(n int, sus common.Suspender, caps common.CapabilityComparator) ([]*net.OrchestratorInfo, error) {
+	var infos []*net.OrchestratorInfo
+	for _, uri := range p.uris[:n] {
+		infos = append(infos, &net.OrchestratorInfo{Transcoder: uri.String()})
+	}
+	return infos, nil
+}
+func (p *fixedPool) Size() int { return len(p.uris) }
+
+func TestReloadablePool(t *testing.T) {
+	assert := assert.New(t)
+
+	// Without a backing pool
+	pool := NewReloadablePool(nil)
+	assert.Nil(pool.GetURLs())
+	assert.Equal(0, pool.Size())
+	infos, err := pool.GetOrchestrators(1, newStubSuspender(), newStubCapabilities())
+	assert.Nil(infos)
+	assert.Equal(errNoPool, err)
+
+	uris := stringsToURIs([]string{"https://127.0.0.1:8935", "https://127.0.0.1:8936"})
+	pool.SetPool(&fixedPool{uris: uris})
+	assert.Equal(uris, pool.GetURLs())
+	assert.Equal(2, pool.Size())
+	infos, err = pool.GetOrchestrators(1, newStubSuspender(), newStubCapabilities())
+	assert.Nil(err)
+	assert.Len(infos, 1)
+	assert.Equal("https://127.0.0.1:8935", infos[0].Transcoder)
+
+	// The backing pool is replaced
+	pool.SetPool(NewOrchestratorPool(nil, uris[1:]))
+	assert.Equal(uris[1:], pool.GetURLs())
+	assert.Equal(1, pool.Size())
+
+	pool.SetPool(nil)
+	assert.Equal(0, pool.Size())
+}
@@ -24,6 +24,8 @@ type MemorySession struct {
 	ended  bool
 	dCache map[string]*dataCache
 	dLock  sync.RWMutex
+	// number of files kept for each path of the session
+	cacheLen int
 }
 
 func NewMemoryDriver(baseURI *url.URL) *MemoryOS {
@@ -35,16 +37,31 @@ func NewMemoryDriver(baseURI *url.URL) *MemoryOS {
 }
 
 func (ostore *MemoryOS) NewSession(path string) OSSession {
+	return ostore.newSession(path, dataCacheLen)
+}
+
+// NewVODSession creates a session that keeps the given number of files for each
+// path of the session, e.g. all the segments of the renditions of a VOD file,
+// rather than only the last ones of live streams
+func (ostore *MemoryOS) NewVODSession(path string, segments int) OSSession {
+	if segments < dataCacheLen {
+		segments = dataCacheLen
+	}
+	return ostore.newSession(path, segments)
+}
+
+func (ostore *MemoryOS) newSession(path string, cacheLen int) OSSession {
 	ostore.lock.Lock()
 	defer ostore.lock.Unlock()
 	if session, ok := ostore.sessions[path]; ok {
 		return session
 	}
 	session := &MemorySession{
-		os:     ostore,
-		path:   path,
-		dCache: make(map[string]*dataCache),
-		dLock:  sync.RWMutex{},
+		os:       ostore,
+		path:     path,
+		dCache:   make(map[string]*dataCache),
+		dLock:    sync.RWMutex{},
+		cacheLen: cacheLen,
 	}
 	ostore.sessions[path] = session
 	return session
@@ -128,7 +145,7 @@ func (ostore *MemorySession) SaveData(name string, data []byte) (string, error)
 func (ostore *MemorySession) getCacheForStream(streamID string) *dataCache {
 	sc, ok := ostore.dCache[streamID]
 	if !ok {
-		sc = newDataCache(dataCacheLen)
+		sc = newDataCache(ostore.cacheLen)
 		ostore.dCache[streamID] = sc
 	}
 	return sc
@@ -61,4 +61,14 @@ func TestLocalOS(t *testing.T) {
 
 	data = sess.GetData(path)
 	assert.Equal(tempData1, string(data))
+
+	// Test VOD sessions keep all the segments of the VOD file
+	sess = os.NewVODSession("vodpath", 3).(*MemorySession)
+	for i := 0; i < 3; i++ {
+		_, err = sess.SaveData(fmt.Sprintf("name1/%d.ts", i), copyBytes(tempData1))
+		assert.Nil(err)
+	}
+	for i := 0; i < 3; i++ {
+		assert.Equal(tempData1, string(sess.GetData(fmt.Sprintf("vodpath/name1/%d.ts", i))))
+	}
 }
@@ -6,12 +6,14 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
@@ -32,9 +34,11 @@ const S3_POLICY_EXPIRE_IN_HOURS = 24
    is created. This policy is valid for S3_POLICY_EXPIRE_IN_HOURS hours.
 */
 type s3OS struct {
-	host               string
-	region             string
-	bucket             string
+	host   string
+	region string
+	bucket string
+	// Guards the credentials, which can be rotated
+	mu                 sync.RWMutex
 	awsAccessKeyID     string
 	awsSecretAccessKey string
 	s3svc              *s3.S3
@@ -79,23 +83,42 @@ func newS3Session(info *net.S3OSInfo) OSSession {
 
 func NewS3Driver(region, bucket, accessKey, accessKeySecret string) OSDriver {
 	os := &s3OS{
-		host:               s3Host(bucket),
-		region:             region,
-		bucket:             bucket,
-		awsAccessKeyID:     accessKey,
-		awsSecretAccessKey: accessKeySecret,
+		host:   s3Host(bucket),
+		region: region,
+		bucket: bucket,
 	}
+	os.setCredentials(accessKey, accessKeySecret)
+	return os
+}
+
+func (os *s3OS) setCredentials(accessKey, accessKeySecret string) {
+	os.awsAccessKeyID = accessKey
+	os.awsSecretAccessKey = accessKeySecret
 	if os.awsAccessKeyID != "" {
 		creds := credentials.NewStaticCredentials(os.awsAccessKeyID, os.awsSecretAccessKey, "")
 		cfg := aws.NewConfig().WithRegion(os.region).WithCredentials(creds)
 		os.s3svc = s3.New(session.New(), cfg)
 	}
-	return os
+}
+
+// UpdateS3Credentials changes the credentials of an S3 driver, e.g. once they were rotated.
+// The sessions created afterwards use the new credentials
+func UpdateS3Credentials(driver OSDriver, accessKey, accessKeySecret string) error {
+	os, ok := driver.(*s3OS)
+	if !ok {
+		return errors.New("not an S3 driver")
+	}
+	os.mu.Lock()
+	defer os.mu.Unlock()
+	os.setCredentials(accessKey, accessKeySecret)
+	return nil
 }
 
 func (os *s3OS) NewSession(path string) OSSession {
+	os.mu.RLock()
 	policy, signature, credential, xAmzDate := createPolicy(os.awsAccessKeyID,
 		os.bucket, os.region, os.awsSecretAccessKey, path)
+	os.mu.RUnlock()
 	sess := &s3Session{
 		host:        s3Host(os.bucket),
 		key:         path,
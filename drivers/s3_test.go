@@ -0,0 +1,22 @@
+package drivers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateS3Credentials(t *testing.T) {
+	assert := assert.New(t)
+
+	os := NewS3Driver("us-east-1", "bucket", "KEY", "SECRET")
+	sess := os.NewSession("stream").(*s3Session)
+	assert.True(strings.HasPrefix(sess.credential, "KEY/"))
+
+	assert.Nil(UpdateS3Credentials(os, "ROTATED", "SECRET2"))
+	sess = os.NewSession("stream").(*s3Session)
+	assert.True(strings.HasPrefix(sess.credential, "ROTATED/"))
+
+	assert.EqualError(UpdateS3Credentials(NewMemoryDriver(nil), "KEY", "SECRET"), "not an S3 driver")
+}
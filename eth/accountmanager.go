@@ -1,9 +1,12 @@
 package eth
 
 import (
+	"bytes"
+	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"math/big"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -11,8 +14,10 @@ import (
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/console"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/golang/glog"
 	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/pm"
 )
 
 var (
@@ -27,6 +32,7 @@ type AccountManager interface {
 	CreateTransactOpts(gasLimit uint64, gasPrice *big.Int) (*bind.TransactOpts, error)
 	SignTx(tx *types.Transaction) (*types.Transaction, error)
 	Sign(msg []byte) ([]byte, error)
+	SignTypedData(typedData *pm.TypedData) ([]byte, error)
 	Account() accounts.Account
 }
 
@@ -151,8 +157,16 @@ func (am *accountManager) SignTx(tx *types.Transaction) (*types.Transaction, err
 
 // Sign byte array message. Account must be unlocked
 func (am *accountManager) Sign(msg []byte) ([]byte, error) {
-	ethHash := accounts.TextHash(msg)
-	sig, err := am.keyStore.SignHash(am.account, ethHash)
+	return am.signHash(accounts.TextHash(msg))
+}
+
+// Sign the EIP-712 digest of typed data. Account must be unlocked
+func (am *accountManager) SignTypedData(typedData *pm.TypedData) ([]byte, error) {
+	return am.signHash(typedData.Hash().Bytes())
+}
+
+func (am *accountManager) signHash(hash []byte) ([]byte, error) {
+	sig, err := am.keyStore.SignHash(am.account, hash)
 	if err != nil {
 		return nil, err
 	}
@@ -192,6 +206,49 @@ func getAccount(accountAddr ethcommon.Address, keyStore *keystore.KeyStore) (acc
 	}
 }
 
+// CreateAccount creates an account encrypted with passphrase in the keystore at
+// keystoreDir, unless the keystore already has an account. It returns the account the node
+// uses by default and whether it was created
+func CreateAccount(keystoreDir, passphrase string) (accounts.Account, bool, error) {
+	keyStore := keystore.NewKeyStore(keystoreDir, keystore.StandardScryptN, keystore.StandardScryptP)
+	if len(keyStore.Accounts()) > 0 {
+		acct, err := getAccount(ethcommon.Address{}, keyStore)
+		return acct, false, err
+	}
+	acct, err := keyStore.NewAccount(passphrase)
+	return acct, err == nil, err
+}
+
+// ImportAccount imports a key into the keystore at keystoreDir, encrypted with passphrase.
+// The key is either a keystore file encrypted with passphrase or a hex encoded private key.
+// It returns the account of the key and whether it was imported, since a key that is
+// already in the keystore is not imported again
+func ImportAccount(keystoreDir string, key []byte, passphrase string) (accounts.Account, bool, error) {
+	var privateKey *ecdsa.PrivateKey
+	if k := bytes.TrimSpace(key); len(k) > 0 && k[0] == '{' {
+		decrypted, err := keystore.DecryptKey(k, passphrase)
+		if err != nil {
+			return accounts.Account{}, false, err
+		}
+		privateKey = decrypted.PrivateKey
+	} else {
+		var err error
+		privateKey, err = crypto.HexToECDSA(strings.TrimPrefix(string(k), "0x"))
+		if err != nil {
+			return accounts.Account{}, false, fmt.Errorf("invalid private key: %v", err)
+		}
+	}
+
+	keyStore := keystore.NewKeyStore(keystoreDir, keystore.StandardScryptN, keystore.StandardScryptP)
+	addr := crypto.PubkeyToAddress(privateKey.PublicKey)
+	if keyStore.HasAddress(addr) {
+		acct, err := keyStore.Find(accounts.Account{Address: addr})
+		return acct, false, err
+	}
+	acct, err := keyStore.ImportECDSA(privateKey, passphrase)
+	return acct, err == nil, err
+}
+
 // Create account in keystore
 func createAccount(keyStore *keystore.KeyStore) (accounts.Account, error) {
 	passphrase, err := getPassphrase(true)
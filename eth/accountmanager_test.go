@@ -1,13 +1,17 @@
 package eth
 
 import (
+	"encoding/hex"
 	"io/ioutil"
+	"math/big"
 	"os"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/livepeer/go-livepeer/crypto"
+	"github.com/livepeer/go-livepeer/pm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -96,6 +100,38 @@ func TestSign(t *testing.T) {
 	assert.True(crypto.VerifySig(a.Address, []byte("foo"), sig))
 }
 
+func TestSignTypedData(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	a, err := ks.NewAccount("")
+	require.Nil(err)
+
+	am, err := NewAccountManager(a.Address, dir, types.EIP155Signer{})
+	require.Nil(err)
+
+	ticket := &pm.Ticket{
+		Sender:    a.Address,
+		FaceValue: big.NewInt(100),
+		WinProb:   big.NewInt(1),
+		Version:   pm.TicketVersionTypedData,
+	}
+
+	_, err = am.SignTypedData(ticket.TypedData())
+	assert.EqualError(err, "authentication needed: password or unlock")
+
+	err = am.Unlock("")
+	require.Nil(err)
+
+	sig, err := am.SignTypedData(ticket.TypedData())
+	assert.Nil(err)
+	assert.True(crypto.VerifyHashSig(a.Address, ticket.Hash().Bytes(), sig))
+	assert.False(crypto.VerifySig(a.Address, ticket.Hash().Bytes(), sig))
+}
+
 func tmpKeyStore(t *testing.T, encrypted bool) (string, *keystore.KeyStore) {
 	d, err := ioutil.TempDir("", "eth-keystore-test")
 	if err != nil {
@@ -111,3 +147,67 @@ func tmpKeyStore(t *testing.T, encrypted bool) (string, *keystore.KeyStore) {
 
 	return d, new(d)
 }
+
+func TestCreateAccount(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "eth-keystore-test")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	acct, created, err := CreateAccount(dir, "foo")
+	require.Nil(err)
+	assert.True(created)
+
+	// The existing account is used
+	again, created, err := CreateAccount(dir, "bar")
+	require.Nil(err)
+	assert.False(created)
+	assert.Equal(acct.Address, again.Address)
+
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	assert.Nil(ks.Unlock(acct, "foo"))
+}
+
+func TestImportAccount(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "eth-keystore-test")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	_, _, err = ImportAccount(dir, []byte("not a key"), "foo")
+	assert.Contains(err.Error(), "invalid private key")
+
+	// Hex encoded private key
+	key, err := ethcrypto.GenerateKey()
+	require.Nil(err)
+	hexKey := []byte("0x" + hex.EncodeToString(ethcrypto.FromECDSA(key)) + "\n")
+	acct, imported, err := ImportAccount(dir, hexKey, "foo")
+	require.Nil(err)
+	assert.True(imported)
+	assert.Equal(ethcrypto.PubkeyToAddress(key.PublicKey), acct.Address)
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	assert.Nil(ks.Unlock(acct, "foo"))
+
+	_, imported, err = ImportAccount(dir, hexKey, "foo")
+	require.Nil(err)
+	assert.False(imported)
+
+	// Keystore file
+	other, ks2 := tmpKeyStore(t, true)
+	defer os.RemoveAll(other)
+	exported, err := ks2.NewAccount("bar")
+	require.Nil(err)
+	keyJSON, err := ioutil.ReadFile(exported.URL.Path)
+	require.Nil(err)
+	_, _, err = ImportAccount(dir, keyJSON, "foo")
+	assert.Equal(keystore.ErrDecrypt, err)
+	acct, imported, err = ImportAccount(dir, keyJSON, "bar")
+	require.Nil(err)
+	assert.True(imported)
+	assert.Equal(exported.Address, acct.Address)
+	_, imported, err = ImportAccount(dir, keyJSON, "bar")
+	require.Nil(err)
+	assert.False(imported)
+}
@@ -47,9 +47,17 @@ type backend struct {
 	abiMap       map[string]*abi.ABI
 	nonceManager *NonceManager
 	signer       types.Signer
+	gpo          GasPriceOracle
+	// estimateGasMargin is the percentage added to gas estimates
+	estimateGasMargin uint64
 }
 
-func NewBackend(client *ethclient.Client, signer types.Signer) (Backend, error) {
+// NewBackend creates a Backend suggesting the gas price of gpo for the transactions sent
+// without one, or the gas price of the Ethereum node if gpo is nil. The gas limit of the
+// transactions sent without one is the gas estimate of the node plus estimateGasMargin
+// percent, for chains whose estimates can fall short, like the estimates of Arbitrum which
+// include the cost of posting transactions to L1 at its current gas price
+func NewBackend(client *ethclient.Client, signer types.Signer, gpo GasPriceOracle, estimateGasMargin uint64) (Backend, error) {
 	abiMap, err := makeABIMap()
 	if err != nil {
 		return nil, err
@@ -60,9 +68,28 @@ func NewBackend(client *ethclient.Client, signer types.Signer) (Backend, error)
 		abiMap,
 		NewNonceManager(client),
 		signer,
+		gpo,
+		estimateGasMargin,
 	}, nil
 }
 
+func (b *backend) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	gas, err := b.Client.EstimateGas(ctx, msg)
+	if err != nil {
+		return 0, err
+	}
+
+	return gas + gas*b.estimateGasMargin/100, nil
+}
+
+func (b *backend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	if b.gpo != nil {
+		return b.gpo.SuggestGasPrice(ctx)
+	}
+
+	return b.Client.SuggestGasPrice(ctx)
+}
+
 func (b *backend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
 	b.nonceManager.Lock(account)
 	defer b.nonceManager.Unlock(account)
@@ -105,13 +132,17 @@ type txLog struct {
 }
 
 func (b *backend) newTxLog(tx *types.Transaction) (txLog, error) {
+	return decodeTxLog(b.abiMap, tx.Data())
+}
+
+// decodeTxLog returns the contract method called by the data of a transaction and its inputs
+func decodeTxLog(abiMap map[string]*abi.ABI, data []byte) (txLog, error) {
 	var txParamsString string
-	data := tx.Data()
 	if len(data) < 4 {
 		return txLog{}, errors.New("no method signature")
 	}
 	methodSig := data[:4]
-	abi, ok := b.abiMap[string(methodSig)]
+	abi, ok := abiMap[string(methodSig)]
 	if !ok {
 		return txLog{}, errors.New("unknown ABI")
 	}
@@ -120,7 +151,7 @@ func (b *backend) newTxLog(tx *types.Transaction) (txLog, error) {
 		return txLog{}, err
 	}
 	txParams := make(map[string]interface{})
-	if err := decodeTxParams(b.abiMap[string(methodSig)], txParams, data); err != nil {
+	if err := decodeTxParams(abiMap[string(methodSig)], txParams, data); err != nil {
 		return txLog{}, err
 	}
 
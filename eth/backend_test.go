@@ -7,10 +7,13 @@ import (
 	"math/big"
 	"testing"
 
+	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -80,7 +83,7 @@ func TestSendTransaction_SendErr_DontUpdateNonce(t *testing.T) {
 	signedTx, err := types.SignTx(tx, signer, privateKey)
 	require.Nil(t, err)
 
-	bi, err := NewBackend(client, signer)
+	bi, err := NewBackend(client, signer, nil, 0)
 	require.Nil(t, err)
 
 	nonceLockBefore := bi.(*backend).nonceManager.getNonceLock(fromAddress)
@@ -93,3 +96,32 @@ func TestSendTransaction_SendErr_DontUpdateNonce(t *testing.T) {
 
 	assert.Equal(t, nonceLockBefore.nonce, nonceLockAfter.nonce)
 }
+
+// stubGasEstimator serves eth_estimateGas
+type stubGasEstimator struct{ gas hexutil.Uint64 }
+
+func (s *stubGasEstimator) EstimateGas(args map[string]interface{}) (hexutil.Uint64, error) {
+	return s.gas, nil
+}
+
+func TestEstimateGas_Margin(t *testing.T) {
+	server := rpc.NewServer()
+	require.Nil(t, server.RegisterName("eth", &stubGasEstimator{gas: 100000}))
+	rpcClient := rpc.DialInProc(server)
+	defer rpcClient.Close()
+	client := ethclient.NewClient(rpcClient)
+	signer := types.NewEIP155Signer(big.NewInt(1234))
+
+	b, err := NewBackend(client, signer, nil, 0)
+	require.Nil(t, err)
+	gas, err := b.EstimateGas(context.Background(), ethereum.CallMsg{})
+	require.Nil(t, err)
+	assert.Equal(t, uint64(100000), gas)
+
+	// Test margin of L2 chains
+	b, err = NewBackend(client, signer, nil, 20)
+	require.Nil(t, err)
+	gas, err = b.EstimateGas(context.Background(), ethereum.CallMsg{})
+	require.Nil(t, err)
+	assert.Equal(t, uint64(120000), gas)
+}
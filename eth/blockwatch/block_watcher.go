@@ -50,6 +50,8 @@ type Config struct {
 	WithLogs            bool
 	Topics              []common.Hash
 	Client              Client
+	// Heartbeat is called on every polling iteration, if not nil
+	Heartbeat func()
 }
 
 // Watcher maintains a consistent representation of the latest `blockRetentionLimit` blocks,
@@ -68,6 +70,7 @@ type Watcher struct {
 	ticker              *time.Ticker
 	withLogs            bool
 	topics              []common.Hash
+	heartbeat           func()
 	mu                  sync.RWMutex
 }
 
@@ -84,6 +87,7 @@ func New(config Config) *Watcher {
 		client:              config.Client,
 		withLogs:            config.WithLogs,
 		topics:              config.Topics,
+		heartbeat:           config.Heartbeat,
 	}
 	return bs
 }
@@ -123,6 +127,9 @@ func (w *Watcher) Watch(ctx context.Context) error {
 			if err := w.pollNextBlock(); err != nil {
 				glog.Errorf("blockwatch.Watcher error encountered - trying again on next polling interval err=%v", err)
 			}
+			if w.heartbeat != nil {
+				w.heartbeat()
+			}
 		}
 	}
 }
@@ -99,6 +99,36 @@ func TestWatcherStartStop(t *testing.T) {
 	}
 }
 
+func TestWatcherHeartbeat(t *testing.T) {
+	fakeClient, err := newFakeClient(basicFakeClientFixture)
+	require.NoError(t, err)
+
+	cfg := config
+	cfg.Store = &stubMiniHeaderStore{}
+	cfg.Client = fakeClient
+	cfg.PollingInterval = 10 * time.Millisecond
+	beats := make(chan struct{}, 10)
+	cfg.Heartbeat = func() {
+		select {
+		case beats <- struct{}{}:
+		default:
+		}
+	}
+	watcher := New(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Watch(ctx)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-beats:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for the heartbeat of the watcher")
+		}
+	}
+}
+
 type blockRangeChunksTestCase struct {
 	from                int
 	to                  int
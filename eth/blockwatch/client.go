@@ -2,7 +2,7 @@ package blockwatch
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"math/big"
 	"time"
 
@@ -31,23 +31,17 @@ type RPCClient struct {
 }
 
 // NewRPCClient returns a new Client for fetching Ethereum blocks using the given
-// ethclient.Client.
-func NewRPCClient(rpcURL string, requestTimeout time.Duration) (*RPCClient, error) {
-	ethClient, err := ethclient.Dial(rpcURL)
-	if err != nil {
-		return nil, err
-	}
-	rpcClient, err := rpc.Dial(rpcURL)
-	if err != nil {
-		return nil, err
-	}
-	return &RPCClient{rpcClient: rpcClient, client: ethClient, requestTimeout: requestTimeout}, nil
+// rpc.Client.
+func NewRPCClient(rpcClient *rpc.Client, requestTimeout time.Duration) *RPCClient {
+	return &RPCClient{rpcClient: rpcClient, client: ethclient.NewClient(rpcClient), requestTimeout: requestTimeout}
 }
 
 type getBlockByNumberResponse struct {
 	Hash       common.Hash `json:"hash"`
 	ParentHash common.Hash `json:"parentHash"`
 	Number     string      `json:"number"`
+	// L1BlockNumber is set by L2 chains like Arbitrum
+	L1BlockNumber string `json:"l1BlockNumber"`
 }
 
 // HeaderByNumber fetches a block header by its number. If no `number` is supplied, it will return the latest
@@ -74,6 +68,26 @@ func (rc *RPCClient) HeaderByNumber(number *big.Int) (*MiniHeader, error) {
 	if err != nil {
 		return nil, err
 	}
+	return header.miniHeader("eth_getBlockByNumber")
+}
+
+// HeaderByHash fetches a block header by its block hash. If no block exists with this number it will return
+// a `ethereum.NotFound` error.
+func (rc *RPCClient) HeaderByHash(hash common.Hash) (*MiniHeader, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rc.requestTimeout)
+	defer cancel()
+
+	// A raw RPC call is used for the same reason as in HeaderByNumber, and to get the L1 block
+	// number of the headers of L2 chains
+	var header getBlockByNumberResponse
+	err := rc.rpcClient.CallContext(ctx, &header, "eth_getBlockByHash", hash, false)
+	if err != nil {
+		return nil, err
+	}
+	return header.miniHeader("eth_getBlockByHash")
+}
+
+func (header *getBlockByNumberResponse) miniHeader(method string) (*MiniHeader, error) {
 	// If it returned an empty struct
 	if header.Number == "" {
 		return nil, ethereum.NotFound
@@ -81,29 +95,19 @@ func (rc *RPCClient) HeaderByNumber(number *big.Int) (*MiniHeader, error) {
 
 	blockNum, ok := math.ParseBig256(header.Number)
 	if !ok {
-		return nil, errors.New("Failed to parse big.Int value from hex-encoded block number returned from eth_getBlockByNumber")
+		return nil, fmt.Errorf("Failed to parse big.Int value from hex-encoded block number returned from %v", method)
 	}
 	miniHeader := &MiniHeader{
 		Hash:   header.Hash,
 		Parent: header.ParentHash,
 		Number: blockNum,
 	}
-	return miniHeader, nil
-}
-
-// HeaderByHash fetches a block header by its block hash. If no block exists with this number it will return
-// a `ethereum.NotFound` error.
-func (rc *RPCClient) HeaderByHash(hash common.Hash) (*MiniHeader, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), rc.requestTimeout)
-	defer cancel()
-	header, err := rc.client.HeaderByHash(ctx, hash)
-	if err != nil {
-		return nil, err
-	}
-	miniHeader := &MiniHeader{
-		Hash:   header.Hash(),
-		Parent: header.ParentHash,
-		Number: header.Number,
+	if header.L1BlockNumber != "" {
+		l1BlockNum, ok := math.ParseBig256(header.L1BlockNumber)
+		if !ok {
+			return nil, fmt.Errorf("Failed to parse big.Int value from hex-encoded L1 block number returned from %v", method)
+		}
+		miniHeader.L1BlockNumber = l1BlockNum
 	}
 	return miniHeader, nil
 }
@@ -0,0 +1,57 @@
+package blockwatch
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubBlockAPI serves the blocks of the eth namespace
+type stubBlockAPI struct{ block map[string]interface{} }
+
+func (s *stubBlockAPI) GetBlockByNumber(number string, fullTx bool) (map[string]interface{}, error) {
+	return s.block, nil
+}
+
+func (s *stubBlockAPI) GetBlockByHash(hash ethcommon.Hash, fullTx bool) (map[string]interface{}, error) {
+	return s.block, nil
+}
+
+func TestRPCClient_Header(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	api := &stubBlockAPI{block: map[string]interface{}{
+		"hash":       ethcommon.HexToHash("0x01"),
+		"parentHash": ethcommon.HexToHash("0x02"),
+		"number":     "0x64",
+	}}
+	server := rpc.NewServer()
+	require.Nil(server.RegisterName("eth", api))
+	rc := &RPCClient{rpcClient: rpc.DialInProc(server), requestTimeout: time.Second}
+	defer rc.rpcClient.Close()
+
+	// Test headers of L1 chains
+	header, err := rc.HeaderByNumber(nil)
+	require.Nil(err)
+	assert.Equal(ethcommon.HexToHash("0x01"), header.Hash)
+	assert.Equal(ethcommon.HexToHash("0x02"), header.Parent)
+	assert.Equal(big.NewInt(100), header.Number)
+	assert.Nil(header.L1BlockNumber)
+
+	// Test headers of L2 chains
+	api.block["l1BlockNumber"] = "0xa"
+	header, err = rc.HeaderByHash(ethcommon.HexToHash("0x01"))
+	require.Nil(err)
+	assert.Equal(big.NewInt(100), header.Number)
+	assert.Equal(big.NewInt(10), header.L1BlockNumber)
+
+	api.block["l1BlockNumber"] = "foo"
+	_, err = rc.HeaderByNumber(big.NewInt(100))
+	assert.EqualError(err, "Failed to parse big.Int value from hex-encoded L1 block number returned from eth_getBlockByNumber")
+}
@@ -13,7 +13,10 @@ type MiniHeader struct {
 	Hash   ethcommon.Hash
 	Parent ethcommon.Hash
 	Number *big.Int
-	Logs   []types.Log
+	// L1BlockNumber is the number of the L1 block of the headers of L2 chains like Arbitrum,
+	// whose contracts see it as the block number, and is nil on L1 chains
+	L1BlockNumber *big.Int
+	Logs          []types.Log
 }
 
 // MiniHeaderStore is an interface for a store that manages the state of a MiniHeader collection
@@ -20,7 +20,6 @@ import (
 	"strings"
 	"time"
 
-	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
@@ -45,6 +44,9 @@ type LivepeerEthClient interface {
 	Setup(password string, gasLimit uint64, gasPrice *big.Int) error
 	Account() accounts.Account
 	Backend() (Backend, error)
+	// Close stops the background work of the client, e.g. the checks of pending transactions
+	// and of the health of the remote signer
+	Close()
 
 	// Rounds
 	InitializeRound() (*types.Transaction, error)
@@ -74,6 +76,7 @@ type LivepeerEthClient interface {
 	RebondFromUnbonded(toAddr ethcommon.Address, unbondingLockID *big.Int) (*types.Transaction, error)
 	Unbond(amount *big.Int) (*types.Transaction, error)
 	WithdrawStake(unbondingLockID *big.Int) (*types.Transaction, error)
+	MoveStake(toAddr ethcommon.Address) (*types.Transaction, error)
 	WithdrawFees() (*types.Transaction, error)
 	ClaimEarnings(endRound *big.Int) error
 	GetTranscoder(addr ethcommon.Address) (*lpTypes.Transcoder, error)
@@ -93,10 +96,12 @@ type LivepeerEthClient interface {
 	CancelUnlock() (*types.Transaction, error)
 	Withdraw() (*types.Transaction, error)
 	RedeemWinningTicket(ticket *pm.Ticket, sig []byte, recipientRand *big.Int) (*types.Transaction, error)
+	BatchRedeemWinningTickets(tickets []*pm.Ticket, sigs [][]byte, recipientRands []*big.Int) (*types.Transaction, error)
 	IsUsedTicket(ticket *pm.Ticket) (bool, error)
 	GetSenderInfo(addr ethcommon.Address) (*pm.SenderInfo, error)
 	UnlockPeriod() (*big.Int, error)
 	ClaimedReserve(reserveHolder ethcommon.Address, claimant ethcommon.Address) (*big.Int, error)
+	ReserveAlloc(reserveHolder ethcommon.Address, claimant ethcommon.Address) (*big.Int, error)
 
 	// Parameters
 	GetTranscoderPoolMaxSize() (*big.Int, error)
@@ -116,6 +121,7 @@ type LivepeerEthClient interface {
 	CheckTx(*types.Transaction) error
 	ReplaceTransaction(*types.Transaction, string, *big.Int) (*types.Transaction, error)
 	Sign([]byte) ([]byte, error)
+	SignTypedData(*pm.TypedData) ([]byte, error)
 	GetGasInfo() (uint64, *big.Int)
 	SetGasInfo(uint64, *big.Int) error
 }
@@ -125,6 +131,7 @@ type client struct {
 	backend        Backend
 
 	controllerAddr      ethcommon.Address
+	contractAddrs       map[string]ethcommon.Address
 	tokenAddr           ethcommon.Address
 	serviceRegistryAddr ethcommon.Address
 	bondingManagerAddr  ethcommon.Address
@@ -147,32 +154,93 @@ type client struct {
 	gasLimit uint64
 	gasPrice *big.Int
 
+	txManager *TxManager
 	txTimeout time.Duration
 }
 
-func NewClient(accountAddr ethcommon.Address, keystoreDir string, eth *ethclient.Client, controllerAddr ethcommon.Address, txTimeout time.Duration) (LivepeerEthClient, error) {
-	chainID, err := eth.ChainID(context.Background())
+// LivepeerEthClientConfig configures a LivepeerEthClient
+type LivepeerEthClientConfig struct {
+	// AccountAddr is the address of the account of the client, which defaults to the first
+	// account of the keystore or of the remote signer
+	AccountAddr ethcommon.Address
+	// KeystoreDir is the directory of the keystore of the account
+	KeystoreDir string
+	// UsbWallet configures the USB hardware wallet of the account if it is not nil
+	UsbWallet *UsbWalletConfig
+	// RemoteSigner configures the remote signer of the account if it is not nil
+	RemoteSigner *RemoteSignerConfig
+	// OfflineSigner queues the transactions of the account to be signed offline if it is not nil
+	OfflineSigner *OfflineAccountManager
+	EthClient     *ethclient.Client
+	// GasPriceOracle suggests the gas price of the transactions sent without one, which
+	// defaults to the gas price of the Ethereum node
+	GasPriceOracle GasPriceOracle
+	// EstimateGasMargin is the percentage added to the gas estimates of the Ethereum node for
+	// the gas limit of transactions, e.g. on L2 chains
+	EstimateGasMargin uint64
+	ControllerAddr    ethcommon.Address
+	// ContractAddrs are the addresses of protocol contracts, keyed by the names of
+	// ContractNames, which are used instead of the addresses registered in the Controller,
+	// e.g. on private deployments
+	ContractAddrs map[string]ethcommon.Address
+	TxTimeout     time.Duration
+	// TxStuckTimeout is the time after which a pending transaction is replaced with a bumped
+	// gas price, disabled if 0
+	TxStuckTimeout time.Duration
+	// MaxGasPrice caps the gas price of replacement transactions if it is not nil
+	MaxGasPrice *big.Int
+}
+
+// ContractNames are the names of the protocol contracts whose addresses are looked up in the
+// Controller
+var ContractNames = []string{"LivepeerToken", "ServiceRegistry", "BondingManager", "TicketBroker", "RoundsManager", "Minter", "LivepeerTokenFaucet"}
+
+// NewClient creates a client for the account of the keystore, of the USB hardware wallet, of
+// the remote signer or of the offline signer of cfg
+func NewClient(cfg LivepeerEthClientConfig) (LivepeerEthClient, error) {
+	if err := ValidContractAddrs(cfg.ContractAddrs); err != nil {
+		return nil, err
+	}
+
+	chainID, err := cfg.EthClient.ChainID(context.Background())
 	if err != nil {
 		return nil, err
 	}
 
 	signer := types.NewEIP155Signer(chainID)
 
-	backend, err := NewBackend(eth, signer)
+	backend, err := NewBackend(cfg.EthClient, signer, cfg.GasPriceOracle, cfg.EstimateGasMargin)
 	if err != nil {
 		return nil, err
 	}
 
-	am, err := NewAccountManager(accountAddr, keystoreDir, signer)
+	var am AccountManager
+	if cfg.UsbWallet != nil {
+		am, err = NewUsbAccountManager(cfg.AccountAddr, *cfg.UsbWallet, chainID)
+	} else if cfg.OfflineSigner != nil {
+		am = cfg.OfflineSigner
+	} else if cfg.RemoteSigner != nil {
+		am, err = NewRemoteAccountManager(cfg.AccountAddr, *cfg.RemoteSigner, signer)
+	} else {
+		am, err = NewAccountManager(cfg.AccountAddr, cfg.KeystoreDir, signer)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	txManager := NewTxManager(backend, am, TxManagerConfig{
+		StuckTimeout: cfg.TxStuckTimeout,
+		MaxGasPrice:  cfg.MaxGasPrice,
+	})
+	go txManager.Start()
+
 	return &client{
 		accountManager: am,
-		backend:        backend,
-		controllerAddr: controllerAddr,
-		txTimeout:      txTimeout,
+		backend:        txManager,
+		controllerAddr: cfg.ControllerAddr,
+		contractAddrs:  cfg.ContractAddrs,
+		txManager:      txManager,
+		txTimeout:      cfg.TxTimeout,
 	}, nil
 }
 
@@ -186,7 +254,7 @@ func (c *client) Setup(password string, gasLimit uint64, gasPrice *big.Int) erro
 }
 
 func (c *client) SetGasInfo(gasLimit uint64, gasPrice *big.Int) error {
-	opts, err := c.accountManager.CreateTransactOpts(gasLimit, gasPrice)
+	opts, err := c.transactOpts(gasLimit, gasPrice)
 	if err != nil {
 		return err
 	}
@@ -200,10 +268,30 @@ func (c *client) SetGasInfo(gasLimit uint64, gasPrice *big.Int) error {
 	}
 }
 
+func (c *client) Close() {
+	c.txManager.Stop()
+	if am, ok := c.accountManager.(*remoteAccountManager); ok {
+		am.Stop()
+	}
+}
+
 func (c *client) GetGasInfo() (gasLimit uint64, gasPrice *big.Int) {
 	return c.gasLimit, c.gasPrice
 }
 
+// transactOpts creates transact opts whose transactions are sent one at a time by the
+// transaction manager
+func (c *client) transactOpts(gasLimit uint64, gasPrice *big.Int) (*bind.TransactOpts, error) {
+	opts, err := c.accountManager.CreateTransactOpts(gasLimit, gasPrice)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Signer = c.txManager.Signer(opts.Signer)
+
+	return opts, nil
+}
+
 func (c *client) setContracts(opts *bind.TransactOpts) error {
 	controller, err := contracts.NewController(c.controllerAddr, c.backend)
 	if err != nil {
@@ -218,7 +306,7 @@ func (c *client) setContracts(opts *bind.TransactOpts) error {
 
 	glog.V(common.SHORT).Infof("Controller: %v", c.controllerAddr.Hex())
 
-	tokenAddr, err := c.GetContract(crypto.Keccak256Hash([]byte("LivepeerToken")))
+	tokenAddr, err := c.contractAddr("LivepeerToken")
 	if err != nil {
 		glog.Errorf("Error getting LivepeerToken address: %v", err)
 		return err
@@ -239,7 +327,7 @@ func (c *client) setContracts(opts *bind.TransactOpts) error {
 
 	glog.V(common.SHORT).Infof("LivepeerToken: %v", c.tokenAddr.Hex())
 
-	serviceRegistryAddr, err := c.GetContract(crypto.Keccak256Hash([]byte("ServiceRegistry")))
+	serviceRegistryAddr, err := c.contractAddr("ServiceRegistry")
 	if err != nil {
 		glog.Errorf("Error getting ServiceRegistry address: %v", err)
 		return err
@@ -260,7 +348,7 @@ func (c *client) setContracts(opts *bind.TransactOpts) error {
 
 	glog.V(common.SHORT).Infof("ServiceRegistry: %v", c.serviceRegistryAddr.Hex())
 
-	bondingManagerAddr, err := c.GetContract(crypto.Keccak256Hash([]byte("BondingManager")))
+	bondingManagerAddr, err := c.contractAddr("BondingManager")
 	if err != nil {
 		glog.Errorf("Error getting BondingManager address: %v", err)
 		return err
@@ -281,7 +369,7 @@ func (c *client) setContracts(opts *bind.TransactOpts) error {
 
 	glog.V(common.SHORT).Infof("BondingManager: %v", c.bondingManagerAddr.Hex())
 
-	brokerAddr, err := c.GetContract(crypto.Keccak256Hash([]byte("TicketBroker")))
+	brokerAddr, err := c.contractAddr("TicketBroker")
 	if err != nil {
 		glog.Errorf("Error getting TicketBroker address: %v", err)
 		return err
@@ -302,7 +390,7 @@ func (c *client) setContracts(opts *bind.TransactOpts) error {
 
 	glog.V(common.SHORT).Infof("TicketBroker: %v", c.ticketBrokerAddr.Hex())
 
-	roundsManagerAddr, err := c.GetContract(crypto.Keccak256Hash([]byte("RoundsManager")))
+	roundsManagerAddr, err := c.contractAddr("RoundsManager")
 	if err != nil {
 		glog.Errorf("Error getting RoundsManager address: %v", err)
 		return err
@@ -323,7 +411,7 @@ func (c *client) setContracts(opts *bind.TransactOpts) error {
 
 	glog.V(common.SHORT).Infof("RoundsManager: %v", c.roundsManagerAddr.Hex())
 
-	minterAddr, err := c.GetContract(crypto.Keccak256Hash([]byte("Minter")))
+	minterAddr, err := c.contractAddr("Minter")
 	if err != nil {
 		glog.Errorf("Error getting Minter address: %v", err)
 		return err
@@ -344,7 +432,7 @@ func (c *client) setContracts(opts *bind.TransactOpts) error {
 
 	glog.V(common.SHORT).Infof("Minter: %v", c.minterAddr.Hex())
 
-	faucetAddr, err := c.GetContract(crypto.Keccak256Hash([]byte("LivepeerTokenFaucet")))
+	faucetAddr, err := c.contractAddr("LivepeerTokenFaucet")
 	if err != nil {
 		glog.Errorf("Error getting LivepeerTokenFaucet address: %v", err)
 		return err
@@ -368,6 +456,34 @@ func (c *client) setContracts(opts *bind.TransactOpts) error {
 	return nil
 }
 
+// contractAddr returns the address of the contract name, from the Controller unless it is
+// overridden
+func (c *client) contractAddr(name string) (ethcommon.Address, error) {
+	if addr, ok := c.contractAddrs[name]; ok {
+		glog.V(common.SHORT).Infof("Using the %v address override: %v", name, addr.Hex())
+		return addr, nil
+	}
+	return c.GetContract(crypto.Keccak256Hash([]byte(name)))
+}
+
+// ValidContractAddrs checks that the contract address overrides are the non-zero addresses of
+// contracts of ContractNames
+func ValidContractAddrs(addrs map[string]ethcommon.Address) error {
+	for name, addr := range addrs {
+		known := false
+		for _, n := range ContractNames {
+			known = known || n == name
+		}
+		if !known {
+			return fmt.Errorf("unknown contract %v, expected one of %v", name, strings.Join(ContractNames, ", "))
+		}
+		if (addr == ethcommon.Address{}) {
+			return fmt.Errorf("the address of contract %v must not be zero", name)
+		}
+	}
+	return nil
+}
+
 func (c *client) Account() accounts.Account {
 	return c.accountManager.Account()
 }
@@ -441,6 +557,12 @@ func (c *client) Bond(amount *big.Int, to ethcommon.Address) (*types.Transaction
 	return c.BondingManagerSession.Bond(amount, to)
 }
 
+// MoveStake moves the whole stake of the account to the orchestrator toAddr, by bonding 0
+// LPT to it
+func (c *client) MoveStake(toAddr ethcommon.Address) (*types.Transaction, error) {
+	return c.Bond(big.NewInt(0), toAddr)
+}
+
 func (c *client) Rebond(unbondingLockID *big.Int) (*types.Transaction, error) {
 	currentRound, err := c.CurrentRound()
 	if err != nil {
@@ -731,7 +853,7 @@ func (c *client) Vote(pollAddr ethcommon.Address, choiceID *big.Int) (*types.Tra
 	}
 
 	gl, gp := c.GetGasInfo()
-	opts, err := c.accountManager.CreateTransactOpts(gl, gp)
+	opts, err := c.transactOpts(gl, gp)
 	if err != nil {
 		return nil, err
 	}
@@ -858,13 +980,14 @@ func (c *client) CheckTx(tx *types.Transaction) error {
 	ctx, cancel := context.WithTimeout(context.Background(), c.txTimeout)
 	defer cancel()
 
-	receipt, err := bind.WaitMined(ctx, c.backend, tx)
+	// The transaction may have been replaced if it got stuck
+	receipt, err := c.txManager.WaitMined(ctx, tx)
 	if err != nil {
 		return err
 	}
 
 	if receipt.Status == uint64(0) {
-		return fmt.Errorf("tx %v failed", tx.Hash().Hex())
+		return fmt.Errorf("tx %v failed", receipt.TxHash.Hex())
 	} else {
 		return nil
 	}
@@ -874,65 +997,10 @@ func (c *client) Sign(msg []byte) ([]byte, error) {
 	return c.accountManager.Sign(msg)
 }
 
-func (c *client) ReplaceTransaction(tx *types.Transaction, method string, gasPrice *big.Int) (*types.Transaction, error) {
-	_, pending, err := c.backend.TransactionByHash(context.Background(), tx.Hash())
-	// Only return here if the error is not related to the tx not being found
-	// Presumably the provided tx was already broadcasted at some point, so even if for some reason the
-	// node being used cannot find it, the originally broadcasted tx is still valid and might be sitting somewhere
-	if err != nil && err != ethereum.NotFound {
-		return nil, err
-	}
-	// If tx was found
-	// If `pending` is true, the tx was mined and included in a block
-	if err == nil && !pending {
-		return nil, ErrReplacingMinedTx
-	}
-
-	// Updated gas price must be at least 10% greater than the gas price used for the original transaction in order
-	// to submit a replacement transaction with the same nonce. 10% is not defined by the protocol, but is the default required price bump
-	// used by many clients: https://github.com/ethereum/go-ethereum/blob/01a7e267dc6d7bbef94882542bbd01bd712f5548/core/tx_pool.go#L148
-	// We add a little extra in addition to the 10% price bump just to be sure
-	minGasPrice := big.NewInt(0).Add(big.NewInt(0).Add(tx.GasPrice(), big.NewInt(0).Div(tx.GasPrice(), big.NewInt(10))), big.NewInt(10))
-
-	// If gas price is not provided, use minimum gas price that satisfies the 10% required price bump
-	if gasPrice == nil {
-		gasPrice = minGasPrice
-
-		suggestedGasPrice, err := c.backend.SuggestGasPrice(context.Background())
-		if err != nil {
-			return nil, err
-		}
-
-		// If the suggested gas price is higher than the bumped gas price, use the suggested gas price
-		// This is to account for any wild market gas price increases between the time of the original tx submission and time
-		// of replacement tx submission
-		// Note: If the suggested gas price is lower than the bumped gas price because market gas prices have dropped
-		// since the time of the original tx submission we cannot use the lower suggested gas price and we still need to use
-		// the bumped gas price in order to properly replace a still pending tx
-		if suggestedGasPrice.Cmp(gasPrice) == 1 {
-			gasPrice = suggestedGasPrice
-		}
-	}
-
-	// Check that gas price meets minimum price bump requirement
-	if gasPrice.Cmp(minGasPrice) == -1 {
-		return nil, fmt.Errorf("Provided gas price does not satisfy required price bump to replace transaction %v", tx.Hash())
-	}
-
-	// Replacement raw tx uses same fields as old tx (reusing the same nonce is crucial) except the gas price is updated
-	newRawTx := types.NewTransaction(tx.Nonce(), *tx.To(), tx.Value(), tx.Gas(), gasPrice, tx.Data())
-
-	newSignedTx, err := c.accountManager.SignTx(newRawTx)
-	if err != nil {
-		return nil, err
-	}
-
-	err = c.backend.SendTransaction(context.Background(), newSignedTx)
-	if err == nil {
-		glog.Infof("\n%vEth Transaction%v\n\nReplacement transaction: \"%v\".  Hash: \"%v\".  Gas Price: %v \n\n%v\n", strings.Repeat("*", 30), strings.Repeat("*", 30), method, newSignedTx.Hash().String(), newSignedTx.GasPrice().String(), strings.Repeat("*", 75))
-	} else {
-		glog.Infof("\n%vEth Transaction%v\n\nReplacement transaction: \"%v\".  Gas Price: %v \nTransaction Failed: %v\n\n%v\n", strings.Repeat("*", 30), strings.Repeat("*", 30), method, newSignedTx.GasPrice().String(), err, strings.Repeat("*", 75))
-	}
+func (c *client) SignTypedData(typedData *pm.TypedData) ([]byte, error) {
+	return c.accountManager.SignTypedData(typedData)
+}
 
-	return newSignedTx, err
+func (c *client) ReplaceTransaction(tx *types.Transaction, method string, gasPrice *big.Int) (*types.Transaction, error) {
+	return c.txManager.ReplaceTransaction(tx, method, gasPrice)
 }
@@ -157,3 +157,20 @@ func TestFindTranscoderHints(t *testing.T) {
 	assert.Equal(hints.PosPrev, ethcommon.HexToAddress("bbb"))
 	assert.Equal(hints.PosNext, ethcommon.HexToAddress("ddd"))
 }
+
+func TestContractAddr_Overrides(t *testing.T) {
+	assert := assert.New(t)
+
+	broker := ethcommon.HexToAddress("aaa")
+	c := &client{contractAddrs: map[string]ethcommon.Address{"TicketBroker": broker}}
+
+	// Test overridden addresses aren't looked up in the Controller
+	addr, err := c.contractAddr("TicketBroker")
+	assert.Nil(err)
+	assert.Equal(broker, addr)
+
+	assert.Nil(ValidContractAddrs(nil))
+	assert.Nil(ValidContractAddrs(c.contractAddrs))
+	assert.EqualError(ValidContractAddrs(map[string]ethcommon.Address{"Broker": broker}), "unknown contract Broker, expected one of LivepeerToken, ServiceRegistry, BondingManager, TicketBroker, RoundsManager, Minter, LivepeerTokenFaucet")
+	assert.EqualError(ValidContractAddrs(map[string]ethcommon.Address{"Minter": {}}), "the address of contract Minter must not be zero")
+}
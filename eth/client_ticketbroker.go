@@ -45,24 +45,40 @@ func (c *client) FundReserve(amount *big.Int) (*types.Transaction, error) {
 // RedeemWinningTicket submits a ticket to be validated by the broker and if a valid winning ticket
 // the broker pays the ticket's face value to the ticket's recipient
 func (c *client) RedeemWinningTicket(ticket *pm.Ticket, sig []byte, recipientRand *big.Int) (*types.Transaction, error) {
-	var recipientRandHash [32]byte
-	copy(recipientRandHash[:], ticket.RecipientRandHash.Bytes()[:32])
-
 	return c.TicketBrokerSession.RedeemWinningTicket(
-		contracts.Struct1{
-			Recipient:         ticket.Recipient,
-			Sender:            ticket.Sender,
-			FaceValue:         ticket.FaceValue,
-			WinProb:           ticket.WinProb,
-			SenderNonce:       new(big.Int).SetUint64(uint64(ticket.SenderNonce)),
-			RecipientRandHash: recipientRandHash,
-			AuxData:           ticket.AuxData(),
-		},
+		ticketStruct(ticket),
 		sig,
 		recipientRand,
 	)
 }
 
+// BatchRedeemWinningTickets submits multiple tickets to be validated by the broker with a single
+// transaction. The broker pays the face value of each valid winning ticket to its recipient and
+// skips the other tickets without failing the transaction
+func (c *client) BatchRedeemWinningTickets(tickets []*pm.Ticket, sigs [][]byte, recipientRands []*big.Int) (*types.Transaction, error) {
+	structs := make([]contracts.Struct1, len(tickets))
+	for i, ticket := range tickets {
+		structs[i] = ticketStruct(ticket)
+	}
+
+	return c.TicketBrokerSession.BatchRedeemWinningTickets(structs, sigs, recipientRands)
+}
+
+func ticketStruct(ticket *pm.Ticket) contracts.Struct1 {
+	var recipientRandHash [32]byte
+	copy(recipientRandHash[:], ticket.RecipientRandHash.Bytes()[:32])
+
+	return contracts.Struct1{
+		Recipient:         ticket.Recipient,
+		Sender:            ticket.Sender,
+		FaceValue:         ticket.FaceValue,
+		WinProb:           ticket.WinProb,
+		SenderNonce:       new(big.Int).SetUint64(uint64(ticket.SenderNonce)),
+		RecipientRandHash: recipientRandHash,
+		AuxData:           ticket.AuxData(),
+	}
+}
+
 // GetSenderInfo returns the info for a sender
 func (c *client) GetSenderInfo(addr ethcommon.Address) (*pm.SenderInfo, error) {
 	info := new(struct {
@@ -102,3 +118,10 @@ func (c *client) IsUsedTicket(ticket *pm.Ticket) (bool, error) {
 
 	return c.TicketBrokerSession.UsedTickets(ticketHash)
 }
+
+// ReserveAlloc returns the amount of the reserve of a reserve holder that a claimant can still
+// claim in the current round
+// This method wraps the underlying contract method ClaimableReserve
+func (c *client) ReserveAlloc(reserveHolder ethcommon.Address, claimant ethcommon.Address) (*big.Int, error) {
+	return c.TicketBrokerSession.ClaimableReserve(reserveHolder, claimant)
+}
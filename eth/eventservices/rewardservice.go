@@ -2,12 +2,18 @@ package eventservices
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"math/big"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/golang/glog"
 	"github.com/livepeer/go-livepeer/eth"
+	"github.com/livepeer/go-livepeer/monitor"
+	"github.com/livepeer/go-livepeer/webhook"
 )
 
 var (
@@ -17,18 +23,66 @@ var (
 
 const blockTime = 15 * time.Second
 
+// RoundsSubscriber subscribes to the NewRound events of round initialization
+type RoundsSubscriber interface {
+	SubscribeRounds(sink chan<- types.Log) event.Subscription
+}
+
+// RewardServiceConfig configures a RewardService
+type RewardServiceConfig struct {
+	// RetryInterval is the interval between the attempts to call reward in a round
+	RetryInterval time.Duration
+	// MaxAttempts is the number of failed attempts to call reward in a round before giving up
+	// on the round, no limit if 0
+	MaxAttempts int
+	// MaxGasPrice is the highest gas price that reward is called at, no cap if nil. Reward is
+	// called once the gas price goes below it, or is missed if the round ends first
+	MaxGasPrice *big.Int
+	// SuggestGasPrice returns the gas price of transactions, required if MaxGasPrice is set
+	SuggestGasPrice func(context.Context) (*big.Int, error)
+	// MissedWebhook is the URL that missed reward calls are posted to, if set
+	MissedWebhook string
+	// Webhooks posts the missed reward calls
+	Webhooks *webhook.Dispatcher
+}
+
+// RewardMissed is the payload of the webhook of a round whose reward was not called by the
+// orchestrator while it was active
+type RewardMissed struct {
+	Round        int64  `json:"round"`
+	Orchestrator string `json:"orchestrator"`
+	Attempts     int    `json:"attempts"`
+	Error        string `json:"error,omitempty"`
+}
+
+// RewardService calls reward for the orchestrator in each round that it is active in. It tries
+// as soon as a round is initialized, then every RetryInterval until reward is called, and
+// reports the rounds whose reward was not called by the time the next round started
 type RewardService struct {
-	client          eth.LivepeerEthClient
-	pendingTx       *types.Transaction
-	working         bool
-	cancelWorker    context.CancelFunc
-	pollingInterval time.Duration
+	client       eth.LivepeerEthClient
+	rounds       RoundsSubscriber
+	cfg          RewardServiceConfig
+	pendingTx    *types.Transaction
+	working      bool
+	cancelWorker context.CancelFunc
+
+	mu sync.Mutex
+	// round is the round of the attempts, rewarded is set once reward is called in it and due
+	// once the orchestrator was found to need to call reward in it
+	round    *big.Int
+	rewarded bool
+	due      bool
+	attempts int
+	lastErr  error
 }
 
-func NewRewardService(client eth.LivepeerEthClient, pollingInterval time.Duration) *RewardService {
+// NewRewardService creates a RewardService that calls reward with client after the round
+// initializations notified by rounds
+func NewRewardService(client eth.LivepeerEthClient, rounds RoundsSubscriber, cfg RewardServiceConfig) *RewardService {
 	return &RewardService{
-		client:          client,
-		pollingInterval: pollingInterval,
+		client: client,
+		rounds: rounds,
+		cfg:    cfg,
 	}
 }
 
@@ -40,15 +94,26 @@ func (s *RewardService) Start(ctx context.Context) error {
 	cancelCtx, cancel := context.WithCancel(ctx)
 	s.cancelWorker = cancel
 
-	tickCh := time.NewTicker(s.pollingInterval).C
+	roundEvents := make(chan types.Log, 10)
+	sub := s.rounds.SubscribeRounds(roundEvents)
+	ticker := time.NewTicker(s.cfg.RetryInterval)
 
 	go func(ctx context.Context) {
+		defer monitor.RecoverAndReport()
+		defer sub.Unsubscribe()
+		defer ticker.Stop()
+
+		s.attempt()
+
 		for {
 			select {
-			case <-tickCh:
-				err := s.tryReward()
+			case <-roundEvents:
+				s.attempt()
+			case <-ticker.C:
+				s.attempt()
+			case err := <-sub.Err():
 				if err != nil {
-					glog.Errorf("Error trying to call reward: %v", err)
+					glog.Errorf("Round subscription error err=%v", err)
 				}
 			case <-ctx.Done():
 				glog.V(5).Infof("Reward service done")
@@ -77,12 +142,94 @@ func (s *RewardService) IsWorking() bool {
 	return s.working
 }
 
-func (s *RewardService) tryReward() error {
+// attempt tries to call reward in the current round unless it was called or given up on
+func (s *RewardService) attempt() {
 	currentRound, err := s.client.CurrentRound()
 	if err != nil {
-		return err
+		glog.Errorf("Error trying to call reward: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	if s.round == nil || s.round.Cmp(currentRound) != 0 {
+		s.startRound(currentRound)
+	}
+	done := s.rewarded || (s.cfg.MaxAttempts > 0 && s.attempts >= s.cfg.MaxAttempts)
+	s.mu.Unlock()
+
+	if done {
+		return
+	}
+
+	err = s.tryReward(currentRound)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		glog.Errorf("Error trying to call reward: %v", err)
+
+		s.attempts++
+		s.lastErr = err
+		if s.cfg.MaxAttempts > 0 && s.attempts >= s.cfg.MaxAttempts {
+			glog.Errorf("Giving up on calling reward for round %v after %v attempts", currentRound, s.attempts)
+		}
+	}
+}
+
+// startRound reports the previous round if its reward was missed and resets the attempts. The
+// caller must hold mu
+func (s *RewardService) startRound(round *big.Int) {
+	if s.round != nil && s.due && !s.rewarded {
+		s.reportMissed()
+	}
+
+	s.round = round
+	s.rewarded = false
+	s.due = false
+	s.attempts = 0
+	s.lastErr = nil
+}
+
+// reportMissed records the missed reward call of the round and posts it to the webhook. The
+// caller must hold mu
+func (s *RewardService) reportMissed() {
+	orch := s.client.Account().Address.Hex()
+
+	glog.Errorf("Missed reward call for round %v orchestrator=%v attempts=%v err=%v", s.round, orch, s.attempts, s.lastErr)
+
+	if monitor.Enabled {
+		monitor.RewardCallMissed()
+	}
+
+	if s.cfg.MissedWebhook == "" || s.cfg.Webhooks == nil {
+		return
+	}
+
+	missed := &RewardMissed{
+		Round:        s.round.Int64(),
+		Orchestrator: orch,
+		Attempts:     s.attempts,
+	}
+	if s.lastErr != nil {
+		missed.Error = s.lastErr.Error()
+	}
+	body, err := json.Marshal(missed)
+	if err != nil {
+		glog.Errorf("Error encoding missed reward call err=%v", err)
+		return
 	}
 
+	// Deliver in the background so that the retries of the webhook don't hold up reward calls
+	go func() {
+		resp, err := s.cfg.Webhooks.Post("rewardMissed", s.cfg.MissedWebhook, nil, body)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+}
+
+func (s *RewardService) tryReward(currentRound *big.Int) error {
 	initialized, err := s.client.CurrentRoundInitialized()
 	if err != nil {
 		return err
@@ -98,54 +245,84 @@ func (s *RewardService) tryReward() error {
 		return err
 	}
 
-	if t.LastRewardRound.Cmp(currentRound) == -1 && initialized && active {
-		var (
-			tx  *types.Transaction
-			err error
-		)
-
-		if s.pendingTx != nil {
-			// Previous attempt to call reward() still pending
-			// Replace pending tx by bumping gas price
-			tx, err = s.client.ReplaceTransaction(s.pendingTx, "reward", nil)
-			if err != nil {
-				if err == eth.ErrReplacingMinedTx || err.Error() == "nonce too low" {
-					// Pending tx confirmed so we should not try to replace next time
-					s.pendingTx = nil
-				}
+	if t.LastRewardRound.Cmp(currentRound) != -1 {
+		// Reward was called for the round, by this service or by another client
+		s.setRewarded()
+		return nil
+	}
 
-				return err
-			}
-		} else {
-			// No previous attempt to call reward(), invoke with next nonce
-			tx, err = s.client.Reward()
-			if err != nil {
-				return err
-			}
+	if !initialized || !active {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.due = true
+	s.mu.Unlock()
+
+	if s.cfg.MaxGasPrice != nil {
+		gasPrice, err := s.cfg.SuggestGasPrice(context.Background())
+		if err != nil {
+			return err
+		}
+		if gasPrice.Cmp(s.cfg.MaxGasPrice) > 0 {
+			glog.Infof("Waiting to call reward for round %v until the gas price %v goes below the max gas price %v", currentRound, gasPrice, s.cfg.MaxGasPrice)
+			return nil
 		}
+	}
 
-		s.pendingTx = tx
+	var tx *types.Transaction
 
-		err = s.client.CheckTx(tx)
+	if s.pendingTx != nil {
+		// Previous attempt to call reward() still pending
+		// Replace pending tx by bumping gas price
+		tx, err = s.client.ReplaceTransaction(s.pendingTx, "reward", nil)
 		if err != nil {
-			if err == context.DeadlineExceeded {
-				glog.Infof("Reward tx did not confirm within defined time window - will try to replace pending tx next time")
+			if err == eth.ErrReplacingMinedTx || err.Error() == "nonce too low" {
+				// Pending tx confirmed so we should not try to replace next time
+				s.pendingTx = nil
 			}
 
 			return err
 		}
-
-		s.pendingTx = nil
-
-		tp, err := s.client.GetTranscoderEarningsPoolForRound(s.client.Account().Address, currentRound)
+	} else {
+		// No previous attempt to call reward(), invoke with next nonce
+		tx, err = s.client.Reward()
 		if err != nil {
 			return err
 		}
+	}
 
-		glog.Infof("Called reward for round %v - %v rewards minted", currentRound, eth.FormatUnits(tp.RewardPool, "LPTU"))
+	s.pendingTx = tx
 
-		return nil
+	err = s.client.CheckTx(tx)
+	if err != nil {
+		if err == context.DeadlineExceeded {
+			glog.Infof("Reward tx did not confirm within defined time window - will try to replace pending tx next time")
+		}
+
+		return err
+	}
+
+	s.pendingTx = nil
+	s.setRewarded()
+
+	if monitor.Enabled {
+		monitor.RewardCalled()
+	}
+
+	tp, err := s.client.GetTranscoderEarningsPoolForRound(s.client.Account().Address, currentRound)
+	if err != nil {
+		return err
 	}
 
+	glog.Infof("Called reward for round %v - %v rewards minted", currentRound, eth.FormatUnits(tp.RewardPool, "LPTU"))
+
 	return nil
 }
+
+func (s *RewardService) setRewarded() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rewarded = true
+}
@@ -0,0 +1,203 @@
+package eventservices
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/livepeer/go-livepeer/eth"
+	lpTypes "github.com/livepeer/go-livepeer/eth/types"
+	"github.com/livepeer/go-livepeer/pm"
+	"github.com/livepeer/go-livepeer/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubRewardClient struct {
+	*eth.StubClient
+
+	round           *big.Int
+	initialized     bool
+	active          bool
+	lastRewardRound *big.Int
+	rewardErr       error
+	rewardCalls     int
+}
+
+func newStubRewardClient() *stubRewardClient {
+	return &stubRewardClient{
+		StubClient:      &eth.StubClient{TranscoderAddress: pm.RandAddress()},
+		round:           big.NewInt(10),
+		initialized:     true,
+		active:          true,
+		lastRewardRound: big.NewInt(9),
+	}
+}
+
+func (c *stubRewardClient) CurrentRound() (*big.Int, error)        { return c.round, nil }
+func (c *stubRewardClient) CurrentRoundInitialized() (bool, error) { return c.initialized, nil }
+func (c *stubRewardClient) IsActiveTranscoder() (bool, error)      { return c.active, nil }
+
+func (c *stubRewardClient) GetTranscoder(addr ethcommon.Address) (*lpTypes.Transcoder, error) {
+	return &lpTypes.Transcoder{LastRewardRound: c.lastRewardRound}, nil
+}
+
+func (c *stubRewardClient) Reward() (*types.Transaction, error) {
+	c.rewardCalls++
+	if c.rewardErr != nil {
+		return nil, c.rewardErr
+	}
+	c.lastRewardRound = c.round
+	return types.NewTransaction(0, pm.RandAddress(), big.NewInt(0), 0, big.NewInt(0), nil), nil
+}
+
+func (c *stubRewardClient) CheckTx(tx *types.Transaction) error { return nil }
+
+func (c *stubRewardClient) GetTranscoderEarningsPoolForRound(addr ethcommon.Address, round *big.Int) (*lpTypes.TokenPools, error) {
+	return &lpTypes.TokenPools{RewardPool: big.NewInt(1)}, nil
+}
+
+func TestRewardService_Attempt(t *testing.T) {
+	assert := assert.New(t)
+
+	client := newStubRewardClient()
+	s := NewRewardService(client, nil, RewardServiceConfig{MaxAttempts: 2})
+
+	// Test reward isn't called before the round is initialized
+	client.initialized = false
+	s.attempt()
+	assert.Equal(0, client.rewardCalls)
+	assert.False(s.due)
+
+	// Test reward is called once in a round
+	client.initialized = true
+	s.attempt()
+	assert.Equal(1, client.rewardCalls)
+	assert.True(s.rewarded)
+	s.attempt()
+	assert.Equal(1, client.rewardCalls)
+
+	// Test reward isn't called by inactive orchestrators
+	client.round = big.NewInt(11)
+	client.active = false
+	s.attempt()
+	assert.Equal(1, client.rewardCalls)
+	assert.False(s.due)
+
+	// Test reward called by another client
+	client.active = true
+	client.lastRewardRound = client.round
+	s.attempt()
+	assert.Equal(1, client.rewardCalls)
+	assert.True(s.rewarded)
+
+	// Test attempts are given up on after the max attempts
+	client.round = big.NewInt(12)
+	client.rewardErr = errors.New("Reward error")
+	s.attempt()
+	s.attempt()
+	s.attempt()
+	assert.Equal(3, client.rewardCalls)
+	assert.Equal(2, s.attempts)
+	assert.EqualError(s.lastErr, "Reward error")
+
+	// Test the attempts are reset in the next round
+	client.round = big.NewInt(13)
+	client.rewardErr = nil
+	s.attempt()
+	assert.Equal(4, client.rewardCalls)
+	assert.Equal(0, s.attempts)
+	assert.True(s.rewarded)
+}
+
+func TestRewardService_MaxGasPrice(t *testing.T) {
+	assert := assert.New(t)
+
+	client := newStubRewardClient()
+	gasPrice := big.NewInt(200)
+	s := NewRewardService(client, nil, RewardServiceConfig{
+		MaxGasPrice:     big.NewInt(100),
+		SuggestGasPrice: func(context.Context) (*big.Int, error) { return gasPrice, nil },
+	})
+
+	// Test reward waits for the gas price to go below the max gas price
+	s.attempt()
+	assert.Equal(0, client.rewardCalls)
+	assert.True(s.due)
+	assert.False(s.rewarded)
+	assert.Equal(0, s.attempts)
+
+	gasPrice = big.NewInt(100)
+	s.attempt()
+	assert.Equal(1, client.rewardCalls)
+	assert.True(s.rewarded)
+
+	// Test gas price errors
+	client.round = big.NewInt(11)
+	s.cfg.SuggestGasPrice = func(context.Context) (*big.Int, error) { return nil, errors.New("SuggestGasPrice error") }
+	s.attempt()
+	assert.Equal(1, client.rewardCalls)
+	assert.Equal(1, s.attempts)
+	assert.EqualError(s.lastErr, "SuggestGasPrice error")
+}
+
+func TestRewardService_Missed(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	missed := make(chan *RewardMissed, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.Nil(err)
+		var m RewardMissed
+		require.Nil(json.Unmarshal(body, &m))
+		missed <- &m
+	}))
+	defer ts.Close()
+
+	client := newStubRewardClient()
+	s := NewRewardService(client, nil, RewardServiceConfig{
+		MissedWebhook: ts.URL,
+		Webhooks:      webhook.NewDispatcher(http.DefaultClient, webhook.DefaultOptions, nil),
+	})
+
+	// Test rounds in which the orchestrator wasn't active aren't missed
+	client.active = false
+	s.attempt()
+	client.round = big.NewInt(11)
+	client.active = true
+	client.rewardErr = errors.New("Reward error")
+	s.attempt()
+	s.attempt()
+	assert.Len(missed, 0)
+
+	// Test the missed round is posted in the next round
+	client.round = big.NewInt(12)
+	client.rewardErr = nil
+	s.attempt()
+	assert.Equal(3, client.rewardCalls)
+
+	select {
+	case m := <-missed:
+		assert.Equal(int64(11), m.Round)
+		assert.Equal(client.Account().Address.Hex(), m.Orchestrator)
+		assert.Equal(2, m.Attempts)
+		assert.Equal("Reward error", m.Error)
+	case <-time.After(time.Second):
+		t.Fatal("missed reward call not posted")
+	}
+
+	// Test rewarded rounds aren't missed
+	client.round = big.NewInt(13)
+	s.attempt()
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(missed, 0)
+}
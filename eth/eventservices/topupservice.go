@@ -0,0 +1,355 @@
+package eventservices
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/eth"
+	"github.com/livepeer/go-livepeer/monitor"
+	"github.com/livepeer/go-livepeer/pm"
+	"github.com/livepeer/go-livepeer/webhook"
+)
+
+var (
+	ErrTopUpServiceStarted = fmt.Errorf("top-up service already started")
+	ErrTopUpServiceStopped = fmt.Errorf("top-up service already stopped")
+)
+
+// Events of the alerts of a TopUpService
+const (
+	TopUpAlertFunded              = "funded"
+	TopUpAlertDailyCapReached     = "dailyCapReached"
+	TopUpAlertInsufficientBalance = "insufficientBalance"
+	TopUpAlertFailed              = "failed"
+)
+
+// topUpSpendWindow is the period over which the top-ups are capped by the daily cap
+var topUpSpendWindow = 24 * time.Hour
+
+// topUpAlertInterval is the minimum interval between the alerts of the same event other than
+// TopUpAlertFunded, so that a top-up that can't be made is not alerted at every check
+var topUpAlertInterval = time.Hour
+
+// SenderEventsSubscriber subscribes to the TicketBroker events of senders
+type SenderEventsSubscriber interface {
+	SubscribeSenderEvents(sink chan<- *pm.SenderEvent) event.Subscription
+}
+
+// TopUpServiceConfig configures a TopUpService. The deposit or the reserve is topped up to its
+// target once it falls below its min, and is not topped up if its min is nil
+type TopUpServiceConfig struct {
+	MinDeposit    *big.Int
+	TargetDeposit *big.Int
+	MinReserve    *big.Int
+	TargetReserve *big.Int
+	// DailyCap caps the amount spent on top-ups in the last 24 hours, no cap if nil
+	DailyCap *big.Int
+	// CheckInterval is the interval at which the deposit and the reserve are checked, in
+	// addition to the checks after the TicketBroker events of the sender
+	CheckInterval time.Duration
+	// AlertWebhook is the URL that the alerts are posted to, if set
+	AlertWebhook string
+	// Webhooks posts the alerts
+	Webhooks *webhook.Dispatcher
+}
+
+// TopUpAlert is the payload of the webhook of the alerts of a TopUpService
+type TopUpAlert struct {
+	Event   string `json:"event"`
+	Sender  string `json:"sender"`
+	Deposit string `json:"deposit"`
+	Reserve string `json:"reserve"`
+	// Amount is the amount funded, or that could not be funded
+	Amount string `json:"amount,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// topUp is an amount spent on a top-up
+type topUp struct {
+	amount *big.Int
+	time   time.Time
+}
+
+// TopUpService tops up the deposit and the reserve of the broadcaster from its wallet when they
+// fall below their min, e.g. after its tickets are redeemed, so that the broadcaster keeps
+// paying for its streams
+type TopUpService struct {
+	client       eth.LivepeerEthClient
+	events       SenderEventsSubscriber
+	cfg          TopUpServiceConfig
+	working      bool
+	cancelWorker context.CancelFunc
+
+	mu         sync.Mutex
+	topUps     []*topUp
+	lastAlerts map[string]time.Time
+}
+
+// NewTopUpService creates a TopUpService for the account of client, which checks the deposit
+// and the reserve after the TicketBroker events notified by events
+func NewTopUpService(client eth.LivepeerEthClient, events SenderEventsSubscriber, cfg TopUpServiceConfig) *TopUpService {
+	return &TopUpService{
+		client:     client,
+		events:     events,
+		cfg:        cfg,
+		lastAlerts: make(map[string]time.Time),
+	}
+}
+
+func (s *TopUpService) Start(ctx context.Context) error {
+	if s.working {
+		return ErrTopUpServiceStarted
+	}
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	s.cancelWorker = cancel
+
+	senderEvents := make(chan *pm.SenderEvent, 10)
+	sub := s.events.SubscribeSenderEvents(senderEvents)
+	ticker := time.NewTicker(s.cfg.CheckInterval)
+
+	// The checks are coalesced and run apart from the events, so that a top-up waiting for its
+	// transaction to be mined doesn't hold up the sender events feed
+	checks := make(chan struct{}, 1)
+	trigger := func() {
+		select {
+		case checks <- struct{}{}:
+		default:
+		}
+	}
+	trigger()
+
+	go func(ctx context.Context) {
+		defer monitor.RecoverAndReport()
+		for {
+			select {
+			case <-checks:
+				s.check()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}(cancelCtx)
+
+	sender := s.client.Account().Address
+	go func(ctx context.Context) {
+		defer monitor.RecoverAndReport()
+		defer sub.Unsubscribe()
+		defer ticker.Stop()
+
+		for {
+			select {
+			case ev := <-senderEvents:
+				if ev.Sender == sender {
+					trigger()
+				}
+			case <-ticker.C:
+				trigger()
+			case err := <-sub.Err():
+				if err != nil {
+					glog.Errorf("Sender events subscription error err=%v", err)
+				}
+			case <-ctx.Done():
+				glog.V(5).Infof("Top-up service done")
+				return
+			}
+		}
+	}(cancelCtx)
+
+	s.working = true
+
+	return nil
+}
+
+func (s *TopUpService) Stop() error {
+	if !s.working {
+		return ErrTopUpServiceStopped
+	}
+
+	s.cancelWorker()
+	s.working = false
+
+	return nil
+}
+
+func (s *TopUpService) IsWorking() bool {
+	return s.working
+}
+
+func (s *TopUpService) check() {
+	if err := s.tryTopUp(); err != nil {
+		glog.Errorf("Error trying to top up deposit and reserve: %v", err)
+	}
+}
+
+func (s *TopUpService) tryTopUp() error {
+	sender := s.client.Account().Address
+
+	// The info is read from the TicketBroker rather than from the cache of the sender watcher,
+	// which is only updated once the events of the previous top-up are seen
+	info, err := s.client.GetSenderInfo(sender)
+	if err != nil {
+		return err
+	}
+
+	// Noop while the sender is unlocking its deposit and reserve to withdraw them
+	if info.WithdrawRound != nil && info.WithdrawRound.Sign() > 0 {
+		return nil
+	}
+
+	deposit := missingFunds(s.cfg.MinDeposit, s.cfg.TargetDeposit, info.Deposit)
+	reserve := big.NewInt(0)
+	if info.Reserve != nil {
+		reserve = missingFunds(s.cfg.MinReserve, s.cfg.TargetReserve, info.Reserve.FundsRemaining)
+	}
+	total := new(big.Int).Add(deposit, reserve)
+	if total.Sign() == 0 {
+		return nil
+	}
+
+	if s.cfg.DailyCap != nil {
+		remaining := new(big.Int).Sub(s.cfg.DailyCap, s.spent())
+		if remaining.Cmp(total) < 0 {
+			s.alert(TopUpAlertDailyCapReached, info, total, nil)
+			return fmt.Errorf("top-up of %v exceeds the remaining daily cap of %v", eth.FormatUnits(total, "ETH"), eth.FormatUnits(remaining, "ETH"))
+		}
+	}
+
+	balance, err := s.balance()
+	if err != nil {
+		return err
+	}
+	if balance.Cmp(total) < 0 {
+		err := fmt.Errorf("wallet balance of %v is lower than the top-up of %v", eth.FormatUnits(balance, "ETH"), eth.FormatUnits(total, "ETH"))
+		s.alert(TopUpAlertInsufficientBalance, info, total, err)
+		return err
+	}
+
+	glog.Infof("Topping up deposit with %v and reserve with %v", eth.FormatUnits(deposit, "ETH"), eth.FormatUnits(reserve, "ETH"))
+
+	if err := s.fund(deposit, reserve); err != nil {
+		s.alert(TopUpAlertFailed, info, total, err)
+		return err
+	}
+
+	s.mu.Lock()
+	s.topUps = append(s.topUps, &topUp{amount: total, time: time.Now()})
+	s.mu.Unlock()
+
+	s.alert(TopUpAlertFunded, info, total, nil)
+
+	return nil
+}
+
+func (s *TopUpService) fund(deposit, reserve *big.Int) error {
+	var (
+		tx  *types.Transaction
+		err error
+	)
+	switch {
+	case deposit.Sign() > 0 && reserve.Sign() > 0:
+		tx, err = s.client.FundDepositAndReserve(deposit, reserve)
+	case deposit.Sign() > 0:
+		tx, err = s.client.FundDeposit(deposit)
+	default:
+		tx, err = s.client.FundReserve(reserve)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.client.CheckTx(tx)
+}
+
+func (s *TopUpService) balance() (*big.Int, error) {
+	backend, err := s.client.Backend()
+	if err != nil {
+		return nil, err
+	}
+
+	return backend.BalanceAt(context.Background(), s.client.Account().Address, nil)
+}
+
+// spent returns the amount spent on top-ups in the spend window, and drops the older top-ups
+func (s *TopUpService) spent() *big.Int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	spent := big.NewInt(0)
+	var topUps []*topUp
+	for _, t := range s.topUps {
+		if time.Since(t.time) < topUpSpendWindow {
+			spent.Add(spent, t.amount)
+			topUps = append(topUps, t)
+		}
+	}
+	s.topUps = topUps
+
+	return spent
+}
+
+// alert logs an alert and posts it to the alert webhook
+func (s *TopUpService) alert(ev string, info *pm.SenderInfo, amount *big.Int, err error) {
+	if ev != TopUpAlertFunded {
+		s.mu.Lock()
+		last, ok := s.lastAlerts[ev]
+		if ok && time.Since(last) < topUpAlertInterval {
+			s.mu.Unlock()
+			return
+		}
+		s.lastAlerts[ev] = time.Now()
+		s.mu.Unlock()
+
+		glog.Errorf("Top-up alert event=%v amount=%v err=%v", ev, eth.FormatUnits(amount, "ETH"), err)
+	}
+
+	if s.cfg.AlertWebhook == "" || s.cfg.Webhooks == nil {
+		return
+	}
+
+	a := &TopUpAlert{
+		Event:   ev,
+		Sender:  s.client.Account().Address.Hex(),
+		Deposit: info.Deposit.String(),
+		Amount:  amount.String(),
+	}
+	if info.Reserve != nil {
+		a.Reserve = info.Reserve.FundsRemaining.String()
+	}
+	if err != nil {
+		a.Error = err.Error()
+	}
+	body, err := json.Marshal(a)
+	if err != nil {
+		glog.Errorf("Error encoding top-up alert err=%v", err)
+		return
+	}
+
+	go func() {
+		resp, err := s.cfg.Webhooks.Post("topUpAlert", s.cfg.AlertWebhook, nil, body)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+}
+
+// missingFunds returns the amount that tops up funds to target if they are below min, or 0
+func missingFunds(min, target, funds *big.Int) *big.Int {
+	if min == nil || funds == nil || funds.Cmp(min) >= 0 {
+		return big.NewInt(0)
+	}
+
+	missing := new(big.Int).Sub(target, funds)
+	if missing.Sign() < 0 {
+		return big.NewInt(0)
+	}
+
+	return missing
+}
@@ -0,0 +1,333 @@
+package eventservices
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/livepeer/go-livepeer/eth"
+	"github.com/livepeer/go-livepeer/pm"
+	"github.com/livepeer/go-livepeer/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubTopUpBackend struct {
+	eth.Backend
+	balance *big.Int
+}
+
+func (b *stubTopUpBackend) BalanceAt(ctx context.Context, addr ethcommon.Address, blockNumber *big.Int) (*big.Int, error) {
+	return b.balance, nil
+}
+
+type stubTopUpClient struct {
+	*eth.StubClient
+
+	info    *pm.SenderInfo
+	backend *stubTopUpBackend
+	fundErr error
+
+	infoCalls int32
+	// block blocks GetSenderInfo until it is closed, if set
+	block chan struct{}
+
+	depositFunded *big.Int
+	reserveFunded *big.Int
+	fundCalls     int
+}
+
+func newStubTopUpClient() *stubTopUpClient {
+	return &stubTopUpClient{
+		StubClient: &eth.StubClient{TranscoderAddress: pm.RandAddress()},
+		info: &pm.SenderInfo{
+			Deposit:       big.NewInt(100),
+			WithdrawRound: big.NewInt(0),
+			Reserve:       &pm.ReserveInfo{FundsRemaining: big.NewInt(100), ClaimedInCurrentRound: big.NewInt(0)},
+		},
+		backend:       &stubTopUpBackend{balance: big.NewInt(1000)},
+		depositFunded: big.NewInt(0),
+		reserveFunded: big.NewInt(0),
+	}
+}
+
+func (c *stubTopUpClient) GetSenderInfo(addr ethcommon.Address) (*pm.SenderInfo, error) {
+	atomic.AddInt32(&c.infoCalls, 1)
+	if c.block != nil {
+		<-c.block
+	}
+	return c.info, nil
+}
+
+func (c *stubTopUpClient) Backend() (eth.Backend, error) { return c.backend, nil }
+
+func (c *stubTopUpClient) FundDepositAndReserve(deposit, reserve *big.Int) (*types.Transaction, error) {
+	if _, err := c.FundDeposit(deposit); err != nil {
+		return nil, err
+	}
+	c.fundCalls--
+	return c.FundReserve(reserve)
+}
+
+func (c *stubTopUpClient) FundDeposit(amount *big.Int) (*types.Transaction, error) {
+	c.fundCalls++
+	if c.fundErr != nil {
+		return nil, c.fundErr
+	}
+	c.depositFunded.Add(c.depositFunded, amount)
+	// The info is replaced like the info read from the TicketBroker after a top-up
+	info := *c.info
+	info.Deposit = new(big.Int).Add(c.info.Deposit, amount)
+	c.info = &info
+	return types.NewTransaction(0, pm.RandAddress(), amount, 0, big.NewInt(0), nil), nil
+}
+
+func (c *stubTopUpClient) FundReserve(amount *big.Int) (*types.Transaction, error) {
+	c.fundCalls++
+	if c.fundErr != nil {
+		return nil, c.fundErr
+	}
+	c.reserveFunded.Add(c.reserveFunded, amount)
+	info := *c.info
+	info.Reserve = &pm.ReserveInfo{
+		FundsRemaining:        new(big.Int).Add(c.info.Reserve.FundsRemaining, amount),
+		ClaimedInCurrentRound: c.info.Reserve.ClaimedInCurrentRound,
+	}
+	c.info = &info
+	return types.NewTransaction(0, pm.RandAddress(), amount, 0, big.NewInt(0), nil), nil
+}
+
+func (c *stubTopUpClient) CheckTx(tx *types.Transaction) error { return nil }
+
+func TestTopUpService_TryTopUp(t *testing.T) {
+	assert := assert.New(t)
+
+	client := newStubTopUpClient()
+	s := NewTopUpService(client, nil, TopUpServiceConfig{
+		MinDeposit:    big.NewInt(50),
+		TargetDeposit: big.NewInt(200),
+		MinReserve:    big.NewInt(50),
+		TargetReserve: big.NewInt(100),
+	})
+
+	// Test funds above their min aren't topped up
+	assert.Nil(s.tryTopUp())
+	assert.Equal(0, client.fundCalls)
+
+	// Test the deposit is topped up to its target
+	client.info.Deposit = big.NewInt(40)
+	assert.Nil(s.tryTopUp())
+	assert.Equal(1, client.fundCalls)
+	assert.Equal(big.NewInt(160), client.depositFunded)
+	assert.Equal(big.NewInt(0), client.reserveFunded)
+
+	// Test the deposit and the reserve are topped up together
+	client.info.Deposit = big.NewInt(0)
+	client.info.Reserve.FundsRemaining = big.NewInt(10)
+	assert.Nil(s.tryTopUp())
+	assert.Equal(2, client.fundCalls)
+	assert.Equal(big.NewInt(360), client.depositFunded)
+	assert.Equal(big.NewInt(90), client.reserveFunded)
+
+	// Test funds aren't topped up while the sender is unlocking
+	client.info.Deposit = big.NewInt(0)
+	client.info.WithdrawRound = big.NewInt(5)
+	assert.Nil(s.tryTopUp())
+	assert.Equal(2, client.fundCalls)
+
+	// Test the reserve isn't topped up without a min
+	client.info.WithdrawRound = big.NewInt(0)
+	client.info.Deposit = big.NewInt(200)
+	client.info.Reserve.FundsRemaining = big.NewInt(0)
+	s.cfg.MinReserve = nil
+	assert.Nil(s.tryTopUp())
+	assert.Equal(2, client.fundCalls)
+
+	// Test the wallet balance must cover the top-up
+	client.info.Deposit = big.NewInt(0)
+	client.backend.balance = big.NewInt(100)
+	assert.EqualError(s.tryTopUp(), "wallet balance of 100 WEI is lower than the top-up of 200 WEI")
+	assert.Equal(2, client.fundCalls)
+
+	// Test funding errors
+	client.backend.balance = big.NewInt(1000)
+	client.fundErr = errors.New("FundDeposit error")
+	assert.EqualError(s.tryTopUp(), "FundDeposit error")
+	assert.Equal(3, client.fundCalls)
+}
+
+func TestTopUpService_DailyCap(t *testing.T) {
+	assert := assert.New(t)
+
+	client := newStubTopUpClient()
+	s := NewTopUpService(client, nil, TopUpServiceConfig{
+		MinDeposit:    big.NewInt(50),
+		TargetDeposit: big.NewInt(100),
+		DailyCap:      big.NewInt(150),
+	})
+
+	client.info.Deposit = big.NewInt(0)
+	assert.Nil(s.tryTopUp())
+	assert.Equal(big.NewInt(100), s.spent())
+
+	// Test top-ups over the remaining daily cap aren't made
+	client.info.Deposit = big.NewInt(0)
+	err := s.tryTopUp()
+	assert.Contains(err.Error(), "exceeds the remaining daily cap")
+	assert.Equal(1, client.fundCalls)
+
+	// Test the top-ups older than a day don't count towards the cap
+	s.topUps[0].time = time.Now().Add(-25 * time.Hour)
+	assert.Nil(s.tryTopUp())
+	assert.Equal(2, client.fundCalls)
+	assert.Len(s.topUps, 1)
+}
+
+func TestTopUpService_Alerts(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	alerts := make(chan *TopUpAlert, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.Nil(err)
+		var a TopUpAlert
+		require.Nil(json.Unmarshal(body, &a))
+		alerts <- &a
+	}))
+	defer ts.Close()
+
+	client := newStubTopUpClient()
+	s := NewTopUpService(client, nil, TopUpServiceConfig{
+		MinDeposit:    big.NewInt(50),
+		TargetDeposit: big.NewInt(100),
+		DailyCap:      big.NewInt(150),
+		AlertWebhook:  ts.URL,
+		Webhooks:      webhook.NewDispatcher(http.DefaultClient, webhook.DefaultOptions, nil),
+	})
+
+	receive := func() *TopUpAlert {
+		select {
+		case a := <-alerts:
+			return a
+		case <-time.After(time.Second):
+			t.Fatal("top-up alert not posted")
+		}
+		return nil
+	}
+
+	// Test top-ups are posted
+	client.info.Deposit = big.NewInt(10)
+	assert.Nil(s.tryTopUp())
+	a := receive()
+	assert.Equal(TopUpAlertFunded, a.Event)
+	assert.Equal(client.Account().Address.Hex(), a.Sender)
+	assert.Equal("10", a.Deposit)
+	assert.Equal("100", a.Reserve)
+	assert.Equal("90", a.Amount)
+
+	// Test reaching the daily cap is posted once per alert interval
+	client.info.Deposit = big.NewInt(0)
+	assert.NotNil(s.tryTopUp())
+	a = receive()
+	assert.Equal(TopUpAlertDailyCapReached, a.Event)
+	assert.Equal("100", a.Amount)
+
+	assert.NotNil(s.tryTopUp())
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(alerts, 0)
+
+	// Test an insufficient balance is posted with its error
+	s.topUps = nil
+	client.backend.balance = big.NewInt(0)
+	assert.NotNil(s.tryTopUp())
+	a = receive()
+	assert.Equal(TopUpAlertInsufficientBalance, a.Event)
+	assert.Contains(a.Error, "wallet balance")
+}
+
+func TestTopUpService_SenderEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	client := newStubTopUpClient()
+	var feed event.Feed
+	s := NewTopUpService(client, &stubSenderEventsSubscriber{&feed}, TopUpServiceConfig{
+		MinDeposit:    big.NewInt(50),
+		TargetDeposit: big.NewInt(100),
+		CheckInterval: time.Hour,
+	})
+
+	// Test the funds are checked at startup
+	assert.Nil(s.Start(context.Background()))
+	defer s.Stop()
+	assert.Equal(ErrTopUpServiceStarted, s.Start(context.Background()))
+	assert.True(s.IsWorking())
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(int32(1), atomic.LoadInt32(&client.infoCalls))
+
+	// Test the events of other senders are ignored
+	feed.Send(&pm.SenderEvent{Type: pm.SenderEventWinningTicketTransfer, Sender: pm.RandAddress()})
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(int32(1), atomic.LoadInt32(&client.infoCalls))
+
+	// Test the funds are checked after the events of the sender
+	feed.Send(&pm.SenderEvent{Type: pm.SenderEventWinningTicketTransfer, Sender: client.Account().Address})
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(int32(2), atomic.LoadInt32(&client.infoCalls))
+
+	assert.Nil(s.Stop())
+	assert.Equal(ErrTopUpServiceStopped, s.Stop())
+}
+
+func TestTopUpService_CoalescedChecks(t *testing.T) {
+	assert := assert.New(t)
+
+	client := newStubTopUpClient()
+	client.block = make(chan struct{})
+	var feed event.Feed
+	s := NewTopUpService(client, &stubSenderEventsSubscriber{&feed}, TopUpServiceConfig{
+		MinDeposit:    big.NewInt(50),
+		TargetDeposit: big.NewInt(100),
+		CheckInterval: time.Hour,
+	})
+	assert.Nil(s.Start(context.Background()))
+	defer s.Stop()
+
+	// Test the events are received while a check is running
+	sent := make(chan struct{})
+	go func() {
+		for i := 0; i < 50; i++ {
+			feed.Send(&pm.SenderEvent{Type: pm.SenderEventWinningTicketTransfer, Sender: client.Account().Address})
+		}
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("sender events blocked by a running check")
+	}
+
+	// Test the events received during a check are coalesced into a single check
+	time.Sleep(20 * time.Millisecond)
+	close(client.block)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(int32(2), atomic.LoadInt32(&client.infoCalls))
+}
+
+type stubSenderEventsSubscriber struct {
+	feed *event.Feed
+}
+
+func (s *stubSenderEventsSubscriber) SubscribeSenderEvents(sink chan<- *pm.SenderEvent) event.Subscription {
+	return s.feed.Subscribe(sink)
+}
@@ -0,0 +1,128 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/common"
+)
+
+// RPCCaller calls the methods of the JSON-RPC API of an Ethereum node
+type RPCCaller interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// FeeOracle suggests the gas price of legacy transactions from the base fee of the latest
+// block plus a tip. The go-ethereum version of the node has no dynamic fee transactions, so
+// transactions have a single gas price that they pay in full: the gas price is the max base
+// fee of the next block plus the tip, capped by maxGasPrice
+type FeeOracle struct {
+	rpc RPCCaller
+	// maxGasPrice caps the gas price if it is not nil
+	maxGasPrice *big.Int
+	// tip is added to the base fee if it is not nil, otherwise the tip is estimated by the
+	// Ethereum node
+	tip *big.Int
+}
+
+// NewFeeOracle creates a FeeOracle using the node of rpc, with a gas price capped by
+// maxGasPrice and a fixed tip, either of which is disabled if nil
+func NewFeeOracle(rpc RPCCaller, maxGasPrice, tip *big.Int) *FeeOracle {
+	return &FeeOracle{
+		rpc:         rpc,
+		maxGasPrice: maxGasPrice,
+		tip:         tip,
+	}
+}
+
+// SuggestGasPrice returns the gas price of the transactions to send. Before the London fork
+// blocks have no base fee, and the gas price suggested by the node is used. It fails if
+// maxGasPrice is below the base fee, as legacy transactions priced below the base fee can't
+// be included in a block
+func (o *FeeOracle) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	baseFee, err := o.BaseFee(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var gasPrice *big.Int
+	if baseFee == nil {
+		if gasPrice, err = o.nodeGasPrice(ctx); err != nil {
+			return nil, err
+		}
+	} else {
+		if o.maxGasPrice != nil && o.maxGasPrice.Cmp(baseFee) < 0 {
+			return nil, fmt.Errorf("maxGasPrice=%v is below the base fee=%v, transactions can't be mined", o.maxGasPrice, baseFee)
+		}
+
+		tip, err := o.Tip(ctx, baseFee)
+		if err != nil {
+			return nil, err
+		}
+
+		// The base fee increases by up to 1/8 per block
+		gasPrice = new(big.Int).Add(baseFee, new(big.Int).Div(baseFee, big.NewInt(8)))
+		gasPrice.Add(gasPrice, tip)
+	}
+
+	if o.maxGasPrice != nil && gasPrice.Cmp(o.maxGasPrice) > 0 {
+		glog.V(common.DEBUG).Infof("Capping gas price=%v to maxGasPrice=%v", gasPrice, o.maxGasPrice)
+		gasPrice = new(big.Int).Set(o.maxGasPrice)
+	}
+
+	return gasPrice, nil
+}
+
+// BaseFee returns the base fee of the latest block, or nil if it has no base fee
+func (o *FeeOracle) BaseFee(ctx context.Context) (*big.Int, error) {
+	var head struct {
+		BaseFee *hexutil.Big `json:"baseFeePerGas"`
+	}
+	if err := o.rpc.CallContext(ctx, &head, "eth_getBlockByNumber", "latest", false); err != nil {
+		return nil, err
+	}
+	if head.BaseFee == nil {
+		return nil, nil
+	}
+
+	return head.BaseFee.ToInt(), nil
+}
+
+// Tip returns the tip of the transactions to send, which is estimated by the node unless it
+// is configured. The tip of nodes that don't support eth_maxPriorityFeePerGas is the
+// difference between the gas price they suggest and the base fee
+func (o *FeeOracle) Tip(ctx context.Context, baseFee *big.Int) (*big.Int, error) {
+	if o.tip != nil {
+		return o.tip, nil
+	}
+
+	var tip hexutil.Big
+	err := o.rpc.CallContext(ctx, &tip, "eth_maxPriorityFeePerGas")
+	if err == nil {
+		return tip.ToInt(), nil
+	}
+
+	glog.V(common.DEBUG).Infof("Estimating tip from the gas price, eth_maxPriorityFeePerGas error: %v", err)
+
+	gasPrice, err := o.nodeGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if gasPrice.Cmp(baseFee) <= 0 {
+		return big.NewInt(0), nil
+	}
+
+	return gasPrice.Sub(gasPrice, baseFee), nil
+}
+
+func (o *FeeOracle) nodeGasPrice(ctx context.Context) (*big.Int, error) {
+	var gasPrice hexutil.Big
+	if err := o.rpc.CallContext(ctx, &gasPrice, "eth_gasPrice"); err != nil {
+		return nil, err
+	}
+
+	return gasPrice.ToInt(), nil
+}
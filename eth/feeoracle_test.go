@@ -0,0 +1,99 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubRPCCaller struct {
+	results map[string]string
+	errs    map[string]error
+	calls   map[string]int
+}
+
+func newStubRPCCaller() *stubRPCCaller {
+	return &stubRPCCaller{
+		results: make(map[string]string),
+		errs:    make(map[string]error),
+		calls:   make(map[string]int),
+	}
+}
+
+func (s *stubRPCCaller) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	s.calls[method]++
+	if err := s.errs[method]; err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(s.results[method]), result)
+}
+
+func TestFeeOracle_SuggestGasPrice(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	rpc := newStubRPCCaller()
+	rpc.results["eth_getBlockByNumber"] = `{"number":"0x1","baseFeePerGas":"0x320"}`
+	rpc.results["eth_maxPriorityFeePerGas"] = `"0x64"`
+	rpc.results["eth_gasPrice"] = `"0x3e8"`
+	o := NewFeeOracle(rpc, nil, nil)
+
+	// gasPrice = baseFee * 9/8 + tip = 800 + 100 + 100
+	gasPrice, err := o.SuggestGasPrice(context.Background())
+	require.Nil(err)
+	assert.Equal(big.NewInt(1000), gasPrice)
+	assert.Zero(rpc.calls["eth_gasPrice"])
+
+	// Test tip estimated from the gas price of nodes without eth_maxPriorityFeePerGas
+	rpc.errs["eth_maxPriorityFeePerGas"] = errors.New("the method eth_maxPriorityFeePerGas does not exist/is not available")
+	gasPrice, err = o.SuggestGasPrice(context.Background())
+	require.Nil(err)
+	assert.Equal(big.NewInt(1100), gasPrice)
+
+	rpc.results["eth_gasPrice"] = `"0x1"`
+	gasPrice, err = o.SuggestGasPrice(context.Background())
+	require.Nil(err)
+	assert.Equal(big.NewInt(900), gasPrice)
+
+	// Test configured tip
+	o = NewFeeOracle(rpc, nil, big.NewInt(5))
+	gasPrice, err = o.SuggestGasPrice(context.Background())
+	require.Nil(err)
+	assert.Equal(big.NewInt(905), gasPrice)
+
+	// Test fee cap
+	o = NewFeeOracle(rpc, big.NewInt(850), big.NewInt(5))
+	gasPrice, err = o.SuggestGasPrice(context.Background())
+	require.Nil(err)
+	assert.Equal(big.NewInt(850), gasPrice)
+
+	// Test fee cap below the base fee
+	o = NewFeeOracle(rpc, big.NewInt(799), nil)
+	_, err = o.SuggestGasPrice(context.Background())
+	assert.EqualError(err, "maxGasPrice=799 is below the base fee=800, transactions can't be mined")
+	o = NewFeeOracle(rpc, big.NewInt(850), big.NewInt(5))
+
+	// Test blocks without a base fee
+	rpc.results["eth_getBlockByNumber"] = `{"number":"0x1"}`
+	rpc.results["eth_gasPrice"] = `"0x3e8"`
+	gasPrice, err = o.SuggestGasPrice(context.Background())
+	require.Nil(err)
+	assert.Equal(big.NewInt(850), gasPrice)
+	o = NewFeeOracle(rpc, nil, nil)
+	gasPrice, err = o.SuggestGasPrice(context.Background())
+	require.Nil(err)
+	assert.Equal(big.NewInt(1000), gasPrice)
+
+	// Test errors
+	rpc.errs["eth_gasPrice"] = errors.New("eth_gasPrice error")
+	_, err = o.SuggestGasPrice(context.Background())
+	assert.EqualError(err, "eth_gasPrice error")
+	rpc.errs["eth_getBlockByNumber"] = errors.New("eth_getBlockByNumber error")
+	_, err = o.SuggestGasPrice(context.Background())
+	assert.EqualError(err, "eth_getBlockByNumber error")
+}
@@ -19,9 +19,15 @@ type GasPriceOracle interface {
 }
 
 // GasPriceMonitor polls for gas price updates and updates its
-// own view of the current gas price that can be used by others
+// own view of the current gas price that can be used by others.
+// The gas price is kept between the min and the max gas price, so that
+// e.g. a spike of the gas price doesn't make transactions overpay
 type GasPriceMonitor struct {
 	gpo GasPriceOracle
+	// minGasPrice is the floor of the gas price if it is not nil
+	minGasPrice *big.Int
+	// maxGasPrice is the ceiling of the gas price if it is not nil
+	maxGasPrice *big.Int
 	// The following fields should be protected by `pollingMu`
 	polling         bool
 	pollingInterval time.Duration
@@ -39,10 +45,13 @@ type GasPriceMonitor struct {
 	update chan struct{}
 }
 
-// NewGasPriceMonitor returns a GasPriceMonitor
-func NewGasPriceMonitor(gpo GasPriceOracle, pollingInterval time.Duration) *GasPriceMonitor {
+// NewGasPriceMonitor returns a GasPriceMonitor with a gas price between
+// minGasPrice and maxGasPrice, either of which is disabled if nil
+func NewGasPriceMonitor(gpo GasPriceOracle, pollingInterval time.Duration, minGasPrice, maxGasPrice *big.Int) *GasPriceMonitor {
 	return &GasPriceMonitor{
 		gpo:             gpo,
+		minGasPrice:     minGasPrice,
+		maxGasPrice:     maxGasPrice,
 		pollingInterval: pollingInterval,
 		gasPrice:        big.NewInt(0),
 	}
@@ -56,6 +65,12 @@ func (gpm *GasPriceMonitor) GasPrice() *big.Int {
 	return gpm.gasPrice
 }
 
+// SuggestGasPrice returns the current gas price, so that the transactions
+// sent without a gas price use the gas price of the monitor
+func (gpm *GasPriceMonitor) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return gpm.GasPrice(), nil
+}
+
 // Start starts polling for gas price updates and returns a channel to receive
 // notifications of gas price changes
 func (gpm *GasPriceMonitor) Start(ctx context.Context) (chan struct{}, error) {
@@ -125,6 +140,15 @@ func (gpm *GasPriceMonitor) fetchAndUpdateGasPrice(ctx context.Context) error {
 		return err
 	}
 
+	if gpm.minGasPrice != nil && gasPrice.Cmp(gpm.minGasPrice) < 0 {
+		glog.V(common.DEBUG).Infof("Raising gas price=%v to minGasPrice=%v", gasPrice, gpm.minGasPrice)
+		gasPrice = gpm.minGasPrice
+	}
+	if gpm.maxGasPrice != nil && gasPrice.Cmp(gpm.maxGasPrice) > 0 {
+		glog.V(common.DEBUG).Infof("Capping gas price=%v to maxGasPrice=%v", gasPrice, gpm.maxGasPrice)
+		gasPrice = gpm.maxGasPrice
+	}
+
 	gpm.updateGasPrice(gasPrice)
 
 	if monitor.Enabled {
@@ -58,7 +58,7 @@ func TestStart(t *testing.T) {
 	gasPrice := big.NewInt(777)
 	gpo := newStubGasPriceOracle(gasPrice)
 
-	gpm := NewGasPriceMonitor(gpo, 1*time.Hour)
+	gpm := NewGasPriceMonitor(gpo, 1*time.Hour, nil, nil)
 
 	assert := assert.New(t)
 
@@ -96,7 +96,7 @@ func TestStart_Polling(t *testing.T) {
 	gpo := newStubGasPriceOracle(gasPrice1)
 
 	pollingInterval := 1 * time.Millisecond
-	gpm := NewGasPriceMonitor(gpo, pollingInterval)
+	gpm := NewGasPriceMonitor(gpo, pollingInterval, nil, nil)
 
 	assert := assert.New(t)
 
@@ -138,7 +138,7 @@ func TestStart_Polling_ContextCancel(t *testing.T) {
 	gpo := newStubGasPriceOracle(gasPrice1)
 
 	pollingInterval := 1 * time.Second
-	gpm := NewGasPriceMonitor(gpo, pollingInterval)
+	gpm := NewGasPriceMonitor(gpo, pollingInterval, nil, nil)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	update, err := gpm.Start(ctx)
@@ -161,7 +161,7 @@ func TestStop(t *testing.T) {
 	gpo := newStubGasPriceOracle(gasPrice)
 	gpo.SetGasPrice(gasPrice)
 
-	gpm := NewGasPriceMonitor(gpo, 1*time.Hour)
+	gpm := NewGasPriceMonitor(gpo, 1*time.Hour, nil, nil)
 
 	assert := assert.New(t)
 
@@ -190,3 +190,30 @@ func TestStop(t *testing.T) {
 	_, ok := (<-gpm.update)
 	assert.False(ok)
 }
+
+func TestFetchAndUpdateGasPrice_Bounds(t *testing.T) {
+	assert := assert.New(t)
+
+	gpo := newStubGasPriceOracle(big.NewInt(777))
+	gpm := NewGasPriceMonitor(gpo, 1*time.Hour, big.NewInt(100), big.NewInt(1000))
+
+	// Test gas prices between the bounds
+	assert.Nil(gpm.fetchAndUpdateGasPrice(context.Background()))
+	assert.Equal(big.NewInt(777), gpm.GasPrice())
+
+	// Test the floor
+	gpo.SetGasPrice(big.NewInt(10))
+	assert.Nil(gpm.fetchAndUpdateGasPrice(context.Background()))
+	assert.Equal(big.NewInt(100), gpm.GasPrice())
+
+	// Test the ceiling
+	gpo.SetGasPrice(big.NewInt(5000))
+	assert.Nil(gpm.fetchAndUpdateGasPrice(context.Background()))
+	assert.Equal(big.NewInt(1000), gpm.GasPrice())
+
+	// Test transactions are suggested the current gas price
+	gasPrice, err := gpm.SuggestGasPrice(context.Background())
+	assert.Nil(err)
+	assert.Equal(big.NewInt(1000), gasPrice)
+	assert.Equal(3, gpo.Queries())
+}
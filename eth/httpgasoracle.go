@@ -0,0 +1,111 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/golang/glog"
+)
+
+// DefaultHTTPGasPriceOracleTimeout is the default time to wait for the response of an external
+// gas price oracle
+const DefaultHTTPGasPriceOracleTimeout = 5 * time.Second
+
+// HTTPGasPriceOracle suggests the gas prices of an external oracle, which responds to GET
+// requests with a JSON object whose gasPrice field is the gas price in Wei, as a number, a
+// decimal string or a 0x prefixed hex string. The gas price of the fallback oracle, e.g. the
+// FeeOracle of the Ethereum node, is used if the external oracle fails
+type HTTPGasPriceOracle struct {
+	url      string
+	client   *http.Client
+	fallback GasPriceOracle
+}
+
+// NewHTTPGasPriceOracle creates an HTTPGasPriceOracle for the oracle at url, waiting for its
+// responses for up to timeout, or DefaultHTTPGasPriceOracleTimeout if it is 0
+func NewHTTPGasPriceOracle(url string, timeout time.Duration, fallback GasPriceOracle) *HTTPGasPriceOracle {
+	if timeout <= 0 {
+		timeout = DefaultHTTPGasPriceOracleTimeout
+	}
+
+	return &HTTPGasPriceOracle{
+		url:      url,
+		client:   &http.Client{Timeout: timeout},
+		fallback: fallback,
+	}
+}
+
+// SuggestGasPrice returns the gas price of the external oracle, or of the fallback oracle if
+// the request fails
+func (o *HTTPGasPriceOracle) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	gasPrice, err := o.fetchGasPrice(ctx)
+	if err == nil {
+		return gasPrice, nil
+	}
+
+	if o.fallback == nil {
+		return nil, err
+	}
+
+	glog.Errorf("Error getting gas price from oracle, using the gas price of the Ethereum node url=%v err=%v", o.url, err)
+
+	return o.fallback.SuggestGasPrice(ctx)
+}
+
+func (o *HTTPGasPriceOracle) fetchGasPrice(ctx context.Context) (*big.Int, error) {
+	req, err := http.NewRequest(http.MethodGet, o.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := o.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gas price oracle responded with %v", resp.Status)
+	}
+
+	var res struct {
+		GasPrice json.RawMessage `json:"gasPrice"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+
+	return parseOracleGasPrice(res.GasPrice)
+}
+
+// parseOracleGasPrice parses a gas price in Wei, which is a JSON number or string
+func parseOracleGasPrice(raw json.RawMessage) (*big.Int, error) {
+	value := strings.Trim(string(raw), `"`)
+	if value == "" || value == "null" {
+		return nil, fmt.Errorf("gas price oracle response has no gasPrice")
+	}
+
+	var gasPrice *big.Int
+	if strings.HasPrefix(value, "0x") {
+		var err error
+		if gasPrice, err = hexutil.DecodeBig(value); err != nil {
+			return nil, fmt.Errorf("invalid gas price %v: %v", value, err)
+		}
+	} else {
+		var ok bool
+		if gasPrice, ok = new(big.Int).SetString(value, 10); !ok {
+			return nil, fmt.Errorf("invalid gas price %v", value)
+		}
+	}
+	if gasPrice.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid gas price %v", value)
+	}
+
+	return gasPrice, nil
+}
@@ -0,0 +1,58 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPGasPriceOracle_SuggestGasPrice(t *testing.T) {
+	assert := assert.New(t)
+
+	var body string
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+
+	fallback := newStubGasPriceOracle(big.NewInt(777))
+	o := NewHTTPGasPriceOracle(ts.URL, 0, fallback)
+
+	// Test the gas price formats
+	for _, b := range []string{`{"gasPrice":1000}`, `{"gasPrice":"1000"}`, `{"gasPrice":"0x3e8"}`} {
+		body = b
+		gasPrice, err := o.SuggestGasPrice(context.Background())
+		assert.Nil(err)
+		assert.Equal(big.NewInt(1000), gasPrice)
+	}
+	assert.Equal(0, fallback.Queries())
+
+	// Test invalid responses fall back to the fallback oracle
+	for _, b := range []string{`{"gasPrice":"abc"}`, `{"gasPrice":0}`, `{}`, `not json`} {
+		body = b
+		gasPrice, err := o.SuggestGasPrice(context.Background())
+		assert.Nil(err)
+		assert.Equal(big.NewInt(777), gasPrice)
+	}
+	assert.Equal(4, fallback.Queries())
+
+	// Test errors without a fallback oracle
+	status = http.StatusServiceUnavailable
+	o = NewHTTPGasPriceOracle(ts.URL, 0, nil)
+	_, err := o.SuggestGasPrice(context.Background())
+	assert.EqualError(err, "gas price oracle responded with 503 Service Unavailable")
+
+	// Test errors of the fallback oracle
+	fallback.SetErr(errors.New("SuggestGasPrice error"))
+	o = NewHTTPGasPriceOracle(ts.URL, 0, fallback)
+	_, err = o.SuggestGasPrice(context.Background())
+	assert.EqualError(err, "SuggestGasPrice error")
+}
@@ -0,0 +1,292 @@
+package eth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/pm"
+)
+
+var (
+	// ErrTxQueuedForSigning is returned for the transactions of an OfflineAccountManager, which
+	// are queued to be signed on another machine rather than sent
+	ErrTxQueuedForSigning = pm.ErrTxQueuedForSigning
+	errOfflineSign        = errors.New("messages can't be signed in offline signing mode")
+)
+
+// UnsignedTx is a transaction queued to be signed offline, in the JSON format exchanged with
+// the machine that holds the key of the account
+type UnsignedTx struct {
+	// ID identifies the transaction by its recipient, value and data, so that a transaction
+	// that is sent again while it is queued, e.g. a retried redemption, is queued once
+	ID       string             `json:"id"`
+	ChainID  *big.Int           `json:"chainId"`
+	From     ethcommon.Address  `json:"from"`
+	Nonce    uint64             `json:"nonce"`
+	To       *ethcommon.Address `json:"to"`
+	Value    *big.Int           `json:"value"`
+	Gas      uint64             `json:"gas"`
+	GasPrice *big.Int           `json:"gasPrice"`
+	Data     hexutil.Bytes      `json:"data"`
+	// Method and Inputs describe the contract call of the transaction, if it calls a protocol
+	// contract, so that it can be reviewed before it is signed
+	Method   string    `json:"method,omitempty"`
+	Inputs   string    `json:"inputs,omitempty"`
+	QueuedAt time.Time `json:"queuedAt"`
+}
+
+// Transaction returns the unsigned transaction
+func (u *UnsignedTx) Transaction() *types.Transaction {
+	return newTransaction(u.Nonce, u.To, u.Value, u.Gas, u.GasPrice, u.Data)
+}
+
+// OfflineAccountManager is an AccountManager for an account whose key is never on the host of
+// the node, e.g. for operators whose policy forbids hot keys. Transactions, e.g. redemptions,
+// bonds and rewards, are queued unsigned instead of being sent, to be exported and signed on
+// an air-gapped machine. The signed raw transactions are then sent by the node. Messages
+// can't be signed, so broadcasters and orchestrators need a keystore account
+type OfflineAccountManager struct {
+	account accounts.Account
+	chainID *big.Int
+	abiMap  map[string]*abi.ABI
+
+	mu sync.Mutex
+	// txs are the queued transactions, in the order in which they were queued
+	txs []*UnsignedTx
+}
+
+// NewOfflineAccountManager creates an OfflineAccountManager for the account at accountAddr on
+// the chain chainID
+func NewOfflineAccountManager(accountAddr ethcommon.Address, chainID *big.Int) (*OfflineAccountManager, error) {
+	if (accountAddr == ethcommon.Address{}) {
+		return nil, errors.New("the address of the account is required in offline signing mode")
+	}
+
+	abiMap, err := makeABIMap()
+	if err != nil {
+		return nil, err
+	}
+
+	glog.Infof("Using Ethereum account: %v in offline signing mode", accountAddr.Hex())
+
+	return &OfflineAccountManager{
+		account: accounts.Account{Address: accountAddr},
+		chainID: chainID,
+		abiMap:  abiMap,
+	}, nil
+}
+
+// Unlock is a no-op, since the key of the account is not on the node
+func (am *OfflineAccountManager) Unlock(passphrase string) error {
+	return nil
+}
+
+func (am *OfflineAccountManager) Lock() error {
+	return nil
+}
+
+// CreateTransactOpts creates transact opts whose transactions are queued
+func (am *OfflineAccountManager) CreateTransactOpts(gasLimit uint64, gasPrice *big.Int) (*bind.TransactOpts, error) {
+	return &bind.TransactOpts{
+		From:     am.account.Address,
+		GasLimit: gasLimit,
+		GasPrice: gasPrice,
+		Signer: func(signer types.Signer, address ethcommon.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != am.account.Address {
+				return nil, errors.New("not authorized to sign this account")
+			}
+
+			return am.SignTx(tx)
+		},
+	}, nil
+}
+
+// SignTx queues the transaction to be signed offline and returns ErrTxQueuedForSigning
+func (am *OfflineAccountManager) SignTx(tx *types.Transaction) (*types.Transaction, error) {
+	u := &UnsignedTx{
+		ID:       unsignedTxID(tx),
+		ChainID:  am.chainID,
+		From:     am.account.Address,
+		To:       tx.To(),
+		Value:    tx.Value(),
+		Gas:      tx.Gas(),
+		GasPrice: tx.GasPrice(),
+		Data:     tx.Data(),
+		QueuedAt: time.Now(),
+	}
+	if txLog, err := decodeTxLog(am.abiMap, tx.Data()); err == nil {
+		u.Method = txLog.method
+		u.Inputs = txLog.inputs
+	}
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	for _, queued := range am.txs {
+		if queued.ID == u.ID {
+			return nil, ErrTxQueuedForSigning
+		}
+	}
+	am.txs = append(am.txs, u)
+
+	glog.Infof("Queued transaction for offline signing id=%v method=%v", u.ID, u.Method)
+
+	return nil, ErrTxQueuedForSigning
+}
+
+// Sign is not supported, since the key of the account is not on the node
+func (am *OfflineAccountManager) Sign(msg []byte) ([]byte, error) {
+	return nil, errOfflineSign
+}
+
+// SignTypedData is not supported, since the key of the account is not on the node
+func (am *OfflineAccountManager) SignTypedData(typedData *pm.TypedData) ([]byte, error) {
+	return nil, errOfflineSign
+}
+
+func (am *OfflineAccountManager) Account() accounts.Account {
+	return am.account
+}
+
+// UnsignedTxs returns the queued transactions with consecutive nonces from nonce, which
+// should be the pending nonce of the account, in the order in which they were queued
+func (am *OfflineAccountManager) UnsignedTxs(nonce uint64) []*UnsignedTx {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	txs := make([]*UnsignedTx, len(am.txs))
+	for i, u := range am.txs {
+		cp := *u
+		cp.Nonce = nonce + uint64(i)
+		txs[i] = &cp
+	}
+	return txs
+}
+
+// Discard removes a queued transaction, and returns false if there is none with the id. The
+// transactions queued after it are renumbered, so the transactions that were signed with
+// their previous nonces are refused by Signed and must be exported and signed again
+func (am *OfflineAccountManager) Discard(id string) bool {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	for i, u := range am.txs {
+		if u.ID == id {
+			am.txs = append(am.txs[:i], am.txs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Signed checks that a signed transaction is the first queued transaction, signed by the
+// account with nonce, which should be the pending nonce of the account, and removes the
+// transaction from the queue. Transactions must be sent in the order of the queue, so that
+// they don't leave a gap in the nonces of the account, and a transaction signed with a nonce
+// that it no longer has, e.g. after a transaction was discarded, is refused. The gas and the
+// gas price of the transaction may differ from the queued transaction, e.g. if they were
+// raised before it was signed
+func (am *OfflineAccountManager) Signed(tx *types.Transaction, nonce uint64) (*UnsignedTx, error) {
+	from, err := types.Sender(types.NewEIP155Signer(am.chainID), tx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction signature: %v", err)
+	}
+	if from != am.account.Address {
+		return nil, fmt.Errorf("transaction is signed by %v instead of %v", from.Hex(), am.account.Address.Hex())
+	}
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	id := unsignedTxID(tx)
+	for i, u := range am.txs {
+		if u.ID != id {
+			continue
+		}
+		if i > 0 {
+			return nil, fmt.Errorf("transaction %v must be sent after the queued transaction %v", u.ID, am.txs[0].ID)
+		}
+		if tx.Nonce() != nonce {
+			return nil, fmt.Errorf("transaction %v is signed with nonce %v instead of %v, export and sign it again", u.ID, tx.Nonce(), nonce)
+		}
+		am.txs = am.txs[1:]
+		return u, nil
+	}
+	return nil, fmt.Errorf("transaction %v is not a queued transaction", tx.Hash().Hex())
+}
+
+// Requeue queues a transaction removed by Signed again, first, e.g. if it couldn't be sent
+func (am *OfflineAccountManager) Requeue(u *UnsignedTx) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	for _, queued := range am.txs {
+		if queued.ID == u.ID {
+			return
+		}
+	}
+	am.txs = append([]*UnsignedTx{u}, am.txs...)
+}
+
+func unsignedTxID(tx *types.Transaction) string {
+	var to []byte
+	if tx.To() != nil {
+		to = tx.To().Bytes()
+	}
+	hash := crypto.Keccak256(to, ethcommon.LeftPadBytes(tx.Value().Bytes(), 32), tx.Data())
+	return hexutil.Encode(hash[:8])
+}
+
+// SignUnsignedTx signs a transaction exported by an OfflineAccountManager with the account of
+// the keystore at keystoreDir, e.g. on an air-gapped machine
+func SignUnsignedTx(keystoreDir, passphrase string, u *UnsignedTx) (*types.Transaction, error) {
+	if u.ChainID == nil || u.ChainID.Sign() <= 0 {
+		return nil, fmt.Errorf("transaction %v has no chain ID", u.ID)
+	}
+
+	keyStore := keystore.NewKeyStore(keystoreDir, keystore.StandardScryptN, keystore.StandardScryptP)
+	acct, err := keyStore.Find(accounts.Account{Address: u.From})
+	if err != nil {
+		return nil, fmt.Errorf("account %v of transaction %v: %v", u.From.Hex(), u.ID, err)
+	}
+
+	return keyStore.SignTxWithPassphrase(acct, passphrase, u.Transaction(), u.ChainID)
+}
+
+// EncodeRawTx returns the hex encoded RLP of a signed transaction, as sent with
+// eth_sendRawTransaction
+func EncodeRawTx(tx *types.Transaction) (string, error) {
+	raw, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return "", err
+	}
+	return hexutil.Encode(raw), nil
+}
+
+// DecodeRawTx decodes a signed transaction encoded by EncodeRawTx
+func DecodeRawTx(rawTx string) (*types.Transaction, error) {
+	raw, err := hexutil.Decode(strings.TrimSpace(rawTx))
+	if err != nil {
+		return nil, fmt.Errorf("invalid raw transaction: %v", err)
+	}
+	tx := new(types.Transaction)
+	if err := rlp.Decode(bytes.NewReader(raw), tx); err != nil {
+		return nil, fmt.Errorf("invalid raw transaction: %v", err)
+	}
+	return tx, nil
+}
@@ -0,0 +1,157 @@
+package eth
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/livepeer/go-livepeer/pm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineAccountManager_Queue(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	_, err := NewOfflineAccountManager(ethcommon.Address{}, big.NewInt(1))
+	assert.EqualError(err, "the address of the account is required in offline signing mode")
+
+	addr := pm.RandAddress()
+	am, err := NewOfflineAccountManager(addr, big.NewInt(1))
+	require.Nil(err)
+	assert.Equal(addr, am.Account().Address)
+	assert.Nil(am.Unlock("foo"))
+
+	_, err = am.Sign([]byte("foo"))
+	assert.Equal(errOfflineSign, err)
+	_, err = am.SignTypedData(&pm.TypedData{})
+	assert.Equal(errOfflineSign, err)
+
+	// Test the transactions of transact opts are queued instead of signed
+	opts, err := am.CreateTransactOpts(100, big.NewInt(5))
+	require.Nil(err)
+	to := pm.RandAddress()
+	_, err = opts.Signer(types.HomesteadSigner{}, addr, types.NewTransaction(7, to, big.NewInt(0), 100, big.NewInt(5), []byte("foo")))
+	assert.Equal(ErrTxQueuedForSigning, err)
+	_, err = opts.Signer(types.HomesteadSigner{}, pm.RandAddress(), types.NewTransaction(7, to, big.NewInt(0), 100, big.NewInt(5), []byte("foo")))
+	assert.EqualError(err, "not authorized to sign this account")
+
+	// Test a transaction sent again while it is queued is queued once
+	_, err = am.SignTx(types.NewTransaction(8, to, big.NewInt(0), 200, big.NewInt(6), []byte("foo")))
+	assert.Equal(ErrTxQueuedForSigning, err)
+	_, err = am.SignTx(types.NewTransaction(8, to, big.NewInt(1), 200, big.NewInt(6), []byte("bar")))
+	assert.Equal(ErrTxQueuedForSigning, err)
+
+	// Test the queued transactions get consecutive nonces in the order in which they were queued
+	txs := am.UnsignedTxs(3)
+	require.Len(txs, 2)
+	assert.Equal(uint64(3), txs[0].Nonce)
+	assert.Equal(addr, txs[0].From)
+	assert.Equal(&to, txs[0].To)
+	assert.Equal(uint64(100), txs[0].Gas)
+	assert.Equal([]byte("foo"), []byte(txs[0].Data))
+	assert.Equal(big.NewInt(1), txs[0].ChainID)
+	assert.Equal(uint64(4), txs[1].Nonce)
+	assert.Equal(big.NewInt(1), txs[1].Value)
+	assert.NotEqual(txs[0].ID, txs[1].ID)
+
+	// Test a discarded transaction is removed
+	assert.True(am.Discard(txs[0].ID))
+	assert.False(am.Discard(txs[0].ID))
+	txs = am.UnsignedTxs(3)
+	require.Len(txs, 1)
+	assert.Equal(uint64(3), txs[0].Nonce)
+	assert.Equal([]byte("bar"), []byte(txs[0].Data))
+}
+
+func TestOfflineAccountManager_SignAndSend(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+	acct, err := ks.NewAccount("foo")
+	require.Nil(err)
+
+	chainID := big.NewInt(4)
+	am, err := NewOfflineAccountManager(acct.Address, chainID)
+	require.Nil(err)
+	to := pm.RandAddress()
+	am.SignTx(types.NewTransaction(0, to, big.NewInt(0), 100, big.NewInt(5), []byte("foo")))
+
+	// Test the exported transactions are signed with the keystore after a JSON round trip
+	data, err := json.Marshal(am.UnsignedTxs(9))
+	require.Nil(err)
+	var exported []*UnsignedTx
+	require.Nil(json.Unmarshal(data, &exported))
+	require.Len(exported, 1)
+
+	_, err = SignUnsignedTx(dir, "bar", exported[0])
+	assert.NotNil(err)
+	signed, err := SignUnsignedTx(dir, "foo", exported[0])
+	require.Nil(err)
+	assert.Equal(uint64(9), signed.Nonce())
+
+	// Test the raw transaction round trips
+	raw, err := EncodeRawTx(signed)
+	require.Nil(err)
+	decoded, err := DecodeRawTx(raw + "\n")
+	require.Nil(err)
+	assert.Equal(signed.Hash(), decoded.Hash())
+	_, err = DecodeRawTx("0xfoo")
+	assert.NotNil(err)
+
+	// Test a transaction signed by another account is refused
+	other, err := ks.NewAccount("foo")
+	require.Nil(err)
+	require.Nil(ks.Unlock(other, "foo"))
+	otherTx, err := ks.SignTx(other, exported[0].Transaction(), chainID)
+	require.Nil(err)
+	_, err = am.Signed(otherTx, 9)
+	assert.Contains(err.Error(), "is signed by")
+
+	// Test a transaction with another chain ID is refused
+	wrongChain, err := ks.SignTxWithPassphrase(acct, "foo", exported[0].Transaction(), big.NewInt(1))
+	require.Nil(err)
+	_, err = am.Signed(wrongChain, 9)
+	assert.Contains(err.Error(), "invalid transaction signature")
+
+	// Test the signed transaction matches and removes the queued transaction
+	// Test a transaction signed with another nonce than the pending nonce is refused
+	_, err = am.Signed(decoded, 8)
+	assert.Contains(err.Error(), "instead of 8")
+
+	// Test the signed transaction matches and removes the queued transaction
+	u, err := am.Signed(decoded, 9)
+	require.Nil(err)
+	assert.Equal(exported[0].ID, u.ID)
+	assert.Empty(am.UnsignedTxs(0))
+	_, err = am.Signed(decoded, 9)
+	assert.Contains(err.Error(), "is not a queued transaction")
+
+	// Test a requeued transaction is queued first
+	am.SignTx(types.NewTransaction(0, to, big.NewInt(0), 100, big.NewInt(5), []byte("bar")))
+	am.Requeue(u)
+	am.Requeue(u)
+	txs := am.UnsignedTxs(9)
+	require.Len(txs, 2)
+	assert.Equal(u.ID, txs[0].ID)
+
+	// Test the transactions must be sent in the order of the queue
+	second, err := SignUnsignedTx(dir, "foo", txs[1])
+	require.Nil(err)
+	_, err = am.Signed(second, 9)
+	assert.Contains(err.Error(), "must be sent after the queued transaction "+u.ID)
+
+	// Test the transactions signed before a discard are refused with their previous nonces
+	assert.True(am.Discard(u.ID))
+	_, err = am.Signed(second, 9)
+	assert.Contains(err.Error(), "signed with nonce 10 instead of 9")
+	txs = am.UnsignedTxs(9)
+	require.Len(txs, 1)
+	assert.Equal(uint64(9), txs[0].Nonce)
+}
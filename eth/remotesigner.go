@@ -0,0 +1,382 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/pm"
+)
+
+// APIs of the remote signers that accounts can be kept in
+const (
+	RemoteSignerClef       = "clef"
+	RemoteSignerWeb3Signer = "web3signer"
+)
+
+// DefaultRemoteSignerTimeout is the default time to wait for the response of a remote signer
+// to a request, which includes the time for operators to approve it in Clef
+const DefaultRemoteSignerTimeout = 30 * time.Second
+
+var ErrRemoteSignerNoAccounts = errors.New("remote signer has no accounts")
+
+// RemoteSignerConfig configures the remote signer that an account is kept in
+type RemoteSignerConfig struct {
+	// URL is the HTTP(S) URL or the IPC path of the RPC API of the signer
+	URL string
+	// API is the API of the signer, RemoteSignerClef or RemoteSignerWeb3Signer
+	API string
+	// Timeout is the time to wait for the response to a request, defaults to
+	// DefaultRemoteSignerTimeout
+	Timeout time.Duration
+	// HealthCheckInterval is the interval at which the signer is checked, disabled if 0
+	HealthCheckInterval time.Duration
+}
+
+// remoteSignerAPI holds the RPC methods of a remote signer API
+type remoteSignerAPI struct {
+	accounts        string
+	signTransaction string
+	signText        string
+	signTypedData   string
+	// health is a method that doesn't need the approval of operators
+	health string
+	// textMimeType is passed with the messages to sign if it is set
+	textMimeType string
+}
+
+var remoteSignerAPIs = map[string]*remoteSignerAPI{
+	RemoteSignerClef: {
+		accounts:        "account_list",
+		signTransaction: "account_signTransaction",
+		signText:        "account_signData",
+		signTypedData:   "account_signTypedData",
+		health:          "account_version",
+		textMimeType:    accounts.MimetypeTextPlain,
+	},
+	RemoteSignerWeb3Signer: {
+		accounts:        "eth_accounts",
+		signTransaction: "eth_signTransaction",
+		signText:        "eth_sign",
+		signTypedData:   "eth_signTypedData",
+		health:          "eth_accounts",
+	},
+}
+
+// remoteTxArgs are the fields of the transactions sent to remote signers
+type remoteTxArgs struct {
+	From     ethcommon.MixedcaseAddress  `json:"from"`
+	To       *ethcommon.MixedcaseAddress `json:"to"`
+	Gas      hexutil.Uint64              `json:"gas"`
+	GasPrice hexutil.Big                 `json:"gasPrice"`
+	Value    hexutil.Big                 `json:"value"`
+	Nonce    hexutil.Uint64              `json:"nonce"`
+	Data     hexutil.Bytes               `json:"data"`
+}
+
+type remoteAccountManager struct {
+	account accounts.Account
+	client  *rpc.Client
+	api     *remoteSignerAPI
+	signer  types.Signer
+	timeout time.Duration
+
+	mu        sync.Mutex
+	unlocked  bool
+	healthErr error
+
+	quit chan struct{}
+}
+
+// NewRemoteAccountManager creates an AccountManager for the account at accountAddr of the
+// remote signer of cfg, e.g. Clef or Web3Signer, or for its first account if accountAddr is
+// not set. The key of the account stays in the signer, which signs the transactions and the
+// messages of the node over its RPC API
+func NewRemoteAccountManager(accountAddr ethcommon.Address, cfg RemoteSignerConfig, signer types.Signer) (AccountManager, error) {
+	api, ok := remoteSignerAPIs[cfg.API]
+	if !ok {
+		return nil, fmt.Errorf("unsupported remote signer API %v", cfg.API)
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultRemoteSignerTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	client, err := rpc.DialContext(ctx, cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	am, err := newRemoteAccountManager(client, api, accountAddr, signer, timeout)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	if cfg.HealthCheckInterval > 0 {
+		go am.healthCheckLoop(cfg.HealthCheckInterval)
+	}
+
+	return am, nil
+}
+
+func newRemoteAccountManager(client *rpc.Client, api *remoteSignerAPI, accountAddr ethcommon.Address, signer types.Signer, timeout time.Duration) (*remoteAccountManager, error) {
+	am := &remoteAccountManager{
+		client:  client,
+		api:     api,
+		signer:  signer,
+		timeout: timeout,
+		quit:    make(chan struct{}),
+	}
+
+	var addrs []ethcommon.Address
+	if err := am.call(&addrs, api.accounts); err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, ErrRemoteSignerNoAccounts
+	}
+
+	if (accountAddr == ethcommon.Address{}) {
+		glog.V(common.SHORT).Infof("Defaulting to first ETH account of remote signer %v", addrs[0].Hex())
+
+		am.account = accounts.Account{Address: addrs[0]}
+	} else {
+		for _, addr := range addrs {
+			if addr == accountAddr {
+				am.account = accounts.Account{Address: addr}
+			}
+		}
+		if (am.account.Address == ethcommon.Address{}) {
+			return nil, ErrAccountNotFound
+		}
+	}
+
+	glog.Infof("Using Ethereum account of remote signer: %v", am.account.Address.Hex())
+
+	return am, nil
+}
+
+// call calls a method of the signer, waiting for its response for up to the request timeout
+func (am *remoteAccountManager) call(result interface{}, method string, args ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), am.timeout)
+	defer cancel()
+
+	if err := am.client.CallContext(ctx, result, method, args...); err != nil {
+		return fmt.Errorf("remote signer %v error: %v", method, err)
+	}
+
+	return nil
+}
+
+// checkHealth checks that the signer responds to requests. The changes of the health of the
+// signer are logged, so that operators find out about it before transactions fail
+func (am *remoteAccountManager) checkHealth() error {
+	var res json.RawMessage
+	err := am.call(&res, am.api.health)
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if err != nil && am.healthErr == nil {
+		glog.Errorf("Remote signer is unhealthy err=%v", err)
+	} else if err == nil && am.healthErr != nil {
+		glog.Infof("Remote signer is healthy again")
+	}
+	am.healthErr = err
+
+	return err
+}
+
+func (am *remoteAccountManager) healthCheckLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			am.checkHealth()
+		case <-am.quit:
+			return
+		}
+	}
+}
+
+// Stop stops the health checks of the signer and closes the connection to it
+func (am *remoteAccountManager) Stop() {
+	close(am.quit)
+	am.client.Close()
+}
+
+// Unlock checks that the signer is healthy, since the key of the account stays in the signer
+func (am *remoteAccountManager) Unlock(pass string) error {
+	if err := am.checkHealth(); err != nil {
+		return err
+	}
+
+	am.mu.Lock()
+	am.unlocked = true
+	am.mu.Unlock()
+
+	glog.Infof("Unlocked ETH account: %v", am.account.Address.Hex())
+
+	return nil
+}
+
+func (am *remoteAccountManager) Lock() error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	am.unlocked = false
+
+	return nil
+}
+
+func (am *remoteAccountManager) isUnlocked() bool {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	return am.unlocked
+}
+
+// Create transact opts for client use - account must be unlocked
+// Can optionally set gas limit and gas price used
+func (am *remoteAccountManager) CreateTransactOpts(gasLimit uint64, gasPrice *big.Int) (*bind.TransactOpts, error) {
+	if !am.isUnlocked() {
+		return nil, ErrLocked
+	}
+
+	return &bind.TransactOpts{
+		From:     am.account.Address,
+		GasLimit: gasLimit,
+		GasPrice: gasPrice,
+		Signer: func(signer types.Signer, address ethcommon.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != am.account.Address {
+				return nil, errors.New("not authorized to sign this account")
+			}
+
+			return am.SignTx(tx)
+		},
+	}, nil
+}
+
+// Sign a transaction with the signer. Account must be unlocked
+func (am *remoteAccountManager) SignTx(tx *types.Transaction) (*types.Transaction, error) {
+	if !am.isUnlocked() {
+		return nil, ErrLocked
+	}
+
+	args := &remoteTxArgs{
+		From:     ethcommon.NewMixedcaseAddress(am.account.Address),
+		Gas:      hexutil.Uint64(tx.Gas()),
+		GasPrice: hexutil.Big(*tx.GasPrice()),
+		Value:    hexutil.Big(*tx.Value()),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		Data:     tx.Data(),
+	}
+	if tx.To() != nil {
+		to := ethcommon.NewMixedcaseAddress(*tx.To())
+		args.To = &to
+	}
+
+	// Clef responds with the raw transaction in an object, Web3Signer with the raw transaction
+	var res json.RawMessage
+	if err := am.call(&res, am.api.signTransaction, args); err != nil {
+		return nil, err
+	}
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(res, &raw); err != nil {
+		var obj struct {
+			Raw hexutil.Bytes `json:"raw"`
+		}
+		if err := json.Unmarshal(res, &obj); err != nil {
+			return nil, fmt.Errorf("invalid remote signer transaction: %v", err)
+		}
+		raw = obj.Raw
+	}
+
+	signed := new(types.Transaction)
+	if err := rlp.DecodeBytes(raw, signed); err != nil {
+		return nil, fmt.Errorf("invalid remote signer transaction: %v", err)
+	}
+
+	// Check that the signer signed the transaction of the node
+	sender, err := types.Sender(am.signer, signed)
+	if err != nil {
+		return nil, err
+	}
+	if sender != am.account.Address || am.signer.Hash(signed) != am.signer.Hash(tx) {
+		return nil, errors.New("remote signer signed another transaction")
+	}
+
+	return signed, nil
+}
+
+// Sign byte array message with the signer. Account must be unlocked
+func (am *remoteAccountManager) Sign(msg []byte) ([]byte, error) {
+	if !am.isUnlocked() {
+		return nil, ErrLocked
+	}
+
+	addr := ethcommon.NewMixedcaseAddress(am.account.Address)
+	args := []interface{}{&addr, hexutil.Encode(msg)}
+	if am.api.textMimeType != "" {
+		args = append([]interface{}{am.api.textMimeType}, args...)
+	}
+
+	var sig hexutil.Bytes
+	if err := am.call(&sig, am.api.signText, args...); err != nil {
+		return nil, err
+	}
+
+	return remoteSignature(sig)
+}
+
+// Sign EIP-712 typed data with the signer. Account must be unlocked
+func (am *remoteAccountManager) SignTypedData(typedData *pm.TypedData) ([]byte, error) {
+	if !am.isUnlocked() {
+		return nil, ErrLocked
+	}
+
+	addr := ethcommon.NewMixedcaseAddress(am.account.Address)
+	var sig hexutil.Bytes
+	if err := am.call(&sig, am.api.signTypedData, &addr, typedData); err != nil {
+		return nil, err
+	}
+
+	return remoteSignature(sig)
+}
+
+func (am *remoteAccountManager) Account() accounts.Account {
+	return am.account
+}
+
+// remoteSignature checks the length of a signature of a signer and converts its V param to
+// 27 or 28
+func remoteSignature(sig []byte) ([]byte, error) {
+	if len(sig) != 65 {
+		return nil, fmt.Errorf("invalid remote signer signature length %v", len(sig))
+	}
+
+	if v := sig[64]; v == byte(0) || v == byte(1) {
+		sig[64] += 27
+	}
+
+	return sig, nil
+}
@@ -0,0 +1,308 @@
+package eth
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/livepeer/go-livepeer/pm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubRemoteSigner signs with a key like a remote signer
+type stubRemoteSigner struct {
+	key     *ecdsa.PrivateKey
+	chainID *big.Int
+	addrs   []ethcommon.Address
+	err     error
+	// tamper makes the signer sign a transaction with another nonce
+	tamper bool
+	// delay delays the response to sign requests
+	delay time.Duration
+
+	mimeType  string
+	typedData json.RawMessage
+}
+
+func newStubRemoteSigner(t *testing.T) *stubRemoteSigner {
+	key, err := ethcrypto.GenerateKey()
+	require.Nil(t, err)
+	return &stubRemoteSigner{
+		key:     key,
+		chainID: big.NewInt(4),
+		addrs:   []ethcommon.Address{pm.RandAddress(), ethcrypto.PubkeyToAddress(key.PublicKey)},
+	}
+}
+
+func (s *stubRemoteSigner) address() ethcommon.Address {
+	return ethcrypto.PubkeyToAddress(s.key.PublicKey)
+}
+
+func (s *stubRemoteSigner) signTx(args StubRemoteTxArgs) (hexutil.Bytes, error) {
+	time.Sleep(s.delay)
+	if s.err != nil {
+		return nil, s.err
+	}
+	nonce := uint64(args.Nonce)
+	if s.tamper {
+		nonce++
+	}
+	tx := types.NewTransaction(nonce, args.To.Address(), args.Value.ToInt(), uint64(args.Gas), args.GasPrice.ToInt(), args.Data)
+	signed, err := types.SignTx(tx, types.NewEIP155Signer(s.chainID), s.key)
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(signed)
+}
+
+func (s *stubRemoteSigner) sign(data hexutil.Bytes) (hexutil.Bytes, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return ethcrypto.Sign(accounts.TextHash(data), s.key)
+}
+
+func (s *stubRemoteSigner) signTypedData(data json.RawMessage) (hexutil.Bytes, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.typedData = data
+	return ethcrypto.Sign(ethcrypto.Keccak256(data), s.key)
+}
+
+// StubRemoteTxArgs are the transaction args received by the stub signers, which the RPC
+// server requires to be of an exported type
+type StubRemoteTxArgs remoteTxArgs
+
+// stubClef serves the account namespace of the Clef API
+type stubClef struct{ *stubRemoteSigner }
+
+func (s *stubClef) List() ([]ethcommon.Address, error) { return s.addrs, s.err }
+
+func (s *stubClef) Version() (string, error) { return "6.0.0", s.err }
+
+func (s *stubClef) SignTransaction(args StubRemoteTxArgs) (map[string]interface{}, error) {
+	raw, err := s.signTx(args)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"raw": raw, "tx": map[string]string{}}, nil
+}
+
+func (s *stubClef) SignData(mimeType string, addr ethcommon.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error) {
+	s.mimeType = mimeType
+	return s.sign(data)
+}
+
+func (s *stubClef) SignTypedData(addr ethcommon.MixedcaseAddress, data json.RawMessage) (hexutil.Bytes, error) {
+	return s.signTypedData(data)
+}
+
+// stubWeb3Signer serves the eth namespace of the Web3Signer API
+type stubWeb3Signer struct{ *stubRemoteSigner }
+
+func (s *stubWeb3Signer) Accounts() ([]ethcommon.Address, error) { return s.addrs, s.err }
+
+func (s *stubWeb3Signer) SignTransaction(args StubRemoteTxArgs) (hexutil.Bytes, error) {
+	return s.signTx(args)
+}
+
+func (s *stubWeb3Signer) Sign(addr ethcommon.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error) {
+	return s.sign(data)
+}
+
+func (s *stubWeb3Signer) SignTypedData(addr ethcommon.MixedcaseAddress, data json.RawMessage) (hexutil.Bytes, error) {
+	return s.signTypedData(data)
+}
+
+func newStubRemoteSignerClient(t *testing.T, namespace string, service interface{}) *rpc.Client {
+	server := rpc.NewServer()
+	require.Nil(t, server.RegisterName(namespace, service))
+	return rpc.DialInProc(server)
+}
+
+func TestNewRemoteAccountManager(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := newStubRemoteSigner(t)
+	client := newStubRemoteSignerClient(t, "account", &stubClef{s})
+	defer client.Close()
+	api := remoteSignerAPIs[RemoteSignerClef]
+	signer := types.NewEIP155Signer(s.chainID)
+
+	// Test default to the first account
+	am, err := newRemoteAccountManager(client, api, ethcommon.Address{}, signer, time.Second)
+	require.Nil(err)
+	assert.Equal(s.addrs[0], am.Account().Address)
+
+	am, err = newRemoteAccountManager(client, api, s.address(), signer, time.Second)
+	require.Nil(err)
+	assert.Equal(s.address(), am.Account().Address)
+
+	_, err = newRemoteAccountManager(client, api, pm.RandAddress(), signer, time.Second)
+	assert.Equal(ErrAccountNotFound, err)
+
+	s.addrs = nil
+	_, err = newRemoteAccountManager(client, api, ethcommon.Address{}, signer, time.Second)
+	assert.Equal(ErrRemoteSignerNoAccounts, err)
+
+	s.err = errors.New("account_list error")
+	_, err = newRemoteAccountManager(client, api, ethcommon.Address{}, signer, time.Second)
+	require.NotNil(err)
+	assert.Contains(err.Error(), "account_list error")
+
+	_, err = NewRemoteAccountManager(ethcommon.Address{}, RemoteSignerConfig{API: "foo"}, signer)
+	assert.EqualError(err, "unsupported remote signer API foo")
+}
+
+func TestRemoteAccountManager_SignTx(t *testing.T) {
+	for _, apiName := range []string{RemoteSignerClef, RemoteSignerWeb3Signer} {
+		t.Run(apiName, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			s := newStubRemoteSigner(t)
+			var client *rpc.Client
+			if apiName == RemoteSignerClef {
+				client = newStubRemoteSignerClient(t, "account", &stubClef{s})
+			} else {
+				client = newStubRemoteSignerClient(t, "eth", &stubWeb3Signer{s})
+			}
+			defer client.Close()
+
+			signer := types.NewEIP155Signer(s.chainID)
+			am, err := newRemoteAccountManager(client, remoteSignerAPIs[apiName], s.address(), signer, 100*time.Millisecond)
+			require.Nil(err)
+			tx := types.NewTransaction(1, pm.RandAddress(), big.NewInt(2), 21000, big.NewInt(3), []byte("foo"))
+
+			// Test locked account
+			_, err = am.CreateTransactOpts(0, nil)
+			assert.Equal(ErrLocked, err)
+			_, err = am.SignTx(tx)
+			assert.Equal(ErrLocked, err)
+
+			require.Nil(am.Unlock(""))
+			opts, err := am.CreateTransactOpts(0, nil)
+			require.Nil(err)
+
+			signed, err := opts.Signer(signer, s.address(), tx)
+			require.Nil(err)
+			sender, err := types.Sender(signer, signed)
+			require.Nil(err)
+			assert.Equal(s.address(), sender)
+			assert.Equal(signer.Hash(tx), signer.Hash(signed))
+
+			// Test signer that signs another transaction
+			s.tamper = true
+			_, err = am.SignTx(tx)
+			assert.EqualError(err, "remote signer signed another transaction")
+			s.tamper = false
+
+			// Test request timeout
+			s.delay = 200 * time.Millisecond
+			_, err = am.SignTx(tx)
+			require.NotNil(err)
+			assert.Contains(err.Error(), "context deadline exceeded")
+		})
+	}
+}
+
+func TestRemoteAccountManager_Sign(t *testing.T) {
+	for _, apiName := range []string{RemoteSignerClef, RemoteSignerWeb3Signer} {
+		t.Run(apiName, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			s := newStubRemoteSigner(t)
+			var client *rpc.Client
+			if apiName == RemoteSignerClef {
+				client = newStubRemoteSignerClient(t, "account", &stubClef{s})
+			} else {
+				client = newStubRemoteSignerClient(t, "eth", &stubWeb3Signer{s})
+			}
+			defer client.Close()
+
+			am, err := newRemoteAccountManager(client, remoteSignerAPIs[apiName], s.address(), types.NewEIP155Signer(s.chainID), time.Second)
+			require.Nil(err)
+			msg := []byte("foo")
+
+			_, err = am.Sign(msg)
+			assert.Equal(ErrLocked, err)
+			require.Nil(am.Unlock(""))
+
+			sig, err := am.Sign(msg)
+			require.Nil(err)
+			assert.True(sig[64] == 27 || sig[64] == 28)
+			sig[64] -= 27
+			pub, err := ethcrypto.SigToPub(accounts.TextHash(msg), sig)
+			require.Nil(err)
+			assert.Equal(s.address(), ethcrypto.PubkeyToAddress(*pub))
+			if apiName == RemoteSignerClef {
+				assert.Equal(accounts.MimetypeTextPlain, s.mimeType)
+			}
+
+			// Test typed data is sent as the payload of eth_signTypedData_v4 requests
+			typedData := &pm.TypedData{Domain: pm.TicketDomain, Ticket: &pm.Ticket{FaceValue: big.NewInt(1), WinProb: big.NewInt(2)}}
+			sig, err = am.SignTypedData(typedData)
+			require.Nil(err)
+			assert.Len(sig, 65)
+			expTypedData, err := json.Marshal(typedData)
+			require.Nil(err)
+			assert.JSONEq(string(expTypedData), string(s.typedData))
+		})
+	}
+}
+
+func TestRemoteAccountManager_CheckHealth(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := newStubRemoteSigner(t)
+	client := newStubRemoteSignerClient(t, "account", &stubClef{s})
+	defer client.Close()
+	am, err := newRemoteAccountManager(client, remoteSignerAPIs[RemoteSignerClef], s.address(), types.NewEIP155Signer(s.chainID), time.Second)
+	require.Nil(err)
+
+	assert.Nil(am.checkHealth())
+
+	s.err = errors.New("account_version error")
+	err = am.checkHealth()
+	require.NotNil(err)
+	assert.Contains(err.Error(), "account_version error")
+	assert.Equal(err, am.healthErr)
+
+	// Test the account can't be unlocked while the signer is unhealthy
+	assert.NotNil(am.Unlock(""))
+	_, err = am.Sign([]byte("foo"))
+	assert.Equal(ErrLocked, err)
+
+	s.err = nil
+	assert.Nil(am.checkHealth())
+	assert.Nil(am.healthErr)
+	assert.Nil(am.Unlock(""))
+
+	// Test the health checks exit once stopped
+	done := make(chan struct{})
+	go func() {
+		am.healthCheckLoop(time.Millisecond)
+		close(done)
+	}()
+	am.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("health checks did not stop")
+	}
+}
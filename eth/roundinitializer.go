@@ -14,7 +14,9 @@ var epochBlocks = big.NewInt(5)
 
 // BlockNumReader describes methods for reading the last seen block number
 type BlockNumReader interface {
-	LastSeenBlock() (*big.Int, error)
+	// LastSeenL1Block returns the last seen L1 block number, since rounds are counted in L1
+	// blocks by the contracts of L2 chains
+	LastSeenL1Block() (*big.Int, error)
 }
 
 // BlockHashReader describes methods for reading the last initialized block hash
@@ -26,22 +28,26 @@ type BlockHashReader interface {
 // epochBlocks. During each epoch a member of the upcoming active set is selected to initialize the round
 // This selection process is purely a client side implementation that attempts to minimize on-chain transaction collisions, but
 // collisions are still possible if initialization transactions are submitted by parties that are not using this selection process
+// If initializeFirst is set, the selection is skipped and the caller initializes the round as soon as it sees that the round is
+// not initialized, whether or not it is in the upcoming active set, to be the first to act
 type RoundInitializer struct {
 	client          LivepeerEthClient
 	blkNumRdr       BlockNumReader
 	blkHashRdr      BlockHashReader
 	pollingInterval time.Duration
+	initializeFirst bool
 
 	quit chan struct{}
 }
 
 // NewRoundInitializer creates a RoundInitializer instance
-func NewRoundInitializer(client LivepeerEthClient, blkNumRdr BlockNumReader, blkHashRdr BlockHashReader, pollingInterval time.Duration) *RoundInitializer {
+func NewRoundInitializer(client LivepeerEthClient, blkNumRdr BlockNumReader, blkHashRdr BlockHashReader, pollingInterval time.Duration, initializeFirst bool) *RoundInitializer {
 	return &RoundInitializer{
 		client:          client,
 		blkNumRdr:       blkNumRdr,
 		blkHashRdr:      blkHashRdr,
 		pollingInterval: pollingInterval,
+		initializeFirst: initializeFirst,
 		quit:            make(chan struct{}),
 	}
 }
@@ -79,25 +85,16 @@ func (r *RoundInitializer) tryInitialize() error {
 		return nil
 	}
 
-	currentRoundStartBlk, err := r.client.CurrentRoundStartBlock()
-	if err != nil {
-		return err
-	}
-
-	lastInitializedBlockHash := r.blkHashRdr.LastInitializedBlockHash()
-	epochSeed, err := r.currentEpochSeed(currentRoundStartBlk, lastInitializedBlockHash)
-	if err != nil {
-		return err
-	}
-
-	ok, err := r.shouldInitialize(epochSeed)
-	if err != nil {
-		return err
-	}
+	if !r.initializeFirst {
+		ok, err := r.selected()
+		if err != nil {
+			return err
+		}
 
-	// Noop if the caller should not initialize the round
-	if !ok {
-		return nil
+		// Noop if the caller should not initialize the round
+		if !ok {
+			return nil
+		}
 	}
 
 	currentRound, err := r.client.CurrentRound()
@@ -121,6 +118,22 @@ func (r *RoundInitializer) tryInitialize() error {
 	return nil
 }
 
+// selected returns whether the caller is selected to initialize the round in the current epoch
+func (r *RoundInitializer) selected() (bool, error) {
+	currentRoundStartBlk, err := r.client.CurrentRoundStartBlock()
+	if err != nil {
+		return false, err
+	}
+
+	lastInitializedBlockHash := r.blkHashRdr.LastInitializedBlockHash()
+	epochSeed, err := r.currentEpochSeed(currentRoundStartBlk, lastInitializedBlockHash)
+	if err != nil {
+		return false, err
+	}
+
+	return r.shouldInitialize(epochSeed)
+}
+
 func (r *RoundInitializer) shouldInitialize(epochSeed *big.Int) (bool, error) {
 	transcoders, err := r.client.TranscoderPool()
 	if err != nil {
@@ -174,7 +187,7 @@ func (r *RoundInitializer) shouldInitialize(epochSeed *big.Int) (bool, error) {
 // This seed is not meant to be unpredictable. The only requirement for the seed is that it is calculated the same way for each
 // party running the round initializer
 func (r *RoundInitializer) currentEpochSeed(roundStartBlk *big.Int, lastInitializedBlkHash [32]byte) (*big.Int, error) {
-	currentBlk, err := r.blkNumRdr.LastSeenBlock()
+	currentBlk, err := r.blkNumRdr.LastSeenL1Block()
 	if err != nil {
 		return nil, err
 	}
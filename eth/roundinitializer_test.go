@@ -19,7 +19,7 @@ type stubBlockNumReader struct {
 	err    error
 }
 
-func (rdr *stubBlockNumReader) LastSeenBlock() (*big.Int, error) {
+func (rdr *stubBlockNumReader) LastSeenL1Block() (*big.Int, error) {
 	if rdr.err != nil {
 		return nil, rdr.err
 	}
@@ -39,12 +39,12 @@ func TestRoundInitializer_CurrentEpochSeed(t *testing.T) {
 	client := &MockClient{}
 	blkNumRdr := &stubBlockNumReader{}
 	blkHashRdr := &stubBlockHashReader{}
-	initializer := NewRoundInitializer(client, blkNumRdr, blkHashRdr, 1*time.Second)
+	initializer := NewRoundInitializer(client, blkNumRdr, blkHashRdr, 1*time.Second, false)
 
 	assert := assert.New(t)
 
 	// Test error getting block num
-	blkNumRdr.err = errors.New("LastSeenBlock error")
+	blkNumRdr.err = errors.New("LastSeenL1Block error")
 
 	_, err := initializer.currentEpochSeed(nil, [32]byte{})
 	assert.EqualError(err, blkNumRdr.err.Error())
@@ -82,7 +82,7 @@ func TestRoundInitializer_ShouldInitialize(t *testing.T) {
 	client := &MockClient{}
 	blkNumRdr := &stubBlockNumReader{}
 	blkHashRdr := &stubBlockHashReader{}
-	initializer := NewRoundInitializer(client, blkNumRdr, blkHashRdr, 1*time.Second)
+	initializer := NewRoundInitializer(client, blkNumRdr, blkHashRdr, 1*time.Second, false)
 
 	assert := assert.New(t)
 
@@ -165,7 +165,7 @@ func TestRoundInitializer_TryInitialize(t *testing.T) {
 	client := &MockClient{}
 	blkNumRdr := &stubBlockNumReader{}
 	blkHashRdr := &stubBlockHashReader{}
-	initializer := NewRoundInitializer(client, blkNumRdr, blkHashRdr, 1*time.Second)
+	initializer := NewRoundInitializer(client, blkNumRdr, blkHashRdr, 1*time.Second, false)
 
 	assert := assert.New(t)
 
@@ -255,3 +255,30 @@ func TestRoundInitializer_TryInitialize(t *testing.T) {
 	err = initializer.tryInitialize()
 	assert.Nil(err)
 }
+
+func TestRoundInitializer_TryInitialize_InitializeFirst(t *testing.T) {
+	client := &MockClient{}
+	blkNumRdr := &stubBlockNumReader{}
+	blkHashRdr := &stubBlockHashReader{}
+	initializer := NewRoundInitializer(client, blkNumRdr, blkHashRdr, 1*time.Second, true)
+
+	assert := assert.New(t)
+
+	// Test current round is initialized
+	client.On("CurrentRoundInitialized").Return(true, nil).Once()
+
+	err := initializer.tryInitialize()
+	assert.Nil(err)
+
+	// Test round initialized without checking the selection of the caller
+	client.On("CurrentRoundInitialized").Return(false, nil)
+	client.On("CurrentRound").Return(big.NewInt(5), nil)
+	client.On("InitializeRound").Return(&types.Transaction{}, nil)
+	client.On("CheckTx", mock.Anything).Return(nil)
+
+	err = initializer.tryInitialize()
+	assert.Nil(err)
+	client.AssertCalled(t, "InitializeRound")
+	client.AssertNotCalled(t, "CurrentRoundStartBlock")
+	client.AssertNotCalled(t, "TranscoderPool")
+}
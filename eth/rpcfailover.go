@@ -0,0 +1,283 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang/glog"
+)
+
+// DefaultRPCFailoverTimeout is the default time to wait for the response of an Ethereum node
+// before the request is sent to the next one
+const DefaultRPCFailoverTimeout = 10 * time.Second
+
+var ErrNoRPCEndpoints = errors.New("no Ethereum node JSON-RPC URLs")
+
+// blockNumberRequest is the JSON-RPC request of the health checks of the nodes
+var blockNumberRequest = []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)
+
+// RPCFailoverConfig configures the Ethereum nodes that JSON-RPC requests fail over between
+type RPCFailoverConfig struct {
+	// URLs are the HTTP(S) URLs of the JSON-RPC APIs of the nodes
+	URLs []string
+	// Timeout is the time to wait for the response of a node before the request is sent to
+	// the next one, defaults to DefaultRPCFailoverTimeout
+	Timeout time.Duration
+	// HealthCheckInterval is the interval at which the health and the latency of the nodes are
+	// checked, disabled if 0
+	HealthCheckInterval time.Duration
+}
+
+// rpcEndpoint is a node that requests fail over to
+type rpcEndpoint struct {
+	url *url.URL
+	// latency is the response time of the last health check
+	latency time.Duration
+	// err is the error of the last request or health check, nil if the node is healthy
+	err error
+}
+
+// failoverTransport is an http.RoundTripper sending the JSON-RPC requests to the healthy
+// node with the lowest latency. Requests that fail, time out or get a server error are sent
+// to the next node, healthy ones first, and the node is marked unhealthy until a request or
+// a health check to it succeeds. JSON-RPC errors are responses, which don't fail over
+type failoverTransport struct {
+	transport http.RoundTripper
+	timeout   time.Duration
+
+	mu        sync.RWMutex
+	endpoints []*rpcEndpoint
+}
+
+// DialRPCFailover creates an RPC client for the nodes of cfg. With a single URL, which can
+// also be a WebSocket URL or an IPC path, the client connects to that node. With several
+// URLs, the requests fail over between the nodes, whose health is checked until ctx is done
+func DialRPCFailover(ctx context.Context, cfg RPCFailoverConfig) (*rpc.Client, error) {
+	if len(cfg.URLs) == 0 {
+		return nil, ErrNoRPCEndpoints
+	}
+	if len(cfg.URLs) == 1 {
+		return rpc.Dial(cfg.URLs[0])
+	}
+
+	t, err := newFailoverTransport(cfg.URLs, cfg.Timeout, http.DefaultTransport)
+	if err != nil {
+		return nil, err
+	}
+
+	t.checkHealth()
+	if cfg.HealthCheckInterval > 0 {
+		go t.healthCheckLoop(ctx, cfg.HealthCheckInterval)
+	}
+
+	// The URL of the client is replaced by the URLs of the nodes for each request
+	return rpc.DialHTTPWithClient(cfg.URLs[0], &http.Client{Transport: t})
+}
+
+func newFailoverTransport(urls []string, timeout time.Duration, transport http.RoundTripper) (*failoverTransport, error) {
+	if timeout <= 0 {
+		timeout = DefaultRPCFailoverTimeout
+	}
+
+	t := &failoverTransport{
+		transport: transport,
+		timeout:   timeout,
+	}
+	for _, rawurl := range urls {
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("Ethereum node JSON-RPC URL %v must be an HTTP(S) URL to fail over between several nodes", rawurl)
+		}
+		t.endpoints = append(t.endpoints, &rpcEndpoint{url: u})
+	}
+
+	return t, nil
+}
+
+// RoundTrip sends req to the nodes in order of preference until one of them responds
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var lastErr error
+	for _, e := range t.preferred() {
+		resp, err := t.send(req, e.url, body)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests {
+			t.setHealth(e, nil, 0)
+			return resp, nil
+		}
+		if err == nil {
+			resp.Body.Close()
+			err = fmt.Errorf("%v", resp.Status)
+		}
+
+		// Noop if the caller gave up on the request
+		if req.Context().Err() != nil {
+			return nil, req.Context().Err()
+		}
+
+		lastErr = fmt.Errorf("Ethereum node %v error: %v", e.url.Host, err)
+		t.setHealth(e, lastErr, 0)
+	}
+
+	return nil, lastErr
+}
+
+// send sends a copy of req with body to the node at u, waiting for its response for up to the
+// timeout of the transport
+func (t *failoverTransport) send(req *http.Request, u *url.URL, body []byte) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+
+	r := req.Clone(ctx)
+	r.URL = u
+	r.Host = u.Host
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+
+	resp, err := t.transport.RoundTrip(r)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The response body is read after RoundTrip returns, within the timeout
+	resp.Body = &cancelReadCloser{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+// preferred returns the healthy nodes from the lowest latency, followed by the unhealthy ones,
+// which are still tried in case all the nodes are unhealthy
+func (t *failoverTransport) preferred() []*rpcEndpoint {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	endpoints := make([]*rpcEndpoint, len(t.endpoints))
+	copy(endpoints, t.endpoints)
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		if (endpoints[i].err == nil) != (endpoints[j].err == nil) {
+			return endpoints[i].err == nil
+		}
+		return endpoints[i].latency < endpoints[j].latency
+	})
+
+	return endpoints
+}
+
+// setHealth records the health of a node, and its latency if it is not 0. The changes of the
+// health of nodes are logged
+func (t *failoverTransport) setHealth(e *rpcEndpoint, err error, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err != nil && e.err == nil {
+		glog.Errorf("Ethereum node is unhealthy, failing over to the next node url=%v err=%v", e.url.Host, err)
+	} else if err == nil && e.err != nil {
+		glog.Infof("Ethereum node is healthy again url=%v", e.url.Host)
+	}
+	e.err = err
+	if latency > 0 {
+		e.latency = latency
+	}
+}
+
+// checkHealth requests the block number of each node, recording its health and its latency
+func (t *failoverTransport) checkHealth() {
+	t.mu.RLock()
+	endpoints := make([]*rpcEndpoint, len(t.endpoints))
+	copy(endpoints, t.endpoints)
+	t.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, e := range endpoints {
+		wg.Add(1)
+		go func(e *rpcEndpoint) {
+			defer wg.Done()
+			start := time.Now()
+			err := t.requestBlockNumber(e.url)
+			if err != nil {
+				err = fmt.Errorf("Ethereum node %v health check error: %v", e.url.Host, err)
+			}
+			t.setHealth(e, err, time.Since(start))
+		}(e)
+	}
+	wg.Wait()
+}
+
+func (t *failoverTransport) requestBlockNumber(u *url.URL) error {
+	req, err := http.NewRequest(http.MethodPost, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.send(req, u, blockNumberRequest)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(resp.Status)
+	}
+
+	var res struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return err
+	}
+	if res.Error != nil {
+		return errors.New(res.Error.Message)
+	}
+
+	return nil
+}
+
+func (t *failoverTransport) healthCheckLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.checkHealth()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// cancelReadCloser cancels the context of a request once its response body is closed
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelReadCloser) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,193 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubRPCNode is an Ethereum node responding to every request with its block number, or with
+// its status code or JSON-RPC error if they are set
+type stubRPCNode struct {
+	*httptest.Server
+	blockNumber int64
+	status      int32
+	rpcErr      atomic.Value
+	delay       int64
+	requests    int32
+}
+
+func newStubRPCNode(blockNumber int64) *stubRPCNode {
+	n := &stubRPCNode{blockNumber: blockNumber}
+	n.rpcErr.Store("")
+	n.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n.requests, 1)
+		time.Sleep(time.Duration(atomic.LoadInt64(&n.delay)))
+		if status := atomic.LoadInt32(&n.status); status != 0 {
+			w.WriteHeader(int(status))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if rpcErr := n.rpcErr.Load().(string); rpcErr != "" {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"%v"}}`, rpcErr)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":"%v"}`, hexutil.EncodeUint64(uint64(n.blockNumber)))
+	}))
+	return n
+}
+
+func (n *stubRPCNode) reset() {
+	atomic.StoreInt32(&n.requests, 0)
+}
+
+func newFailoverClient(t *testing.T, timeout time.Duration, nodes ...*stubRPCNode) (*rpc.Client, *failoverTransport) {
+	var urls []string
+	for _, n := range nodes {
+		urls = append(urls, n.URL)
+	}
+	ft, err := newFailoverTransport(urls, timeout, http.DefaultTransport)
+	require.Nil(t, err)
+	client, err := rpc.DialHTTPWithClient(urls[0], &http.Client{Transport: ft})
+	require.Nil(t, err)
+	return client, ft
+}
+
+func blockNumber(client *rpc.Client) (uint64, error) {
+	var res hexutil.Uint64
+	err := client.CallContext(context.Background(), &res, "eth_blockNumber")
+	return uint64(res), err
+}
+
+func TestRPCFailover_FailOver(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	a := newStubRPCNode(1)
+	defer a.Close()
+	b := newStubRPCNode(2)
+	defer b.Close()
+	client, ft := newFailoverClient(t, 50*time.Millisecond, a, b)
+
+	// Test requests are sent to the first node
+	num, err := blockNumber(client)
+	require.Nil(err)
+	assert.Equal(uint64(1), num)
+
+	// Test server errors fail over to the next node, which is preferred afterwards
+	atomic.StoreInt32(&a.status, http.StatusBadGateway)
+	num, err = blockNumber(client)
+	require.Nil(err)
+	assert.Equal(uint64(2), num)
+	assert.NotNil(ft.endpoints[0].err)
+
+	a.reset()
+	num, err = blockNumber(client)
+	require.Nil(err)
+	assert.Equal(uint64(2), num)
+	assert.Zero(atomic.LoadInt32(&a.requests))
+
+	// Test timeouts fail over to the next node
+	atomic.StoreInt32(&a.status, 0)
+	ft.checkHealth()
+	assert.Nil(ft.endpoints[0].err)
+	atomic.StoreInt64(&b.delay, int64(100*time.Millisecond))
+	ft.endpoints[1].latency = 0
+	num, err = blockNumber(client)
+	require.Nil(err)
+	assert.Equal(uint64(1), num)
+	assert.NotNil(ft.endpoints[1].err)
+	atomic.StoreInt64(&b.delay, 0)
+
+	// Test JSON-RPC errors don't fail over
+	a.rpcErr.Store("execution reverted")
+	b.reset()
+	_, err = blockNumber(client)
+	assert.EqualError(err, "execution reverted")
+	assert.Zero(atomic.LoadInt32(&b.requests))
+	assert.Nil(ft.endpoints[0].err)
+
+	// Test all nodes failing
+	atomic.StoreInt32(&a.status, http.StatusServiceUnavailable)
+	atomic.StoreInt32(&b.status, http.StatusTooManyRequests)
+	_, err = blockNumber(client)
+	require.NotNil(err)
+	assert.Contains(err.Error(), "429 Too Many Requests")
+
+	// Test unhealthy nodes are still tried
+	atomic.StoreInt32(&b.status, 0)
+	num, err = blockNumber(client)
+	require.Nil(err)
+	assert.Equal(uint64(2), num)
+}
+
+func TestRPCFailover_CheckHealth(t *testing.T) {
+	assert := assert.New(t)
+
+	a := newStubRPCNode(1)
+	defer a.Close()
+	b := newStubRPCNode(2)
+	defer b.Close()
+	c := newStubRPCNode(3)
+	defer c.Close()
+	_, ft := newFailoverClient(t, time.Second, a, b, c)
+
+	// Test healthy nodes are preferred from the lowest latency
+	atomic.StoreInt64(&a.delay, int64(20*time.Millisecond))
+	atomic.StoreInt32(&c.status, http.StatusInternalServerError)
+	ft.checkHealth()
+	preferred := ft.preferred()
+	assert.Equal(b.URL, preferred[0].url.String())
+	assert.Equal(a.URL, preferred[1].url.String())
+	assert.Equal(c.URL, preferred[2].url.String())
+	assert.NotNil(preferred[2].err)
+
+	// Test JSON-RPC errors make nodes unhealthy
+	b.rpcErr.Store("header not found")
+	ft.checkHealth()
+	assert.Equal(a.URL, ft.preferred()[0].url.String())
+	assert.Contains(ft.endpoints[1].err.Error(), "header not found")
+}
+
+func TestDialRPCFailover(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := DialRPCFailover(context.Background(), RPCFailoverConfig{})
+	assert.Equal(ErrNoRPCEndpoints, err)
+
+	_, err = DialRPCFailover(context.Background(), RPCFailoverConfig{URLs: []string{"http://127.0.0.1:8545", "ws://127.0.0.1:8546"}})
+	assert.EqualError(err, "Ethereum node JSON-RPC URL ws://127.0.0.1:8546 must be an HTTP(S) URL to fail over between several nodes")
+
+	a := newStubRPCNode(1)
+	defer a.Close()
+	client, err := DialRPCFailover(context.Background(), RPCFailoverConfig{URLs: []string{a.URL}})
+	assert.Nil(err)
+	num, err := blockNumber(client)
+	assert.Nil(err)
+	assert.Equal(uint64(1), num)
+
+	// Test the health checks stop once the context is done
+	ft, err := newFailoverTransport([]string{a.URL, a.URL}, time.Second, http.DefaultTransport)
+	assert.Nil(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ft.healthCheckLoop(ctx, time.Millisecond)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("health checks did not stop")
+	}
+}
@@ -0,0 +1,223 @@
+package eth
+
+import (
+	"fmt"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	lpTypes "github.com/livepeer/go-livepeer/eth/types"
+)
+
+// Types of the bonding transactions that can be previewed
+const (
+	StakeActionBond          = "bond"
+	StakeActionRebond        = "rebond"
+	StakeActionUnbond        = "unbond"
+	StakeActionWithdrawStake = "withdrawStake"
+	StakeActionMoveStake     = "moveStake"
+)
+
+// StakePreview is the stake of a delegator before and after a bonding transaction, so that
+// operators can check the result of a transaction before sending it
+type StakePreview struct {
+	Action    string            `json:"action"`
+	Delegator ethcommon.Address `json:"delegator"`
+
+	// Stake is the bonded stake of the delegator, including its unclaimed rewards, which are
+	// claimed by the transaction
+	Stake    *big.Int          `json:"stake"`
+	Delegate ethcommon.Address `json:"delegate"`
+	Status   string            `json:"status"`
+
+	NewStake    *big.Int          `json:"newStake"`
+	NewDelegate ethcommon.Address `json:"newDelegate"`
+	NewStatus   string            `json:"newStatus"`
+
+	// Unbonding is the stake locked by an unbond until WithdrawRound
+	Unbonding     *big.Int `json:"unbonding,omitempty"`
+	WithdrawRound *big.Int `json:"withdrawRound,omitempty"`
+	// Withdrawn is the stake returned to the wallet by a withdrawal
+	Withdrawn *big.Int `json:"withdrawn,omitempty"`
+}
+
+// PreviewBond previews bonding amount to the orchestrator to, which also moves the stake of
+// the delegator to it
+func PreviewBond(client LivepeerEthClient, amount *big.Int, to ethcommon.Address) (*StakePreview, error) {
+	p, err := newStakePreview(client, StakeActionBond)
+	if err != nil {
+		return nil, err
+	}
+
+	if amount.Sign() < 0 {
+		return nil, fmt.Errorf("bond amount %v must not be negative", amount)
+	}
+
+	balance, err := client.BalanceOf(p.Delegator)
+	if err != nil {
+		return nil, err
+	}
+	if balance.Cmp(amount) < 0 {
+		return nil, fmt.Errorf("bond amount %v exceeds the token balance %v", amount, balance)
+	}
+
+	p.NewStake = new(big.Int).Add(p.Stake, amount)
+	p.delegateTo(to)
+
+	return p, nil
+}
+
+// PreviewRebond previews rebonding the stake of an unbonding lock, to the orchestrator to if
+// the delegator is unbonded
+func PreviewRebond(client LivepeerEthClient, unbondingLockID *big.Int, to ethcommon.Address) (*StakePreview, error) {
+	p, err := newStakePreview(client, StakeActionRebond)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := p.unbondingLock(client, unbondingLockID)
+	if err != nil {
+		return nil, err
+	}
+
+	p.NewStake = new(big.Int).Add(p.Stake, lock.Amount)
+	if p.Status == "Unbonded" {
+		if (to == ethcommon.Address{}) {
+			return nil, fmt.Errorf("an address to rebond to is required to rebond from unbonded")
+		}
+		p.delegateTo(to)
+	} else {
+		p.NewDelegate = p.Delegate
+		p.NewStatus = p.Status
+	}
+
+	return p, nil
+}
+
+// PreviewUnbond previews unbonding amount of the stake of the delegator
+func PreviewUnbond(client LivepeerEthClient, amount *big.Int) (*StakePreview, error) {
+	p, err := newStakePreview(client, StakeActionUnbond)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Status == "Unbonded" {
+		return nil, fmt.Errorf("delegator is not bonded")
+	}
+	if amount.Sign() <= 0 || amount.Cmp(p.Stake) > 0 {
+		return nil, fmt.Errorf("unbond amount %v must be positive and at most the stake %v", amount, p.Stake)
+	}
+
+	currentRound, err := client.CurrentRound()
+	if err != nil {
+		return nil, err
+	}
+	unbondingPeriod, err := client.UnbondingPeriod()
+	if err != nil {
+		return nil, err
+	}
+
+	p.NewStake = new(big.Int).Sub(p.Stake, amount)
+	p.NewDelegate = p.Delegate
+	p.NewStatus = p.Status
+	if p.NewStake.Sign() == 0 {
+		p.NewStatus = "Unbonded"
+	}
+	p.Unbonding = amount
+	p.WithdrawRound = new(big.Int).Add(currentRound, new(big.Int).SetUint64(unbondingPeriod))
+
+	return p, nil
+}
+
+// PreviewWithdrawStake previews withdrawing the stake of an unbonding lock
+func PreviewWithdrawStake(client LivepeerEthClient, unbondingLockID *big.Int) (*StakePreview, error) {
+	p, err := newStakePreview(client, StakeActionWithdrawStake)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := p.unbondingLock(client, unbondingLockID)
+	if err != nil {
+		return nil, err
+	}
+
+	currentRound, err := client.CurrentRound()
+	if err != nil {
+		return nil, err
+	}
+	if lock.WithdrawRound.Cmp(currentRound) > 0 {
+		return nil, fmt.Errorf("unbonding lock %v can't be withdrawn before round %v", unbondingLockID, lock.WithdrawRound)
+	}
+
+	p.NewStake = p.Stake
+	p.NewDelegate = p.Delegate
+	p.NewStatus = p.Status
+	p.Withdrawn = lock.Amount
+
+	return p, nil
+}
+
+// PreviewMoveStake previews moving the whole stake of the delegator to the orchestrator to
+func PreviewMoveStake(client LivepeerEthClient, to ethcommon.Address) (*StakePreview, error) {
+	p, err := newStakePreview(client, StakeActionMoveStake)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Status == "Unbonded" || p.Stake.Sign() == 0 {
+		return nil, fmt.Errorf("delegator has no stake to move")
+	}
+	if to == p.Delegate {
+		return nil, fmt.Errorf("stake is already delegated to %v", to.Hex())
+	}
+
+	p.NewStake = p.Stake
+	p.delegateTo(to)
+
+	return p, nil
+}
+
+func newStakePreview(client LivepeerEthClient, action string) (*StakePreview, error) {
+	addr := client.Account().Address
+	d, err := client.GetDelegator(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// The pending stake is -1 if it can't be computed, e.g. before the first round of the
+	// delegator
+	stake := d.BondedAmount
+	if d.PendingStake != nil && d.PendingStake.Sign() >= 0 {
+		stake = d.PendingStake
+	}
+
+	return &StakePreview{
+		Action:    action,
+		Delegator: addr,
+		Stake:     stake,
+		Delegate:  d.DelegateAddress,
+		Status:    d.Status,
+	}, nil
+}
+
+// delegateTo sets the new delegate, whose stake is pending until the next round if the
+// delegate changes or the delegator was unbonded
+func (p *StakePreview) delegateTo(to ethcommon.Address) {
+	p.NewDelegate = to
+	p.NewStatus = p.Status
+	if to != p.Delegate || p.Status == "Unbonded" {
+		p.NewStatus = "Pending"
+	}
+}
+
+func (p *StakePreview) unbondingLock(client LivepeerEthClient, unbondingLockID *big.Int) (*lpTypes.UnbondingLock, error) {
+	lock, err := client.GetDelegatorUnbondingLock(p.Delegator, unbondingLockID)
+	if err != nil {
+		return nil, err
+	}
+	// Used and missing locks have a withdraw round of 0
+	if lock.WithdrawRound == nil || lock.WithdrawRound.Sign() == 0 {
+		return nil, fmt.Errorf("unbonding lock %v does not exist or was already used", unbondingLockID)
+	}
+
+	return lock, nil
+}
@@ -0,0 +1,176 @@
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	lpTypes "github.com/livepeer/go-livepeer/eth/types"
+	"github.com/livepeer/go-livepeer/pm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubStakeClient struct {
+	*StubClient
+	delegator *lpTypes.Delegator
+	lock      *lpTypes.UnbondingLock
+	balance   *big.Int
+	round     *big.Int
+}
+
+func newStubStakeClient() *stubStakeClient {
+	return &stubStakeClient{
+		StubClient: &StubClient{TranscoderAddress: pm.RandAddress()},
+		delegator: &lpTypes.Delegator{
+			BondedAmount:    big.NewInt(100),
+			PendingStake:    big.NewInt(110),
+			DelegateAddress: pm.RandAddress(),
+			Status:          "Bonded",
+		},
+		lock:    &lpTypes.UnbondingLock{Amount: big.NewInt(50), WithdrawRound: big.NewInt(12)},
+		balance: big.NewInt(1000),
+		round:   big.NewInt(10),
+	}
+}
+
+func (c *stubStakeClient) GetDelegator(addr ethcommon.Address) (*lpTypes.Delegator, error) {
+	return c.delegator, nil
+}
+
+func (c *stubStakeClient) GetDelegatorUnbondingLock(addr ethcommon.Address, id *big.Int) (*lpTypes.UnbondingLock, error) {
+	return c.lock, nil
+}
+
+func (c *stubStakeClient) BalanceOf(addr ethcommon.Address) (*big.Int, error) { return c.balance, nil }
+func (c *stubStakeClient) CurrentRound() (*big.Int, error)                    { return c.round, nil }
+func (c *stubStakeClient) UnbondingPeriod() (uint64, error)                   { return 7, nil }
+
+func TestPreviewBond(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	client := newStubStakeClient()
+
+	// Test bonding more to the same delegate
+	p, err := PreviewBond(client, big.NewInt(40), client.delegator.DelegateAddress)
+	require.Nil(err)
+	assert.Equal(StakeActionBond, p.Action)
+	assert.Equal(client.Account().Address, p.Delegator)
+	assert.Equal(big.NewInt(110), p.Stake)
+	assert.Equal(big.NewInt(150), p.NewStake)
+	assert.Equal("Bonded", p.NewStatus)
+
+	// Test bonding to another delegate
+	to := pm.RandAddress()
+	p, err = PreviewBond(client, big.NewInt(40), to)
+	require.Nil(err)
+	assert.Equal(to, p.NewDelegate)
+	assert.Equal("Pending", p.NewStatus)
+
+	// Test the bonded amount is used without a pending stake
+	client.delegator.PendingStake = big.NewInt(-1)
+	p, err = PreviewBond(client, big.NewInt(0), to)
+	require.Nil(err)
+	assert.Equal(big.NewInt(100), p.NewStake)
+
+	// Test the token balance must cover the amount
+	_, err = PreviewBond(client, big.NewInt(1001), to)
+	assert.EqualError(err, "bond amount 1001 exceeds the token balance 1000")
+}
+
+func TestPreviewRebond(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	client := newStubStakeClient()
+
+	p, err := PreviewRebond(client, big.NewInt(0), ethcommon.Address{})
+	require.Nil(err)
+	assert.Equal(big.NewInt(160), p.NewStake)
+	assert.Equal(client.delegator.DelegateAddress, p.NewDelegate)
+	assert.Equal("Bonded", p.NewStatus)
+
+	// Test rebonding from unbonded requires a delegate
+	client.delegator.Status = "Unbonded"
+	client.delegator.PendingStake = big.NewInt(0)
+	_, err = PreviewRebond(client, big.NewInt(0), ethcommon.Address{})
+	assert.EqualError(err, "an address to rebond to is required to rebond from unbonded")
+
+	to := pm.RandAddress()
+	p, err = PreviewRebond(client, big.NewInt(0), to)
+	require.Nil(err)
+	assert.Equal(big.NewInt(50), p.NewStake)
+	assert.Equal(to, p.NewDelegate)
+	assert.Equal("Pending", p.NewStatus)
+
+	// Test used unbonding locks
+	client.lock.WithdrawRound = big.NewInt(0)
+	_, err = PreviewRebond(client, big.NewInt(3), to)
+	assert.EqualError(err, "unbonding lock 3 does not exist or was already used")
+}
+
+func TestPreviewUnbond(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	client := newStubStakeClient()
+
+	p, err := PreviewUnbond(client, big.NewInt(60))
+	require.Nil(err)
+	assert.Equal(big.NewInt(50), p.NewStake)
+	assert.Equal("Bonded", p.NewStatus)
+	assert.Equal(big.NewInt(60), p.Unbonding)
+	assert.Equal(big.NewInt(17), p.WithdrawRound)
+
+	// Test unbonding the whole stake
+	p, err = PreviewUnbond(client, big.NewInt(110))
+	require.Nil(err)
+	assert.Zero(p.NewStake.Sign())
+	assert.Equal("Unbonded", p.NewStatus)
+
+	_, err = PreviewUnbond(client, big.NewInt(111))
+	assert.EqualError(err, "unbond amount 111 must be positive and at most the stake 110")
+
+	client.delegator.Status = "Unbonded"
+	_, err = PreviewUnbond(client, big.NewInt(1))
+	assert.EqualError(err, "delegator is not bonded")
+}
+
+func TestPreviewWithdrawStake(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	client := newStubStakeClient()
+
+	// Test locks can't be withdrawn before their withdraw round
+	_, err := PreviewWithdrawStake(client, big.NewInt(1))
+	assert.EqualError(err, "unbonding lock 1 can't be withdrawn before round 12")
+
+	client.round = big.NewInt(12)
+	p, err := PreviewWithdrawStake(client, big.NewInt(1))
+	require.Nil(err)
+	assert.Equal(big.NewInt(110), p.NewStake)
+	assert.Equal(big.NewInt(50), p.Withdrawn)
+}
+
+func TestPreviewMoveStake(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	client := newStubStakeClient()
+
+	to := pm.RandAddress()
+	p, err := PreviewMoveStake(client, to)
+	require.Nil(err)
+	assert.Equal(big.NewInt(110), p.NewStake)
+	assert.Equal(to, p.NewDelegate)
+	assert.Equal("Pending", p.NewStatus)
+
+	_, err = PreviewMoveStake(client, client.delegator.DelegateAddress)
+	assert.Contains(err.Error(), "stake is already delegated to")
+
+	client.delegator.Status = "Unbonded"
+	_, err = PreviewMoveStake(client, to)
+	assert.EqualError(err, "delegator has no stake to move")
+}
@@ -60,6 +60,51 @@ func (m *MockClient) GetTranscoderPoolMaxSize() (*big.Int, error) {
 	return mockBigInt(args, 0), args.Error(1)
 }
 
+func (m *MockClient) GetTranscoder(addr common.Address) (*lpTypes.Transcoder, error) {
+	args := m.Called(addr)
+	t, _ := args.Get(0).(*lpTypes.Transcoder)
+	return t, args.Error(1)
+}
+
+func (m *MockClient) GetDelegator(addr common.Address) (*lpTypes.Delegator, error) {
+	args := m.Called(addr)
+	d, _ := args.Get(0).(*lpTypes.Delegator)
+	return d, args.Error(1)
+}
+
+func (m *MockClient) Bond(amount *big.Int, toAddr common.Address) (*types.Transaction, error) {
+	args := m.Called(amount, toAddr)
+	return mockTransaction(args, 0), args.Error(1)
+}
+
+func (m *MockClient) MoveStake(toAddr common.Address) (*types.Transaction, error) {
+	args := m.Called(toAddr)
+	return mockTransaction(args, 0), args.Error(1)
+}
+
+func (m *MockClient) GetDelegatorUnbondingLock(addr common.Address, unbondingLockId *big.Int) (*lpTypes.UnbondingLock, error) {
+	args := m.Called(addr, unbondingLockId)
+	l, _ := args.Get(0).(*lpTypes.UnbondingLock)
+	return l, args.Error(1)
+}
+
+func (m *MockClient) Transcoder(blockRewardCut, feeShare *big.Int) (*types.Transaction, error) {
+	args := m.Called(blockRewardCut, feeShare)
+	return mockTransaction(args, 0), args.Error(1)
+}
+
+// ServiceRegistry
+
+func (m *MockClient) SetServiceURI(serviceURI string) (*types.Transaction, error) {
+	args := m.Called(serviceURI)
+	return mockTransaction(args, 0), args.Error(1)
+}
+
+func (m *MockClient) GetServiceURI(addr common.Address) (string, error) {
+	args := m.Called(addr)
+	return args.String(0), args.Error(1)
+}
+
 // RoundsManager
 
 // InitializeRound submits a round initialization transaction
@@ -81,6 +126,11 @@ func (m *MockClient) CurrentRoundInitialized() (bool, error) {
 }
 
 // CurrentRoundStartBlock returns the block number that the current round started in
+func (m *MockClient) CurrentRoundLocked() (bool, error) {
+	args := m.Called()
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockClient) CurrentRoundStartBlock() (*big.Int, error) {
 	args := m.Called()
 	return mockBigInt(args, 0), args.Error(1)
@@ -176,6 +226,7 @@ type StubClient struct {
 	PoolSize                     *big.Int
 	ClaimedAmount                *big.Int
 	ClaimedReserveError          error
+	ReserveAllocToReturn         *big.Int
 	Orch                         *lpTypes.Transcoder
 	Err                          error
 	CheckTxErr                   error
@@ -190,6 +241,7 @@ type stubTranscoder struct {
 func (e *StubClient) Setup(password string, gasLimit uint64, gasPrice *big.Int) error { return nil }
 func (e *StubClient) Account() accounts.Account                                       { return accounts.Account{Address: e.TranscoderAddress} }
 func (e *StubClient) Backend() (Backend, error)                                       { return nil, nil }
+func (e *StubClient) Close()                                                          {}
 
 // Rounds
 
@@ -240,6 +292,9 @@ func (e *StubClient) Unbond(*big.Int) (*types.Transaction, error) { return nil,
 func (e *StubClient) WithdrawStake(*big.Int) (*types.Transaction, error) {
 	return nil, nil
 }
+func (e *StubClient) MoveStake(toAddr common.Address) (*types.Transaction, error) {
+	return nil, nil
+}
 func (e *StubClient) WithdrawFees() (*types.Transaction, error) { return nil, nil }
 func (e *StubClient) ClaimEarnings(endRound *big.Int) error {
 	return nil
@@ -298,6 +353,9 @@ func (e *StubClient) Withdraw() (*types.Transaction, error) {
 func (e *StubClient) RedeemWinningTicket(ticket *pm.Ticket, sig []byte, recipientRand *big.Int) (*types.Transaction, error) {
 	return nil, nil
 }
+func (e *StubClient) BatchRedeemWinningTickets(tickets []*pm.Ticket, sigs [][]byte, recipientRands []*big.Int) (*types.Transaction, error) {
+	return nil, nil
+}
 func (e *StubClient) IsUsedTicket(ticket *pm.Ticket) (bool, error) {
 	return true, nil
 }
@@ -313,6 +371,9 @@ func (e *StubClient) GetSenderInfo(addr ethcommon.Address) (*pm.SenderInfo, erro
 func (e *StubClient) ClaimableReserve(reserveHolder, claimant ethcommon.Address) (*big.Int, error) {
 	return nil, nil
 }
+func (e *StubClient) ReserveAlloc(reserveHolder, claimant ethcommon.Address) (*big.Int, error) {
+	return e.ReserveAllocToReturn, nil
+}
 func (e *StubClient) UnlockPeriod() (*big.Int, error) {
 	return nil, nil
 }
@@ -336,6 +397,9 @@ func (c *StubClient) ReplaceTransaction(tx *types.Transaction, method string, ga
 	return nil, nil
 }
 func (c *StubClient) Sign(msg []byte) ([]byte, error)   { return msg, c.Err }
+func (c *StubClient) SignTypedData(typedData *pm.TypedData) ([]byte, error) {
+	return typedData.Hash().Bytes(), c.Err
+}
 func (c *StubClient) GetGasInfo() (uint64, *big.Int)    { return 0, nil }
 func (c *StubClient) SetGasInfo(uint64, *big.Int) error { return nil }
 
@@ -0,0 +1,341 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/common"
+)
+
+// DefaultTxStuckTimeout is the default time after which a pending transaction is replaced
+// with a bumped gas price
+const DefaultTxStuckTimeout = 3 * time.Minute
+
+var (
+	// pendingTxsCheckInterval is the interval at which pending transactions are checked
+	pendingTxsCheckInterval = 15 * time.Second
+	// waitMinedInterval is the interval at which the receipts of transactions are polled
+	waitMinedInterval = time.Second
+	// minedTxRetention is the time that mined transactions are kept for, so that the callers
+	// waiting for a transaction that got replaced still find the receipt of the replacement
+	minedTxRetention = 10 * time.Minute
+)
+
+// TxManagerConfig configures a TxManager
+type TxManagerConfig struct {
+	// StuckTimeout is the time after which a pending transaction is replaced with a bumped
+	// gas price, disabled if 0
+	StuckTimeout time.Duration
+	// MaxGasPrice caps the gas price of replacement transactions if it is not nil
+	MaxGasPrice *big.Int
+}
+
+// pendingTx holds the transactions sent with a nonce
+type pendingTx struct {
+	// txs are the transaction and its replacements, the latest last
+	txs    []*types.Transaction
+	sentAt time.Time
+	// minedAt is the time that a transaction of the nonce was found mined
+	minedAt time.Time
+}
+
+// TxManager sends the transactions of an account one at a time: each transaction is signed
+// with the next nonce of the account and sent before the next one is signed, so that
+// concurrent transactions, e.g. ticket redemptions, never share a nonce. It tracks the
+// pending transactions and replaces the ones that are not mined after the stuck timeout with
+// the same transaction at a bumped gas price
+type TxManager struct {
+	Backend
+	am  AccountManager
+	cfg TxManagerConfig
+
+	// sendMu is held from signing a transaction to sending it
+	sendMu sync.Mutex
+
+	mu sync.Mutex
+	// signed is the hash of the transaction signed under sendMu
+	signed  ethcommon.Hash
+	pending map[uint64]*pendingTx
+
+	quit chan struct{}
+}
+
+// NewTxManager creates a TxManager that sends the transactions of the account of am with
+// backend
+func NewTxManager(backend Backend, am AccountManager, cfg TxManagerConfig) *TxManager {
+	return &TxManager{
+		Backend: backend,
+		am:      am,
+		cfg:     cfg,
+		pending: make(map[uint64]*pendingTx),
+		quit:    make(chan struct{}),
+	}
+}
+
+// Start kicks off a loop that checks the pending transactions
+func (tm *TxManager) Start() {
+	ticker := time.NewTicker(pendingTxsCheckInterval)
+
+	for {
+		select {
+		case <-tm.quit:
+			ticker.Stop()
+			return
+		case <-ticker.C:
+			if err := tm.checkPendingTxs(); err != nil {
+				glog.Errorf("Error checking pending transactions: %v", err)
+			}
+		}
+	}
+}
+
+// Stop signals the loop to exit gracefully
+func (tm *TxManager) Stop() {
+	close(tm.quit)
+}
+
+// Signer wraps the signer of transact opts so that transactions are signed with the next
+// nonce of the account, one at a time. A transaction signed by it must be sent with
+// SendTransaction, which bound contracts do right after signing it
+func (tm *TxManager) Signer(signFn bind.SignerFn) bind.SignerFn {
+	return func(signer types.Signer, addr ethcommon.Address, tx *types.Transaction) (*types.Transaction, error) {
+		tm.sendMu.Lock()
+
+		// The nonce picked by the bound contract may have been used by a transaction
+		// sent since then
+		nonce, err := tm.Backend.PendingNonceAt(context.Background(), addr)
+		if err != nil {
+			tm.sendMu.Unlock()
+			return nil, err
+		}
+		if nonce != tx.Nonce() {
+			tx = newTransaction(nonce, tx.To(), tx.Value(), tx.Gas(), tx.GasPrice(), tx.Data())
+		}
+
+		signedTx, err := signFn(signer, addr, tx)
+		if err != nil {
+			tm.sendMu.Unlock()
+			return nil, err
+		}
+
+		tm.mu.Lock()
+		tm.signed = signedTx.Hash()
+		tm.mu.Unlock()
+
+		return signedTx, nil
+	}
+}
+
+// SendTransaction sends a transaction and tracks it until it is mined
+func (tm *TxManager) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	tm.mu.Lock()
+	signed := tm.signed == tx.Hash()
+	if signed {
+		tm.signed = ethcommon.Hash{}
+	}
+	tm.mu.Unlock()
+
+	// Transactions that were not signed by Signer, e.g. replacements, wait for the
+	// transaction being signed to be sent
+	if !signed {
+		tm.sendMu.Lock()
+	}
+	defer tm.sendMu.Unlock()
+
+	if err := tm.Backend.SendTransaction(ctx, tx); err != nil {
+		return err
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	p, ok := tm.pending[tx.Nonce()]
+	if !ok {
+		p = &pendingTx{}
+		tm.pending[tx.Nonce()] = p
+	}
+	p.txs = append(p.txs, tx)
+	p.sentAt = time.Now()
+
+	return nil
+}
+
+// WaitMined waits for a transaction or for one of its replacements to be mined and returns
+// its receipt
+func (tm *TxManager) WaitMined(ctx context.Context, tx *types.Transaction) (*types.Receipt, error) {
+	ticker := time.NewTicker(waitMinedInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, t := range tm.replacements(tx) {
+			receipt, err := tm.Backend.TransactionReceipt(ctx, t.Hash())
+			if receipt != nil {
+				return receipt, nil
+			}
+			if err != nil && err != ethereum.NotFound {
+				glog.V(common.DEBUG).Infof("Error getting receipt of tx=%v: %v", t.Hash().Hex(), err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// replacements returns a transaction and the transactions that replaced it
+func (tm *TxManager) replacements(tx *types.Transaction) []*types.Transaction {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	p, ok := tm.pending[tx.Nonce()]
+	if !ok {
+		return []*types.Transaction{tx}
+	}
+	for _, t := range p.txs {
+		if t.Hash() == tx.Hash() {
+			return append([]*types.Transaction(nil), p.txs...)
+		}
+	}
+
+	return []*types.Transaction{tx}
+}
+
+// ReplaceTransaction replaces a pending transaction with the same transaction at gasPrice, or
+// at a bumped gas price if gasPrice is nil
+func (tm *TxManager) ReplaceTransaction(tx *types.Transaction, method string, gasPrice *big.Int) (*types.Transaction, error) {
+	_, pending, err := tm.Backend.TransactionByHash(context.Background(), tx.Hash())
+	// Only return here if the error is not related to the tx not being found
+	// Presumably the provided tx was already broadcasted at some point, so even if for some reason the
+	// node being used cannot find it, the originally broadcasted tx is still valid and might be sitting somewhere
+	if err != nil && err != ethereum.NotFound {
+		return nil, err
+	}
+	// If tx was found
+	// If `pending` is true, the tx was mined and included in a block
+	if err == nil && !pending {
+		return nil, ErrReplacingMinedTx
+	}
+
+	// Updated gas price must be at least 10% greater than the gas price used for the original transaction in order
+	// to submit a replacement transaction with the same nonce. 10% is not defined by the protocol, but is the default required price bump
+	// used by many clients: https://github.com/ethereum/go-ethereum/blob/01a7e267dc6d7bbef94882542bbd01bd712f5548/core/tx_pool.go#L148
+	// We add a little extra in addition to the 10% price bump just to be sure
+	minGasPrice := big.NewInt(0).Add(big.NewInt(0).Add(tx.GasPrice(), big.NewInt(0).Div(tx.GasPrice(), big.NewInt(10))), big.NewInt(10))
+
+	// If gas price is not provided, use minimum gas price that satisfies the 10% required price bump
+	if gasPrice == nil {
+		gasPrice = minGasPrice
+
+		suggestedGasPrice, err := tm.Backend.SuggestGasPrice(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		// If the suggested gas price is higher than the bumped gas price, use the suggested gas price
+		// This is to account for any wild market gas price increases between the time of the original tx submission and time
+		// of replacement tx submission
+		// Note: If the suggested gas price is lower than the bumped gas price because market gas prices have dropped
+		// since the time of the original tx submission we cannot use the lower suggested gas price and we still need to use
+		// the bumped gas price in order to properly replace a still pending tx
+		if suggestedGasPrice.Cmp(gasPrice) == 1 {
+			gasPrice = suggestedGasPrice
+		}
+
+		if max := tm.cfg.MaxGasPrice; max != nil && gasPrice.Cmp(max) > 0 {
+			if minGasPrice.Cmp(max) > 0 {
+				return nil, fmt.Errorf("bumped gas price %v to replace transaction %v exceeds the max gas price %v", minGasPrice, tx.Hash().Hex(), max)
+			}
+			gasPrice = max
+		}
+	}
+
+	// Check that gas price meets minimum price bump requirement
+	if gasPrice.Cmp(minGasPrice) == -1 {
+		return nil, fmt.Errorf("Provided gas price does not satisfy required price bump to replace transaction %v", tx.Hash())
+	}
+
+	// Replacement raw tx uses same fields as old tx (reusing the same nonce is crucial) except the gas price is updated
+	newRawTx := newTransaction(tx.Nonce(), tx.To(), tx.Value(), tx.Gas(), gasPrice, tx.Data())
+
+	newSignedTx, err := tm.am.SignTx(newRawTx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tm.SendTransaction(context.Background(), newSignedTx)
+	if err == nil {
+		glog.Infof("\n%vEth Transaction%v\n\nReplacement transaction: \"%v\".  Hash: \"%v\".  Gas Price: %v \n\n%v\n", strings.Repeat("*", 30), strings.Repeat("*", 30), method, newSignedTx.Hash().String(), newSignedTx.GasPrice().String(), strings.Repeat("*", 75))
+	} else {
+		glog.Infof("\n%vEth Transaction%v\n\nReplacement transaction: \"%v\".  Gas Price: %v \nTransaction Failed: %v\n\n%v\n", strings.Repeat("*", 30), strings.Repeat("*", 30), method, newSignedTx.GasPrice().String(), err, strings.Repeat("*", 75))
+	}
+
+	return newSignedTx, err
+}
+
+// checkPendingTxs drops the transactions that have been mined for long enough and replaces
+// the transactions that are stuck, lowest nonce first since the others can't be mined first
+func (tm *TxManager) checkPendingTxs() error {
+	nonce, err := tm.Backend.NonceAt(context.Background(), tm.am.Account().Address, nil)
+	if err != nil {
+		return err
+	}
+
+	var stuck []*types.Transaction
+	now := time.Now()
+
+	tm.mu.Lock()
+	for n, p := range tm.pending {
+		if n < nonce {
+			if p.minedAt.IsZero() {
+				p.minedAt = now
+			} else if now.Sub(p.minedAt) >= minedTxRetention {
+				delete(tm.pending, n)
+			}
+			continue
+		}
+
+		if tm.cfg.StuckTimeout > 0 && now.Sub(p.sentAt) >= tm.cfg.StuckTimeout {
+			stuck = append(stuck, p.txs[len(p.txs)-1])
+		}
+	}
+	tm.mu.Unlock()
+
+	sort.Slice(stuck, func(i, j int) bool { return stuck[i].Nonce() < stuck[j].Nonce() })
+
+	for _, tx := range stuck {
+		glog.Infof("Replacing transaction stuck for over %v nonce=%v hash=%v", tm.cfg.StuckTimeout, tx.Nonce(), tx.Hash().Hex())
+
+		if _, err := tm.ReplaceTransaction(tx, "stuck transaction", nil); err != nil {
+			glog.Errorf("Error replacing stuck transaction nonce=%v hash=%v: %v", tx.Nonce(), tx.Hash().Hex(), err)
+			// Try again after another stuck timeout
+			tm.mu.Lock()
+			if p, ok := tm.pending[tx.Nonce()]; ok {
+				p.sentAt = now
+			}
+			tm.mu.Unlock()
+		}
+	}
+
+	return nil
+}
+
+func newTransaction(nonce uint64, to *ethcommon.Address, value *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *types.Transaction {
+	if to == nil {
+		return types.NewContractCreation(nonce, value, gasLimit, gasPrice, data)
+	}
+
+	return types.NewTransaction(nonce, *to, value, gasLimit, gasPrice, data)
+}
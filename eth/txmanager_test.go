@@ -0,0 +1,280 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/livepeer/go-livepeer/pm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubTxBackend is a Backend with a tx pool that refuses transactions with the nonce of a
+// pending transaction unless their gas price is bumped
+type stubTxBackend struct {
+	Backend
+
+	mu       sync.Mutex
+	pool     map[uint64]*types.Transaction
+	receipts map[ethcommon.Hash]*types.Receipt
+	nonce    uint64
+	gasPrice *big.Int
+	sendErr  error
+}
+
+func newStubTxBackend() *stubTxBackend {
+	return &stubTxBackend{
+		pool:     make(map[uint64]*types.Transaction),
+		receipts: make(map[ethcommon.Hash]*types.Receipt),
+		gasPrice: big.NewInt(100),
+	}
+}
+
+func (b *stubTxBackend) PendingNonceAt(ctx context.Context, addr ethcommon.Address) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return uint64(len(b.pool)), nil
+}
+
+func (b *stubTxBackend) NonceAt(ctx context.Context, addr ethcommon.Address, blockNumber *big.Int) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.nonce, nil
+}
+
+func (b *stubTxBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.sendErr != nil {
+		return b.sendErr
+	}
+	if pending, ok := b.pool[tx.Nonce()]; ok && tx.GasPrice().Cmp(pending.GasPrice()) <= 0 {
+		return errors.New("replacement transaction underpriced")
+	}
+	b.pool[tx.Nonce()] = tx
+	return nil
+}
+
+func (b *stubTxBackend) TransactionByHash(ctx context.Context, hash ethcommon.Hash) (*types.Transaction, bool, error) {
+	return nil, false, ethereum.NotFound
+}
+
+func (b *stubTxBackend) TransactionReceipt(ctx context.Context, hash ethcommon.Hash) (*types.Receipt, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if receipt, ok := b.receipts[hash]; ok {
+		return receipt, nil
+	}
+	return nil, ethereum.NotFound
+}
+
+func (b *stubTxBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return b.gasPrice, nil
+}
+
+type stubTxAccountManager struct {
+	AccountManager
+	key    *ecdsa.PrivateKey
+	signer types.Signer
+}
+
+func newStubTxAccountManager(t *testing.T) *stubTxAccountManager {
+	key, err := ethcrypto.GenerateKey()
+	require.Nil(t, err)
+	return &stubTxAccountManager{key: key, signer: types.NewEIP155Signer(big.NewInt(4))}
+}
+
+func (am *stubTxAccountManager) Account() accounts.Account {
+	return accounts.Account{Address: ethcrypto.PubkeyToAddress(am.key.PublicKey)}
+}
+
+func (am *stubTxAccountManager) SignTx(tx *types.Transaction) (*types.Transaction, error) {
+	return types.SignTx(tx, am.signer, am.key)
+}
+
+func (am *stubTxAccountManager) signFn(signer types.Signer, addr ethcommon.Address, tx *types.Transaction) (*types.Transaction, error) {
+	return am.SignTx(tx)
+}
+
+// sendTx sends a transaction like a bound contract that picked nonce
+func sendTx(tm *TxManager, am *stubTxAccountManager, nonce uint64) (*types.Transaction, error) {
+	tx := types.NewTransaction(nonce, pm.RandAddress(), big.NewInt(0), 21000, big.NewInt(100), nil)
+	signed, err := tm.Signer(am.signFn)(am.signer, am.Account().Address, tx)
+	if err != nil {
+		return nil, err
+	}
+	return signed, tm.SendTransaction(context.Background(), signed)
+}
+
+func TestTxManager_Stop(t *testing.T) {
+	tm := NewTxManager(newStubTxBackend(), newStubTxAccountManager(t), TxManagerConfig{})
+	done := make(chan struct{})
+	go func() {
+		tm.Start()
+		close(done)
+	}()
+
+	// Test the loop checking the pending transactions exits once stopped
+	tm.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("transaction manager did not stop")
+	}
+}
+
+func TestTxManager_SendTransaction(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	b := newStubTxBackend()
+	am := newStubTxAccountManager(t)
+	tm := NewTxManager(b, am, TxManagerConfig{})
+
+	// Test concurrent transactions that picked the same nonce are sent with the next nonces
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := sendTx(tm, am, 0)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.Nil(err)
+	}
+	assert.Len(b.pool, 10)
+	assert.Len(tm.pending, 10)
+	for nonce := uint64(0); nonce < 10; nonce++ {
+		assert.Contains(b.pool, nonce)
+	}
+
+	// Test the next transaction is signed after a failed send
+	b.sendErr = errors.New("SendTransaction error")
+	_, err := sendTx(tm, am, 0)
+	assert.EqualError(err, "SendTransaction error")
+	b.sendErr = nil
+	tx, err := sendTx(tm, am, 0)
+	require.Nil(err)
+	assert.Equal(uint64(10), tx.Nonce())
+
+	// Test signing error
+	signErr := errors.New("SignTx error")
+	_, err = tm.Signer(func(types.Signer, ethcommon.Address, *types.Transaction) (*types.Transaction, error) {
+		return nil, signErr
+	})(am.signer, am.Account().Address, tx)
+	assert.Equal(signErr, err)
+	_, err = sendTx(tm, am, 0)
+	assert.Nil(err)
+}
+
+func TestTxManager_ReplaceTransaction(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	b := newStubTxBackend()
+	am := newStubTxAccountManager(t)
+	tm := NewTxManager(b, am, TxManagerConfig{MaxGasPrice: big.NewInt(200)})
+
+	tx, err := sendTx(tm, am, 0)
+	require.Nil(err)
+
+	// Test gas price bumped by 10% and 10 wei
+	newTx, err := tm.ReplaceTransaction(tx, "foo", nil)
+	require.Nil(err)
+	assert.Equal(big.NewInt(120), newTx.GasPrice())
+	assert.Equal(tx.Nonce(), newTx.Nonce())
+	assert.Equal(newTx, b.pool[0])
+
+	// Test suggested gas price higher than the bump
+	b.gasPrice = big.NewInt(150)
+	newTx, err = tm.ReplaceTransaction(newTx, "foo", nil)
+	require.Nil(err)
+	assert.Equal(big.NewInt(150), newTx.GasPrice())
+
+	// Test suggested gas price capped by the max gas price
+	b.gasPrice = big.NewInt(300)
+	newTx, err = tm.ReplaceTransaction(newTx, "foo", nil)
+	require.Nil(err)
+	assert.Equal(big.NewInt(200), newTx.GasPrice())
+
+	// Test bump over the max gas price
+	_, err = tm.ReplaceTransaction(newTx, "foo", nil)
+	require.NotNil(err)
+	assert.Contains(err.Error(), "exceeds the max gas price 200")
+
+	// Test waiting for the original transaction returns the receipt of the replacement
+	receipt := &types.Receipt{TxHash: newTx.Hash(), Status: 1}
+	b.receipts[newTx.Hash()] = receipt
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res, err := tm.WaitMined(ctx, tx)
+	require.Nil(err)
+	assert.Equal(receipt, res)
+
+	// Test waiting for a transaction that isn't mined
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	other := types.NewTransaction(5, pm.RandAddress(), big.NewInt(0), 21000, big.NewInt(100), nil)
+	_, err = tm.WaitMined(ctx, other)
+	assert.Equal(context.DeadlineExceeded, err)
+}
+
+func TestTxManager_CheckPendingTxs(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	b := newStubTxBackend()
+	am := newStubTxAccountManager(t)
+	tm := NewTxManager(b, am, TxManagerConfig{StuckTimeout: time.Minute})
+
+	tx0, err := sendTx(tm, am, 0)
+	require.Nil(err)
+	tx1, err := sendTx(tm, am, 0)
+	require.Nil(err)
+
+	// Test pending transactions aren't replaced before the stuck timeout
+	require.Nil(tm.checkPendingTxs())
+	assert.Equal(tx0, b.pool[0])
+	assert.Equal(tx1, b.pool[1])
+
+	// Test stuck transactions are replaced
+	tm.pending[1].sentAt = time.Now().Add(-time.Minute)
+	require.Nil(tm.checkPendingTxs())
+	assert.Equal(tx0, b.pool[0])
+	assert.Equal(big.NewInt(120), b.pool[1].GasPrice())
+	assert.Len(tm.pending[1].txs, 2)
+	assert.Equal(b.pool[1], tm.pending[1].txs[1])
+
+	// Test mined transactions are kept for a while, then dropped
+	b.nonce = 1
+	require.Nil(tm.checkPendingTxs())
+	assert.False(tm.pending[0].minedAt.IsZero())
+	assert.True(tm.pending[1].minedAt.IsZero())
+	tm.pending[0].minedAt = time.Now().Add(-minedTxRetention)
+	require.Nil(tm.checkPendingTxs())
+	assert.NotContains(tm.pending, uint64(0))
+	assert.Contains(tm.pending, uint64(1))
+
+	// Test stuck transactions that can't be replaced are tried again after the stuck timeout
+	b.sendErr = errors.New("SendTransaction error")
+	tm.pending[1].sentAt = time.Now().Add(-time.Minute)
+	require.Nil(tm.checkPendingTxs())
+	assert.Len(tm.pending[1].txs, 2)
+	assert.True(time.Since(tm.pending[1].sentAt) < time.Minute)
+}
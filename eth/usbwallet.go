@@ -0,0 +1,235 @@
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/usbwallet"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/console"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/pm"
+)
+
+var (
+	ErrUsbWalletNotFound  = errors.New("no USB hardware wallet found")
+	ErrUsbConfirmTimeout  = errors.New("timed out waiting for the confirmation of the USB hardware wallet")
+	errUsbWalletSignTyped = errors.New("USB hardware wallets can't sign typed data")
+)
+
+// DefaultUsbConfirmTimeout is the default time to wait for a transaction to be confirmed on
+// a USB hardware wallet
+const DefaultUsbConfirmTimeout = time.Minute
+
+// UsbWalletConfig configures the account of a USB hardware wallet
+type UsbWalletConfig struct {
+	// DerivationPath is the HD path of the account in the wallet, defaults to
+	// accounts.DefaultBaseDerivationPath
+	DerivationPath accounts.DerivationPath
+	// ConfirmTimeout is the time to wait for a transaction to be confirmed on the wallet,
+	// defaults to DefaultUsbConfirmTimeout
+	ConfirmTimeout time.Duration
+}
+
+type usbAccountManager struct {
+	account        accounts.Account
+	wallet         accounts.Wallet
+	chainID        *big.Int
+	confirmTimeout time.Duration
+	unlocked       bool
+}
+
+// NewUsbAccountManager creates an AccountManager for the account at the derivation path of
+// cfg of the first Ledger or Trezor wallet plugged in. If accountAddr is set, the account of
+// the wallet must be accountAddr. Transactions are sent to the wallet to be confirmed on the
+// device, but messages can't be signed with the hardware wallets supported by go-ethereum, so
+// these accounts can't sign tickets or the other messages that nodes exchange
+func NewUsbAccountManager(accountAddr ethcommon.Address, cfg UsbWalletConfig, chainID *big.Int) (AccountManager, error) {
+	wallet, err := findUsbWallet()
+	if err != nil {
+		return nil, err
+	}
+
+	glog.Infof("Found USB hardware wallet %v", wallet.URL())
+
+	return newUsbAccountManager(wallet, accountAddr, cfg, chainID)
+}
+
+func newUsbAccountManager(wallet accounts.Wallet, accountAddr ethcommon.Address, cfg UsbWalletConfig, chainID *big.Int) (AccountManager, error) {
+	if err := openUsbWallet(wallet); err != nil {
+		return nil, err
+	}
+
+	path := cfg.DerivationPath
+	if path == nil {
+		path = accounts.DefaultBaseDerivationPath
+	}
+	acct, err := wallet.Derive(path, true)
+	if err != nil {
+		wallet.Close()
+		return nil, err
+	}
+	if (accountAddr != ethcommon.Address{}) && acct.Address != accountAddr {
+		wallet.Close()
+		return nil, fmt.Errorf("%v: account of derivation path %v is %v", ErrAccountNotFound, path, acct.Address.Hex())
+	}
+
+	confirmTimeout := cfg.ConfirmTimeout
+	if confirmTimeout <= 0 {
+		confirmTimeout = DefaultUsbConfirmTimeout
+	}
+
+	glog.Infof("Using Ethereum account: %v derivationPath=%v", acct.Address.Hex(), path)
+
+	return &usbAccountManager{
+		account:        acct,
+		wallet:         wallet,
+		chainID:        chainID,
+		confirmTimeout: confirmTimeout,
+	}, nil
+}
+
+// findUsbWallet returns the first Ledger or Trezor wallet plugged in
+func findUsbWallet() (accounts.Wallet, error) {
+	var hubErr error
+	for _, newHub := range []func() (*usbwallet.Hub, error){usbwallet.NewLedgerHub, usbwallet.NewTrezorHubWithHID} {
+		hub, err := newHub()
+		if err != nil {
+			hubErr = err
+			continue
+		}
+		if wallets := hub.Wallets(); len(wallets) > 0 {
+			return wallets[0], nil
+		}
+	}
+	if hubErr != nil {
+		return nil, fmt.Errorf("%v: %v", ErrUsbWalletNotFound, hubErr)
+	}
+
+	return nil, ErrUsbWalletNotFound
+}
+
+// openUsbWallet opens a connection to a wallet, prompting for the PIN and passphrase of
+// Trezor wallets
+func openUsbWallet(wallet accounts.Wallet) error {
+	err := wallet.Open("")
+	for err == usbwallet.ErrTrezorPINNeeded || err == usbwallet.ErrTrezorPassphraseNeeded {
+		if err == usbwallet.ErrTrezorPINNeeded {
+			glog.Infof("Please enter the PIN of the USB hardware wallet in the layout shown on the device")
+		} else {
+			glog.Infof("Please enter the passphrase of the USB hardware wallet")
+		}
+
+		var secret string
+		secret, err = console.Stdin.PromptPassword("Passphrase: ")
+		if err != nil {
+			return err
+		}
+		err = wallet.Open(secret)
+	}
+
+	return err
+}
+
+// Unlock checks that the wallet is still connected, since the key of the account never
+// leaves the device
+func (am *usbAccountManager) Unlock(pass string) error {
+	if _, err := am.wallet.Status(); err != nil {
+		return err
+	}
+
+	am.unlocked = true
+
+	glog.Infof("Unlocked ETH account: %v", am.account.Address.Hex())
+
+	return nil
+}
+
+func (am *usbAccountManager) Lock() error {
+	am.unlocked = false
+
+	return nil
+}
+
+// Create transact opts for client use - account must be unlocked
+// Can optionally set gas limit and gas price used
+func (am *usbAccountManager) CreateTransactOpts(gasLimit uint64, gasPrice *big.Int) (*bind.TransactOpts, error) {
+	if !am.unlocked {
+		return nil, ErrLocked
+	}
+
+	return &bind.TransactOpts{
+		From:     am.account.Address,
+		GasLimit: gasLimit,
+		GasPrice: gasPrice,
+		Signer: func(signer types.Signer, address ethcommon.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != am.account.Address {
+				return nil, errors.New("not authorized to sign this account")
+			}
+
+			return am.SignTx(tx)
+		},
+	}, nil
+}
+
+// SignTx sends a transaction to the wallet and waits for it to be confirmed on the device
+// for up to the confirmation timeout. Account must be unlocked
+func (am *usbAccountManager) SignTx(tx *types.Transaction) (*types.Transaction, error) {
+	if !am.unlocked {
+		return nil, ErrLocked
+	}
+
+	glog.Infof("Please confirm the transaction on the USB hardware wallet nonce=%v", tx.Nonce())
+
+	type result struct {
+		tx  *types.Transaction
+		err error
+	}
+	// The wallet keeps waiting for the confirmation after the timeout, the transactions that
+	// follow are sent to the wallet once it is confirmed or refused on the device
+	resc := make(chan result, 1)
+	go func() {
+		signed, err := am.wallet.SignTx(am.account, tx, am.chainID)
+		resc <- result{signed, err}
+	}()
+
+	select {
+	case res := <-resc:
+		return res.tx, res.err
+	case <-time.After(am.confirmTimeout):
+		return nil, ErrUsbConfirmTimeout
+	}
+}
+
+// Sign byte array message. Account must be unlocked
+func (am *usbAccountManager) Sign(msg []byte) ([]byte, error) {
+	if !am.unlocked {
+		return nil, ErrLocked
+	}
+
+	sig, err := am.wallet.SignText(am.account, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert the V param to 27 or 28
+	if v := sig[64]; v == byte(0) || v == byte(1) {
+		sig[64] += 27
+	}
+
+	return sig, nil
+}
+
+// SignTypedData is not supported, since hardware wallets don't sign raw digests
+func (am *usbAccountManager) SignTypedData(typedData *pm.TypedData) ([]byte, error) {
+	return nil, errUsbWalletSignTyped
+}
+
+func (am *usbAccountManager) Account() accounts.Account {
+	return am.account
+}
@@ -0,0 +1,219 @@
+package eth
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/livepeer/go-livepeer/pm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubUsbWallet struct {
+	key       *ecdsa.PrivateKey
+	paths     []accounts.DerivationPath
+	deriveErr error
+	statusErr error
+	signErr   error
+	// confirm blocks SignTx until it is closed if it is not nil
+	confirm chan struct{}
+	closed  bool
+}
+
+func newStubUsbWallet(t *testing.T) *stubUsbWallet {
+	key, err := ethcrypto.GenerateKey()
+	require.Nil(t, err)
+	return &stubUsbWallet{key: key}
+}
+
+func (w *stubUsbWallet) address() ethcommon.Address {
+	return ethcrypto.PubkeyToAddress(w.key.PublicKey)
+}
+
+func (w *stubUsbWallet) URL() accounts.URL {
+	return accounts.URL{Scheme: "ledger", Path: "stub"}
+}
+
+func (w *stubUsbWallet) Status() (string, error) { return "Ethereum app online", w.statusErr }
+
+func (w *stubUsbWallet) Open(passphrase string) error { return nil }
+
+func (w *stubUsbWallet) Close() error {
+	w.closed = true
+	return nil
+}
+
+func (w *stubUsbWallet) Accounts() []accounts.Account { return nil }
+
+func (w *stubUsbWallet) Contains(account accounts.Account) bool {
+	return account.Address == w.address()
+}
+
+func (w *stubUsbWallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	w.paths = append(w.paths, path)
+	if w.deriveErr != nil {
+		return accounts.Account{}, w.deriveErr
+	}
+	return accounts.Account{Address: w.address()}, nil
+}
+
+func (w *stubUsbWallet) SelfDerive(bases []accounts.DerivationPath, chain ethereum.ChainStateReader) {
+}
+
+func (w *stubUsbWallet) SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+func (w *stubUsbWallet) SignDataWithPassphrase(account accounts.Account, passphrase, mimeType string, data []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+func (w *stubUsbWallet) SignText(account accounts.Account, text []byte) ([]byte, error) {
+	if w.signErr != nil {
+		return nil, w.signErr
+	}
+	return ethcrypto.Sign(accounts.TextHash(text), w.key)
+}
+
+func (w *stubUsbWallet) SignTextWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return w.SignText(account, hash)
+}
+
+func (w *stubUsbWallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if w.confirm != nil {
+		<-w.confirm
+	}
+	if w.signErr != nil {
+		return nil, w.signErr
+	}
+	return types.SignTx(tx, types.NewEIP155Signer(chainID), w.key)
+}
+
+func (w *stubUsbWallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return w.SignTx(account, tx, chainID)
+}
+
+func TestNewUsbAccountManager(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Test default derivation path
+	wallet := newStubUsbWallet(t)
+	am, err := newUsbAccountManager(wallet, ethcommon.Address{}, UsbWalletConfig{}, big.NewInt(1))
+	require.Nil(err)
+	assert.Equal(wallet.address(), am.Account().Address)
+	assert.Equal([]accounts.DerivationPath{accounts.DefaultBaseDerivationPath}, wallet.paths)
+	assert.Equal(DefaultUsbConfirmTimeout, am.(*usbAccountManager).confirmTimeout)
+
+	// Test custom derivation path and account address
+	path, err := accounts.ParseDerivationPath("m/44'/60'/1'/0/0")
+	require.Nil(err)
+	wallet.paths = nil
+	am, err = newUsbAccountManager(wallet, wallet.address(), UsbWalletConfig{DerivationPath: path, ConfirmTimeout: time.Second}, big.NewInt(1))
+	require.Nil(err)
+	assert.Equal([]accounts.DerivationPath{path}, wallet.paths)
+	assert.Equal(time.Second, am.(*usbAccountManager).confirmTimeout)
+
+	// Test account of the derivation path that isn't accountAddr
+	_, err = newUsbAccountManager(wallet, pm.RandAddress(), UsbWalletConfig{}, big.NewInt(1))
+	require.NotNil(err)
+	assert.Contains(err.Error(), ErrAccountNotFound.Error())
+	assert.True(wallet.closed)
+
+	// Test Derive error
+	wallet = newStubUsbWallet(t)
+	wallet.deriveErr = accounts.ErrWalletClosed
+	_, err = newUsbAccountManager(wallet, ethcommon.Address{}, UsbWalletConfig{}, big.NewInt(1))
+	assert.Equal(accounts.ErrWalletClosed, err)
+	assert.True(wallet.closed)
+}
+
+func TestUsbAccountManager_SignTx(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	wallet := newStubUsbWallet(t)
+	chainID := big.NewInt(4)
+	am, err := newUsbAccountManager(wallet, ethcommon.Address{}, UsbWalletConfig{ConfirmTimeout: 50 * time.Millisecond}, chainID)
+	require.Nil(err)
+	tx := types.NewTransaction(1, pm.RandAddress(), big.NewInt(0), 21000, big.NewInt(1), nil)
+
+	// Test locked account
+	_, err = am.CreateTransactOpts(0, nil)
+	assert.Equal(ErrLocked, err)
+	_, err = am.SignTx(tx)
+	assert.Equal(ErrLocked, err)
+
+	// Test disconnected wallet
+	wallet.statusErr = accounts.ErrWalletClosed
+	assert.Equal(accounts.ErrWalletClosed, am.Unlock(""))
+	wallet.statusErr = nil
+	require.Nil(am.Unlock(""))
+
+	opts, err := am.CreateTransactOpts(0, nil)
+	require.Nil(err)
+	assert.Equal(wallet.address(), opts.From)
+
+	signer := types.NewEIP155Signer(chainID)
+	signed, err := opts.Signer(signer, wallet.address(), tx)
+	require.Nil(err)
+	sender, err := types.Sender(signer, signed)
+	require.Nil(err)
+	assert.Equal(wallet.address(), sender)
+
+	_, err = opts.Signer(signer, pm.RandAddress(), tx)
+	assert.EqualError(err, "not authorized to sign this account")
+
+	// Test transaction refused on the device
+	wallet.signErr = accounts.ErrNotSupported
+	_, err = am.SignTx(tx)
+	assert.Equal(accounts.ErrNotSupported, err)
+	wallet.signErr = nil
+
+	// Test confirmation timeout
+	wallet.confirm = make(chan struct{})
+	defer close(wallet.confirm)
+	_, err = am.SignTx(tx)
+	assert.Equal(ErrUsbConfirmTimeout, err)
+
+	require.Nil(am.Lock())
+	_, err = am.SignTx(tx)
+	assert.Equal(ErrLocked, err)
+}
+
+func TestUsbAccountManager_Sign(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	wallet := newStubUsbWallet(t)
+	am, err := newUsbAccountManager(wallet, ethcommon.Address{}, UsbWalletConfig{}, big.NewInt(1))
+	require.Nil(err)
+	msg := []byte("foo")
+
+	_, err = am.Sign(msg)
+	assert.Equal(ErrLocked, err)
+	require.Nil(am.Unlock(""))
+
+	sig, err := am.Sign(msg)
+	require.Nil(err)
+	assert.True(sig[64] == 27 || sig[64] == 28)
+	sig[64] -= 27
+	pub, err := ethcrypto.SigToPub(accounts.TextHash(msg), sig)
+	require.Nil(err)
+	assert.Equal(wallet.address(), ethcrypto.PubkeyToAddress(*pub))
+
+	// Test wallet that doesn't sign messages
+	wallet.signErr = accounts.ErrNotSupported
+	_, err = am.Sign(msg)
+	assert.Equal(accounts.ErrNotSupported, err)
+
+	_, err = am.SignTypedData(&pm.TypedData{})
+	assert.Equal(errUsbWalletSignTyped, err)
+}
@@ -0,0 +1,307 @@
+package watchers
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/eth/blockwatch"
+	"github.com/livepeer/go-livepeer/eth/contracts"
+	"github.com/livepeer/go-livepeer/monitor"
+)
+
+// earningsConfirmations is the number of confirmations of the blocks whose earnings are
+// indexed, so that indexed earnings aren't changed by reorgs
+var earningsConfirmations = big.NewInt(12)
+
+// earningsBlockRange is the maximum number of blocks whose logs are requested at once
+var earningsBlockRange = big.NewInt(5000)
+
+var (
+	winningTicketRedeemedTopic = crypto.Keccak256Hash([]byte("WinningTicketRedeemed(address,address,uint256,uint256,uint256,uint256,bytes)"))
+	rewardTopic                = crypto.Keccak256Hash([]byte("Reward(address,uint256)"))
+	bondTopic                  = crypto.Keccak256Hash([]byte("Bond(address,address,address,uint256,uint256)"))
+)
+
+type earningsStore interface {
+	LastIndexedEarningsBlock() (*big.Int, error)
+	InsertEarnings(earnings []*common.DBEarning, lastBlock *big.Int) error
+}
+
+type earningsBackend interface {
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// EarningsIndexer indexes the fees, rewards and bonds of an address into an earnings store,
+// for bookkeeping. It backfills the WinningTicketRedeemed, Reward and Bond events of the
+// address since a start block, or since the latest block without a start block, and then
+// indexes the events of new blocks once they are confirmed
+type EarningsIndexer struct {
+	addr               ethcommon.Address
+	ticketBrokerAddr   ethcommon.Address
+	bondingManagerAddr ethcommon.Address
+	startBlock         *big.Int
+
+	bw      BlockWatcher
+	backend earningsBackend
+	store   earningsStore
+	tbDec   *EventDecoder
+	bmDec   *EventDecoder
+
+	quit chan struct{}
+}
+
+// NewEarningsIndexer creates an EarningsIndexer for the earnings of addr since startBlock. The
+// earnings are indexed from the latest confirmed block if startBlock is nil and no earnings
+// were indexed yet.
+func NewEarningsIndexer(addr, ticketBrokerAddr, bondingManagerAddr ethcommon.Address, startBlock *big.Int, bw BlockWatcher, backend earningsBackend, store earningsStore) (*EarningsIndexer, error) {
+	tbDec, err := NewEventDecoder(ticketBrokerAddr, contracts.TicketBrokerABI)
+	if err != nil {
+		return nil, err
+	}
+	bmDec, err := NewEventDecoder(bondingManagerAddr, contracts.BondingManagerABI)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EarningsIndexer{
+		addr:               addr,
+		ticketBrokerAddr:   ticketBrokerAddr,
+		bondingManagerAddr: bondingManagerAddr,
+		startBlock:         startBlock,
+		bw:                 bw,
+		backend:            backend,
+		store:              store,
+		tbDec:              tbDec,
+		bmDec:              bmDec,
+		quit:               make(chan struct{}),
+	}, nil
+}
+
+// Watch indexes the earnings up to the latest block, and then the earnings of the blocks that
+// the block watcher receives
+func (ix *EarningsIndexer) Watch() {
+	blockEvents := make(chan []*blockwatch.Event, 10)
+	sub := ix.bw.Subscribe(blockEvents)
+	defer sub.Unsubscribe()
+
+	// The earnings are indexed in another goroutine so that the block events keep being
+	// received while the earnings are backfilled, which takes a while. It indexes up to the
+	// last head received meanwhile.
+	heads := make(chan *big.Int, 1)
+	go ix.indexHeads(heads)
+
+	if latest, err := ix.bw.GetLatestBlock(); err != nil {
+		glog.Errorf("Error getting the latest block to index earnings: %v", err)
+	} else if latest != nil {
+		setHead(heads, latest.Number)
+	}
+
+	for {
+		select {
+		case <-ix.quit:
+			return
+		case err := <-sub.Err():
+			glog.Errorf("error with block subscription: %v", err)
+		case events := <-blockEvents:
+			if len(events) > 0 {
+				setHead(heads, events[len(events)-1].BlockHeader.Number)
+			}
+		}
+	}
+}
+
+// setHead replaces the head that wasn't indexed yet, if any. It never blocks since Watch is
+// the only sender.
+func setHead(heads chan *big.Int, head *big.Int) {
+	select {
+	case <-heads:
+	default:
+	}
+	heads <- head
+}
+
+func (ix *EarningsIndexer) indexHeads(heads chan *big.Int) {
+	defer monitor.RecoverAndReport()
+	for {
+		select {
+		case <-ix.quit:
+			return
+		case head := <-heads:
+			ix.sync(head)
+		}
+	}
+}
+
+// Stop signals the watcher loop to exit gracefully
+func (ix *EarningsIndexer) Stop() {
+	close(ix.quit)
+}
+
+func (ix *EarningsIndexer) sync(head *big.Int) {
+	if err := ix.indexEarnings(head); err != nil {
+		glog.Errorf("Error indexing earnings addr=%v err=%v", ix.addr.Hex(), err)
+	}
+}
+
+// indexEarnings indexes the earnings of the confirmed blocks up to head, in ranges of
+// earningsBlockRange blocks, after the last indexed block
+func (ix *EarningsIndexer) indexEarnings(head *big.Int) error {
+	to := new(big.Int).Sub(head, earningsConfirmations)
+	if to.Sign() < 0 {
+		return nil
+	}
+
+	last, err := ix.store.LastIndexedEarningsBlock()
+	if err != nil {
+		return err
+	}
+	from := ix.startBlock
+	switch {
+	case last != nil && (from == nil || last.Cmp(from) >= 0):
+		from = new(big.Int).Add(last, big.NewInt(1))
+	case from == nil:
+		from = to
+	}
+
+	for from.Cmp(to) <= 0 {
+		select {
+		case <-ix.quit:
+			return nil
+		default:
+		}
+
+		end := new(big.Int).Add(from, earningsBlockRange)
+		end.Sub(end, big.NewInt(1))
+		if end.Cmp(to) > 0 {
+			end = to
+		}
+
+		earnings, err := ix.fetchEarnings(from, end)
+		if err != nil {
+			return err
+		}
+		if err := ix.store.InsertEarnings(earnings, end); err != nil {
+			return err
+		}
+		if len(earnings) > 0 {
+			glog.V(common.DEBUG).Infof("Indexed earnings addr=%v fromBlock=%v toBlock=%v count=%v", ix.addr.Hex(), from, end, len(earnings))
+		}
+
+		from = new(big.Int).Add(end, big.NewInt(1))
+	}
+
+	return nil
+}
+
+// fetchEarnings returns the earnings in the blocks from from to to
+func (ix *EarningsIndexer) fetchEarnings(from, to *big.Int) ([]*common.DBEarning, error) {
+	addrTopic := ethcommon.BytesToHash(ix.addr.Bytes())
+	queries := []ethereum.FilterQuery{
+		// The recipient of tickets is the second indexed field
+		{Addresses: []ethcommon.Address{ix.ticketBrokerAddr}, Topics: [][]ethcommon.Hash{{winningTicketRedeemedTopic}, nil, {addrTopic}}},
+		{Addresses: []ethcommon.Address{ix.bondingManagerAddr}, Topics: [][]ethcommon.Hash{{rewardTopic}, {addrTopic}}},
+		// The delegator of bonds is the third indexed field
+		{Addresses: []ethcommon.Address{ix.bondingManagerAddr}, Topics: [][]ethcommon.Hash{{bondTopic}, nil, nil, {addrTopic}}},
+	}
+
+	ctx := context.Background()
+	blockTimes := make(map[uint64]time.Time)
+	var earnings []*common.DBEarning
+	for _, q := range queries {
+		q.FromBlock = from
+		q.ToBlock = to
+		logs, err := ix.backend.FilterLogs(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, log := range logs {
+			e, err := ix.decodeEarning(log)
+			if err != nil {
+				return nil, err
+			}
+			if e == nil {
+				continue
+			}
+
+			blockTime, ok := blockTimes[log.BlockNumber]
+			if !ok {
+				header, err := ix.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(log.BlockNumber))
+				if err != nil {
+					return nil, fmt.Errorf("could not get block %v: %v", log.BlockNumber, err)
+				}
+				blockTime = time.Unix(int64(header.Time), 0)
+				blockTimes[log.BlockNumber] = blockTime
+			}
+			e.BlockTime = blockTime
+
+			earnings = append(earnings, e)
+		}
+	}
+
+	return earnings, nil
+}
+
+// decodeEarning returns the earning of a log, or nil if the log isn't an earning of the address
+func (ix *EarningsIndexer) decodeEarning(log types.Log) (*common.DBEarning, error) {
+	e := &common.DBEarning{
+		BlockNumber: int64(log.BlockNumber),
+		TxHash:      log.TxHash,
+		LogIndex:    int64(log.Index),
+	}
+
+	if log.Address == ix.ticketBrokerAddr {
+		var redeemed contracts.TicketBrokerWinningTicketRedeemed
+		if err := ix.tbDec.Decode("WinningTicketRedeemed", log, &redeemed); err != nil {
+			return nil, fmt.Errorf("failed to decode WinningTicketRedeemed event: %v", err)
+		}
+		if redeemed.Recipient != ix.addr {
+			return nil, nil
+		}
+		e.Kind = common.EarningFees
+		e.Amount = redeemed.FaceValue
+		e.Counterparty = redeemed.Sender
+		return e, nil
+	}
+
+	eventName, err := ix.bmDec.FindEventName(log)
+	if err != nil {
+		return nil, nil
+	}
+	switch eventName {
+	case "Reward":
+		var reward contracts.BondingManagerReward
+		if err := ix.bmDec.Decode("Reward", log, &reward); err != nil {
+			return nil, fmt.Errorf("failed to decode Reward event: %v", err)
+		}
+		if reward.Transcoder != ix.addr {
+			return nil, nil
+		}
+		e.Kind = common.EarningReward
+		e.Amount = reward.Amount
+	case "Bond":
+		var bond contracts.BondingManagerBond
+		if err := ix.bmDec.Decode("Bond", log, &bond); err != nil {
+			return nil, fmt.Errorf("failed to decode Bond event: %v", err)
+		}
+		if bond.Delegator != ix.addr {
+			return nil, nil
+		}
+		e.Kind = common.EarningBond
+		e.Amount = bond.AdditionalAmount
+		e.Counterparty = bond.NewDelegate
+	default:
+		return nil, nil
+	}
+
+	return e, nil
+}
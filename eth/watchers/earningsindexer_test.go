@@ -0,0 +1,238 @@
+package watchers
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/eth/blockwatch"
+	"github.com/livepeer/go-livepeer/eth/contracts"
+	"github.com/livepeer/go-livepeer/pm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubEarningsBackend struct {
+	logs    []types.Log
+	queries []ethereum.FilterQuery
+	// FilterLogs waits for release if set
+	release chan struct{}
+}
+
+// FilterLogs returns the logs matching the addresses, block range and topics of the query, like
+// an Ethereum node
+func (b *stubEarningsBackend) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	if b.release != nil {
+		<-b.release
+	}
+	b.queries = append(b.queries, q)
+	var logs []types.Log
+	for _, log := range b.logs {
+		if log.Address != q.Addresses[0] || log.BlockNumber < q.FromBlock.Uint64() || log.BlockNumber > q.ToBlock.Uint64() {
+			continue
+		}
+		match := true
+		for i, topics := range q.Topics {
+			if topics != nil && (i >= len(log.Topics) || log.Topics[i] != topics[0]) {
+				match = false
+			}
+		}
+		if match {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func (b *stubEarningsBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: number, Time: 1000 * number.Uint64()}, nil
+}
+
+type stubEarningsStore struct {
+	earnings  []*common.DBEarning
+	lastBlock *big.Int
+}
+
+func (s *stubEarningsStore) LastIndexedEarningsBlock() (*big.Int, error) {
+	return s.lastBlock, nil
+}
+
+func (s *stubEarningsStore) InsertEarnings(earnings []*common.DBEarning, lastBlock *big.Int) error {
+	s.earnings = append(s.earnings, earnings...)
+	s.lastBlock = lastBlock
+	return nil
+}
+
+func newStubEventLog(t *testing.T, contract ethcommon.Address, abiJSON, eventName string, block uint64, indexed []ethcommon.Address, data ...interface{}) types.Log {
+	a, err := abi.JSON(strings.NewReader(abiJSON))
+	require.Nil(t, err)
+	event := a.Events[eventName]
+	packed, err := event.Inputs.NonIndexed().Pack(data...)
+	require.Nil(t, err)
+
+	topics := []ethcommon.Hash{event.ID()}
+	for _, addr := range indexed {
+		topics = append(topics, ethcommon.BytesToHash(addr.Bytes()))
+	}
+	return types.Log{
+		Address:     contract,
+		Topics:      topics,
+		Data:        packed,
+		BlockNumber: block,
+		TxHash:      ethcommon.BigToHash(new(big.Int).SetUint64(block)),
+	}
+}
+
+func TestEarningsIndexer_Topics(t *testing.T) {
+	assert := assert.New(t)
+
+	tb, err := abi.JSON(strings.NewReader(contracts.TicketBrokerABI))
+	assert.Nil(err)
+	bm, err := abi.JSON(strings.NewReader(contracts.BondingManagerABI))
+	assert.Nil(err)
+	assert.Equal(tb.Events["WinningTicketRedeemed"].ID(), winningTicketRedeemedTopic)
+	assert.Equal(bm.Events["Reward"].ID(), rewardTopic)
+	assert.Equal(bm.Events["Bond"].ID(), bondTopic)
+}
+
+func TestEarningsIndexer_IndexEarnings(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	defer func(r *big.Int) { earningsBlockRange = r }(earningsBlockRange)
+	earningsBlockRange = big.NewInt(10)
+
+	addr := pm.RandAddress()
+	other := pm.RandAddress()
+	sender := pm.RandAddress()
+	delegate := pm.RandAddress()
+	redeemed := func(block uint64, recipient ethcommon.Address, faceValue int64) types.Log {
+		return newStubEventLog(t, stubTicketBrokerAddr, contracts.TicketBrokerABI, "WinningTicketRedeemed", block, []ethcommon.Address{sender, recipient},
+			big.NewInt(faceValue), big.NewInt(1), big.NewInt(2), big.NewInt(3), []byte{})
+	}
+	backend := &stubEarningsBackend{logs: []types.Log{
+		redeemed(5, addr, 100),
+		redeemed(6, other, 200),
+		newStubEventLog(t, stubBondingManagerAddr, contracts.BondingManagerABI, "Reward", 15, []ethcommon.Address{addr}, big.NewInt(300)),
+		newStubEventLog(t, stubBondingManagerAddr, contracts.BondingManagerABI, "Bond", 25, []ethcommon.Address{delegate, ethcommon.Address{}, addr}, big.NewInt(400), big.NewInt(400)),
+		// Not confirmed yet
+		redeemed(95, addr, 500),
+	}}
+	store := &stubEarningsStore{}
+
+	ix, err := NewEarningsIndexer(addr, stubTicketBrokerAddr, stubBondingManagerAddr, big.NewInt(3), &stubBlockWatcher{}, backend, store)
+	require.Nil(err)
+
+	// Test the earnings are backfilled from the start block up to the confirmed blocks
+	require.Nil(ix.indexEarnings(big.NewInt(100)))
+	assert.Equal(big.NewInt(88), store.lastBlock)
+	require.Len(store.earnings, 3)
+	assert.Equal(&common.DBEarning{
+		Kind:         common.EarningFees,
+		Amount:       big.NewInt(100),
+		Counterparty: sender,
+		BlockNumber:  5,
+		BlockTime:    time.Unix(5000, 0),
+		TxHash:       backend.logs[0].TxHash,
+	}, store.earnings[0])
+	assert.Equal(common.EarningReward, store.earnings[1].Kind)
+	assert.Equal(big.NewInt(300), store.earnings[1].Amount)
+	assert.Equal(common.EarningBond, store.earnings[2].Kind)
+	assert.Equal(big.NewInt(400), store.earnings[2].Amount)
+	assert.Equal(delegate, store.earnings[2].Counterparty)
+
+	// Test the logs are requested in ranges of blocks
+	assert.Len(backend.queries, 9*3)
+	assert.Equal(big.NewInt(3), backend.queries[0].FromBlock)
+	assert.Equal(big.NewInt(12), backend.queries[0].ToBlock)
+
+	// Test the indexing resumes after the last indexed block
+	backend.queries = nil
+	require.Nil(ix.indexEarnings(big.NewInt(110)))
+	assert.Equal(big.NewInt(98), store.lastBlock)
+	assert.Len(store.earnings, 4)
+	assert.Len(backend.queries, 3)
+	assert.Equal(big.NewInt(89), backend.queries[0].FromBlock)
+
+	// Test nothing is requested before new blocks are confirmed
+	backend.queries = nil
+	require.Nil(ix.indexEarnings(big.NewInt(110)))
+	assert.Empty(backend.queries)
+
+	// Test the earnings are indexed from the latest confirmed block without a start block
+	store = &stubEarningsStore{}
+	ix, err = NewEarningsIndexer(addr, stubTicketBrokerAddr, stubBondingManagerAddr, nil, &stubBlockWatcher{}, backend, store)
+	require.Nil(err)
+	require.Nil(ix.indexEarnings(big.NewInt(5)))
+	assert.Nil(store.lastBlock)
+	require.Nil(ix.indexEarnings(big.NewInt(100)))
+	assert.Equal(big.NewInt(88), store.lastBlock)
+	assert.Len(backend.queries, 3)
+	assert.Equal(big.NewInt(88), backend.queries[0].FromBlock)
+	assert.Empty(store.earnings)
+}
+
+func TestEarningsIndexer_Watch(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	addr := pm.RandAddress()
+	backend := &stubEarningsBackend{logs: []types.Log{
+		newStubEventLog(t, stubBondingManagerAddr, contracts.BondingManagerABI, "Reward", 30, []ethcommon.Address{addr}, big.NewInt(300)),
+	}}
+	store := &stubEarningsStore{}
+	bw := &stubBlockWatcher{latestHeader: &blockwatch.MiniHeader{Number: big.NewInt(40)}}
+
+	ix, err := NewEarningsIndexer(addr, stubTicketBrokerAddr, stubBondingManagerAddr, nil, bw, backend, store)
+	require.Nil(err)
+	go ix.Watch()
+	defer ix.Stop()
+
+	// Test the earnings up to the latest block are indexed at startup
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(big.NewInt(28), store.lastBlock)
+	assert.Empty(store.earnings)
+
+	// Test the earnings are indexed once new blocks confirm them
+	bw.sink <- []*blockwatch.Event{{Type: blockwatch.Added, BlockHeader: &blockwatch.MiniHeader{Number: big.NewInt(42)}}}
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(big.NewInt(30), store.lastBlock)
+	require.Len(store.earnings, 1)
+	assert.Equal(time.Unix(30000, 0), store.earnings[0].BlockTime)
+}
+
+func TestEarningsIndexer_WatchBackfill(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	backend := &stubEarningsBackend{release: make(chan struct{})}
+	store := &stubEarningsStore{}
+	bw := &stubBlockWatcher{latestHeader: &blockwatch.MiniHeader{Number: big.NewInt(40)}}
+
+	ix, err := NewEarningsIndexer(pm.RandAddress(), stubTicketBrokerAddr, stubBondingManagerAddr, big.NewInt(0), bw, backend, store)
+	require.Nil(err)
+	go ix.Watch()
+	defer ix.Stop()
+	time.Sleep(20 * time.Millisecond)
+
+	// Test the block events are received while the earnings are backfilled
+	for i := int64(41); i <= 60; i++ {
+		select {
+		case bw.sink <- []*blockwatch.Event{{Type: blockwatch.Added, BlockHeader: &blockwatch.MiniHeader{Number: big.NewInt(i)}}}:
+		case <-time.After(time.Second):
+			t.Fatal("block events aren't received during the backfill")
+		}
+	}
+
+	// Test the earnings are indexed up to the last head once the backfill is done
+	close(backend.release)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(big.NewInt(48), store.lastBlock)
+}
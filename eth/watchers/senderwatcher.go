@@ -20,6 +20,7 @@ import (
 type SenderWatcher struct {
 	senders        map[ethcommon.Address]*pm.SenderInfo
 	claimedReserve map[ethcommon.Address]*big.Int // map representing how much a recipient has drawn from a sender's reserve
+	fetches        map[ethcommon.Address]*senderInfoFetch
 	mu             sync.RWMutex
 	quit           chan struct{}
 	watcher        BlockWatcher
@@ -30,6 +31,8 @@ type SenderWatcher struct {
 	// subscriptions
 	reserveChangeFeed  event.Feed
 	reserveChangeScope event.SubscriptionScope
+	senderEventFeed    event.Feed
+	senderEventScope   event.SubscriptionScope
 }
 
 // NewSenderWatcher initiates a new SenderWatcher
@@ -46,10 +49,18 @@ func NewSenderWatcher(ticketBrokerAddr ethcommon.Address, watcher BlockWatcher,
 		lpEth:          lpEth,
 		senders:        make(map[ethcommon.Address]*pm.SenderInfo),
 		claimedReserve: make(map[ethcommon.Address]*big.Int),
+		fetches:        make(map[ethcommon.Address]*senderInfoFetch),
 		dec:            dec,
 	}, nil
 }
 
+// senderInfoFetch is an RPC call fetching the info of a sender that isn't cached
+type senderInfoFetch struct {
+	done chan struct{}
+	info *pm.SenderInfo
+	err  error
+}
+
 // GetSenderInfo returns information about a sender's deposit and reserve
 // if values for a sender are not cached an RPC call to a remote ethereum node will be made to initialize the cache
 func (sw *SenderWatcher) GetSenderInfo(addr ethcommon.Address) (*pm.SenderInfo, error) {
@@ -57,16 +68,49 @@ func (sw *SenderWatcher) GetSenderInfo(addr ethcommon.Address) (*pm.SenderInfo,
 	cache := sw.senders[addr]
 	sw.mu.RUnlock()
 	if cache == nil {
-		info, err := sw.lpEth.GetSenderInfo(addr)
-		if err != nil {
-			return nil, fmt.Errorf("GetSenderInfo RPC call to remote node failed: %v", err)
-		}
-		sw.setSenderInfo(addr, info)
-		return info, nil
+		return sw.fetchSenderInfo(addr)
 	}
 	return cache, nil
 }
 
+// fetchSenderInfo initializes the cache of a sender with a single RPC call, which the calls
+// for the same sender made while it is in flight wait for, so that the tickets of a new
+// sender that arrive at once don't each make an RPC call
+func (sw *SenderWatcher) fetchSenderInfo(addr ethcommon.Address) (*pm.SenderInfo, error) {
+	sw.mu.Lock()
+	if info := sw.senders[addr]; info != nil {
+		sw.mu.Unlock()
+		return info, nil
+	}
+	if sw.fetches == nil {
+		sw.fetches = make(map[ethcommon.Address]*senderInfoFetch)
+	}
+	fetch, ok := sw.fetches[addr]
+	if ok {
+		sw.mu.Unlock()
+		<-fetch.done
+	} else {
+		fetch = &senderInfoFetch{done: make(chan struct{})}
+		sw.fetches[addr] = fetch
+		sw.mu.Unlock()
+
+		fetch.info, fetch.err = sw.lpEth.GetSenderInfo(addr)
+		if fetch.err == nil {
+			sw.setSenderInfo(addr, fetch.info)
+		}
+
+		sw.mu.Lock()
+		delete(sw.fetches, addr)
+		sw.mu.Unlock()
+		close(fetch.done)
+	}
+
+	if fetch.err != nil {
+		return nil, fmt.Errorf("GetSenderInfo RPC call to remote node failed: %v", fetch.err)
+	}
+	return fetch.info, nil
+}
+
 func (sw *SenderWatcher) setSenderInfo(addr ethcommon.Address, info *pm.SenderInfo) {
 	sw.mu.Lock()
 	defer sw.mu.Unlock()
@@ -126,6 +170,7 @@ func (sw *SenderWatcher) Watch() {
 func (sw *SenderWatcher) Stop() {
 	close(sw.quit)
 	sw.reserveChangeScope.Close()
+	sw.senderEventScope.Close()
 }
 
 // Clear removes a key-value pair from the map
@@ -145,10 +190,22 @@ func (sw *SenderWatcher) SubscribeReserveChange(sink chan<- ethcommon.Address) e
 	return sw.reserveChangeScope.Track(sw.reserveChangeFeed.Subscribe(sink))
 }
 
+// SubscribeSenderEvents notifies subscribers of the TicketBroker events of all senders. The events
+// of logs removed by a chain reorg are notified again with Removed set, before the events of the
+// blocks that replaced them
+// The sink channel should have ample buffer space to avoid blocking other subscribers.
+func (sw *SenderWatcher) SubscribeSenderEvents(sink chan<- *pm.SenderEvent) event.Subscription {
+	return sw.senderEventScope.Track(sw.senderEventFeed.Subscribe(sink))
+}
+
 func (sw *SenderWatcher) handleBlockEvents(events []*blockwatch.Event) {
 	for _, event := range events {
-		for _, log := range event.BlockHeader.Logs {
+		logs := event.BlockHeader.Logs
+		for i := range logs {
+			log := logs[i]
 			if event.Type == blockwatch.Removed {
+				// Replay the removed logs newest first, so that subscribers undo their events in reverse
+				log = logs[len(logs)-1-i]
 				log.Removed = true
 			}
 			if err := sw.handleLog(log); err != nil {
@@ -165,26 +222,49 @@ func (sw *SenderWatcher) handleLog(log types.Log) error {
 		return nil
 	}
 
+	ev, err := sw.updateSender(eventName, log)
+	if err != nil || ev == nil {
+		return err
+	}
+
+	// The event is sent once the lock is released, so that slow subscribers don't hold up the
+	// handling of the logs and the readers of the senders
+	sw.senderEventFeed.Send(ev)
+
+	return nil
+}
+
+// updateSender updates the info of the sender of a TicketBroker log, and returns the event of
+// the log or nil if the log is not a sender event
+func (sw *SenderWatcher) updateSender(eventName string, log types.Log) (*pm.SenderEvent, error) {
 	sw.mu.Lock()
 	defer sw.mu.Unlock()
 
 	var sender ethcommon.Address
+	ev := &pm.SenderEvent{
+		Type:        pm.SenderEventType(eventName),
+		Removed:     log.Removed,
+		BlockNumber: log.BlockNumber,
+		TxHash:      log.TxHash,
+	}
 	switch eventName {
 	case "DepositFunded":
 		var depositFunded contracts.TicketBrokerDepositFunded
 		if err := sw.dec.Decode("DepositFunded", log, &depositFunded); err != nil {
-			return fmt.Errorf("failed to decode DepositFunded event: %v", err)
+			return nil, fmt.Errorf("failed to decode DepositFunded event: %v", err)
 		}
 		sender = depositFunded.Sender
+		ev.Amount = depositFunded.Amount
 		if info, ok := sw.senders[sender]; ok && !log.Removed {
 			info.Deposit.Add(info.Deposit, depositFunded.Amount)
 		}
 	case "ReserveFunded":
 		var reserveFunded contracts.TicketBrokerReserveFunded
 		if err := sw.dec.Decode("ReserveFunded", log, &reserveFunded); err != nil {
-			return fmt.Errorf("failed to decode ReserveFunded event: %v", err)
+			return nil, fmt.Errorf("failed to decode ReserveFunded event: %v", err)
 		}
 		sender = reserveFunded.ReserveHolder
+		ev.Amount = reserveFunded.Amount
 		if info, ok := sw.senders[sender]; ok && !log.Removed {
 			info.Reserve.FundsRemaining.Add(info.Reserve.FundsRemaining, reserveFunded.Amount)
 			sw.reserveChangeFeed.Send(sender)
@@ -192,9 +272,10 @@ func (sw *SenderWatcher) handleLog(log types.Log) error {
 	case "Withdrawal":
 		var withdrawal contracts.TicketBrokerWithdrawal
 		if err := sw.dec.Decode("Withdrawal", log, &withdrawal); err != nil {
-			return fmt.Errorf("failed to decode Withdrawal event: %v", err)
+			return nil, fmt.Errorf("failed to decode Withdrawal event: %v", err)
 		}
 		sender = withdrawal.Sender
+		ev.Amount = new(big.Int).Add(withdrawal.Deposit, withdrawal.Reserve)
 		if info, ok := sw.senders[sender]; ok && !log.Removed {
 			info.Deposit = big.NewInt(0)
 			info.Reserve.FundsRemaining = big.NewInt(0)
@@ -204,10 +285,12 @@ func (sw *SenderWatcher) handleLog(log types.Log) error {
 	case "WinningTicketTransfer":
 		var winningTicketTransfer contracts.TicketBrokerWinningTicketTransfer
 		if err := sw.dec.Decode("WinningTicketTransfer", log, &winningTicketTransfer); err != nil {
-			return fmt.Errorf("failed to decode WinningTicketTransfer event: %v", err)
+			return nil, fmt.Errorf("failed to decode WinningTicketTransfer event: %v", err)
 		}
 		amount := winningTicketTransfer.Amount
 		sender = winningTicketTransfer.Sender
+		ev.Amount = amount
+		ev.Recipient = winningTicketTransfer.Recipient
 
 		if info, ok := sw.senders[sender]; ok && !log.Removed {
 			// See if amount > deposit
@@ -236,9 +319,10 @@ func (sw *SenderWatcher) handleLog(log types.Log) error {
 		// Set withdraw block
 		var unlock contracts.TicketBrokerUnlock
 		if err := sw.dec.Decode("Unlock", log, &unlock); err != nil {
-			return fmt.Errorf("failed to decode Unlock event: %v", err)
+			return nil, fmt.Errorf("failed to decode Unlock event: %v", err)
 		}
 		sender = unlock.Sender
+		ev.EndRound = unlock.EndRound
 		if info, ok := sw.senders[sender]; ok && !log.Removed {
 			info.WithdrawRound = unlock.EndRound
 		}
@@ -246,20 +330,20 @@ func (sw *SenderWatcher) handleLog(log types.Log) error {
 		// Unset withdrawRound
 		var unlockCancelled contracts.TicketBrokerUnlockCancelled
 		if err := sw.dec.Decode("UnlockCancelled", log, &unlockCancelled); err != nil {
-			return fmt.Errorf("failed to decode UnlockCancelled event: %v", err)
+			return nil, fmt.Errorf("failed to decode UnlockCancelled event: %v", err)
 		}
 		sender = unlockCancelled.Sender
 		if info, ok := sw.senders[sender]; ok && !log.Removed {
 			info.WithdrawRound = big.NewInt(0)
 		}
 	default:
-		return nil
+		return nil, nil
 	}
 
 	if _, ok := sw.senders[sender]; ok && log.Removed {
 		info, err := sw.lpEth.GetSenderInfo(sender)
 		if err != nil {
-			return fmt.Errorf("GetSenderInfo RPC call to remote node failed: %v", err)
+			return nil, fmt.Errorf("GetSenderInfo RPC call to remote node failed: %v", err)
 		}
 		sw.senders[sender] = info
 		if eventName == "ReserveFunded" {
@@ -267,12 +351,14 @@ func (sw *SenderWatcher) handleLog(log types.Log) error {
 		}
 	}
 
+	ev.Sender = sender
+
 	if sender == sw.lpEth.Account().Address && monitor.Enabled {
 		monitor.Deposit(sender.Hex(), sw.senders[sender].Deposit)
 		monitor.Reserve(sender.Hex(), sw.senders[sender].Reserve.FundsRemaining)
 	}
 
-	return nil
+	return ev, nil
 }
 
 func (sw *SenderWatcher) handleRoundEvent(log types.Log) error {
@@ -285,7 +371,7 @@ func (sw *SenderWatcher) handleRoundEvent(log types.Log) error {
 			if err != nil {
 				return fmt.Errorf("GetSenderInfo RPC call to remote node failed: %v", err)
 			}
-			info = i
+			sw.senders[sender] = i
 		} else {
 			info.Reserve.ClaimedInCurrentRound = big.NewInt(0)
 		}
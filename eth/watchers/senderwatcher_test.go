@@ -3,11 +3,14 @@ package watchers
 import (
 	"errors"
 	"math/big"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/livepeer/go-livepeer/eth"
 	"github.com/livepeer/go-livepeer/eth/blockwatch"
 	"github.com/livepeer/go-livepeer/pm"
@@ -57,6 +60,83 @@ func TestGetAndSetSenderInfo(t *testing.T) {
 	assert.Zero(info.Reserve.FundsRemaining.Cmp(big.NewInt(5)))
 }
 
+// countingClient counts the GetSenderInfo RPC calls, which block until release is closed
+type countingClient struct {
+	*eth.StubClient
+	calls   int32
+	release chan struct{}
+	err     error
+}
+
+func (c *countingClient) GetSenderInfo(addr ethcommon.Address) (*pm.SenderInfo, error) {
+	atomic.AddInt32(&c.calls, 1)
+	<-c.release
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.StubClient.GetSenderInfo(addr)
+}
+
+func TestGetSenderInfo_ConcurrentFetches(t *testing.T) {
+	assert := assert.New(t)
+	lpEth := &countingClient{
+		StubClient: &eth.StubClient{
+			SenderInfo: &pm.SenderInfo{
+				Deposit: big.NewInt(10),
+				Reserve: &pm.ReserveInfo{
+					FundsRemaining:        big.NewInt(5),
+					ClaimedInCurrentRound: big.NewInt(0),
+				},
+			},
+		},
+		release: make(chan struct{}),
+	}
+	sw, err := NewSenderWatcher(stubTicketBrokerAddr, &stubBlockWatcher{}, lpEth, &stubTimeWatcher{})
+	require.Nil(t, err)
+	sender := pm.RandAddress()
+
+	getInfos := func() ([]*pm.SenderInfo, []error) {
+		var wg sync.WaitGroup
+		infos := make([]*pm.SenderInfo, 10)
+		errs := make([]error, 10)
+		for i := range infos {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				infos[i], errs[i] = sw.GetSenderInfo(sender)
+			}(i)
+		}
+		time.Sleep(20 * time.Millisecond)
+		lpEth.release <- struct{}{}
+		wg.Wait()
+		return infos, errs
+	}
+
+	// Calls made while the RPC call is in flight share its error, which isn't cached
+	lpEth.err = errors.New("GetSenderInfo error")
+	_, errs := getInfos()
+	assert.Equal(int32(1), atomic.LoadInt32(&lpEth.calls))
+	for _, err := range errs {
+		assert.EqualError(err, "GetSenderInfo RPC call to remote node failed: GetSenderInfo error")
+	}
+
+	// and its result, which is cached
+	lpEth.err = nil
+	infos, errs := getInfos()
+	assert.Equal(int32(2), atomic.LoadInt32(&lpEth.calls))
+	for i := range infos {
+		assert.Nil(errs[i])
+		assert.Equal(lpEth.SenderInfo, infos[i])
+	}
+
+	close(lpEth.release)
+	info, err := sw.GetSenderInfo(sender)
+	assert.Nil(err)
+	assert.Equal(lpEth.SenderInfo, info)
+	assert.Equal(int32(2), atomic.LoadInt32(&lpEth.calls))
+	assert.Empty(sw.fetches)
+}
+
 func TestSenderWatcher_Clear(t *testing.T) {
 	assert := assert.New(t)
 	stubClientSenderInfo := &pm.SenderInfo{
@@ -689,7 +769,11 @@ func TestNewRoundEvent_LogRemoved(t *testing.T) {
 	// change stub RPC call values
 	expectedClaimedInCurrentRound := big.NewInt(500)
 	expectedClaimedAmount := big.NewInt(2000)
-	lpEth.SenderInfo.Reserve.ClaimedInCurrentRound = expectedClaimedInCurrentRound
+	lpEth.SenderInfo = &pm.SenderInfo{
+		Reserve: &pm.ReserveInfo{
+			ClaimedInCurrentRound: expectedClaimedInCurrentRound,
+		},
+	}
 	lpEth.ClaimedAmount = expectedClaimedAmount
 
 	newRoundEvent.Removed = true
@@ -780,3 +864,92 @@ func TestSubscribeReserveChange(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestSubscribeSenderEvents(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	watcher := &stubBlockWatcher{}
+	lpEth := &eth.StubClient{}
+
+	sw, err := NewSenderWatcher(stubTicketBrokerAddr, watcher, lpEth, &stubTimeWatcher{})
+	require.Nil(err)
+
+	sink := make(chan *pm.SenderEvent, 10)
+	sub := sw.SubscribeSenderEvents(sink)
+	defer sub.Unsubscribe()
+
+	// Test the events of senders that aren't cached are notified
+	header := defaultMiniHeader()
+	header.Logs = []types.Log{newStubReserveFundedLog(), newStubUnlockLog(), newStubWinningTicketLog()}
+	blockEvent := &blockwatch.Event{
+		Type:        blockwatch.Added,
+		BlockHeader: header,
+	}
+	sw.handleBlockEvents([]*blockwatch.Event{blockEvent})
+	require.Len(sink, 3)
+
+	ev := <-sink
+	assert.Equal(pm.SenderEventReserveFunded, ev.Type)
+	assert.Equal(stubSender, ev.Sender)
+	assert.Equal("5000000000000000000", ev.Amount.String())
+	assert.False(ev.Removed)
+
+	ev = <-sink
+	assert.Equal(pm.SenderEventUnlock, ev.Type)
+	assert.Equal(big.NewInt(150), ev.EndRound)
+
+	ev = <-sink
+	assert.Equal(pm.SenderEventWinningTicketTransfer, ev.Type)
+	assert.Equal(stubClaimant, ev.Recipient)
+	assert.Equal(big.NewInt(200000000000), ev.Amount)
+
+	// Test removed logs are replayed newest first
+	blockEvent.Type = blockwatch.Removed
+	sw.handleBlockEvents([]*blockwatch.Event{blockEvent})
+	require.Len(sink, 3)
+	for _, typ := range []pm.SenderEventType{pm.SenderEventWinningTicketTransfer, pm.SenderEventUnlock, pm.SenderEventReserveFunded} {
+		ev = <-sink
+		assert.Equal(typ, ev.Type)
+		assert.True(ev.Removed)
+	}
+
+	// Test unknown events aren't notified
+	header.Logs = []types.Log{newStubBaseLog()}
+	blockEvent.Type = blockwatch.Added
+	sw.handleBlockEvents([]*blockwatch.Event{blockEvent})
+	assert.Len(sink, 0)
+}
+
+func TestSubscribeSenderEvents_SlowSubscriber(t *testing.T) {
+	require := require.New(t)
+	watcher := &stubBlockWatcher{}
+	lpEth := &eth.StubClient{
+		SenderInfo: &pm.SenderInfo{
+			Reserve: &pm.ReserveInfo{
+				FundsRemaining: big.NewInt(100),
+			},
+		},
+	}
+
+	sw, err := NewSenderWatcher(stubTicketBrokerAddr, watcher, lpEth, &stubTimeWatcher{})
+	require.Nil(err)
+
+	// Test a subscriber that doesn't receive its events doesn't hold the lock of the senders
+	sink := make(chan *pm.SenderEvent)
+	sub := sw.SubscribeSenderEvents(sink)
+	defer sub.Unsubscribe()
+	go sw.handleLog(newStubReserveFundedLog())
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		sw.GetSenderInfo(stubSender)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("senders locked while sending an event")
+	}
+	<-sink
+}
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"math/big"
 	"sync"
+	"time"
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/livepeer/go-livepeer/eth/contracts"
 
@@ -24,9 +26,14 @@ import (
 // consumers of the TimeWatcher can subscribe to following data feeds:
 // 	* Last Initialized Round Number
 //	* Last Seen Block Number
+//
+// The rounds are also polled from the RoundsManager every roundsPollingInterval, so that the
+// rounds initialized in NewRound events that were missed, e.g. while the block watcher was
+// disconnected, are still noticed and notified to the round subscribers
 type TimeWatcher struct {
 	// state
 	mu                       sync.RWMutex
+	currentRound             *big.Int
 	lastInitializedRound     *big.Int
 	lastInitializedBlockHash [32]byte
 	transcoderPoolSize       *big.Int
@@ -46,6 +53,13 @@ type TimeWatcher struct {
 	quit chan struct{}
 }
 
+// roundsPollingInterval is the interval at which the current round and the last initialized
+// round are read from the RoundsManager
+var roundsPollingInterval = 1 * time.Minute
+
+// newRoundTopic is the topic of NewRound events
+var newRoundTopic = crypto.Keccak256Hash([]byte("NewRound(uint256,bytes32)"))
+
 // NewTimeWatcher creates a new instance of TimeWatcher and sets the initial cache through an RPC call to an ethereum node
 func NewTimeWatcher(roundsManagerAddr ethcommon.Address, watcher BlockWatcher, lpEth eth.LivepeerEthClient) (*TimeWatcher, error) {
 	dec, err := NewEventDecoder(roundsManagerAddr, contracts.RoundsManagerABI)
@@ -61,6 +75,20 @@ func NewTimeWatcher(roundsManagerAddr ethcommon.Address, watcher BlockWatcher, l
 	}, nil
 }
 
+// CurrentRound gets the current round from cache. The current round is not initialized until
+// it is the last initialized round
+func (tw *TimeWatcher) CurrentRound() *big.Int {
+	tw.mu.RLock()
+	defer tw.mu.RUnlock()
+	return tw.currentRound
+}
+
+func (tw *TimeWatcher) setCurrentRound(round *big.Int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.currentRound = round
+}
+
 // LastInitializedRound gets the last initialized round from cache
 func (tw *TimeWatcher) LastInitializedRound() *big.Int {
 	tw.mu.RLock()
@@ -75,6 +103,19 @@ func (tw *TimeWatcher) LastInitializedBlockHash() [32]byte {
 	return tw.lastInitializedBlockHash
 }
 
+// BlockHashForRound returns the blockhash that a round was initialized with, from cache for the
+// last initialized round and through an RPC call for other rounds
+func (tw *TimeWatcher) BlockHashForRound(round *big.Int) ([32]byte, error) {
+	tw.mu.RLock()
+	lastRound, lastHash := tw.lastInitializedRound, tw.lastInitializedBlockHash
+	tw.mu.RUnlock()
+
+	if lastRound != nil && lastRound.Cmp(round) == 0 {
+		return lastHash, nil
+	}
+	return tw.lpEth.BlockHashForRound(round)
+}
+
 func (tw *TimeWatcher) setLastInitializedRound(round *big.Int, hash [32]byte) {
 	tw.mu.Lock()
 	defer tw.mu.Unlock()
@@ -118,6 +159,12 @@ func (tw *TimeWatcher) Watch() error {
 	}
 	tw.setLastInitializedRound(lr, bh)
 
+	cr, err := tw.lpEth.CurrentRound()
+	if err != nil {
+		return fmt.Errorf("error fetching initial currentRound value err=%v", err)
+	}
+	tw.setCurrentRound(cr)
+
 	if err := tw.fetchAndSetTranscoderPoolSize(); err != nil {
 		return fmt.Errorf("error fetching initial transcoderPoolSize err=%v", err)
 	}
@@ -135,6 +182,8 @@ func (tw *TimeWatcher) Watch() error {
 	events := make(chan []*blockwatch.Event, 10)
 	sub := tw.watcher.Subscribe(events)
 	defer sub.Unsubscribe()
+	ticker := time.NewTicker(roundsPollingInterval)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-tw.quit:
@@ -143,6 +192,10 @@ func (tw *TimeWatcher) Watch() error {
 			glog.Error(err)
 		case events := <-events:
 			tw.handleBlockEvents(events)
+		case <-ticker.C:
+			if err := tw.pollRounds(); err != nil {
+				glog.Errorf("error polling rounds err=%v", err)
+			}
 		}
 	}
 }
@@ -209,6 +262,11 @@ func (tw *TimeWatcher) handleLog(log types.Log) error {
 		return fmt.Errorf("unable to decode event: %v", err)
 	}
 
+	// Noop if the round was already noticed when polling the rounds
+	if last := tw.LastInitializedRound(); !log.Removed && last != nil && last.Cmp(nr.Round) == 0 {
+		return nil
+	}
+
 	tw.roundSubFeed.Send(log)
 
 	if log.Removed {
@@ -223,6 +281,7 @@ func (tw *TimeWatcher) handleLog(log types.Log) error {
 		tw.setLastInitializedRound(lr, bh)
 	} else {
 		tw.setLastInitializedRound(nr.Round, nr.BlockHash)
+		tw.setCurrentRound(nr.Round)
 	}
 
 	// Get the active transcoder pool size when we receive a NewRound event
@@ -233,6 +292,41 @@ func (tw *TimeWatcher) handleLog(log types.Log) error {
 	return nil
 }
 
+// pollRounds reads the current round and the last initialized round from the RoundsManager. If
+// the last initialized round is not the cached one, its NewRound event was missed, and the
+// round subscribers are sent a NewRound log for it
+func (tw *TimeWatcher) pollRounds() error {
+	cr, err := tw.lpEth.CurrentRound()
+	if err != nil {
+		return err
+	}
+	tw.setCurrentRound(cr)
+
+	lr, err := tw.lpEth.LastInitializedRound()
+	if err != nil {
+		return err
+	}
+	if last := tw.LastInitializedRound(); last != nil && last.Cmp(lr) == 0 {
+		return nil
+	}
+
+	bh, err := tw.lpEth.BlockHashForRound(lr)
+	if err != nil {
+		return err
+	}
+
+	glog.Infof("Missed NewRound event, found initialized round=%v when polling", lr)
+
+	tw.setLastInitializedRound(lr, bh)
+	tw.roundSubFeed.Send(types.Log{
+		Address: tw.dec.addr,
+		Topics:  []ethcommon.Hash{newRoundTopic, ethcommon.BigToHash(lr)},
+		Data:    bh[:],
+	})
+
+	return tw.fetchAndSetTranscoderPoolSize()
+}
+
 func (tw *TimeWatcher) fetchAndSetTranscoderPoolSize() error {
 	size, err := tw.lpEth.GetTranscoderPoolSize()
 	if err != nil {
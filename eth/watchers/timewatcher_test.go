@@ -11,6 +11,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/livepeer/go-livepeer/eth"
 	"github.com/livepeer/go-livepeer/eth/blockwatch"
+	"github.com/livepeer/go-livepeer/eth/contracts"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -31,6 +32,23 @@ func TestSetAndGet_LastInitializedRound_LastInitializedBlockHash(t *testing.T) {
 	assert.Equal(h, hash)
 }
 
+func TestBlockHashForRound(t *testing.T) {
+	assert := assert.New(t)
+	lpEth := &eth.StubClient{BlockHashToReturn: [32]byte{1}}
+	tw := &TimeWatcher{lpEth: lpEth}
+	tw.setLastInitializedRound(big.NewInt(5), [32]byte{5})
+
+	// Last initialized round is cached
+	bh, err := tw.BlockHashForRound(big.NewInt(5))
+	assert.Nil(err)
+	assert.Equal([32]byte{5}, bh)
+
+	// Other rounds are fetched
+	bh, err = tw.BlockHashForRound(big.NewInt(4))
+	assert.Nil(err)
+	assert.Equal([32]byte{1}, bh)
+}
+
 func TestSetAndGet_TranscoderPoolSize(t *testing.T) {
 	assert := assert.New(t)
 	tw := &TimeWatcher{}
@@ -250,3 +268,54 @@ func TestSubscribeRounds(t *testing.T) {
 	update := <-events
 	assert.Equal(newRoundEvent, update)
 }
+
+func TestTimeWatcher_PollRounds(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	lpEth := &eth.StubClient{
+		PoolSize:          big.NewInt(50),
+		BlockHashToReturn: ethcommon.HexToHash("foo"),
+		Round:             big.NewInt(8),
+	}
+	tw, err := NewTimeWatcher(stubRoundsManagerAddr, &stubBlockWatcher{}, lpEth)
+	require.Nil(err)
+	tw.setLastInitializedRound(big.NewInt(7), [32]byte{7})
+
+	events := make(chan types.Log, 10)
+	sub := tw.SubscribeRounds(events)
+	defer sub.Unsubscribe()
+
+	// Test missed round is notified with a NewRound log
+	require.Nil(tw.pollRounds())
+	assert.Equal(big.NewInt(0), tw.CurrentRound())
+	assert.Equal(big.NewInt(8), tw.LastInitializedRound())
+	assert.Equal([32]byte(lpEth.BlockHashToReturn), tw.LastInitializedBlockHash())
+	assert.Equal(big.NewInt(50), tw.GetTranscoderPoolSize())
+	require.Len(events, 1)
+	log := <-events
+	var nr contracts.RoundsManagerNewRound
+	require.Nil(tw.dec.Decode("NewRound", log, &nr))
+	assert.Equal(big.NewInt(8), nr.Round)
+	assert.Equal([32]byte(lpEth.BlockHashToReturn), nr.BlockHash)
+
+	// Test rounds that were noticed aren't notified again
+	require.Nil(tw.pollRounds())
+	assert.Len(events, 0)
+	require.Nil(tw.handleLog(newStubNewRoundLog()))
+	assert.Len(events, 0)
+
+	// Test NewRound log sets the current round
+	lpEth.Round = big.NewInt(9)
+	log = newStubNewRoundLog()
+	log.Topics[1] = ethcommon.BigToHash(big.NewInt(9))
+	require.Nil(tw.handleLog(log))
+	assert.Len(events, 1)
+	assert.Equal(big.NewInt(9), tw.CurrentRound())
+	require.Nil(tw.pollRounds())
+	assert.Len(events, 1)
+
+	// Test RPC error
+	lpEth.RoundsErr = errors.New("RoundsErr")
+	assert.EqualError(tw.pollRounds(), "RoundsErr")
+}
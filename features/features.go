@@ -0,0 +1,123 @@
+// Package features gates experimental subsystems behind feature flags, so that they can
+// ship disabled and be enabled on a node without rebuilding it. Features are enabled with
+// the -features flag, or at runtime from the CLI server
+package features
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Feature is an experimental subsystem that is disabled unless enabled on the node
+type Feature struct {
+	Name        string
+	Description string
+	enabled     int32
+}
+
+// Enabled returns true if the feature is enabled on the node. It is cheap enough to be
+// called on every use of the subsystem, so that toggling the feature takes effect at once
+func (f *Feature) Enabled() bool {
+	return atomic.LoadInt32(&f.enabled) == 1
+}
+
+func (f *Feature) set(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&f.enabled, v)
+}
+
+// Status is the state of a feature, as reported by the CLI server
+type Status struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+var (
+	mu       sync.Mutex
+	features = make(map[string]*Feature)
+)
+
+// Register registers a disabled feature. It is meant to be called when initializing the
+// package of the subsystem, e.g.
+//
+//	var lowLatencyHLS = features.Register("llhls", "Low latency HLS playlists")
+//
+// and panics if the name is already registered or is not a valid name for -features
+func Register(name, description string) *Feature {
+	mu.Lock()
+	defer mu.Unlock()
+	if name == "" || strings.ContainsAny(name, ", ") {
+		panic(fmt.Sprintf("invalid feature name %q", name))
+	}
+	if _, ok := features[name]; ok {
+		panic(fmt.Sprintf("feature %v registered twice", name))
+	}
+	f := &Feature{Name: name, Description: description}
+	features[name] = f
+	return f
+}
+
+// Set enables or disables a feature
+func Set(name string, enabled bool) error {
+	mu.Lock()
+	defer mu.Unlock()
+	f, ok := features[name]
+	if !ok {
+		return fmt.Errorf("unknown feature %v", name)
+	}
+	f.set(enabled)
+	return nil
+}
+
+// Configure enables the features of list, a comma separated list of names, and disables
+// the others. Nothing changes if list names an unknown feature
+func Configure(list string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	enabled := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := features[name]; !ok {
+			return fmt.Errorf("unknown feature %v", name)
+		}
+		enabled[name] = true
+	}
+	for name, f := range features {
+		f.set(enabled[name])
+	}
+	return nil
+}
+
+// List returns the state of the registered features, sorted by name
+func List() []Status {
+	mu.Lock()
+	defer mu.Unlock()
+	list := make([]Status, 0, len(features))
+	for _, f := range features {
+		list = append(list, Status{Name: f.Name, Description: f.Description, Enabled: f.Enabled()})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// EnabledList returns the names of the enabled features as a comma separated list, like
+// the value of -features
+func EnabledList() string {
+	var names []string
+	for _, s := range List() {
+		if s.Enabled {
+			names = append(names, s.Name)
+		}
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,41 @@
+package features
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatures(t *testing.T) {
+	assert := assert.New(t)
+	defer func() { features = make(map[string]*Feature) }()
+
+	foo := Register("foo", "Foo subsystem")
+	bar := Register("bar", "Bar subsystem")
+	assert.Panics(func() { Register("foo", "Foo again") })
+	assert.Panics(func() { Register("foo,bar", "") })
+	assert.False(foo.Enabled())
+	assert.Equal("", EnabledList())
+
+	assert.Nil(Configure(" foo, bar,"))
+	assert.True(foo.Enabled())
+	assert.True(bar.Enabled())
+	assert.Equal("bar,foo", EnabledList())
+
+	// Features that are not listed are disabled
+	assert.Nil(Configure("bar"))
+	assert.False(foo.Enabled())
+	assert.True(bar.Enabled())
+
+	// Unknown features are rejected
+	assert.EqualError(Configure("foo,baz"), "unknown feature baz")
+	assert.False(foo.Enabled())
+	assert.EqualError(Set("baz", true), "unknown feature baz")
+
+	assert.Nil(Set("foo", true))
+	assert.Nil(Set("bar", false))
+	assert.Equal([]Status{
+		{Name: "bar", Description: "Bar subsystem", Enabled: false},
+		{Name: "foo", Description: "Foo subsystem", Enabled: true},
+	}, List())
+}
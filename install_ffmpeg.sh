@@ -53,6 +53,56 @@ if [ ! -e "$HOME/x264" ]; then
   make install-lib-static
 fi
 
+if [ ! -e "$HOME/x265" ]; then
+  git clone https://bitbucket.org/multicoreware/x265_git.git "$HOME/x265"
+  cd "$HOME/x265"
+  git checkout 3.4
+  cd build
+  cmake -G "Unix Makefiles" -DCMAKE_INSTALL_PREFIX="$HOME/compiled" -DENABLE_SHARED=off -DENABLE_CLI=off ../source
+  make
+  make install
+fi
+
+if [ ! -e "$HOME/aom" ]; then
+  git clone https://aomedia.googlesource.com/aom "$HOME/aom"
+  cd "$HOME/aom"
+  git checkout v2.0.0
+  mkdir -p build-lp
+  cd build-lp
+  cmake -G "Unix Makefiles" -DCMAKE_INSTALL_PREFIX="$HOME/compiled" -DBUILD_SHARED_LIBS=off -DENABLE_DOCS=off -DENABLE_EXAMPLES=off -DENABLE_TESTS=off -DENABLE_TOOLS=off ..
+  make
+  make install
+fi
+
+if [ ! -e "$HOME/libvpx" ]; then
+  git clone https://chromium.googlesource.com/webm/libvpx.git "$HOME/libvpx"
+  cd "$HOME/libvpx"
+  git checkout v1.9.0
+  ./configure --prefix="$HOME/compiled" --enable-pic --enable-static --disable-shared --disable-examples --disable-tools --disable-docs --disable-unit-tests
+  make
+  make install
+fi
+
+if [ ! -e "$HOME/libogg-1.3.4" ]; then
+  cd "$HOME"
+  curl -LO https://downloads.xiph.org/releases/ogg/libogg-1.3.4.tar.xz
+  tar xf libogg-1.3.4.tar.xz
+  cd "$HOME/libogg-1.3.4"
+  ./configure ${HOST_OS:-} --prefix="$HOME/compiled" --disable-shared --with-pic
+  make
+  make install
+fi
+
+if [ ! -e "$HOME/libvorbis-1.3.7" ]; then
+  cd "$HOME"
+  curl -LO https://downloads.xiph.org/releases/vorbis/libvorbis-1.3.7.tar.xz
+  tar xf libvorbis-1.3.7.tar.xz
+  cd "$HOME/libvorbis-1.3.7"
+  ./configure ${HOST_OS:-} --prefix="$HOME/compiled" --disable-shared --with-pic --with-ogg="$HOME/compiled"
+  make
+  make install
+fi
+
 # Static linking of gnutls on Linux/Mac
 if [[ $(uname) != *"MSYS"* ]]; then
   # rm -rf "$HOME/gmp-6.1.2"
@@ -114,17 +164,18 @@ if [ ! -e "$HOME/ffmpeg/libavcodec/libavcodec.a" ]; then
     --disable-muxers --disable-demuxers --disable-parsers --disable-protocols \
     --disable-encoders --disable-decoders --disable-filters --disable-bsfs \
     --disable-postproc --disable-lzma \
-    --enable-gnutls --enable-libx264 --enable-gpl \
+    --enable-gnutls --enable-libx264 --enable-libx265 --enable-libaom --enable-libvpx --enable-libvorbis --enable-gpl \
     --enable-protocol=https,rtmp,file \
-    --enable-muxer=mpegts,hls,segment,mp4 --enable-demuxer=flv,mpegts,mp4,mov \
-    --enable-bsf=h264_mp4toannexb,aac_adtstoasc,h264_metadata,h264_redundant_pps \
-    --enable-parser=aac,aac_latm,h264 \
+    --enable-muxer=mpegts,hls,segment,mp4,webm --enable-demuxer=flv,mpegts,mp4,mov \
+    --enable-bsf=h264_mp4toannexb,hevc_mp4toannexb,aac_adtstoasc,h264_metadata,h264_redundant_pps \
+    --enable-parser=aac,aac_latm,h264,hevc,av1 \
     --enable-filter=abuffer,buffer,abuffersink,buffersink,afifo,fifo,aformat \
     --enable-filter=aresample,asetnsamples,fps,scale \
-    --enable-encoder=aac,libx264 \
-    --enable-decoder=aac,h264 \
+    --enable-encoder=aac,libx264,libx265,libaom_av1,libvpx_vp8,libvpx_vp9,libvorbis \
+    --enable-decoder=aac,h264,hevc,libaom_av1 \
     --extra-cflags="-I${HOME}/compiled/include" \
     --extra-ldflags="-L${HOME}/compiled/lib ${EXTRA_LDFLAGS}" \
+    --extra-libs="-lstdc++" \
     --prefix="$HOME/compiled" \
     $EXTRA_FFMPEG_FLAGS
   make
@@ -18,6 +18,8 @@ import (
 	"go.opencensus.io/tag"
 )
 
+// The values of the segment error labels are the names of the corresponding
+// common.ErrorCode so that errors can be aggregated across nodes
 type (
 	SegmentUploadError    string
 	SegmentTranscodeError string
@@ -25,23 +27,23 @@ type (
 
 const (
 	SegmentUploadErrorUnknown               SegmentUploadError    = "Unknown"
-	SegmentUploadErrorGenCreds              SegmentUploadError    = "GenCreds"
-	SegmentUploadErrorOS                    SegmentUploadError    = "ObjectStorage"
-	SegmentUploadErrorSessionEnded          SegmentUploadError    = "SessionEnded"
-	SegmentUploadErrorInsufficientBalance   SegmentUploadError    = "InsufficientBalance"
-	SegmentUploadErrorTimeout               SegmentUploadError    = "Timeout"
+	SegmentUploadErrorGenCreds              SegmentUploadError    = "PaymentGenCreds"
+	SegmentUploadErrorOS                    SegmentUploadError    = "StorageSaveData"
+	SegmentUploadErrorSessionEnded          SegmentUploadError    = "IngestSessionEnded"
+	SegmentUploadErrorInsufficientBalance   SegmentUploadError    = "PaymentInsufficientBalance"
+	SegmentUploadErrorTimeout               SegmentUploadError    = "IngestTimeout"
 	SegmentTranscodeErrorUnknown            SegmentTranscodeError = "Unknown"
-	SegmentTranscodeErrorUnknownResponse    SegmentTranscodeError = "UnknownResponse"
-	SegmentTranscodeErrorTranscode          SegmentTranscodeError = "Transcode"
-	SegmentTranscodeErrorOrchestratorBusy   SegmentTranscodeError = "OrchestratorBusy"
-	SegmentTranscodeErrorOrchestratorCapped SegmentTranscodeError = "OrchestratorCapped"
-	SegmentTranscodeErrorParseResponse      SegmentTranscodeError = "ParseResponse"
-	SegmentTranscodeErrorReadBody           SegmentTranscodeError = "ReadBody"
-	SegmentTranscodeErrorNoOrchestrators    SegmentTranscodeError = "NoOrchestrators"
-	SegmentTranscodeErrorDownload           SegmentTranscodeError = "Download"
-	SegmentTranscodeErrorSaveData           SegmentTranscodeError = "SaveData"
-	SegmentTranscodeErrorSessionEnded       SegmentTranscodeError = "SessionEnded"
-	SegmentTranscodeErrorPlaylist           SegmentTranscodeError = "Playlist"
+	SegmentTranscodeErrorUnknownResponse    SegmentTranscodeError = "TranscodeUnknownResponse"
+	SegmentTranscodeErrorTranscode          SegmentTranscodeError = "TranscodeFailed"
+	SegmentTranscodeErrorOrchestratorBusy   SegmentTranscodeError = "TranscodeOrchestratorBusy"
+	SegmentTranscodeErrorOrchestratorCapped SegmentTranscodeError = "TranscodeOrchestratorCapped"
+	SegmentTranscodeErrorParseResponse      SegmentTranscodeError = "TranscodeParseResponse"
+	SegmentTranscodeErrorReadBody           SegmentTranscodeError = "TranscodeReadBody"
+	SegmentTranscodeErrorNoOrchestrators    SegmentTranscodeError = "TranscodeNoOrchestrators"
+	SegmentTranscodeErrorDownload           SegmentTranscodeError = "StorageDownload"
+	SegmentTranscodeErrorSaveData           SegmentTranscodeError = "StorageSaveData"
+	SegmentTranscodeErrorSessionEnded       SegmentTranscodeError = "TranscodeSessionEnded"
+	SegmentTranscodeErrorPlaylist           SegmentTranscodeError = "StoragePlaylist"
 
 	numberOfSegmentsToCalcAverage = 30
 	gweiConversionFactor          = 1000000000
@@ -71,6 +73,7 @@ type (
 		kSender                       tag.Key
 		kRecipient                    tag.Key
 		kManifestID                   tag.Key
+		kOrchestratorURI              tag.Key
 		mSegmentSourceAppeared        *stats.Int64Measure
 		mSegmentEmerged               *stats.Int64Measure
 		mSegmentEmergedUnprocessed    *stats.Int64Measure
@@ -108,14 +111,38 @@ type (
 		mReserve            *stats.Float64Measure
 
 		// Metrics for receiving payments
-		mTicketValueRecv       *stats.Float64Measure
-		mTicketsRecv           *stats.Int64Measure
-		mPaymentRecvErr        *stats.Int64Measure
-		mWinningTicketsRecv    *stats.Int64Measure
-		mValueRedeemed         *stats.Float64Measure
-		mTicketRedemptionError *stats.Int64Measure
-		mSuggestedGasPrice     *stats.Float64Measure
-		mTranscodingPrice      *stats.Float64Measure
+		mTicketValueRecv        *stats.Float64Measure
+		mTicketsRecv            *stats.Int64Measure
+		mPaymentRecvErr         *stats.Int64Measure
+		mWinningTicketsRecv     *stats.Int64Measure
+		mValueRedeemed          *stats.Float64Measure
+		mTicketRedemptionError  *stats.Int64Measure
+		mTicketRedemptionFailed *stats.Int64Measure
+		mRedemptionSubmitted    *stats.Int64Measure
+		mSuggestedGasPrice      *stats.Float64Measure
+		mTranscodingPrice       *stats.Float64Measure
+
+		// Metrics for per-sender analytics
+		mSenderPixelsTranscoded *stats.Int64Measure
+		mSenderTicketValueRecv  *stats.Float64Measure
+		mSenderPendingFloat     *stats.Float64Measure
+
+		// Metrics for the self-test canary
+		mCanarySucceeded *stats.Int64Measure
+		mCanaryFailed    *stats.Int64Measure
+		mCanaryLatency   *stats.Float64Measure
+
+		// Metrics for the reward service
+		mRewardCalls       *stats.Int64Measure
+		mRewardCallsMissed *stats.Int64Measure
+
+		// Metrics for verification
+		mOrchestratorSuspended *stats.Int64Measure
+		mQualityScore          *stats.Float64Measure
+		mPixelsOverReported    *stats.Int64Measure
+
+		// Metrics for the protection of public endpoints
+		mRequestRejected *stats.Int64Measure
 
 		lock        sync.Mutex
 		emergeTimes map[uint64]map[uint64]time.Time // nonce:seqNo
@@ -172,6 +199,7 @@ func InitCensus(nodeType, nodeID, version string) {
 	census.kSender = tag.MustNewKey("sender")
 	census.kRecipient = tag.MustNewKey("recipient")
 	census.kManifestID = tag.MustNewKey("manifestID")
+	census.kOrchestratorURI = tag.MustNewKey("orchestrator_uri")
 	census.ctx, err = tag.New(ctx, tag.Insert(census.kNodeType, nodeType), tag.Insert(census.kNodeID, nodeID))
 	if err != nil {
 		glog.Fatal("Error creating context", err)
@@ -221,9 +249,33 @@ func InitCensus(nodeType, nodeID, version string) {
 	census.mWinningTicketsRecv = stats.Int64("winning_tickets_recv", "WinningTicketsRecv", "tot")
 	census.mValueRedeemed = stats.Float64("value_redeemed", "ValueRedeemed", "gwei")
 	census.mTicketRedemptionError = stats.Int64("ticket_redemption_errors", "TicketRedemptionError", "tot")
+	census.mTicketRedemptionFailed = stats.Int64("ticket_redemptions_failed", "TicketRedemptionFailed", "tot")
+	census.mRedemptionSubmitted = stats.Int64("ticket_redemptions_submitted", "RedemptionSubmitted", "tot")
 	census.mSuggestedGasPrice = stats.Float64("suggested_gas_price", "SuggestedGasPrice", "gwei")
 	census.mTranscodingPrice = stats.Float64("transcoding_price", "TranscodingPrice", "wei")
 
+	// Metrics for per-sender analytics
+	census.mSenderPixelsTranscoded = stats.Int64("sender_pixels_transcoded", "SenderPixelsTranscoded", "tot")
+	census.mSenderTicketValueRecv = stats.Float64("sender_ticket_value_recv", "SenderTicketValueRecv", "gwei")
+	census.mSenderPendingFloat = stats.Float64("sender_pending_float", "SenderPendingFloat", "gwei")
+
+	// Metrics for the self-test canary
+	census.mCanarySucceeded = stats.Int64("canary_succeeded_total", "CanarySucceeded", "tot")
+	census.mCanaryFailed = stats.Int64("canary_failed_total", "CanaryFailed", "tot")
+	census.mCanaryLatency = stats.Float64("canary_latency_seconds", "Canary latency, from segment push till playback check", "sec")
+
+	// Metrics for the reward service
+	census.mRewardCalls = stats.Int64("reward_calls_total", "RewardCalls", "tot")
+	census.mRewardCallsMissed = stats.Int64("reward_calls_missed_total", "RewardCallsMissed", "tot")
+
+	// Metrics for verification
+	census.mOrchestratorSuspended = stats.Int64("orchestrator_suspended_total", "OrchestratorSuspended", "tot")
+	census.mQualityScore = stats.Float64("orchestrator_quality_score", "Quality score of renditions against their source", "score")
+	census.mPixelsOverReported = stats.Int64("pixels_overreported_total", "Pixels reported by orchestrators in excess of the decoded pixels", "tot")
+
+	// Metrics for the protection of public endpoints
+	census.mRequestRejected = stats.Int64("requests_rejected_total", "RequestRejected", "tot")
+
 	glog.Infof("Compiler: %s Arch %s OS %s Go version %s", runtime.Compiler, runtime.GOARCH, runtime.GOOS, runtime.Version())
 	glog.Infof("Livepeer version: %s", version)
 	glog.Infof("Node type %s node ID %s", nodeType, nodeID)
@@ -525,6 +577,20 @@ func InitCensus(nodeType, nodeID, version string) {
 			TagKeys:     append([]tag.Key{census.kSender}, baseTags...),
 			Aggregation: view.Sum(),
 		},
+		{
+			Name:        "ticket_redemptions_failed",
+			Measure:     census.mTicketRedemptionFailed,
+			Description: "Winning tickets given up on after failing the max redemption attempts",
+			TagKeys:     append([]tag.Key{census.kSender}, baseTags...),
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        "ticket_redemptions_submitted",
+			Measure:     census.mRedemptionSubmitted,
+			Description: "Transactions sent to redeem winning tickets",
+			TagKeys:     append([]tag.Key{census.kSender}, baseTags...),
+			Aggregation: view.Sum(),
+		},
 		{
 			Name:        "suggested_gas_price",
 			Measure:     census.mSuggestedGasPrice,
@@ -539,9 +605,104 @@ func InitCensus(nodeType, nodeID, version string) {
 			TagKeys:     append([]tag.Key{census.kSender}, baseTags...),
 			Aggregation: view.LastValue(),
 		},
+
+		// Metrics for per-sender analytics
+		{
+			Name:        "sender_pixels_transcoded",
+			Measure:     census.mSenderPixelsTranscoded,
+			Description: "Pixels transcoded for a sender",
+			TagKeys:     append([]tag.Key{census.kSender}, baseTags...),
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        "sender_ticket_value_recv",
+			Measure:     census.mSenderTicketValueRecv,
+			Description: "Ticket value accepted from a sender",
+			TagKeys:     append([]tag.Key{census.kSender}, baseTags...),
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        "sender_pending_float",
+			Measure:     census.mSenderPendingFloat,
+			Description: "Face value of the winning tickets of a sender that are not redeemed yet",
+			TagKeys:     append([]tag.Key{census.kSender}, baseTags...),
+			Aggregation: view.LastValue(),
+		},
+
+		// Metrics for the self-test canary
+		{
+			Name:        "canary_succeeded_total",
+			Measure:     census.mCanarySucceeded,
+			Description: "Self-test canary runs that succeeded",
+			TagKeys:     baseTags,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        "canary_failed_total",
+			Measure:     census.mCanaryFailed,
+			Description: "Self-test canary runs that failed, by failed stage",
+			TagKeys:     append([]tag.Key{census.kErrorCode}, baseTags...),
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        "canary_latency_seconds",
+			Measure:     census.mCanaryLatency,
+			Description: "Self-test canary latency, from segment push till playback check, seconds",
+			TagKeys:     baseTags,
+			Aggregation: view.Distribution(0, .500, .75, 1.000, 1.500, 2.000, 2.500, 3.000, 3.500, 4.000, 4.500, 5.000, 10.000),
+		},
+
+		// Metrics for the reward service
+		{
+			Name:        "reward_calls_total",
+			Measure:     census.mRewardCalls,
+			Description: "Rounds in which the reward service called reward",
+			TagKeys:     baseTags,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        "reward_calls_missed_total",
+			Measure:     census.mRewardCallsMissed,
+			Description: "Rounds in which the orchestrator was active but the reward service did not call reward",
+			TagKeys:     baseTags,
+			Aggregation: view.Count(),
+		},
+
+		// Metrics for verification
+		{
+			Name:        "orchestrator_suspended_total",
+			Measure:     census.mOrchestratorSuspended,
+			Description: "Orchestrators suspended for repeatedly failing verification or low quality scores, by orchestrator and reason",
+			TagKeys:     append([]tag.Key{census.kOrchestratorURI, census.kErrorCode}, baseTags...),
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        "orchestrator_quality_score",
+			Measure:     census.mQualityScore,
+			Description: "Last quality score (SSIM or VMAF) of a rendition against its source, by orchestrator and profile",
+			TagKeys:     append([]tag.Key{census.kOrchestratorURI, census.kProfile}, baseTags...),
+			Aggregation: view.LastValue(),
+		},
+		{
+			Name:        "pixels_overreported_total",
+			Measure:     census.mPixelsOverReported,
+			Description: "Pixels reported by orchestrators in excess of the pixels counted by decoding the renditions",
+			TagKeys:     append([]tag.Key{census.kOrchestratorURI}, baseTags...),
+			Aggregation: view.Sum(),
+		},
+
+		// Metrics for the protection of public endpoints
+		{
+			Name:        "requests_rejected_total",
+			Measure:     census.mRequestRejected,
+			Description: "Requests to the public endpoints of the orchestrator rejected by rate, concurrency or size limits, by reason",
+			TagKeys:     append([]tag.Key{census.kErrorCode}, baseTags...),
+			Aggregation: view.Count(),
+		},
 	}
 
-	// Register the views
+	// Register the views of the metric groups that are collected
+	views = metricsConfig.filterViews(views)
 	if err := view.Register(views...); err != nil {
 		glog.Fatalf("Failed to register views: %v", err)
 	}
@@ -558,7 +719,7 @@ func InitCensus(nodeType, nodeID, version string) {
 
 	// Register the Prometheus exporters as a stats exporter.
 	view.RegisterExporter(pe)
-	stats.Record(ctx, mVersions.M(1))
+	record(ctx, mVersions.M(1))
 	ctx, err = tag.New(census.ctx, tag.Insert(census.kErrorCode, "LostSegment"))
 	if err != nil {
 		glog.Fatal("Error creating context", err)
@@ -585,7 +746,7 @@ func LogDiscoveryError(code string) {
 		glog.Error("Error creating context", err)
 		return
 	}
-	stats.Record(ctx, census.mDiscoveryError.M(1))
+	record(ctx, census.mDiscoveryError.M(1))
 }
 
 func (cen *censusMetricsCounter) successRate() float64 {
@@ -710,11 +871,11 @@ func (cen *censusMetricsCounter) timeoutWatcher(ctx context.Context) {
 			for seqNo, tm := range emerged {
 				ago := now.Sub(tm)
 				if ago > timeToWaitForError {
-					stats.Record(cen.ctx, cen.mSegmentEmerged.M(1))
+					record(cen.ctx, cen.mSegmentEmerged.M(1))
 					delete(emerged, seqNo)
 					// This shouldn't happen, but if it is, we record
 					// `LostSegment` error, to try to find out why we missed segment
-					stats.Record(ctx, cen.mSegmentTranscodeFailed.M(1))
+					record(ctx, cen.mSegmentTranscodeFailed.M(1))
 					glog.Errorf("LostSegment nonce=%d seqNo=%d emerged=%ss ago", nonce, seqNo, ago)
 				}
 			}
@@ -741,13 +902,13 @@ func (cen *censusMetricsCounter) timeoutWatcher(ctx context.Context) {
 func MaxSessions(maxSessions int) {
 	census.lock.Lock()
 	defer census.lock.Unlock()
-	stats.Record(census.ctx, census.mMaxSessions.M(int64(maxSessions)))
+	record(census.ctx, census.mMaxSessions.M(int64(maxSessions)))
 }
 
 func CurrentSessions(currentSessions int) {
 	census.lock.Lock()
 	defer census.lock.Unlock()
-	stats.Record(census.ctx, census.mCurrentSessions.M(int64(currentSessions)))
+	record(census.ctx, census.mCurrentSessions.M(int64(currentSessions)))
 }
 
 func TranscodeTry(nonce, seqNo uint64) {
@@ -771,7 +932,7 @@ func TranscodeTry(nonce, seqNo uint64) {
 				glog.Error("Error creating context", err)
 				return
 			}
-			stats.Record(ctx, census.mTranscodeRetried.M(1))
+			record(ctx, census.mTranscodeRetried.M(1))
 		} else {
 			av.tries[seqNo] = tryData{tries: 1, first: time.Now()}
 		}
@@ -782,9 +943,9 @@ func TranscodeTry(nonce, seqNo uint64) {
 func SetTranscodersNumberAndLoad(load, capacity, number int) {
 	census.lock.Lock()
 	defer census.lock.Unlock()
-	stats.Record(census.ctx, census.mTranscodersLoad.M(int64(load)))
-	stats.Record(census.ctx, census.mTranscodersCapacity.M(int64(capacity)))
-	stats.Record(census.ctx, census.mTranscodersNumber.M(int64(number)))
+	record(census.ctx, census.mTranscodersLoad.M(int64(load)))
+	record(census.ctx, census.mTranscodersCapacity.M(int64(capacity)))
+	record(census.ctx, census.mTranscodersNumber.M(int64(number)))
 }
 
 func SegmentEmerged(nonce, seqNo uint64, profilesNum int) {
@@ -802,7 +963,7 @@ func (cen *censusMetricsCounter) segmentEmerged(nonce, seqNo uint64, profilesNum
 		avg.addEmerged(seqNo)
 	}
 	cen.emergeTimes[nonce][seqNo] = time.Now()
-	stats.Record(cen.ctx, cen.mSegmentEmergedUnprocessed.M(1))
+	record(cen.ctx, cen.mSegmentEmergedUnprocessed.M(1))
 }
 
 func SourceSegmentAppeared(nonce, seqNo uint64, manifestID, profile string) {
@@ -819,7 +980,7 @@ func (cen *censusMetricsCounter) segmentSourceAppeared(nonce, seqNo uint64, prof
 		glog.Error("Error creating context", err)
 		return
 	}
-	stats.Record(ctx, cen.mSegmentSourceAppeared.M(1))
+	record(ctx, cen.mSegmentSourceAppeared.M(1))
 }
 
 func SegmentUploaded(nonce, seqNo uint64, uploadDur time.Duration) {
@@ -828,7 +989,7 @@ func SegmentUploaded(nonce, seqNo uint64, uploadDur time.Duration) {
 }
 
 func (cen *censusMetricsCounter) segmentUploaded(nonce, seqNo uint64, uploadDur time.Duration) {
-	stats.Record(cen.ctx, cen.mSegmentUploaded.M(1), cen.mUploadTime.M(float64(uploadDur/time.Second)))
+	record(cen.ctx, cen.mSegmentUploaded.M(1), cen.mUploadTime.M(float64(uploadDur/time.Second)))
 }
 
 func AuthWebhookFinished(dur time.Duration) {
@@ -836,7 +997,7 @@ func AuthWebhookFinished(dur time.Duration) {
 }
 
 func (cen *censusMetricsCounter) authWebhookFinished(dur time.Duration) {
-	stats.Record(cen.ctx, cen.mAuthWebhookTime.M(float64(dur)/float64(time.Millisecond)))
+	record(cen.ctx, cen.mAuthWebhookTime.M(float64(dur)/float64(time.Millisecond)))
 }
 
 func SegmentUploadFailed(nonce, seqNo uint64, code SegmentUploadError, reason string, permanent bool) {
@@ -864,7 +1025,7 @@ func (cen *censusMetricsCounter) segmentUploadFailed(nonce, seqNo uint64, code S
 		glog.Error("Error creating context", err)
 		return
 	}
-	stats.Record(ctx, cen.mSegmentUploadFailed.M(1))
+	record(ctx, cen.mSegmentUploadFailed.M(1))
 	if permanent {
 		cen.countSegmentTranscoded(nonce, seqNo, true)
 		cen.sendSuccess()
@@ -885,7 +1046,7 @@ func (cen *censusMetricsCounter) segmentTranscoded(nonce, seqNo uint64, transcod
 		glog.Error("Error creating context", err)
 		return
 	}
-	stats.Record(ctx, cen.mSegmentTranscoded.M(1), cen.mTranscodeTime.M(float64(transcodeDur/time.Second)))
+	record(ctx, cen.mSegmentTranscoded.M(1), cen.mTranscodeTime.M(float64(transcodeDur/time.Second)))
 }
 
 func SegmentTranscodeFailed(subType SegmentTranscodeError, nonce, seqNo uint64, err error, permanent bool) {
@@ -901,7 +1062,7 @@ func (cen *censusMetricsCounter) segmentTranscodeFailed(nonce, seqNo uint64, cod
 		glog.Error("Error creating context", err)
 		return
 	}
-	stats.Record(ctx, cen.mSegmentTranscodeFailed.M(1))
+	record(ctx, cen.mSegmentTranscodeFailed.M(1))
 	if permanent {
 		cen.countSegmentEmerged(nonce, seqNo)
 		cen.countSegmentTranscoded(nonce, seqNo, code != SegmentTranscodeErrorSessionEnded)
@@ -917,13 +1078,13 @@ func (cen *censusMetricsCounter) countSegmentTranscoded(nonce, seqNo uint64, fai
 
 func (cen *censusMetricsCounter) countSegmentEmerged(nonce, seqNo uint64) {
 	if _, ok := cen.emergeTimes[nonce][seqNo]; ok {
-		stats.Record(cen.ctx, cen.mSegmentEmerged.M(1))
+		record(cen.ctx, cen.mSegmentEmerged.M(1))
 		delete(cen.emergeTimes[nonce], seqNo)
 	}
 }
 
 func (cen *censusMetricsCounter) sendSuccess() {
-	stats.Record(cen.ctx, cen.mSuccessRate.M(cen.successRate()))
+	record(cen.ctx, cen.mSuccessRate.M(cen.successRate()))
 }
 
 func SegmentFullyTranscoded(nonce, seqNo uint64, profiles string, errCode SegmentTranscodeError) {
@@ -938,17 +1099,17 @@ func SegmentFullyTranscoded(nonce, seqNo uint64, profiles string, errCode Segmen
 	if st, ok := census.emergeTimes[nonce][seqNo]; ok {
 		if errCode == "" {
 			latency := time.Since(st)
-			stats.Record(ctx, census.mTranscodeOverallLatency.M(float64(latency/time.Second)))
+			record(ctx, census.mTranscodeOverallLatency.M(float64(latency/time.Second)))
 		}
 		census.countSegmentEmerged(nonce, seqNo)
 	}
 	if errCode == "" {
-		stats.Record(ctx, census.mSegmentTranscodedAllAppeared.M(1))
+		record(ctx, census.mSegmentTranscodedAllAppeared.M(1))
 	}
 	failed := errCode != "" && errCode != SegmentTranscodeErrorSessionEnded
 	census.countSegmentTranscoded(nonce, seqNo, failed)
 	if !failed {
-		stats.Record(ctx, census.mSegmentTranscodedUnprocessed.M(1))
+		record(ctx, census.mSegmentTranscodedUnprocessed.M(1))
 	}
 	census.sendSuccess()
 }
@@ -971,10 +1132,10 @@ func (cen *censusMetricsCounter) segmentTranscodedAppeared(nonce, seqNo uint64,
 	if st, ok := cen.emergeTimes[nonce][seqNo]; ok {
 		latency := time.Since(st)
 		glog.V(logLevel).Infof("Recording latency for segment nonce=%d seqNo=%d profile=%s latency=%s", nonce, seqNo, profile, latency)
-		stats.Record(ctx, cen.mTranscodeLatency.M(float64(latency/time.Second)))
+		record(ctx, cen.mTranscodeLatency.M(float64(latency/time.Second)))
 	}
 
-	stats.Record(ctx, cen.mSegmentTranscodedAppeared.M(1))
+	record(ctx, cen.mSegmentTranscodedAppeared.M(1))
 }
 
 func StreamCreateFailed(nonce uint64, reason string) {
@@ -985,7 +1146,7 @@ func StreamCreateFailed(nonce uint64, reason string) {
 func (cen *censusMetricsCounter) streamCreateFailed(nonce uint64, reason string) {
 	cen.lock.Lock()
 	defer cen.lock.Unlock()
-	stats.Record(cen.ctx, cen.mStreamCreateFailed.M(1))
+	record(cen.ctx, cen.mStreamCreateFailed.M(1))
 }
 
 func newAverager() *segmentsAverager {
@@ -1003,7 +1164,7 @@ func StreamCreated(hlsStrmID string, nonce uint64) {
 func (cen *censusMetricsCounter) streamCreated(nonce uint64) {
 	cen.lock.Lock()
 	defer cen.lock.Unlock()
-	stats.Record(cen.ctx, cen.mStreamCreated.M(1))
+	record(cen.ctx, cen.mStreamCreated.M(1))
 	cen.success[nonce] = newAverager()
 }
 
@@ -1015,7 +1176,7 @@ func StreamStarted(nonce uint64) {
 func (cen *censusMetricsCounter) streamStarted(nonce uint64) {
 	cen.lock.Lock()
 	defer cen.lock.Unlock()
-	stats.Record(cen.ctx, cen.mStreamStarted.M(1))
+	record(cen.ctx, cen.mStreamStarted.M(1))
 }
 
 func StreamEnded(nonce uint64) {
@@ -1026,7 +1187,7 @@ func StreamEnded(nonce uint64) {
 func (cen *censusMetricsCounter) streamEnded(nonce uint64) {
 	cen.lock.Lock()
 	defer cen.lock.Unlock()
-	stats.Record(cen.ctx, cen.mStreamEnded.M(1))
+	record(cen.ctx, cen.mStreamEnded.M(1))
 	delete(cen.emergeTimes, nonce)
 	if avg, has := cen.success[nonce]; has {
 		if avg.canBeRemoved() {
@@ -1053,7 +1214,7 @@ func TicketValueSent(recipient string, manifestID string, value *big.Rat) {
 		glog.Fatal(err)
 	}
 
-	stats.Record(ctx, census.mTicketValueSent.M(fracwei2gwei(value)))
+	record(ctx, census.mTicketValueSent.M(fracwei2gwei(value)))
 }
 
 // TicketsSent records the number of tickets sent to a recipient for a manifestID
@@ -1070,7 +1231,7 @@ func TicketsSent(recipient string, manifestID string, numTickets int) {
 		glog.Fatal(err)
 	}
 
-	stats.Record(ctx, census.mTicketsSent.M(int64(numTickets)))
+	record(ctx, census.mTicketsSent.M(int64(numTickets)))
 }
 
 // PaymentCreateError records a error from payment creation
@@ -1083,16 +1244,16 @@ func PaymentCreateError(recipient string, manifestID string) {
 		glog.Fatal(err)
 	}
 
-	stats.Record(ctx, census.mPaymentCreateError.M(1))
+	record(ctx, census.mPaymentCreateError.M(1))
 }
 
 // Deposit records the current deposit for the broadcaster
 func Deposit(sender string, deposit *big.Int) {
-	stats.Record(census.ctx, census.mDeposit.M(wei2gwei(deposit)))
+	record(census.ctx, census.mDeposit.M(wei2gwei(deposit)))
 }
 
 func Reserve(sender string, reserve *big.Int) {
-	stats.Record(census.ctx, census.mReserve.M(wei2gwei(reserve)))
+	record(census.ctx, census.mReserve.M(wei2gwei(reserve)))
 }
 
 // TicketValueRecv records the ticket value received from a sender for a manifestID
@@ -1109,7 +1270,7 @@ func TicketValueRecv(sender string, manifestID string, value *big.Rat) {
 		glog.Fatal(err)
 	}
 
-	stats.Record(ctx, census.mTicketValueRecv.M(fracwei2gwei(value)))
+	record(ctx, census.mTicketValueRecv.M(fracwei2gwei(value)))
 }
 
 // TicketsRecv records the number of tickets received from a sender for a manifestID
@@ -1126,7 +1287,7 @@ func TicketsRecv(sender string, manifestID string, numTickets int) {
 		glog.Fatal(err)
 	}
 
-	stats.Record(ctx, census.mTicketsRecv.M(int64(numTickets)))
+	record(ctx, census.mTicketsRecv.M(int64(numTickets)))
 }
 
 // PaymentRecvError records an error from receiving a payment
@@ -1157,7 +1318,7 @@ func PaymentRecvError(sender string, manifestID string, errStr string) {
 		glog.Fatal(err)
 	}
 
-	stats.Record(ctx, census.mPaymentRecvErr.M(1))
+	record(ctx, census.mPaymentRecvErr.M(1))
 }
 
 // WinningTicketsRecv records the number of winning tickets received from a sender
@@ -1174,7 +1335,7 @@ func WinningTicketsRecv(sender string, numTickets int) {
 		glog.Fatal(err)
 	}
 
-	stats.Record(ctx, census.mWinningTicketsRecv.M(int64(numTickets)))
+	record(ctx, census.mWinningTicketsRecv.M(int64(numTickets)))
 }
 
 // ValueRedeemed records the value from redeeming winning tickets from a sender
@@ -1191,7 +1352,7 @@ func ValueRedeemed(sender string, value *big.Int) {
 		glog.Fatal(err)
 	}
 
-	stats.Record(ctx, census.mValueRedeemed.M(wei2gwei(value)))
+	record(ctx, census.mValueRedeemed.M(wei2gwei(value)))
 }
 
 // TicketRedemptionError records an error from redeeming a ticket
@@ -1204,7 +1365,34 @@ func TicketRedemptionError(sender string) {
 		glog.Fatal(err)
 	}
 
-	stats.Record(ctx, census.mTicketRedemptionError.M(1))
+	record(ctx, census.mTicketRedemptionError.M(1))
+}
+
+// TicketRedemptionFailed records a winning ticket that is given up on after failing the max
+// redemption attempts
+func TicketRedemptionFailed(sender string) {
+	census.lock.Lock()
+	defer census.lock.Unlock()
+
+	ctx, err := tag.New(census.ctx, tag.Insert(census.kSender, sender))
+	if err != nil {
+		glog.Fatal(err)
+	}
+
+	record(ctx, census.mTicketRedemptionFailed.M(1))
+}
+
+// RedemptionSubmitted records a transaction sent to redeem winning tickets of a sender
+func RedemptionSubmitted(sender string) {
+	census.lock.Lock()
+	defer census.lock.Unlock()
+
+	ctx, err := tag.New(census.ctx, tag.Insert(census.kSender, sender))
+	if err != nil {
+		glog.Fatal(err)
+	}
+
+	record(ctx, census.mRedemptionSubmitted.M(1))
 }
 
 // SuggestedGasPrice records the last suggested gas price
@@ -1212,7 +1400,7 @@ func SuggestedGasPrice(gasPrice *big.Int) {
 	census.lock.Lock()
 	defer census.lock.Unlock()
 
-	stats.Record(census.ctx, census.mSuggestedGasPrice.M(wei2gwei(gasPrice)))
+	record(census.ctx, census.mSuggestedGasPrice.M(wei2gwei(gasPrice)))
 }
 
 // TranscodingPrice records the last transcoding price
@@ -1222,8 +1410,56 @@ func TranscodingPrice(sender string, price *big.Rat) {
 
 	floatWei, ok := price.Float64()
 	if ok {
-		stats.Record(census.ctx, census.mTranscodingPrice.M(floatWei))
+		record(census.ctx, census.mTranscodingPrice.M(floatWei))
+	}
+}
+
+// SenderPixelsTranscoded records the number of pixels transcoded for a sender
+func SenderPixelsTranscoded(sender string, pixels int64) {
+	census.lock.Lock()
+	defer census.lock.Unlock()
+
+	if pixels <= 0 {
+		return
+	}
+
+	ctx, err := tag.New(census.ctx, tag.Insert(census.kSender, sender))
+	if err != nil {
+		glog.Fatal(err)
+	}
+
+	record(ctx, census.mSenderPixelsTranscoded.M(pixels))
+}
+
+// SenderTicketValueRecv records the EV of a ticket accepted from a sender
+func SenderTicketValueRecv(sender string, value *big.Rat) {
+	census.lock.Lock()
+	defer census.lock.Unlock()
+
+	if value.Cmp(big.NewRat(0, 1)) <= 0 {
+		return
+	}
+
+	ctx, err := tag.New(census.ctx, tag.Insert(census.kSender, sender))
+	if err != nil {
+		glog.Fatal(err)
+	}
+
+	record(ctx, census.mSenderTicketValueRecv.M(fracwei2gwei(value)))
+}
+
+// SenderPendingFloat records the face value of the winning tickets of a sender that are not
+// redeemed yet
+func SenderPendingFloat(sender string, pending *big.Int) {
+	census.lock.Lock()
+	defer census.lock.Unlock()
+
+	ctx, err := tag.New(census.ctx, tag.Insert(census.kSender, sender))
+	if err != nil {
+		glog.Fatal(err)
 	}
+
+	record(ctx, census.mSenderPendingFloat.M(wei2gwei(pending)))
 }
 
 // Convert wei to gwei
@@ -1237,3 +1473,70 @@ func fracwei2gwei(wei *big.Rat) float64 {
 	floatWei, _ := wei.Float64()
 	return floatWei / gweiConversionFactor
 }
+
+// CanarySucceeded records a successful run of the self-test canary
+func CanarySucceeded(latency time.Duration) {
+	record(census.ctx, census.mCanarySucceeded.M(1), census.mCanaryLatency.M(latency.Seconds()))
+}
+
+// CanaryFailed records a failed run of the self-test canary along with the stage that failed
+func CanaryFailed(stage string) {
+	ctx, err := tag.New(census.ctx, tag.Insert(census.kErrorCode, stage))
+	if err != nil {
+		glog.Error("Error creating context", err)
+		return
+	}
+	record(ctx, census.mCanaryFailed.M(1))
+}
+
+// RewardCalled records a round in which the reward service called reward
+func RewardCalled() {
+	record(census.ctx, census.mRewardCalls.M(1))
+}
+
+// RewardCallMissed records a round in which the reward service did not call reward while the
+// orchestrator was active
+func RewardCallMissed() {
+	record(census.ctx, census.mRewardCallsMissed.M(1))
+}
+
+// OrchestratorSuspended records the suspension of an orchestrator that repeatedly failed
+// verification, with the code of the reason of the suspension
+func OrchestratorSuspended(orch, code string) {
+	ctx, err := tag.New(census.ctx, tag.Insert(census.kOrchestratorURI, orch), tag.Insert(census.kErrorCode, code))
+	if err != nil {
+		glog.Error("Error creating context", err)
+		return
+	}
+	record(ctx, census.mOrchestratorSuspended.M(1))
+}
+
+// RequestRejected records a request to a public endpoint of the orchestrator rejected by a limit
+func RequestRejected(reason string) {
+	ctx, err := tag.New(census.ctx, tag.Insert(census.kErrorCode, reason))
+	if err != nil {
+		glog.Error("Error creating context", err)
+		return
+	}
+	record(ctx, census.mRequestRejected.M(1))
+}
+
+// QualityScore records the quality score of a rendition transcoded by an orchestrator
+func QualityScore(orch, profile string, score float64) {
+	ctx, err := tag.New(census.ctx, tag.Insert(census.kOrchestratorURI, orch), tag.Insert(census.kProfile, profile))
+	if err != nil {
+		glog.Error("Error creating context", err)
+		return
+	}
+	record(ctx, census.mQualityScore.M(score))
+}
+
+// PixelsOverReported records the pixels that an orchestrator reported in excess of the decoded pixels
+func PixelsOverReported(orch string, pixels int64) {
+	ctx, err := tag.New(census.ctx, tag.Insert(census.kOrchestratorURI, orch))
+	if err != nil {
+		glog.Error("Error creating context", err)
+		return
+	}
+	record(ctx, census.mPixelsOverReported.M(pixels))
+}
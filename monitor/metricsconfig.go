@@ -0,0 +1,214 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+)
+
+// Groups of metrics that can be enabled and sampled independently
+const (
+	MetricGroupStream      = "stream"
+	MetricGroupSegment     = "segment"
+	MetricGroupSessions    = "sessions"
+	MetricGroupTranscoders = "transcoders"
+	MetricGroupPayment     = "payment"
+	MetricGroupSender      = "sender"
+	MetricGroupCanary      = "canary"
+	MetricGroupReward      = "reward"
+)
+
+// metricGroups maps measure names to their group. Measures that are not
+// listed, e.g. versions, are always collected
+var metricGroups = map[string]string{
+	"broadcast_client_start_failed_total": MetricGroupStream,
+	"stream_created_total":                MetricGroupStream,
+	"stream_started_total":                MetricGroupStream,
+	"stream_ended_total":                  MetricGroupStream,
+	"stream_create_failed_total":          MetricGroupStream,
+	"auth_webhook_time_milliseconds":      MetricGroupStream,
+
+	"segment_source_appeared_total":            MetricGroupSegment,
+	"segment_source_emerged_total":             MetricGroupSegment,
+	"segment_source_emerged_unprocessed_total": MetricGroupSegment,
+	"segment_source_uploaded_total":            MetricGroupSegment,
+	"segment_source_upload_failed_total":       MetricGroupSegment,
+	"segment_transcoded_total":                 MetricGroupSegment,
+	"segment_transcoded_unprocessed_total":     MetricGroupSegment,
+	"segment_transcode_failed_total":           MetricGroupSegment,
+	"segment_transcoded_appeared_total":        MetricGroupSegment,
+	"segment_transcoded_all_appeared_total":    MetricGroupSegment,
+	"success_rate":                             MetricGroupSegment,
+	"transcode_time_seconds":                   MetricGroupSegment,
+	"transcode_latency_seconds":                MetricGroupSegment,
+	"transcode_overall_latency_seconds":        MetricGroupSegment,
+	"upload_time_seconds":                      MetricGroupSegment,
+	"transcode_retried":                        MetricGroupSegment,
+
+	"max_sessions_total":     MetricGroupSessions,
+	"current_sessions_total": MetricGroupSessions,
+	"discovery_errors_total": MetricGroupSessions,
+
+	"orchestrator_suspended_total": MetricGroupSessions,
+	"orchestrator_quality_score":   MetricGroupSessions,
+	"requests_rejected_total":      MetricGroupSessions,
+
+	"transcoders_number":   MetricGroupTranscoders,
+	"transcoders_capacity": MetricGroupTranscoders,
+	"transcoders_load":     MetricGroupTranscoders,
+
+	"ticket_value_sent":            MetricGroupPayment,
+	"tickets_sent":                 MetricGroupPayment,
+	"payment_create_errors":        MetricGroupPayment,
+	"broadcaster_deposit":          MetricGroupPayment,
+	"broadcaster_reserve":          MetricGroupPayment,
+	"ticket_value_recv":            MetricGroupPayment,
+	"tickets_recv":                 MetricGroupPayment,
+	"payment_recv_errors":          MetricGroupPayment,
+	"winning_tickets_recv":         MetricGroupPayment,
+	"value_redeemed":               MetricGroupPayment,
+	"ticket_redemption_errors":     MetricGroupPayment,
+	"ticket_redemptions_failed":    MetricGroupPayment,
+	"ticket_redemptions_submitted": MetricGroupPayment,
+	"suggested_gas_price":          MetricGroupPayment,
+	"transcoding_price":            MetricGroupPayment,
+
+	"pixels_overreported_total": MetricGroupPayment,
+
+	"sender_pixels_transcoded": MetricGroupSender,
+	"sender_ticket_value_recv": MetricGroupSender,
+	"sender_pending_float":     MetricGroupSender,
+
+	"canary_succeeded_total": MetricGroupCanary,
+	"canary_failed_total":    MetricGroupCanary,
+	"canary_latency_seconds": MetricGroupCanary,
+
+	"reward_calls_total":        MetricGroupReward,
+	"reward_calls_missed_total": MetricGroupReward,
+}
+
+// MetricsConfig controls which groups of metrics are collected and at what rate
+type MetricsConfig struct {
+	// Groups that are collected. All groups are collected if nil
+	Groups map[string]bool
+	// SampleRates holds the fraction, between 0 and 1, of the measurements of a group
+	// that are recorded. Groups without a sample rate are always recorded
+	SampleRates map[string]float64
+}
+
+var metricsConfig *MetricsConfig
+
+// InitMetricsConfig sets the configuration used by InitCensus. It must be called before InitCensus
+func InitMetricsConfig(cfg *MetricsConfig) {
+	metricsConfig = cfg
+}
+
+// MetricGroups returns the names of all metric groups
+func MetricGroups() []string {
+	seen := make(map[string]bool)
+	var groups []string
+	for _, g := range metricGroups {
+		if !seen[g] {
+			seen[g] = true
+			groups = append(groups, g)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+func isMetricGroup(group string) bool {
+	for _, g := range metricGroups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMetricsConfig parses a comma separated list of groups, e.g. "stream,payment", and a comma
+// separated list of group sample rates, e.g. "segment=0.1". Empty strings select the defaults
+func ParseMetricsConfig(groups, sampleRates string) (*MetricsConfig, error) {
+	cfg := &MetricsConfig{}
+	if groups != "" {
+		cfg.Groups = make(map[string]bool)
+		for _, g := range strings.Split(groups, ",") {
+			g = strings.TrimSpace(g)
+			if !isMetricGroup(g) {
+				return nil, fmt.Errorf("unknown metric group %q, must be one of %s", g, strings.Join(MetricGroups(), ","))
+			}
+			cfg.Groups[g] = true
+		}
+	}
+	if sampleRates != "" {
+		cfg.SampleRates = make(map[string]float64)
+		for _, kv := range strings.Split(sampleRates, ",") {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("invalid metric sample rate %q, must be in the form group=rate", kv)
+			}
+			g := strings.TrimSpace(parts[0])
+			if !isMetricGroup(g) {
+				return nil, fmt.Errorf("unknown metric group %q, must be one of %s", g, strings.Join(MetricGroups(), ","))
+			}
+			rate, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+			if err != nil || rate < 0 || rate > 1 {
+				return nil, fmt.Errorf("invalid metric sample rate %q, must be between 0 and 1", parts[1])
+			}
+			cfg.SampleRates[g] = rate
+		}
+	}
+	return cfg, nil
+}
+
+// enabled returns whether the measure with the provided name should be collected
+func (cfg *MetricsConfig) enabled(measure string) bool {
+	if cfg == nil || cfg.Groups == nil {
+		return true
+	}
+	g, ok := metricGroups[measure]
+	return !ok || cfg.Groups[g]
+}
+
+// filterViews drops the views of the groups that are not collected
+func (cfg *MetricsConfig) filterViews(views []*view.View) []*view.View {
+	var res []*view.View
+	for _, v := range views {
+		if cfg.enabled(v.Measure.Name()) {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// sample drops measurements according to the sample rate of their group
+func (cfg *MetricsConfig) sample(ms []stats.Measurement) []stats.Measurement {
+	if cfg == nil || len(cfg.SampleRates) == 0 {
+		return ms
+	}
+	res := ms[:0:0]
+	for _, m := range ms {
+		rate, ok := cfg.SampleRates[metricGroups[m.Measure().Name()]]
+		if ok && rand.Float64() >= rate {
+			continue
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+// record records the measurements that are sampled. Measurements of groups that
+// are not collected have no views and are discarded by opencensus
+func record(ctx context.Context, ms ...stats.Measurement) {
+	ms = metricsConfig.sample(ms)
+	if len(ms) == 0 {
+		return
+	}
+	stats.Record(ctx, ms...)
+}
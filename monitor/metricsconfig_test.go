@@ -0,0 +1,115 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+)
+
+func TestParseMetricsConfig(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cfg, err := ParseMetricsConfig("", "")
+	require.Nil(err)
+	assert.Nil(cfg.Groups)
+	assert.Nil(cfg.SampleRates)
+
+	cfg, err = ParseMetricsConfig("stream, payment", "segment=0.1,canary=1")
+	require.Nil(err)
+	assert.Equal(map[string]bool{MetricGroupStream: true, MetricGroupPayment: true}, cfg.Groups)
+	assert.Equal(map[string]float64{MetricGroupSegment: 0.1, MetricGroupCanary: 1}, cfg.SampleRates)
+
+	_, err = ParseMetricsConfig("foo", "")
+	assert.Contains(err.Error(), `unknown metric group "foo"`)
+	_, err = ParseMetricsConfig("", "foo=0.5")
+	assert.Contains(err.Error(), `unknown metric group "foo"`)
+	_, err = ParseMetricsConfig("", "segment")
+	assert.Contains(err.Error(), "must be in the form group=rate")
+	_, err = ParseMetricsConfig("", "segment=2")
+	assert.Contains(err.Error(), "must be between 0 and 1")
+	_, err = ParseMetricsConfig("", "segment=bar")
+	assert.Contains(err.Error(), "must be between 0 and 1")
+}
+
+func TestMetricGroups(t *testing.T) {
+	assert.Equal(t, []string{"canary", "payment", "reward", "segment", "sender", "sessions", "stream", "transcoders"}, MetricGroups())
+}
+
+func TestMetricsConfig_FilterViews(t *testing.T) {
+	assert := assert.New(t)
+
+	versions := &view.View{Measure: stats.Int64("versions", "", "")}
+	streams := &view.View{Measure: stats.Int64("stream_created_total", "", "")}
+	segments := &view.View{Measure: stats.Int64("segment_transcoded_total", "", "")}
+	rejected := &view.View{Measure: stats.Int64("requests_rejected_total", "", "")}
+	views := []*view.View{versions, streams, segments, rejected}
+
+	var cfg *MetricsConfig
+	assert.Equal(views, cfg.filterViews(views))
+	cfg = &MetricsConfig{}
+	assert.Equal(views, cfg.filterViews(views))
+
+	// Ungrouped metrics are always collected
+	cfg = &MetricsConfig{Groups: map[string]bool{MetricGroupStream: true}}
+	assert.Equal([]*view.View{versions, streams}, cfg.filterViews(views))
+	cfg = &MetricsConfig{Groups: map[string]bool{MetricGroupSessions: true}}
+	assert.Equal([]*view.View{versions, rejected}, cfg.filterViews(views))
+}
+
+func TestMetricsConfig_Sample(t *testing.T) {
+	assert := assert.New(t)
+
+	mStream := stats.Int64("stream_created_total", "", "")
+	mSegment := stats.Int64("segment_transcoded_total", "", "")
+	ms := []stats.Measurement{mStream.M(1), mSegment.M(1)}
+
+	var cfg *MetricsConfig
+	assert.Len(cfg.sample(ms), 2)
+
+	cfg = &MetricsConfig{SampleRates: map[string]float64{MetricGroupSegment: 0}}
+	res := cfg.sample(ms)
+	assert.Len(res, 1)
+	assert.Equal(mStream, res[0].Measure())
+	// The original measurements are left untouched
+	assert.Len(ms, 2)
+	assert.Equal(mSegment, ms[1].Measure())
+
+	cfg.SampleRates[MetricGroupSegment] = 1
+	assert.Len(cfg.sample(ms), 2)
+
+	cfg.SampleRates[MetricGroupSegment] = 0.5
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		sampled += len(cfg.sample([]stats.Measurement{mSegment.M(1)}))
+	}
+	assert.InDelta(500, sampled, 100)
+}
+
+func TestRecord_Sampled(t *testing.T) {
+	m := stats.Int64("test_record_sampled", "", "")
+	v := &view.View{Name: "test_record_sampled", Measure: m, Aggregation: view.Count()}
+	require.Nil(t, view.Register(v))
+	defer view.Unregister(v)
+
+	metricGroups[m.Name()] = MetricGroupSegment
+	defer delete(metricGroups, m.Name())
+	InitMetricsConfig(&MetricsConfig{SampleRates: map[string]float64{MetricGroupSegment: 0}})
+	defer InitMetricsConfig(nil)
+
+	record(context.Background(), m.M(1))
+	rows, err := view.RetrieveData(v.Name)
+	require.Nil(t, err)
+	assert.Empty(t, rows)
+
+	InitMetricsConfig(nil)
+	record(context.Background(), m.M(1))
+	rows, err = view.RetrieveData(v.Name)
+	require.Nil(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, int64(1), rows[0].Data.(*view.CountData).Value)
+}
@@ -0,0 +1,43 @@
+package monitor
+
+import "math/big"
+
+// PaymentMetrics records the payment events of the pm package, which it is notified of as
+// its pm.Metrics
+type PaymentMetrics struct{}
+
+// TicketReceived records the EV of a ticket accepted from a sender
+func (PaymentMetrics) TicketReceived(sender string, ev *big.Rat) {
+	SenderTicketValueRecv(sender, ev)
+}
+
+// TicketWon records a winning ticket received from a sender
+func (PaymentMetrics) TicketWon(sender string, faceValue *big.Int) {
+	WinningTicketsRecv(sender, 1)
+}
+
+// RedemptionSubmitted records a transaction sent to redeem winning tickets of a sender
+func (PaymentMetrics) RedemptionSubmitted(sender string, faceValue *big.Int) {
+	RedemptionSubmitted(sender)
+}
+
+// RedemptionSucceeded records the value of a winning ticket redeemed
+func (PaymentMetrics) RedemptionSucceeded(sender string, faceValue *big.Int) {
+	ValueRedeemed(sender, faceValue)
+}
+
+// RedemptionFailed records a failed attempt to redeem winning tickets
+func (PaymentMetrics) RedemptionFailed(sender string, err error) {
+	TicketRedemptionError(sender)
+}
+
+// RedemptionAbandoned records a winning ticket given up on
+func (PaymentMetrics) RedemptionAbandoned(sender string) {
+	TicketRedemptionFailed(sender)
+}
+
+// PendingFloat records the face value of the winning tickets of a sender that are not
+// redeemed yet
+func (PaymentMetrics) PendingFloat(sender string, pending *big.Int) {
+	SenderPendingFloat(sender, pending)
+}
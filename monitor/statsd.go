@@ -0,0 +1,193 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// Line formats supported by the StatsD exporter
+const (
+	StatsdFormatStatsd    = "statsd"
+	StatsdFormatDogstatsd = "dogstatsd"
+)
+
+// maxStatsdPacketSize keeps packets below the usual MTU to avoid fragmentation
+const maxStatsdPacketSize = 1432
+
+var statsdReplacer = strings.NewReplacer(":", "_", "|", "_", "@", "_", ",", "_", "#", "_", " ", "_", "\n", "_")
+
+// StatsdOptions configures a StatsdExporter
+type StatsdOptions struct {
+	// Addr is the UDP address of the StatsD server or agent, e.g. 127.0.0.1:8125
+	Addr string
+	// Prefix is prepended to the metric names
+	Prefix string
+	// Tags are attached to every metric, in the form key:value. Tags are only sent with the dogstatsd format
+	Tags []string
+	// Format is either StatsdFormatStatsd or StatsdFormatDogstatsd
+	Format string
+}
+
+// StatsdExporter exports opencensus views to a StatsD server or a Datadog agent.
+// Counts and sums are sent as counters of the change since the last export, last
+// values as gauges and distributions as count and sum counters with a mean gauge
+type StatsdExporter struct {
+	opts StatsdOptions
+	conn net.Conn
+
+	mu   sync.Mutex
+	last map[string]float64
+}
+
+// NewStatsdExporter creates a StatsdExporter sending metrics over UDP
+func NewStatsdExporter(opts StatsdOptions) (*StatsdExporter, error) {
+	switch opts.Format {
+	case "":
+		opts.Format = StatsdFormatDogstatsd
+	case StatsdFormatStatsd, StatsdFormatDogstatsd:
+	default:
+		return nil, fmt.Errorf("invalid StatsD format %q, must be %s or %s", opts.Format, StatsdFormatStatsd, StatsdFormatDogstatsd)
+	}
+	for _, t := range opts.Tags {
+		if !strings.Contains(t, ":") {
+			return nil, fmt.Errorf("invalid StatsD tag %q, must be in the form key:value", t)
+		}
+	}
+	conn, err := net.Dial("udp", opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	return &StatsdExporter{
+		opts: opts,
+		conn: conn,
+		last: make(map[string]float64),
+	}, nil
+}
+
+// InitStatsd registers the StatsD exporter with opencensus so that it receives all views
+func InitStatsd(e *StatsdExporter) {
+	view.RegisterExporter(e)
+}
+
+// ExportView implements view.Exporter
+func (e *StatsdExporter) ExportView(vd *view.Data) {
+	var lines []string
+	e.mu.Lock()
+	for _, row := range vd.Rows {
+		name := e.opts.Prefix + statsdReplacer.Replace(vd.View.Name)
+		tags := e.tags(row.Tags)
+		key := name + "|" + rowKey(row.Tags)
+
+		switch data := row.Data.(type) {
+		case *view.CountData:
+			lines = e.appendDelta(lines, name, tags, key, float64(data.Value))
+		case *view.SumData:
+			lines = e.appendDelta(lines, name, tags, key, data.Value)
+		case *view.LastValueData:
+			lines = append(lines, e.line(name, data.Value, "g", tags))
+		case *view.DistributionData:
+			count := e.delta(key+"|count", float64(data.Count))
+			sum := e.delta(key+"|sum", data.Mean*float64(data.Count))
+			if count > 0 {
+				lines = append(lines,
+					e.line(name+".count", count, "c", tags),
+					e.line(name+".sum", sum, "c", tags),
+					e.line(name+".mean", sum/count, "g", tags))
+			}
+		}
+	}
+	e.mu.Unlock()
+
+	if err := e.send(lines); err != nil {
+		glog.Errorf("Error sending metrics to StatsD addr=%s err=%v", e.opts.Addr, err)
+	}
+}
+
+// Caller of this function should hold the lock
+func (e *StatsdExporter) appendDelta(lines []string, name, tags, key string, value float64) []string {
+	if d := e.delta(key, value); d > 0 {
+		lines = append(lines, e.line(name, d, "c", tags))
+	}
+	return lines
+}
+
+// delta returns the change of a cumulative value since the last export.
+// Caller of this function should hold the lock
+func (e *StatsdExporter) delta(key string, value float64) float64 {
+	last, ok := e.last[key]
+	e.last[key] = value
+	// Cumulative values only decrease if the view was re-registered
+	if !ok || value < last {
+		return value
+	}
+	return value - last
+}
+
+// rowKey identifies the row of a view by its tag values, whether or not the tags are sent,
+// so that the cumulative values of the rows are tracked separately
+func rowKey(rowTags []tag.Tag) string {
+	var b strings.Builder
+	for _, t := range rowTags {
+		b.WriteString(t.Key.Name())
+		b.WriteByte(0)
+		b.WriteString(t.Value)
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+func (e *StatsdExporter) tags(rowTags []tag.Tag) string {
+	if e.opts.Format != StatsdFormatDogstatsd {
+		return ""
+	}
+	tags := make([]string, 0, len(e.opts.Tags)+len(rowTags))
+	tags = append(tags, e.opts.Tags...)
+	for _, t := range rowTags {
+		tags = append(tags, statsdReplacer.Replace(t.Key.Name())+":"+statsdReplacer.Replace(t.Value))
+	}
+	return strings.Join(tags, ",")
+}
+
+func (e *StatsdExporter) line(name string, value float64, typ, tags string) string {
+	l := name + ":" + strconv.FormatFloat(value, 'f', -1, 64) + "|" + typ
+	if tags != "" {
+		l += "|#" + tags
+	}
+	return l
+}
+
+// send writes the lines in as few packets as possible
+func (e *StatsdExporter) send(lines []string) error {
+	var buf bytes.Buffer
+	for _, l := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(l) > maxStatsdPacketSize {
+			if _, err := e.conn.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(l)
+	}
+	if buf.Len() > 0 {
+		if _, err := e.conn.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the connection to the StatsD server
+func (e *StatsdExporter) Close() error {
+	return e.conn.Close()
+}
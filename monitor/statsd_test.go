@@ -0,0 +1,144 @@
+package monitor
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+func newStatsdListener(t *testing.T) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.Nil(t, err)
+	return conn
+}
+
+func readStatsdLines(t *testing.T, conn *net.UDPConn) []string {
+	buf := make([]byte, 65536)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	require.Nil(t, err)
+	return strings.Split(string(buf[:n]), "\n")
+}
+
+func TestNewStatsdExporter_Errors(t *testing.T) {
+	_, err := NewStatsdExporter(StatsdOptions{Addr: "127.0.0.1:8125", Format: "foo"})
+	assert.Contains(t, err.Error(), `invalid StatsD format "foo"`)
+
+	_, err = NewStatsdExporter(StatsdOptions{Addr: "127.0.0.1:8125", Tags: []string{"foo"}})
+	assert.Contains(t, err.Error(), `invalid StatsD tag "foo"`)
+
+	_, err = NewStatsdExporter(StatsdOptions{Addr: "foo"})
+	assert.NotNil(t, err)
+}
+
+func TestStatsdExporter_ExportView(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	listener := newStatsdListener(t)
+	defer listener.Close()
+
+	e, err := NewStatsdExporter(StatsdOptions{
+		Addr:   listener.LocalAddr().String(),
+		Prefix: "livepeer.",
+		Tags:   []string{"env:test"},
+	})
+	require.Nil(err)
+	defer e.Close()
+	assert.Equal(StatsdFormatDogstatsd, e.opts.Format)
+
+	kNode := tag.MustNewKey("node_id")
+	count := &view.View{Name: "segments_total", Measure: stats.Int64("segments_total", "", ""), Aggregation: view.Count()}
+	gauge := &view.View{Name: "sessions", Measure: stats.Int64("sessions", "", ""), Aggregation: view.LastValue()}
+	dist := &view.View{Name: "latency_seconds", Measure: stats.Float64("latency_seconds", "", ""), Aggregation: view.Distribution(1, 2)}
+	tags := []tag.Tag{{Key: kNode, Value: "a,b"}}
+
+	e.ExportView(&view.Data{View: count, Rows: []*view.Row{{Tags: tags, Data: &view.CountData{Value: 3}}}})
+	assert.Equal([]string{"livepeer.segments_total:3|c|#env:test,node_id:a_b"}, readStatsdLines(t, listener))
+
+	// Counters are sent as the change since the last export
+	e.ExportView(&view.Data{View: count, Rows: []*view.Row{{Tags: tags, Data: &view.CountData{Value: 5}}}})
+	assert.Equal([]string{"livepeer.segments_total:2|c|#env:test,node_id:a_b"}, readStatsdLines(t, listener))
+
+	e.ExportView(&view.Data{View: gauge, Rows: []*view.Row{{Data: &view.LastValueData{Value: 7}}}})
+	assert.Equal([]string{"livepeer.sessions:7|g|#env:test"}, readStatsdLines(t, listener))
+
+	e.ExportView(&view.Data{View: dist, Rows: []*view.Row{{Data: &view.DistributionData{Count: 4, Mean: 1.5}}}})
+	assert.Equal([]string{
+		"livepeer.latency_seconds.count:4|c|#env:test",
+		"livepeer.latency_seconds.sum:6|c|#env:test",
+		"livepeer.latency_seconds.mean:1.5|g|#env:test",
+	}, readStatsdLines(t, listener))
+
+	e.ExportView(&view.Data{View: dist, Rows: []*view.Row{{Data: &view.DistributionData{Count: 6, Mean: 2}}}})
+	assert.Equal([]string{
+		"livepeer.latency_seconds.count:2|c|#env:test",
+		"livepeer.latency_seconds.sum:6|c|#env:test",
+		"livepeer.latency_seconds.mean:3|g|#env:test",
+	}, readStatsdLines(t, listener))
+}
+
+func TestStatsdExporter_StatsdFormat(t *testing.T) {
+	listener := newStatsdListener(t)
+	defer listener.Close()
+
+	e, err := NewStatsdExporter(StatsdOptions{
+		Addr:   listener.LocalAddr().String(),
+		Tags:   []string{"env:test"},
+		Format: StatsdFormatStatsd,
+	})
+	require.Nil(t, err)
+	defer e.Close()
+
+	v := &view.View{Name: "sessions", Measure: stats.Int64("sessions", "", ""), Aggregation: view.LastValue()}
+	tags := []tag.Tag{{Key: tag.MustNewKey("node_id"), Value: "a"}}
+	e.ExportView(&view.Data{View: v, Rows: []*view.Row{{Tags: tags, Data: &view.LastValueData{Value: 1}}}})
+
+	// Tags are not supported by plain StatsD
+	assert.Equal(t, []string{"sessions:1|g"}, readStatsdLines(t, listener))
+
+	// The changes of counters are tracked for each row
+	c := &view.View{Name: "segments_total", Measure: stats.Int64("segments_total", "", ""), Aggregation: view.Count()}
+	rows := func(a, b int64) []*view.Row {
+		return []*view.Row{
+			{Tags: []tag.Tag{{Key: tag.MustNewKey("node_id"), Value: "a"}}, Data: &view.CountData{Value: a}},
+			{Tags: []tag.Tag{{Key: tag.MustNewKey("node_id"), Value: "b"}}, Data: &view.CountData{Value: b}},
+		}
+	}
+	e.ExportView(&view.Data{View: c, Rows: rows(3, 10)})
+	assert.Equal(t, []string{"segments_total:3|c", "segments_total:10|c"}, readStatsdLines(t, listener))
+	e.ExportView(&view.Data{View: c, Rows: rows(4, 12)})
+	assert.Equal(t, []string{"segments_total:1|c", "segments_total:2|c"}, readStatsdLines(t, listener))
+}
+
+func TestStatsdExporter_Packets(t *testing.T) {
+	assert := assert.New(t)
+
+	listener := newStatsdListener(t)
+	defer listener.Close()
+
+	e, err := NewStatsdExporter(StatsdOptions{Addr: listener.LocalAddr().String()})
+	require.Nil(t, err)
+	defer e.Close()
+
+	var lines []string
+	for i := 0; i < 100; i++ {
+		lines = append(lines, strings.Repeat("a", 50)+":1|c")
+	}
+	require.Nil(t, e.send(lines))
+
+	received := 0
+	for received < len(lines) {
+		packet := readStatsdLines(t, listener)
+		assert.True(len(strings.Join(packet, "\n")) <= maxStatsdPacketSize)
+		received += len(packet)
+	}
+	assert.Equal(len(lines), received)
+}
@@ -9,6 +9,13 @@ type RemoteTranscoderInfo struct {
 	Capacity int
 }
 
+// TranscoderDeviceInfo is the load of a GPU of a transcoder
+type TranscoderDeviceInfo struct {
+	Device      string
+	Sessions    int
+	MaxSessions int
+}
+
 type NodeStatus struct {
 	Manifests                   map[string]*m3u8.MasterPlaylist
 	OrchestratorPool            []string
@@ -19,5 +26,9 @@ type NodeStatus struct {
 	RegisteredTranscodersNumber int
 	RegisteredTranscoders       []RemoteTranscoderInfo
 	LocalTranscoding            bool // Indicates orchestrator that is also transcoder
+	// TranscoderDevices are the GPUs that the node transcodes on, if any
+	TranscoderDevices []TranscoderDeviceInfo `json:",omitempty"`
+	// Capabilities are the names of the capabilities advertised by an orchestrator
+	Capabilities []string `json:",omitempty"`
 	// xxx add transcoder's version here
 }
@@ -58,16 +58,19 @@ type VideoProfile_Format int32
 const (
 	VideoProfile_MPEGTS VideoProfile_Format = 0
 	VideoProfile_MP4    VideoProfile_Format = 1
+	VideoProfile_WEBM   VideoProfile_Format = 2
 )
 
 var VideoProfile_Format_name = map[int32]string{
 	0: "MPEGTS",
 	1: "MP4",
+	2: "WEBM",
 }
 
 var VideoProfile_Format_value = map[string]int32{
 	"MPEGTS": 0,
 	"MP4":    1,
+	"WEBM":   2,
 }
 
 func (x VideoProfile_Format) String() string {
@@ -86,6 +89,11 @@ const (
 	VideoProfile_H264_MAIN             VideoProfile_Profile = 2
 	VideoProfile_H264_HIGH             VideoProfile_Profile = 3
 	VideoProfile_H264_CONSTRAINED_HIGH VideoProfile_Profile = 4
+	VideoProfile_H265_MAIN             VideoProfile_Profile = 5
+	VideoProfile_AV1_MAIN              VideoProfile_Profile = 6
+	VideoProfile_VP8                   VideoProfile_Profile = 7
+	VideoProfile_VP9                   VideoProfile_Profile = 8
+	VideoProfile_AUDIO_ONLY            VideoProfile_Profile = 9
 )
 
 var VideoProfile_Profile_name = map[int32]string{
@@ -94,6 +102,11 @@ var VideoProfile_Profile_name = map[int32]string{
 	2: "H264_MAIN",
 	3: "H264_HIGH",
 	4: "H264_CONSTRAINED_HIGH",
+	5: "H265_MAIN",
+	6: "AV1_MAIN",
+	7: "VP8",
+	8: "VP9",
+	9: "AUDIO_ONLY",
 }
 
 var VideoProfile_Profile_value = map[string]int32{
@@ -102,6 +115,11 @@ var VideoProfile_Profile_value = map[string]int32{
 	"H264_MAIN":             2,
 	"H264_HIGH":             3,
 	"H264_CONSTRAINED_HIGH": 4,
+	"H265_MAIN":             5,
+	"AV1_MAIN":              6,
+	"VP8":                   7,
+	"VP9":                   8,
+	"AUDIO_ONLY":            9,
 }
 
 func (x VideoProfile_Profile) String() string {
@@ -112,6 +130,41 @@ func (VideoProfile_Profile) EnumDescriptor() ([]byte, []int) {
 	return fileDescriptor_034e29c79f9ba827, []int{8, 1}
 }
 
+type PriceUpdate_Reason int32
+
+const (
+	// The price per pixel changed
+	PriceUpdate_PRICE PriceUpdate_Reason = 0
+	// The ticket face value changed, typically with the gas price
+	PriceUpdate_FACE_VALUE PriceUpdate_Reason = 1
+	// A new round started
+	PriceUpdate_ROUND PriceUpdate_Reason = 2
+	// The recipientRand of the ticket params is rotated
+	PriceUpdate_RECIPIENT_RAND PriceUpdate_Reason = 3
+)
+
+var PriceUpdate_Reason_name = map[int32]string{
+	0: "PRICE",
+	1: "FACE_VALUE",
+	2: "ROUND",
+	3: "RECIPIENT_RAND",
+}
+
+var PriceUpdate_Reason_value = map[string]int32{
+	"PRICE":          0,
+	"FACE_VALUE":     1,
+	"ROUND":          2,
+	"RECIPIENT_RAND": 3,
+}
+
+func (x PriceUpdate_Reason) String() string {
+	return proto.EnumName(PriceUpdate_Reason_name, int32(x))
+}
+
+func (PriceUpdate_Reason) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_034e29c79f9ba827, []int{21, 0}
+}
+
 type PingPong struct {
 	// Implementation defined
 	Value                []byte   `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
@@ -158,10 +211,12 @@ type OrchestratorRequest struct {
 	// Ethereum address of the broadcaster
 	Address []byte `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
 	// Broadcaster's signature over its address
-	Sig                  []byte   `protobuf:"bytes,2,opt,name=sig,proto3" json:"sig,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Sig []byte `protobuf:"bytes,2,opt,name=sig,proto3" json:"sig,omitempty"`
+	// Protocol versions and features supported by the broadcaster
+	Protocol             *ProtocolInfo `protobuf:"bytes,3,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
 }
 
 func (m *OrchestratorRequest) Reset()         { *m = OrchestratorRequest{} }
@@ -203,6 +258,13 @@ func (m *OrchestratorRequest) GetSig() []byte {
 	return nil
 }
 
+func (m *OrchestratorRequest) GetProtocol() *ProtocolInfo {
+	if m != nil {
+		return m.Protocol
+	}
+	return nil
+}
+
 //
 //OSInfo needed to negotiate storages that will be used.
 //It carries info needed to write to the storage.
@@ -484,6 +546,16 @@ type OrchestratorInfo struct {
 	Address []byte `protobuf:"bytes,4,opt,name=address,proto3" json:"address,omitempty"`
 	// Features and constraints supported by the orchestrator
 	Capabilities *Capabilities `protobuf:"bytes,5,opt,name=capabilities,proto3" json:"capabilities,omitempty"`
+	// Protocol version and features negotiated with the broadcaster
+	Protocol *ProtocolInfo `protobuf:"bytes,6,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	// Token authorizing the broadcaster to submit segments for the session
+	AuthToken *AuthToken `protobuf:"bytes,7,opt,name=auth_token,json=authToken,proto3" json:"auth_token,omitempty"`
+	// Additional URIs of the transcoder, e.g. per region or per provider, that
+	// broadcasters fail over to if the transcoder is unreachable
+	AltTranscoders []string `protobuf:"bytes,8,rep,name=alt_transcoders,json=altTranscoders,proto3" json:"alt_transcoders,omitempty"`
+	// Prices of the renditions that need capabilities which cost more to
+	// transcode, relative to the price_info of other renditions
+	CapabilityPrices []*CapabilityPrice `protobuf:"bytes,9,rep,name=capability_prices,json=capabilityPrices,proto3" json:"capability_prices,omitempty"`
 	// Orchestrator returns info about own input object storage, if it wants it to be used.
 	Storage              []*OSInfo `protobuf:"bytes,32,rep,name=storage,proto3" json:"storage,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
@@ -551,6 +623,34 @@ func (m *OrchestratorInfo) GetCapabilities() *Capabilities {
 	return nil
 }
 
+func (m *OrchestratorInfo) GetProtocol() *ProtocolInfo {
+	if m != nil {
+		return m.Protocol
+	}
+	return nil
+}
+
+func (m *OrchestratorInfo) GetAuthToken() *AuthToken {
+	if m != nil {
+		return m.AuthToken
+	}
+	return nil
+}
+
+func (m *OrchestratorInfo) GetAltTranscoders() []string {
+	if m != nil {
+		return m.AltTranscoders
+	}
+	return nil
+}
+
+func (m *OrchestratorInfo) GetCapabilityPrices() []*CapabilityPrice {
+	if m != nil {
+		return m.CapabilityPrices
+	}
+	return nil
+}
+
 func (m *OrchestratorInfo) GetStorage() []*OSInfo {
 	if m != nil {
 		return m.Storage
@@ -575,6 +675,17 @@ type SegData struct {
 	Duration int32 `protobuf:"varint,6,opt,name=duration,proto3" json:"duration,omitempty"`
 	// Capabilities used by this segment.
 	Capabilities *Capabilities `protobuf:"bytes,7,opt,name=capabilities,proto3" json:"capabilities,omitempty"`
+	// Protocol versions and features supported by the broadcaster
+	Protocol *ProtocolInfo `protobuf:"bytes,8,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	// Auth token issued by the orchestrator for the session
+	AuthToken *AuthToken `protobuf:"bytes,9,opt,name=auth_token,json=authToken,proto3" json:"auth_token,omitempty"`
+	// Start of the part of the segment that a clip keeps, in milliseconds from
+	// the start of the segment
+	ClipFrom int64 `protobuf:"varint,10,opt,name=clip_from,json=clipFrom,proto3" json:"clip_from,omitempty"`
+	// End of the part of the segment that a clip keeps, in milliseconds from the
+	// start of the segment. The segment is not clipped if both bounds are 0, and
+	// is clipped until its end if only clip_to is 0
+	ClipTo int64 `protobuf:"varint,11,opt,name=clip_to,json=clipTo,proto3" json:"clip_to,omitempty"`
 	// Broadcaster's preferred storage medium(s)
 	// XXX should we include this in a sig somewhere until certs are authenticated?
 	Storage []*OSInfo `protobuf:"bytes,32,rep,name=storage,proto3" json:"storage,omitempty"`
@@ -665,6 +776,34 @@ func (m *SegData) GetCapabilities() *Capabilities {
 	return nil
 }
 
+func (m *SegData) GetProtocol() *ProtocolInfo {
+	if m != nil {
+		return m.Protocol
+	}
+	return nil
+}
+
+func (m *SegData) GetAuthToken() *AuthToken {
+	if m != nil {
+		return m.AuthToken
+	}
+	return nil
+}
+
+func (m *SegData) GetClipFrom() int64 {
+	if m != nil {
+		return m.ClipFrom
+	}
+	return 0
+}
+
+func (m *SegData) GetClipTo() int64 {
+	if m != nil {
+		return m.ClipTo
+	}
+	return 0
+}
+
 func (m *SegData) GetStorage() []*OSInfo {
 	if m != nil {
 		return m.Storage
@@ -809,7 +948,12 @@ type TranscodedSegmentData struct {
 	// URL where the transcoded data can be downloaded from.
 	Url string `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
 	// Amount of pixels processed (output pixels)
-	Pixels               int64    `protobuf:"varint,2,opt,name=pixels,proto3" json:"pixels,omitempty"`
+	Pixels int64 `protobuf:"varint,2,opt,name=pixels,proto3" json:"pixels,omitempty"`
+	// Duration of the rendition, in milliseconds. Unset if unknown
+	Duration int32 `protobuf:"varint,3,opt,name=duration,proto3" json:"duration,omitempty"`
+	// Perceptual hash of the rendition: the 64 bit difference hash of its average
+	// frame, big-endian. Unset if the orchestrator doesn't hash renditions
+	PerceptualHash       []byte   `protobuf:"bytes,4,opt,name=perceptual_hash,json=perceptualHash,proto3" json:"perceptual_hash,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -854,15 +998,32 @@ func (m *TranscodedSegmentData) GetPixels() int64 {
 	return 0
 }
 
+func (m *TranscodedSegmentData) GetDuration() int32 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
+func (m *TranscodedSegmentData) GetPerceptualHash() []byte {
+	if m != nil {
+		return m.PerceptualHash
+	}
+	return nil
+}
+
 // A set of transcoded segments following the profiles specified in the job.
 type TranscodeData struct {
 	// Transcoded data, in the order specified in the job options
 	Segments []*TranscodedSegmentData `protobuf:"bytes,1,rep,name=segments,proto3" json:"segments,omitempty"`
 	// Signature of the hash of the concatenated hashes
-	Sig                  []byte   `protobuf:"bytes,2,opt,name=sig,proto3" json:"sig,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Sig []byte `protobuf:"bytes,2,opt,name=sig,proto3" json:"sig,omitempty"`
+	// Receipt for the segments transcoded for the stream since the previous receipt.
+	// Only set periodically
+	Receipt              *TranscodeReceipt `protobuf:"bytes,3,opt,name=receipt,proto3" json:"receipt,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
 func (m *TranscodeData) Reset()         { *m = TranscodeData{} }
@@ -904,6 +1065,98 @@ func (m *TranscodeData) GetSig() []byte {
 	return nil
 }
 
+func (m *TranscodeData) GetReceipt() *TranscodeReceipt {
+	if m != nil {
+		return m.Receipt
+	}
+	return nil
+}
+
+// Signed Merkle root over the hashes of the segments transcoded for a stream
+type TranscodeReceipt struct {
+	// Manifest ID of the stream
+	ManifestId string `protobuf:"bytes,1,opt,name=manifest_id,json=manifestId,proto3" json:"manifest_id,omitempty"`
+	// Lowest and highest sequence numbers of the segments covered by the receipt
+	StartSeq int64 `protobuf:"varint,2,opt,name=start_seq,json=startSeq,proto3" json:"start_seq,omitempty"`
+	EndSeq   int64 `protobuf:"varint,3,opt,name=end_seq,json=endSeq,proto3" json:"end_seq,omitempty"`
+	// Number of segments covered by the receipt
+	Segments int64 `protobuf:"varint,4,opt,name=segments,proto3" json:"segments,omitempty"`
+	// Merkle root of the segment hashes, sorted by sequence number
+	Root []byte `protobuf:"bytes,5,opt,name=root,proto3" json:"root,omitempty"`
+	// Signature of the orchestrator over the receipt
+	Sig                  []byte   `protobuf:"bytes,6,opt,name=sig,proto3" json:"sig,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TranscodeReceipt) Reset()         { *m = TranscodeReceipt{} }
+func (m *TranscodeReceipt) String() string { return proto.CompactTextString(m) }
+func (*TranscodeReceipt) ProtoMessage()    {}
+func (*TranscodeReceipt) Descriptor() ([]byte, []int) {
+	return fileDescriptor_034e29c79f9ba827, []int{11}
+}
+
+func (m *TranscodeReceipt) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TranscodeReceipt.Unmarshal(m, b)
+}
+func (m *TranscodeReceipt) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TranscodeReceipt.Marshal(b, m, deterministic)
+}
+func (m *TranscodeReceipt) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TranscodeReceipt.Merge(m, src)
+}
+func (m *TranscodeReceipt) XXX_Size() int {
+	return xxx_messageInfo_TranscodeReceipt.Size(m)
+}
+func (m *TranscodeReceipt) XXX_DiscardUnknown() {
+	xxx_messageInfo_TranscodeReceipt.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TranscodeReceipt proto.InternalMessageInfo
+
+func (m *TranscodeReceipt) GetManifestId() string {
+	if m != nil {
+		return m.ManifestId
+	}
+	return ""
+}
+
+func (m *TranscodeReceipt) GetStartSeq() int64 {
+	if m != nil {
+		return m.StartSeq
+	}
+	return 0
+}
+
+func (m *TranscodeReceipt) GetEndSeq() int64 {
+	if m != nil {
+		return m.EndSeq
+	}
+	return 0
+}
+
+func (m *TranscodeReceipt) GetSegments() int64 {
+	if m != nil {
+		return m.Segments
+	}
+	return 0
+}
+
+func (m *TranscodeReceipt) GetRoot() []byte {
+	if m != nil {
+		return m.Root
+	}
+	return nil
+}
+
+func (m *TranscodeReceipt) GetSig() []byte {
+	if m != nil {
+		return m.Sig
+	}
+	return nil
+}
+
 // Response that a transcoder sends after transcoding a segment.
 type TranscodeResult struct {
 	// Sequence number of the transcoded results.
@@ -915,17 +1168,20 @@ type TranscodeResult struct {
 	//	*TranscodeResult_Data
 	Result isTranscodeResult_Result `protobuf_oneof:"result"`
 	// Used to notify a broadcaster of updated orchestrator information
-	Info                 *OrchestratorInfo `protobuf:"bytes,16,opt,name=info,proto3" json:"info,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
-	XXX_unrecognized     []byte            `json:"-"`
-	XXX_sizecache        int32             `json:"-"`
+	Info *OrchestratorInfo `protobuf:"bytes,16,opt,name=info,proto3" json:"info,omitempty"`
+	// Set when the price or ticket params of the orchestrator changed since
+	// the payment sent with the segment
+	PriceUpdate          *PriceUpdate `protobuf:"bytes,17,opt,name=price_update,json=priceUpdate,proto3" json:"price_update,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
 }
 
 func (m *TranscodeResult) Reset()         { *m = TranscodeResult{} }
 func (m *TranscodeResult) String() string { return proto.CompactTextString(m) }
 func (*TranscodeResult) ProtoMessage()    {}
 func (*TranscodeResult) Descriptor() ([]byte, []int) {
-	return fileDescriptor_034e29c79f9ba827, []int{11}
+	return fileDescriptor_034e29c79f9ba827, []int{12}
 }
 
 func (m *TranscodeResult) XXX_Unmarshal(b []byte) error {
@@ -997,6 +1253,13 @@ func (m *TranscodeResult) GetInfo() *OrchestratorInfo {
 	return nil
 }
 
+func (m *TranscodeResult) GetPriceUpdate() *PriceUpdate {
+	if m != nil {
+		return m.PriceUpdate
+	}
+	return nil
+}
+
 // XXX_OneofWrappers is for the internal use of the proto package.
 func (*TranscodeResult) XXX_OneofWrappers() []interface{} {
 	return []interface{}{
@@ -1020,7 +1283,7 @@ func (m *RegisterRequest) Reset()         { *m = RegisterRequest{} }
 func (m *RegisterRequest) String() string { return proto.CompactTextString(m) }
 func (*RegisterRequest) ProtoMessage()    {}
 func (*RegisterRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_034e29c79f9ba827, []int{12}
+	return fileDescriptor_034e29c79f9ba827, []int{13}
 }
 
 func (m *RegisterRequest) XXX_Unmarshal(b []byte) error {
@@ -1061,6 +1324,8 @@ type NotifySegment struct {
 	Url string `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
 	// Configuration for the transcoding job
 	SegData *SegData `protobuf:"bytes,3,opt,name=segData,proto3" json:"segData,omitempty"`
+	// ETH address of the broadcaster that signed the segment, if known
+	Sender []byte `protobuf:"bytes,4,opt,name=sender,proto3" json:"sender,omitempty"`
 	// ID for this particular transcoding task.
 	TaskId int64 `protobuf:"varint,16,opt,name=taskId,proto3" json:"taskId,omitempty"`
 	// Deprecated by fullProfiles. Set of presets to transcode into.
@@ -1075,7 +1340,7 @@ func (m *NotifySegment) Reset()         { *m = NotifySegment{} }
 func (m *NotifySegment) String() string { return proto.CompactTextString(m) }
 func (*NotifySegment) ProtoMessage()    {}
 func (*NotifySegment) Descriptor() ([]byte, []int) {
-	return fileDescriptor_034e29c79f9ba827, []int{13}
+	return fileDescriptor_034e29c79f9ba827, []int{14}
 }
 
 func (m *NotifySegment) XXX_Unmarshal(b []byte) error {
@@ -1110,6 +1375,13 @@ func (m *NotifySegment) GetSegData() *SegData {
 	return nil
 }
 
+func (m *NotifySegment) GetSender() []byte {
+	if m != nil {
+		return m.Sender
+	}
+	return nil
+}
+
 func (m *NotifySegment) GetTaskId() int64 {
 	if m != nil {
 		return m.TaskId
@@ -1141,17 +1413,22 @@ type TicketParams struct {
 	// Block number at which the current set of advertised TicketParams is no longer valid
 	ExpirationBlock []byte `protobuf:"bytes,6,opt,name=expiration_block,json=expirationBlock,proto3" json:"expiration_block,omitempty"`
 	// Expected ticket expiration params
-	ExpirationParams     *TicketExpirationParams `protobuf:"bytes,7,opt,name=expiration_params,json=expirationParams,proto3" json:"expiration_params,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}                `json:"-"`
-	XXX_unrecognized     []byte                  `json:"-"`
-	XXX_sizecache        int32                   `json:"-"`
+	ExpirationParams *TicketExpirationParams `protobuf:"bytes,7,opt,name=expiration_params,json=expirationParams,proto3" json:"expiration_params,omitempty"`
+	// Optional split of the winnings with the operator of the transcoder pool
+	// of the recipient
+	PayoutSplit *PayoutSplit `protobuf:"bytes,8,opt,name=payout_split,json=payoutSplit,proto3" json:"payout_split,omitempty"`
+	// Version of the tickets, which selects how they are hashed and signed
+	Version              uint32   `protobuf:"varint,9,opt,name=version,proto3" json:"version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *TicketParams) Reset()         { *m = TicketParams{} }
 func (m *TicketParams) String() string { return proto.CompactTextString(m) }
 func (*TicketParams) ProtoMessage()    {}
 func (*TicketParams) Descriptor() ([]byte, []int) {
-	return fileDescriptor_034e29c79f9ba827, []int{14}
+	return fileDescriptor_034e29c79f9ba827, []int{15}
 }
 
 func (m *TicketParams) XXX_Unmarshal(b []byte) error {
@@ -1221,6 +1498,20 @@ func (m *TicketParams) GetExpirationParams() *TicketExpirationParams {
 	return nil
 }
 
+func (m *TicketParams) GetPayoutSplit() *PayoutSplit {
+	if m != nil {
+		return m.PayoutSplit
+	}
+	return nil
+}
+
+func (m *TicketParams) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
 // Sender Params (nonces and signatures)
 type TicketSenderParams struct {
 	// Monotonically increasing counter that makes the ticket
@@ -1237,7 +1528,7 @@ func (m *TicketSenderParams) Reset()         { *m = TicketSenderParams{} }
 func (m *TicketSenderParams) String() string { return proto.CompactTextString(m) }
 func (*TicketSenderParams) ProtoMessage()    {}
 func (*TicketSenderParams) Descriptor() ([]byte, []int) {
-	return fileDescriptor_034e29c79f9ba827, []int{15}
+	return fileDescriptor_034e29c79f9ba827, []int{16}
 }
 
 func (m *TicketSenderParams) XXX_Unmarshal(b []byte) error {
@@ -1287,7 +1578,7 @@ func (m *TicketExpirationParams) Reset()         { *m = TicketExpirationParams{}
 func (m *TicketExpirationParams) String() string { return proto.CompactTextString(m) }
 func (*TicketExpirationParams) ProtoMessage()    {}
 func (*TicketExpirationParams) Descriptor() ([]byte, []int) {
-	return fileDescriptor_034e29c79f9ba827, []int{16}
+	return fileDescriptor_034e29c79f9ba827, []int{17}
 }
 
 func (m *TicketExpirationParams) XXX_Unmarshal(b []byte) error {
@@ -1345,7 +1636,7 @@ func (m *Payment) Reset()         { *m = Payment{} }
 func (m *Payment) String() string { return proto.CompactTextString(m) }
 func (*Payment) ProtoMessage()    {}
 func (*Payment) Descriptor() ([]byte, []int) {
-	return fileDescriptor_034e29c79f9ba827, []int{17}
+	return fileDescriptor_034e29c79f9ba827, []int{18}
 }
 
 func (m *Payment) XXX_Unmarshal(b []byte) error {
@@ -1401,10 +1692,310 @@ func (m *Payment) GetExpectedPrice() *PriceInfo {
 	return nil
 }
 
+// Version of the protocol between broadcasters and orchestrators, exchanged in
+// `GetOrchestrator` and with every segment so that nodes running different
+// releases agree on what they can send each other
+type ProtocolInfo struct {
+	// Protocol version spoken by the node. Nodes that predate versioning don't
+	// send any, which is version 0
+	Version uint32 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	// Oldest protocol version the node is compatible with
+	MinVersion uint32 `protobuf:"varint,2,opt,name=min_version,json=minVersion,proto3" json:"min_version,omitempty"`
+	// Optional protocol features supported by the node. In responses, the
+	// features supported by both nodes
+	Features []string `protobuf:"bytes,3,rep,name=features,proto3" json:"features,omitempty"`
+	// Largest segment, in bytes, sent in the body of segment requests instead of
+	// through object storage. In responses, the smaller size of both nodes. Nodes
+	// always use object storage when available if 0
+	InlineMaxSize        uint64   `protobuf:"varint,4,opt,name=inline_max_size,json=inlineMaxSize,proto3" json:"inline_max_size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ProtocolInfo) Reset()         { *m = ProtocolInfo{} }
+func (m *ProtocolInfo) String() string { return proto.CompactTextString(m) }
+func (*ProtocolInfo) ProtoMessage()    {}
+func (*ProtocolInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_034e29c79f9ba827, []int{19}
+}
+
+func (m *ProtocolInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ProtocolInfo.Unmarshal(m, b)
+}
+func (m *ProtocolInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ProtocolInfo.Marshal(b, m, deterministic)
+}
+func (m *ProtocolInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ProtocolInfo.Merge(m, src)
+}
+func (m *ProtocolInfo) XXX_Size() int {
+	return xxx_messageInfo_ProtocolInfo.Size(m)
+}
+func (m *ProtocolInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_ProtocolInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ProtocolInfo proto.InternalMessageInfo
+
+func (m *ProtocolInfo) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *ProtocolInfo) GetMinVersion() uint32 {
+	if m != nil {
+		return m.MinVersion
+	}
+	return 0
+}
+
+func (m *ProtocolInfo) GetFeatures() []string {
+	if m != nil {
+		return m.Features
+	}
+	return nil
+}
+
+func (m *ProtocolInfo) GetInlineMaxSize() uint64 {
+	if m != nil {
+		return m.InlineMaxSize
+	}
+	return 0
+}
+
+// Token issued by the orchestrator authorizing a broadcaster session. The
+// orchestrator returns a refreshed token with every segment, and broadcasters
+// refresh tokens about to expire with `GetOrchestrator`
+type AuthToken struct {
+	// Opaque token, only meaningful to the orchestrator
+	Token []byte `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	// Session authorized by the token
+	SessionId string `protobuf:"bytes,2,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	// Unix time at which the token expires, in seconds
+	Expiration           int64    `protobuf:"varint,3,opt,name=expiration,proto3" json:"expiration,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuthToken) Reset()         { *m = AuthToken{} }
+func (m *AuthToken) String() string { return proto.CompactTextString(m) }
+func (*AuthToken) ProtoMessage()    {}
+func (*AuthToken) Descriptor() ([]byte, []int) {
+	return fileDescriptor_034e29c79f9ba827, []int{20}
+}
+
+func (m *AuthToken) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuthToken.Unmarshal(m, b)
+}
+func (m *AuthToken) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuthToken.Marshal(b, m, deterministic)
+}
+func (m *AuthToken) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuthToken.Merge(m, src)
+}
+func (m *AuthToken) XXX_Size() int {
+	return xxx_messageInfo_AuthToken.Size(m)
+}
+func (m *AuthToken) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuthToken.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuthToken proto.InternalMessageInfo
+
+func (m *AuthToken) GetToken() []byte {
+	if m != nil {
+		return m.Token
+	}
+	return nil
+}
+
+func (m *AuthToken) GetSessionId() string {
+	if m != nil {
+		return m.SessionId
+	}
+	return ""
+}
+
+func (m *AuthToken) GetExpiration() int64 {
+	if m != nil {
+		return m.Expiration
+	}
+	return 0
+}
+
+// Proposed by the orchestrator with a segment result when its price or ticket
+// params changed during the session, e.g. on a gas price spike or when a new
+// round starts. The broadcaster either accepts the update and pays the new
+// price for the following segments, or selects another orchestrator
+type PriceUpdate struct {
+	// Why the orchestrator proposes the update
+	Reason PriceUpdate_Reason `protobuf:"varint,1,opt,name=reason,proto3,enum=net.PriceUpdate_Reason" json:"reason,omitempty"`
+	// Price of the following segments
+	PriceInfo *PriceInfo `protobuf:"bytes,2,opt,name=price_info,json=priceInfo,proto3" json:"price_info,omitempty"`
+	// Ticket params to use for the following payments
+	TicketParams         *TicketParams `protobuf:"bytes,3,opt,name=ticket_params,json=ticketParams,proto3" json:"ticket_params,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *PriceUpdate) Reset()         { *m = PriceUpdate{} }
+func (m *PriceUpdate) String() string { return proto.CompactTextString(m) }
+func (*PriceUpdate) ProtoMessage()    {}
+func (*PriceUpdate) Descriptor() ([]byte, []int) {
+	return fileDescriptor_034e29c79f9ba827, []int{21}
+}
+
+func (m *PriceUpdate) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PriceUpdate.Unmarshal(m, b)
+}
+func (m *PriceUpdate) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PriceUpdate.Marshal(b, m, deterministic)
+}
+func (m *PriceUpdate) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PriceUpdate.Merge(m, src)
+}
+func (m *PriceUpdate) XXX_Size() int {
+	return xxx_messageInfo_PriceUpdate.Size(m)
+}
+func (m *PriceUpdate) XXX_DiscardUnknown() {
+	xxx_messageInfo_PriceUpdate.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PriceUpdate proto.InternalMessageInfo
+
+func (m *PriceUpdate) GetReason() PriceUpdate_Reason {
+	if m != nil {
+		return m.Reason
+	}
+	return PriceUpdate_PRICE
+}
+
+func (m *PriceUpdate) GetPriceInfo() *PriceInfo {
+	if m != nil {
+		return m.PriceInfo
+	}
+	return nil
+}
+
+func (m *PriceUpdate) GetTicketParams() *TicketParams {
+	if m != nil {
+		return m.TicketParams
+	}
+	return nil
+}
+
+// Share of the winnings of tickets that the recipient pays out to the operator
+// of its transcoder pool once the tickets are redeemed
+type PayoutSplit struct {
+	// ETH address of the pool operator
+	Recipient []byte `protobuf:"bytes,1,opt,name=recipient,proto3" json:"recipient,omitempty"`
+	// Share of the face value paid out to the pool operator, in parts per million
+	Share                int64    `protobuf:"varint,2,opt,name=share,proto3" json:"share,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PayoutSplit) Reset()         { *m = PayoutSplit{} }
+func (m *PayoutSplit) String() string { return proto.CompactTextString(m) }
+func (*PayoutSplit) ProtoMessage()    {}
+func (*PayoutSplit) Descriptor() ([]byte, []int) {
+	return fileDescriptor_034e29c79f9ba827, []int{22}
+}
+
+func (m *PayoutSplit) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PayoutSplit.Unmarshal(m, b)
+}
+func (m *PayoutSplit) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PayoutSplit.Marshal(b, m, deterministic)
+}
+func (m *PayoutSplit) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PayoutSplit.Merge(m, src)
+}
+func (m *PayoutSplit) XXX_Size() int {
+	return xxx_messageInfo_PayoutSplit.Size(m)
+}
+func (m *PayoutSplit) XXX_DiscardUnknown() {
+	xxx_messageInfo_PayoutSplit.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PayoutSplit proto.InternalMessageInfo
+
+func (m *PayoutSplit) GetRecipient() []byte {
+	if m != nil {
+		return m.Recipient
+	}
+	return nil
+}
+
+func (m *PayoutSplit) GetShare() int64 {
+	if m != nil {
+		return m.Share
+	}
+	return 0
+}
+
+// Price of the pixels of the renditions that need a capability, e.g. a codec
+// that is slower to encode
+type CapabilityPrice struct {
+	// Capability needed by the renditions
+	Capability uint32 `protobuf:"varint,1,opt,name=capability,proto3" json:"capability,omitempty"`
+	// Price of their pixels, as a percentage of the price per pixel
+	Percent              uint32   `protobuf:"varint,2,opt,name=percent,proto3" json:"percent,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CapabilityPrice) Reset()         { *m = CapabilityPrice{} }
+func (m *CapabilityPrice) String() string { return proto.CompactTextString(m) }
+func (*CapabilityPrice) ProtoMessage()    {}
+func (*CapabilityPrice) Descriptor() ([]byte, []int) {
+	return fileDescriptor_034e29c79f9ba827, []int{23}
+}
+
+func (m *CapabilityPrice) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CapabilityPrice.Unmarshal(m, b)
+}
+func (m *CapabilityPrice) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CapabilityPrice.Marshal(b, m, deterministic)
+}
+func (m *CapabilityPrice) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CapabilityPrice.Merge(m, src)
+}
+func (m *CapabilityPrice) XXX_Size() int {
+	return xxx_messageInfo_CapabilityPrice.Size(m)
+}
+func (m *CapabilityPrice) XXX_DiscardUnknown() {
+	xxx_messageInfo_CapabilityPrice.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CapabilityPrice proto.InternalMessageInfo
+
+func (m *CapabilityPrice) GetCapability() uint32 {
+	if m != nil {
+		return m.Capability
+	}
+	return 0
+}
+
+func (m *CapabilityPrice) GetPercent() uint32 {
+	if m != nil {
+		return m.Percent
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterEnum("net.OSInfo_StorageType", OSInfo_StorageType_name, OSInfo_StorageType_value)
 	proto.RegisterEnum("net.VideoProfile_Format", VideoProfile_Format_name, VideoProfile_Format_value)
 	proto.RegisterEnum("net.VideoProfile_Profile", VideoProfile_Profile_name, VideoProfile_Profile_value)
+	proto.RegisterEnum("net.PriceUpdate_Reason", PriceUpdate_Reason_name, PriceUpdate_Reason_value)
 	proto.RegisterType((*PingPong)(nil), "net.PingPong")
 	proto.RegisterType((*OrchestratorRequest)(nil), "net.OrchestratorRequest")
 	proto.RegisterType((*OSInfo)(nil), "net.OSInfo")
@@ -1417,6 +2008,7 @@ func init() {
 	proto.RegisterType((*VideoProfile)(nil), "net.VideoProfile")
 	proto.RegisterType((*TranscodedSegmentData)(nil), "net.TranscodedSegmentData")
 	proto.RegisterType((*TranscodeData)(nil), "net.TranscodeData")
+	proto.RegisterType((*TranscodeReceipt)(nil), "net.TranscodeReceipt")
 	proto.RegisterType((*TranscodeResult)(nil), "net.TranscodeResult")
 	proto.RegisterType((*RegisterRequest)(nil), "net.RegisterRequest")
 	proto.RegisterType((*NotifySegment)(nil), "net.NotifySegment")
@@ -1424,102 +2016,144 @@ func init() {
 	proto.RegisterType((*TicketSenderParams)(nil), "net.TicketSenderParams")
 	proto.RegisterType((*TicketExpirationParams)(nil), "net.TicketExpirationParams")
 	proto.RegisterType((*Payment)(nil), "net.Payment")
+	proto.RegisterType((*ProtocolInfo)(nil), "net.ProtocolInfo")
+	proto.RegisterType((*AuthToken)(nil), "net.AuthToken")
+	proto.RegisterType((*PriceUpdate)(nil), "net.PriceUpdate")
+	proto.RegisterType((*PayoutSplit)(nil), "net.PayoutSplit")
+	proto.RegisterType((*CapabilityPrice)(nil), "net.CapabilityPrice")
 }
 
 func init() { proto.RegisterFile("net/lp_rpc.proto", fileDescriptor_034e29c79f9ba827) }
 
 var fileDescriptor_034e29c79f9ba827 = []byte{
-	// 1427 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x57, 0xdd, 0x6f, 0xdb, 0x38,
-	0x12, 0x8f, 0x2c, 0xc7, 0x1f, 0x63, 0x3b, 0x51, 0x98, 0x8f, 0x2a, 0xb9, 0x6b, 0xe1, 0xea, 0xda,
-	0x43, 0xfa, 0xd0, 0x5c, 0xe1, 0xb4, 0x3d, 0xf4, 0xed, 0xf2, 0xe1, 0x26, 0x2e, 0x5a, 0xc7, 0xa0,
-	0xd3, 0x02, 0xf7, 0x70, 0x30, 0x14, 0x89, 0x76, 0x78, 0x71, 0x24, 0x95, 0xa2, 0xaf, 0x49, 0xff,
-	0x83, 0x7b, 0xdc, 0xb7, 0xdd, 0x7d, 0xd9, 0xc5, 0x02, 0xfb, 0x7f, 0x2e, 0x38, 0xa4, 0x14, 0x39,
-	0xc9, 0x02, 0xc5, 0x62, 0xdf, 0xf8, 0xfb, 0xcd, 0x70, 0x38, 0x1c, 0x0d, 0x7f, 0xa4, 0xc0, 0x89,
-	0x98, 0xfc, 0xc7, 0x34, 0x19, 0x89, 0x24, 0xd8, 0x49, 0x44, 0x2c, 0x63, 0x62, 0x47, 0x4c, 0x7a,
-	0x6d, 0xa8, 0x0d, 0x78, 0x34, 0x19, 0xc4, 0xd1, 0x84, 0xac, 0xc1, 0xe2, 0xff, 0xfc, 0xe9, 0x8c,
-	0xb9, 0x56, 0xdb, 0xda, 0x6e, 0x52, 0x0d, 0xbc, 0x3d, 0x58, 0x3d, 0x11, 0xc1, 0x39, 0x4b, 0xa5,
-	0xf0, 0x65, 0x2c, 0x28, 0xfb, 0x3c, 0x63, 0xa9, 0x24, 0x2e, 0x54, 0xfd, 0x30, 0x14, 0x2c, 0x4d,
-	0x8d, 0x7b, 0x06, 0x89, 0x03, 0x76, 0xca, 0x27, 0x6e, 0x09, 0x59, 0x35, 0xf4, 0x7e, 0xb0, 0xa0,
-	0x72, 0x32, 0xec, 0x45, 0xe3, 0x98, 0xbc, 0x81, 0x46, 0x2a, 0x63, 0xe1, 0x4f, 0xd8, 0xe9, 0x75,
-	0xa2, 0x57, 0x5a, 0xea, 0x3c, 0xd8, 0x89, 0x98, 0xdc, 0xd1, 0x1e, 0x3b, 0xc3, 0x1b, 0x33, 0x2d,
-	0xfa, 0x92, 0xa7, 0x50, 0x49, 0x77, 0x79, 0x34, 0x8e, 0x5d, 0xa7, 0x6d, 0x6d, 0x37, 0x3a, 0x2d,
-	0x9c, 0x35, 0xdc, 0xd5, 0xf3, 0xa8, 0x31, 0x7a, 0xcf, 0xa1, 0x51, 0x08, 0x41, 0x00, 0x2a, 0x87,
-	0x3d, 0xda, 0x3d, 0x38, 0x75, 0x16, 0x48, 0x05, 0x4a, 0xc3, 0x5d, 0xc7, 0x52, 0xdc, 0xd1, 0xc9,
-	0xc9, 0xd1, 0xfb, 0xae, 0x53, 0xf2, 0x7e, 0xb1, 0xa0, 0x96, 0xc5, 0x20, 0x04, 0xca, 0xe7, 0x71,
-	0x2a, 0x31, 0xad, 0x3a, 0xc5, 0xb1, 0xda, 0xce, 0x05, 0xbb, 0xc6, 0xed, 0xd4, 0xa9, 0x1a, 0x92,
-	0x0d, 0xa8, 0x24, 0xf1, 0x94, 0x07, 0xd7, 0xae, 0x8d, 0xa4, 0x41, 0xe4, 0xaf, 0x50, 0x4f, 0xf9,
-	0x24, 0xf2, 0xe5, 0x4c, 0x30, 0xb7, 0x8c, 0xa6, 0x1b, 0x82, 0x3c, 0x02, 0x08, 0x04, 0x0b, 0x59,
-	0x24, 0xb9, 0x3f, 0x75, 0x17, 0xd1, 0x5c, 0x60, 0xc8, 0x16, 0xd4, 0xae, 0xf6, 0x2e, 0xbf, 0x1e,
-	0xfa, 0x92, 0xb9, 0x15, 0xb4, 0xe6, 0xd8, 0xfb, 0x08, 0xf5, 0x81, 0xe0, 0x01, 0xc3, 0x24, 0x3d,
-	0x68, 0x26, 0x0a, 0x0c, 0x98, 0xf8, 0x18, 0x71, 0x9d, 0xac, 0x4d, 0xe7, 0x38, 0xf2, 0x04, 0x5a,
-	0x09, 0xbf, 0x62, 0xd3, 0x34, 0x73, 0x2a, 0xa1, 0xd3, 0x3c, 0xe9, 0xfd, 0x07, 0x9a, 0x07, 0x7e,
-	0xe2, 0x9f, 0xf1, 0x29, 0x97, 0x9c, 0xa5, 0x6a, 0x03, 0x67, 0x5c, 0xa6, 0x52, 0xf0, 0x68, 0xe2,
-	0x5a, 0x6d, 0x7b, 0xbb, 0x4c, 0x6f, 0x08, 0xd2, 0x86, 0xc6, 0xa5, 0x1f, 0x85, 0xaa, 0x09, 0x38,
-	0x4b, 0xdd, 0x12, 0xda, 0x8b, 0xd4, 0x56, 0x0b, 0x1a, 0x07, 0x71, 0xa4, 0x1a, 0x85, 0x47, 0x32,
-	0xf5, 0xbe, 0x2b, 0x81, 0x53, 0x6c, 0x1d, 0xcc, 0xfe, 0x11, 0x80, 0x14, 0x7e, 0x94, 0x06, 0x71,
-	0xc8, 0x84, 0x29, 0x74, 0x81, 0x21, 0xaf, 0xa1, 0x25, 0x79, 0x70, 0xc1, 0xe4, 0x28, 0xf1, 0x85,
-	0x7f, 0x99, 0x62, 0xe6, 0x8d, 0xce, 0x0a, 0x7e, 0xec, 0x53, 0xb4, 0x0c, 0xd0, 0x40, 0x9b, 0xb2,
-	0x80, 0xc8, 0x73, 0x00, 0xac, 0xc0, 0x08, 0x3b, 0xc4, 0xc6, 0x49, 0x4b, 0x38, 0x29, 0xaf, 0x1c,
-	0xad, 0x27, 0x79, 0x11, 0x0b, 0xed, 0x5b, 0x9e, 0x6f, 0xdf, 0x57, 0xd0, 0x0c, 0x0a, 0x45, 0xc1,
-	0x2f, 0x95, 0xad, 0x5f, 0xac, 0x16, 0x9d, 0x73, 0x23, 0x4f, 0xa1, 0x6a, 0x9a, 0xd5, 0x6d, 0xb7,
-	0xed, 0xed, 0x46, 0xa7, 0x51, 0x68, 0x6a, 0x9a, 0xd9, 0xbc, 0x9f, 0x6d, 0xa8, 0x0e, 0xd9, 0xe4,
-	0xd0, 0x97, 0xbe, 0x2a, 0xc5, 0xa5, 0x1f, 0xf1, 0x31, 0x4b, 0x65, 0x2f, 0x34, 0xa7, 0xa8, 0xc0,
-	0xe0, 0x41, 0x62, 0x9f, 0xcd, 0xa7, 0x53, 0x43, 0xec, 0x4f, 0x3f, 0x3d, 0xc7, 0xed, 0x35, 0x29,
-	0x8e, 0x55, 0xdf, 0x24, 0x22, 0x1e, 0xf3, 0x29, 0xcb, 0xb6, 0x92, 0xe3, 0xec, 0x28, 0x2e, 0xe6,
-	0x47, 0x51, 0x79, 0x87, 0x33, 0xe1, 0x4b, 0x1e, 0x47, 0xd8, 0x65, 0x8b, 0x34, 0xc7, 0x77, 0x76,
-	0x5e, 0xfd, 0x33, 0x77, 0xae, 0xa2, 0x8f, 0x67, 0xd3, 0xe9, 0x20, 0xcb, 0xf5, 0x31, 0xfa, 0xea,
-	0xe8, 0x9f, 0x78, 0xc8, 0x62, 0x63, 0xa1, 0x73, 0x6e, 0xe4, 0x9f, 0xd0, 0x2a, 0xe2, 0x8e, 0xeb,
-	0xfd, 0xde, 0xbc, 0x79, 0xbf, 0xdb, 0x13, 0x77, 0xdd, 0xbf, 0x7d, 0xd3, 0xc4, 0x5d, 0xef, 0x7b,
-	0x1b, 0x9a, 0x45, 0xbb, 0xaa, 0x7a, 0xe4, 0x5f, 0x32, 0x94, 0x9d, 0x3a, 0xc5, 0xb1, 0xd2, 0xca,
-	0x2f, 0x3c, 0x94, 0xe7, 0xee, 0x0a, 0x16, 0x51, 0x03, 0xa5, 0x0c, 0xe7, 0x8c, 0x4f, 0xce, 0xa5,
-	0x4b, 0x90, 0x36, 0x48, 0x75, 0xdb, 0x19, 0x57, 0x87, 0x80, 0xb9, 0xab, 0x68, 0xc8, 0xa0, 0xfa,
-	0x42, 0xe3, 0x24, 0x75, 0xd7, 0xda, 0xd6, 0x76, 0x8b, 0xaa, 0x21, 0x79, 0x01, 0x95, 0x71, 0x2c,
-	0x2e, 0x7d, 0xe9, 0xae, 0xa3, 0x38, 0xba, 0x77, 0x12, 0xde, 0x79, 0x8b, 0x76, 0x6a, 0xfc, 0xd4,
-	0xaa, 0xe3, 0x24, 0x3d, 0x64, 0x91, 0xbb, 0x81, 0x61, 0x0c, 0x22, 0xbb, 0x50, 0x35, 0x9d, 0xe0,
-	0x3e, 0xc0, 0x50, 0x9b, 0x77, 0x43, 0x65, 0x35, 0xc8, 0x3c, 0x55, 0x42, 0x93, 0x38, 0x71, 0x5d,
-	0x4c, 0x53, 0x0d, 0xbd, 0x87, 0x50, 0xd1, 0x0b, 0x2a, 0xdd, 0xfc, 0x30, 0xe8, 0x1e, 0x9d, 0x0e,
-	0x9d, 0x05, 0x52, 0x05, 0xfb, 0xc3, 0xe0, 0xa5, 0x63, 0x79, 0xff, 0x85, 0x6a, 0x56, 0xa8, 0x55,
-	0x58, 0xee, 0xf6, 0x0f, 0x4e, 0x0e, 0xbb, 0x74, 0x74, 0xd8, 0x7d, 0xbb, 0xf7, 0xf1, 0xbd, 0x12,
-	0xdd, 0x15, 0x68, 0x1d, 0x77, 0x5e, 0xbf, 0x1c, 0xed, 0xef, 0x0d, 0xbb, 0xef, 0x7b, 0xfd, 0xae,
-	0x63, 0x91, 0x16, 0xd4, 0x91, 0xfa, 0xb0, 0xd7, 0xeb, 0x3b, 0xa5, 0x1c, 0x1e, 0xf7, 0x8e, 0x8e,
-	0x1d, 0x9b, 0x6c, 0xc2, 0x3a, 0xc2, 0x83, 0x93, 0xfe, 0xf0, 0x94, 0xee, 0xf5, 0xfa, 0xdd, 0x43,
-	0x6d, 0x2a, 0x7b, 0x7b, 0xb0, 0x7e, 0x9a, 0x49, 0x45, 0x38, 0x64, 0x93, 0x4b, 0x16, 0x49, 0x3c,
-	0x4a, 0x0e, 0xd8, 0x33, 0x31, 0x35, 0x72, 0xa2, 0x86, 0x28, 0xd2, 0x28, 0x76, 0xe6, 0xfc, 0x18,
-	0xe4, 0xfd, 0x1b, 0x5a, 0x79, 0x08, 0x9c, 0xfa, 0x1a, 0x6a, 0xa9, 0x8e, 0x94, 0xa2, 0xe6, 0x35,
-	0x3a, 0x5b, 0x5a, 0x6b, 0xee, 0x5b, 0x88, 0xe6, 0xbe, 0xf7, 0x5c, 0x73, 0x3f, 0x5a, 0xb0, 0x9c,
-	0xcf, 0xa2, 0x2c, 0x9d, 0x4d, 0x65, 0x76, 0x86, 0xad, 0x9b, 0x33, 0xbc, 0x01, 0x8b, 0x4c, 0x88,
-	0x58, 0xe8, 0x1b, 0xe5, 0x78, 0x81, 0x6a, 0x48, 0xb6, 0xa1, 0x1c, 0xfa, 0xd2, 0x37, 0xd2, 0x45,
-	0xe6, 0x73, 0x50, 0x6b, 0x1f, 0x2f, 0x50, 0xf4, 0x20, 0xcf, 0xa0, 0x5c, 0xb8, 0x06, 0xd7, 0xf5,
-	0x69, 0xbb, 0xa5, 0xb3, 0x14, 0x5d, 0xf6, 0x6b, 0x50, 0x11, 0x98, 0x88, 0xd7, 0x85, 0x65, 0xca,
-	0x26, 0x3c, 0x95, 0x2c, 0xbf, 0xc2, 0x37, 0xa0, 0x92, 0xb2, 0x40, 0xb0, 0xec, 0xbe, 0x33, 0x48,
-	0x69, 0x84, 0x3a, 0xe0, 0x01, 0x97, 0xd7, 0xa6, 0x78, 0x39, 0xf6, 0xfe, 0x6f, 0x41, 0xab, 0x1f,
-	0x4b, 0x3e, 0xbe, 0x36, 0x55, 0xb9, 0xa7, 0xf4, 0x7f, 0x87, 0x6a, 0xaa, 0x25, 0xce, 0x6c, 0xa6,
-	0xa9, 0x6f, 0x6a, 0xcd, 0xd1, 0xcc, 0xa8, 0xd6, 0x97, 0x7e, 0x7a, 0xd1, 0x0b, 0x71, 0x27, 0x36,
-	0x35, 0x68, 0x4e, 0xd1, 0x56, 0xe6, 0x15, 0xed, 0x5d, 0xb9, 0x56, 0x72, 0xec, 0x77, 0xe5, 0xda,
-	0x63, 0xc7, 0xf3, 0x7e, 0x2a, 0x41, 0xb3, 0x78, 0x23, 0xa8, 0xfb, 0x4b, 0xb0, 0x80, 0x27, 0x9c,
-	0x45, 0xd2, 0xe8, 0xe9, 0x0d, 0x41, 0x1e, 0x02, 0x8c, 0xfd, 0x80, 0x8d, 0xf4, 0x1b, 0x47, 0x7f,
-	0xb7, 0xba, 0x62, 0x3e, 0x29, 0x82, 0x6c, 0x42, 0xed, 0x0b, 0x8f, 0x46, 0x89, 0x88, 0xcf, 0x8c,
-	0xbe, 0x56, 0xbf, 0xf0, 0x68, 0x20, 0xe2, 0x33, 0xb2, 0x03, 0xab, 0x79, 0x98, 0x91, 0xf0, 0xa3,
-	0x70, 0x84, 0x2a, 0xac, 0xd5, 0x76, 0x25, 0x37, 0x51, 0x3f, 0x0a, 0x8f, 0x95, 0x24, 0x13, 0x28,
-	0xa7, 0x8c, 0x85, 0x46, 0x77, 0x71, 0x4c, 0x9e, 0x81, 0xc3, 0xae, 0x12, 0xae, 0xa5, 0x76, 0x74,
-	0x36, 0x8d, 0x83, 0x0b, 0x14, 0xe0, 0x26, 0x5d, 0xbe, 0xe1, 0xf7, 0x15, 0x4d, 0x8e, 0x61, 0xa5,
-	0xe0, 0x6a, 0xae, 0x41, 0x2d, 0xc6, 0x7f, 0x29, 0x5c, 0x83, 0xdd, 0xdc, 0xc7, 0x5c, 0x88, 0x85,
-	0x05, 0x34, 0xe3, 0xf5, 0x80, 0x68, 0xdf, 0x21, 0x8b, 0x42, 0x26, 0x4c, 0x99, 0x1e, 0x43, 0x33,
-	0x45, 0x3c, 0x8a, 0xe2, 0x28, 0xd0, 0x8f, 0xb0, 0x16, 0x6d, 0x68, 0xae, 0xaf, 0xa8, 0x7b, 0x9a,
-	0xfb, 0x2b, 0x6c, 0xdc, 0xbf, 0x2c, 0x79, 0x0a, 0x4b, 0x81, 0x60, 0x3a, 0x59, 0x11, 0xcf, 0xa2,
-	0xd0, 0x74, 0x7b, 0x2b, 0x63, 0xa9, 0x22, 0xc9, 0x1b, 0xd8, 0x9c, 0x77, 0xd3, 0x45, 0xd0, 0xa5,
-	0xd4, 0x0b, 0x6d, 0xcc, 0xcd, 0xc0, 0x62, 0xa8, 0x7a, 0x7a, 0xbf, 0x96, 0xa0, 0x3a, 0xf0, 0xaf,
-	0xb1, 0xdd, 0xee, 0xbc, 0x0f, 0xac, 0x6f, 0x7b, 0x1f, 0x60, 0xb3, 0xab, 0x0d, 0x9a, 0xb5, 0x0c,
-	0xba, 0xbf, 0xd8, 0xf6, 0x1f, 0x28, 0x36, 0xe9, 0xc1, 0x9a, 0xc9, 0xcc, 0x54, 0xd7, 0x04, 0x2b,
-	0xa3, 0xa8, 0x3c, 0x28, 0x04, 0x2b, 0x7e, 0x0d, 0x4a, 0xe4, 0xdd, 0x2f, 0xf4, 0x0a, 0x96, 0xd8,
-	0x55, 0xc2, 0x02, 0xc9, 0xc2, 0x11, 0xbe, 0x59, 0xcc, 0x2b, 0xe4, 0xf6, 0x83, 0xa6, 0x95, 0x79,
-	0x21, 0xd5, 0xb9, 0x82, 0x66, 0x51, 0x07, 0xc8, 0x3e, 0x2c, 0x1f, 0x31, 0x39, 0x47, 0xb9, 0x77,
-	0xd4, 0xc2, 0xa8, 0xc1, 0xd6, 0xfd, 0x3a, 0x42, 0x9e, 0x40, 0x59, 0xfd, 0x20, 0x10, 0xfd, 0xda,
-	0xce, 0xfe, 0x15, 0xb6, 0xe6, 0x61, 0xa7, 0x0f, 0x70, 0x7a, 0xf3, 0x86, 0xfb, 0x17, 0x90, 0x4c,
-	0x6b, 0x0a, 0xec, 0x1a, 0x4e, 0xb9, 0x25, 0x42, 0x5b, 0x5a, 0xe8, 0xe6, 0x24, 0xe5, 0x85, 0x75,
-	0x56, 0xc1, 0x5f, 0x94, 0xdd, 0xdf, 0x02, 0x00, 0x00, 0xff, 0xff, 0xb3, 0xe9, 0x2a, 0x3a, 0xb6,
-	0x0c, 0x00, 0x00,
+	// 2027 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x58, 0xcd, 0x72, 0xdb, 0xc8,
+	0x11, 0x16, 0x08, 0xfe, 0x36, 0x49, 0x09, 0x1a, 0xcb, 0x32, 0x2c, 0x27, 0x1b, 0x1a, 0x59, 0xaf,
+	0xb5, 0x07, 0xcb, 0x1b, 0x69, 0xed, 0xc4, 0xb7, 0x50, 0x22, 0x2d, 0x71, 0x23, 0x51, 0xac, 0xa1,
+	0xa4, 0x54, 0x0e, 0x29, 0x04, 0x02, 0x86, 0xe4, 0x94, 0x48, 0x00, 0xc6, 0x0c, 0xd7, 0x92, 0x1f,
+	0x20, 0x87, 0x54, 0xe5, 0x01, 0x72, 0x4c, 0xaa, 0x72, 0x48, 0xe5, 0x9a, 0xf7, 0xc8, 0x03, 0xe4,
+	0x96, 0x5b, 0x2e, 0x79, 0x86, 0xd4, 0xfc, 0x80, 0x04, 0x24, 0xa5, 0x6c, 0xef, 0x89, 0xd3, 0x5f,
+	0xf7, 0x60, 0x7a, 0x7a, 0xbe, 0xe9, 0xee, 0x21, 0x58, 0x21, 0xe1, 0x2f, 0xa7, 0xb1, 0x9b, 0xc4,
+	0xfe, 0x4e, 0x9c, 0x44, 0x3c, 0x42, 0x66, 0x48, 0xb8, 0xd3, 0x82, 0xea, 0x80, 0x86, 0xe3, 0x41,
+	0x14, 0x8e, 0xd1, 0x06, 0x94, 0xbe, 0xf7, 0xa6, 0x73, 0x62, 0x1b, 0x2d, 0x63, 0xbb, 0x81, 0x95,
+	0xe0, 0xc4, 0xf0, 0xe0, 0x34, 0xf1, 0x27, 0x84, 0xf1, 0xc4, 0xe3, 0x51, 0x82, 0xc9, 0xbb, 0x39,
+	0x61, 0x1c, 0xd9, 0x50, 0xf1, 0x82, 0x20, 0x21, 0x8c, 0x69, 0xf3, 0x54, 0x44, 0x16, 0x98, 0x8c,
+	0x8e, 0xed, 0x82, 0x44, 0xc5, 0x10, 0xbd, 0x80, 0xaa, 0x5c, 0xd2, 0x8f, 0xa6, 0xb6, 0xd9, 0x32,
+	0xb6, 0xeb, 0xbb, 0xeb, 0x3b, 0x21, 0xe1, 0x3b, 0x03, 0x0d, 0xf6, 0xc2, 0x51, 0x84, 0x17, 0x26,
+	0xce, 0x9f, 0x0c, 0x28, 0x9f, 0x0e, 0x05, 0x88, 0xde, 0x40, 0x9d, 0xf1, 0x28, 0xf1, 0xc6, 0xe4,
+	0xec, 0x26, 0x56, 0x8e, 0xad, 0xee, 0x3e, 0x92, 0x93, 0x95, 0xc5, 0xce, 0x70, 0xa9, 0xc6, 0x59,
+	0x5b, 0xf4, 0x0c, 0xca, 0x6c, 0x8f, 0x86, 0xa3, 0xc8, 0xb6, 0xe4, 0x92, 0x4d, 0x39, 0x6b, 0xb8,
+	0xa7, 0xe6, 0x61, 0xad, 0x74, 0x5e, 0x40, 0x3d, 0xf3, 0x09, 0x04, 0x50, 0xee, 0xf4, 0x70, 0xf7,
+	0xe0, 0xcc, 0x5a, 0x41, 0x65, 0x28, 0x0c, 0xf7, 0x2c, 0x43, 0x60, 0x87, 0xa7, 0xa7, 0x87, 0xc7,
+	0x5d, 0xab, 0xe0, 0xfc, 0xc5, 0x80, 0x6a, 0xfa, 0x0d, 0x84, 0xa0, 0x38, 0x89, 0x18, 0x97, 0x6e,
+	0xd5, 0xb0, 0x1c, 0x8b, 0xdd, 0x5f, 0x91, 0x1b, 0xb9, 0xfb, 0x1a, 0x16, 0x43, 0xb4, 0x09, 0xe5,
+	0x38, 0x9a, 0x52, 0xff, 0x46, 0xee, 0xbd, 0x86, 0xb5, 0x84, 0x7e, 0x04, 0x35, 0x46, 0xc7, 0xa1,
+	0xc7, 0xe7, 0x09, 0xb1, 0x8b, 0x52, 0xb5, 0x04, 0xd0, 0x17, 0x00, 0x7e, 0x42, 0x02, 0x12, 0x72,
+	0xea, 0x4d, 0xed, 0x92, 0x54, 0x67, 0x10, 0xb4, 0x05, 0xd5, 0xeb, 0xf6, 0xec, 0x43, 0xc7, 0xe3,
+	0xc4, 0x2e, 0x4b, 0xed, 0x42, 0x76, 0xce, 0xa1, 0x36, 0x48, 0xa8, 0x4f, 0xa4, 0x93, 0x0e, 0x34,
+	0x62, 0x21, 0x0c, 0x48, 0x72, 0x1e, 0x52, 0xe5, 0xac, 0x89, 0x73, 0x18, 0xfa, 0x12, 0x9a, 0x31,
+	0xbd, 0x26, 0x53, 0x96, 0x1a, 0x15, 0xa4, 0x51, 0x1e, 0x74, 0x7e, 0x0b, 0x8d, 0x03, 0x2f, 0xf6,
+	0x2e, 0xe9, 0x94, 0x72, 0x4a, 0x98, 0xd8, 0xc0, 0x25, 0xe5, 0x8c, 0x27, 0x34, 0x1c, 0xdb, 0x46,
+	0xcb, 0xdc, 0x2e, 0xe2, 0x25, 0x80, 0x5a, 0x50, 0x9f, 0x79, 0x61, 0x20, 0x38, 0x43, 0x09, 0xb3,
+	0x0b, 0x52, 0x9f, 0x85, 0xb6, 0x9a, 0x50, 0x3f, 0x88, 0x42, 0xc1, 0x2b, 0x1a, 0x72, 0xe6, 0xfc,
+	0xcb, 0x04, 0x2b, 0xcb, 0x34, 0xe9, 0xfd, 0x17, 0x00, 0x3c, 0xf1, 0x42, 0xe6, 0x47, 0x01, 0x49,
+	0x74, 0xa0, 0x33, 0x08, 0x7a, 0x0d, 0x4d, 0x4e, 0xfd, 0x2b, 0xc2, 0xdd, 0xd8, 0x4b, 0xbc, 0x19,
+	0xb3, 0x0b, 0x19, 0x7e, 0x9d, 0x49, 0xcd, 0x40, 0x2a, 0x70, 0x83, 0x67, 0x24, 0xf4, 0x02, 0x40,
+	0x46, 0xc0, 0x95, 0x0c, 0x51, 0xa4, 0x5c, 0xd5, 0xa4, 0xd4, 0x91, 0xc3, 0xb5, 0x38, 0x1d, 0x66,
+	0xd9, 0x5e, 0xcc, 0xb3, 0xfd, 0x15, 0x34, 0xfc, 0x4c, 0x50, 0xec, 0x52, 0x66, 0xfd, 0x6c, 0xb4,
+	0x70, 0xce, 0x2c, 0x77, 0x25, 0xca, 0x1f, 0xbd, 0x12, 0xc2, 0x5d, 0x6f, 0xce, 0x27, 0x2e, 0x8f,
+	0xae, 0x48, 0x68, 0x57, 0x32, 0xee, 0xb6, 0xe7, 0x7c, 0x72, 0x26, 0x50, 0x5c, 0xf3, 0xd2, 0x21,
+	0x7a, 0x0e, 0x6b, 0xde, 0x94, 0xbb, 0xcb, 0x38, 0x31, 0xbb, 0xda, 0x32, 0xb7, 0x6b, 0x78, 0xd5,
+	0x9b, 0xf2, 0xb3, 0x25, 0x8a, 0xda, 0xb0, 0xbe, 0x70, 0xeb, 0xc6, 0x95, 0xfb, 0x65, 0x76, 0xad,
+	0x65, 0x6e, 0xd7, 0x77, 0x37, 0xf2, 0x5b, 0xb8, 0x91, 0x71, 0xc1, 0x96, 0x9f, 0x07, 0x18, 0x7a,
+	0x06, 0x15, 0x7d, 0xed, 0xec, 0x96, 0x9c, 0x58, 0xcf, 0x5c, 0x4f, 0x9c, 0xea, 0x9c, 0x7f, 0x14,
+	0xa1, 0x32, 0x24, 0xe3, 0x8e, 0xc7, 0x3d, 0x71, 0xa8, 0x33, 0x2f, 0xa4, 0x23, 0xc2, 0x78, 0x2f,
+	0xd0, 0xe9, 0x23, 0x83, 0xc8, 0x0c, 0x42, 0xde, 0x69, 0x12, 0x8a, 0xa1, 0xbc, 0x69, 0x1e, 0x9b,
+	0xc8, 0x83, 0x6a, 0x60, 0x39, 0x16, 0x37, 0x20, 0x4e, 0xa2, 0x11, 0x9d, 0x92, 0xf4, 0x50, 0x16,
+	0x72, 0x9a, 0x83, 0x4a, 0xcb, 0x1c, 0xb4, 0x05, 0xd5, 0x60, 0x9e, 0x78, 0x9c, 0x46, 0xa1, 0x0c,
+	0x78, 0x09, 0x2f, 0xe4, 0x3b, 0x67, 0x58, 0xf9, 0xfc, 0x33, 0xac, 0x7e, 0xee, 0x19, 0xd6, 0x3e,
+	0x76, 0x86, 0x4f, 0xa0, 0xe6, 0x4f, 0x69, 0xec, 0x8e, 0x92, 0x68, 0x66, 0x83, 0x0c, 0x45, 0x55,
+	0x00, 0x6f, 0x93, 0x68, 0x86, 0x1e, 0x41, 0x45, 0x2a, 0x79, 0x64, 0xd7, 0xa5, 0xaa, 0x2c, 0xc4,
+	0xb3, 0xe8, 0x13, 0x4f, 0x43, 0xec, 0x78, 0x34, 0x9f, 0x4e, 0x07, 0x69, 0xfc, 0x9e, 0xb6, 0xcc,
+	0x85, 0xfb, 0x17, 0x34, 0x20, 0x91, 0xd6, 0xe0, 0x9c, 0x19, 0xfa, 0x39, 0x34, 0xb3, 0xf2, 0xae,
+	0xed, 0xfc, 0xbf, 0x79, 0x79, 0xbb, 0xdb, 0x13, 0xf7, 0xec, 0x9f, 0x7e, 0xd2, 0xc4, 0x3d, 0xe7,
+	0xdf, 0x26, 0x34, 0xb2, 0x7a, 0xc1, 0x84, 0xd0, 0x9b, 0x11, 0x99, 0xd4, 0x6b, 0x58, 0x8e, 0x45,
+	0xe1, 0x7a, 0x4f, 0x03, 0x3e, 0xb1, 0xd7, 0xe5, 0xc1, 0x2a, 0x41, 0xe4, 0xdd, 0x09, 0xa1, 0xe3,
+	0x09, 0xb7, 0x91, 0x84, 0xb5, 0x24, 0xee, 0xf2, 0x25, 0x15, 0x29, 0x86, 0xd8, 0x0f, 0xa4, 0x22,
+	0x15, 0x05, 0x6b, 0x46, 0x31, 0xb3, 0x37, 0x5a, 0xc6, 0x76, 0x13, 0x8b, 0x21, 0xfa, 0x06, 0xca,
+	0xa3, 0x28, 0x99, 0x79, 0xdc, 0x7e, 0x28, 0x4b, 0x8f, 0x7d, 0xc7, 0xe1, 0x9d, 0xb7, 0x52, 0x8f,
+	0xb5, 0x9d, 0x58, 0x75, 0x14, 0xb3, 0x0e, 0x09, 0xed, 0x4d, 0xf9, 0x19, 0x2d, 0xa1, 0x3d, 0xa8,
+	0x68, 0x76, 0xda, 0x8f, 0xe4, 0xa7, 0x1e, 0xdf, 0xfd, 0x94, 0xfe, 0xc5, 0xa9, 0xa5, 0x70, 0x68,
+	0x1c, 0xc5, 0xb6, 0x2d, 0xdd, 0x14, 0x43, 0xe7, 0x39, 0x94, 0xd5, 0x82, 0xa2, 0x2a, 0x9d, 0x0c,
+	0xba, 0x87, 0x67, 0x43, 0x6b, 0x05, 0x55, 0xc0, 0x3c, 0x19, 0x7c, 0x6b, 0x19, 0xa8, 0x0a, 0xc5,
+	0x5f, 0x77, 0xf7, 0x4f, 0xac, 0x82, 0xf3, 0x37, 0x03, 0x2a, 0x69, 0xcc, 0x1e, 0xc0, 0x5a, 0xb7,
+	0x7f, 0x70, 0xda, 0xe9, 0x62, 0xb7, 0xd3, 0x7d, 0xdb, 0x3e, 0x3f, 0x16, 0xd5, 0x6d, 0x1d, 0x9a,
+	0x47, 0xbb, 0xaf, 0xbf, 0x75, 0xf7, 0xdb, 0xc3, 0xee, 0x71, 0xaf, 0xdf, 0xb5, 0x0c, 0xd4, 0x84,
+	0x9a, 0x84, 0x4e, 0xda, 0xbd, 0xbe, 0x55, 0x58, 0x88, 0x47, 0xbd, 0xc3, 0x23, 0xcb, 0x44, 0x8f,
+	0xe1, 0xa1, 0x14, 0x0f, 0x4e, 0xfb, 0xc3, 0x33, 0xdc, 0xee, 0xf5, 0xbb, 0x1d, 0xa5, 0x2a, 0x6a,
+	0xcb, 0x57, 0x6a, 0x62, 0x09, 0x35, 0xa0, 0xda, 0xbe, 0xf8, 0x99, 0x92, 0xca, 0xc2, 0xb9, 0x8b,
+	0xc1, 0x2f, 0xac, 0x8a, 0x1a, 0xbc, 0xb1, 0xaa, 0x68, 0x15, 0xa0, 0x7d, 0xde, 0xe9, 0x9d, 0xba,
+	0xa7, 0xfd, 0xe3, 0xdf, 0x58, 0x35, 0xe7, 0xf7, 0x06, 0x3c, 0x5c, 0x64, 0xa5, 0x60, 0x48, 0xc6,
+	0x33, 0x12, 0x72, 0x99, 0x29, 0x2c, 0x30, 0xe7, 0xc9, 0x54, 0xe7, 0x7d, 0x31, 0x94, 0xd5, 0x54,
+	0x56, 0x25, 0x9d, 0x1e, 0xb4, 0x94, 0xbb, 0xdf, 0xe6, 0xad, 0xfb, 0xfd, 0x1c, 0xd6, 0x62, 0x92,
+	0xf8, 0x24, 0xe6, 0x73, 0x6f, 0xea, 0xca, 0x44, 0xa2, 0x12, 0xc6, 0xea, 0x12, 0x3e, 0xf2, 0xd8,
+	0xc4, 0xf9, 0x83, 0x01, 0xcd, 0x85, 0x23, 0xd2, 0x81, 0xd7, 0x50, 0x65, 0xca, 0x1f, 0x26, 0x4b,
+	0x5c, 0x7d, 0x77, 0x4b, 0x95, 0x96, 0xfb, 0xdc, 0xc5, 0x0b, 0xdb, 0x7b, 0x9a, 0xa0, 0x97, 0x50,
+	0x49, 0x88, 0x4f, 0x68, 0xcc, 0x75, 0xb9, 0x79, 0x98, 0xff, 0x10, 0x56, 0x4a, 0x9c, 0x5a, 0x39,
+	0x7f, 0x37, 0xc0, 0xba, 0xad, 0x45, 0x3f, 0x81, 0x7a, 0x9a, 0x28, 0x5d, 0x1a, 0xa4, 0x05, 0x31,
+	0x93, 0x3b, 0x9f, 0x40, 0x8d, 0x71, 0x2f, 0xe1, 0xee, 0x32, 0x83, 0x56, 0x25, 0x30, 0x24, 0xef,
+	0x44, 0xda, 0x20, 0x61, 0x20, 0x55, 0xa6, 0x8a, 0x1e, 0x09, 0x03, 0xa1, 0xd8, 0xca, 0x6c, 0xb3,
+	0xa8, 0x27, 0xa5, 0x5b, 0x41, 0x50, 0x4c, 0xa2, 0x88, 0xeb, 0x64, 0x2a, 0xc7, 0xe9, 0xf6, 0xca,
+	0x8b, 0xed, 0x39, 0xff, 0x34, 0x60, 0x2d, 0xe3, 0x2d, 0x9b, 0x4f, 0x79, 0x9a, 0xc7, 0x8d, 0x65,
+	0x1e, 0xdf, 0x84, 0x12, 0x49, 0x92, 0x28, 0x51, 0xfd, 0xd1, 0xd1, 0x0a, 0x56, 0x22, 0xda, 0x86,
+	0x62, 0xe0, 0x71, 0x4f, 0x47, 0x06, 0xe5, 0x23, 0x23, 0x42, 0x7b, 0xb4, 0x82, 0xa5, 0x05, 0xfa,
+	0x1a, 0x8a, 0x99, 0xa6, 0x4e, 0xc5, 0xf0, 0x76, 0xd7, 0x80, 0xa5, 0x09, 0xda, 0xd3, 0x9d, 0x8f,
+	0x3b, 0x8f, 0x03, 0x71, 0xdb, 0xd7, 0xe5, 0x14, 0x6b, 0x59, 0xe5, 0xcf, 0x25, 0x8e, 0xeb, 0xf1,
+	0x52, 0xd8, 0xaf, 0x42, 0x39, 0x91, 0xde, 0x3b, 0x5d, 0x58, 0xc3, 0x64, 0x4c, 0x19, 0x27, 0x8b,
+	0xa6, 0x77, 0x13, 0xca, 0x8c, 0xf8, 0x09, 0x49, 0x5b, 0x3e, 0x2d, 0x89, 0xf0, 0x89, 0xca, 0xe0,
+	0x53, 0x7e, 0x93, 0xc6, 0x3c, 0x95, 0x9d, 0x3f, 0x1b, 0xd0, 0xec, 0x47, 0x9c, 0x8e, 0x6e, 0x34,
+	0x53, 0xee, 0x21, 0xf5, 0x57, 0x50, 0x61, 0xaa, 0x36, 0xea, 0x08, 0x34, 0x54, 0xb3, 0xaa, 0x30,
+	0x9c, 0x2a, 0xd5, 0xfa, 0xa1, 0xe8, 0x84, 0x14, 0x7f, 0xb5, 0x24, 0x70, 0xee, 0xb1, 0xab, 0x5e,
+	0x20, 0xc3, 0x62, 0x62, 0x2d, 0xe5, 0x4a, 0xe4, 0x7a, 0xbe, 0x44, 0x7e, 0x57, 0xac, 0x16, 0x2c,
+	0xf3, 0xbb, 0x62, 0xf5, 0xa9, 0xe5, 0x38, 0xff, 0x2d, 0x40, 0x23, 0xdb, 0x2c, 0x89, 0xd6, 0x2e,
+	0x21, 0x3e, 0x8d, 0x29, 0x09, 0xb9, 0x2e, 0xd0, 0x4b, 0x00, 0xfd, 0x18, 0x60, 0xe4, 0xf9, 0xc4,
+	0x55, 0xaf, 0x05, 0xc5, 0xf1, 0x9a, 0x40, 0x2e, 0x04, 0x80, 0x1e, 0x43, 0xf5, 0x3d, 0x0d, 0xdd,
+	0x38, 0x89, 0x2e, 0x75, 0xc1, 0xae, 0xbc, 0xa7, 0xe1, 0x20, 0x89, 0x2e, 0xd1, 0x0e, 0x3c, 0x58,
+	0x7c, 0xc6, 0x4d, 0xbc, 0x30, 0xc8, 0xde, 0xc6, 0xf5, 0x85, 0x0a, 0x7b, 0x61, 0x20, 0x2e, 0xa4,
+	0xe0, 0x1e, 0x23, 0x24, 0x48, 0xb9, 0x27, 0xc6, 0xe8, 0x6b, 0xb0, 0xc8, 0x75, 0x4c, 0xd5, 0xdd,
+	0x76, 0x2f, 0xa7, 0x91, 0x7f, 0xa5, 0x89, 0xb8, 0xb6, 0xc4, 0xf7, 0x05, 0x8c, 0x8e, 0x60, 0x3d,
+	0x63, 0xaa, 0x3b, 0x44, 0x55, 0xdd, 0x9f, 0x64, 0x3a, 0xc4, 0xee, 0xc2, 0x46, 0xf7, 0x8a, 0x16,
+	0xb9, 0x85, 0x48, 0x2e, 0x79, 0x37, 0xd1, 0x9c, 0xbb, 0x2c, 0x9e, 0x52, 0x6e, 0x57, 0xb3, 0x5c,
+	0x92, 0x8a, 0xa1, 0xc0, 0x71, 0x3d, 0x5e, 0x0a, 0xa2, 0xd2, 0x7c, 0x4f, 0x12, 0x46, 0x23, 0x55,
+	0xee, 0x9b, 0x38, 0x15, 0x9d, 0x1e, 0x20, 0xb5, 0xf4, 0x50, 0x1e, 0xa0, 0x5e, 0xe4, 0x29, 0x34,
+	0xd4, 0x81, 0xba, 0x61, 0x14, 0xfa, 0xea, 0xb9, 0xd3, 0xc4, 0x75, 0x85, 0xf5, 0x05, 0x74, 0x37,
+	0xaf, 0x38, 0x1f, 0x60, 0xf3, 0xfe, 0x5d, 0xa0, 0x67, 0xb0, 0xea, 0x27, 0x44, 0xed, 0x3d, 0x89,
+	0xe6, 0x61, 0xa0, 0x6f, 0x62, 0x33, 0x45, 0xb1, 0x00, 0xd1, 0x1b, 0x78, 0x9c, 0x37, 0x53, 0x31,
+	0x55, 0x27, 0xa3, 0x16, 0xda, 0xcc, 0xcd, 0x90, 0xb1, 0x95, 0xf9, 0xf2, 0xaf, 0x05, 0xa8, 0x0c,
+	0xbc, 0x1b, 0xc9, 0xea, 0x3b, 0x9d, 0xb8, 0xf1, 0x69, 0x9d, 0xf8, 0x92, 0xd3, 0x85, 0x1c, 0xa7,
+	0xef, 0x3d, 0x3b, 0xf3, 0x87, 0x9c, 0x5d, 0x0f, 0x36, 0xb4, 0x67, 0x3a, 0xba, 0xfa, 0x63, 0x45,
+	0x99, 0xcf, 0x1f, 0x65, 0x3e, 0x96, 0x3d, 0x0d, 0x8c, 0xf8, 0xdd, 0x13, 0x7a, 0x05, 0xab, 0xe4,
+	0x3a, 0x26, 0x3e, 0x27, 0x81, 0xea, 0x96, 0xed, 0x52, 0xa6, 0x8f, 0x5b, 0x3e, 0x1d, 0x9a, 0xa9,
+	0x95, 0x84, 0x9c, 0x3f, 0x1a, 0xd0, 0xc8, 0x76, 0x85, 0x59, 0x66, 0x18, 0x39, 0x66, 0xc8, 0x04,
+	0x4f, 0x43, 0x37, 0xd5, 0x16, 0xa4, 0x16, 0x66, 0x34, 0xbc, 0xd0, 0x06, 0x5b, 0x50, 0x1d, 0x11,
+	0xf9, 0x46, 0x14, 0xe1, 0x10, 0x4d, 0xfd, 0x42, 0x46, 0x5f, 0xc1, 0x1a, 0x0d, 0xa7, 0x34, 0x24,
+	0xee, 0xcc, 0xbb, 0x76, 0x19, 0xfd, 0xa0, 0x1e, 0x96, 0x45, 0xdc, 0x54, 0xf0, 0x89, 0x77, 0x3d,
+	0xa4, 0x1f, 0x88, 0xf3, 0x3b, 0xa8, 0x2d, 0x7a, 0x4e, 0xd1, 0x3d, 0xa9, 0x96, 0x54, 0x3f, 0xfb,
+	0xa5, 0x20, 0xee, 0x38, 0x23, 0x4c, 0xac, 0x28, 0xea, 0x4c, 0x41, 0x3f, 0x4f, 0x15, 0xd2, 0x0b,
+	0x44, 0x0b, 0xbf, 0x8c, 0xb3, 0x2e, 0x26, 0x19, 0xc4, 0xf9, 0x8f, 0x01, 0xf5, 0x4c, 0x8e, 0x45,
+	0x2f, 0x45, 0x5a, 0xf5, 0x58, 0x14, 0xe6, 0xde, 0xf0, 0x19, 0x8b, 0x1d, 0x2c, 0xd5, 0x58, 0x9b,
+	0xdd, 0x7a, 0xa0, 0x15, 0x3e, 0xf6, 0x40, 0xbb, 0xc3, 0x3e, 0xf3, 0x93, 0xd8, 0xe7, 0xec, 0x43,
+	0x59, 0x2d, 0x8c, 0x6a, 0x50, 0x1a, 0xe0, 0xde, 0x41, 0xd7, 0x5a, 0x11, 0xfd, 0xc9, 0xdb, 0xf6,
+	0x41, 0xd7, 0xbd, 0x68, 0x1f, 0x9f, 0x8b, 0xbe, 0xa8, 0x06, 0x25, 0x7c, 0x7a, 0xde, 0xef, 0x58,
+	0x05, 0x84, 0x60, 0x15, 0x77, 0x0f, 0x7a, 0x83, 0x5e, 0xb7, 0x7f, 0xe6, 0xe2, 0x76, 0xbf, 0x63,
+	0x99, 0x4e, 0x1b, 0xea, 0x99, 0x14, 0xf0, 0x91, 0xdc, 0xb9, 0x01, 0x25, 0x36, 0xf1, 0x12, 0xa2,
+	0xeb, 0x84, 0x12, 0x9c, 0x5f, 0xc1, 0xda, 0xad, 0x97, 0x96, 0xfc, 0x03, 0x60, 0x01, 0x69, 0x96,
+	0x64, 0x10, 0x41, 0x21, 0xd9, 0xbd, 0x84, 0x5c, 0x93, 0x24, 0x15, 0x77, 0xaf, 0xa1, 0x91, 0xad,
+	0x88, 0x68, 0x1f, 0xd6, 0x0e, 0x09, 0xcf, 0x41, 0xf6, 0x9d, 0xba, 0xa9, 0x4b, 0xdc, 0xd6, 0xfd,
+	0x15, 0x15, 0x7d, 0x09, 0x45, 0xf1, 0x3f, 0x11, 0x52, 0xff, 0xa2, 0xa4, 0x7f, 0x19, 0x6d, 0xe5,
+	0xc5, 0xdd, 0x3e, 0xc0, 0xf2, 0x75, 0x89, 0x7e, 0x09, 0x28, 0x2d, 0xa0, 0x19, 0x54, 0xbd, 0x2b,
+	0x6f, 0x55, 0xd6, 0x2d, 0x55, 0xf2, 0x73, 0x75, 0xf2, 0x1b, 0xe3, 0xb2, 0x2c, 0x1f, 0x4f, 0x7b,
+	0xff, 0x1b, 0x00, 0x48, 0xd2, 0x59, 0xdd, 0xbd, 0x12, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
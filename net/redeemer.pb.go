@@ -204,38 +204,79 @@ func (m *MaxFloatUpdate) GetMaxFloat() []byte {
 	return nil
 }
 
+type WinningTickets struct {
+	Tickets              []*Ticket `protobuf:"bytes,1,rep,name=tickets,proto3" json:"tickets,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *WinningTickets) Reset()         { *m = WinningTickets{} }
+func (m *WinningTickets) String() string { return proto.CompactTextString(m) }
+func (*WinningTickets) ProtoMessage()    {}
+func (*WinningTickets) Descriptor() ([]byte, []int) {
+	return fileDescriptor_41a074e4ea0232f2, []int{4}
+}
+
+func (m *WinningTickets) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WinningTickets.Unmarshal(m, b)
+}
+func (m *WinningTickets) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WinningTickets.Marshal(b, m, deterministic)
+}
+func (m *WinningTickets) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WinningTickets.Merge(m, src)
+}
+func (m *WinningTickets) XXX_Size() int {
+	return xxx_messageInfo_WinningTickets.Size(m)
+}
+func (m *WinningTickets) XXX_DiscardUnknown() {
+	xxx_messageInfo_WinningTickets.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WinningTickets proto.InternalMessageInfo
+
+func (m *WinningTickets) GetTickets() []*Ticket {
+	if m != nil {
+		return m.Tickets
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Ticket)(nil), "net.Ticket")
 	proto.RegisterType((*QueueTicketRes)(nil), "net.QueueTicketRes")
 	proto.RegisterType((*MaxFloatReq)(nil), "net.MaxFloatReq")
 	proto.RegisterType((*MaxFloatUpdate)(nil), "net.MaxFloatUpdate")
+	proto.RegisterType((*WinningTickets)(nil), "net.WinningTickets")
 }
 
 func init() { proto.RegisterFile("net/redeemer.proto", fileDescriptor_41a074e4ea0232f2) }
 
 var fileDescriptor_41a074e4ea0232f2 = []byte{
-	// 323 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x92, 0xc1, 0x4e, 0xfa, 0x40,
-	0x10, 0xc6, 0x29, 0xfc, 0xff, 0x04, 0xa7, 0x50, 0xcb, 0x98, 0x28, 0x81, 0x0b, 0x69, 0x42, 0xc2,
-	0x45, 0x54, 0x48, 0x3c, 0x71, 0xd5, 0x78, 0x21, 0xd1, 0xaa, 0xe7, 0x66, 0xa5, 0x63, 0xd2, 0x48,
-	0xb7, 0xeb, 0x76, 0x48, 0x78, 0x03, 0xdf, 0xc8, 0xe7, 0x33, 0x6c, 0xbb, 0x64, 0xe5, 0xe6, 0xad,
-	0xf3, 0xcd, 0x37, 0xbf, 0xf9, 0x26, 0x5d, 0x40, 0x49, 0x7c, 0xa5, 0x29, 0x25, 0xca, 0x49, 0xcf,
-	0x94, 0x2e, 0xb8, 0xc0, 0x96, 0x24, 0x1e, 0x86, 0xfb, 0xc6, 0x46, 0x25, 0x5a, 0xad, 0x2b, 0x39,
-	0xfa, 0x6a, 0x42, 0xfb, 0x25, 0x5b, 0x7f, 0x10, 0xe3, 0x2d, 0xf4, 0xd8, 0x7c, 0x25, 0x4a, 0x68,
-	0x91, 0x97, 0x03, 0x6f, 0xec, 0x4d, 0xfd, 0x79, 0x7f, 0x26, 0x89, 0x67, 0x95, 0xe7, 0xd1, 0x34,
-	0xe2, 0x2e, 0x3b, 0x15, 0x9e, 0x43, 0xbb, 0x24, 0x99, 0x92, 0x1e, 0x34, 0xc7, 0xde, 0xb4, 0x1b,
-	0xd7, 0x15, 0x3e, 0x40, 0x9f, 0x76, 0x2a, 0xd3, 0x82, 0xb3, 0x42, 0x5a, 0x66, 0xcb, 0x30, 0x47,
-	0x0e, 0xf3, 0xee, 0xe0, 0xa9, 0xe9, 0x21, 0x1d, 0x29, 0xb8, 0x84, 0x5e, 0xc5, 0xb4, 0x94, 0x7f,
-	0x86, 0x72, 0xe1, 0x50, 0x9e, 0x4d, 0xdf, 0xe6, 0x2b, 0x9d, 0x0a, 0x27, 0x10, 0x68, 0x5a, 0x67,
-	0x2a, 0x23, 0xc9, 0x89, 0x16, 0x32, 0x1d, 0xfc, 0x37, 0x39, 0x7b, 0x07, 0x35, 0x16, 0x32, 0x8d,
-	0x42, 0x08, 0x9e, 0xb6, 0xb4, 0xa5, 0x8a, 0x17, 0x53, 0x19, 0x4d, 0xc0, 0x5f, 0x89, 0xdd, 0xfd,
-	0xa6, 0x10, 0x1c, 0xd3, 0xa7, 0x73, 0xa7, 0xe7, 0xde, 0x19, 0x5d, 0x42, 0x60, 0x6d, 0xaf, 0x2a,
-	0x15, 0x4c, 0x38, 0x82, 0x93, 0x5c, 0xec, 0x92, 0xf7, 0xbd, 0x54, 0x9b, 0x3b, 0x79, 0x6d, 0x99,
-	0x7f, 0x7b, 0x10, 0xd8, 0x1d, 0xd5, 0x1f, 0xc2, 0x1b, 0xf0, 0x9d, 0xd5, 0xe8, 0x3b, 0x77, 0x0d,
-	0xcf, 0x4c, 0x71, 0x94, 0xac, 0x81, 0x0b, 0xe8, 0xd8, 0xa5, 0x18, 0x1a, 0x8b, 0x13, 0xb5, 0x1e,
-	0xfa, 0x9d, 0x2a, 0x6a, 0xe0, 0x12, 0x4e, 0x57, 0x85, 0xcc, 0xb8, 0xd0, 0x7f, 0x9e, 0xbd, 0xf6,
-	0xde, 0xda, 0xe6, 0xc5, 0x2c, 0x7e, 0x02, 0x00, 0x00, 0xff, 0xff, 0xf3, 0xd4, 0x7e, 0x1d, 0x5e,
-	0x02, 0x00, 0x00,
+	// 346 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x92, 0x41, 0x4f, 0xc2, 0x40,
+	0x10, 0x85, 0x29, 0x28, 0xe2, 0x14, 0x6a, 0x19, 0x12, 0x25, 0x70, 0x21, 0x4d, 0x48, 0xb8, 0x88,
+	0x0a, 0x89, 0x5e, 0xb8, 0x6a, 0xbc, 0x90, 0x68, 0xd5, 0x78, 0x6c, 0x56, 0x3a, 0x9a, 0x8d, 0x74,
+	0x5b, 0xb7, 0x4b, 0xc2, 0x3f, 0xf0, 0x1f, 0xf9, 0xfb, 0x4c, 0x77, 0x5b, 0xb2, 0x72, 0xf3, 0xd6,
+	0x79, 0xf3, 0xf6, 0xeb, 0x7b, 0xdb, 0x02, 0x0a, 0x52, 0x17, 0x92, 0x62, 0xa2, 0x84, 0xe4, 0x34,
+	0x93, 0xa9, 0x4a, 0xb1, 0x21, 0x48, 0x0d, 0xfc, 0x62, 0xb1, 0xce, 0x22, 0x99, 0xad, 0x8c, 0x1c,
+	0x7c, 0xd7, 0xa1, 0xf9, 0xcc, 0x57, 0x9f, 0xa4, 0xf0, 0x1a, 0x3a, 0x4a, 0x3f, 0x45, 0x19, 0x93,
+	0x2c, 0xc9, 0xfb, 0xce, 0xc8, 0x99, 0xb8, 0xb3, 0xee, 0x54, 0x90, 0x9a, 0x1a, 0xcf, 0x83, 0x5e,
+	0x84, 0x6d, 0x65, 0x4d, 0x78, 0x0a, 0xcd, 0x9c, 0x44, 0x4c, 0xb2, 0x5f, 0x1f, 0x39, 0x93, 0x76,
+	0x58, 0x4e, 0x78, 0x0f, 0x5d, 0xda, 0x66, 0x5c, 0x32, 0xc5, 0x53, 0x51, 0x31, 0x1b, 0x9a, 0x39,
+	0xb4, 0x98, 0xb7, 0x3b, 0x4f, 0x49, 0xf7, 0x69, 0x4f, 0xc1, 0x05, 0x74, 0x0c, 0xb3, 0xa2, 0x1c,
+	0x68, 0xca, 0x99, 0x45, 0x79, 0xd2, 0xfb, 0x2a, 0x5f, 0x6e, 0x4d, 0x38, 0x06, 0x4f, 0xd2, 0x8a,
+	0x67, 0x9c, 0x84, 0x8a, 0x24, 0x13, 0x71, 0xff, 0x50, 0xe7, 0xec, 0xec, 0xd4, 0x90, 0x89, 0x38,
+	0xf0, 0xc1, 0x7b, 0xdc, 0xd0, 0x86, 0x0c, 0x2f, 0xa4, 0x3c, 0x18, 0x83, 0xbb, 0x64, 0xdb, 0xbb,
+	0x75, 0xca, 0x54, 0x48, 0x5f, 0x56, 0x4f, 0xc7, 0xee, 0x19, 0x9c, 0x83, 0x57, 0xd9, 0x5e, 0xb2,
+	0x98, 0x29, 0xc2, 0x21, 0x1c, 0x27, 0x6c, 0x1b, 0xbd, 0x17, 0x52, 0x69, 0x6e, 0x25, 0xa5, 0x25,
+	0xb8, 0x01, 0xef, 0x95, 0x0b, 0xc1, 0xc5, 0x87, 0x79, 0x53, 0x11, 0xf0, 0xc8, 0x5c, 0x68, 0x71,
+	0xe5, 0x8d, 0x89, 0x3b, 0x73, 0xad, 0x62, 0x61, 0xb5, 0x9b, 0xfd, 0x38, 0xe0, 0x95, 0x5a, 0xf9,
+	0x69, 0xf1, 0x0a, 0x5c, 0x2b, 0x33, 0xda, 0xe7, 0x06, 0x3d, 0x3d, 0xec, 0x55, 0xaa, 0xe1, 0x1c,
+	0x5a, 0x55, 0x5a, 0xf4, 0xb5, 0xc5, 0xea, 0x38, 0xe8, 0xfd, 0x51, 0x4c, 0x9d, 0xa0, 0x86, 0x0b,
+	0x38, 0x59, 0xa6, 0x82, 0xab, 0x54, 0xfe, 0xfb, 0xec, 0xa5, 0xf3, 0xd6, 0xd4, 0xbf, 0xda, 0xfc,
+	0x77, 0x00, 0x37, 0x12, 0x9d, 0x5d, 0x97, 0x02, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -29,6 +29,38 @@ type ReserveInfo struct {
 	ClaimedInCurrentRound *big.Int
 }
 
+// SenderEventType is the name of the TicketBroker event of a SenderEvent
+type SenderEventType string
+
+const (
+	SenderEventDepositFunded         SenderEventType = "DepositFunded"
+	SenderEventReserveFunded         SenderEventType = "ReserveFunded"
+	SenderEventWithdrawal            SenderEventType = "Withdrawal"
+	SenderEventWinningTicketTransfer SenderEventType = "WinningTicketTransfer"
+	SenderEventUnlock                SenderEventType = "Unlock"
+	SenderEventUnlockCancelled       SenderEventType = "UnlockCancelled"
+)
+
+// SenderEvent is a TicketBroker event that changed the deposit, the reserve or the unlock
+// period of a sender
+type SenderEvent struct {
+	Type   SenderEventType
+	Sender ethcommon.Address
+
+	// Amount is the amount funded or transferred, and the deposit plus the reserve withdrawn
+	Amount *big.Int
+	// Recipient is the recipient of a WinningTicketTransfer
+	Recipient ethcommon.Address
+	// EndRound is the round at which the unlock period of an Unlock ends
+	EndRound *big.Int
+
+	// Removed is set if the log of the event was removed by a chain reorg, in which case the
+	// event was notified before and its changes should be undone
+	Removed     bool
+	BlockNumber uint64
+	TxHash      ethcommon.Hash
+}
+
 // Broker is an interface which serves as an abstraction over an on-chain
 // smart contract that handles the administrative tasks in a probabilistic micropayment protocol
 // including processing deposits and pay outs
@@ -57,9 +89,18 @@ type Broker interface {
 	// the broker pays the ticket's face value to the ticket's recipient
 	RedeemWinningTicket(ticket *Ticket, sig []byte, recipientRand *big.Int) (*types.Transaction, error)
 
+	// BatchRedeemWinningTickets submits multiple tickets to be redeemed with a single transaction.
+	// The transaction does not fail if some of the tickets can't be redeemed, so the caller
+	// should check which tickets are used once the transaction confirms
+	BatchRedeemWinningTickets(tickets []*Ticket, sigs [][]byte, recipientRands []*big.Int) (*types.Transaction, error)
+
 	// IsUsedTicket checks if a ticket has been used
 	IsUsedTicket(ticket *Ticket) (bool, error)
 
+	// ReserveAlloc returns the amount of the reserve of a reserve holder that a claimant can
+	// still claim in the current round, which is 0 if the claimant is not an active orchestrator
+	ReserveAlloc(reserveHolder ethcommon.Address, claimant ethcommon.Address) (*big.Int, error)
+
 	// CheckTx waits for a transaction to confirm on-chain and returns an error
 	// if the transaction failed
 	CheckTx(tx *types.Transaction) error
@@ -0,0 +1,108 @@
+package pm
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrorClass is how a payment error affects the payments that follow it
+type ErrorClass int
+
+const (
+	// ErrorUnknown is the class of errors that the classifier doesn't know about
+	ErrorUnknown ErrorClass = iota
+	// ErrorRetryable errors only fail the payment they are returned for, which can be sent
+	// again, e.g. with a new nonce or once ticket params are refreshed
+	ErrorRetryable
+	// ErrorFatal errors fail every payment of the sender to the recipient until the
+	// cause of the error is fixed, e.g. the sender funds its reserve
+	ErrorFatal
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorRetryable:
+		return "retryable"
+	case ErrorFatal:
+		return "fatal"
+	}
+	return "unknown"
+}
+
+// ErrorClassifier classifies the errors returned by senders and recipients, so that callers
+// don't have to match error messages. Errors are classified by the first error in their
+// chain that is registered with the classifier, and FatalReceiveErr and ErrSenderValidation
+// errors are always fatal
+type ErrorClassifier struct {
+	mu      sync.RWMutex
+	classes []errorClass
+}
+
+type errorClass struct {
+	err   error
+	class ErrorClass
+}
+
+// NewErrorClassifier creates a new ErrorClassifier that knows about the errors of pm
+func NewErrorClassifier() *ErrorClassifier {
+	c := &ErrorClassifier{}
+	c.Register(ErrTicketParamsExpired, ErrorRetryable)
+	c.Register(ErrTicketUsed, ErrorRetryable)
+	c.Register(ErrRecipientRandExpired, ErrorRetryable)
+	c.Register(errInvalidTicketSignature, ErrorRetryable)
+	c.Register(errInvalidCreationRoundBlockHash, ErrorRetryable)
+	c.Register(ErrInsufficientSenderReserve, ErrorFatal)
+	c.Register(errSenderBlacklisted, ErrorFatal)
+	c.Register(errTicketExpired, ErrorFatal)
+	return c
+}
+
+// Errors is the classifier of the errors of pm. Callers can register their own errors with it
+var Errors = NewErrorClassifier()
+
+// Register sets the class of err and of the errors wrapping it
+func (c *ErrorClassifier) Register(err error, class ErrorClass) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.classes {
+		if c.classes[i].err == err {
+			c.classes[i].class = class
+			return
+		}
+	}
+	c.classes = append(c.classes, errorClass{err, class})
+}
+
+// Classify returns the class of err
+func (c *ErrorClassifier) Classify(err error) ErrorClass {
+	if err == nil {
+		return ErrorUnknown
+	}
+
+	var fatalErr *FatalReceiveErr
+	var validationErr ErrSenderValidation
+	if errors.As(err, &fatalErr) || errors.As(err, &validationErr) {
+		return ErrorFatal
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, ec := range c.classes {
+		if errors.Is(err, ec.err) {
+			return ec.class
+		}
+	}
+	return ErrorUnknown
+}
+
+// IsRetryable returns true if err only fails the payment it is returned for
+func (c *ErrorClassifier) IsRetryable(err error) bool {
+	return c.Classify(err) == ErrorRetryable
+}
+
+// IsFatal returns true if err fails every payment until its cause is fixed
+func (c *ErrorClassifier) IsFatal(err error) bool {
+	return c.Classify(err) == ErrorFatal
+}
@@ -0,0 +1,64 @@
+package pm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorClassifier_Classify(t *testing.T) {
+	assert := assert.New(t)
+	c := NewErrorClassifier()
+
+	assert.Equal(ErrorUnknown, c.Classify(nil))
+	assert.Equal(ErrorUnknown, c.Classify(errors.New("foo")))
+
+	assert.True(c.IsRetryable(ErrTicketParamsExpired))
+	assert.True(c.IsRetryable(ErrTicketUsed))
+	assert.True(c.IsRetryable(errInvalidTicketSignature))
+	assert.True(c.IsFatal(ErrInsufficientSenderReserve))
+	assert.True(c.IsFatal(errSenderBlacklisted))
+
+	// Wrapped errors are classified as the errors they wrap
+	assert.True(c.IsRetryable(fmt.Errorf("%w sender=foo", ErrTicketUsed)))
+	assert.True(c.IsFatal(fmt.Errorf("payment failed: %w", ErrInsufficientSenderReserve)))
+
+	// Fatal receive and sender validation errors are fatal whatever they wrap
+	assert.True(c.IsFatal(NewFatalReceiveErr(errors.New("foo"))))
+	assert.True(c.IsFatal(NewFatalReceiveErr(ErrTicketParamsExpired)))
+	assert.True(c.IsFatal(ErrSenderValidation{errors.New("no sender reserve")}))
+	assert.True(c.IsFatal(fmt.Errorf("stop: %w", ErrSenderValidation{errors.New("no sender deposit")})))
+	assert.False(c.IsRetryable(ErrSenderValidation{}))
+
+	// Errors in a fatal receive error can still be matched
+	assert.True(errors.Is(NewFatalReceiveErr(ErrInsufficientSenderReserve), ErrInsufficientSenderReserve))
+}
+
+func TestErrorClassifier_Register(t *testing.T) {
+	assert := assert.New(t)
+	c := NewErrorClassifier()
+
+	errFoo := errors.New("foo")
+	c.Register(errFoo, ErrorRetryable)
+	assert.True(c.IsRetryable(errFoo))
+	assert.True(c.IsRetryable(fmt.Errorf("bar: %w", errFoo)))
+
+	// Errors can be reclassified
+	c.Register(errFoo, ErrorFatal)
+	assert.True(c.IsFatal(errFoo))
+	c.Register(ErrTicketParamsExpired, ErrorFatal)
+	assert.True(c.IsFatal(ErrTicketParamsExpired))
+
+	// Classifiers don't share their errors
+	assert.Equal(ErrorUnknown, Errors.Classify(errFoo))
+	assert.True(Errors.IsRetryable(ErrTicketParamsExpired))
+}
+
+func TestErrorClass_String(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("unknown", ErrorUnknown.String())
+	assert.Equal("retryable", ErrorRetryable.String())
+	assert.Equal("fatal", ErrorFatal.String())
+}
@@ -0,0 +1,62 @@
+package pm
+
+import (
+	"math/big"
+	"sync"
+)
+
+// Metrics is notified of the payment events of the recipient and of its sender monitor, so
+// that the health of payments can be graphed by a monitoring backend that pm doesn't depend
+// on. Senders are identified by their hex encoded address
+type Metrics interface {
+	// TicketReceived is called for every ticket accepted by the recipient
+	TicketReceived(sender string, ev *big.Rat)
+	// TicketWon is called for every winning ticket received
+	TicketWon(sender string, faceValue *big.Int)
+	// RedemptionSubmitted is called when the transaction redeeming a winning ticket is sent
+	RedemptionSubmitted(sender string, faceValue *big.Int)
+	// RedemptionSucceeded is called when a winning ticket is redeemed
+	RedemptionSucceeded(sender string, faceValue *big.Int)
+	// RedemptionFailed is called when an attempt to redeem a winning ticket fails
+	RedemptionFailed(sender string, err error)
+	// RedemptionAbandoned is called when a winning ticket is given up on after failing the
+	// max redemption attempts
+	RedemptionAbandoned(sender string)
+	// PendingFloat is called with the face value of the winning tickets of a sender that
+	// are not redeemed yet, whenever it changes
+	PendingFloat(sender string, pending *big.Int)
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) TicketReceived(sender string, ev *big.Rat)             {}
+func (noopMetrics) TicketWon(sender string, faceValue *big.Int)           {}
+func (noopMetrics) RedemptionSubmitted(sender string, faceValue *big.Int) {}
+func (noopMetrics) RedemptionSucceeded(sender string, faceValue *big.Int) {}
+func (noopMetrics) RedemptionFailed(sender string, err error)             {}
+func (noopMetrics) RedemptionAbandoned(sender string)                     {}
+func (noopMetrics) PendingFloat(sender string, pending *big.Int)          {}
+
+var (
+	metricsMu sync.RWMutex
+	metrics   Metrics = noopMetrics{}
+)
+
+// SetMetrics sets the Metrics notified of the payment events of pm. Events are dropped if m
+// is nil, which is the default
+func SetMetrics(m Metrics) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	if m == nil {
+		m = noopMetrics{}
+	}
+	metrics = m
+}
+
+func getMetrics() Metrics {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+
+	return metrics
+}
@@ -0,0 +1,145 @@
+package pm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubMetrics struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (m *stubMetrics) record(format string, args ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, fmt.Sprintf(format, args...))
+}
+
+func (m *stubMetrics) recorded() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.events...)
+}
+
+func (m *stubMetrics) TicketReceived(sender string, ev *big.Rat) {
+	m.record("received %v %v", sender, ev.RatString())
+}
+
+func (m *stubMetrics) TicketWon(sender string, faceValue *big.Int) {
+	m.record("won %v %v", sender, faceValue)
+}
+
+func (m *stubMetrics) RedemptionSubmitted(sender string, faceValue *big.Int) {
+	m.record("submitted %v %v", sender, faceValue)
+}
+
+func (m *stubMetrics) RedemptionSucceeded(sender string, faceValue *big.Int) {
+	m.record("succeeded %v %v", sender, faceValue)
+}
+
+func (m *stubMetrics) RedemptionFailed(sender string, err error) {
+	m.record("failed %v %v", sender, err)
+}
+
+func (m *stubMetrics) RedemptionAbandoned(sender string) {
+	m.record("abandoned %v", sender)
+}
+
+func (m *stubMetrics) PendingFloat(sender string, pending *big.Int) {
+	m.record("pending %v %v", sender, pending)
+}
+
+func TestMetrics_Recipient(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	m := &stubMetrics{}
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	sender, b, v, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
+	r := newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
+	params := ticketParamsOrFatal(t, r, sender)
+	ticket := newTicket(sender, params, 1)
+
+	_, _, err := r.ReceiveTicket(ticket, sig, params.Seed)
+	require.Nil(err)
+
+	v.SetIsWinningTicket(true)
+	_, won, err := r.ReceiveTicket(newTicket(sender, params, 2), sig, params.Seed)
+	require.Nil(err)
+	require.True(won)
+
+	// Refused tickets are not received
+	v.SetIsWinningTicket(false)
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 2), sig, params.Seed)
+	require.NotNil(err)
+
+	ev := ticket.EV().RatString()
+	assert.Equal([]string{
+		fmt.Sprintf("received %v %v", sender.String(), ev),
+		fmt.Sprintf("won %v %v", sender.String(), params.FaceValue),
+		fmt.Sprintf("received %v %v", sender.String(), ev),
+	}, m.recorded())
+}
+
+func TestMetrics_SenderMonitor(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	m := &stubMetrics{}
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	cfg, b, smgr, tm := localSenderMonitorFixture()
+	addr := RandAddress()
+	smgr.info[addr] = &SenderInfo{
+		Deposit:       big.NewInt(500),
+		WithdrawRound: big.NewInt(0),
+		Reserve: &ReserveInfo{
+			FundsRemaining:        big.NewInt(1000),
+			ClaimedInCurrentRound: big.NewInt(0),
+		},
+	}
+	smgr.claimedReserve[addr] = big.NewInt(100)
+	sm := NewSenderMonitor(cfg, b, smgr, tm, newStubTicketStore())
+
+	ticket := defaultSignedTicket(addr, uint32(0))
+	sm.subFloat(addr, ticket.FaceValue)
+	_, err := sm.redeemWinningTicket(ticket)
+	require.Nil(err)
+	require.Nil(sm.addFloat(addr, ticket.FaceValue))
+
+	b.redeemShouldFail = true
+	_, err = sm.redeemWinningTicket(defaultSignedTicket(addr, uint32(1)))
+	require.NotNil(err)
+
+	b.redeemShouldFail = false
+	b.checkTxErr = errors.New("checktx error")
+	_, err = sm.redeemWinningTicket(defaultSignedTicket(addr, uint32(2)))
+	require.NotNil(err)
+
+	sm.redemptionFailed(ticket, 3, err)
+
+	sender := addr.String()
+	assert.Equal([]string{
+		fmt.Sprintf("pending %v %v", sender, ticket.FaceValue),
+		fmt.Sprintf("submitted %v %v", sender, ticket.FaceValue),
+		fmt.Sprintf("succeeded %v %v", sender, ticket.FaceValue),
+		fmt.Sprintf("pending %v 0", sender),
+		fmt.Sprintf("failed %v stub broker redeem error", sender),
+		fmt.Sprintf("submitted %v %v", sender, ticket.FaceValue),
+		fmt.Sprintf("failed %v checktx error", sender),
+		fmt.Sprintf("abandoned %v", sender),
+	}, m.recorded())
+}
+
+func TestSetMetrics_Nil(t *testing.T) {
+	SetMetrics(nil)
+	assert.Equal(t, noopMetrics{}, getMetrics())
+}
@@ -0,0 +1,48 @@
+package pm
+
+import "math/big"
+
+// TicketResult is the outcome of the receipt of one of the tickets of a payment
+type TicketResult struct {
+	Ticket *Ticket
+	Sig    []byte
+
+	// SessionID is set if the ticket won
+	SessionID string
+	Won       bool
+	// Err is set if the ticket is refused
+	Err error
+}
+
+// ticketReceiver receives the tickets of payments one at a time
+type ticketReceiver interface {
+	ReceiveTicket(ticket *Ticket, sig []byte, seed *big.Int) (sessionID string, won bool, err error)
+}
+
+// receivePayment receives the tickets of a batch one at a time, so that a refused ticket doesn't
+// refuse the tickets that follow it unless its error is fatal, in which case the tickets that
+// follow are refused with the same error without being received
+func receivePayment(r ticketReceiver, batch *TicketBatch, seed *big.Int) []*TicketResult {
+	results := make([]*TicketResult, len(batch.SenderParams))
+
+	var fatalErr error
+	for i, sp := range batch.SenderParams {
+		res := &TicketResult{
+			Ticket: NewTicket(batch.TicketParams, batch.TicketExpirationParams, batch.Sender, sp.SenderNonce),
+			Sig:    sp.Sig,
+		}
+		results[i] = res
+
+		if fatalErr != nil {
+			res.Err = fatalErr
+			continue
+		}
+
+		res.SessionID, res.Won, res.Err = r.ReceiveTicket(res.Ticket, res.Sig, seed)
+		if Errors.IsFatal(res.Err) {
+			fatalErr = res.Err
+		}
+	}
+
+	return results
+}
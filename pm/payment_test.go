@@ -0,0 +1,80 @@
+package pm
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceivePayment(t *testing.T) {
+	assert := assert.New(t)
+
+	sender, b, v, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
+	r := newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
+	params := ticketParamsOrFatal(t, r, sender)
+
+	batch := &TicketBatch{
+		TicketParams:           params,
+		TicketExpirationParams: params.ExpirationParams,
+		Sender:                 sender,
+		SenderParams: []*TicketSenderParams{
+			{SenderNonce: 1, Sig: sig},
+			{SenderNonce: 2, Sig: sig},
+			// Test a replayed nonce is refused without refusing the tickets that follow
+			{SenderNonce: 2, Sig: sig},
+			{SenderNonce: 3, Sig: sig},
+		},
+	}
+
+	results := r.ReceivePayment(batch, params.Seed)
+	assert.Len(results, 4)
+	for i, res := range results {
+		assert.Equal(batch.SenderParams[i].SenderNonce, res.Ticket.SenderNonce)
+		assert.Equal(sig, res.Sig)
+		if i == 2 {
+			assert.Contains(res.Err.Error(), "invalid ticket senderNonce")
+			continue
+		}
+		assert.Nil(res.Err)
+	}
+}
+
+type stubTicketReceiver struct {
+	errs   map[uint32]error
+	nonces []uint32
+}
+
+func (r *stubTicketReceiver) ReceiveTicket(ticket *Ticket, sig []byte, seed *big.Int) (string, bool, error) {
+	r.nonces = append(r.nonces, ticket.SenderNonce)
+	return "", false, r.errs[ticket.SenderNonce]
+}
+
+func TestReceivePayment_FatalError(t *testing.T) {
+	assert := assert.New(t)
+
+	fatalErr := NewFatalReceiveErr(errors.New("fatal error"))
+	r := &stubTicketReceiver{
+		errs: map[uint32]error{
+			1: errors.New("non fatal error"),
+			2: fatalErr,
+		},
+	}
+	batch := &TicketBatch{
+		TicketParams:           &TicketParams{},
+		TicketExpirationParams: &TicketExpirationParams{},
+		SenderParams: []*TicketSenderParams{
+			{SenderNonce: 0}, {SenderNonce: 1}, {SenderNonce: 2}, {SenderNonce: 3},
+		},
+	}
+
+	results := receivePayment(r, batch, big.NewInt(0))
+	assert.Equal([]uint32{0, 1, 2}, r.nonces)
+	assert.Nil(results[0].Err)
+	assert.EqualError(results[1].Err, "non fatal error")
+	assert.Equal(fatalErr, results[2].Err)
+	// The tickets after a fatal error are refused with the same error
+	assert.Equal(fatalErr, results[3].Err)
+	assert.Equal(uint32(3), results[3].Ticket.SenderNonce)
+}
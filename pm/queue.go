@@ -1,11 +1,14 @@
 package pm
 
 import (
+	"math"
 	"math/big"
+	"time"
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/event"
 	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/monitor"
 )
 
 // RedeemableEmitter is an interface that describes methods for
@@ -18,7 +21,12 @@ type RedeemableEmitter interface {
 
 type redemption struct {
 	SignedTicket *SignedTicket
-	resCh        chan struct {
+	// batch holds the tickets of a batch redemption, in which case SignedTicket is nil
+	batch []*SignedTicket
+	// unredeemed are the errors of the tickets of the batch that the transaction didn't
+	// redeem, which the consumer sets before it sends the result
+	unredeemed map[*SignedTicket]error
+	resCh      chan struct {
 		txHash ethcommon.Hash
 		err    error
 	}
@@ -32,8 +40,9 @@ type redemption struct {
 //
 // Based off of: https://github.com/lightningnetwork/lnd/blob/master/htlcswitch/queue.go
 type ticketQueue struct {
-	// blockSub returns a subscription to receive the last seen block number
-	blockSub func(chan<- *big.Int) event.Subscription
+	// tm provides the last seen block numbers, on which tickets are redeemed, and
+	// the last initialized round, after which tickets expire
+	tm TimeManager
 
 	// redeemable is a channel that a queue consumer will receive
 	// redeemable tickets on as a sender's max float becomes
@@ -43,16 +52,42 @@ type ticketQueue struct {
 	sender ethcommon.Address
 	store  TicketStore
 
+	// batchSize is the number of redeemable tickets that are redeemed together, and
+	// batchInterval the longest time that redeemable tickets wait for a full batch.
+	// Tickets are redeemed one at a time if batchSize is less than 2
+	batchSize     int
+	batchInterval time.Duration
+	// batchStart is when the tickets waiting for a full batch became redeemable
+	batchStart time.Time
+
+	// retryBackoff is how long a ticket waits before its redemption is retried after the
+	// first failed attempt, and it doubles with every failed attempt up to maxRetryBackoff.
+	// Tickets are removed from the queue once maxAttempts redemption attempts failed, or
+	// retried until they expire if maxAttempts is 0
+	retryBackoff    time.Duration
+	maxRetryBackoff time.Duration
+	maxAttempts     int
+
+	// onRemove is called, if set, for every ticket that leaves the queue because it is
+	// redeemed, expired or failed
+	onRemove func(ticket *SignedTicket)
+	// onFail is called, if set, for every ticket that leaves the queue because its
+	// redemption failed maxAttempts times, with the error of the last attempt
+	onFail func(ticket *SignedTicket, attempts int, err error)
+
 	quit chan struct{}
+	// stopped is closed when the queue loop exits
+	stopped chan struct{}
 }
 
-func newTicketQueue(store TicketStore, sender ethcommon.Address, blockSub func(chan<- *big.Int) event.Subscription) *ticketQueue {
+func newTicketQueue(store TicketStore, sender ethcommon.Address, tm TimeManager) *ticketQueue {
 	return &ticketQueue{
-		blockSub:   blockSub,
+		tm:         tm,
 		redeemable: make(chan *redemption),
 		store:      store,
 		sender:     sender,
 		quit:       make(chan struct{}),
+		stopped:    make(chan struct{}),
 	}
 }
 
@@ -91,9 +126,11 @@ func (q *ticketQueue) Length() (int, error) {
 // is sufficient to cover the face value of the ticket at the head of the queue. If the max float is sufficient, we pop
 // the ticket at the head of the queue and send it into q.redeemable which an external listener can use to receive redeemable tickets
 func (q *ticketQueue) startQueueLoop() {
+	defer monitor.RecoverAndReport()
 	blockNums := make(chan *big.Int, 10)
-	sub := q.blockSub(blockNums)
+	sub := q.tm.SubscribeBlocks(blockNums)
 	defer sub.Unsubscribe()
+	defer close(q.stopped)
 
 ticketLoop:
 	for {
@@ -103,6 +140,13 @@ ticketLoop:
 				glog.Errorf("Block subscription error err=%v", err)
 			}
 		case latestBlock := <-blockNums:
+			if q.batchSize > 1 {
+				if !q.redeemBatch(latestBlock) {
+					return
+				}
+				continue
+			}
+
 			numTickets, err := q.Length()
 			if err != nil {
 				glog.Errorf("Error getting queue length err=%v", err)
@@ -117,6 +161,9 @@ ticketLoop:
 				if nextTicket == nil {
 					continue ticketLoop
 				}
+				if q.removeIfExpired(nextTicket) {
+					continue
+				}
 
 				if nextTicket.ParamsExpirationBlock.Cmp(latestBlock) <= 0 {
 					resCh := make(chan struct {
@@ -124,20 +171,28 @@ ticketLoop:
 						err    error
 					})
 
-					q.redeemable <- &redemption{nextTicket, resCh}
+					select {
+					case q.redeemable <- &redemption{SignedTicket: nextTicket, resCh: resCh}:
+					case <-q.quit:
+						return
+					}
 					select {
 					case res := <-resCh:
 						// after receiving the response we can close the channel so it can be GC'd
 						close(resCh)
 						if res.err != nil {
 							glog.Errorf("Error redeeming err=%v", res.err)
-							continue
+							// Failed tickets wait for their backoff, so the redemption
+							// is not retried before the next block
+							q.failed(nextTicket, res.err)
+							continue ticketLoop
 						}
 						err := q.store.MarkWinningTicketRedeemed(nextTicket, res.txHash)
 						if err != nil {
 							glog.Error(err)
 							continue
 						}
+						q.removed(nextTicket)
 					case <-q.quit:
 						return
 					}
@@ -148,3 +203,146 @@ ticketLoop:
 		}
 	}
 }
+
+// redeemBatch sends the redeemable tickets of the queue to be redeemed together once there
+// are batchSize of them, or once they waited batchInterval for a full batch. Returns false
+// if the queue is stopped
+func (q *ticketQueue) redeemBatch(latestBlock *big.Int) bool {
+	tickets, err := q.store.SelectEarliestWinningTickets(q.sender, latestBlock, q.batchSize)
+	if err != nil {
+		glog.Errorf("Unable select earliest winning tickets err=%v", err)
+		return true
+	}
+	// Tickets in the last round of their validity period don't wait for a full batch
+	round := q.tm.LastInitializedRound().Int64()
+	var batch []*SignedTicket
+	expiring := false
+	for _, ticket := range tickets {
+		if q.removeIfExpired(ticket) {
+			continue
+		}
+		batch = append(batch, ticket)
+		expiring = expiring || ticket.expirationRound()-1 <= round
+	}
+	if len(batch) == 0 {
+		q.batchStart = time.Time{}
+		return true
+	}
+	if q.batchStart.IsZero() {
+		q.batchStart = time.Now()
+	}
+	if len(batch) < q.batchSize && time.Since(q.batchStart) < q.batchInterval && !expiring {
+		return true
+	}
+
+	resCh := make(chan struct {
+		txHash ethcommon.Hash
+		err    error
+	})
+	red := &redemption{batch: batch, resCh: resCh}
+	select {
+	case q.redeemable <- red:
+	case <-q.quit:
+		return false
+	}
+	select {
+	case res := <-resCh:
+		close(resCh)
+		if res.err != nil {
+			glog.Errorf("Error redeeming batch sender=%v tickets=%v err=%v", q.sender.Hex(), len(batch), res.err)
+			for _, ticket := range batch {
+				q.failed(ticket, res.err)
+			}
+			return true
+		}
+		// Only the tickets that the broker redeemed are marked, the others are retried
+		for _, ticket := range batch {
+			if err, ok := red.unredeemed[ticket]; ok {
+				q.failed(ticket, err)
+				continue
+			}
+			if err := q.store.MarkWinningTicketRedeemed(ticket, res.txHash); err != nil {
+				glog.Error(err)
+				continue
+			}
+			q.removed(ticket)
+		}
+		q.batchStart = time.Time{}
+	case <-q.quit:
+		return false
+	}
+	return true
+}
+
+// removeIfExpired removes a ticket from the store if its validity period is over, since the
+// broker would not redeem it anymore. Returns true if the ticket expired
+func (q *ticketQueue) removeIfExpired(ticket *SignedTicket) bool {
+	if ticket.expirationRound() > q.tm.LastInitializedRound().Int64() {
+		return false
+	}
+	glog.Errorf("Removing expired winning ticket sender=%v recipientRandHash=%x senderNonce=%v creationRound=%v", q.sender.Hex(), ticket.RecipientRandHash, ticket.SenderNonce, ticket.CreationRound)
+	if err := q.store.RemoveWinningTicket(ticket); err != nil {
+		glog.Error(err)
+		return true
+	}
+	q.removed(ticket)
+	return true
+}
+
+// failed records a failed redemption of a ticket. The redemption is retried once the
+// backoff for the number of failed attempts passed, unless the ticket reached the max
+// attempts, in which case it is removed from the queue
+func (q *ticketQueue) failed(ticket *SignedTicket, err error) {
+	// Redemptions that were not submitted because the monitor stopped did not fail
+	if err == errMonitorStopped {
+		return
+	}
+	// Redemptions waiting for an offline signer did not fail either, they are retried after
+	// the backoff of the next attempt so that the signer isn't sent a transaction every block
+	if err == ErrTxQueuedForSigning {
+		if err := q.store.MarkWinningTicketFailed(ticket, ticket.RedeemAttempts, time.Now().Add(q.backoff(ticket.RedeemAttempts+1))); err != nil {
+			glog.Error(err)
+		}
+		return
+	}
+
+	attempts := ticket.RedeemAttempts + 1
+	if q.maxAttempts > 0 && attempts >= q.maxAttempts {
+		glog.Errorf("Removing winning ticket after failed redemption attempts=%v sender=%v recipientRandHash=%x senderNonce=%v err=%v", attempts, q.sender.Hex(), ticket.RecipientRandHash, ticket.SenderNonce, err)
+		if err := q.store.RemoveWinningTicket(ticket); err != nil {
+			glog.Error(err)
+			return
+		}
+		q.removed(ticket)
+		if q.onFail != nil {
+			q.onFail(ticket, attempts, err)
+		}
+		return
+	}
+
+	if err := q.store.MarkWinningTicketFailed(ticket, attempts, time.Now().Add(q.backoff(attempts))); err != nil {
+		glog.Error(err)
+	}
+}
+
+// backoff returns how long a ticket waits before its redemption is retried after a number
+// of failed attempts
+func (q *ticketQueue) backoff(attempts int) time.Duration {
+	backoff := q.retryBackoff
+	for i := 1; i < attempts && backoff <= math.MaxInt64/2; i++ {
+		if q.maxRetryBackoff > 0 && backoff >= q.maxRetryBackoff {
+			break
+		}
+		backoff *= 2
+	}
+	if q.maxRetryBackoff > 0 && backoff > q.maxRetryBackoff {
+		return q.maxRetryBackoff
+	}
+	return backoff
+}
+
+func (q *ticketQueue) removed(ticket *SignedTicket) {
+	if q.onRemove != nil {
+		q.onRemove(ticket)
+	}
+}
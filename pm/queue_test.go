@@ -1,6 +1,7 @@
 package pm
 
 import (
+	"errors"
 	"math/big"
 	"sync"
 	"testing"
@@ -8,11 +9,12 @@ import (
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func defaultSignedTicket(sender ethcommon.Address, senderNonce uint32) *SignedTicket {
 	return &SignedTicket{
-		&Ticket{
+		Ticket: &Ticket{
 			Recipient:              RandAddress(),
 			Sender:                 sender,
 			FaceValue:              big.NewInt(50),
@@ -24,8 +26,8 @@ func defaultSignedTicket(sender ethcommon.Address, senderNonce uint32) *SignedTi
 			ParamsExpirationBlock:  big.NewInt(0),
 			PricePerPixel:          big.NewRat(1, 1),
 		},
-		RandBytes(32),
-		big.NewInt(7),
+		Sig:           RandBytes(32),
+		RecipientRand: big.NewInt(7),
 	}
 }
 
@@ -69,7 +71,7 @@ func TestTicketQueueLoop(t *testing.T) {
 	ts := newStubTicketStore()
 	tm := &stubTimeManager{}
 
-	q := newTicketQueue(ts, sender, tm.SubscribeBlocks)
+	q := newTicketQueue(ts, sender, tm)
 	q.Start()
 	defer q.Stop()
 
@@ -127,7 +129,7 @@ func TestTicketQueueLoopConcurrent(t *testing.T) {
 	ts := newStubTicketStore()
 	tm := &stubTimeManager{}
 
-	q := newTicketQueue(ts, sender, tm.SubscribeBlocks)
+	q := newTicketQueue(ts, sender, tm)
 	q.Start()
 	defer q.Stop()
 
@@ -183,6 +185,250 @@ func TestTicketQueueLoopConcurrent(t *testing.T) {
 	assert.Equal(0, qlen)
 }
 
+func TestTicketQueueLoop_Batch(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := RandAddress()
+	ts := newStubTicketStore()
+	tm := &stubTimeManager{}
+
+	q := newTicketQueue(ts, sender, tm)
+	q.batchSize = 3
+	q.batchInterval = time.Hour
+	q.Start()
+	defer q.Stop()
+
+	receive := func() *redemption {
+		select {
+		case red := <-q.Redeemable():
+			return red
+		case <-time.After(100 * time.Millisecond):
+			return nil
+		}
+	}
+	respond := func(red *redemption, err error) {
+		red.resCh <- struct {
+			txHash ethcommon.Hash
+			err    error
+		}{RandHash(), err}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// Tickets with non-expired params don't count towards a batch
+	nonExpTicket := defaultSignedTicket(sender, 0)
+	nonExpTicket.ParamsExpirationBlock = big.NewInt(100)
+	q.Add(nonExpTicket)
+	for i := 1; i < 3; i++ {
+		q.Add(defaultSignedTicket(sender, uint32(i)))
+	}
+	time.Sleep(20 * time.Millisecond)
+	tm.blockNumSink <- big.NewInt(1)
+	assert.Nil(receive())
+
+	// Full batches are redeemed together
+	q.Add(defaultSignedTicket(sender, 3))
+	tm.blockNumSink <- big.NewInt(2)
+	red := receive()
+	require.NotNil(red)
+	assert.Nil(red.SignedTicket)
+	require.Len(red.batch, 3)
+	for i, ticket := range red.batch {
+		assert.Equal(uint32(i+1), ticket.SenderNonce)
+	}
+	// Tickets of failed batches stay in the queue
+	respond(red, errors.New("redeem error"))
+	qlen, err := q.Length()
+	assert.Nil(err)
+	assert.Equal(4, qlen)
+	for _, ticket := range red.batch {
+		assert.Equal(1, ticket.RedeemAttempts)
+	}
+
+	// Tickets that the batch didn't redeem stay in the queue
+	q.retryBackoff = 0
+	tm.blockNumSink <- big.NewInt(3)
+	red = receive()
+	require.NotNil(red)
+	red.unredeemed = map[*SignedTicket]error{red.batch[1]: errors.New("ticket not redeemed by batch")}
+	respond(red, nil)
+	qlen, err = q.Length()
+	assert.Nil(err)
+	assert.Equal(2, qlen)
+	assert.Equal(2, red.batch[1].RedeemAttempts)
+
+	q.Add(defaultSignedTicket(sender, 5))
+	q.Add(defaultSignedTicket(sender, 6))
+	tm.blockNumSink <- big.NewInt(3)
+	red = receive()
+	require.NotNil(red)
+	require.Len(red.batch, 3)
+	assert.Equal(uint32(2), red.batch[0].SenderNonce)
+	respond(red, nil)
+	qlen, err = q.Length()
+	assert.Nil(err)
+	assert.Equal(1, qlen)
+
+	// Partial batches are redeemed once they waited for the batch interval
+	q.batchInterval = 50 * time.Millisecond
+	q.Add(defaultSignedTicket(sender, 4))
+	tm.blockNumSink <- big.NewInt(4)
+	assert.Nil(receive())
+	tm.blockNumSink <- big.NewInt(5)
+	red = receive()
+	require.NotNil(red)
+	require.Len(red.batch, 1)
+	assert.Equal(uint32(4), red.batch[0].SenderNonce)
+	respond(red, nil)
+
+	// The non-expired ticket is redeemed once its params expire and it waited for the batch interval
+	tm.blockNumSink <- big.NewInt(100)
+	assert.Nil(receive())
+	time.Sleep(50 * time.Millisecond)
+	tm.blockNumSink <- big.NewInt(101)
+	red = receive()
+	require.NotNil(red)
+	assert.Equal([]*SignedTicket{nonExpTicket}, red.batch)
+	respond(red, nil)
+	qlen, err = q.Length()
+	assert.Nil(err)
+	assert.Equal(0, qlen)
+}
+
+func TestTicketQueueLoop_Retry(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender := RandAddress()
+	ts := newStubTicketStore()
+	tm := &stubTimeManager{}
+
+	q := newTicketQueue(ts, sender, tm)
+	q.retryBackoff = 200 * time.Millisecond
+	q.maxRetryBackoff = time.Hour
+	q.maxAttempts = 3
+	var removed []*SignedTicket
+	q.onRemove = func(ticket *SignedTicket) { removed = append(removed, ticket) }
+	var failed *SignedTicket
+	var failedAttempts int
+	var failedErr error
+	q.onFail = func(ticket *SignedTicket, attempts int, err error) {
+		failed, failedAttempts, failedErr = ticket, attempts, err
+	}
+	q.Start()
+	defer q.Stop()
+
+	receive := func() *redemption {
+		select {
+		case red := <-q.Redeemable():
+			return red
+		case <-time.After(100 * time.Millisecond):
+			return nil
+		}
+	}
+	respond := func(red *redemption, err error) {
+		red.resCh <- struct {
+			txHash ethcommon.Hash
+			err    error
+		}{RandHash(), err}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	ticket0 := defaultSignedTicket(sender, 0)
+	ticket1 := defaultSignedTicket(sender, 1)
+	require.Nil(q.Add(ticket0))
+	require.Nil(q.Add(ticket1))
+	time.Sleep(20 * time.Millisecond)
+
+	// Failed redemptions are not retried in the same block
+	tm.blockNumSink <- big.NewInt(1)
+	red := receive()
+	require.NotNil(red)
+	assert.Equal(ticket0, red.SignedTicket)
+	respond(red, errors.New("redeem error"))
+	assert.Nil(receive())
+	assert.Equal(1, ticket0.RedeemAttempts)
+
+	// Tickets waiting for their backoff don't hold back the other tickets
+	tm.blockNumSink <- big.NewInt(2)
+	red = receive()
+	require.NotNil(red)
+	assert.Equal(ticket1, red.SignedTicket)
+	respond(red, nil)
+	assert.Equal([]*SignedTicket{ticket1}, removed)
+
+	// Failed tickets are retried once their backoff passed, and the backoff doubles
+	time.Sleep(100 * time.Millisecond)
+	tm.blockNumSink <- big.NewInt(3)
+	red = receive()
+	require.NotNil(red)
+	assert.Equal(ticket0, red.SignedTicket)
+	respond(red, errors.New("redeem error"))
+	assert.Equal(2, ticket0.RedeemAttempts)
+	time.Sleep(200 * time.Millisecond)
+	tm.blockNumSink <- big.NewInt(4)
+	assert.Nil(receive())
+
+	// Tickets are removed once they reached the max attempts
+	time.Sleep(150 * time.Millisecond)
+	tm.blockNumSink <- big.NewInt(5)
+	red = receive()
+	require.NotNil(red)
+	respond(red, errors.New("last redeem error"))
+	assert.Equal(ticket0, failed)
+	assert.Equal(3, failedAttempts)
+	assert.EqualError(failedErr, "last redeem error")
+	assert.Equal([]*SignedTicket{ticket1, ticket0}, removed)
+	assert.Empty(ts.tickets[sender][1:])
+	qlen, err := q.Length()
+	assert.Nil(err)
+	assert.Equal(0, qlen)
+
+	// Redemptions that are not submitted because the monitor stopped are not attempts
+	ticket2 := defaultSignedTicket(sender, 2)
+	require.Nil(q.Add(ticket2))
+	tm.blockNumSink <- big.NewInt(6)
+	red = receive()
+	require.NotNil(red)
+	respond(red, errMonitorStopped)
+	assert.Equal(0, ticket2.RedeemAttempts)
+
+	// Redemptions waiting for an offline signer are not attempts either
+	time.Sleep(150 * time.Millisecond)
+	tm.blockNumSink <- big.NewInt(7)
+	red = receive()
+	require.NotNil(red)
+	respond(red, ErrTxQueuedForSigning)
+	assert.Equal(0, ticket2.RedeemAttempts)
+	assert.Equal(ticket0, failed)
+	qlen, err = q.Length()
+	assert.Nil(err)
+	assert.Equal(1, qlen)
+}
+
+func TestTicketQueue_Backoff(t *testing.T) {
+	assert := assert.New(t)
+
+	q := newTicketQueue(newStubTicketStore(), RandAddress(), &stubTimeManager{})
+	assert.Equal(time.Duration(0), q.backoff(1))
+	assert.Equal(time.Duration(0), q.backoff(10))
+
+	q.retryBackoff = time.Minute
+	assert.Equal(time.Minute, q.backoff(1))
+	assert.Equal(2*time.Minute, q.backoff(2))
+	assert.Equal(8*time.Minute, q.backoff(4))
+
+	// Backoffs are capped by the max backoff
+	q.maxRetryBackoff = 5 * time.Minute
+	assert.Equal(4*time.Minute, q.backoff(3))
+	assert.Equal(5*time.Minute, q.backoff(4))
+	assert.Equal(5*time.Minute, q.backoff(1000))
+
+	// Backoffs without a max don't overflow
+	q.maxRetryBackoff = 0
+	assert.True(q.backoff(1000) > 0)
+}
+
 func TestTicketQueueConsumeBlockNums(t *testing.T) {
 	assert := assert.New(t)
 
@@ -190,7 +436,7 @@ func TestTicketQueueConsumeBlockNums(t *testing.T) {
 	ts := newStubTicketStore()
 	tm := &stubTimeManager{}
 
-	q := newTicketQueue(ts, sender, tm.SubscribeBlocks)
+	q := newTicketQueue(ts, sender, tm)
 	q.Start()
 	defer q.Stop()
 	time.Sleep(20 * time.Millisecond)
@@ -208,7 +454,7 @@ func TestTicketQueue_Add(t *testing.T) {
 	ts := newStubTicketStore()
 	tm := &stubTimeManager{}
 
-	q := newTicketQueue(ts, sender, tm.SubscribeBlocks)
+	q := newTicketQueue(ts, sender, tm)
 
 	ticket := defaultSignedTicket(sender, 0)
 
@@ -229,7 +475,7 @@ func TestTicketQueue_Length(t *testing.T) {
 	ts := newStubTicketStore()
 	tm := &stubTimeManager{}
 
-	q := newTicketQueue(ts, sender, tm.SubscribeBlocks)
+	q := newTicketQueue(ts, sender, tm)
 
 	ts.tickets[sender] = []*SignedTicket{defaultSignedTicket(sender, 0), defaultSignedTicket(sender, 1), defaultSignedTicket(sender, 2)}
 
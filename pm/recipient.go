@@ -1,22 +1,39 @@
 package pm
 
 import (
-	"crypto/hmac"
 	"crypto/rand"
-	"crypto/sha256"
+	"fmt"
 	"math/big"
 	"sync"
+	"time"
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
+
+	"github.com/livepeer/go-livepeer/monitor"
 )
 
 // ErrTicketParamsExpired is returned when ticket params have expired
 var ErrTicketParamsExpired = errors.New("TicketParams expired")
 
-var errInsufficientSenderReserve = errors.New("insufficient sender reserve")
+// ErrInsufficientSenderReserve is returned when the reserve of the sender can't cover the
+// tickets it sends
+var ErrInsufficientSenderReserve = errors.New("insufficient sender reserve")
+
+// ErrTicketUsed is returned when a ticket reuses the nonce of a ticket already received
+var ErrTicketUsed = errors.New("invalid ticket senderNonce")
+
+// ErrRecipientRandExpired is returned when a ticket uses a recipientRand that was used for
+// too many tickets or for too long, and that was rotated
+var ErrRecipientRandExpired = errors.New("recipientRand expired")
+
+var errInvalidPayoutSplit = errors.New("invalid ticket payout split")
+
+var errInvalidTicketVersion = errors.New("invalid ticket version")
+
+var errNonWinningTicket = errors.New("ticket did not win")
 
 // maxWinProb = 2^256 - 1
 var maxWinProb = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
@@ -29,6 +46,10 @@ type Recipient interface {
 	// ReceiveTicket validates and processes a received ticket
 	ReceiveTicket(ticket *Ticket, sig []byte, seed *big.Int) (sessionID string, won bool, err error)
 
+	// ReceivePayment validates and processes each of the tickets of a payment, and returns
+	// the result of each ticket in the order of the batch
+	ReceivePayment(batch *TicketBatch, seed *big.Int) []*TicketResult
+
 	// RedeemWinningTicket redeems a single winning ticket
 	RedeemWinningTicket(ticket *Ticket, sig []byte, seed *big.Int) error
 
@@ -39,8 +60,47 @@ type Recipient interface {
 	// TxCostMultiplier returns the tx cost multiplier for an address
 	TxCostMultiplier(sender ethcommon.Address) (*big.Rat, error)
 
-	// EV returns the recipients EV requirement for a ticket as configured on startup
-	EV() *big.Rat
+	// EV returns the recipients EV requirement for a ticket created at price, which is
+	// the EV configured on startup unless the ticket params are tuned
+	EV(price *big.Rat) *big.Rat
+
+	// ExportTickets returns the winning tickets of the recipient that are not yet redeemed
+	ExportTickets() ([]*SignedTicket, error)
+
+	// RemoveExportedTickets removes the exported tickets that another node imported, and
+	// returns the number of tickets removed
+	RemoveExportedTickets(tickets []*SignedTicket) (int, error)
+
+	// ImportTickets queues winning tickets exported by another recipient with the same
+	// address for redemption, and returns the number of tickets queued
+	ImportTickets(tickets []*SignedTicket) (int, error)
+
+	// SessionPayments returns a snapshot of the payments received for a session or nil if
+	// no payments were received for the session
+	SessionPayments(sessionID string) *SessionPayments
+
+	// ShouldRotateTicketParams returns true if the sender should replace the ticket params
+	// with the provided recipientRandHash before its tickets are refused
+	ShouldRotateTicketParams(recipientRandHash ethcommon.Hash) bool
+}
+
+// SessionPayments holds the payments received for a session, i.e. with the ticket params
+// identified by a recipientRandHash. It lets orchestrators check that the payments of a
+// session cover the work done for it
+type SessionPayments struct {
+	SessionID string
+	Sender    ethcommon.Address
+	// EV is the cumulative expected value of the tickets received
+	EV *big.Rat
+	// Tickets is the number of tickets received
+	Tickets int64
+	// WinningTickets is the number of winning tickets received
+	WinningTickets int64
+	// Redeemed is the face value of the winning tickets queued for redemption
+	Redeemed *big.Int
+
+	expirationBlock *big.Int
+	firstReceived   time.Time
 }
 
 // TicketParamsConfig contains config information for a recipient to determine
@@ -55,6 +115,43 @@ type TicketParamsConfig struct {
 	// TxCostMultiplier is the desired multiplier of the transaction
 	// cost for redemption
 	TxCostMultiplier int
+
+	// PayoutSplit is the optional split of the winnings of tickets with the operator
+	// of the transcoder pool of the recipient
+	PayoutSplit *PayoutSplit
+
+	// TicketVersion is the version of the tickets accepted by the recipient, which
+	// selects how they are hashed and signed
+	TicketVersion uint32
+
+	// Reputation tracks the violations of senders and refuses the tickets of blacklisted
+	// senders, may be nil
+	Reputation *SenderReputationTracker
+
+	// Unlocks refuses the tickets of senders that unlocked their deposit and reserve, may
+	// be nil
+	Unlocks *UnlockWatcher
+
+	// RecipientRandMaxTickets is the max number of tickets accepted with the same
+	// recipientRand, 0 for no limit
+	RecipientRandMaxTickets int
+
+	// RecipientRandTTL is the max time during which tickets are accepted with the same
+	// recipientRand after its first ticket, 0 for no limit
+	RecipientRandTTL time.Duration
+
+	// FreeTickets enables the free tier, in which free tickets are accepted without checking
+	// the deposit and reserve of their sender
+	FreeTickets bool
+
+	// Tuner computes the EV and the face value of tickets from the price of their ticket
+	// params and the transaction cost of redeeming them, in place of EV and TxCostMultiplier.
+	// EV is still the min balance required to transcode. May be nil
+	Tuner *TicketParamsTuner
+
+	// SeedSource derives the recipientRands of ticket params, may be nil to derive them from
+	// the secret of the recipient only
+	SeedSource SeedSource
 }
 
 // GasPriceMonitor defines methods for monitoring gas prices
@@ -80,6 +177,9 @@ type recipient struct {
 	}
 	senderNoncesLock sync.Mutex
 
+	sessions     map[string]*SessionPayments
+	sessionsLock sync.Mutex
+
 	cfg TicketParamsConfig
 
 	quit chan struct{}
@@ -115,8 +215,9 @@ func NewRecipientWithSecret(addr ethcommon.Address, broker Broker, val Validator
 			nonce           uint32
 			expirationBlock *big.Int
 		}),
-		cfg:  cfg,
-		quit: make(chan struct{}),
+		sessions: make(map[string]*SessionPayments),
+		cfg:      cfg,
+		quit:     make(chan struct{}),
 	}
 }
 
@@ -130,31 +231,89 @@ func (r *recipient) Stop() {
 	close(r.quit)
 }
 
+// ReceivePayment validates and processes each of the tickets of a payment
+func (r *recipient) ReceivePayment(batch *TicketBatch, seed *big.Int) []*TicketResult {
+	return receivePayment(r, batch, seed)
+}
+
 // ReceiveTicket validates and processes a received ticket
 func (r *recipient) ReceiveTicket(ticket *Ticket, sig []byte, seed *big.Int) (string, bool, error) {
-	recipientRand := r.rand(seed, ticket.Sender, ticket.FaceValue, ticket.WinProb, ticket.ParamsExpirationBlock, ticket.PricePerPixel, ticket.expirationParams())
+	if r.cfg.Reputation.IsBlacklisted(ticket.Sender) {
+		return "", false, &FatalReceiveErr{errSenderBlacklisted}
+	}
+
+	recipientRand, err := r.rand(seed, ticket.Sender, ticket.FaceValue, ticket.WinProb, ticket.ParamsExpirationBlock, ticket.PricePerPixel, ticket.expirationParams())
+	if err != nil {
+		return "", false, err
+	}
+
+	// Free tickets of the free tier can't draw from the funds of the sender, which doesn't
+	// need a deposit or a reserve to send them. They are validated like other tickets
+	// otherwise, so that their nonces and sessions are accounted for
+	free := r.cfg.FreeTickets && ticket.IsFree()
+
 	// If sender validation check fails, abort
-	if err := r.sm.ValidateSender(ticket.Sender); err != nil {
-		return "", false, &FatalReceiveErr{err}
+	if !free {
+		if err := r.sm.ValidateSender(ticket.Sender); err != nil {
+			return "", false, &FatalReceiveErr{err}
+		}
+		if err := r.cfg.Unlocks.ValidateSender(ticket.Sender); err != nil {
+			return "", false, &FatalReceiveErr{err}
+		}
+	}
+
+	// Tickets of another version are signed over another hash, so are refused before their
+	// signature is checked
+	if ticket.Version != r.cfg.TicketVersion {
+		return "", false, &FatalReceiveErr{errInvalidTicketVersion}
 	}
 
 	// If any of the basic ticket validity checks fail, abort
 	if err := r.val.ValidateTicket(r.addr, ticket, sig, recipientRand); err != nil {
-		if err.Error() == errInvalidTicketSignature.Error() {
+		if err == errInvalidTicketSignature {
+			r.cfg.Reputation.RecordViolation(ticket.Sender, ViolationInvalidSignature)
 			return "", false, err
 		}
 		return "", false, &FatalReceiveErr{err}
 	}
 
+	// Broadcasters that predate payout splits don't send the split back with their tickets,
+	// which are then split as advertised by the recipient
+	if ticket.PayoutSplit == nil {
+		ticket.PayoutSplit = r.cfg.PayoutSplit
+	} else if !ticket.PayoutSplit.Equal(r.cfg.PayoutSplit) {
+		return "", false, &FatalReceiveErr{errInvalidPayoutSplit}
+	}
+
+	// Tickets are refused once their recipientRand is rotated, which limits how much a
+	// sender learns about the recipientRand from the tickets of the session
+	if r.recipientRandExpired(ticket.RecipientRandHash) {
+		return "", false, ErrRecipientRandExpired
+	}
+
+	// Tickets are refused once the winning tickets of the sender that are not redeemed yet
+	// exhaust its max float, until they are redeemed
+	if !free {
+		maxFloat, err := r.sm.MaxFloat(ticket.Sender)
+		if err != nil {
+			return "", false, err
+		}
+		if ticket.FaceValue.Cmp(maxFloat) > 0 {
+			return "", false, &FatalReceiveErr{ErrInsufficientSenderReserve}
+		}
+	}
+
 	var sessionID string
 	var won bool
 
 	if r.val.IsWinningTicket(ticket, sig, recipientRand) {
 		sessionID = ticket.RecipientRandHash.Hex()
 		won = true
+		getMetrics().TicketWon(ticket.Sender.String(), ticket.FaceValue)
 	}
 
 	if err := r.updateSenderNonce(recipientRand, ticket); err != nil {
+		r.cfg.Reputation.RecordViolation(ticket.Sender, ViolationDoubleSpend)
 		return sessionID, won, err
 	}
 
@@ -164,26 +323,212 @@ func (r *recipient) ReceiveTicket(ticket *Ticket, sig []byte, seed *big.Int) (st
 		return sessionID, won, ErrTicketParamsExpired
 	}
 
+	r.recordPayment(ticket, won)
+	getMetrics().TicketReceived(ticket.Sender.String(), ticket.EV())
+
 	return sessionID, won, nil
 }
 
 // RedeemWinningTicket redeems a single winning ticket
 func (r *recipient) RedeemWinningTicket(ticket *Ticket, sig []byte, seed *big.Int) error {
-	recipientRand := r.rand(seed, ticket.Sender, ticket.FaceValue, ticket.WinProb, ticket.ParamsExpirationBlock, ticket.PricePerPixel, ticket.expirationParams())
-	return r.sm.QueueTicket(&SignedTicket{ticket, sig, recipientRand})
+	recipientRand, err := r.rand(seed, ticket.Sender, ticket.FaceValue, ticket.WinProb, ticket.ParamsExpirationBlock, ticket.PricePerPixel, ticket.expirationParams())
+	if err != nil {
+		return err
+	}
+	if err := r.sm.QueueTicket(&SignedTicket{Ticket: ticket, Sig: sig, RecipientRand: recipientRand}); err != nil {
+		return err
+	}
+
+	r.sessionsLock.Lock()
+	if session, ok := r.sessions[ticket.RecipientRandHash.Hex()]; ok {
+		session.Redeemed.Add(session.Redeemed, ticket.FaceValue)
+	}
+	r.sessionsLock.Unlock()
+
+	return nil
+}
+
+// SessionPayments returns a snapshot of the payments received for a session or nil if no
+// payments were received for the session. Sessions are forgotten once their ticket params
+// expire
+func (r *recipient) SessionPayments(sessionID string) *SessionPayments {
+	r.sessionsLock.Lock()
+	defer r.sessionsLock.Unlock()
+
+	session, ok := r.sessions[sessionID]
+	if !ok {
+		return nil
+	}
+	return &SessionPayments{
+		SessionID:      session.SessionID,
+		Sender:         session.Sender,
+		EV:             new(big.Rat).Set(session.EV),
+		Tickets:        session.Tickets,
+		WinningTickets: session.WinningTickets,
+		Redeemed:       new(big.Int).Set(session.Redeemed),
+	}
+}
+
+// ShouldRotateTicketParams returns true once the ticket params with the provided
+// recipientRandHash used half of the tickets or of the time that their recipientRand is
+// accepted for, so that the sender switches to new params while the tickets of payments in
+// flight are still accepted. The params of sessions with a winning ticket are rotated right
+// away, since redeeming the ticket reveals their recipientRand
+func (r *recipient) ShouldRotateTicketParams(recipientRandHash ethcommon.Hash) bool {
+	r.sessionsLock.Lock()
+	defer r.sessionsLock.Unlock()
+
+	session, ok := r.sessions[recipientRandHash.Hex()]
+	if !ok {
+		return false
+	}
+	if session.WinningTickets > 0 {
+		return true
+	}
+	if max := int64(r.cfg.RecipientRandMaxTickets); max > 0 && 2*session.Tickets >= max {
+		return true
+	}
+	return r.cfg.RecipientRandTTL > 0 && 2*time.Since(session.firstReceived) >= r.cfg.RecipientRandTTL
+}
+
+// recipientRandExpired returns true if the recipientRand of the ticket params with the
+// provided recipientRandHash was used for the max number of tickets or for longer than its TTL
+func (r *recipient) recipientRandExpired(recipientRandHash ethcommon.Hash) bool {
+	r.sessionsLock.Lock()
+	defer r.sessionsLock.Unlock()
+
+	session, ok := r.sessions[recipientRandHash.Hex()]
+	if !ok {
+		return false
+	}
+	if max := int64(r.cfg.RecipientRandMaxTickets); max > 0 && session.Tickets >= max {
+		return true
+	}
+	return r.cfg.RecipientRandTTL > 0 && time.Since(session.firstReceived) > r.cfg.RecipientRandTTL
+}
+
+// recordPayment adds a received ticket to the payments of its session
+func (r *recipient) recordPayment(ticket *Ticket, won bool) {
+	r.sessionsLock.Lock()
+	defer r.sessionsLock.Unlock()
+
+	sessionID := ticket.RecipientRandHash.Hex()
+	session, ok := r.sessions[sessionID]
+	if !ok {
+		session = &SessionPayments{
+			SessionID:       sessionID,
+			Sender:          ticket.Sender,
+			EV:              new(big.Rat),
+			Redeemed:        big.NewInt(0),
+			expirationBlock: ticket.ParamsExpirationBlock,
+			firstReceived:   time.Now(),
+		}
+		r.sessions[sessionID] = session
+	}
+
+	session.EV.Add(session.EV, ticket.EV())
+	session.Tickets++
+	if won {
+		session.WinningTickets++
+	}
+}
+
+// ExportTickets returns the winning tickets of the recipient that are not yet redeemed, along
+// with their signatures and recipientRands, so that another node can redeem them. The tickets
+// stay queued until the node that imported them confirms it with RemoveExportedTickets, so
+// that an export that doesn't reach the importer doesn't lose any tickets
+func (r *recipient) ExportTickets() ([]*SignedTicket, error) {
+	return r.sm.WinningTickets()
+}
+
+// RemoveExportedTickets removes the exported tickets that another node imported from the queue,
+// so that the recipient doesn't redeem them too. Tickets that are not queued anymore, e.g.
+// because they were redeemed or removed already, are skipped. Returns the number of tickets
+// removed
+func (r *recipient) RemoveExportedTickets(tickets []*SignedTicket) (int, error) {
+	queued, err := r.sm.WinningTickets()
+	if err != nil {
+		return 0, err
+	}
+	bySig := make(map[string]*SignedTicket)
+	for _, ticket := range queued {
+		bySig[string(ticket.Sig)] = ticket
+	}
+
+	var remove []*SignedTicket
+	for _, ticket := range tickets {
+		if ticket == nil {
+			continue
+		}
+		if queuedTicket, ok := bySig[string(ticket.Sig)]; ok {
+			remove = append(remove, queuedTicket)
+			delete(bySig, string(ticket.Sig))
+		}
+	}
+	if err := r.sm.RemoveWinningTickets(remove); err != nil {
+		return 0, err
+	}
+	return len(remove), nil
+}
+
+// ImportTickets queues winning tickets exported by another recipient with the same address
+// for redemption, e.g. when an orchestrator is moved to another machine. The tickets are
+// validated like received tickets before any of them is queued, except that their version
+// and payout split are the ones they were received with. Expired tickets are skipped since
+// they can't be redeemed anymore, and so are the tickets that are already stored, so that an
+// import can be retried. Returns the number of tickets queued
+func (r *recipient) ImportTickets(tickets []*SignedTicket) (int, error) {
+	var valid []*SignedTicket
+	for i, ticket := range tickets {
+		if ticket == nil || ticket.Ticket == nil || ticket.RecipientRand == nil || ticket.FaceValue == nil || ticket.WinProb == nil || ticket.ParamsExpirationBlock == nil {
+			return 0, fmt.Errorf("invalid ticket index=%v: missing ticket fields", i)
+		}
+		if err := r.val.ValidateTicket(r.addr, ticket.Ticket, ticket.Sig, ticket.RecipientRand); err != nil {
+			if err == errTicketExpired {
+				glog.Warningf("Skipping import of expired ticket sender=%v recipientRandHash=%x senderNonce=%v", ticket.Sender.Hex(), ticket.RecipientRandHash, ticket.SenderNonce)
+				continue
+			}
+			return 0, fmt.Errorf("invalid ticket index=%v: %w", i, err)
+		}
+		if !r.val.IsWinningTicket(ticket.Ticket, ticket.Sig, ticket.RecipientRand) {
+			return 0, fmt.Errorf("invalid ticket index=%v: %w", i, errNonWinningTicket)
+		}
+		valid = append(valid, ticket)
+	}
+
+	queued := 0
+	for _, ticket := range valid {
+		if err := r.sm.QueueTicket(ticket); err != nil {
+			if err == ErrTicketStored {
+				glog.Infof("Skipping import of stored ticket sender=%v recipientRandHash=%x senderNonce=%v", ticket.Sender.Hex(), ticket.RecipientRandHash, ticket.SenderNonce)
+				continue
+			}
+			return queued, err
+		}
+		queued++
+	}
+	return queued, nil
 }
 
 // TicketParams returns the recipient's currently accepted ticket parameters
 func (r *recipient) TicketParams(sender ethcommon.Address, price *big.Rat) (*TicketParams, error) {
+	if r.cfg.Reputation.IsBlacklisted(sender) {
+		return nil, errSenderBlacklisted
+	}
+
 	randBytes := RandBytes(32)
 
 	seed := new(big.Int).SetBytes(randBytes)
 
+	ev := r.ev(price)
 	faceValue := big.NewInt(0)
 	// If price is 0 face value, win prob and EV are 0 because no payments are required
 	if price.Num().Cmp(big.NewInt(0)) > 0 {
 		var err error
-		faceValue, err = r.faceValue(sender)
+		faceValue, err = r.faceValue(sender, ev)
+		if err == ErrInsufficientSenderReserve {
+			r.cfg.Reputation.RecordViolation(sender, ViolationInsufficientFunds)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -192,14 +537,17 @@ func (r *recipient) TicketParams(sender ethcommon.Address, price *big.Rat) (*Tic
 	lastBlock := r.tm.LastSeenBlock()
 	expirationBlock := new(big.Int).Add(lastBlock, paramsExpirationBlock)
 
-	winProb := r.winProb(faceValue)
+	winProb := calcWinProb(faceValue, ev)
 
 	ticketExpirationParams := &TicketExpirationParams{
 		CreationRound:          r.tm.LastInitializedRound().Int64(),
 		CreationRoundBlockHash: r.tm.LastInitializedBlockHash(),
 	}
 
-	recipientRand := r.rand(seed, sender, faceValue, winProb, expirationBlock, price, ticketExpirationParams)
+	recipientRand, err := r.rand(seed, sender, faceValue, winProb, expirationBlock, price, ticketExpirationParams)
+	if err != nil {
+		return nil, err
+	}
 	recipientRandHash := crypto.Keccak256Hash(ethcommon.LeftPadBytes(recipientRand.Bytes(), uint256Size))
 
 	return &TicketParams{
@@ -211,6 +559,8 @@ func (r *recipient) TicketParams(sender ethcommon.Address, price *big.Rat) (*Tic
 		ExpirationBlock:   expirationBlock,
 		PricePerPixel:     price,
 		ExpirationParams:  ticketExpirationParams,
+		PayoutSplit:       r.cfg.PayoutSplit,
+		Version:           r.cfg.TicketVersion,
 	}, nil
 }
 
@@ -221,9 +571,23 @@ func (r *recipient) txCost() *big.Int {
 	return new(big.Int).Mul(big.NewInt(int64(r.cfg.RedeemGas)), gasPrice)
 }
 
-func (r *recipient) faceValue(sender ethcommon.Address) (*big.Int, error) {
-	// faceValue = txCost * txCostMultiplier
-	faceValue := new(big.Int).Mul(r.txCost(), big.NewInt(int64(r.cfg.TxCostMultiplier)))
+// ev returns the EV of the tickets of ticket params created at price
+func (r *recipient) ev(price *big.Rat) *big.Int {
+	if r.cfg.Tuner != nil {
+		return r.cfg.Tuner.EV(price)
+	}
+	return r.cfg.EV
+}
+
+func (r *recipient) faceValue(sender ethcommon.Address, ev *big.Int) (*big.Int, error) {
+	var faceValue *big.Int
+	if r.cfg.Tuner != nil {
+		// faceValue = txCost / overhead
+		faceValue = r.cfg.Tuner.FaceValue(r.txCost())
+	} else {
+		// faceValue = txCost * txCostMultiplier
+		faceValue = new(big.Int).Mul(r.txCost(), big.NewInt(int64(r.cfg.TxCostMultiplier)))
+	}
 
 	// TODO: Consider setting faceValue to some value higher than
 	// EV in this case where the default faceValue < the desired EV.
@@ -231,8 +595,8 @@ func (r *recipient) faceValue(sender ethcommon.Address) (*big.Int, error) {
 	// desired EV in this case (which would result in winProb = 100%).
 	// In practice, EV should be smaller than the default faceValue
 	// so this shouldn't be a problem in most cases
-	if faceValue.Cmp(r.cfg.EV) < 0 {
-		faceValue = r.cfg.EV
+	if faceValue.Cmp(ev) < 0 {
+		faceValue = ev
 	}
 
 	// Fetch current max float for sender
@@ -242,10 +606,10 @@ func (r *recipient) faceValue(sender ethcommon.Address) (*big.Int, error) {
 	}
 
 	if faceValue.Cmp(maxFloat) > 0 {
-		if maxFloat.Cmp(r.cfg.EV) < 0 {
+		if maxFloat.Cmp(ev) < 0 {
 			// If maxFloat < EV, then there is no
 			// acceptable faceValue
-			return nil, errInsufficientSenderReserve
+			return nil, ErrInsufficientSenderReserve
 		}
 
 		// If faceValue > maxFloat
@@ -256,28 +620,29 @@ func (r *recipient) faceValue(sender ethcommon.Address) (*big.Int, error) {
 	return faceValue, nil
 }
 
-func (r *recipient) winProb(faceValue *big.Int) *big.Int {
+// calcWinProb returns the win probability of tickets of faceValue that have an EV of ev
+func calcWinProb(faceValue *big.Int, ev *big.Int) *big.Int {
 	// Return 0 if faceValue happens to be 0
 	if faceValue.Cmp(big.NewInt(0)) == 0 {
 		return big.NewInt(0)
 	}
 	// Return maxWinProb if faceValue = EV
-	if faceValue.Cmp(r.cfg.EV) == 0 {
+	if faceValue.Cmp(ev) == 0 {
 		return maxWinProb
 	}
 
 	m := new(big.Int)
 	x, m := new(big.Int).DivMod(maxWinProb, faceValue, m)
 	if m.Int64() != 0 {
-		return new(big.Int).Mul(r.cfg.EV, x.Add(x, big.NewInt(1)))
+		return new(big.Int).Mul(ev, x.Add(x, big.NewInt(1)))
 	}
 	// Compute winProb as the numerator of a fraction over maxWinProb
-	return new(big.Int).Mul(r.cfg.EV, x)
+	return new(big.Int).Mul(ev, x)
 }
 
 func (r *recipient) TxCostMultiplier(sender ethcommon.Address) (*big.Rat, error) {
 	// 'r.faceValue(sender)' will return min(defaultFaceValue, MaxFloat(sender))
-	faceValue, err := r.faceValue(sender)
+	faceValue, err := r.faceValue(sender, r.cfg.EV)
 
 	if err != nil {
 		return nil, err
@@ -289,18 +654,19 @@ func (r *recipient) TxCostMultiplier(sender ethcommon.Address) (*big.Rat, error)
 	return new(big.Rat).SetFrac(faceValue, r.txCost()), nil
 }
 
-func (r *recipient) rand(seed *big.Int, sender ethcommon.Address, faceValue *big.Int, winProb *big.Int, expirationBlock *big.Int, price *big.Rat, ticketExpirationParams *TicketExpirationParams) *big.Int {
-	h := hmac.New(sha256.New, r.secret[:])
+func (r *recipient) rand(seed *big.Int, sender ethcommon.Address, faceValue *big.Int, winProb *big.Int, expirationBlock *big.Int, price *big.Rat, ticketExpirationParams *TicketExpirationParams) (*big.Int, error) {
 	msg := append(seed.Bytes(), sender.Bytes()...)
 	msg = append(msg, faceValue.Bytes()...)
 	msg = append(msg, winProb.Bytes()...)
 	msg = append(msg, expirationBlock.Bytes()...)
 	msg = append(msg, price.Num().Bytes()...)
 	msg = append(msg, price.Denom().Bytes()...)
-	msg = append(msg, ticketExpirationParams.AuxData()...)
 
-	h.Write(msg)
-	return new(big.Int).SetBytes(h.Sum(nil))
+	seedSource := r.cfg.SeedSource
+	if seedSource == nil {
+		seedSource = secretSeedSource{}
+	}
+	return seedSource.RecipientRand(r.secret, msg, ticketExpirationParams)
 }
 
 func (r *recipient) updateSenderNonce(rand *big.Int, ticket *Ticket) error {
@@ -310,7 +676,7 @@ func (r *recipient) updateSenderNonce(rand *big.Int, ticket *Ticket) error {
 	randStr := rand.String()
 	sn, ok := r.senderNonces[randStr]
 	if ok && ticket.SenderNonce <= sn.nonce {
-		return errors.Errorf("invalid ticket senderNonce sender=%v nonce=%v highest=%v", ticket.Sender.Hex(), ticket.SenderNonce, sn.nonce)
+		return fmt.Errorf("%w sender=%v nonce=%v highest=%v", ErrTicketUsed, ticket.Sender.Hex(), ticket.SenderNonce, sn.nonce)
 	}
 
 	r.senderNonces[randStr] = &struct {
@@ -321,12 +687,13 @@ func (r *recipient) updateSenderNonce(rand *big.Int, ticket *Ticket) error {
 	return nil
 }
 
-// EV Returns the required ticket EV for a recipient
-func (r *recipient) EV() *big.Rat {
-	return new(big.Rat).SetFrac(r.cfg.EV, big.NewInt(1))
+// EV Returns the required ticket EV for a recipient, for tickets created at price
+func (r *recipient) EV(price *big.Rat) *big.Rat {
+	return new(big.Rat).SetFrac(r.ev(price), big.NewInt(1))
 }
 
 func (r *recipient) senderNoncesCleanupLoop() {
+	defer monitor.RecoverAndReport()
 	sink := make(chan *big.Int, 10)
 	sub := r.tm.SubscribeBlocks(sink)
 	defer sub.Unsubscribe()
@@ -344,6 +711,14 @@ func (r *recipient) senderNoncesCleanupLoop() {
 				}
 			}
 			r.senderNoncesLock.Unlock()
+
+			r.sessionsLock.Lock()
+			for sessionID, session := range r.sessions {
+				if session.expirationBlock.Cmp(latestBlock) <= 0 {
+					delete(r.sessions, sessionID)
+				}
+			}
+			r.sessionsLock.Unlock()
 		}
 	}
 }
@@ -357,3 +732,7 @@ func NewFatalReceiveErr(err error) *FatalReceiveErr {
 		err,
 	}
 }
+
+func (e *FatalReceiveErr) Unwrap() error {
+	return e.error
+}
@@ -3,6 +3,7 @@ package pm
 import (
 	"crypto/hmac"
 	"crypto/sha256"
+	"fmt"
 	"math/big"
 	"strings"
 	"sync"
@@ -68,6 +69,7 @@ func newTicket(sender ethcommon.Address, params *TicketParams, senderNonce uint3
 		PricePerPixel:          params.PricePerPixel,
 		CreationRound:          params.ExpirationParams.CreationRound,
 		CreationRoundBlockHash: params.ExpirationParams.CreationRoundBlockHash,
+		Version:                params.Version,
 	}
 }
 
@@ -160,6 +162,33 @@ func TestReceiveTicket_InvalidRecipientRand(t *testing.T) {
 	assert.True(ok)
 }
 
+func TestReceiveTicket_ExpiredTicket(t *testing.T) {
+	assert := assert.New(t)
+	sender, b, _, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
+
+	sv := &stubSigVerifier{}
+	sv.SetVerifyResult(true)
+	v := NewValidator(sv, tm)
+	secret := [32]byte{3}
+	r := NewRecipientWithSecret(RandAddress(), b, v, gm, sm, tm, secret, cfg)
+	params, err := r.TicketParams(sender, big.NewRat(1, 1))
+	require.Nil(t, err)
+
+	// Tickets are accepted during their validity period
+	tm.round = big.NewInt(2)
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 0), sig, params.Seed)
+	assert.Nil(err)
+
+	// and refused once the broker would not redeem them anymore
+	tm.round = big.NewInt(3)
+	sessionID, won, err := r.ReceiveTicket(newTicket(sender, params, 1), sig, params.Seed)
+	assert.Equal("", sessionID)
+	assert.False(won)
+	assert.EqualError(err, errTicketExpired.Error())
+	_, ok := err.(*FatalReceiveErr)
+	assert.True(ok)
+}
+
 func TestReceiveTicket_InvalidSignature(t *testing.T) {
 	assert := assert.New(t)
 	sender, b, _, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
@@ -199,6 +228,28 @@ func TestReceiveTicket_InvalidSender(t *testing.T) {
 	assert.EqualError(err, "Invalid Sender")
 }
 
+func TestReceiveTicket_UnlockedSender(t *testing.T) {
+	assert := assert.New(t)
+	sender, b, v, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
+	smgr := newStubSenderManager()
+	smgr.info[sender] = &SenderInfo{WithdrawRound: big.NewInt(0)}
+	cfg.Unlocks = NewUnlockWatcher(&stubSenderEventSource{}, smgr)
+	r := newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
+
+	params, err := r.TicketParams(sender, big.NewRat(1, 1))
+	require.Nil(t, err)
+
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 1), sig, params.Seed)
+	assert.Nil(err)
+
+	// Test the tickets of senders that unlocked their deposit and reserve are refused
+	cfg.Unlocks.handleSenderEvent(&SenderEvent{Type: SenderEventUnlock, Sender: sender, EndRound: big.NewInt(10)})
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 2), sig, params.Seed)
+	assert.EqualError(err, fmt.Sprintf("sender %v unlocked its deposit and reserve until round 10", sender.Hex()))
+	_, ok := err.(*FatalReceiveErr)
+	assert.True(ok)
+}
+
 func TestReceiveTicket_ValidNonWinningTicket(t *testing.T) {
 	sender, b, v, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
 	secret := [32]byte{3}
@@ -262,6 +313,73 @@ func TestReceiveTicket_ValidWinningTicket(t *testing.T) {
 	}
 }
 
+func TestReceiveTicket_PayoutSplit(t *testing.T) {
+	sender, b, v, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
+	split := &PayoutSplit{Recipient: RandAddress(), Share: 100000}
+	cfg.PayoutSplit = split
+	r := newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
+	assert := assert.New(t)
+
+	params := ticketParamsOrFatal(t, r, sender)
+	assert.Equal(split, params.PayoutSplit)
+
+	// Tickets sent back with the split are accepted
+	ticket := newTicket(sender, params, 1)
+	ticket.PayoutSplit = params.PayoutSplit
+	_, _, err := r.ReceiveTicket(ticket, sig, params.Seed)
+	assert.Nil(err)
+
+	// Tickets of broadcasters that don't send the split back get the split of the recipient
+	ticket = newTicket(sender, params, 2)
+	_, _, err = r.ReceiveTicket(ticket, sig, params.Seed)
+	assert.Nil(err)
+	assert.Equal(split, ticket.PayoutSplit)
+
+	// Tickets with another split are rejected
+	ticket = newTicket(sender, params, 3)
+	ticket.PayoutSplit = &PayoutSplit{Recipient: split.Recipient, Share: 200000}
+	_, _, err = r.ReceiveTicket(ticket, sig, params.Seed)
+	assert.EqualError(err, errInvalidPayoutSplit.Error())
+	_, ok := err.(*FatalReceiveErr)
+	assert.True(ok)
+
+	// Recipients without a split reject tickets with one
+	cfg.PayoutSplit = nil
+	r = newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
+	params = ticketParamsOrFatal(t, r, sender)
+	assert.Nil(params.PayoutSplit)
+	ticket = newTicket(sender, params, 1)
+	ticket.PayoutSplit = split
+	_, _, err = r.ReceiveTicket(ticket, sig, params.Seed)
+	assert.EqualError(err, errInvalidPayoutSplit.Error())
+}
+
+func TestReceiveTicket_Version(t *testing.T) {
+	sender, b, v, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
+	cfg.TicketVersion = TicketVersionTypedData
+	reputation := NewSenderReputationTracker(SenderReputationConfig{MaxInvalidSignatures: 1})
+	cfg.Reputation = reputation
+	r := newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
+	assert := assert.New(t)
+
+	params := ticketParamsOrFatal(t, r, sender)
+	assert.Equal(TicketVersionTypedData, params.Version)
+
+	// Tickets of the version of the recipient are accepted
+	ticket := newTicket(sender, params, 1)
+	_, _, err := r.ReceiveTicket(ticket, sig, params.Seed)
+	assert.Nil(err)
+
+	// Tickets of another version are rejected without counting as invalid signatures
+	ticket = newTicket(sender, params, 2)
+	ticket.Version = TicketVersionLegacy
+	_, _, err = r.ReceiveTicket(ticket, sig, params.Seed)
+	assert.EqualError(err, errInvalidTicketVersion.Error())
+	_, ok := err.(*FatalReceiveErr)
+	assert.True(ok)
+	assert.Nil(reputation.Reputation(sender))
+}
+
 func TestReceiveTicket_InvalidSenderNonce(t *testing.T) {
 	sender, b, v, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
 	r := newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
@@ -307,6 +425,85 @@ func TestReceiveTicket_InvalidSenderNonce(t *testing.T) {
 	assert.False(t, ok)
 }
 
+func TestReceiveTicket_MaxFloatExhausted(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	sender, b, v, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
+	r := newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
+	params, err := r.TicketParams(sender, big.NewRat(1, 1))
+	require.Nil(err)
+
+	// Tickets are refused while their face value exceeds the max float
+	sm.maxFloat = new(big.Int).Sub(params.FaceValue, big.NewInt(1))
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 1), sig, params.Seed)
+	assert.EqualError(err, ErrInsufficientSenderReserve.Error())
+	_, ok := err.(*FatalReceiveErr)
+	assert.True(ok)
+
+	sm.maxFloatErr = errors.New("MaxFloat error")
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 1), sig, params.Seed)
+	assert.EqualError(err, "MaxFloat error")
+	sm.maxFloatErr = nil
+
+	// and accepted again once the max float covers them
+	sm.maxFloat = params.FaceValue
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 1), sig, params.Seed)
+	assert.Nil(err)
+}
+
+func TestReceiveTicket_SenderReputation(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	sender, b, _, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
+	cfg.Reputation = NewSenderReputationTracker(SenderReputationConfig{MaxDoubleSpends: 2, MaxInvalidSignatures: 2})
+
+	sv := &stubSigVerifier{}
+	sv.SetVerifyResult(true)
+	v := NewValidator(sv, tm)
+	r := NewRecipientWithSecret(RandAddress(), b, v, gm, sm, tm, [32]byte{3}, cfg)
+	params, err := r.TicketParams(sender, big.NewRat(1, 1))
+	require.Nil(err)
+
+	// Tickets with invalid signatures and replayed nonces are violations
+	sv.SetVerifyResult(false)
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 0), sig, params.Seed)
+	assert.EqualError(err, errInvalidTicketSignature.Error())
+	sv.SetVerifyResult(true)
+	ticket := newTicket(sender, params, 1)
+	_, _, err = r.ReceiveTicket(ticket, sig, params.Seed)
+	require.Nil(err)
+	_, _, err = r.ReceiveTicket(ticket, sig, params.Seed)
+	assert.Contains(err.Error(), "invalid ticket senderNonce")
+	assert.True(Errors.IsRetryable(err))
+
+	rep := cfg.Reputation.Reputation(sender)
+	assert.Equal(int64(1), rep.InvalidSignatures)
+	assert.Equal(int64(1), rep.DoubleSpends)
+	assert.False(rep.Blacklisted)
+
+	// Blacklisted senders are refused
+	_, _, err = r.ReceiveTicket(ticket, sig, params.Seed)
+	assert.Contains(err.Error(), "invalid ticket senderNonce")
+	assert.True(cfg.Reputation.IsBlacklisted(sender))
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 2), sig, params.Seed)
+	assert.EqualError(err, errSenderBlacklisted.Error())
+	_, ok := err.(*FatalReceiveErr)
+	assert.True(ok)
+	_, err = r.TicketParams(sender, big.NewRat(1, 1))
+	assert.Equal(errSenderBlacklisted, err)
+
+	// Senders are accepted again once cleared
+	assert.True(cfg.Reputation.Clear(sender))
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 2), sig, params.Seed)
+	assert.Nil(err)
+
+	// Requesting params with an insufficient reserve is a violation
+	sm.maxFloat = big.NewInt(0)
+	_, err = r.TicketParams(sender, big.NewRat(1, 1))
+	assert.Equal(ErrInsufficientSenderReserve, err)
+	assert.Equal(int64(1), cfg.Reputation.Reputation(sender).InsufficientFunds)
+}
+
 func TestReceiveTicket_ValidNonWinningTicket_Concurrent(t *testing.T) {
 	sender, b, v, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
 	r := newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
@@ -506,7 +703,7 @@ func TestTicketParams(t *testing.T) {
 	// Test insufficient sender reserve error
 	sm.maxFloat = new(big.Int).Sub(cfg.EV, big.NewInt(1))
 	_, err = r.TicketParams(sender, big.NewRat(1, 1))
-	assert.EqualError(err, errInsufficientSenderReserve.Error())
+	assert.EqualError(err, ErrInsufficientSenderReserve.Error())
 
 	// Test default faceValue < EV and maxFloat > EV
 	// Set gas price = 0 to set default faceValue = 0
@@ -523,7 +720,7 @@ func TestTicketParams(t *testing.T) {
 	sm.maxFloat = big.NewInt(0) // Set maxFloat to some value less than EV
 
 	_, err = r.TicketParams(sender, big.NewRat(1, 1))
-	assert.EqualError(err, errInsufficientSenderReserve.Error())
+	assert.EqualError(err, ErrInsufficientSenderReserve.Error())
 }
 
 func TestTxCostMultiplier_UsingFaceValue_ReturnsDefaultMultiplier(t *testing.T) {
@@ -575,7 +772,7 @@ func TestTxCostMultiplier_InsufficientReserve_ReturnsError(t *testing.T) {
 
 	mul, err := r.TxCostMultiplier(sender)
 	assert.Nil(t, mul)
-	assert.EqualError(t, err, errInsufficientSenderReserve.Error())
+	assert.EqualError(t, err, ErrInsufficientSenderReserve.Error())
 }
 
 func TestSenderNoncesCleanupLoop(t *testing.T) {
@@ -643,3 +840,329 @@ func TestSenderNoncesCleanupLoop(t *testing.T) {
 	time.Sleep(20 * time.Millisecond)
 	assert.True(tm.blockNumSub.(*stubSubscription).unsubscribed)
 }
+
+func TestExportTickets(t *testing.T) {
+	assert := assert.New(t)
+
+	_, b, v, gm, sm, tm, cfg, _ := newRecipientFixtureOrFatal(t)
+	r := newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
+
+	tickets, err := r.ExportTickets()
+	assert.Nil(err)
+	assert.Empty(tickets)
+
+	ticket := defaultSignedTicket(RandAddress(), uint32(0))
+	sm.queued = []*SignedTicket{ticket}
+	tickets, err = r.ExportTickets()
+	assert.Nil(err)
+	assert.Equal([]*SignedTicket{ticket}, tickets)
+	// The exported tickets stay queued until the import is confirmed
+	assert.Empty(sm.removed)
+
+	sm.shouldFail = errors.New("WinningTickets error")
+	_, err = r.ExportTickets()
+	assert.EqualError(err, "WinningTickets error")
+}
+
+func TestRemoveExportedTickets(t *testing.T) {
+	assert := assert.New(t)
+
+	_, b, v, gm, sm, tm, cfg, _ := newRecipientFixtureOrFatal(t)
+	r := newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
+	queued := []*SignedTicket{defaultSignedTicket(RandAddress(), uint32(0)), defaultSignedTicket(RandAddress(), uint32(1))}
+	sm.queued = queued
+
+	// Only the exported tickets that are still queued are removed
+	exported := *queued[0]
+	other := defaultSignedTicket(RandAddress(), uint32(2))
+	n, err := r.RemoveExportedTickets([]*SignedTicket{&exported, &exported, other, nil})
+	assert.Nil(err)
+	assert.Equal(1, n)
+	assert.Equal([]*SignedTicket{queued[0]}, sm.removed)
+
+	sm.removeShouldFail = errors.New("RemoveWinningTickets error")
+	_, err = r.RemoveExportedTickets(queued)
+	assert.EqualError(err, "RemoveWinningTickets error")
+
+	sm.shouldFail = errors.New("WinningTickets error")
+	_, err = r.RemoveExportedTickets(queued)
+	assert.EqualError(err, "WinningTickets error")
+}
+
+func TestImportTickets(t *testing.T) {
+	assert := assert.New(t)
+
+	_, b, v, gm, sm, tm, cfg, _ := newRecipientFixtureOrFatal(t)
+	r := newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
+	tickets := []*SignedTicket{defaultSignedTicket(RandAddress(), uint32(0)), defaultSignedTicket(RandAddress(), uint32(1))}
+
+	// Missing fields
+	n, err := r.ImportTickets([]*SignedTicket{{Ticket: &Ticket{}}, tickets[0]})
+	assert.EqualError(err, "invalid ticket index=0: missing ticket fields")
+	assert.Equal(0, n)
+	assert.Empty(sm.queued)
+
+	// Non winning tickets
+	n, err = r.ImportTickets(tickets)
+	assert.EqualError(err, "invalid ticket index=0: ticket did not win")
+	assert.Equal(0, n)
+	assert.Empty(sm.queued)
+
+	// Invalid tickets
+	v.SetIsWinningTicket(true)
+	v.SetIsValidTicket(false)
+	n, err = r.ImportTickets(tickets)
+	assert.EqualError(err, "invalid ticket index=0: stub validator invalid ticket error")
+	assert.Equal(0, n)
+	assert.Empty(sm.queued)
+
+	// Expired tickets are skipped
+	v.validateErr = errTicketExpired
+	n, err = r.ImportTickets(tickets)
+	assert.Nil(err)
+	assert.Equal(0, n)
+	assert.Empty(sm.queued)
+
+	v.validateErr = nil
+	v.SetIsValidTicket(true)
+	n, err = r.ImportTickets(tickets)
+	assert.Nil(err)
+	assert.Equal(2, n)
+	assert.Equal(tickets, sm.queued)
+
+	// Imports are idempotent, tickets that are already stored are skipped
+	third := defaultSignedTicket(RandAddress(), uint32(2))
+	n, err = r.ImportTickets(append(tickets, third, third))
+	assert.Nil(err)
+	assert.Equal(1, n)
+	assert.Equal(append(tickets, third), sm.queued)
+
+	// Queue errors return the number of tickets queued
+	sm.shouldFail = errors.New("QueueTicket error")
+	n, err = r.ImportTickets(tickets)
+	assert.EqualError(err, "QueueTicket error")
+	assert.Equal(0, n)
+}
+
+func TestSessionPayments(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender, b, v, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
+	r := newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
+	params := ticketParamsOrFatal(t, r, sender)
+	sessionID := params.RecipientRandHash.Hex()
+
+	assert.Nil(r.SessionPayments(sessionID))
+
+	// Non winning tickets
+	for i := 1; i <= 2; i++ {
+		_, _, err := r.ReceiveTicket(newTicket(sender, params, uint32(i)), sig, params.Seed)
+		require.Nil(err)
+	}
+
+	// Winning tickets
+	v.SetIsWinningTicket(true)
+	ticket := newTicket(sender, params, 3)
+	_, won, err := r.ReceiveTicket(ticket, sig, params.Seed)
+	require.Nil(err)
+	require.True(won)
+
+	// Tickets that are refused are not counted
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 3), sig, params.Seed)
+	require.NotNil(err)
+
+	session := r.SessionPayments(sessionID)
+	require.NotNil(session)
+	assert.Equal(sessionID, session.SessionID)
+	assert.Equal(sender, session.Sender)
+	assert.Equal(new(big.Rat).Mul(ticket.EV(), big.NewRat(3, 1)), session.EV)
+	assert.Equal(int64(3), session.Tickets)
+	assert.Equal(int64(1), session.WinningTickets)
+	assert.Equal(big.NewInt(0), session.Redeemed)
+
+	require.Nil(r.RedeemWinningTicket(ticket, sig, params.Seed))
+	session2 := r.SessionPayments(sessionID)
+	assert.Equal(ticket.FaceValue, session2.Redeemed)
+	// Snapshots are not modified
+	assert.Equal(big.NewInt(0), session.Redeemed)
+
+	// Tickets that can't be queued are not redeemed
+	sm.shouldFail = errors.New("QueueTicket error")
+	assert.NotNil(r.RedeemWinningTicket(ticket, sig, params.Seed))
+	assert.Equal(ticket.FaceValue, r.SessionPayments(sessionID).Redeemed)
+
+	// Sessions of other ticket params are separate
+	sm.shouldFail = nil
+	v.SetIsWinningTicket(false)
+	otherParams := ticketParamsOrFatal(t, r, sender)
+	_, _, err = r.ReceiveTicket(newTicket(sender, otherParams, 1), sig, otherParams.Seed)
+	require.Nil(err)
+	assert.Equal(int64(1), r.SessionPayments(otherParams.RecipientRandHash.Hex()).Tickets)
+	assert.Equal(int64(3), r.SessionPayments(sessionID).Tickets)
+}
+
+func TestSessionPayments_Cleanup(t *testing.T) {
+	assert := assert.New(t)
+
+	tm := &stubTimeManager{}
+	r := &recipient{
+		tm:   tm,
+		quit: make(chan struct{}),
+		sessions: map[string]*SessionPayments{
+			"blastoise": {expirationBlock: big.NewInt(2)},
+			"charizard": {expirationBlock: big.NewInt(1)},
+		},
+	}
+
+	go r.senderNoncesCleanupLoop()
+	defer r.Stop()
+	time.Sleep(20 * time.Millisecond)
+
+	tm.blockNumSink <- big.NewInt(1)
+	time.Sleep(20 * time.Millisecond)
+
+	r.sessionsLock.Lock()
+	defer r.sessionsLock.Unlock()
+	_, ok := r.sessions["blastoise"]
+	assert.True(ok)
+	_, ok = r.sessions["charizard"]
+	assert.False(ok)
+}
+
+func TestReceiveTicket_RecipientRandMaxTickets(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender, b, v, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
+	cfg.RecipientRandMaxTickets = 4
+	r := newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
+	params := ticketParamsOrFatal(t, r, sender)
+
+	assert.False(r.ShouldRotateTicketParams(params.RecipientRandHash))
+
+	for i := 1; i <= 4; i++ {
+		_, _, err := r.ReceiveTicket(newTicket(sender, params, uint32(i)), sig, params.Seed)
+		require.Nil(err)
+		// Rotation is proposed once half of the tickets are received
+		assert.Equal(i >= 2, r.ShouldRotateTicketParams(params.RecipientRandHash))
+	}
+
+	_, won, err := r.ReceiveTicket(newTicket(sender, params, 5), sig, params.Seed)
+	assert.Equal(ErrRecipientRandExpired, err)
+	assert.False(won)
+	assert.True(Errors.IsRetryable(err))
+	assert.Equal(int64(4), r.SessionPayments(params.RecipientRandHash.Hex()).Tickets)
+
+	// New ticket params are accepted
+	otherParams := ticketParamsOrFatal(t, r, sender)
+	assert.False(r.ShouldRotateTicketParams(otherParams.RecipientRandHash))
+	_, _, err = r.ReceiveTicket(newTicket(sender, otherParams, 1), sig, otherParams.Seed)
+	assert.Nil(err)
+}
+
+func TestReceiveTicket_RecipientRandTTL(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender, b, v, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
+	cfg.RecipientRandTTL = 100 * time.Millisecond
+	r := newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
+	params := ticketParamsOrFatal(t, r, sender)
+
+	_, _, err := r.ReceiveTicket(newTicket(sender, params, 1), sig, params.Seed)
+	require.Nil(err)
+	assert.False(r.ShouldRotateTicketParams(params.RecipientRandHash))
+
+	time.Sleep(60 * time.Millisecond)
+	assert.True(r.ShouldRotateTicketParams(params.RecipientRandHash))
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 2), sig, params.Seed)
+	assert.Nil(err)
+
+	time.Sleep(60 * time.Millisecond)
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 3), sig, params.Seed)
+	assert.Equal(ErrRecipientRandExpired, err)
+}
+
+func TestShouldRotateTicketParams_WinningTicket(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender, b, v, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
+	r := newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
+	params := ticketParamsOrFatal(t, r, sender)
+
+	// Without limits, params are only rotated after a winning ticket
+	for i := 1; i <= 10; i++ {
+		_, _, err := r.ReceiveTicket(newTicket(sender, params, uint32(i)), sig, params.Seed)
+		require.Nil(err)
+	}
+	assert.False(r.ShouldRotateTicketParams(params.RecipientRandHash))
+
+	v.SetIsWinningTicket(true)
+	_, won, err := r.ReceiveTicket(newTicket(sender, params, 11), sig, params.Seed)
+	require.Nil(err)
+	require.True(won)
+	assert.True(r.ShouldRotateTicketParams(params.RecipientRandHash))
+
+	// Tickets of the params are still accepted
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 12), sig, params.Seed)
+	assert.Nil(err)
+}
+
+func TestReceiveTicket_FreeTickets(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender, b, v, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
+	cfg.FreeTickets = true
+	r := newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
+	params, err := r.TicketParams(sender, big.NewRat(0, 1))
+	require.Nil(err)
+	require.True(params.IsFree())
+
+	// The sender has no funds
+	sm.validateSenderErr = errors.New("ValidateSender error")
+	sm.maxFloatErr = errors.New("MaxFloat error")
+
+	_, won, err := r.ReceiveTicket(newTicket(sender, params, 1), sig, params.Seed)
+	assert.Nil(err)
+	assert.False(won)
+
+	// Nonces are still checked
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 1), sig, params.Seed)
+	assert.Contains(err.Error(), ErrTicketUsed.Error())
+
+	session := r.SessionPayments(params.RecipientRandHash.Hex())
+	require.NotNil(session)
+	assert.Equal(int64(1), session.Tickets)
+	assert.Zero(session.EV.Sign())
+
+	// Tickets with a value still need funds
+	sm.maxFloatErr = nil
+	paidParams, err := r.TicketParams(sender, big.NewRat(1, 1))
+	require.Nil(err)
+	_, _, err = r.ReceiveTicket(newTicket(sender, paidParams, 1), sig, paidParams.Seed)
+	assert.EqualError(err, "ValidateSender error")
+
+	// Free tickets need funds without the free tier
+	cfg.FreeTickets = false
+	r = newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
+	params, err = r.TicketParams(sender, big.NewRat(0, 1))
+	require.Nil(err)
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 1), sig, params.Seed)
+	assert.EqualError(err, "ValidateSender error")
+}
+
+func TestTicket_IsFree(t *testing.T) {
+	assert := assert.New(t)
+
+	ticket := &Ticket{FaceValue: big.NewInt(0), WinProb: big.NewInt(0)}
+	assert.True(ticket.IsFree())
+	assert.True((&Ticket{}).IsFree())
+	ticket.FaceValue = big.NewInt(1)
+	assert.False(ticket.IsFree())
+	ticket = &Ticket{FaceValue: big.NewInt(0), WinProb: big.NewInt(1)}
+	assert.False(ticket.IsFree())
+	assert.True((&TicketParams{FaceValue: big.NewInt(0), WinProb: big.NewInt(0)}).IsFree())
+}
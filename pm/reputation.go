@@ -0,0 +1,180 @@
+package pm
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+var errSenderBlacklisted = errors.New("sender is blacklisted")
+
+// SenderViolation is a kind of misbehavior of a sender detected by a recipient
+type SenderViolation int
+
+const (
+	// ViolationDoubleSpend is a ticket reusing the nonce of a ticket already received
+	ViolationDoubleSpend SenderViolation = iota
+	// ViolationInsufficientFunds is a request for ticket params by a sender whose reserve can't
+	// cover the EV of tickets
+	ViolationInsufficientFunds
+	// ViolationInvalidSignature is a ticket with an invalid signature
+	ViolationInvalidSignature
+)
+
+func (v SenderViolation) String() string {
+	switch v {
+	case ViolationDoubleSpend:
+		return "double_spend"
+	case ViolationInsufficientFunds:
+		return "insufficient_funds"
+	case ViolationInvalidSignature:
+		return "invalid_signature"
+	}
+	return "unknown"
+}
+
+// SenderReputationConfig contains the number of violations of each kind after which a
+// sender is blacklisted. A threshold of 0 disables blacklisting for that kind
+type SenderReputationConfig struct {
+	MaxDoubleSpends      int64
+	MaxInsufficientFunds int64
+	MaxInvalidSignatures int64
+}
+
+// SenderReputation holds a snapshot of the violations of a sender
+type SenderReputation struct {
+	Sender            ethcommon.Address
+	DoubleSpends      int64
+	InsufficientFunds int64
+	InvalidSignatures int64
+	Blacklisted       bool
+	BlacklistedAt     time.Time
+	LastViolation     time.Time
+}
+
+// SenderReputationTracker tracks the violations of the senders of the tickets received by a
+// recipient, and blacklists the senders exceeding the thresholds of its config. The tickets
+// of blacklisted senders are refused until they are cleared from the blacklist. A nil
+// SenderReputationTracker doesn't track or blacklist anything
+type SenderReputationTracker struct {
+	cfg SenderReputationConfig
+
+	mu      sync.RWMutex
+	senders map[ethcommon.Address]*SenderReputation
+}
+
+// NewSenderReputationTracker creates a new SenderReputationTracker
+func NewSenderReputationTracker(cfg SenderReputationConfig) *SenderReputationTracker {
+	return &SenderReputationTracker{
+		cfg:     cfg,
+		senders: make(map[ethcommon.Address]*SenderReputation),
+	}
+}
+
+// RecordViolation records a violation of a sender, which blacklists the sender if it
+// exceeds the threshold for the kind of violation
+func (t *SenderReputationTracker) RecordViolation(sender ethcommon.Address, v SenderViolation) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.senders[sender]
+	if !ok {
+		s = &SenderReputation{Sender: sender}
+		t.senders[sender] = s
+	}
+
+	var count, max int64
+	switch v {
+	case ViolationDoubleSpend:
+		s.DoubleSpends++
+		count, max = s.DoubleSpends, t.cfg.MaxDoubleSpends
+	case ViolationInsufficientFunds:
+		s.InsufficientFunds++
+		count, max = s.InsufficientFunds, t.cfg.MaxInsufficientFunds
+	case ViolationInvalidSignature:
+		s.InvalidSignatures++
+		count, max = s.InvalidSignatures, t.cfg.MaxInvalidSignatures
+	default:
+		return
+	}
+	s.LastViolation = time.Now()
+
+	if !s.Blacklisted && max > 0 && count >= max {
+		s.Blacklisted = true
+		s.BlacklistedAt = s.LastViolation
+		glog.Warningf("Blacklisted sender=%v violation=%v count=%v", sender.Hex(), v, count)
+	}
+}
+
+// IsBlacklisted returns whether a sender is blacklisted
+func (t *SenderReputationTracker) IsBlacklisted(sender ethcommon.Address) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	s, ok := t.senders[sender]
+	return ok && s.Blacklisted
+}
+
+// Reputation returns a snapshot of the violations of a sender or nil if the sender has none
+func (t *SenderReputationTracker) Reputation(sender ethcommon.Address) *SenderReputation {
+	if t == nil {
+		return nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	s, ok := t.senders[sender]
+	if !ok {
+		return nil
+	}
+	snapshot := *s
+	return &snapshot
+}
+
+// Blacklist returns a snapshot of the blacklisted senders ordered by most recently blacklisted
+func (t *SenderReputationTracker) Blacklist() []*SenderReputation {
+	res := []*SenderReputation{}
+	if t == nil {
+		return res
+	}
+	t.mu.RLock()
+	for _, s := range t.senders {
+		if s.Blacklisted {
+			snapshot := *s
+			res = append(res, &snapshot)
+		}
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].BlacklistedAt.After(res[j].BlacklistedAt)
+	})
+	return res
+}
+
+// Clear removes a sender from the blacklist and resets its violations. It returns false if
+// the sender has no violations
+func (t *SenderReputationTracker) Clear(sender ethcommon.Address) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.senders[sender]; !ok {
+		return false
+	}
+	delete(t.senders, sender)
+	glog.Infof("Cleared violations of sender=%v", sender.Hex())
+	return true
+}
@@ -0,0 +1,81 @@
+package pm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSenderReputationTracker_Blacklist(t *testing.T) {
+	assert := assert.New(t)
+	tracker := NewSenderReputationTracker(SenderReputationConfig{MaxDoubleSpends: 2, MaxInvalidSignatures: 1})
+	sender := RandAddress()
+
+	assert.Nil(tracker.Reputation(sender))
+	assert.False(tracker.IsBlacklisted(sender))
+	assert.Empty(tracker.Blacklist())
+
+	tracker.RecordViolation(sender, ViolationDoubleSpend)
+	assert.False(tracker.IsBlacklisted(sender))
+	rep := tracker.Reputation(sender)
+	assert.Equal(sender, rep.Sender)
+	assert.Equal(int64(1), rep.DoubleSpends)
+	assert.False(rep.LastViolation.IsZero())
+	assert.True(rep.BlacklistedAt.IsZero())
+
+	// Thresholds of 0 never blacklist
+	for i := 0; i < 10; i++ {
+		tracker.RecordViolation(sender, ViolationInsufficientFunds)
+	}
+	assert.False(tracker.IsBlacklisted(sender))
+	assert.Equal(int64(10), tracker.Reputation(sender).InsufficientFunds)
+
+	tracker.RecordViolation(sender, ViolationDoubleSpend)
+	assert.True(tracker.IsBlacklisted(sender))
+	rep = tracker.Reputation(sender)
+	assert.Equal(int64(2), rep.DoubleSpends)
+	assert.True(rep.Blacklisted)
+	assert.False(rep.BlacklistedAt.IsZero())
+
+	// Snapshots are not modified by later violations
+	tracker.RecordViolation(sender, ViolationDoubleSpend)
+	assert.Equal(int64(2), rep.DoubleSpends)
+	assert.Equal(int64(3), tracker.Reputation(sender).DoubleSpends)
+
+	other := RandAddress()
+	tracker.RecordViolation(other, ViolationInvalidSignature)
+	assert.True(tracker.IsBlacklisted(other))
+	blacklist := tracker.Blacklist()
+	assert.Len(blacklist, 2)
+	assert.Equal(other, blacklist[0].Sender)
+	assert.Equal(sender, blacklist[1].Sender)
+
+	// Cleared senders start over
+	assert.True(tracker.Clear(sender))
+	assert.False(tracker.Clear(sender))
+	assert.False(tracker.IsBlacklisted(sender))
+	assert.Nil(tracker.Reputation(sender))
+	tracker.RecordViolation(sender, ViolationDoubleSpend)
+	assert.False(tracker.IsBlacklisted(sender))
+	assert.Len(tracker.Blacklist(), 1)
+}
+
+func TestSenderReputationTracker_Nil(t *testing.T) {
+	assert := assert.New(t)
+	var tracker *SenderReputationTracker
+	sender := RandAddress()
+
+	tracker.RecordViolation(sender, ViolationDoubleSpend)
+	assert.False(tracker.IsBlacklisted(sender))
+	assert.Nil(tracker.Reputation(sender))
+	assert.Empty(tracker.Blacklist())
+	assert.False(tracker.Clear(sender))
+}
+
+func TestSenderViolation_String(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("double_spend", ViolationDoubleSpend.String())
+	assert.Equal("insufficient_funds", ViolationInsufficientFunds.String())
+	assert.Equal("invalid_signature", ViolationInvalidSignature.String())
+	assert.Equal("unknown", SenderViolation(-1).String())
+}
@@ -0,0 +1,101 @@
+package pm
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"math/big"
+	"sync"
+)
+
+// RoundsManager provides the blockhashes that the rounds of the protocol are initialized with
+type RoundsManager interface {
+	// LastInitializedRound returns the last initialized round of the Livepeer protocol
+	LastInitializedRound() *big.Int
+	// BlockHashForRound returns the blockhash that a round was initialized with
+	BlockHashForRound(round *big.Int) ([32]byte, error)
+}
+
+// SeedSource derives the recipientRands that a recipient commits to in its ticket params
+type SeedSource interface {
+	// RecipientRand derives a recipientRand from the secret of the recipient, the fields of
+	// ticket params serialized in msg and the round that the ticket params are created in
+	RecipientRand(secret [32]byte, msg []byte, expirationParams *TicketExpirationParams) (*big.Int, error)
+}
+
+// secretSeedSource derives recipientRands from the secret of the recipient only, trusting
+// the creation round blockhash of tickets, which the TicketBroker checks on redemption
+type secretSeedSource struct{}
+
+func (secretSeedSource) RecipientRand(secret [32]byte, msg []byte, expirationParams *TicketExpirationParams) (*big.Int, error) {
+	return hmacRand(secret[:], append(msg, expirationParams.AuxData()...)), nil
+}
+
+// roundSeedSource derives recipientRands from a key of the round that ticket params are
+// created in, itself derived from the secret of the recipient and the blockhash that the
+// round was initialized with on-chain
+type roundSeedSource struct {
+	rm RoundsManager
+
+	mu sync.Mutex
+	// blockHashes caches the blockhashes of the rounds of the tickets that can still be redeemed
+	blockHashes map[int64][32]byte
+}
+
+// NewRoundSeedSource creates a SeedSource deriving recipientRands from the blockhashes of the
+// rounds returned by rm. Tickets are refused if the creation round blockhash they are created
+// with is not the one of their round, e.g. if the initialization of the round was reorged, so
+// that tickets that the TicketBroker would not redeem are invalidated at round boundaries
+func NewRoundSeedSource(rm RoundsManager) SeedSource {
+	return &roundSeedSource{
+		rm:          rm,
+		blockHashes: make(map[int64][32]byte),
+	}
+}
+
+func (s *roundSeedSource) RecipientRand(secret [32]byte, msg []byte, expirationParams *TicketExpirationParams) (*big.Int, error) {
+	blockHash, err := s.blockHash(expirationParams.CreationRound)
+	if err != nil {
+		return nil, err
+	}
+	if blockHash != expirationParams.CreationRoundBlockHash {
+		return nil, errInvalidCreationRoundBlockHash
+	}
+
+	roundKey := hmacRand(secret[:], blockHash[:])
+	return hmacRand(roundKey.Bytes(), append(msg, expirationParams.AuxData()...)), nil
+}
+
+// blockHash returns the blockhash of a round, fetching it if it is not cached. The blockhashes
+// of the rounds before the last one are cached until their tickets expire
+func (s *roundSeedSource) blockHash(round int64) ([32]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lastRound := s.rm.LastInitializedRound().Int64()
+	for r := range s.blockHashes {
+		if r+ticketValidityPeriod <= lastRound {
+			delete(s.blockHashes, r)
+		}
+	}
+
+	if blockHash, ok := s.blockHashes[round]; ok {
+		return blockHash, nil
+	}
+
+	blockHash, err := s.rm.BlockHashForRound(big.NewInt(round))
+	if err != nil {
+		return [32]byte{}, err
+	}
+	// The initialization of the last round can still be reorged, and rounds that are not
+	// initialized yet have no blockhash until they are
+	if round < lastRound {
+		s.blockHashes[round] = blockHash
+	}
+	return blockHash, nil
+}
+
+func hmacRand(key []byte, msg []byte) *big.Int {
+	h := hmac.New(sha256.New, key)
+	h.Write(msg)
+	return new(big.Int).SetBytes(h.Sum(nil))
+}
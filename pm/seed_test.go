@@ -0,0 +1,140 @@
+package pm
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubRoundsManager struct {
+	round       *big.Int
+	blockHashes map[int64][32]byte
+	calls       map[int64]int
+	err         error
+}
+
+func newStubRoundsManager(round int64) *stubRoundsManager {
+	return &stubRoundsManager{
+		round:       big.NewInt(round),
+		blockHashes: make(map[int64][32]byte),
+		calls:       make(map[int64]int),
+	}
+}
+
+func (rm *stubRoundsManager) LastInitializedRound() *big.Int {
+	return rm.round
+}
+
+func (rm *stubRoundsManager) BlockHashForRound(round *big.Int) ([32]byte, error) {
+	rm.calls[round.Int64()]++
+	if rm.err != nil {
+		return [32]byte{}, rm.err
+	}
+	return rm.blockHashes[round.Int64()], nil
+}
+
+func TestRoundSeedSource_RecipientRand(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	rm := newStubRoundsManager(5)
+	rm.blockHashes[5] = RandHash()
+	s := NewRoundSeedSource(rm)
+	secret := [32]byte{1}
+	msg := []byte("foo")
+	params := &TicketExpirationParams{CreationRound: 5, CreationRoundBlockHash: rm.blockHashes[5]}
+
+	rand, err := s.RecipientRand(secret, msg, params)
+	require.Nil(err)
+	secretRand, err := secretSeedSource{}.RecipientRand(secret, msg, params)
+	require.Nil(err)
+	assert.NotEqual(secretRand, rand)
+
+	// Test recipientRand is deterministic
+	rand2, err := s.RecipientRand(secret, msg, params)
+	require.Nil(err)
+	assert.Equal(rand, rand2)
+
+	// Test recipientRand depends on the secret
+	rand2, err = s.RecipientRand([32]byte{2}, msg, params)
+	require.Nil(err)
+	assert.NotEqual(rand, rand2)
+
+	// Test creation round blockhash that isn't the one of the round
+	params.CreationRoundBlockHash = RandHash()
+	_, err = s.RecipientRand(secret, msg, params)
+	assert.Equal(errInvalidCreationRoundBlockHash, err)
+	assert.True(Errors.IsRetryable(err))
+
+	// Test reorged initialization of the round
+	rm.blockHashes[5] = params.CreationRoundBlockHash
+	rand2, err = s.RecipientRand(secret, msg, params)
+	require.Nil(err)
+	assert.NotEqual(rand, rand2)
+
+	// Test BlockHashForRound error
+	rm.err = errors.New("BlockHashForRound error")
+	_, err = s.RecipientRand(secret, msg, params)
+	assert.EqualError(err, "BlockHashForRound error")
+}
+
+func TestRoundSeedSource_BlockHashCache(t *testing.T) {
+	assert := assert.New(t)
+
+	rm := newStubRoundsManager(5)
+	rm.blockHashes[4] = RandHash()
+	rm.blockHashes[5] = RandHash()
+	s := NewRoundSeedSource(rm).(*roundSeedSource)
+
+	for i := 0; i < 2; i++ {
+		for _, round := range []int64{4, 5} {
+			bh, err := s.blockHash(round)
+			assert.Nil(err)
+			assert.Equal(rm.blockHashes[round], bh)
+		}
+	}
+	// Rounds before the last one are cached, the last one can still be reorged
+	assert.Equal(1, rm.calls[4])
+	assert.Equal(2, rm.calls[5])
+	assert.Len(s.blockHashes, 1)
+
+	// Blockhashes are dropped once the tickets of their round expire
+	rm.round = big.NewInt(4 + ticketValidityPeriod)
+	_, err := s.blockHash(rm.round.Int64())
+	assert.Nil(err)
+	assert.Empty(s.blockHashes)
+}
+
+func TestReceiveTicket_RoundSeedSource(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender, b, v, gm, sm, tm, cfg, sig := newRecipientFixtureOrFatal(t)
+	rm := newStubRoundsManager(tm.round.Int64())
+	rm.blockHashes[tm.round.Int64()] = tm.blkHash
+	cfg.SeedSource = NewRoundSeedSource(rm)
+	secret := [32]byte{3}
+	r := NewRecipientWithSecret(RandAddress(), b, v, gm, sm, tm, secret, cfg)
+
+	params := ticketParamsOrFatal(t, r, sender)
+	ticket := newTicket(sender, params, 1)
+	_, _, err := r.ReceiveTicket(ticket, sig, params.Seed)
+	require.Nil(err)
+
+	require.Nil(r.RedeemWinningTicket(ticket, sig, params.Seed))
+	assert.NotEqual(genRecipientRand(sender, secret, params), sm.queued[0].RecipientRand)
+
+	// Tickets are refused once the initialization of their round is reorged
+	rm.blockHashes[tm.round.Int64()] = RandHash()
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 2), sig, params.Seed)
+	assert.Equal(errInvalidCreationRoundBlockHash, err)
+	assert.NotNil(r.RedeemWinningTicket(ticket, sig, params.Seed))
+
+	tm.blkHash = rm.blockHashes[tm.round.Int64()]
+	params = ticketParamsOrFatal(t, r, sender)
+	_, _, err = r.ReceiveTicket(newTicket(sender, params, 1), sig, params.Seed)
+	assert.Nil(err)
+}
@@ -3,6 +3,7 @@ package pm
 import (
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
 	"sync/atomic"
 
@@ -15,6 +16,10 @@ type ErrSenderValidation struct {
 	error
 }
 
+func (e ErrSenderValidation) Unwrap() error {
+	return e.error
+}
+
 // Sender enables starting multiple probabilistic micropayment sessions with multiple recipients
 // and create tickets that adhere to each session's params and unique nonce requirements.
 type Sender interface {
@@ -30,6 +35,22 @@ type Sender interface {
 
 	// EV returns the ticket EV for a session
 	EV(sessionID string) (*big.Rat, error)
+
+	// SpentEV returns the total EV of the tickets sent to a recipient
+	SpentEV(recipient ethcommon.Address) *big.Rat
+
+	// PendingEV returns the total EV of the sent tickets that can still be redeemed, less the
+	// deposit that redemptions already drew
+	PendingEV() *big.Rat
+
+	// MaxEV returns the max total EV of a ticket batch
+	MaxEV() *big.Rat
+}
+
+// MaxPriceSource provides the max price per pixel that a sender is willing to pay, which can
+// change at runtime. A nil max price means that any price is accepted
+type MaxPriceSource interface {
+	MaxPrice() *big.Rat
 }
 
 type session struct {
@@ -44,27 +65,55 @@ type sender struct {
 	senderManager     SenderManager
 	maxEV             *big.Rat
 	depositMultiplier int
+	maxPrice          MaxPriceSource
 
 	sessions sync.Map
+
+	// mu protects spent, pending and lastDeposit, and makes the check of the deposit of the
+	// sender and the recording of the EV of a ticket batch atomic
+	mu sync.Mutex
+	// spent is the total EV of the tickets sent to each recipient
+	spent map[ethcommon.Address]*big.Rat
+	// pending is the total EV of the tickets sent for each creation round that wasn't drawn
+	// from the deposit by redemptions yet
+	pending map[int64]*big.Rat
+	// lastDeposit is the deposit of the sender when it was last checked
+	lastDeposit *big.Int
 }
 
-// NewSender creates a new Sender instance.
-func NewSender(signer Signer, timeManager TimeManager, senderManager SenderManager, maxEV *big.Rat, depositMultiplier int) Sender {
+// NewSender creates a new Sender instance. Ticket params advertising a price per pixel higher
+// than the max price of maxPrice are refused. maxPrice can be nil if any price is accepted
+func NewSender(signer Signer, timeManager TimeManager, senderManager SenderManager, maxEV *big.Rat, depositMultiplier int, maxPrice MaxPriceSource) Sender {
 	return &sender{
 		signer:            signer,
 		timeManager:       timeManager,
 		senderManager:     senderManager,
 		maxEV:             maxEV,
 		depositMultiplier: depositMultiplier,
+		maxPrice:          maxPrice,
+		spent:             make(map[ethcommon.Address]*big.Rat),
+		pending:           make(map[int64]*big.Rat),
 	}
 }
 
+// StartSession creates a session for a given set of ticket params. A session restarted with the
+// same recipientRandHash keeps its sender nonce, because the recipient would reject tickets
+// reusing a nonce
 func (s *sender) StartSession(ticketParams TicketParams) string {
 	sessionID := ticketParams.RecipientRandHash.Hex()
 
+	// Hold s.mu so that no tickets are created for the previous session while it is replaced
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var senderNonce uint32
+	if prev, ok := s.sessions.Load(sessionID); ok {
+		senderNonce = atomic.LoadUint32(&prev.(*session).senderNonce)
+	}
+
 	s.sessions.Store(sessionID, &session{
 		ticketParams: ticketParams,
-		senderNonce:  0,
+		senderNonce:  senderNonce,
 	})
 
 	return sessionID
@@ -80,6 +129,114 @@ func (s *sender) EV(sessionID string) (*big.Rat, error) {
 	return ticketEV(session.ticketParams.FaceValue, session.ticketParams.WinProb), nil
 }
 
+// SpentEV returns the total EV of the tickets sent to a recipient
+func (s *sender) SpentEV(recipient ethcommon.Address) *big.Rat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	spent, ok := s.spent[recipient]
+	if !ok {
+		return big.NewRat(0, 1)
+	}
+
+	return new(big.Rat).Set(spent)
+}
+
+// MaxEV returns the max total EV of a ticket batch
+func (s *sender) MaxEV() *big.Rat {
+	return new(big.Rat).Set(s.maxEV)
+}
+
+// PendingEV returns the total EV of the sent tickets that can still be redeemed, less the
+// deposit that redemptions already drew
+func (s *sender) PendingEV() *big.Rat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.pendingEV()
+}
+
+// pendingEV returns the total EV of the tickets created in rounds that have not expired yet
+// and drops the EV of the expired rounds. The caller must hold s.mu
+func (s *sender) pendingEV() *big.Rat {
+	round := s.timeManager.LastInitializedRound().Int64()
+	total := big.NewRat(0, 1)
+	for creationRound, ev := range s.pending {
+		if creationRound+ticketValidityPeriod <= round {
+			delete(s.pending, creationRound)
+			continue
+		}
+		total.Add(total, ev)
+	}
+
+	return total
+}
+
+// recordEV records the EV of numTickets tickets sent to a recipient. The caller must hold s.mu
+func (s *sender) recordEV(ticketParams *TicketParams, expirationParams *TicketExpirationParams, numTickets int) {
+	ev := ticketEV(ticketParams.FaceValue, ticketParams.WinProb)
+	ev.Mul(ev, new(big.Rat).SetInt64(int64(numTickets)))
+
+	if spent, ok := s.spent[ticketParams.Recipient]; ok {
+		spent.Add(spent, ev)
+	} else {
+		s.spent[ticketParams.Recipient] = ev
+	}
+
+	if pending, ok := s.pending[expirationParams.CreationRound]; ok {
+		pending.Add(pending, ev)
+	} else {
+		s.pending[expirationParams.CreationRound] = new(big.Rat).Set(ev)
+	}
+}
+
+// releaseEV removes the EV of numTickets tickets that were recorded but not sent. The caller
+// must hold s.mu
+func (s *sender) releaseEV(ticketParams *TicketParams, expirationParams *TicketExpirationParams, numTickets int) {
+	ev := ticketEV(ticketParams.FaceValue, ticketParams.WinProb)
+	ev.Mul(ev, new(big.Rat).SetInt64(int64(numTickets)))
+
+	if spent, ok := s.spent[ticketParams.Recipient]; ok {
+		spent.Sub(spent, ev)
+	}
+	if pending, ok := s.pending[expirationParams.CreationRound]; ok {
+		if pending.Sub(pending, ev).Sign() <= 0 {
+			delete(s.pending, expirationParams.CreationRound)
+		}
+	}
+}
+
+// settleRedemptions removes the deposit drawn by the redemptions of winning tickets since the
+// deposit was last checked from the pending EV, starting with the oldest rounds, since the
+// deposit no longer covers the tickets that were redeemed. The caller must hold s.mu
+func (s *sender) settleRedemptions(deposit *big.Int) {
+	last := s.lastDeposit
+	s.lastDeposit = new(big.Int).Set(deposit)
+	if last == nil || deposit.Cmp(last) >= 0 {
+		return
+	}
+
+	drawn := new(big.Rat).SetInt(new(big.Int).Sub(last, deposit))
+	rounds := make([]int64, 0, len(s.pending))
+	for round := range s.pending {
+		rounds = append(rounds, round)
+	}
+	sort.Slice(rounds, func(i, j int) bool { return rounds[i] < rounds[j] })
+	for _, round := range rounds {
+		if drawn.Sign() <= 0 {
+			return
+		}
+		pending := s.pending[round]
+		if pending.Cmp(drawn) <= 0 {
+			drawn.Sub(drawn, pending)
+			delete(s.pending, round)
+			continue
+		}
+		pending.Sub(pending, drawn)
+		return
+	}
+}
+
 func (s *sender) validateSender(info *SenderInfo) error {
 	maxWithdrawRound := new(big.Int).Add(s.timeManager.LastInitializedRound(), big.NewInt(1))
 	if info.WithdrawRound.Int64() != 0 && info.WithdrawRound.Cmp(maxWithdrawRound) != 1 {
@@ -104,7 +261,9 @@ func (s *sender) CreateTicketBatch(sessionID string, size int) (*TicketBatch, er
 		return nil, err
 	}
 
+	s.mu.Lock()
 	if err := s.validateTicketParams(&session.ticketParams, size); err != nil {
+		s.mu.Unlock()
 		return nil, err
 	}
 
@@ -124,11 +283,20 @@ func (s *sender) CreateTicketBatch(sessionID string, size int) (*TicketBatch, er
 		Sender:                 s.signer.Account().Address,
 	}
 
+	// Reserve the sender nonces and the EV of the batch before releasing the lock, so that
+	// the tickets are signed without blocking the other sessions
+	lastNonce := atomic.AddUint32(&session.senderNonce, uint32(size))
+	s.recordEV(ticketParams, expirationParams, size)
+	s.mu.Unlock()
+
 	for i := 0; i < size; i++ {
-		senderNonce := atomic.AddUint32(&session.senderNonce, 1)
-		ticket := NewTicket(&session.ticketParams, expirationParams, s.signer.Account().Address, senderNonce)
-		sig, err := s.signer.Sign(ticket.Hash().Bytes())
+		senderNonce := lastNonce - uint32(size-1-i)
+		ticket := NewTicket(ticketParams, expirationParams, s.signer.Account().Address, senderNonce)
+		sig, err := signTicket(s.signer, ticket)
 		if err != nil {
+			s.mu.Lock()
+			s.releaseEV(ticketParams, expirationParams, size)
+			s.mu.Unlock()
 			return nil, errors.Wrapf(err, "error signing ticket for session: %v", sessionID)
 		}
 
@@ -140,12 +308,24 @@ func (s *sender) CreateTicketBatch(sessionID string, size int) (*TicketBatch, er
 
 // ValidateTicketParams checks if ticket params are acceptable
 func (s *sender) ValidateTicketParams(ticketParams *TicketParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// Check for sending a single ticket
 	return s.validateTicketParams(ticketParams, 1)
 }
 
-// validateTicketParams checks if ticket params are acceptable for a specific number of tickets
+// validateTicketParams checks if ticket params are acceptable for a specific number of tickets.
+// The caller must hold s.mu
 func (s *sender) validateTicketParams(ticketParams *TicketParams, numTickets int) error {
+	if !validTicketVersion(ticketParams.Version) {
+		return errUnsupportedTicketVersion
+	}
+
+	if err := s.validatePrice(ticketParams); err != nil {
+		return err
+	}
+
 	if ticketParams.ExpirationBlock.Int64() == 0 {
 		return nil
 	}
@@ -155,6 +335,8 @@ func (s *sender) validateTicketParams(ticketParams *TicketParams, numTickets int
 		return ErrTicketParamsExpired
 	}
 
+	// Tickets without value, like the free tickets of the free tier, can't draw from the
+	// deposit of the sender, which doesn't need one to send them
 	ev := ticketEV(ticketParams.FaceValue, ticketParams.WinProb)
 	if ev.Cmp(big.NewRat(0, 1)) <= 0 {
 		return nil
@@ -168,6 +350,7 @@ func (s *sender) validateTicketParams(ticketParams *TicketParams, numTickets int
 	if err := s.validateSender(info); err != nil {
 		return err
 	}
+	s.settleRedemptions(info.Deposit)
 
 	totalEV := ev.Mul(ev, new(big.Rat).SetInt64(int64(numTickets)))
 	if totalEV.Cmp(s.maxEV) > 0 {
@@ -179,6 +362,27 @@ func (s *sender) validateTicketParams(ticketParams *TicketParams, numTickets int
 		return fmt.Errorf("ticket faceValue %v > max faceValue %v", ticketParams.FaceValue, maxFaceValue)
 	}
 
+	// The tickets that can still be redeemed must not be able to draw more than the deposit
+	pendingEV := new(big.Rat).Add(s.pendingEV(), totalEV)
+	if pendingEV.Cmp(new(big.Rat).SetInt(info.Deposit)) > 0 {
+		return fmt.Errorf("pending ticket EV %v > deposit %v", pendingEV.FloatString(5), info.Deposit)
+	}
+
+	return nil
+}
+
+// validatePrice checks the price per pixel that ticket params are created for against the max
+// price of the sender
+func (s *sender) validatePrice(ticketParams *TicketParams) error {
+	if s.maxPrice == nil || ticketParams.PricePerPixel == nil {
+		return nil
+	}
+
+	maxPrice := s.maxPrice.MaxPrice()
+	if maxPrice != nil && ticketParams.PricePerPixel.Cmp(maxPrice) > 0 {
+		return fmt.Errorf("ticket params price per pixel %v > max price per pixel %v", ticketParams.PricePerPixel.FloatString(5), maxPrice.FloatString(5))
+	}
+
 	return nil
 }
 
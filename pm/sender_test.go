@@ -5,6 +5,7 @@ import (
 	"math/big"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	ethcommon "github.com/ethereum/go-ethereum/common"
@@ -65,6 +66,24 @@ func TestStartSession_GivenConcurrentUsage_RecordsAllSessions(t *testing.T) {
 	}
 }
 
+func TestStartSession_ExistingSession_KeepsSenderNonce(t *testing.T) {
+	assert := assert.New(t)
+	sender := defaultSender(t)
+	ticketParams := defaultTicketParams(t, RandAddress())
+	sessionID := sender.StartSession(ticketParams)
+
+	_, err := sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(t, err)
+
+	// Restarting the session doesn't reuse sender nonces, but uses the new params
+	ticketParams.FaceValue = big.NewInt(200)
+	assert.Equal(sessionID, sender.StartSession(ticketParams))
+	batch, err := sender.CreateTicketBatch(sessionID, 1)
+	require.Nil(t, err)
+	assert.Equal(uint32(3), batch.SenderParams[0].SenderNonce)
+	assert.Equal(big.NewInt(200), batch.FaceValue)
+}
+
 func TestSenderEV_NonExistantSession_ReturnsError(t *testing.T) {
 	sender := defaultSender(t)
 
@@ -184,6 +203,25 @@ func TestCreateTicketBatch_GetSenderInfoError_ReturnsError(t *testing.T) {
 	assert.EqualError(t, err, "GetSenderInfo error")
 }
 
+func TestCreateTicketBatch_FreeTickets_NoSenderInfo(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := defaultSender(t)
+	sm := sender.senderManager.(*stubSenderManager)
+	sm.err = errors.New("GetSenderInfo error")
+
+	params := defaultTicketParams(t, RandAddress())
+	params.FaceValue = big.NewInt(0)
+	params.WinProb = big.NewInt(0)
+	sessionID := sender.StartSession(params)
+
+	batch, err := sender.CreateTicketBatch(sessionID, 2)
+	assert.Nil(err)
+	assert.Len(batch.SenderParams, 2)
+	assert.Equal(uint32(2), batch.SenderParams[1].SenderNonce)
+	assert.Zero(sender.SpentEV(params.Recipient).Sign())
+}
+
 func TestCreateTicketBatch_EVTooHigh_ReturnsError(t *testing.T) {
 	// Test single ticket EV too high
 	sender := defaultSender(t)
@@ -375,6 +413,26 @@ func TestCreateTicketBatch_MultipleTickets(t *testing.T) {
 	}
 }
 
+func TestCreateTicketBatch_TypedData(t *testing.T) {
+	sender := defaultSender(t)
+	am := sender.signer.(*stubSigner)
+	am.saveSignRequest = true
+	am.signResponse = RandBytes(42)
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.Version = TicketVersionTypedData
+	sessionID := sender.StartSession(ticketParams)
+
+	batch, err := sender.CreateTicketBatch(sessionID, 2)
+	require.Nil(t, err)
+
+	assert := assert.New(t)
+	tickets := batch.Tickets()
+	for i := 0; i < 2; i++ {
+		assert.Equal(TicketVersionTypedData, tickets[i].Version)
+		assert.Equal(tickets[i].TypedData().Hash().Bytes(), am.signRequests[i])
+	}
+}
+
 func TestCreateTicketBatch_SigningError_ReturnsError(t *testing.T) {
 	sender := defaultSender(t)
 	recipient := RandAddress()
@@ -428,6 +486,159 @@ func TestCreateTicketBatch_ConcurrentCallsForSameSession_SenderNonceIncrementsCo
 	assert.Equal(totalTickets, len(uniqueNonces))
 }
 
+func TestCreateTicketBatch_RecordsSpentAndPendingEV(t *testing.T) {
+	assert := assert.New(t)
+	sender := defaultSender(t)
+	tm := sender.timeManager.(*stubTimeManager)
+	recipient1 := RandAddress()
+	recipient2 := RandAddress()
+
+	assert.Zero(sender.SpentEV(recipient1).Sign())
+	assert.Zero(sender.PendingEV().Sign())
+
+	ticketParams1 := defaultTicketParams(t, recipient1)
+	ticketParams1.WinProb = new(big.Int).Div(maxWinProb, big.NewInt(2))
+	ticketParams2 := defaultTicketParams(t, recipient2)
+	ticketParams2.FaceValue = big.NewInt(20)
+	ticketParams2.WinProb = new(big.Int).Div(maxWinProb, big.NewInt(2))
+	session1 := sender.StartSession(ticketParams1)
+	session2 := sender.StartSession(ticketParams2)
+
+	_, err := sender.CreateTicketBatch(session1, 2)
+	require.Nil(t, err)
+	_, err = sender.CreateTicketBatch(session1, 1)
+	require.Nil(t, err)
+	_, err = sender.CreateTicketBatch(session2, 1)
+	require.Nil(t, err)
+
+	ev1 := ticketEV(ticketParams1.FaceValue, ticketParams1.WinProb)
+	ev2 := ticketEV(ticketParams2.FaceValue, ticketParams2.WinProb)
+	expSpent1 := new(big.Rat).Mul(ev1, big.NewRat(3, 1))
+	assert.Zero(expSpent1.Cmp(sender.SpentEV(recipient1)))
+	assert.Zero(ev2.Cmp(sender.SpentEV(recipient2)))
+	assert.Zero(new(big.Rat).Add(expSpent1, ev2).Cmp(sender.PendingEV()))
+
+	// Failed batches are not recorded
+	sender.signer.(*stubSigner).signShouldFail = true
+	_, err = sender.CreateTicketBatch(session2, 1)
+	assert.NotNil(err)
+	assert.Zero(ev2.Cmp(sender.SpentEV(recipient2)))
+	sender.signer.(*stubSigner).signShouldFail = false
+
+	// The EV of tickets that expired is no longer pending, but is still spent
+	tm.round = big.NewInt(tm.round.Int64() + ticketValidityPeriod)
+	assert.Zero(sender.PendingEV().Sign())
+	assert.Len(sender.pending, 0)
+	assert.Zero(expSpent1.Cmp(sender.SpentEV(recipient1)))
+}
+
+func TestCreateTicketBatch_PendingEVTooHigh_ReturnsError(t *testing.T) {
+	assert := assert.New(t)
+	sender := defaultSender(t)
+	tm := sender.timeManager.(*stubTimeManager)
+	senderAddr := sender.signer.Account().Address
+	sm := sender.senderManager.(*stubSenderManager)
+	sm.info[senderAddr].Deposit = big.NewInt(400)
+
+	// EV = 100 per ticket
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue = big.NewInt(200)
+	ticketParams.WinProb = new(big.Int).Div(maxWinProb, big.NewInt(2))
+	sessionID := sender.StartSession(ticketParams)
+
+	for i := 0; i < 4; i++ {
+		_, err := sender.CreateTicketBatch(sessionID, 1)
+		require.Nil(t, err)
+	}
+
+	_, err := sender.CreateTicketBatch(sessionID, 1)
+	assert.EqualError(err, "pending ticket EV 500.00000 > deposit 400")
+	assert.EqualError(sender.ValidateTicketParams(&ticketParams), "pending ticket EV 500.00000 > deposit 400")
+
+	// Tickets can be sent again once earlier tickets expire
+	tm.round = big.NewInt(tm.round.Int64() + ticketValidityPeriod)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Nil(err)
+}
+
+func TestCreateTicketBatch_Redemptions_SettlePendingEV(t *testing.T) {
+	assert := assert.New(t)
+	sender := defaultSender(t)
+	tm := sender.timeManager.(*stubTimeManager)
+	senderAddr := sender.signer.Account().Address
+	sm := sender.senderManager.(*stubSenderManager)
+	sm.info[senderAddr].Deposit = big.NewInt(600)
+
+	// EV = 100 per ticket
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.FaceValue = big.NewInt(200)
+	ticketParams.WinProb = new(big.Int).Div(maxWinProb, big.NewInt(2))
+	sessionID := sender.StartSession(ticketParams)
+
+	firstRound := tm.round.Int64()
+	for i := 0; i < 6; i++ {
+		if i == 3 {
+			// Tickets of a later round
+			tm.round = new(big.Int).Add(tm.round, big.NewInt(1))
+		}
+		_, err := sender.CreateTicketBatch(sessionID, 1)
+		require.Nil(t, err)
+	}
+	assert.Equal("600", sender.PendingEV().FloatString(0))
+	_, err := sender.CreateTicketBatch(sessionID, 1)
+	assert.EqualError(err, "pending ticket EV 700.00000 > deposit 600")
+
+	// The redemption of a winning ticket draws its face value from the deposit, and the
+	// tickets it covers, starting with the oldest round, are no longer pending
+	sm.info[senderAddr].Deposit = big.NewInt(400)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.EqualError(err, "pending ticket EV 500.00000 > deposit 400")
+	assert.Equal("400", sender.PendingEV().FloatString(0))
+	assert.Equal("100", sender.pending[firstRound].FloatString(0))
+
+	// Deposit increases don't change the pending EV
+	sm.info[senderAddr].Deposit = big.NewInt(1000)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.Nil(err)
+	assert.Equal("500", sender.PendingEV().FloatString(0))
+}
+
+func TestCreateTicketBatch_SignsOutsideLock(t *testing.T) {
+	sender := defaultSender(t)
+	signer := &blockingSigner{stubSigner: sender.signer.(*stubSigner), started: make(chan struct{}), release: make(chan struct{})}
+	sender.signer = signer
+	sessionID := sender.StartSession(defaultTicketParams(t, RandAddress()))
+
+	go sender.CreateTicketBatch(sessionID, 1)
+	<-signer.started
+	defer close(signer.release)
+
+	// The sender can be used by other sessions while a batch is signed
+	done := make(chan struct{})
+	go func() {
+		sender.PendingEV()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("sender is locked while a ticket batch is signed")
+	}
+}
+
+type blockingSigner struct {
+	*stubSigner
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSigner) Sign(msg []byte) ([]byte, error) {
+	s.once.Do(func() { close(s.started) })
+	<-s.release
+	return s.stubSigner.Sign(msg)
+}
+
 func TestValidateParams_ValidateSender(t *testing.T) {
 	sender := defaultSender(t)
 	sm := sender.senderManager.(*stubSenderManager)
@@ -505,6 +716,60 @@ func TestValidateTicketParams_ExpiredParams_ReturnsError(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+type stubMaxPriceSource struct {
+	maxPrice *big.Rat
+}
+
+func (s *stubMaxPriceSource) MaxPrice() *big.Rat {
+	return s.maxPrice
+}
+
+func TestValidateTicketParams_PriceTooHigh_ReturnsError(t *testing.T) {
+	assert := assert.New(t)
+	sender := defaultSender(t)
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.PricePerPixel = big.NewRat(11, 10)
+
+	// Test no max price source
+	assert.Nil(sender.ValidateTicketParams(&ticketParams))
+
+	// Test no max price
+	maxPrice := &stubMaxPriceSource{}
+	sender.maxPrice = maxPrice
+	assert.Nil(sender.ValidateTicketParams(&ticketParams))
+
+	// Test price > max price
+	maxPrice.maxPrice = big.NewRat(1, 1)
+	err := sender.ValidateTicketParams(&ticketParams)
+	assert.EqualError(err, "ticket params price per pixel 1.10000 > max price per pixel 1.00000")
+
+	// Test no tickets are created for a session with a price > max price
+	sessionID := sender.StartSession(ticketParams)
+	_, err = sender.CreateTicketBatch(sessionID, 1)
+	assert.EqualError(err, "ticket params price per pixel 1.10000 > max price per pixel 1.00000")
+
+	// Test price = max price
+	maxPrice.maxPrice = big.NewRat(11, 10)
+	assert.Nil(sender.ValidateTicketParams(&ticketParams))
+
+	// Test unset price
+	maxPrice.maxPrice = big.NewRat(1, 1)
+	ticketParams.PricePerPixel = nil
+	assert.Nil(sender.ValidateTicketParams(&ticketParams))
+}
+
+func TestValidateTicketParams_UnsupportedVersion_ReturnsError(t *testing.T) {
+	sender := defaultSender(t)
+	ticketParams := defaultTicketParams(t, RandAddress())
+	ticketParams.Version = TicketVersionTypedData + 1
+	err := sender.ValidateTicketParams(&ticketParams)
+	assert.EqualError(t, err, errUnsupportedTicketVersion.Error())
+
+	ticketParams.Version = TicketVersionTypedData
+	err = sender.ValidateTicketParams(&ticketParams)
+	assert.Nil(t, err)
+}
+
 func TestValidateTicketParams_GetSenderInfoError(t *testing.T) {
 	sender := defaultSender(t)
 	sm := sender.senderManager.(*stubSenderManager)
@@ -589,7 +854,7 @@ func defaultSender(t *testing.T) *sender {
 		Reserve:       &ReserveInfo{FundsRemaining: big.NewInt(10)},
 		WithdrawRound: big.NewInt(0),
 	}
-	s := NewSender(am, tm, sm, big.NewRat(100, 1), 2)
+	s := NewSender(am, tm, sm, big.NewRat(100, 1), 2, nil)
 	return s.(*sender)
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"strconv"
 	"sync"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/audit"
 	"github.com/livepeer/go-livepeer/monitor"
 	"github.com/pkg/errors"
 )
@@ -19,6 +21,26 @@ import (
 // pending amount to be ignored when calculating the sender's max float
 const minDepositPendingRatio = 3.0
 
+var errMonitorStopped = errors.New("sender monitor stopped")
+
+// ErrTxQueuedForSigning is returned for the transactions that are queued to be signed offline
+// rather than sent. It is defined here rather than in the eth package, which depends on pm, so
+// that the redemptions waiting for an offline signer aren't counted as failed attempts
+var ErrTxQueuedForSigning = errors.New("transaction queued for offline signing")
+
+// failedRedemptionsBufferSize is the number of failed redemptions that are kept until they
+// are received from the channel returned by LocalSenderMonitor.FailedRedemptions()
+const failedRedemptionsBufferSize = 100
+
+// FailedRedemption is a winning ticket that is given up on because its redemption failed
+// the max number of attempts
+type FailedRedemption struct {
+	Ticket   *SignedTicket
+	Attempts int
+	// Err is the error of the last redemption attempt
+	Err error
+}
+
 // unixNow returns the current unix time
 // This is a wrapper function that can be stubbed in tests
 var unixNow = func() int64 {
@@ -37,6 +59,12 @@ type SenderMonitor interface {
 	// QueueTicket adds a ticket to the queue for a remote sender
 	QueueTicket(ticket *SignedTicket) error
 
+	// WinningTickets returns the queued winning tickets of all remote senders
+	WinningTickets() ([]*SignedTicket, error)
+
+	// RemoveWinningTickets removes queued winning tickets, which are not redeemed anymore
+	RemoveWinningTickets(tickets []*SignedTicket) error
+
 	// MaxFloat returns a remote sender's max float
 	MaxFloat(addr ethcommon.Address) (*big.Int, error)
 
@@ -45,12 +73,21 @@ type SenderMonitor interface {
 }
 
 type remoteSender struct {
-	// pendingAmount is the sum of the face values of tickets that are
-	// currently pending redemption on-chain
+	// pendingAmount is the sum of the face values of winning tickets that are
+	// queued or pending redemption on-chain, i.e. not yet redeemed
 	pendingAmount *big.Int
 
 	queue *ticketQueue
 
+	// brokerReserveAlloc is the allocation of the reserve of the sender that the TicketBroker
+	// lets the claimant claim in reserveAllocRound, nil if it could not be fetched.
+	// reserveAllocGen is incremented when it is cleared, so that a fetch that started before
+	// doesn't set an outdated allocation
+	brokerReserveAlloc *big.Int
+	reserveAllocRound  int64
+	reserveAllocSet    bool
+	reserveAllocGen    uint64
+
 	// Max float subscriptions
 	subFeed  event.Feed
 	subScope event.SubscriptionScope
@@ -72,6 +109,23 @@ type LocalSenderMonitorConfig struct {
 	RedeemGas       int
 	SuggestGasPrice func(context.Context) (*big.Int, error)
 	RPCTimeout      time.Duration
+
+	// The number of winning tickets of a sender that are redeemed with a single transaction,
+	// and the longest time that redeemable tickets wait for a full batch. Tickets are redeemed
+	// one at a time if RedeemBatchSize is less than 2
+	RedeemBatchSize     int
+	RedeemBatchInterval time.Duration
+
+	// The time that a winning ticket waits before its redemption is retried after the first
+	// failed attempt, which doubles with every failed attempt up to RedeemMaxRetryBackoff, and
+	// the number of failed attempts after which a ticket is given up on. Tickets are retried
+	// until they expire if RedeemMaxAttempts is 0
+	RedeemRetryBackoff    time.Duration
+	RedeemMaxRetryBackoff time.Duration
+	RedeemMaxAttempts     int
+
+	// Log of ticket redemptions, may be nil
+	AuditLog *audit.Log
 }
 
 type LocalSenderMonitor struct {
@@ -91,7 +145,10 @@ type LocalSenderMonitor struct {
 
 	ticketStore TicketStore
 
-	quit chan struct{}
+	failedRedemptions chan *FailedRedemption
+
+	quit     chan struct{}
+	stopOnce sync.Once
 }
 
 // NewSenderMonitor returns a new SenderMonitor
@@ -105,11 +162,15 @@ func NewSenderMonitor(cfg *LocalSenderMonitorConfig, broker Broker, smgr SenderM
 		redeemable:  make(chan *redemption),
 		ticketStore: store,
 		quit:        make(chan struct{}),
+
+		failedRedemptions: make(chan *FailedRedemption, failedRedemptionsBufferSize),
 	}
 }
 
-// Start initiates the helper goroutines for the monitor
+// Start initiates the helper goroutines for the monitor and resumes the ticket queues of
+// the senders with winning tickets left in the ticket store, e.g. by a restart
 func (sm *LocalSenderMonitor) Start() {
+	sm.resumeTicketQueues()
 	go sm.startCleanupLoop()
 	go sm.watchReserveChange()
 	go sm.watchPoolSizeChange()
@@ -117,7 +178,50 @@ func (sm *LocalSenderMonitor) Start() {
 
 // Stop signals the monitor to exit gracefully
 func (sm *LocalSenderMonitor) Stop() {
-	close(sm.quit)
+	sm.stopOnce.Do(func() { close(sm.quit) })
+}
+
+// Shutdown stops the monitor and waits until the ticket redemptions in progress are
+// complete or ctx is done. Winning tickets that are not redeemed remain in the ticket
+// store and are queued for redemption again when the node restarts
+func (sm *LocalSenderMonitor) Shutdown(ctx context.Context) error {
+	sm.Stop()
+
+	sm.mu.Lock()
+	queues := make([]*ticketQueue, 0, len(sm.senders))
+	for _, s := range sm.senders {
+		queues = append(queues, s.queue)
+	}
+	sm.mu.Unlock()
+
+	for _, q := range queues {
+		select {
+		case <-q.stopped:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// resumeTicketQueues starts the ticket queues of the senders with non-redeemed winning
+// tickets in the ticket store
+func (sm *LocalSenderMonitor) resumeTicketQueues() {
+	senders, err := sm.ticketStore.WinningTicketSenders()
+	if err != nil {
+		glog.Errorf("Unable to resume ticket redemptions err=%v", err)
+		return
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	for _, sender := range senders {
+		sm.ensureCache(sender)
+	}
+	if len(senders) > 0 {
+		glog.Infof("Resumed ticket redemptions for senders=%v", len(senders))
+	}
 }
 
 // addFloat adds to a remote sender's max float
@@ -153,6 +257,8 @@ func (sm *LocalSenderMonitor) subFloat(addr ethcommon.Address, amount *big.Int)
 
 // MaxFloat returns a remote sender's max float
 func (sm *LocalSenderMonitor) MaxFloat(addr ethcommon.Address) (*big.Int, error) {
+	sm.fetchReserveAlloc(addr)
+
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
@@ -161,14 +267,86 @@ func (sm *LocalSenderMonitor) MaxFloat(addr ethcommon.Address) (*big.Int, error)
 	return sm.maxFloat(addr)
 }
 
-// QueueTicket adds a ticket to the queue for a remote sender
+// QueueTicket adds a ticket to the queue for a remote sender. The face value of the ticket
+// is subtracted from the sender's max float until the ticket leaves the queue, i.e. until it
+// is redeemed or expires
 func (sm *LocalSenderMonitor) QueueTicket(ticket *SignedTicket) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
 	sm.ensureCache(ticket.Sender)
 
-	return sm.senders[ticket.Sender].queue.Add(ticket)
+	if err := sm.senders[ticket.Sender].queue.Add(ticket); err != nil {
+		return err
+	}
+
+	pendingAmount := sm.senders[ticket.Sender].pendingAmount
+	pendingAmount.Add(pendingAmount, ticket.FaceValue)
+	sm.sendMaxFloatChange(ticket.Sender)
+	return nil
+}
+
+// WinningTickets returns the queued winning tickets of all senders, i.e. the tickets that
+// are not yet redeemed, including the tickets waiting to retry a failed redemption
+func (sm *LocalSenderMonitor) WinningTickets() ([]*SignedTicket, error) {
+	senders, err := sm.ticketStore.WinningTicketSenders()
+	if err != nil {
+		return nil, err
+	}
+
+	tickets := []*SignedTicket{}
+	for _, sender := range senders {
+		senderTickets, err := sm.ticketStore.WinningTickets(sender)
+		if err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, senderTickets...)
+	}
+	return tickets, nil
+}
+
+// RemoveWinningTickets removes queued winning tickets from the ticket store, e.g. once they
+// are exported to another node, and releases their face value from the max float of their
+// senders
+func (sm *LocalSenderMonitor) RemoveWinningTickets(tickets []*SignedTicket) error {
+	for _, ticket := range tickets {
+		if err := sm.ticketStore.RemoveWinningTicket(ticket); err != nil {
+			return err
+		}
+		sm.releaseFloat(ticket)
+	}
+	return nil
+}
+
+// releaseFloat adds the face value of a ticket that left the queue back to the sender's
+// max float. The redemption of the ticket claims from the reserve of the sender, so its
+// allocation is fetched again
+func (sm *LocalSenderMonitor) releaseFloat(ticket *SignedTicket) {
+	sm.mu.Lock()
+	sm.clearReserveAlloc(ticket.Sender)
+	sm.mu.Unlock()
+
+	if err := sm.addFloat(ticket.Sender, ticket.FaceValue); err != nil {
+		glog.Error(err)
+	}
+}
+
+// FailedRedemptions returns a channel that a caller can use to receive the winning tickets
+// that are given up on because their redemption failed the max number of attempts
+func (sm *LocalSenderMonitor) FailedRedemptions() <-chan *FailedRedemption {
+	return sm.failedRedemptions
+}
+
+// redemptionFailed notifies the receivers of FailedRedemptions() of a ticket that is given up on
+func (sm *LocalSenderMonitor) redemptionFailed(ticket *SignedTicket, attempts int, err error) {
+	getMetrics().RedemptionAbandoned(ticket.Ticket.Sender.String())
+	sm.auditRedemption(audit.EventTicketRedemptionAbandoned, ticket, nil, err)
+
+	select {
+	case sm.failedRedemptions <- &FailedRedemption{Ticket: ticket, Attempts: attempts, Err: err}:
+	default:
+		glog.Errorf("Dropped failed redemption notification sender=%v recipientRandHash=%x senderNonce=%v", ticket.Sender.Hex(), ticket.RecipientRandHash, ticket.SenderNonce)
+	}
 }
 
 // ValidateSender checks whether a sender's unlock period ends the round after the next round
@@ -213,6 +391,11 @@ func (sm *LocalSenderMonitor) maxFloat(addr ethcommon.Address) (*big.Int, error)
 	return new(big.Int).Sub(reserveAlloc, pendingAmount), nil
 }
 
+// reserveAlloc returns the allocation of the reserve of a sender for the claimant, i.e. the
+// reserve divided by the number of active orchestrators minus what the claimant already
+// claimed in the current round. It is capped by the allocation of the TicketBroker, which is
+// 0 if the claimant is not an active orchestrator
+// Caller should hold the lock for LocalSenderMonitor
 func (sm *LocalSenderMonitor) reserveAlloc(addr ethcommon.Address) (*big.Int, error) {
 	info, err := sm.smgr.GetSenderInfo(addr)
 	if err != nil {
@@ -227,11 +410,98 @@ func (sm *LocalSenderMonitor) reserveAlloc(addr ethcommon.Address) (*big.Int, er
 		return big.NewInt(0), nil
 	}
 	reserve := new(big.Int).Add(info.Reserve.FundsRemaining, info.Reserve.ClaimedInCurrentRound)
-	return new(big.Int).Sub(new(big.Int).Div(reserve, poolSize), claimed), nil
+	alloc := new(big.Int).Sub(new(big.Int).Div(reserve, poolSize), claimed)
+
+	if brokerAlloc := sm.brokerReserveAlloc(addr); brokerAlloc != nil && brokerAlloc.Cmp(alloc) < 0 {
+		return new(big.Int).Set(brokerAlloc), nil
+	}
+	return alloc, nil
+}
+
+// brokerReserveAlloc returns the cached allocation of the reserve of a sender that the
+// TicketBroker lets the claimant claim, which is fetched by fetchReserveAlloc(). It is nil if
+// it could not be fetched, in which case the allocation is only computed from the cached
+// sender info
+// Caller should hold the lock for LocalSenderMonitor
+func (sm *LocalSenderMonitor) brokerReserveAlloc(addr ethcommon.Address) *big.Int {
+	rs, ok := sm.senders[addr]
+	if !ok {
+		return nil
+	}
+	return rs.brokerReserveAlloc
+}
+
+// fetchReserveAlloc fetches the allocation of the reserve of a sender that the TicketBroker
+// lets the claimant claim in the current round, unless it is already cached for the round.
+// It is fetched once per round by the round watcher, and again when the reserve of the sender
+// changes or its tickets are redeemed. The lock for LocalSenderMonitor is not held during
+// the RPC call, so that it doesn't block the other senders
+// Caller should not hold the lock for LocalSenderMonitor
+func (sm *LocalSenderMonitor) fetchReserveAlloc(addr ethcommon.Address) {
+	sm.mu.Lock()
+	sm.ensureCache(addr)
+	rs := sm.senders[addr]
+	sm.mu.Unlock()
+
+	sm.refreshReserveAlloc(addr, rs)
+}
+
+// fetchReserveAllocs fetches the allocations of the TicketBroker for all the cached senders,
+// without counting as an access to the senders
+func (sm *LocalSenderMonitor) fetchReserveAllocs() {
+	sm.mu.Lock()
+	senders := make(map[ethcommon.Address]*remoteSender, len(sm.senders))
+	for addr, rs := range sm.senders {
+		senders[addr] = rs
+	}
+	sm.mu.Unlock()
+
+	for addr, rs := range senders {
+		sm.refreshReserveAlloc(addr, rs)
+	}
+}
+
+func (sm *LocalSenderMonitor) refreshReserveAlloc(addr ethcommon.Address, rs *remoteSender) {
+	round := sm.tm.LastInitializedRound().Int64()
+
+	sm.mu.Lock()
+	if rs.reserveAllocSet && rs.reserveAllocRound == round {
+		sm.mu.Unlock()
+		return
+	}
+	gen := rs.reserveAllocGen
+	sm.mu.Unlock()
+
+	alloc, err := sm.broker.ReserveAlloc(addr, sm.cfg.Claimant)
+	if err != nil {
+		glog.Errorf("Unable to get reserve alloc sender=%v err=%v", addr.Hex(), err)
+		alloc = nil
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if rs.reserveAllocGen != gen {
+		return
+	}
+	rs.brokerReserveAlloc = alloc
+	rs.reserveAllocRound = round
+	rs.reserveAllocSet = true
+}
+
+// clearReserveAlloc drops the allocation of the TicketBroker for a sender, which is fetched
+// again the next time it is needed
+// Caller should hold the lock for LocalSenderMonitor
+func (sm *LocalSenderMonitor) clearReserveAlloc(addr ethcommon.Address) {
+	if rs, ok := sm.senders[addr]; ok {
+		rs.reserveAllocSet = false
+		rs.reserveAllocGen++
+	}
 }
 
 // Returns the current available funds for a sender that could cover redemptions
 func (sm *LocalSenderMonitor) availableFunds(addr ethcommon.Address) (*big.Int, error) {
+	sm.fetchReserveAlloc(addr)
+
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
@@ -252,6 +522,25 @@ func (sm *LocalSenderMonitor) availableFunds(addr ethcommon.Address) (*big.Int,
 	return new(big.Int).Sub(new(big.Int).Add(reserveAlloc, info.Deposit), pendingAmount), nil
 }
 
+// redemptionFunds returns the available funds for a sender that could cover the redemption of
+// tickets with a total face value of amount. The tickets being redeemed are queued, so their
+// face value is part of the pending amount of the sender but must not be covered twice
+func (sm *LocalSenderMonitor) redemptionFunds(addr ethcommon.Address, amount *big.Int) (*big.Int, error) {
+	funds, err := sm.availableFunds(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	pendingAmount := sm.senders[addr].pendingAmount
+	if pendingAmount.Cmp(amount) < 0 {
+		return funds.Add(funds, pendingAmount), nil
+	}
+	return funds.Add(funds, amount), nil
+}
+
 // ensureCache is a helper that checks if a remote sender is initialized
 // and if not will fetch and cache the remote sender's reserve alloc
 // Caller should hold the lock for LocalSenderMonitor
@@ -268,13 +557,27 @@ func (sm *LocalSenderMonitor) ensureCache(addr ethcommon.Address) {
 // Caller should hold the lock for LocalSenderMonitor unless the caller is
 // ensureCache() in which case the caller of ensureCache() should hold the lock
 func (sm *LocalSenderMonitor) cache(addr ethcommon.Address) {
-	queue := newTicketQueue(sm.ticketStore, addr, sm.tm.SubscribeBlocks)
+	queue := newTicketQueue(sm.ticketStore, addr, sm.tm)
+	queue.batchSize = sm.cfg.RedeemBatchSize
+	queue.batchInterval = sm.cfg.RedeemBatchInterval
+	queue.retryBackoff = sm.cfg.RedeemRetryBackoff
+	queue.maxRetryBackoff = sm.cfg.RedeemMaxRetryBackoff
+	queue.maxAttempts = sm.cfg.RedeemMaxAttempts
+	queue.onRemove = sm.releaseFloat
+	queue.onFail = sm.redemptionFailed
 	queue.Start()
 	done := make(chan struct{})
 	go sm.startTicketQueueConsumerLoop(queue, done)
 
+	// The tickets left in the store, e.g. by a restart, are still pending
+	pendingAmount, err := sm.ticketStore.WinningTicketFaceValue(addr)
+	if err != nil {
+		glog.Errorf("Unable to get face value of winning tickets sender=%v err=%v", addr.Hex(), err)
+		pendingAmount = big.NewInt(0)
+	}
+
 	sm.senders[addr] = &remoteSender{
-		pendingAmount: big.NewInt(0),
+		pendingAmount: pendingAmount,
 		queue:         queue,
 		done:          done,
 		lastAccess:    unixNow(),
@@ -285,10 +588,30 @@ func (sm *LocalSenderMonitor) cache(addr ethcommon.Address) {
 // that receives redeemable tickets from a ticketQueue and feeds them into
 // a single output channel in a fan-in manner
 func (sm *LocalSenderMonitor) startTicketQueueConsumerLoop(queue *ticketQueue, done chan struct{}) {
+	defer monitor.RecoverAndReport()
 	for {
 		select {
 		case red := <-queue.Redeemable():
-			tx, err := sm.redeemWinningTicket(red.SignedTicket)
+			select {
+			case <-sm.quit:
+				// Do not submit new redemptions once the monitor is stopped
+				red.resCh <- struct {
+					txHash ethcommon.Hash
+					err    error
+				}{ethcommon.Hash{}, errMonitorStopped}
+				queue.Stop()
+
+				return
+			default:
+			}
+
+			var tx *types.Transaction
+			var err error
+			if red.batch != nil {
+				tx, red.unredeemed, err = sm.redeemWinningTickets(red.batch)
+			} else {
+				tx, err = sm.redeemWinningTicket(red.SignedTicket)
+			}
 			if err != nil {
 				red.resCh <- struct {
 					txHash ethcommon.Hash
@@ -319,6 +642,7 @@ func (sm *LocalSenderMonitor) startTicketQueueConsumerLoop(queue *ticketQueue, d
 // startCleanupLoop initiates a loop that runs a cleanup worker
 // every cleanupInterval
 func (sm *LocalSenderMonitor) startCleanupLoop() {
+	defer monitor.RecoverAndReport()
 	ticker := time.NewTicker(sm.cfg.CleanupInterval)
 
 	for {
@@ -332,13 +656,18 @@ func (sm *LocalSenderMonitor) startCleanupLoop() {
 }
 
 // cleanup removes tracked remote senders that have exceeded
-// their ttl
+// their ttl. Senders with queued tickets are kept until their tickets
+// are redeemed or expire, so that the tickets of inactive senders are
+// redeemed as well
 func (sm *LocalSenderMonitor) cleanup() {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
 	for k, v := range sm.senders {
 		if unixNow()-v.lastAccess > int64(sm.cfg.TTL) && v.subScope.Count() == 0 {
+			if qlen, err := v.queue.Length(); err != nil || qlen > 0 {
+				continue
+			}
 			// Signal the ticket queue consumer to exit gracefully
 			v.done <- struct{}{}
 			v.subScope.Close() // close the maxfloat subscriptions
@@ -349,7 +678,7 @@ func (sm *LocalSenderMonitor) cleanup() {
 }
 
 func (sm *LocalSenderMonitor) redeemWinningTicket(ticket *SignedTicket) (*types.Transaction, error) {
-	availableFunds, err := sm.availableFunds(ticket.Sender)
+	availableFunds, err := sm.redemptionFunds(ticket.Sender, ticket.Ticket.FaceValue)
 	if err != nil {
 		return nil, err
 	}
@@ -369,53 +698,137 @@ func (sm *LocalSenderMonitor) redeemWinningTicket(ticket *SignedTicket) (*types.
 		return nil, errors.New("insufficient sender funds for redeem tx cost")
 	}
 
-	// Subtract the ticket face value from the sender's current max float
-	// This amount will be considered pending until the ticket redemption
-	// transaction confirms on-chain
-	sm.subFloat(ticket.Ticket.Sender, ticket.Ticket.FaceValue)
-
-	defer func() {
-		// Add the ticket face value back to the sender's current max float
-		// This amount is no longer considered pending since the ticket
-		// redemption transaction either confirmed on-chain or was not
-		// submitted at all
-		//
-		// TODO(yondonfu): Should ultimately add back only the amount that
-		// was actually successfully redeemed in order to take into account
-		// the case where the ticket was not redeemd for its full face value
-		// because the reserve was insufficient
-		if err := sm.addFloat(ticket.Ticket.Sender, ticket.Ticket.FaceValue); err != nil {
-			glog.Error(err)
-		}
-	}()
+	// The ticket face value was subtracted from the sender's max float when the
+	// ticket was queued, and is added back once the ticket leaves the queue
+	//
+	// TODO(yondonfu): Should ultimately add back only the amount that
+	// was actually successfully redeemed in order to take into account
+	// the case where the ticket was not redeemd for its full face value
+	// because the reserve was insufficient
 
 	// Assume that that this call will return immediately if there
 	// is an error in transaction submission
+	sender := ticket.Ticket.Sender.String()
 	tx, err := sm.broker.RedeemWinningTicket(ticket.Ticket, ticket.Sig, ticket.RecipientRand)
 	if err != nil {
-		if monitor.Enabled {
-			monitor.TicketRedemptionError(ticket.Ticket.Sender.String())
-		}
+		getMetrics().RedemptionFailed(sender, err)
+		sm.auditRedemption(audit.EventTicketRedemptionFailed, ticket, nil, err)
 		return nil, err
 	}
+	getMetrics().RedemptionSubmitted(sender, ticket.Ticket.FaceValue)
 
 	// Wait for transaction to confirm
 	if err := sm.broker.CheckTx(tx); err != nil {
-		if monitor.Enabled {
-			monitor.TicketRedemptionError(ticket.Ticket.Sender.String())
-		}
+		getMetrics().RedemptionFailed(sender, err)
+		sm.auditRedemption(audit.EventTicketRedemptionFailed, ticket, tx, err)
 		return nil, err
 	}
 
-	if monitor.Enabled {
-		// TODO(yondonfu): Handle case where < ticket.FaceValue is actually
-		// redeemed i.e. if sender reserve cannot cover the full ticket.FaceValue
-		monitor.ValueRedeemed(ticket.Ticket.Sender.String(), ticket.Ticket.FaceValue)
-	}
+	sm.auditRedemption(audit.EventTicketRedeemed, ticket, tx, nil)
+
+	// TODO(yondonfu): Handle case where < ticket.FaceValue is actually
+	// redeemed i.e. if sender reserve cannot cover the full ticket.FaceValue
+	getMetrics().RedemptionSucceeded(sender, ticket.Ticket.FaceValue)
 
 	return tx, nil
 }
 
+// redeemWinningTickets redeems the winning tickets of a sender with a single transaction, and
+// returns the errors of the tickets that the transaction didn't redeem
+func (sm *LocalSenderMonitor) redeemWinningTickets(tickets []*SignedTicket) (*types.Transaction, map[*SignedTicket]error, error) {
+	sender := tickets[0].Ticket.Sender
+	faceValue := big.NewInt(0)
+	for _, ticket := range tickets {
+		faceValue.Add(faceValue, ticket.Ticket.FaceValue)
+	}
+	availableFunds, err := sm.redemptionFunds(sender, faceValue)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sm.cfg.RPCTimeout)
+	gasPrice, err := sm.cfg.SuggestGasPrice(ctx)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	cancel()
+
+	// The gas of a batch is at most the gas of redeeming its tickets one at a time
+	txCost := new(big.Int).Mul(big.NewInt(int64(sm.cfg.RedeemGas*len(tickets))), gasPrice)
+	if availableFunds.Cmp(txCost) <= 0 {
+		return nil, nil, errors.New("insufficient sender funds for redeem tx cost")
+	}
+
+	batch := make([]*Ticket, len(tickets))
+	sigs := make([][]byte, len(tickets))
+	recipientRands := make([]*big.Int, len(tickets))
+	for i, ticket := range tickets {
+		batch[i] = ticket.Ticket
+		sigs[i] = ticket.Sig
+		recipientRands[i] = ticket.RecipientRand
+	}
+
+	tx, err := sm.broker.BatchRedeemWinningTickets(batch, sigs, recipientRands)
+	if err != nil {
+		getMetrics().RedemptionFailed(sender.String(), err)
+		for _, ticket := range tickets {
+			sm.auditRedemption(audit.EventTicketRedemptionFailed, ticket, nil, err)
+		}
+		return nil, nil, err
+	}
+	getMetrics().RedemptionSubmitted(sender.String(), faceValue)
+
+	if err := sm.broker.CheckTx(tx); err != nil {
+		getMetrics().RedemptionFailed(sender.String(), err)
+		for _, ticket := range tickets {
+			sm.auditRedemption(audit.EventTicketRedemptionFailed, ticket, tx, err)
+		}
+		return nil, nil, err
+	}
+
+	// The transaction confirms even if the broker skipped some of the tickets
+	unredeemed := make(map[*SignedTicket]error)
+	for _, ticket := range tickets {
+		used, err := sm.broker.IsUsedTicket(ticket.Ticket)
+		if err == nil && !used {
+			err = errors.New("ticket not redeemed by batch")
+		}
+		if err != nil {
+			getMetrics().RedemptionFailed(sender.String(), err)
+			sm.auditRedemption(audit.EventTicketRedemptionFailed, ticket, tx, err)
+			unredeemed[ticket] = err
+			continue
+		}
+		sm.auditRedemption(audit.EventTicketRedeemed, ticket, tx, nil)
+		getMetrics().RedemptionSucceeded(sender.String(), ticket.Ticket.FaceValue)
+	}
+
+	return tx, unredeemed, nil
+}
+
+func (sm *LocalSenderMonitor) auditRedemption(typ string, ticket *SignedTicket, tx *types.Transaction, err error) {
+	data := map[string]string{
+		"sender":            ticket.Ticket.Sender.Hex(),
+		"recipient":         ticket.Ticket.Recipient.Hex(),
+		"faceValue":         ticket.Ticket.FaceValue.String(),
+		"senderNonce":       strconv.FormatUint(uint64(ticket.Ticket.SenderNonce), 10),
+		"recipientRandHash": ticket.Ticket.RecipientRandHash.Hex(),
+	}
+	if split := ticket.Ticket.PayoutSplit; split != nil {
+		// Pool operators settle their share of the winnings from these entries
+		data["payoutRecipient"] = split.Recipient.Hex()
+		data["payoutAmount"] = split.Amount(ticket.Ticket.FaceValue).String()
+	}
+	if tx != nil {
+		data["tx"] = tx.Hash().Hex()
+	}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	sm.cfg.AuditLog.Append(typ, data)
+}
+
 // SubscribeMaxFloatChange notifies subcribers when the max float for a sender has changed
 // and that it should call LocalSenderMonitor.MaxFloat() to get the latest value
 func (sm *LocalSenderMonitor) SubscribeMaxFloatChange(sender ethcommon.Address, sink chan<- struct{}) event.Subscription {
@@ -431,10 +844,12 @@ func (sm *LocalSenderMonitor) SubscribeMaxFloatChange(sender ethcommon.Address,
 
 // The caller of this function should hold the lock for sm.senders
 func (sm *LocalSenderMonitor) sendMaxFloatChange(sender ethcommon.Address) {
+	getMetrics().PendingFloat(sender.String(), new(big.Int).Set(sm.senders[sender].pendingAmount))
 	sm.senders[sender].subFeed.Send(struct{}{})
 }
 
 func (sm *LocalSenderMonitor) watchReserveChange() {
+	defer monitor.RecoverAndReport()
 	sink := make(chan ethcommon.Address, 10)
 	sub := sm.smgr.SubscribeReserveChange(sink)
 	defer sub.Unsubscribe()
@@ -448,6 +863,7 @@ func (sm *LocalSenderMonitor) watchReserveChange() {
 			continue
 		case sender := <-sink:
 			sm.mu.Lock()
+			sm.clearReserveAlloc(sender)
 			sm.sendMaxFloatChange(sender)
 			sm.mu.Unlock()
 		}
@@ -455,6 +871,7 @@ func (sm *LocalSenderMonitor) watchReserveChange() {
 }
 
 func (sm *LocalSenderMonitor) watchPoolSizeChange() {
+	defer monitor.RecoverAndReport()
 	sink := make(chan types.Log, 10)
 	sub := sm.tm.SubscribeRounds(sink)
 	defer sub.Unsubscribe()
@@ -468,6 +885,8 @@ func (sm *LocalSenderMonitor) watchPoolSizeChange() {
 			glog.Error(err)
 			continue
 		case <-sink:
+			// The allocations of the TicketBroker are per round
+			sm.fetchReserveAllocs()
 			poolSize := sm.tm.GetTranscoderPoolSize()
 			if poolSize.Cmp(lastPoolSize) != 0 {
 				sm.handlePoolSizeChange()
@@ -1,15 +1,21 @@
 package pm
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/audit"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -310,6 +316,204 @@ func TestQueueTicketAndSignalNewBlock(t *testing.T) {
 	assert.True(b.IsUsedTicket(signedT3.Ticket))
 }
 
+func TestShutdown(t *testing.T) {
+	cfg, b, smgr, tm := localSenderMonitorFixture()
+	addr := RandAddress()
+	smgr.info[addr] = &SenderInfo{
+		Deposit:       big.NewInt(500),
+		WithdrawRound: big.NewInt(0),
+		Reserve: &ReserveInfo{
+			FundsRemaining:        big.NewInt(5000),
+			ClaimedInCurrentRound: big.NewInt(0),
+		},
+	}
+	b.checkTxWait = make(chan struct{})
+
+	ts := newStubTicketStore()
+	smgr.claimedReserve[addr] = big.NewInt(100)
+	sm := NewSenderMonitor(cfg, b, smgr, tm, ts)
+	sm.Start()
+	defer sm.Stop()
+
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Start a redemption that waits for its transaction to confirm
+	signedT := defaultSignedTicket(addr, uint32(0))
+	require.Nil(sm.QueueTicket(signedT))
+	time.Sleep(20 * time.Millisecond)
+	tm.blockNumSink <- big.NewInt(5)
+	time.Sleep(20 * time.Millisecond)
+	assert.True(b.IsUsedTicket(signedT.Ticket))
+
+	// Shutdown waits for the redemption in progress until the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(context.DeadlineExceeded, sm.Shutdown(ctx))
+
+	errCh := make(chan error)
+	go func() { errCh <- sm.Shutdown(context.Background()) }()
+	close(b.checkTxWait)
+	select {
+	case err := <-errCh:
+		assert.Nil(err)
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown did not return after the redemption completed")
+	}
+
+	// The redeemed ticket is removed from the queue
+	qlen, err := sm.senders[addr].queue.Length()
+	assert.Nil(err)
+	assert.Equal(0, qlen)
+}
+
+func TestStart_ResumesTicketQueues(t *testing.T) {
+	cfg, b, smgr, tm := localSenderMonitorFixture()
+	addr := RandAddress()
+	smgr.info[addr] = &SenderInfo{
+		Deposit:       big.NewInt(500),
+		WithdrawRound: big.NewInt(0),
+		Reserve: &ReserveInfo{
+			FundsRemaining:        big.NewInt(5000),
+			ClaimedInCurrentRound: big.NewInt(0),
+		},
+	}
+	smgr.claimedReserve[addr] = big.NewInt(100)
+
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// A ticket left in the store before the node restarted
+	ts := newStubTicketStore()
+	signedT := defaultSignedTicket(addr, uint32(0))
+	require.Nil(ts.StoreWinningTicket(signedT))
+
+	// The ticket is not resumed if the store fails
+	ts.loadShouldFail = true
+	sm := NewSenderMonitor(cfg, b, smgr, tm, ts)
+	sm.Start()
+	assert.Nil(sm.senders[addr])
+	sm.Stop()
+	ts.loadShouldFail = false
+
+	sm = NewSenderMonitor(cfg, b, smgr, tm, ts)
+	sm.Start()
+	defer sm.Stop()
+	require.NotNil(sm.senders[addr])
+	qlen, err := sm.senders[addr].queue.Length()
+	assert.Nil(err)
+	assert.Equal(1, qlen)
+
+	// The ticket is redeemed without any new activity from the sender
+	time.Sleep(20 * time.Millisecond)
+	tm.blockNumSink <- big.NewInt(5)
+	time.Sleep(20 * time.Millisecond)
+	assert.True(b.IsUsedTicket(signedT.Ticket))
+}
+
+func TestQueueTicket_ExpiredTickets(t *testing.T) {
+	cfg, b, smgr, tm := localSenderMonitorFixture()
+	addr := RandAddress()
+	smgr.info[addr] = &SenderInfo{
+		Deposit:       big.NewInt(500),
+		WithdrawRound: big.NewInt(0),
+		Reserve: &ReserveInfo{
+			FundsRemaining:        big.NewInt(5000),
+			ClaimedInCurrentRound: big.NewInt(0),
+		},
+	}
+	smgr.claimedReserve[addr] = big.NewInt(100)
+	tm.round = big.NewInt(102)
+
+	ts := newStubTicketStore()
+	sm := NewSenderMonitor(cfg, b, smgr, tm, ts)
+	sm.Start()
+	defer sm.Stop()
+
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Tickets whose validity period is over are removed instead of redeemed
+	expiredT := defaultSignedTicket(addr, 0)
+	signedT := defaultSignedTicket(addr, 1)
+	signedT.CreationRound = 101
+	require.Nil(sm.QueueTicket(expiredT))
+	require.Nil(sm.QueueTicket(signedT))
+	time.Sleep(20 * time.Millisecond)
+	tm.blockNumSink <- big.NewInt(5)
+	time.Sleep(20 * time.Millisecond)
+
+	used, err := b.IsUsedTicket(expiredT.Ticket)
+	assert.Nil(err)
+	assert.False(used)
+	used, err = b.IsUsedTicket(signedT.Ticket)
+	assert.Nil(err)
+	assert.True(used)
+	assert.Len(ts.tickets[addr], 1)
+	qlen, err := sm.senders[addr].queue.Length()
+	assert.Nil(err)
+	assert.Equal(0, qlen)
+	// Expired tickets are no longer pending
+	assert.Zero(sm.senders[addr].pendingAmount.Sign())
+}
+
+func TestQueueTicket_BatchExpiringTickets(t *testing.T) {
+	cfg, b, smgr, tm := localSenderMonitorFixture()
+	addr := RandAddress()
+	smgr.info[addr] = &SenderInfo{
+		Deposit:       big.NewInt(500),
+		WithdrawRound: big.NewInt(0),
+		Reserve: &ReserveInfo{
+			FundsRemaining:        big.NewInt(5000),
+			ClaimedInCurrentRound: big.NewInt(0),
+		},
+	}
+	smgr.claimedReserve[addr] = big.NewInt(100)
+	cfg.RedeemBatchSize = 3
+	cfg.RedeemBatchInterval = time.Hour
+	tm.round = big.NewInt(100)
+
+	ts := newStubTicketStore()
+	sm := NewSenderMonitor(cfg, b, smgr, tm, ts)
+	sm.Start()
+	defer sm.Stop()
+
+	assert := assert.New(t)
+	require := require.New(t)
+
+	signedT0 := defaultSignedTicket(addr, 0)
+	signedT1 := defaultSignedTicket(addr, 1)
+	require.Nil(sm.QueueTicket(signedT0))
+	require.Nil(sm.QueueTicket(signedT1))
+	time.Sleep(20 * time.Millisecond)
+	tm.blockNumSink <- big.NewInt(5)
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(b.batches)
+
+	// Partial batches are redeemed in the last round of the validity period of their tickets
+	tm.round = big.NewInt(101)
+	tm.blockNumSink <- big.NewInt(6)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal([]int{2}, b.batches)
+	used, err := b.IsUsedTicket(signedT0.Ticket)
+	assert.Nil(err)
+	assert.True(used)
+	used, err = b.IsUsedTicket(signedT1.Ticket)
+	assert.Nil(err)
+	assert.True(used)
+
+	// Expired tickets are removed from batches
+	tm.round = big.NewInt(102)
+	require.Nil(sm.QueueTicket(defaultSignedTicket(addr, 2)))
+	time.Sleep(20 * time.Millisecond)
+	tm.blockNumSink <- big.NewInt(7)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal([]int{2}, b.batches)
+	qlen, err := sm.senders[addr].queue.Length()
+	assert.Nil(err)
+	assert.Equal(0, qlen)
+}
+
 func TestCleanup(t *testing.T) {
 	cfg, b, smgr, tm := localSenderMonitorFixture()
 	cfg.TTL = 5
@@ -490,6 +694,17 @@ func TestCleanup(t *testing.T) {
 	sm.cleanup()
 	assert.NotNil(sm.senders[addr1])
 	assert.Nil(sm.senders[addr2])
+
+	// Senders with queued tickets are not cleaned up until their tickets are redeemed
+	signedT := defaultSignedTicket(addr2, 0)
+	signedT.ParamsExpirationBlock = big.NewInt(100)
+	require.Nil(sm.QueueTicket(signedT))
+	increaseTime(10)
+	sm.cleanup()
+	require.NotNil(sm.senders[addr2])
+	require.Nil(sm.ticketStore.MarkWinningTicketRedeemed(signedT, RandHash()))
+	sm.cleanup()
+	assert.Nil(sm.senders[addr2])
 }
 
 func TestReserveAlloc(t *testing.T) {
@@ -520,6 +735,167 @@ func TestReserveAlloc(t *testing.T) {
 	assert.Zero(expectedAlloc.Cmp(alloc))
 }
 
+func TestReserveAlloc_BrokerReserveAlloc(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	cfg, b, smgr, tm := localSenderMonitorFixture()
+	tm.round = big.NewInt(1)
+	addr := RandAddress()
+	smgr.info[addr] = &SenderInfo{
+		Deposit:       big.NewInt(100),
+		WithdrawRound: big.NewInt(0),
+		Reserve: &ReserveInfo{
+			FundsRemaining:        big.NewInt(5000),
+			ClaimedInCurrentRound: big.NewInt(0),
+		},
+	}
+	smgr.claimedReserve[addr] = big.NewInt(100)
+	sm := NewSenderMonitor(cfg, b, smgr, tm, newStubTicketStore())
+	maxFloat := func() *big.Int {
+		mf, err := sm.MaxFloat(addr)
+		require.Nil(err)
+		return mf
+	}
+
+	// Test the TicketBroker doesn't return an alloc
+	assert.Equal(big.NewInt(900), maxFloat())
+
+	// Test the alloc of the TicketBroker is fetched once per round
+	b.reserveAllocs[addr] = big.NewInt(300)
+	assert.Equal(big.NewInt(900), maxFloat())
+	tm.round = big.NewInt(2)
+	assert.Equal(big.NewInt(300), maxFloat())
+	assert.Equal(big.NewInt(300), maxFloat())
+	assert.Equal(2, b.reserveAllocCalls)
+
+	// Test the alloc of the TicketBroker only caps the alloc
+	b.reserveAllocs[addr] = big.NewInt(1000)
+	sm.mu.Lock()
+	sm.clearReserveAlloc(addr)
+	sm.mu.Unlock()
+	assert.Equal(big.NewInt(900), maxFloat())
+
+	// Test the claimant is not an active orchestrator
+	b.reserveAllocs[addr] = big.NewInt(0)
+	tm.round = big.NewInt(3)
+	assert.Equal(big.NewInt(0), maxFloat())
+
+	// Test ReserveAlloc error
+	b.reserveAllocShouldFail = true
+	tm.round = big.NewInt(4)
+	assert.Equal(big.NewInt(900), maxFloat())
+}
+
+func TestReserveAlloc_BrokerReserveAlloc_OutsideLock(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	cfg, b, smgr, tm := localSenderMonitorFixture()
+	addr := RandAddress()
+	addr2 := RandAddress()
+	for _, a := range []ethcommon.Address{addr, addr2} {
+		smgr.info[a] = &SenderInfo{
+			Deposit:       big.NewInt(100),
+			WithdrawRound: big.NewInt(0),
+			Reserve: &ReserveInfo{
+				FundsRemaining:        big.NewInt(5000),
+				ClaimedInCurrentRound: big.NewInt(0),
+			},
+		}
+		smgr.claimedReserve[a] = big.NewInt(100)
+	}
+	b.reserveAllocs[addr] = big.NewInt(300)
+	sm := NewSenderMonitor(cfg, b, smgr, tm, newStubTicketStore())
+	require.Nil(sm.QueueTicket(defaultSignedTicket(addr2, 0)))
+
+	// The tickets of other senders are queued while the alloc of a sender is fetched
+	b.reserveAllocWait = make(chan struct{})
+	errC := make(chan error)
+	go func() {
+		_, err := sm.MaxFloat(addr)
+		errC <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	queued := make(chan error)
+	go func() { queued <- sm.QueueTicket(defaultSignedTicket(addr2, 1)) }()
+	select {
+	case err := <-queued:
+		assert.Nil(err)
+	case <-time.After(time.Second):
+		t.Fatal("QueueTicket blocked by the ReserveAlloc call")
+	}
+	close(b.reserveAllocWait)
+	require.Nil(<-errC)
+
+	mf, err := sm.MaxFloat(addr)
+	require.Nil(err)
+	assert.Equal(big.NewInt(300), mf)
+
+	// A fetch that started before the alloc was cleared doesn't set it
+	b.reserveAllocWait = make(chan struct{})
+	tm.round = big.NewInt(1)
+	go func() {
+		_, err := sm.MaxFloat(addr)
+		errC <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	b.mu.Lock()
+	b.reserveAllocs[addr] = big.NewInt(200)
+	b.mu.Unlock()
+	sm.mu.Lock()
+	sm.clearReserveAlloc(addr)
+	sm.mu.Unlock()
+	close(b.reserveAllocWait)
+	require.Nil(<-errC)
+	sm.mu.Lock()
+	assert.False(sm.senders[addr].reserveAllocSet)
+	sm.mu.Unlock()
+	mf, err = sm.MaxFloat(addr)
+	require.Nil(err)
+	assert.Equal(big.NewInt(200), mf)
+}
+
+func TestWatchPoolSizeChange_FetchesReserveAllocs(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	cfg, b, smgr, tm := localSenderMonitorFixture()
+	addr := RandAddress()
+	smgr.info[addr] = &SenderInfo{
+		Deposit:       big.NewInt(100),
+		WithdrawRound: big.NewInt(0),
+		Reserve: &ReserveInfo{
+			FundsRemaining:        big.NewInt(5000),
+			ClaimedInCurrentRound: big.NewInt(0),
+		},
+	}
+	smgr.claimedReserve[addr] = big.NewInt(100)
+	b.reserveAllocs[addr] = big.NewInt(300)
+	sm := NewSenderMonitor(cfg, b, smgr, tm, newStubTicketStore())
+	_, err := sm.MaxFloat(addr)
+	require.Nil(err)
+	assert.Equal(1, b.reserveAllocCalls)
+
+	go sm.watchPoolSizeChange()
+	defer sm.Stop()
+	time.Sleep(20 * time.Millisecond)
+
+	// The alloc is fetched by the round watcher rather than by MaxFloat
+	b.mu.Lock()
+	b.reserveAllocs[addr] = big.NewInt(200)
+	b.mu.Unlock()
+	tm.round = big.NewInt(1)
+	tm.roundSink <- types.Log{}
+	time.Sleep(20 * time.Millisecond)
+	b.mu.Lock()
+	assert.Equal(2, b.reserveAllocCalls)
+	b.mu.Unlock()
+	mf, err := sm.MaxFloat(addr)
+	require.Nil(err)
+	assert.Equal(big.NewInt(200), mf)
+	b.mu.Lock()
+	assert.Equal(2, b.reserveAllocCalls)
+	b.mu.Unlock()
+}
+
 func TestSenderMonitor_ValidateSender(t *testing.T) {
 	cfg, b, smgr, tm := localSenderMonitorFixture()
 	addr := RandAddress()
@@ -769,7 +1145,151 @@ func TestRedeemWinningTicket_SingleTicket(t *testing.T) {
 	assert.True(ok)
 }
 
-func TestRedeemWinningTicket_addFloatError(t *testing.T) {
+func TestRedeemWinningTicket_AuditLog(t *testing.T) {
+	cfg, b, smgr, tm := localSenderMonitorFixture()
+	addr := RandAddress()
+	smgr.info[addr] = &SenderInfo{
+		Deposit:       big.NewInt(500),
+		WithdrawRound: big.NewInt(0),
+		Reserve: &ReserveInfo{
+			FundsRemaining:        big.NewInt(1000),
+			ClaimedInCurrentRound: big.NewInt(0),
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "audit")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	cfg.AuditLog, err = audit.Open(filepath.Join(dir, "audit.log"))
+	require.Nil(t, err)
+	defer cfg.AuditLog.Close()
+
+	ts := newStubTicketStore()
+	smgr.claimedReserve[addr] = big.NewInt(100)
+	sm := NewSenderMonitor(cfg, b, smgr, tm, ts)
+	sm.Start()
+	defer sm.Stop()
+	assert := assert.New(t)
+
+	ticket := defaultSignedTicket(addr, uint32(0))
+	payoutRecipient := RandAddress()
+	ticket.PayoutSplit = &PayoutSplit{Recipient: payoutRecipient, Share: 200000}
+	tx, err := sm.redeemWinningTicket(ticket)
+	require.Nil(t, err)
+	b.redeemShouldFail = true
+	_, err = sm.redeemWinningTicket(defaultSignedTicket(addr, uint32(1)))
+	assert.NotNil(err)
+
+	var buf bytes.Buffer
+	require.Nil(t, cfg.AuditLog.Export(&buf))
+	var entries []audit.Entry
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var e audit.Entry
+		require.Nil(t, dec.Decode(&e))
+		entries = append(entries, e)
+	}
+	require.Len(t, entries, 2)
+	assert.Equal(audit.EventTicketRedeemed, entries[0].Type)
+	assert.Equal(addr.Hex(), entries[0].Data["sender"])
+	assert.Equal(tx.Hash().Hex(), entries[0].Data["tx"])
+	assert.Equal("0", entries[0].Data["senderNonce"])
+	assert.Equal(payoutRecipient.Hex(), entries[0].Data["payoutRecipient"])
+	assert.Equal("10", entries[0].Data["payoutAmount"])
+	assert.Equal(audit.EventTicketRedemptionFailed, entries[1].Type)
+	assert.Equal("1", entries[1].Data["senderNonce"])
+	assert.Equal("stub broker redeem error", entries[1].Data["error"])
+	assert.NotContains(entries[1].Data, "payoutRecipient")
+}
+
+func TestRedeemWinningTickets_Batch(t *testing.T) {
+	cfg, b, smgr, tm := localSenderMonitorFixture()
+	addr := RandAddress()
+	smgr.info[addr] = &SenderInfo{
+		Deposit:       big.NewInt(500),
+		WithdrawRound: big.NewInt(0),
+		Reserve: &ReserveInfo{
+			FundsRemaining:        big.NewInt(1000),
+			ClaimedInCurrentRound: big.NewInt(0),
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "audit")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	cfg.AuditLog, err = audit.Open(filepath.Join(dir, "audit.log"))
+	require.Nil(t, err)
+	defer cfg.AuditLog.Close()
+
+	ts := newStubTicketStore()
+	smgr.claimedReserve[addr] = big.NewInt(100)
+	sm := NewSenderMonitor(cfg, b, smgr, tm, ts)
+	sm.Start()
+	defer sm.Stop()
+	assert := assert.New(t)
+
+	tickets := []*SignedTicket{defaultSignedTicket(addr, 0), defaultSignedTicket(addr, 1), defaultSignedTicket(addr, 2)}
+	isUsed := func(ticket *SignedTicket) bool {
+		used, err := b.IsUsedTicket(ticket.Ticket)
+		require.Nil(t, err)
+		return used
+	}
+
+	// The tickets that the broker skips are logged as failed redemptions
+	b.unredeemable[tickets[1].Hash()] = true
+	tx, unredeemed, err := sm.redeemWinningTickets(tickets)
+	assert.Nil(err)
+	assert.NotNil(tx)
+	require.Len(t, unredeemed, 1)
+	assert.EqualError(unredeemed[tickets[1]], "ticket not redeemed by batch")
+	assert.Equal([]int{3}, b.batches)
+	assert.True(isUsed(tickets[0]))
+	assert.False(isUsed(tickets[1]))
+	assert.True(isUsed(tickets[2]))
+
+	// The face value of the batch is no longer pending
+	assert.Zero(sm.senders[addr].pendingAmount.Sign())
+
+	tickets = []*SignedTicket{defaultSignedTicket(addr, 3), defaultSignedTicket(addr, 4)}
+	b.redeemShouldFail = true
+	failedTx, _, err := sm.redeemWinningTickets(tickets)
+	assert.EqualError(err, "stub broker redeem error")
+	assert.Nil(failedTx)
+	b.redeemShouldFail = false
+
+	b.checkTxErr = errors.New("checktx error")
+	failedTx, _, err = sm.redeemWinningTickets(tickets)
+	assert.EqualError(err, "checktx error")
+	assert.Nil(failedTx)
+	assert.Zero(sm.senders[addr].pendingAmount.Sign())
+
+	var buf bytes.Buffer
+	require.Nil(t, cfg.AuditLog.Export(&buf))
+	var entries []audit.Entry
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var e audit.Entry
+		require.Nil(t, dec.Decode(&e))
+		entries = append(entries, e)
+	}
+	require.Len(t, entries, 7)
+	assert.Equal(audit.EventTicketRedeemed, entries[0].Type)
+	assert.Equal(tx.Hash().Hex(), entries[0].Data["tx"])
+	assert.Equal(audit.EventTicketRedemptionFailed, entries[1].Type)
+	assert.Equal("1", entries[1].Data["senderNonce"])
+	assert.Equal("ticket not redeemed by batch", entries[1].Data["error"])
+	assert.Equal(audit.EventTicketRedeemed, entries[2].Type)
+	for _, e := range entries[3:5] {
+		assert.Equal(audit.EventTicketRedemptionFailed, e.Type)
+		assert.Equal("stub broker redeem error", e.Data["error"])
+	}
+	for _, e := range entries[5:] {
+		assert.Equal(audit.EventTicketRedemptionFailed, e.Type)
+		assert.Equal("checktx error", e.Data["error"])
+	}
+}
+
+func TestQueueTicket_BatchRedemption(t *testing.T) {
 	cfg, b, smgr, tm := localSenderMonitorFixture()
 	addr := RandAddress()
 	smgr.info[addr] = &SenderInfo{
@@ -780,6 +1300,8 @@ func TestRedeemWinningTicket_addFloatError(t *testing.T) {
 			ClaimedInCurrentRound: big.NewInt(0),
 		},
 	}
+	cfg.RedeemBatchSize = 2
+	cfg.RedeemBatchInterval = time.Hour
 
 	ts := newStubTicketStore()
 	smgr.claimedReserve[addr] = big.NewInt(100)
@@ -787,18 +1309,175 @@ func TestRedeemWinningTicket_addFloatError(t *testing.T) {
 	sm.Start()
 	defer sm.Stop()
 
+	assert := assert.New(t)
+	require := require.New(t)
+
+	signedT0 := defaultSignedTicket(addr, 0)
+	require.Nil(sm.QueueTicket(signedT0))
+	time.Sleep(20 * time.Millisecond)
+	tm.blockNumSink <- big.NewInt(5)
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(b.batches)
+
+	signedT1 := defaultSignedTicket(addr, 1)
+	require.Nil(sm.QueueTicket(signedT1))
+	tm.blockNumSink <- big.NewInt(6)
+	time.Sleep(20 * time.Millisecond)
+	used, err := b.IsUsedTicket(signedT0.Ticket)
+	assert.Nil(err)
+	assert.True(used)
+	used, err = b.IsUsedTicket(signedT1.Ticket)
+	assert.Nil(err)
+	assert.True(used)
+	assert.Equal([]int{2}, b.batches)
+
+	qlen, err := sm.senders[addr].queue.Length()
+	assert.Nil(err)
+	assert.Equal(0, qlen)
+	assert.Zero(sm.senders[addr].pendingAmount.Sign())
+}
+
+func TestReleaseFloat_addFloatError(t *testing.T) {
+	cfg, b, smgr, tm := localSenderMonitorFixture()
+	addr := RandAddress()
+	smgr.info[addr] = &SenderInfo{
+		Deposit:       big.NewInt(500),
+		WithdrawRound: big.NewInt(0),
+		Reserve: &ReserveInfo{
+			FundsRemaining:        big.NewInt(5000),
+			ClaimedInCurrentRound: big.NewInt(0),
+		},
+	}
+
+	ts := newStubTicketStore()
+	smgr.claimedReserve[addr] = big.NewInt(100)
+	sm := NewSenderMonitor(cfg, b, smgr, tm, ts)
+
 	assert := assert.New(t)
 
 	signedT := defaultSignedTicket(addr, uint32(0))
 	sm.ensureCache(addr)
-	sm.senders[addr].pendingAmount = big.NewInt(-100)
 
 	errLogsBefore := glog.Stats.Error.Lines()
-	tx, err := sm.redeemWinningTicket(signedT)
-	assert.NotNil(tx)
+	sm.releaseFloat(signedT)
 	errLogsAfter := glog.Stats.Error.Lines()
-	assert.Nil(err)
 	assert.Greater(errLogsAfter, errLogsBefore)
+	assert.Zero(sm.senders[addr].pendingAmount.Sign())
+}
+
+func TestQueueTicket_MaxFloat(t *testing.T) {
+	cfg, b, smgr, tm := localSenderMonitorFixture()
+	addr := RandAddress()
+	// The deposit doesn't cover the queued tickets, so they are covered by the reserve
+	smgr.info[addr] = &SenderInfo{
+		Deposit:       big.NewInt(100),
+		WithdrawRound: big.NewInt(0),
+		Reserve: &ReserveInfo{
+			FundsRemaining:        big.NewInt(5000),
+			ClaimedInCurrentRound: big.NewInt(0),
+		},
+	}
+	smgr.claimedReserve[addr] = big.NewInt(100)
+	reserveAlloc := big.NewInt(900)
+
+	ts := newStubTicketStore()
+	sm := NewSenderMonitor(cfg, b, smgr, tm, ts)
+	sm.Start()
+	defer sm.Stop()
+
+	assert := assert.New(t)
+	require := require.New(t)
+	maxFloat := func(sm *LocalSenderMonitor) *big.Int {
+		mf, err := sm.MaxFloat(addr)
+		require.Nil(err)
+		return mf
+	}
+
+	assert.Equal(reserveAlloc, maxFloat(sm))
+
+	// The face value of queued tickets is subtracted from the max float
+	sink := make(chan struct{}, 10)
+	sub := sm.SubscribeMaxFloatChange(addr, sink)
+	defer sub.Unsubscribe()
+	signedT := defaultSignedTicket(addr, 0)
+	require.Nil(sm.QueueTicket(signedT))
+	assert.Equal(new(big.Int).Sub(reserveAlloc, signedT.FaceValue), maxFloat(sm))
+	<-sink
+
+	// and stays subtracted while the ticket is not redeemed
+	b.redeemShouldFail = true
+	time.Sleep(20 * time.Millisecond)
+	tm.blockNumSink <- big.NewInt(5)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(new(big.Int).Sub(reserveAlloc, signedT.FaceValue), maxFloat(sm))
+
+	// The face value is added back once the ticket is redeemed
+	b.redeemShouldFail = false
+	tm.blockNumSink <- big.NewInt(6)
+	time.Sleep(20 * time.Millisecond)
+	assert.True(b.IsUsedTicket(signedT.Ticket))
+	assert.Equal(reserveAlloc, maxFloat(sm))
+
+	// Tickets left in the store by a restart are subtracted from the max float
+	ts = newStubTicketStore()
+	require.Nil(ts.StoreWinningTicket(defaultSignedTicket(addr, 1)))
+	require.Nil(ts.StoreWinningTicket(defaultSignedTicket(addr, 2)))
+	sm2 := NewSenderMonitor(cfg, b, smgr, tm, ts)
+	defer sm2.Stop()
+	assert.Equal(new(big.Int).Sub(reserveAlloc, big.NewInt(100)), maxFloat(sm2))
+}
+
+func TestQueueTicket_FailedRedemptions(t *testing.T) {
+	cfg, b, smgr, tm := localSenderMonitorFixture()
+	cfg.RedeemMaxAttempts = 2
+	addr := RandAddress()
+	smgr.info[addr] = &SenderInfo{
+		Deposit:       big.NewInt(100),
+		WithdrawRound: big.NewInt(0),
+		Reserve: &ReserveInfo{
+			FundsRemaining:        big.NewInt(5000),
+			ClaimedInCurrentRound: big.NewInt(0),
+		},
+	}
+	smgr.claimedReserve[addr] = big.NewInt(100)
+	reserveAlloc := big.NewInt(900)
+
+	ts := newStubTicketStore()
+	sm := NewSenderMonitor(cfg, b, smgr, tm, ts)
+	sm.Start()
+	defer sm.Stop()
+
+	assert := assert.New(t)
+	require := require.New(t)
+
+	signedT := defaultSignedTicket(addr, 0)
+	require.Nil(sm.QueueTicket(signedT))
+	b.redeemShouldFail = true
+	time.Sleep(20 * time.Millisecond)
+
+	// Tickets stay queued until they reached the max attempts
+	tm.blockNumSink <- big.NewInt(5)
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(sm.FailedRedemptions(), 0)
+	tm.blockNumSink <- big.NewInt(6)
+
+	select {
+	case f := <-sm.FailedRedemptions():
+		assert.Equal(signedT, f.Ticket)
+		assert.Equal(2, f.Attempts)
+		assert.EqualError(f.Err, "stub broker redeem error")
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for failed redemption")
+	}
+
+	// The face value of the failed ticket is added back to the max float
+	time.Sleep(20 * time.Millisecond)
+	mf, err := sm.MaxFloat(addr)
+	require.Nil(err)
+	assert.Equal(reserveAlloc, mf)
+	qlen, err := sm.senders[addr].queue.Length()
+	require.Nil(err)
+	assert.Equal(0, qlen)
 }
 
 func TestSubscribeMaxFloatChange(t *testing.T) {
@@ -843,6 +1522,51 @@ func TestSubscribeMaxFloatChange(t *testing.T) {
 	assert.Equal(newMaxFloat, mf)
 }
 
+func TestQueueTicket_MaxFloat_BrokerReserveAlloc(t *testing.T) {
+	cfg, b, smgr, tm := localSenderMonitorFixture()
+	addr := RandAddress()
+	smgr.info[addr] = &SenderInfo{
+		Deposit:       big.NewInt(100),
+		WithdrawRound: big.NewInt(0),
+		Reserve: &ReserveInfo{
+			FundsRemaining:        big.NewInt(5000),
+			ClaimedInCurrentRound: big.NewInt(0),
+		},
+	}
+	smgr.claimedReserve[addr] = big.NewInt(100)
+	b.reserveAllocs[addr] = big.NewInt(500)
+
+	sm := NewSenderMonitor(cfg, b, smgr, tm, newStubTicketStore())
+	sm.Start()
+	defer sm.Stop()
+
+	assert := assert.New(t)
+	require := require.New(t)
+	maxFloat := func() *big.Int {
+		mf, err := sm.MaxFloat(addr)
+		require.Nil(err)
+		return mf
+	}
+
+	assert.Equal(big.NewInt(500), maxFloat())
+
+	// The face value of queued tickets is subtracted from the alloc of the TicketBroker
+	signedT := defaultSignedTicket(addr, 0)
+	require.Nil(sm.QueueTicket(signedT))
+	assert.Equal(new(big.Int).Sub(big.NewInt(500), signedT.FaceValue), maxFloat())
+
+	// The float is released once the ticket is redeemed, and the TicketBroker then allocates
+	// what is left of the reserve of the sender
+	b.mu.Lock()
+	b.reserveAllocs[addr] = new(big.Int).Sub(big.NewInt(500), signedT.FaceValue)
+	b.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	tm.blockNumSink <- big.NewInt(5)
+	time.Sleep(20 * time.Millisecond)
+	assert.True(b.IsUsedTicket(signedT.Ticket))
+	assert.Equal(new(big.Int).Sub(big.NewInt(500), signedT.FaceValue), maxFloat())
+}
+
 func TestWatchReserveChange(t *testing.T) {
 	assert := assert.New(t)
 	cfg, b, smgr, tm := localSenderMonitorFixture()
@@ -912,3 +1636,73 @@ func stubLocalSenderMonitorCfg() *LocalSenderMonitorConfig {
 		RPCTimeout: 5 * time.Minute,
 	}
 }
+
+func TestWinningTickets(t *testing.T) {
+	cfg, b, smgr, tm := localSenderMonitorFixture()
+	ts := newStubTicketStore()
+	sm := NewSenderMonitor(cfg, b, smgr, tm, ts)
+
+	assert := assert.New(t)
+	require := require.New(t)
+
+	tickets, err := sm.WinningTickets()
+	require.Nil(err)
+	assert.Empty(tickets)
+
+	addr := RandAddress()
+	otherAddr := RandAddress()
+	signedT := defaultSignedTicket(addr, 0)
+	otherT := defaultSignedTicket(otherAddr, 0)
+	redeemedT := defaultSignedTicket(addr, 1)
+	for _, ticket := range []*SignedTicket{signedT, otherT, redeemedT} {
+		require.Nil(ts.StoreWinningTicket(ticket))
+	}
+	require.Nil(ts.MarkWinningTicketRedeemed(redeemedT, RandHash()))
+
+	tickets, err = sm.WinningTickets()
+	require.Nil(err)
+	assert.Len(tickets, 2)
+	assert.Contains(tickets, signedT)
+	assert.Contains(tickets, otherT)
+
+	ts.loadShouldFail = true
+	_, err = sm.WinningTickets()
+	assert.EqualError(err, "stub TicketStore load error")
+}
+
+func TestRemoveWinningTickets(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	cfg, b, smgr, tm := localSenderMonitorFixture()
+	addr := RandAddress()
+	smgr.info[addr] = &SenderInfo{
+		Deposit:       big.NewInt(100),
+		WithdrawRound: big.NewInt(0),
+		Reserve: &ReserveInfo{
+			FundsRemaining:        big.NewInt(5000),
+			ClaimedInCurrentRound: big.NewInt(0),
+		},
+	}
+	smgr.claimedReserve[addr] = big.NewInt(100)
+	ts := newStubTicketStore()
+	sm := NewSenderMonitor(cfg, b, smgr, tm, ts)
+
+	signedT := defaultSignedTicket(addr, 0)
+	require.Nil(sm.QueueTicket(signedT))
+	assert.Equal(ErrTicketStored, sm.QueueTicket(signedT))
+	mf, err := sm.MaxFloat(addr)
+	require.Nil(err)
+	assert.Equal(new(big.Int).Sub(big.NewInt(900), signedT.FaceValue), mf)
+
+	// The float of removed tickets is released
+	require.Nil(sm.RemoveWinningTickets([]*SignedTicket{signedT}))
+	tickets, err := sm.WinningTickets()
+	require.Nil(err)
+	assert.Empty(tickets)
+	mf, err = sm.MaxFloat(addr)
+	require.Nil(err)
+	assert.Equal(big.NewInt(900), mf)
+
+	ts.removeShouldFail = true
+	assert.Error(sm.RemoveWinningTickets([]*SignedTicket{signedT}))
+}
@@ -3,8 +3,9 @@ package pm
 import "github.com/ethereum/go-ethereum/accounts"
 
 // Signer supports identifying as an Ethereum account owner, by providing the
-// Account and enabling message signing.
+// Account and enabling message and EIP-712 typed data signing.
 type Signer interface {
 	Sign(msg []byte) ([]byte, error)
+	SignTypedData(typedData *TypedData) ([]byte, error)
 	Account() accounts.Account
 }
@@ -1,7 +1,18 @@
 package pm
 
 import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/golang/glog"
 	"github.com/livepeer/go-livepeer/crypto"
 )
 
@@ -11,6 +22,10 @@ type SigVerifier interface {
 	// Verify checks if a provided signature over a message
 	// is valid for a given ETH address
 	Verify(addr ethcommon.Address, msg, sig []byte) bool
+
+	// VerifyTypedData checks if a provided signature over EIP-712
+	// typed data is valid for a given ETH address
+	VerifyTypedData(addr ethcommon.Address, typedData *TypedData, sig []byte) bool
 }
 
 // DefaultSigVerifier is client-side-only implementation of sig verification, i.e. not relying on
@@ -24,6 +39,186 @@ func (sv *DefaultSigVerifier) Verify(addr ethcommon.Address, msg, sig []byte) bo
 	return crypto.VerifySig(addr, msg, sig)
 }
 
+// VerifyTypedData checks if a provided signature over EIP-712
+// typed data is valid for a given ETH address
+func (sv *DefaultSigVerifier) VerifyTypedData(addr ethcommon.Address, typedData *TypedData, sig []byte) bool {
+	return crypto.VerifyHashSig(addr, typedData.Hash().Bytes(), sig)
+}
+
+// erc1271ABI is the ABI of the isValidSignature function of ERC-1271 contracts
+const erc1271ABI = `[{"constant":true,"inputs":[{"name":"hash","type":"bytes32"},{"name":"signature","type":"bytes"}],"name":"isValidSignature","outputs":[{"name":"magicValue","type":"bytes4"}],"payable":false,"stateMutability":"view","type":"function"}]`
+
+// erc1271MagicValue is returned by isValidSignature for valid signatures
+var erc1271MagicValue = []byte{0x16, 0x26, 0xba, 0x7e}
+
+// erc1271CallTimeout is the timeout of isValidSignature calls
+var erc1271CallTimeout = 5 * time.Second
+
+// eoaCacheTTL is the duration during which an address without code isn't checked again, so
+// that counterfactual wallets are recognized some time after they are deployed
+var eoaCacheTTL = 10 * time.Minute
+
+// sigCacheTTL is the duration during which the result of an isValidSignature call is reused
+var sigCacheTTL = 10 * time.Minute
+
+// maxCachedSigs is the maximum number of isValidSignature results that are cached
+var maxCachedSigs = 10000
+
+// ContractSigVerifier is an implementation of the SigVerifier interface that also accepts the
+// signatures of smart contract wallets, e.g. Gnosis Safe or account abstraction wallets.
+// Signatures that aren't ECDSA signatures of an address are checked with the ERC-1271
+// isValidSignature function of the address if it is a contract. The hash passed to
+// isValidSignature is the hash that an EOA signs, i.e. the personal message hash of messages
+// and the EIP-712 digest of typed data.
+// Note that the TicketBroker must support ERC-1271 to redeem the tickets of contract senders
+type ContractSigVerifier struct {
+	caller bind.ContractCaller
+	abi    abi.ABI
+
+	mu sync.Mutex
+	// eoas are the addresses without code, with the time at which they were checked
+	eoas map[ethcommon.Address]time.Time
+	// contracts are the addresses with code, which are contracts for good
+	contracts map[ethcommon.Address]bool
+	// sigs are the results of isValidSignature calls, keyed by the address, hash and signature
+	sigs map[string]sigResult
+}
+
+type sigResult struct {
+	valid     bool
+	checkedAt time.Time
+}
+
+// NewContractSigVerifier returns a ContractSigVerifier calling contracts with caller
+func NewContractSigVerifier(caller bind.ContractCaller) (*ContractSigVerifier, error) {
+	parsed, err := abi.JSON(strings.NewReader(erc1271ABI))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ContractSigVerifier{
+		caller:    caller,
+		abi:       parsed,
+		eoas:      make(map[ethcommon.Address]time.Time),
+		contracts: make(map[ethcommon.Address]bool),
+		sigs:      make(map[string]sigResult),
+	}, nil
+}
+
+// Verify checks if a provided signature over a message
+// is valid for a given ETH address
+func (sv *ContractSigVerifier) Verify(addr ethcommon.Address, msg, sig []byte) bool {
+	if crypto.VerifySig(addr, msg, sig) {
+		return true
+	}
+	return sv.isValidSignature(addr, accounts.TextHash(msg), sig)
+}
+
+// VerifyTypedData checks if a provided signature over EIP-712
+// typed data is valid for a given ETH address
+func (sv *ContractSigVerifier) VerifyTypedData(addr ethcommon.Address, typedData *TypedData, sig []byte) bool {
+	hash := typedData.Hash().Bytes()
+	if crypto.VerifyHashSig(addr, hash, sig) {
+		return true
+	}
+	return sv.isValidSignature(addr, hash, sig)
+}
+
+// isValidSignature checks the signature with the isValidSignature function of addr, if addr is
+// a contract
+func (sv *ContractSigVerifier) isValidSignature(addr ethcommon.Address, hash, sig []byte) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), erc1271CallTimeout)
+	defer cancel()
+
+	isContract, err := sv.isContract(ctx, addr)
+	if err != nil {
+		glog.Errorf("Error getting the code of sig signer addr=%v err=%v", addr.Hex(), err)
+		return false
+	}
+	if !isContract {
+		return false
+	}
+
+	key := string(addr.Bytes()) + string(hash) + string(sig)
+	if valid, ok := sv.cachedSig(key); ok {
+		return valid
+	}
+
+	var hash32 [32]byte
+	copy(hash32[:], hash)
+	data, err := sv.abi.Pack("isValidSignature", hash32, sig)
+	if err != nil {
+		return false
+	}
+
+	out, err := sv.caller.CallContract(ctx, ethereum.CallMsg{To: &addr, Data: data}, nil)
+	if err != nil {
+		// Contracts without isValidSignature revert
+		glog.Warningf("Error calling isValidSignature addr=%v err=%v", addr.Hex(), err)
+		return false
+	}
+
+	// The bytes4 magic value is left aligned in its 32 byte word
+	valid := len(out) >= len(erc1271MagicValue) && bytes.Equal(out[:len(erc1271MagicValue)], erc1271MagicValue)
+	sv.cacheSig(key, valid)
+	return valid
+}
+
+func (sv *ContractSigVerifier) cachedSig(key string) (bool, bool) {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	res, ok := sv.sigs[key]
+	if !ok || time.Since(res.checkedAt) >= sigCacheTTL {
+		return false, false
+	}
+	return res.valid, true
+}
+
+func (sv *ContractSigVerifier) cacheSig(key string, valid bool) {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	if len(sv.sigs) >= maxCachedSigs {
+		for k, res := range sv.sigs {
+			if time.Since(res.checkedAt) >= sigCacheTTL {
+				delete(sv.sigs, k)
+			}
+		}
+		// Start over if all the results are recent
+		if len(sv.sigs) >= maxCachedSigs {
+			sv.sigs = make(map[string]sigResult)
+		}
+	}
+	sv.sigs[key] = sigResult{valid: valid, checkedAt: time.Now()}
+}
+
+func (sv *ContractSigVerifier) isContract(ctx context.Context, addr ethcommon.Address) (bool, error) {
+	sv.mu.Lock()
+	if sv.contracts[addr] {
+		sv.mu.Unlock()
+		return true, nil
+	}
+	if checkedAt, ok := sv.eoas[addr]; ok && time.Since(checkedAt) < eoaCacheTTL {
+		sv.mu.Unlock()
+		return false, nil
+	}
+	sv.mu.Unlock()
+
+	code, err := sv.caller.CodeAt(ctx, addr, nil)
+	if err != nil {
+		return false, err
+	}
+
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	if len(code) > 0 {
+		sv.contracts[addr] = true
+		delete(sv.eoas, addr)
+		return true, nil
+	}
+	sv.eoas[addr] = time.Now()
+	return false, nil
+}
+
 // ApprovedSigVerifier is an implementation of the SigVerifier interface
 // that relies on an implementation of the Broker interface to provide a registry
 // mapping ETH addresses to approved signer sets. This implementation will
@@ -1,5 +1,192 @@
 package pm
 
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/livepeer/go-livepeer/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubWallet is an ERC-1271 wallet accepting the signatures of its owner, like simple account
+// abstraction wallets
+type stubWallet struct {
+	t       *testing.T
+	addr    ethcommon.Address
+	owner   ethcommon.Address
+	code    []byte
+	codeErr error
+	revert  bool
+
+	codeAtCalls int
+	calls       int
+}
+
+func (w *stubWallet) CodeAt(ctx context.Context, contract ethcommon.Address, blockNumber *big.Int) ([]byte, error) {
+	w.codeAtCalls++
+	if contract != w.addr {
+		return nil, nil
+	}
+	return w.code, w.codeErr
+}
+
+func (w *stubWallet) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	w.calls++
+	if w.revert {
+		return nil, errors.New("execution reverted")
+	}
+
+	parsed, err := abi.JSON(strings.NewReader(erc1271ABI))
+	require.Nil(w.t, err)
+	method := parsed.Methods["isValidSignature"]
+	require.Equal(w.t, method.ID(), call.Data[:4])
+	args, err := method.Inputs.UnpackValues(call.Data[4:])
+	require.Nil(w.t, err)
+	hash := args[0].([32]byte)
+	sig := args[1].([]byte)
+
+	out := make([]byte, 32)
+	if *call.To == w.addr && crypto.VerifyHashSig(w.owner, hash[:], sig) {
+		copy(out, erc1271MagicValue)
+	}
+	return out, nil
+}
+
+func TestContractSigVerifier_Verify(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	key, err := ethcrypto.GenerateKey()
+	require.Nil(err)
+	owner := ethcrypto.PubkeyToAddress(key.PublicKey)
+	wallet := &stubWallet{t: t, addr: RandAddress(), owner: owner, code: []byte{0x60}}
+
+	sv, err := NewContractSigVerifier(wallet)
+	require.Nil(err)
+
+	msg := RandBytes(32)
+	sig, err := ethcrypto.Sign(accounts.TextHash(msg), key)
+	require.Nil(err)
+	sig[64] += 27
+
+	// Test ECDSA signatures don't require calls
+	assert.True(sv.Verify(owner, msg, sig))
+	assert.Zero(wallet.codeAtCalls)
+	assert.Zero(wallet.calls)
+
+	// Test signatures of the owner are valid for the wallet
+	assert.True(sv.Verify(wallet.addr, msg, sig))
+	assert.Equal(1, wallet.calls)
+	assert.False(sv.Verify(wallet.addr, RandBytes(32), sig))
+
+	// Test the results of isValidSignature are cached
+	assert.True(sv.Verify(wallet.addr, msg, sig))
+	assert.Equal(2, wallet.calls)
+
+	// Test the code of contracts is only requested once
+	assert.Equal(1, wallet.codeAtCalls)
+
+	// Test signatures of other keys are invalid
+	otherKey, err := ethcrypto.GenerateKey()
+	require.Nil(err)
+	otherSig, err := ethcrypto.Sign(accounts.TextHash(msg), otherKey)
+	require.Nil(err)
+	otherSig[64] += 27
+	assert.False(sv.Verify(wallet.addr, msg, otherSig))
+
+	// Test the results are checked again after the cache TTL
+	defer func(ttl time.Duration) { sigCacheTTL = ttl }(sigCacheTTL)
+	sigCacheTTL = 0
+
+	// Test contracts without isValidSignature are invalid
+	wallet.revert = true
+	assert.False(sv.Verify(wallet.addr, msg, sig))
+	assert.Equal(4, wallet.calls)
+}
+
+func TestContractSigVerifier_MaxCachedSigs(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	defer func(max int) { maxCachedSigs = max }(maxCachedSigs)
+	maxCachedSigs = 2
+
+	wallet := &stubWallet{t: t, addr: RandAddress(), code: []byte{0x60}}
+	sv, err := NewContractSigVerifier(wallet)
+	require.Nil(err)
+
+	for i := 0; i < 3; i++ {
+		assert.False(sv.Verify(wallet.addr, RandBytes(32), RandBytes(65)))
+		assert.True(len(sv.sigs) <= maxCachedSigs)
+	}
+	assert.Len(sv.sigs, 1)
+}
+
+func TestContractSigVerifier_VerifyTypedData(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	key, err := ethcrypto.GenerateKey()
+	require.Nil(err)
+	wallet := &stubWallet{t: t, addr: RandAddress(), owner: ethcrypto.PubkeyToAddress(key.PublicKey), code: []byte{0x60}}
+
+	sv, err := NewContractSigVerifier(wallet)
+	require.Nil(err)
+
+	ticket := typedDataTicket()
+	ticket.Sender = wallet.addr
+	sig, err := ethcrypto.Sign(ticket.TypedData().Hash().Bytes(), key)
+	require.Nil(err)
+	sig[64] += 27
+
+	assert.True(sv.VerifyTypedData(wallet.addr, ticket.TypedData(), sig))
+	assert.True(verifyTicketSig(sv, ticket, sig))
+	assert.False(sv.VerifyTypedData(wallet.addr, typedDataTicket().TypedData(), sig))
+}
+
+func TestContractSigVerifier_EOA(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	defer func(ttl time.Duration) { eoaCacheTTL = ttl }(eoaCacheTTL)
+	eoaCacheTTL = time.Hour
+
+	wallet := &stubWallet{t: t, addr: RandAddress()}
+	sv, err := NewContractSigVerifier(wallet)
+	require.Nil(err)
+
+	// Test isValidSignature isn't called for addresses without code
+	assert.False(sv.Verify(wallet.addr, RandBytes(32), RandBytes(65)))
+	assert.False(sv.Verify(wallet.addr, RandBytes(32), RandBytes(65)))
+	assert.Equal(1, wallet.codeAtCalls)
+	assert.Zero(wallet.calls)
+
+	// Test addresses without code are checked again after the cache TTL, e.g. once a
+	// counterfactual wallet is deployed
+	eoaCacheTTL = 0
+	wallet.code = []byte{0x60}
+	assert.False(sv.Verify(wallet.addr, RandBytes(32), RandBytes(65)))
+	assert.Equal(2, wallet.codeAtCalls)
+	assert.Equal(1, wallet.calls)
+
+	// Test errors getting the code are invalid
+	wallet.codeErr = errors.New("CodeAt error")
+	sv, err = NewContractSigVerifier(wallet)
+	require.Nil(err)
+	assert.False(sv.Verify(wallet.addr, RandBytes(32), RandBytes(65)))
+	assert.Equal(1, wallet.calls)
+}
+
 // func TestVerify(t *testing.T) {
 // 	msg := []byte("foo")
 // 	personalMsg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", 32, msg)
@@ -0,0 +1,263 @@
+package simulator
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/livepeer/go-livepeer/pm"
+)
+
+var errTicketRedeemed = errors.New("ticket already redeemed")
+
+// ledger simulates the funds held by the TicketBroker: the deposits and reserves of the
+// senders and the winnings paid to the recipient
+type ledger struct {
+	mu       sync.Mutex
+	deposits map[ethcommon.Address]*big.Int
+	reserves map[ethcommon.Address]*big.Int
+	redeemed map[ethcommon.Hash]bool
+	paid     *big.Int
+}
+
+func newLedger() *ledger {
+	return &ledger{
+		deposits: make(map[ethcommon.Address]*big.Int),
+		reserves: make(map[ethcommon.Address]*big.Int),
+		redeemed: make(map[ethcommon.Hash]bool),
+		paid:     big.NewInt(0),
+	}
+}
+
+func (l *ledger) fund(sender ethcommon.Address, deposit, reserve *big.Int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.deposits[sender] = new(big.Int).Set(deposit)
+	l.reserves[sender] = new(big.Int).Set(reserve)
+}
+
+// funds returns the total deposits and reserves of the senders
+func (l *ledger) funds() *big.Int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	total := big.NewInt(0)
+	for sender, deposit := range l.deposits {
+		total.Add(total, deposit)
+		total.Add(total, l.reserves[sender])
+	}
+	return total
+}
+
+// redeem pays the face value of a winning ticket to the recipient, from the deposit of the
+// sender and then from its reserve, as the TicketBroker does. Tickets can only be redeemed once
+func (l *ledger) redeem(ticket *pm.Ticket) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	hash := ticket.Hash()
+	if l.redeemed[hash] {
+		return errTicketRedeemed
+	}
+	l.redeemed[hash] = true
+
+	deposit := l.deposits[ticket.Sender]
+	reserve := l.reserves[ticket.Sender]
+	fromDeposit := minInt(deposit, ticket.FaceValue)
+	fromReserve := minInt(reserve, new(big.Int).Sub(ticket.FaceValue, fromDeposit))
+	deposit.Sub(deposit, fromDeposit)
+	reserve.Sub(reserve, fromReserve)
+	l.paid.Add(l.paid, fromDeposit)
+	l.paid.Add(l.paid, fromReserve)
+	return nil
+}
+
+func minInt(x, y *big.Int) *big.Int {
+	if x.Cmp(y) < 0 {
+		return new(big.Int).Set(x)
+	}
+	return new(big.Int).Set(y)
+}
+
+func (l *ledger) paidOut() *big.Int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return new(big.Int).Set(l.paid)
+}
+
+// GetSenderInfo returns the deposit and reserve of a sender
+func (l *ledger) GetSenderInfo(addr ethcommon.Address) (*pm.SenderInfo, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	deposit, ok := l.deposits[addr]
+	if !ok {
+		return nil, errors.New("unknown sender")
+	}
+	return &pm.SenderInfo{
+		Deposit:       new(big.Int).Set(deposit),
+		WithdrawRound: big.NewInt(0),
+		Reserve: &pm.ReserveInfo{
+			FundsRemaining:        new(big.Int).Set(l.reserves[addr]),
+			ClaimedInCurrentRound: big.NewInt(0),
+		},
+	}, nil
+}
+
+// ClaimedReserve returns 0 since the winnings of the recipient are paid from deposits first
+func (l *ledger) ClaimedReserve(reserveHolder ethcommon.Address, claimant ethcommon.Address) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+
+// Clear is a noop since the ledger doesn't cache anything
+func (l *ledger) Clear(addr ethcommon.Address) {}
+
+// SubscribeReserveChange returns a subscription that never notifies anything, since
+// reserves are not funded during a simulation
+func (l *ledger) SubscribeReserveChange(sink chan<- ethcommon.Address) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// senderMonitor redeems the winning tickets queued by the recipient right away
+type senderMonitor struct {
+	ledger *ledger
+}
+
+func (sm *senderMonitor) Start() {}
+
+func (sm *senderMonitor) Stop() {}
+
+func (sm *senderMonitor) QueueTicket(ticket *pm.SignedTicket) error {
+	return sm.ledger.redeem(ticket.Ticket)
+}
+
+func (sm *senderMonitor) WinningTickets() ([]*pm.SignedTicket, error) {
+	return nil, nil
+}
+
+func (sm *senderMonitor) RemoveWinningTickets(tickets []*pm.SignedTicket) error {
+	return nil
+}
+
+// MaxFloat returns the funds of a sender, since winning tickets are redeemed right away
+func (sm *senderMonitor) MaxFloat(addr ethcommon.Address) (*big.Int, error) {
+	info, err := sm.ledger.GetSenderInfo(addr)
+	if err != nil {
+		return nil, err
+	}
+	return info.Deposit.Add(info.Deposit, info.Reserve.FundsRemaining), nil
+}
+
+func (sm *senderMonitor) ValidateSender(addr ethcommon.Address) error {
+	_, err := sm.ledger.GetSenderInfo(addr)
+	return err
+}
+
+// timeManager simulates the rounds of the protocol, which are advanced by the simulation
+// so that the EV of old tickets stops counting against the deposits of senders
+type timeManager struct {
+	mu        sync.Mutex
+	round     *big.Int
+	blockHash [32]byte
+
+	roundFeed event.Feed
+	blockFeed event.Feed
+}
+
+func newTimeManager() *timeManager {
+	return &timeManager{round: big.NewInt(1), blockHash: pm.RandHash()}
+}
+
+func (tm *timeManager) nextRound() {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tm.round = new(big.Int).Add(tm.round, big.NewInt(1))
+	tm.blockHash = pm.RandHash()
+}
+
+func (tm *timeManager) LastInitializedRound() *big.Int {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	return tm.round
+}
+
+func (tm *timeManager) LastInitializedBlockHash() [32]byte {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	return tm.blockHash
+}
+
+func (tm *timeManager) GetTranscoderPoolSize() *big.Int {
+	return big.NewInt(1)
+}
+
+// LastSeenBlock is constant, so ticket params don't expire during a simulation
+func (tm *timeManager) LastSeenBlock() *big.Int {
+	return big.NewInt(1)
+}
+
+func (tm *timeManager) SubscribeRounds(sink chan<- types.Log) event.Subscription {
+	return tm.roundFeed.Subscribe(sink)
+}
+
+func (tm *timeManager) SubscribeBlocks(sink chan<- *big.Int) event.Subscription {
+	return tm.blockFeed.Subscribe(sink)
+}
+
+// gasPriceMonitor returns a gas price that makes the face value of tickets the configured one
+type gasPriceMonitor struct {
+	gasPrice *big.Int
+}
+
+func (gpm *gasPriceMonitor) GasPrice() *big.Int {
+	return gpm.gasPrice
+}
+
+// keySigner signs tickets with a private key
+type keySigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func newKeySigner() (*keySigner, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	return &keySigner{key: key}, nil
+}
+
+func (s *keySigner) Sign(msg []byte) ([]byte, error) {
+	return s.signHash(accounts.TextHash(msg))
+}
+
+func (s *keySigner) SignTypedData(typedData *pm.TypedData) ([]byte, error) {
+	return s.signHash(typedData.Hash().Bytes())
+}
+
+// signHash signs a hash with a v value of 27 or 28, which is what the TicketBroker expects
+func (s *keySigner) signHash(hash []byte) ([]byte, error) {
+	sig, err := crypto.Sign(hash, s.key)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+	return sig, nil
+}
+
+func (s *keySigner) Account() accounts.Account {
+	return accounts.Account{Address: crypto.PubkeyToAddress(s.key.PublicKey)}
+}
@@ -0,0 +1,299 @@
+// Package simulator runs simulated probabilistic micropayment sessions between senders and
+// a recipient of package pm, and checks that value is conserved, so that changes to ticket
+// parameters can be validated before they are used on mainnet.
+//
+// Senders and the recipient are the ones used by the node, while the TicketBroker, the
+// rounds of the protocol and the gas price are simulated. Winning tickets are redeemed as
+// soon as they are received.
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"strings"
+
+	"github.com/livepeer/go-livepeer/pm"
+)
+
+// Config is the configuration of a simulation
+type Config struct {
+	// Senders is the number of senders, which take turns to start sessions
+	Senders int
+	// Sessions is the number of sessions, each with its own ticket params
+	Sessions int
+	// TicketsPerSession is the number of tickets sent in every session
+	TicketsPerSession int
+
+	// FaceValue is the face value of tickets
+	FaceValue *big.Int
+	// WinProb is the probability of tickets to win, in (0, 1]
+	WinProb *big.Rat
+	// TicketVersion is the version of the tickets accepted by the recipient
+	TicketVersion uint32
+
+	// Deposit and Reserve are the funds of every sender. They default to funds that cover
+	// every ticket sent, so that senders never refuse to send tickets
+	Deposit *big.Int
+	Reserve *big.Int
+
+	// DoubleSpendRate is the probability that a ticket already sent is sent again after a ticket
+	DoubleSpendRate float64
+	// BadSigRate is the probability that a ticket is sent with an invalid signature
+	BadSigRate float64
+
+	// Seed seeds the choice of adversarial behaviors. Tickets win randomly regardless
+	Seed int64
+}
+
+// Result holds the outcome of a simulation
+type Result struct {
+	// Tickets is the number of tickets sent, including adversarial tickets
+	Tickets int64
+	// DoubleSpends is the number of tickets sent again
+	DoubleSpends int64
+	// BadSigs is the number of tickets sent with an invalid signature
+	BadSigs int64
+	// Refused is the number of tickets refused by the recipient
+	Refused int64
+	// RefusedBatches is the number of sessions for which the sender refused to create tickets,
+	// e.g. because its deposit can't cover them
+	RefusedBatches int64
+
+	// WinningTickets is the number of winning tickets redeemed
+	WinningTickets int64
+	// RefusedRedemptions is the number of winning tickets that could not be redeemed
+	// because they were already redeemed
+	RefusedRedemptions int64
+
+	// TicketEV is the expected value of a ticket, 0 if no tickets were sent
+	TicketEV *big.Rat
+	// SpentEV is the EV of the tickets created by the senders
+	SpentEV *big.Rat
+	// ReceivedEV is the EV of the tickets accepted by the recipient
+	ReceivedEV *big.Rat
+	// Paid is the face value paid out for winning tickets
+	Paid *big.Int
+	// FundsSpent is the amount by which the deposits and reserves of the senders decreased
+	FundsSpent *big.Int
+
+	// Violations lists the broken invariants of the simulation
+	Violations []string
+
+	faceValue *big.Int
+}
+
+// Err returns an error describing the violations of the simulation, if any
+func (res *Result) Err() error {
+	if len(res.Violations) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(res.Violations, "; "))
+}
+
+// Deviation returns the number of standard deviations between the value paid out and the EV
+// received by the recipient. The value paid out converges to the EV received as the number of
+// tickets grows, so a large deviation means that tickets don't win as often as they should
+func (res *Result) Deviation() float64 {
+	faceValue, _ := new(big.Rat).SetInt(res.faceValue).Float64()
+	ev, _ := res.TicketEV.Float64()
+	p := ev / faceValue
+	accepted := float64(res.Tickets - res.Refused)
+	stddev := faceValue * math.Sqrt(accepted*p*(1-p))
+
+	paid, _ := new(big.Rat).SetInt(res.Paid).Float64()
+	received, _ := res.ReceivedEV.Float64()
+	if stddev == 0 {
+		if paid == received {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (paid - received) / stddev
+}
+
+type simSender struct {
+	pm.Sender
+	signer *keySigner
+}
+
+// Run runs a simulation. The returned error is about the configuration or the setup of the
+// simulation, while the violations of conservation of value are in the result
+func Run(cfg Config) (*Result, error) {
+	if cfg.Senders <= 0 || cfg.Sessions <= 0 || cfg.TicketsPerSession <= 0 {
+		return nil, errors.New("senders, sessions and tickets per session must be positive")
+	}
+	if cfg.FaceValue == nil || cfg.FaceValue.Sign() <= 0 {
+		return nil, errors.New("face value must be positive")
+	}
+	if cfg.WinProb == nil || cfg.WinProb.Sign() <= 0 || cfg.WinProb.Cmp(big.NewRat(1, 1)) > 0 {
+		return nil, errors.New("win probability must be in (0, 1]")
+	}
+	evRat := new(big.Rat).Mul(new(big.Rat).SetInt(cfg.FaceValue), cfg.WinProb)
+	ev := new(big.Int).Quo(evRat.Num(), evRat.Denom())
+	if ev.Sign() <= 0 {
+		return nil, fmt.Errorf("ticket EV of face value %v and win probability %v is less than 1 wei", cfg.FaceValue, cfg.WinProb.FloatString(10))
+	}
+
+	deposit, reserve := cfg.Deposit, cfg.Reserve
+	if deposit == nil {
+		total := int64(cfg.Sessions) * int64(cfg.TicketsPerSession)
+		deposit = new(big.Int).Mul(cfg.FaceValue, big.NewInt(total))
+	}
+	if reserve == nil {
+		reserve = new(big.Int).Set(deposit)
+	}
+
+	l := newLedger()
+	tm := newTimeManager()
+	recipientAddr := pm.RandAddress()
+	r, err := pm.NewRecipient(
+		recipientAddr,
+		nil,
+		pm.NewValidator(&pm.DefaultSigVerifier{}, tm),
+		// The face value of tickets is the tx cost of their redemption
+		&gasPriceMonitor{gasPrice: cfg.FaceValue},
+		&senderMonitor{ledger: l},
+		tm,
+		pm.TicketParamsConfig{
+			EV:               ev,
+			RedeemGas:        1,
+			TxCostMultiplier: 1,
+			TicketVersion:    cfg.TicketVersion,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	senders := make([]*simSender, cfg.Senders)
+	for i := range senders {
+		signer, err := newKeySigner()
+		if err != nil {
+			return nil, err
+		}
+		l.fund(signer.Account().Address, deposit, reserve)
+		senders[i] = &simSender{
+			Sender: pm.NewSender(signer, tm, l, new(big.Rat).SetInt(deposit), 1, nil),
+			signer: signer,
+		}
+	}
+	initialFunds := l.funds()
+
+	res := &Result{
+		TicketEV:   new(big.Rat),
+		SpentEV:    new(big.Rat),
+		ReceivedEV: new(big.Rat),
+		faceValue:  cfg.FaceValue,
+	}
+	var honestRefused, adversarialAccepted, sessionWinningTickets int64
+
+	receive := func(ticket *pm.Ticket, sig []byte, params *pm.TicketParams, adversarial bool) {
+		res.Tickets++
+		_, won, err := r.ReceiveTicket(ticket, sig, params.Seed)
+		if err != nil {
+			res.Refused++
+			if !adversarial {
+				honestRefused++
+			}
+		} else if adversarial {
+			adversarialAccepted++
+		}
+
+		// Winning tickets are redeemed even if they are refused, like the orchestrator does
+		if won {
+			if err := r.RedeemWinningTicket(ticket, sig, params.Seed); err != nil {
+				res.RefusedRedemptions++
+			} else {
+				res.WinningTickets++
+			}
+		}
+	}
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	for i := 0; i < cfg.Sessions; i++ {
+		sender := senders[i%len(senders)]
+		params, err := r.TicketParams(sender.signer.Account().Address, big.NewRat(1, 1))
+		if err != nil {
+			return nil, err
+		}
+		sessionID := sender.StartSession(*params)
+		batch, err := sender.CreateTicketBatch(sessionID, cfg.TicketsPerSession)
+		if err != nil {
+			res.RefusedBatches++
+			tm.nextRound()
+			continue
+		}
+
+		// Tickets are received as the orchestrator receives them from the ticket params and
+		// the sender params of the batch
+		var tickets []*pm.Ticket
+		for _, sp := range batch.SenderParams {
+			tickets = append(tickets, pm.NewTicket(batch.TicketParams, batch.TicketExpirationParams, batch.Sender, sp.SenderNonce))
+		}
+		if res.TicketEV.Sign() == 0 {
+			res.TicketEV = tickets[0].EV()
+		}
+
+		var sent []int
+		for j, sp := range batch.SenderParams {
+			if rng.Float64() < cfg.BadSigRate {
+				sig := append([]byte(nil), sp.Sig...)
+				sig[0] ^= 0xff
+				res.BadSigs++
+				receive(tickets[j], sig, params, true)
+			} else {
+				receive(tickets[j], sp.Sig, params, false)
+				sent = append(sent, j)
+			}
+
+			if len(sent) > 0 && rng.Float64() < cfg.DoubleSpendRate {
+				k := sent[rng.Intn(len(sent))]
+				res.DoubleSpends++
+				receive(tickets[k], batch.SenderParams[k].Sig, params, true)
+			}
+		}
+
+		if session := r.SessionPayments(sessionID); session != nil {
+			res.ReceivedEV.Add(res.ReceivedEV, session.EV)
+			sessionWinningTickets += session.WinningTickets
+		}
+
+		// Rounds advance so that the EV of old tickets stops counting against deposits
+		tm.nextRound()
+	}
+
+	for _, sender := range senders {
+		res.SpentEV.Add(res.SpentEV, sender.SpentEV(recipientAddr))
+	}
+	res.Paid = l.paidOut()
+	res.FundsSpent = new(big.Int).Sub(initialFunds, l.funds())
+
+	violation := func(format string, args ...interface{}) {
+		res.Violations = append(res.Violations, fmt.Sprintf(format, args...))
+	}
+	if res.FundsSpent.Cmp(res.Paid) != 0 {
+		violation("funds spent %v != value paid %v", res.FundsSpent, res.Paid)
+	}
+	if expected := new(big.Int).Mul(cfg.FaceValue, big.NewInt(res.WinningTickets)); res.Paid.Cmp(expected) != 0 {
+		violation("value paid %v != face value of %v winning tickets %v", res.Paid, res.WinningTickets, expected)
+	}
+	if sessionWinningTickets != res.WinningTickets {
+		violation("winning tickets of sessions %v != winning tickets redeemed %v", sessionWinningTickets, res.WinningTickets)
+	}
+	if honestRefused > 0 {
+		violation("%v honest tickets refused", honestRefused)
+	}
+	if adversarialAccepted > 0 {
+		violation("%v adversarial tickets accepted", adversarialAccepted)
+	}
+	// Tickets with a bad signature are created by the sender but never received
+	lostEV := new(big.Rat).Mul(res.TicketEV, new(big.Rat).SetInt64(res.BadSigs))
+	if expected := new(big.Rat).Sub(res.SpentEV, lostEV); res.ReceivedEV.Cmp(expected) != 0 {
+		violation("EV received %v != EV spent by senders %v", res.ReceivedEV.FloatString(5), expected.FloatString(5))
+	}
+
+	return res, nil
+}
@@ -0,0 +1,155 @@
+package simulator
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/livepeer/go-livepeer/pm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_InvalidConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := Config{Senders: 1, Sessions: 1, TicketsPerSession: 1, FaceValue: big.NewInt(100), WinProb: big.NewRat(1, 2)}
+
+	invalid := cfg
+	invalid.Sessions = 0
+	_, err := Run(invalid)
+	assert.EqualError(err, "senders, sessions and tickets per session must be positive")
+
+	invalid = cfg
+	invalid.FaceValue = big.NewInt(0)
+	_, err = Run(invalid)
+	assert.EqualError(err, "face value must be positive")
+
+	invalid = cfg
+	invalid.WinProb = big.NewRat(3, 2)
+	_, err = Run(invalid)
+	assert.EqualError(err, "win probability must be in (0, 1]")
+
+	invalid = cfg
+	invalid.WinProb = big.NewRat(1, 1000)
+	_, err = Run(invalid)
+	assert.EqualError(err, "ticket EV of face value 100 and win probability 0.0010000000 is less than 1 wei")
+}
+
+func TestRun_Honest(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sessions := 200
+	if testing.Short() {
+		sessions = 20
+	}
+	res, err := Run(Config{
+		Senders:           3,
+		Sessions:          sessions,
+		TicketsPerSession: 10,
+		FaceValue:         big.NewInt(1000000),
+		WinProb:           big.NewRat(1, 10),
+	})
+	require.Nil(err)
+	assert.Nil(res.Err())
+
+	assert.Equal(int64(sessions*10), res.Tickets)
+	assert.Zero(res.Refused)
+	assert.Zero(res.RefusedRedemptions)
+	assert.Equal(res.SpentEV, res.ReceivedEV)
+	assert.Equal(res.Paid, res.FundsSpent)
+	assert.Less(math.Abs(res.Deviation()), 6.0)
+}
+
+func TestRun_Adversarial(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sessions := 200
+	if testing.Short() {
+		sessions = 20
+	}
+	for _, version := range []uint32{pm.TicketVersionLegacy, pm.TicketVersionTypedData} {
+		res, err := Run(Config{
+			Senders:           2,
+			Sessions:          sessions,
+			TicketsPerSession: 10,
+			FaceValue:         big.NewInt(1000),
+			// Many winning tickets are double spent
+			WinProb:         big.NewRat(1, 2),
+			TicketVersion:   version,
+			DoubleSpendRate: 0.2,
+			BadSigRate:      0.1,
+			Seed:            int64(version),
+		})
+		require.Nil(err)
+		assert.Nil(res.Err())
+
+		assert.NotZero(res.DoubleSpends)
+		assert.NotZero(res.BadSigs)
+		assert.NotZero(res.RefusedRedemptions)
+		assert.Equal(res.DoubleSpends+res.BadSigs, res.Refused)
+		assert.Equal(int64(sessions*10)+res.DoubleSpends, res.Tickets)
+		assert.Equal(res.Paid, res.FundsSpent)
+		assert.Less(math.Abs(res.Deviation()), 6.0)
+	}
+}
+
+func TestRun_InsufficientFunds(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Senders refuse to send tickets once their funds can't cover them
+	res, err := Run(Config{
+		Senders:           1,
+		Sessions:          20,
+		TicketsPerSession: 10,
+		FaceValue:         big.NewInt(1000),
+		WinProb:           big.NewRat(1, 2),
+		Deposit:           big.NewInt(20000),
+		Reserve:           big.NewInt(10000),
+	})
+	require.Nil(err)
+	assert.Nil(res.Err())
+	assert.NotZero(res.RefusedBatches)
+	assert.Zero(res.Refused)
+	assert.True(res.Paid.Cmp(big.NewInt(30000)) <= 0)
+	assert.Equal(res.Paid, res.FundsSpent)
+
+	// No tickets are sent without a reserve
+	res, err = Run(Config{
+		Senders:           1,
+		Sessions:          2,
+		TicketsPerSession: 10,
+		FaceValue:         big.NewInt(1000),
+		WinProb:           big.NewRat(1, 2),
+		Reserve:           big.NewInt(0),
+	})
+	require.Nil(err)
+	assert.Nil(res.Err())
+	assert.Equal(int64(2), res.RefusedBatches)
+	assert.Zero(res.Tickets)
+	assert.Zero(res.Deviation())
+}
+
+func TestRun_RandomConfigs(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		cfg := Config{
+			Senders:           1 + rng.Intn(3),
+			Sessions:          1 + rng.Intn(20),
+			TicketsPerSession: 1 + rng.Intn(10),
+			FaceValue:         big.NewInt(1000 + rng.Int63n(1000000)),
+			WinProb:           big.NewRat(1, 1+rng.Int63n(100)),
+			TicketVersion:     uint32(rng.Intn(2)),
+			DoubleSpendRate:   rng.Float64() / 2,
+			BadSigRate:        rng.Float64() / 2,
+			Seed:              rng.Int63(),
+		}
+		res, err := Run(cfg)
+		require.Nil(t, err)
+		assert.Nil(t, res.Err(), "config %+v", cfg)
+	}
+}
@@ -1,9 +1,11 @@
 package pm
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -22,6 +24,7 @@ type stubTicketStore struct {
 	stubBlockStore
 	tickets          map[ethcommon.Address][]*SignedTicket
 	submitted        map[string]bool
+	retryAt          map[string]time.Time
 	storeShouldFail  bool
 	loadShouldFail   bool
 	removeShouldFail bool
@@ -32,6 +35,7 @@ func newStubTicketStore() *stubTicketStore {
 	return &stubTicketStore{
 		tickets:   make(map[ethcommon.Address][]*SignedTicket),
 		submitted: make(map[string]bool),
+		retryAt:   make(map[string]time.Time),
 	}
 }
 
@@ -46,7 +50,7 @@ func (ts *stubTicketStore) StoreWinningTicket(ticket *SignedTicket) error {
 	// if ticket exists don't insert it
 	for _, t := range ts.tickets[ticket.Sender] {
 		if fmt.Sprintf("%x", t.Sig) == fmt.Sprintf("%x", ticket.Sig) {
-			return nil
+			return ErrTicketStored
 		}
 	}
 
@@ -61,13 +65,31 @@ func (ts *stubTicketStore) SelectEarliestWinningTicket(sender ethcommon.Address)
 		return nil, fmt.Errorf("stub TicketStore load error")
 	}
 	for _, t := range ts.tickets[sender] {
-		if !ts.submitted[fmt.Sprintf("%x", t.Sig)] {
+		if !ts.submitted[fmt.Sprintf("%x", t.Sig)] && !ts.retryAt[fmt.Sprintf("%x", t.Sig)].After(time.Now()) {
 			return t, nil
 		}
 	}
 	return nil, nil
 }
 
+func (ts *stubTicketStore) SelectEarliestWinningTickets(sender ethcommon.Address, block *big.Int, limit int) ([]*SignedTicket, error) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	if ts.loadShouldFail {
+		return nil, fmt.Errorf("stub TicketStore load error")
+	}
+	var tickets []*SignedTicket
+	for _, t := range ts.tickets[sender] {
+		if len(tickets) == limit {
+			break
+		}
+		if !ts.submitted[fmt.Sprintf("%x", t.Sig)] && !ts.retryAt[fmt.Sprintf("%x", t.Sig)].After(time.Now()) && t.ParamsExpirationBlock.Cmp(block) <= 0 {
+			tickets = append(tickets, t)
+		}
+	}
+	return tickets, nil
+}
+
 func (ts *stubTicketStore) MarkWinningTicketRedeemed(ticket *SignedTicket, txHash ethcommon.Hash) error {
 	ts.lock.Lock()
 	defer ts.lock.Unlock()
@@ -75,6 +97,14 @@ func (ts *stubTicketStore) MarkWinningTicketRedeemed(ticket *SignedTicket, txHas
 	return nil
 }
 
+func (ts *stubTicketStore) MarkWinningTicketFailed(ticket *SignedTicket, attempts int, retryAt time.Time) error {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	ticket.RedeemAttempts = attempts
+	ts.retryAt[fmt.Sprintf("%x", ticket.Sig)] = retryAt
+	return nil
+}
+
 func (ts *stubTicketStore) RemoveWinningTicket(ticket *SignedTicket) error {
 	ts.lock.Lock()
 	defer ts.lock.Unlock()
@@ -110,6 +140,54 @@ func (ts *stubTicketStore) WinningTicketCount(sender ethcommon.Address) (int, er
 	return count, nil
 }
 
+func (ts *stubTicketStore) WinningTickets(sender ethcommon.Address) ([]*SignedTicket, error) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	if ts.loadShouldFail {
+		return nil, fmt.Errorf("stub TicketStore load error")
+	}
+	var tickets []*SignedTicket
+	for _, t := range ts.tickets[sender] {
+		if !ts.submitted[fmt.Sprintf("%x", t.Sig)] {
+			tickets = append(tickets, t)
+		}
+	}
+	return tickets, nil
+}
+
+func (ts *stubTicketStore) WinningTicketSenders() ([]ethcommon.Address, error) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	if ts.loadShouldFail {
+		return nil, fmt.Errorf("stub TicketStore load error")
+	}
+	var senders []ethcommon.Address
+	for sender, tickets := range ts.tickets {
+		for _, t := range tickets {
+			if !ts.submitted[fmt.Sprintf("%x", t.Sig)] {
+				senders = append(senders, sender)
+				break
+			}
+		}
+	}
+	return senders, nil
+}
+
+func (ts *stubTicketStore) WinningTicketFaceValue(sender ethcommon.Address) (*big.Int, error) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	if ts.loadShouldFail {
+		return nil, fmt.Errorf("stub TicketStore load error")
+	}
+	faceValue := big.NewInt(0)
+	for _, t := range ts.tickets[sender] {
+		if !ts.submitted[fmt.Sprintf("%x", t.Sig)] {
+			faceValue.Add(faceValue, t.FaceValue)
+		}
+	}
+	return faceValue, nil
+}
+
 func (ts *stubBlockStore) LastSeenBlock() (*big.Int, error) {
 	return ts.lastBlock, ts.err
 }
@@ -126,6 +204,10 @@ func (sv *stubSigVerifier) Verify(addr ethcommon.Address, msg, sig []byte) bool
 	return sv.verifyResult
 }
 
+func (sv *stubSigVerifier) VerifyTypedData(addr ethcommon.Address, typedData *TypedData, sig []byte) bool {
+	return sv.verifyResult
+}
+
 type stubBroker struct {
 	deposits        map[ethcommon.Address]*big.Int
 	reserves        map[ethcommon.Address]*big.Int
@@ -133,17 +215,32 @@ type stubBroker struct {
 	approvedSigners map[ethcommon.Address]bool
 	mu              sync.Mutex
 
-	redeemShouldFail           bool
-	getSenderInfoShouldFail    bool
-	claimableReserveShouldFail bool
+	// batches holds the number of tickets of each batch redemption
+	batches []int
+	// unredeemable holds the tickets that batch redemptions skip
+	unredeemable map[ethcommon.Hash]bool
+
+	// reserveAllocs holds the reserve allocs of senders, which are nil if unset
+	reserveAllocs     map[ethcommon.Address]*big.Int
+	reserveAllocCalls int
+	// reserveAllocWait blocks ReserveAlloc until it is closed, if not nil
+	reserveAllocWait chan struct{}
+
+	redeemShouldFail        bool
+	getSenderInfoShouldFail bool
+	reserveAllocShouldFail  bool
 
 	checkTxErr error
+	// checkTxWait blocks CheckTx until it is closed, if not nil
+	checkTxWait chan struct{}
 }
 
 func newStubBroker() *stubBroker {
 	return &stubBroker{
 		usedTickets:     make(map[ethcommon.Hash]bool),
 		approvedSigners: make(map[ethcommon.Address]bool),
+		unredeemable:    make(map[ethcommon.Hash]bool),
+		reserveAllocs:   make(map[ethcommon.Address]*big.Int),
 	}
 }
 
@@ -184,6 +281,26 @@ func (b *stubBroker) RedeemWinningTicket(ticket *Ticket, sig []byte, recipientRa
 	return &types.Transaction{}, nil
 }
 
+func (b *stubBroker) BatchRedeemWinningTickets(tickets []*Ticket, sigs [][]byte, recipientRands []*big.Int) (*types.Transaction, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.redeemShouldFail {
+		return nil, fmt.Errorf("stub broker redeem error")
+	}
+
+	b.batches = append(b.batches, len(tickets))
+	for _, ticket := range tickets {
+		// The broker skips the tickets that it can't redeem instead of failing the transaction
+		if b.unredeemable[ticket.Hash()] {
+			continue
+		}
+		b.usedTickets[ticket.Hash()] = true
+	}
+
+	return &types.Transaction{}, nil
+}
+
 func (b *stubBroker) IsUsedTicket(ticket *Ticket) (bool, error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -191,21 +308,33 @@ func (b *stubBroker) IsUsedTicket(ticket *Ticket) (bool, error) {
 	return b.usedTickets[ticket.Hash()], nil
 }
 
-func (b *stubBroker) ClaimableReserve(reserveHolder ethcommon.Address, claimant ethcommon.Address) (*big.Int, error) {
-	if b.claimableReserveShouldFail {
-		return nil, fmt.Errorf("stub broker ClaimableReserve error")
+func (b *stubBroker) ReserveAlloc(reserveHolder ethcommon.Address, claimant ethcommon.Address) (*big.Int, error) {
+	if b.reserveAllocWait != nil {
+		<-b.reserveAllocWait
 	}
 
-	return b.reserves[reserveHolder], nil
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.reserveAllocCalls++
+	if b.reserveAllocShouldFail {
+		return nil, fmt.Errorf("stub broker ReserveAlloc error")
+	}
+
+	return b.reserveAllocs[reserveHolder], nil
 }
 
 func (b *stubBroker) CheckTx(tx *types.Transaction) error {
+	if b.checkTxWait != nil {
+		<-b.checkTxWait
+	}
 	return b.checkTxErr
 }
 
 type stubValidator struct {
 	isValidTicket   bool
 	isWinningTicket bool
+	validateErr     error
 }
 
 func (v *stubValidator) SetIsValidTicket(isValidTicket bool) {
@@ -217,6 +346,9 @@ func (v *stubValidator) SetIsWinningTicket(isWinningTicket bool) {
 }
 
 func (v *stubValidator) ValidateTicket(recipient ethcommon.Address, ticket *Ticket, sig []byte, recipientRand *big.Int) error {
+	if v.validateErr != nil {
+		return v.validateErr
+	}
 	if !v.isValidTicket {
 		return fmt.Errorf("stub validator invalid ticket error")
 	}
@@ -253,6 +385,10 @@ func (s *stubSigner) Sign(msg []byte) ([]byte, error) {
 	return s.signResponse, nil
 }
 
+func (s *stubSigner) SignTypedData(typedData *TypedData) ([]byte, error) {
+	return s.Sign(typedData.Hash().Bytes())
+}
+
 func (s *stubSigner) Account() accounts.Account {
 	return s.account
 }
@@ -271,6 +407,9 @@ type stubTimeManager struct {
 }
 
 func (m *stubTimeManager) LastInitializedRound() *big.Int {
+	if m.round == nil {
+		return big.NewInt(0)
+	}
 	return m.round
 }
 
@@ -370,6 +509,8 @@ type stubSenderMonitor struct {
 	maxFloatErr       error
 	validateSenderErr error
 	shouldFail        error
+	removed           []*SignedTicket
+	removeShouldFail  error
 }
 
 func newStubSenderMonitor() *stubSenderMonitor {
@@ -391,10 +532,30 @@ func (s *stubSenderMonitor) QueueTicket(ticket *SignedTicket) error {
 	if s.shouldFail != nil {
 		return s.shouldFail
 	}
+	for _, t := range s.queued {
+		if bytes.Equal(t.Sig, ticket.Sig) {
+			return ErrTicketStored
+		}
+	}
 	s.queued = append(s.queued, ticket)
 	return nil
 }
 
+func (s *stubSenderMonitor) WinningTickets() ([]*SignedTicket, error) {
+	if s.shouldFail != nil {
+		return nil, s.shouldFail
+	}
+	return s.queued, nil
+}
+
+func (s *stubSenderMonitor) RemoveWinningTickets(tickets []*SignedTicket) error {
+	if s.removeShouldFail != nil {
+		return s.removeShouldFail
+	}
+	s.removed = append(s.removed, tickets...)
+	return nil
+}
+
 func (s *stubSenderMonitor) AddFloat(addr ethcommon.Address, amount *big.Int) error {
 	if s.addFloatErr != nil {
 		return s.addFloatErr
@@ -434,6 +595,11 @@ func (m *MockRecipient) ReceiveTicket(ticket *Ticket, sig []byte, seed *big.Int)
 	return args.String(0), args.Bool(1), args.Error(2)
 }
 
+// ReceivePayment validates and processes each of the tickets of a payment with ReceiveTicket
+func (m *MockRecipient) ReceivePayment(batch *TicketBatch, seed *big.Int) []*TicketResult {
+	return receivePayment(m, batch, seed)
+}
+
 // RedeemWinningTickets redeems all winning tickets with the broker
 // for a all sessionIDs
 func (m *MockRecipient) RedeemWinningTickets(sessionIDs []string) error {
@@ -471,11 +637,49 @@ func (m *MockRecipient) TxCostMultiplier(sender ethcommon.Address) (*big.Rat, er
 }
 
 // EV Returns the recipient's request ticket EV
-func (m *MockRecipient) EV() *big.Rat {
-	args := m.Called()
+func (m *MockRecipient) EV(price *big.Rat) *big.Rat {
+	args := m.Called(price)
 	return args.Get(0).(*big.Rat)
 }
 
+// ExportTickets returns the non-redeemed winning tickets of the recipient
+func (m *MockRecipient) ExportTickets() ([]*SignedTicket, error) {
+	args := m.Called()
+	var tickets []*SignedTicket
+	if args.Get(0) != nil {
+		tickets = args.Get(0).([]*SignedTicket)
+	}
+	return tickets, args.Error(1)
+}
+
+// RemoveExportedTickets removes the exported tickets that another node imported
+func (m *MockRecipient) RemoveExportedTickets(tickets []*SignedTicket) (int, error) {
+	args := m.Called(tickets)
+	return args.Int(0), args.Error(1)
+}
+
+// ImportTickets queues winning tickets exported by another recipient for redemption
+func (m *MockRecipient) ImportTickets(tickets []*SignedTicket) (int, error) {
+	args := m.Called(tickets)
+	return args.Int(0), args.Error(1)
+}
+
+// SessionPayments returns a snapshot of the payments received for a session
+func (m *MockRecipient) SessionPayments(sessionID string) *SessionPayments {
+	args := m.Called(sessionID)
+	var session *SessionPayments
+	if args.Get(0) != nil {
+		session = args.Get(0).(*SessionPayments)
+	}
+	return session
+}
+
+// ShouldRotateTicketParams returns true if the sender should replace its ticket params
+func (m *MockRecipient) ShouldRotateTicketParams(recipientRandHash ethcommon.Hash) bool {
+	args := m.Called(recipientRandHash)
+	return args.Bool(0)
+}
+
 // MockSender is useful for testing components that depend on pm.Sender
 type MockSender struct {
 	mock.Mock
@@ -517,3 +721,39 @@ func (m *MockSender) ValidateTicketParams(ticketParams *TicketParams) error {
 	args := m.Called(ticketParams)
 	return args.Error(0)
 }
+
+// SpentEV returns the total EV of the tickets sent to a recipient
+func (m *MockSender) SpentEV(recipient ethcommon.Address) *big.Rat {
+	args := m.Called(recipient)
+
+	var ev *big.Rat
+	if args.Get(0) != nil {
+		ev = args.Get(0).(*big.Rat)
+	}
+
+	return ev
+}
+
+// MaxEV returns the max total EV of a ticket batch
+func (m *MockSender) MaxEV() *big.Rat {
+	args := m.Called()
+
+	var ev *big.Rat
+	if args.Get(0) != nil {
+		ev = args.Get(0).(*big.Rat)
+	}
+
+	return ev
+}
+
+// PendingEV returns the total EV of the sent tickets that can still be redeemed
+func (m *MockSender) PendingEV() *big.Rat {
+	args := m.Called()
+
+	var ev *big.Rat
+	if args.Get(0) != nil {
+		ev = args.Get(0).(*big.Rat)
+	}
+
+	return ev
+}
@@ -5,6 +5,7 @@ import (
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
 )
 
 // Constants for byte sizes of Solidity types
@@ -14,6 +15,14 @@ const (
 	bytes32Size = 32
 )
 
+// ticketValidityPeriod is the number of rounds during which a ticket can be redeemed, starting
+// with its creation round, as set in the TicketBroker contract
+const ticketValidityPeriod = 2
+
+// PayoutShareDivisor is the divisor of the share of a PayoutSplit, which like the fee share
+// and reward cut of transcoders in the protocol is in parts per million
+const PayoutShareDivisor = 1000000
+
 // SignedTicket is a wrapper around a Ticket with the sender's signature over the ticket and
 // the recipient recipientRand
 type SignedTicket struct {
@@ -27,6 +36,9 @@ type SignedTicket struct {
 	// RecipientRand is the recipient's random value that should be
 	// the preimage for the ticket's recipientRandHash
 	RecipientRand *big.Int
+
+	// RedeemAttempts is the number of failed attempts to redeem the ticket
+	RedeemAttempts int
 }
 
 // TicketParams represents the parameters defined by a receiver that a sender must adhere to when
@@ -47,6 +59,13 @@ type TicketParams struct {
 	PricePerPixel *big.Rat
 
 	ExpirationParams *TicketExpirationParams
+
+	// PayoutSplit is the optional split of the winnings of tickets between the recipient
+	// and the operator of its transcoder pool
+	PayoutSplit *PayoutSplit
+
+	// Version is the version of the tickets, which selects how they are hashed and signed
+	Version uint32
 }
 
 // WinProbRat returns the ticket WinProb as a percentage represented as a big.Rat
@@ -54,6 +73,47 @@ func (p *TicketParams) WinProbRat() *big.Rat {
 	return winProbRat(p.WinProb)
 }
 
+// IsFree returns true if the params are for free tickets, which have a zero face value and
+// a zero win probability
+func (p *TicketParams) IsFree() bool {
+	return isFree(p.FaceValue, p.WinProb)
+}
+
+// PayoutSplit is a share of the winnings of tickets that the recipient pays out to the
+// operator of its transcoder pool once the tickets are redeemed
+type PayoutSplit struct {
+	// Recipient is the ETH address of the pool operator
+	Recipient ethcommon.Address
+
+	// Share is the share of the face value paid out to Recipient, in parts per million
+	Share int64
+}
+
+// Validate returns an error if the split doesn't pay a valid share to an address
+func (s *PayoutSplit) Validate() error {
+	if s.Recipient == (ethcommon.Address{}) {
+		return errors.New("payout split recipient is not set")
+	}
+	if s.Share <= 0 || s.Share > PayoutShareDivisor {
+		return errors.Errorf("payout split share %v is not between 0 and %v", s.Share, PayoutShareDivisor)
+	}
+	return nil
+}
+
+// Amount returns the amount paid out to the pool operator for a ticket with faceValue
+func (s *PayoutSplit) Amount(faceValue *big.Int) *big.Int {
+	amount := new(big.Int).Mul(faceValue, big.NewInt(s.Share))
+	return amount.Div(amount, big.NewInt(PayoutShareDivisor))
+}
+
+// Equal returns true if both splits pay the same share to the same address
+func (s *PayoutSplit) Equal(other *PayoutSplit) bool {
+	if s == nil || other == nil {
+		return s == other
+	}
+	return s.Recipient == other.Recipient && s.Share == other.Share
+}
+
 // TicketExpirationParams indicates when/how a ticket expires
 type TicketExpirationParams struct {
 	CreationRound int64
@@ -92,6 +152,8 @@ func (b *TicketBatch) Tickets() []*Ticket {
 			RecipientRandHash:      b.RecipientRandHash,
 			CreationRound:          b.CreationRound,
 			CreationRoundBlockHash: b.CreationRoundBlockHash,
+			PayoutSplit:            b.PayoutSplit,
+			Version:                b.Version,
 		}
 		tickets = append(tickets, ticket)
 	}
@@ -136,6 +198,14 @@ type Ticket struct {
 	ParamsExpirationBlock *big.Int
 
 	PricePerPixel *big.Rat
+
+	// PayoutSplit is the optional share of the face value that the recipient pays out to
+	// the operator of its transcoder pool. It is not part of the ticket hash, because the
+	// TicketBroker contract pays the whole face value to the recipient
+	PayoutSplit *PayoutSplit
+
+	// Version is the version of the ticket, which selects how it is hashed and signed
+	Version uint32
 }
 
 // NewTicket creates a Ticket instance
@@ -151,6 +221,8 @@ func NewTicket(params *TicketParams, expirationParams *TicketExpirationParams, s
 		CreationRoundBlockHash: expirationParams.CreationRoundBlockHash,
 		ParamsExpirationBlock:  params.ExpirationBlock,
 		PricePerPixel:          params.PricePerPixel,
+		PayoutSplit:            params.PayoutSplit,
+		Version:                params.Version,
 	}
 }
 
@@ -164,13 +236,28 @@ func (t *Ticket) WinProbRat() *big.Rat {
 	return winProbRat(t.WinProb)
 }
 
+// IsFree returns true if the ticket is free, i.e. it has a zero face value and a zero win
+// probability, so it never wins and can't draw from the funds of its sender
+func (t *Ticket) IsFree() bool {
+	return isFree(t.FaceValue, t.WinProb)
+}
+
 // Hash returns the keccak-256 hash of the ticket's fields as tightly packed
-// arguments as described in the Solidity documentation
+// arguments as described in the Solidity documentation, or the EIP-712 digest of the
+// ticket within TicketDomain for typed data tickets
 // See: https://solidity.readthedocs.io/en/v0.4.25/units-and-global-variables.html#mathematical-and-cryptographic-functions
 func (t *Ticket) Hash() ethcommon.Hash {
+	if t.Version == TicketVersionTypedData {
+		return t.TypedData().Hash()
+	}
 	return crypto.Keccak256Hash(t.flatten())
 }
 
+// TypedData returns the ticket as EIP-712 typed data of TicketDomain
+func (t *Ticket) TypedData() *TypedData {
+	return &TypedData{Domain: TicketDomain, Ticket: t}
+}
+
 // AuxData returns the ticket's CreationRound and CreationRoundBlockHash encoded into a byte array:
 // [0:31] = CreationRound (left padded with zero bytes)
 // [32..63] = CreationRoundBlockHash
@@ -189,6 +276,11 @@ func (t *Ticket) expirationParams() *TicketExpirationParams {
 	}
 }
 
+// expirationRound returns the first round in which the ticket can no longer be redeemed
+func (t *Ticket) expirationRound() int64 {
+	return t.CreationRound + ticketValidityPeriod
+}
+
 func (t *Ticket) flatten() []byte {
 	auxData := t.AuxData()
 
@@ -226,3 +318,7 @@ func ticketEV(faceValue *big.Int, winProb *big.Int) *big.Rat {
 func winProbRat(winProb *big.Int) *big.Rat {
 	return new(big.Rat).SetFrac(winProb, maxWinProb)
 }
+
+func isFree(faceValue *big.Int, winProb *big.Int) bool {
+	return (faceValue == nil || faceValue.Sign() == 0) && (winProb == nil || winProb.Sign() == 0)
+}
@@ -265,6 +265,8 @@ func TestTickets(t *testing.T) {
 		assert.Equal(batch.RecipientRandHash, ticket.RecipientRandHash)
 		assert.Equal(batch.CreationRound, ticket.CreationRound)
 		assert.Equal(batch.CreationRoundBlockHash, ticket.CreationRoundBlockHash)
+		assert.Equal(batch.PayoutSplit, ticket.PayoutSplit)
+		assert.Equal(batch.Version, ticket.Version)
 	}
 
 	// Test batch size = 1
@@ -275,6 +277,8 @@ func TestTickets(t *testing.T) {
 		WinProb:           randInt,
 		RecipientRandHash: RandHash(),
 		Seed:              randInt,
+		PayoutSplit:       &PayoutSplit{Recipient: RandAddress(), Share: 1000},
+		Version:           TicketVersionTypedData,
 	}
 	expirationParams := &TicketExpirationParams{
 		CreationRound:          10,
@@ -304,3 +308,26 @@ func TestTickets(t *testing.T) {
 		checkTicket(batch, i, tickets[i])
 	}
 }
+
+func TestPayoutSplit(t *testing.T) {
+	assert := assert.New(t)
+
+	split := &PayoutSplit{Recipient: RandAddress(), Share: 250000}
+	assert.Nil(split.Validate())
+	assert.Equal(big.NewInt(25), split.Amount(big.NewInt(100)))
+	// Amounts are rounded down
+	assert.Zero(split.Amount(big.NewInt(3)).Sign())
+
+	assert.True(split.Equal(&PayoutSplit{Recipient: split.Recipient, Share: 250000}))
+	assert.False(split.Equal(&PayoutSplit{Recipient: split.Recipient, Share: 250001}))
+	assert.False(split.Equal(&PayoutSplit{Recipient: RandAddress(), Share: 250000}))
+	assert.False(split.Equal(nil))
+	var nilSplit *PayoutSplit
+	assert.True(nilSplit.Equal(nil))
+	assert.False(nilSplit.Equal(split))
+
+	assert.EqualError((&PayoutSplit{Share: 1}).Validate(), "payout split recipient is not set")
+	assert.EqualError((&PayoutSplit{Recipient: RandAddress()}).Validate(), "payout split share 0 is not between 0 and 1000000")
+	assert.EqualError((&PayoutSplit{Recipient: RandAddress(), Share: PayoutShareDivisor + 1}).Validate(), "payout split share 1000001 is not between 0 and 1000000")
+	assert.Nil((&PayoutSplit{Recipient: RandAddress(), Share: PayoutShareDivisor}).Validate())
+}
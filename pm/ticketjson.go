@@ -0,0 +1,127 @@
+package pm
+
+import (
+	"encoding/json"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+// signedTicketJSON is the JSON encoding of a signed ticket, which has the fields stored for
+// winning tickets. Integers are encoded as decimal strings, which don't lose precision in
+// JavaScript
+type signedTicketJSON struct {
+	Recipient              ethcommon.Address `json:"recipient"`
+	Sender                 ethcommon.Address `json:"sender"`
+	FaceValue              string            `json:"faceValue"`
+	WinProb                string            `json:"winProb"`
+	SenderNonce            uint32            `json:"senderNonce"`
+	RecipientRandHash      ethcommon.Hash    `json:"recipientRandHash"`
+	CreationRound          int64             `json:"creationRound"`
+	CreationRoundBlockHash ethcommon.Hash    `json:"creationRoundBlockHash"`
+	ParamsExpirationBlock  string            `json:"paramsExpirationBlock"`
+	PayoutSplit            *payoutSplitJSON  `json:"payoutSplit,omitempty"`
+	Version                uint32            `json:"version"`
+	Sig                    hexutil.Bytes     `json:"sig"`
+	RecipientRand          string            `json:"recipientRand"`
+}
+
+type payoutSplitJSON struct {
+	Recipient ethcommon.Address `json:"recipient"`
+	Share     int64             `json:"share"`
+}
+
+// MarshalJSON encodes a signed ticket along with its signature and recipientRand, so that
+// winning tickets can be exported and redeemed by another node
+func (t *SignedTicket) MarshalJSON() ([]byte, error) {
+	if t.Ticket == nil {
+		return nil, errors.New("cannot marshal nil ticket")
+	}
+
+	var split *payoutSplitJSON
+	if t.PayoutSplit != nil {
+		split = &payoutSplitJSON{Recipient: t.PayoutSplit.Recipient, Share: t.PayoutSplit.Share}
+	}
+
+	return json.Marshal(&signedTicketJSON{
+		Recipient:              t.Recipient,
+		Sender:                 t.Sender,
+		FaceValue:              decimalString(t.FaceValue),
+		WinProb:                decimalString(t.WinProb),
+		SenderNonce:            t.SenderNonce,
+		RecipientRandHash:      t.RecipientRandHash,
+		CreationRound:          t.CreationRound,
+		CreationRoundBlockHash: t.CreationRoundBlockHash,
+		ParamsExpirationBlock:  decimalString(t.ParamsExpirationBlock),
+		PayoutSplit:            split,
+		Version:                t.Version,
+		Sig:                    t.Sig,
+		RecipientRand:          decimalString(t.RecipientRand),
+	})
+}
+
+// UnmarshalJSON decodes a signed ticket encoded by MarshalJSON
+func (t *SignedTicket) UnmarshalJSON(data []byte) error {
+	var tj signedTicketJSON
+	if err := json.Unmarshal(data, &tj); err != nil {
+		return err
+	}
+
+	faceValue, err := parseDecimal("faceValue", tj.FaceValue)
+	if err != nil {
+		return err
+	}
+	winProb, err := parseDecimal("winProb", tj.WinProb)
+	if err != nil {
+		return err
+	}
+	paramsExpirationBlock, err := parseDecimal("paramsExpirationBlock", tj.ParamsExpirationBlock)
+	if err != nil {
+		return err
+	}
+	recipientRand, err := parseDecimal("recipientRand", tj.RecipientRand)
+	if err != nil {
+		return err
+	}
+
+	var split *PayoutSplit
+	if tj.PayoutSplit != nil {
+		split = &PayoutSplit{Recipient: tj.PayoutSplit.Recipient, Share: tj.PayoutSplit.Share}
+	}
+
+	*t = SignedTicket{
+		Ticket: &Ticket{
+			Recipient:              tj.Recipient,
+			Sender:                 tj.Sender,
+			FaceValue:              faceValue,
+			WinProb:                winProb,
+			SenderNonce:            tj.SenderNonce,
+			RecipientRandHash:      tj.RecipientRandHash,
+			CreationRound:          tj.CreationRound,
+			CreationRoundBlockHash: tj.CreationRoundBlockHash,
+			ParamsExpirationBlock:  paramsExpirationBlock,
+			PayoutSplit:            split,
+			Version:                tj.Version,
+		},
+		Sig:           tj.Sig,
+		RecipientRand: recipientRand,
+	}
+	return nil
+}
+
+func decimalString(x *big.Int) string {
+	if x == nil {
+		return "0"
+	}
+	return x.String()
+}
+
+func parseDecimal(name, s string) (*big.Int, error) {
+	x, ok := new(big.Int).SetString(s, 10)
+	if !ok || x.Sign() < 0 {
+		return nil, errors.Errorf("invalid ticket %v %q", name, s)
+	}
+	return x, nil
+}
@@ -0,0 +1,64 @@
+package pm
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedTicket_JSON(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ticket := &SignedTicket{
+		Ticket: &Ticket{
+			Recipient:              RandAddress(),
+			Sender:                 RandAddress(),
+			FaceValue:              new(big.Int).SetBytes(RandBytes(32)),
+			WinProb:                new(big.Int).SetBytes(RandBytes(32)),
+			SenderNonce:            12,
+			RecipientRandHash:      RandHash(),
+			CreationRound:          3,
+			CreationRoundBlockHash: RandHash(),
+			ParamsExpirationBlock:  big.NewInt(100),
+			PayoutSplit:            &PayoutSplit{Recipient: RandAddress(), Share: 250000},
+			Version:                TicketVersionTypedData,
+		},
+		Sig:           RandBytes(65),
+		RecipientRand: new(big.Int).SetBytes(RandBytes(32)),
+	}
+
+	data, err := json.Marshal(ticket)
+	require.Nil(err)
+	// Integers are decimal strings
+	assert.Contains(string(data), `"faceValue":"`+ticket.FaceValue.String()+`"`)
+	assert.Contains(string(data), `"recipientRand":"`+ticket.RecipientRand.String()+`"`)
+
+	var res SignedTicket
+	require.Nil(json.Unmarshal(data, &res))
+	assert.Equal(ticket, &res)
+
+	// Tickets without a payout split round trip too
+	ticket.PayoutSplit = nil
+	data, err = json.Marshal([]*SignedTicket{ticket})
+	require.Nil(err)
+	assert.NotContains(string(data), "payoutSplit")
+	var tickets []*SignedTicket
+	require.Nil(json.Unmarshal(data, &tickets))
+	assert.Equal([]*SignedTicket{ticket}, tickets)
+
+	// Invalid integers are refused
+	invalid := strings.Replace(string(data), `"winProb":"`+ticket.WinProb.String()+`"`, `"winProb":"-1"`, 1)
+	err = json.Unmarshal([]byte(invalid), &tickets)
+	assert.EqualError(err, `invalid ticket winProb "-1"`)
+	invalid = strings.Replace(string(data), `"faceValue":"`+ticket.FaceValue.String()+`"`, `"faceValue":"0x10"`, 1)
+	err = json.Unmarshal([]byte(invalid), &tickets)
+	assert.EqualError(err, `invalid ticket faceValue "0x10"`)
+
+	_, err = json.Marshal(&SignedTicket{})
+	assert.Contains(err.Error(), "cannot marshal nil ticket")
+}
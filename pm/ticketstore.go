@@ -1,24 +1,55 @@
 package pm
 
-import ethcommon "github.com/ethereum/go-ethereum/common"
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// ErrTicketStored is returned when storing a winning ticket whose signature is already
+// stored, whether the stored ticket is redeemed or not
+var ErrTicketStored = errors.New("winning ticket already stored")
 
 // TicketStore is an interface which describes an object capable
 // of persisting tickets
 type TicketStore interface {
 	// SelectEarliestWinningTicket selects the earliest stored winning ticket for a 'sender'
-	// which is not yet redeemed
+	// which is not yet redeemed and not waiting to retry a failed redemption
 	SelectEarliestWinningTicket(sender ethcommon.Address) (*SignedTicket, error)
 
+	// SelectEarliestWinningTickets selects up to 'limit' of the earliest stored winning tickets
+	// for a 'sender' which are not yet redeemed, not waiting to retry a failed redemption and
+	// whose params expired at or before 'block', from the earliest to the latest
+	SelectEarliestWinningTickets(sender ethcommon.Address, block *big.Int, limit int) ([]*SignedTicket, error)
+
 	// RemoveWinningTicket removes a ticket
 	RemoveWinningTicket(ticket *SignedTicket) error
 
-	// StoreWinningTicket stores a signed ticket
+	// StoreWinningTicket stores a signed ticket, or returns ErrTicketStored if a ticket with
+	// the same signature is already stored
 	StoreWinningTicket(ticket *SignedTicket) error
 
 	// MarkWinningTicketRedeemed stores the on-chain transaction hash and timestamp of redemption
 	// This marks the ticket as being 'redeemed'
 	MarkWinningTicketRedeemed(ticket *SignedTicket, txHash ethcommon.Hash) error
 
+	// MarkWinningTicketFailed stores the number of failed redemption attempts of a ticket and
+	// the time before which its redemption is not retried
+	MarkWinningTicketFailed(ticket *SignedTicket, attempts int, retryAt time.Time) error
+
 	// WinningTicketCount returns the amount of non-redeemed winning tickets for a sender in the TicketStore
 	WinningTicketCount(sender ethcommon.Address) (int, error)
+
+	// WinningTickets returns the non-redeemed winning tickets for a sender in the TicketStore,
+	// from the earliest to the latest
+	WinningTickets(sender ethcommon.Address) ([]*SignedTicket, error)
+
+	// WinningTicketSenders returns the senders with non-redeemed winning tickets in the TicketStore
+	WinningTicketSenders() ([]ethcommon.Address, error)
+
+	// WinningTicketFaceValue returns the sum of the face values of the non-redeemed winning tickets for a sender
+	// in the TicketStore
+	WinningTicketFaceValue(sender ethcommon.Address) (*big.Int, error)
 }
@@ -0,0 +1,65 @@
+package pm
+
+import (
+	"errors"
+	"math/big"
+)
+
+// TicketParamsTuner computes the face value and the win probability of tickets from the price
+// per pixel of the recipient and the transaction cost of redeeming them, so that operators set
+// the share of the face value of tickets spent on redemptions instead of picking these numbers.
+// The face value of tickets is the transaction cost divided by the overhead, and their EV pays
+// for a fixed number of pixels at the price of the ticket params
+type TicketParamsTuner struct {
+	overhead        *big.Rat
+	pixelsPerTicket int64
+}
+
+// NewTicketParamsTuner creates a TicketParamsTuner for a target overhead in (0, 1], e.g. 1/100
+// for redemptions to cost 1% of the face value of tickets, and for tickets paying for
+// pixelsPerTicket pixels
+func NewTicketParamsTuner(overhead *big.Rat, pixelsPerTicket int64) (*TicketParamsTuner, error) {
+	if overhead == nil || overhead.Sign() <= 0 || overhead.Cmp(big.NewRat(1, 1)) > 0 {
+		return nil, errors.New("overhead must be in (0, 1]")
+	}
+	if pixelsPerTicket <= 0 {
+		return nil, errors.New("pixels per ticket must be positive")
+	}
+
+	return &TicketParamsTuner{
+		overhead:        new(big.Rat).Set(overhead),
+		pixelsPerTicket: pixelsPerTicket,
+	}, nil
+}
+
+// FaceValue returns the face value of tickets whose redemption costs txCost
+func (t *TicketParamsTuner) FaceValue(txCost *big.Int) *big.Int {
+	return ceilRat(new(big.Rat).Quo(new(big.Rat).SetInt(txCost), t.overhead))
+}
+
+// EV returns the EV of tickets paying for the pixels of a ticket at price
+func (t *TicketParamsTuner) EV(price *big.Rat) *big.Int {
+	return ceilRat(new(big.Rat).Mul(price, new(big.Rat).SetInt64(t.pixelsPerTicket)))
+}
+
+// Params returns the face value and the win probability of tickets created at price, whose
+// redemption costs txCost. The face value is raised to the EV of tickets if the EV is higher,
+// in which case tickets always win
+func (t *TicketParamsTuner) Params(price *big.Rat, txCost *big.Int) (*big.Int, *big.Int) {
+	ev := t.EV(price)
+	faceValue := t.FaceValue(txCost)
+	if faceValue.Cmp(ev) < 0 {
+		faceValue = ev
+	}
+
+	return faceValue, calcWinProb(faceValue, ev)
+}
+
+// ceilRat rounds x up to an integer
+func ceilRat(x *big.Rat) *big.Int {
+	q, m := new(big.Int).DivMod(x.Num(), x.Denom(), new(big.Int))
+	if m.Sign() != 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
@@ -0,0 +1,104 @@
+package pm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTicketParamsTuner(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewTicketParamsTuner(nil, 1)
+	assert.EqualError(err, "overhead must be in (0, 1]")
+	_, err = NewTicketParamsTuner(big.NewRat(0, 1), 1)
+	assert.EqualError(err, "overhead must be in (0, 1]")
+	_, err = NewTicketParamsTuner(big.NewRat(11, 10), 1)
+	assert.EqualError(err, "overhead must be in (0, 1]")
+	_, err = NewTicketParamsTuner(big.NewRat(1, 100), 0)
+	assert.EqualError(err, "pixels per ticket must be positive")
+
+	_, err = NewTicketParamsTuner(big.NewRat(1, 1), 1)
+	assert.Nil(err)
+}
+
+func TestTicketParamsTuner_Params(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	tuner, err := NewTicketParamsTuner(big.NewRat(1, 100), 1000)
+	require.Nil(err)
+
+	// Test faceValue = txCost / overhead, EV = price * pixelsPerTicket
+	faceValue, winProb := tuner.Params(big.NewRat(3, 2), big.NewInt(500))
+	assert.Equal(big.NewInt(50000), faceValue)
+	assert.Equal(calcWinProb(big.NewInt(50000), big.NewInt(1500)), winProb)
+	assert.Equal(big.NewInt(1500), tuner.EV(big.NewRat(3, 2)))
+
+	// Test the params are deterministic
+	faceValue2, winProb2 := tuner.Params(big.NewRat(3, 2), big.NewInt(500))
+	assert.Equal(faceValue, faceValue2)
+	assert.Equal(winProb, winProb2)
+
+	// Test values are rounded up
+	assert.Equal(big.NewInt(334), tuner.EV(big.NewRat(1, 3)))
+	tuner, err = NewTicketParamsTuner(big.NewRat(3, 100), 1000)
+	require.Nil(err)
+	assert.Equal(big.NewInt(3334), tuner.FaceValue(big.NewInt(100)))
+
+	// Test faceValue is raised to EV, in which case tickets always win
+	faceValue, winProb = tuner.Params(big.NewRat(10, 1), big.NewInt(1))
+	assert.Equal(big.NewInt(10000), faceValue)
+	assert.Equal(maxWinProb, winProb)
+}
+
+func TestTicketParams_Tuner(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	sender, b, v, gm, sm, tm, cfg, _ := newRecipientFixtureOrFatal(t)
+	tuner, err := NewTicketParamsTuner(big.NewRat(1, 50), 1000)
+	require.Nil(err)
+	cfg.Tuner = tuner
+	r := newRecipientOrFatal(t, RandAddress(), b, v, gm, sm, tm, cfg)
+
+	// txCost = redeemGas * gasPrice = 10000 * 100
+	params, err := r.TicketParams(sender, big.NewRat(2, 1))
+	require.Nil(err)
+	assert.Equal(big.NewInt(50000000), params.FaceValue)
+	assert.Equal(calcWinProb(params.FaceValue, big.NewInt(2000)), params.WinProb)
+
+	// Test the EV follows the price
+	params, err = r.TicketParams(sender, big.NewRat(4, 1))
+	require.Nil(err)
+	assert.Equal(big.NewInt(50000000), params.FaceValue)
+	assert.Equal(calcWinProb(params.FaceValue, big.NewInt(4000)), params.WinProb)
+
+	// Test the required EV is the tuned EV, rather than the configured EV
+	assert.Equal(big.NewRat(4000, 1), r.EV(big.NewRat(4, 1)))
+	assert.NotEqual(0, new(big.Rat).SetInt(cfg.EV).Cmp(r.EV(big.NewRat(4, 1))))
+
+	// Test the faceValue follows the gas price
+	gm.gasPrice = big.NewInt(200)
+	params, err = r.TicketParams(sender, big.NewRat(4, 1))
+	require.Nil(err)
+	assert.Equal(big.NewInt(100000000), params.FaceValue)
+
+	txCostMultiplier, err := r.TxCostMultiplier(sender)
+	require.Nil(err)
+	assert.Equal(big.NewRat(50, 1), txCostMultiplier)
+
+	// Test faceValue is capped by the max float of the sender
+	sm.maxFloat = big.NewInt(10000)
+	params, err = r.TicketParams(sender, big.NewRat(4, 1))
+	require.Nil(err)
+	assert.Equal(big.NewInt(10000), params.FaceValue)
+	assert.Equal(calcWinProb(big.NewInt(10000), big.NewInt(4000)), params.WinProb)
+
+	// Test max float < EV
+	sm.maxFloat = big.NewInt(3999)
+	_, err = r.TicketParams(sender, big.NewRat(4, 1))
+	assert.Equal(ErrInsufficientSenderReserve, err)
+}
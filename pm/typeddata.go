@@ -0,0 +1,155 @@
+package pm
+
+import (
+	"encoding/json"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// Ticket versions select how tickets are hashed and signed. Recipients advertise the
+// version of the tickets that they accept in their ticket params
+const (
+	// TicketVersionLegacy tickets are hashed by tightly packing their fields and signed as
+	// personal messages, which is what the TicketBroker contract verifies
+	TicketVersionLegacy uint32 = 0
+	// TicketVersionTypedData tickets are hashed and signed as EIP-712 typed data, so that
+	// wallets and hardware signers can display the contents of the tickets they sign
+	TicketVersionTypedData uint32 = 1
+)
+
+var errUnsupportedTicketVersion = errors.New("unsupported ticket version")
+
+// EIP-712 types of the domain and of tickets, which have the fields of the Ticket struct of
+// the TicketBroker contract
+// See: https://eips.ethereum.org/EIPS/eip-712
+const (
+	domainType = "EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"
+	ticketType = "Ticket(address recipient,address sender,uint256 faceValue,uint256 winProb,uint256 senderNonce,bytes32 recipientRandHash,bytes auxData)"
+)
+
+var (
+	domainTypeHash = crypto.Keccak256Hash([]byte(domainType))
+	ticketTypeHash = crypto.Keccak256Hash([]byte(ticketType))
+)
+
+// TypedDataDomain is the EIP-712 domain of typed data tickets, which binds their signatures
+// to a chain and to the TicketBroker contract redeeming them
+type TypedDataDomain struct {
+	Name              string
+	Version           string
+	ChainID           *big.Int
+	VerifyingContract ethcommon.Address
+}
+
+// TicketDomain is the domain of the typed data tickets sent and received by the node. Its
+// chain ID and verifying contract are set on startup
+var TicketDomain = &TypedDataDomain{
+	Name:    "Livepeer TicketBroker",
+	Version: "1",
+	ChainID: big.NewInt(0),
+}
+
+// Separator returns the EIP-712 domain separator of the domain
+func (d *TypedDataDomain) Separator() ethcommon.Hash {
+	return crypto.Keccak256Hash(
+		domainTypeHash.Bytes(),
+		crypto.Keccak256([]byte(d.Name)),
+		crypto.Keccak256([]byte(d.Version)),
+		ethcommon.LeftPadBytes(d.ChainID.Bytes(), uint256Size),
+		ethcommon.LeftPadBytes(d.VerifyingContract.Bytes(), uint256Size),
+	)
+}
+
+// TypedData is a ticket as EIP-712 typed data of a domain
+type TypedData struct {
+	Domain *TypedDataDomain
+	Ticket *Ticket
+}
+
+// Hash returns the EIP-712 digest of the typed data, which is what signers sign
+func (td *TypedData) Hash() ethcommon.Hash {
+	return crypto.Keccak256Hash([]byte{0x19, 0x01}, td.Domain.Separator().Bytes(), td.structHash().Bytes())
+}
+
+func (td *TypedData) structHash() ethcommon.Hash {
+	t := td.Ticket
+	return crypto.Keccak256Hash(
+		ticketTypeHash.Bytes(),
+		ethcommon.LeftPadBytes(t.Recipient.Bytes(), uint256Size),
+		ethcommon.LeftPadBytes(t.Sender.Bytes(), uint256Size),
+		ethcommon.LeftPadBytes(t.FaceValue.Bytes(), uint256Size),
+		ethcommon.LeftPadBytes(t.WinProb.Bytes(), uint256Size),
+		ethcommon.LeftPadBytes(new(big.Int).SetUint64(uint64(t.SenderNonce)).Bytes(), uint256Size),
+		t.RecipientRandHash.Bytes(),
+		crypto.Keccak256(t.AuxData()),
+	)
+}
+
+type typedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// MarshalJSON encodes the typed data as the payload of eth_signTypedData_v4 requests to
+// wallets. Integers are encoded as decimal strings, which don't lose precision in JavaScript
+func (td *TypedData) MarshalJSON() ([]byte, error) {
+	t := td.Ticket
+	return json.Marshal(map[string]interface{}{
+		"types": map[string][]typedDataField{
+			"EIP712Domain": {
+				{"name", "string"},
+				{"version", "string"},
+				{"chainId", "uint256"},
+				{"verifyingContract", "address"},
+			},
+			"Ticket": {
+				{"recipient", "address"},
+				{"sender", "address"},
+				{"faceValue", "uint256"},
+				{"winProb", "uint256"},
+				{"senderNonce", "uint256"},
+				{"recipientRandHash", "bytes32"},
+				{"auxData", "bytes"},
+			},
+		},
+		"primaryType": "Ticket",
+		"domain": map[string]string{
+			"name":              td.Domain.Name,
+			"version":           td.Domain.Version,
+			"chainId":           td.Domain.ChainID.String(),
+			"verifyingContract": td.Domain.VerifyingContract.Hex(),
+		},
+		"message": map[string]string{
+			"recipient":         t.Recipient.Hex(),
+			"sender":            t.Sender.Hex(),
+			"faceValue":         t.FaceValue.String(),
+			"winProb":           t.WinProb.String(),
+			"senderNonce":       new(big.Int).SetUint64(uint64(t.SenderNonce)).String(),
+			"recipientRandHash": t.RecipientRandHash.Hex(),
+			"auxData":           "0x" + ethcommon.Bytes2Hex(t.AuxData()),
+		},
+	})
+}
+
+// signTicket signs a ticket as its version requires
+func signTicket(signer Signer, ticket *Ticket) ([]byte, error) {
+	if ticket.Version == TicketVersionTypedData {
+		return signer.SignTypedData(ticket.TypedData())
+	}
+	return signer.Sign(ticket.Hash().Bytes())
+}
+
+// verifyTicketSig checks the signature of a ticket as its version requires
+func verifyTicketSig(sv SigVerifier, ticket *Ticket, sig []byte) bool {
+	if ticket.Version == TicketVersionTypedData {
+		return sv.VerifyTypedData(ticket.Sender, ticket.TypedData(), sig)
+	}
+	return sv.Verify(ticket.Sender, ticket.Hash().Bytes(), sig)
+}
+
+func validTicketVersion(version uint32) bool {
+	return version == TicketVersionLegacy || version == TicketVersionTypedData
+}
@@ -0,0 +1,198 @@
+package pm
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/livepeer/go-livepeer/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func typedDataTicket() *Ticket {
+	return &Ticket{
+		Recipient:              RandAddress(),
+		Sender:                 RandAddress(),
+		FaceValue:              new(big.Int).SetBytes(RandBytes(32)),
+		WinProb:                new(big.Int).SetBytes(RandBytes(32)),
+		SenderNonce:            1234,
+		RecipientRandHash:      RandHash(),
+		CreationRound:          10,
+		CreationRoundBlockHash: RandHash(),
+		Version:                TicketVersionTypedData,
+	}
+}
+
+// abiEncode packs values as Solidity's abi.encode does
+func abiEncode(t *testing.T, types []string, values ...interface{}) []byte {
+	var args abi.Arguments
+	for _, typ := range types {
+		abiType, err := abi.NewType(typ, nil)
+		require.Nil(t, err)
+		args = append(args, abi.Argument{Type: abiType})
+	}
+	b, err := args.Pack(values...)
+	require.Nil(t, err)
+	return b
+}
+
+func TestTypedData_Hash(t *testing.T) {
+	assert := assert.New(t)
+
+	domain := &TypedDataDomain{
+		Name:              "Livepeer TicketBroker",
+		Version:           "1",
+		ChainID:           big.NewInt(4),
+		VerifyingContract: RandAddress(),
+	}
+	ticket := typedDataTicket()
+	td := &TypedData{Domain: domain, Ticket: ticket}
+
+	// The digest is the one that a contract computes with abi.encode
+	domainSeparator := ethcrypto.Keccak256(abiEncode(t,
+		[]string{"bytes32", "bytes32", "bytes32", "uint256", "address"},
+		ethcrypto.Keccak256Hash([]byte(domainType)),
+		ethcrypto.Keccak256Hash([]byte(domain.Name)),
+		ethcrypto.Keccak256Hash([]byte(domain.Version)),
+		domain.ChainID,
+		domain.VerifyingContract,
+	))
+	structHash := ethcrypto.Keccak256(abiEncode(t,
+		[]string{"bytes32", "address", "address", "uint256", "uint256", "uint256", "bytes32", "bytes32"},
+		ethcrypto.Keccak256Hash([]byte(ticketType)),
+		ticket.Recipient,
+		ticket.Sender,
+		ticket.FaceValue,
+		ticket.WinProb,
+		big.NewInt(int64(ticket.SenderNonce)),
+		ticket.RecipientRandHash,
+		ethcrypto.Keccak256Hash(ticket.AuxData()),
+	))
+	assert.Equal(ethcommon.BytesToHash(domainSeparator), domain.Separator())
+	assert.Equal(ethcrypto.Keccak256Hash([]byte{0x19, 0x01}, domainSeparator, structHash), td.Hash())
+
+	// The digest is bound to the domain
+	otherDomain := *domain
+	otherDomain.ChainID = big.NewInt(1)
+	assert.NotEqual(td.Hash(), (&TypedData{Domain: &otherDomain, Ticket: ticket}).Hash())
+}
+
+func TestTypedData_MarshalJSON(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	domain := &TypedDataDomain{
+		Name:              "Livepeer TicketBroker",
+		Version:           "1",
+		ChainID:           big.NewInt(4),
+		VerifyingContract: RandAddress(),
+	}
+	ticket := typedDataTicket()
+	b, err := json.Marshal(&TypedData{Domain: domain, Ticket: ticket})
+	require.Nil(err)
+
+	var payload struct {
+		Types       map[string][]typedDataField
+		PrimaryType string
+		Domain      map[string]string
+		Message     map[string]string
+	}
+	require.Nil(json.Unmarshal(b, &payload))
+	assert.Equal("Ticket", payload.PrimaryType)
+	assert.Len(payload.Types["EIP712Domain"], 4)
+	assert.Len(payload.Types["Ticket"], 7)
+	assert.Equal(map[string]string{
+		"name":              "Livepeer TicketBroker",
+		"version":           "1",
+		"chainId":           "4",
+		"verifyingContract": domain.VerifyingContract.Hex(),
+	}, payload.Domain)
+	assert.Equal(map[string]string{
+		"recipient":         ticket.Recipient.Hex(),
+		"sender":            ticket.Sender.Hex(),
+		"faceValue":         ticket.FaceValue.String(),
+		"winProb":           ticket.WinProb.String(),
+		"senderNonce":       "1234",
+		"recipientRandHash": ticket.RecipientRandHash.Hex(),
+		"auxData":           "0x" + ethcommon.Bytes2Hex(ticket.AuxData()),
+	}, payload.Message)
+
+	// The types are the ones that the hash is computed over
+	encodeType := func(name string) string {
+		typ := name + "("
+		for i, f := range payload.Types[name] {
+			if i > 0 {
+				typ += ","
+			}
+			typ += f.Type + " " + f.Name
+		}
+		return typ + ")"
+	}
+	assert.Equal(domainType, encodeType("EIP712Domain"))
+	assert.Equal(ticketType, encodeType("Ticket"))
+}
+
+func TestTicketHash_Version(t *testing.T) {
+	assert := assert.New(t)
+
+	ticket := typedDataTicket()
+	assert.Equal(ticket.TypedData().Hash(), ticket.Hash())
+	assert.Equal(TicketDomain, ticket.TypedData().Domain)
+
+	ticket.Version = TicketVersionLegacy
+	assert.Equal(ethcrypto.Keccak256Hash(ticket.flatten()), ticket.Hash())
+}
+
+func TestSignTicket(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	key, err := ethcrypto.GenerateKey()
+	require.Nil(err)
+	addr := ethcrypto.PubkeyToAddress(key.PublicKey)
+	sign := func(hash []byte) []byte {
+		sig, err := ethcrypto.Sign(hash, key)
+		require.Nil(err)
+		sig[64] += 27
+		return sig
+	}
+	sv := &DefaultSigVerifier{}
+
+	// Typed data tickets are signed over their digest as is
+	ticket := typedDataTicket()
+	ticket.Sender = addr
+	signer := &stubSigner{saveSignRequest: true}
+	_, err = signTicket(signer, ticket)
+	require.Nil(err)
+	assert.Equal([][]byte{ticket.TypedData().Hash().Bytes()}, signer.signRequests)
+
+	sig := sign(ticket.Hash().Bytes())
+	assert.True(verifyTicketSig(sv, ticket, sig))
+	assert.True(crypto.VerifyHashSig(addr, ticket.Hash().Bytes(), sig))
+
+	// Legacy tickets are signed as personal messages
+	ticket.Version = TicketVersionLegacy
+	assert.False(verifyTicketSig(sv, ticket, sig))
+	signer.signRequests = nil
+	_, err = signTicket(signer, ticket)
+	require.Nil(err)
+	assert.Equal([][]byte{ticket.Hash().Bytes()}, signer.signRequests)
+
+	personalSig := sign(ethcommon.BytesToHash(ethcrypto.Keccak256([]byte("\x19Ethereum Signed Message:\n32"), ticket.Hash().Bytes())).Bytes())
+	assert.True(verifyTicketSig(sv, ticket, personalSig))
+
+	// Signatures of one version are invalid for the other
+	ticket.Version = TicketVersionTypedData
+	assert.False(verifyTicketSig(sv, ticket, personalSig))
+}
+
+func TestValidTicketVersion(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(validTicketVersion(TicketVersionLegacy))
+	assert.True(validTicketVersion(TicketVersionTypedData))
+	assert.False(validTicketVersion(2))
+}
@@ -0,0 +1,137 @@
+package pm
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/golang/glog"
+)
+
+// SenderEventSource notifies subscribers of the TicketBroker events of senders
+type SenderEventSource interface {
+	SubscribeSenderEvents(sink chan<- *SenderEvent) event.Subscription
+}
+
+// UnlockWatcher freezes the senders that unlock their deposit and reserve: their tickets are
+// refused as soon as the Unlock is seen, rather than in the last round of the unlock period,
+// since the sender can withdraw its funds before winning tickets are redeemed. A sender is
+// unfrozen once it cancels the unlock or withdraws its funds. A nil UnlockWatcher doesn't
+// freeze anything
+type UnlockWatcher struct {
+	events SenderEventSource
+	smgr   SenderManager
+
+	mu sync.RWMutex
+	// frozen are the end rounds of the unlock periods of the frozen senders
+	frozen map[ethcommon.Address]*big.Int
+
+	quit chan struct{}
+}
+
+// NewUnlockWatcher creates an UnlockWatcher for the sender events of events. The sender
+// infos of smgr are checked for unlocks that happened before the watcher started
+func NewUnlockWatcher(events SenderEventSource, smgr SenderManager) *UnlockWatcher {
+	return &UnlockWatcher{
+		events: events,
+		smgr:   smgr,
+		frozen: make(map[ethcommon.Address]*big.Int),
+		quit:   make(chan struct{}),
+	}
+}
+
+// Watch freezes and unfreezes senders as their Unlock, UnlockCancelled and Withdrawal events
+// are received
+func (w *UnlockWatcher) Watch() {
+	events := make(chan *SenderEvent, 10)
+	sub := w.events.SubscribeSenderEvents(events)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-w.quit:
+			return
+		case err := <-sub.Err():
+			if err != nil {
+				glog.Errorf("Sender event subscription error err=%v", err)
+			}
+		case ev := <-events:
+			w.handleSenderEvent(ev)
+		}
+	}
+}
+
+// Stop signals the watcher loop to exit gracefully
+func (w *UnlockWatcher) Stop() {
+	close(w.quit)
+}
+
+func (w *UnlockWatcher) handleSenderEvent(ev *SenderEvent) {
+	switch ev.Type {
+	case SenderEventUnlock:
+		// A removed Unlock was undone by a reorg
+		if ev.Removed {
+			w.unfreeze(ev.Sender, "the unlock was removed by a reorg")
+			return
+		}
+		w.freeze(ev.Sender, ev.EndRound)
+	case SenderEventUnlockCancelled:
+		if !ev.Removed {
+			w.unfreeze(ev.Sender, "the unlock was cancelled")
+		}
+	case SenderEventWithdrawal:
+		if !ev.Removed {
+			w.unfreeze(ev.Sender, "the deposit and reserve were withdrawn")
+		}
+	}
+}
+
+func (w *UnlockWatcher) freeze(sender ethcommon.Address, endRound *big.Int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.frozen[sender]; ok {
+		return
+	}
+	w.frozen[sender] = endRound
+	glog.Warningf("Froze sender=%v which unlocked its deposit and reserve until endRound=%v, its tickets are refused", sender.Hex(), endRound)
+}
+
+func (w *UnlockWatcher) unfreeze(sender ethcommon.Address, reason string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.frozen[sender]; !ok {
+		return
+	}
+	delete(w.frozen, sender)
+	glog.Infof("Unfroze sender=%v since %v", sender.Hex(), reason)
+}
+
+// ValidateSender returns an error if the sender is frozen, i.e. if it unlocked its deposit
+// and reserve
+func (w *UnlockWatcher) ValidateSender(sender ethcommon.Address) error {
+	if w == nil {
+		return nil
+	}
+
+	w.mu.RLock()
+	endRound, ok := w.frozen[sender]
+	w.mu.RUnlock()
+	if !ok {
+		// The unlock may predate the watcher
+		info, err := w.smgr.GetSenderInfo(sender)
+		if err != nil {
+			return fmt.Errorf("could not get sender info for %v: %v", sender.Hex(), err)
+		}
+		if info == nil || info.WithdrawRound == nil || info.WithdrawRound.Sign() == 0 {
+			return nil
+		}
+		endRound = info.WithdrawRound
+		w.freeze(sender, endRound)
+	}
+
+	return fmt.Errorf("sender %v unlocked its deposit and reserve until round %v", sender.Hex(), endRound)
+}
@@ -0,0 +1,81 @@
+package pm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubSenderEventSource struct {
+	feed event.Feed
+}
+
+func (s *stubSenderEventSource) SubscribeSenderEvents(sink chan<- *SenderEvent) event.Subscription {
+	return s.feed.Subscribe(sink)
+}
+
+func TestUnlockWatcher_ValidateSender(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := RandAddress()
+	smgr := newStubSenderManager()
+	smgr.info[sender] = &SenderInfo{WithdrawRound: big.NewInt(0)}
+	w := NewUnlockWatcher(&stubSenderEventSource{}, smgr)
+
+	assert.Nil(w.ValidateSender(sender))
+
+	// Test senders are frozen by their Unlock
+	w.handleSenderEvent(&SenderEvent{Type: SenderEventUnlock, Sender: sender, EndRound: big.NewInt(10)})
+	assert.EqualError(w.ValidateSender(sender), fmt.Sprintf("sender %v unlocked its deposit and reserve until round 10", sender.Hex()))
+	assert.Nil(w.ValidateSender(RandAddress()))
+
+	// Test senders are unfrozen when the unlock is cancelled
+	w.handleSenderEvent(&SenderEvent{Type: SenderEventUnlockCancelled, Sender: sender})
+	assert.Nil(w.ValidateSender(sender))
+
+	// Test senders are unfrozen when the Unlock is removed by a reorg
+	w.handleSenderEvent(&SenderEvent{Type: SenderEventUnlock, Sender: sender, EndRound: big.NewInt(10)})
+	w.handleSenderEvent(&SenderEvent{Type: SenderEventUnlock, Sender: sender, EndRound: big.NewInt(10), Removed: true})
+	assert.Nil(w.ValidateSender(sender))
+
+	// Test senders are unfrozen when they withdraw
+	w.handleSenderEvent(&SenderEvent{Type: SenderEventUnlock, Sender: sender, EndRound: big.NewInt(10)})
+	w.handleSenderEvent(&SenderEvent{Type: SenderEventWithdrawal, Sender: sender})
+	assert.Nil(w.ValidateSender(sender))
+
+	// Test unlocks that predate the watcher freeze senders
+	smgr.info[sender].WithdrawRound = big.NewInt(12)
+	assert.EqualError(w.ValidateSender(sender), fmt.Sprintf("sender %v unlocked its deposit and reserve until round 12", sender.Hex()))
+	// Test frozen senders stay frozen until an event unfreezes them
+	smgr.info[sender].WithdrawRound = big.NewInt(0)
+	assert.NotNil(w.ValidateSender(sender))
+
+	smgr.err = errors.New("GetSenderInfo error")
+	assert.Contains(w.ValidateSender(RandAddress()).Error(), "GetSenderInfo error")
+
+	// Test a nil watcher doesn't freeze anything
+	var nilWatcher *UnlockWatcher
+	assert.Nil(nilWatcher.ValidateSender(sender))
+}
+
+func TestUnlockWatcher_Watch(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := RandAddress()
+	smgr := newStubSenderManager()
+	smgr.info[sender] = &SenderInfo{WithdrawRound: big.NewInt(0)}
+	events := &stubSenderEventSource{}
+	w := NewUnlockWatcher(events, smgr)
+	go w.Watch()
+	defer w.Stop()
+
+	time.Sleep(20 * time.Millisecond)
+	events.feed.Send(&SenderEvent{Type: SenderEventUnlock, Sender: sender, EndRound: big.NewInt(10)})
+	time.Sleep(20 * time.Millisecond)
+	assert.NotNil(w.ValidateSender(sender))
+}
@@ -15,6 +15,7 @@ var (
 	errInvalidTicketSignature        = errors.New("invalid ticket signature")
 	errInvalidCreationRound          = errors.New("invalid ticket creation round")
 	errInvalidCreationRoundBlockHash = errors.New("invalid ticket creation round block hash")
+	errTicketExpired                 = errors.New("ticket expired")
 )
 
 // Validator is an interface which describes an object capable
@@ -56,7 +57,20 @@ func (v *validator) ValidateTicket(recipient ethcommon.Address, ticket *Ticket,
 		return errInvalidTicketRecipientRand
 	}
 
-	if !v.sigVerifier.Verify(ticket.Sender, ticket.Hash().Bytes(), sig) {
+	// The broker doesn't redeem tickets after their validity period
+	round := v.tm.LastInitializedRound().Int64()
+	if ticket.CreationRound > round {
+		return errInvalidCreationRound
+	}
+	if ticket.expirationRound() <= round {
+		return errTicketExpired
+	}
+
+	if !validTicketVersion(ticket.Version) {
+		return errUnsupportedTicketVersion
+	}
+
+	if !verifyTicketSig(v.sigVerifier, ticket, sig) {
 		return errInvalidTicketSignature
 	}
 
@@ -122,6 +122,63 @@ func TestValidateTicket(t *testing.T) {
 	if err := v.ValidateTicket(recipient, ticket, sig, recipientRand); err != nil {
 		t.Errorf("expected valid ticket, got error %v", err)
 	}
+
+	// Test valid typed data ticket
+	ticket.Version = TicketVersionTypedData
+	if err := v.ValidateTicket(recipient, ticket, sig, recipientRand); err != nil {
+		t.Errorf("expected valid ticket, got error %v", err)
+	}
+
+	// Test unsupported version
+	ticket.Version = TicketVersionTypedData + 1
+	err = v.ValidateTicket(recipient, ticket, sig, recipientRand)
+	if err != errUnsupportedTicketVersion {
+		t.Errorf("expected unsupported ticket version error, got %v", err)
+	}
+}
+
+func TestValidateTicket_CreationRound(t *testing.T) {
+	recipient := ethcommon.HexToAddress("73AEd7b5dEb30222fa896f399d46cC99c7BEe57F")
+	sender := ethcommon.HexToAddress("A69cdA26600c155cF2c150964Bdb5371ac3f606F")
+	sig := []byte("foo")
+	recipientRand := big.NewInt(10)
+	recipientRandHash := crypto.Keccak256Hash(ethcommon.LeftPadBytes(recipientRand.Bytes(), uint256Size))
+
+	sv := &stubSigVerifier{}
+	sv.SetVerifyResult(true)
+
+	tm := &stubTimeManager{round: big.NewInt(10)}
+
+	v := NewValidator(sv, tm)
+
+	ticket := &Ticket{
+		Recipient:         recipient,
+		Sender:            sender,
+		FaceValue:         big.NewInt(0),
+		WinProb:           big.NewInt(0),
+		SenderNonce:       0,
+		RecipientRandHash: recipientRandHash,
+	}
+
+	// Test tickets created in the current round and the previous round
+	for _, round := range []int64{10, 9} {
+		ticket.CreationRound = round
+		if err := v.ValidateTicket(recipient, ticket, sig, recipientRand); err != nil {
+			t.Errorf("expected valid ticket for creation round %v, got error %v", round, err)
+		}
+	}
+
+	// Test expired ticket
+	ticket.CreationRound = 8
+	if err := v.ValidateTicket(recipient, ticket, sig, recipientRand); err != errTicketExpired {
+		t.Errorf("expected ticket expired error, got %v", err)
+	}
+
+	// Test creation round after the last initialized round
+	ticket.CreationRound = 11
+	if err := v.ValidateTicket(recipient, ticket, sig, recipientRand); err != errInvalidCreationRound {
+		t.Errorf("expected invalid creation round error, got %v", err)
+	}
 }
 
 func TestIsWinningTicket(t *testing.T) {
@@ -0,0 +1,149 @@
+// Package sdnotify implements the systemd service notification protocol, used to report
+// the readiness of a node and to feed the systemd watchdog
+package sdnotify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// States sent to the service manager
+const (
+	StateReady    = "READY=1"
+	StateStopping = "STOPPING=1"
+	StateWatchdog = "WATCHDOG=1"
+)
+
+// Status returns the state that sets the status of the service shown by systemctl
+func Status(status string) string {
+	return "STATUS=" + status
+}
+
+// Notify sends states to the service manager. It does nothing if the process is not
+// supervised by systemd with Type=notify
+func Notify(states ...string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	// Names starting with @ are in the abstract namespace
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(strings.Join(states, "\n")))
+	return err
+}
+
+// WatchdogTimeout returns the timeout of the systemd watchdog of the process, or 0 if the
+// watchdog is not enabled
+func WatchdogTimeout() (time.Duration, error) {
+	usec := os.Getenv("WATCHDOG_USEC")
+	if usec == "" {
+		return 0, nil
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid WATCHDOG_USEC %q", usec)
+	}
+	return time.Duration(n) * time.Microsecond, nil
+}
+
+// Watchdog feeds the systemd watchdog as long as the loops registered with it are alive,
+// so that systemd restarts a node whose service loops are hung. A nil Watchdog does nothing
+type Watchdog struct {
+	timeout time.Duration
+	notify  func(...string) error
+
+	mu    sync.Mutex
+	loops map[string]*loop
+}
+
+type loop struct {
+	period time.Duration
+	// last is the time of the last iteration, zero until the first one
+	last time.Time
+}
+
+// NewWatchdog creates a Watchdog for a systemd watchdog with the given timeout
+func NewWatchdog(timeout time.Duration) *Watchdog {
+	return &Watchdog{timeout: timeout, notify: Notify, loops: make(map[string]*loop)}
+}
+
+// Timeout returns the timeout of the systemd watchdog
+func (w *Watchdog) Timeout() time.Duration {
+	return w.timeout
+}
+
+// Register registers a loop that iterates at least every period and returns the function
+// that the loop calls on every iteration. From its first iteration on, the loop is
+// considered hung if it does not iterate for twice its period
+func (w *Watchdog) Register(name string, period time.Duration) func() {
+	if w == nil {
+		return func() {}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	l := &loop{period: period}
+	w.loops[name] = l
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		l.last = time.Now()
+	}
+}
+
+// hung returns the names of the registered loops that are hung at now
+func (w *Watchdog) hung(now time.Time) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var names []string
+	for name, l := range w.loops {
+		if !l.last.IsZero() && now.Sub(l.last) > 2*l.period {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run feeds the watchdog every half of its timeout until ctx is done
+func (w *Watchdog) Run(ctx context.Context) {
+	if w == nil {
+		return
+	}
+	ticker := time.NewTicker(w.timeout / 2)
+	defer ticker.Stop()
+	var wasHung string
+	for {
+		if hung := strings.Join(w.hung(time.Now()), ","); hung != "" {
+			if hung != wasHung {
+				glog.Errorf("Not feeding the systemd watchdog, loops are hung: %v", hung)
+			}
+			wasHung = hung
+		} else {
+			wasHung = ""
+			if err := w.notify(StateWatchdog); err != nil {
+				glog.Errorf("Error feeding the systemd watchdog err=%v", err)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package sdnotify
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotify(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Not supervised by systemd
+	os.Unsetenv("NOTIFY_SOCKET")
+	assert.Nil(Notify(StateReady))
+
+	dir, err := ioutil.TempDir("", "sdnotify")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.Nil(err)
+	defer conn.Close()
+
+	os.Setenv("NOTIFY_SOCKET", socket)
+	defer os.Unsetenv("NOTIFY_SOCKET")
+	require.Nil(Notify(StateReady, Status("Running")))
+	buf := make([]byte, 256)
+	n, err := conn.Read(buf)
+	require.Nil(err)
+	assert.Equal("READY=1\nSTATUS=Running", string(buf[:n]))
+
+	os.Setenv("NOTIFY_SOCKET", filepath.Join(dir, "missing"))
+	assert.NotNil(Notify(StateReady))
+}
+
+func TestWatchdogTimeout(t *testing.T) {
+	assert := assert.New(t)
+	defer os.Unsetenv("WATCHDOG_USEC")
+	defer os.Unsetenv("WATCHDOG_PID")
+
+	os.Unsetenv("WATCHDOG_USEC")
+	timeout, err := WatchdogTimeout()
+	assert.Nil(err)
+	assert.Zero(timeout)
+
+	os.Setenv("WATCHDOG_USEC", "30000000")
+	timeout, err = WatchdogTimeout()
+	assert.Nil(err)
+	assert.Equal(30*time.Second, timeout)
+
+	os.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	timeout, err = WatchdogTimeout()
+	assert.Nil(err)
+	assert.Equal(30*time.Second, timeout)
+
+	// The watchdog is meant for another process
+	os.Setenv("WATCHDOG_PID", "1")
+	timeout, err = WatchdogTimeout()
+	assert.Nil(err)
+	assert.Zero(timeout)
+
+	os.Unsetenv("WATCHDOG_PID")
+	os.Setenv("WATCHDOG_USEC", "soon")
+	_, err = WatchdogTimeout()
+	assert.EqualError(err, `invalid WATCHDOG_USEC "soon"`)
+}
+
+func TestWatchdog(t *testing.T) {
+	assert := assert.New(t)
+
+	// A nil watchdog does nothing
+	var nilWatchdog *Watchdog
+	nilWatchdog.Register("loop", time.Second)()
+	nilWatchdog.Run(context.Background())
+
+	// Loops are not checked until their first iteration
+	w := NewWatchdog(20 * time.Millisecond)
+	beat := w.Register("b", 5*time.Millisecond)
+	w.Register("a", 5*time.Millisecond)()
+	now := time.Now()
+	assert.Empty(w.hung(now))
+	assert.Equal([]string{"a"}, w.hung(now.Add(11*time.Millisecond)))
+	beat()
+	assert.Equal([]string{"a", "b"}, w.hung(time.Now().Add(11*time.Millisecond)))
+
+	var mu sync.Mutex
+	var fed int
+	feeds := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		n := fed
+		fed = 0
+		return n
+	}
+	w = NewWatchdog(20 * time.Millisecond)
+	w.notify = func(states ...string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal([]string{StateWatchdog}, states)
+		fed++
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	beat = w.Register("loop", 20*time.Millisecond)
+	beat()
+	go w.Run(ctx)
+
+	// The watchdog is fed while the loop iterates
+	for i := 0; i < 5; i++ {
+		time.Sleep(10 * time.Millisecond)
+		beat()
+	}
+	assert.True(feeds() >= 3)
+
+	// and not anymore once the loop is hung
+	time.Sleep(60 * time.Millisecond)
+	feeds()
+	time.Sleep(50 * time.Millisecond)
+	assert.Zero(feeds())
+}
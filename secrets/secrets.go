@@ -0,0 +1,192 @@
+// Package secrets resolves references to secrets kept in HashiCorp Vault or AWS Secrets
+// Manager, so that the values of sensitive flags don't have to be kept in plaintext
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+)
+
+// Schemes of the references to secrets
+const (
+	// vault://<path>#<field> reads a field of a secret of a Vault KV secrets engine,
+	// e.g. vault://secret/data/livepeer#ethPassword
+	VaultScheme = "vault://"
+	// awssm://<secret id>[#<key>] reads a secret of AWS Secrets Manager, or a key of a
+	// secret holding a JSON object
+	AWSScheme = "awssm://"
+)
+
+// requestTimeout bounds the time taken to read a secret
+const requestTimeout = 10 * time.Second
+
+// IsReference returns true if v is a reference to a secret rather than a value
+func IsReference(v string) bool {
+	return strings.HasPrefix(v, VaultScheme) || strings.HasPrefix(v, AWSScheme)
+}
+
+// Resolver reads the secrets that references point to. Vault is configured with the
+// VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE environment variables, AWS Secrets Manager
+// with the usual AWS environment variables and shared config files
+type Resolver struct {
+	getenv func(string) string
+	client *http.Client
+	// awsEndpoint overrides the endpoint of AWS Secrets Manager, for tests
+	awsEndpoint string
+}
+
+// NewResolver creates a resolver reading its configuration with getenv, e.g. os.Getenv
+func NewResolver(getenv func(string) string) *Resolver {
+	return &Resolver{getenv: getenv, client: &http.Client{Timeout: requestTimeout}}
+}
+
+// Resolve returns the values of the secrets referenced by refs, keyed like refs. Each
+// secret is read once, even if several of its fields are referenced
+func (r *Resolver) Resolve(refs map[string]string) (map[string]string, error) {
+	secrets := make(map[string]map[string]interface{})
+	values := make(map[string]string)
+	for k, ref := range refs {
+		v, err := r.resolve(ref, secrets)
+		if err != nil {
+			return nil, fmt.Errorf("error reading secret %v: %v", ref, err)
+		}
+		values[k] = v
+	}
+	return values, nil
+}
+
+func (r *Resolver) resolve(ref string, secrets map[string]map[string]interface{}) (string, error) {
+	var id, field string
+	switch {
+	case strings.HasPrefix(ref, VaultScheme):
+		id = strings.TrimPrefix(ref, VaultScheme)
+	case strings.HasPrefix(ref, AWSScheme):
+		id = strings.TrimPrefix(ref, AWSScheme)
+	default:
+		return "", errors.New("not a reference to a secret")
+	}
+	if i := strings.LastIndex(id, "#"); i >= 0 {
+		id, field = id[:i], id[i+1:]
+	}
+	if id == "" {
+		return "", errors.New("missing secret")
+	}
+
+	if strings.HasPrefix(ref, AWSScheme) && field == "" {
+		// The whole secret, which needn't be a JSON object
+		return r.readAWS(id)
+	}
+	if strings.HasPrefix(ref, VaultScheme) && field == "" {
+		return "", errors.New("missing field of the secret")
+	}
+	key := ref[:len(ref)-len(field)]
+	secret, ok := secrets[key]
+	if !ok {
+		var err error
+		if strings.HasPrefix(ref, VaultScheme) {
+			secret, err = r.readVault(id)
+		} else {
+			secret, err = r.readAWSObject(id)
+		}
+		if err != nil {
+			return "", err
+		}
+		secrets[key] = secret
+	}
+	v, ok := secret[field]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %q", field)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(v), nil
+}
+
+// readVault reads a secret of a KV secrets engine, of either version
+func (r *Resolver) readVault(path string) (map[string]interface{}, error) {
+	addr, token := r.getenv("VAULT_ADDR"), r.getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		return nil, errors.New("VAULT_ADDR and VAULT_TOKEN must be set")
+	}
+	req, err := http.NewRequest("GET", strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := r.getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned %v", resp.Status)
+	}
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, fmt.Errorf("invalid response from vault: %v", err)
+	}
+	// The fields of a secret of a KV version 2 engine are nested along with its metadata
+	if data, ok := secret.Data["data"].(map[string]interface{}); ok {
+		if _, ok := secret.Data["metadata"]; ok {
+			return data, nil
+		}
+	}
+	return secret.Data, nil
+}
+
+// readAWS reads the value of a secret of AWS Secrets Manager
+func (r *Resolver) readAWS(id string) (string, error) {
+	cfg := aws.NewConfig().WithHTTPClient(r.client)
+	// Secrets named by their ARN are read from their region
+	if a, err := arn.Parse(id); err == nil {
+		cfg = cfg.WithRegion(a.Region)
+	}
+	if r.awsEndpoint != "" {
+		cfg = cfg.WithEndpoint(r.awsEndpoint)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{Config: *cfg, SharedConfigState: session.SharedConfigEnable})
+	if err != nil {
+		return "", err
+	}
+	out, err := secretsmanager.New(sess).GetSecretValue(&secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+	if err != nil {
+		return "", err
+	}
+	if out.SecretString == nil {
+		return string(out.SecretBinary), nil
+	}
+	return *out.SecretString, nil
+}
+
+// readAWSObject reads a secret of AWS Secrets Manager holding a JSON object
+func (r *Resolver) readAWSObject(id string) (map[string]interface{}, error) {
+	v, err := r.readAWS(id)
+	if err != nil {
+		return nil, err
+	}
+	var secret map[string]interface{}
+	if err := json.Unmarshal([]byte(v), &secret); err != nil {
+		return nil, errors.New("secret is not a JSON object")
+	}
+	return secret, nil
+}
@@ -0,0 +1,123 @@
+package secrets
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsReference(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(IsReference("vault://secret/data/livepeer#ethPassword"))
+	assert.True(IsReference("awssm://livepeer"))
+	assert.False(IsReference("https://example.com/auth"))
+	assert.False(IsReference("ACCESSKEYID/ACCESSKEY"))
+}
+
+func TestResolve_Vault(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	reads := 0
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		reads++
+		switch r.URL.Path {
+		case "/v1/secret/data/livepeer":
+			assert.Equal("ns", r.Header.Get("X-Vault-Namespace"))
+			w.Write([]byte(`{"data":{"data":{"ethPassword":"pass","port":1935},"metadata":{"version":2}}}`))
+		case "/v1/kv/livepeer":
+			w.Write([]byte(`{"data":{"s3creds":"KEY/SECRET"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	env := map[string]string{"VAULT_ADDR": vault.URL + "/", "VAULT_TOKEN": "token", "VAULT_NAMESPACE": "ns"}
+	r := NewResolver(func(k string) string { return env[k] })
+
+	values, err := r.Resolve(map[string]string{
+		"ethPassword": "vault://secret/data/livepeer#ethPassword",
+		"port":        "vault://secret/data/livepeer#port",
+		"s3creds":     "vault://kv/livepeer#s3creds",
+	})
+	require.Nil(err)
+	assert.Equal(map[string]string{"ethPassword": "pass", "port": "1935", "s3creds": "KEY/SECRET"}, values)
+	// Each secret is read once
+	assert.Equal(2, reads)
+
+	_, err = r.Resolve(map[string]string{"a": "vault://secret/data/livepeer#missing"})
+	assert.EqualError(err, `error reading secret vault://secret/data/livepeer#missing: secret has no field "missing"`)
+	_, err = r.Resolve(map[string]string{"a": "vault://secret/data/livepeer"})
+	assert.EqualError(err, "error reading secret vault://secret/data/livepeer: missing field of the secret")
+	_, err = r.Resolve(map[string]string{"a": "vault://secret/data/other#a"})
+	assert.EqualError(err, "error reading secret vault://secret/data/other#a: vault returned 404 Not Found")
+
+	env["VAULT_TOKEN"] = "other"
+	_, err = r.Resolve(map[string]string{"a": "vault://kv/livepeer#s3creds"})
+	assert.EqualError(err, "error reading secret vault://kv/livepeer#s3creds: vault returned 403 Forbidden")
+	delete(env, "VAULT_TOKEN")
+	_, err = r.Resolve(map[string]string{"a": "vault://kv/livepeer#s3creds"})
+	assert.EqualError(err, "error reading secret vault://kv/livepeer#s3creds: VAULT_ADDR and VAULT_TOKEN must be set")
+}
+
+func TestResolve_AWS(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	secrets := map[string]string{
+		"livepeer": `{"cliToken":"token","s3creds":"KEY/SECRET"}`,
+		"arn:aws:secretsmanager:eu-west-1:123456789012:secret:orchSecret-AbCdEf": "secret",
+	}
+	sm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+		body, _ := ioutil.ReadAll(r.Body)
+		var in struct{ SecretId string }
+		require.Nil(json.Unmarshal(body, &in))
+		v, ok := secrets[in.SecretId]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceNotFoundException","Message":"Secrets Manager can't find the specified secret."}`))
+			return
+		}
+		out, _ := json.Marshal(map[string]string{"Name": in.SecretId, "SecretString": v})
+		w.Write(out)
+	}))
+	defer sm.Close()
+
+	env := map[string]string{
+		"AWS_ACCESS_KEY_ID":     "KEY",
+		"AWS_SECRET_ACCESS_KEY": "SECRET",
+		"AWS_REGION":            "us-east-1",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	r := NewResolver(func(string) string { return "" })
+	r.awsEndpoint = sm.URL
+
+	values, err := r.Resolve(map[string]string{
+		"cliToken":   "awssm://livepeer#cliToken",
+		"s3creds":    "awssm://livepeer#s3creds",
+		"orchSecret": "awssm://arn:aws:secretsmanager:eu-west-1:123456789012:secret:orchSecret-AbCdEf",
+	})
+	require.Nil(err)
+	assert.Equal(map[string]string{"cliToken": "token", "s3creds": "KEY/SECRET", "orchSecret": "secret"}, values)
+
+	_, err = r.Resolve(map[string]string{"a": "awssm://arn:aws:secretsmanager:eu-west-1:123456789012:secret:orchSecret-AbCdEf#key"})
+	assert.EqualError(err, "error reading secret awssm://arn:aws:secretsmanager:eu-west-1:123456789012:secret:orchSecret-AbCdEf#key: secret is not a JSON object")
+	_, err = r.Resolve(map[string]string{"a": "awssm://other"})
+	require.NotNil(err)
+	assert.Contains(err.Error(), "ResourceNotFoundException")
+}
@@ -0,0 +1,606 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	lpcommon "github.com/livepeer/go-livepeer/common"
+)
+
+// apiPrefix is the path of the versioned management API on the CLI server
+const apiPrefix = "/api/v1"
+
+// Types of the parameters of the management API
+const (
+	apiString  = "string"
+	apiInteger = "integer"
+	apiNumber  = "number"
+	apiBoolean = "boolean"
+	// Amounts in base units that don't fit in 64 bits, sent as decimal strings
+	apiBigInt = "bigint"
+)
+
+// Results of the operations of the management API
+const (
+	// The operation doesn't return anything, and responds with 204 No Content
+	resultNone = ""
+	// The JSON response of the CLI server endpoint
+	resultJSON = "json"
+	// The text response of the CLI server endpoint, as a JSON string
+	resultString = "string"
+	// The binary response of the CLI server endpoint, as a 0x prefixed hex JSON string
+	resultHex = "hex"
+	// The big-endian integer response of the CLI server endpoint, as a decimal JSON string
+	resultBigInt = "bigint"
+)
+
+// apiParam is a parameter of an operation of the management API
+type apiParam struct {
+	name     string
+	typ      string
+	required bool
+	desc     string
+}
+
+// apiOperation is an operation of the management API. Operations are implemented by the
+// CLI server endpoints, which receive the parameters as form values
+type apiOperation struct {
+	id      string
+	method  string
+	path    string
+	summary string
+	tag     string
+	legacy  string
+	params  []apiParam
+	result  string
+	// The operation requires an Ethereum client
+	onchain bool
+}
+
+var apiOperations = []apiOperation{
+	// Node
+	{id: "getStatus", method: "GET", path: "/status", tag: "node", summary: "Get the status of the node", legacy: "/status", result: resultJSON},
+	{id: "getLogLevel", method: "GET", path: "/logLevel", tag: "node", summary: "Get the log verbosity", legacy: "/getLogLevel", result: resultJSON},
+	{id: "setLogLevel", method: "POST", path: "/logLevel", tag: "node", summary: "Set the log verbosity", legacy: "/setLogLevel", params: []apiParam{
+		{name: "loglevel", typ: apiInteger, required: true, desc: "Verbosity from 0 to 6"},
+	}},
+	{id: "reload", method: "POST", path: "/reload", tag: "node", summary: "Reload the settings that can be changed without a restart", legacy: "/reload", result: resultJSON},
+	{id: "getConfigSnapshot", method: "GET", path: "/config/snapshot", tag: "node", summary: "Export the effective configuration of the node as a YAML config file, without secrets", legacy: "/configSnapshot", result: resultString},
+	{id: "getReady", method: "GET", path: "/ready", tag: "node", summary: "Check that the node serves requests and is not shutting down", legacy: "/ready", result: resultString},
+	{id: "listFeatures", method: "GET", path: "/features", tag: "node", summary: "List the experimental features and whether they are enabled", legacy: "/features", result: resultJSON},
+	{id: "setFeature", method: "POST", path: "/features", tag: "node", summary: "Enable or disable an experimental feature until the node restarts", legacy: "/setFeature", params: []apiParam{
+		{name: "name", typ: apiString, required: true, desc: "Name of the feature"},
+		{name: "enabled", typ: apiBoolean, required: true, desc: "Whether the feature is enabled"},
+	}},
+
+	// Account
+	{id: "getEthAddress", method: "GET", path: "/account/address", tag: "account", summary: "Get the Ethereum address of the node", legacy: "/ethAddr", result: resultString, onchain: true},
+	{id: "getTokenBalance", method: "GET", path: "/account/tokenBalance", tag: "account", summary: "Get the LPT balance in base units", legacy: "/tokenBalance", result: resultString, onchain: true},
+	{id: "getEthBalance", method: "GET", path: "/account/ethBalance", tag: "account", summary: "Get the ETH balance in Wei", legacy: "/ethBalance", result: resultString, onchain: true},
+	{id: "transferTokens", method: "POST", path: "/account/transfer", tag: "account", summary: "Transfer LPT", legacy: "/transferTokens", onchain: true, params: []apiParam{
+		{name: "to", typ: apiString, required: true, desc: "Address of the recipient"},
+		{name: "amount", typ: apiBigInt, required: true, desc: "Amount of LPT in base units"},
+	}},
+	{id: "signMessage", method: "POST", path: "/account/sign", tag: "account", summary: "Sign a message with the Ethereum account of the node", legacy: "/signMessage", result: resultHex, onchain: true, params: []apiParam{
+		{name: "message", typ: apiString, required: true, desc: "Message to sign"},
+	}},
+	{id: "listUnsignedTransactions", method: "GET", path: "/account/unsignedTransactions", tag: "account", summary: "List the transactions queued to be signed offline, with the nonces to sign them with", legacy: "/unsignedTransactions", result: resultJSON, onchain: true},
+	{id: "sendSignedTransaction", method: "POST", path: "/account/unsignedTransactions/send", tag: "account", summary: "Send an offline signed queued transaction, and get its hash", legacy: "/sendSignedTransaction", result: resultString, onchain: true, params: []apiParam{
+		{name: "tx", typ: apiString, required: true, desc: "Hex encoded signed raw transaction"},
+	}},
+	{id: "discardUnsignedTransaction", method: "POST", path: "/account/unsignedTransactions/discard", tag: "account", summary: "Remove a transaction queued to be signed offline", legacy: "/discardUnsignedTransaction", onchain: true, params: []apiParam{
+		{name: "id", typ: apiString, required: true, desc: "ID of the queued transaction"},
+	}},
+	{id: "getGasPrice", method: "GET", path: "/gasPrice", tag: "account", summary: "Get the gas price in Wei, 0 if automatic", legacy: "/gasPrice", result: resultString, onchain: true},
+	{id: "setGasPrice", method: "POST", path: "/gasPrice", tag: "account", summary: "Set the gas price", legacy: "/setGasPrice", onchain: true, params: []apiParam{
+		{name: "amount", typ: apiBigInt, required: true, desc: "Gas price in Wei, 0 for automatic"},
+	}},
+
+	// Protocol
+	{id: "getContractAddresses", method: "GET", path: "/protocol/contracts", tag: "protocol", summary: "Get the addresses of the protocol contracts", legacy: "/contractAddresses", result: resultJSON, onchain: true},
+	{id: "getProtocolParameters", method: "GET", path: "/protocol/parameters", tag: "protocol", summary: "Get the protocol parameters", legacy: "/protocolParameters", result: resultJSON, onchain: true},
+	{id: "getCurrentRound", method: "GET", path: "/protocol/round", tag: "protocol", summary: "Get the current round", legacy: "/currentRound", result: resultBigInt, onchain: true},
+	{id: "initializeRound", method: "POST", path: "/protocol/round", tag: "protocol", summary: "Initialize the current round", legacy: "/initializeRound", onchain: true},
+	{id: "listOrchestrators", method: "GET", path: "/protocol/orchestrators", tag: "protocol", summary: "List the registered orchestrators", legacy: "/registeredOrchestrators", result: resultJSON, onchain: true},
+	{id: "vote", method: "POST", path: "/protocol/vote", tag: "protocol", summary: "Vote in a poll", legacy: "/vote", result: resultHex, onchain: true, params: []apiParam{
+		{name: "poll", typ: apiString, required: true, desc: "Address of the poll contract"},
+		{name: "choiceID", typ: apiInteger, required: true, desc: "ID of the choice to vote for"},
+	}},
+
+	// Orchestrator
+	{id: "getOrchestratorInfo", method: "GET", path: "/orchestrator", tag: "orchestrator", summary: "Get the on-chain info and price of the orchestrator", legacy: "/orchestratorInfo", result: resultJSON, onchain: true},
+	{id: "activateOrchestrator", method: "POST", path: "/orchestrator/activate", tag: "orchestrator", summary: "Register the node as an orchestrator", legacy: "/activateOrchestrator", onchain: true, params: []apiParam{
+		{name: "blockRewardCut", typ: apiNumber, required: true, desc: "Percentage of the block rewards kept by the orchestrator"},
+		{name: "feeShare", typ: apiNumber, required: true, desc: "Percentage of the fees shared with delegators"},
+		{name: "pricePerUnit", typ: apiInteger, required: true, desc: "Price in Wei per pixelsPerUnit pixels"},
+		{name: "pixelsPerUnit", typ: apiInteger, required: true, desc: "Number of pixels priced at pricePerUnit"},
+		{name: "serviceURI", typ: apiString, required: true, desc: "Service URI of the orchestrator"},
+		{name: "amount", typ: apiBigInt, desc: "Amount of LPT in base units to bond to self"},
+		{name: "unbondingLockId", typ: apiBigInt, desc: "Unbonding lock to rebond with instead of bonding amount"},
+		{name: "skipServiceURICheck", typ: apiBoolean, desc: "Register the service URI without checking that it reaches the node"},
+	}},
+	{id: "setOrchestratorConfig", method: "POST", path: "/orchestrator/config", tag: "orchestrator", summary: "Update the orchestrator configuration", legacy: "/setOrchestratorConfig", onchain: true, params: []apiParam{
+		{name: "blockRewardCut", typ: apiNumber, desc: "Percentage of the block rewards kept by the orchestrator"},
+		{name: "feeShare", typ: apiNumber, desc: "Percentage of the fees shared with delegators"},
+		{name: "pricePerUnit", typ: apiInteger, desc: "Price in Wei per pixelsPerUnit pixels"},
+		{name: "pixelsPerUnit", typ: apiInteger, desc: "Number of pixels priced at pricePerUnit"},
+		{name: "serviceURI", typ: apiString, desc: "Service URI of the orchestrator"},
+		{name: "skipServiceURICheck", typ: apiBoolean, desc: "Register the service URI without checking that it reaches the node"},
+	}},
+	{id: "checkServiceURI", method: "GET", path: "/orchestrator/serviceURI", tag: "orchestrator", summary: "Check that a service URI is public and reaches the node", legacy: "/checkServiceURI", result: resultJSON, onchain: true, params: []apiParam{
+		{name: "serviceURI", typ: apiString, desc: "Service URI to check, the current service URI of the node by default"},
+	}},
+	{id: "reward", method: "POST", path: "/orchestrator/reward", tag: "orchestrator", summary: "Call reward for the current round", legacy: "/reward", onchain: true},
+
+	// Delegator
+	{id: "getDelegator", method: "GET", path: "/delegator", tag: "delegator", summary: "Get the delegator info of the node", legacy: "/delegatorInfo", result: resultJSON, onchain: true},
+	{id: "bond", method: "POST", path: "/delegator/bond", tag: "delegator", summary: "Bond LPT to an orchestrator", legacy: "/bond", onchain: true, params: []apiParam{
+		{name: "amount", typ: apiBigInt, required: true, desc: "Amount of LPT in base units"},
+		{name: "toAddr", typ: apiString, required: true, desc: "Address of the orchestrator"},
+	}},
+	{id: "unbond", method: "POST", path: "/delegator/unbond", tag: "delegator", summary: "Unbond LPT", legacy: "/unbond", onchain: true, params: []apiParam{
+		{name: "amount", typ: apiBigInt, required: true, desc: "Amount of LPT in base units"},
+	}},
+	{id: "rebond", method: "POST", path: "/delegator/rebond", tag: "delegator", summary: "Rebond the stake of an unbonding lock", legacy: "/rebond", onchain: true, params: []apiParam{
+		{name: "unbondingLockId", typ: apiBigInt, required: true, desc: "ID of the unbonding lock"},
+		{name: "toAddr", typ: apiString, desc: "Address of the orchestrator to rebond to, if unbonded"},
+	}},
+	{id: "listUnbondingLocks", method: "GET", path: "/delegator/unbondingLocks", tag: "delegator", summary: "List the unbonding locks", legacy: "/unbondingLocks", result: resultJSON, onchain: true, params: []apiParam{
+		{name: "withdrawable", typ: apiBoolean, desc: "Only list the locks that can be withdrawn"},
+	}},
+	{id: "withdrawStake", method: "POST", path: "/delegator/withdrawStake", tag: "delegator", summary: "Withdraw the stake of an unbonding lock", legacy: "/withdrawStake", onchain: true, params: []apiParam{
+		{name: "unbondingLockId", typ: apiBigInt, required: true, desc: "ID of the unbonding lock"},
+	}},
+	{id: "moveStake", method: "POST", path: "/delegator/moveStake", tag: "delegator", summary: "Move the whole stake to another orchestrator", legacy: "/moveStake", result: resultJSON, onchain: true, params: []apiParam{
+		{name: "toAddr", typ: apiString, required: true, desc: "Address of the orchestrator"},
+	}},
+	{id: "previewStake", method: "GET", path: "/delegator/preview", tag: "delegator", summary: "Preview the stake after a bonding transaction without sending it", legacy: "/previewStake", result: resultJSON, onchain: true, params: []apiParam{
+		{name: "action", typ: apiString, required: true, desc: "Transaction to preview: bond, rebond, unbond, withdrawStake or moveStake"},
+		{name: "amount", typ: apiBigInt, desc: "Amount of LPT in base units to bond or unbond"},
+		{name: "toAddr", typ: apiString, desc: "Address of the orchestrator to bond, rebond or move the stake to"},
+		{name: "unbondingLockId", typ: apiBigInt, desc: "ID of the unbonding lock to rebond or withdraw"},
+	}},
+	{id: "withdrawFees", method: "POST", path: "/delegator/withdrawFees", tag: "delegator", summary: "Withdraw fees", legacy: "/withdrawFees", onchain: true},
+	{id: "claimEarnings", method: "POST", path: "/delegator/claimEarnings", tag: "delegator", summary: "Claim rewards and fees", legacy: "/claimEarnings", onchain: true, params: []apiParam{
+		{name: "endRound", typ: apiBigInt, required: true, desc: "Round to claim up to"},
+	}},
+
+	// Broadcaster
+	{id: "getBroadcastConfig", method: "GET", path: "/broadcaster/config", tag: "broadcaster", summary: "Get the maximum price and transcoding options", legacy: "/getBroadcastConfig", result: resultJSON},
+	{id: "setBroadcastConfig", method: "POST", path: "/broadcaster/config", tag: "broadcaster", summary: "Set the maximum price and transcoding options", legacy: "/setBroadcastConfig", params: []apiParam{
+		{name: "maxPricePerUnit", typ: apiInteger, desc: "Maximum price in Wei per pixelsPerUnit pixels, 0 for no maximum"},
+		{name: "pixelsPerUnit", typ: apiInteger, desc: "Number of pixels priced at maxPricePerUnit"},
+		{name: "transcodingOptions", typ: apiString, desc: "Comma separated list of transcoding profiles"},
+	}},
+	{id: "setStreamProfiles", method: "POST", path: "/broadcaster/streams/profiles", tag: "broadcaster", summary: "Replace the transcoding profiles of a live stream from its next segment", legacy: "/setStreamProfiles", result: resultJSON, params: []apiParam{
+		{name: "manifestID", typ: apiString, required: true, desc: "Manifest ID of the stream"},
+		{name: "presets", typ: apiString, desc: "Comma separated list of transcoding profiles"},
+		{name: "profiles", typ: apiString, desc: "JSON array of custom profiles, as returned by the auth webhook"},
+	}},
+	{id: "clip", method: "POST", path: "/broadcaster/clips", tag: "broadcaster", summary: "Transcode a frame-accurate clip of a recorded stream", legacy: "/clip", result: resultJSON, params: []apiParam{
+		{name: "url", typ: apiString, required: true, desc: "URL of the HLS media playlist of the recorded stream"},
+		{name: "start", typ: apiNumber, required: true, desc: "Start of the clip, in seconds from the start of the recording"},
+		{name: "end", typ: apiNumber, required: true, desc: "End of the clip, in seconds from the start of the recording"},
+		{name: "presets", typ: apiString, desc: "Comma separated list of transcoding profiles"},
+		{name: "profiles", typ: apiString, desc: "JSON array of custom profiles, as returned by the auth webhook"},
+	}},
+	{id: "getSenderInfo", method: "GET", path: "/broadcaster/sender", tag: "broadcaster", summary: "Get the deposit and reserve of the broadcaster", legacy: "/senderInfo", result: resultJSON, onchain: true},
+	{id: "fundDepositAndReserve", method: "POST", path: "/broadcaster/sender/fund", tag: "broadcaster", summary: "Fund the deposit and reserve", legacy: "/fundDepositAndReserve", onchain: true, params: []apiParam{
+		{name: "depositAmount", typ: apiBigInt, required: true, desc: "Deposit amount in Wei"},
+		{name: "reserveAmount", typ: apiBigInt, required: true, desc: "Reserve amount in Wei"},
+	}},
+	{id: "unlock", method: "POST", path: "/broadcaster/sender/unlock", tag: "broadcaster", summary: "Start the unlock period of the deposit and reserve", legacy: "/unlock", onchain: true},
+	{id: "cancelUnlock", method: "POST", path: "/broadcaster/sender/cancelUnlock", tag: "broadcaster", summary: "Cancel the unlock of the deposit and reserve", legacy: "/cancelUnlock", onchain: true},
+	{id: "withdraw", method: "POST", path: "/broadcaster/sender/withdraw", tag: "broadcaster", summary: "Withdraw the unlocked deposit and reserve", legacy: "/withdraw", onchain: true},
+	{id: "getFleetStatus", method: "GET", path: "/broadcaster/fleet", tag: "broadcaster", summary: "Get the members, streams and orchestrator performance of the fleet of a coordinator", legacy: "/coordinator", result: resultJSON},
+
+	// Onboarding
+	{id: "ensureDeposit", method: "POST", path: "/onboarding/deposit", tag: "onboarding", summary: "Top up the deposit and reserve of the broadcaster to at least the given amounts, funding only what is missing", legacy: "/ensureDeposit", result: resultJSON, onchain: true, params: []apiParam{
+		{name: "depositAmount", typ: apiBigInt, required: true, desc: "Minimum deposit in Wei"},
+		{name: "reserveAmount", typ: apiBigInt, required: true, desc: "Minimum reserve in Wei"},
+	}},
+	{id: "ensureOrchestrator", method: "POST", path: "/onboarding/orchestrator", tag: "onboarding", summary: "Register the node as an orchestrator, or update the settings that differ if it is registered", legacy: "/ensureOrchestrator", result: resultJSON, onchain: true, params: []apiParam{
+		{name: "blockRewardCut", typ: apiNumber, required: true, desc: "Percentage of the block rewards kept by the orchestrator"},
+		{name: "feeShare", typ: apiNumber, required: true, desc: "Percentage of the fees shared with delegators"},
+		{name: "pricePerUnit", typ: apiInteger, required: true, desc: "Price in Wei per pixelsPerUnit pixels"},
+		{name: "pixelsPerUnit", typ: apiInteger, required: true, desc: "Number of pixels priced at pricePerUnit"},
+		{name: "serviceURI", typ: apiString, required: true, desc: "Service URI of the orchestrator"},
+		{name: "amount", typ: apiBigInt, desc: "Minimum stake in LPT base units bonded to the orchestrator, bonding only what is missing"},
+		{name: "skipServiceURICheck", typ: apiBoolean, desc: "Register the service URI without checking that it reaches the node"},
+	}},
+
+	// Monitoring
+	{id: "getSenderStats", method: "GET", path: "/stats/senders", tag: "monitoring", summary: "Get the analytics of the broadcasters of an orchestrator", legacy: "/senderStats", result: resultJSON, params: []apiParam{
+		{name: "sender", typ: apiString, desc: "Only return the analytics of this broadcaster"},
+	}},
+	{id: "getBandwidth", method: "GET", path: "/stats/bandwidth", tag: "monitoring", summary: "Get the ingress and egress bytes of the node", legacy: "/bandwidth", result: resultJSON},
+	{id: "getCanary", method: "GET", path: "/canary", tag: "monitoring", summary: "Get the results of the self-test canary", legacy: "/canary", result: resultJSON},
+	{id: "getTranscoderPool", method: "GET", path: "/transcoders", tag: "monitoring", summary: "Get the load and the performance of the transcoders connected to an orchestrator", legacy: "/transcoderPool", result: resultJSON},
+	{id: "listWebhooks", method: "GET", path: "/webhooks", tag: "monitoring", summary: "Get the delivery counters and dead letters of the outbound webhooks", legacy: "/webhooks", result: resultJSON},
+	{id: "getVerifierStatus", method: "GET", path: "/verification/status", tag: "monitoring", summary: "Get the status of the verifiers and orchestrators", legacy: "/verifierStatus", result: resultJSON},
+	{id: "listVerificationResults", method: "GET", path: "/verification/results", tag: "monitoring", summary: "List the verification results, most recent first", legacy: "/verificationResults", result: resultJSON, params: []apiParam{
+		{name: "manifestID", typ: apiString, desc: "Only return the results of this stream"},
+		{name: "orchestrator", typ: apiString, desc: "Only return the results of this orchestrator"},
+		{name: "since", typ: apiString, desc: "RFC 3339 time of the oldest result"},
+		{name: "until", typ: apiString, desc: "RFC 3339 time of the most recent result"},
+		{name: "limit", typ: apiInteger, desc: "Maximum number of results"},
+	}},
+	{id: "getPixelChecks", method: "GET", path: "/verification/pixelChecks", tag: "monitoring", summary: "Get the results of the pixel count checks", legacy: "/pixelChecks", result: resultJSON},
+	{id: "listTranscodeReceipts", method: "GET", path: "/receipts", tag: "monitoring", summary: "List the stored transcode receipts, most recent first", legacy: "/transcodeReceipts", result: resultJSON, params: []apiParam{
+		{name: "manifestID", typ: apiString, desc: "Only return the receipts of this stream"},
+		{name: "orchestrator", typ: apiString, desc: "Only return the receipts of this orchestrator"},
+		{name: "seqNo", typ: apiInteger, desc: "Only return the receipts that cover this segment, with its Merkle proof"},
+		{name: "limit", typ: apiInteger, desc: "Maximum number of receipts"},
+	}},
+	{id: "getEarnings", method: "GET", path: "/stats/earnings", tag: "monitoring", summary: "Get the fees, rewards and bonds of the node indexed from the chain", legacy: "/earnings", result: resultJSON, onchain: true, params: []apiParam{
+		{name: "kind", typ: apiString, desc: "Only return the earnings of this kind: fees, reward or bond"},
+		{name: "since", typ: apiString, desc: "RFC 3339 time of the oldest earnings"},
+		{name: "until", typ: apiString, desc: "RFC 3339 time after the most recent earnings"},
+		{name: "interval", typ: apiString, desc: "Also sum the earnings per day, month or year"},
+		{name: "events", typ: apiBoolean, desc: "Also list the indexed earnings"},
+	}},
+}
+
+// apiRecorder records the response of a CLI server endpoint
+type apiRecorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (r *apiRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *apiRecorder) Write(b []byte) (int, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+func (r *apiRecorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+}
+
+func respondAPIError(w http.ResponseWriter, msg string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+func respondAPIJSON(w http.ResponseWriter, data []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// apiHandler serves the management API and its OpenAPI document from the endpoints of the
+// CLI server registered on mux
+func (s *LivepeerServer) apiHandler(mux http.Handler) http.Handler {
+	spec, err := json.MarshalIndent(openAPISpec(), "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, apiPrefix)
+		if path == "/openapi.json" && r.Method == http.MethodGet {
+			respondAPIJSON(w, spec)
+			return
+		}
+
+		var allowed []string
+		for _, op := range apiOperations {
+			if op.path != path {
+				continue
+			}
+			if op.method != r.Method {
+				allowed = append(allowed, op.method)
+				continue
+			}
+			if op.onchain && s.LivepeerNode.Eth == nil {
+				respondAPIError(w, "not available on an off-chain node", http.StatusNotFound)
+				return
+			}
+			serveAPIOperation(w, r, op, mux)
+			return
+		}
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			respondAPIError(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		respondAPIError(w, "not found", http.StatusNotFound)
+	})
+}
+
+// apiParams returns the parameters of a request, from the query of GET requests and from
+// the JSON object in the body of other requests
+func apiParams(r *http.Request) (map[string]string, error) {
+	params := make(map[string]string)
+	if r.Method == http.MethodGet {
+		for k, v := range r.URL.Query() {
+			params[k] = v[0]
+		}
+		return params, nil
+	}
+
+	var body map[string]interface{}
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&body); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid JSON body: %v", err)
+	}
+	for k, v := range body {
+		switch v := v.(type) {
+		case nil:
+		case string:
+			params[k] = v
+		case json.Number:
+			params[k] = v.String()
+		case bool:
+			params[k] = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("invalid value for %v", k)
+		}
+	}
+	return params, nil
+}
+
+// checkAPIParam returns an error if the value of a parameter doesn't have its type
+func checkAPIParam(p apiParam, v string) error {
+	var err error
+	switch p.typ {
+	case apiInteger:
+		_, err = strconv.ParseInt(v, 10, 64)
+	case apiNumber:
+		_, err = strconv.ParseFloat(v, 64)
+	case apiBoolean:
+		_, err = strconv.ParseBool(v)
+	case apiBigInt:
+		_, err = lpcommon.ParseBigInt(v)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %v %v: %v", p.typ, p.name, v)
+	}
+	return nil
+}
+
+// serveAPIOperation calls the CLI server endpoint of an operation and converts its response
+func serveAPIOperation(w http.ResponseWriter, r *http.Request, op apiOperation, mux http.Handler) {
+	params, err := apiParams(r)
+	if err != nil {
+		respondAPIError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	form := url.Values{}
+	for _, p := range op.params {
+		v, ok := params[p.name]
+		delete(params, p.name)
+		if !ok || v == "" {
+			if p.required {
+				respondAPIError(w, fmt.Sprintf("missing parameter %v", p.name), http.StatusBadRequest)
+				return
+			}
+			continue
+		}
+		if err := checkAPIParam(p, v); err != nil {
+			respondAPIError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		form.Set(p.name, v)
+	}
+	for name := range params {
+		respondAPIError(w, fmt.Sprintf("unknown parameter %v", name), http.StatusBadRequest)
+		return
+	}
+
+	var req *http.Request
+	if op.method == http.MethodGet {
+		req, err = http.NewRequestWithContext(r.Context(), op.method, op.legacy+"?"+form.Encode(), nil)
+	} else {
+		req, err = http.NewRequestWithContext(r.Context(), op.method, op.legacy, strings.NewReader(form.Encode()))
+		if req != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	if err != nil {
+		respondAPIError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rec := &apiRecorder{header: make(http.Header)}
+	mux.ServeHTTP(rec, req)
+
+	body := rec.body.Bytes()
+	if rec.code == 0 {
+		rec.code = http.StatusOK
+	}
+	if rec.code < 200 || rec.code >= 300 {
+		msg := strings.TrimSpace(string(body))
+		if msg == "" {
+			msg = http.StatusText(rec.code)
+		}
+		respondAPIError(w, msg, rec.code)
+		return
+	}
+	if op.result == resultNone {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	// The endpoints respond with an empty body if the feature is not enabled on the node
+	if len(body) == 0 {
+		respondAPIError(w, "not available on this node", http.StatusNotFound)
+		return
+	}
+
+	var data []byte
+	switch op.result {
+	case resultJSON:
+		data = body
+	case resultString:
+		data, err = json.Marshal(strings.TrimSpace(string(body)))
+	case resultHex:
+		data, err = json.Marshal(fmt.Sprintf("0x%x", body))
+	case resultBigInt:
+		data, err = json.Marshal(new(big.Int).SetBytes(body).String())
+	}
+	if err != nil {
+		respondAPIError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	respondAPIJSON(w, data)
+}
+
+// openAPISchema returns the JSON schema of a parameter type
+func openAPISchema(typ string) map[string]interface{} {
+	switch typ {
+	case apiInteger:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case apiNumber:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case apiBoolean:
+		return map[string]interface{}{"type": "boolean"}
+	case apiBigInt:
+		return map[string]interface{}{"type": "string", "format": "bigint", "pattern": "^[0-9]+$"}
+	}
+	return map[string]interface{}{"type": "string"}
+}
+
+// openAPISpec returns the OpenAPI document of the management API
+func openAPISpec() map[string]interface{} {
+	errorResponse := map[string]interface{}{
+		"description": "Error",
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"},
+			},
+		},
+	}
+
+	paths := make(map[string]interface{})
+	tags := make(map[string]bool)
+	for _, op := range apiOperations {
+		operation := map[string]interface{}{
+			"operationId": op.id,
+			"summary":     op.summary,
+			"tags":        []string{op.tag},
+		}
+		tags[op.tag] = true
+
+		if op.method == http.MethodGet {
+			var parameters []interface{}
+			for _, p := range op.params {
+				parameters = append(parameters, map[string]interface{}{
+					"name":        p.name,
+					"in":          "query",
+					"description": p.desc,
+					"required":    p.required,
+					"schema":      openAPISchema(p.typ),
+				})
+			}
+			if len(parameters) > 0 {
+				operation["parameters"] = parameters
+			}
+		} else if len(op.params) > 0 {
+			properties := make(map[string]interface{})
+			var required []string
+			for _, p := range op.params {
+				schema := openAPISchema(p.typ)
+				schema["description"] = p.desc
+				properties[p.name] = schema
+				if p.required {
+					required = append(required, p.name)
+				}
+			}
+			schema := map[string]interface{}{
+				"type":                 "object",
+				"properties":           properties,
+				"additionalProperties": false,
+			}
+			if len(required) > 0 {
+				schema["required"] = required
+			}
+			operation["requestBody"] = map[string]interface{}{
+				"required": len(required) > 0,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": schema},
+				},
+			}
+		}
+
+		responses := map[string]interface{}{"default": errorResponse}
+		switch op.result {
+		case resultNone:
+			responses["204"] = map[string]interface{}{"description": "Success"}
+		case resultJSON:
+			responses["200"] = map[string]interface{}{
+				"description": "Success",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": map[string]interface{}{}},
+				},
+			}
+		default:
+			responses["200"] = map[string]interface{}{
+				"description": "Success",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+				},
+			}
+		}
+		operation["responses"] = responses
+
+		item, ok := paths[op.path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[op.path] = item
+		}
+		item[strings.ToLower(op.method)] = operation
+	}
+
+	var tagNames []string
+	for tag := range tags {
+		tagNames = append(tagNames, tag)
+	}
+	sort.Strings(tagNames)
+	var tagList []interface{}
+	for _, tag := range tagNames {
+		tagList = append(tagList, map[string]interface{}{"name": tag})
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Livepeer node management API",
+			"version":     "v1",
+			"description": "Management API of the CLI server of a Livepeer node. Requests must present the -cliToken of the node as a bearer token, if set.",
+		},
+		"servers": []interface{}{
+			map[string]interface{}{"url": "http://localhost:7935" + apiPrefix},
+		},
+		"tags":     tagList,
+		"paths":    paths,
+		"security": []interface{}{map[string]interface{}{}, map[string]interface{}{"bearerAuth": []string{}}},
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+			"schemas": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"error": map[string]interface{}{"type": "string"}},
+					"required":   []string{"error"},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/eth"
+)
+
+var updateOpenAPI = flag.Bool("updateOpenAPI", false, "Update doc/api/openapi.json")
+
+const openAPIFile = "../doc/api/openapi.json"
+
+func TestAPIHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var form url.Values
+	var method string
+	mux := http.NewServeMux()
+	legacy := func(path string, h func(w http.ResponseWriter)) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			require.Nil(r.ParseForm())
+			form, method = r.Form, r.Method
+			h(w)
+		})
+	}
+	legacy("/status", func(w http.ResponseWriter) { w.Write([]byte(`{"Version":"0.5.9"}`)) })
+	legacy("/ethAddr", func(w http.ResponseWriter) { w.Write([]byte("0xabc")) })
+	legacy("/currentRound", func(w http.ResponseWriter) { w.Write([]byte{0x01, 0x00}) })
+	legacy("/signMessage", func(w http.ResponseWriter) { w.Write([]byte{0xab, 0xcd}) })
+	legacy("/bond", func(w http.ResponseWriter) {})
+	legacy("/unbondingLocks", func(w http.ResponseWriter) { w.Write([]byte("[]")) })
+	legacy("/delegatorInfo", func(w http.ResponseWriter) {})
+	legacy("/unlock", func(w http.ResponseWriter) { respondWith500(w, "could not execute unlock: nope") })
+
+	s := &LivepeerServer{LivepeerNode: &core.LivepeerNode{}}
+	handler := s.apiHandler(mux)
+	do := func(method, path, body string) (int, string) {
+		req := httptest.NewRequest(method, apiPrefix+path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code, strings.TrimSpace(rr.Body.String())
+	}
+
+	code, body := do("GET", "/status", "")
+	assert.Equal(http.StatusOK, code)
+	assert.Equal(`{"Version":"0.5.9"}`, body)
+
+	// On-chain operations are not available without an Ethereum client
+	code, body = do("GET", "/account/address", "")
+	assert.Equal(http.StatusNotFound, code)
+	assert.Equal(`{"error":"not available on an off-chain node"}`, body)
+
+	s.LivepeerNode.Eth = &eth.StubClient{}
+	code, body = do("GET", "/account/address", "")
+	assert.Equal(http.StatusOK, code)
+	assert.Equal(`"0xabc"`, body)
+	code, body = do("GET", "/protocol/round", "")
+	assert.Equal(http.StatusOK, code)
+	assert.Equal(`"256"`, body)
+
+	// Parameters are sent to the endpoints as form values
+	code, body = do("POST", "/account/sign", `{"message":"hello"}`)
+	assert.Equal(http.StatusOK, code)
+	assert.Equal(`"0xabcd"`, body)
+	assert.Equal(url.Values{"message": {"hello"}}, form)
+
+	code, _ = do("POST", "/delegator/bond", `{"amount":1000000000000000000000,"toAddr":"0x1"}`)
+	assert.Equal(http.StatusNoContent, code)
+	assert.Equal("POST", method)
+	assert.Equal(url.Values{"amount": {"1000000000000000000000"}, "toAddr": {"0x1"}}, form)
+
+	code, body = do("GET", "/delegator/unbondingLocks?withdrawable=true", "")
+	assert.Equal(http.StatusOK, code)
+	assert.Equal("[]", body)
+	assert.Equal("GET", method)
+	assert.Equal(url.Values{"withdrawable": {"true"}}, form)
+
+	// Parameters are validated
+	code, body = do("POST", "/delegator/bond", `{"amount":"1"}`)
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal(`{"error":"missing parameter toAddr"}`, body)
+	code, body = do("POST", "/delegator/bond", `{"amount":"many","toAddr":"0x1"}`)
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal(`{"error":"invalid bigint amount: many"}`, body)
+	code, body = do("POST", "/delegator/bond", `{"amount":"1","toAddr":"0x1","to":"0x2"}`)
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal(`{"error":"unknown parameter to"}`, body)
+	code, body = do("POST", "/delegator/bond", `{"amount":["1"]}`)
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal(`{"error":"invalid value for amount"}`, body)
+	code, _ = do("POST", "/delegator/bond", `{`)
+	assert.Equal(http.StatusBadRequest, code)
+	code, body = do("GET", "/delegator/unbondingLocks?withdrawable=maybe", "")
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal(`{"error":"invalid boolean withdrawable: maybe"}`, body)
+
+	// Errors of the endpoints are returned as JSON
+	code, body = do("POST", "/broadcaster/sender/unlock", "")
+	assert.Equal(http.StatusInternalServerError, code)
+	assert.Equal(`{"error":"could not execute unlock: nope"}`, body)
+	code, body = do("GET", "/delegator", "")
+	assert.Equal(http.StatusNotFound, code)
+	assert.Equal(`{"error":"not available on this node"}`, body)
+
+	// Routing
+	code, body = do("GET", "/foo", "")
+	assert.Equal(http.StatusNotFound, code)
+	assert.Equal(`{"error":"not found"}`, body)
+	req := httptest.NewRequest("DELETE", apiPrefix+"/protocol/round", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(http.StatusMethodNotAllowed, rr.Code)
+	assert.Equal("GET, POST", rr.Header().Get("Allow"))
+
+	// The OpenAPI document is served along with the API
+	code, body = do("GET", "/openapi.json", "")
+	assert.Equal(http.StatusOK, code)
+	var spec map[string]interface{}
+	require.Nil(json.Unmarshal([]byte(body), &spec))
+	assert.Equal("3.0.3", spec["openapi"])
+}
+
+func TestAPIOperations(t *testing.T) {
+	assert := assert.New(t)
+
+	ids := make(map[string]bool)
+	routes := make(map[string]bool)
+	for _, op := range apiOperations {
+		assert.False(ids[op.id], "duplicate operation %v", op.id)
+		ids[op.id] = true
+		route := op.method + " " + op.path
+		assert.False(routes[route], "duplicate route %v", route)
+		routes[route] = true
+		assert.True(op.method == http.MethodGet || op.method == http.MethodPost, "method of %v", op.id)
+		assert.NotEmpty(op.legacy, "legacy endpoint of %v", op.id)
+		for _, p := range op.params {
+			assert.NotEmpty(p.desc, "description of %v of %v", p.name, op.id)
+		}
+	}
+}
+
+// TestOpenAPISpec checks that the committed OpenAPI document, from which the client in
+// apiclient is generated, is up to date. Run with -updateOpenAPI to update it
+func TestOpenAPISpec(t *testing.T) {
+	require := require.New(t)
+
+	spec, err := json.MarshalIndent(openAPISpec(), "", "  ")
+	require.Nil(err)
+	spec = append(spec, '\n')
+	if *updateOpenAPI {
+		require.Nil(ioutil.WriteFile(openAPIFile, spec, 0644))
+	}
+	committed, err := ioutil.ReadFile(openAPIFile)
+	require.Nil(err)
+	require.True(bytes.Equal(committed, spec), "%v is out of date; run `go test ./server -run TestOpenAPISpec -updateOpenAPI` and `go generate ./apiclient`", openAPIFile)
+}
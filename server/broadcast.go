@@ -1,6 +1,7 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -25,6 +26,12 @@ import (
 )
 
 var refreshTimeout = 2500 * time.Millisecond
+
+var errUnsupportedProfiles = errors.New("ErrUnsupportedProfiles")
+
+// The auth token of a session is refreshed when it expires within authTokenRefreshWindow
+var authTokenRefreshWindow = 2 * time.Minute
+
 var maxDuration = (5 * time.Minute)
 var maxDurationSec = maxDuration.Seconds()
 
@@ -32,12 +39,28 @@ var Policy *verification.Policy
 var BroadcastCfg = &BroadcastConfig{}
 var MaxAttempts = 3
 
+// AdaptiveBitrate scales the bitrates of the renditions with the complexity of
+// the segments, if set
+var AdaptiveBitrate *core.AdaptiveBitrate
+
+// KeyframeAlignment encodes the renditions with aligned keyframes and rejects
+// results whose keyframes aren't aligned, if set
+var KeyframeAlignment *core.KeyframeAlignment
+
+// VideoSignatures checks the video signatures of the renditions of sampled segments
+// against their source, and rejects the results that don't match, if set
+var VideoSignatures *verification.SignatureChecker
+
 var getOrchestratorInfoRPC = GetOrchestratorInfo
 var downloadSeg = drivers.GetSegmentData
 
 type BroadcastConfig struct {
-	maxPrice *big.Rat
-	mu       sync.RWMutex
+	maxPrice        *big.Rat
+	freeTickets     bool
+	paymentSegments int
+	// requiredCapabilities are required of orchestrators for every stream
+	requiredCapabilities []core.Capability
+	mu                   sync.RWMutex
 }
 
 func (cfg *BroadcastConfig) MaxPrice() *big.Rat {
@@ -52,6 +75,51 @@ func (cfg *BroadcastConfig) SetMaxPrice(price *big.Rat) {
 	cfg.maxPrice = price
 }
 
+// FreeTickets returns true if free tickets are sent to the orchestrators that don't charge
+// for transcoding
+func (cfg *BroadcastConfig) FreeTickets() bool {
+	cfg.mu.RLock()
+	defer cfg.mu.RUnlock()
+	return cfg.freeTickets
+}
+
+func (cfg *BroadcastConfig) SetFreeTickets(free bool) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.freeTickets = free
+}
+
+// PaymentSegments returns the number of segments that a payment sent to an orchestrator
+// covers, so that the segments that follow a payment are sent without tickets
+func (cfg *BroadcastConfig) PaymentSegments() int {
+	cfg.mu.RLock()
+	defer cfg.mu.RUnlock()
+	if cfg.paymentSegments < 1 {
+		return 1
+	}
+	return cfg.paymentSegments
+}
+
+func (cfg *BroadcastConfig) SetPaymentSegments(segments int) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.paymentSegments = segments
+}
+
+// RequiredCapabilities returns the capabilities that orchestrators must support for every
+// stream, in addition to those needed by the profiles of the stream
+func (cfg *BroadcastConfig) RequiredCapabilities() []core.Capability {
+	cfg.mu.RLock()
+	defer cfg.mu.RUnlock()
+	return cfg.requiredCapabilities
+}
+
+func (cfg *BroadcastConfig) SetRequiredCapabilities(caps []core.Capability) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.requiredCapabilities = caps
+}
+
 type BroadcastSessionsManager struct {
 	// Accessing or changing any of the below requires ownership of this mutex
 	sessLock *sync.Mutex
@@ -67,6 +135,12 @@ type BroadcastSessionsManager struct {
 
 	createSessions func() ([]*BroadcastSession, error)
 	sus            *suspender
+
+	// params of the stream that new sessions are created with
+	params *core.StreamParameters
+
+	// lanes of the orchestrators that segments are dispatched to in turn, if any
+	lanes []parallelLane
 }
 
 func (bsm *BroadcastSessionsManager) selectSession() *BroadcastSession {
@@ -107,6 +181,7 @@ func (bsm *BroadcastSessionsManager) removeSession(session *BroadcastSession) {
 	defer bsm.sessLock.Unlock()
 
 	delete(bsm.sessMap, session.OrchestratorInfo.Transcoder)
+	bsm.unpin(session.OrchestratorInfo.Transcoder)
 }
 
 func (bsm *BroadcastSessionsManager) completeSession(sess *BroadcastSession) {
@@ -120,7 +195,12 @@ func (bsm *BroadcastSessionsManager) completeSession(sess *BroadcastSession) {
 			bsm.sessMap[sess.OrchestratorInfo.Transcoder] = sess
 		}
 
-		bsm.sel.Complete(sess)
+		if !bsm.parkSession(sess) {
+			bsm.sel.Complete(sess)
+		}
+	}
+	if Fleet != nil {
+		Fleet.recordSegment(sess.OrchestratorInfo.GetTranscoder())
 	}
 }
 
@@ -175,16 +255,35 @@ func (bsm *BroadcastSessionsManager) refreshSessions() {
 	bsm.sel.Add(uniqueSessions)
 }
 
+// updateParams sets the params that new sessions are created with. The existing
+// sessions take the params over with their next segment.
+func (bsm *BroadcastSessionsManager) updateParams(params *core.StreamParameters) {
+	bsm.sessLock.Lock()
+	defer bsm.sessLock.Unlock()
+	bsm.params = params
+}
+
+func (bsm *BroadcastSessionsManager) streamParams() *core.StreamParameters {
+	bsm.sessLock.Lock()
+	defer bsm.sessLock.Unlock()
+	return bsm.params
+}
+
 func (bsm *BroadcastSessionsManager) cleanup() {
 	bsm.sessLock.Lock()
 	defer bsm.sessLock.Unlock()
 	bsm.finished = true
+	liveSessionManagers.remove(bsm)
 	bsm.sel.Clear()
 	bsm.sessMap = make(map[string]*BroadcastSession) // prevent segfaults
+	bsm.lanes = newLanes()
 }
 
 func (bsm *BroadcastSessionsManager) suspendOrch(sess *BroadcastSession) {
 	bsm.sus.suspend(sess.OrchestratorInfo.GetTranscoder(), bsm.poolSize/bsm.numOrchs)
+	if Fleet != nil {
+		Fleet.recordFailure(sess.OrchestratorInfo.GetTranscoder())
+	}
 }
 
 func NewSessionManager(node *core.LivepeerNode, params *core.StreamParameters, sel BroadcastSessionsSelector) *BroadcastSessionsManager {
@@ -196,17 +295,20 @@ func NewSessionManager(node *core.LivepeerNode, params *core.StreamParameters, s
 	numOrchs := int(math.Min(poolSize, maxInflight*2))
 	sus := newSuspender()
 	bsm := &BroadcastSessionsManager{
-		mid:     params.ManifestID,
-		sel:     sel,
-		sessMap: make(map[string]*BroadcastSession),
-		createSessions: func() ([]*BroadcastSession, error) {
-			return selectOrchestrator(node, params, numOrchs, sus)
-		},
+		mid:      params.ManifestID,
+		sel:      sel,
+		sessMap:  make(map[string]*BroadcastSession),
 		sessLock: &sync.Mutex{},
 		numOrchs: numOrchs,
 		poolSize: int(poolSize),
 		sus:      sus,
+		params:   params,
+		lanes:    newLanes(),
 	}
+	bsm.createSessions = func() ([]*BroadcastSession, error) {
+		return selectOrchestrator(node, bsm.streamParams(), numOrchs, sus)
+	}
+	liveSessionManagers.add(bsm)
 	bsm.refreshSessions()
 	return bsm
 }
@@ -236,7 +338,7 @@ func selectOrchestrator(n *core.LivepeerNode, params *core.StreamParameters, cou
 		)
 
 		if n.Sender != nil {
-			ticketParams = pmTicketParams(tinfo.TicketParams)
+			ticketParams = pmTicketParams(tinfo.TicketParams, tinfo.PriceInfo)
 			sessionID = n.Sender.StartSession(*ticketParams)
 		}
 
@@ -272,7 +374,15 @@ func selectOrchestrator(n *core.LivepeerNode, params *core.StreamParameters, cou
 	return sessions, nil
 }
 
-func processSegment(cxn *rtmpConnection, seg *stream.HLSSegment) ([]string, error) {
+// processSegment transcodes a segment with params, the params of the stream when the
+// segment arrived, or with the params of the selected session if params are nil
+func processSegment(cxn *rtmpConnection, params *core.StreamParameters, seg *stream.HLSSegment) ([]string, error) {
+	done, ok := startSegment()
+	defer done()
+	if !ok {
+		glog.Errorf("Dropping segment nonce=%d manifestID=%s seqNo=%d: %v", cxn.nonce, cxn.mid, seg.SeqNo, errDraining)
+		return nil, errDraining
+	}
 
 	rtmpStrm := cxn.stream
 	nonce := cxn.nonce
@@ -286,6 +396,7 @@ func processSegment(cxn *rtmpConnection, seg *stream.HLSSegment) ([]string, erro
 	}
 
 	glog.V(common.DEBUG).Infof("Processing segment nonce=%d manifestID=%s seqNo=%d dur=%v", nonce, mid, seg.SeqNo, seg.Duration)
+	cxn.bandwidth.RecordIngress(mid, "", int64(len(seg.Data)))
 	if monitor.Enabled {
 		monitor.SegmentEmerged(nonce, seg.SeqNo, len(BroadcastJobVideoProfiles))
 	}
@@ -307,6 +418,7 @@ func processSegment(cxn *rtmpConnection, seg *stream.HLSSegment) ([]string, erro
 	}
 	if cpl.GetOSSession().IsExternal() {
 		seg.Name = uri // hijack seg.Name to convey the uploaded URI
+		cxn.bandwidth.RecordEgress(mid, "", int64(len(seg.Data)))
 	}
 	err = cpl.InsertHLSSegment(vProfile, seg.SeqNo, uri, seg.Duration)
 	if monitor.Enabled {
@@ -319,6 +431,11 @@ func processSegment(cxn *rtmpConnection, seg *stream.HLSSegment) ([]string, erro
 		}
 	}
 
+	if cxn.order != nil {
+		cxn.order.start(seg.SeqNo)
+		defer cxn.order.done(seg.SeqNo)
+	}
+
 	var sv *verification.SegmentVerifier
 	if Policy != nil {
 		sv = verification.NewSegmentVerifier(Policy)
@@ -327,7 +444,7 @@ func processSegment(cxn *rtmpConnection, seg *stream.HLSSegment) ([]string, erro
 	for i := 0; i < MaxAttempts; i++ {
 		// if fails, retry; rudimentary
 		var urls []string
-		if urls, err = transcodeSegment(cxn, seg, name, sv); err == nil {
+		if urls, err = transcodeSegment(cxn, params, seg, name, sv); err == nil {
 			return urls, nil
 		}
 
@@ -345,12 +462,11 @@ func processSegment(cxn *rtmpConnection, seg *stream.HLSSegment) ([]string, erro
 	return nil, err
 }
 
-func transcodeSegment(cxn *rtmpConnection, seg *stream.HLSSegment, name string,
+func transcodeSegment(cxn *rtmpConnection, params *core.StreamParameters, seg *stream.HLSSegment, name string,
 	verifier *verification.SegmentVerifier) ([]string, error) {
 
 	nonce := cxn.nonce
-	cpl := cxn.pl
-	sess := cxn.sessManager.selectSession()
+	sess := cxn.sessManager.selectSegmentSession(seg.SeqNo)
 	// Return early under a few circumstances:
 	// View-only (non-transcoded) streams or no sessions available
 	if sess == nil {
@@ -364,13 +480,44 @@ func transcodeSegment(cxn *rtmpConnection, seg *stream.HLSSegment, name string,
 		return nil, nil
 	}
 
+	// The profiles of the stream changed since the session was created, so the
+	// segment carries the new profiles if the orchestrator supports them
+	if params != nil && sess.Params != params {
+		if !sess.supports(params) {
+			glog.Infof("Orchestrator does not support the profiles of the stream, removing session nonce=%d manifestID=%s orch=%s", nonce, cxn.mid, sess.OrchestratorInfo.Transcoder)
+			cxn.sessManager.removeSession(sess)
+			return nil, errUnsupportedProfiles
+		}
+		newSess := &BroadcastSession{}
+		*newSess = *sess
+		newSess.Params = params
+		sess = newSess
+	}
+
+	// Adaptive bitrates only change the bitrates that the orchestrator encodes the
+	// segment at, so the playlists keep the profiles of the stream
+	streamParams := sess.Params
+	profiles := streamParams.Profiles
+	if AdaptiveBitrate != nil && cxn.profile != nil {
+		if complexity := core.SegmentComplexity(*cxn.profile, len(seg.Data), seg.Duration); complexity > 0 {
+			newParams := *sess.Params
+			newParams.Profiles = AdaptiveBitrate.Profiles(profiles, complexity)
+			newSess := &BroadcastSession{}
+			*newSess = *sess
+			newSess.Params = &newParams
+			sess = newSess
+			glog.V(common.DEBUG).Infof("Adapted bitrates of segment nonce=%d manifestID=%s seqNo=%d complexity=%.2f percent=%d",
+				nonce, cxn.mid, seg.SeqNo, complexity, AdaptiveBitrate.Percent(complexity))
+		}
+	}
+
 	glog.Infof("Trying to transcode segment nonce=%d seqNo=%d", nonce, seg.SeqNo)
 	if monitor.Enabled {
 		monitor.TranscodeTry(nonce, seg.SeqNo)
 	}
 
-	// storage the orchestrator prefers
-	if ios := sess.OrchestratorOS; ios != nil {
+	// storage the orchestrator prefers, unless the segment is sent inline anyway
+	if ios := sess.OrchestratorOS; ios != nil && !sendsInline(sess, seg) {
 		// XXX handle case when orch expects direct upload
 		uri, err := ios.SaveData(name, seg.Data)
 		if err != nil {
@@ -383,12 +530,27 @@ func transcodeSegment(cxn *rtmpConnection, seg *stream.HLSSegment, name string,
 			return nil, err
 		}
 		seg.Name = uri // hijack seg.Name to convey the uploaded URI
+		cxn.bandwidth.RecordEgress(cxn.mid, "", int64(len(seg.Data)))
+	}
+
+	// Refresh the auth token ahead of its expiry, so that the orchestrator doesn't refuse
+	// segments of long running streams
+	if token := sess.OrchestratorInfo.GetAuthToken(); token != nil && time.Until(time.Unix(token.Expiration, 0)) < authTokenRefreshWindow {
+		glog.V(common.VERBOSE).Infof("Auth token expiring, refreshing for orch=%v", sess.OrchestratorInfo.Transcoder)
+		newSess, err := refreshSession(sess)
+		if err != nil {
+			// The token may still be valid, let the orchestrator decide
+			glog.Errorf("Unable to refresh auth token for orch=%v err=%v", sess.OrchestratorInfo.Transcoder, err)
+		} else {
+			sess = newSess
+		}
 	}
 
 	// send segment to the orchestrator
 	if sess.Sender != nil {
-		if err := sess.Sender.ValidateTicketParams(pmTicketParams(sess.OrchestratorInfo.TicketParams)); err != nil {
-			if err != pm.ErrTicketParamsExpired {
+		if err := sess.Sender.ValidateTicketParams(pmTicketParams(sess.OrchestratorInfo.TicketParams, sess.OrchestratorInfo.PriceInfo)); err != nil {
+			// Retryable errors, i.e. expired ticket params, are fixed by refreshing the params
+			if !pm.Errors.IsRetryable(err) {
 				glog.Error("Invalid ticket params err=", err)
 				cxn.sessManager.suspendOrch(sess)
 				cxn.sessManager.removeSession(sess)
@@ -405,17 +567,6 @@ func transcodeSegment(cxn *rtmpConnection, seg *stream.HLSSegment, name string,
 			sess = newSess
 		}
 	}
-	res, err := SubmitSegment(sess, seg, nonce)
-	if err != nil || res == nil {
-		cxn.sessManager.suspendOrch(sess)
-		cxn.sessManager.removeSession(sess)
-		if res == nil && err == nil {
-			err = errors.New("empty response")
-		}
-		return nil, err
-	}
-
-	cxn.sessManager.completeSession(updateSession(sess, res))
 
 	// download transcoded segments from the transcoder
 	gotErr := false // only send one error msg per segment list
@@ -429,22 +580,39 @@ func transcodeSegment(cxn *rtmpConnection, seg *stream.HLSSegment, name string,
 		}
 	}
 
+	// The renditions of clipped segments only last as long as the clip
+	duration := seg.Duration
+	if streamParams.Clip != nil {
+		duration = streamParams.Clip.Duration(seg.Duration)
+	}
+	insert := func(i int, url string) {
+		err := cxn.insertRendition(&profiles[i], seg.SeqNo, url, duration)
+		if err != nil {
+			// InsertHLSSegment only returns ErrSegmentAlreadyExists error
+			// Right now InsertHLSSegment call is atomic regarding transcoded segments - we either inserting
+			// all the transcoded segments or none, so we shouldn't hit this error
+			// But report in case that InsertHLSSegment changed or something wrong is going on in other parts of workflow
+			glog.Errorf("Playlist insertion error nonce=%d manifestID=%s seqNo=%d err=%s", nonce, cxn.mid, seg.SeqNo, err)
+			if monitor.Enabled {
+				monitor.SegmentTranscodeFailed(monitor.SegmentTranscodeErrorPlaylist, nonce, seg.SeqNo, err, false)
+			}
+		}
+	}
+
 	var dlErr error
-	segData := make([][]byte, len(res.Segments))
-	n := len(res.Segments)
-	segURLs := make([]string, len(res.Segments))
+	numProfiles := len(sess.Params.Profiles)
+	segData := make([][]byte, numProfiles)
+	segURLs := make([]string, numProfiles)
 	segLock := &sync.Mutex{}
-	cond := sync.NewCond(segLock)
+	var dlWg sync.WaitGroup
+	// Renditions are published as soon as they are downloaded, unless they are
+	// all needed first to check them. Note that renditions sent ahead of a failed
+	// result stay published
+	publishEarly := verifier == nil && Quality == nil && PixelChecker == nil && ReceiptStore == nil && KeyframeAlignment == nil && VideoSignatures == nil && cxn.order == nil
+	published := make([]bool, numProfiles)
 
 	dlFunc := func(url string, pixels int64, i int) {
-		defer func() {
-			cond.L.Lock()
-			n--
-			if n == 0 {
-				cond.Signal()
-			}
-			cond.L.Unlock()
-		}()
+		defer dlWg.Done()
 
 		bos := sess.BroadcasterOS
 		profile := sess.Params.Profiles[i]
@@ -453,7 +621,8 @@ func transcodeSegment(cxn *rtmpConnection, seg *stream.HLSSegment, name string,
 		// Download segment data in the following cases:
 		// - A verification policy is set. The segment data is needed for signature verification and/or pixel count verification
 		// - The segment data needs to be uploaded to the broadcaster's own OS
-		if verifier != nil || (bos != nil && !drivers.IsOwnExternal(url)) {
+		// - Quality scoring, pixel count checks, transcode receipts, keyframe alignment or video signatures are enabled
+		if verifier != nil || Quality != nil || PixelChecker != nil || ReceiptStore != nil || KeyframeAlignment != nil || VideoSignatures != nil || (bos != nil && !drivers.IsOwnExternal(url)) {
 			d, err := downloadSeg(url)
 			if err != nil {
 				errFunc(monitor.SegmentTranscodeErrorDownload, url, err)
@@ -466,6 +635,7 @@ func transcodeSegment(cxn *rtmpConnection, seg *stream.HLSSegment, name string,
 			}
 
 			data = d
+			cxn.bandwidth.RecordIngress(cxn.mid, sess.OrchestratorInfo.Transcoder, int64(len(d)))
 		}
 
 		if bos != nil && !drivers.IsOwnExternal(url) {
@@ -485,6 +655,9 @@ func transcodeSegment(cxn *rtmpConnection, seg *stream.HLSSegment, name string,
 				}
 				return
 			}
+			if bos.IsExternal() {
+				cxn.bandwidth.RecordEgress(cxn.mid, "", int64(len(data)))
+			}
 			url = newURL
 		}
 
@@ -498,23 +671,129 @@ func transcodeSegment(cxn *rtmpConnection, seg *stream.HLSSegment, name string,
 		segData[i] = data
 		segLock.Unlock()
 
+		if publishEarly {
+			insert(i, url)
+			segLock.Lock()
+			published[i] = true
+			segLock.Unlock()
+		}
+
 		if monitor.Enabled {
 			monitor.TranscodedSegmentAppeared(nonce, seg.SeqNo, profile.Name)
 		}
 	}
 
-	for i, v := range res.Segments {
-		go dlFunc(v.Url, v.Pixels, i)
+	// Renditions sent ahead of the result are downloaded right away
+	started := make([]bool, numProfiles)
+	startDl := func(i int, rendition *net.TranscodedSegmentData) {
+		segLock.Lock()
+		defer segLock.Unlock()
+		if i < 0 || i >= numProfiles || started[i] {
+			return
+		}
+		started[i] = true
+		dlWg.Add(1)
+		go dlFunc(rendition.Url, rendition.Pixels, i)
 	}
 
-	cond.L.Lock()
-	for n != 0 {
-		cond.Wait()
+	if seg.Name == "" || sendsInline(sess, seg) {
+		// Segments sent through object storage were counted when they were uploaded
+		cxn.bandwidth.RecordEgress(cxn.mid, sess.OrchestratorInfo.Transcoder, int64(len(seg.Data)))
 	}
-	cond.L.Unlock()
+	res, err := submitSegment(sess, seg, nonce, startDl)
+	if err != nil || res == nil {
+		dlWg.Wait()
+		if common.ErrorCodeOf(err) == common.ErrCodeIngestAuthToken {
+			// The orchestrator doesn't accept the token anymore, e.g. since it restarted. Get a
+			// new token rather than dropping the orchestrator, the segment is retried
+			if newSess, rerr := refreshSession(sess); rerr == nil {
+				cxn.sessManager.completeSession(newSess)
+				return nil, err
+			}
+		}
+		cxn.sessManager.suspendOrch(sess)
+		cxn.sessManager.removeSession(sess)
+		if res == nil && err == nil {
+			err = errors.New("empty response")
+		}
+		return nil, err
+	}
+
+	// Sessions return to the pool with the profiles of the stream rather than the
+	// adapted bitrates, which would be adapted again
+	completed := updateSession(sess, res)
+	completed.Params = streamParams
+	if update := res.PriceUpdate; update != nil {
+		// The orchestrator changed its price or ticket params during the session. Pay the new
+		// price for the following segments if it's acceptable, or select another orchestrator
+		err := validatePriceInfo(update.PriceInfo)
+		if err == nil {
+			info := &net.OrchestratorInfo{PriceInfo: update.PriceInfo, CapabilityPrices: sess.OrchestratorInfo.GetCapabilityPrices()}
+			err = validateCapabilityPrices(info, streamParams.Profiles)
+		}
+		if err != nil {
+			glog.Warningf("Rejecting price update from orch=%v reason=%v err=%v", sess.OrchestratorInfo.Transcoder, update.Reason, err)
+			cxn.sessManager.removeSession(sess)
+		} else {
+			glog.V(common.VERBOSE).Infof("Accepting price update from orch=%v reason=%v price=%v", sess.OrchestratorInfo.Transcoder, update.Reason, update.PriceInfo)
+			cxn.sessManager.completeSession(completed)
+		}
+	} else {
+		cxn.sessManager.completeSession(completed)
+	}
+
+	for i, v := range res.Segments {
+		startDl(i, v)
+	}
+	dlWg.Wait()
 	if dlErr != nil {
 		return nil, dlErr
 	}
+	if len(res.Segments) < numProfiles {
+		segData, segURLs = segData[:len(res.Segments)], segURLs[:len(res.Segments)]
+	}
+
+	// Record the segment before it is verified, since the orchestrator
+	// covers every segment it delivers in its receipts
+	if ReceiptStore != nil {
+		recordReceipt(cxn, sess, seg.SeqNo, res.TranscodeData, segData)
+	}
+
+	// Cheap sanity checks of the durations, pixel counts and perceptual hashes that the
+	// orchestrator reported, which don't need the renditions themselves
+	sanityParams := &verification.Params{
+		ManifestID:   sess.Params.ManifestID,
+		Source:       seg,
+		Profiles:     sess.Params.Profiles,
+		Orchestrator: sess.OrchestratorInfo,
+		Results:      res.TranscodeData,
+	}
+	if err := verification.CheckRenditions(sanityParams); err != nil {
+		glog.Errorf("Error checking renditions nonce=%d manifestID=%s seqNo=%d orch=%s err=%s", nonce, cxn.mid, seg.SeqNo, sess.OrchestratorInfo.Transcoder, err)
+		cxn.sessManager.removeSession(sess)
+		penalizeOrch(cxn.sessManager, sess, err)
+		return nil, err
+	}
+	if KeyframeAlignment != nil {
+		sanityParams.Renditions = segData
+		if err := verification.CheckKeyframes(sanityParams, KeyframeAlignment.GOP); err != nil {
+			glog.Errorf("Error checking keyframes nonce=%d manifestID=%s seqNo=%d orch=%s err=%s", nonce, cxn.mid, seg.SeqNo, sess.OrchestratorInfo.Transcoder, err)
+			cxn.sessManager.removeSession(sess)
+			penalizeOrch(cxn.sessManager, sess, err)
+			return nil, err
+		}
+	}
+	// The signatures are computed from the downloaded renditions, since the orchestrator
+	// could report any signature
+	if VideoSignatures != nil && VideoSignatures.Sample(sanityParams) {
+		sanityParams.Renditions = segData
+		if err := VideoSignatures.Check(sanityParams); err != nil {
+			glog.Errorf("Error checking video signatures nonce=%d manifestID=%s seqNo=%d orch=%s err=%s", nonce, cxn.mid, seg.SeqNo, sess.OrchestratorInfo.Transcoder, err)
+			cxn.sessManager.removeSession(sess)
+			penalizeOrch(cxn.sessManager, sess, err)
+			return nil, err
+		}
+	}
 
 	if verifier != nil {
 		// verify potentially can change content of segURLs
@@ -525,17 +804,19 @@ func transcodeSegment(cxn *rtmpConnection, seg *stream.HLSSegment, name string,
 		}
 	}
 
+	if Quality != nil {
+		scoreQuality(cxn, sess, seg, segData)
+	}
+	if PixelChecker != nil {
+		checkPixels(cxn, sess, seg, res.TranscodeData, segData)
+	}
+
+	if cxn.order != nil {
+		cxn.order.wait(seg.SeqNo)
+	}
 	for i, url := range segURLs {
-		err := cpl.InsertHLSSegment(&sess.Params.Profiles[i], seg.SeqNo, url, seg.Duration)
-		if err != nil {
-			// InsertHLSSegment only returns ErrSegmentAlreadyExists error
-			// Right now InsertHLSSegment call is atomic regarding transcoded segments - we either inserting
-			// all the transcoded segments or none, so we shouldn't hit this error
-			// But report in case that InsertHLSSegment changed or something wrong is going on in other parts of workflow
-			glog.Errorf("Playlist insertion error nonce=%d manifestID=%s seqNo=%d err=%s", nonce, cxn.mid, seg.SeqNo, err)
-			if monitor.Enabled {
-				monitor.SegmentTranscodeFailed(monitor.SegmentTranscodeErrorPlaylist, nonce, seg.SeqNo, err, false)
-			}
+		if !published[i] {
+			insert(i, url)
 		}
 	}
 
@@ -582,6 +863,10 @@ func verify(verifier *verification.SegmentVerifier, cxn *rtmpConnection,
 		// Remove the O from the working set for now
 		// Error falls through towards end if necessary
 		cxn.sessManager.removeSession(sess)
+		// Suspend the O if it keeps failing verification
+		penalizeOrch(cxn.sessManager, sess, err)
+	} else if err == nil {
+		penalizeOrch(cxn.sessManager, sess, nil)
 	}
 	if accepted != nil {
 		// The returned set of results has been accepted by the verifier
@@ -620,6 +905,16 @@ func verify(verifier *verification.SegmentVerifier, cxn *rtmpConnection,
 	return err // possibly nil
 }
 
+// supports checks whether the orchestrator of the session supports the capabilities of
+// params, as discovery does. Orchestrators without capabilities only transcode jobs
+// that need legacy features.
+func (s *BroadcastSession) supports(params *core.StreamParameters) bool {
+	if s.OrchestratorInfo.Capabilities == nil {
+		return params.Capabilities.LegacyOnly()
+	}
+	return params.Capabilities.CompatibleWith(s.OrchestratorInfo.Capabilities)
+}
+
 // Return an updated copy of the given session using the received transcode result
 func updateSession(sess *BroadcastSession, res *ReceivedTranscodeResult) *BroadcastSession {
 	// Instead of mutating the existing session we copy it and return an updated copy
@@ -645,21 +940,25 @@ func updateSession(sess *BroadcastSession, res *ReceivedTranscodeResult) *Broadc
 		// and the next time this BroadcastSession is used, the ticket params will be validated
 		// during ticket creation in genPayment(). If ticket params validation during ticket
 		// creation fails, then this BroadcastSession will be removed
-		newSess.PMSessionID = newSess.Sender.StartSession(*pmTicketParams(oInfo.TicketParams))
+		newSess.PMSessionID = newSess.Sender.StartSession(*pmTicketParams(oInfo.TicketParams, oInfo.PriceInfo))
 	}
 
 	return newSess
 }
 
 func refreshSession(sess *BroadcastSession) (*BroadcastSession, error) {
-	uri, err := url.Parse(sess.OrchestratorInfo.Transcoder)
-	if err != nil {
-		return nil, err
+	var (
+		oInfo *net.OrchestratorInfo
+		err   error
+	)
+	// Fall back to the other URIs of the transcoder if it can't be reached
+	for _, transcoder := range sess.transcoderURIs() {
+		oInfo, err = refreshInfo(sess, transcoder)
+		if err == nil {
+			break
+		}
+		glog.Errorf("Unable to refresh session from orch=%s err=%v", transcoder, err)
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
-	defer cancel()
-
-	oInfo, err := getOrchestratorInfoRPC(ctx, sess.Broadcaster, uri)
 	if err != nil {
 		return nil, err
 	}
@@ -672,3 +971,24 @@ func refreshSession(sess *BroadcastSession) (*BroadcastSession, error) {
 
 	return updateSession(sess, res), nil
 }
+
+// refreshInfo gets the OrchestratorInfo of the session from the transcoder URI, which
+// must belong to the same orchestrator as the session
+func refreshInfo(sess *BroadcastSession, transcoder string) (*net.OrchestratorInfo, error) {
+	uri, err := url.Parse(transcoder)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+
+	oInfo, err := getOrchestratorInfoRPC(ctx, sess.Broadcaster, uri)
+	if err != nil {
+		return nil, err
+	}
+	addr := sess.OrchestratorInfo.GetAddress()
+	if len(addr) > 0 && len(oInfo.GetAddress()) > 0 && !bytes.Equal(addr, oInfo.GetAddress()) {
+		return nil, fmt.Errorf("orchestrator address mismatch at %v", transcoder)
+	}
+	return oInfo, nil
+}
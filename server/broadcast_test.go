@@ -2,11 +2,16 @@ package server
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/big"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -151,6 +156,10 @@ func (pm *stubPlaylistManager) GetHLSMediaPlaylist(rendition string) *m3u8.Media
 	return nil
 }
 
+func (pm *stubPlaylistManager) RemoveHLSRendition(rendition string) {}
+
+func (pm *stubPlaylistManager) EndHLSPlaylists() {}
+
 func (pm *stubPlaylistManager) GetOSSession() drivers.OSSession {
 	return pm.os
 }
@@ -503,7 +512,7 @@ func TestTranscodeSegment_UploadFailed_SuspendAndRemove(t *testing.T) {
 		sessManager: bsm,
 	}
 	seg := &stream.HLSSegment{}
-	_, err := transcodeSegment(cxn, seg, "dummy", nil)
+	_, err := transcodeSegment(cxn, cxn.params, seg, "dummy", nil)
 	assert.EqualError(err, "some error")
 	_, ok := cxn.sessManager.sessMap[sess.OrchestratorInfo.GetTranscoder()]
 	assert.False(ok)
@@ -559,7 +568,7 @@ func TestTranscodeSegment_ExpiredParams_GetOrchestratorInfoAndRetry(t *testing.T
 
 	// Validate TicketParams error (not ErrTicketParamsExpired) -> Don't refresh, remove session & suspend orch
 	sender.On("ValidateTicketParams", mock.Anything).Return(errors.New("some error")).Once()
-	_, err = transcodeSegment(cxn, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+	_, err = transcodeSegment(cxn, cxn.params, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
 	assert.True(strings.Contains(err.Error(), "some error"))
 	_, ok := cxn.sessManager.sessMap[ts.URL]
 	assert.False(ok)
@@ -574,7 +583,7 @@ func TestTranscodeSegment_ExpiredParams_GetOrchestratorInfoAndRetry(t *testing.T
 	}
 	// Expired Orchestrator Info -> GetOrchestratorInfo error -> Error
 	sender.On("ValidateTicketParams", mock.Anything).Return(pm.ErrTicketParamsExpired)
-	_, err = transcodeSegment(cxn, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+	_, err = transcodeSegment(cxn, cxn.params, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
 	assert.True(strings.Contains(err.Error(), "unable to refresh ticket params"))
 	_, ok = cxn.sessManager.sessMap[ts.URL]
 	assert.False(ok)
@@ -610,7 +619,7 @@ func TestTranscodeSegment_ExpiredParams_GetOrchestratorInfoAndRetry(t *testing.T
 	balance.On("StageUpdate", mock.Anything, mock.Anything).Return(1, big.NewRat(100, 1), big.NewRat(100, 1))
 	sender.On("CreateTicketBatch", mock.Anything, mock.Anything).Return(nil, pm.ErrTicketParamsExpired).Once()
 	balance.On("Credit", mock.Anything)
-	_, err = transcodeSegment(cxn, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+	_, err = transcodeSegment(cxn, cxn.params, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
 	assert.EqualError(err, pm.ErrTicketParamsExpired.Error())
 	_, ok = cxn.sessManager.sessMap[ts.URL]
 	assert.False(ok)
@@ -627,7 +636,7 @@ func TestTranscodeSegment_ExpiredParams_GetOrchestratorInfoAndRetry(t *testing.T
 
 	sender.On("ValidateTicketParams", mock.Anything).Return(nil)
 	sender.On("CreateTicketBatch", mock.Anything, mock.Anything).Return(defaultTicketBatch(), nil).Once()
-	_, err = transcodeSegment(cxn, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+	_, err = transcodeSegment(cxn, cxn.params, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
 	assert.Nil(err)
 
 	completedSess := cxn.sessManager.sessMap[ts.URL]
@@ -642,6 +651,112 @@ func TestTranscodeSegment_ExpiredParams_GetOrchestratorInfoAndRetry(t *testing.T
 	assert.Equal(tr.Info.TicketParams.ExpirationBlock, completedSessInfo.TicketParams.ExpirationBlock)
 }
 
+func TestTranscodeSegment_RefreshAuthToken(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	ts, mux := stubTLSServer()
+	defer ts.Close()
+
+	tr := &net.TranscodeResult{
+		Result: &net.TranscodeResult_Data{
+			Data: &net.TranscodeData{
+				Segments: []*net.TranscodedSegmentData{{Url: "test.flv"}},
+				Sig:      []byte("bar"),
+			},
+		},
+	}
+	buf, err := proto.Marshal(tr)
+	require.Nil(err)
+
+	var tokens []string
+	mux.HandleFunc("/segment", func(w http.ResponseWriter, r *http.Request) {
+		data, err := base64.StdEncoding.DecodeString(r.Header.Get(segmentHeader))
+		require.Nil(err)
+		var segData net.SegData
+		require.Nil(proto.Unmarshal(data, &segData))
+		tokens = append(tokens, string(segData.AuthToken.GetToken()))
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf)
+	})
+
+	expiring := &net.AuthToken{Token: []byte("expiring"), SessionId: "sess", Expiration: time.Now().Add(time.Minute).Unix()}
+	refreshed := &net.AuthToken{Token: []byte("refreshed"), SessionId: "sess2", Expiration: time.Now().Add(time.Hour).Unix()}
+	oldGetOrchestratorInfoRPC := getOrchestratorInfoRPC
+	defer func() { getOrchestratorInfoRPC = oldGetOrchestratorInfoRPC }()
+	var refreshErr error
+	getOrchestratorInfoRPC = func(ctx context.Context, bcast common.Broadcaster, orchestratorServer *url.URL) (*net.OrchestratorInfo, error) {
+		return &net.OrchestratorInfo{Transcoder: ts.URL, AuthToken: refreshed}, refreshErr
+	}
+
+	transcode := func(token *net.AuthToken) {
+		sess := StubBroadcastSession(ts.URL)
+		sess.Params.Profiles = []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9}
+		sess.OrchestratorInfo.AuthToken = token
+		cxn := &rtmpConnection{
+			mid:         core.ManifestID("foo"),
+			pl:          &stubPlaylistManager{manifestID: core.ManifestID("foo")},
+			profile:     &ffmpeg.P144p30fps16x9,
+			sessManager: bsmWithSessList([]*BroadcastSession{sess}),
+		}
+		_, err := transcodeSegment(cxn, cxn.params, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+		assert.Nil(err)
+	}
+
+	// Tokens about to expire are refreshed before the segment is sent
+	transcode(expiring)
+	// but not the others
+	transcode(refreshed)
+	// Segments are still sent with the current token if the refresh fails
+	refreshErr = errors.New("GetOrchestrator error")
+	transcode(expiring)
+	assert.Equal([]string{"refreshed", "refreshed", "expiring"}, tokens)
+}
+
+func TestTranscodeSegment_RejectedAuthToken(t *testing.T) {
+	assert := assert.New(t)
+
+	ts, mux := stubTLSServer()
+	defer ts.Close()
+	mux.HandleFunc("/segment", func(w http.ResponseWriter, r *http.Request) {
+		httpErrorWithCode(w, errAuthTokenInvalid.Error(), http.StatusForbidden, common.ErrCodeIngestAuthToken)
+	})
+
+	refreshed := &net.AuthToken{Token: []byte("refreshed"), SessionId: "sess2", Expiration: time.Now().Add(time.Hour).Unix()}
+	oldGetOrchestratorInfoRPC := getOrchestratorInfoRPC
+	defer func() { getOrchestratorInfoRPC = oldGetOrchestratorInfoRPC }()
+	var refreshErr error
+	getOrchestratorInfoRPC = func(ctx context.Context, bcast common.Broadcaster, orchestratorServer *url.URL) (*net.OrchestratorInfo, error) {
+		return &net.OrchestratorInfo{Transcoder: ts.URL, AuthToken: refreshed}, refreshErr
+	}
+
+	sess := StubBroadcastSession(ts.URL)
+	sess.Params.Profiles = []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9}
+	sess.OrchestratorInfo.AuthToken = &net.AuthToken{Token: []byte("stale"), SessionId: "sess", Expiration: time.Now().Add(time.Hour).Unix()}
+	bsm := bsmWithSessList([]*BroadcastSession{sess})
+	cxn := &rtmpConnection{
+		mid:         core.ManifestID("foo"),
+		pl:          &stubPlaylistManager{manifestID: core.ManifestID("foo")},
+		profile:     &ffmpeg.P144p30fps16x9,
+		sessManager: bsm,
+	}
+
+	// Sessions whose token is refused, e.g. by a restarted orchestrator, get a new token
+	_, err := transcodeSegment(cxn, cxn.params, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+	assert.Equal(common.ErrCodeIngestAuthToken, common.ErrorCodeOf(err))
+	assert.Zero(bsm.sus.Suspended(ts.URL))
+	completed, ok := bsm.sessMap[ts.URL]
+	assert.True(ok)
+	assert.Equal(refreshed, completed.OrchestratorInfo.AuthToken)
+
+	// and are only dropped if the token can't be refreshed
+	refreshErr = errors.New("GetOrchestrator error")
+	_, err = transcodeSegment(cxn, cxn.params, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+	assert.Equal(common.ErrCodeIngestAuthToken, common.ErrorCodeOf(err))
+	_, ok = bsm.sessMap[ts.URL]
+	assert.False(ok)
+}
+
 func TestTranscodeSegment_SuspendOrchestrator(t *testing.T) {
 	require := require.New(t)
 	assert := assert.New(t)
@@ -682,7 +797,7 @@ func TestTranscodeSegment_SuspendOrchestrator(t *testing.T) {
 		sessManager: bsm,
 	}
 
-	_, err = transcodeSegment(cxn, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+	_, err = transcodeSegment(cxn, cxn.params, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
 
 	assert.EqualError(err, "OrchestratorBusy")
 	assert.Equal(bsm.sus.Suspended(ts.URL), bsm.poolSize/bsm.numOrchs)
@@ -722,7 +837,7 @@ func TestTranscodeSegment_CompleteSession(t *testing.T) {
 		sessManager: bsm,
 	}
 
-	_, err = transcodeSegment(cxn, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+	_, err = transcodeSegment(cxn, cxn.params, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
 	assert.Nil(err)
 
 	completedSess := bsm.sessMap[ts.URL]
@@ -739,7 +854,7 @@ func TestTranscodeSegment_CompleteSession(t *testing.T) {
 	buf, err = proto.Marshal(tr)
 	require.Nil(err)
 
-	_, err = transcodeSegment(cxn, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+	_, err = transcodeSegment(cxn, cxn.params, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
 	assert.Nil(err)
 
 	// Check that BroadcastSession.OrchestratorInfo was updated
@@ -749,6 +864,468 @@ func TestTranscodeSegment_CompleteSession(t *testing.T) {
 	assert.Equal(tr.Info.PriceInfo.PixelsPerUnit, completedSessInfo.PriceInfo.PixelsPerUnit)
 }
 
+func TestTranscodeSegment_PriceUpdate(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	ts, mux := stubTLSServer()
+	defer ts.Close()
+	var buf []byte
+	mux.HandleFunc("/segment", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf)
+	})
+
+	defer BroadcastCfg.SetMaxPrice(nil)
+	BroadcastCfg.SetMaxPrice(big.NewRat(5, 1))
+
+	transcode := func(price int64) *BroadcastSessionsManager {
+		info := &net.OrchestratorInfo{Transcoder: ts.URL, PriceInfo: &net.PriceInfo{PricePerUnit: price, PixelsPerUnit: 1}}
+		tr := &net.TranscodeResult{
+			Result: &net.TranscodeResult_Data{
+				Data: &net.TranscodeData{
+					Segments: []*net.TranscodedSegmentData{{Url: "test.flv"}},
+					Sig:      []byte("bar"),
+				},
+			},
+			Info:        info,
+			PriceUpdate: &net.PriceUpdate{Reason: net.PriceUpdate_PRICE, PriceInfo: info.PriceInfo},
+		}
+		var err error
+		buf, err = proto.Marshal(tr)
+		require.Nil(err)
+
+		sess := StubBroadcastSession(ts.URL)
+		sess.Params.Profiles = []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9}
+		bsm := bsmWithSessList([]*BroadcastSession{sess})
+		cxn := &rtmpConnection{
+			mid:         core.ManifestID("foo"),
+			pl:          &stubPlaylistManager{manifestID: core.ManifestID("foo")},
+			profile:     &ffmpeg.P144p30fps16x9,
+			sessManager: bsm,
+		}
+		_, err = transcodeSegment(cxn, cxn.params, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+		assert.Nil(err)
+		return bsm
+	}
+
+	// Acceptable updates are applied to the session
+	bsm := transcode(5)
+	require.Contains(bsm.sessMap, ts.URL)
+	assert.Equal(int64(5), bsm.sessMap[ts.URL].OrchestratorInfo.PriceInfo.PricePerUnit)
+
+	// Updates above the max price remove the session, so that another orchestrator is selected
+	bsm = transcode(6)
+	assert.NotContains(bsm.sessMap, ts.URL)
+	assert.Zero(bsm.sus.Suspended(ts.URL))
+}
+
+func TestTranscodeSegment_CheckRenditions(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	defer func() { orchFailures = &verificationFailures{failures: make(map[string]int)} }()
+	orchFailures = &verificationFailures{failures: make(map[string]int)}
+
+	ts, mux := stubTLSServer()
+	defer ts.Close()
+	tr := &net.TranscodeResult{
+		Result: &net.TranscodeResult_Data{
+			Data: &net.TranscodeData{
+				// Much shorter than the source
+				Segments: []*net.TranscodedSegmentData{{Url: "test.flv", Duration: 500}},
+				Sig:      []byte("bar"),
+			},
+		},
+		Info: &net.OrchestratorInfo{Transcoder: ts.URL},
+	}
+	buf, err := proto.Marshal(tr)
+	require.Nil(err)
+	mux.HandleFunc("/segment", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf)
+	})
+
+	sess := StubBroadcastSession(ts.URL)
+	sess.Params.Profiles = []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9}
+	bsm := bsmWithSessList([]*BroadcastSession{sess})
+	pl := &stubPlaylistManager{manifestID: core.ManifestID("foo")}
+	cxn := &rtmpConnection{
+		mid:         core.ManifestID("foo"),
+		pl:          pl,
+		profile:     &ffmpeg.P144p30fps16x9,
+		sessManager: bsm,
+	}
+	_, err = transcodeSegment(cxn, cxn.params, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+	assert.Equal(verification.ErrRenditionMismatch, err)
+	assert.NotContains(bsm.sessMap, ts.URL)
+	assert.Equal(1, orchFailures.failures[ts.URL])
+}
+
+// stubSignatureFFmpeg stands in for ffmpeg: it writes the signature of a single frame
+// of valid segments, and fails to sign the others
+const stubSignatureFFmpeg = `#!/bin/sh
+while [ $# -gt 1 ]; do
+	case "$1" in
+	-i) in="$2" ;;
+	-vf) out="${2#*filename=}" ;;
+	esac
+	shift
+done
+grep -q garbage "$in" && exit 1
+head -c 121 /dev/zero > "$out"
+`
+
+func TestTranscodeSegment_CheckVideoSignatures(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	defer func() { orchFailures = &verificationFailures{failures: make(map[string]int)} }()
+	orchFailures = &verificationFailures{failures: make(map[string]int)}
+
+	tmpdir, err := ioutil.TempDir("", "")
+	require.Nil(err)
+	defer os.RemoveAll(tmpdir)
+	defer func(cmd string) { core.FFmpegCommand = cmd }(core.FFmpegCommand)
+	core.FFmpegCommand = filepath.Join(tmpdir, "ffmpeg")
+	require.Nil(ioutil.WriteFile(core.FFmpegCommand, []byte(stubSignatureFFmpeg), 0755))
+	defer func(workDir string) { core.WorkDir = workDir }(core.WorkDir)
+	core.WorkDir = tmpdir
+	VideoSignatures = verification.NewSignatureChecker(1)
+	defer func() { VideoSignatures = nil }()
+
+	renditions := map[string][]byte{}
+	oldDownloadSeg := downloadSeg
+	defer func() { downloadSeg = oldDownloadSeg }()
+	downloadSeg = func(url string) ([]byte, error) { return renditions[url], nil }
+
+	ts, mux := stubTLSServer()
+	defer ts.Close()
+	mux.HandleFunc("/segment", func(w http.ResponseWriter, r *http.Request) {
+		buf, err := proto.Marshal(&net.TranscodeResult{
+			Result: &net.TranscodeResult_Data{Data: &net.TranscodeData{Segments: []*net.TranscodedSegmentData{{Url: "P144p30fps16x9.ts"}}, Sig: []byte("bar")}},
+		})
+		require.Nil(err)
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf)
+	})
+
+	newCxn := func() (*rtmpConnection, *BroadcastSessionsManager) {
+		sess := StubBroadcastSession(ts.URL)
+		sess.Params.Profiles = []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9}
+		bsm := bsmWithSessList([]*BroadcastSession{sess})
+		return &rtmpConnection{
+			mid:         core.ManifestID("foo"),
+			pl:          &stubPlaylistManager{os: &stubOSSession{}},
+			profile:     &ffmpeg.P144p30fps16x9,
+			sessManager: bsm,
+		}, bsm
+	}
+	seg := &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}
+
+	renditions["P144p30fps16x9.ts"] = []byte("rendition")
+	cxn, bsm := newCxn()
+	urls, err := transcodeSegment(cxn, nil, seg, "dummy", nil)
+	require.Nil(err)
+	assert.Len(urls, 1)
+	assert.Contains(bsm.sessMap, ts.URL)
+
+	// the renditions are signed by the broadcaster, so garbage is rejected whatever the
+	// orchestrator reports
+	renditions["P144p30fps16x9.ts"] = []byte("garbage")
+	cxn, bsm = newCxn()
+	_, err = transcodeSegment(cxn, nil, seg, "dummy", nil)
+	assert.Equal(verification.ErrVideoSignatureMismatch, err)
+	assert.NotContains(bsm.sessMap, ts.URL)
+	assert.Equal(1, orchFailures.failures[ts.URL])
+
+	// the signature files are removed
+	files, err := ioutil.ReadDir(tmpdir)
+	require.Nil(err)
+	assert.Len(files, 1)
+}
+
+// insertNotifier sends the names of the renditions of the segments inserted into the playlist
+type insertNotifier struct {
+	stubPlaylistManager
+	inserted chan string
+}
+
+func (pm *insertNotifier) InsertHLSSegment(profile *ffmpeg.VideoProfile, seqNo uint64, uri string, duration float64) error {
+	pm.inserted <- profile.Name
+	return nil
+}
+
+func TestTranscodeSegment_StreamRenditions(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	pl := &insertNotifier{inserted: make(chan string, 2)}
+	var early []string
+	ts, mux := stubTLSServer()
+	defer ts.Close()
+	mux.HandleFunc("/segment", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("multipart/mixed", r.Header.Get("Accept"))
+		mw := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+		w.WriteHeader(http.StatusOK)
+		segs := []*net.TranscodedSegmentData{{Url: "P144p30fps16x9.ts"}, {Url: "P240p30fps16x9.ts"}}
+		require.Nil(writeResponsePart(mw, renditionContentType, segs[1], "1"))
+		w.(http.Flusher).Flush()
+
+		// The rendition is published before the result is sent
+		select {
+		case name := <-pl.inserted:
+			early = append(early, name)
+		case <-time.After(time.Second):
+		}
+
+		tr := &net.TranscodeResult{
+			Result: &net.TranscodeResult_Data{Data: &net.TranscodeData{Segments: segs, Sig: []byte("bar")}},
+			Info:   &net.OrchestratorInfo{Transcoder: ts.URL},
+		}
+		require.Nil(writeResponsePart(mw, transcodeResultContentType, tr, ""))
+		mw.Close()
+	})
+
+	sess := StubBroadcastSession(ts.URL)
+	sess.Params.Profiles = []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9, ffmpeg.P240p30fps16x9}
+	cxn := &rtmpConnection{
+		mid:         core.ManifestID("foo"),
+		pl:          pl,
+		profile:     &ffmpeg.P144p30fps16x9,
+		sessManager: bsmWithSessList([]*BroadcastSession{sess}),
+	}
+	urls, err := transcodeSegment(cxn, cxn.params, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+	require.Nil(err)
+	assert.Equal([]string{"P144p30fps16x9.ts", "P240p30fps16x9.ts"}, urls)
+	assert.Equal([]string{"P240p30fps16x9"}, early)
+
+	// The other rendition is published with the result, and each rendition only once
+	require.Len(pl.inserted, 1)
+	assert.Equal("P144p30fps16x9", <-pl.inserted)
+}
+
+func TestTranscodeSegment_UpdatedProfiles(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	var profiles []string
+	ts, mux := stubTLSServer()
+	defer ts.Close()
+	mux.HandleFunc("/segment", func(w http.ResponseWriter, r *http.Request) {
+		data, err := base64.StdEncoding.DecodeString(r.Header.Get(segmentHeader))
+		require.Nil(err)
+		var segData net.SegData
+		require.Nil(proto.Unmarshal(data, &segData))
+		md, err := coreSegMetadata(&segData)
+		require.Nil(err)
+		profiles = append(profiles, common.ProfilesNames(md.Profiles))
+
+		var segs []*net.TranscodedSegmentData
+		for _, p := range md.Profiles {
+			segs = append(segs, &net.TranscodedSegmentData{Url: p.Name + ".ts"})
+		}
+		buf, err := proto.Marshal(&net.TranscodeResult{
+			Result: &net.TranscodeResult_Data{Data: &net.TranscodeData{Segments: segs, Sig: []byte("bar")}},
+		})
+		require.Nil(err)
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf)
+	})
+
+	oldParams := &core.StreamParameters{ManifestID: "foo", Profiles: []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9}}
+	caps, err := core.JobCapabilities(oldParams)
+	require.Nil(err)
+	oldParams.Capabilities = caps
+	sess := StubBroadcastSession(ts.URL)
+	sess.Params = oldParams
+	bsm := bsmWithSessList([]*BroadcastSession{sess})
+	cxn := &rtmpConnection{
+		mid:         core.ManifestID("foo"),
+		pl:          &stubPlaylistManager{os: &stubOSSession{}},
+		profile:     &ffmpeg.P144p30fps16x9,
+		sessManager: bsm,
+		params:      oldParams,
+	}
+
+	// The session takes the new profiles over with the next segment
+	newParams := &core.StreamParameters{ManifestID: "foo", Profiles: []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9, ffmpeg.P240p30fps16x9}}
+	caps, err = core.JobCapabilities(newParams)
+	require.Nil(err)
+	newParams.Capabilities = caps
+	urls, err := transcodeSegment(cxn, newParams, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+	require.Nil(err)
+	assert.Equal([]string{"P144p30fps16x9.ts", "P240p30fps16x9.ts"}, urls)
+	assert.Equal([]string{"P144p30fps16x9,P240p30fps16x9"}, profiles)
+	assert.Equal(newParams, bsm.sessMap[ts.URL].Params)
+	// The session is copied rather than mutated
+	assert.Equal(oldParams, sess.Params)
+
+	// Segments that started with the old profiles keep them
+	urls, err = transcodeSegment(cxn, oldParams, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+	require.Nil(err)
+	assert.Equal([]string{"P144p30fps16x9.ts"}, urls)
+	assert.Equal("P144p30fps16x9", profiles[1])
+
+	// Orchestrators that don't support the new profiles are removed
+	hevc := ffmpeg.P240p30fps16x9
+	hevc.Profile = common.ProfileH265Main
+	hevcParams := &core.StreamParameters{ManifestID: "foo", Profiles: []ffmpeg.VideoProfile{hevc}}
+	caps, err = core.JobCapabilities(hevcParams)
+	require.Nil(err)
+	hevcParams.Capabilities = caps
+	_, err = transcodeSegment(cxn, hevcParams, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+	assert.Equal(errUnsupportedProfiles, err)
+	assert.NotContains(bsm.sessMap, ts.URL)
+	assert.Len(profiles, 2)
+}
+
+func TestTranscodeSegment_AdaptiveBitrate(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	defer func() { AdaptiveBitrate = nil }()
+
+	var bitrates []string
+	ts, mux := stubTLSServer()
+	defer ts.Close()
+	mux.HandleFunc("/segment", func(w http.ResponseWriter, r *http.Request) {
+		data, err := base64.StdEncoding.DecodeString(r.Header.Get(segmentHeader))
+		require.Nil(err)
+		var segData net.SegData
+		require.Nil(proto.Unmarshal(data, &segData))
+		md, err := coreSegMetadata(&segData)
+		require.Nil(err)
+		bitrates = append(bitrates, md.Profiles[0].Bitrate)
+
+		buf, err := proto.Marshal(&net.TranscodeResult{
+			Result: &net.TranscodeResult_Data{Data: &net.TranscodeData{Segments: []*net.TranscodedSegmentData{{Url: "test.ts"}}, Sig: []byte("bar")}},
+		})
+		require.Nil(err)
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf)
+	})
+
+	params := &core.StreamParameters{ManifestID: "foo", Profiles: []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9}}
+	sess := StubBroadcastSession(ts.URL)
+	sess.Params = params
+	bsm := bsmWithSessList([]*BroadcastSession{sess})
+	pl := &stubPlaylistManager{os: &stubOSSession{}}
+	cxn := &rtmpConnection{
+		mid:         core.ManifestID("foo"),
+		pl:          pl,
+		profile:     &ffmpeg.P144p30fps16x9,
+		sessManager: bsm,
+		params:      params,
+	}
+	// half of the bits per pixel of a source of average complexity
+	data := make([]byte, int(core.ReferenceBitsPerPixel*256*144*30*2/8/2))
+
+	// Disabled
+	_, err := transcodeSegment(cxn, nil, &stream.HLSSegment{Data: data, Duration: 2.0}, "dummy", nil)
+	require.Nil(err)
+
+	AdaptiveBitrate = &core.AdaptiveBitrate{MinPercent: 25, MaxPercent: 150}
+	_, err = transcodeSegment(cxn, nil, &stream.HLSSegment{Data: data, Duration: 2.0}, "dummy", nil)
+	require.Nil(err)
+	// The playlists and the session pool keep the profiles of the stream
+	assert.Equal("400k", pl.profile.Bitrate)
+	assert.Equal(params, bsm.sessMap[ts.URL].Params)
+
+	// Complex segments are encoded at higher bitrates, within the bounds
+	_, err = transcodeSegment(cxn, nil, &stream.HLSSegment{Data: append(data, data...), Duration: 1.0}, "dummy", nil)
+	require.Nil(err)
+
+	// Segments of unknown complexity keep the bitrates of the profiles
+	_, err = transcodeSegment(cxn, nil, &stream.HLSSegment{Data: data}, "dummy", nil)
+	require.Nil(err)
+	assert.Equal([]string{"400000", "200000", "600000", "400000"}, bitrates)
+}
+
+// tsVideoPacket returns an MPEG-TS packet starting a video frame at pts, flagged
+// as a keyframe if key is set
+func tsVideoPacket(pts int64, key bool) []byte {
+	pkt := make([]byte, 188)
+	copy(pkt, []byte{0x47, 0x41, 0x00, 0x30, 1, 0, 0, 0, 1, 0xe0, 0, 0, 0x80, 0x80, 5})
+	if key {
+		pkt[5] = 0x40
+	}
+	pkt[15] = 0x21 | byte(pts>>29)&0x0e
+	pkt[16], pkt[17] = byte(pts>>22), byte(pts>>14)|1
+	pkt[18], pkt[19] = byte(pts>>7), byte(pts<<1)|1
+	return pkt
+}
+
+func TestTranscodeSegment_KeyframeAlignment(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	KeyframeAlignment = &core.KeyframeAlignment{GOP: time.Second}
+	defer func() { KeyframeAlignment = nil }()
+
+	// keyframes every second, and a rendition whose second keyframe is late
+	aligned := append(append(tsVideoPacket(0, true), tsVideoPacket(90000, true)...), tsVideoPacket(171000, false)...)
+	late := append(append(tsVideoPacket(0, true), tsVideoPacket(90000, false)...), tsVideoPacket(171000, true)...)
+	renditions := map[string][]byte{}
+	oldDownloadSeg := downloadSeg
+	defer func() { downloadSeg = oldDownloadSeg }()
+	downloadSeg = func(url string) ([]byte, error) { return renditions[url], nil }
+
+	ts, mux := stubTLSServer()
+	defer ts.Close()
+	mux.HandleFunc("/segment", func(w http.ResponseWriter, r *http.Request) {
+		buf, err := proto.Marshal(&net.TranscodeResult{
+			Result: &net.TranscodeResult_Data{Data: &net.TranscodeData{Segments: []*net.TranscodedSegmentData{{Url: "P144p30fps16x9.ts"}, {Url: "P240p30fps16x9.ts"}}, Sig: []byte("bar")}},
+		})
+		require.Nil(err)
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf)
+	})
+
+	newCxn := func() (*rtmpConnection, *BroadcastSessionsManager) {
+		sess := StubBroadcastSession(ts.URL)
+		sess.Params.Profiles = KeyframeAlignment.Profiles([]ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9, ffmpeg.P240p30fps16x9})
+		bsm := bsmWithSessList([]*BroadcastSession{sess})
+		return &rtmpConnection{
+			mid:         core.ManifestID("foo"),
+			pl:          &stubPlaylistManager{os: &stubOSSession{}},
+			profile:     &ffmpeg.P144p30fps16x9,
+			sessManager: bsm,
+		}, bsm
+	}
+
+	renditions["P144p30fps16x9.ts"], renditions["P240p30fps16x9.ts"] = aligned, aligned
+	cxn, _ := newCxn()
+	urls, err := transcodeSegment(cxn, nil, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+	require.Nil(err)
+	assert.Len(urls, 2)
+
+	// Results whose keyframes aren't aligned are rejected, and the orchestrator removed
+	renditions["P240p30fps16x9.ts"] = late
+	cxn, bsm := newCxn()
+	_, err = transcodeSegment(cxn, nil, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil)
+	assert.Equal(verification.ErrKeyframeMisaligned, err)
+	assert.NotContains(bsm.sessMap, ts.URL)
+}
+
+func TestSessionManager_UpdateParams(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	n, _ := core.NewLivepeerNode(nil, "", nil)
+	n.OrchestratorPool = &stubDiscovery{infos: []*net.OrchestratorInfo{{Transcoder: "transcoder1"}}}
+	storage := drivers.NewMemoryDriver(nil).NewSession("foo")
+	params := &core.StreamParameters{ManifestID: "foo", OS: storage}
+	bsm := NewSessionManager(n, params, &LIFOSelector{})
+	require.Contains(bsm.sessMap, "transcoder1")
+	assert.Equal(params, bsm.sessMap["transcoder1"].Params)
+
+	// Sessions created after the update get the new params
+	newParams := &core.StreamParameters{ManifestID: "foo", OS: storage, Profiles: []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9}}
+	bsm.updateParams(newParams)
+	sessions, err := bsm.createSessions()
+	require.Nil(err)
+	require.Len(sessions, 1)
+	assert.Equal(newParams, sessions[0].Params)
+}
+
 func TestProcessSegment_MaxAttempts(t *testing.T) {
 	assert := assert.New(t)
 
@@ -780,21 +1357,21 @@ func TestProcessSegment_MaxAttempts(t *testing.T) {
 
 	// Sanity check: zero attempts should not transcode
 	MaxAttempts = 0
-	_, err := processSegment(cxn, seg)
+	_, err := processSegment(cxn, cxn.params, seg)
 	assert.Nil(err)
 	assert.Equal(0, transcodeCalls, "Unexpectedly submitted segment")
 	assert.Len(bsm.sessMap, 2)
 
 	// One failed transcode attempt. Should leave another in the map
 	MaxAttempts = 1
-	_, err = processSegment(cxn, seg)
+	_, err = processSegment(cxn, cxn.params, seg)
 	assert.NotNil(err)
 	assert.Equal("Hit max transcode attempts: UnknownResponse", err.Error())
 	assert.Equal(1, transcodeCalls, "Segment submission calls did not match")
 	assert.Len(bsm.sessMap, 1)
 
 	// Drain the swamp! Empty out the session list
-	_, err = processSegment(cxn, seg)
+	_, err = processSegment(cxn, cxn.params, seg)
 	assert.NotNil(err)
 	assert.Equal("Hit max transcode attempts: UnknownResponse", err.Error())
 	assert.Equal(2, transcodeCalls, "Segment submission calls did not match")
@@ -802,7 +1379,7 @@ func TestProcessSegment_MaxAttempts(t *testing.T) {
 
 	// The session list is empty. TODO Should return an error indicating such
 	// (This test should fail and be corrected once this is actually implemented)
-	_, err = processSegment(cxn, seg)
+	_, err = processSegment(cxn, cxn.params, seg)
 	assert.Nil(err)
 	assert.Equal(2, transcodeCalls, "Segment submission calls did not match")
 	assert.Len(bsm.sessMap, 0)
@@ -850,7 +1427,7 @@ func TestTranscodeSegment_VerifyPixels(t *testing.T) {
 		sessManager: bsm,
 	}
 
-	urls, err := transcodeSegment(cxn, &stream.HLSSegment{Data: []byte("dummy")}, "dummy", nil)
+	urls, err := transcodeSegment(cxn, cxn.params, &stream.HLSSegment{Data: []byte("dummy")}, "dummy", nil)
 	assert.Nil(err)
 	assert.NotNil(urls)
 	assert.Len(urls, 1)
@@ -871,7 +1448,7 @@ func TestTranscodeSegment_VerifyPixels(t *testing.T) {
 
 	sender.On("ValidateTicketParams", mock.Anything).Return(nil)
 
-	urls, err = transcodeSegment(cxn, &stream.HLSSegment{Data: []byte("dummy")}, "dummy", nil)
+	urls, err = transcodeSegment(cxn, cxn.params, &stream.HLSSegment{Data: []byte("dummy")}, "dummy", nil)
 	assert.Nil(err)
 	assert.Equal("test.flv", urls[0])
 
@@ -881,7 +1458,7 @@ func TestTranscodeSegment_VerifyPixels(t *testing.T) {
 	bsm = bsmWithSessList([]*BroadcastSession{sess})
 	cxn.sessManager = bsm
 
-	_, err = transcodeSegment(cxn, &stream.HLSSegment{Data: []byte("dummy")}, "dummy", nil)
+	_, err = transcodeSegment(cxn, cxn.params, &stream.HLSSegment{Data: []byte("dummy")}, "dummy", nil)
 	assert.Nil(err)
 
 	// Wait for async pixels verification to finish
@@ -977,7 +1554,7 @@ func TestHLSInsertion(t *testing.T) {
 	}
 
 	seg := &stream.HLSSegment{SeqNo: 93}
-	_, err = transcodeSegment(cxn, seg, "dummy", nil)
+	_, err = transcodeSegment(cxn, cxn.params, seg, "dummy", nil)
 	assert.Nil(err)
 
 	// some sanity checks
@@ -1029,29 +1606,29 @@ func TestVerifier_Invocation(t *testing.T) {
 	}
 
 	seg := &stream.HLSSegment{}
-	_, err = transcodeSegment(cxn, seg, "dummy", segmentVerifier)
+	_, err = transcodeSegment(cxn, cxn.params, seg, "dummy", segmentVerifier)
 	assert.Nil(err)
 	assert.Equal(1, verifier.calls)
 	require.NotNil(verifier.params)
 	assert.Equal(cxn.mid, verifier.params.ManifestID)
 	assert.Equal(seg, verifier.params.Source)
 	// Do it again for good measure
-	_, err = transcodeSegment(cxn, seg, "dummy", segmentVerifier)
+	_, err = transcodeSegment(cxn, cxn.params, seg, "dummy", segmentVerifier)
 	assert.Nil(err)
 	assert.Equal(2, verifier.calls)
 
 	// now "disable" the verifier and ensure no calls
-	_, err = transcodeSegment(cxn, seg, "dummy", nil)
+	_, err = transcodeSegment(cxn, cxn.params, seg, "dummy", nil)
 	assert.Nil(err)
 	assert.Equal(2, verifier.calls)
 
 	// Pass in a nil policy
-	_, err = transcodeSegment(cxn, seg, "dummy", verification.NewSegmentVerifier(nil))
+	_, err = transcodeSegment(cxn, cxn.params, seg, "dummy", verification.NewSegmentVerifier(nil))
 	assert.Nil(err)
 
 	// Pass in a policy but no verifier specified
 	policy = &verification.Policy{}
-	_, err = transcodeSegment(cxn, seg, "dummy", verification.NewSegmentVerifier(policy))
+	_, err = transcodeSegment(cxn, cxn.params, seg, "dummy", verification.NewSegmentVerifier(policy))
 	assert.Nil(err)
 }
 
@@ -1177,15 +1754,15 @@ func TestVerifier_HLSInsertion(t *testing.T) {
 	defer func() { downloadSeg = oldDownloadSeg }()
 	downloadSeg = func(url string) ([]byte, error) { return []byte("foo"), nil }
 
-	_, err := transcodeSegment(cxn, seg, "dummy", verifier)
+	_, err := transcodeSegment(cxn, cxn.params, seg, "dummy", verifier)
 	assert.Equal(verification.ErrTampered, err)
 	assert.Empty(pl.uri) // sanity check that no insertion happened
 
-	_, err = transcodeSegment(cxn, seg, "dummy", verifier)
+	_, err = transcodeSegment(cxn, cxn.params, seg, "dummy", verifier)
 	assert.Equal(verification.ErrTampered, err)
 	assert.Empty(pl.uri)
 
-	_, err = transcodeSegment(cxn, seg, "dummy", verifier)
+	_, err = transcodeSegment(cxn, cxn.params, seg, "dummy", verifier)
 	assert.Nil(err)
 	assert.Equal(baseURL+"/resp2", pl.uri)
 }
@@ -1221,7 +1798,7 @@ func TestDownloadSegError_SuspendAndRemove(t *testing.T) {
 	oldDownloadSeg := downloadSeg
 	defer func() { downloadSeg = oldDownloadSeg }()
 	downloadSeg = func(url string) ([]byte, error) { return nil, errors.New("some error") }
-	_, err := transcodeSegment(cxn, seg, "dummy", verifier)
+	_, err := transcodeSegment(cxn, cxn.params, seg, "dummy", verifier)
 	assert.EqualError(err, "some error")
 	_, ok := cxn.sessManager.sessMap[sess.OrchestratorInfo.GetTranscoder()]
 	assert.False(ok)
@@ -1283,6 +1860,28 @@ func TestRefreshSession(t *testing.T) {
 	newSess, err = refreshSession(sess)
 	assert.Nil(newSess)
 	assert.EqualError(err, "context timeout")
+
+	// fail over to the alternative URIs of the transcoder
+	var tried []string
+	getOrchestratorInfoRPC = func(ctx context.Context, bcast common.Broadcaster, serv *url.URL) (*net.OrchestratorInfo, error) {
+		tried = append(tried, serv.String())
+		if serv.String() == "foo" {
+			return nil, errors.New("unreachable")
+		}
+		return successOrchInfoUpdate, nil
+	}
+	sess.OrchestratorInfo.AltTranscoders = []string{"bar"}
+	newSess, err = refreshSession(sess)
+	assert.Nil(err)
+	assert.Equal(successOrchInfoUpdate, newSess.OrchestratorInfo)
+	assert.Equal([]string{"foo", "bar"}, tried)
+
+	// but not to a different orchestrator
+	sess.OrchestratorInfo.Address = []byte("addr1")
+	successOrchInfoUpdate.Address = []byte("addr2")
+	newSess, err = refreshSession(sess)
+	assert.Nil(newSess)
+	assert.EqualError(err, "orchestrator address mismatch at bar")
 }
 
 func defaultTicketBatch() *pm.TicketBatch {
@@ -1335,21 +1934,21 @@ func TestVerifier_SegDownload(t *testing.T) {
 	// When there is no broadcaster OS, segments should not be downloaded
 	url := "somewhere1"
 	cxn.sessManager = bsmWithSessList([]*BroadcastSession{genBcastSess(t, url, nil, mid)})
-	_, err := transcodeSegment(cxn, seg, "dummy", nil)
+	_, err := transcodeSegment(cxn, cxn.params, seg, "dummy", nil)
 	assert.Nil(err)
 	assert.False(downloaded[url])
 
 	// When segments are in the broadcaster's external OS, segments should not be downloaded
 	url = "https://livepeer.s3.amazonaws.com/resp1"
 	cxn.sessManager = bsmWithSessList([]*BroadcastSession{genBcastSess(t, url, externalOS, mid)})
-	_, err = transcodeSegment(cxn, seg, "dummy", nil)
+	_, err = transcodeSegment(cxn, cxn.params, seg, "dummy", nil)
 	assert.Nil(err)
 	assert.False(downloaded[url])
 
 	// When segments are not in the broadcaster's external OS, segments should be downloaded
 	url = "somewhere2"
 	cxn.sessManager = bsmWithSessList([]*BroadcastSession{genBcastSess(t, url, externalOS, mid)})
-	_, err = transcodeSegment(cxn, seg, "dummy", nil)
+	_, err = transcodeSegment(cxn, cxn.params, seg, "dummy", nil)
 	assert.Nil(err)
 	assert.True(downloaded[url])
 
@@ -1362,21 +1961,21 @@ func TestVerifier_SegDownload(t *testing.T) {
 	// When there is no broadcaster OS, segments should be downloaded
 	url = "somewhere3"
 	cxn.sessManager = bsmWithSessList([]*BroadcastSession{genBcastSess(t, url, nil, mid)})
-	_, err = transcodeSegment(cxn, seg, "dummy", verifier)
+	_, err = transcodeSegment(cxn, cxn.params, seg, "dummy", verifier)
 	assert.Nil(err)
 	assert.True(downloaded[url])
 
 	// When segments are in the broadcaster's external OS, segments should be downloaded
 	url = "https://livepeer.s3.amazonaws.com/resp2"
 	cxn.sessManager = bsmWithSessList([]*BroadcastSession{genBcastSess(t, url, externalOS, mid)})
-	_, err = transcodeSegment(cxn, seg, "dummy", verifier)
+	_, err = transcodeSegment(cxn, cxn.params, seg, "dummy", verifier)
 	assert.Nil(err)
 	assert.True(downloaded[url])
 
 	// When segments are not in the broadcaster's exernal OS, segments should be downloaded
 	url = "somewhere4"
 	cxn.sessManager = bsmWithSessList([]*BroadcastSession{genBcastSess(t, url, externalOS, mid)})
-	_, err = transcodeSegment(cxn, seg, "dummy", verifier)
+	_, err = transcodeSegment(cxn, cxn.params, seg, "dummy", verifier)
 	assert.Nil(err)
 	assert.True(downloaded[url])
 }
@@ -1403,7 +2002,7 @@ func TestProcessSegment_VideoFormat(t *testing.T) {
 	downloadSeg = func(url string) ([]byte, error) { return []byte(url), nil }
 
 	// processSegment will also call transcodeSegment; also check that behavior
-	_, err := processSegment(cxn, seg)
+	_, err := processSegment(cxn, cxn.params, seg)
 
 	assert.Nil(err)
 	assert.Equal(ffmpeg.FormatNone, cxn.profile.Format)
@@ -1426,7 +2025,7 @@ func TestProcessSegment_VideoFormat(t *testing.T) {
 	}
 	cxn.sessManager = bsmWithSessList([]*BroadcastSession{sess})
 
-	_, err = processSegment(cxn, seg)
+	_, err = processSegment(cxn, cxn.params, seg)
 
 	assert.Nil(err)
 	for _, p := range sess.Params.Profiles {
@@ -1449,7 +2048,7 @@ func TestProcessSegment_VideoFormat(t *testing.T) {
 	}
 	cxn.sessManager = bsmWithSessList([]*BroadcastSession{sess})
 
-	_, err = processSegment(cxn, seg)
+	_, err = processSegment(cxn, cxn.params, seg)
 
 	assert.Nil(err)
 	for _, p := range sess.Params.Profiles {
@@ -1459,6 +2058,58 @@ func TestProcessSegment_VideoFormat(t *testing.T) {
 	assert.Equal([]string{"P240p30fps16x9/0.ts"}, orchOS.saved)
 	assert.Equal([]string{"P240p30fps16x9/0.ts", "P144p30fps16x9/0.ts"}, bcastOS.saved)
 	assert.Equal("saved_P240p30fps16x9/0.ts", seg.Name)
+
+	// Segments sent inline aren't uploaded to the orchestrator OS
+	oldInlineMaxSize := core.InlineSegmentMaxSize
+	defer func() { core.InlineSegmentMaxSize = oldInlineMaxSize }()
+	core.InlineSegmentMaxSize = 10
+	sess.OrchestratorInfo.Protocol = &net.ProtocolInfo{InlineMaxSize: 10}
+	orchOS = &stubOSSession{}
+	sess.OrchestratorOS = orchOS
+	cxn.sessManager = bsmWithSessList([]*BroadcastSession{sess})
+
+	_, err = processSegment(cxn, cxn.params, seg)
+
+	assert.Nil(err)
+	assert.Empty(orchOS.saved)
+	assert.Equal("", seg.Name)
+}
+
+func TestTranscodeSegment_RecordEgress(t *testing.T) {
+	assert := assert.New(t)
+
+	bcastOS := &stubOSSession{}
+	orchOS := &stubOSSession{}
+	sess := genBcastSess(t, "", bcastOS, "")
+	cxn := &rtmpConnection{
+		mid:         "foo",
+		pl:          &stubPlaylistManager{os: bcastOS},
+		profile:     &ffmpeg.P240p30fps16x9,
+		sessManager: bsmWithSessList([]*BroadcastSession{sess}),
+		bandwidth:   core.NewBandwidthTracker(),
+	}
+	oldDownloadSeg := downloadSeg
+	defer func() { downloadSeg = oldDownloadSeg }()
+	downloadSeg = func(url string) ([]byte, error) { return []byte(url), nil }
+
+	// Segments sent inline are counted once, for the orchestrator
+	seg := &stream.HLSSegment{Data: []byte("dummy")}
+	_, err := transcodeSegment(cxn, cxn.params, seg, "dummy", nil)
+	assert.Nil(err)
+	assert.Equal(int64(5), cxn.bandwidth.Totals().EgressBytes)
+	peers := cxn.bandwidth.PeerStats()
+	if assert.Len(peers, 1) {
+		assert.Equal(sess.OrchestratorInfo.Transcoder, peers[0].ID)
+	}
+
+	// Segments sent through object storage are counted once, for the upload
+	sess.OrchestratorOS = orchOS
+	cxn.sessManager = bsmWithSessList([]*BroadcastSession{sess})
+	seg = &stream.HLSSegment{Data: []byte("dummy")}
+	_, err = transcodeSegment(cxn, cxn.params, seg, "dummy", nil)
+	assert.Nil(err)
+	assert.Equal([]string{"dummy"}, orchOS.saved)
+	assert.Equal(int64(10), cxn.bandwidth.Totals().EgressBytes)
 }
 
 func TestProcessSegment_CheckDuration(t *testing.T) {
@@ -1467,12 +2118,12 @@ func TestProcessSegment_CheckDuration(t *testing.T) {
 	cxn := &rtmpConnection{}
 
 	// Check less-than-zero
-	_, err := processSegment(cxn, seg)
+	_, err := processSegment(cxn, cxn.params, seg)
 	assert.Equal("Invalid duration -1", err.Error())
 
 	// CHeck greater than max duration
 	seg.Duration = maxDurationSec + 0.01
-	_, err = processSegment(cxn, seg)
+	_, err = processSegment(cxn, cxn.params, seg)
 	assert.Equal("Invalid duration 300.01", err.Error())
 }
 
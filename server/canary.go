@@ -0,0 +1,229 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/monitor"
+)
+
+// Stages of the pipeline that a canary run can fail at
+const (
+	CanaryStagePush      = "Push"
+	CanaryStageDiscovery = "Discovery"
+	CanaryStageTranscode = "Transcode"
+	CanaryStagePlayback  = "Playback"
+)
+
+var errCanaryNoRenditions = errors.New("no renditions returned")
+
+// CanaryResult is the outcome of a single canary run
+type CanaryResult struct {
+	Time       time.Time
+	Success    bool
+	Stage      string `json:",omitempty"`
+	Error      string `json:",omitempty"`
+	Latency    time.Duration
+	SeqNo      uint64
+	Renditions int
+}
+
+// Canary periodically pushes a test segment through the node's own HTTP ingest, which exercises
+// discovery, payment and transcoding, and then checks that the stream can be played back
+type Canary struct {
+	baseURL  string
+	segment  []byte
+	duration time.Duration
+	interval time.Duration
+	client   *http.Client
+	mid      string
+
+	mu     sync.RWMutex
+	seqNo  uint64
+	last   *CanaryResult
+	passed int64
+	failed int64
+}
+
+// CanaryStatus is a snapshot of the results of a Canary
+type CanaryStatus struct {
+	ManifestID string
+	Interval   time.Duration
+	Passed     int64
+	Failed     int64
+	Last       *CanaryResult
+}
+
+// NewCanary creates a Canary that pushes segment, which must be a MPEG-TS segment of the provided
+// duration, to the HTTP ingest listening on httpAddr
+func NewCanary(httpAddr string, segment []byte, duration, interval time.Duration) *Canary {
+	return &Canary{
+		baseURL:  "http://" + httpAddr,
+		segment:  segment,
+		duration: duration,
+		interval: interval,
+		client:   &http.Client{Timeout: common.HTTPTimeout + duration},
+		mid:      "canary-" + common.RandName(),
+	}
+}
+
+// Start runs the canary every interval until the context is done
+func (c *Canary) Start(ctx context.Context) {
+	glog.Infof("Starting self-test canary manifestID=%s interval=%v", c.mid, c.interval)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Run()
+		}
+	}
+}
+
+// Run performs a single canary run and records the result
+func (c *Canary) Run() *CanaryResult {
+	c.mu.Lock()
+	seqNo := c.seqNo
+	c.seqNo++
+	c.mu.Unlock()
+
+	start := time.Now()
+	res := &CanaryResult{Time: start, SeqNo: seqNo}
+	stage, renditions, err := c.run(seqNo)
+	res.Latency = time.Since(start)
+	res.Renditions = renditions
+	if err != nil {
+		res.Stage = stage
+		res.Error = err.Error()
+		glog.Errorf("Self-test canary failed manifestID=%s seqNo=%d stage=%s err=%v", c.mid, seqNo, stage, err)
+		if monitor.Enabled {
+			monitor.CanaryFailed(stage)
+		}
+	} else {
+		res.Success = true
+		glog.V(common.DEBUG).Infof("Self-test canary succeeded manifestID=%s seqNo=%d latency=%v", c.mid, seqNo, res.Latency)
+		if monitor.Enabled {
+			monitor.CanarySucceeded(res.Latency)
+		}
+	}
+
+	c.mu.Lock()
+	c.last = res
+	if res.Success {
+		c.passed++
+	} else {
+		c.failed++
+	}
+	c.mu.Unlock()
+
+	return res
+}
+
+// Status returns a snapshot of the canary results
+func (c *Canary) Status() *CanaryStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return &CanaryStatus{
+		ManifestID: c.mid,
+		Interval:   c.interval,
+		Passed:     c.passed,
+		Failed:     c.failed,
+		Last:       c.last,
+	}
+}
+
+func (c *Canary) run(seqNo uint64) (string, int, error) {
+	url := fmt.Sprintf("%s/live/%s/%d.ts", c.baseURL, c.mid, seqNo)
+	req, err := http.NewRequest("POST", url, bytes.NewReader(c.segment))
+	if err != nil {
+		return CanaryStagePush, 0, err
+	}
+	req.Header.Set("Accept", "multipart/mixed")
+	req.Header.Set("Content-Duration", fmt.Sprintf("%d", c.duration.Milliseconds()))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return CanaryStagePush, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		err := fmt.Errorf("unexpected status %v: %s", resp.Status, strings.TrimSpace(string(body)))
+		// The broadcaster could not find an orchestrator to transcode with
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			return CanaryStageDiscovery, 0, err
+		}
+		return CanaryStageTranscode, 0, err
+	}
+
+	renditions, err := countRenditions(resp)
+	if err != nil {
+		return CanaryStageTranscode, renditions, err
+	}
+
+	// The stream should be playable with the transcoded renditions
+	playlist, err := c.client.Get(fmt.Sprintf("%s/stream/%s.m3u8", c.baseURL, c.mid))
+	if err != nil {
+		return CanaryStagePlayback, renditions, err
+	}
+	defer playlist.Body.Close()
+	body, err := ioutil.ReadAll(playlist.Body)
+	if err != nil {
+		return CanaryStagePlayback, renditions, err
+	}
+	if playlist.StatusCode != http.StatusOK {
+		return CanaryStagePlayback, renditions, fmt.Errorf("unexpected playlist status %v", playlist.Status)
+	}
+	if !strings.HasPrefix(string(body), "#EXTM3U") {
+		return CanaryStagePlayback, renditions, errors.New("invalid playlist")
+	}
+
+	return "", renditions, nil
+}
+
+// countRenditions counts the non-empty renditions in a multipart push response
+func countRenditions(resp *http.Response) (int, error) {
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		return 0, errCanaryNoRenditions
+	}
+
+	renditions := 0
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return renditions, err
+		}
+		data, err := ioutil.ReadAll(p)
+		if err != nil {
+			return renditions, err
+		}
+		if len(data) > 0 {
+			renditions++
+		}
+	}
+	if renditions == 0 {
+		return 0, errCanaryNoRenditions
+	}
+	return renditions, nil
+}
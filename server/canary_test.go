@@ -0,0 +1,172 @@
+package server
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func canaryPushHandler(renditions int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mw := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < renditions; i++ {
+			p, _ := mw.CreatePart(nil)
+			p.Write([]byte("rendition"))
+		}
+		mw.Close()
+	}
+}
+
+func newTestCanary(t *testing.T, push, playlist http.HandlerFunc) (*Canary, func()) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/live/", push)
+	mux.HandleFunc("/stream/", playlist)
+	ts := httptest.NewServer(mux)
+	c := NewCanary(strings.TrimPrefix(ts.URL, "http://"), []byte("segment"), 2*time.Second, time.Minute)
+	return c, ts.Close
+}
+
+func TestCanary_Success(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var pushPath, accept, duration string
+	push := func(w http.ResponseWriter, r *http.Request) {
+		pushPath = r.URL.Path
+		accept = r.Header.Get("Accept")
+		duration = r.Header.Get("Content-Duration")
+		canaryPushHandler(2)(w, r)
+	}
+	var playlistPath string
+	playlist := func(w http.ResponseWriter, r *http.Request) {
+		playlistPath = r.URL.Path
+		w.Write([]byte("#EXTM3U\n"))
+	}
+	c, cleanup := newTestCanary(t, push, playlist)
+	defer cleanup()
+
+	res := c.Run()
+	require.True(res.Success, res.Error)
+	assert.Equal(uint64(0), res.SeqNo)
+	assert.Equal(2, res.Renditions)
+	assert.Empty(res.Stage)
+	assert.Equal(fmt.Sprintf("/live/%s/0.ts", c.mid), pushPath)
+	assert.Equal("multipart/mixed", accept)
+	assert.Equal("2000", duration)
+	assert.Equal(fmt.Sprintf("/stream/%s.m3u8", c.mid), playlistPath)
+
+	// Sequence numbers increase with every run
+	res = c.Run()
+	assert.True(res.Success)
+	assert.Equal(uint64(1), res.SeqNo)
+	assert.Equal(fmt.Sprintf("/live/%s/1.ts", c.mid), pushPath)
+
+	status := c.Status()
+	assert.Equal(c.mid, status.ManifestID)
+	assert.Equal(int64(2), status.Passed)
+	assert.Equal(int64(0), status.Failed)
+	assert.Equal(res, status.Last)
+}
+
+func TestCanary_Failures(t *testing.T) {
+	okPlaylist := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("#EXTM3U\n"))
+	}
+	tests := []struct {
+		name     string
+		push     http.HandlerFunc
+		playlist http.HandlerFunc
+		stage    string
+		err      string
+	}{
+		{
+			name: "no sessions",
+			push: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "No sessions available", http.StatusServiceUnavailable)
+			},
+			playlist: okPlaylist,
+			stage:    CanaryStageDiscovery,
+			err:      "No sessions available",
+		},
+		{
+			name: "transcode error",
+			push: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "transcode failed", http.StatusInternalServerError)
+			},
+			playlist: okPlaylist,
+			stage:    CanaryStageTranscode,
+			err:      "transcode failed",
+		},
+		{
+			name:     "no renditions",
+			push:     canaryPushHandler(0),
+			playlist: okPlaylist,
+			stage:    CanaryStageTranscode,
+			err:      errCanaryNoRenditions.Error(),
+		},
+		{
+			name: "not multipart",
+			push: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("foo"))
+			},
+			playlist: okPlaylist,
+			stage:    CanaryStageTranscode,
+			err:      errCanaryNoRenditions.Error(),
+		},
+		{
+			name: "playlist not found",
+			push: canaryPushHandler(1),
+			playlist: func(w http.ResponseWriter, r *http.Request) {
+				http.NotFound(w, r)
+			},
+			stage: CanaryStagePlayback,
+			err:   "unexpected playlist status",
+		},
+		{
+			name: "invalid playlist",
+			push: canaryPushHandler(1),
+			playlist: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("foo"))
+			},
+			stage: CanaryStagePlayback,
+			err:   "invalid playlist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, cleanup := newTestCanary(t, tt.push, tt.playlist)
+			defer cleanup()
+
+			res := c.Run()
+			assert := assert.New(t)
+			assert.False(res.Success)
+			assert.Equal(tt.stage, res.Stage)
+			assert.Contains(res.Error, tt.err)
+
+			status := c.Status()
+			assert.Equal(int64(0), status.Passed)
+			assert.Equal(int64(1), status.Failed)
+			assert.Equal(res, status.Last)
+		})
+	}
+}
+
+func TestCanary_PushError(t *testing.T) {
+	c, cleanup := newTestCanary(t, canaryPushHandler(1), canaryPushHandler(1))
+	// Nothing is listening anymore
+	cleanup()
+
+	res := c.Run()
+	assert.False(t, res.Success)
+	assert.Equal(t, CanaryStagePush, res.Stage)
+}
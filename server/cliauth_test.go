@@ -0,0 +1,128 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCert writes a self-signed certificate for 127.0.0.1 and its key to dir
+func writeTestCert(t *testing.T, dir, name string, usage x509.ExtKeyUsage) (string, string) {
+	require := require.New(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(err)
+	tmpl := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{usage},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	require.Nil(err)
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	require.Nil(err)
+	certFile, keyFile := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+".key")
+	require.Nil(writeFile(certFile, "CERTIFICATE", cert))
+	require.Nil(writeFile(keyFile, "EC PRIVATE KEY", keyBytes))
+	return certFile, keyFile
+}
+
+func TestCliAuth(t *testing.T) {
+	assert := assert.New(t)
+
+	handler := cliAuth("foo", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	get := func(path, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(http.StatusOK, get("/status", "Bearer foo").Code)
+	rr := get("/status", "")
+	assert.Equal(http.StatusUnauthorized, rr.Code)
+	assert.Equal("Bearer", rr.Header().Get("WWW-Authenticate"))
+	assert.Equal(http.StatusUnauthorized, get("/status", "Bearer bar").Code)
+	assert.Equal(http.StatusUnauthorized, get("/status", "foo").Code)
+	// Unlike the diagnostics token, the token can't be passed as a parameter
+	assert.Equal(http.StatusUnauthorized, get("/status?token=foo", "").Code)
+}
+
+func TestNewCliTLSConfig(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "TestNewCliTLSConfig")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	serverCert, serverKey := writeTestCert(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := writeTestCert(t, dir, "client", x509.ExtKeyUsageClientAuth)
+	otherCert, otherKey := writeTestCert(t, dir, "other", x509.ExtKeyUsageClientAuth)
+
+	_, err = NewCliTLSConfig(filepath.Join(dir, "missing.pem"), serverKey, "")
+	assert.True(os.IsNotExist(err))
+	_, err = NewCliTLSConfig(serverCert, serverKey, filepath.Join(dir, "missing.pem"))
+	assert.True(os.IsNotExist(err))
+	_, err = NewCliTLSConfig(serverCert, serverKey, serverKey)
+	assert.Contains(err.Error(), "no certificates found")
+
+	config, err := NewCliTLSConfig(serverCert, serverKey, clientCert)
+	require.Nil(err)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.TLS = config
+	ts.StartTLS()
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	pem, err := ioutil.ReadFile(serverCert)
+	require.Nil(err)
+	require.True(roots.AppendCertsFromPEM(pem))
+	get := func(certFile, keyFile string) error {
+		tlsConfig := &tls.Config{RootCAs: roots}
+		if certFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			require.Nil(err)
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		resp, err := client.Get(ts.URL)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	// Clients must present a certificate signed by the client CA
+	assert.Nil(get(clientCert, clientKey))
+	assert.NotNil(get("", ""))
+	assert.NotNil(get(otherCert, otherKey))
+}
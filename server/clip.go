@@ -0,0 +1,211 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/livepeer/lpms/stream"
+	"github.com/livepeer/m3u8"
+)
+
+// clipClient fetches the playlists and the segments of the recordings that are clipped
+var clipClient = &http.Client{Timeout: common.HTTPTimeout}
+
+// clipTimeout is how long the renditions of a clip can be played from the broadcaster
+// once they are transcoded
+var clipTimeout = 10 * time.Minute
+
+var errClipPlaylist = errors.New("not a media playlist")
+
+type clipResult struct {
+	ManifestID core.ManifestID `json:"manifestID"`
+	// Master playlist of the renditions of the clip
+	Playlist string `json:"playlist"`
+	// URLs of the renditions of each segment of the clip, in the order of the profiles
+	Segments [][]string `json:"segments"`
+}
+
+// clipHandler transcodes a clip of a recorded stream into presets and profiles in the
+// JSON format of the auth webhook. The recording is the HLS media playlist of the stream,
+// and the clip runs from start to end seconds into the recording. The segments that the
+// bounds of the clip fall into are cut frame-accurately by orchestrators that support
+// the clip capability, and paid for like the segments of live streams.
+func clipHandler(s *LivepeerServer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			respondWithError(w, "clipping requires a POST request", http.StatusMethodNotAllowed)
+			return
+		}
+		if Draining() {
+			respondWithError(w, errDraining.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		start, err := strconv.ParseFloat(r.FormValue("start"), 64)
+		if err != nil {
+			respondWith400(w, fmt.Sprintf("invalid start: %v", err))
+			return
+		}
+		end, err := strconv.ParseFloat(r.FormValue("end"), 64)
+		if err != nil {
+			respondWith400(w, fmt.Sprintf("invalid end: %v", err))
+			return
+		}
+		profiles, err := formProfiles(r)
+		if err != nil {
+			respondWith400(w, err.Error())
+			return
+		}
+
+		playlistURL, err := url.Parse(r.FormValue("url"))
+		if err != nil {
+			respondWith400(w, fmt.Sprintf("invalid url: %v", err))
+			return
+		}
+		segs, err := fetchRecording(playlistURL)
+		if err != nil {
+			respondWith400(w, fmt.Sprintf("unable to fetch the recording: %v", err))
+			return
+		}
+		durations := make([]float64, len(segs))
+		for i, seg := range segs {
+			durations[i] = seg.Duration
+		}
+		clipSegs, err := core.ClipSegments(durations, start, end)
+		if err != nil {
+			respondWith400(w, err.Error())
+			return
+		}
+
+		res, err := s.transcodeClip(playlistURL, segs, clipSegs, profiles)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("unable to transcode the clip: %v", err))
+			return
+		}
+		respondWithJSON(w, res)
+	})
+}
+
+// fetchRecording fetches the segments of the HLS media playlist of a recording
+func fetchRecording(playlistURL *url.URL) ([]*m3u8.MediaSegment, error) {
+	resp, err := clipClient.Get(playlistURL.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected playlist status %v", resp.Status)
+	}
+	pl, listType, err := m3u8.DecodeFrom(resp.Body, true)
+	if err != nil {
+		return nil, err
+	}
+	mpl, ok := pl.(*m3u8.MediaPlaylist)
+	if listType != m3u8.MEDIA || !ok {
+		return nil, errClipPlaylist
+	}
+	var segs []*m3u8.MediaSegment
+	for _, seg := range mpl.Segments {
+		if seg == nil {
+			break
+		}
+		segs = append(segs, seg)
+	}
+	return segs, nil
+}
+
+func fetchClipSegment(segURL string) ([]byte, error) {
+	resp, err := clipClient.Get(segURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected segment status %v", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// transcodeClip transcodes the segments of a recording that are part of a clip as a new
+// stream, which orchestrators without the clip capability are not selected for. The
+// stream is removed clipTimeout after it is transcoded, or as soon as a segment fails.
+func (s *LivepeerServer) transcodeClip(playlistURL *url.URL, segs []*m3u8.MediaSegment, clipSegs []core.ClipSegment,
+	profiles []ffmpeg.VideoProfile) (*clipResult, error) {
+
+	segURLs := make([]string, len(clipSegs))
+	for i, cs := range clipSegs {
+		u, err := playlistURL.Parse(segs[cs.Index].URI)
+		if err != nil {
+			return nil, fmt.Errorf("invalid segment URI %v: %v", segs[cs.Index].URI, err)
+		}
+		segURLs[i] = u.String()
+	}
+	format := common.ProfileExtensionFormat(path.Ext(segURLs[0]))
+	if format == ffmpeg.FormatNone {
+		format = ffmpeg.FormatMPEGTS
+	}
+
+	mid := core.RandomManifestID()
+	params := &core.StreamParameters{
+		ManifestID:           mid,
+		Profiles:             profiles,
+		Format:               format,
+		RequiredCapabilities: []core.Capability{core.Capability_Clip},
+		VODSegments:          len(clipSegs),
+	}
+	cxn, err := s.registerConnection(stream.NewBasicRTMPVideoStream(params))
+	if err != nil {
+		return nil, err
+	}
+	glog.Infof("Transcoding clip manifestID=%s url=%s segments=%d", mid, playlistURL, len(clipSegs))
+
+	res := &clipResult{ManifestID: mid, Playlist: fmt.Sprintf("/stream/%s.m3u8", mid)}
+	for i, cs := range clipSegs {
+		data, err := fetchClipSegment(segURLs[i])
+		if err != nil {
+			removeRTMPStream(s, mid)
+			return nil, fmt.Errorf("unable to fetch segment %v: %v", segURLs[i], err)
+		}
+		seg := &stream.HLSSegment{
+			Data:     data,
+			SeqNo:    uint64(i),
+			Duration: segs[cs.Index].Duration,
+		}
+		segParams := cxn.streamParams()
+		if cs.Clip != nil {
+			clipParams := *segParams
+			clipParams.Clip = cs.Clip
+			segParams = &clipParams
+		}
+		urls, err := processSegment(cxn, segParams, seg)
+		if err == nil && len(urls) == 0 {
+			// the segments of live streams are only published as the source when no
+			// orchestrator transcodes them, but clips are not played without renditions
+			err = errNoOrchs
+		}
+		if err != nil {
+			removeRTMPStream(s, mid)
+			return nil, err
+		}
+		res.Segments = append(res.Segments, urls)
+	}
+	cxn.pl.EndHLSPlaylists()
+
+	s.connectionLock.Lock()
+	cxn.lastUsed = time.Now()
+	s.connectionLock.Unlock()
+	go removeIdleStream(s, mid, clipTimeout)
+
+	return res, nil
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/net"
+)
+
+func TestClipHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	s := setupServer()
+	defer serverCleanup(s)
+	defer func() { s.LivepeerNode.OrchestratorPool = nil }()
+
+	// A recording of 3 segments of 2 seconds
+	recording := http.NewServeMux()
+	recording.HandleFunc("/rec/index.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:2\n#EXT-X-MEDIA-SEQUENCE:0\n" +
+			"#EXTINF:2.000,\n0.ts\n#EXTINF:2.000,\n1.ts\n#EXTINF:2.000,\n2.ts\n#EXT-X-ENDLIST\n"))
+	})
+	recording.HandleFunc("/rec/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("segment " + strings.TrimPrefix(r.URL.Path, "/rec/")))
+	})
+	rec := httptest.NewServer(recording)
+	defer rec.Close()
+
+	type received struct {
+		seq              int64
+		clipFrom, clipTo int64
+		data             string
+	}
+	var (
+		mu   sync.Mutex
+		segs []received
+	)
+	ts, mux := stubTLSServer()
+	defer ts.Close()
+	mux.HandleFunc("/segment", func(w http.ResponseWriter, r *http.Request) {
+		data, err := base64.StdEncoding.DecodeString(r.Header.Get(segmentHeader))
+		require.Nil(err)
+		var segData net.SegData
+		require.Nil(proto.Unmarshal(data, &segData))
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		segs = append(segs, received{segData.Seq, segData.ClipFrom, segData.ClipTo, string(body)})
+		mu.Unlock()
+		buf, err := proto.Marshal(&net.TranscodeResult{
+			Result: &net.TranscodeResult_Data{Data: &net.TranscodeData{Segments: []*net.TranscodedSegmentData{{Url: ts.URL + "/rendition"}}}},
+		})
+		require.Nil(err)
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf)
+	})
+	mux.HandleFunc("/rendition", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("clipped rendition"))
+	})
+	caps := core.NewCapabilities([]core.Capability{core.Capability_H264, core.Capability_MPEGTS, core.Capability_StorageDirect, core.Capability_Clip}, nil)
+	s.LivepeerNode.OrchestratorPool = &stubDiscovery{infos: []*net.OrchestratorInfo{{Transcoder: ts.URL, Capabilities: caps.ToNetCapabilities()}}}
+
+	handler := clipHandler(s)
+	playlist := rec.URL + "/rec/index.m3u8"
+	code, body := postForm(handler, url.Values{"url": {playlist}, "start": {"1.5"}, "end": {"3"}, "presets": {"P144p30fps16x9"}})
+	require.Equal(http.StatusOK, code, body)
+	var res clipResult
+	require.Nil(json.Unmarshal([]byte(body), &res))
+	assert.Equal("/stream/"+string(res.ManifestID)+".m3u8", res.Playlist)
+	assert.Len(res.Segments, 2)
+
+	// the segments that the bounds of the clip fall into are clipped
+	assert.Equal([]received{{0, 1500, 0, "segment 0.ts"}, {1, 0, 1000, "segment 1.ts"}}, segs)
+	s.connectionLock.RLock()
+	cxn, ok := s.rtmpConnections[res.ManifestID]
+	s.connectionLock.RUnlock()
+	require.True(ok)
+	mpl := cxn.pl.GetHLSMediaPlaylist("P144p30fps16x9")
+	require.NotNil(mpl)
+	assert.Equal(0.5, mpl.Segments[0].Duration)
+	assert.Equal(1.0, mpl.Segments[1].Duration)
+	// the stream only transcodes on orchestrators with the clip capability
+	assert.Contains(cxn.params.Capabilities.Names(), "clip")
+
+	code, body = postForm(handler, url.Values{"url": {playlist}, "start": {"3"}, "end": {"1"}, "presets": {"P144p30fps16x9"}})
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal("invalid clip from 3 to 1", body)
+	code, body = postForm(handler, url.Values{"url": {playlist}, "start": {"7"}, "end": {"8"}, "presets": {"P144p30fps16x9"}})
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal("clip from 7 to 8 is outside of the recording of 6 seconds", body)
+	code, body = postForm(handler, url.Values{"url": {playlist}, "start": {"now"}, "end": {"1"}, "presets": {"P144p30fps16x9"}})
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Contains(body, "invalid start")
+	code, body = postForm(handler, url.Values{"url": {playlist}, "start": {"0"}, "end": {"1"}})
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal("missing presets or profiles", body)
+	code, body = postForm(handler, url.Values{"url": {rec.URL + "/missing.m3u8"}, "start": {"0"}, "end": {"1"}, "presets": {"P144p30fps16x9"}})
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal("unable to fetch the recording: unexpected playlist status 404 Not Found", body)
+
+	// clips are not published without renditions
+	s.LivepeerNode.OrchestratorPool = &stubDiscovery{}
+	code, body = postForm(handler, url.Values{"url": {playlist}, "start": {"0"}, "end": {"1"}, "presets": {"P144p30fps16x9"}})
+	assert.Equal(http.StatusInternalServerError, code)
+	assert.Equal("unable to transcode the clip: ErrNoOrchs", body)
+	s.connectionLock.RLock()
+	assert.Len(s.rtmpConnections, 1)
+	s.connectionLock.RUnlock()
+
+	resp := httpGetResp(handler)
+	assert.Equal(http.StatusMethodNotAllowed, resp.StatusCode)
+}
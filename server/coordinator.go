@@ -0,0 +1,549 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/core"
+)
+
+// A fleet of broadcasters behind a load balancer shares its state through a coordinator,
+// one of the broadcasters that serves the coordinator API on its CLI server. The members
+// of the fleet claim the streams they ingest from the coordinator, so that the segments
+// of a stream that reach another member are forwarded to the member that ingests it, and
+// report the performance of the orchestrators they use. In return, they get the maximum
+// price of the coordinator and the orchestrators that a member suspended
+
+// fleetSuspension is how long an orchestrator suspended by a member of the fleet is
+// avoided by the other members
+const fleetSuspension = 5 * time.Minute
+
+// coordinatorTimeout bounds the requests of the members to the coordinator
+const coordinatorTimeout = 5 * time.Second
+
+// fleetForwardedHeader is set on the segments that a member of the fleet forwards to the
+// member that ingests the stream, to the URL of the forwarding member
+const fleetForwardedHeader = "Livepeer-Fleet-Forwarded"
+
+// fleetSecretHeader carries the secret shared by the members of the fleet on their
+// requests to the coordinator
+const fleetSecretHeader = "Livepeer-Fleet-Secret"
+
+var errIngestedElsewhere = errors.New("stream is ingested by another broadcaster of the fleet")
+
+// FleetCoordinator serves the coordinator API of a fleet of broadcasters when set
+var FleetCoordinator *Coordinator
+
+// Fleet makes the broadcaster a member of a fleet when set
+var Fleet *FleetMember
+
+// fleetOrchReport is the performance of an orchestrator reported by a member of the fleet
+// since its last heartbeat
+type fleetOrchReport struct {
+	Segments int `json:"segments"`
+	Failures int `json:"failures"`
+}
+
+// fleetHeartbeat is sent by the members of the fleet to the coordinator at every interval
+type fleetHeartbeat struct {
+	Node          string                      `json:"node"`
+	Streams       []string                    `json:"streams"`
+	Orchestrators map[string]*fleetOrchReport `json:"orchestrators"`
+	Suspended     []string                    `json:"suspended"`
+}
+
+// fleetState is the state shared with the members of the fleet in response to a heartbeat
+type fleetState struct {
+	MaxPrice  *big.Rat `json:"maxPrice"`
+	Suspended []string `json:"suspended"`
+}
+
+// FleetOrchStats is the performance of an orchestrator across the fleet
+type FleetOrchStats struct {
+	Segments       int        `json:"segments"`
+	Failures       int        `json:"failures"`
+	SuspendedUntil *time.Time `json:"suspendedUntil,omitempty"`
+}
+
+// FleetNodeStatus is a member of the fleet
+type FleetNodeStatus struct {
+	Node          string    `json:"node"`
+	LastHeartbeat time.Time `json:"lastHeartbeat"`
+	Streams       int       `json:"streams"`
+}
+
+// FleetStatus is the state of the fleet held by the coordinator
+type FleetStatus struct {
+	Nodes         []FleetNodeStatus          `json:"nodes"`
+	Streams       map[string]string          `json:"streams"`
+	Orchestrators map[string]*FleetOrchStats `json:"orchestrators"`
+	MaxPrice      *big.Rat                   `json:"maxPrice"`
+}
+
+// Coordinator holds the state of a fleet of broadcasters
+type Coordinator struct {
+	mu sync.Mutex
+	// ttl is the time after its last heartbeat after which a member is considered gone,
+	// along with its claims
+	ttl time.Duration
+	// secret is shared by the members of the fleet, and required for the claims and
+	// heartbeats that register members
+	secret  string
+	nodes   map[string]time.Time // member URL => last heartbeat
+	streams map[string]string    // manifest ID => URL of the member that ingests it
+	orchs   map[string]*FleetOrchStats
+	now     func() time.Time
+}
+
+// NewCoordinator returns the coordinator of a fleet whose members share secret and send
+// a heartbeat at every interval
+func NewCoordinator(interval time.Duration, secret string) (*Coordinator, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("must provide a secret")
+	}
+	return &Coordinator{
+		ttl:     3 * interval,
+		secret:  secret,
+		nodes:   make(map[string]time.Time),
+		streams: make(map[string]string),
+		orchs:   make(map[string]*FleetOrchStats),
+		now:     time.Now,
+	}, nil
+}
+
+// alive returns whether a member sent a heartbeat recently. Requires the lock
+func (c *Coordinator) alive(node string) bool {
+	last, ok := c.nodes[node]
+	return ok && c.now().Sub(last) < c.ttl
+}
+
+// expire forgets the members that are gone and their claims. Requires the lock
+func (c *Coordinator) expire() {
+	for node := range c.nodes {
+		if !c.alive(node) {
+			delete(c.nodes, node)
+		}
+	}
+	for mid, node := range c.streams {
+		if _, ok := c.nodes[node]; !ok {
+			delete(c.streams, mid)
+		}
+	}
+}
+
+// Claim assigns a stream to a member, unless another member that is alive ingests it
+// already. It returns the URL of the member that ingests the stream
+func (c *Coordinator) Claim(mid, node string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if owner, ok := c.streams[mid]; ok && owner != node && c.alive(owner) {
+		return owner
+	}
+	c.streams[mid] = node
+	c.nodes[node] = c.now()
+	return node
+}
+
+// Release unassigns a stream that a member stopped ingesting
+func (c *Coordinator) Release(mid, node string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.streams[mid] == node {
+		delete(c.streams, mid)
+	}
+}
+
+// Heartbeat records the report of a member and returns the orchestrators that are
+// suspended across the fleet. The streams of the member are claimed again, so that a
+// restarted coordinator recovers them
+func (c *Coordinator) Heartbeat(hb *fleetHeartbeat) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	c.nodes[hb.Node] = now
+	c.expire()
+
+	for _, mid := range hb.Streams {
+		if owner, ok := c.streams[mid]; !ok || !c.alive(owner) {
+			c.streams[mid] = hb.Node
+		}
+	}
+	for orch, report := range hb.Orchestrators {
+		stats := c.orchStats(orch)
+		stats.Segments += report.Segments
+		stats.Failures += report.Failures
+	}
+	for _, orch := range hb.Suspended {
+		until := now.Add(fleetSuspension)
+		c.orchStats(orch).SuspendedUntil = &until
+	}
+
+	suspended := []string{}
+	for orch, stats := range c.orchs {
+		if stats.SuspendedUntil != nil && stats.SuspendedUntil.After(now) {
+			suspended = append(suspended, orch)
+		}
+	}
+	sort.Strings(suspended)
+	return suspended
+}
+
+// orchStats returns the stats of an orchestrator, creating them if needed. Requires the lock
+func (c *Coordinator) orchStats(orch string) *FleetOrchStats {
+	stats, ok := c.orchs[orch]
+	if !ok {
+		stats = &FleetOrchStats{}
+		c.orchs[orch] = stats
+	}
+	return stats
+}
+
+// Status returns the members of the fleet, their streams and the performance of the
+// orchestrators across the fleet
+func (c *Coordinator) Status() *FleetStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expire()
+
+	status := &FleetStatus{
+		Nodes:         []FleetNodeStatus{},
+		Streams:       make(map[string]string),
+		Orchestrators: make(map[string]*FleetOrchStats),
+	}
+	streams := make(map[string]int)
+	for mid, node := range c.streams {
+		status.Streams[mid] = node
+		streams[node]++
+	}
+	for node, last := range c.nodes {
+		status.Nodes = append(status.Nodes, FleetNodeStatus{Node: node, LastHeartbeat: last, Streams: streams[node]})
+	}
+	sort.Slice(status.Nodes, func(i, j int) bool { return status.Nodes[i].Node < status.Nodes[j].Node })
+	for orch, stats := range c.orchs {
+		s := *stats
+		status.Orchestrators[orch] = &s
+	}
+	return status
+}
+
+func coordinatorHandler(h func(c *Coordinator) http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if FleetCoordinator == nil {
+			respondWithError(w, "not a fleet coordinator", http.StatusNotFound)
+			return
+		}
+		h(FleetCoordinator).ServeHTTP(w, r)
+	})
+}
+
+// mustHaveFleetSecret rejects the requests of members that don't have the secret of the
+// fleet, so that only members can register the URLs that segments are forwarded to
+func mustHaveFleetSecret(c *Coordinator, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(fleetSecretHeader)), []byte(c.secret)) != 1 {
+			glog.Errorf("Invalid fleet secret from addr=%v", r.RemoteAddr)
+			respondWithError(w, "invalid secret", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func respondWithJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		respondWith500(w, fmt.Sprintf("could not marshal response: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func fleetStatusHandler(c *Coordinator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := c.Status()
+		status.MaxPrice = BroadcastCfg.MaxPrice()
+		respondWithJSON(w, status)
+	})
+}
+
+func fleetClaimHandler(c *Coordinator) http.Handler {
+	return mustHaveFleetSecret(c, mustHaveFormParams(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respondWithJSON(w, map[string]string{"node": c.Claim(r.FormValue("manifestID"), r.FormValue("node"))})
+	}), "manifestID", "node"))
+}
+
+func fleetReleaseHandler(c *Coordinator) http.Handler {
+	return mustHaveFleetSecret(c, mustHaveFormParams(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Release(r.FormValue("manifestID"), r.FormValue("node"))
+		w.WriteHeader(http.StatusOK)
+	}), "manifestID", "node"))
+}
+
+func fleetHeartbeatHandler(c *Coordinator) http.Handler {
+	return mustHaveFleetSecret(c, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var hb fleetHeartbeat
+		if err := json.NewDecoder(r.Body).Decode(&hb); err != nil {
+			respondWith400(w, fmt.Sprintf("invalid heartbeat: %v", err))
+			return
+		}
+		if hb.Node == "" {
+			respondWith400(w, "missing node")
+			return
+		}
+		respondWithJSON(w, &fleetState{MaxPrice: BroadcastCfg.MaxPrice(), Suspended: c.Heartbeat(&hb)})
+	}))
+}
+
+// FleetMember coordinates a broadcaster with the other members of its fleet
+type FleetMember struct {
+	coordinator string
+	token       string
+	secret      string
+	node        string
+	interval    time.Duration
+	client      *http.Client
+
+	mu        sync.Mutex
+	claimed   map[core.ManifestID]bool
+	orchs     map[string]*fleetOrchReport // since the last heartbeat
+	suspended []string                    // since the last heartbeat
+	// fleetSuspended are the orchestrators suspended across the fleet
+	fleetSuspended map[string]bool
+}
+
+// NewFleetMember returns a member of the fleet of the coordinator, whose CLI server is
+// at coordinatorURL and requires token if set, and whose members share secret. node is
+// the URL at which the other members reach the HTTP ingest of the broadcaster
+func NewFleetMember(coordinatorURL, token, secret, node string, interval time.Duration) (*FleetMember, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("must provide a secret")
+	}
+	for _, u := range []string{coordinatorURL, node} {
+		if parsed, err := url.ParseRequestURI(u); err != nil || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid URL %q", u)
+		}
+	}
+	return &FleetMember{
+		coordinator:    strings.TrimSuffix(coordinatorURL, "/"),
+		token:          token,
+		secret:         secret,
+		node:           strings.TrimSuffix(node, "/"),
+		interval:       interval,
+		client:         &http.Client{Timeout: coordinatorTimeout},
+		claimed:        make(map[core.ManifestID]bool),
+		orchs:          make(map[string]*fleetOrchReport),
+		fleetSuspended: make(map[string]bool),
+	}, nil
+}
+
+// post sends a request to the coordinator API and decodes the JSON response into res if set
+func (f *FleetMember) post(path, contentType string, body []byte, res interface{}) error {
+	req, err := http.NewRequest("POST", f.coordinator+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(fleetSecretHeader, f.secret)
+	if f.token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.token)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("coordinator returned %v: %v", resp.Status, strings.TrimSpace(string(data)))
+	}
+	if res == nil {
+		return nil
+	}
+	return json.Unmarshal(data, res)
+}
+
+// Claim claims a stream from the coordinator and returns the URL of the member that
+// ingests it, which is the URL of the broadcaster if it can ingest it
+func (f *FleetMember) Claim(mid core.ManifestID) (string, error) {
+	f.mu.Lock()
+	claimed := f.claimed[mid]
+	f.mu.Unlock()
+	if claimed {
+		return f.node, nil
+	}
+
+	form := url.Values{"manifestID": {string(mid)}, "node": {f.node}}
+	var res struct {
+		Node string `json:"node"`
+	}
+	if err := f.post("/coordinator/claim", "application/x-www-form-urlencoded", []byte(form.Encode()), &res); err != nil {
+		return "", err
+	}
+	if res.Node == f.node {
+		f.mu.Lock()
+		f.claimed[mid] = true
+		f.mu.Unlock()
+	}
+	return res.Node, nil
+}
+
+// Release releases a stream that the broadcaster stopped ingesting
+func (f *FleetMember) Release(mid core.ManifestID) {
+	f.mu.Lock()
+	claimed := f.claimed[mid]
+	delete(f.claimed, mid)
+	f.mu.Unlock()
+	if !claimed {
+		return
+	}
+
+	go func() {
+		form := url.Values{"manifestID": {string(mid)}, "node": {f.node}}
+		if err := f.post("/coordinator/release", "application/x-www-form-urlencoded", []byte(form.Encode()), nil); err != nil {
+			glog.Errorf("Error releasing stream from the fleet coordinator manifestID=%s err=%v", mid, err)
+		}
+	}()
+}
+
+// Node returns the URL at which the other members reach the broadcaster
+func (f *FleetMember) Node() string {
+	return f.node
+}
+
+// forward sends a segment of a stream that another member ingests to that member
+func (f *FleetMember) forward(w http.ResponseWriter, r *http.Request, body []byte, owner string) {
+	target, err := url.Parse(owner)
+	if err != nil {
+		respondWith500(w, fmt.Sprintf("invalid fleet member URL %v: %v", owner, err))
+		return
+	}
+	glog.Infof("Forwarding segment to fleet member url=%s member=%s", r.URL.Path, owner)
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Host = target.Host
+	r.Header.Set(fleetForwardedHeader, f.node)
+	httputil.NewSingleHostReverseProxy(target).ServeHTTP(w, r)
+}
+
+func (f *FleetMember) orchReport(orch string) *fleetOrchReport {
+	report, ok := f.orchs[orch]
+	if !ok {
+		report = &fleetOrchReport{}
+		f.orchs[orch] = report
+	}
+	return report
+}
+
+// recordSegment reports a segment transcoded by an orchestrator
+func (f *FleetMember) recordSegment(orch string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.orchReport(orch).Segments++
+}
+
+// recordFailure reports a segment that an orchestrator failed to transcode
+func (f *FleetMember) recordFailure(orch string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.orchReport(orch).Failures++
+}
+
+// recordSuspension reports an orchestrator suspended for failing verification, so that
+// the other members avoid it as well
+func (f *FleetMember) recordSuspension(orch string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.suspended = append(f.suspended, orch)
+}
+
+// Suspended returns whether an orchestrator is suspended across the fleet
+func (f *FleetMember) Suspended(orch string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.fleetSuspended[orch]
+}
+
+// heartbeat sends the streams of the broadcaster and its reports since the last heartbeat
+// to the coordinator, and applies the state of the fleet
+func (f *FleetMember) heartbeat() error {
+	f.mu.Lock()
+	hb := &fleetHeartbeat{Node: f.node, Streams: []string{}, Orchestrators: f.orchs, Suspended: f.suspended}
+	for mid := range f.claimed {
+		hb.Streams = append(hb.Streams, string(mid))
+	}
+	f.orchs = make(map[string]*fleetOrchReport)
+	f.suspended = nil
+	f.mu.Unlock()
+	sort.Strings(hb.Streams)
+
+	body, err := json.Marshal(hb)
+	if err != nil {
+		return err
+	}
+	var state fleetState
+	if err := f.post("/coordinator/heartbeat", "application/json", body, &state); err != nil {
+		// Send the reports with the next heartbeat
+		f.mu.Lock()
+		for orch, report := range hb.Orchestrators {
+			r := f.orchReport(orch)
+			r.Segments += report.Segments
+			r.Failures += report.Failures
+		}
+		f.suspended = append(f.suspended, hb.Suspended...)
+		f.mu.Unlock()
+		return err
+	}
+
+	f.mu.Lock()
+	f.fleetSuspended = make(map[string]bool)
+	for _, orch := range state.Suspended {
+		f.fleetSuspended[orch] = true
+	}
+	f.mu.Unlock()
+
+	if current := BroadcastCfg.MaxPrice(); (current == nil) != (state.MaxPrice == nil) || (current != nil && current.Cmp(state.MaxPrice) != 0) {
+		BroadcastCfg.SetMaxPrice(state.MaxPrice)
+		if state.MaxPrice == nil {
+			glog.Info("Maximum transcoding price of the fleet is not set, accepting ANY price")
+		} else {
+			glog.Infof("Maximum transcoding price of the fleet: %v per %v pixels", state.MaxPrice.Num(), state.MaxPrice.Denom())
+		}
+	}
+	return nil
+}
+
+// Run sends a heartbeat to the coordinator at every interval until ctx is done
+func (f *FleetMember) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		if err := f.heartbeat(); err != nil {
+			glog.Errorf("Error sending heartbeat to the fleet coordinator err=%v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
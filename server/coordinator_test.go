@@ -0,0 +1,240 @@
+package server
+
+import (
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/lpms/stream"
+)
+
+func TestCoordinator(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	_, err := NewCoordinator(10*time.Second, "")
+	assert.EqualError(err, "must provide a secret")
+	c, err := NewCoordinator(10*time.Second, "fleet")
+	require.Nil(t, err)
+	c.now = func() time.Time { return now }
+
+	// Streams are ingested by the first member that claims them
+	assert.Equal("http://a", c.Claim("foo", "http://a"))
+	assert.Equal("http://a", c.Claim("foo", "http://b"))
+	assert.Equal("http://a", c.Claim("foo", "http://a"))
+	assert.Equal("http://b", c.Claim("bar", "http://b"))
+
+	// Released streams can be claimed by other members
+	c.Release("foo", "http://b")
+	assert.Equal("http://a", c.Claim("foo", "http://b"))
+	c.Release("foo", "http://a")
+	assert.Equal("http://b", c.Claim("foo", "http://b"))
+
+	// Performance is aggregated across members
+	assert.Equal([]string{}, c.Heartbeat(&fleetHeartbeat{Node: "http://a", Orchestrators: map[string]*fleetOrchReport{"https://o1": {Segments: 3, Failures: 1}}}))
+	suspended := c.Heartbeat(&fleetHeartbeat{
+		Node:          "http://b",
+		Streams:       []string{"foo", "bar"},
+		Orchestrators: map[string]*fleetOrchReport{"https://o1": {Segments: 2}, "https://o2": {Failures: 4}},
+		Suspended:     []string{"https://o2"},
+	})
+	assert.Equal([]string{"https://o2"}, suspended)
+
+	status := c.Status()
+	until := now.Add(fleetSuspension)
+	assert.Equal([]FleetNodeStatus{{Node: "http://a", LastHeartbeat: now, Streams: 0}, {Node: "http://b", LastHeartbeat: now, Streams: 2}}, status.Nodes)
+	assert.Equal(map[string]string{"foo": "http://b", "bar": "http://b"}, status.Streams)
+	assert.Equal(map[string]*FleetOrchStats{
+		"https://o1": {Segments: 5, Failures: 1},
+		"https://o2": {Failures: 4, SuspendedUntil: &until},
+	}, status.Orchestrators)
+
+	// The streams of the members that are gone can be claimed
+	now = now.Add(20 * time.Second)
+	c.Heartbeat(&fleetHeartbeat{Node: "http://a"})
+	now = now.Add(15 * time.Second)
+	assert.Equal("http://a", c.Claim("foo", "http://a"))
+	status = c.Status()
+	assert.Len(status.Nodes, 1)
+	assert.Equal(map[string]string{"foo": "http://a"}, status.Streams)
+
+	// A heartbeat restores the claims of a member, unless another member claimed them
+	c.Heartbeat(&fleetHeartbeat{Node: "http://b", Streams: []string{"foo", "bar"}})
+	assert.Equal(map[string]string{"foo": "http://a", "bar": "http://b"}, c.Status().Streams)
+
+	// Suspensions expire
+	now = now.Add(fleetSuspension)
+	assert.Equal([]string{}, c.Heartbeat(&fleetHeartbeat{Node: "http://a"}))
+}
+
+func TestFleetMember(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	defer func() { FleetCoordinator = nil }()
+	defer BroadcastCfg.SetMaxPrice(BroadcastCfg.MaxPrice())
+
+	mux := http.NewServeMux()
+	mux.Handle("/coordinator", coordinatorHandler(fleetStatusHandler))
+	mux.Handle("/coordinator/claim", coordinatorHandler(fleetClaimHandler))
+	mux.Handle("/coordinator/release", coordinatorHandler(fleetReleaseHandler))
+	mux.Handle("/coordinator/heartbeat", coordinatorHandler(fleetHeartbeatHandler))
+	ts := httptest.NewServer(cliAuth("secret", mux))
+	defer ts.Close()
+
+	_, err := NewFleetMember("coordinator", "secret", "fleet", "http://a", time.Second)
+	assert.EqualError(err, `invalid URL "coordinator"`)
+	_, err = NewFleetMember(ts.URL, "secret", "", "http://a", time.Second)
+	assert.EqualError(err, "must provide a secret")
+
+	a, err := NewFleetMember(ts.URL+"/", "secret", "fleet", "http://a/", time.Second)
+	require.Nil(err)
+	assert.Equal("http://a", a.Node())
+	_, err = a.Claim("foo")
+	assert.EqualError(err, "coordinator returned 404 Not Found: not a fleet coordinator")
+
+	FleetCoordinator, err = NewCoordinator(time.Second, "fleet")
+	require.Nil(err)
+	owner, err := a.Claim("foo")
+	require.Nil(err)
+	assert.Equal("http://a", owner)
+
+	b, err := NewFleetMember(ts.URL, "secret", "fleet", "http://b", time.Second)
+	require.Nil(err)
+	owner, err = b.Claim("foo")
+	require.Nil(err)
+	assert.Equal("http://a", owner)
+
+	unauthorized, err := NewFleetMember(ts.URL, "", "fleet", "http://c", time.Second)
+	require.Nil(err)
+	_, err = unauthorized.Claim("foo")
+	assert.EqualError(err, "coordinator returned 401 Unauthorized: Unauthorized")
+
+	// Members must have the secret of the fleet to register
+	stranger, err := NewFleetMember(ts.URL, "secret", "other", "http://c", time.Second)
+	require.Nil(err)
+	_, err = stranger.Claim("bar")
+	assert.EqualError(err, "coordinator returned 401 Unauthorized: invalid secret")
+	assert.EqualError(stranger.heartbeat(), "coordinator returned 401 Unauthorized: invalid secret")
+	for _, node := range FleetCoordinator.Status().Nodes {
+		assert.NotEqual("http://c", node.Node)
+	}
+
+	// The suspensions and the maximum price of the fleet are applied
+	a.recordSegment("https://o1")
+	a.recordFailure("https://o2")
+	a.recordSuspension("https://o2")
+	require.Nil(a.heartbeat())
+	assert.True(a.Suspended("https://o2"))
+	assert.False(a.Suspended("https://o1"))
+	sus := newSuspender()
+	assert.Equal(0, sus.Suspended("https://o2"))
+	Fleet = a
+	assert.Equal(1, sus.Suspended("https://o2"))
+	Fleet = nil
+
+	BroadcastCfg.SetMaxPrice(big.NewRat(1, 3))
+	require.Nil(b.heartbeat())
+	assert.Zero(big.NewRat(1, 3).Cmp(BroadcastCfg.MaxPrice()))
+	BroadcastCfg.SetMaxPrice(nil)
+	require.Nil(b.heartbeat())
+	assert.Nil(BroadcastCfg.MaxPrice())
+
+	status := FleetCoordinator.Status()
+	assert.Equal(map[string]string{"foo": "http://a"}, status.Streams)
+	assert.Equal(1, status.Orchestrators["https://o1"].Segments)
+	assert.Equal(1, status.Orchestrators["https://o2"].Failures)
+
+	resp := httpGetResp(coordinatorHandler(fleetStatusHandler))
+	require.Equal(http.StatusOK, resp.StatusCode)
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Contains(string(body), `"streams":{"foo":"http://a"}`)
+
+	// Reports are kept until the coordinator receives them
+	FleetCoordinator = nil
+	a.recordSegment("https://o1")
+	assert.NotNil(a.heartbeat())
+	FleetCoordinator, err = NewCoordinator(time.Second, "fleet")
+	require.Nil(err)
+	require.Nil(a.heartbeat())
+	status = FleetCoordinator.Status()
+	assert.Equal(1, status.Orchestrators["https://o1"].Segments)
+	// The claims are restored on the new coordinator
+	assert.Equal(map[string]string{"foo": "http://a"}, status.Streams)
+
+	a.Release("foo")
+	assert.Eventually(func() bool { return len(FleetCoordinator.Status().Streams) == 0 }, time.Second, 10*time.Millisecond)
+	owner, err = b.Claim("foo")
+	require.Nil(err)
+	assert.Equal("http://b", owner)
+}
+
+func TestPush_FleetForwarding(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	defer func() { FleetCoordinator, Fleet = nil, nil }()
+
+	var forwarded *http.Request
+	var forwardedBody []byte
+	member := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r
+		forwardedBody, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte("transcoded"))
+	}))
+	defer member.Close()
+
+	mux := http.NewServeMux()
+	mux.Handle("/coordinator/claim", coordinatorHandler(fleetClaimHandler))
+	mux.Handle("/coordinator/release", coordinatorHandler(fleetReleaseHandler))
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	var err error
+	FleetCoordinator, err = NewCoordinator(time.Minute, "fleet")
+	require.Nil(err)
+	FleetCoordinator.Claim("remote", member.URL)
+
+	s := setupServer()
+	defer serverCleanup(s)
+	Fleet, _ = NewFleetMember(ts.URL, "", "fleet", "http://a", time.Minute)
+
+	// Segments of streams ingested by other members are forwarded
+	w := httptest.NewRecorder()
+	s.HandlePush(w, httptest.NewRequest("POST", "/live/remote/1.ts", strings.NewReader("segment")))
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("transcoded", w.Body.String())
+	require.NotNil(forwarded)
+	assert.Equal("/live/remote/1.ts", forwarded.URL.Path)
+	assert.Equal("http://a", forwarded.Header.Get(fleetForwardedHeader))
+	assert.Equal("segment", string(forwardedBody))
+	assert.Nil(s.rtmpConnections["remote"])
+
+	// Forwarded segments and the segments of unclaimed streams are ingested
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/live/remote/2.ts", nil)
+	req.Header.Set(fleetForwardedHeader, member.URL)
+	Fleet.node = member.URL
+	s.HandlePush(w, req)
+	assert.NotNil(s.rtmpConnections["remote"])
+	Fleet.node = "http://a"
+
+	w = httptest.NewRecorder()
+	s.HandlePush(w, httptest.NewRequest("POST", "/live/local/1.ts", nil))
+	assert.NotNil(s.rtmpConnections["local"])
+	assert.Equal("http://a", FleetCoordinator.Status().Streams["local"])
+
+	// Streams claimed by other members can't be ingested over RTMP
+	FleetCoordinator.Claim("rtmp", member.URL)
+	_, err = s.registerConnection(stream.NewBasicRTMPVideoStream(&core.StreamParameters{ManifestID: "rtmp"}))
+	assert.Equal(errIngestedElsewhere, err)
+
+	// Ended streams are released
+	require.Nil(removeRTMPStream(s, "local"))
+	assert.Eventually(func() bool { _, ok := FleetCoordinator.Status().Streams["local"]; return !ok }, time.Second, 10*time.Millisecond)
+}
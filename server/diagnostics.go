@@ -0,0 +1,126 @@
+package server
+
+import (
+	"archive/zip"
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/pprof"
+	"regexp"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/common"
+)
+
+// DiagnosticsToken enables the pprof, expvar and diagnostic bundle endpoints on the CLI server
+// when set. Requests to these endpoints must present the token
+var DiagnosticsToken string
+
+// RecentLogs holds recent log output to include in diagnostic bundles
+var RecentLogs *common.LogBuffer
+
+// Flags with names matching this pattern are redacted from diagnostic bundles
+var sensitiveFlag = regexp.MustCompile(`(?i)(password|secret|token|key|creds|webhook)`)
+
+const redacted = "[REDACTED]"
+
+func (s *LivepeerServer) registerDiagnosticsHandlers(mux *http.ServeMux, token string) {
+	mux.Handle("/debug/pprof/", diagnosticsAuth(token, http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", diagnosticsAuth(token, http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("/debug/pprof/profile", diagnosticsAuth(token, http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", diagnosticsAuth(token, http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("/debug/pprof/trace", diagnosticsAuth(token, http.HandlerFunc(pprof.Trace)))
+	mux.Handle("/debug/vars", diagnosticsAuth(token, expvar.Handler()))
+	mux.Handle("/debug/bundle", diagnosticsAuth(token, diagnosticBundleHandler(s, RecentLogs)))
+}
+
+// diagnosticsAuth only allows requests that present the token either as a bearer token
+// in the Authorization header or via the `token` query parameter
+func diagnosticsAuth(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The token parameter takes precedence, since the bearer token is the CLI token
+		// if the CLI server requires one
+		provided := r.URL.Query().Get("token")
+		if auth := r.Header.Get("Authorization"); provided == "" && strings.HasPrefix(auth, "Bearer ") {
+			provided = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// diagnosticBundleHandler responds with a zip archive containing goroutine dumps, a heap profile,
+// the node configuration, the node status and recent logs
+func diagnosticBundleHandler(s *LivepeerServer, logs *common.LogBuffer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := fmt.Sprintf("livepeer-diagnostics-%s.zip", time.Now().UTC().Format("20060102T150405Z"))
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+
+		zw := zip.NewWriter(w)
+		defer zw.Close()
+
+		entries := []struct {
+			name  string
+			write func(io.Writer) error
+		}{
+			{"goroutines.txt", func(w io.Writer) error { return runtimepprof.Lookup("goroutine").WriteTo(w, 2) }},
+			{"heap.pprof", func(w io.Writer) error {
+				runtime.GC()
+				return runtimepprof.Lookup("heap").WriteTo(w, 0)
+			}},
+			{"config.json", func(w io.Writer) error { return writeJSON(w, flagValues(flag.CommandLine)) }},
+			{"status.json", func(w io.Writer) error { return writeJSON(w, s.GetNodeStatus()) }},
+			{"logs.txt", func(w io.Writer) error {
+				if logs == nil {
+					return nil
+				}
+				_, err := w.Write(logs.Bytes())
+				return err
+			}},
+		}
+
+		for _, e := range entries {
+			f, err := zw.Create(e.name)
+			if err != nil {
+				glog.Errorf("Unable to create diagnostic bundle entry name=%s err=%v", e.name, err)
+				return
+			}
+			if err := e.write(f); err != nil {
+				// Record the failure in the bundle and carry on with the remaining entries
+				glog.Errorf("Unable to write diagnostic bundle entry name=%s err=%v", e.name, err)
+				fmt.Fprintf(f, "\nerror: %v\n", err)
+			}
+		}
+	})
+}
+
+// flagValues returns the values of all flags in the set with sensitive values redacted
+func flagValues(fs *flag.FlagSet) map[string]string {
+	res := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		val := f.Value.String()
+		if val != "" && sensitiveFlag.MatchString(f.Name) {
+			val = redacted
+		}
+		res[f.Name] = val
+	})
+	return res
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
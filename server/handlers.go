@@ -2,16 +2,29 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"math/big"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/audit"
 	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
 	"github.com/livepeer/go-livepeer/eth"
 	"github.com/livepeer/go-livepeer/eth/types"
+	"github.com/livepeer/go-livepeer/features"
 	"github.com/livepeer/go-livepeer/pm"
+	"github.com/livepeer/go-livepeer/verification"
+	"github.com/livepeer/go-livepeer/webhook"
+	"github.com/livepeer/lpms/ffmpeg"
 )
 
 func respondWith500(w http.ResponseWriter, errMsg string) {
@@ -202,7 +215,7 @@ func cancelUnlockHandler(client eth.LivepeerEthClient) http.Handler {
 	})
 }
 
-func withdrawHandler(client eth.LivepeerEthClient) http.Handler {
+func withdrawHandler(client eth.LivepeerEthClient, auditLog *audit.Log) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if client == nil {
 			respondWith500(w, "missing ETH client")
@@ -211,11 +224,13 @@ func withdrawHandler(client eth.LivepeerEthClient) http.Handler {
 
 		tx, err := client.Withdraw()
 		if err != nil {
+			auditWithdrawal(auditLog, "deposit", nil, err)
 			respondWith500(w, fmt.Sprintf("could not execute withdraw: %v", err))
 			return
 		}
 
 		err = client.CheckTx(tx)
+		auditWithdrawal(auditLog, "deposit", tx, err)
 		if err != nil {
 			respondWith500(w, fmt.Sprintf("could not execute withdraw: %v", err))
 			return
@@ -226,6 +241,18 @@ func withdrawHandler(client eth.LivepeerEthClient) http.Handler {
 	})
 }
 
+// auditWithdrawal records a withdrawal of funds of the provided type, e.g. "deposit" or "fees"
+func auditWithdrawal(auditLog *audit.Log, typ string, tx *ethtypes.Transaction, err error) {
+	data := map[string]string{"type": typ}
+	if tx != nil {
+		data["tx"] = tx.Hash().Hex()
+	}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	auditLog.Append(audit.EventWithdrawal, data)
+}
+
 func senderInfoHandler(client eth.LivepeerEthClient) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if client == nil {
@@ -364,3 +391,1049 @@ func voteHandler(client eth.LivepeerEthClient) http.Handler {
 		w.Write(tx.Hash().Bytes())
 	})
 }
+
+func moveStakeHandler(client eth.LivepeerEthClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if client == nil {
+			respondWith500(w, "missing ETH client")
+			return
+		}
+
+		toAddr := r.FormValue("toAddr")
+		if !ethcommon.IsHexAddress(toAddr) {
+			respondWith400(w, "invalid toAddr")
+			return
+		}
+
+		preview, err := eth.PreviewMoveStake(client, ethcommon.HexToAddress(toAddr))
+		if err != nil {
+			respondWith400(w, fmt.Sprintf("could not move stake: %v", err))
+			return
+		}
+
+		tx, err := client.MoveStake(ethcommon.HexToAddress(toAddr))
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not execute moveStake: %v", err))
+			return
+		}
+
+		if err := client.CheckTx(tx); err != nil {
+			respondWith500(w, fmt.Sprintf("could not execute moveStake: %v", err))
+			return
+		}
+
+		data, err := json.Marshal(preview)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not encode stake: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+// previewStakeHandler returns the stake of the delegator before and after the bonding
+// transaction of action, without sending it
+func previewStakeHandler(client eth.LivepeerEthClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if client == nil {
+			respondWith500(w, "missing ETH client")
+			return
+		}
+
+		bigIntParam := func(name string) (*big.Int, bool) {
+			v, err := common.ParseBigInt(r.FormValue(name))
+			if err != nil {
+				respondWith400(w, fmt.Sprintf("invalid %v: %v", name, err))
+				return nil, false
+			}
+			return v, true
+		}
+		addrParam := func(optional bool) (ethcommon.Address, bool) {
+			toAddr := r.FormValue("toAddr")
+			if toAddr == "" && optional {
+				return ethcommon.Address{}, true
+			}
+			if !ethcommon.IsHexAddress(toAddr) {
+				respondWith400(w, "invalid toAddr")
+				return ethcommon.Address{}, false
+			}
+			return ethcommon.HexToAddress(toAddr), true
+		}
+
+		var (
+			preview *eth.StakePreview
+			err     error
+		)
+		switch action := r.FormValue("action"); action {
+		case eth.StakeActionBond:
+			amount, ok := bigIntParam("amount")
+			if !ok {
+				return
+			}
+			to, ok := addrParam(false)
+			if !ok {
+				return
+			}
+			preview, err = eth.PreviewBond(client, amount, to)
+		case eth.StakeActionRebond:
+			id, ok := bigIntParam("unbondingLockId")
+			if !ok {
+				return
+			}
+			to, ok := addrParam(true)
+			if !ok {
+				return
+			}
+			preview, err = eth.PreviewRebond(client, id, to)
+		case eth.StakeActionUnbond:
+			amount, ok := bigIntParam("amount")
+			if !ok {
+				return
+			}
+			preview, err = eth.PreviewUnbond(client, amount)
+		case eth.StakeActionWithdrawStake:
+			id, ok := bigIntParam("unbondingLockId")
+			if !ok {
+				return
+			}
+			preview, err = eth.PreviewWithdrawStake(client, id)
+		case eth.StakeActionMoveStake:
+			to, ok := addrParam(false)
+			if !ok {
+				return
+			}
+			preview, err = eth.PreviewMoveStake(client, to)
+		default:
+			respondWith400(w, fmt.Sprintf("invalid action %v", action))
+			return
+		}
+		if err != nil {
+			respondWith400(w, fmt.Sprintf("could not preview %v: %v", r.FormValue("action"), err))
+			return
+		}
+
+		data, err := json.Marshal(preview)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not encode stake: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+func senderStatsHandler(tracker *core.SenderStatsTracker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tracker == nil {
+			respondWith500(w, "missing sender stats tracker")
+			return
+		}
+
+		var res interface{}
+		if sender := r.FormValue("sender"); sender != "" {
+			if !ethcommon.IsHexAddress(sender) {
+				respondWith400(w, "invalid sender address")
+				return
+			}
+			stats := tracker.Stats(ethcommon.HexToAddress(sender))
+			if stats == nil {
+				respondWithError(w, "unknown sender", http.StatusNotFound)
+				return
+			}
+			res = stats
+		} else {
+			res = tracker.AllStats()
+		}
+
+		data, err := json.Marshal(res)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal sender stats: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+func senderBlacklistHandler(tracker *pm.SenderReputationTracker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tracker == nil {
+			respondWith500(w, "missing sender reputation tracker")
+			return
+		}
+
+		var res interface{}
+		if sender := r.FormValue("sender"); sender != "" {
+			if !ethcommon.IsHexAddress(sender) {
+				respondWith400(w, "invalid sender address")
+				return
+			}
+			rep := tracker.Reputation(ethcommon.HexToAddress(sender))
+			if rep == nil {
+				respondWithError(w, "unknown sender", http.StatusNotFound)
+				return
+			}
+			res = rep
+		} else {
+			res = tracker.Blacklist()
+		}
+
+		data, err := json.Marshal(res)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal sender blacklist: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+func clearSenderBlacklistHandler(tracker *pm.SenderReputationTracker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tracker == nil {
+			respondWith500(w, "missing sender reputation tracker")
+			return
+		}
+
+		sender := r.FormValue("sender")
+		if !ethcommon.IsHexAddress(sender) {
+			respondWith400(w, "invalid sender address")
+			return
+		}
+		if !tracker.Clear(ethcommon.HexToAddress(sender)) {
+			respondWithError(w, "unknown sender", http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// exportTicketsHandler exports the unredeemed winning tickets of the recipient as JSON, or
+// as a protobuf WinningTickets message with format=proto. The tickets stay queued until the
+// importer confirms the import with confirmExportedTicketsHandler
+func exportTicketsHandler(recipient pm.Recipient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			respondWithError(w, "exporting tickets requires a POST request", http.StatusMethodNotAllowed)
+			return
+		}
+		if recipient == nil {
+			respondWith500(w, "missing recipient")
+			return
+		}
+
+		format := r.FormValue("format")
+		if format != "" && format != "json" && format != "proto" {
+			respondWith400(w, fmt.Sprintf("invalid format %v", format))
+			return
+		}
+
+		tickets, err := recipient.ExportTickets()
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not export tickets: %v", err))
+			return
+		}
+
+		contentType := "application/json"
+		var data []byte
+		if format == "proto" {
+			contentType = protoContentType
+			data, err = marshalWinningTickets(tickets)
+		} else {
+			data, err = json.Marshal(tickets)
+		}
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal tickets: %v", err))
+			return
+		}
+
+		glog.Infof("Exported %v winning tickets", len(tickets))
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+// importTicketsHandler queues the winning tickets exported by another node for redemption.
+// Tickets are protobuf encoded if the content type of the request is application/x-protobuf
+// and JSON encoded otherwise
+func importTicketsHandler(recipient pm.Recipient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			respondWithError(w, "importing tickets requires a POST request", http.StatusMethodNotAllowed)
+			return
+		}
+		if recipient == nil {
+			respondWith500(w, "missing recipient")
+			return
+		}
+
+		tickets, err := readWinningTickets(r)
+		if err != nil {
+			respondWith400(w, err.Error())
+			return
+		}
+
+		imported, err := recipient.ImportTickets(tickets)
+		if err != nil {
+			glog.Errorf("Imported %v of %v winning tickets err=%v", imported, len(tickets), err)
+			respondWith400(w, fmt.Sprintf("could not import tickets: %v", err))
+			return
+		}
+
+		glog.Infof("Imported %v of %v winning tickets", imported, len(tickets))
+		data, err := json.Marshal(map[string]int{"imported": imported})
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal response: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+// confirmExportedTicketsHandler removes the exported tickets posted to it from the queue of the
+// recipient, once the node that imported them confirmed the import. Tickets are encoded like
+// the tickets posted to importTicketsHandler
+func confirmExportedTicketsHandler(recipient pm.Recipient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			respondWithError(w, "confirming exported tickets requires a POST request", http.StatusMethodNotAllowed)
+			return
+		}
+		if recipient == nil {
+			respondWith500(w, "missing recipient")
+			return
+		}
+
+		tickets, err := readWinningTickets(r)
+		if err != nil {
+			respondWith400(w, err.Error())
+			return
+		}
+
+		removed, err := recipient.RemoveExportedTickets(tickets)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not remove exported tickets: %v", err))
+			return
+		}
+
+		glog.Infof("Removed %v of %v exported winning tickets", removed, len(tickets))
+		data, err := json.Marshal(map[string]int{"removed": removed})
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal response: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+// readWinningTickets reads the winning tickets of a request, which are protobuf encoded if the
+// content type of the request is application/x-protobuf and JSON encoded otherwise
+func readWinningTickets(r *http.Request) ([]*pm.SignedTicket, error) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read tickets: %v", err)
+	}
+
+	var tickets []*pm.SignedTicket
+	if r.Header.Get("Content-Type") == protoContentType {
+		tickets, err = unmarshalWinningTickets(data)
+	} else {
+		err = json.Unmarshal(data, &tickets)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal tickets: %v", err)
+	}
+	return tickets, nil
+}
+
+func bandwidthHandler(tracker *core.BandwidthTracker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tracker == nil {
+			respondWith500(w, "missing bandwidth tracker")
+			return
+		}
+
+		res := struct {
+			Totals  *core.BandwidthStats
+			Streams []*core.BandwidthStats
+			Peers   []*core.BandwidthStats
+		}{
+			Totals:  tracker.Totals(),
+			Streams: tracker.StreamStats(),
+			Peers:   tracker.PeerStats(),
+		}
+
+		data, err := json.Marshal(res)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal bandwidth stats: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+func canaryHandler(canary *Canary) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if canary == nil {
+			respondWithError(w, "self-test canary not enabled", http.StatusNotFound)
+			return
+		}
+
+		data, err := json.Marshal(canary.Status())
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal canary status: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+// transcoderPoolHandler returns the load and the performance of the transcoders connected to an
+// orchestrator
+func transcoderPoolHandler(manager *core.RemoteTranscoderManager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if manager == nil {
+			respondWithError(w, "node does not accept remote transcoders", http.StatusNotFound)
+			return
+		}
+
+		data, err := json.Marshal(manager.PoolStatus())
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal transcoder pool status: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+func verifierStatusHandler(policy *verification.Policy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var verifier *verification.FailoverVerifier
+		if policy != nil {
+			verifier, _ = policy.Verifier.(*verification.FailoverVerifier)
+		}
+		if verifier == nil {
+			respondWithError(w, "external verifier not enabled", http.StatusNotFound)
+			return
+		}
+
+		data, err := json.Marshal(verifier.Status())
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal verifier status: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+func pixelChecksHandler(checker *verification.PixelChecker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if checker == nil {
+			respondWithError(w, "pixel count checks not enabled", http.StatusNotFound)
+			return
+		}
+
+		data, err := json.Marshal(checker.Stats())
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal pixel count checks: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+func verificationResultsHandler(db *common.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if db == nil {
+			respondWith500(w, "missing database")
+			return
+		}
+
+		filter := &common.DBVerificationFilter{
+			ManifestID:   r.FormValue("manifestID"),
+			Orchestrator: r.FormValue("orchestrator"),
+		}
+		var err error
+		if since := r.FormValue("since"); since != "" {
+			if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+				respondWith400(w, fmt.Sprintf("invalid since time: %v", err))
+				return
+			}
+		}
+		if until := r.FormValue("until"); until != "" {
+			if filter.Until, err = time.Parse(time.RFC3339, until); err != nil {
+				respondWith400(w, fmt.Sprintf("invalid until time: %v", err))
+				return
+			}
+		}
+		if limit := r.FormValue("limit"); limit != "" {
+			if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit <= 0 {
+				respondWith400(w, "limit must be a positive integer")
+				return
+			}
+		}
+
+		results, err := db.VerificationResults(filter)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not query verification results: %v", err))
+			return
+		}
+
+		data, err := json.Marshal(results)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal verification results: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+// transcodeReceipt is a stored transcode receipt along with the Merkle proof of a segment
+type transcodeReceipt struct {
+	*common.DBTranscodeReceipt
+	// Merkle leaf of the segment, if a sequence number is queried
+	Leaf []byte `json:",omitempty"`
+	// Proof that the leaf is part of the root of the receipt
+	Proof [][]byte `json:",omitempty"`
+}
+
+func transcodeReceiptsHandler(db *common.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if db == nil {
+			respondWith500(w, "missing database")
+			return
+		}
+
+		filter := &common.DBTranscodeReceiptFilter{
+			ManifestID:   r.FormValue("manifestID"),
+			Orchestrator: r.FormValue("orchestrator"),
+		}
+		if seqNo := r.FormValue("seqNo"); seqNo != "" {
+			seq, err := strconv.ParseInt(seqNo, 10, 64)
+			if err != nil || seq < 0 {
+				respondWith400(w, "seqNo must be a non-negative integer")
+				return
+			}
+			filter.SeqNo = &seq
+		}
+		if limit := r.FormValue("limit"); limit != "" {
+			var err error
+			if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit <= 0 {
+				respondWith400(w, "limit must be a positive integer")
+				return
+			}
+		}
+
+		receipts, err := db.TranscodeReceipts(filter)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not query transcode receipts: %v", err))
+			return
+		}
+
+		res := make([]*transcodeReceipt, len(receipts))
+		for i, receipt := range receipts {
+			res[i] = &transcodeReceipt{DBTranscodeReceipt: receipt}
+			if filter.SeqNo == nil {
+				continue
+			}
+			segs := make([]core.ReceiptSegment, len(receipt.Segments))
+			for j, s := range receipt.Segments {
+				segs[j] = core.ReceiptSegment{SeqNo: s.SeqNo, Hash: s.Hash}
+			}
+			// The segment may be missing from receipts that did not match the segments received
+			if leaf, proof, err := core.ReceiptProof(segs, *filter.SeqNo); err == nil {
+				res[i].Leaf, res[i].Proof = leaf, proof
+			}
+		}
+
+		data, err := json.Marshal(res)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal transcode receipts: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+// earningsPeriod is the sum of the earnings of each kind in a period of time
+type earningsPeriod struct {
+	Start   *time.Time `json:"start,omitempty"`
+	Fees    *big.Int   `json:"fees"`
+	Rewards *big.Int   `json:"rewards"`
+	Bonded  *big.Int   `json:"bonded"`
+	Count   int        `json:"count"`
+}
+
+func newEarningsPeriod(start *time.Time) *earningsPeriod {
+	return &earningsPeriod{Start: start, Fees: big.NewInt(0), Rewards: big.NewInt(0), Bonded: big.NewInt(0)}
+}
+
+func (p *earningsPeriod) add(e *common.DBEarning) {
+	switch e.Kind {
+	case common.EarningFees:
+		p.Fees.Add(p.Fees, e.Amount)
+	case common.EarningReward:
+		p.Rewards.Add(p.Rewards, e.Amount)
+	case common.EarningBond:
+		p.Bonded.Add(p.Bonded, e.Amount)
+	}
+	p.Count++
+}
+
+// earningsSummary is the response of the earnings endpoint
+type earningsSummary struct {
+	LastIndexedBlock *big.Int            `json:"lastIndexedBlock"`
+	Total            *earningsPeriod     `json:"total"`
+	Periods          []*earningsPeriod   `json:"periods,omitempty"`
+	Earnings         []*common.DBEarning `json:"earnings,omitempty"`
+}
+
+// periodStart returns the start of the day, month or year of t, in UTC
+func periodStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	switch interval {
+	case "day":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// earningsHandler returns the fees, rewards and bonds of the node indexed by the earnings
+// indexer between the since and until times, in total and, with the interval form value, per
+// day, month or year. The indexed earnings are also listed with events=true
+func earningsHandler(db *common.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if db == nil {
+			respondWith500(w, "missing database")
+			return
+		}
+
+		filter := &common.DBEarningsFilter{Kind: r.FormValue("kind")}
+		switch filter.Kind {
+		case "", common.EarningFees, common.EarningReward, common.EarningBond:
+		default:
+			respondWith400(w, fmt.Sprintf("invalid kind %v", filter.Kind))
+			return
+		}
+		var err error
+		if since := r.FormValue("since"); since != "" {
+			if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+				respondWith400(w, fmt.Sprintf("invalid since time: %v", err))
+				return
+			}
+		}
+		if until := r.FormValue("until"); until != "" {
+			if filter.Until, err = time.Parse(time.RFC3339, until); err != nil {
+				respondWith400(w, fmt.Sprintf("invalid until time: %v", err))
+				return
+			}
+		}
+		interval := r.FormValue("interval")
+		switch interval {
+		case "", "day", "month", "year":
+		default:
+			respondWith400(w, fmt.Sprintf("invalid interval %v", interval))
+			return
+		}
+
+		earnings, err := db.Earnings(filter)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not query earnings: %v", err))
+			return
+		}
+		last, err := db.LastIndexedEarningsBlock()
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not query earnings: %v", err))
+			return
+		}
+
+		res := &earningsSummary{LastIndexedBlock: last, Total: newEarningsPeriod(nil)}
+		var period *earningsPeriod
+		for _, e := range earnings {
+			res.Total.add(e)
+			if interval == "" {
+				continue
+			}
+			// Earnings are sorted by block, so the earnings of a period are consecutive
+			if start := periodStart(e.BlockTime, interval); period == nil || !period.Start.Equal(start) {
+				period = newEarningsPeriod(&start)
+				res.Periods = append(res.Periods, period)
+			}
+			period.add(e)
+		}
+		if r.FormValue("events") == "true" {
+			res.Earnings = earnings
+		}
+
+		data, err := json.Marshal(res)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal earnings: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+func auditLogHandler(auditLog *audit.Log) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auditLog == nil {
+			respondWithError(w, "audit log not enabled", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		if err := auditLog.Export(w); err != nil {
+			respondWith500(w, fmt.Sprintf("could not export audit log: %v", err))
+		}
+	})
+}
+
+func verifyAuditLogHandler(auditLog *audit.Log) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auditLog == nil {
+			respondWithError(w, "audit log not enabled", http.StatusNotFound)
+			return
+		}
+
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(auditLog.Export(pw))
+		}()
+		n, err := audit.Verify(pr)
+		pr.Close()
+
+		res := struct {
+			Entries int
+			Valid   bool
+			Error   string `json:",omitempty"`
+		}{
+			Entries: n,
+			Valid:   err == nil,
+		}
+		if err != nil {
+			res.Error = err.Error()
+		}
+
+		data, err := json.Marshal(res)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal audit log verification: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+func webhooksHandler(webhooks *webhook.Dispatcher) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if webhooks == nil {
+			respondWith500(w, "missing webhook dispatcher")
+			return
+		}
+
+		res := struct {
+			Deliveries  []*webhook.DeliveryStats
+			DeadLetters []*webhook.DeadLetter
+		}{
+			Deliveries:  webhooks.Stats(),
+			DeadLetters: []*webhook.DeadLetter{},
+		}
+		if dlq := webhooks.DeadLetterQueue(); dlq != nil {
+			deadLetters, err := dlq.List()
+			if err != nil {
+				respondWith500(w, fmt.Sprintf("could not list dead letters: %v", err))
+				return
+			}
+			res.DeadLetters = deadLetters
+		}
+
+		data, err := json.Marshal(res)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal webhook deliveries: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+// replayWebhookHandler replays the dead letter with the provided id, or all dead letters if id is "all"
+func replayWebhookHandler(webhooks *webhook.Dispatcher) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if webhooks == nil || webhooks.DeadLetterQueue() == nil {
+			respondWithError(w, "webhook dead-letter queue not enabled", http.StatusNotFound)
+			return
+		}
+
+		ids := []string{r.FormValue("id")}
+		if ids[0] == "all" {
+			deadLetters, err := webhooks.DeadLetterQueue().List()
+			if err != nil {
+				respondWith500(w, fmt.Sprintf("could not list dead letters: %v", err))
+				return
+			}
+			ids = ids[:0]
+			for _, dl := range deadLetters {
+				ids = append(ids, dl.ID)
+			}
+		}
+
+		res := struct {
+			Replayed int
+			Failed   map[string]string `json:",omitempty"`
+		}{}
+		for _, id := range ids {
+			err := webhooks.Replay(id)
+			if err == webhook.ErrDeadLetterNotFound {
+				respondWithError(w, fmt.Sprintf("dead letter not found id=%v", id), http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				if res.Failed == nil {
+					res.Failed = make(map[string]string)
+				}
+				res.Failed[id] = err.Error()
+				continue
+			}
+			res.Replayed++
+		}
+
+		data, err := json.Marshal(res)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal webhook replay: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+func discardWebhookHandler(webhooks *webhook.Dispatcher) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if webhooks == nil || webhooks.DeadLetterQueue() == nil {
+			respondWithError(w, "webhook dead-letter queue not enabled", http.StatusNotFound)
+			return
+		}
+
+		id := r.FormValue("id")
+		err := webhooks.DeadLetterQueue().Remove(id)
+		if err == webhook.ErrDeadLetterNotFound {
+			respondWithError(w, fmt.Sprintf("dead letter not found id=%v", id), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not discard dead letter: %v", err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func reloadHandler(reload func() ([]string, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if reload == nil {
+			respondWithError(w, "reload not supported", http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodPost {
+			respondWithError(w, "reload requires a POST request", http.StatusMethodNotAllowed)
+			return
+		}
+
+		changed, err := reload()
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not reload settings: %v", err))
+			return
+		}
+		if changed == nil {
+			changed = []string{}
+		}
+
+		data, err := json.Marshal(map[string][]string{"changed": changed})
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal reloaded settings: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+func configSnapshotHandler(snapshot func() ([]byte, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if snapshot == nil {
+			respondWithError(w, "config snapshot not supported", http.StatusNotFound)
+			return
+		}
+
+		data, err := snapshot()
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not export config snapshot: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/yaml")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+// readyHandler responds once the node serves requests, until it starts shutting down.
+// Getting the status locks the streams and the transcoders of the node, so that a
+// deadlocked node doesn't respond
+func readyHandler(s *LivepeerServer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if Draining() {
+			respondWithError(w, errDraining.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		s.GetNodeStatus()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ready"))
+	})
+}
+
+// setStreamProfilesHandler replaces the profiles of a live stream with presets and
+// profiles in the JSON format of the auth webhook. The orchestrators receive the new
+// profiles with the next segment, so the stream doesn't need to be restarted
+func setStreamProfilesHandler(s *LivepeerServer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			respondWithError(w, "setting stream profiles requires a POST request", http.StatusMethodNotAllowed)
+			return
+		}
+
+		profiles, err := formProfiles(r)
+		if err != nil {
+			respondWith400(w, err.Error())
+			return
+		}
+
+		params, err := s.updateStreamProfiles(core.ManifestID(r.FormValue("manifestID")), profiles)
+		if err == errUnknownStream {
+			respondWithError(w, fmt.Sprintf("unknown stream manifestID=%v", r.FormValue("manifestID")), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			respondWith400(w, fmt.Sprintf("invalid profiles: %v", err))
+			return
+		}
+		names := make([]string, 0, len(params.Profiles))
+		for _, p := range params.Profiles {
+			names = append(names, p.Name)
+		}
+		respondWithJSON(w, map[string][]string{"profiles": names})
+	})
+}
+
+// formProfiles parses the presets of a form, and its profiles in the JSON format of
+// the auth webhook
+func formProfiles(r *http.Request) ([]ffmpeg.VideoProfile, error) {
+	var profiles []ffmpeg.VideoProfile
+	if presets := r.FormValue("presets"); presets != "" {
+		for _, name := range strings.Split(presets, ",") {
+			p, ok := ffmpeg.VideoProfileLookup[strings.TrimSpace(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown preset %v", name)
+			}
+			profiles = append(profiles, p)
+		}
+	}
+	if js := r.FormValue("profiles"); js != "" {
+		resp := &authWebhookResponse{}
+		if err := json.Unmarshal([]byte(js), &resp.Profiles); err != nil {
+			return nil, fmt.Errorf("invalid profiles: %v", err)
+		}
+		parsed, err := jsonProfileToVideoProfile(resp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid profiles: %v", err)
+		}
+		profiles = append(profiles, parsed...)
+	}
+	if len(profiles) == 0 {
+		return nil, errors.New("missing presets or profiles")
+	}
+	return profiles, nil
+}
+
+func featuresHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respondWithJSON(w, features.List())
+	})
+}
+
+// setFeatureHandler enables or disables a feature until the node is restarted, or until
+// -features is reloaded
+func setFeatureHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			respondWithError(w, "setting a feature requires a POST request", http.StatusMethodNotAllowed)
+			return
+		}
+		enabled, err := strconv.ParseBool(r.FormValue("enabled"))
+		if err != nil {
+			respondWith400(w, fmt.Sprintf("invalid enabled value: %v", r.FormValue("enabled")))
+			return
+		}
+		name := r.FormValue("name")
+		if err := features.Set(name, enabled); err != nil {
+			respondWithError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		glog.Infof("Feature %v enabled=%v", name, enabled)
+		w.WriteHeader(http.StatusOK)
+	})
+}
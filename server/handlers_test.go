@@ -1,6 +1,7 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,14 +11,28 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/livepeer/go-livepeer/audit"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/drivers"
 	"github.com/livepeer/go-livepeer/eth"
+	lpTypes "github.com/livepeer/go-livepeer/eth/types"
+	"github.com/livepeer/go-livepeer/features"
 	"github.com/livepeer/go-livepeer/pm"
+	"github.com/livepeer/go-livepeer/verification"
+	"github.com/livepeer/go-livepeer/webhook"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/livepeer/lpms/stream"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -473,7 +488,7 @@ func TestCancelUnlockHandler_Success(t *testing.T) {
 }
 
 func TestWithdrawHandler_MissingClient(t *testing.T) {
-	handler := withdrawHandler(nil)
+	handler := withdrawHandler(nil, nil)
 
 	resp := httpPostFormResp(handler, nil)
 	body, _ := ioutil.ReadAll(resp.Body)
@@ -485,7 +500,7 @@ func TestWithdrawHandler_MissingClient(t *testing.T) {
 
 func TestWithdrawHandler_TransactionSubmissionError(t *testing.T) {
 	client := &eth.MockClient{}
-	handler := withdrawHandler(client)
+	handler := withdrawHandler(client, nil)
 
 	client.On("Withdraw").Return(nil, errors.New("Withdraw error"))
 
@@ -498,7 +513,7 @@ func TestWithdrawHandler_TransactionSubmissionError(t *testing.T) {
 }
 func TestWithdrawHandler_TransactionWaitError(t *testing.T) {
 	client := &eth.MockClient{}
-	handler := withdrawHandler(client)
+	handler := withdrawHandler(client, nil)
 
 	client.On("Withdraw").Return(nil, nil)
 	client.On("CheckTx", mock.Anything).Return(errors.New("CheckTx error"))
@@ -513,7 +528,7 @@ func TestWithdrawHandler_TransactionWaitError(t *testing.T) {
 
 func TestWithdrawHandler_Success(t *testing.T) {
 	client := &eth.MockClient{}
-	handler := withdrawHandler(client)
+	handler := withdrawHandler(client, nil)
 
 	client.On("Withdraw").Return(nil, nil)
 	client.On("CheckTx", mock.Anything).Return(nil)
@@ -832,3 +847,1066 @@ func httpResp(handler http.Handler, method string, body io.Reader, headers map[s
 
 	return w.Result()
 }
+
+func TestSenderStatsHandler_MissingTracker(t *testing.T) {
+	handler := senderStatsHandler(nil)
+
+	resp := httpGetResp(handler)
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal("missing sender stats tracker", strings.TrimSpace(string(body)))
+}
+
+func TestSenderStatsHandler_InvalidSender(t *testing.T) {
+	handler := senderStatsHandler(core.NewSenderStatsTracker())
+
+	form := url.Values{
+		"sender": {"foo"},
+	}
+	resp := httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	assert.Equal("invalid sender address", strings.TrimSpace(string(body)))
+}
+
+func TestSenderStatsHandler_UnknownSender(t *testing.T) {
+	handler := senderStatsHandler(core.NewSenderStatsTracker())
+
+	form := url.Values{
+		"sender": {pm.RandAddress().Hex()},
+	}
+	resp := httpPostFormResp(handler, strings.NewReader(form.Encode()))
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestSenderStatsHandler_Success(t *testing.T) {
+	tracker := core.NewSenderStatsTracker()
+	sender := pm.RandAddress()
+	tracker.RecordSegment(sender, core.ManifestID("foo"), 100, big.NewRat(1, 1))
+	handler := senderStatsHandler(tracker)
+
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// All senders
+	resp := httpGetResp(handler)
+	body, _ := ioutil.ReadAll(resp.Body)
+	require.Equal(http.StatusOK, resp.StatusCode)
+
+	var all []*core.SenderStats
+	require.Nil(json.Unmarshal(body, &all))
+	require.Len(all, 1)
+	assert.Equal(sender, all[0].Sender)
+	assert.Equal(int64(100), all[0].PixelsTranscoded)
+
+	// Single sender
+	form := url.Values{
+		"sender": {sender.Hex()},
+	}
+	resp = httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	body, _ = ioutil.ReadAll(resp.Body)
+	require.Equal(http.StatusOK, resp.StatusCode)
+
+	var stats core.SenderStats
+	require.Nil(json.Unmarshal(body, &stats))
+	assert.Equal(sender, stats.Sender)
+	assert.Equal(1, stats.Sessions)
+	assert.Zero(stats.FeesEarned.Cmp(big.NewRat(1, 1)))
+}
+
+func TestSenderBlacklistHandler_MissingTracker(t *testing.T) {
+	resp := httpGetResp(senderBlacklistHandler(nil))
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal("missing sender reputation tracker", strings.TrimSpace(string(body)))
+
+	resp = httpPostFormResp(clearSenderBlacklistHandler(nil), nil)
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+}
+
+func TestSenderBlacklistHandler_Success(t *testing.T) {
+	tracker := pm.NewSenderReputationTracker(pm.SenderReputationConfig{MaxDoubleSpends: 1})
+	sender := pm.RandAddress()
+	tracker.RecordViolation(sender, pm.ViolationDoubleSpend)
+	tracker.RecordViolation(pm.RandAddress(), pm.ViolationInvalidSignature)
+	handler := senderBlacklistHandler(tracker)
+
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Blacklisted senders
+	resp := httpGetResp(handler)
+	body, _ := ioutil.ReadAll(resp.Body)
+	require.Equal(http.StatusOK, resp.StatusCode)
+
+	var blacklist []*pm.SenderReputation
+	require.Nil(json.Unmarshal(body, &blacklist))
+	require.Len(blacklist, 1)
+	assert.Equal(sender, blacklist[0].Sender)
+	assert.Equal(int64(1), blacklist[0].DoubleSpends)
+
+	// Single sender
+	form := url.Values{
+		"sender": {sender.Hex()},
+	}
+	resp = httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	body, _ = ioutil.ReadAll(resp.Body)
+	require.Equal(http.StatusOK, resp.StatusCode)
+
+	var rep pm.SenderReputation
+	require.Nil(json.Unmarshal(body, &rep))
+	assert.True(rep.Blacklisted)
+
+	form.Set("sender", "foo")
+	resp = httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	form.Set("sender", pm.RandAddress().Hex())
+	resp = httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func TestClearSenderBlacklistHandler(t *testing.T) {
+	tracker := pm.NewSenderReputationTracker(pm.SenderReputationConfig{MaxDoubleSpends: 1})
+	sender := pm.RandAddress()
+	tracker.RecordViolation(sender, pm.ViolationDoubleSpend)
+	handler := clearSenderBlacklistHandler(tracker)
+
+	assert := assert.New(t)
+
+	form := url.Values{
+		"sender": {"foo"},
+	}
+	resp := httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	form.Set("sender", sender.Hex())
+	resp = httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.False(tracker.IsBlacklisted(sender))
+
+	resp = httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func TestBandwidthHandler_MissingTracker(t *testing.T) {
+	handler := bandwidthHandler(nil)
+
+	resp := httpGetResp(handler)
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal("missing bandwidth tracker", strings.TrimSpace(string(body)))
+}
+
+func TestBandwidthHandler_Success(t *testing.T) {
+	tracker := core.NewBandwidthTracker()
+	tracker.RecordIngress(core.ManifestID("foo"), "", 100)
+	tracker.RecordEgress(core.ManifestID("foo"), "https://127.0.0.1:8935", 50)
+	handler := bandwidthHandler(tracker)
+
+	assert := assert.New(t)
+	require := require.New(t)
+
+	resp := httpGetResp(handler)
+	body, _ := ioutil.ReadAll(resp.Body)
+	require.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("application/json", resp.Header.Get("Content-Type"))
+
+	var res struct {
+		Totals  *core.BandwidthStats
+		Streams []*core.BandwidthStats
+		Peers   []*core.BandwidthStats
+	}
+	require.Nil(json.Unmarshal(body, &res))
+	assert.Equal(int64(100), res.Totals.IngressBytes)
+	assert.Equal(int64(50), res.Totals.EgressBytes)
+	require.Len(res.Streams, 1)
+	assert.Equal("foo", res.Streams[0].ID)
+	require.Len(res.Peers, 1)
+	assert.Equal("https://127.0.0.1:8935", res.Peers[0].ID)
+	assert.Equal(int64(0), res.Peers[0].IngressBytes)
+	assert.Equal(int64(50), res.Peers[0].EgressBytes)
+}
+
+func TestCanaryHandler_Disabled(t *testing.T) {
+	handler := canaryHandler(nil)
+
+	resp := httpGetResp(handler)
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+	assert.Equal("self-test canary not enabled", strings.TrimSpace(string(body)))
+}
+
+func TestCanaryHandler_Success(t *testing.T) {
+	canary := NewCanary("127.0.0.1:1", []byte("foo"), time.Second, time.Minute)
+	handler := canaryHandler(canary)
+
+	assert := assert.New(t)
+	require := require.New(t)
+
+	resp := httpGetResp(handler)
+	body, _ := ioutil.ReadAll(resp.Body)
+	require.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("application/json", resp.Header.Get("Content-Type"))
+
+	var status CanaryStatus
+	require.Nil(json.Unmarshal(body, &status))
+	assert.Equal(canary.mid, status.ManifestID)
+	assert.Equal(time.Minute, status.Interval)
+	assert.Nil(status.Last)
+}
+
+func TestTranscoderPoolHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	resp := httpGetResp(transcoderPoolHandler(nil))
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+	assert.Equal("node does not accept remote transcoders", strings.TrimSpace(string(body)))
+
+	m := core.NewRemoteTranscoderManager()
+	go m.Manage(&common.StubServerStream{}, 5)
+	time.Sleep(1 * time.Millisecond)
+
+	resp = httpGetResp(transcoderPoolHandler(m))
+	body, _ = ioutil.ReadAll(resp.Body)
+	require.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("application/json", resp.Header.Get("Content-Type"))
+
+	var status core.TranscoderPoolStatus
+	require.Nil(json.Unmarshal(body, &status))
+	assert.Equal(5, status.Capacity)
+	require.Len(status.Transcoders, 1)
+	assert.Equal("TestAddress", status.Transcoders[0].Address)
+	assert.Equal(0, status.Transcoders[0].Segments)
+}
+
+func tempAuditLog(t *testing.T) (*audit.Log, string, func()) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.Nil(t, err)
+	path := filepath.Join(dir, "audit.log")
+	l, err := audit.Open(path)
+	require.Nil(t, err)
+	return l, path, func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestWithdrawHandler_AuditLog(t *testing.T) {
+	auditLog, _, cleanup := tempAuditLog(t)
+	defer cleanup()
+
+	client := &eth.MockClient{}
+	handler := withdrawHandler(client, auditLog)
+
+	client.On("Withdraw").Return(nil, nil)
+	client.On("CheckTx", mock.Anything).Return(errors.New("CheckTx error"))
+
+	resp := httpPostFormResp(handler, nil)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	var buf bytes.Buffer
+	require.Nil(t, auditLog.Export(&buf))
+	var e audit.Entry
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &e))
+	assert.Equal(t, audit.EventWithdrawal, e.Type)
+	assert.Equal(t, map[string]string{"type": "deposit", "error": "CheckTx error"}, e.Data)
+}
+
+func TestAuditLogHandlers_Disabled(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, handler := range []http.Handler{auditLogHandler(nil), verifyAuditLogHandler(nil)} {
+		resp := httpGetResp(handler)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(http.StatusNotFound, resp.StatusCode)
+		assert.Equal("audit log not enabled", strings.TrimSpace(string(body)))
+	}
+}
+
+func TestAuditLogHandler_Success(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	auditLog, _, cleanup := tempAuditLog(t)
+	defer cleanup()
+	auditLog.Append(audit.EventPriceChanged, map[string]string{"pricePerPixel": "1"})
+	auditLog.Append(audit.EventPriceChanged, map[string]string{"pricePerPixel": "2"})
+
+	resp := httpGetResp(auditLogHandler(auditLog))
+	body, _ := ioutil.ReadAll(resp.Body)
+	require.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("application/x-ndjson", resp.Header.Get("Content-Type"))
+	n, err := audit.Verify(bytes.NewReader(body))
+	assert.Nil(err)
+	assert.Equal(2, n)
+}
+
+func TestVerifyAuditLogHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	auditLog, path, cleanup := tempAuditLog(t)
+	defer cleanup()
+	auditLog.Append(audit.EventPriceChanged, map[string]string{"pricePerPixel": "1"})
+
+	type result struct {
+		Entries int
+		Valid   bool
+		Error   string
+	}
+	verify := func() result {
+		resp := httpGetResp(verifyAuditLogHandler(auditLog))
+		body, _ := ioutil.ReadAll(resp.Body)
+		require.Equal(http.StatusOK, resp.StatusCode)
+		var res result
+		require.Nil(json.Unmarshal(body, &res))
+		return res
+	}
+
+	assert.Equal(result{Entries: 1, Valid: true}, verify())
+
+	// Tamper with the log
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	require.Nil(err)
+	f.WriteString(`{"seq":1,"type":"Withdrawal"}` + "\n")
+	f.Close()
+
+	res := verify()
+	assert.Equal(1, res.Entries)
+	assert.False(res.Valid)
+	assert.Contains(res.Error, "line 2")
+}
+
+func tempWebhooks(t *testing.T, opts webhook.Options) (*webhook.Dispatcher, func()) {
+	dir, err := ioutil.TempDir("", "webhooks")
+	require.Nil(t, err)
+	dlq, err := webhook.NewDeadLetterQueue(dir, 100)
+	require.Nil(t, err)
+	return webhook.NewDispatcher(http.DefaultClient, opts, dlq), func() { os.RemoveAll(dir) }
+}
+
+func TestWebhookHandlers_Disabled(t *testing.T) {
+	assert := assert.New(t)
+
+	resp := httpGetResp(webhooksHandler(nil))
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+
+	noDLQ := webhook.NewDispatcher(http.DefaultClient, webhook.DefaultOptions, nil)
+	resp = httpPostFormResp(replayWebhookHandler(noDLQ), strings.NewReader("id=all"))
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+	resp = httpPostFormResp(discardWebhookHandler(noDLQ), strings.NewReader("id=1-ab"))
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+
+	// Deliveries are reported without a dead-letter queue
+	resp = httpGetResp(webhooksHandler(noDLQ))
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.JSONEq(`{"Deliveries":[],"DeadLetters":[]}`, string(body))
+}
+
+func TestWebhookHandlers(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var up int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&up) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	webhooks, cleanup := tempWebhooks(t, webhook.Options{MaxAttempts: 1})
+	defer cleanup()
+	for i := 0; i < 3; i++ {
+		resp, err := webhooks.Post("auth", ts.URL, nil, []byte("{}"))
+		require.Nil(err)
+		resp.Body.Close()
+	}
+
+	type status struct {
+		Deliveries  []*webhook.DeliveryStats
+		DeadLetters []*webhook.DeadLetter
+	}
+	getStatus := func() status {
+		resp := httpGetResp(webhooksHandler(webhooks))
+		require.Equal(http.StatusOK, resp.StatusCode)
+		var s status
+		require.Nil(json.NewDecoder(resp.Body).Decode(&s))
+		return s
+	}
+
+	s := getStatus()
+	require.Len(s.Deliveries, 1)
+	assert.Equal("auth", s.Deliveries[0].Webhook)
+	assert.Equal(int64(3), s.Deliveries[0].Failed)
+	require.Len(s.DeadLetters, 3)
+	assert.Equal(ts.URL, s.DeadLetters[0].URL)
+
+	// Discard a dead letter
+	resp := httpPostFormResp(discardWebhookHandler(webhooks), strings.NewReader("id="+s.DeadLetters[0].ID))
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	resp = httpPostFormResp(discardWebhookHandler(webhooks), strings.NewReader("id="+s.DeadLetters[0].ID))
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+
+	// Replaying while the webhook is down keeps the dead letter
+	resp = httpPostFormResp(replayWebhookHandler(webhooks), strings.NewReader("id="+s.DeadLetters[1].ID))
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Contains(string(body), `"Replayed":0`)
+	assert.Contains(string(body), "503 Service Unavailable")
+	assert.Len(getStatus().DeadLetters, 2)
+
+	// Replay all dead letters once the webhook is back
+	atomic.StoreInt32(&up, 1)
+	resp = httpPostFormResp(replayWebhookHandler(webhooks), strings.NewReader("id=all"))
+	body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.JSONEq(`{"Replayed":2}`, string(body))
+
+	s = getStatus()
+	assert.Empty(s.DeadLetters)
+	assert.Equal(int64(2), s.Deliveries[0].Delivered)
+
+	resp = httpPostFormResp(replayWebhookHandler(webhooks), strings.NewReader("id=1-ab"))
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func TestVerifierStatusHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	resp := httpGetResp(verifierStatusHandler(nil))
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+	resp = httpGetResp(verifierStatusHandler(&verification.Policy{Retries: 2}))
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+
+	verifier := verification.NewFailoverVerifier([]string{"http://verifier1", "http://verifier2"}, verification.FailoverOptions{})
+	resp = httpGetResp(verifierStatusHandler(&verification.Policy{Verifier: verifier}))
+	assert.Equal(http.StatusOK, resp.StatusCode)
+
+	var status []verification.VerifierStatus
+	assert.Nil(json.NewDecoder(resp.Body).Decode(&status))
+	assert.Len(status, 2)
+	assert.Equal("http://verifier1", status[0].Addr)
+	assert.True(status[0].Healthy)
+}
+
+func TestVerificationResultsHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	resp := httpGetResp(verificationResultsHandler(nil))
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+
+	dbh, dbraw, err := common.TempDB(t)
+	require.Nil(err)
+	defer dbh.Close()
+	defer dbraw.Close()
+
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i, orch := range []string{"https://o1", "https://o2", "https://o1"} {
+		require.Nil(dbh.InsertVerificationResult(&common.DBVerificationResult{
+			CreatedAt:    start.Add(time.Duration(i) * time.Minute),
+			ManifestID:   "mid",
+			SeqNo:        uint64(i),
+			Orchestrator: orch,
+			Score:        1,
+		}))
+	}
+
+	query := func(form string) ([]*common.DBVerificationResult, int) {
+		resp := httpPostFormResp(verificationResultsHandler(dbh), strings.NewReader(form))
+		if resp.StatusCode != http.StatusOK {
+			return nil, resp.StatusCode
+		}
+		var results []*common.DBVerificationResult
+		require.Nil(json.NewDecoder(resp.Body).Decode(&results))
+		return results, resp.StatusCode
+	}
+
+	results, code := query("")
+	assert.Equal(http.StatusOK, code)
+	assert.Len(results, 3)
+
+	results, _ = query("orchestrator=https://o1&manifestID=mid")
+	require.Len(results, 2)
+	assert.Equal(uint64(2), results[0].SeqNo)
+	assert.Equal(uint64(0), results[1].SeqNo)
+
+	results, _ = query("since=2020-06-01T12:01:00Z&until=2020-06-01T12:02:00Z")
+	require.Len(results, 1)
+	assert.Equal("https://o2", results[0].Orchestrator)
+
+	results, _ = query("limit=1")
+	require.Len(results, 1)
+	assert.Equal(uint64(2), results[0].SeqNo)
+
+	results, _ = query("manifestID=other")
+	assert.Empty(results)
+
+	for _, form := range []string{"since=yesterday", "until=1590969600", "limit=0", "limit=foo"} {
+		_, code = query(form)
+		assert.Equal(http.StatusBadRequest, code, form)
+	}
+}
+
+func TestEarningsHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	resp := httpGetResp(earningsHandler(nil))
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+
+	dbh, dbraw, err := common.TempDB(t)
+	require.Nil(err)
+	defer dbh.Close()
+	defer dbraw.Close()
+
+	start := time.Date(2020, 6, 30, 12, 0, 0, 0, time.UTC)
+	var earnings []*common.DBEarning
+	for i, kind := range []string{common.EarningFees, common.EarningReward, common.EarningFees, common.EarningBond} {
+		earnings = append(earnings, &common.DBEarning{
+			Kind:        kind,
+			Amount:      big.NewInt(int64(100 * (i + 1))),
+			BlockNumber: int64(i),
+			BlockTime:   start.Add(time.Duration(i) * 12 * time.Hour),
+			TxHash:      ethcommon.BigToHash(big.NewInt(int64(i))),
+		})
+	}
+	require.Nil(dbh.InsertEarnings(earnings, big.NewInt(10)))
+
+	query := func(form string) (*earningsSummary, int) {
+		resp := httpPostFormResp(earningsHandler(dbh), strings.NewReader(form))
+		if resp.StatusCode != http.StatusOK {
+			return nil, resp.StatusCode
+		}
+		var res earningsSummary
+		require.Nil(json.NewDecoder(resp.Body).Decode(&res))
+		return &res, resp.StatusCode
+	}
+
+	res, code := query("")
+	assert.Equal(http.StatusOK, code)
+	assert.Equal(big.NewInt(10), res.LastIndexedBlock)
+	assert.Equal(big.NewInt(400), res.Total.Fees)
+	assert.Equal(big.NewInt(200), res.Total.Rewards)
+	assert.Equal(big.NewInt(400), res.Total.Bonded)
+	assert.Equal(4, res.Total.Count)
+	assert.Empty(res.Periods)
+	assert.Empty(res.Earnings)
+
+	// Test the earnings are summed per period
+	res, _ = query("interval=month&events=true")
+	require.Len(res.Periods, 2)
+	assert.Equal(time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), res.Periods[0].Start.UTC())
+	assert.Equal(big.NewInt(100), res.Periods[0].Fees)
+	assert.Equal(1, res.Periods[0].Count)
+	assert.Equal(time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC), res.Periods[1].Start.UTC())
+	assert.Equal(big.NewInt(300), res.Periods[1].Fees)
+	assert.Equal(big.NewInt(200), res.Periods[1].Rewards)
+	assert.Len(res.Earnings, 4)
+
+	res, _ = query("interval=day&kind=fees")
+	require.Len(res.Periods, 2)
+	assert.Equal(2, res.Total.Count)
+	assert.Zero(res.Total.Rewards.Sign())
+
+	res, _ = query("since=2020-07-01T00:00:00Z&until=2020-07-01T12:00:00Z")
+	assert.Equal(1, res.Total.Count)
+	assert.Equal(big.NewInt(200), res.Total.Rewards)
+
+	for _, form := range []string{"since=yesterday", "until=1590969600", "interval=week", "kind=foo"} {
+		_, code = query(form)
+		assert.Equal(http.StatusBadRequest, code, form)
+	}
+}
+
+func TestPixelChecksHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	resp := httpGetResp(pixelChecksHandler(nil))
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+
+	resp = httpGetResp(pixelChecksHandler(verification.NewPixelChecker(1)))
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	var stats []*verification.PixelCheckStats
+	assert.Nil(json.NewDecoder(resp.Body).Decode(&stats))
+	assert.Empty(stats)
+}
+
+func TestReloadHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	resp := httpPostFormResp(reloadHandler(nil), nil)
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+
+	var changed []string
+	var err error
+	reload := func() ([]string, error) { return changed, err }
+
+	resp = httpGetResp(reloadHandler(reload))
+	assert.Equal(http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp = httpPostFormResp(reloadHandler(reload), nil)
+	require.Equal(http.StatusOK, resp.StatusCode)
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(`{"changed":[]}`, string(body))
+
+	changed = []string{"maxSessions", "v"}
+	resp = httpPostFormResp(reloadHandler(reload), nil)
+	require.Equal(http.StatusOK, resp.StatusCode)
+	body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(`{"changed":["maxSessions","v"]}`, string(body))
+
+	err = errors.New("invalid config")
+	resp = httpPostFormResp(reloadHandler(reload), nil)
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+	body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal("could not reload settings: invalid config", strings.TrimSpace(string(body)))
+}
+
+func TestConfigSnapshotHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	resp := httpGetResp(configSnapshotHandler(nil))
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+
+	var err error
+	snapshot := func() ([]byte, error) { return []byte("maxSessions: 20\n"), err }
+	resp = httpGetResp(configSnapshotHandler(snapshot))
+	require.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("application/yaml", resp.Header.Get("Content-Type"))
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal("maxSessions: 20\n", string(body))
+
+	err = errors.New("unreadable config file")
+	resp = httpGetResp(configSnapshotHandler(snapshot))
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+	body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal("could not export config snapshot: unreadable config file", strings.TrimSpace(string(body)))
+}
+
+func TestReadyHandler(t *testing.T) {
+	assert := assert.New(t)
+	defer func() { nodeDrainer = newDrainer() }()
+
+	s := setupServer()
+	defer serverCleanup(s)
+	resp := httpGetResp(readyHandler(s))
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal("ready", string(body))
+
+	Drain()
+	resp = httpGetResp(readyHandler(s))
+	assert.Equal(http.StatusServiceUnavailable, resp.StatusCode)
+	body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal("node is shutting down", strings.TrimSpace(string(body)))
+}
+
+var testFeature = features.Register("handlersTest", "Feature of the handler tests")
+
+func TestFeatureHandlers(t *testing.T) {
+	assert := assert.New(t)
+	defer features.Set("handlersTest", false)
+
+	code, body := postForm(setFeatureHandler(), url.Values{"name": {"handlersTest"}, "enabled": {"true"}})
+	assert.Equal(http.StatusOK, code)
+	assert.True(testFeature.Enabled())
+
+	resp := httpGetResp(featuresHandler())
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	data, _ := ioutil.ReadAll(resp.Body)
+	assert.Contains(string(data), `{"name":"handlersTest","description":"Feature of the handler tests","enabled":true}`)
+
+	code, body = postForm(setFeatureHandler(), url.Values{"name": {"handlersTest"}, "enabled": {"maybe"}})
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal("invalid enabled value: maybe", body)
+	code, body = postForm(setFeatureHandler(), url.Values{"name": {"unknown"}, "enabled": {"false"}})
+	assert.Equal(http.StatusNotFound, code)
+	assert.Equal("unknown feature unknown", body)
+	assert.True(testFeature.Enabled())
+
+	resp = httpGetResp(setFeatureHandler())
+	assert.Equal(http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestSetStreamProfilesHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	s := setupServer()
+	defer serverCleanup(s)
+	drivers.NodeStorage = drivers.NewMemoryDriver(nil)
+
+	mid := core.SplitStreamIDString(t.Name()).ManifestID
+	strm := stream.NewBasicRTMPVideoStream(&core.StreamParameters{ManifestID: mid, Profiles: []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9}})
+	cxn, err := s.registerConnection(strm)
+	require.Nil(err)
+	handler := setStreamProfilesHandler(s)
+
+	code, body := postForm(handler, url.Values{"manifestID": {string(mid)}, "presets": {"P240p30fps16x9"}, "profiles": {`[{"name":"custom","width":320,"height":180,"bitrate":250000}]`}})
+	assert.Equal(http.StatusOK, code)
+	assert.Equal(`{"profiles":["P240p30fps16x9","custom"]}`, body)
+	profiles := cxn.streamParams().Profiles
+	require.Len(profiles, 2)
+	assert.Equal("320x180", profiles[1].Resolution)
+
+	code, body = postForm(handler, url.Values{"manifestID": {string(mid)}})
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal("missing presets or profiles", body)
+	code, body = postForm(handler, url.Values{"manifestID": {string(mid)}, "presets": {"P240p30fps16x9,unknown"}})
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal("unknown preset unknown", body)
+	code, _ = postForm(handler, url.Values{"manifestID": {string(mid)}, "profiles": {"nope"}})
+	assert.Equal(http.StatusBadRequest, code)
+	code, _ = postForm(handler, url.Values{"manifestID": {string(mid)}, "profiles": {`[{"name":"bad","profile":"unknown"}]`}})
+	assert.Equal(http.StatusBadRequest, code)
+	code, body = postForm(handler, url.Values{"manifestID": {"unknown"}, "presets": {"P240p30fps16x9"}})
+	assert.Equal(http.StatusNotFound, code)
+	assert.Equal("unknown stream manifestID=unknown", body)
+	// Rejected updates leave the profiles alone
+	assert.Equal(profiles, cxn.streamParams().Profiles)
+
+	resp := httpGetResp(handler)
+	assert.Equal(http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func exportedTickets() []*pm.SignedTicket {
+	return []*pm.SignedTicket{
+		{
+			Ticket: &pm.Ticket{
+				Recipient:              pm.RandAddress(),
+				Sender:                 pm.RandAddress(),
+				FaceValue:              big.NewInt(100),
+				WinProb:                big.NewInt(200),
+				SenderNonce:            1,
+				RecipientRandHash:      pm.RandHash(),
+				CreationRound:          2,
+				CreationRoundBlockHash: pm.RandHash(),
+				ParamsExpirationBlock:  big.NewInt(3),
+			},
+			RecipientRand: big.NewInt(4),
+			Sig:           pm.RandBytes(65),
+		},
+	}
+}
+
+func TestTicketHandlers_MissingRecipient(t *testing.T) {
+	assert := assert.New(t)
+
+	resp := httpPostFormResp(exportTicketsHandler(nil), nil)
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal("missing recipient", strings.TrimSpace(string(body)))
+
+	resp = httpPostResp(importTicketsHandler(nil), strings.NewReader("[]"), nil)
+	body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal("missing recipient", strings.TrimSpace(string(body)))
+
+	resp = httpPostResp(confirmExportedTicketsHandler(nil), strings.NewReader("[]"), nil)
+	body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal("missing recipient", strings.TrimSpace(string(body)))
+}
+
+func TestExportTicketsHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	tickets := exportedTickets()
+	recipient := new(pm.MockRecipient)
+	recipient.On("ExportTickets").Return(tickets, nil)
+	handler := exportTicketsHandler(recipient)
+
+	resp := httpGetResp(handler)
+	assert.Equal(http.StatusMethodNotAllowed, resp.StatusCode)
+	recipient.AssertNotCalled(t, "ExportTickets")
+
+	// JSON by default
+	resp = httpPostFormResp(handler, nil)
+	body, _ := ioutil.ReadAll(resp.Body)
+	require.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("application/json", resp.Header.Get("Content-Type"))
+	var res []*pm.SignedTicket
+	require.Nil(json.Unmarshal(body, &res))
+	assert.Equal(tickets, res)
+
+	// Protobuf
+	form := url.Values{"format": {"proto"}}
+	resp = httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	body, _ = ioutil.ReadAll(resp.Body)
+	require.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("application/x-protobuf", resp.Header.Get("Content-Type"))
+	res, err := unmarshalWinningTickets(body)
+	require.Nil(err)
+	assert.Equal(tickets, res)
+
+	// Invalid formats are rejected before the tickets are exported
+	form.Set("format", "xml")
+	resp = httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	assert.Equal("invalid format xml", strings.TrimSpace(string(body)))
+	recipient.AssertNumberOfCalls(t, "ExportTickets", 2)
+
+	recipient = new(pm.MockRecipient)
+	recipient.On("ExportTickets").Return(nil, errors.New("ExportTickets error"))
+	resp = httpPostFormResp(exportTicketsHandler(recipient), nil)
+	body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal("could not export tickets: ExportTickets error", strings.TrimSpace(string(body)))
+}
+
+func TestImportTicketsHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	tickets := exportedTickets()
+	recipient := new(pm.MockRecipient)
+	recipient.On("ImportTickets", tickets).Return(1, nil)
+	handler := importTicketsHandler(recipient)
+
+	resp := httpGetResp(handler)
+	assert.Equal(http.StatusMethodNotAllowed, resp.StatusCode)
+
+	// JSON
+	data, err := json.Marshal(tickets)
+	require.Nil(err)
+	resp = httpPostResp(handler, bytes.NewReader(data), nil)
+	body, _ := ioutil.ReadAll(resp.Body)
+	require.Equal(http.StatusOK, resp.StatusCode)
+	assert.JSONEq(`{"imported":1}`, string(body))
+
+	// Protobuf
+	data, err = marshalWinningTickets(tickets)
+	require.Nil(err)
+	resp = httpPostResp(handler, bytes.NewReader(data), map[string]string{"Content-Type": "application/x-protobuf"})
+	body, _ = ioutil.ReadAll(resp.Body)
+	require.Equal(http.StatusOK, resp.StatusCode)
+	assert.JSONEq(`{"imported":1}`, string(body))
+	recipient.AssertNumberOfCalls(t, "ImportTickets", 2)
+
+	resp = httpPostResp(handler, strings.NewReader("foo"), nil)
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	recipient.AssertNumberOfCalls(t, "ImportTickets", 2)
+
+	recipient = new(pm.MockRecipient)
+	recipient.On("ImportTickets", mock.Anything).Return(0, errors.New("ImportTickets error"))
+	resp = httpPostResp(importTicketsHandler(recipient), strings.NewReader("[]"), nil)
+	body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	assert.Equal("could not import tickets: ImportTickets error", strings.TrimSpace(string(body)))
+}
+
+func TestConfirmExportedTicketsHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	tickets := exportedTickets()
+	recipient := new(pm.MockRecipient)
+	recipient.On("RemoveExportedTickets", tickets).Return(1, nil)
+	handler := confirmExportedTicketsHandler(recipient)
+
+	resp := httpGetResp(handler)
+	assert.Equal(http.StatusMethodNotAllowed, resp.StatusCode)
+
+	data, err := json.Marshal(tickets)
+	require.Nil(err)
+	resp = httpPostResp(handler, bytes.NewReader(data), nil)
+	body, _ := ioutil.ReadAll(resp.Body)
+	require.Equal(http.StatusOK, resp.StatusCode)
+	assert.JSONEq(`{"removed":1}`, string(body))
+
+	data, err = marshalWinningTickets(tickets)
+	require.Nil(err)
+	resp = httpPostResp(handler, bytes.NewReader(data), map[string]string{"Content-Type": "application/x-protobuf"})
+	require.Equal(http.StatusOK, resp.StatusCode)
+	recipient.AssertNumberOfCalls(t, "RemoveExportedTickets", 2)
+
+	resp = httpPostResp(handler, strings.NewReader("foo"), nil)
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	recipient.AssertNumberOfCalls(t, "RemoveExportedTickets", 2)
+
+	recipient = new(pm.MockRecipient)
+	recipient.On("RemoveExportedTickets", mock.Anything).Return(0, errors.New("RemoveExportedTickets error"))
+	resp = httpPostResp(confirmExportedTicketsHandler(recipient), strings.NewReader("[]"), nil)
+	body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal("could not remove exported tickets: RemoveExportedTickets error", strings.TrimSpace(string(body)))
+}
+
+func TestMoveStakeHandler_MissingClient(t *testing.T) {
+	handler := moveStakeHandler(nil)
+
+	resp := httpPostFormResp(handler, nil)
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal("missing ETH client", strings.TrimSpace(string(body)))
+}
+
+func TestMoveStakeHandler_InvalidToAddr(t *testing.T) {
+	client := &eth.MockClient{}
+	handler := moveStakeHandler(client)
+
+	form := url.Values{
+		"toAddr": {"foo"},
+	}
+	resp := httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	assert.Equal("invalid toAddr", strings.TrimSpace(string(body)))
+}
+
+func TestMoveStakeHandler_NoStake(t *testing.T) {
+	client := &eth.MockClient{}
+	handler := moveStakeHandler(client)
+
+	addr := pm.RandAddress()
+	client.On("Account").Return(accounts.Account{Address: addr})
+	client.On("GetDelegator", addr).Return(&lpTypes.Delegator{BondedAmount: big.NewInt(0), Status: "Unbonded"}, nil)
+
+	form := url.Values{
+		"toAddr": {pm.RandAddress().Hex()},
+	}
+	resp := httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	assert.Equal("could not move stake: delegator has no stake to move", strings.TrimSpace(string(body)))
+	client.AssertNotCalled(t, "MoveStake", mock.Anything)
+}
+
+func TestMoveStakeHandler_TransactionSubmissionError(t *testing.T) {
+	client := &eth.MockClient{}
+	handler := moveStakeHandler(client)
+
+	addr := pm.RandAddress()
+	to := pm.RandAddress()
+	client.On("Account").Return(accounts.Account{Address: addr})
+	client.On("GetDelegator", addr).Return(&lpTypes.Delegator{BondedAmount: big.NewInt(100), DelegateAddress: pm.RandAddress(), Status: "Bonded"}, nil)
+	client.On("MoveStake", to).Return(nil, errors.New("MoveStake error"))
+
+	form := url.Values{
+		"toAddr": {to.Hex()},
+	}
+	resp := httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal("could not execute moveStake: MoveStake error", strings.TrimSpace(string(body)))
+}
+
+func TestMoveStakeHandler_Success(t *testing.T) {
+	client := &eth.MockClient{}
+	handler := moveStakeHandler(client)
+
+	addr := pm.RandAddress()
+	to := pm.RandAddress()
+	client.On("Account").Return(accounts.Account{Address: addr})
+	client.On("GetDelegator", addr).Return(&lpTypes.Delegator{BondedAmount: big.NewInt(100), DelegateAddress: pm.RandAddress(), Status: "Bonded"}, nil)
+	client.On("MoveStake", to).Return(nil, nil)
+	client.On("CheckTx", mock.Anything).Return(nil)
+
+	form := url.Values{
+		"toAddr": {to.Hex()},
+	}
+	resp := httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert := assert.New(t)
+	require := require.New(t)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	var preview eth.StakePreview
+	require.Nil(json.Unmarshal(body, &preview))
+	assert.Equal(eth.StakeActionMoveStake, preview.Action)
+	assert.Equal(big.NewInt(100), preview.NewStake)
+	assert.Equal(to, preview.NewDelegate)
+	assert.Equal("Pending", preview.NewStatus)
+}
+
+func TestPreviewStakeHandler_InvalidAction(t *testing.T) {
+	client := &eth.MockClient{}
+	handler := previewStakeHandler(client)
+
+	form := url.Values{
+		"action": {"foo"},
+	}
+	resp := httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	assert.Equal("invalid action foo", strings.TrimSpace(string(body)))
+}
+
+func TestPreviewStakeHandler_InvalidAmount(t *testing.T) {
+	client := &eth.MockClient{}
+	handler := previewStakeHandler(client)
+
+	form := url.Values{
+		"action": {"unbond"},
+		"amount": {"foo"},
+	}
+	resp := httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(strings.TrimSpace(string(body)), "invalid amount")
+}
+
+func TestPreviewStakeHandler_Unbond(t *testing.T) {
+	client := &eth.MockClient{}
+	handler := previewStakeHandler(client)
+
+	addr := pm.RandAddress()
+	client.On("Account").Return(accounts.Account{Address: addr})
+	client.On("GetDelegator", addr).Return(&lpTypes.Delegator{BondedAmount: big.NewInt(100), DelegateAddress: pm.RandAddress(), Status: "Bonded"}, nil)
+	client.On("CurrentRound").Return(big.NewInt(10), nil)
+
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Test unbonding more than the stake
+	form := url.Values{
+		"action": {"unbond"},
+		"amount": {"101"},
+	}
+	resp := httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	assert.Equal("could not preview unbond: unbond amount 101 must be positive and at most the stake 100", strings.TrimSpace(string(body)))
+
+	form.Set("amount", "40")
+	resp = httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	var preview eth.StakePreview
+	require.Nil(json.Unmarshal(body, &preview))
+	assert.Equal(big.NewInt(60), preview.NewStake)
+	assert.Equal(big.NewInt(40), preview.Unbonding)
+	assert.Equal(big.NewInt(10), preview.WithdrawRound)
+	client.AssertNotCalled(t, "MoveStake", mock.Anything)
+}
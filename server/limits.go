@@ -0,0 +1,181 @@
+package server
+
+import (
+	"math"
+	gonet "net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"google.golang.org/grpc/codes"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/monitor"
+)
+
+// Limits of the public endpoints of orchestrators, which protect them from floods of
+// requests. A limit of 0 disables it
+var (
+	// Requests per second accepted from an IP address, over HTTP and gRPC
+	IPRateLimit float64
+	// Segments per second accepted from a broadcaster
+	SenderRateLimit float64
+	// GetOrchestrator requests served at the same time
+	MaxDiscoveryRequests = 100
+	// Size in bytes of the body of segment requests
+	MaxSegmentSize int64 = 128 << 20
+)
+
+// Timeouts of the connections to the public endpoints of orchestrators, against
+// clients that open connections and send requests as slowly as possible
+var (
+	readHeaderTimeout = 10 * time.Second
+	idleTimeout       = 2 * time.Minute
+)
+
+// Reasons for rejecting requests, used as metric labels
+const (
+	rejectIPRate      = "ip_rate_limit"
+	rejectSenderRate  = "sender_rate_limit"
+	rejectDiscovery   = "discovery_limit"
+	rejectSegmentSize = "segment_too_large"
+)
+
+// requestLimits enforces the limits of the public endpoints of an orchestrator. A nil
+// requestLimits doesn't limit anything
+type requestLimits struct {
+	ips       *rateLimiter
+	senders   *rateLimiter
+	discovery chan struct{}
+}
+
+func newRequestLimits() *requestLimits {
+	l := &requestLimits{
+		ips:     newRateLimiter(IPRateLimit),
+		senders: newRateLimiter(SenderRateLimit),
+	}
+	if MaxDiscoveryRequests > 0 {
+		l.discovery = make(chan struct{}, MaxDiscoveryRequests)
+	}
+	return l
+}
+
+func (l *requestLimits) allowIP(ip string) bool {
+	return l == nil || l.ips.allow(ip)
+}
+
+func (l *requestLimits) allowSender(sender string) bool {
+	return l == nil || l.senders.allow(sender)
+}
+
+// startDiscovery returns false if too many GetOrchestrator requests are being served, and
+// otherwise a function to call once the request is served
+func (l *requestLimits) startDiscovery() (func(), bool) {
+	if l == nil || l.discovery == nil {
+		return func() {}, true
+	}
+	select {
+	case l.discovery <- struct{}{}:
+		return func() { <-l.discovery }, true
+	default:
+		return func() {}, false
+	}
+}
+
+// rejectRequest records a request rejected for reason
+func rejectRequest(reason, client string) {
+	glog.V(common.DEBUG).Infof("Rejected request client=%v reason=%v", client, reason)
+	if monitor.Enabled {
+		monitor.RequestRejected(reason)
+	}
+}
+
+// rateLimiter is a token bucket per key, e.g. per IP address. Each bucket holds up to two
+// seconds of requests, so that bursts of requests go through. A nil rateLimiter allows
+// every request
+type rateLimiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	pruned  time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   math.Max(1, 2*rate),
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+func (l *rateLimiter) allow(key string) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.pruned) > time.Minute {
+		// Full buckets are the same as new buckets
+		for k, b := range l.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+				delete(l.buckets, k)
+			}
+		}
+		l.pruned = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// clientIP returns the IP address of the client of r. Requests served through a relay
+// come from the relay, which sends the address of the client in X-Forwarded-For
+func clientIP(r *http.Request) string {
+	if relayed, _ := r.Context().Value(relayedKey{}).(bool); relayed {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			addrs := strings.Split(fwd, ",")
+			return strings.TrimSpace(addrs[len(addrs)-1])
+		}
+	}
+	host, _, err := gonet.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// grpcError responds to a gRPC request with an error, without going through the gRPC
+// server
+func grpcError(w http.ResponseWriter, code codes.Code, msg string) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Grpc-Status", strconv.Itoa(int(code)))
+	w.Header().Set("Grpc-Message", msg)
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/drivers"
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/livepeer/lpms/stream"
+)
+
+func TestRateLimiter(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(newRateLimiter(0))
+	var nilLimiter *rateLimiter
+	assert.True(nilLimiter.allow("foo"))
+
+	now := time.Unix(1000, 0)
+	l := newRateLimiter(2)
+	l.now = func() time.Time { return now }
+
+	// Bursts of two seconds of requests go through
+	for i := 0; i < 4; i++ {
+		assert.True(l.allow("foo"))
+	}
+	assert.False(l.allow("foo"))
+	// Keys have their own bucket
+	assert.True(l.allow("bar"))
+
+	// Buckets refill at the rate of the limiter
+	now = now.Add(500 * time.Millisecond)
+	assert.True(l.allow("foo"))
+	assert.False(l.allow("foo"))
+	now = now.Add(time.Hour)
+	for i := 0; i < 4; i++ {
+		assert.True(l.allow("foo"))
+	}
+	assert.False(l.allow("foo"))
+
+	// Full buckets are dropped
+	assert.Len(l.buckets, 1)
+	assert.NotContains(l.buckets, "bar")
+
+	// Limiters below one request per second still allow single requests
+	l = newRateLimiter(0.1)
+	l.now = func() time.Time { return now }
+	assert.True(l.allow("foo"))
+	assert.False(l.allow("foo"))
+}
+
+func TestRequestLimits_Discovery(t *testing.T) {
+	assert := assert.New(t)
+
+	var nilLimits *requestLimits
+	done, ok := nilLimits.startDiscovery()
+	assert.True(ok)
+	done()
+	assert.True(nilLimits.allowIP("foo"))
+	assert.True(nilLimits.allowSender("foo"))
+
+	oldMax := MaxDiscoveryRequests
+	defer func() { MaxDiscoveryRequests = oldMax }()
+	MaxDiscoveryRequests = 2
+	l := newRequestLimits()
+
+	done1, ok := l.startDiscovery()
+	assert.True(ok)
+	done2, ok := l.startDiscovery()
+	assert.True(ok)
+	_, ok = l.startDiscovery()
+	assert.False(ok)
+	done1()
+	done3, ok := l.startDiscovery()
+	assert.True(ok)
+	done2()
+	done3()
+
+	MaxDiscoveryRequests = 0
+	l = newRequestLimits()
+	for i := 0; i < 10; i++ {
+		_, ok = l.startDiscovery()
+		assert.True(ok)
+	}
+}
+
+func TestGetOrchestrator_DiscoveryLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	oldMax := MaxDiscoveryRequests
+	defer func() { MaxDiscoveryRequests = oldMax }()
+	MaxDiscoveryRequests = 1
+
+	drivers.NodeStorage = drivers.NewMemoryDriver(nil)
+	lp := &lphttp{orchestrator: &stubOrchestrator{offchain: true}, limits: newRequestLimits()}
+	_, err := lp.GetOrchestrator(context.Background(), &net.OrchestratorRequest{})
+	assert.Nil(err)
+
+	done, ok := lp.limits.startDiscovery()
+	assert.True(ok)
+	_, err = lp.GetOrchestrator(context.Background(), &net.OrchestratorRequest{})
+	assert.Equal(codes.ResourceExhausted, status.Code(err))
+	done()
+	_, err = lp.GetOrchestrator(context.Background(), &net.OrchestratorRequest{})
+	assert.Nil(err)
+}
+
+func TestClientIP(t *testing.T) {
+	assert := assert.New(t)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "1.2.3.4:5678"
+	assert.Equal("1.2.3.4", clientIP(req))
+	req.RemoteAddr = "[::1]:5678"
+	assert.Equal("::1", clientIP(req))
+	req.RemoteAddr = "foo"
+	assert.Equal("foo", clientIP(req))
+
+	// X-Forwarded-For is only trusted for requests served through a relay
+	req.RemoteAddr = "1.2.3.4:5678"
+	req.Header.Set("X-Forwarded-For", "10.0.0.1, 5.6.7.8")
+	assert.Equal("1.2.3.4", clientIP(req))
+	relayed := req.WithContext(context.WithValue(req.Context(), relayedKey{}, true))
+	assert.Equal("5.6.7.8", clientIP(relayed))
+	relayed.Header.Del("X-Forwarded-For")
+	assert.Equal("1.2.3.4", clientIP(relayed))
+}
+
+func TestLPHTTP_IPRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	oldRate := IPRateLimit
+	defer func() { IPRateLimit = oldRate }()
+	IPRateLimit = 1
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/foo", func(w http.ResponseWriter, r *http.Request) {})
+	lp := &lphttp{transRPC: mux, limits: newRequestLimits()}
+	serve := func(remoteAddr string, grpc bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/foo", nil)
+		req.RemoteAddr = remoteAddr
+		if grpc {
+			req.ProtoMajor = 2
+			req.Header.Set("Content-Type", "application/grpc")
+		}
+		w := httptest.NewRecorder()
+		lp.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(http.StatusOK, serve("1.2.3.4:1", false).Code)
+	assert.Equal(http.StatusOK, serve("1.2.3.4:2", false).Code)
+	w := serve("1.2.3.4:3", false)
+	assert.Equal(http.StatusTooManyRequests, w.Code)
+	assert.Equal(common.ErrCodeIngestRateLimited, common.ParseErrorCode(w.Header().Get(errorCodeHeader)))
+
+	// gRPC clients get a gRPC error
+	w = serve("1.2.3.4:4", true)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("application/grpc", w.Header().Get("Content-Type"))
+	assert.Equal(strconv.Itoa(int(codes.ResourceExhausted)), w.Header().Get("Grpc-Status"))
+
+	// Other clients are not limited
+	assert.Equal(http.StatusOK, serve("5.6.7.8:1", false).Code)
+}
+
+func TestServeSegment_Limits(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	oldRate, oldSize := SenderRateLimit, MaxSegmentSize
+	defer func() { SenderRateLimit, MaxSegmentSize = oldRate, oldSize }()
+	SenderRateLimit, MaxSegmentSize = 1, 4
+
+	orch := &mockOrchestrator{}
+	orch.On("VerifySig", mock.Anything, mock.Anything, mock.Anything).Return(true)
+	drivers.NodeStorage = drivers.NewMemoryDriver(nil)
+	uri, _ := url.Parse("foo")
+	orch.On("ServiceURI").Return(uri)
+	orch.On("Address").Return(ethcommon.Address{})
+	orch.On("PriceInfo", mock.Anything).Return(&net.PriceInfo{}, nil)
+	orch.On("TicketParams", mock.Anything, mock.Anything).Return(&net.TicketParams{}, nil)
+	orch.On("ProcessPayment", mock.Anything, mock.Anything).Return(nil)
+	orch.On("SufficientBalance", mock.Anything, mock.Anything).Return(true)
+
+	lp := &lphttp{orchestrator: orch, limits: newRequestLimits()}
+	s := &BroadcastSession{
+		Broadcaster: stubBroadcaster2(),
+		Params: &core.StreamParameters{
+			ManifestID: core.RandomManifestID(),
+			Profiles:   []ffmpeg.VideoProfile{ffmpeg.P720p30fps16x9},
+		},
+	}
+	creds, err := genSegCreds(s, &stream.HLSSegment{})
+	require.Nil(err)
+	headers := map[string]string{
+		paymentHeader: "",
+		segmentHeader: creds,
+	}
+
+	// Segments larger than the limit are rejected
+	resp := httpPostResp(http.HandlerFunc(lp.ServeSegment), bytes.NewReader([]byte("fooba")), headers)
+	resp.Body.Close()
+	assert.Equal(http.StatusRequestEntityTooLarge, resp.StatusCode)
+	assert.Equal(common.ErrCodeIngestTooLarge, common.ParseErrorCode(resp.Header.Get(errorCodeHeader)))
+
+	// Segments up to the limit are read
+	resp = httpPostResp(http.HandlerFunc(lp.ServeSegment), bytes.NewReader([]byte("foob")), headers)
+	resp.Body.Close()
+	assert.Equal(common.ErrCodeIngestHashMismatch, common.ParseErrorCode(resp.Header.Get(errorCodeHeader)))
+
+	// Broadcasters sending too many segments are rejected
+	resp = httpPostResp(http.HandlerFunc(lp.ServeSegment), bytes.NewReader([]byte("foo")), headers)
+	resp.Body.Close()
+	assert.Equal(http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(common.ErrCodeIngestRateLimited, common.ParseErrorCode(resp.Header.Get(errorCodeHeader)))
+}
@@ -22,12 +22,15 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/livepeer/go-livepeer/audit"
 	"github.com/livepeer/go-livepeer/drivers"
 	"github.com/livepeer/go-livepeer/monitor"
 	"github.com/livepeer/go-livepeer/net"
 	"github.com/livepeer/go-livepeer/pm"
+	"github.com/livepeer/go-livepeer/webhook"
 
 	"github.com/golang/glog"
 	"github.com/livepeer/go-livepeer/common"
@@ -48,6 +51,7 @@ var errDiscovery = errors.New("ErrDiscovery")
 var errNoOrchs = errors.New("ErrNoOrchs")
 var errUnknownStream = errors.New("ErrUnknownStream")
 var errMismatchedParams = errors.New("Mismatched type for stream params")
+var errNoProfiles = errors.New("ErrNoProfiles")
 
 const HLSWaitInterval = time.Second
 const HLSBufferCap = uint(43200) //12 hrs assuming 1s segment
@@ -59,7 +63,23 @@ const BroadcastRetry = 15 * time.Second
 
 var BroadcastJobVideoProfiles = []ffmpeg.VideoProfile{ffmpeg.P240p30fps4x3, ffmpeg.P360p30fps16x9}
 
-var AuthWebhookURL string
+// authWebhookURL can be changed while the node runs, so it is accessed atomically
+var authWebhookURL atomic.Value
+
+// AuthWebhookURL returns the URL of the webhook that authenticates the streams
+func AuthWebhookURL() string {
+	u, _ := authWebhookURL.Load().(string)
+	return u
+}
+
+// SetAuthWebhookURL sets the URL of the webhook that authenticates the streams, which is
+// empty if streams aren't authenticated
+func SetAuthWebhookURL(u string) {
+	authWebhookURL.Store(u)
+}
+
+// Webhooks delivers the outbound webhooks of the node
+var Webhooks = webhook.NewDispatcher(&http.Client{Timeout: common.HTTPTimeout}, webhook.DefaultOptions, nil)
 
 // For HTTP push watchdog
 var httpPushTimeout = 1 * time.Minute
@@ -74,9 +94,91 @@ type rtmpConnection struct {
 	stream      stream.RTMPVideoStream
 	pl          core.PlaylistManager
 	profile     *ffmpeg.VideoProfile
-	params      *core.StreamParameters
 	sessManager *BroadcastSessionsManager
+	bandwidth   *core.BandwidthTracker
+	auditLog    *audit.Log
+	receipts    *core.ReceiptTracker
 	lastUsed    time.Time
+	// order of the segments transcoded in parallel, if they are
+	order *segmentOrder
+
+	// params are replaced, not mutated, when the profiles of the stream change,
+	// so segments keep the params they started with
+	paramsLock sync.RWMutex
+	params     *core.StreamParameters
+}
+
+func (cxn *rtmpConnection) streamParams() *core.StreamParameters {
+	cxn.paramsLock.RLock()
+	defer cxn.paramsLock.RUnlock()
+	return cxn.params
+}
+
+// updateProfiles replaces the profiles of the stream. Segments that are already being
+// transcoded keep the old profiles, and the next segments are sent to the orchestrators
+// with the new profiles. Renditions that were removed leave the master playlist.
+func (cxn *rtmpConnection) updateProfiles(profiles []ffmpeg.VideoProfile) (*core.StreamParameters, error) {
+	if len(profiles) == 0 {
+		return nil, errNoProfiles
+	}
+	cxn.paramsLock.Lock()
+	defer cxn.paramsLock.Unlock()
+
+	params := &core.StreamParameters{}
+	*params = *cxn.params
+	params.Profiles = append([]ffmpeg.VideoProfile(nil), profiles...)
+	for i, p := range params.Profiles {
+		// Set output formats of HTTP push streams if not explicitly specified
+		if p.Format == ffmpeg.FormatNone {
+			params.Profiles[i].Format = params.Format
+		}
+		if err := common.ValidateProfileCodec(params.Profiles[i]); err != nil {
+			return nil, fmt.Errorf("%v profile=%v", err, p.Name)
+		}
+	}
+	if KeyframeAlignment != nil {
+		params.Profiles = KeyframeAlignment.Profiles(params.Profiles)
+	}
+	caps, err := core.JobCapabilities(params)
+	if err != nil {
+		return nil, err
+	}
+	params.Capabilities = caps
+
+	kept := make(map[string]bool)
+	for _, p := range params.Profiles {
+		kept[p.Name] = true
+	}
+	for _, p := range cxn.params.Profiles {
+		if !kept[p.Name] {
+			cxn.pl.RemoveHLSRendition(p.Name)
+		}
+	}
+	cxn.params = params
+	cxn.sessManager.updateParams(params)
+	return params, nil
+}
+
+// insertRendition inserts a transcoded segment in the playlist of its rendition, unless
+// the profile was removed from the stream while the segment was being transcoded, which
+// would add the removed rendition back to the master playlist
+func (cxn *rtmpConnection) insertRendition(profile *ffmpeg.VideoProfile, seqNo uint64, uri string, duration float64) error {
+	cxn.paramsLock.RLock()
+	defer cxn.paramsLock.RUnlock()
+	if cxn.params != nil {
+		found := false
+		for _, p := range cxn.params.Profiles {
+			if p.Name == profile.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			glog.V(common.DEBUG).Infof("Not inserting segment of removed rendition manifestID=%s seqNo=%d profile=%s", cxn.mid, seqNo, profile.Name)
+			return nil
+		}
+	}
+	return cxn.pl.InsertHLSSegment(profile, seqNo, uri, duration)
 }
 
 type LivepeerServer struct {
@@ -85,6 +187,7 @@ type LivepeerServer struct {
 	LivepeerNode          *core.LivepeerNode
 	HTTPMux               *http.ServeMux
 	ExposeCurrentManifest bool
+	Canary                *Canary
 
 	// Thread sensitive fields. All accesses to the
 	// following fields should be protected by `connectionLock`
@@ -108,6 +211,9 @@ type authWebhookResponse struct {
 		Profile string `json:"profile"`
 		GOP     string `json:"gop"`
 	} `json:"profiles"`
+	// Capabilities are required of orchestrators for the stream, in addition
+	// to the -requiredCapabilities of the broadcaster
+	Capabilities []string `json:"capabilities"`
 }
 
 func NewLivepeerServer(rtmpAddr string, lpNode *core.LivepeerNode, httpIngest bool, transcodingOptions string) (*LivepeerServer, error) {
@@ -150,6 +256,7 @@ func NewLivepeerServer(rtmpAddr string, lpNode *core.LivepeerNode, httpIngest bo
 	}
 	if lpNode.NodeType == core.BroadcasterNode && httpIngest {
 		opts.HttpMux.HandleFunc("/live/", ls.HandlePush)
+		opts.HttpMux.HandleFunc("/vod/", ls.HandleVOD)
 	}
 	return ls, nil
 }
@@ -179,7 +286,7 @@ func (s *LivepeerServer) StartMediaServer(ctx context.Context, httpAddr string)
 	if s.LivepeerNode.NodeType == core.BroadcasterNode {
 		go func() {
 			glog.V(4).Infof("HTTP Server listening on http://%v", httpAddr)
-			ec <- http.ListenAndServe(httpAddr, s.HTTPMux)
+			ec <- http.ListenAndServe(httpAddr, monitor.ReportPanics(s.HTTPMux))
 		}()
 	}
 
@@ -207,6 +314,11 @@ func createRTMPStreamIDHandler(s *LivepeerServer) func(url *url.URL) (strmID str
 		var err error
 		var key string
 		profiles := []ffmpeg.VideoProfile{}
+		requiredCaps := BroadcastCfg.RequiredCapabilities()
+		if Draining() {
+			glog.Errorf("Rejecting stream url=%s: %v", url, errDraining)
+			return nil
+		}
 		if resp, err = authenticateStream(url.String()); err != nil {
 			glog.Error("Authentication denied for ", err)
 			return nil
@@ -228,6 +340,13 @@ func createRTMPStreamIDHandler(s *LivepeerServer) func(url *url.URL) (strmID str
 			if len(resp.Profiles) <= 0 && len(resp.Presets) <= 0 {
 				profiles = BroadcastJobVideoProfiles
 			}
+
+			caps, err := core.ParseCapabilities(strings.Join(resp.Capabilities, ","))
+			if err != nil {
+				glog.Errorf("Rejecting stream url=%s: %v", url, err)
+				return nil
+			}
+			requiredCaps = append(append([]core.Capability(nil), requiredCaps...), caps...)
 		} else {
 			profiles = BroadcastJobVideoProfiles
 		}
@@ -243,7 +362,7 @@ func createRTMPStreamIDHandler(s *LivepeerServer) func(url *url.URL) (strmID str
 		// Ensure there's no concurrent StreamID with the same name
 		s.connectionLock.RLock()
 		defer s.connectionLock.RUnlock()
-		if core.MaxSessions > 0 && len(s.rtmpConnections) >= core.MaxSessions {
+		if maxSessions := core.MaxSessions(); maxSessions > 0 && len(s.rtmpConnections) >= maxSessions {
 			glog.Error("Too many connections")
 			return nil
 		}
@@ -260,13 +379,15 @@ func createRTMPStreamIDHandler(s *LivepeerServer) func(url *url.URL) (strmID str
 			ManifestID: mid,
 			RtmpKey:    key,
 			// HTTP push mutates `profiles` so make a copy of it
-			Profiles: append([]ffmpeg.VideoProfile(nil), profiles...),
+			Profiles:             append([]ffmpeg.VideoProfile(nil), profiles...),
+			RequiredCapabilities: requiredCaps,
 		}
 	}
 }
 
 func authenticateStream(url string) (*authWebhookResponse, error) {
-	if AuthWebhookURL == "" {
+	webhookURL := AuthWebhookURL()
+	if webhookURL == "" {
 		return nil, nil
 	}
 	started := time.Now()
@@ -275,7 +396,7 @@ func authenticateStream(url string) (*authWebhookResponse, error) {
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.Post(AuthWebhookURL, "application/json", bytes.NewBuffer(jsonValue))
+	resp, err := Webhooks.PostNoDeadLetter("auth", webhookURL, nil, jsonValue)
 
 	if err != nil {
 		return nil, err
@@ -342,6 +463,14 @@ func jsonProfileToVideoProfile(resp *authWebhookResponse) ([]ffmpeg.VideoProfile
 			Profile:      encodingProfile,
 			GOP:          gop,
 		}
+		// Some codecs are only muxed into one format
+		prof.Format = common.CodecFormat(encodingProfile)
+		if common.ProfileCodec(encodingProfile) == common.NoVideo {
+			// Audio-only renditions have no video to size, and the bitrate is
+			// only advertised in the master playlist
+			prof.Resolution = "0x0"
+			prof.Framerate, prof.FramerateDen = 0, 0
+		}
 		profiles = append(profiles, prof)
 	}
 	return profiles, nil
@@ -385,7 +514,7 @@ func gotRTMPStreamHandler(s *LivepeerServer) func(url *url.URL, rtmpStrm stream.
 						monitor.StreamStarted(nonce)
 					}
 				}
-				go processSegment(cxn, seg)
+				go processSegment(cxn, cxn.streamParams(), seg)
 			})
 
 			segOptions := segmenter.SegmenterOptions{
@@ -445,10 +574,19 @@ func (s *LivepeerServer) registerConnection(rtmpStrm stream.RTMPVideoStream) (*r
 		params.Resolution = fmt.Sprintf("%vx%v", rtmpStrm.Width(), rtmpStrm.Height())
 	}
 	if params.OS == nil {
-		params.OS = drivers.NodeStorage.NewSession(string(mid))
+		if memOS, ok := drivers.NodeStorage.(*drivers.MemoryOS); ok && params.VODSegments > 0 {
+			// the playlists of VOD files and clips keep all of their segments
+			params.OS = memOS.NewVODSession(string(mid), params.VODSegments)
+		} else {
+			params.OS = drivers.NodeStorage.NewSession(string(mid))
+		}
 	}
 	storage := params.OS
 
+	if KeyframeAlignment != nil {
+		params.Profiles = KeyframeAlignment.Profiles(params.Profiles)
+	}
+
 	// Generate and set capabilities
 	caps, err := core.JobCapabilities(params)
 	if err != nil {
@@ -471,8 +609,20 @@ func (s *LivepeerServer) registerConnection(rtmpStrm stream.RTMPVideoStream) (*r
 		// We can only have one concurrent stream per ManifestID
 		return nil, errAlreadyExists
 	}
+	if Fleet != nil {
+		owner, err := Fleet.Claim(mid)
+		if err != nil {
+			glog.Errorf("Error claiming stream from the fleet coordinator, ingesting it manifestID=%s err=%v", mid, err)
+		} else if owner != Fleet.Node() {
+			glog.Errorf("Refusing stream ingested by another broadcaster of the fleet manifestID=%s member=%s", mid, owner)
+			return nil, errIngestedElsewhere
+		}
+	}
 
-	playlist := core.NewBasicPlaylistManager(mid, storage)
+	var playlist core.PlaylistManager = core.NewBasicPlaylistManager(mid, storage)
+	if params.VODSegments > 0 {
+		playlist = core.NewVODPlaylistManager(mid, storage, params.VODSegments)
+	}
 	var stakeRdr stakeReader
 	if s.LivepeerNode.Eth != nil {
 		stakeRdr = &storeStakeReader{store: s.LivepeerNode.Database}
@@ -485,8 +635,14 @@ func (s *LivepeerServer) registerConnection(rtmpStrm stream.RTMPVideoStream) (*r
 		profile:     &vProfile,
 		params:      params,
 		sessManager: NewSessionManager(s.LivepeerNode, params, NewMinLSSelector(stakeRdr, 1.0)),
+		bandwidth:   s.LivepeerNode.Bandwidth,
+		auditLog:    s.LivepeerNode.AuditLog,
+		receipts:    core.NewReceiptTracker(),
 		lastUsed:    time.Now(),
 	}
+	if ParallelOrchestrators > 1 {
+		cxn.order = newSegmentOrder()
+	}
 
 	s.connectionLock.Lock()
 	_, exists = s.rtmpConnections[mid]
@@ -495,6 +651,7 @@ func (s *LivepeerServer) registerConnection(rtmpStrm stream.RTMPVideoStream) (*r
 	if exists {
 		// We can only have one concurrent stream per ManifestID
 		s.connectionLock.Unlock()
+		cxn.sessManager.cleanup()
 		return nil, errAlreadyExists
 	}
 	s.rtmpConnections[mid] = cxn
@@ -521,8 +678,12 @@ func removeRTMPStream(s *LivepeerServer, mid core.ManifestID) error {
 	cxn.stream.Close()
 	cxn.sessManager.cleanup()
 	cxn.pl.Cleanup()
+	cxn.bandwidth.RemoveStream(mid)
 	glog.Infof("Ended stream with id=%s", mid)
 	delete(s.rtmpConnections, mid)
+	if Fleet != nil {
+		Fleet.Release(mid)
+	}
 
 	if monitor.Enabled {
 		monitor.StreamEnded(cxn.nonce)
@@ -532,6 +693,24 @@ func removeRTMPStream(s *LivepeerServer, mid core.ManifestID) error {
 	return nil
 }
 
+// removeIdleStream removes a stream once it hasn't been used for the timeout
+func removeIdleStream(s *LivepeerServer, mid core.ManifestID, timeout time.Duration) {
+	ticker := time.NewTicker(timeout)
+	defer ticker.Stop()
+	for range ticker.C {
+		var lastUsed time.Time
+		s.connectionLock.RLock()
+		if cxn, exists := s.rtmpConnections[mid]; exists {
+			lastUsed = cxn.lastUsed
+		}
+		s.connectionLock.RUnlock()
+		if time.Since(lastUsed) > timeout {
+			_ = removeRTMPStream(s, mid)
+			return
+		}
+	}
+}
+
 //End RTMP Publish Handlers
 
 //HLS Play Handlers
@@ -609,6 +788,8 @@ func getHLSSegmentHandler(s *LivepeerServer) func(url *url.URL) ([]byte, error)
 		}
 		data := os.GetData(segName)
 		if len(data) > 0 {
+			mid := core.ManifestID(parts[0])
+			s.LivepeerNode.Bandwidth.RecordEgress(mid, s.LivepeerNode.BandwidthPeer(mid), int64(len(data)))
 			return data, nil
 		}
 		return nil, vidplayer.ErrNotFound
@@ -650,6 +831,11 @@ func (s *LivepeerServer) HandlePush(w http.ResponseWriter, r *http.Request) {
 	r.Body.Close()
 	r.URL = &url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path}
 
+	if Draining() {
+		http.Error(w, errDraining.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
 	// Determine the input format the request is claiming to have
 	ext := path.Ext(r.URL.Path)
 	format := common.ProfileExtensionFormat(ext)
@@ -675,6 +861,17 @@ func (s *LivepeerServer) HandlePush(w http.ResponseWriter, r *http.Request) {
 	}
 	s.connectionLock.Unlock()
 
+	// Forward the segments of the streams ingested by another broadcaster of the fleet
+	if !exists && Fleet != nil && r.Header.Get(fleetForwardedHeader) == "" {
+		owner, err := Fleet.Claim(mid)
+		if err != nil {
+			glog.Errorf("Error claiming stream from the fleet coordinator, ingesting it manifestID=%s err=%v", mid, err)
+		} else if owner != Fleet.Node() {
+			Fleet.forward(w, r, body, owner)
+			return
+		}
+	}
+
 	// Check for presence and register if a fresh cxn
 	if !exists {
 		appData := (createRTMPStreamIDHandler(s))(r.URL)
@@ -686,6 +883,10 @@ func (s *LivepeerServer) HandlePush(w http.ResponseWriter, r *http.Request) {
 		params := streamParams(st)
 		params.Resolution = r.Header.Get("Content-Resolution")
 		params.Format = format
+		// Only select orchestrators that decode the AV1 source
+		if format == ffmpeg.FormatMP4 && common.IsAV1MP4(body) {
+			params.RequiredCapabilities = append(params.RequiredCapabilities, core.Capability_AV1Decode)
+		}
 		// Set output formats if not explicitly specified
 		for i, v := range params.Profiles {
 			if ffmpeg.FormatNone == v.Format {
@@ -700,22 +901,7 @@ func (s *LivepeerServer) HandlePush(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Start a watchdog to remove session after a period of inactivity
-		ticker := time.NewTicker(httpPushTimeout)
-		go func(s *LivepeerServer, mid core.ManifestID) {
-			defer ticker.Stop()
-			for range ticker.C {
-				var lastUsed time.Time
-				s.connectionLock.RLock()
-				if cxn, exists := s.rtmpConnections[mid]; exists {
-					lastUsed = cxn.lastUsed
-				}
-				s.connectionLock.RUnlock()
-				if time.Since(lastUsed) > httpPushTimeout {
-					_ = removeRTMPStream(s, mid)
-					return
-				}
-			}
-		}(s, mid)
+		go removeIdleStream(s, mid, httpPushTimeout)
 	}
 
 	fname := path.Base(r.URL.Path)
@@ -758,8 +944,10 @@ func (s *LivepeerServer) HandlePush(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	// Do the transcoding!
-	urls, err := processSegment(cxn, seg)
+	// Do the transcoding! The profiles of the stream may change meanwhile, so the
+	// renditions are named after the profiles that the segment started with
+	params := cxn.streamParams()
+	urls, err := processSegment(cxn, params, seg)
 	if err != nil {
 		// TODO distinguish between user errors (400) and server errors (500)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -800,7 +988,7 @@ func (s *LivepeerServer) HandlePush(w http.ResponseWriter, r *http.Request) {
 		if length == 0 {
 			typ, ext, length = "application/vnd+livepeer.uri", ".txt", len(url)
 		} else {
-			format := cxn.params.Profiles[i].Format
+			format := params.Profiles[i].Format
 			ext, err = common.ProfileFormatExtension(format)
 			if err != nil {
 				glog.Error("Unknown extension for format: ", err)
@@ -811,7 +999,7 @@ func (s *LivepeerServer) HandlePush(w http.ResponseWriter, r *http.Request) {
 				glog.Error("Unknown mime type for format: ", err)
 			}
 		}
-		profile := cxn.params.Profiles[i].Name
+		profile := params.Profiles[i].Name
 		fname := fmt.Sprintf(`"%s_%d%s"`, profile, seq, ext)
 		hdrs := textproto.MIMEHeader{
 			"Content-Type":        {typ + "; name=" + fname},
@@ -835,8 +1023,8 @@ func (s *LivepeerServer) HandlePush(w http.ResponseWriter, r *http.Request) {
 
 //Helper Methods Begin
 
-// StreamPrefix match all leading spaces, slashes and optionally `stream/`
-var StreamPrefix = regexp.MustCompile(`^[ /]*(stream/)?|(live/)?`) // test carefully!
+// StreamPrefix match all leading spaces, slashes and optionally `stream/` or `vod/`
+var StreamPrefix = regexp.MustCompile(`^[ /]*(stream/|vod/)?|(live/)?`) // test carefully!
 
 func cleanStreamPrefix(reqPath string) string {
 	return StreamPrefix.ReplaceAllString(reqPath, "")
@@ -862,6 +1050,22 @@ func parsePresets(presets []string) []ffmpeg.VideoProfile {
 	return profs
 }
 
+// updateStreamProfiles replaces the profiles of a live stream from its next segment
+func (s *LivepeerServer) updateStreamProfiles(mid core.ManifestID, profiles []ffmpeg.VideoProfile) (*core.StreamParameters, error) {
+	s.connectionLock.RLock()
+	cxn, ok := s.rtmpConnections[mid]
+	s.connectionLock.RUnlock()
+	if !ok {
+		return nil, errUnknownStream
+	}
+	params, err := cxn.updateProfiles(profiles)
+	if err != nil {
+		return nil, err
+	}
+	glog.Infof("Updated profiles of stream manifestID=%s profiles=%v", mid, common.ProfilesNames(params.Profiles))
+	return params, nil
+}
+
 func (s *LivepeerServer) LastManifestID() core.ManifestID {
 	s.connectionLock.RLock()
 	defer s.connectionLock.RUnlock()
@@ -896,11 +1100,17 @@ func (s *LivepeerServer) GetNodeStatus() *net.NodeStatus {
 		OrchestratorPool:      []string{},
 		RegisteredTranscoders: []net.RemoteTranscoderInfo{},
 		LocalTranscoding:      s.LivepeerNode.TranscoderManager == nil,
+		Capabilities:          s.LivepeerNode.Capabilities.Names(),
 	}
 	if s.LivepeerNode.TranscoderManager != nil {
 		res.RegisteredTranscodersNumber = s.LivepeerNode.TranscoderManager.RegisteredTranscodersCount()
 		res.RegisteredTranscoders = s.LivepeerNode.TranscoderManager.RegisteredTranscodersInfo()
 	}
+	if lb, ok := s.LivepeerNode.Transcoder.(*core.LoadBalancingTranscoder); ok {
+		for _, d := range lb.DeviceLoads() {
+			res.TranscoderDevices = append(res.TranscoderDevices, net.TranscoderDeviceInfo{Device: d.Device, Sessions: d.Sessions, MaxSessions: d.MaxSessions})
+		}
+	}
 	if s.LivepeerNode.OrchestratorPool != nil {
 		urls := s.LivepeerNode.OrchestratorPool.GetURLs()
 		for _, url := range urls {
@@ -922,6 +1132,5 @@ func (s *LivepeerServer) LatestPlaylist() core.PlaylistManager {
 }
 
 func shouldStopStream(err error) bool {
-	_, ok := err.(pm.ErrSenderValidation)
-	return ok
+	return pm.Errors.IsFatal(err)
 }
@@ -300,8 +300,8 @@ func TestCreateRTMPStreamHandlerCap(t *testing.T) {
 	}
 	createSid := createRTMPStreamIDHandler(s)
 	u, _ := url.Parse("http://hot/id1/secret")
-	oldMaxSessions := core.MaxSessions
-	core.MaxSessions = 1
+	defer core.SetMaxSessions(core.MaxSessions())
+	core.SetMaxSessions(1)
 	// happy case
 	sid := createSid(u).(*core.StreamParameters)
 	mid := sid.ManifestID
@@ -317,7 +317,6 @@ func TestCreateRTMPStreamHandlerCap(t *testing.T) {
 	if params != nil {
 		t.Error("Stream should be denied because of capacity cap")
 	}
-	core.MaxSessions = oldMaxSessions
 }
 
 type authWebhookReq struct {
@@ -331,7 +330,7 @@ func TestCreateRTMPStreamHandlerWebhook(t *testing.T) {
 	s.RTMPSegmenter = &StubSegmenter{skip: true}
 	createSid := createRTMPStreamIDHandler(s)
 
-	AuthWebhookURL = "http://localhost:8938/notexisting"
+	SetAuthWebhookURL("http://localhost:8938/notexisting")
 	u, _ := url.Parse("http://hot/something/id1")
 	sid := createSid(u)
 	assert.Nil(sid, "Webhook auth failed")
@@ -349,7 +348,7 @@ func TestCreateRTMPStreamHandlerWebhook(t *testing.T) {
 		w.Write(nil)
 	}))
 	defer ts.Close()
-	AuthWebhookURL = ts.URL
+	SetAuthWebhookURL(ts.URL)
 	sid = createSid(u)
 	assert.NotNil(sid, "On empty response with 200 code should pass")
 
@@ -358,10 +357,10 @@ func TestCreateRTMPStreamHandlerWebhook(t *testing.T) {
 		t := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Write([]byte(resp))
 		}))
-		AuthWebhookURL = t.URL
+		SetAuthWebhookURL(t.URL)
 		return t
 	}
-	defer func() { AuthWebhookURL = "" }()
+	defer SetAuthWebhookURL("")
 	BroadcastJobVideoProfiles = []ffmpeg.VideoProfile{ffmpeg.P360p30fps16x9}
 
 	// empty manifestID
@@ -491,6 +490,18 @@ func TestCreateRTMPStreamHandlerWebhook(t *testing.T) {
 	params = createSid(u).(*core.StreamParameters)
 	assert.Len(params.Profiles, 1)
 	assert.Equal(ffmpeg.GOPIntraOnly, params.Profiles[0].GOP)
+
+	// required capabilities of the broadcaster and of the webhook
+	BroadcastCfg.SetRequiredCapabilities([]core.Capability{core.Capability_GOP})
+	defer BroadcastCfg.SetRequiredCapabilities(nil)
+	ts15 := makeServer(`{"manifestID":"a", "capabilities": ["mp4"]}`)
+	defer ts15.Close()
+	params = createSid(u).(*core.StreamParameters)
+	assert.Equal([]core.Capability{core.Capability_GOP, core.Capability_MP4}, params.RequiredCapabilities)
+	assert.Equal([]core.Capability{core.Capability_GOP}, BroadcastCfg.RequiredCapabilities())
+	ts16 := makeServer(`{"manifestID":"a", "capabilities": ["unknown"]}`)
+	defer ts16.Close()
+	assert.Nil(createSid(u))
 }
 
 func TestCreateRTMPStreamHandler(t *testing.T) {
@@ -667,7 +678,7 @@ func TestGotRTMPStreamHandler(t *testing.T) {
 
 func TestMultiStream(t *testing.T) {
 	// set unlimited sessions because this tests creates 500 streams
-	core.MaxSessions = 0
+	core.SetMaxSessions(0)
 	//Turning off logging to stderr because this test prints ALOT of logs.
 	//Ideally we would record the flag value and set it back instead of hardcoding the value,
 	// but the `flag` doesn't allow easy access to existing flag value.
@@ -851,6 +862,87 @@ func TestRegisterConnection(t *testing.T) {
 
 }
 
+func TestRegisterConnection_KeyframeAlignment(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	s := setupServer()
+	defer serverCleanup(s)
+	drivers.NodeStorage = drivers.NewMemoryDriver(nil)
+	KeyframeAlignment = &core.KeyframeAlignment{GOP: time.Second}
+	defer func() { KeyframeAlignment = nil }()
+
+	mid := core.SplitStreamIDString(t.Name()).ManifestID
+	strm := stream.NewBasicRTMPVideoStream(&core.StreamParameters{ManifestID: mid, Profiles: []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9}})
+	cxn, err := s.registerConnection(strm)
+	require.Nil(err)
+	params := cxn.streamParams()
+	assert.Equal(time.Second, params.Profiles[0].GOP)
+	assert.Contains(params.Capabilities.Names(), "gop")
+
+	// Updated profiles are aligned too
+	params, err = s.updateStreamProfiles(mid, []ffmpeg.VideoProfile{ffmpeg.P240p30fps16x9})
+	require.Nil(err)
+	assert.Equal(time.Second, params.Profiles[0].GOP)
+	assert.Contains(params.Capabilities.Names(), "gop")
+}
+
+func TestUpdateStreamProfiles(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	s := setupServer()
+	defer serverCleanup(s)
+	drivers.NodeStorage = drivers.NewMemoryDriver(nil)
+
+	p144, p240, p360 := ffmpeg.P144p30fps16x9, ffmpeg.P240p30fps16x9, ffmpeg.P360p30fps16x9
+	mid := core.SplitStreamIDString(t.Name()).ManifestID
+	strm := stream.NewBasicRTMPVideoStream(&core.StreamParameters{ManifestID: mid, Profiles: []ffmpeg.VideoProfile{p144, p240}, Format: ffmpeg.FormatMP4})
+	cxn, err := s.registerConnection(strm)
+	require.Nil(err)
+	oldParams := cxn.streamParams()
+	for _, p := range oldParams.Profiles {
+		require.Nil(cxn.pl.InsertHLSSegment(&p, 1, p.Name+"/1.mp4", 2))
+	}
+
+	_, err = s.updateStreamProfiles("unknown", []ffmpeg.VideoProfile{p144})
+	assert.Equal(errUnknownStream, err)
+	_, err = s.updateStreamProfiles(mid, nil)
+	assert.Equal(errNoProfiles, err)
+	av1 := p240
+	av1.Profile = common.ProfileAV1Main
+	av1.Format = ffmpeg.FormatMPEGTS
+	_, err = s.updateStreamProfiles(mid, []ffmpeg.VideoProfile{av1})
+	assert.Error(err)
+	assert.Equal(oldParams, cxn.streamParams())
+
+	// Replace a rendition
+	params, err := s.updateStreamProfiles(mid, []ffmpeg.VideoProfile{p144, p360})
+	require.Nil(err)
+	assert.Equal(params, cxn.streamParams())
+	assert.Equal(params, cxn.sessManager.streamParams())
+	assert.Equal(mid, params.ManifestID)
+	assert.Equal(oldParams.OS, params.OS)
+	// Unspecified formats are set to the format of the stream
+	assert.Equal(ffmpeg.FormatMP4, params.Profiles[1].Format)
+	caps, err := core.JobCapabilities(params)
+	require.Nil(err)
+	assert.Equal(caps, params.Capabilities)
+
+	// The old params are left to the segments that started with them
+	assert.Equal([]ffmpeg.VideoProfile{p144, p240}, oldParams.Profiles)
+
+	// The removed rendition leaves the master playlist
+	variants := cxn.pl.GetHLSMasterPlaylist().Variants
+	require.Len(variants, 1)
+	assert.Equal(string(mid)+"/"+p144.Name+".m3u8", variants[0].URI)
+
+	// Segments in flight with the old profiles don't add the removed rendition back
+	require.Nil(cxn.insertRendition(&p240, 2, p240.Name+"/2.mp4", 2))
+	require.Nil(cxn.insertRendition(&p144, 2, p144.Name+"/2.mp4", 2))
+	require.Len(cxn.pl.GetHLSMasterPlaylist().Variants, 1)
+	assert.Nil(cxn.pl.GetHLSMediaPlaylist(p240.Name))
+	assert.Equal(uint(2), cxn.pl.GetHLSMediaPlaylist(p144.Name).Count())
+}
+
 func TestBroadcastSessionManagerWithStreamStartStop(t *testing.T) {
 	assert := assert.New(t)
 
@@ -963,6 +1055,8 @@ func TestParseManifestID(t *testing.T) {
 	}
 
 	checkMid("/stream/stream/stream", "stream")
+	checkMid("/vod/abc.mp4", "abc")
+	checkMid("/vod/vod/abc.mp4", "vod")
 }
 
 func TestParseStreamID(t *testing.T) {
@@ -1080,6 +1174,27 @@ func TestJsonProfileToVideoProfiles(t *testing.T) {
 	p, err = jsonProfileToVideoProfile(resp)
 	assert.Nil(err)
 	assert.Equal(ffmpeg.ProfileH264Baseline, p[0].Profile)
+	assert.Equal(ffmpeg.FormatNone, p[0].Format)
+
+	// test encoding profiles that set the format of their codec
+	resp.Profiles[0].Profile = "av1main"
+	p, err = jsonProfileToVideoProfile(resp)
+	assert.Nil(err)
+	assert.Equal(ffmpeg.FormatMP4, p[0].Format)
+	resp.Profiles[0].Profile = "vp9"
+	p, err = jsonProfileToVideoProfile(resp)
+	assert.Nil(err)
+	assert.Equal(common.ProfileVP9, p[0].Profile)
+	assert.Equal(common.FormatWebM, p[0].Format)
+
+	// test audio-only profile, which has no video to size
+	resp.Profiles[0].Profile = "AudioOnly"
+	p, err = jsonProfileToVideoProfile(resp)
+	assert.Nil(err)
+	assert.Equal(common.ProfileAudioOnly, p[0].Profile)
+	assert.Equal("0x0", p[0].Resolution)
+	assert.Equal(ffmpeg.FormatNone, p[0].Format)
+	assert.Zero(p[0].Framerate)
 
 	// test invalid encoding profile
 	resp.Profiles[0].Profile = "invalid"
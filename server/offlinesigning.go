@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/eth"
+)
+
+// The offline signing endpoints export the transactions queued by the node in offline signing
+// mode, to be signed on an air-gapped machine, and send the signed raw transactions
+
+// unsignedTxsHandler lists the queued transactions with the nonces that they must be signed
+// with, from the pending nonce of the account
+func unsignedTxsHandler(client eth.LivepeerEthClient, signer *eth.OfflineAccountManager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if client == nil {
+			respondWith500(w, "missing ETH client")
+			return
+		}
+		if signer == nil {
+			respondWith400(w, "offline signing is not enabled")
+			return
+		}
+
+		backend, err := client.Backend()
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not get ETH backend: %v", err))
+			return
+		}
+		nonce, err := backend.PendingNonceAt(r.Context(), signer.Account().Address)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not get pending nonce: %v", err))
+			return
+		}
+
+		data, err := json.Marshal(signer.UnsignedTxs(nonce))
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal unsigned transactions: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+// sendSignedTxHandler sends the signed raw transaction of the tx form value, which must be the
+// first queued transaction, signed by the account with its pending nonce, and responds with
+// its hash
+func sendSignedTxHandler(client eth.LivepeerEthClient, signer *eth.OfflineAccountManager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if client == nil {
+			respondWith500(w, "missing ETH client")
+			return
+		}
+		if signer == nil {
+			respondWith400(w, "offline signing is not enabled")
+			return
+		}
+
+		tx, err := eth.DecodeRawTx(r.FormValue("tx"))
+		if err != nil {
+			respondWith400(w, err.Error())
+			return
+		}
+
+		backend, err := client.Backend()
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not get ETH backend: %v", err))
+			return
+		}
+
+		nonce, err := backend.PendingNonceAt(r.Context(), signer.Account().Address)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not get pending nonce: %v", err))
+			return
+		}
+
+		u, err := signer.Signed(tx, nonce)
+		if err != nil {
+			respondWith400(w, err.Error())
+			return
+		}
+		if err := backend.SendTransaction(r.Context(), tx); err != nil {
+			// The transaction is queued again, still first, so that it can be signed again,
+			// e.g. with another gas price
+			signer.Requeue(u)
+			respondWith500(w, fmt.Sprintf("could not send transaction: %v", err))
+			return
+		}
+
+		glog.Infof("Sent offline signed transaction id=%v method=%v hash=%v", u.ID, u.Method, tx.Hash().Hex())
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(tx.Hash().Hex()))
+	})
+}
+
+// discardUnsignedTxHandler removes the queued transaction of the id form value, e.g. a
+// redemption of a ticket that expired while it was queued
+func discardUnsignedTxHandler(signer *eth.OfflineAccountManager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if signer == nil {
+			respondWith400(w, "offline signing is not enabled")
+			return
+		}
+
+		id := r.FormValue("id")
+		if !signer.Discard(id) {
+			respondWith400(w, fmt.Sprintf("no queued transaction %v", id))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("discardUnsignedTransaction success"))
+	})
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/livepeer/go-livepeer/eth"
+	"github.com/livepeer/go-livepeer/pm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubOfflineBackend struct {
+	eth.Backend
+	nonce   uint64
+	sent    []*ethtypes.Transaction
+	sendErr error
+}
+
+func (b *stubOfflineBackend) PendingNonceAt(ctx context.Context, account ethcommon.Address) (uint64, error) {
+	return b.nonce, nil
+}
+
+func (b *stubOfflineBackend) SendTransaction(ctx context.Context, tx *ethtypes.Transaction) error {
+	if b.sendErr != nil {
+		return b.sendErr
+	}
+	b.sent = append(b.sent, tx)
+	return nil
+}
+
+type stubOfflineClient struct {
+	*eth.StubClient
+	backend *stubOfflineBackend
+}
+
+func (c *stubOfflineClient) Backend() (eth.Backend, error) {
+	return c.backend, nil
+}
+
+func TestUnsignedTxsHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	client := &stubOfflineClient{StubClient: &eth.StubClient{}, backend: &stubOfflineBackend{nonce: 5}}
+
+	resp := httpGetResp(unsignedTxsHandler(nil, nil))
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+	resp = httpGetResp(unsignedTxsHandler(client, nil))
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	assert.Equal("offline signing is not enabled", strings.TrimSpace(string(body)))
+
+	signer, err := eth.NewOfflineAccountManager(pm.RandAddress(), big.NewInt(1))
+	require.Nil(err)
+	signer.SignTx(ethtypes.NewTransaction(0, pm.RandAddress(), big.NewInt(0), 100, big.NewInt(1), []byte("foo")))
+	signer.SignTx(ethtypes.NewTransaction(0, pm.RandAddress(), big.NewInt(0), 100, big.NewInt(1), []byte("bar")))
+
+	// Test the queued transactions are listed with nonces from the pending nonce
+	resp = httpGetResp(unsignedTxsHandler(client, signer))
+	require.Equal(http.StatusOK, resp.StatusCode)
+	var txs []*eth.UnsignedTx
+	require.Nil(json.NewDecoder(resp.Body).Decode(&txs))
+	require.Len(txs, 2)
+	assert.Equal(uint64(5), txs[0].Nonce)
+	assert.Equal(uint64(6), txs[1].Nonce)
+}
+
+func TestSendSignedTxHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	key, err := ethcrypto.GenerateKey()
+	require.Nil(err)
+	chainID := big.NewInt(1)
+	signer, err := eth.NewOfflineAccountManager(ethcrypto.PubkeyToAddress(key.PublicKey), chainID)
+	require.Nil(err)
+	signer.SignTx(ethtypes.NewTransaction(0, pm.RandAddress(), big.NewInt(0), 100, big.NewInt(1), []byte("foo")))
+
+	backend := &stubOfflineBackend{}
+	client := &stubOfflineClient{StubClient: &eth.StubClient{}, backend: backend}
+	handler := sendSignedTxHandler(client, signer)
+	send := func(tx string) (int, string) {
+		resp := httpPostFormResp(handler, strings.NewReader(url.Values{"tx": {tx}}.Encode()))
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, strings.TrimSpace(string(body))
+	}
+
+	status, body := send("0xfoo")
+	assert.Equal(http.StatusBadRequest, status)
+	assert.Contains(body, "invalid raw transaction")
+
+	u := signer.UnsignedTxs(0)[0]
+	tx, err := ethtypes.SignTx(u.Transaction(), ethtypes.NewEIP155Signer(chainID), key)
+	require.Nil(err)
+	raw, err := eth.EncodeRawTx(tx)
+	require.Nil(err)
+
+	// Test a transaction signed with another nonce than the pending nonce is refused
+	backend.nonce = 1
+	status, body = send(raw)
+	assert.Equal(http.StatusBadRequest, status)
+	assert.Contains(body, "signed with nonce 0 instead of 1")
+	backend.nonce = 0
+
+	// Test a failed transaction is queued again
+	backend.sendErr = errors.New("nonce too low")
+	status, body = send(raw)
+	assert.Equal(http.StatusInternalServerError, status)
+	assert.Equal("could not send transaction: nonce too low", body)
+	assert.Len(signer.UnsignedTxs(0), 1)
+
+	// Test the signed transaction is sent and removed from the queue
+	backend.sendErr = nil
+	status, body = send(raw)
+	assert.Equal(http.StatusOK, status)
+	assert.Equal(tx.Hash().Hex(), body)
+	require.Len(backend.sent, 1)
+	assert.Equal(tx.Hash(), backend.sent[0].Hash())
+	assert.Empty(signer.UnsignedTxs(0))
+
+	// Test a transaction that is not queued is refused
+	status, body = send(raw)
+	assert.Equal(http.StatusBadRequest, status)
+	assert.Contains(body, "is not a queued transaction")
+	assert.Len(backend.sent, 1)
+}
+
+func TestDiscardUnsignedTxHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	resp := httpPostFormResp(discardUnsignedTxHandler(nil), strings.NewReader("id=foo"))
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	signer, err := eth.NewOfflineAccountManager(pm.RandAddress(), big.NewInt(1))
+	require.Nil(err)
+	signer.SignTx(ethtypes.NewTransaction(0, pm.RandAddress(), big.NewInt(0), 100, big.NewInt(1), []byte("foo")))
+	id := signer.UnsignedTxs(0)[0].ID
+
+	resp = httpPostFormResp(discardUnsignedTxHandler(signer), strings.NewReader("id=foo"))
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	assert.Equal("no queued transaction foo", strings.TrimSpace(string(body)))
+
+	resp = httpPostFormResp(discardUnsignedTxHandler(signer), strings.NewReader("id="+id))
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Empty(signer.UnsignedTxs(0))
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/eth"
+)
+
+// The onboarding endpoints bring a node to a target state, such as a funded deposit or a
+// registered orchestrator, and only send the transactions that are still needed to reach
+// it. Provisioning systems can retry them until they succeed without funding or bonding
+// twice
+
+// respondWithChanged responds with the parts of the state of the node that were changed
+func respondWithChanged(w http.ResponseWriter, changed []string) {
+	data, err := json.Marshal(map[string][]string{"changed": changed})
+	if err != nil {
+		respondWith500(w, fmt.Sprintf("could not marshal changes: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// missingAmount returns how much must be added to current to reach target, 0 if none
+func missingAmount(target, current *big.Int) *big.Int {
+	if current == nil {
+		current = big.NewInt(0)
+	}
+	missing := new(big.Int).Sub(target, current)
+	if missing.Sign() < 0 {
+		missing.SetInt64(0)
+	}
+	return missing
+}
+
+// ensureDepositHandler tops up the deposit and the reserve of the broadcaster to at least
+// the depositAmount and reserveAmount form values, in Wei
+func ensureDepositHandler(client eth.LivepeerEthClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if client == nil {
+			respondWith500(w, "missing ETH client")
+			return
+		}
+
+		depositAmount, err := common.ParseBigInt(r.FormValue("depositAmount"))
+		if err != nil {
+			respondWith400(w, fmt.Sprintf("invalid depositAmount: %v", err))
+			return
+		}
+		reserveAmount, err := common.ParseBigInt(r.FormValue("reserveAmount"))
+		if err != nil {
+			respondWith400(w, fmt.Sprintf("invalid reserveAmount: %v", err))
+			return
+		}
+
+		info, err := client.GetSenderInfo(client.Account().Address)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not get sender info: %v", err))
+			return
+		}
+		var funds *big.Int
+		if info.Reserve != nil {
+			funds = info.Reserve.FundsRemaining
+		}
+		deposit := missingAmount(depositAmount, info.Deposit)
+		reserve := missingAmount(reserveAmount, funds)
+
+		changed := []string{}
+		if deposit.Sign() > 0 || reserve.Sign() > 0 {
+			glog.Infof("Funding deposit with %v and reserve with %v", deposit, reserve)
+			tx, err := client.FundDepositAndReserve(deposit, reserve)
+			if err == nil {
+				err = client.CheckTx(tx)
+			}
+			if err != nil {
+				respondWith500(w, fmt.Sprintf("could not execute fundDepositAndReserve: %v", err))
+				return
+			}
+			if deposit.Sign() > 0 {
+				changed = append(changed, "deposit")
+			}
+			if reserve.Sign() > 0 {
+				changed = append(changed, "reserve")
+			}
+		}
+		respondWithChanged(w, changed)
+	})
+}
+
+// ensureOrchestratorHandler registers the node as an orchestrator with the blockRewardCut,
+// feeShare, pricePerUnit, pixelsPerUnit and serviceURI form values, or updates the ones
+// that differ if it is already registered. With the amount form value, the stake bonded
+// to the orchestrator is topped up to at least amount, in LPT base units. The serviceURI
+// must reach the node, unless skipServiceURICheck is true
+func ensureOrchestratorHandler(s *LivepeerServer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := s.LivepeerNode.Eth
+		if client == nil {
+			respondWith500(w, "missing ETH client")
+			return
+		}
+
+		blockRewardCut, err := strconv.ParseFloat(r.FormValue("blockRewardCut"), 64)
+		if err != nil {
+			respondWith400(w, fmt.Sprintf("invalid blockRewardCut: %v", err))
+			return
+		}
+		feeShare, err := strconv.ParseFloat(r.FormValue("feeShare"), 64)
+		if err != nil {
+			respondWith400(w, fmt.Sprintf("invalid feeShare: %v", err))
+			return
+		}
+		pricePerUnit, err := strconv.ParseInt(r.FormValue("pricePerUnit"), 10, 64)
+		if err != nil || pricePerUnit < 0 {
+			respondWith400(w, fmt.Sprintf("invalid pricePerUnit: %v", r.FormValue("pricePerUnit")))
+			return
+		}
+		pixelsPerUnit, err := strconv.ParseInt(r.FormValue("pixelsPerUnit"), 10, 64)
+		if err != nil || pixelsPerUnit <= 0 {
+			respondWith400(w, fmt.Sprintf("invalid pixelsPerUnit: %v", r.FormValue("pixelsPerUnit")))
+			return
+		}
+		serviceURI := r.FormValue("serviceURI")
+		if _, err := url.ParseRequestURI(serviceURI); err != nil {
+			respondWith400(w, fmt.Sprintf("invalid serviceURI: %v", err))
+			return
+		}
+		if err := validateServiceURI(r, client, serviceURI); err != nil {
+			respondWith400(w, fmt.Sprintf("invalid serviceURI: %v", err))
+			return
+		}
+		var amount *big.Int
+		if v := r.FormValue("amount"); v != "" {
+			if amount, err = common.ParseBigInt(v); err != nil {
+				respondWith400(w, fmt.Sprintf("invalid amount: %v", err))
+				return
+			}
+		}
+
+		addr := client.Account().Address
+		changed := []string{}
+
+		price := big.NewRat(pricePerUnit, pixelsPerUnit)
+		if current := s.LivepeerNode.GetBasePrice(); current == nil || current.Cmp(price) != 0 {
+			s.LivepeerNode.SetBasePrice(price)
+			glog.Infof("Price per pixel set to %d wei for %d pixels", pricePerUnit, pixelsPerUnit)
+			changed = append(changed, "price")
+		}
+
+		if amount != nil {
+			d, err := client.GetDelegator(addr)
+			if err != nil {
+				respondWith500(w, fmt.Sprintf("could not get delegator: %v", err))
+				return
+			}
+			missing := missingAmount(amount, d.BondedAmount)
+			// Bonding to self also moves the stake bonded to another orchestrator
+			if missing.Sign() > 0 || d.DelegateAddress != addr {
+				glog.Infof("Bonding %v...", missing)
+				tx, err := client.Bond(missing, addr)
+				if err == nil {
+					err = client.CheckTx(tx)
+				}
+				if err != nil {
+					respondWith500(w, fmt.Sprintf("could not bond: %v", err))
+					return
+				}
+				changed = append(changed, "stake")
+			}
+		}
+
+		t, err := client.GetTranscoder(addr)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not get orchestrator: %v", err))
+			return
+		}
+		rewardCut, share := eth.FromPerc(blockRewardCut), eth.FromPerc(feeShare)
+		if t.Status != "Registered" || t.RewardCut == nil || t.RewardCut.Cmp(rewardCut) != 0 || t.FeeShare == nil || t.FeeShare.Cmp(share) != 0 {
+			locked, err := client.CurrentRoundLocked()
+			if err != nil {
+				respondWith500(w, err.Error())
+				return
+			}
+			if locked {
+				respondWithError(w, "current round is locked, retry in the next round", http.StatusServiceUnavailable)
+				return
+			}
+			glog.Infof("Setting orchestrator commission rates for %v: reward cut=%v feeshare=%v", addr.Hex(), blockRewardCut, feeShare)
+			tx, err := client.Transcoder(rewardCut, share)
+			if err == nil {
+				err = client.CheckTx(tx)
+			}
+			if err != nil {
+				respondWith500(w, fmt.Sprintf("could not set commission rates: %v", err))
+				return
+			}
+			changed = append(changed, "commission")
+		}
+
+		currentServiceURI, err := client.GetServiceURI(addr)
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not get service URI: %v", err))
+			return
+		}
+		if currentServiceURI != serviceURI {
+			if err := s.setServiceURI(serviceURI); err != nil {
+				respondWith500(w, fmt.Sprintf("could not set service URI: %v", err))
+				return
+			}
+			changed = append(changed, "serviceURI")
+		}
+
+		respondWithChanged(w, changed)
+	})
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/eth"
+	lpTypes "github.com/livepeer/go-livepeer/eth/types"
+	"github.com/livepeer/go-livepeer/pm"
+)
+
+func postForm(handler http.Handler, form url.Values) (int, string) {
+	resp := httpPostFormResp(handler, strings.NewReader(form.Encode()))
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, strings.TrimSpace(string(body))
+}
+
+// intMatcher compares mocked big.Int values, which can be equal with different internals
+func intMatcher(i int64) interface{} {
+	return mock.MatchedBy(func(x *big.Int) bool { return x.Cmp(big.NewInt(i)) == 0 })
+}
+
+func TestEnsureDepositHandler(t *testing.T) {
+	assert := assert.New(t)
+	addr := ethcommon.HexToAddress("0x1234")
+	form := url.Values{"depositAmount": {"50"}, "reserveAmount": {"80"}}
+
+	status, body := postForm(ensureDepositHandler(nil), form)
+	assert.Equal(http.StatusInternalServerError, status)
+	assert.Equal("missing ETH client", body)
+
+	client := &eth.MockClient{}
+	client.On("Account").Return(accounts.Account{Address: addr})
+	status, body = postForm(ensureDepositHandler(client), url.Values{"depositAmount": {"foo"}, "reserveAmount": {"80"}})
+	assert.Equal(http.StatusBadRequest, status)
+	assert.Contains(body, "invalid depositAmount")
+
+	// Only the missing funds are sent
+	info := &pm.SenderInfo{Deposit: big.NewInt(30), Reserve: &pm.ReserveInfo{FundsRemaining: big.NewInt(100)}}
+	client.On("GetSenderInfo", addr).Return(info, nil).Once()
+	client.On("FundDepositAndReserve", intMatcher(20), intMatcher(0)).Return(nil, nil).Once()
+	client.On("CheckTx").Return(nil).Once()
+	status, body = postForm(ensureDepositHandler(client), form)
+	assert.Equal(http.StatusOK, status)
+	assert.Equal(`{"changed":["deposit"]}`, body)
+
+	// Nothing is sent once funded
+	info = &pm.SenderInfo{Deposit: big.NewInt(50), Reserve: &pm.ReserveInfo{FundsRemaining: big.NewInt(100)}}
+	client.On("GetSenderInfo", addr).Return(info, nil).Once()
+	status, body = postForm(ensureDepositHandler(client), form)
+	assert.Equal(http.StatusOK, status)
+	assert.Equal(`{"changed":[]}`, body)
+
+	info = &pm.SenderInfo{Deposit: big.NewInt(0), Reserve: &pm.ReserveInfo{FundsRemaining: big.NewInt(0)}}
+	client.On("GetSenderInfo", addr).Return(info, nil).Once()
+	client.On("FundDepositAndReserve", intMatcher(50), intMatcher(80)).Return(nil, nil).Once()
+	client.On("CheckTx").Return(errors.New("CheckTx error")).Once()
+	status, body = postForm(ensureDepositHandler(client), form)
+	assert.Equal(http.StatusInternalServerError, status)
+	assert.Equal("could not execute fundDepositAndReserve: CheckTx error", body)
+	client.AssertExpectations(t)
+}
+
+func TestEnsureOrchestratorHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	addr := ethcommon.HexToAddress("0x1234")
+	form := url.Values{
+		"blockRewardCut": {"10"},
+		"feeShare":       {"5"},
+		"pricePerUnit":   {"100"},
+		"pixelsPerUnit":  {"4"},
+		"serviceURI":     {"https://orch.example.com:8935"},
+		"amount":         {"1000"},
+	}
+
+	var checkErr error
+	defer func(check func(ethcommon.Address, string) error) { checkServiceURI = check }(checkServiceURI)
+	checkServiceURI = func(a ethcommon.Address, serviceURI string) error {
+		assert.Equal(addr, a)
+		return checkErr
+	}
+
+	client := &eth.MockClient{}
+	client.On("Account").Return(accounts.Account{Address: addr})
+	n, err := core.NewLivepeerNode(client, "", nil)
+	require.Nil(err)
+	s := &LivepeerServer{LivepeerNode: n}
+
+	invalid := url.Values{}
+	for k, v := range form {
+		invalid[k] = v
+	}
+	invalid.Set("pixelsPerUnit", "0")
+	status, body := postForm(ensureOrchestratorHandler(s), invalid)
+	assert.Equal(http.StatusBadRequest, status)
+	assert.Equal("invalid pixelsPerUnit: 0", body)
+
+	// A new orchestrator is registered
+	client.On("GetDelegator", addr).Return(&lpTypes.Delegator{BondedAmount: big.NewInt(400), DelegateAddress: addr}, nil).Once()
+	client.On("Bond", intMatcher(600), addr).Return(nil, nil).Once()
+	client.On("GetTranscoder", addr).Return(&lpTypes.Transcoder{Status: "Not Registered"}, nil).Once()
+	client.On("CurrentRoundLocked").Return(false, nil).Once()
+	client.On("Transcoder", eth.FromPerc(10), eth.FromPerc(5)).Return(nil, nil).Once()
+	client.On("GetServiceURI", addr).Return("", nil).Once()
+	client.On("SetServiceURI", "https://orch.example.com:8935").Return(nil, nil).Once()
+	client.On("CheckTx").Return(nil).Times(3)
+	status, body = postForm(ensureOrchestratorHandler(s), form)
+	require.Equal(http.StatusOK, status, body)
+	assert.Equal(`{"changed":["price","stake","commission","serviceURI"]}`, body)
+	assert.Zero(big.NewRat(25, 1).Cmp(n.GetBasePrice()))
+	assert.Equal("https://orch.example.com:8935", n.GetServiceURI().String())
+	client.AssertExpectations(t)
+
+	// Nothing is sent once registered
+	registered := &lpTypes.Transcoder{Status: "Registered", RewardCut: eth.FromPerc(10), FeeShare: eth.FromPerc(5)}
+	client.On("GetDelegator", addr).Return(&lpTypes.Delegator{BondedAmount: big.NewInt(1000), DelegateAddress: addr}, nil).Once()
+	client.On("GetTranscoder", addr).Return(registered, nil).Once()
+	client.On("GetServiceURI", addr).Return("https://orch.example.com:8935", nil).Once()
+	status, body = postForm(ensureOrchestratorHandler(s), form)
+	require.Equal(http.StatusOK, status, body)
+	assert.Equal(`{"changed":[]}`, body)
+
+	// Commission rates can't be changed while the round is locked
+	form.Set("feeShare", "50")
+	form.Del("amount")
+	client.On("GetTranscoder", addr).Return(registered, nil).Once()
+	client.On("CurrentRoundLocked").Return(true, nil).Once()
+	status, body = postForm(ensureOrchestratorHandler(s), form)
+	assert.Equal(http.StatusServiceUnavailable, status)
+	assert.Equal("current round is locked, retry in the next round", body)
+
+	client.On("GetTranscoder", addr).Return(registered, nil).Once()
+	client.On("CurrentRoundLocked").Return(false, nil).Once()
+	client.On("Transcoder", eth.FromPerc(10), eth.FromPerc(50)).Return(nil, errors.New("reverted")).Once()
+	status, body = postForm(ensureOrchestratorHandler(s), form)
+	assert.Equal(http.StatusInternalServerError, status)
+	assert.Equal("could not set commission rates: reverted", body)
+	client.AssertExpectations(t)
+
+	// Nothing is sent if the service URI doesn't reach the node
+	checkErr = errors.New("not reachable")
+	status, body = postForm(ensureOrchestratorHandler(s), form)
+	assert.Equal(http.StatusBadRequest, status)
+	assert.Equal("invalid serviceURI: not reachable", body)
+
+	// The check can be skipped
+	form.Set("skipServiceURICheck", "true")
+	client.On("GetTranscoder", addr).Return(&lpTypes.Transcoder{Status: "Registered", RewardCut: eth.FromPerc(10), FeeShare: eth.FromPerc(50)}, nil).Once()
+	client.On("GetServiceURI", addr).Return("https://orch.example.com:8935", nil).Once()
+	status, body = postForm(ensureOrchestratorHandler(s), form)
+	require.Equal(http.StatusOK, status, body)
+	client.AssertExpectations(t)
+}
@@ -13,28 +13,53 @@ import (
 	"net/http"
 	"net/textproto"
 	"os"
-	"os/signal"
+	"path"
 	"strconv"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/cenkalti/backoff"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/golang/glog"
 	"golang.org/x/net/http2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/status"
 
 	"github.com/livepeer/go-livepeer/common"
 	"github.com/livepeer/go-livepeer/core"
+	lpcrypto "github.com/livepeer/go-livepeer/crypto"
 	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/go-livepeer/sdnotify"
 )
 
 const protoVerLPT = "Livepeer-Transcoder-1.0"
 const transcodingErrorMimeType = "livepeer/transcoding-error"
 
+// The stream of a standalone transcoder is idle between segments, so both ends ping each
+// other to detect a connection that died without being closed, e.g. dropped by a NAT or a
+// load balancer, instead of waiting for segments that will never come
+var (
+	transcoderKeepalive = keepalive.ClientParameters{
+		Time:                30 * time.Second,
+		Timeout:             10 * time.Second,
+		PermitWithoutStream: true,
+	}
+	orchestratorKeepalive = keepalive.ServerParameters{
+		Time:    time.Minute,
+		Timeout: 20 * time.Second,
+	}
+	// orchestratorKeepalivePolicy allows the pings of transcoders, which the server would
+	// otherwise answer with GOAWAY
+	orchestratorKeepalivePolicy = keepalive.EnforcementPolicy{
+		MinTime:             10 * time.Second,
+		PermitWithoutStream: true,
+	}
+)
+
 var errSecret = errors.New("Invalid secret")
 var errZeroCapacity = errors.New("Zero capacity")
 
@@ -48,8 +73,18 @@ func RunTranscoder(n *core.LivepeerNode, orchAddr string, capacity int) {
 	expb.MaxElapsedTime = 0
 	backoff.Retry(func() error {
 		glog.Info("Registering transcoder to ", orchAddr)
+		start := time.Now()
 		err := runTranscoder(n, orchAddr, capacity)
 		glog.Info("Unregistering transcoder: ", err)
+		if time.Since(start) > expb.MaxInterval {
+			// A stream that lasted was not one of a series of failed attempts, so the
+			// transcoder reconnects promptly rather than after their backoff
+			expb.Reset()
+		}
+		if Draining() {
+			// Do not reconnect while the node shuts down
+			return nil
+		}
 		if _, fatal := err.(core.RemoteTranscoderFatalError); fatal {
 			glog.Info("Terminating transcoder because of ", err)
 			// Returning nil here will make `backoff` to stop trying to reconnect and exit
@@ -79,7 +114,8 @@ func checkTranscoderError(err error) error {
 func runTranscoder(n *core.LivepeerNode, orchAddr string, capacity int) error {
 	tlsConfig := &tls.Config{InsecureSkipVerify: true}
 	conn, err := grpc.Dial(orchAddr,
-		grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+		grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
+		grpc.WithKeepaliveParams(transcoderKeepalive))
 	if err != nil {
 		glog.Error("Did not connect transcoder to orchesrator: ", err)
 		return err
@@ -96,18 +132,18 @@ func runTranscoder(n *core.LivepeerNode, orchAddr string, capacity int) error {
 		glog.Error("Could not register transcoder to orchestrator ", err)
 		return err
 	}
+	if err := sdnotify.Notify(sdnotify.StateReady, sdnotify.Status("Connected to orchestrator "+orchAddr)); err != nil {
+		glog.Error("Error notifying systemd err=", err)
+	}
 
-	// Catch interrupt signal to shut down transcoder
-	exitc := make(chan os.Signal)
-	signal.Notify(exitc, os.Interrupt, syscall.SIGTERM)
-	defer signal.Stop(exitc)
+	// Stop receiving segments when the node shuts down
 	go func() {
 		select {
-		case sig := <-exitc:
-			glog.Infof("Exiting Livepeer Transcoder: %v", sig)
+		case <-nodeDrainer.draining:
+			glog.Info("Exiting Livepeer Transcoder")
 			// Cancelling context will close connection to orchestrator
 			cancel()
-			return
+		case <-ctx.Done():
 		}
 	}()
 
@@ -120,9 +156,13 @@ func runTranscoder(n *core.LivepeerNode, orchAddr string, capacity int) error {
 			wg.Wait()
 			return err
 		}
+		// The segment was already assigned to us, so it is transcoded even if the node
+		// started draining in the meantime
+		done, _ := startSegment()
 		wg.Add(1)
 		go func() {
 			runTranscode(n, orchAddr, httpc, notify)
+			done()
 			wg.Done()
 		}()
 	}
@@ -144,7 +184,7 @@ func runTranscode(n *core.LivepeerNode, orchAddr string, httpc *http.Client, not
 	profiles := md.Profiles
 	md.Fname = notify.Url
 
-	tData, err := n.Transcoder.Transcode(md)
+	tData, err := transcodeSource(n, httpc, notify, md)
 	glog.V(common.VERBOSE).Infof("Transcoding done for taskId=%d url=%s err=%v", notify.TaskId, notify.Url, err)
 	if err == nil && len(tData.Segments) != len(profiles) {
 		err = errors.New("segment / profile mismatch")
@@ -167,6 +207,7 @@ func runTranscode(n *core.LivepeerNode, orchAddr string, httpc *http.Client, not
 				"Content-Type":   {ctyp},
 				"Content-Length": {strconv.Itoa(len(v.Data))},
 				"Pixels":         {strconv.FormatInt(v.Pixels, 10)},
+				"Frames":         {strconv.Itoa(v.Frames)},
 			}
 			fw, err := w.CreatePart(hdrs)
 			if err != nil {
@@ -187,6 +228,7 @@ func runTranscode(n *core.LivepeerNode, orchAddr string, httpc *http.Client, not
 	req.Header.Set("TaskId", strconv.FormatInt(notify.TaskId, 10))
 	if tData != nil {
 		req.Header.Set("Pixels", strconv.FormatInt(tData.Pixels, 10))
+		req.Header.Set("Frames", strconv.Itoa(tData.Frames))
 	}
 	resp, err := httpc.Do(req)
 	if err != nil {
@@ -198,6 +240,60 @@ func runTranscode(n *core.LivepeerNode, orchAddr string, httpc *http.Client, not
 	glog.V(common.VERBOSE).Infof("Transcoding done results sent for taskId=%d url=%s err=%v", notify.TaskId, notify.Url, err)
 }
 
+// transcodeSource transcodes the source segment of a task. If the hash of the segment
+// is known, the segment is only transcoded if it is the one the broadcaster sent
+func transcodeSource(n *core.LivepeerNode, httpc *http.Client, notify *net.NotifySegment, md *core.SegTranscodingMetadata) (*core.TranscodeData, error) {
+	if (md.Hash != ethcommon.Hash{}) {
+		fname, err := fetchSourceSegment(n.WorkDir, httpc, notify, md)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(fname)
+		md.Fname = fname
+	}
+	return n.Transcoder.Transcode(md)
+}
+
+// fetchSourceSegment downloads the source segment of a task and checks it against the
+// hash and, if the broadcaster is known, the signature of the broadcaster. Returns the
+// name of the local copy of the segment that should be transcoded
+func fetchSourceSegment(workDir string, httpc *http.Client, notify *net.NotifySegment, md *core.SegTranscodingMetadata) (string, error) {
+	resp, err := httpc.Get(notify.Url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Error downloading source segment status=%d", resp.StatusCode)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if !bytes.Equal(crypto.Keccak256(data), md.Hash.Bytes()) {
+		glog.Errorf("Source segment hash mismatch taskId=%d url=%s", notify.TaskId, notify.Url)
+		return "", core.ErrSourceSegmentHash
+	}
+	if sender := ethcommon.BytesToAddress(notify.Sender); (sender != ethcommon.Address{}) {
+		if !lpcrypto.VerifySig(sender, crypto.Keccak256(md.Flatten()), md.Sig) {
+			glog.Errorf("Source segment signature mismatch taskId=%d url=%s sender=%v", notify.TaskId, notify.Url, sender.Hex())
+			return "", core.ErrSourceSegmentSig
+		}
+	}
+
+	if _, err := os.Stat(workDir); os.IsNotExist(err) {
+		if err := os.Mkdir(workDir, 0700); err != nil {
+			return "", err
+		}
+	}
+	fname := path.Join(workDir, common.RandName()+".tempfile")
+	if err := ioutil.WriteFile(fname, data, 0644); err != nil {
+		return "", err
+	}
+	return fname, nil
+}
+
 // Orchestrator gRPC
 
 func (h *lphttp) RegisterTranscoder(req *net.RegisterRequest, stream net.Transcoder_RegisterTranscoderServer) error {
@@ -300,11 +396,16 @@ func (h *lphttp) TranscodeResults(w http.ResponseWriter, r *http.Request) {
 				break
 			}
 
-			segments = append(segments, &core.TranscodedSegmentData{Data: body, Pixels: encodedPixels})
+			// Transcoders that predate frame counts don't send any
+			encodedFrames, _ := strconv.Atoi(p.Header.Get("Frames"))
+
+			segments = append(segments, &core.TranscodedSegmentData{Data: body, Pixels: encodedPixels, Frames: encodedFrames})
 		}
+		decodedFrames, _ := strconv.Atoi(r.Header.Get("Frames"))
 		res.TranscodeData = &core.TranscodeData{
 			Segments: segments,
 			Pixels:   decodedPixels,
+			Frames:   decodedFrames,
 		}
 		orch.TranscoderResults(tid, &res)
 	}
@@ -9,18 +9,27 @@ import (
 	"math/rand"
 	"mime"
 	"mime/multipart"
+	gonet "net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/livepeer/go-livepeer/common"
 	"github.com/livepeer/go-livepeer/core"
 	"github.com/livepeer/go-livepeer/net"
 	"github.com/livepeer/lpms/ffmpeg"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 type stubTranscoder struct {
@@ -32,10 +41,11 @@ type stubTranscoder struct {
 
 var testRemoteTranscoderResults = &core.TranscodeData{
 	Segments: []*core.TranscodedSegmentData{
-		&core.TranscodedSegmentData{Data: []byte("body1"), Pixels: 777},
-		&core.TranscodedSegmentData{Data: []byte("body2"), Pixels: 888},
+		&core.TranscodedSegmentData{Data: []byte("body1"), Pixels: 777, Frames: 60},
+		&core.TranscodedSegmentData{Data: []byte("body2"), Pixels: 888, Frames: 30},
 	},
 	Pixels: 999,
+	Frames: 60,
 }
 
 func (st *stubTranscoder) Transcode(md *core.SegTranscodingMetadata) (*core.TranscodeData, error) {
@@ -96,6 +106,7 @@ func TestRemoteTranscoder_Profiles(t *testing.T) {
 	assert.NotNil(body)
 	assert.Equal("742", headers.Get("TaskId"))
 	assert.Equal("999", headers.Get("Pixels"))
+	assert.Equal("60", headers.Get("Frames"))
 	assert.Equal("multipart/mixed; boundary=17b336b6e6ae071e928f", headers.Get("Content-Type"))
 	assert.Equal(node.OrchSecret, headers.Get("Credentials"))
 	assert.Equal(protoVerLPT, headers.Get("Authorization"))
@@ -117,6 +128,7 @@ func TestRemoteTranscoder_Profiles(t *testing.T) {
 		pixels, err := strconv.ParseInt(p.Header.Get("Pixels"), 10, 64)
 		assert.NoError(err)
 		assert.Equal(testRemoteTranscoderResults.Segments[i].Pixels, pixels)
+		assert.Equal(strconv.Itoa(testRemoteTranscoderResults.Segments[i].Frames), p.Header.Get("Frames"))
 
 		assert.Equal("video/mp2t", strings.ToLower(p.Header.Get("Content-Type")))
 
@@ -175,6 +187,90 @@ func TestRemoteTranscoder_FullProfiles(t *testing.T) {
 	assert.Nil(nil, tr.profiles)
 }
 
+func TestRemoteTranscoder_SourceSegment(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	httpc := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+
+	segment := []byte("source segment")
+	served := segment
+	var headers http.Header
+	var body []byte
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write(served)
+			return
+		}
+		out, err := ioutil.ReadAll(r.Body)
+		assert.NoError(err)
+		headers = r.Header
+		body = out
+		w.Write(nil)
+	}))
+	defer ts.Close()
+	parsedURL, _ := url.Parse(ts.URL)
+
+	orch := newStubOrchestrator()
+	md := &core.SegTranscodingMetadata{
+		ManifestID: "mid",
+		Seq:        3,
+		Hash:       ethcommon.BytesToHash(ethcrypto.Keccak256(segment)),
+		Profiles:   []ffmpeg.VideoProfile{ffmpeg.P720p60fps16x9, ffmpeg.P144p30fps16x9},
+	}
+	sig, err := orch.Sign(md.Flatten())
+	require.Nil(err)
+	md.Sig = sig
+	segData, err := core.NetSegData(md)
+	require.Nil(err)
+	notify := &net.NotifySegment{
+		TaskId:  742,
+		SegData: segData,
+		Url:     ts.URL + "/segment.ts",
+		Sender:  orch.Address().Bytes(),
+	}
+
+	workDir, err := ioutil.TempDir("", "TestRemoteTranscoder_SourceSegment")
+	require.Nil(err)
+	defer os.RemoveAll(workDir)
+	tr := &stubTranscoder{}
+	node, _ := core.NewLivepeerNode(nil, workDir, nil)
+	node.OrchSecret = "verbigsecret"
+	node.Transcoder = tr
+
+	// The verified segment is transcoded from a local copy that is removed afterwards
+	runTranscode(node, parsedURL.Host, httpc, notify)
+	assert.Equal(1, tr.called)
+	assert.True(strings.HasPrefix(tr.fname, workDir))
+	_, err = os.Stat(tr.fname)
+	assert.True(os.IsNotExist(err))
+	assert.True(strings.HasPrefix(headers.Get("Content-Type"), "multipart/mixed"))
+
+	// Segments signed by someone else are rejected
+	notify.Sender = newStubOrchestrator().Address().Bytes()
+	runTranscode(node, parsedURL.Host, httpc, notify)
+	assert.Equal(1, tr.called)
+	assert.Equal(transcodingErrorMimeType, headers.Get("Content-Type"))
+	assert.Equal(core.ErrSourceSegmentSig.Error(), string(body))
+
+	// Tampered segments are rejected even if the broadcaster is unknown
+	notify.Sender = nil
+	served = []byte("tampered segment")
+	runTranscode(node, parsedURL.Host, httpc, notify)
+	assert.Equal(1, tr.called)
+	assert.Equal(transcodingErrorMimeType, headers.Get("Content-Type"))
+	assert.Equal(core.ErrSourceSegmentHash.Error(), string(body))
+
+	served = segment
+	runTranscode(node, parsedURL.Host, httpc, notify)
+	assert.Equal(2, tr.called)
+
+	// Download errors are returned to the orchestrator
+	notify.Url = "https://127.0.0.1:0/segment.ts"
+	runTranscode(node, parsedURL.Host, httpc, notify)
+	assert.Equal(2, tr.called)
+	assert.Equal(transcodingErrorMimeType, headers.Get("Content-Type"))
+}
+
 func TestRemoteTranscoderError(t *testing.T) {
 	httpc := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
 	profiles := []ffmpeg.VideoProfile{ffmpeg.P720p60fps16x9, ffmpeg.P144p30fps16x9}
@@ -224,3 +320,55 @@ func TestRemoteTranscoderError(t *testing.T) {
 	assert.NotNil(body)
 	assert.Equal("segment / profile mismatch", string(body))
 }
+
+type stubRegistrationServer struct {
+	mu    sync.Mutex
+	calls int
+}
+
+// RegisterTranscoder ends the stream of the first registration and refuses the next one
+func (s *stubRegistrationServer) RegisterTranscoder(req *net.RegisterRequest, stream net.Transcoder_RegisterTranscoderServer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.calls > 1 {
+		return errSecret
+	}
+	return nil
+}
+
+func TestRunTranscoder_Reconnects(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "TestRunTranscoder_Reconnects")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile, err := getCert(&url.URL{Host: "127.0.0.1"}, dir)
+	require.Nil(err)
+	creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+	require.Nil(err)
+
+	lis, err := gonet.Listen("tcp", "127.0.0.1:0")
+	require.Nil(err)
+	s := grpc.NewServer(grpc.Creds(creds), grpc.KeepaliveParams(orchestratorKeepalive), grpc.KeepaliveEnforcementPolicy(orchestratorKeepalivePolicy))
+	orch := &stubRegistrationServer{}
+	net.RegisterTranscoderServer(s, orch)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	// Test the transcoder registers again when its stream ends, and stops once it is refused
+	done := make(chan struct{})
+	go func() {
+		RunTranscoder(&core.LivepeerNode{OrchSecret: "foo"}, lis.Addr().String(), 1)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("transcoder did not stop")
+	}
+	orch.mu.Lock()
+	defer orch.mu.Unlock()
+	assert.Equal(2, orch.calls)
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"sync"
+)
+
+// ParallelOrchestrators is the number of orchestrators that the segments of a stream
+// are dispatched to in turn, so that an orchestrator has that many segment durations
+// to transcode each of its segments. Segments are transcoded one after the other by
+// the best orchestrator if it is 1 or less.
+var ParallelOrchestrators = 1
+
+// parallelLane pins an orchestrator to the segments whose sequence number, modulo the
+// number of lanes, is the index of the lane
+type parallelLane struct {
+	// transcoder of the orchestrator, empty if none is pinned yet
+	orch string
+	// session of the orchestrator while it isn't transcoding a segment of the lane
+	sess *BroadcastSession
+}
+
+func newLanes() []parallelLane {
+	if ParallelOrchestrators <= 1 {
+		return nil
+	}
+	return make([]parallelLane, ParallelOrchestrators)
+}
+
+// selectSegmentSession selects the session that transcodes a segment. With parallel
+// lanes, it is the session of the lane of the segment, unless the orchestrator of the
+// lane is still busy with an earlier segment, or was removed. Sessions are then
+// selected as usual, and pin their orchestrator to the lane if it has none.
+func (bsm *BroadcastSessionsManager) selectSegmentSession(seqNo uint64) *BroadcastSession {
+	if len(bsm.lanes) == 0 {
+		return bsm.selectSession()
+	}
+	bsm.sessLock.Lock()
+	lane := &bsm.lanes[seqNo%uint64(len(bsm.lanes))]
+	if sess := lane.sess; sess != nil {
+		lane.sess = nil
+		if _, ok := bsm.sessMap[lane.orch]; ok {
+			bsm.sessLock.Unlock()
+			return sess
+		}
+		lane.orch = ""
+	}
+	bsm.sessLock.Unlock()
+
+	sess := bsm.selectSession()
+	if sess == nil {
+		return nil
+	}
+	bsm.sessLock.Lock()
+	defer bsm.sessLock.Unlock()
+	if lane.orch == "" && !bsm.pinned(sess.OrchestratorInfo.Transcoder) {
+		lane.orch = sess.OrchestratorInfo.Transcoder
+	}
+	return sess
+}
+
+// pinned checks if an orchestrator is pinned to a lane. Requires sessLock.
+func (bsm *BroadcastSessionsManager) pinned(orch string) bool {
+	for _, lane := range bsm.lanes {
+		if lane.orch == orch {
+			return true
+		}
+	}
+	return false
+}
+
+// parkSession returns a session to the lane that its orchestrator is pinned to,
+// rather than to the selector. Requires sessLock.
+func (bsm *BroadcastSessionsManager) parkSession(sess *BroadcastSession) bool {
+	for i := range bsm.lanes {
+		if bsm.lanes[i].orch == sess.OrchestratorInfo.Transcoder {
+			bsm.lanes[i].sess = sess
+			return true
+		}
+	}
+	return false
+}
+
+// unpin unpins a removed orchestrator from its lane. Requires sessLock.
+func (bsm *BroadcastSessionsManager) unpin(orch string) {
+	for i := range bsm.lanes {
+		if bsm.lanes[i].orch == orch {
+			bsm.lanes[i] = parallelLane{}
+		}
+	}
+}
+
+// segmentOrder publishes the segments of a stream that are transcoded in parallel in
+// the order of their sequence numbers, so that players polling the playlists don't
+// skip a segment that was transcoded after the next one
+type segmentOrder struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	// number of segments in flight, by sequence number
+	pending map[uint64]int
+}
+
+func newSegmentOrder() *segmentOrder {
+	o := &segmentOrder{pending: make(map[uint64]int)}
+	o.cond = sync.NewCond(&o.mu)
+	return o
+}
+
+// start records that a segment is in flight
+func (o *segmentOrder) start(seqNo uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pending[seqNo]++
+}
+
+// done records that a segment was published or given up on
+func (o *segmentOrder) done(seqNo uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.pending[seqNo]--; o.pending[seqNo] <= 0 {
+		delete(o.pending, seqNo)
+	}
+	o.cond.Broadcast()
+}
+
+// wait blocks until the earlier segments in flight are done. Segments that haven't
+// arrived yet aren't waited for.
+func (o *segmentOrder) wait(seqNo uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for o.earlierPending(seqNo) {
+		o.cond.Wait()
+	}
+}
+
+func (o *segmentOrder) earlierPending(seqNo uint64) bool {
+	for s := range o.pending {
+		if s < seqNo {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/livepeer/lpms/stream"
+)
+
+func TestSelectSegmentSession_Lanes(t *testing.T) {
+	assert := assert.New(t)
+
+	sessA, sessB, sessC := StubBroadcastSession("A"), StubBroadcastSession("B"), StubBroadcastSession("C")
+	bsm := bsmWithSessList([]*BroadcastSession{sessA, sessB, sessC})
+
+	// Without lanes, sessions are selected as usual
+	assert.Equal(sessC, bsm.selectSegmentSession(0))
+	bsm.completeSession(sessC)
+	assert.Equal(sessC, bsm.selectSegmentSession(1))
+	bsm.completeSession(sessC)
+
+	// Segments are dispatched to the orchestrators of their lanes in turn
+	bsm.lanes = make([]parallelLane, 2)
+	assert.Equal(sessC, bsm.selectSegmentSession(0))
+	assert.Equal(sessB, bsm.selectSegmentSession(1))
+	bsm.completeSession(sessC)
+	bsm.completeSession(sessB)
+	// the sessions of the lanes are kept out of the selector
+	assert.Equal(1, bsm.sel.Size())
+	assert.Equal(sessC, bsm.selectSegmentSession(2))
+	assert.Equal(sessB, bsm.selectSegmentSession(3))
+	bsm.completeSession(sessB)
+
+	// Segments of a lane whose orchestrator is busy go to another orchestrator
+	assert.Equal(sessA, bsm.selectSegmentSession(4))
+	bsm.completeSession(sessA)
+	assert.Equal(1, bsm.sel.Size())
+	bsm.completeSession(sessC)
+	assert.Equal(sessC, bsm.selectSegmentSession(4))
+
+	// Removed orchestrators are unpinned, and the lane pins the next one
+	bsm.removeSession(sessC)
+	assert.Equal(parallelLane{}, bsm.lanes[0])
+	assert.Equal(sessA, bsm.selectSegmentSession(6))
+	assert.Equal("A", bsm.lanes[0].orch)
+	assert.Equal(sessB, bsm.selectSegmentSession(7))
+	assert.Nil(bsm.selectSegmentSession(8))
+}
+
+func TestSegmentOrder(t *testing.T) {
+	assert := assert.New(t)
+	o := newSegmentOrder()
+
+	o.start(1)
+	o.start(2)
+	waited := make(chan struct{})
+	go func() {
+		o.wait(2)
+		close(waited)
+	}()
+	// segments that are first, or whose earlier segments haven't arrived, don't wait
+	o.wait(1)
+	o.wait(0)
+
+	select {
+	case <-waited:
+		assert.Fail("segment published ahead of an earlier segment")
+	case <-time.After(50 * time.Millisecond):
+	}
+	o.done(1)
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		assert.Fail("segment not published after the earlier segment")
+	}
+
+	// segments of the same sequence number are counted
+	o.start(3)
+	o.start(3)
+	o.done(3)
+	assert.True(o.earlierPending(4))
+	o.done(3)
+	o.done(2)
+	assert.False(o.earlierPending(4))
+	assert.Empty(o.pending)
+}
+
+// orderPlaylistManager records the sequence numbers of the segments of the renditions
+// in the order they are inserted
+type orderPlaylistManager struct {
+	stubPlaylistManager
+	mu   sync.Mutex
+	seqs []uint64
+}
+
+func (pm *orderPlaylistManager) InsertHLSSegment(profile *ffmpeg.VideoProfile, seqNo uint64, uri string, duration float64) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if profile.Name != "source" {
+		pm.seqs = append(pm.seqs, seqNo)
+	}
+	return nil
+}
+
+func (pm *orderPlaylistManager) inserted() []uint64 {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return append([]uint64(nil), pm.seqs...)
+}
+
+func TestProcessSegment_Parallel(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// The first segment is transcoded after the second one
+	received := make(chan uint64, 2)
+	release := make(chan struct{})
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		data, err := base64.StdEncoding.DecodeString(r.Header.Get(segmentHeader))
+		require.Nil(err)
+		var segData net.SegData
+		require.Nil(proto.Unmarshal(data, &segData))
+		received <- uint64(segData.Seq)
+		if segData.Seq == 0 {
+			<-release
+		}
+		buf, err := proto.Marshal(&net.TranscodeResult{
+			Result: &net.TranscodeResult_Data{Data: &net.TranscodeData{Segments: []*net.TranscodedSegmentData{{Url: "test.ts"}}, Sig: []byte("bar")}},
+		})
+		require.Nil(err)
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf)
+	}
+	ts1, mux1 := stubTLSServer()
+	defer ts1.Close()
+	mux1.HandleFunc("/segment", handler)
+	ts2, mux2 := stubTLSServer()
+	defer ts2.Close()
+	mux2.HandleFunc("/segment", handler)
+
+	params := &core.StreamParameters{ManifestID: "foo", Profiles: []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9}}
+	sess1, sess2 := StubBroadcastSession(ts1.URL), StubBroadcastSession(ts2.URL)
+	sess1.Params, sess2.Params = params, params
+	bsm := bsmWithSessList([]*BroadcastSession{sess1, sess2})
+	bsm.lanes = make([]parallelLane, 2)
+	pl := &orderPlaylistManager{stubPlaylistManager: stubPlaylistManager{os: &stubOSSession{}}}
+	cxn := &rtmpConnection{
+		mid:         core.ManifestID("foo"),
+		pl:          pl,
+		profile:     &ffmpeg.VideoProfile{Name: "source", Format: ffmpeg.FormatMPEGTS},
+		sessManager: bsm,
+		params:      params,
+		bandwidth:   core.NewBandwidthTracker(),
+		order:       newSegmentOrder(),
+	}
+
+	var wg sync.WaitGroup
+	process := func(seqNo uint64) {
+		defer wg.Done()
+		_, err := processSegment(cxn, nil, &stream.HLSSegment{SeqNo: seqNo, Data: []byte("dummy"), Duration: 2.0})
+		assert.Nil(err)
+	}
+	wg.Add(2)
+	go process(0)
+	assert.Equal(uint64(0), <-received)
+	go process(1)
+	assert.Equal(uint64(1), <-received)
+
+	// The second segment waits for the first one to be published
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(pl.inserted())
+	close(release)
+	wg.Wait()
+	assert.Equal([]uint64{0, 1}, pl.inserted())
+	// each orchestrator transcoded a segment
+	assert.NotEqual(bsm.lanes[0].orch, bsm.lanes[1].orch)
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/monitor"
+	"github.com/livepeer/go-livepeer/verification"
+)
+
+// VerificationFailureThreshold is the number of consecutive failed verifications of an
+// orchestrator, across all streams, after which it is suspended in all of them. Zero disables suspensions
+var VerificationFailureThreshold = 3
+
+// VerificationPenalty is the number of session refreshes an orchestrator that
+// repeatedly fails verification is suspended for
+var VerificationPenalty = 10
+
+// VerificationWebhookURL receives an event whenever an orchestrator is suspended for failing verification
+var VerificationWebhookURL string
+
+// suspensionCodes are the verification errors whose names are the codes of suspensions. Any
+// other error is suspended with the VerificationFailed code, so that the codes, which label
+// the suspensions metric, are a fixed set
+var suspensionCodes = []error{
+	verification.ErrTampered,
+	verification.ErrAudioMismatch,
+	verification.ErrKeyframeMisaligned,
+	verification.ErrUndecodable,
+	verification.ErrResolutionMismatch,
+	verification.ErrDurationMismatch,
+	verification.ErrRetranscodeMismatch,
+	verification.ErrRenditionMismatch,
+	verification.ErrVideoSignatureMismatch,
+	verification.ErrPixelMismatch,
+	verification.ErrPixelsAbsent,
+}
+
+const (
+	suspensionCodeVerificationFailed = "VerificationFailed"
+	suspensionCodeLowQuality         = "LowQuality"
+)
+
+// suspensionCode returns the code of a suspension for a verification error
+func suspensionCode(err error) string {
+	for _, e := range suspensionCodes {
+		if err == e {
+			return e.Error()
+		}
+	}
+	return suspensionCodeVerificationFailed
+}
+
+// orchSuspendedEvent is the payload sent to VerificationWebhookURL. Code is one of a fixed set
+// of codes, while Reason is the error that the orchestrator was suspended for
+type orchSuspendedEvent struct {
+	Event        string    `json:"event"`
+	Orchestrator string    `json:"orchestrator"`
+	ManifestID   string    `json:"manifestID"`
+	Failures     int       `json:"failures,omitempty"`
+	Score        float64   `json:"score,omitempty"`
+	Code         string    `json:"code"`
+	Reason       string    `json:"reason"`
+	Penalty      int       `json:"penalty"`
+	Time         time.Time `json:"time"`
+}
+
+// verificationFailures counts the consecutive failed verifications of orchestrators
+type verificationFailures struct {
+	mu       sync.Mutex
+	failures map[string]int // orchestrator service URI => consecutive failed verifications
+}
+
+var orchFailures = &verificationFailures{failures: make(map[string]int)}
+
+// record counts a failed verification if failed is true, and resets the count otherwise.
+// It returns the number of consecutive failures of the orchestrator
+func (vf *verificationFailures) record(orch string, failed bool) int {
+	vf.mu.Lock()
+	defer vf.mu.Unlock()
+
+	if !failed {
+		delete(vf.failures, orch)
+		return 0
+	}
+	vf.failures[orch]++
+	return vf.failures[orch]
+}
+
+// sessionManagers are the session managers of the streams of the node. Failures are counted
+// across streams, so an orchestrator that fails verification is suspended in all of them
+type sessionManagers struct {
+	mu   sync.Mutex
+	bsms map[*BroadcastSessionsManager]bool
+}
+
+var liveSessionManagers = &sessionManagers{bsms: make(map[*BroadcastSessionsManager]bool)}
+
+func (sm *sessionManagers) add(bsm *BroadcastSessionsManager) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.bsms[bsm] = true
+}
+
+func (sm *sessionManagers) remove(bsm *BroadcastSessionsManager) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	delete(sm.bsms, bsm)
+}
+
+// suspend suspends an orchestrator in every session manager, including bsm
+func (sm *sessionManagers) suspend(bsm *BroadcastSessionsManager, orch string, penalty int) {
+	sm.mu.Lock()
+	bsms := []*BroadcastSessionsManager{bsm}
+	for b := range sm.bsms {
+		if b != bsm {
+			bsms = append(bsms, b)
+		}
+	}
+	sm.mu.Unlock()
+
+	for _, b := range bsms {
+		b.sus.suspend(orch, penalty)
+	}
+}
+
+// penalizeOrch suspends the orchestrator of the session if it has failed verification
+// VerificationFailureThreshold times in a row, and emits an event about it.
+// It returns whether the orchestrator was suspended
+func penalizeOrch(bsm *BroadcastSessionsManager, sess *BroadcastSession, err error) bool {
+	failures := orchFailures.record(sess.OrchestratorInfo.GetTranscoder(), err != nil)
+	if VerificationFailureThreshold <= 0 || failures < VerificationFailureThreshold {
+		return false
+	}
+
+	suspendPenalizedOrch(bsm, sess, &orchSuspendedEvent{Failures: failures, Code: suspensionCode(err), Reason: err.Error()})
+	return true
+}
+
+// suspendPenalizedOrch suspends the orchestrator of the session for VerificationPenalty refreshes
+// in every stream and emits the event, which holds the reason of the suspension
+func suspendPenalizedOrch(bsm *BroadcastSessionsManager, sess *BroadcastSession, event *orchSuspendedEvent) {
+	event.Event = "orchestrator_suspended"
+	event.Orchestrator = sess.OrchestratorInfo.GetTranscoder()
+	event.ManifestID = string(sess.Params.ManifestID)
+	event.Penalty = VerificationPenalty
+	event.Time = time.Now()
+
+	liveSessionManagers.suspend(bsm, event.Orchestrator, VerificationPenalty)
+	if Fleet != nil {
+		Fleet.recordSuspension(event.Orchestrator)
+	}
+	glog.Errorf("Suspending orchestrator orch=%s manifestID=%s failures=%d score=%v penalty=%d code=%s reason=%s",
+		event.Orchestrator, event.ManifestID, event.Failures, event.Score, event.Penalty, event.Code, event.Reason)
+	if monitor.Enabled {
+		monitor.OrchestratorSuspended(event.Orchestrator, event.Code)
+	}
+	if VerificationWebhookURL != "" {
+		go sendSuspendedEvent(event)
+	}
+}
+
+func sendSuspendedEvent(event *orchSuspendedEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		glog.Error("Error marshaling verification event err=", err)
+		return
+	}
+	resp, err := Webhooks.Post("verification", VerificationWebhookURL, nil, body)
+	if err != nil {
+		glog.Errorf("Error sending verification event orch=%s err=%v", event.Orchestrator, err)
+		return
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livepeer/go-livepeer/verification"
+)
+
+func TestPenalizeOrch(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func() { orchFailures = &verificationFailures{failures: make(map[string]int)} }()
+	orchFailures = &verificationFailures{failures: make(map[string]int)}
+
+	bsm := StubBroadcastSessionsManager()
+	sess := StubBroadcastSession("penalized")
+	other := StubBroadcastSession("other")
+
+	// Suspended after the threshold of consecutive failures is reached
+	for i := 1; i < VerificationFailureThreshold; i++ {
+		assert.False(penalizeOrch(bsm, sess, verification.ErrTampered))
+	}
+	assert.False(penalizeOrch(bsm, other, verification.ErrTampered))
+	assert.Equal(0, bsm.sus.Suspended("penalized"))
+	assert.True(penalizeOrch(bsm, sess, verification.ErrTampered))
+	assert.Equal(VerificationPenalty, bsm.sus.Suspended("penalized"))
+	assert.Equal(0, bsm.sus.Suspended("other"))
+
+	// Failures are counted across streams
+	bsm2 := StubBroadcastSessionsManager()
+	assert.True(penalizeOrch(bsm2, sess, verification.ErrTampered))
+	assert.Equal(VerificationPenalty, bsm2.sus.Suspended("penalized"))
+
+	// Orchestrators are suspended in every stream
+	bsm3 := StubBroadcastSessionsManager()
+	liveSessionManagers.add(bsm3)
+	assert.True(penalizeOrch(bsm2, sess, verification.ErrTampered))
+	assert.Equal(VerificationPenalty, bsm3.sus.Suspended("penalized"))
+	assert.Equal(2*VerificationPenalty, bsm2.sus.Suspended("penalized"))
+	assert.Equal(VerificationPenalty, bsm.sus.Suspended("penalized"))
+	bsm3.cleanup()
+	assert.False(liveSessionManagers.bsms[bsm3])
+
+	// A successful verification resets the count
+	assert.False(penalizeOrch(bsm, sess, nil))
+	assert.False(penalizeOrch(bsm, sess, verification.ErrTampered))
+
+	// Suspensions can be disabled
+	defer func(threshold int) { VerificationFailureThreshold = threshold }(VerificationFailureThreshold)
+	VerificationFailureThreshold = 0
+	for i := 0; i < 10; i++ {
+		assert.False(penalizeOrch(bsm2, other, verification.ErrTampered))
+	}
+}
+
+func TestPenalizeOrch_Webhook(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	defer func() { orchFailures = &verificationFailures{failures: make(map[string]int)} }()
+	orchFailures = &verificationFailures{failures: make(map[string]int)}
+
+	events := make(chan *orchSuspendedEvent, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.Nil(err)
+		var event orchSuspendedEvent
+		require.Nil(json.Unmarshal(body, &event))
+		events <- &event
+	}))
+	defer ts.Close()
+	defer func() { VerificationWebhookURL = "" }()
+	VerificationWebhookURL = ts.URL
+
+	bsm := StubBroadcastSessionsManager()
+	sess := StubBroadcastSession("penalized")
+	for i := 0; i < VerificationFailureThreshold; i++ {
+		penalizeOrch(bsm, sess, verification.ErrTampered)
+	}
+
+	select {
+	case event := <-events:
+		assert.Equal("orchestrator_suspended", event.Event)
+		assert.Equal("penalized", event.Orchestrator)
+		assert.Equal(string(sess.Params.ManifestID), event.ManifestID)
+		assert.Equal(VerificationFailureThreshold, event.Failures)
+		assert.Equal(verification.ErrTampered.Error(), event.Code)
+		assert.Equal(verification.ErrTampered.Error(), event.Reason)
+		assert.Equal(VerificationPenalty, event.Penalty)
+	case <-time.After(5 * time.Second):
+		assert.Fail("Timed out waiting for the verification event")
+	}
+}
+
+func TestSuspensionCode(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("Tampered", suspensionCode(verification.ErrTampered))
+	assert.Equal("PixelMismatch", suspensionCode(verification.ErrPixelMismatch))
+	assert.Equal("AudioMismatch", suspensionCode(verification.ErrAudioMismatch))
+	// free-form errors don't become codes
+	assert.Equal(suspensionCodeVerificationFailed, suspensionCode(errors.New("verifier at 10.0.0.1 said no")))
+}
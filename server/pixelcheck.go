@@ -0,0 +1,90 @@
+package server
+
+import (
+	"math/big"
+	"strconv"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/audit"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/monitor"
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/go-livepeer/verification"
+	"github.com/livepeer/lpms/stream"
+)
+
+// PixelChecker cross-checks the pixel counts that orchestrators charge for, if set
+var PixelChecker *verification.PixelChecker
+
+// maxPixelCheckJobs is the maximum number of segments checked concurrently.
+// Segments sampled while all jobs are busy are not checked
+const maxPixelCheckJobs = 2
+
+var pixelCheckJobs = make(chan struct{}, maxPixelCheckJobs)
+
+// checkPixels cross-checks the pixel counts of a segment in the background if the segment is sampled
+func checkPixels(cxn *rtmpConnection, sess *BroadcastSession, source *stream.HLSSegment, res *net.TranscodeData, segData [][]byte) {
+	params := &verification.Params{
+		ManifestID:   sess.Params.ManifestID,
+		Source:       source,
+		Profiles:     sess.Params.Profiles,
+		Orchestrator: sess.OrchestratorInfo,
+		Results:      res,
+		Renditions:   segData,
+	}
+	if PixelChecker == nil || !PixelChecker.Sample(params) {
+		return
+	}
+
+	select {
+	case pixelCheckJobs <- struct{}{}:
+	default:
+		glog.V(common.DEBUG).Infof("Skipping pixel count check, all jobs are busy manifestID=%s seqNo=%d", cxn.mid, source.SeqNo)
+		return
+	}
+
+	go func() {
+		defer func() { <-pixelCheckJobs }()
+		defer monitor.RecoverAndReport()
+		checkSegmentPixels(cxn.sessManager, cxn.auditLog, sess, params)
+	}()
+}
+
+// checkSegmentPixels checks the pixel counts reported for a segment. The fees of pixels
+// that were reported in excess are credited back to the balance of the session, so that
+// they are deducted from the next payments to the orchestrator, and the orchestrator is
+// penalized as if it failed verification
+func checkSegmentPixels(bsm *BroadcastSessionsManager, auditLog *audit.Log, sess *BroadcastSession, params *verification.Params) {
+	over, err := PixelChecker.Check(params)
+	if err != nil {
+		glog.Errorf("Error checking pixel counts manifestID=%s seqNo=%d err=%v", params.ManifestID, params.Source.SeqNo, err)
+		return
+	}
+	if over == 0 {
+		return
+	}
+
+	orch := sess.OrchestratorInfo.GetTranscoder()
+	refund := big.NewRat(0, 1)
+	if price := sess.OrchestratorInfo.GetPriceInfo(); price != nil && price.PixelsPerUnit > 0 {
+		refund.Mul(big.NewRat(over, 1), big.NewRat(price.PricePerUnit, price.PixelsPerUnit))
+	}
+	glog.Errorf("Orchestrator over-reported pixels orch=%s manifestID=%s seqNo=%d pixels=%d refund=%v",
+		orch, params.ManifestID, params.Source.SeqNo, over, refund.FloatString(0))
+
+	if sess.Balance != nil && refund.Sign() > 0 {
+		sess.Balance.Credit(refund)
+	}
+	auditLog.Append(audit.EventPixelsOverReported, map[string]string{
+		"orchestrator": orch,
+		"manifestID":   string(params.ManifestID),
+		"seqNo":        strconv.FormatUint(params.Source.SeqNo, 10),
+		"pixels":       strconv.FormatInt(over, 10),
+		"refund":       refund.RatString(),
+	})
+	if monitor.Enabled {
+		monitor.PixelsOverReported(orch, over)
+	}
+	penalizeOrch(bsm, sess, verification.ErrPixelMismatch)
+}
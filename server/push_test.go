@@ -79,7 +79,7 @@ func TestPush_MultipartReturn(t *testing.T) {
 	})
 
 	sess := StubBroadcastSession(ts.URL)
-	sess.Params.Profiles = []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9}
+	sess.Params.Profiles = []ffmpeg.VideoProfile{ffmpeg.P144p25fps16x9}
 	sess.Params.ManifestID = "mani"
 	bsm := bsmWithSessList([]*BroadcastSession{sess})
 
@@ -95,7 +95,7 @@ func TestPush_MultipartReturn(t *testing.T) {
 		pl:          pl,
 		profile:     &ffmpeg.P144p30fps16x9,
 		sessManager: bsm,
-		params:      &core.StreamParameters{Profiles: []ffmpeg.VideoProfile{ffmpeg.P144p25fps16x9}},
+		params:      sess.Params,
 	}
 
 	s.rtmpConnections["mani"] = cxn
@@ -503,9 +503,8 @@ func TestPush_SetVideoProfileFormats(t *testing.T) {
 		w.Write(val)
 	}))
 	defer ts.Close()
-	oldURL := AuthWebhookURL
-	defer func() { AuthWebhookURL = oldURL }()
-	AuthWebhookURL = ts.URL
+	defer SetAuthWebhookURL(AuthWebhookURL())
+	SetAuthWebhookURL(ts.URL)
 
 	h, r, w = requestSetup(s)
 	req = httptest.NewRequest("POST", "/live/web/0.mp4", r)
@@ -533,7 +532,7 @@ func ignoreRoutines() []goleak.Option {
 		"github.com/ethereum/go-ethereum/consensus/ethash.(*Ethash).remote", "github.com/ethereum/go-ethereum/core.(*txSenderCacher).cache",
 		"internal/poll.runtime_pollWait", "github.com/livepeer/go-livepeer/core.(*RemoteTranscoderManager).Manage", "github.com/livepeer/lpms/core.(*LPMS).Start",
 		"github.com/livepeer/go-livepeer/server.(*LivepeerServer).StartMediaServer", "github.com/livepeer/go-livepeer/core.(*RemoteTranscoderManager).Manage.func1",
-		"github.com/livepeer/go-livepeer/server.(*LivepeerServer).HandlePush.func1", "github.com/rjeczalik/notify.(*nonrecursiveTree).dispatch",
+		"github.com/livepeer/go-livepeer/server.removeIdleStream", "github.com/rjeczalik/notify.(*nonrecursiveTree).dispatch",
 		"github.com/rjeczalik/notify.(*nonrecursiveTree).internal", "github.com/livepeer/lpms/stream.NewBasicRTMPVideoStream.func1"}
 
 	res := make([]goleak.Option, 0, len(funcs2ignore))
@@ -769,7 +768,7 @@ func TestPush_ForAuthWebhookFailure(t *testing.T) {
 	defer serverCleanup(s)
 	handler, reader, w := requestSetup(s)
 
-	AuthWebhookURL = "notaurl"
+	SetAuthWebhookURL("notaurl")
 	req := httptest.NewRequest("POST", "/live/seg.ts", reader)
 
 	handler.ServeHTTP(w, req)
@@ -782,7 +781,7 @@ func TestPush_ForAuthWebhookFailure(t *testing.T) {
 	assert.Contains(strings.TrimSpace(string(body)), "Could not create stream ID")
 
 	// reset AuthWebhookURL to original value
-	AuthWebhookURL = ""
+	SetAuthWebhookURL("")
 }
 
 func TestPush_ResolutionWithoutContentResolutionHeader(t *testing.T) {
@@ -848,7 +847,7 @@ func TestPush_WebhookRequestURL(t *testing.T) {
 
 	defer ts.Close()
 
-	AuthWebhookURL = ts.URL
+	SetAuthWebhookURL(ts.URL)
 	handler, reader, w := requestSetup(s)
 	req := httptest.NewRequest("POST", "/live/seg.ts", reader)
 	handler.ServeHTTP(w, req)
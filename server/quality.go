@@ -0,0 +1,67 @@
+package server
+
+import (
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/monitor"
+	"github.com/livepeer/go-livepeer/verification"
+	"github.com/livepeer/lpms/stream"
+)
+
+// Quality scores sampled renditions against their source, if set
+var Quality *verification.QualityScorer
+
+// maxQualityJobs is the maximum number of segments scored concurrently.
+// Segments sampled while all jobs are busy are not scored
+const maxQualityJobs = 2
+
+var qualityJobs = make(chan struct{}, maxQualityJobs)
+
+// scoreQuality scores the renditions of a segment in the background if the segment is sampled,
+// and suspends the orchestrator if its average score falls below the minimum score
+func scoreQuality(cxn *rtmpConnection, sess *BroadcastSession, source *stream.HLSSegment, segData [][]byte) {
+	params := &verification.Params{
+		ManifestID:   sess.Params.ManifestID,
+		Source:       source,
+		Profiles:     sess.Params.Profiles,
+		Orchestrator: sess.OrchestratorInfo,
+		Renditions:   segData,
+	}
+	if Quality == nil || !Quality.Sample(params) {
+		return
+	}
+
+	select {
+	case qualityJobs <- struct{}{}:
+	default:
+		glog.V(common.DEBUG).Infof("Skipping quality scoring, all jobs are busy manifestID=%s seqNo=%d", cxn.mid, source.SeqNo)
+		return
+	}
+
+	go func() {
+		defer func() { <-qualityJobs }()
+		defer monitor.RecoverAndReport()
+		scoreSegmentQuality(cxn.sessManager, sess, params)
+	}()
+}
+
+func scoreSegmentQuality(bsm *BroadcastSessionsManager, sess *BroadcastSession, params *verification.Params) {
+	scores, err := Quality.Score(params)
+	if err != nil {
+		glog.Errorf("Error scoring quality manifestID=%s seqNo=%d err=%v", params.ManifestID, params.Source.SeqNo, err)
+		return
+	}
+
+	orch := sess.OrchestratorInfo.GetTranscoder()
+	if monitor.Enabled {
+		for profile, score := range scores {
+			monitor.QualityScore(orch, profile, score)
+		}
+	}
+
+	if Quality.LowQuality(orch) {
+		avg, _ := Quality.Average(orch)
+		suspendPenalizedOrch(bsm, sess, &orchSuspendedEvent{Score: avg, Code: suspensionCodeLowQuality, Reason: suspensionCodeLowQuality})
+	}
+}
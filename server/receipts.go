@@ -0,0 +1,84 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+	lpcrypto "github.com/livepeer/go-livepeer/crypto"
+	"github.com/livepeer/go-livepeer/net"
+)
+
+// ReceiptStore stores the transcode receipts sent by orchestrators, if set
+var ReceiptStore *common.DB
+
+var (
+	errReceiptMismatch = errors.New("receipt does not match the segments received")
+	errReceiptSig      = errors.New("invalid receipt signature")
+)
+
+// recordReceipt records a segment received from the orchestrator of the session. Receipts
+// sent by the orchestrator are checked against the segments received from it within the
+// sequence numbers they cover, once all of them have been received, and stored along with
+// the segments so that the delivery of any of them can be proven later. Segments are only
+// recorded for orchestrators that negotiated receipts, since the others never send any
+func recordReceipt(cxn *rtmpConnection, sess *BroadcastSession, seqNo uint64, res *net.TranscodeData, segData [][]byte) {
+	if ReceiptStore == nil || cxn.receipts == nil {
+		return
+	}
+	if !core.HasProtocolFeature(sess.OrchestratorInfo.GetProtocol(), core.ProtocolFeatureReceipts) {
+		return
+	}
+
+	orch := sess.OrchestratorInfo.GetTranscoder()
+	cxn.receipts.Add(orch, core.ReceiptSegment{SeqNo: int64(seqNo), Hash: core.SegmentHash(segData)})
+	for _, b := range cxn.receipts.Settle(orch, res.GetReceipt()) {
+		receipt := b.Receipt
+		err := checkReceipt(sess, receipt, b.Segments)
+		if err != nil {
+			glog.Errorf("Error checking transcode receipt orch=%s manifestID=%s startSeq=%d endSeq=%d err=%v",
+				orch, cxn.mid, receipt.StartSeq, receipt.EndSeq, err)
+		}
+
+		r := &common.DBTranscodeReceipt{
+			ManifestID:   string(cxn.mid),
+			Orchestrator: orch,
+			StartSeq:     receipt.StartSeq,
+			EndSeq:       receipt.EndSeq,
+			Root:         receipt.Root,
+			Sig:          receipt.Sig,
+			Verified:     err == nil,
+		}
+		for _, s := range b.Segments {
+			r.Segments = append(r.Segments, common.DBReceiptSegment{SeqNo: s.SeqNo, Hash: s.Hash})
+		}
+		ReceiptStore.InsertTranscodeReceipt(r)
+	}
+}
+
+// checkReceipt checks that a receipt covers exactly the given segments and that it
+// is signed by the orchestrator of the session
+func checkReceipt(sess *BroadcastSession, receipt *net.TranscodeReceipt, segs []core.ReceiptSegment) error {
+	expected := core.NewReceipt(sess.Params.ManifestID, segs)
+	if receipt.ManifestId != expected.ManifestId || receipt.Segments != expected.Segments ||
+		receipt.StartSeq != expected.StartSeq || receipt.EndSeq != expected.EndSeq ||
+		!bytes.Equal(receipt.Root, expected.Root) {
+		return errReceiptMismatch
+	}
+
+	// Verify the signature against the orchestrator provided address if it exists
+	// Otherwise verify the signature against the ticket recipient address
+	addr := ethcommon.BytesToAddress(sess.OrchestratorInfo.GetAddress())
+	if (addr == ethcommon.Address{}) {
+		addr = ethcommon.BytesToAddress(sess.OrchestratorInfo.GetTicketParams().GetRecipient())
+	}
+	if !lpcrypto.VerifySig(addr, crypto.Keccak256(core.FlattenReceipt(receipt)), receipt.Sig) {
+		return errReceiptSig
+	}
+	return nil
+}
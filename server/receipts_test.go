@@ -0,0 +1,209 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+	lpcrypto "github.com/livepeer/go-livepeer/crypto"
+	"github.com/livepeer/go-livepeer/net"
+)
+
+func TestRecordReceipt(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dbh, dbraw, err := common.TempDB(t)
+	require.Nil(err)
+	defer dbh.Close()
+	defer dbraw.Close()
+	defer func() { ReceiptStore = nil }()
+	ReceiptStore = dbh
+
+	orch := newStubOrchestrator()
+	cxn := &rtmpConnection{mid: "mid", receipts: core.NewReceiptTracker()}
+	sess := &BroadcastSession{
+		Params: &core.StreamParameters{ManifestID: "mid"},
+		OrchestratorInfo: &net.OrchestratorInfo{
+			Transcoder: "https://o1",
+			Address:    orch.Address().Bytes(),
+			Protocol:   &net.ProtocolInfo{Features: []string{core.ProtocolFeatureReceipts}},
+		},
+	}
+	renditions := func(seqNo int) [][]byte {
+		return [][]byte{{byte(seqNo), 0}, {byte(seqNo), 1}}
+	}
+	signedReceipt := func(segs []core.ReceiptSegment) *net.TranscodeReceipt {
+		r := core.NewReceipt("mid", segs)
+		r.Sig, err = orch.Sign(core.FlattenReceipt(r))
+		require.Nil(err)
+		return r
+	}
+
+	// Segments without receipts are only recorded
+	var segs []core.ReceiptSegment
+	for seqNo := 0; seqNo < 3; seqNo++ {
+		segs = append(segs, core.ReceiptSegment{SeqNo: int64(seqNo), Hash: core.SegmentHash(renditions(seqNo))})
+		var receipt *net.TranscodeReceipt
+		if seqNo == 2 {
+			receipt = signedReceipt(segs)
+		}
+		recordReceipt(cxn, sess, uint64(seqNo), &net.TranscodeData{Receipt: receipt}, renditions(seqNo))
+	}
+
+	receipts, err := dbh.TranscodeReceipts(nil)
+	require.Nil(err)
+	require.Len(receipts, 1)
+	assert.True(receipts[0].Verified)
+	assert.Equal("https://o1", receipts[0].Orchestrator)
+	assert.Equal(int64(0), receipts[0].StartSeq)
+	assert.Equal(int64(2), receipts[0].EndSeq)
+	assert.Len(receipts[0].Segments, 3)
+
+	// Receipts that don't match the segments received are stored but not verified
+	recordReceipt(cxn, sess, 3, &net.TranscodeData{}, renditions(3))
+	mismatch := signedReceipt([]core.ReceiptSegment{
+		{SeqNo: 3, Hash: core.SegmentHash(renditions(3))},
+		{SeqNo: 4, Hash: core.SegmentHash(renditions(5))},
+	})
+	recordReceipt(cxn, sess, 4, &net.TranscodeData{Receipt: mismatch}, renditions(4))
+	receipts, err = dbh.TranscodeReceipts(&common.DBTranscodeReceiptFilter{Limit: 1})
+	require.Nil(err)
+	assert.False(receipts[0].Verified)
+	assert.Len(receipts[0].Segments, 2)
+
+	// Receipts that arrive before some of their segments wait for them
+	segs = nil
+	for seqNo := 5; seqNo < 8; seqNo++ {
+		segs = append(segs, core.ReceiptSegment{SeqNo: int64(seqNo), Hash: core.SegmentHash(renditions(seqNo))})
+	}
+	recordReceipt(cxn, sess, 5, &net.TranscodeData{}, renditions(5))
+	recordReceipt(cxn, sess, 7, &net.TranscodeData{Receipt: signedReceipt(segs)}, renditions(7))
+	receipts, err = dbh.TranscodeReceipts(nil)
+	require.Nil(err)
+	assert.Len(receipts, 2)
+	recordReceipt(cxn, sess, 6, &net.TranscodeData{}, renditions(6))
+	receipts, err = dbh.TranscodeReceipts(&common.DBTranscodeReceiptFilter{Limit: 1})
+	require.Nil(err)
+	assert.True(receipts[0].Verified)
+	assert.Equal(int64(5), receipts[0].StartSeq)
+	assert.Len(receipts[0].Segments, 3)
+
+	// Segments of orchestrators that didn't negotiate receipts aren't recorded
+	legacy := &BroadcastSession{
+		Params:           sess.Params,
+		OrchestratorInfo: &net.OrchestratorInfo{Transcoder: "https://o2", Address: orch.Address().Bytes()},
+	}
+	recordReceipt(cxn, legacy, 8, &net.TranscodeData{}, renditions(8))
+	assert.Empty(cxn.receipts.Flush("https://o2"))
+
+	// No-op if receipts are disabled
+	ReceiptStore = nil
+	recordReceipt(cxn, sess, 8, &net.TranscodeData{Receipt: signedReceipt(segs)}, renditions(8))
+	receipts, err = dbh.TranscodeReceipts(nil)
+	require.Nil(err)
+	assert.Len(receipts, 3)
+}
+
+func TestCheckReceipt(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	orch := newStubOrchestrator()
+	sess := &BroadcastSession{
+		Params:           &core.StreamParameters{ManifestID: "mid"},
+		OrchestratorInfo: &net.OrchestratorInfo{Address: orch.Address().Bytes()},
+	}
+	segs := []core.ReceiptSegment{
+		{SeqNo: 1, Hash: core.SegmentHash([][]byte{{1}})},
+		{SeqNo: 2, Hash: core.SegmentHash([][]byte{{2}})},
+	}
+	sign := func(r *net.TranscodeReceipt) *net.TranscodeReceipt {
+		sig, err := orch.Sign(core.FlattenReceipt(r))
+		require.Nil(err)
+		r.Sig = sig
+		return r
+	}
+
+	assert.Nil(checkReceipt(sess, sign(core.NewReceipt("mid", segs)), segs))
+
+	// Receipts for other streams or segments are rejected
+	assert.Equal(errReceiptMismatch, checkReceipt(sess, sign(core.NewReceipt("foo", segs)), segs))
+	assert.Equal(errReceiptMismatch, checkReceipt(sess, sign(core.NewReceipt("mid", segs[:1])), segs))
+	r := core.NewReceipt("mid", segs)
+	r.EndSeq = 3
+	assert.Equal(errReceiptMismatch, checkReceipt(sess, sign(r), segs))
+
+	// The receipt must be signed by the orchestrator
+	r = sign(core.NewReceipt("mid", segs))
+	r.Sig[0]++
+	assert.Equal(errReceiptSig, checkReceipt(sess, r, segs))
+	other := newStubOrchestrator()
+	r.Sig, _ = other.Sign(core.FlattenReceipt(r))
+	assert.Equal(errReceiptSig, checkReceipt(sess, r, segs))
+
+	// The ticket recipient is used if the orchestrator has no address
+	sess.OrchestratorInfo = &net.OrchestratorInfo{TicketParams: &net.TicketParams{Recipient: orch.Address().Bytes()}}
+	assert.Nil(checkReceipt(sess, sign(core.NewReceipt("mid", segs)), segs))
+}
+
+func TestTranscodeReceiptsHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	resp := httpGetResp(transcodeReceiptsHandler(nil))
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+
+	dbh, dbraw, err := common.TempDB(t)
+	require.Nil(err)
+	defer dbh.Close()
+	defer dbraw.Close()
+
+	segs := []core.ReceiptSegment{
+		{SeqNo: 0, Hash: core.SegmentHash([][]byte{{0}})},
+		{SeqNo: 1, Hash: core.SegmentHash([][]byte{{1}})},
+		{SeqNo: 2, Hash: core.SegmentHash([][]byte{{2}})},
+	}
+	root := core.ReceiptRoot(segs)
+	r := &common.DBTranscodeReceipt{ManifestID: "mid", Orchestrator: "https://o1", StartSeq: 0, EndSeq: 2, Root: root, Verified: true}
+	for _, s := range segs {
+		r.Segments = append(r.Segments, common.DBReceiptSegment{SeqNo: s.SeqNo, Hash: s.Hash})
+	}
+	require.Nil(dbh.InsertTranscodeReceipt(r))
+
+	query := func(form string) ([]*transcodeReceipt, int) {
+		resp := httpPostFormResp(transcodeReceiptsHandler(dbh), strings.NewReader(form))
+		if resp.StatusCode != http.StatusOK {
+			return nil, resp.StatusCode
+		}
+		var receipts []*transcodeReceipt
+		require.Nil(json.NewDecoder(resp.Body).Decode(&receipts))
+		return receipts, resp.StatusCode
+	}
+
+	receipts, code := query("manifestID=mid")
+	assert.Equal(http.StatusOK, code)
+	require.Len(receipts, 1)
+	assert.Equal(root, receipts[0].Root)
+	assert.Nil(receipts[0].Proof)
+
+	// The proof of a segment is returned along with the receipts that cover it
+	receipts, _ = query("manifestID=mid&seqNo=1")
+	require.Len(receipts, 1)
+	assert.Equal(segs[1].Leaf(), receipts[0].Leaf)
+	assert.True(lpcrypto.VerifyMerkleProof(root, receipts[0].Leaf, receipts[0].Proof))
+
+	receipts, _ = query("manifestID=mid&seqNo=3")
+	assert.Empty(receipts)
+
+	_, code = query("seqNo=foo")
+	assert.Equal(http.StatusBadRequest, code)
+	_, code = query("limit=0")
+	assert.Equal(http.StatusBadRequest, code)
+}
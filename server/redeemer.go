@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	gonet "net"
@@ -12,6 +13,8 @@ import (
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/livepeer/go-livepeer/common"
 	"github.com/livepeer/go-livepeer/eth"
 	"github.com/livepeer/go-livepeer/net"
 	"github.com/livepeer/go-livepeer/pm"
@@ -209,6 +212,18 @@ func (r *RedeemerClient) QueueTicket(ticket *pm.SignedTicket) error {
 	return err
 }
 
+// WinningTickets returns an error since the winning tickets sent to the Redeemer are
+// queued by the Redeemer
+func (r *RedeemerClient) WinningTickets() ([]*pm.SignedTicket, error) {
+	return nil, errors.New("winning tickets are queued by the remote redeemer")
+}
+
+// RemoveWinningTickets returns an error since the winning tickets sent to the Redeemer are
+// queued by the Redeemer
+func (r *RedeemerClient) RemoveWinningTickets(tickets []*pm.SignedTicket) error {
+	return errors.New("winning tickets are queued by the remote redeemer")
+}
+
 // MaxFloat returns the max float for 'sender'
 // If no local cache is available this method will remotely request
 // max float from the Redeemer server and start watching for subsequent updates from the Redeemer server
@@ -343,21 +358,26 @@ func (r *RedeemerClient) startCleanupLoop() {
 	}
 }
 
+// pmTicket converts a ticket sent over the wire. Missing fields are zero, which tickets
+// fail to validate with
 func pmTicket(ticket *net.Ticket) *pm.SignedTicket {
+	params := ticket.GetTicketParams()
 	return &pm.SignedTicket{
 		Ticket: &pm.Ticket{
-			Recipient:              ethcommon.BytesToAddress(ticket.TicketParams.Recipient),
-			Sender:                 ethcommon.BytesToAddress(ticket.Sender),
-			FaceValue:              new(big.Int).SetBytes(ticket.TicketParams.FaceValue),
-			WinProb:                new(big.Int).SetBytes(ticket.TicketParams.WinProb),
-			SenderNonce:            ticket.SenderParams.SenderNonce,
-			RecipientRandHash:      ethcommon.BytesToHash(ticket.TicketParams.RecipientRandHash),
-			CreationRound:          ticket.ExpirationParams.CreationRound,
-			CreationRoundBlockHash: ethcommon.BytesToHash(ticket.ExpirationParams.CreationRoundBlockHash),
-			ParamsExpirationBlock:  new(big.Int).SetBytes(ticket.TicketParams.ExpirationBlock),
+			Recipient:              ethcommon.BytesToAddress(params.GetRecipient()),
+			Sender:                 ethcommon.BytesToAddress(ticket.GetSender()),
+			FaceValue:              new(big.Int).SetBytes(params.GetFaceValue()),
+			WinProb:                new(big.Int).SetBytes(params.GetWinProb()),
+			SenderNonce:            ticket.GetSenderParams().GetSenderNonce(),
+			RecipientRandHash:      ethcommon.BytesToHash(params.GetRecipientRandHash()),
+			CreationRound:          ticket.GetExpirationParams().GetCreationRound(),
+			CreationRoundBlockHash: ethcommon.BytesToHash(ticket.GetExpirationParams().GetCreationRoundBlockHash()),
+			ParamsExpirationBlock:  new(big.Int).SetBytes(params.GetExpirationBlock()),
+			PayoutSplit:            common.PmPayoutSplit(params.GetPayoutSplit()),
+			Version:                params.GetVersion(),
 		},
-		RecipientRand: new(big.Int).SetBytes(ticket.RecipientRand),
-		Sig:           ticket.SenderParams.Sig,
+		RecipientRand: new(big.Int).SetBytes(ticket.GetRecipientRand()),
+		Sig:           ticket.GetSenderParams().GetSig(),
 	}
 }
 
@@ -371,6 +391,8 @@ func protoTicket(ticket *pm.SignedTicket) *net.Ticket {
 			WinProb:           ticket.WinProb.Bytes(),
 			RecipientRandHash: ticket.RecipientRandHash.Bytes(),
 			ExpirationBlock:   ticket.ParamsExpirationBlock.Bytes(),
+			PayoutSplit:       common.ProtoPayoutSplit(ticket.PayoutSplit),
+			Version:           ticket.Version,
 		},
 		SenderParams: &net.TicketSenderParams{
 			SenderNonce: ticket.SenderNonce,
@@ -382,3 +404,29 @@ func protoTicket(ticket *pm.SignedTicket) *net.Ticket {
 		},
 	}
 }
+
+// protoContentType is the content type of protobuf encoded winning tickets
+const protoContentType = "application/x-protobuf"
+
+// marshalWinningTickets encodes winning tickets along with their signatures and
+// recipientRands as a protobuf WinningTickets message
+func marshalWinningTickets(tickets []*pm.SignedTicket) ([]byte, error) {
+	msg := &net.WinningTickets{Tickets: make([]*net.Ticket, len(tickets))}
+	for i, ticket := range tickets {
+		msg.Tickets[i] = protoTicket(ticket)
+	}
+	return proto.Marshal(msg)
+}
+
+// unmarshalWinningTickets decodes winning tickets encoded by marshalWinningTickets
+func unmarshalWinningTickets(data []byte) ([]*pm.SignedTicket, error) {
+	var msg net.WinningTickets
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	tickets := make([]*pm.SignedTicket, len(msg.Tickets))
+	for i, ticket := range msg.Tickets {
+		tickets[i] = pmTicket(ticket)
+	}
+	return tickets, nil
+}
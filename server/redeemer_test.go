@@ -728,6 +728,65 @@ func TestProtoTicket(t *testing.T) {
 	}
 	pTicket := protoTicket(ogTicket)
 	assert.Equal(t, ogTicket, pmTicket(pTicket))
+
+	// Missing fields are zero
+	ticket := pmTicket(&net.Ticket{Sender: []byte("bar")})
+	assert.Equal(t, ogTicket.Sender, ticket.Sender)
+	assert.Equal(t, big.NewInt(0), ticket.FaceValue)
+	assert.Nil(t, ticket.Sig)
+}
+
+func TestMarshalWinningTickets(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	tickets := []*pm.SignedTicket{
+		{
+			Ticket: &pm.Ticket{
+				Recipient:              pm.RandAddress(),
+				Sender:                 pm.RandAddress(),
+				FaceValue:              big.NewInt(100),
+				WinProb:                big.NewInt(200),
+				SenderNonce:            3,
+				RecipientRandHash:      pm.RandHash(),
+				CreationRound:          4,
+				CreationRoundBlockHash: pm.RandHash(),
+				ParamsExpirationBlock:  big.NewInt(5),
+				PayoutSplit:            &pm.PayoutSplit{Recipient: pm.RandAddress(), Share: 125000},
+				Version:                pm.TicketVersionTypedData,
+			},
+			RecipientRand: big.NewInt(6),
+			Sig:           pm.RandBytes(65),
+		},
+		{
+			Ticket: &pm.Ticket{
+				Recipient:              pm.RandAddress(),
+				Sender:                 pm.RandAddress(),
+				FaceValue:              big.NewInt(1),
+				WinProb:                big.NewInt(2),
+				RecipientRandHash:      pm.RandHash(),
+				CreationRoundBlockHash: pm.RandHash(),
+				ParamsExpirationBlock:  big.NewInt(0),
+			},
+			RecipientRand: big.NewInt(7),
+			Sig:           pm.RandBytes(65),
+		},
+	}
+
+	data, err := marshalWinningTickets(tickets)
+	require.Nil(err)
+	res, err := unmarshalWinningTickets(data)
+	require.Nil(err)
+	assert.Equal(tickets, res)
+
+	data, err = marshalWinningTickets(nil)
+	require.Nil(err)
+	res, err = unmarshalWinningTickets(data)
+	assert.Nil(err)
+	assert.Empty(res)
+
+	_, err = unmarshalWinningTickets([]byte("foo"))
+	assert.NotNil(err)
 }
 
 // Stubs
@@ -758,6 +817,17 @@ func (s *stubSenderMonitor) QueueTicket(ticket *pm.SignedTicket) error {
 	return nil
 }
 
+func (s *stubSenderMonitor) WinningTickets() ([]*pm.SignedTicket, error) {
+	if s.shouldFail != nil {
+		return nil, s.shouldFail
+	}
+	return s.queued, nil
+}
+
+func (s *stubSenderMonitor) RemoveWinningTickets(tickets []*pm.SignedTicket) error {
+	return s.shouldFail
+}
+
 func (s *stubSenderMonitor) MaxFloat(addr ethcommon.Address) (*big.Int, error) {
 	if s.shouldFail != nil {
 		return nil, s.shouldFail
@@ -0,0 +1,359 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	gonet "net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/http2"
+
+	"github.com/livepeer/go-livepeer/monitor"
+	"github.com/livepeer/go-livepeer/pm"
+)
+
+// Orchestrators without public ingress, e.g. behind CGNAT, serve broadcasters through a
+// relay: the orchestrator opens a tunnel to the relay, over which the relay forwards the
+// requests that broadcasters send to the service URI of the orchestrator
+const (
+	relayPath         = "/relay/tunnel"
+	relayProtocol     = "livepeer-relay"
+	relaySecretHeader = "Livepeer-Relay-Secret"
+	relayHostHeader   = "Livepeer-Relay-Host"
+	relayIDHeader     = "Livepeer-Relay-ID"
+)
+
+var (
+	relayDialTimeout   = 10 * time.Second
+	relayRetryInterval = 5 * time.Second
+	relayPingInterval  = 20 * time.Second
+	relayPingTimeout   = 10 * time.Second
+)
+
+// RelayURI is the relay that the orchestrator serves broadcasters through, if any, and
+// RelaySecret the secret shared by the relay and the orchestrators using it
+var (
+	RelayURI    *url.URL
+	RelaySecret string
+)
+
+var errRelayNoTunnel = errors.New("orchestrator not connected to the relay")
+
+// relayedKey is the context key marking the requests served through a relay
+type relayedKey struct{}
+
+// Relay forwards the requests of broadcasters to the orchestrators that opened a tunnel to
+// it. Requests are routed by host, so each orchestrator must use its own host, e.g. a DNS
+// name resolving to the relay, in its service URI
+type Relay struct {
+	secret string
+	proxy  *httputil.ReverseProxy
+	server *http.Server
+
+	mu      sync.Mutex
+	tunnels map[string]*relayTunnel
+	quit    chan struct{}
+}
+
+// relayTunnel is the tunnel of a host, opened by the orchestrator with the random id
+// that it sends with all its tunnels. Only that orchestrator can replace the tunnel
+// while it's open, so that other holders of the secret can't take over the host
+type relayTunnel struct {
+	cc *http2.ClientConn
+	id string
+}
+
+// NewRelay creates a new relay accepting tunnels from the orchestrators sharing secret
+func NewRelay(secret string) (*Relay, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("must provide a secret")
+	}
+	r := &Relay{
+		secret:  secret,
+		tunnels: make(map[string]*relayTunnel),
+		quit:    make(chan struct{}),
+	}
+	r.proxy = &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = "https"
+			req.URL.Host = req.Host
+		},
+		Transport: relayTransport{r},
+		// Streams gRPC and multipart responses as the orchestrator writes them
+		FlushInterval: -1,
+	}
+	return r, nil
+}
+
+// Start starts the relay server
+// This method will block
+func (r *Relay) Start(uri *url.URL, workDir string) error {
+	cert, key, err := getCert(uri, workDir)
+	if err != nil {
+		return err
+	}
+	r.server = &http.Server{
+		Addr:              uri.Host,
+		Handler:           monitor.ReportPanics(r),
+		ReadHeaderTimeout: readHeaderTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+	return r.server.ListenAndServeTLS(cert, key)
+}
+
+// Stop stops the relay server and closes the tunnels
+func (r *Relay) Stop() {
+	close(r.quit)
+	if r.server != nil {
+		r.server.Close()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for host, t := range r.tunnels {
+		t.cc.Close()
+		delete(r.tunnels, host)
+	}
+}
+
+func (r *Relay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == relayPath && strings.EqualFold(req.Header.Get("Upgrade"), relayProtocol) {
+		r.serveTunnel(w, req)
+		return
+	}
+	if r.tunnel(req.Host) == nil {
+		http.Error(w, errRelayNoTunnel.Error(), http.StatusBadGateway)
+		return
+	}
+	r.proxy.ServeHTTP(w, req)
+}
+
+// serveTunnel takes over the connection of a tunnel request, over which the relay then
+// sends requests to the orchestrator
+func (r *Relay) serveTunnel(w http.ResponseWriter, req *http.Request) {
+	if subtle.ConstantTimeCompare([]byte(req.Header.Get(relaySecretHeader)), []byte(r.secret)) != 1 {
+		glog.Errorf("Invalid relay secret from addr=%v", req.RemoteAddr)
+		http.Error(w, "invalid secret", http.StatusUnauthorized)
+		return
+	}
+	host := req.Header.Get(relayHostHeader)
+	if host == "" {
+		http.Error(w, "missing "+relayHostHeader, http.StatusBadRequest)
+		return
+	}
+	id := req.Header.Get(relayIDHeader)
+	if id == "" {
+		http.Error(w, "missing "+relayIDHeader, http.StatusBadRequest)
+		return
+	}
+	if r.registered(host, id) {
+		glog.Errorf("Relay tunnel refused for host=%v already registered by another orchestrator addr=%v", host, req.RemoteAddr)
+		http.Error(w, "host already registered", http.StatusConflict)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "tunnels require HTTP/1.1", http.StatusBadRequest)
+		return
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		glog.Errorf("Unable to take over relay tunnel from addr=%v err=%v", req.RemoteAddr, err)
+		return
+	}
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: " + relayProtocol + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return
+	}
+	cc, err := (&http2.Transport{}).NewClientConn(&bufferedConn{Conn: conn, r: rw.Reader})
+	if err != nil {
+		glog.Errorf("Unable to open relay tunnel from addr=%v err=%v", req.RemoteAddr, err)
+		conn.Close()
+		return
+	}
+
+	r.mu.Lock()
+	if old, ok := r.tunnels[host]; ok {
+		if old.id != id && old.cc.CanTakeNewRequest() {
+			// Another orchestrator registered the host in the meantime
+			r.mu.Unlock()
+			cc.Close()
+			return
+		}
+		// The orchestrator reconnected
+		old.cc.Close()
+	}
+	r.tunnels[host] = &relayTunnel{cc: cc, id: id}
+	r.mu.Unlock()
+	glog.Infof("Relay tunnel opened host=%v addr=%v", host, req.RemoteAddr)
+
+	go r.keepAlive(host, cc)
+}
+
+// keepAlive pings the orchestrator over the tunnel, which also keeps the NAT mapping of
+// the tunnel alive, and closes the tunnel if the orchestrator doesn't answer
+func (r *Relay) keepAlive(host string, cc *http2.ClientConn) {
+	ticker := time.NewTicker(relayPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.quit:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), relayPingTimeout)
+		err := cc.Ping(ctx)
+		cancel()
+		if err != nil || !cc.CanTakeNewRequest() {
+			r.mu.Lock()
+			if t, ok := r.tunnels[host]; ok && t.cc == cc {
+				delete(r.tunnels, host)
+			}
+			r.mu.Unlock()
+			cc.Close()
+			glog.Errorf("Relay tunnel closed host=%v err=%v", host, err)
+			return
+		}
+	}
+}
+
+func (r *Relay) tunnel(host string) *http2.ClientConn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tunnels[host]
+	if !ok {
+		return nil
+	}
+	if !t.cc.CanTakeNewRequest() {
+		delete(r.tunnels, host)
+		return nil
+	}
+	return t.cc
+}
+
+// registered returns whether another orchestrator than the one with id has an open
+// tunnel for host
+func (r *Relay) registered(host, id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tunnels[host]
+	return ok && t.id != id && t.cc.CanTakeNewRequest()
+}
+
+// relayTransport sends requests over the tunnel of the orchestrator of their host
+type relayTransport struct {
+	r *Relay
+}
+
+func (t relayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cc := t.r.tunnel(req.URL.Host)
+	if cc == nil {
+		return nil, errRelayNoTunnel
+	}
+	return cc.RoundTrip(req)
+}
+
+// ServeRelay serves handler to broadcasters through the relay at relay, for the host of
+// the service URI of the orchestrator, until ctx is done. The tunnel is reopened whenever
+// it is lost
+func ServeRelay(ctx context.Context, relay *url.URL, secret, host string, handler http.Handler) {
+	// The relay binds the host to the id until the tunnel is lost
+	id := hex.EncodeToString(pm.RandBytes(16))
+	srv := &http2.Server{}
+	relayed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), relayedKey{}, true)))
+	})
+	for {
+		conn, err := dialRelay(relay, secret, host, id)
+		if err != nil {
+			glog.Errorf("Unable to connect to relay=%v err=%v", relay.Host, err)
+		} else {
+			glog.Infof("Serving broadcasters through relay=%v host=%v", relay.Host, host)
+			done := make(chan struct{})
+			go func() {
+				select {
+				case <-ctx.Done():
+					conn.Close()
+				case <-done:
+				}
+			}()
+			srv.ServeConn(conn, &http2.ServeConnOpts{Handler: relayed})
+			close(done)
+			if ctx.Err() == nil {
+				glog.Errorf("Lost connection to relay=%v", relay.Host)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(relayRetryInterval):
+		}
+	}
+}
+
+// dialRelay opens a tunnel to the relay for host, identified by id
+func dialRelay(relay *url.URL, secret, host, id string) (gonet.Conn, error) {
+	dialer := &gonet.Dialer{Timeout: relayDialTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", relay.Host, &tls.Config{
+		InsecureSkipVerify: true,
+		// Tunnel requests take over the connection, which HTTP/2 doesn't allow
+		NextProtos: []string{"http/1.1"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", "https://"+relay.Host+relayPath, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", relayProtocol)
+	req.Header.Set(relaySecretHeader, secret)
+	req.Header.Set(relayHostHeader, host)
+	req.Header.Set(relayIDHeader, id)
+
+	conn.SetDeadline(time.Now().Add(relayDialTimeout))
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		conn.Close()
+		return nil, fmt.Errorf("relay refused tunnel status=%v err=%v", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	conn.SetDeadline(time.Time{})
+	return &bufferedConn{Conn: conn, r: br}, nil
+}
+
+// bufferedConn is a connection whose first bytes were read into a buffer
+type bufferedConn struct {
+	gonet.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+
+	"github.com/livepeer/go-livepeer/net"
+)
+
+func stubRelay(t *testing.T, secret string) (*Relay, *httptest.Server) {
+	relay, err := NewRelay(secret)
+	require.Nil(t, err)
+	ts := httptest.NewUnstartedServer(relay)
+	ts.TLS = &tls.Config{NextProtos: []string{http2.NextProtoTLS, "http/1.1"}}
+	ts.StartTLS()
+	return relay, ts
+}
+
+func TestRelay_ServeBroadcasters(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	_, err := NewRelay("")
+	assert.EqualError(err, "must provide a secret")
+
+	relay, ts := stubRelay(t, "secret")
+	defer ts.Close()
+	defer relay.Stop()
+	relayURL, _ := url.Parse(ts.URL)
+
+	orch := newStubOrchestrator()
+	orch.serviceURI = ts.URL
+	mux := http.NewServeMux()
+	mux.HandleFunc("/foo", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("bar " + r.Host))
+	})
+	s := grpc.NewServer()
+	lp := &lphttp{orchestrator: orch, orchRPC: s, transRPC: mux}
+	net.RegisterOrchestratorServer(s, lp)
+
+	client := &http.Client{Transport: &http2.Transport{TLSClientConfig: tlsConfig}}
+	get := func() (int, string) {
+		resp, err := client.Get(ts.URL + "/foo")
+		require.Nil(err)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		require.Nil(err)
+		return resp.StatusCode, string(body)
+	}
+
+	// Requests fail until the orchestrator opens its tunnel
+	status, body := get()
+	assert.Equal(http.StatusBadGateway, status)
+	assert.Contains(body, errRelayNoTunnel.Error())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ServeRelay(ctx, relayURL, "secret", relayURL.Host, lp)
+	assert.Eventually(func() bool { return relay.tunnel(relayURL.Host) != nil }, 5*time.Second, 10*time.Millisecond)
+
+	// HTTP and gRPC requests reach the orchestrator
+	status, body = get()
+	assert.Equal(http.StatusOK, status)
+	assert.Equal("bar "+relayURL.Host, body)
+	assert.True(CheckOrchestratorAvailability(orch))
+
+	// Hosts without a tunnel are not routed to the orchestrator
+	req, err := http.NewRequest("GET", ts.URL+"/foo", nil)
+	require.Nil(err)
+	req.Host = "other:8935"
+	resp, err := client.Do(req)
+	require.Nil(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusBadGateway, resp.StatusCode)
+
+	// Requests fail again once the orchestrator closes its tunnel
+	cancel()
+	assert.Eventually(func() bool { return relay.tunnel(relayURL.Host) == nil }, 5*time.Second, 10*time.Millisecond)
+	status, _ = get()
+	assert.Equal(http.StatusBadGateway, status)
+}
+
+func TestRelay_DialRelay(t *testing.T) {
+	assert := assert.New(t)
+
+	relay, ts := stubRelay(t, "secret")
+	defer ts.Close()
+	defer relay.Stop()
+	relayURL, _ := url.Parse(ts.URL)
+
+	_, err := dialRelay(relayURL, "wrong", "orch:8935", "id")
+	assert.EqualError(err, "relay refused tunnel status=401 err=invalid secret")
+	_, err = dialRelay(relayURL, "secret", "", "id")
+	assert.EqualError(err, "relay refused tunnel status=400 err=missing Livepeer-Relay-Host")
+	_, err = dialRelay(relayURL, "secret", "orch:8935", "")
+	assert.EqualError(err, "relay refused tunnel status=400 err=missing Livepeer-Relay-ID")
+	assert.Nil(relay.tunnel("orch:8935"))
+
+	conn, err := dialRelay(relayURL, "secret", "orch:8935", "id")
+	assert.Nil(err)
+	defer conn.Close()
+	assert.Eventually(func() bool { return relay.tunnel("orch:8935") != nil }, 5*time.Second, 10*time.Millisecond)
+
+	// Reconnecting orchestrators replace their previous tunnel
+	old := relay.tunnel("orch:8935")
+	conn2, err := dialRelay(relayURL, "secret", "orch:8935", "id")
+	assert.Nil(err)
+	defer conn2.Close()
+	assert.Eventually(func() bool {
+		cc := relay.tunnel("orch:8935")
+		return cc != nil && cc != old
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.False(old.CanTakeNewRequest())
+
+	// Other orchestrators can't take over the host while its tunnel is open
+	cur := relay.tunnel("orch:8935")
+	_, err = dialRelay(relayURL, "secret", "orch:8935", "other")
+	assert.EqualError(err, "relay refused tunnel status=409 err=host already registered")
+	assert.Equal(cur, relay.tunnel("orch:8935"))
+	assert.True(cur.CanTakeNewRequest())
+
+	// but can register it once the tunnel is closed
+	cur.Close()
+	conn3, err := dialRelay(relayURL, "secret", "orch:8935", "other")
+	assert.Nil(err)
+	defer conn3.Close()
+	assert.Eventually(func() bool {
+		cc := relay.tunnel("orch:8935")
+		return cc != nil && cc != cur
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRelay_KeepAlive(t *testing.T) {
+	assert := assert.New(t)
+
+	oldInterval, oldTimeout := relayPingInterval, relayPingTimeout
+	defer func() { relayPingInterval, relayPingTimeout = oldInterval, oldTimeout }()
+	relayPingInterval, relayPingTimeout = 50*time.Millisecond, time.Second
+
+	relay, ts := stubRelay(t, "secret")
+	defer ts.Close()
+	defer relay.Stop()
+	relayURL, _ := url.Parse(ts.URL)
+
+	// Tunnels stay open while the orchestrator answers pings
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ServeRelay(ctx, relayURL, "secret", "orch:8935", http.NotFoundHandler())
+	assert.Eventually(func() bool { return relay.tunnel("orch:8935") != nil }, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(5 * relayPingInterval)
+	assert.NotNil(relay.tunnel("orch:8935"))
+
+	// and are closed when it doesn't, e.g. when it never serves the tunnel
+	conn, err := dialRelay(relayURL, "secret", "silent:8935", "id")
+	assert.Nil(err)
+	defer conn.Close()
+	assert.Eventually(func() bool { return relay.tunnel("silent:8935") != nil }, 5*time.Second, 10*time.Millisecond)
+	assert.Eventually(func() bool { return relay.tunnel("silent:8935") == nil }, 5*time.Second, 10*time.Millisecond)
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"crypto/hmac"
 	"fmt"
 	"math/big"
 	"net/http"
@@ -10,11 +11,17 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 
 	"github.com/livepeer/go-livepeer/common"
 	"github.com/livepeer/go-livepeer/core"
 	"github.com/livepeer/go-livepeer/drivers"
+	"github.com/livepeer/go-livepeer/monitor"
 	"github.com/livepeer/go-livepeer/net"
 	"github.com/livepeer/go-livepeer/pm"
 	ffmpeg "github.com/livepeer/lpms/ffmpeg"
@@ -30,8 +37,12 @@ import (
 const GRPCConnectTimeout = 3 * time.Second
 const GRPCTimeout = 8 * time.Second
 
+var errAuthTokenInvalid = errors.New("invalid auth token")
+var errAuthTokenExpired = errors.New("auth token expired")
+
 type Orchestrator interface {
 	ServiceURI() *url.URL
+	AltServiceURIs() []*url.URL
 	Address() ethcommon.Address
 	TranscoderSecret() string
 	Sign([]byte) ([]byte, error)
@@ -46,7 +57,10 @@ type Orchestrator interface {
 	PriceInfo(sender ethcommon.Address) (*net.PriceInfo, error)
 	SufficientBalance(addr ethcommon.Address, manifestID core.ManifestID) bool
 	DebitFees(addr ethcommon.Address, manifestID core.ManifestID, price *net.PriceInfo, pixels int64)
+	ShouldRotateTicketParams(payment net.Payment) bool
 	Capabilities() *net.Capabilities
+	CapabilityPrices() core.CapabilityPrices
+	AuthToken(sessionID string, expiration int64) *net.AuthToken
 }
 
 // Balance describes methods for a session's balance maintenance
@@ -99,12 +113,38 @@ type BroadcastSession struct {
 	PMSessionID      string
 	Balance          Balance
 	LatencyScore     float64
+	// Transcoder URI that segments are submitted to, if the session failed over to one
+	// of the alternative URIs of the orchestrator
+	Endpoint string
+}
+
+// transcoderURIs returns the URIs of the transcoder of the session, starting with the
+// URI that segments are submitted to
+func (s *BroadcastSession) transcoderURIs() []string {
+	info := s.OrchestratorInfo
+	uris := []string{info.GetTranscoder()}
+	seen := map[string]bool{info.GetTranscoder(): true}
+	for _, uri := range info.GetAltTranscoders() {
+		if uri != "" && !seen[uri] {
+			uris = append(uris, uri)
+			seen[uri] = true
+		}
+	}
+	for i, uri := range uris {
+		if uri == s.Endpoint && i > 0 {
+			// Keep the order of the other URIs
+			uris = append([]string{uri}, append(uris[:i:i], uris[i+1:]...)...)
+			break
+		}
+	}
+	return uris
 }
 
 // ReceivedTranscodeResult contains received transcode result data and related metadata
 type ReceivedTranscodeResult struct {
 	*net.TranscodeData
 	Info         *net.OrchestratorInfo
+	PriceUpdate  *net.PriceUpdate
 	LatencyScore float64
 }
 
@@ -112,12 +152,23 @@ type lphttp struct {
 	orchestrator Orchestrator
 	orchRPC      *grpc.Server
 	transRPC     *http.ServeMux
+	limits       *requestLimits
 }
 
 // grpc methods
 func (h *lphttp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ct := r.Header.Get("Content-Type")
-	if r.ProtoMajor == 2 && strings.HasPrefix(ct, "application/grpc") {
+	isGRPC := r.ProtoMajor == 2 && strings.HasPrefix(ct, "application/grpc")
+	if ip := clientIP(r); !h.limits.allowIP(ip) {
+		rejectRequest(rejectIPRate, ip)
+		if isGRPC {
+			grpcError(w, codes.ResourceExhausted, "too many requests")
+		} else {
+			httpErrorWithCode(w, "Too Many Requests", http.StatusTooManyRequests, common.ErrCodeIngestRateLimited)
+		}
+		return
+	}
+	if isGRPC {
 		h.orchRPC.ServeHTTP(w, r)
 	} else {
 		h.transRPC.ServeHTTP(w, r)
@@ -125,6 +176,12 @@ func (h *lphttp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *lphttp) GetOrchestrator(context context.Context, req *net.OrchestratorRequest) (*net.OrchestratorInfo, error) {
+	done, ok := h.limits.startDiscovery()
+	defer done()
+	if !ok {
+		rejectRequest(rejectDiscovery, ethcommon.BytesToAddress(req.GetAddress()).Hex())
+		return nil, status.Error(codes.ResourceExhausted, "too many discovery requests")
+	}
 	return getOrchestrator(h.orchestrator, req)
 }
 
@@ -134,11 +191,12 @@ func (h *lphttp) Ping(context context.Context, req *net.PingPong) (*net.PingPong
 
 // XXX do something about the implicit start of the http mux? this smells
 func StartTranscodeServer(orch Orchestrator, bind string, mux *http.ServeMux, workDir string, acceptRemoteTranscoders bool) {
-	s := grpc.NewServer()
+	s := grpc.NewServer(grpc.KeepaliveParams(orchestratorKeepalive), grpc.KeepaliveEnforcementPolicy(orchestratorKeepalivePolicy))
 	lp := lphttp{
 		orchestrator: orch,
 		orchRPC:      s,
 		transRPC:     mux,
+		limits:       newRequestLimits(),
 	}
 	net.RegisterOrchestratorServer(s, &lp)
 	lp.transRPC.HandleFunc("/segment", lp.ServeSegment)
@@ -146,16 +204,23 @@ func StartTranscodeServer(orch Orchestrator, bind string, mux *http.ServeMux, wo
 		net.RegisterTranscoderServer(s, &lp)
 		lp.transRPC.HandleFunc("/transcodeResults", lp.TranscodeResults)
 	}
+	registerStandardServices(s)
 
 	cert, key, err := getCert(orch.ServiceURI(), workDir)
 	if err != nil {
 		return // XXX return error
 	}
 
+	if RelayURI != nil {
+		go ServeRelay(context.Background(), RelayURI, RelaySecret, orch.ServiceURI().Host, &lp)
+	}
+
 	glog.Info("Listening for RPC on ", bind)
 	srv := http.Server{
-		Addr:    bind,
-		Handler: &lp,
+		Addr:              bind,
+		Handler:           monitor.ReportPanics(&lp),
+		ReadHeaderTimeout: readHeaderTimeout,
+		IdleTimeout:       idleTimeout,
 		// XXX doesn't handle streaming RPC well; split remote transcoder RPC?
 		//ReadTimeout:  HTTPTimeout,
 		//WriteTimeout: HTTPTimeout,
@@ -163,6 +228,25 @@ func StartTranscodeServer(orch Orchestrator, bind string, mux *http.ServeMux, wo
 	srv.ListenAndServeTLS(cert, key)
 }
 
+// registerStandardServices registers the standard gRPC health and reflection services on
+// s, for load balancers, service meshes and tools such as grpcurl. The services already
+// registered on s are reported as serving until the node starts draining
+func registerStandardServices(s *grpc.Server) *health.Server {
+	hs := health.NewServer()
+	for name := range s.GetServiceInfo() {
+		hs.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+	draining := nodeDrainer.draining
+	go func() {
+		<-draining
+		// Reports every service as not serving
+		hs.Shutdown()
+	}()
+	healthpb.RegisterHealthServer(s, hs)
+	reflection.Register(s)
+	return hs
+}
+
 // CheckOrchestratorAvailability - the broadcaster calls CheckOrchestratorAvailability which invokes Ping on the orchestrator
 func CheckOrchestratorAvailability(orch Orchestrator) bool {
 	ts := time.Now()
@@ -215,6 +299,10 @@ func GetOrchestratorInfo(ctx context.Context, bcast common.Broadcaster, orchestr
 		glog.Errorf("Could not get orchestrator orch=%v err=%v", orchestratorServer, err)
 		return nil, errors.New("Could not get orchestrator err=" + err.Error())
 	}
+	if _, err := core.NegotiateProtocol(r.Protocol); err != nil {
+		glog.Errorf("Could not use orchestrator orch=%v err=%v", orchestratorServer, err)
+		return nil, err
+	}
 
 	return r, nil
 }
@@ -239,17 +327,53 @@ func genOrchestratorReq(b common.Broadcaster) (*net.OrchestratorRequest, error)
 	if err != nil {
 		return nil, err
 	}
-	return &net.OrchestratorRequest{Address: b.Address().Bytes(), Sig: sig}, nil
+	return &net.OrchestratorRequest{Address: b.Address().Bytes(), Sig: sig, Protocol: core.NewProtocolInfo()}, nil
 }
 
 func getOrchestrator(orch Orchestrator, req *net.OrchestratorRequest) (*net.OrchestratorInfo, error) {
+	if Draining() {
+		return nil, errDraining
+	}
+
+	protocol, err := core.NegotiateProtocol(req.Protocol)
+	if err != nil {
+		return nil, err
+	}
+
 	addr := ethcommon.BytesToAddress(req.Address)
 	if err := verifyOrchestratorReq(orch, addr, req.Sig); err != nil {
 		return nil, fmt.Errorf("Invalid orchestrator request (%v)", err)
 	}
 
 	// currently, orchestrator == transcoder
-	return orchestratorInfo(orch, addr, orch.ServiceURI().String())
+	tr, err := orchestratorInfo(orch, addr, orch.ServiceURI().String())
+	if err != nil {
+		return nil, err
+	}
+	tr.Protocol = protocol
+	tr.AuthToken = newAuthToken(orch, "")
+	return tr, nil
+}
+
+// newAuthToken returns a fresh auth token for the broadcaster session sessionID, or for
+// a new session if sessionID is empty
+func newAuthToken(orch Orchestrator, sessionID string) *net.AuthToken {
+	if sessionID == "" {
+		sessionID = string(core.RandomManifestID())
+	}
+	return orch.AuthToken(sessionID, time.Now().Add(core.AuthTokenValidPeriod).Unix())
+}
+
+// verifyAuthToken checks that token was issued by the orchestrator and hasn't expired
+func verifyAuthToken(orch Orchestrator, token *net.AuthToken) error {
+	expected := orch.AuthToken(token.SessionId, token.Expiration)
+	if !hmac.Equal(expected.Token, token.Token) {
+		return errAuthTokenInvalid
+	}
+	if time.Now().Unix() >= token.Expiration {
+		return errAuthTokenExpired
+	}
+	return nil
 }
 
 func orchestratorInfo(orch Orchestrator, addr ethcommon.Address, serviceURI string) (*net.OrchestratorInfo, error) {
@@ -270,6 +394,10 @@ func orchestratorInfo(orch Orchestrator, addr ethcommon.Address, serviceURI stri
 		Address:      orch.Address().Bytes(),
 		Capabilities: orch.Capabilities(),
 	}
+	tr.CapabilityPrices = orch.CapabilityPrices().ToNet()
+	for _, uri := range orch.AltServiceURIs() {
+		tr.AltTranscoders = append(tr.AltTranscoders, uri.String())
+	}
 
 	os := drivers.NodeStorage.NewSession(string(core.RandomManifestID()))
 
@@ -288,11 +416,16 @@ func verifyOrchestratorReq(orch Orchestrator, addr ethcommon.Address, sig []byte
 	return orch.CheckCapacity("")
 }
 
-func pmTicketParams(params *net.TicketParams) *pm.TicketParams {
+// pmTicketParams converts the ticket params of an orchestrator, which are created for the price
+// per pixel it advertises
+func pmTicketParams(params *net.TicketParams, priceInfo *net.PriceInfo) *pm.TicketParams {
 	if params == nil {
 		return nil
 	}
 
+	// An invalid price is left unset, it is refused by the checks of the orchestrator price
+	price, _ := common.RatPriceInfo(priceInfo)
+
 	return &pm.TicketParams{
 		Recipient:         ethcommon.BytesToAddress(params.Recipient),
 		FaceValue:         new(big.Int).SetBytes(params.FaceValue),
@@ -300,10 +433,13 @@ func pmTicketParams(params *net.TicketParams) *pm.TicketParams {
 		RecipientRandHash: ethcommon.BytesToHash(params.RecipientRandHash),
 		Seed:              new(big.Int).SetBytes(params.Seed),
 		ExpirationBlock:   new(big.Int).SetBytes(params.ExpirationBlock),
+		PricePerPixel:     price,
 		ExpirationParams: &pm.TicketExpirationParams{
 			CreationRound:          params.ExpirationParams.GetCreationRound(),
 			CreationRoundBlockHash: ethcommon.BytesToHash(params.ExpirationParams.GetCreationRoundBlockHash()),
 		},
+		PayoutSplit: common.PmPayoutSplit(params.PayoutSplit),
+		Version:     params.Version,
 	}
 }
 
@@ -342,6 +478,24 @@ func coreSegMetadata(segData *net.SegData) (*core.SegTranscodingMetadata, error)
 		dur = 2 * time.Second // assume 2sec default duration
 	}
 
+	protocol, err := core.NegotiateProtocol(segData.Protocol)
+	if err != nil {
+		glog.Error("Protocol check failed: ", err)
+		return nil, err
+	}
+
+	var clip *core.ClipRange
+	if segData.ClipFrom != 0 || segData.ClipTo != 0 {
+		clip = &core.ClipRange{
+			From: time.Duration(segData.ClipFrom) * time.Millisecond,
+			To:   time.Duration(segData.ClipTo) * time.Millisecond,
+		}
+		if err := clip.Validate(dur); err != nil {
+			glog.Errorf("Invalid clip from=%v to=%v dur=%v", clip.From, clip.To, dur)
+			return nil, err
+		}
+	}
+
 	caps := core.CapabilitiesFromNetCapabilities(segData.Capabilities)
 	if caps == nil {
 		// For older broadcasters. Note if there are any orchestrator
@@ -357,5 +511,9 @@ func coreSegMetadata(segData *net.SegData) (*core.SegTranscodingMetadata, error)
 		OS:         os,
 		Duration:   dur,
 		Caps:       caps,
+		Sig:        segData.Sig,
+		Protocol:   protocol,
+		AuthToken:  segData.AuthToken,
+		Clip:       clip,
 	}, nil
 }
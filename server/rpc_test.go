@@ -14,6 +14,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"pgregory.net/rapid"
 
 	"github.com/ethereum/go-ethereum/accounts"
@@ -68,9 +70,14 @@ type stubOrchestrator struct {
 	ticketParams *net.TicketParams
 	priceInfo    *net.PriceInfo
 	serviceURI   string
+	altURIs      []string
 	res          *core.TranscodeResult
 	offchain     bool
 	caps         *core.Capabilities
+
+	capabilityPrices core.CapabilityPrices
+
+	rotateTicketParams bool
 }
 
 func (r *stubOrchestrator) ServiceURI() *url.URL {
@@ -81,6 +88,15 @@ func (r *stubOrchestrator) ServiceURI() *url.URL {
 	return url
 }
 
+func (r *stubOrchestrator) AltServiceURIs() []*url.URL {
+	var uris []*url.URL
+	for _, uri := range r.altURIs {
+		u, _ := url.Parse(uri)
+		uris = append(uris, u)
+	}
+	return uris
+}
+
 func (r *stubOrchestrator) CurrentBlock() *big.Int {
 	return r.block
 }
@@ -148,12 +164,22 @@ func (r *stubOrchestrator) SufficientBalance(addr ethcommon.Address, manifestID
 func (r *stubOrchestrator) DebitFees(addr ethcommon.Address, manifestID core.ManifestID, price *net.PriceInfo, pixels int64) {
 }
 
+func (r *stubOrchestrator) ShouldRotateTicketParams(payment net.Payment) bool {
+	return r.rotateTicketParams
+}
+
 func (r *stubOrchestrator) Capabilities() *net.Capabilities {
 	if r.caps != nil {
 		return r.caps.ToNetCapabilities()
 	}
 	return core.NewCapabilities(nil, nil).ToNetCapabilities()
 }
+func (r *stubOrchestrator) CapabilityPrices() core.CapabilityPrices {
+	return r.capabilityPrices
+}
+func (r *stubOrchestrator) AuthToken(sessionID string, expiration int64) *net.AuthToken {
+	return &net.AuthToken{Token: []byte(fmt.Sprintf("%v|%v", sessionID, expiration)), SessionId: sessionID, Expiration: expiration}
+}
 func (r *stubOrchestrator) LegacyOnly() bool {
 	return true
 }
@@ -329,13 +355,13 @@ func TestEstimateFee(t *testing.T) {
 	assert := assert.New(t)
 
 	// Test nil priceInfo
-	fee, err := estimateFee(&stream.HLSSegment{}, []ffmpeg.VideoProfile{}, nil)
+	fee, err := estimateFee(&stream.HLSSegment{}, []ffmpeg.VideoProfile{}, nil, nil)
 	assert.Nil(err)
 	assert.Nil(fee)
 
 	// Test first profile is invalid
 	profiles := []ffmpeg.VideoProfile{ffmpeg.VideoProfile{Resolution: "foo"}}
-	_, err = estimateFee(&stream.HLSSegment{}, profiles, big.NewRat(1, 1))
+	_, err = estimateFee(&stream.HLSSegment{}, profiles, big.NewRat(1, 1), nil)
 	assert.Error(err)
 
 	// Test non-first profile is invalid
@@ -343,11 +369,11 @@ func TestEstimateFee(t *testing.T) {
 		ffmpeg.P144p30fps16x9,
 		ffmpeg.VideoProfile{Resolution: "foo"},
 	}
-	_, err = estimateFee(&stream.HLSSegment{}, profiles, big.NewRat(1, 1))
+	_, err = estimateFee(&stream.HLSSegment{}, profiles, big.NewRat(1, 1), nil)
 	assert.Error(err)
 
 	// Test no profiles
-	fee, err = estimateFee(&stream.HLSSegment{Duration: 2.0}, []ffmpeg.VideoProfile{}, big.NewRat(1, 1))
+	fee, err = estimateFee(&stream.HLSSegment{Duration: 2.0}, []ffmpeg.VideoProfile{}, big.NewRat(1, 1), nil)
 	assert.Nil(err)
 	assert.Zero(fee.Cmp(big.NewRat(0, 1)))
 
@@ -358,7 +384,7 @@ func TestEstimateFee(t *testing.T) {
 	expFee := new(big.Rat).SetInt64(2211840)
 	expFee.Mul(expFee, new(big.Rat).SetFloat64(pixelEstimateMultiplier))
 	expFee.Mul(expFee, priceInfo)
-	fee, err = estimateFee(&stream.HLSSegment{Duration: 2.0}, profiles, priceInfo)
+	fee, err = estimateFee(&stream.HLSSegment{Duration: 2.0}, profiles, priceInfo, nil)
 	assert.Nil(err)
 	assert.Zero(fee.Cmp(expFee))
 
@@ -368,7 +394,7 @@ func TestEstimateFee(t *testing.T) {
 	expFee = new(big.Rat).SetInt64(8346240)
 	expFee.Mul(expFee, new(big.Rat).SetFloat64(pixelEstimateMultiplier))
 	expFee.Mul(expFee, priceInfo)
-	fee, err = estimateFee(&stream.HLSSegment{Duration: 2.0}, profiles, priceInfo)
+	fee, err = estimateFee(&stream.HLSSegment{Duration: 2.0}, profiles, priceInfo, nil)
 	assert.Nil(err)
 	assert.Zero(fee.Cmp(expFee))
 
@@ -378,7 +404,7 @@ func TestEstimateFee(t *testing.T) {
 	expFee.Mul(expFee, new(big.Rat).SetFloat64(pixelEstimateMultiplier))
 	expFee.Mul(expFee, priceInfo)
 	// Calculations should take ceiling of duration i.e. 2.2 -> 3
-	fee, err = estimateFee(&stream.HLSSegment{Duration: 2.2}, profiles, priceInfo)
+	fee, err = estimateFee(&stream.HLSSegment{Duration: 2.2}, profiles, priceInfo, nil)
 	assert.Nil(err)
 	assert.Zero(fee.Cmp(expFee))
 
@@ -388,7 +414,7 @@ func TestEstimateFee(t *testing.T) {
 	expFee = new(big.Rat).SetInt64(22472640)
 	expFee.Mul(expFee, new(big.Rat).SetFloat64(pixelEstimateMultiplier))
 	expFee.Mul(expFee, priceInfo)
-	fee, err = estimateFee(&stream.HLSSegment{Duration: 3.0}, profiles, priceInfo)
+	fee, err = estimateFee(&stream.HLSSegment{Duration: 3.0}, profiles, priceInfo, nil)
 	assert.Nil(err)
 	assert.Zero(fee.Cmp(expFee))
 	assert.Equal(uint(0), profiles[0].Framerate, "Profile framerate was reset")
@@ -401,7 +427,28 @@ func TestEstimateFee(t *testing.T) {
 	expFee = new(big.Rat).SetInt64(12519360)
 	expFee.Mul(expFee, new(big.Rat).SetFloat64(pixelEstimateMultiplier))
 	expFee.Mul(expFee, priceInfo)
-	fee, err = estimateFee(&stream.HLSSegment{Duration: 3.0}, profiles, priceInfo)
+	fee, err = estimateFee(&stream.HLSSegment{Duration: 3.0}, profiles, priceInfo, nil)
+	assert.Nil(err)
+	assert.Zero(fee.Cmp(expFee))
+
+	// Test estimation with capability prices
+	// pixels = (256 * 144 * 30 * 3 * 400%) + (426 * 240 * 30 * 3)
+	profiles[0].Profile = common.ProfileAV1Main
+	capabilityPrices := core.CapabilityPrices{core.Capability_AV1Encode: 400, core.Capability_HEVC: 150}
+	expFee = new(big.Rat).SetInt64(22472640)
+	expFee.Mul(expFee, new(big.Rat).SetFloat64(pixelEstimateMultiplier))
+	expFee.Mul(expFee, priceInfo)
+	fee, err = estimateFee(&stream.HLSSegment{Duration: 3.0}, profiles, priceInfo, capabilityPrices)
+	assert.Nil(err)
+	assert.Zero(fee.Cmp(expFee))
+
+	// Test audio-only profiles aren't charged for
+	// pixels = (426 * 240 * 30 * 3)
+	profiles[0] = ffmpeg.VideoProfile{Resolution: "0x0", Bitrate: "128k", Profile: common.ProfileAudioOnly}
+	expFee = new(big.Rat).SetInt64(9201600)
+	expFee.Mul(expFee, new(big.Rat).SetFloat64(pixelEstimateMultiplier))
+	expFee.Mul(expFee, priceInfo)
+	fee, err = estimateFee(&stream.HLSSegment{Duration: 3.0}, profiles, priceInfo, capabilityPrices)
 	assert.Nil(err)
 	assert.Zero(fee.Cmp(expFee))
 }
@@ -485,6 +532,83 @@ func TestNewBalanceUpdate(t *testing.T) {
 	assert.Zero(big.NewRat(0, 1).Cmp(update.Debit))
 	assert.Equal(Staged, int(update.Status))
 	balance.AssertCalled(t, "StageUpdate", ev, ev)
+
+	// Test BalanceUpdate creation for free tickets
+	s.PMSessionID = "free"
+	sender.On("EV", s.PMSessionID).Return(big.NewRat(0, 1), nil)
+	BroadcastCfg.SetFreeTickets(true)
+	defer BroadcastCfg.SetFreeTickets(false)
+
+	update, err = newBalanceUpdate(s, big.NewRat(0, 1))
+	assert.Nil(err)
+	assert.Equal(1, update.NumTickets)
+	assert.Zero(big.NewRat(0, 1).Cmp(update.NewCredit))
+	assert.Zero(big.NewRat(0, 1).Cmp(update.ExistingCredit))
+	balance.AssertNumberOfCalls(t, "StageUpdate", 2)
+}
+
+func TestNewBalanceUpdate_PaymentSegments(t *testing.T) {
+	assert := assert.New(t)
+
+	sender := &pm.MockSender{}
+	balance := &mockBalance{}
+	s := &BroadcastSession{
+		Params:      &core.StreamParameters{ManifestID: core.RandomManifestID()},
+		PMSessionID: "foo",
+		Sender:      sender,
+		Balance:     balance,
+	}
+	ev := big.NewRat(5, 1)
+	sender.On("EV", s.PMSessionID).Return(ev, nil)
+	sender.On("MaxEV").Return(nil).Twice()
+
+	BroadcastCfg.SetPaymentSegments(3)
+	defer BroadcastCfg.SetPaymentSegments(0)
+
+	// Test the payment carries the tickets for the credit of the 2 segments that follow
+	minCredit := big.NewRat(4, 1)
+	balance.On("StageUpdate", ev, ev).Return(1, ev, big.NewRat(1, 1)).Once()
+
+	update, err := newBalanceUpdate(s, minCredit)
+	assert.Nil(err)
+	assert.Equal(3, update.NumTickets)
+	assert.Zero(big.NewRat(15, 1).Cmp(update.NewCredit))
+	assert.Zero(big.NewRat(1, 1).Cmp(update.ExistingCredit))
+
+	// Test the number of extra tickets is rounded up
+	minCredit = big.NewRat(7, 1)
+	balance.On("StageUpdate", minCredit, ev).Return(1, ev, big.NewRat(3, 1)).Once()
+
+	update, err = newBalanceUpdate(s, minCredit)
+	assert.Nil(err)
+	assert.Equal(4, update.NumTickets)
+	assert.Zero(big.NewRat(20, 1).Cmp(update.NewCredit))
+
+	// Test no tickets are added while the existing credit covers the segment
+	balance.On("StageUpdate", minCredit, ev).Return(0, big.NewRat(0, 1), big.NewRat(14, 1)).Once()
+
+	update, err = newBalanceUpdate(s, minCredit)
+	assert.Nil(err)
+	assert.Equal(0, update.NumTickets)
+	assert.Zero(big.NewRat(0, 1).Cmp(update.NewCredit))
+	assert.Zero(big.NewRat(14, 1).Cmp(update.ExistingCredit))
+
+	// Test the extra tickets are capped by the headroom under the max EV of a ticket batch
+	sender.On("MaxEV").Return(big.NewRat(17, 1))
+	balance.On("StageUpdate", minCredit, ev).Return(1, ev, big.NewRat(3, 1)).Once()
+
+	update, err = newBalanceUpdate(s, minCredit)
+	assert.Nil(err)
+	assert.Equal(3, update.NumTickets)
+	assert.Zero(big.NewRat(15, 1).Cmp(update.NewCredit))
+
+	// Test no extra tickets are added without headroom
+	balance.On("StageUpdate", minCredit, ev).Return(4, big.NewRat(20, 1), big.NewRat(3, 1)).Once()
+
+	update, err = newBalanceUpdate(s, minCredit)
+	assert.Nil(err)
+	assert.Equal(4, update.NumTickets)
+	assert.Zero(big.NewRat(20, 1).Cmp(update.NewCredit))
 }
 
 func TestGenPayment(t *testing.T) {
@@ -628,6 +752,47 @@ func TestPing(t *testing.T) {
 	}
 }
 
+func TestPriceUpdate(t *testing.T) {
+	assert := assert.New(t)
+
+	price := &net.PriceInfo{PricePerUnit: 2, PixelsPerUnit: 3}
+	params := &net.TicketParams{FaceValue: []byte{1}, ExpirationParams: &net.TicketExpirationParams{CreationRound: 10}}
+	payment := net.Payment{
+		TicketParams:     &net.TicketParams{FaceValue: []byte{1}},
+		ExpirationParams: &net.TicketExpirationParams{CreationRound: 10},
+		ExpectedPrice:    &net.PriceInfo{PricePerUnit: 4, PixelsPerUnit: 6},
+	}
+	oInfo := &net.OrchestratorInfo{PriceInfo: price, TicketParams: params}
+
+	// Nothing changed
+	assert.Nil(priceUpdate(payment, oInfo, false))
+	// Off-chain
+	assert.Nil(priceUpdate(net.Payment{}, &net.OrchestratorInfo{}, true))
+
+	payment.ExpectedPrice = &net.PriceInfo{PricePerUnit: 1, PixelsPerUnit: 3}
+	update := priceUpdate(payment, oInfo, false)
+	assert.Equal(net.PriceUpdate_PRICE, update.Reason)
+	assert.Equal(price, update.PriceInfo)
+	assert.Equal(params, update.TicketParams)
+
+	payment.ExpectedPrice = price
+	payment.TicketParams.FaceValue = []byte{2}
+	assert.Equal(net.PriceUpdate_FACE_VALUE, priceUpdate(payment, oInfo, false).Reason)
+
+	payment.TicketParams.FaceValue = []byte{1}
+	payment.ExpirationParams.CreationRound = 9
+	assert.Equal(net.PriceUpdate_ROUND, priceUpdate(payment, oInfo, false).Reason)
+
+	// The recipientRand is rotated although nothing else changed
+	payment.ExpirationParams.CreationRound = 10
+	update = priceUpdate(payment, oInfo, true)
+	assert.Equal(net.PriceUpdate_RECIPIENT_RAND, update.Reason)
+	assert.Equal(params, update.TicketParams)
+	// Other reasons take precedence
+	payment.TicketParams.FaceValue = []byte{2}
+	assert.Equal(net.PriceUpdate_FACE_VALUE, priceUpdate(payment, oInfo, true).Reason)
+}
+
 func TestValidatePrice(t *testing.T) {
 	assert := assert.New(t)
 	mid := core.RandomManifestID()
@@ -667,6 +832,29 @@ func TestValidatePrice(t *testing.T) {
 	err = validatePrice(s)
 	assert.EqualError(err, fmt.Sprintf("Orchestrator price higher than the set maximum price of %v wei per %v pixels", int64(1), int64(5)))
 
+	// The price of the renditions that need a priced capability is checked against MaxPrice
+	av1 := ffmpeg.P144p30fps16x9
+	av1.Profile = common.ProfileAV1Main
+	s.Params.Profiles = []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9, av1}
+	s.OrchestratorInfo.CapabilityPrices = []*net.CapabilityPrice{{Capability: uint32(core.Capability_AV1Encode), Percent: 400}}
+	BroadcastCfg.SetMaxPrice(big.NewRat(4, 3))
+	assert.Nil(validatePrice(s))
+	BroadcastCfg.SetMaxPrice(big.NewRat(1, 1))
+	err = validatePrice(s)
+	assert.EqualError(err, "Orchestrator price of 400% of its price per pixel for capabilities higher than the set maximum price of 1 wei per 1 pixels")
+	// Capability prices above the max are refused
+	BroadcastCfg.SetMaxPrice(nil)
+	s.OrchestratorInfo.CapabilityPrices[0].Percent = core.MaxCapabilityPercent + 1
+	err = validatePrice(s)
+	assert.EqualError(err, "Orchestrator capability price of 1001% higher than the maximum of 1000%")
+	// Surcharges on the capabilities that can't be priced are ignored
+	s.Params.Profiles[1].Profile = ffmpeg.ProfileH264Main
+	s.OrchestratorInfo.CapabilityPrices = []*net.CapabilityPrice{{Capability: uint32(core.Capability_ProfileH264Main), Percent: 100000}}
+	BroadcastCfg.SetMaxPrice(big.NewRat(1, 3))
+	assert.Nil(validatePrice(s))
+	s.Params.Profiles = nil
+	s.OrchestratorInfo.CapabilityPrices = nil
+
 	// O.PriceInfo is nil
 	s.OrchestratorInfo.PriceInfo = nil
 	err = validatePrice(s)
@@ -678,6 +866,21 @@ func TestValidatePrice(t *testing.T) {
 	assert.EqualError(err, "pixels per unit is 0")
 }
 
+func TestPmTicketParams_PricePerPixel(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(pmTicketParams(nil, &net.PriceInfo{PricePerUnit: 1, PixelsPerUnit: 3}))
+
+	params := &net.TicketParams{FaceValue: []byte{5}, WinProb: []byte{6}}
+	tp := pmTicketParams(params, &net.PriceInfo{PricePerUnit: 1, PixelsPerUnit: 3})
+	assert.Equal(big.NewRat(1, 3), tp.PricePerPixel)
+	assert.Equal(big.NewInt(5), tp.FaceValue)
+
+	// Missing and invalid prices are left unset
+	assert.Nil(pmTicketParams(params, nil).PricePerPixel)
+	assert.Nil(pmTicketParams(params, &net.PriceInfo{PricePerUnit: 1, PixelsPerUnit: 0}).PricePerPixel)
+}
+
 func TestGetPayment_GivenInvalidBase64_ReturnsError(t *testing.T) {
 	header := "not base64"
 
@@ -793,6 +996,109 @@ func TestGetOrchestrator_GivenInvalidSig_ReturnsError(t *testing.T) {
 	assert.Nil(oInfo)
 }
 
+func TestGetOrchestrator_Protocol(t *testing.T) {
+	orch := &mockOrchestrator{}
+	drivers.NodeStorage = drivers.NewMemoryDriver(nil)
+	orch.On("VerifySig", mock.Anything, mock.Anything, mock.Anything).Return(true)
+	orch.On("ServiceURI").Return(url.Parse("http://someuri.com"))
+	orch.On("Address").Return(ethcommon.Address{})
+	orch.On("TicketParams", mock.Anything, mock.Anything).Return(nil, nil)
+	orch.On("PriceInfo", mock.Anything).Return(nil, nil)
+
+	assert := assert.New(t)
+	oInfo, err := getOrchestrator(orch, &net.OrchestratorRequest{Protocol: core.NewProtocolInfo()})
+	assert.Nil(err)
+	assert.Equal(uint32(core.ProtocolVersion), oInfo.Protocol.Version)
+	assert.True(core.HasProtocolFeature(oInfo.Protocol, core.ProtocolFeatureReceipts))
+
+	// Broadcasters that predate versioning don't get optional features
+	oInfo, err = getOrchestrator(orch, &net.OrchestratorRequest{})
+	assert.Nil(err)
+	assert.False(core.HasProtocolFeature(oInfo.Protocol, core.ProtocolFeatureReceipts))
+
+	oInfo, err = getOrchestrator(orch, &net.OrchestratorRequest{Protocol: &net.ProtocolInfo{Version: core.ProtocolVersion + 1, MinVersion: core.ProtocolVersion + 1}})
+	assert.IsType(&core.IncompatibleProtocolError{}, err)
+	assert.Nil(oInfo)
+}
+
+func TestOrchestratorInfo_AltTranscoders(t *testing.T) {
+	assert := assert.New(t)
+	drivers.NodeStorage = drivers.NewMemoryDriver(nil)
+	orch := &stubOrchestrator{offchain: true, altURIs: []string{"https://alt1:8935", "https://alt2:8935"}}
+
+	oInfo, err := orchestratorInfo(orch, ethcommon.Address{}, "https://orch:8935")
+	assert.Nil(err)
+	assert.Equal("https://orch:8935", oInfo.Transcoder)
+	assert.Equal([]string{"https://alt1:8935", "https://alt2:8935"}, oInfo.AltTranscoders)
+
+	orch.altURIs = nil
+	oInfo, err = orchestratorInfo(orch, ethcommon.Address{}, "https://orch:8935")
+	assert.Nil(err)
+	assert.Empty(oInfo.AltTranscoders)
+}
+
+func TestOrchestratorInfo_CapabilityPrices(t *testing.T) {
+	assert := assert.New(t)
+	drivers.NodeStorage = drivers.NewMemoryDriver(nil)
+	orch := &stubOrchestrator{offchain: true}
+
+	oInfo, err := orchestratorInfo(orch, ethcommon.Address{}, "https://orch:8935")
+	assert.Nil(err)
+	assert.Empty(oInfo.CapabilityPrices)
+
+	orch.capabilityPrices = core.CapabilityPrices{core.Capability_AV1Encode: 400}
+	oInfo, err = orchestratorInfo(orch, ethcommon.Address{}, "https://orch:8935")
+	assert.Nil(err)
+	assert.Equal(orch.capabilityPrices, core.CapabilityPricesFromNet(oInfo.CapabilityPrices))
+}
+
+func TestBroadcastSession_TranscoderURIs(t *testing.T) {
+	assert := assert.New(t)
+	sess := &BroadcastSession{OrchestratorInfo: &net.OrchestratorInfo{
+		Transcoder:     "https://orch",
+		AltTranscoders: []string{"https://alt1", "", "https://orch", "https://alt2", "https://alt1"},
+	}}
+	assert.Equal([]string{"https://orch", "https://alt1", "https://alt2"}, sess.transcoderURIs())
+
+	// The URI that segments were last submitted to comes first
+	sess.Endpoint = "https://alt2"
+	assert.Equal([]string{"https://alt2", "https://orch", "https://alt1"}, sess.transcoderURIs())
+	sess.Endpoint = "https://orch"
+	assert.Equal([]string{"https://orch", "https://alt1", "https://alt2"}, sess.transcoderURIs())
+
+	// and is ignored once the orchestrator stops advertising it
+	sess.Endpoint = "https://gone"
+	assert.Equal([]string{"https://orch", "https://alt1", "https://alt2"}, sess.transcoderURIs())
+	sess.OrchestratorInfo.AltTranscoders = nil
+	assert.Equal([]string{"https://orch"}, sess.transcoderURIs())
+}
+
+func TestAuthToken(t *testing.T) {
+	assert := assert.New(t)
+	orch := &stubOrchestrator{}
+
+	token := newAuthToken(orch, "")
+	assert.NotEmpty(token.SessionId)
+	assert.InDelta(time.Now().Add(core.AuthTokenValidPeriod).Unix(), token.Expiration, 1)
+	assert.Nil(verifyAuthToken(orch, token))
+
+	// Refreshed tokens keep the session
+	assert.Equal(token.SessionId, newAuthToken(orch, token.SessionId).SessionId)
+	assert.NotEqual(token.SessionId, newAuthToken(orch, "").SessionId)
+
+	// Tampered tokens are rejected
+	tampered := *token
+	tampered.Expiration++
+	assert.Equal(errAuthTokenInvalid, verifyAuthToken(orch, &tampered))
+	tampered = *token
+	tampered.SessionId = "other"
+	assert.Equal(errAuthTokenInvalid, verifyAuthToken(orch, &tampered))
+
+	// and so are expired tokens
+	expired := orch.AuthToken("sess", time.Now().Add(-time.Second).Unix())
+	assert.Equal(errAuthTokenExpired, verifyAuthToken(orch, expired))
+}
+
 func TestGetOrchestrator_GivenValidSig_ReturnsOrchTicketParams(t *testing.T) {
 	orch := &mockOrchestrator{}
 	drivers.NodeStorage = drivers.NewMemoryDriver(nil)
@@ -1046,12 +1352,25 @@ func (o *mockOrchestrator) DebitFees(addr ethcommon.Address, manifestID core.Man
 	o.Called(addr, manifestID, price, pixels)
 }
 
+func (o *mockOrchestrator) ShouldRotateTicketParams(payment net.Payment) bool {
+	return false
+}
+
 func (o *mockOrchestrator) Capabilities() *net.Capabilities {
 	return core.NewCapabilities(nil, nil).ToNetCapabilities()
 }
+func (o *mockOrchestrator) CapabilityPrices() core.CapabilityPrices {
+	return nil
+}
+func (o *mockOrchestrator) AuthToken(sessionID string, expiration int64) *net.AuthToken {
+	return &net.AuthToken{Token: []byte(fmt.Sprintf("%v|%v", sessionID, expiration)), SessionId: sessionID, Expiration: expiration}
+}
 func (o *mockOrchestrator) LegacyOnly() bool {
 	return true
 }
+func (o *mockOrchestrator) AltServiceURIs() []*url.URL {
+	return nil
+}
 
 func defaultTicketParams() *net.TicketParams {
 	return &net.TicketParams{
@@ -1088,3 +1407,37 @@ func defaultTicketSenderParams(t *testing.T) *net.TicketSenderParams {
 		Sig:         pm.RandBytes(123),
 	}
 }
+
+func TestRegisterStandardServices(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	defer func() { nodeDrainer = newDrainer() }()
+
+	s := grpc.NewServer()
+	net.RegisterOrchestratorServer(s, &lphttp{})
+	hs := registerStandardServices(s)
+	assert.Contains(s.GetServiceInfo(), "grpc.health.v1.Health")
+	assert.Contains(s.GetServiceInfo(), "grpc.reflection.v1alpha.ServerReflection")
+
+	check := func(service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+		res, err := hs.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		return res.GetStatus(), err
+	}
+	for _, service := range []string{"", "net.Orchestrator"} {
+		status, err := check(service)
+		require.Nil(err)
+		assert.Equal(healthpb.HealthCheckResponse_SERVING, status)
+	}
+	_, err := check("net.Transcoder")
+	assert.NotNil(err)
+
+	// Draining nodes are reported as not serving
+	Drain()
+	assert.Eventually(func() bool {
+		status, err := check("net.Orchestrator")
+		return err == nil && status == healthpb.HealthCheckResponse_NOT_SERVING
+	}, time.Second, 10*time.Millisecond)
+	status, err := check("")
+	require.Nil(err)
+	assert.Equal(healthpb.HealthCheckResponse_NOT_SERVING, status)
+}
@@ -6,10 +6,17 @@ import (
 	"crypto/tls"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
 	"math/big"
+	"mime"
+	"mime/multipart"
+	gonet "net"
 	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,8 +24,10 @@ import (
 	"github.com/livepeer/go-livepeer/core"
 	"github.com/livepeer/go-livepeer/drivers"
 	"github.com/livepeer/go-livepeer/eth"
+	"github.com/livepeer/go-livepeer/features"
 	"github.com/livepeer/go-livepeer/monitor"
 	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/go-livepeer/verification"
 	"github.com/livepeer/lpms/ffmpeg"
 	"github.com/livepeer/lpms/stream"
 	"golang.org/x/net/http2"
@@ -33,9 +42,19 @@ import (
 
 const paymentHeader = "Livepeer-Payment"
 const segmentHeader = "Livepeer-Segment"
+const errorCodeHeader = "Livepeer-Error-Code"
+const renditionHeader = "Livepeer-Rendition"
+
+// Content types of the parts of multipart segment responses
+const renditionContentType = "application/vnd+livepeer.rendition"
+const transcodeResultContentType = "application/vnd+livepeer.transcoderesult"
 
 const pixelEstimateMultiplier = 1.02
 
+// renditionHashes makes orchestrators send the perceptual hash of every rendition, which
+// is computed with the ffmpeg binary
+var renditionHashes = features.Register("phash", "Perceptual hashes of renditions in transcode results, computed with the ffmpeg binary")
+
 var errSegEncoding = errors.New("ErrorSegEncoding")
 var errSegSig = errors.New("ErrSegSig")
 var errFormat = errors.New("unrecognized profile output format")
@@ -52,10 +71,17 @@ var httpClient = &http.Client{
 func (h *lphttp) ServeSegment(w http.ResponseWriter, r *http.Request) {
 	orch := h.orchestrator
 
+	done, ok := startSegment()
+	defer done()
+	if !ok {
+		httpErrorWithCode(w, errDraining.Error(), http.StatusServiceUnavailable, common.ErrCodeIngestShuttingDown)
+		return
+	}
+
 	payment, err := getPayment(r.Header.Get(paymentHeader))
 	if err != nil {
 		glog.Error("Could not parse payment")
-		http.Error(w, err.Error(), http.StatusPaymentRequired)
+		httpErrorWithCode(w, err.Error(), http.StatusPaymentRequired, common.ErrCodePaymentParse)
 		return
 	}
 
@@ -67,13 +93,25 @@ func (h *lphttp) ServeSegment(w http.ResponseWriter, r *http.Request) {
 	segData, err := verifySegCreds(orch, seg, sender)
 	if err != nil {
 		glog.Error("Could not verify segment creds")
-		http.Error(w, err.Error(), http.StatusForbidden)
+		code := common.ErrCodeIngestSegCreds
+		if _, ok := err.(*core.IncompatibleProtocolError); ok {
+			code = common.ErrCodeIngestProtocol
+		} else if err == errAuthTokenInvalid || err == errAuthTokenExpired {
+			code = common.ErrCodeIngestAuthToken
+		}
+		httpErrorWithCode(w, err.Error(), http.StatusForbidden, code)
+		return
+	}
+
+	if !h.limits.allowSender(sender.Hex()) {
+		rejectRequest(rejectSenderRate, sender.Hex())
+		httpErrorWithCode(w, "Too Many Requests", http.StatusTooManyRequests, common.ErrCodeIngestRateLimited)
 		return
 	}
 
 	if err := orch.ProcessPayment(payment, segData.ManifestID); err != nil {
 		glog.Errorf("error processing payment: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpErrorWithCode(w, err.Error(), http.StatusBadRequest, common.ErrCodePaymentProcess)
 		return
 	}
 
@@ -82,22 +120,33 @@ func (h *lphttp) ServeSegment(w http.ResponseWriter, r *http.Request) {
 	// the case where the price is actually set to 0 because ProcessPayment() should guarantee a price attached
 	if payment.GetExpectedPrice().GetPricePerUnit() > 0 && !orch.SufficientBalance(sender, segData.ManifestID) {
 		glog.Errorf("Insufficient credit balance for stream - manifestID=%v\n", segData.ManifestID)
-		http.Error(w, "Insufficient balance", http.StatusBadRequest)
+		httpErrorWithCode(w, "Insufficient balance", http.StatusBadRequest, common.ErrCodePaymentInsufficientBalance)
 		return
 	}
 
 	oInfo, err := orchestratorInfo(orch, sender, orch.ServiceURI().String())
 	if err != nil {
 		glog.Errorf("Error updating orchestrator info - err=%v", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpErrorWithCode(w, "Internal Server Error", http.StatusInternalServerError, common.ErrCodePaymentOrchestratorInfo)
 		return
 	}
+	oInfo.Protocol = segData.Protocol
+	oInfo.AuthToken = newAuthToken(orch, segData.AuthToken.GetSessionId())
 
 	// download the segment and check the hash
-	data, err := ioutil.ReadAll(r.Body)
+	body := r.Body
+	if MaxSegmentSize > 0 {
+		body = ioutil.NopCloser(io.LimitReader(r.Body, MaxSegmentSize+1))
+	}
+	data, err := ioutil.ReadAll(body)
 	if err != nil {
 		glog.Errorf("Could not read request body - err=%v", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpErrorWithCode(w, "Internal Server Error", http.StatusInternalServerError, common.ErrCodeIngestReadBody)
+		return
+	}
+	if MaxSegmentSize > 0 && int64(len(data)) > MaxSegmentSize {
+		rejectRequest(rejectSegmentSize, sender.Hex())
+		httpErrorWithCode(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge, common.ErrCodeIngestTooLarge)
 		return
 	}
 
@@ -111,14 +160,14 @@ func (h *lphttp) ServeSegment(w http.ResponseWriter, r *http.Request) {
 		glog.V(common.DEBUG).Infof("Getting segment from %s took %s", uri, took)
 		if err != nil {
 			glog.Errorf("Error getting input segment from input OS - segment=%v err=%v", uri, err)
-			http.Error(w, "BadRequest", http.StatusBadRequest)
+			httpErrorWithCode(w, "BadRequest", http.StatusBadRequest, common.ErrCodeIngestDownload)
 			return
 		}
 		if took > common.HTTPTimeout {
 			// download from object storage took more time when broadcaster will be waiting for result
 			// so there is no point to start transcoding process
 			glog.Errorf(" Getting segment from %s took too long, aborting", uri)
-			http.Error(w, "BadRequest", http.StatusBadRequest)
+			httpErrorWithCode(w, "BadRequest", http.StatusBadRequest, common.ErrCodeIngestDownloadTimeout)
 			return
 		}
 	}
@@ -126,14 +175,26 @@ func (h *lphttp) ServeSegment(w http.ResponseWriter, r *http.Request) {
 	hash := crypto.Keccak256(data)
 	if !bytes.Equal(hash, segData.Hash.Bytes()) {
 		glog.Error("Mismatched hash for body; rejecting")
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		httpErrorWithCode(w, "Forbidden", http.StatusForbidden, common.ErrCodeIngestHashMismatch)
 		return
 	}
 
+	// Broadcasters accepting multipart responses get every rendition as soon as it's
+	// uploaded, followed by the transcode result. The renditions can only be streamed
+	// if the response can be flushed, otherwise the result is sent on its own
+	var mw *multipart.Writer
+	flusher, canFlush := w.(http.Flusher)
+	if r.Header.Get("Accept") == "multipart/mixed" && canFlush {
+		mw = multipart.NewWriter(w)
+		w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	}
+
 	// Send down 200OK early as an indication that the upload completed
 	// Any further errors come through the response body
 	w.WriteHeader(http.StatusOK)
-	w.(http.Flusher).Flush()
+	if canFlush {
+		flusher.Flush()
+	}
 
 	hlsStream := stream.HLSSegment{
 		SeqNo: uint64(segData.Seq),
@@ -146,6 +207,7 @@ func (h *lphttp) ServeSegment(w http.ResponseWriter, r *http.Request) {
 	// Upload to OS and construct segment result set
 	var segments []*net.TranscodedSegmentData
 	var pixels int64
+	capabilityPrices := orch.CapabilityPrices()
 	for i := 0; err == nil && i < len(res.TranscodeData.Segments); i++ {
 		var ext string
 		ext, err = common.ProfileFormatExtension(segData.Profiles[i].Format)
@@ -160,15 +222,29 @@ func (h *lphttp) ServeSegment(w http.ResponseWriter, r *http.Request) {
 			glog.Error("Could not upload segment ", segData.Seq)
 			break
 		}
-		pixels += res.TranscodeData.Segments[i].Pixels
+		pixels += capabilityPrices.PricedPixels(segData.Profiles[i], res.TranscodeData.Segments[i].Pixels)
 		d := &net.TranscodedSegmentData{
-			Url:    uri,
-			Pixels: res.TranscodeData.Segments[i].Pixels,
+			Url:      uri,
+			Pixels:   res.TranscodeData.Segments[i].Pixels,
+			Duration: renditionDuration(segData, i, res.TranscodeData),
+		}
+		if renditionHashes.Enabled() {
+			hash, err := verification.RenditionHash(res.TranscodeData.Segments[i].Data)
+			if err != nil {
+				glog.Errorf("Could not hash rendition manifestID=%s seqNo=%d profile=%s err=%v", segData.ManifestID, segData.Seq, segData.Profiles[i].Name, err)
+			}
+			d.PerceptualHash = hash
 		}
 		segments = append(segments, d)
+		if mw != nil {
+			if err := writeResponsePart(mw, renditionContentType, d, strconv.Itoa(i)); err != nil {
+				glog.Errorf("Unable to send rendition manifestID=%s seqNo=%d err=%v", segData.ManifestID, segData.Seq, err)
+			}
+			flusher.Flush()
+		}
 	}
 
-	// Debit the fee for the total pixel count
+	// Debit the fee for the total pixel count, priced by the capabilities of the renditions
 	orch.DebitFees(sender, segData.ManifestID, payment.GetExpectedPrice(), pixels)
 
 	// construct the response
@@ -181,14 +257,24 @@ func (h *lphttp) ServeSegment(w http.ResponseWriter, r *http.Request) {
 			Data: &net.TranscodeData{
 				Segments: segments,
 				Sig:      res.Sig,
+				Receipt:  res.Receipt,
 			}},
 		}
 	}
 
 	tr := &net.TranscodeResult{
-		Seq:    segData.Seq,
-		Result: result.Result,
-		Info:   oInfo,
+		Seq:         segData.Seq,
+		Result:      result.Result,
+		Info:        oInfo,
+		PriceUpdate: priceUpdate(payment, oInfo, orch.ShouldRotateTicketParams(payment)),
+	}
+	if mw != nil {
+		if err := writeResponsePart(mw, transcodeResultContentType, tr, ""); err != nil {
+			glog.Error("Unable to send transcode result ", err)
+			return
+		}
+		mw.Close()
+		return
 	}
 	buf, err := proto.Marshal(tr)
 	if err != nil {
@@ -198,6 +284,102 @@ func (h *lphttp) ServeSegment(w http.ResponseWriter, r *http.Request) {
 	w.Write(buf)
 }
 
+// writeResponsePart writes msg as a part of a multipart segment response. rendition is
+// the index of the rendition in renditions parts
+func writeResponsePart(mw *multipart.Writer, contentType string, msg proto.Message, rendition string) error {
+	buf, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	// The length lets the broadcaster read the part before the next one begins
+	hdrs := textproto.MIMEHeader{
+		"Content-Type":   {contentType},
+		"Content-Length": {strconv.Itoa(len(buf))},
+	}
+	if rendition != "" {
+		hdrs.Set(renditionHeader, rendition)
+	}
+	part, err := mw.CreatePart(hdrs)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(buf)
+	return err
+}
+
+// renditionDuration returns the duration in milliseconds of the rendition i of a segment,
+// from its number of frames and its frame rate, which is the frame rate of the source if
+// the profile has none. Returns 0 if the transcoder didn't count the frames
+func renditionDuration(md *core.SegTranscodingMetadata, i int, tData *core.TranscodeData) int32 {
+	frames := tData.Segments[i].Frames
+	if frames <= 0 {
+		return 0
+	}
+	profile := md.Profiles[i]
+	if profile.Framerate > 0 {
+		den := profile.FramerateDen
+		if den == 0 {
+			den = 1
+		}
+		return int32(int64(frames) * int64(den) * 1000 / int64(profile.Framerate))
+	}
+	if tData.Frames > 0 && md.Duration > 0 {
+		return int32(int64(md.Duration/time.Millisecond) * int64(frames) / int64(tData.Frames))
+	}
+	return 0
+}
+
+// priceUpdate returns the update to propose to the broadcaster if the price or the ticket
+// params in oInfo changed since the payment or if the recipientRand of the payment needs to
+// be rotated, or nil
+func priceUpdate(payment net.Payment, oInfo *net.OrchestratorInfo, rotate bool) *net.PriceUpdate {
+	if payment.TicketParams == nil || oInfo.TicketParams == nil {
+		return nil
+	}
+
+	update := &net.PriceUpdate{PriceInfo: oInfo.PriceInfo, TicketParams: oInfo.TicketParams}
+	paid, err := common.RatPriceInfo(payment.ExpectedPrice)
+	if err != nil {
+		return nil
+	}
+	price, err := common.RatPriceInfo(oInfo.PriceInfo)
+	if err != nil {
+		return nil
+	}
+	switch {
+	case (paid == nil) != (price == nil) || (price != nil && price.Cmp(paid) != 0):
+		update.Reason = net.PriceUpdate_PRICE
+	case !bytes.Equal(payment.TicketParams.FaceValue, oInfo.TicketParams.FaceValue):
+		update.Reason = net.PriceUpdate_FACE_VALUE
+	case payment.GetExpirationParams().GetCreationRound() != oInfo.TicketParams.GetExpirationParams().GetCreationRound():
+		update.Reason = net.PriceUpdate_ROUND
+	case rotate:
+		update.Reason = net.PriceUpdate_RECIPIENT_RAND
+	default:
+		return nil
+	}
+	return update
+}
+
+// httpErrorWithCode replies to the request with the specified error message and HTTP code
+// and attaches the pipeline error code so that the broadcaster can aggregate failures
+func httpErrorWithCode(w http.ResponseWriter, error string, status int, code common.ErrorCode) {
+	w.Header().Set(errorCodeHeader, strconv.Itoa(int(code)))
+	http.Error(w, error, status)
+}
+
+// transcodeErrorCode maps the error string of a TranscodeResult to an error code
+func transcodeErrorCode(err string) common.ErrorCode {
+	switch err {
+	case core.ErrOrchBusy.Error():
+		return common.ErrCodeTranscodeOrchestratorBusy
+	case core.ErrOrchCap.Error():
+		return common.ErrCodeTranscodeOrchestratorCapped
+	default:
+		return common.ErrCodeTranscodeFailed
+	}
+}
+
 func getPayment(header string) (net.Payment, error) {
 	buf, err := base64.StdEncoding.DecodeString(header)
 	if err != nil {
@@ -230,6 +412,8 @@ func makeFfmpegVideoProfiles(protoProfiles []*net.VideoProfile) ([]ffmpeg.VideoP
 		case net.VideoProfile_MPEGTS:
 		case net.VideoProfile_MP4:
 			format = ffmpeg.FormatMP4
+		case net.VideoProfile_WEBM:
+			format = common.FormatWebM
 		default:
 			return nil, errFormat
 		}
@@ -244,6 +428,16 @@ func makeFfmpegVideoProfiles(protoProfiles []*net.VideoProfile) ([]ffmpeg.VideoP
 			encoderProf = ffmpeg.ProfileH264High
 		case net.VideoProfile_H264_CONSTRAINED_HIGH:
 			encoderProf = ffmpeg.ProfileH264ConstrainedHigh
+		case net.VideoProfile_H265_MAIN:
+			encoderProf = common.ProfileH265Main
+		case net.VideoProfile_AV1_MAIN:
+			encoderProf = common.ProfileAV1Main
+		case net.VideoProfile_VP8:
+			encoderProf = common.ProfileVP8
+		case net.VideoProfile_VP9:
+			encoderProf = common.ProfileVP9
+		case net.VideoProfile_AUDIO_ONLY:
+			encoderProf = common.ProfileAudioOnly
 		default:
 			return nil, errProfile
 		}
@@ -263,6 +457,9 @@ func makeFfmpegVideoProfiles(protoProfiles []*net.VideoProfile) ([]ffmpeg.VideoP
 			Profile:      encoderProf,
 			GOP:          gop,
 		}
+		if err := common.ValidateProfileCodec(prof); err != nil {
+			return nil, err
+		}
 		profiles = append(profiles, prof)
 	}
 	return profiles, nil
@@ -290,6 +487,15 @@ func verifySegCreds(orch Orchestrator, segCreds string, broadcaster ethcommon.Ad
 		glog.Error("Sig check failed")
 		return nil, errSegSig
 	}
+	md.Sender = broadcaster
+
+	// Broadcasters that predate auth tokens don't send any
+	if md.AuthToken != nil {
+		if err := verifyAuthToken(orch, md.AuthToken); err != nil {
+			glog.Errorf("Auth token check failed sessionID=%v err=%v", md.AuthToken.SessionId, err)
+			return nil, err
+		}
+	}
 
 	if !md.Caps.CompatibleWith(orch.Capabilities()) {
 		glog.Error("Capability check failed")
@@ -305,7 +511,35 @@ func verifySegCreds(orch Orchestrator, segCreds string, broadcaster ethcommon.Ad
 }
 
 func SubmitSegment(sess *BroadcastSession, seg *stream.HLSSegment, nonce uint64) (*ReceivedTranscodeResult, error) {
-	uploaded := seg.Name != "" // hijack seg.Name to convey the uploaded URI
+	return submitSegment(sess, seg, nonce, nil)
+}
+
+// isUnreachable returns true if err is the error of a request to a transcoder that
+// could not be dialed, which is safe to send again to another URI of the transcoder
+func isUnreachable(err error) bool {
+	if uerr, ok := err.(*url.Error); ok {
+		err = uerr.Err
+	}
+	opErr, ok := err.(*gonet.OpError)
+	return ok && opErr.Op == "dial"
+}
+
+// sendsInline returns true if seg is sent in the body of the segment request to the
+// orchestrator of sess rather than through object storage
+func sendsInline(sess *BroadcastSession, seg *stream.HLSSegment) bool {
+	protocol, err := core.NegotiateProtocol(sess.OrchestratorInfo.GetProtocol())
+	return err == nil && core.SendInline(protocol, len(seg.Data))
+}
+
+// submitSegment submits the segment to the orchestrator of sess. If onRendition is set, the
+// orchestrator is asked to send every rendition as soon as it's ready and onRendition is
+// called with the index and the data of every rendition received before the result
+func submitSegment(sess *BroadcastSession, seg *stream.HLSSegment, nonce uint64,
+	onRendition func(int, *net.TranscodedSegmentData)) (*ReceivedTranscodeResult, error) {
+
+	// Segments small enough for both nodes are sent inline even if they were uploaded
+	// to the broadcaster OS, which saves the orchestrator a download
+	uploaded := seg.Name != "" && !sendsInline(sess, seg) // hijack seg.Name to convey the uploaded URI
 
 	segCreds, err := genSegCreds(sess, seg)
 	if err != nil {
@@ -325,7 +559,8 @@ func SubmitSegment(sess *BroadcastSession, seg *stream.HLSSegment, nonce uint64)
 	}
 
 	params := sess.Params
-	fee, err := estimateFee(seg, params.Profiles, priceInfo)
+	capabilityPrices := core.CapabilityPricesFromNet(sess.OrchestratorInfo.GetCapabilityPrices())
+	fee, err := estimateFee(seg, params.Profiles, priceInfo, capabilityPrices)
 	if err != nil {
 		return nil, err
 	}
@@ -362,32 +597,60 @@ func SubmitSegment(sess *BroadcastSession, seg *stream.HLSSegment, nonce uint64)
 	ctx, cancel := context.WithTimeout(context.Background(), dur)
 	defer cancel()
 
-	ti := sess.OrchestratorInfo
-	req, err := http.NewRequestWithContext(ctx, "POST", ti.Transcoder+"/segment", bytes.NewBuffer(data))
-	if err != nil {
-		glog.Errorf("Could not generate transcode request to orch=%s", ti.Transcoder)
-		if monitor.Enabled {
-			monitor.SegmentUploadFailed(nonce, seg.SeqNo, monitor.SegmentUploadErrorGenCreds, err.Error(), false)
+	var (
+		resp      *http.Response
+		start     time.Time
+		uploadDur time.Duration
+	)
+	// Fail over to the other URIs of the transcoder while it can't be reached
+	uris := sess.transcoderURIs()
+	orchURI := uris[0]
+	for _, uri := range uris {
+		var req *http.Request
+		req, err = http.NewRequestWithContext(ctx, "POST", uri+"/segment", bytes.NewBuffer(data))
+		if err != nil {
+			glog.Errorf("Could not generate transcode request to orch=%s", uri)
+			if monitor.Enabled {
+				monitor.SegmentUploadFailed(nonce, seg.SeqNo, monitor.SegmentUploadErrorGenCreds, err.Error(), false)
+			}
+			return nil, err
 		}
-		return nil, err
-	}
 
-	req.Header.Set(segmentHeader, segCreds)
-	req.Header.Set(paymentHeader, payment)
-	if uploaded {
-		req.Header.Set("Content-Type", "application/vnd+livepeer.uri")
-	} else {
-		// Technically incorrect for MP4 uploads but doesn't really matter
-		// TODO should we set this to some generic "Livepeer video" type?
-		req.Header.Set("Content-Type", "video/MP2T")
-	}
+		req.Header.Set(segmentHeader, segCreds)
+		req.Header.Set(paymentHeader, payment)
+		if uploaded {
+			req.Header.Set("Content-Type", "application/vnd+livepeer.uri")
+		} else {
+			// Technically incorrect for MP4 uploads but doesn't really matter
+			// TODO should we set this to some generic "Livepeer video" type?
+			req.Header.Set("Content-Type", "video/MP2T")
+		}
+		if onRendition != nil {
+			req.Header.Set("Accept", "multipart/mixed")
+		}
 
-	glog.Infof("Submitting segment nonce=%d manifestID=%s seqNo=%d bytes=%v orch=%s", nonce, params.ManifestID, seg.SeqNo, len(data), ti.Transcoder)
-	start := time.Now()
-	resp, err := httpClient.Do(req)
-	uploadDur := time.Since(start)
+		glog.Infof("Submitting segment nonce=%d manifestID=%s seqNo=%d bytes=%v orch=%s", nonce, params.ManifestID, seg.SeqNo, len(data), uri)
+		start = time.Now()
+		resp, err = httpClient.Do(req)
+		uploadDur = time.Since(start)
+		orchURI = uri
+		if err != nil && isUnreachable(err) && ctx.Err() == nil {
+			glog.Errorf("Unable to reach orch=%s nonce=%d manifestID=%s seqNo=%d err=%v", uri, nonce, params.ManifestID, seg.SeqNo, err)
+			continue
+		}
+		if err == nil && uri != uris[0] {
+			glog.Infof("Failed over to orch=%s from=%s manifestID=%s", uri, uris[0], params.ManifestID)
+		}
+		if err == nil {
+			sess.Endpoint = uri
+		}
+		break
+	}
+	if resp == nil && err == nil {
+		err = errors.New("no transcoder URI")
+	}
 	if err != nil {
-		glog.Errorf("Unable to submit segment orch=%v nonce=%d manifestID=%s seqNo=%d orch=%s err=%v", ti.Transcoder, nonce, params.ManifestID, seg.SeqNo, ti.Transcoder, err)
+		glog.Errorf("Unable to submit segment orch=%v nonce=%d manifestID=%s seqNo=%d err=%v", orchURI, nonce, params.ManifestID, seg.SeqNo, err)
 		if monitor.Enabled {
 			monitor.SegmentUploadFailed(nonce, seg.SeqNo, monitor.SegmentUploadErrorUnknown, err.Error(), false)
 		}
@@ -409,23 +672,24 @@ func SubmitSegment(sess *BroadcastSession, seg *stream.HLSSegment, nonce uint64)
 	if resp.StatusCode != 200 {
 		data, _ := ioutil.ReadAll(resp.Body)
 		errorString := strings.TrimSpace(string(data))
-		glog.Errorf("Error submitting segment nonce=%d manifestID=%s seqNo=%d code=%d orch=%s err=%v", nonce, params.ManifestID, seg.SeqNo, resp.StatusCode, ti.Transcoder, string(data))
+		glog.Errorf("Error submitting segment nonce=%d manifestID=%s seqNo=%d code=%d orch=%s err=%v", nonce, params.ManifestID, seg.SeqNo, resp.StatusCode, orchURI, string(data))
+		code := common.ParseErrorCode(resp.Header.Get(errorCodeHeader))
 		if monitor.Enabled {
-			monitor.SegmentUploadFailed(nonce, seg.SeqNo, monitor.SegmentUploadError(resp.Status),
+			monitor.SegmentUploadFailed(nonce, seg.SeqNo, monitor.SegmentUploadError(code.String()),
 				fmt.Sprintf("Code: %d Error: %s", resp.StatusCode, errorString), false)
 		}
-		return nil, fmt.Errorf(errorString)
+		return nil, common.NewCodedError(code, fmt.Errorf(errorString))
 	}
-	glog.Infof("Uploaded segment nonce=%d manifestID=%s seqNo=%d orch=%s dur=%s", nonce, params.ManifestID, seg.SeqNo, ti.Transcoder, uploadDur)
+	glog.Infof("Uploaded segment nonce=%d manifestID=%s seqNo=%d orch=%s dur=%s", nonce, params.ManifestID, seg.SeqNo, orchURI, uploadDur)
 	if monitor.Enabled {
 		monitor.SegmentUploaded(nonce, seg.SeqNo, uploadDur)
 	}
 
-	data, err = ioutil.ReadAll(resp.Body)
+	data, err = readTranscodeResult(resp, onRendition)
 	tookAllDur := time.Since(start)
 
 	if err != nil {
-		glog.Errorf("Unable to read response body for segment nonce=%d manifestID=%s seqNo=%d orch=%s err=%v", nonce, params.ManifestID, seg.SeqNo, ti.Transcoder, err)
+		glog.Errorf("Unable to read response body for segment nonce=%d manifestID=%s seqNo=%d orch=%s err=%v", nonce, params.ManifestID, seg.SeqNo, orchURI, err)
 		if monitor.Enabled {
 			monitor.SegmentTranscodeFailed(monitor.SegmentTranscodeErrorReadBody, nonce, seg.SeqNo, err, false)
 		}
@@ -436,7 +700,7 @@ func SubmitSegment(sess *BroadcastSession, seg *stream.HLSSegment, nonce uint64)
 	var tr net.TranscodeResult
 	err = proto.Unmarshal(data, &tr)
 	if err != nil {
-		glog.Errorf("Unable to parse response for segment nonce=%d manifestID=%s seqNo=%d orch=%s err=%v", nonce, params.ManifestID, seg.SeqNo, ti.Transcoder, err)
+		glog.Errorf("Unable to parse response for segment nonce=%d manifestID=%s seqNo=%d orch=%s err=%v", nonce, params.ManifestID, seg.SeqNo, orchURI, err)
 		if monitor.Enabled {
 			monitor.SegmentTranscodeFailed(monitor.SegmentTranscodeErrorParseResponse, nonce, seg.SeqNo, err, false)
 		}
@@ -448,26 +712,20 @@ func SubmitSegment(sess *BroadcastSession, seg *stream.HLSSegment, nonce uint64)
 	switch res := tr.Result.(type) {
 	case *net.TranscodeResult_Error:
 		err = fmt.Errorf(res.Error)
-		glog.Errorf("Transcode failed for segment nonce=%d manifestID=%s seqNo=%d orch=%s err=%v", nonce, params.ManifestID, seg.SeqNo, ti.Transcoder, err)
+		glog.Errorf("Transcode failed for segment nonce=%d manifestID=%s seqNo=%d orch=%s err=%v", nonce, params.ManifestID, seg.SeqNo, orchURI, err)
 		if err.Error() == "MediaStats Failure" {
 			glog.Info("Ensure the keyframe interval is 4 seconds or less")
 		}
+		code := transcodeErrorCode(res.Error)
 		if monitor.Enabled {
-			switch res.Error {
-			case "OrchestratorBusy":
-				monitor.SegmentTranscodeFailed(monitor.SegmentTranscodeErrorOrchestratorBusy, nonce, seg.SeqNo, err, false)
-			case "OrchestratorCapped":
-				monitor.SegmentTranscodeFailed(monitor.SegmentTranscodeErrorOrchestratorCapped, nonce, seg.SeqNo, err, false)
-			default:
-				monitor.SegmentTranscodeFailed(monitor.SegmentTranscodeErrorTranscode, nonce, seg.SeqNo, err, false)
-			}
+			monitor.SegmentTranscodeFailed(monitor.SegmentTranscodeError(code.String()), nonce, seg.SeqNo, err, false)
 		}
-		return nil, err
+		return nil, common.NewCodedError(code, err)
 	case *net.TranscodeResult_Data:
 		// fall through here for the normal case
 		tdata = res.Data
 	default:
-		glog.Errorf("Unexpected or unset transcode response field for nonce=%d manifestID=%s seqNo=%d orch=%s", nonce, params.ManifestID, seg.SeqNo, ti.Transcoder)
+		glog.Errorf("Unexpected or unset transcode response field for nonce=%d manifestID=%s seqNo=%d orch=%s", nonce, params.ManifestID, seg.SeqNo, orchURI)
 		err = fmt.Errorf("UnknownResponse")
 		if monitor.Enabled {
 			monitor.SegmentTranscodeFailed(monitor.SegmentTranscodeErrorUnknownResponse, nonce, seg.SeqNo, err, false)
@@ -479,10 +737,15 @@ func SubmitSegment(sess *BroadcastSession, seg *stream.HLSSegment, nonce uint64)
 	balUpdate.Status = ReceivedChange
 	if priceInfo != nil {
 		// The update's debit is the transcoding fee which is computed as the total number of pixels processed
-		// for all results returned multiplied by the orchestrator's price
+		// for all results returned multiplied by the orchestrator's price, priced by the capabilities of
+		// the renditions
 		var pixelCount int64
-		for _, res := range tdata.Segments {
-			pixelCount += res.Pixels
+		for i, res := range tdata.Segments {
+			if i < len(params.Profiles) {
+				pixelCount += capabilityPrices.PricedPixels(params.Profiles[i], res.Pixels)
+			} else {
+				pixelCount += res.Pixels
+			}
 		}
 
 		balUpdate.Debit.Mul(new(big.Rat).SetInt64(pixelCount), priceInfo)
@@ -494,11 +757,12 @@ func SubmitSegment(sess *BroadcastSession, seg *stream.HLSSegment, nonce uint64)
 	}
 
 	glog.Infof("Successfully transcoded segment nonce=%d manifestID=%s segName=%s seqNo=%d orch=%s dur=%s", nonce,
-		string(params.ManifestID), seg.Name, seg.SeqNo, ti.Transcoder, transcodeDur)
+		string(params.ManifestID), seg.Name, seg.SeqNo, orchURI, transcodeDur)
 
 	return &ReceivedTranscodeResult{
 		TranscodeData: tdata,
 		Info:          tr.Info,
+		PriceUpdate:   tr.PriceUpdate,
 		LatencyScore:  tookAllDur.Seconds() / seg.Duration,
 	}, nil
 }
@@ -522,6 +786,9 @@ func genSegCreds(sess *BroadcastSession, seg *stream.HLSSegment) (string, error)
 		OS:         storage,
 		Duration:   time.Duration(seg.Duration * float64(time.Second)),
 		Caps:       params.Capabilities,
+		Protocol:   core.NewProtocolInfo(),
+		AuthToken:  sess.OrchestratorInfo.GetAuthToken(),
+		Clip:       params.Clip,
 	}
 	sig, err := sess.Broadcaster.Sign(md.Flatten())
 	if err != nil {
@@ -543,7 +810,67 @@ func genSegCreds(sess *BroadcastSession, seg *stream.HLSSegment) (string, error)
 	return base64.StdEncoding.EncodeToString(data), nil
 }
 
-func estimateFee(seg *stream.HLSSegment, profiles []ffmpeg.VideoProfile, priceInfo *big.Rat) (*big.Rat, error) {
+// readTranscodeResult reads the transcode result from the body of a segment response,
+// calling onRendition with the renditions sent ahead of the result in multipart responses
+func readTranscodeResult(resp *http.Response, onRendition func(int, *net.TranscodedSegmentData)) ([]byte, error) {
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		// Orchestrators that don't send renditions ahead of the result
+		return ioutil.ReadAll(resp.Body)
+	}
+
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := readPart(part)
+		if err != nil {
+			return nil, err
+		}
+		switch part.Header.Get("Content-Type") {
+		case transcodeResultContentType:
+			return data, nil
+		case renditionContentType:
+			i, err := strconv.Atoi(part.Header.Get(renditionHeader))
+			if err != nil {
+				glog.Errorf("Invalid rendition index in segment response err=%v", err)
+				continue
+			}
+			var rendition net.TranscodedSegmentData
+			if err := proto.Unmarshal(data, &rendition); err != nil {
+				glog.Errorf("Unable to parse rendition in segment response err=%v", err)
+				continue
+			}
+			if onRendition != nil {
+				onRendition(i, &rendition)
+			}
+		}
+	}
+}
+
+// readPart reads the body of a part of a multipart segment response. Parts with a length
+// are read without waiting for the boundary of the next part
+func readPart(part *multipart.Part) ([]byte, error) {
+	n, err := strconv.Atoi(part.Header.Get("Content-Length"))
+	if err != nil || n < 0 {
+		return ioutil.ReadAll(part)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(part, int64(n)))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return data, nil
+}
+
+func estimateFee(seg *stream.HLSSegment, profiles []ffmpeg.VideoProfile, priceInfo *big.Rat, capabilityPrices core.CapabilityPrices) (*big.Rat, error) {
 	if priceInfo == nil {
 		return nil, nil
 	}
@@ -552,6 +879,10 @@ func estimateFee(seg *stream.HLSSegment, profiles []ffmpeg.VideoProfile, priceIn
 	// Estimate the number of output pixels
 	var outPixels int64
 	for _, p := range profiles {
+		if common.ProfileCodec(p.Profile) == common.NoVideo {
+			// Audio-only renditions pass the audio through, which isn't charged for
+			continue
+		}
 		w, h, err := ffmpeg.VideoProfileResolution(p)
 		if err != nil {
 			return nil, err
@@ -569,7 +900,7 @@ func estimateFee(seg *stream.HLSSegment, profiles []ffmpeg.VideoProfile, priceIn
 		}
 		// Take ceilings, as it is better to overestimate
 		fps := math.Ceil((float64(framerate) / float64(framerateDen)))
-		outPixels += int64(w*h) * int64(fps) * int64(math.Ceil(seg.Duration))
+		outPixels += capabilityPrices.PricedPixels(p, int64(w*h)*int64(fps)*int64(math.Ceil(seg.Duration)))
 	}
 
 	// feeEstimate = pixels * pixelEstimateMultiplier * priceInfo
@@ -598,6 +929,13 @@ func newBalanceUpdate(sess *BroadcastSession, minCredit *big.Rat) (*BalanceUpdat
 		return nil, err
 	}
 
+	// Free tickets don't add credit, so a single one is sent with every segment of the free
+	// tier for the orchestrator to account for the session
+	if ev.Sign() == 0 && BroadcastCfg.FreeTickets() {
+		update.NumTickets = 1
+		return update, nil
+	}
+
 	// The orchestrator requires the broadcaster's balance to be at least the EV of a single ticket
 	// Use the ticket EV when creating the balance update if the passed in minCredit is less than the ticket EV
 	safeMinCredit := minCredit
@@ -607,6 +945,32 @@ func newBalanceUpdate(sess *BroadcastSession, minCredit *big.Rat) (*BalanceUpdat
 
 	update.NumTickets, update.NewCredit, update.ExistingCredit = sess.Balance.StageUpdate(safeMinCredit, ev)
 
+	// A payment covering several segments carries the tickets for the credit of the segments
+	// that follow, which are then sent without a payment until the credit runs out. The extra
+	// tickets are capped by the headroom left under the max EV of a ticket batch, so that they
+	// don't make the sender refuse the whole batch
+	if segments := BroadcastCfg.PaymentSegments(); update.NumTickets > 0 && segments > 1 {
+		extraCredit := new(big.Rat).Mul(safeMinCredit, new(big.Rat).SetInt64(int64(segments-1)))
+		sizeRat := extraCredit.Quo(extraCredit, ev)
+		extraTickets := new(big.Int).Div(sizeRat.Num(), sizeRat.Denom())
+		if !sizeRat.IsInt() {
+			extraTickets.Add(extraTickets, big.NewInt(1))
+		}
+		if maxEV := sess.Sender.MaxEV(); maxEV != nil {
+			maxRat := new(big.Rat).Quo(maxEV, ev)
+			headroom := new(big.Int).Div(maxRat.Num(), maxRat.Denom())
+			headroom.Sub(headroom, big.NewInt(int64(update.NumTickets)))
+			if headroom.Sign() < 0 {
+				headroom.SetInt64(0)
+			}
+			if extraTickets.Cmp(headroom) > 0 {
+				extraTickets = headroom
+			}
+		}
+		update.NumTickets += int(extraTickets.Int64())
+		update.NewCredit = new(big.Rat).Mul(new(big.Rat).SetInt64(int64(update.NumTickets)), ev)
+	}
+
 	return update, nil
 }
 
@@ -664,6 +1028,8 @@ func genPayment(sess *BroadcastSession, numTickets int) (string, error) {
 			RecipientRandHash: batch.RecipientRandHash.Bytes(),
 			Seed:              batch.Seed.Bytes(),
 			ExpirationBlock:   batch.ExpirationBlock.Bytes(),
+			PayoutSplit:       common.ProtoPayoutSplit(batch.PayoutSplit),
+			Version:           batch.Version,
 		}
 
 		protoPayment.ExpirationParams = &net.TicketExpirationParams{
@@ -701,7 +1067,41 @@ func genPayment(sess *BroadcastSession, numTickets int) (string, error) {
 }
 
 func validatePrice(sess *BroadcastSession) error {
-	oPrice, err := common.RatPriceInfo(sess.OrchestratorInfo.GetPriceInfo())
+	if err := validatePriceInfo(sess.OrchestratorInfo.GetPriceInfo()); err != nil {
+		return err
+	}
+	var profiles []ffmpeg.VideoProfile
+	if sess.Params != nil {
+		profiles = sess.Params.Profiles
+	}
+	return validateCapabilityPrices(sess.OrchestratorInfo, profiles)
+}
+
+// validateCapabilityPrices checks the prices of the renditions of profiles, with the
+// capability prices of an orchestrator, against BroadcastConfig.MaxPrice
+func validateCapabilityPrices(info *net.OrchestratorInfo, profiles []ffmpeg.VideoProfile) error {
+	percent := core.CapabilityPricesFromNet(info.GetCapabilityPrices()).MaxPercent(profiles)
+	if percent == 100 {
+		return nil
+	}
+	if percent > core.MaxCapabilityPercent {
+		return fmt.Errorf("Orchestrator capability price of %v%% higher than the maximum of %v%%", percent, core.MaxCapabilityPercent)
+	}
+	oPrice, err := common.RatPriceInfo(info.GetPriceInfo())
+	if err != nil {
+		return err
+	}
+	price := new(big.Rat).Mul(oPrice, big.NewRat(int64(percent), 100))
+	maxPrice := BroadcastCfg.MaxPrice()
+	if maxPrice != nil && price.Cmp(maxPrice) == 1 {
+		return fmt.Errorf("Orchestrator price of %v%% of its price per pixel for capabilities higher than the set maximum price of %v wei per %v pixels", percent, maxPrice.Num().Int64(), maxPrice.Denom().Int64())
+	}
+	return nil
+}
+
+// validatePriceInfo checks the price of an orchestrator against BroadcastConfig.MaxPrice
+func validatePriceInfo(priceInfo *net.PriceInfo) error {
+	oPrice, err := common.RatPriceInfo(priceInfo)
 	if err != nil {
 		return err
 	}
@@ -6,8 +6,11 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/big"
+	"mime/multipart"
+	gonet "net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -55,6 +58,7 @@ func TestServeSegment_GetPaymentError(t *testing.T) {
 	assert := assert.New(t)
 	assert.Equal(http.StatusPaymentRequired, resp.StatusCode)
 	assert.Contains(strings.TrimSpace(string(body)), "base64")
+	assert.Equal("201", resp.Header.Get(errorCodeHeader))
 }
 
 func TestServeSegment_VerifySegCredsError(t *testing.T) {
@@ -74,6 +78,63 @@ func TestServeSegment_VerifySegCredsError(t *testing.T) {
 	assert := assert.New(t)
 	assert.Equal(http.StatusForbidden, resp.StatusCode)
 	assert.Equal(errSegEncoding.Error(), strings.TrimSpace(string(body)))
+	assert.Equal(common.ErrCodeIngestSegCreds, common.ParseErrorCode(resp.Header.Get(errorCodeHeader)))
+}
+
+func TestServeSegment_IncompatibleProtocol(t *testing.T) {
+	orch := &mockOrchestrator{}
+	handler := serveSegmentHandler(orch)
+
+	segData := &net.SegData{Protocol: &net.ProtocolInfo{Version: core.ProtocolVersion + 1, MinVersion: core.ProtocolVersion + 1}}
+	data, err := proto.Marshal(segData)
+	require.Nil(t, err)
+	headers := map[string]string{
+		paymentHeader: "",
+		segmentHeader: base64.StdEncoding.EncodeToString(data),
+	}
+	resp := httpPostResp(handler, nil, headers)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusForbidden, resp.StatusCode)
+	assert.Contains(string(body), "incompatible protocol version")
+	assert.Equal(common.ErrCodeIngestProtocol, common.ParseErrorCode(resp.Header.Get(errorCodeHeader)))
+}
+
+func TestServeSegment_AuthTokenError(t *testing.T) {
+	orch := &mockOrchestrator{}
+	handler := serveSegmentHandler(orch)
+	orch.On("VerifySig", mock.Anything, mock.Anything, mock.Anything).Return(true)
+
+	s := &BroadcastSession{
+		Broadcaster: stubBroadcaster2(),
+		Params: &core.StreamParameters{
+			ManifestID: core.RandomManifestID(),
+			Profiles:   []ffmpeg.VideoProfile{ffmpeg.P720p60fps16x9},
+		},
+		OrchestratorInfo: &net.OrchestratorInfo{
+			AuthToken: orch.AuthToken("sess", time.Now().Add(-time.Second).Unix()),
+		},
+	}
+	creds, err := genSegCreds(s, &stream.HLSSegment{Data: []byte("foo")})
+	require.Nil(t, err)
+	headers := map[string]string{
+		paymentHeader: "",
+		segmentHeader: creds,
+	}
+	resp := httpPostResp(handler, nil, headers)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusForbidden, resp.StatusCode)
+	assert.Equal(errAuthTokenExpired.Error(), strings.TrimSpace(string(body)))
+	assert.Equal(common.ErrCodeIngestAuthToken, common.ParseErrorCode(resp.Header.Get(errorCodeHeader)))
 }
 
 func TestServeSegment_MismatchHashError(t *testing.T) {
@@ -113,6 +174,7 @@ func TestServeSegment_MismatchHashError(t *testing.T) {
 	assert := assert.New(t)
 	assert.Equal(http.StatusForbidden, resp.StatusCode)
 	assert.Equal("Forbidden", strings.TrimSpace(string(body)))
+	assert.Equal(common.ErrCodeIngestHashMismatch, common.ParseErrorCode(resp.Header.Get(errorCodeHeader)))
 }
 
 func TestServeSegment_TranscodeSegError(t *testing.T) {
@@ -381,6 +443,57 @@ func TestGenSegCreds_Profiles(t *testing.T) {
 	assert.Equal(expectedProfiles, segData.FullProfiles)
 }
 
+func TestGenSegCreds_Clip(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	b := stubBroadcaster2()
+	clip := &core.ClipRange{From: 500 * time.Millisecond, To: 1500 * time.Millisecond}
+	s := &BroadcastSession{
+		Broadcaster: b,
+		Params: &core.StreamParameters{
+			ManifestID: core.RandomManifestID(),
+			Profiles:   []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9},
+			Clip:       clip,
+		},
+	}
+	seg := &stream.HLSSegment{Data: []byte("foo"), Duration: 2.0}
+
+	data, err := genSegCreds(s, seg)
+	require.Nil(err)
+	buf, err := base64.StdEncoding.DecodeString(data)
+	require.Nil(err)
+	segData := &net.SegData{}
+	require.Nil(proto.Unmarshal(buf, segData))
+	assert.Equal(int64(500), segData.ClipFrom)
+	assert.Equal(int64(1500), segData.ClipTo)
+
+	md, err := coreSegMetadata(segData)
+	require.Nil(err)
+	assert.Equal(clip, md.Clip)
+	// the bounds of the clip are signed by the broadcaster
+	assert.True(b.VerifySig(b.Address(), string(md.Flatten()), segData.Sig))
+	md.Clip = &core.ClipRange{From: 500 * time.Millisecond}
+	assert.False(b.VerifySig(b.Address(), string(md.Flatten()), segData.Sig))
+
+	// segments that aren't clipped have no bounds
+	s.Params.Clip = nil
+	data, err = genSegCreds(s, seg)
+	require.Nil(err)
+	buf, _ = base64.StdEncoding.DecodeString(data)
+	segData = &net.SegData{}
+	require.Nil(proto.Unmarshal(buf, segData))
+	md, err = coreSegMetadata(segData)
+	require.Nil(err)
+	assert.Nil(md.Clip)
+
+	// bounds that don't keep part of the segment are rejected
+	for _, bounds := range [][2]int64{{-1, 0}, {0, -1}, {2000, 0}, {1000, 500}, {1000, 1000}} {
+		segData.ClipFrom, segData.ClipTo = bounds[0], bounds[1]
+		_, err = coreSegMetadata(segData)
+		assert.Equal(core.ErrClipRange, err, "bounds %v", bounds)
+	}
+}
+
 func TestCoreSegMetadata_FullProfiles(t *testing.T) {
 	assert := assert.New(t)
 
@@ -417,6 +530,41 @@ func TestCoreSegMetadata_FullProfiles(t *testing.T) {
 	profiles[0].Format = ffmpeg.FormatMPEGTS
 	assert.Equal(profiles, md.Profiles)
 
+	// Test H265 profile roundtrip
+	segData.FullProfiles[1].Profile = net.VideoProfile_H265_MAIN
+	md, err = coreSegMetadata(segData)
+	assert.Nil(err)
+	assert.Equal(common.ProfileH265Main, md.Profiles[1].Profile)
+
+	// Test AV1 profile roundtrip, which is only muxed into MP4
+	segData.FullProfiles[1].Profile = net.VideoProfile_AV1_MAIN
+	md, err = coreSegMetadata(segData)
+	assert.Nil(err)
+	assert.Equal(common.ProfileAV1Main, md.Profiles[1].Profile)
+	segData.FullProfiles[1].Format = net.VideoProfile_MPEGTS
+	md, err = coreSegMetadata(segData)
+	assert.Nil(md)
+	assert.Equal(common.ErrCodecFormat, err)
+	segData.FullProfiles[1].Format = net.VideoProfile_MP4
+
+	// Test VP8 profile roundtrip, which is only muxed into WebM
+	segData.FullProfiles[1].Profile = net.VideoProfile_VP8
+	md, err = coreSegMetadata(segData)
+	assert.Nil(md)
+	assert.Equal(common.ErrCodecFormat, err)
+	segData.FullProfiles[1].Format = net.VideoProfile_WEBM
+	md, err = coreSegMetadata(segData)
+	assert.Nil(err)
+	assert.Equal(common.ProfileVP8, md.Profiles[1].Profile)
+	assert.Equal(common.FormatWebM, md.Profiles[1].Format)
+	segData.FullProfiles[1].Format = net.VideoProfile_MP4
+
+	// Test audio-only profile roundtrip
+	segData.FullProfiles[1].Profile = net.VideoProfile_AUDIO_ONLY
+	md, err = coreSegMetadata(segData)
+	assert.Nil(err)
+	assert.Equal(common.ProfileAudioOnly, md.Profiles[1].Profile)
+
 	// Test deserialization failure from invalid full profile format
 	segData.FullProfiles[1].Format = -1
 	md, err = coreSegMetadata(segData)
@@ -810,6 +958,137 @@ func TestServeSegment_ReturnMultipleTranscodedSegmentData(t *testing.T) {
 	assert.Equal(2, len(res.Data.Segments))
 }
 
+func TestServeSegment_Multipart(t *testing.T) {
+	orch := &mockOrchestrator{}
+	handler := serveSegmentHandler(orch)
+
+	require := require.New(t)
+
+	orch.On("VerifySig", mock.Anything, mock.Anything, mock.Anything).Return(true)
+
+	s := &BroadcastSession{
+		Broadcaster: stubBroadcaster2(),
+		Params: &core.StreamParameters{
+			ManifestID: core.RandomManifestID(),
+			Profiles: []ffmpeg.VideoProfile{
+				ffmpeg.P720p60fps16x9,
+				ffmpeg.P240p30fps16x9,
+			},
+		},
+	}
+	seg := &stream.HLSSegment{Data: []byte("foo")}
+	creds, err := genSegCreds(s, seg)
+	require.Nil(err)
+
+	md, err := verifySegCreds(orch, creds, ethcommon.Address{})
+	require.Nil(err)
+
+	drivers.NodeStorage = drivers.NewMemoryDriver(nil)
+	url, _ := url.Parse("foo")
+	orch.On("ServiceURI").Return(url)
+	orch.On("Address").Return(ethcommon.Address{})
+	orch.On("PriceInfo", mock.Anything).Return(&net.PriceInfo{}, nil)
+	orch.On("TicketParams", mock.Anything, mock.Anything).Return(&net.TicketParams{}, nil)
+	orch.On("ProcessPayment", net.Payment{}, s.Params.ManifestID).Return(nil)
+	orch.On("SufficientBalance", mock.Anything, s.Params.ManifestID).Return(true)
+
+	tRes := &core.TranscodeResult{
+		TranscodeData: &core.TranscodeData{Segments: []*core.TranscodedSegmentData{
+			{Data: []byte("foo"), Pixels: 1, Frames: 120},
+			{Data: []byte("bar"), Pixels: 2, Frames: 60},
+		}},
+		Sig: []byte("foo"),
+		OS:  drivers.NewMemoryDriver(nil).NewSession(""),
+	}
+	orch.On("TranscodeSeg", md, seg).Return(tRes, nil)
+	orch.On("DebitFees", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	headers := map[string]string{
+		paymentHeader: "",
+		segmentHeader: creds,
+		"Accept":      "multipart/mixed",
+	}
+	resp := httpPostResp(handler, bytes.NewReader(seg.Data), headers)
+	defer resp.Body.Close()
+
+	// The renditions are sent ahead of the result
+	var renditions []*net.TranscodedSegmentData
+	body, err := readTranscodeResult(resp, func(i int, rendition *net.TranscodedSegmentData) {
+		assert.Equal(t, len(renditions), i)
+		renditions = append(renditions, rendition)
+	})
+	require.Nil(err)
+
+	var tr net.TranscodeResult
+	require.Nil(proto.Unmarshal(body, &tr))
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	res, ok := tr.Result.(*net.TranscodeResult_Data)
+	require.True(ok)
+	assert.Equal([]byte("foo"), res.Data.Sig)
+	require.Len(renditions, 2)
+	for i, rendition := range renditions {
+		assert.Equal(res.Data.Segments[i].Url, rendition.Url)
+		assert.Equal(int64(i+1), rendition.Pixels)
+		assert.Equal(int32(2000), rendition.Duration)
+	}
+
+	// Writers that can't be flushed get the result on its own
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "http://example.com", bytes.NewReader(seg.Data))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	handler.ServeHTTP(struct{ http.ResponseWriter }{rec}, req)
+	resp = rec.Result()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.NotContains(resp.Header.Get("Content-Type"), "multipart/mixed")
+	body, err = readTranscodeResult(resp, nil)
+	require.Nil(err)
+	require.Nil(proto.Unmarshal(body, &tr))
+	_, ok = tr.Result.(*net.TranscodeResult_Data)
+	assert.True(ok)
+
+	// Responses without a result are truncated
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	require.Nil(writeResponsePart(mw, renditionContentType, renditions[0], "0"))
+	mw.Close()
+	resp = &http.Response{
+		Header: http.Header{"Content-Type": {"multipart/mixed; boundary=" + mw.Boundary()}},
+		Body:   ioutil.NopCloser(&buf),
+	}
+	_, err = readTranscodeResult(resp, nil)
+	assert.Equal(io.ErrUnexpectedEOF, err)
+}
+
+func TestRenditionDuration(t *testing.T) {
+	assert := assert.New(t)
+
+	md := &core.SegTranscodingMetadata{
+		Duration: 2 * time.Second,
+		Profiles: []ffmpeg.VideoProfile{
+			ffmpeg.P240p30fps16x9,
+			{Name: "source fps"},
+			{Name: "ntsc", Framerate: 30000, FramerateDen: 1001},
+		},
+	}
+	tData := &core.TranscodeData{
+		Segments: []*core.TranscodedSegmentData{{Frames: 45}, {Frames: 50}, {Frames: 60}},
+		Frames:   100,
+	}
+	assert.Equal(int32(1500), renditionDuration(md, 0, tData))
+	assert.Equal(int32(1000), renditionDuration(md, 1, tData))
+	assert.Equal(int32(2002), renditionDuration(md, 2, tData))
+
+	// Transcoders that don't count frames
+	tData.Frames = 0
+	assert.Equal(int32(0), renditionDuration(md, 1, tData))
+	tData.Segments[0].Frames = 0
+	assert.Equal(int32(0), renditionDuration(md, 0, tData))
+}
+
 func TestServeSegment_ProcessPaymentError(t *testing.T) {
 	orch := &mockOrchestrator{}
 	handler := serveSegmentHandler(orch)
@@ -946,6 +1225,9 @@ func TestServeSegment_UpdateOrchestratorInfo(t *testing.T) {
 	assert.Equal(price.PricePerUnit, tr.Info.PriceInfo.PricePerUnit)
 	assert.Equal(price.PixelsPerUnit, tr.Info.PriceInfo.PixelsPerUnit)
 	assert.Equal(addr.Bytes(), tr.Info.Address)
+	assert.True(core.HasProtocolFeature(tr.Info.Protocol, core.ProtocolFeatureReceipts))
+	// A new session is started for broadcasters without an auth token
+	assert.Nil(verifyAuthToken(orch, tr.Info.AuthToken))
 
 	// Test orchestratorInfo error
 	orch.On("ProcessPayment", net.Payment{}, s.Params.ManifestID).Return(nil).Once()
@@ -1022,6 +1304,7 @@ func TestServeSegment_InsufficientBalance(t *testing.T) {
 
 	assert.Equal(http.StatusBadRequest, resp.StatusCode)
 	assert.Equal("Insufficient balance", strings.TrimSpace(string(body)))
+	assert.Equal(common.ErrCodePaymentInsufficientBalance, common.ParseErrorCode(resp.Header.Get(errorCodeHeader)))
 }
 
 func TestServeSegment_DebitFees_SingleRendition(t *testing.T) {
@@ -1475,6 +1758,83 @@ func TestSubmitSegment_HttpPostError(t *testing.T) {
 	balance.AssertCalled(t, "Credit", existingCredit)
 }
 
+func TestSubmitSegment_AltTranscoders(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	buf, err := proto.Marshal(&net.TranscodeResult{
+		Result: &net.TranscodeResult_Data{Data: &net.TranscodeData{Segments: []*net.TranscodedSegmentData{{Url: "foo"}}}},
+	})
+	require.Nil(err)
+
+	ts, mux := stubTLSServer()
+	defer ts.Close()
+	var requests int
+	mux.HandleFunc("/segment", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		data, err := ioutil.ReadAll(r.Body)
+		require.Nil(err)
+		assert.Equal([]byte("dummy"), data)
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf)
+	})
+
+	s := &BroadcastSession{
+		Broadcaster: stubBroadcaster2(),
+		Params:      &core.StreamParameters{ManifestID: core.RandomManifestID()},
+		OrchestratorInfo: &net.OrchestratorInfo{
+			Transcoder:     "https://127.0.0.1:1",
+			AltTranscoders: []string{ts.URL},
+			PriceInfo: &net.PriceInfo{
+				PricePerUnit:  1,
+				PixelsPerUnit: 1,
+			},
+		},
+	}
+
+	// Segments fail over to the alternative URI when the transcoder is unreachable
+	res, err := SubmitSegment(s, &stream.HLSSegment{Data: []byte("dummy")}, 0)
+	require.Nil(err)
+	assert.Equal("foo", res.Segments[0].Url)
+	assert.Equal(1, requests)
+	assert.Equal(ts.URL, s.Endpoint)
+	assert.Equal(ts.URL, s.transcoderURIs()[0])
+
+	// Errors other than unreachable transcoders are not retried
+	mux.HandleFunc("/fail/segment", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	s.Endpoint = ""
+	s.OrchestratorInfo.Transcoder = ts.URL + "/fail"
+	_, err = SubmitSegment(s, &stream.HLSSegment{Data: []byte("dummy")}, 0)
+	assert.NotNil(err)
+	assert.Equal(2, requests)
+
+	// The last error is returned when no URI can be reached
+	s.OrchestratorInfo.Transcoder = "https://127.0.0.1:1"
+	s.OrchestratorInfo.AltTranscoders = []string{"https://127.0.0.1:2"}
+	_, err = SubmitSegment(s, &stream.HLSSegment{Data: []byte("dummy")}, 0)
+	require.NotNil(err)
+	assert.Contains(err.Error(), "127.0.0.1:2")
+	assert.Contains(err.Error(), "connection refused")
+	assert.Equal(2, requests)
+}
+
+func TestIsUnreachable(t *testing.T) {
+	assert := assert.New(t)
+
+	dialErr := &gonet.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	assert.True(isUnreachable(dialErr))
+	assert.True(isUnreachable(&url.Error{Op: "Post", URL: "https://127.0.0.1:1/segment", Err: dialErr}))
+
+	// Requests that reached the transcoder may have been processed
+	readErr := &gonet.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+	assert.False(isUnreachable(&url.Error{Op: "Post", URL: "https://127.0.0.1:1/segment", Err: readErr}))
+	assert.False(isUnreachable(errors.New("dial tcp 127.0.0.1:1: connection refused")))
+	assert.False(isUnreachable(nil))
+}
+
 func TestSubmitSegment_Non200StatusCode(t *testing.T) {
 	ts, mux := stubTLSServer()
 	defer ts.Close()
@@ -1514,6 +1874,51 @@ func TestSubmitSegment_Non200StatusCode(t *testing.T) {
 	balance.AssertNotCalled(t, "Credit", mock.Anything)
 }
 
+func TestSubmitSegment_Non200StatusCode_ErrorCode(t *testing.T) {
+	assert := assert.New(t)
+
+	ts, mux := stubTLSServer()
+	defer ts.Close()
+	code := common.ErrCodeUnknown
+	mux.HandleFunc("/segment", func(w http.ResponseWriter, r *http.Request) {
+		if code == common.ErrCodeUnknown {
+			http.Error(w, "Server error", http.StatusInternalServerError)
+			return
+		}
+		httpErrorWithCode(w, "Server error", http.StatusBadRequest, code)
+	})
+
+	s := &BroadcastSession{
+		Broadcaster: stubBroadcaster2(),
+		Params:      &core.StreamParameters{ManifestID: core.RandomManifestID()},
+		OrchestratorInfo: &net.OrchestratorInfo{
+			Transcoder: ts.URL,
+			PriceInfo: &net.PriceInfo{
+				PricePerUnit:  1,
+				PixelsPerUnit: 1,
+			},
+		},
+	}
+
+	// Test orchestrator that does not send an error code
+	_, err := SubmitSegment(s, &stream.HLSSegment{}, 0)
+	assert.EqualError(err, "Server error")
+	assert.Equal(common.ErrCodeUnknown, common.ErrorCodeOf(err))
+
+	// Test orchestrator that sends an error code
+	code = common.ErrCodePaymentInsufficientBalance
+	_, err = SubmitSegment(s, &stream.HLSSegment{}, 0)
+	assert.EqualError(err, "Server error")
+	assert.Equal(common.ErrCodePaymentInsufficientBalance, common.ErrorCodeOf(err))
+}
+
+func TestTranscodeErrorCode(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(common.ErrCodeTranscodeOrchestratorBusy, transcodeErrorCode(core.ErrOrchBusy.Error()))
+	assert.Equal(common.ErrCodeTranscodeOrchestratorCapped, transcodeErrorCode(core.ErrOrchCap.Error()))
+	assert.Equal(common.ErrCodeTranscodeFailed, transcodeErrorCode("foo"))
+}
+
 func TestSubmitSegment_ProtoUnmarshalError(t *testing.T) {
 	ts, mux := stubTLSServer()
 	defer ts.Close()
@@ -1597,6 +2002,7 @@ func TestSubmitSegment_TranscodeResultError(t *testing.T) {
 	_, err = SubmitSegment(s, &stream.HLSSegment{}, 0)
 
 	assert.Equal(t, "TranscodeResult error", err.Error())
+	assert.Equal(t, common.ErrCodeTranscodeFailed, common.ErrorCodeOf(err))
 	balance.AssertNotCalled(t, "Credit", mock.Anything)
 }
 
@@ -1806,6 +2212,30 @@ func TestSubmitSegment_Success(t *testing.T) {
 	seg := &stream.HLSSegment{Name: "foo", Data: []byte("dummy")}
 	SubmitSegment(s, seg, 0)
 
+	// Test when uploaded input data is small enough to be sent inline
+	uploadedChecks := runChecks
+	runChecks = func(r *http.Request) {
+		assert.Equal("video/MP2T", r.Header.Get("Content-Type"))
+
+		data, err := ioutil.ReadAll(r.Body)
+		require.Nil(err)
+
+		assert.Equal([]byte("dummy"), data)
+	}
+	core.InlineSegmentMaxSize = 10
+	s.OrchestratorInfo.Protocol = &net.ProtocolInfo{InlineMaxSize: 5}
+	SubmitSegment(s, seg, 0)
+
+	// Segments larger than the size of either node are uploaded
+	runChecks = uploadedChecks
+	s.OrchestratorInfo.Protocol = &net.ProtocolInfo{InlineMaxSize: 4}
+	SubmitSegment(s, seg, 0)
+	core.InlineSegmentMaxSize = 4
+	s.OrchestratorInfo.Protocol = &net.ProtocolInfo{InlineMaxSize: 10}
+	SubmitSegment(s, seg, 0)
+	core.InlineSegmentMaxSize = 0
+	s.OrchestratorInfo.Protocol = nil
+
 	// Test completeBalanceUpdate() adds back change when the update status is ReceivedChange
 
 	// Use a custom matcher func to compare mocked big.Rat values
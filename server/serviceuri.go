@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	gonet "net"
+	"net/http"
+	"net/url"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/crypto"
+	"github.com/livepeer/go-livepeer/eth"
+	"github.com/livepeer/go-livepeer/net"
+)
+
+// Registering a service URI that broadcasters can't reach is a common misconfiguration: the
+// orchestrator doesn't get any work until another transaction fixes it. The service URI is
+// checked before it is registered by pinging the orchestrator at the URI, through the same
+// path as broadcasters, and verifying that the pong is signed by this node
+
+// checkServiceURI checks that serviceURI is a public address of the orchestrator addr. It is
+// replaced in tests, whose orchestrators are only reachable on loopback
+var checkServiceURI = checkPublicServiceURI
+
+func checkPublicServiceURI(addr ethcommon.Address, serviceURI string) error {
+	uri, err := url.ParseRequestURI(serviceURI)
+	if err != nil {
+		return err
+	}
+	if uri.Scheme != "https" {
+		return fmt.Errorf("service URI %v must use https", serviceURI)
+	}
+	if uri.Port() == "" {
+		return fmt.Errorf("service URI %v must include a port", serviceURI)
+	}
+
+	ips, err := gonet.LookupIP(uri.Hostname())
+	if err != nil {
+		return fmt.Errorf("could not resolve service URI %v: %v", serviceURI, err)
+	}
+	for _, ip := range ips {
+		if !isPublicIP(ip) {
+			return fmt.Errorf("service URI %v resolves to the non-public address %v", serviceURI, ip)
+		}
+	}
+
+	return pingServiceURI(addr, uri)
+}
+
+// pingServiceURI pings the orchestrator at uri and checks that the pong is signed by addr
+func pingServiceURI(addr ethcommon.Address, uri *url.URL) error {
+	orchClient, conn, err := startOrchestratorClient(uri)
+	if err != nil {
+		return fmt.Errorf("service URI %v is not reachable: %v", uri, err)
+	}
+	defer conn.Close()
+
+	ping := make([]byte, 32)
+	if _, err := rand.Read(ping); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), GRPCTimeout)
+	defer cancel()
+
+	pong, err := orchClient.Ping(ctx, &net.PingPong{Value: ping})
+	if err != nil {
+		return fmt.Errorf("service URI %v did not respond to a ping: %v", uri, err)
+	}
+	if !crypto.VerifySig(addr, ethcrypto.Keccak256(ping), pong.Value) {
+		return fmt.Errorf("service URI %v is served by another node than %v", uri, addr.Hex())
+	}
+
+	return nil
+}
+
+func isPublicIP(ip gonet.IP) bool {
+	if ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, block := range privateIPBlocks {
+		if block.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+var privateIPBlocks = func() []*gonet.IPNet {
+	var blocks []*gonet.IPNet
+	for _, cidr := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7"} {
+		_, block, _ := gonet.ParseCIDR(cidr)
+		blocks = append(blocks, block)
+	}
+	return blocks
+}()
+
+// validateServiceURI checks serviceURI before it is registered, unless the
+// skipServiceURICheck form value is true, e.g. on nodes that can't reach their own public
+// address
+func validateServiceURI(r *http.Request, client eth.LivepeerEthClient, serviceURI string) error {
+	if r.FormValue("skipServiceURICheck") == "true" {
+		glog.Warningf("Skipping the check of service URI %v", serviceURI)
+		return nil
+	}
+	return checkServiceURI(client.Account().Address, serviceURI)
+}
+
+// checkServiceURIHandler checks that the serviceURI form value, or the current service URI
+// of the node if it is not set, is a public address at which the node can be reached
+func checkServiceURIHandler(s *LivepeerServer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := s.LivepeerNode.Eth
+		if client == nil {
+			respondWith500(w, "missing ETH client")
+			return
+		}
+
+		serviceURI := r.FormValue("serviceURI")
+		if serviceURI == "" {
+			if uri := s.LivepeerNode.GetServiceURI(); uri != nil {
+				serviceURI = uri.String()
+			}
+		}
+		if serviceURI == "" {
+			respondWith400(w, "missing serviceURI")
+			return
+		}
+
+		addr := client.Account().Address
+		if err := checkServiceURI(addr, serviceURI); err != nil {
+			respondWith400(w, err.Error())
+			return
+		}
+
+		data, err := json.Marshal(map[string]string{"serviceURI": serviceURI, "address": addr.Hex()})
+		if err != nil {
+			respondWith500(w, fmt.Sprintf("could not marshal service URI: %v", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"crypto/tls"
+	gonet "net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/eth"
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/go-livepeer/pm"
+)
+
+func stubServiceURIServer(orch *stubOrchestrator) *httptest.Server {
+	s := grpc.NewServer()
+	lp := &lphttp{orchestrator: orch, orchRPC: s, transRPC: http.NewServeMux()}
+	net.RegisterOrchestratorServer(s, lp)
+	ts := httptest.NewUnstartedServer(lp)
+	ts.TLS = &tls.Config{NextProtos: []string{http2.NextProtoTLS, "http/1.1"}}
+	ts.StartTLS()
+	return ts
+}
+
+func TestPingServiceURI(t *testing.T) {
+	assert := assert.New(t)
+
+	orch := newStubOrchestrator()
+	ts := stubServiceURIServer(orch)
+	defer ts.Close()
+	uri, _ := url.Parse(ts.URL)
+
+	assert.Nil(pingServiceURI(orch.Address(), uri))
+
+	// Test service URIs served by another node
+	other := pm.RandAddress()
+	assert.EqualError(pingServiceURI(other, uri), "service URI "+ts.URL+" is served by another node than "+other.Hex())
+}
+
+func TestCheckPublicServiceURI(t *testing.T) {
+	assert := assert.New(t)
+	addr := pm.RandAddress()
+
+	assert.EqualError(checkPublicServiceURI(addr, "http://1.2.3.4:8935"), "service URI http://1.2.3.4:8935 must use https")
+	assert.EqualError(checkPublicServiceURI(addr, "https://1.2.3.4"), "service URI https://1.2.3.4 must include a port")
+	assert.EqualError(checkPublicServiceURI(addr, "https://127.0.0.1:8935"), "service URI https://127.0.0.1:8935 resolves to the non-public address 127.0.0.1")
+	assert.EqualError(checkPublicServiceURI(addr, "https://192.168.1.10:8935"), "service URI https://192.168.1.10:8935 resolves to the non-public address 192.168.1.10")
+	assert.NotNil(checkPublicServiceURI(addr, "foo"))
+
+	assert.True(isPublicIP(gonet.ParseIP("1.2.3.4")))
+	assert.True(isPublicIP(gonet.ParseIP("2001:4860::8888")))
+	for _, ip := range []string{"0.0.0.0", "10.1.2.3", "172.20.0.1", "100.64.0.1", "169.254.1.1", "::1", "fd00::1"} {
+		assert.False(isPublicIP(gonet.ParseIP(ip)), ip)
+	}
+}
+
+func TestCheckServiceURIHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	orch := newStubOrchestrator()
+	ts := stubServiceURIServer(orch)
+	defer ts.Close()
+
+	// The orchestrator is only reachable on loopback
+	defer func(check func(ethcommon.Address, string) error) { checkServiceURI = check }(checkServiceURI)
+	checkServiceURI = func(addr ethcommon.Address, serviceURI string) error {
+		uri, err := url.Parse(serviceURI)
+		require.Nil(err)
+		return pingServiceURI(addr, uri)
+	}
+
+	n, err := core.NewLivepeerNode(nil, "", nil)
+	require.Nil(err)
+	s := &LivepeerServer{LivepeerNode: n}
+
+	status, body := postForm(checkServiceURIHandler(s), url.Values{})
+	assert.Equal(http.StatusInternalServerError, status)
+	assert.Equal("missing ETH client", body)
+
+	client := &eth.MockClient{}
+	client.On("Account").Return(accounts.Account{Address: orch.Address()})
+	n.Eth = client
+
+	status, body = postForm(checkServiceURIHandler(s), url.Values{})
+	assert.Equal(http.StatusBadRequest, status)
+	assert.Equal("missing serviceURI", body)
+
+	// Test the current service URI of the node is checked by default
+	uri, _ := url.Parse(ts.URL)
+	n.SetServiceURI(uri)
+	status, body = postForm(checkServiceURIHandler(s), url.Values{})
+	assert.Equal(http.StatusOK, status)
+	assert.Equal(`{"address":"`+orch.Address().Hex()+`","serviceURI":"`+ts.URL+`"}`, body)
+
+	status, body = postForm(checkServiceURIHandler(s), url.Values{"serviceURI": {"https://127.0.0.1:1"}})
+	assert.Equal(http.StatusBadRequest, status)
+	assert.Contains(body, "is not reachable")
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/core"
+)
+
+var errDraining = errors.New("node is shutting down")
+
+// drainer tracks the segments in flight so that a node shutting down can refuse new
+// work and wait for the work in progress to complete
+type drainer struct {
+	mu sync.Mutex
+	// draining is closed when the node starts draining
+	draining chan struct{}
+	segments int
+	// idle is closed when the last segment in flight is done
+	idle chan struct{}
+}
+
+func newDrainer() *drainer {
+	idle := make(chan struct{})
+	close(idle)
+	return &drainer{draining: make(chan struct{}), idle: idle}
+}
+
+var nodeDrainer = newDrainer()
+
+// Drain makes the node refuse new streams, segments and sessions. Segments in flight
+// are still processed, see WaitSegments
+func Drain() {
+	d := nodeDrainer
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	select {
+	case <-d.draining:
+	default:
+		close(d.draining)
+	}
+}
+
+// Draining returns whether the node is shutting down
+func Draining() bool {
+	select {
+	case <-nodeDrainer.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// startSegment registers a segment in flight and returns the function to call once the
+// segment is done. It returns false if the node is draining and the segment should be refused
+func startSegment() (func(), bool) {
+	d := nodeDrainer
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	select {
+	case <-d.draining:
+		return func() {}, false
+	default:
+	}
+	if d.segments == 0 {
+		d.idle = make(chan struct{})
+	}
+	d.segments++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.segments--
+			if d.segments == 0 {
+				close(d.idle)
+			}
+		})
+	}, true
+}
+
+// WaitSegments waits until the segments in flight are done or ctx is done
+func WaitSegments(ctx context.Context) error {
+	d := nodeDrainer
+	d.mu.Lock()
+	idle := d.idle
+	d.mu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return fmt.Errorf("%d segments still in flight: %v", d.segments, ctx.Err())
+	}
+}
+
+// EndStreams ends the streams of the broadcaster, which cleans up their sessions and
+// object storage
+func (s *LivepeerServer) EndStreams() {
+	s.connectionLock.RLock()
+	mids := make([]core.ManifestID, 0, len(s.rtmpConnections))
+	for mid := range s.rtmpConnections {
+		mids = append(mids, mid)
+	}
+	s.connectionLock.RUnlock()
+
+	for _, mid := range mids {
+		if err := removeRTMPStream(s, mid); err != nil {
+			glog.Errorf("Error ending stream manifestID=%s err=%v", mid, err)
+		}
+	}
+}
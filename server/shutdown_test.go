@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/lpms/stream"
+)
+
+func TestDrain(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	defer func() { nodeDrainer = newDrainer() }()
+
+	// Nothing in flight
+	require.Nil(WaitSegments(context.Background()))
+
+	done, ok := startSegment()
+	require.True(ok)
+	assert.False(Draining())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.EqualError(WaitSegments(ctx), "1 segments still in flight: context deadline exceeded")
+
+	Drain()
+	Drain()
+	assert.True(Draining())
+
+	// New segments are refused while draining
+	_, ok = startSegment()
+	assert.False(ok)
+	_, err := processSegment(&rtmpConnection{}, nil, &stream.HLSSegment{})
+	assert.Equal(errDraining, err)
+
+	// Segments in flight are waited for
+	errCh := make(chan error)
+	go func() { errCh <- WaitSegments(context.Background()) }()
+	done()
+	done()
+	select {
+	case err := <-errCh:
+		assert.Nil(err)
+	case <-time.After(time.Second):
+		t.Fatal("WaitSegments did not return after the segment was done")
+	}
+
+	// New streams and sessions are refused while draining
+	_, err = getOrchestrator(&mockOrchestrator{}, &net.OrchestratorRequest{})
+	assert.Equal(errDraining, err)
+
+	resp := httpPostResp(serveSegmentHandler(&mockOrchestrator{}), nil, nil)
+	defer resp.Body.Close()
+	assert.Equal(http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal("108", resp.Header.Get(errorCodeHeader))
+
+	s := setupServer()
+	defer serverCleanup(s)
+	handler, reader, w := requestSetup(s)
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/live/"+t.Name()+"/0.ts", reader))
+	assert.Equal(http.StatusServiceUnavailable, w.Code)
+
+	u, _ := url.Parse("rtmp://localhost/stream/" + t.Name())
+	assert.Nil(createRTMPStreamIDHandler(s)(u))
+}
+
+func TestEndStreams(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	s := setupServer()
+	defer serverCleanup(s)
+
+	var mids []core.ManifestID
+	for i := 0; i < 2; i++ {
+		mid := core.RandomManifestID()
+		_, err := s.registerConnection(stream.NewBasicRTMPVideoStream(&core.StreamParameters{ManifestID: mid}))
+		require.Nil(err)
+		mids = append(mids, mid)
+	}
+
+	s.EndStreams()
+	s.connectionLock.RLock()
+	defer s.connectionLock.RUnlock()
+	for _, mid := range mids {
+		assert.NotContains(s.rtmpConnections, mid)
+	}
+}
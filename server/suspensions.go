@@ -35,6 +35,10 @@ func (s *suspender) Suspended(orch string) int {
 	if s.list[orch] < s.count {
 		delete(s.list, orch)
 	}
+	if s.list[orch] == 0 && Fleet != nil && Fleet.Suspended(orch) {
+		// Suspended by another broadcaster of the fleet
+		return 1
+	}
 	return s.list[orch]
 }
 
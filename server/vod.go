@@ -0,0 +1,249 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/drivers"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/livepeer/lpms/stream"
+)
+
+// VODHosts are the hosts of the object stores that VOD files can be fetched from with
+// the url query parameter. Files can only be pushed in the body of requests if it is empty.
+var VODHosts []string
+
+// maxVODSize is the maximum size of a VOD file, which is kept in memory while it is segmented
+var maxVODSize int64 = 1 << 30
+
+// vodClient downloads the VOD files that are pushed as the URL of an object store
+var vodClient = &http.Client{
+	Timeout: 10 * time.Minute,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if err := checkVODURL(req.URL); err != nil {
+			return err
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	},
+}
+
+// vodTimeout is how long the renditions of a VOD file can be played from the
+// broadcaster once they are transcoded
+var vodTimeout = time.Hour
+
+type vodResult struct {
+	ManifestID core.ManifestID `json:"manifestID"`
+	// Master playlist of the renditions of the file
+	Playlist string `json:"playlist"`
+	// URLs of the renditions of each segment of the file, in the order of the profiles
+	Segments [][]string `json:"segments"`
+	// URLs of the MP4 files of the renditions, in the order of the profiles, if asked for
+	MP4 []string `json:"mp4,omitempty"`
+}
+
+// HandleVOD transcodes a complete file, pushed as the body of the request or as the
+// URL of an object store in the url query parameter. The file is authenticated like
+// the segments pushed to /live/, split into segments of SegLen, and the segments are
+// transcoded in turn by the orchestrators that live streams select and pay. The
+// renditions are published as HLS playlists that keep all the segments of the file,
+// and as MP4 files if the mp4 query parameter is true.
+func (s *LivepeerServer) HandleVOD(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, "VOD transcoding requires a POST request", http.StatusMethodNotAllowed)
+		return
+	}
+	if Draining() {
+		respondWithError(w, errDraining.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	mp4 := false
+	if v := r.URL.Query().Get("mp4"); v != "" {
+		var err error
+		if mp4, err = strconv.ParseBool(v); err != nil {
+			respondWith400(w, fmt.Sprintf("invalid mp4: %v", err))
+			return
+		}
+	}
+
+	var data []byte
+	var err error
+	if src := r.URL.Query().Get("url"); src != "" {
+		data, err = fetchVOD(src)
+		if err != nil {
+			respondWith400(w, fmt.Sprintf("unable to fetch the file: %v", err))
+			return
+		}
+	} else {
+		data, err = ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxVODSize))
+		if err != nil {
+			respondWithError(w, fmt.Sprintf("Error reading http request body: %v", err), http.StatusRequestEntityTooLarge)
+			return
+		}
+	}
+	if len(data) == 0 {
+		respondWith400(w, "missing file")
+		return
+	}
+	glog.Infof("Got VOD request at url=%s ua=%s addr=%s len=%d", r.URL.String(), r.UserAgent(), r.RemoteAddr, len(data))
+
+	reqURL := &url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path}
+	appData := (createRTMPStreamIDHandler(s))(reqURL)
+	if appData == nil {
+		respondWith500(w, "Could not create stream ID")
+		return
+	}
+	params := streamParams(stream.NewBasicRTMPVideoStream(appData))
+	params.Format = ffmpeg.FormatMPEGTS
+	for i, v := range params.Profiles {
+		if ffmpeg.FormatNone == v.Format {
+			params.Profiles[i].Format = ffmpeg.FormatMPEGTS
+		} else if mp4 && v.Format != ffmpeg.FormatMPEGTS {
+			// the MP4 files are muxed from the segments of the renditions
+			respondWith400(w, "mp4 outputs require MPEG-TS renditions")
+			return
+		}
+	}
+
+	segs, err := core.SegmentVOD(s.LivepeerNode.WorkDir, data, SegLen)
+	if err != nil {
+		respondWith400(w, fmt.Sprintf("unable to segment the file: %v", err))
+		return
+	}
+	params.VODSegments = len(segs)
+
+	res, err := s.transcodeVOD(params, segs, mp4)
+	if err != nil {
+		respondWith500(w, fmt.Sprintf("unable to transcode the file: %v", err))
+		return
+	}
+	respondWithJSON(w, res)
+}
+
+func fetchVOD(src string) ([]byte, error) {
+	u, err := url.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVODURL(u); err != nil {
+		return nil, err
+	}
+	resp, err := vodClient.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v", resp.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxVODSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxVODSize {
+		return nil, fmt.Errorf("file larger than %v bytes", maxVODSize)
+	}
+	return data, nil
+}
+
+// checkVODURL checks that VOD files are only fetched from the object stores of VODHosts
+func checkVODURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	for _, host := range VODHosts {
+		if u.Host == host {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %v is not an allowed object store", u.Host)
+}
+
+// transcodeVOD transcodes the segments of a VOD file as a new stream, and ends its
+// playlists after the last segment. The stream is removed vodTimeout after it is
+// transcoded, or as soon as a segment fails.
+func (s *LivepeerServer) transcodeVOD(params *core.StreamParameters, segs []*stream.HLSSegment, mp4 bool) (*vodResult, error) {
+	cxn, err := s.registerConnection(stream.NewBasicRTMPVideoStream(params))
+	if err != nil {
+		return nil, err
+	}
+	mid := cxn.mid
+	glog.Infof("Transcoding VOD file manifestID=%s segments=%d", mid, len(segs))
+
+	res := &vodResult{ManifestID: mid, Playlist: fmt.Sprintf("/stream/%s.m3u8", mid)}
+	// the whole file is transcoded into the same profiles, even if the profiles of the
+	// stream are changed meanwhile
+	params = cxn.streamParams()
+	// the segments of each rendition, kept to mux the MP4 files
+	renditions := make([][][]byte, len(params.Profiles))
+	for _, seg := range segs {
+		urls, err := processSegment(cxn, params, seg)
+		if err == nil && len(urls) == 0 {
+			// the source of VOD files is not played in place of renditions
+			err = errNoOrchs
+		}
+		if err != nil {
+			removeRTMPStream(s, mid)
+			return nil, err
+		}
+		res.Segments = append(res.Segments, urls)
+		if !mp4 {
+			continue
+		}
+		for i, u := range urls {
+			data, err := renditionData(cxn, u)
+			if err != nil {
+				removeRTMPStream(s, mid)
+				return nil, fmt.Errorf("unable to download rendition %v: %v", u, err)
+			}
+			renditions[i] = append(renditions[i], data)
+		}
+	}
+	cxn.pl.EndHLSPlaylists()
+
+	if mp4 {
+		for i, profile := range params.Profiles {
+			data, err := core.MuxMP4(s.LivepeerNode.WorkDir, renditions[i])
+			if err != nil {
+				removeRTMPStream(s, mid)
+				return nil, err
+			}
+			uri, err := cxn.pl.GetOSSession().SaveData(profile.Name+".mp4", data)
+			if err != nil {
+				removeRTMPStream(s, mid)
+				return nil, err
+			}
+			res.MP4 = append(res.MP4, uri)
+		}
+	}
+
+	s.connectionLock.Lock()
+	cxn.lastUsed = time.Now()
+	s.connectionLock.Unlock()
+	go removeIdleStream(s, mid, vodTimeout)
+
+	return res, nil
+}
+
+// renditionData returns the data of a rendition from the memory of the broadcaster,
+// or downloads it from the object store that the rendition was saved to
+func renditionData(cxn *rtmpConnection, uri string) ([]byte, error) {
+	if memOS, ok := cxn.pl.GetOSSession().(*drivers.MemorySession); ok {
+		if data := memOS.GetData(uri); data != nil {
+			return data, nil
+		}
+	}
+	return drivers.GetSegmentData(uri)
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/lpms/ffmpeg"
+)
+
+// stubFFmpeg stands in for ffmpeg: it splits files into STUB_SEGMENTS segments, two by
+// default, and copies the input of the MP4 files
+const stubFFmpeg = `#!/bin/sh
+while [ $# -gt 1 ]; do
+	case "$1" in
+	-i) in="$2" ;;
+	-hls_segment_filename) segs="$2" ;;
+	esac
+	shift
+done
+if [ -n "$segs" ]; then
+	dir=$(dirname "$1")
+	printf '#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:2\n#EXT-X-MEDIA-SEQUENCE:0\n' > "$1"
+	i=0
+	while [ $i -lt "${STUB_SEGMENTS:-2}" ]; do
+		printf seg$i > "$dir/$i.ts"
+		printf '#EXTINF:2.000,\n%s.ts\n' $i >> "$1"
+		i=$((i+1))
+	done
+	printf '#EXT-X-ENDLIST\n' >> "$1"
+else
+	cat "$in" > "$1"
+fi
+`
+
+func TestHandleVOD(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	s := setupServer()
+	defer serverCleanup(s)
+	defer func() { s.LivepeerNode.OrchestratorPool = nil }()
+
+	tmpdir, err := ioutil.TempDir("", "")
+	require.Nil(err)
+	defer os.RemoveAll(tmpdir)
+	defer func(cmd string) { core.FFmpegCommand = cmd }(core.FFmpegCommand)
+	core.FFmpegCommand = filepath.Join(tmpdir, "ffmpeg")
+	require.Nil(ioutil.WriteFile(core.FFmpegCommand, []byte(stubFFmpeg), 0755))
+	defer func(workDir string) { s.LivepeerNode.WorkDir = workDir }(s.LivepeerNode.WorkDir)
+	s.LivepeerNode.WorkDir = tmpdir
+	oldProfs := BroadcastJobVideoProfiles
+	defer func() { BroadcastJobVideoProfiles = oldProfs }()
+	BroadcastJobVideoProfiles = []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9}
+	defer SetAuthWebhookURL(AuthWebhookURL())
+	SetAuthWebhookURL("")
+
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	ts, mux := stubTLSServer()
+	defer ts.Close()
+	mux.HandleFunc("/segment", func(w http.ResponseWriter, r *http.Request) {
+		data, err := base64.StdEncoding.DecodeString(r.Header.Get(segmentHeader))
+		require.Nil(err)
+		var segData net.SegData
+		require.Nil(proto.Unmarshal(data, &segData))
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, string(body))
+		mu.Unlock()
+		buf, err := proto.Marshal(&net.TranscodeResult{
+			Result: &net.TranscodeResult_Data{Data: &net.TranscodeData{Segments: []*net.TranscodedSegmentData{{Url: ts.URL + "/rendition/" + string(body)}}}},
+		})
+		require.Nil(err)
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf)
+	})
+	mux.HandleFunc("/rendition/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.TrimPrefix(r.URL.Path, "/rendition/") + "@144p"))
+	})
+	s.LivepeerNode.OrchestratorPool = &stubDiscovery{infos: []*net.OrchestratorInfo{{Transcoder: ts.URL}}}
+
+	post := func(target string, body []byte) (int, string) {
+		req := httptest.NewRequest("POST", target, bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		s.HandleVOD(w, req)
+		return w.Code, strings.TrimSpace(w.Body.String())
+	}
+
+	code, body := post("http://example.com/vod/movie.mp4?mp4=true", []byte("file"))
+	require.Equal(http.StatusOK, code, body)
+	var res vodResult
+	require.Nil(json.Unmarshal([]byte(body), &res))
+	assert.Equal(core.ManifestID("movie"), res.ManifestID)
+	assert.Equal("/stream/movie.m3u8", res.Playlist)
+	assert.Len(res.Segments, 2)
+	assert.Equal([]string{"seg0", "seg1"}, received)
+
+	// the playlists keep all the segments of the file, and end with its last segment
+	s.connectionLock.RLock()
+	cxn, ok := s.rtmpConnections["movie"]
+	s.connectionLock.RUnlock()
+	require.True(ok)
+	mpl := cxn.pl.GetHLSMediaPlaylist("P144p30fps16x9")
+	require.NotNil(mpl)
+	assert.Equal(uint(2), mpl.Count())
+	assert.Contains(mpl.String(), "#EXT-X-ENDLIST")
+
+	// the MP4 files are muxed from the segments of the renditions
+	require.Len(res.MP4, 1)
+	assert.Equal("/stream/movie/P144p30fps16x9.mp4", res.MP4[0])
+	mp4URL, err := url.Parse(res.MP4[0])
+	require.Nil(err)
+	mp4, err := getHLSSegmentHandler(s)(mp4URL)
+	require.Nil(err)
+	assert.Equal("seg0@144pseg1@144p", string(mp4))
+
+	// files are also fetched from object stores
+	store := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/movie.mp4" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("file"))
+	}))
+	defer store.Close()
+	storeURL, err := url.Parse(store.URL)
+	require.Nil(err)
+	code, body = post("http://example.com/vod/stored.mp4?url="+store.URL+"/movie.mp4", nil)
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal(fmt.Sprintf("unable to fetch the file: host %v is not an allowed object store", storeURL.Host), body)
+	defer func(hosts []string) { VODHosts = hosts }(VODHosts)
+	VODHosts = []string{storeURL.Host}
+	code, body = post("http://example.com/vod/stored.mp4?url="+store.URL+"/movie.mp4", nil)
+	assert.Equal(http.StatusOK, code, body)
+	assert.NotContains(body, "mp4\":")
+	code, body = post("http://example.com/vod/missing.mp4?url="+store.URL+"/missing.mp4", nil)
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal("unable to fetch the file: unexpected status 404 Not Found", body)
+	code, body = post("http://example.com/vod/local.mp4?url=file:///etc/passwd", nil)
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal(`unable to fetch the file: unsupported scheme "file"`, body)
+
+	// files larger than the max size are refused
+	defer func(size int64) { maxVODSize = size }(maxVODSize)
+	maxVODSize = 3
+	code, body = post("http://example.com/vod/large.mp4?url="+store.URL+"/movie.mp4", nil)
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal("unable to fetch the file: file larger than 3 bytes", body)
+	code, body = post("http://example.com/vod/large.mp4", []byte("file"))
+	assert.Equal(http.StatusRequestEntityTooLarge, code)
+	assert.Contains(body, "request body too large")
+	maxVODSize = 1 << 30
+
+	// the renditions of all the segments of long files are kept in memory
+	defer os.Unsetenv("STUB_SEGMENTS")
+	os.Setenv("STUB_SEGMENTS", "15")
+	received = nil
+	code, body = post("http://example.com/vod/long.mp4", []byte("file"))
+	require.Equal(http.StatusOK, code, body)
+	res = vodResult{}
+	require.Nil(json.Unmarshal([]byte(body), &res))
+	require.Len(res.Segments, 15)
+	for i, urls := range res.Segments {
+		require.Len(urls, 1)
+		segURL, err := url.Parse(urls[0])
+		require.Nil(err)
+		data, err := getHLSSegmentHandler(s)(segURL)
+		require.Nil(err)
+		assert.Equal(fmt.Sprintf("seg%d@144p", i), string(data))
+	}
+	os.Unsetenv("STUB_SEGMENTS")
+
+	code, body = post("http://example.com/vod/empty.mp4", nil)
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal("missing file", body)
+	code, body = post("http://example.com/vod/movie.mp4?mp4=maybe", []byte("file"))
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Contains(body, "invalid mp4")
+
+	// the renditions of VOD files are not published without orchestrators
+	s.LivepeerNode.OrchestratorPool = &stubDiscovery{}
+	code, body = post("http://example.com/vod/noorchs.mp4", []byte("file"))
+	assert.Equal(http.StatusInternalServerError, code)
+	assert.Equal("unable to transcode the file: ErrNoOrchs", body)
+	s.connectionLock.RLock()
+	_, ok = s.rtmpConnections["noorchs"]
+	s.connectionLock.RUnlock()
+	assert.False(ok)
+
+	core.FFmpegCommand = "false"
+	code, body = post("http://example.com/vod/invalid.mp4", []byte("file"))
+	assert.Equal(http.StatusBadRequest, code)
+	assert.Equal("unable to segment the file: segment: exit status 1", body)
+
+	req := httptest.NewRequest("GET", "http://example.com/vod/movie.mp4", nil)
+	w := httptest.NewRecorder()
+	s.HandleVOD(w, req)
+	assert.Equal(http.StatusMethodNotAllowed, w.Code)
+}
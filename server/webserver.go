@@ -13,13 +13,11 @@ import (
 	"strings"
 	"time"
 
-	// pprof adds handlers to default mux via `init()`
-	_ "net/http/pprof"
-
 	"github.com/cenkalti/backoff"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/audit"
 	lpcommon "github.com/livepeer/go-livepeer/common"
 	"github.com/livepeer/go-livepeer/core"
 	"github.com/livepeer/go-livepeer/eth"
@@ -31,6 +29,14 @@ import (
 
 var vFlag *glog.Level = flag.Lookup("v").Value.(*glog.Level)
 
+// Reload reloads the settings of the node that can be changed without a restart and
+// returns the names of the flags that changed, if set
+var Reload func() ([]string, error)
+
+// ConfigSnapshot returns the effective configuration of the node as a config file,
+// without secrets, if set
+var ConfigSnapshot func() ([]byte, error)
+
 func (s *LivepeerServer) setServiceURI(serviceURI string) error {
 
 	parsedURI, err := url.Parse(serviceURI)
@@ -63,12 +69,21 @@ func (s *LivepeerServer) setServiceURI(serviceURI string) error {
 // StartCliWebserver starts web server for CLI
 // blocks until exit
 func (s *LivepeerServer) StartCliWebserver(bindAddr string) {
-	mux := s.cliWebServerHandlers(bindAddr)
+	var handler http.Handler = s.cliWebServerHandlers(bindAddr)
+	if CliToken != "" {
+		handler = cliAuth(CliToken, handler)
+	}
 	srv := &http.Server{
-		Addr:    bindAddr,
-		Handler: mux,
+		Addr:      bindAddr,
+		Handler:   monitor.ReportPanics(handler),
+		TLSConfig: CliTLS,
 	}
 
+	if CliTLS != nil {
+		glog.Info("CLI server listening on https://", bindAddr)
+		glog.Error(srv.ListenAndServeTLS("", ""))
+		return
+	}
 	glog.Info("CLI server listening on ", bindAddr)
 	srv.ListenAndServe()
 }
@@ -76,12 +91,17 @@ func (s *LivepeerServer) StartCliWebserver(bindAddr string) {
 func (s *LivepeerServer) setOnChainConfig() {}
 
 func (s *LivepeerServer) cliWebServerHandlers(bindAddr string) *http.ServeMux {
-	// Override default mux because pprof only uses the default mux
-	// We really don't want to accidentally pull pprof into other listeners.
-	// Pprof, like the CLI, is a strictly private API!
-	mux := http.DefaultServeMux
+	// pprof and expvar add handlers to the default mux via `init()`.
+	// Reset the default mux because we really don't want to accidentally pull
+	// them into other listeners. Diagnostics, like the CLI, are a strictly private API
+	// and are only registered on the CLI mux when a token is configured.
+	mux := http.NewServeMux()
 	http.DefaultServeMux = http.NewServeMux()
 
+	if DiagnosticsToken != "" {
+		s.registerDiagnosticsHandlers(mux, DiagnosticsToken)
+	}
+
 	mux.Handle("/signMessage", mustHaveFormParams(signMessageHandler(s.LivepeerNode.Eth), "message"))
 
 	mux.Handle("/vote", mustHaveFormParams(voteHandler(s.LivepeerNode.Eth), "poll", "choiceID"))
@@ -127,6 +147,11 @@ func (s *LivepeerServer) cliWebServerHandlers(bindAddr string) *http.ServeMux {
 			}
 
 			BroadcastCfg.SetMaxPrice(price)
+			maxPrice := "0"
+			if price != nil {
+				maxPrice = price.RatString()
+			}
+			s.LivepeerNode.AuditLog.Append(audit.EventMaxPriceChanged, map[string]string{"maxPricePerPixel": maxPrice})
 
 			glog.Infof("Maximum transcoding price: %d per %q pixels\n", pr, px)
 		}
@@ -151,6 +176,9 @@ func (s *LivepeerServer) cliWebServerHandlers(bindAddr string) *http.ServeMux {
 		}
 	})
 
+	mux.Handle("/setStreamProfiles", mustHaveFormParams(setStreamProfilesHandler(s), "manifestID"))
+	mux.Handle("/clip", mustHaveFormParams(clipHandler(s), "url", "start", "end"))
+
 	mux.HandleFunc("/getBroadcastConfig", func(w http.ResponseWriter, r *http.Request) {
 		pNames := []string{}
 		for _, p := range BroadcastJobVideoProfiles {
@@ -283,6 +311,10 @@ func (s *LivepeerServer) cliWebServerHandlers(bindAddr string) *http.ServeMux {
 			respondWith400(w, err.Error())
 			return
 		}
+		if err := validateServiceURI(r, s.LivepeerNode.Eth, serviceURI); err != nil {
+			respondWith400(w, err.Error())
+			return
+		}
 
 		unbondingLockIDStr := r.FormValue("unbondingLockId")
 		if unbondingLockIDStr != "" {
@@ -363,6 +395,11 @@ func (s *LivepeerServer) cliWebServerHandlers(bindAddr string) *http.ServeMux {
 		w.Write([]byte("success"))
 	})
 
+	// Onboarding
+	mux.Handle("/ensureDeposit", mustHaveFormParams(ensureDepositHandler(s.LivepeerNode.Eth), "depositAmount", "reserveAmount"))
+	mux.Handle("/ensureOrchestrator", mustHaveFormParams(ensureOrchestratorHandler(s), "blockRewardCut", "feeShare", "pricePerUnit", "pixelsPerUnit", "serviceURI"))
+	mux.Handle("/checkServiceURI", checkServiceURIHandler(s))
+
 	//Set transcoder config on-chain.
 	mux.HandleFunc("/setOrchestratorConfig", func(w http.ResponseWriter, r *http.Request) {
 		if err := r.ParseForm(); err != nil {
@@ -417,6 +454,23 @@ func (s *LivepeerServer) cliWebServerHandlers(bindAddr string) *http.ServeMux {
 			return
 		}
 
+		// The service URI is checked before any transaction is sent
+		serviceURI := r.FormValue("serviceURI")
+		if serviceURI != "" {
+			if _, err := url.ParseRequestURI(serviceURI); err != nil {
+				glog.Error(err)
+				respondWith400(w, err.Error())
+				return
+			}
+			if t.ServiceURI != serviceURI {
+				if err := validateServiceURI(r, s.LivepeerNode.Eth, serviceURI); err != nil {
+					glog.Error(err)
+					respondWith400(w, err.Error())
+					return
+				}
+			}
+		}
+
 		if feeShareStr != "" && blockRewardCutStr != "" && (t.RewardCut.Cmp(eth.FromPerc(blockRewardCut)) != 0 || t.FeeShare.Cmp(eth.FromPerc(feeShare)) != 0) {
 			tx, err := s.LivepeerNode.Eth.Transcoder(eth.FromPerc(blockRewardCut), eth.FromPerc(feeShare))
 			if err != nil {
@@ -435,21 +489,12 @@ func (s *LivepeerServer) cliWebServerHandlers(bindAddr string) *http.ServeMux {
 			}
 		}
 
-		serviceURI := r.FormValue("serviceURI")
-		if serviceURI != "" {
-			if _, err := url.ParseRequestURI(serviceURI); err != nil {
+		if serviceURI != "" && t.ServiceURI != serviceURI {
+			if err := s.setServiceURI(serviceURI); err != nil {
 				glog.Error(err)
-				respondWith400(w, err.Error())
+				respondWith500(w, err.Error())
 				return
 			}
-
-			if t.ServiceURI != serviceURI {
-				if err := s.setServiceURI(serviceURI); err != nil {
-					glog.Error(err)
-					respondWith500(w, err.Error())
-					return
-				}
-			}
 		}
 
 	})
@@ -581,11 +626,13 @@ func (s *LivepeerServer) cliWebServerHandlers(bindAddr string) *http.ServeMux {
 			}
 			tx, err := s.LivepeerNode.Eth.WithdrawStake(unbondingLockID)
 			if err != nil {
+				auditWithdrawal(s.LivepeerNode.AuditLog, "stake", nil, err)
 				glog.Error(err)
 				return
 			}
 
 			err = s.LivepeerNode.Eth.CheckTx(tx)
+			auditWithdrawal(s.LivepeerNode.AuditLog, "stake", tx, err)
 			if err != nil {
 				glog.Error(err)
 				return
@@ -593,6 +640,9 @@ func (s *LivepeerServer) cliWebServerHandlers(bindAddr string) *http.ServeMux {
 		}
 	})
 
+	mux.Handle("/moveStake", mustHaveFormParams(moveStakeHandler(s.LivepeerNode.Eth), "toAddr"))
+	mux.Handle("/previewStake", mustHaveFormParams(previewStakeHandler(s.LivepeerNode.Eth), "action"))
+
 	mux.HandleFunc("/unbondingLocks", func(w http.ResponseWriter, r *http.Request) {
 		if s.LivepeerNode.Database != nil {
 			if err := r.ParseForm(); err != nil {
@@ -678,11 +728,13 @@ func (s *LivepeerServer) cliWebServerHandlers(bindAddr string) *http.ServeMux {
 		if s.LivepeerNode.Eth != nil {
 			tx, err := s.LivepeerNode.Eth.WithdrawFees()
 			if err != nil {
+				auditWithdrawal(s.LivepeerNode.AuditLog, "fees", nil, err)
 				glog.Error(err)
 				return
 			}
 
 			err = s.LivepeerNode.Eth.CheckTx(tx)
+			auditWithdrawal(s.LivepeerNode.AuditLog, "fees", tx, err)
 			if err != nil {
 				glog.Error(err)
 				return
@@ -1191,10 +1243,66 @@ func (s *LivepeerServer) cliWebServerHandlers(bindAddr string) *http.ServeMux {
 	mux.Handle("/fundDeposit", mustHaveFormParams(fundDepositHandler(s.LivepeerNode.Eth), "amount"))
 	mux.Handle("/unlock", unlockHandler(s.LivepeerNode.Eth))
 	mux.Handle("/cancelUnlock", cancelUnlockHandler(s.LivepeerNode.Eth))
-	mux.Handle("/withdraw", withdrawHandler(s.LivepeerNode.Eth))
+	mux.Handle("/withdraw", withdrawHandler(s.LivepeerNode.Eth, s.LivepeerNode.AuditLog))
 	mux.Handle("/senderInfo", senderInfoHandler(s.LivepeerNode.Eth))
 	mux.Handle("/ticketBrokerParams", ticketBrokerParamsHandler(s.LivepeerNode.Eth))
 
+	// Orchestrator analytics
+	mux.Handle("/senderStats", senderStatsHandler(s.LivepeerNode.SenderStats))
+	mux.Handle("/senderBlacklist", senderBlacklistHandler(s.LivepeerNode.SenderReputation))
+	mux.Handle("/clearSenderBlacklist", mustHaveFormParams(clearSenderBlacklistHandler(s.LivepeerNode.SenderReputation), "sender"))
+
+	// Winning ticket export and import
+	mux.Handle("/exportTickets", exportTicketsHandler(s.LivepeerNode.Recipient))
+	mux.Handle("/importTickets", importTicketsHandler(s.LivepeerNode.Recipient))
+	mux.Handle("/confirmExportedTickets", confirmExportedTicketsHandler(s.LivepeerNode.Recipient))
+
+	// Bandwidth accounting
+	mux.Handle("/bandwidth", bandwidthHandler(s.LivepeerNode.Bandwidth))
+
+	// Payment audit log
+	mux.Handle("/auditLog", auditLogHandler(s.LivepeerNode.AuditLog))
+	mux.Handle("/verifyAuditLog", verifyAuditLogHandler(s.LivepeerNode.AuditLog))
+
+	// Outbound webhook deliveries
+	mux.Handle("/webhooks", webhooksHandler(Webhooks))
+	mux.Handle("/replayWebhook", mustHaveFormParams(replayWebhookHandler(Webhooks), "id"))
+	mux.Handle("/discardWebhook", mustHaveFormParams(discardWebhookHandler(Webhooks), "id"))
+
+	// Self-test canary
+	mux.Handle("/canary", canaryHandler(s.Canary))
+
+	// Remote transcoders
+	mux.Handle("/transcoderPool", transcoderPoolHandler(s.LivepeerNode.TranscoderManager))
+
+	// Hot reload of settings
+	mux.Handle("/reload", reloadHandler(Reload))
+	mux.Handle("/configSnapshot", configSnapshotHandler(ConfigSnapshot))
+	mux.Handle("/ready", readyHandler(s))
+	mux.Handle("/features", featuresHandler())
+	mux.Handle("/setFeature", mustHaveFormParams(setFeatureHandler(), "name", "enabled"))
+	mux.Handle("/coordinator", coordinatorHandler(fleetStatusHandler))
+	mux.Handle("/coordinator/claim", coordinatorHandler(fleetClaimHandler))
+	mux.Handle("/coordinator/release", coordinatorHandler(fleetReleaseHandler))
+	mux.Handle("/coordinator/heartbeat", coordinatorHandler(fleetHeartbeatHandler))
+
+	// Verification
+	mux.Handle("/verifierStatus", verifierStatusHandler(Policy))
+	mux.Handle("/verificationResults", verificationResultsHandler(s.LivepeerNode.Database))
+	mux.Handle("/pixelChecks", pixelChecksHandler(PixelChecker))
+	mux.Handle("/transcodeReceipts", transcodeReceiptsHandler(s.LivepeerNode.Database))
+
+	// Accounting
+	mux.Handle("/earnings", earningsHandler(s.LivepeerNode.Database))
+
+	// Offline signing
+	mux.Handle("/unsignedTransactions", unsignedTxsHandler(s.LivepeerNode.Eth, s.LivepeerNode.OfflineSigner))
+	mux.Handle("/sendSignedTransaction", mustHaveFormParams(sendSignedTxHandler(s.LivepeerNode.Eth, s.LivepeerNode.OfflineSigner), "tx"))
+	mux.Handle("/discardUnsignedTransaction", mustHaveFormParams(discardUnsignedTxHandler(s.LivepeerNode.OfflineSigner), "id"))
+
+	// Versioned management API, implemented by the endpoints above
+	mux.Handle(apiPrefix+"/", s.apiHandler(mux))
+
 	// Metrics
 	if monitor.Enabled {
 		mux.Handle("/metrics", monitor.Exporter)
@@ -65,6 +65,9 @@ type epicResults struct {
 
 type EpicClassifier struct {
 	Addr string
+
+	// Client used for requests to the classifier, http.DefaultClient if nil
+	Client *http.Client
 }
 
 func epicResultsToVerificationResults(er *epicResults) (*Results, error) {
@@ -148,7 +151,11 @@ func (e *EpicClassifier) Verify(params *Params) (*Results, error) {
 
 	// Submit request and process results
 	startTime := time.Now()
-	resp, err := http.Post(e.Addr, "application/json", bytes.NewBuffer(reqData))
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(e.Addr, "application/json", bytes.NewBuffer(reqData))
 	if err != nil {
 		glog.Error("Could not submit request ", err)
 		return nil, err
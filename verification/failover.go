@@ -0,0 +1,171 @@
+package verification
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+var ErrNoHealthyVerifier = errors.New("NoHealthyVerifier")
+
+// FailoverOptions configures a FailoverVerifier
+type FailoverOptions struct {
+	// Timeout of a single request to a verifier
+	Timeout time.Duration
+	// Retries of a request to a verifier before failing over to the next verifier
+	Retries int
+	// HealthCheckInterval is how often unhealthy verifiers are checked for recovery
+	HealthCheckInterval time.Duration
+}
+
+// VerifierStatus is the health of a verifier of a FailoverVerifier
+type VerifierStatus struct {
+	Addr      string
+	Healthy   bool
+	LastError string `json:",omitempty"`
+	Since     time.Time
+}
+
+type verifierEndpoint struct {
+	verifier Verifier
+	status   VerifierStatus
+}
+
+// FailoverVerifier verifies segments with the first healthy verifier of a list of Epic
+// classifier endpoints. A verifier that fails to respond is marked as unhealthy and the
+// next one is tried. Unhealthy verifiers are checked periodically and used again once
+// they respond
+type FailoverVerifier struct {
+	client   *http.Client
+	retries  int
+	interval time.Duration
+
+	mu        sync.RWMutex
+	endpoints []*verifierEndpoint
+}
+
+func NewFailoverVerifier(addrs []string, opts FailoverOptions) *FailoverVerifier {
+	client := &http.Client{Timeout: opts.Timeout}
+	fv := &FailoverVerifier{client: client, retries: opts.Retries, interval: opts.HealthCheckInterval}
+	now := time.Now()
+	for _, addr := range addrs {
+		fv.endpoints = append(fv.endpoints, &verifierEndpoint{
+			verifier: &EpicClassifier{Addr: addr, Client: client},
+			status:   VerifierStatus{Addr: addr, Healthy: true, Since: now},
+		})
+	}
+	return fv
+}
+
+func (fv *FailoverVerifier) Verify(params *Params) (*Results, error) {
+	err := ErrNoHealthyVerifier
+	for i, ep := range fv.endpoints {
+		if !fv.healthy(i) {
+			continue
+		}
+		for attempt := 0; attempt <= fv.retries; attempt++ {
+			var res *Results
+			res, err = ep.verifier.Verify(params)
+			if !isVerifierFailure(err) {
+				return res, err
+			}
+			glog.Errorf("Verifier request failed addr=%s attempt=%d err=%v", ep.status.Addr, attempt, err)
+		}
+		fv.setHealth(i, err)
+	}
+	glog.Errorf("No verifier available manifestID=%s err=%v", params.ManifestID, err)
+	return nil, err
+}
+
+// isVerifierFailure returns whether err means that the verifier did not respond,
+// as opposed to a verification result
+func isVerifierFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var urlErr *url.Error
+	return err == ErrVerifierStatus || errors.As(err, &urlErr)
+}
+
+func (fv *FailoverVerifier) healthy(i int) bool {
+	fv.mu.RLock()
+	defer fv.mu.RUnlock()
+
+	return fv.endpoints[i].status.Healthy
+}
+
+// setHealth marks a verifier as healthy if err is nil, and as unhealthy otherwise
+func (fv *FailoverVerifier) setHealth(i int, err error) {
+	fv.mu.Lock()
+	defer fv.mu.Unlock()
+
+	status := &fv.endpoints[i].status
+	healthy := err == nil
+	if status.Healthy != healthy {
+		status.Since = time.Now()
+		if healthy {
+			glog.Infof("Verifier is healthy addr=%s", status.Addr)
+		} else {
+			glog.Errorf("Verifier is unhealthy addr=%s err=%v", status.Addr, err)
+		}
+	}
+	status.Healthy = healthy
+	status.LastError = ""
+	if err != nil {
+		status.LastError = err.Error()
+	}
+}
+
+// Status returns the health of all verifiers
+func (fv *FailoverVerifier) Status() []VerifierStatus {
+	fv.mu.RLock()
+	defer fv.mu.RUnlock()
+
+	res := make([]VerifierStatus, len(fv.endpoints))
+	for i, ep := range fv.endpoints {
+		res[i] = ep.status
+	}
+	return res
+}
+
+// StartHealthChecks checks unhealthy verifiers every health check interval until the context is done
+func (fv *FailoverVerifier) StartHealthChecks(ctx context.Context) {
+	ticker := time.NewTicker(fv.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fv.checkHealth()
+		}
+	}
+}
+
+func (fv *FailoverVerifier) checkHealth() {
+	for i, ep := range fv.endpoints {
+		if fv.healthy(i) {
+			continue
+		}
+		fv.setHealth(i, fv.ping(ep.status.Addr))
+	}
+}
+
+// ping checks that the verifier responds. The verifier only serves verification
+// requests, so any response that is not a server error means it is up
+func (fv *FailoverVerifier) ping(addr string) error {
+	resp, err := fv.client.Get(addr)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return ErrVerifierStatus
+	}
+	return nil
+}
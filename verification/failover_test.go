@@ -0,0 +1,124 @@
+package verification
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livepeer/go-livepeer/drivers"
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/lpms/stream"
+)
+
+func TestFailoverVerifier_Verify(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Use external S3 bucket
+	drivers.S3BUCKET = "livepeer"
+	defer func() { drivers.S3BUCKET = "" }()
+
+	ts, mux := stubVerificationServer()
+	defer ts.Close()
+	var down, up, slow int32
+	var downUp, upUp int32 = 1, 1
+	mux.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&down, 1)
+		if r.Method == "GET" && atomic.LoadInt32(&downUp) == 1 {
+			// Responds to health checks once the verifier is back
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&slow, 1)
+		time.Sleep(200 * time.Millisecond)
+	})
+	mux.HandleFunc("/up", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&up, 1)
+		if atomic.LoadInt32(&upUp) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		buf, err := json.Marshal(&epicResults{
+			Results: []epicResultFields{
+				{VideoAvailable: true, Tamper: 1, OCSVMDist: -1.0},
+			},
+		})
+		assert.Nil(err)
+		w.Write(buf)
+	})
+
+	params := &Params{
+		Source:       &stream.HLSSegment{SeqNo: 73},
+		Results:      &net.TranscodeData{},
+		Orchestrator: &net.OrchestratorInfo{Transcoder: "pretend"},
+	}
+	fv := NewFailoverVerifier([]string{ts.URL + "/down", ts.URL + "/slow", ts.URL + "/up"}, FailoverOptions{
+		Timeout:             50 * time.Millisecond,
+		Retries:             1,
+		HealthCheckInterval: time.Hour,
+	})
+
+	// Fails over to the healthy verifier, after retrying the others
+	_, err := fv.Verify(params)
+	assert.Equal(ErrTampered, err)
+	assert.Equal(int32(2), atomic.LoadInt32(&down))
+	assert.Equal(int32(2), atomic.LoadInt32(&slow))
+	assert.Equal(int32(1), atomic.LoadInt32(&up))
+
+	status := fv.Status()
+	require.Len(status, 3)
+	assert.False(status[0].Healthy)
+	assert.Equal(ErrVerifierStatus.Error(), status[0].LastError)
+	assert.False(status[1].Healthy)
+	assert.Contains(status[1].LastError, "Client.Timeout")
+	assert.True(status[2].Healthy)
+
+	// Unhealthy verifiers are skipped
+	_, err = fv.Verify(params)
+	assert.Equal(ErrTampered, err)
+	assert.Equal(int32(2), atomic.LoadInt32(&down))
+	assert.Equal(int32(2), atomic.LoadInt32(&slow))
+	assert.Equal(int32(2), atomic.LoadInt32(&up))
+
+	// Health checks restore verifiers that respond
+	fv.checkHealth()
+	status = fv.Status()
+	assert.True(status[0].Healthy)
+	assert.Empty(status[0].LastError)
+	assert.False(status[1].Healthy)
+
+	// The verifier is down again, and so is the last one
+	atomic.StoreInt32(&downUp, 0)
+	atomic.StoreInt32(&upUp, 0)
+	_, err = fv.Verify(params)
+	assert.Equal(ErrVerifierStatus, err)
+	for _, s := range fv.Status() {
+		assert.False(s.Healthy)
+	}
+
+	// Without healthy verifiers, verification fails immediately
+	_, err = fv.Verify(params)
+	assert.Equal(ErrNoHealthyVerifier, err)
+	fv.checkHealth()
+	_, err = fv.Verify(params)
+	assert.Equal(ErrNoHealthyVerifier, err)
+}
+
+func TestFailoverVerifier_VerificationResults(t *testing.T) {
+	assert := assert.New(t)
+
+	// Verification results are not verifier failures
+	assert.False(isVerifierFailure(nil))
+	assert.False(isVerifierFailure(ErrTampered))
+	assert.False(isVerifierFailure(ErrAudioMismatch))
+	assert.False(isVerifierFailure(ErrVideoUnavailable))
+	assert.True(isVerifierFailure(ErrVerifierStatus))
+}
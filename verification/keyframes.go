@@ -0,0 +1,148 @@
+package verification
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/lpms/ffmpeg"
+)
+
+var ErrKeyframeMisaligned = Retryable{errors.New("KeyframeMisaligned")}
+
+const (
+	tsPacketSize = 188
+	tsClockRate  = 90000
+
+	// Maximum difference between the timestamps of the matching keyframes of two
+	// renditions, and between a GOP and the GOP of the profiles, in 90kHz ticks.
+	// Renditions at different frame rates place keyframes up to a frame apart.
+	maxKeyframeOffset = tsClockRate / 20
+)
+
+// CheckKeyframes checks that the renditions of a segment are aligned for adaptive
+// bitrate switching: every rendition starts with a keyframe, the renditions place
+// their keyframes at the same timestamps, and no GOP is longer than gop. Only the
+// MPEG-TS renditions with video are checked.
+func CheckKeyframes(params *Params, gop time.Duration) error {
+	var ref []int64
+	refName := ""
+	maxGOP := int64(gop.Seconds()*tsClockRate) + maxKeyframeOffset
+	for i, data := range params.Renditions {
+		if i >= len(params.Profiles) {
+			break
+		}
+		profile := params.Profiles[i]
+		if profile.Format != ffmpeg.FormatMPEGTS && profile.Format != ffmpeg.FormatNone {
+			continue
+		}
+		video, ok := tsKeyframes(data)
+		if !ok {
+			continue
+		}
+		if !video.startsWithKey {
+			glog.Errorf("Rendition does not start with a keyframe manifestID=%s profile=%s", params.ManifestID, profile.Name)
+			return ErrKeyframeMisaligned
+		}
+		keyframes := video.keyframes
+		// the last GOP runs until the last frame
+		for j, end := range append(keyframes[1:], video.last) {
+			if gop := end - keyframes[j]; gop > maxGOP {
+				glog.Errorf("Rendition GOP is too long manifestID=%s profile=%s gop=%v",
+					params.ManifestID, profile.Name, time.Duration(gop*int64(time.Second)/tsClockRate))
+				return ErrKeyframeMisaligned
+			}
+		}
+		if ref == nil {
+			ref, refName = keyframes, profile.Name
+			continue
+		}
+		if !keyframesAligned(ref, keyframes) {
+			glog.Errorf("Rendition keyframes are not aligned manifestID=%s profile=%s keyframes=%d reference=%s keyframes=%d",
+				params.ManifestID, profile.Name, len(keyframes), refName, len(ref))
+			return ErrKeyframeMisaligned
+		}
+	}
+	return nil
+}
+
+func keyframesAligned(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if d := a[i] - b[i]; d > maxKeyframeOffset || d < -maxKeyframeOffset {
+			return false
+		}
+	}
+	return true
+}
+
+// tsVideo are the timestamps of the video of an MPEG-TS segment, relative to its
+// first video frame
+type tsVideo struct {
+	keyframes     []int64
+	last          int64
+	startsWithKey bool
+}
+
+// tsKeyframes returns the timestamps of the keyframes and of the last frame of the
+// video of an MPEG-TS segment. Keyframes are the PES packets that the muxer flagged
+// as random access points. It returns false if the segment isn't MPEG-TS or has no
+// video.
+func tsKeyframes(data []byte) (*tsVideo, bool) {
+	if len(data) < tsPacketSize || data[0] != 0x47 {
+		return nil, false
+	}
+	var (
+		video    tsVideo
+		first    int64
+		found    bool
+		videoPID = -1
+	)
+	for off := 0; off+tsPacketSize <= len(data); off += tsPacketSize {
+		pkt := data[off : off+tsPacketSize]
+		if pkt[0] != 0x47 {
+			return nil, false
+		}
+		pid := int(pkt[1]&0x1f)<<8 | int(pkt[2])
+		start := pkt[1]&0x40 != 0
+		if !start || (videoPID >= 0 && pid != videoPID) {
+			continue
+		}
+		payload := 4
+		randomAccess := false
+		if pkt[3]&0x20 != 0 {
+			afLen := int(pkt[4])
+			if afLen > 0 {
+				randomAccess = pkt[5]&0x40 != 0
+			}
+			payload += 1 + afLen
+		}
+		if pkt[3]&0x10 == 0 || payload+14 > tsPacketSize {
+			continue
+		}
+		pes := pkt[payload:]
+		// PES start code, then a video stream id
+		if pes[0] != 0 || pes[1] != 0 || pes[2] != 1 || pes[3]&0xf0 != 0xe0 {
+			continue
+		}
+		if pes[7]&0x80 == 0 {
+			// no PTS
+			continue
+		}
+		videoPID = pid
+		pts := int64(pes[9]&0x0e)<<29 | int64(pes[10])<<22 | int64(pes[11]&0xfe)<<14 | int64(pes[12])<<7 | int64(pes[13])>>1
+		if !found {
+			first, found, video.startsWithKey = pts, true, randomAccess
+		}
+		if randomAccess {
+			video.keyframes = append(video.keyframes, pts-first)
+		}
+		if pts-first > video.last {
+			video.last = pts - first
+		}
+	}
+	return &video, found
+}
@@ -0,0 +1,118 @@
+package verification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/livepeer/lpms/ffmpeg"
+)
+
+type tsFrame struct {
+	pts int64
+	key bool
+}
+
+// tsSegment muxes the PES headers of video frames into MPEG-TS packets, with an
+// audio frame flagged as a random access point ahead of every video frame
+func tsSegment(frames ...tsFrame) []byte {
+	var data []byte
+	pes := func(pid int, streamID byte, pts int64, key bool) []byte {
+		pkt := make([]byte, tsPacketSize)
+		for i := range pkt {
+			pkt[i] = 0xff
+		}
+		pkt[0], pkt[1], pkt[2] = 0x47, 0x40|byte(pid>>8), byte(pid)
+		pkt[3] = 0x30
+		pkt[4] = 1
+		pkt[5] = 0
+		if key {
+			pkt[5] = 0x40
+		}
+		h := pkt[6:]
+		h[0], h[1], h[2], h[3] = 0, 0, 1, streamID
+		h[4], h[5], h[6], h[7], h[8] = 0, 0, 0x80, 0x80, 5
+		h[9] = 0x21 | byte(pts>>29)&0x0e
+		h[10] = byte(pts >> 22)
+		h[11] = byte(pts>>14) | 1
+		h[12] = byte(pts >> 7)
+		h[13] = byte(pts<<1) | 1
+		return pkt
+	}
+	for _, f := range frames {
+		data = append(data, pes(0x101, 0xc0, f.pts, true)...)
+		data = append(data, pes(0x100, 0xe0, f.pts, f.key)...)
+	}
+	return data
+}
+
+// gopFrames returns 30fps frames of a 2 second segment, starting at pts, with a
+// keyframe every gop frames
+func gopFrames(pts int64, gop int) []tsFrame {
+	var frames []tsFrame
+	for i := 0; i < 60; i++ {
+		frames = append(frames, tsFrame{pts: pts + int64(i)*3000, key: i%gop == 0})
+	}
+	return frames
+}
+
+func TestTSKeyframes(t *testing.T) {
+	assert := assert.New(t)
+
+	video, ok := tsKeyframes(tsSegment(gopFrames(1<<32, 30)...))
+	assert.True(ok)
+	assert.Equal(&tsVideo{keyframes: []int64{0, 90000}, last: 177000, startsWithKey: true}, video)
+
+	video, ok = tsKeyframes(tsSegment(gopFrames(0, 30)[1:]...))
+	assert.True(ok)
+	assert.False(video.startsWithKey)
+
+	// not MPEG-TS, or without video
+	_, ok = tsKeyframes([]byte("not a segment"))
+	assert.False(ok)
+	_, ok = tsKeyframes(make([]byte, 2*tsPacketSize))
+	assert.False(ok)
+	_, ok = tsKeyframes(tsSegment()[:0])
+	assert.False(ok)
+}
+
+func TestCheckKeyframes(t *testing.T) {
+	assert := assert.New(t)
+
+	params := func(renditions ...[]byte) *Params {
+		p := &Params{ManifestID: "mid", Renditions: renditions}
+		for range renditions {
+			p.Profiles = append(p.Profiles, ffmpeg.P240p30fps16x9)
+		}
+		return p
+	}
+	aligned := tsSegment(gopFrames(0, 30)...)
+
+	assert.Nil(CheckKeyframes(params(aligned, aligned), time.Second))
+	// timestamps are relative to the start of the renditions
+	assert.Nil(CheckKeyframes(params(aligned, tsSegment(gopFrames(126000, 30)...)), time.Second))
+	// keyframes a frame apart are aligned
+	shifted := gopFrames(0, 30)
+	shifted[30].key, shifted[31].key = false, true
+	assert.Nil(CheckKeyframes(params(aligned, tsSegment(shifted...)), time.Second))
+
+	// every rendition starts with a keyframe
+	assert.Equal(ErrKeyframeMisaligned, CheckKeyframes(params(tsSegment(gopFrames(0, 30)[1:]...)), time.Second))
+	// GOPs are no longer than the GOP of the profiles, up to the end of the rendition
+	assert.Equal(ErrKeyframeMisaligned, CheckKeyframes(params(tsSegment(gopFrames(0, 60)...)), time.Second))
+	long := gopFrames(0, 30)
+	long[30].key = false
+	long[40].key = true
+	assert.Equal(ErrKeyframeMisaligned, CheckKeyframes(params(tsSegment(long...)), time.Second))
+	// keyframes are at the same timestamps
+	assert.Equal(ErrKeyframeMisaligned, CheckKeyframes(params(aligned, tsSegment(gopFrames(0, 20)...)), time.Second))
+	shifted[31].key, shifted[40].key = false, true
+	assert.Equal(ErrKeyframeMisaligned, CheckKeyframes(params(aligned, tsSegment(shifted...)), 2*time.Second))
+
+	// renditions in other formats, or without video, aren't checked
+	p := params(aligned, tsSegment(gopFrames(0, 20)...), []byte("audio"))
+	p.Profiles[1].Format = ffmpeg.FormatMP4
+	assert.Nil(CheckKeyframes(p, time.Second))
+	assert.Nil(CheckKeyframes(&Params{}, time.Second))
+}
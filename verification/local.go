@@ -0,0 +1,119 @@
+package verification
+
+import (
+	"errors"
+	"math"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/lpms/ffmpeg"
+)
+
+var ErrUndecodable = Retryable{errors.New("Undecodable")}
+var ErrResolutionMismatch = Retryable{errors.New("ResolutionMismatch")}
+var ErrDurationMismatch = Retryable{errors.New("DurationMismatch")}
+
+// DefaultDurationTolerance is the default maximum relative difference between
+// the duration of a rendition and the duration of its source segment
+const DefaultDurationTolerance = 0.2
+
+// LocalVerifier verifies renditions within the broadcaster, without an external
+// verifier. Every rendition is decoded, which checks that it is decodable and
+// counts its pixels, and its resolution and duration are checked against the
+// requested profile and the source segment
+type LocalVerifier struct {
+	// DurationTolerance is the maximum relative difference between the duration
+	// of a rendition and the duration of the source segment
+	DurationTolerance float64
+
+	decode func(data []byte) (*ffmpeg.MediaInfo, error)
+}
+
+func NewLocalVerifier(durationTolerance float64) *LocalVerifier {
+	return &LocalVerifier{DurationTolerance: durationTolerance, decode: decodeSegment}
+}
+
+func (lv *LocalVerifier) Verify(params *Params) (*Results, error) {
+	if len(params.Renditions) != len(params.Profiles) {
+		return nil, ErrPixelsAbsent
+	}
+
+	var err error
+	passed := 0
+	pixels := make([]int64, len(params.Renditions))
+	// Check every rendition so that the pixel counts are complete,
+	// but only return the first error
+	for i, data := range params.Renditions {
+		rerr := lv.verifyRendition(params, params.Profiles[i], data, &pixels[i])
+		if rerr != nil {
+			glog.Errorf("Local verification failed manifestID=%s profile=%s err=%v", params.ManifestID, params.Profiles[i].Name, rerr)
+			if err == nil {
+				err = rerr
+			}
+			continue
+		}
+		passed++
+	}
+
+	// The score is the fraction of renditions that passed verification
+	score := 0.0
+	if len(params.Renditions) > 0 {
+		score = float64(passed) / float64(len(params.Renditions))
+	}
+	return &Results{Score: score, Pixels: pixels}, err
+}
+
+func (lv *LocalVerifier) verifyRendition(params *Params, profile ffmpeg.VideoProfile, data []byte, pixels *int64) error {
+	if len(data) == 0 {
+		return ErrPixelsAbsent
+	}
+	info, err := lv.decode(data)
+	if err != nil || info.Frames <= 0 {
+		return ErrUndecodable
+	}
+	*pixels = info.Pixels
+
+	if !resolutionMatches(profile, info) {
+		return ErrResolutionMismatch
+	}
+
+	if params.Source != nil && !durationMatches(profile, info, params.Source.Duration, lv.DurationTolerance) {
+		return ErrDurationMismatch
+	}
+	return nil
+}
+
+// resolutionMatches checks the average pixels per frame against the profile resolution.
+// The transcoder scales the longest side of the source to the profile and keeps the
+// aspect ratio of the source for the other side, which is never longer
+func resolutionMatches(profile ffmpeg.VideoProfile, info *ffmpeg.MediaInfo) bool {
+	w, h, err := ffmpeg.VideoProfileResolution(profile)
+	if err != nil || w <= 0 || h <= 0 {
+		// Nothing to check against
+		return true
+	}
+	frames := int64(info.Frames)
+	if info.Pixels%frames != 0 {
+		// The frames of a rendition all have the same resolution
+		return false
+	}
+	framePixels := info.Pixels / frames
+	landscape := framePixels%int64(w) == 0 && framePixels/int64(w) <= int64(w)
+	portrait := framePixels%int64(h) == 0 && framePixels/int64(h) <= int64(h)
+	return landscape || portrait
+}
+
+// durationMatches checks the duration of the rendition, computed from its frames and
+// the profile framerate, against the duration of the source segment
+func durationMatches(profile ffmpeg.VideoProfile, info *ffmpeg.MediaInfo, sourceDuration, tolerance float64) bool {
+	if profile.Framerate == 0 || sourceDuration <= 0 {
+		// The source framerate is passed through, so the duration can't be computed
+		return true
+	}
+	den := profile.FramerateDen
+	if den == 0 {
+		den = 1
+	}
+	duration := float64(info.Frames) * float64(den) / float64(profile.Framerate)
+	return math.Abs(duration-sourceDuration)/sourceDuration <= tolerance
+}
@@ -0,0 +1,128 @@
+package verification
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/livepeer/lpms/stream"
+)
+
+// stubDecode returns the media info keyed by the rendition data
+func stubDecode(infos map[string]*ffmpeg.MediaInfo) func([]byte) (*ffmpeg.MediaInfo, error) {
+	return func(data []byte) (*ffmpeg.MediaInfo, error) {
+		info, ok := infos[string(data)]
+		if !ok {
+			return nil, errors.New("Invalid data found when processing input")
+		}
+		return info, nil
+	}
+}
+
+func TestLocalVerifier_Verify(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// 2 second segments at 30fps
+	infos := map[string]*ffmpeg.MediaInfo{
+		"360p":        {Frames: 60, Pixels: 60 * 640 * 360},
+		"240p":        {Frames: 60, Pixels: 60 * 426 * 240},
+		"portrait":    {Frames: 60, Pixels: 60 * 202 * 360},
+		"wrongres":    {Frames: 60, Pixels: 60 * 1280 * 720},
+		"short":       {Frames: 30, Pixels: 30 * 640 * 360},
+		"noframes":    {Frames: 0, Pixels: 0},
+		"variable":    {Frames: 60, Pixels: 60*640*360 + 1},
+		"passthrough": {Frames: 120, Pixels: 120 * 640 * 360},
+	}
+	p360 := ffmpeg.P360p30fps16x9
+	p240 := ffmpeg.P240p30fps16x9
+	lv := NewLocalVerifier(DefaultDurationTolerance)
+	lv.decode = stubDecode(infos)
+	source := &stream.HLSSegment{Duration: 2.0}
+
+	verify := func(profiles []ffmpeg.VideoProfile, renditions ...string) (*Results, error) {
+		data := make([][]byte, len(renditions))
+		for i, r := range renditions {
+			data[i] = []byte(r)
+		}
+		return lv.Verify(&Params{Source: source, Profiles: profiles, Renditions: data})
+	}
+
+	res, err := verify([]ffmpeg.VideoProfile{p360, p240}, "360p", "240p")
+	require.Nil(err)
+	assert.Equal(1.0, res.Score)
+	assert.Equal([]int64{60 * 640 * 360, 60 * 426 * 240}, res.Pixels)
+
+	// Portrait sources are scaled to the height of the profile
+	_, err = verify([]ffmpeg.VideoProfile{p360}, "portrait")
+	assert.Nil(err)
+
+	// Pixel counts are gathered for all renditions even if one fails
+	res, err = verify([]ffmpeg.VideoProfile{p360, p240}, "wrongres", "240p")
+	assert.Equal(ErrResolutionMismatch, err)
+	assert.Equal(0.5, res.Score)
+	assert.Equal([]int64{60 * 1280 * 720, 60 * 426 * 240}, res.Pixels)
+
+	_, err = verify([]ffmpeg.VideoProfile{p360}, "variable")
+	assert.Equal(ErrResolutionMismatch, err)
+
+	res, err = verify([]ffmpeg.VideoProfile{p360}, "short")
+	assert.Equal(ErrDurationMismatch, err)
+	assert.Equal(0.0, res.Score)
+
+	_, err = verify([]ffmpeg.VideoProfile{p360}, "garbage")
+	assert.Equal(ErrUndecodable, err)
+	_, err = verify([]ffmpeg.VideoProfile{p360}, "noframes")
+	assert.Equal(ErrUndecodable, err)
+	_, err = verify([]ffmpeg.VideoProfile{p360}, "")
+	assert.Equal(ErrPixelsAbsent, err)
+
+	// The duration is not checked when the source framerate is passed through
+	passthrough := p360
+	passthrough.Framerate = 0
+	_, err = verify([]ffmpeg.VideoProfile{passthrough}, "passthrough")
+	assert.Nil(err)
+
+	// Renditions must match the profiles
+	_, err = verify([]ffmpeg.VideoProfile{p360, p240}, "360p")
+	assert.Equal(ErrPixelsAbsent, err)
+
+	// Errors are retryable so that another orchestrator can be tried
+	assert.True(IsRetryable(ErrUndecodable))
+	assert.True(IsRetryable(ErrResolutionMismatch))
+	assert.True(IsRetryable(ErrDurationMismatch))
+	assert.False(IsFatal(ErrDurationMismatch))
+}
+
+func TestLocalVerifier_SegmentVerifier(t *testing.T) {
+	assert := assert.New(t)
+
+	infos := map[string]*ffmpeg.MediaInfo{
+		"360p": {Frames: 60, Pixels: 60 * 640 * 360},
+	}
+	lv := NewLocalVerifier(DefaultDurationTolerance)
+	lv.decode = stubDecode(infos)
+	sv := NewSegmentVerifier(&Policy{Verifier: lv, Retries: 2})
+
+	params := func(reportedPixels int64) *Params {
+		return &Params{
+			Source:     &stream.HLSSegment{Duration: 2.0},
+			Profiles:   []ffmpeg.VideoProfile{ffmpeg.P360p30fps16x9},
+			Renditions: [][]byte{[]byte("360p")},
+			Results:    &net.TranscodeData{Segments: []*net.TranscodedSegmentData{{Pixels: reportedPixels}}},
+		}
+	}
+
+	// The decoded pixel counts are checked against the reported ones
+	p := params(60 * 640 * 360)
+	res, err := sv.Verify(p)
+	assert.Nil(err)
+	assert.Equal(p, res)
+
+	_, err = sv.Verify(params(50))
+	assert.Equal(ErrPixelMismatch, err)
+}
@@ -0,0 +1,136 @@
+package verification
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"os"
+	"os/exec"
+)
+
+var ErrNoFrames = errors.New("NoFrames")
+
+// Frames are downscaled to hashWidth x hashHeight grayscale pixels before they are hashed
+const (
+	hashWidth  = 9
+	hashHeight = 8
+	frameSize  = hashWidth * hashHeight
+)
+
+// frameHashes returns the perceptual hash of every frame of a segment
+func frameHashes(data []byte) ([]uint64, error) {
+	raw, err := downscaledFrames(data)
+	if err != nil {
+		return nil, err
+	}
+	return hashFrames(raw)
+}
+
+// RenditionHash returns the perceptual hash of a rendition: the difference hash of its
+// average frame, big-endian. Averaging the frames makes the hash comparable between
+// renditions of the same segment with different frame rates
+func RenditionHash(data []byte) ([]byte, error) {
+	raw, err := downscaledFrames(data)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := averageFrameHash(raw)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, hash)
+	return buf, nil
+}
+
+// downscaledFrames returns the frames of a segment downscaled to grayscale. The frames
+// are decoded and downscaled with the ffmpeg binary, which must be in the PATH
+func downscaledFrames(data []byte) ([]byte, error) {
+	fname, err := tempSegment(data)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(fname)
+
+	ctx, cancel := context.WithTimeout(context.Background(), qualityTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	scale := fmt.Sprintf("scale=%d:%d:flags=area,format=gray", hashWidth, hashHeight)
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-nostats", "-i", fname, "-an", "-vf", scale, "-f", "rawvideo", "-")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("error running ffmpeg for frame hashes: %w: %s", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// hashFrames returns the difference hash of every downscaled grayscale frame
+func hashFrames(raw []byte) ([]uint64, error) {
+	if len(raw) == 0 || len(raw)%frameSize != 0 {
+		return nil, ErrNoFrames
+	}
+	hashes := make([]uint64, 0, len(raw)/frameSize)
+	for i := 0; i < len(raw); i += frameSize {
+		hashes = append(hashes, dHash(raw[i:i+frameSize]))
+	}
+	return hashes, nil
+}
+
+// averageFrameHash returns the difference hash of the average of the downscaled
+// grayscale frames
+func averageFrameHash(raw []byte) (uint64, error) {
+	if len(raw) == 0 || len(raw)%frameSize != 0 {
+		return 0, ErrNoFrames
+	}
+	var sums [frameSize]int
+	for i, v := range raw {
+		sums[i%frameSize] += int(v)
+	}
+	frames := len(raw) / frameSize
+	avg := make([]byte, frameSize)
+	for i, sum := range sums {
+		avg[i] = byte(sum / frames)
+	}
+	return dHash(avg), nil
+}
+
+// dHash computes the difference hash of a frame: every bit is set if a pixel is
+// brighter than the pixel to its right, which is robust to scaling and re-encoding
+func dHash(frame []byte) uint64 {
+	var hash uint64
+	for y := 0; y < hashHeight; y++ {
+		row := frame[y*hashWidth : (y+1)*hashWidth]
+		for x := 0; x < hashWidth-1; x++ {
+			hash <<= 1
+			if row[x] > row[x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// hashDistance returns the average Hamming distance between the hashes of matching
+// frames, and the relative difference between the number of frames
+func hashDistance(a, b []uint64) (float64, float64) {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	max := len(a)
+	if len(b) > max {
+		max = len(b)
+	}
+	if n == 0 {
+		return 64, 1
+	}
+	total := 0
+	for i := 0; i < n; i++ {
+		total += bits.OnesCount64(a[i] ^ b[i])
+	}
+	return float64(total) / float64(n), float64(max-n) / float64(max)
+}
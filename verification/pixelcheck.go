@@ -0,0 +1,100 @@
+package verification
+
+import (
+	"sort"
+	"sync"
+)
+
+// PixelCheckStats are the results of the pixel count cross-checks of an orchestrator
+type PixelCheckStats struct {
+	Orchestrator string
+	// Segments that were checked
+	Checked int
+	// Segments for which more pixels were reported than counted
+	OverReported int
+	// Pixels reported by the orchestrator for the checked segments
+	ReportedPixels int64
+	// Pixels counted by decoding the renditions of the checked segments
+	CountedPixels int64
+	// Pixels that were reported but not counted
+	OverReportedPixels int64
+}
+
+// PixelChecker cross-checks the pixel counts that orchestrators report, which are used
+// to compute their fees, against the pixel counts of the decoded renditions. Segments
+// are sampled like verified segments
+type PixelChecker struct {
+	// Fraction of segments, between 0 and 1, that are checked
+	SampleRate float64
+
+	count func(params *Params) ([]int64, error)
+
+	mu    sync.Mutex
+	stats map[string]*PixelCheckStats
+}
+
+func NewPixelChecker(sampleRate float64) *PixelChecker {
+	return &PixelChecker{
+		SampleRate: sampleRate,
+		count:      countPixelParams,
+		stats:      make(map[string]*PixelCheckStats),
+	}
+}
+
+// Sample returns whether the pixel counts of a segment should be checked
+func (pc *PixelChecker) Sample(params *Params) bool {
+	return sampled(params, pc.SampleRate)
+}
+
+// Check counts the pixels of the renditions of a segment and returns the number of
+// pixels that the orchestrator reported in excess. Under-reported pixels are not
+// charged for, so they are recorded but not returned
+func (pc *PixelChecker) Check(params *Params) (int64, error) {
+	if params.Results == nil {
+		return 0, ErrPixelsAbsent
+	}
+	counted, err := pc.count(params)
+	if err != nil {
+		return 0, err
+	}
+
+	var reportedTotal, countedTotal, over int64
+	for i, seg := range params.Results.Segments {
+		reportedTotal += seg.Pixels
+		countedTotal += counted[i]
+		if seg.Pixels > counted[i] {
+			over += seg.Pixels - counted[i]
+		}
+	}
+
+	orch := orchKey(params)
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	s, ok := pc.stats[orch]
+	if !ok {
+		s = &PixelCheckStats{Orchestrator: orch}
+		pc.stats[orch] = s
+	}
+	s.Checked++
+	s.ReportedPixels += reportedTotal
+	s.CountedPixels += countedTotal
+	if over > 0 {
+		s.OverReported++
+		s.OverReportedPixels += over
+	}
+	return over, nil
+}
+
+// Stats returns the results of the cross-checks of every orchestrator
+func (pc *PixelChecker) Stats() []*PixelCheckStats {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	res := make([]*PixelCheckStats, 0, len(pc.stats))
+	for _, s := range pc.stats {
+		c := *s
+		res = append(res, &c)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Orchestrator < res[j].Orchestrator })
+	return res
+}
@@ -0,0 +1,74 @@
+package verification
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livepeer/go-livepeer/net"
+)
+
+func TestPixelChecker_Check(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	counted := []int64{100, 200}
+	pc := NewPixelChecker(1)
+	pc.count = func(params *Params) ([]int64, error) { return counted, nil }
+
+	params := func(orch string, reported ...int64) *Params {
+		p := sampleParams("mid", 1, orch)
+		p.Results = &net.TranscodeData{}
+		for _, px := range reported {
+			p.Results.Segments = append(p.Results.Segments, &net.TranscodedSegmentData{Pixels: px})
+		}
+		return p
+	}
+
+	over, err := pc.Check(params("o1", 100, 200))
+	require.Nil(err)
+	assert.Equal(int64(0), over)
+
+	// Only pixels reported in excess are returned
+	over, err = pc.Check(params("o1", 150, 150))
+	require.Nil(err)
+	assert.Equal(int64(50), over)
+
+	over, err = pc.Check(params("o2", 90, 200))
+	require.Nil(err)
+	assert.Equal(int64(0), over)
+
+	stats := pc.Stats()
+	require.Len(stats, 2)
+	assert.Equal(&PixelCheckStats{
+		Orchestrator:       "o1",
+		Checked:            2,
+		OverReported:       1,
+		ReportedPixels:     600,
+		CountedPixels:      600,
+		OverReportedPixels: 50,
+	}, stats[0])
+	assert.Equal(&PixelCheckStats{
+		Orchestrator:   "o2",
+		Checked:        1,
+		ReportedPixels: 290,
+		CountedPixels:  300,
+	}, stats[1])
+
+	// Stats are copies
+	stats[0].Checked = 10
+	assert.Equal(2, pc.Stats()[0].Checked)
+
+	// Segments that can't be counted are not recorded
+	pc.count = func(params *Params) ([]int64, error) { return nil, errors.New("Invalid data found when processing input") }
+	_, err = pc.Check(params("o1", 100, 200))
+	assert.NotNil(err)
+	assert.Equal(2, pc.Stats()[0].Checked)
+
+	p := params("o1")
+	p.Results = nil
+	_, err = pc.Check(p)
+	assert.Equal(ErrPixelsAbsent, err)
+}
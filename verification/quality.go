@@ -0,0 +1,206 @@
+package verification
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/lpms/ffmpeg"
+)
+
+// QualityMetric is a full-reference video quality metric
+type QualityMetric string
+
+const (
+	// SSIM scores range from 0 to 1
+	SSIM QualityMetric = "ssim"
+	// VMAF scores range from 0 to 100. Requires ffmpeg to be built with libvmaf
+	VMAF QualityMetric = "vmaf"
+)
+
+var ErrUnknownQualityMetric = errors.New("UnknownQualityMetric")
+var ErrQualityScoreMissing = errors.New("QualityScoreMissing")
+
+// qualityTimeout is the maximum time spent scoring a rendition
+var qualityTimeout = 30 * time.Second
+
+// qualityWeight is the weight of a new score in the moving average of the scores of an orchestrator
+const qualityWeight = 0.2
+
+var ssimRegex = regexp.MustCompile(`SSIM .*All:([0-9.]+)`)
+var vmafRegex = regexp.MustCompile(`VMAF score *[:=] *([0-9.]+)`)
+
+type compareFn func(metric QualityMetric, source, rendition []byte, profile ffmpeg.VideoProfile) (float64, error)
+
+// QualityScorer scores sampled renditions against their source segment with a
+// full-reference quality metric, and keeps a moving average of the scores of every
+// orchestrator. Scoring runs the ffmpeg binary, which must be in the PATH
+type QualityScorer struct {
+	Metric QualityMetric
+
+	// Fraction of segments, between 0 and 1, that are scored. Segments are sampled
+	// like verified segments, so with a rate at or below the verification sample rate
+	// only verified segments are scored
+	SampleRate float64
+
+	// MinScore is the average score below which an orchestrator should not be selected.
+	// Orchestrators are never excluded if zero
+	MinScore float64
+
+	compare compareFn
+
+	mu       sync.Mutex
+	averages map[string]float64
+}
+
+func NewQualityScorer(metric QualityMetric, sampleRate, minScore float64) (*QualityScorer, error) {
+	if metric != SSIM && metric != VMAF {
+		return nil, ErrUnknownQualityMetric
+	}
+	return &QualityScorer{
+		Metric:     metric,
+		SampleRate: sampleRate,
+		MinScore:   minScore,
+		compare:    compareFFmpeg,
+		averages:   make(map[string]float64),
+	}, nil
+}
+
+// Sample returns whether the renditions of a segment should be scored
+func (qs *QualityScorer) Sample(params *Params) bool {
+	return sampled(params, qs.SampleRate)
+}
+
+// Score returns the quality scores of the renditions of a segment by profile name, and
+// updates the average score of the orchestrator once with the lowest score of the segment, so
+// that segments weigh the same whatever their number of profiles. Renditions that can't be
+// scored are skipped
+func (qs *QualityScorer) Score(params *Params) (map[string]float64, error) {
+	if params.Source == nil || len(params.Source.Data) == 0 || len(params.Renditions) != len(params.Profiles) {
+		return nil, ErrPixelsAbsent
+	}
+
+	var err error
+	scores := make(map[string]float64)
+	for i, data := range params.Renditions {
+		profile := params.Profiles[i]
+		score, serr := qs.compare(qs.Metric, params.Source.Data, data, profile)
+		if serr != nil {
+			glog.Errorf("Error scoring rendition quality manifestID=%s profile=%s err=%v", params.ManifestID, profile.Name, serr)
+			err = serr
+			continue
+		}
+		scores[profile.Name] = score
+	}
+	if len(scores) == 0 {
+		return nil, err
+	}
+
+	min := math.Inf(1)
+	for _, score := range scores {
+		min = math.Min(min, score)
+	}
+
+	orch := orchKey(params)
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	avg, ok := qs.averages[orch]
+	if !ok {
+		avg = min
+	}
+	qs.averages[orch] = (1-qualityWeight)*avg + qualityWeight*min
+	return scores, nil
+}
+
+// Average returns the moving average of the scores of an orchestrator, if it has been scored
+func (qs *QualityScorer) Average(orch string) (float64, bool) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	avg, ok := qs.averages[orch]
+	return avg, ok
+}
+
+// LowQuality returns whether the average score of an orchestrator is below the minimum score
+func (qs *QualityScorer) LowQuality(orch string) bool {
+	avg, ok := qs.Average(orch)
+	return ok && qs.MinScore > 0 && avg < qs.MinScore
+}
+
+// compareFFmpeg scores a rendition with the ffmpeg binary. The source is scaled to the
+// resolution of the rendition, and resampled to the framerate of the profile if set
+func compareFFmpeg(metric QualityMetric, source, rendition []byte, profile ffmpeg.VideoProfile) (float64, error) {
+	srcFile, err := tempSegment(source)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(srcFile)
+	rendFile, err := tempSegment(rendition)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(rendFile)
+
+	ref := "[1:v]"
+	if profile.Framerate > 0 {
+		den := profile.FramerateDen
+		if den == 0 {
+			den = 1
+		}
+		ref = fmt.Sprintf("[1:v]fps=%d/%d[fps];[fps]", profile.Framerate, den)
+	}
+	filter := "ssim"
+	regex := ssimRegex
+	if metric == VMAF {
+		filter = "libvmaf"
+		regex = vmafRegex
+	}
+	graph := fmt.Sprintf("%s[0:v]scale2ref[ref][main];[main][ref]%s", ref, filter)
+
+	ctx, cancel := context.WithTimeout(context.Background(), qualityTimeout)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-nostats", "-i", rendFile, "-i", srcFile, "-lavfi", graph, "-f", "null", "-")
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("error running ffmpeg for quality scoring: %w", err)
+	}
+	return parseQualityScore(regex, stderr.String())
+}
+
+func parseQualityScore(regex *regexp.Regexp, output string) (float64, error) {
+	match := regex.FindStringSubmatch(output)
+	if len(match) < 2 {
+		return 0, ErrQualityScoreMissing
+	}
+	return strconv.ParseFloat(match[1], 64)
+}
+
+func tempSegment(data []byte) (string, error) {
+	tempfile, err := ioutil.TempFile("", common.RandName())
+	if err != nil {
+		return "", fmt.Errorf("error creating temp file for quality scoring: %w", err)
+	}
+	if _, err := tempfile.Write(data); err != nil {
+		tempfile.Close()
+		os.Remove(tempfile.Name())
+		return "", fmt.Errorf("error writing temp file for quality scoring: %w", err)
+	}
+	if err := tempfile.Close(); err != nil {
+		os.Remove(tempfile.Name())
+		return "", fmt.Errorf("error closing temp file for quality scoring: %w", err)
+	}
+	return tempfile.Name(), nil
+}
@@ -0,0 +1,127 @@
+package verification
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livepeer/lpms/ffmpeg"
+)
+
+// stubCompare returns the score keyed by the rendition data
+func stubCompare(scores map[string]float64) compareFn {
+	return func(metric QualityMetric, source, rendition []byte, profile ffmpeg.VideoProfile) (float64, error) {
+		score, ok := scores[string(rendition)]
+		if !ok {
+			return 0, errors.New("Invalid data found when processing input")
+		}
+		return score, nil
+	}
+}
+
+func TestQualityScorer_Score(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	_, err := NewQualityScorer("psnr", 1, 0)
+	assert.Equal(ErrUnknownQualityMetric, err)
+
+	qs, err := NewQualityScorer(SSIM, 1, 0.9)
+	require.Nil(err)
+	qs.compare = stubCompare(map[string]float64{"good": 0.98, "bad": 0.5})
+	p360 := ffmpeg.P360p30fps16x9
+	p240 := ffmpeg.P240p30fps16x9
+
+	params := sampleParams("mid", 1, "o1")
+	params.Profiles = []ffmpeg.VideoProfile{p360, p240}
+	params.Renditions = [][]byte{[]byte("good"), []byte("good")}
+	scores, err := qs.Score(params)
+	require.Nil(err)
+	assert.Equal(map[string]float64{p360.Name: 0.98, p240.Name: 0.98}, scores)
+	avg, ok := qs.Average("o1")
+	assert.True(ok)
+	assert.InDelta(0.98, avg, 0.0001)
+	assert.False(qs.LowQuality("o1"))
+
+	// The average is updated once per segment with its lowest score
+	params.Renditions = [][]byte{[]byte("good"), []byte("bad")}
+	_, err = qs.Score(params)
+	require.Nil(err)
+	avg, _ = qs.Average("o1")
+	assert.InDelta(0.8*0.98+0.2*0.5, avg, 0.0001)
+
+	// Unscored orchestrators are not low quality
+	_, ok = qs.Average("o2")
+	assert.False(ok)
+	assert.False(qs.LowQuality("o2"))
+
+	// Renditions that can't be scored are skipped
+	params.Renditions = [][]byte{[]byte("garbage"), []byte("bad")}
+	scores, err = qs.Score(params)
+	require.Nil(err)
+	assert.Equal(map[string]float64{p240.Name: 0.5}, scores)
+
+	// The average drops with repeated low scores
+	params.Renditions = [][]byte{[]byte("bad"), []byte("bad")}
+	for i := 0; i < 3; i++ {
+		_, err = qs.Score(params)
+		require.Nil(err)
+	}
+	avg, _ = qs.Average("o1")
+	assert.True(avg < 0.9)
+	assert.True(qs.LowQuality("o1"))
+
+	// Low quality never triggers without a minimum score
+	qs.MinScore = 0
+	assert.False(qs.LowQuality("o1"))
+
+	params.Renditions = [][]byte{[]byte("garbage"), []byte("garbage")}
+	_, err = qs.Score(params)
+	assert.EqualError(err, "Invalid data found when processing input")
+
+	// The source and all renditions are needed
+	params.Renditions = [][]byte{[]byte("good")}
+	_, err = qs.Score(params)
+	assert.Equal(ErrPixelsAbsent, err)
+	params.Source.Data = nil
+	_, err = qs.Score(params)
+	assert.Equal(ErrPixelsAbsent, err)
+}
+
+func TestQualityScorer_Sample(t *testing.T) {
+	assert := assert.New(t)
+
+	qs, err := NewQualityScorer(VMAF, 0.1, 0)
+	assert.Nil(err)
+	p := &Policy{SampleRate: 0.5}
+	scored := 0
+	for i := uint64(0); i < 1000; i++ {
+		params := sampleParams("mid", i, "o1")
+		if qs.Sample(params) {
+			scored++
+			// Scored segments are also verified at a higher verification sample rate
+			assert.True(p.sample(params))
+		}
+	}
+	assert.InDelta(100, scored, 40)
+}
+
+func TestParseQualityScore(t *testing.T) {
+	assert := assert.New(t)
+
+	score, err := parseQualityScore(ssimRegex, "[Parsed_ssim_1 @ 0x5567] SSIM Y:0.991448 (20.679412) U:0.994333 (22.465571) V:0.994604 (22.678784) All:0.992452 (21.222131)")
+	assert.Nil(err)
+	assert.Equal(0.992452, score)
+
+	score, err = parseQualityScore(vmafRegex, "[Parsed_libvmaf_1 @ 0x5567] VMAF score: 93.125107")
+	assert.Nil(err)
+	assert.Equal(93.125107, score)
+	score, err = parseQualityScore(vmafRegex, "[libvmaf @ 0x5567] VMAF score = 87.5")
+	assert.Nil(err)
+	assert.Equal(87.5, score)
+
+	_, err = parseQualityScore(ssimRegex, "Conversion failed!")
+	assert.Equal(ErrQualityScoreMissing, err)
+}
@@ -0,0 +1,71 @@
+package verification
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/common"
+)
+
+// resultSweepInterval is the minimum time between two deletions of the outcomes that
+// are older than the retention of the policy
+var resultSweepInterval = time.Hour
+
+// resultSweep tracks the last deletion of old outcomes
+type resultSweep struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+// storeResult persists the outcome of the verification of a segment, if the policy has a store
+func (p *Policy) storeResult(params *Params, res *Results, err error) {
+	if p.Store == nil {
+		return
+	}
+
+	r := &common.DBVerificationResult{
+		CreatedAt:    time.Now(),
+		ManifestID:   string(params.ManifestID),
+		Orchestrator: orchKey(params),
+		URIs:         params.URIs,
+	}
+	if params.Source != nil {
+		r.SeqNo = params.Source.SeqNo
+	}
+	if res != nil {
+		r.Score = res.Score
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if serr := p.Store.InsertVerificationResult(r); serr != nil {
+		glog.Errorf("Error storing verification result manifestID=%s seqNo=%d err=%v", r.ManifestID, r.SeqNo, serr)
+	}
+	p.sweepResults(r.CreatedAt)
+}
+
+// sweepResults deletes the stored outcomes that are older than the retention of the
+// policy, at most once every resultSweepInterval
+func (p *Policy) sweepResults(now time.Time) {
+	if p.Retention <= 0 {
+		return
+	}
+	p.sweep.mu.Lock()
+	if now.Sub(p.sweep.last) < resultSweepInterval {
+		p.sweep.mu.Unlock()
+		return
+	}
+	p.sweep.last = now
+	p.sweep.mu.Unlock()
+
+	deleted, err := p.Store.DeleteVerificationResults(now.Add(-p.Retention))
+	if err != nil {
+		glog.Errorf("Error deleting verification results older than retention=%v err=%v", p.Retention, err)
+		return
+	}
+	if deleted > 0 {
+		glog.V(common.DEBUG).Infof("Deleted verification results older than retention=%v count=%d", p.Retention, deleted)
+	}
+}
@@ -0,0 +1,101 @@
+package verification
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livepeer/go-livepeer/common"
+)
+
+type stubResultStore struct {
+	results []*common.DBVerificationResult
+	err     error
+	deleted []time.Time
+}
+
+func (s *stubResultStore) InsertVerificationResult(res *common.DBVerificationResult) error {
+	s.results = append(s.results, res)
+	return s.err
+}
+
+func (s *stubResultStore) DeleteVerificationResults(before time.Time) (int64, error) {
+	s.deleted = append(s.deleted, before)
+	return 0, s.err
+}
+
+func TestSegmentVerifier_StoreResults(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	store := &stubResultStore{}
+	verifier := &stubVerifier{results: &Results{Score: 0.8}}
+	p := &Policy{Verifier: verifier, Retries: 2, Store: store}
+
+	params := sampleParams("mid", 7, "o1")
+	params.URIs = []string{"/stream/mid/P240p30fps16x9/7.ts"}
+	_, err := NewSegmentVerifier(p).Verify(params)
+	require.Nil(err)
+	require.Len(store.results, 1)
+	res := store.results[0]
+	assert.Equal("mid", res.ManifestID)
+	assert.Equal(uint64(7), res.SeqNo)
+	assert.Equal("o1", res.Orchestrator)
+	assert.Equal(0.8, res.Score)
+	assert.Empty(res.Error)
+	assert.Equal(params.URIs, res.URIs)
+	assert.False(res.CreatedAt.IsZero())
+
+	// Failures are stored along with the error
+	verifier.err = ErrTampered
+	_, err = NewSegmentVerifier(p).Verify(sampleParams("mid", 8, "o2"))
+	assert.Equal(ErrTampered, err)
+	require.Len(store.results, 2)
+	assert.Equal("o2", store.results[1].Orchestrator)
+	assert.Equal(ErrTampered.Error(), store.results[1].Error)
+
+	// Errors storing the result do not affect verification
+	store.err = errors.New("Stub Store Error")
+	verifier.err = nil
+	_, err = NewSegmentVerifier(p).Verify(sampleParams("mid", 9, "o1"))
+	assert.Nil(err)
+	assert.Len(store.results, 3)
+
+	// Segments that are not sampled are not stored
+	p.SampleRate = 0.000001
+	_, err = NewSegmentVerifier(p).Verify(sampleParams("mid", 10, "o1"))
+	assert.Nil(err)
+	assert.Len(store.results, 3)
+}
+
+func TestPolicy_SweepResults(t *testing.T) {
+	assert := assert.New(t)
+
+	store := &stubResultStore{}
+	p := &Policy{Store: store}
+	now := time.Now()
+
+	// Outcomes are kept forever without a retention
+	p.sweepResults(now)
+	assert.Empty(store.deleted)
+
+	// Old outcomes are deleted at most once per interval
+	p.Retention = 24 * time.Hour
+	p.sweepResults(now)
+	assert.Equal([]time.Time{now.Add(-24 * time.Hour)}, store.deleted)
+	p.sweepResults(now.Add(resultSweepInterval / 2))
+	assert.Len(store.deleted, 1)
+	later := now.Add(resultSweepInterval)
+	p.sweepResults(later)
+	assert.Equal([]time.Time{now.Add(-24 * time.Hour), later.Add(-24 * time.Hour)}, store.deleted)
+
+	// Storing an outcome sweeps the old ones
+	p.sweep.last = time.Time{}
+	_, err := NewSegmentVerifier(p).Verify(sampleParams("mid", 1, "o1"))
+	assert.Nil(err)
+	assert.Len(store.results, 1)
+	assert.Len(store.deleted, 3)
+}
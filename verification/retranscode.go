@@ -0,0 +1,90 @@
+package verification
+
+import (
+	"errors"
+	"os"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/core"
+)
+
+var ErrRetranscodeMismatch = Retryable{errors.New("RetranscodeMismatch")}
+
+// DefaultMaxHashDistance is the default maximum average Hamming distance, out of 64 bits,
+// between the perceptual hashes of the frames of a rendition and of its re-transcode
+const DefaultMaxHashDistance = 10
+
+// maxFrameDifference is the maximum relative difference between the number of frames
+// of a rendition and of its re-transcode
+const maxFrameDifference = 0.1
+
+// RetranscodeVerifier verifies renditions by transcoding the source segment again
+// with the same profiles, and comparing the perceptual hashes of the frames of the
+// renditions against the frames of the local renditions. It doesn't rely on any
+// external verifier. Encoders don't produce identical output, so renditions are
+// compared perceptually rather than byte for byte
+type RetranscodeVerifier struct {
+	// MaxDistance is the maximum average Hamming distance, out of 64 bits, between
+	// the hashes of matching frames
+	MaxDistance float64
+
+	transcoder core.Transcoder
+	hash       func(data []byte) ([]uint64, error)
+}
+
+func NewRetranscodeVerifier(transcoder core.Transcoder, maxDistance float64) *RetranscodeVerifier {
+	return &RetranscodeVerifier{MaxDistance: maxDistance, transcoder: transcoder, hash: frameHashes}
+}
+
+func (rv *RetranscodeVerifier) Verify(params *Params) (*Results, error) {
+	if params.Source == nil || len(params.Source.Data) == 0 || len(params.Renditions) != len(params.Profiles) {
+		return nil, ErrPixelsAbsent
+	}
+
+	fname, err := tempSegment(params.Source.Data)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(fname)
+	local, err := rv.transcoder.Transcode(&core.SegTranscodingMetadata{
+		ManifestID: params.ManifestID,
+		Fname:      fname,
+		Profiles:   params.Profiles,
+	})
+	if err != nil {
+		// Not the fault of the orchestrator
+		glog.Errorf("Error re-transcoding segment for verification manifestID=%s err=%v", params.ManifestID, err)
+		return nil, err
+	}
+	if len(local.Segments) != len(params.Renditions) {
+		return nil, errors.New("re-transcode returned a different number of renditions")
+	}
+
+	// The score is the average similarity of the renditions, between 0 and 1
+	score := 0.0
+	for i, data := range params.Renditions {
+		localHashes, err := rv.hash(local.Segments[i].Data)
+		if err != nil {
+			glog.Errorf("Error hashing re-transcoded rendition manifestID=%s profile=%s err=%v", params.ManifestID, params.Profiles[i].Name, err)
+			return nil, err
+		}
+		hashes, err := rv.hash(data)
+		if err != nil {
+			glog.Errorf("Error hashing rendition manifestID=%s profile=%s err=%v", params.ManifestID, params.Profiles[i].Name, err)
+			return &Results{Score: score / float64(len(params.Renditions))}, ErrUndecodable
+		}
+
+		distance, frameDiff := hashDistance(hashes, localHashes)
+		score += 1 - distance/64
+		if distance > rv.MaxDistance || frameDiff > maxFrameDifference {
+			glog.Errorf("Rendition does not match its re-transcode manifestID=%s profile=%s distance=%v frameDiff=%v",
+				params.ManifestID, params.Profiles[i].Name, distance, frameDiff)
+			return &Results{Score: score / float64(len(params.Renditions))}, ErrRetranscodeMismatch
+		}
+	}
+
+	// Pixel counts are left to the caller, which counts them from the renditions of
+	// the orchestrator rather than from the local renditions
+	return &Results{Score: score / float64(len(params.Renditions))}, nil
+}
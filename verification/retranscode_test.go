@@ -0,0 +1,148 @@
+package verification
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/lpms/ffmpeg"
+)
+
+type stubTranscoder struct {
+	renditions [][]byte
+	err        error
+	md         *core.SegTranscodingMetadata
+}
+
+func (st *stubTranscoder) Transcode(md *core.SegTranscodingMetadata) (*core.TranscodeData, error) {
+	st.md = md
+	if st.err != nil {
+		return nil, st.err
+	}
+	td := &core.TranscodeData{}
+	for _, r := range st.renditions {
+		td.Segments = append(td.Segments, &core.TranscodedSegmentData{Data: r})
+	}
+	return td, nil
+}
+
+// stubHash returns the frame hashes keyed by the rendition data
+func stubHash(hashes map[string][]uint64) func([]byte) ([]uint64, error) {
+	return func(data []byte) ([]uint64, error) {
+		h, ok := hashes[string(data)]
+		if !ok {
+			return nil, ErrNoFrames
+		}
+		return h, nil
+	}
+}
+
+func TestRetranscodeVerifier_Verify(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	frames := []uint64{0x0, 0xffff, 0xff00ff00ff00ff00, 0x123456789abcdef0}
+	similar := []uint64{0x1, 0xfff7, 0xff00ff00ff00ff01, 0x123456789abcdef1}
+	different := []uint64{0xffffffffffffffff, 0xffffffffffff0000, 0x00ff00ff00ff00ff, 0xedcba9876543210f}
+	hashes := map[string][]uint64{
+		"local":     frames,
+		"similar":   similar,
+		"different": different,
+		"short":     frames[:2],
+	}
+	st := &stubTranscoder{renditions: [][]byte{[]byte("local")}}
+	rv := NewRetranscodeVerifier(st, DefaultMaxHashDistance)
+	rv.hash = stubHash(hashes)
+
+	verify := func(rendition string) (*Results, error) {
+		params := sampleParams("mid", 1, "o1")
+		params.Profiles = []ffmpeg.VideoProfile{ffmpeg.P240p30fps16x9}
+		params.Renditions = [][]byte{[]byte(rendition)}
+		return rv.Verify(params)
+	}
+
+	res, err := verify("similar")
+	require.Nil(err)
+	assert.InDelta(1-1.0/64, res.Score, 0.0001)
+	assert.Nil(res.Pixels)
+	// The source is transcoded again with the same profiles
+	assert.Equal([]ffmpeg.VideoProfile{ffmpeg.P240p30fps16x9}, st.md.Profiles)
+	assert.NotEmpty(st.md.Fname)
+
+	res, err = verify("different")
+	assert.Equal(ErrRetranscodeMismatch, err)
+	assert.True(res.Score < 0.5)
+
+	_, err = verify("short")
+	assert.Equal(ErrRetranscodeMismatch, err)
+
+	_, err = verify("garbage")
+	assert.Equal(ErrUndecodable, err)
+
+	// Errors are retryable so that another orchestrator can be tried
+	assert.True(IsRetryable(ErrRetranscodeMismatch))
+
+	// Local transcoding failures are not the fault of the orchestrator
+	st.err = errors.New("Transcoder Error")
+	_, err = verify("similar")
+	assert.Equal(st.err, err)
+	assert.False(IsRetryable(err))
+
+	st.err = nil
+	st.renditions = nil
+	_, err = verify("similar")
+	assert.NotNil(err)
+	assert.False(IsRetryable(err))
+
+	// The source and all renditions are needed
+	params := sampleParams("mid", 1, "o1")
+	params.Profiles = []ffmpeg.VideoProfile{ffmpeg.P240p30fps16x9}
+	_, err = rv.Verify(params)
+	assert.Equal(ErrPixelsAbsent, err)
+}
+
+func TestHashFrames(t *testing.T) {
+	assert := assert.New(t)
+
+	// A frame that gets darker to the right has all bits set
+	frame := make([]byte, frameSize)
+	for y := 0; y < hashHeight; y++ {
+		for x := 0; x < hashWidth; x++ {
+			frame[y*hashWidth+x] = byte(255 - 10*x)
+		}
+	}
+	flat := make([]byte, frameSize)
+	hashes, err := hashFrames(append(frame, flat...))
+	assert.Nil(err)
+	assert.Equal([]uint64{0xffffffffffffffff, 0}, hashes)
+
+	_, err = hashFrames(nil)
+	assert.Equal(ErrNoFrames, err)
+	_, err = hashFrames(frame[:frameSize-1])
+	assert.Equal(ErrNoFrames, err)
+
+	// The average of a frame getting darker to the right and of a frame getting
+	// brighter to the right is flat
+	hash, err := averageFrameHash(append(frame, frame...))
+	assert.Nil(err)
+	assert.Equal(uint64(0xffffffffffffffff), hash)
+	brighter := make([]byte, frameSize)
+	for i := range brighter {
+		brighter[i] = byte(10 * (i % hashWidth))
+	}
+	hash, err = averageFrameHash(append(frame, brighter...))
+	assert.Nil(err)
+	assert.Equal(uint64(0), hash)
+	_, err = averageFrameHash(frame[:frameSize-1])
+	assert.Equal(ErrNoFrames, err)
+
+	distance, frameDiff := hashDistance([]uint64{0x0, 0x3}, []uint64{0x1, 0x0, 0x0, 0x0})
+	assert.Equal(1.5, distance)
+	assert.Equal(0.5, frameDiff)
+	distance, frameDiff = hashDistance(nil, []uint64{0x0})
+	assert.Equal(64.0, distance)
+	assert.Equal(1.0, frameDiff)
+}
@@ -0,0 +1,107 @@
+package verification
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"sync"
+)
+
+// sampleKey keys the sampling hash. Every other input of the hash is known to the
+// orchestrators, so without a secret key they could predict which segments are verified
+var sampleKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// SetSampleKey sets the secret key of the sampling of segments, which is generated at
+// startup otherwise. Setting the same key keeps the samples the same across restarts.
+// It should be called before any segment is sampled
+func SetSampleKey(key []byte) {
+	sampleKey = key
+}
+
+// orchTrust tracks the consecutive successful verifications of orchestrators
+type orchTrust struct {
+	mu     sync.Mutex
+	passed map[string]int
+}
+
+// record updates the trust of an orchestrator with a verification result. Only errors
+// caused by the orchestrator, i.e. retryable ones, make the orchestrator lose its trust
+func (t *orchTrust) record(orch string, err error) {
+	if err != nil && !IsRetryable(err) {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.passed == nil {
+		t.passed = make(map[string]int)
+	}
+	if err != nil {
+		delete(t.passed, orch)
+		return
+	}
+	t.passed[orch]++
+}
+
+func (t *orchTrust) trusted(orch string, threshold int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.passed[orch] >= threshold
+}
+
+// sampleRate returns the fraction of the segments of an orchestrator that are verified
+func (p *Policy) sampleRate(orch string) float64 {
+	if p.SampleRate <= 0 {
+		return 1
+	}
+	rate := p.SampleRate
+	if p.UntrustedSampleRate > rate && !p.trust.trusted(orch, p.TrustThreshold) {
+		rate = p.UntrustedSampleRate
+	}
+	return math.Min(rate, 1)
+}
+
+// sample returns whether a segment should be verified. The decision is seeded with the
+// stream and the source segment so that it is repeatable, and so that streams are
+// sampled independently of each other, and it is keyed with a secret so that
+// orchestrators can't predict it
+func (p *Policy) sample(params *Params) bool {
+	return sampled(params, p.sampleRate(orchKey(params)))
+}
+
+// sampled returns whether a segment is in a sample of the given rate. The samples
+// of lower rates are subsets of the samples of higher rates
+func sampled(params *Params, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+
+	h := hmac.New(sha256.New, sampleKey)
+	h.Write([]byte(params.ManifestID))
+	if params.Source != nil {
+		seqNo := make([]byte, 8)
+		binary.BigEndian.PutUint64(seqNo, params.Source.SeqNo)
+		h.Write(seqNo)
+		h.Write(params.Source.Data)
+	}
+	sum := binary.BigEndian.Uint64(h.Sum(nil))
+	return float64(sum)/math.MaxUint64 < rate
+}
+
+// orchKey identifies the orchestrator that transcoded a segment
+func orchKey(params *Params) string {
+	if params.Orchestrator == nil {
+		return ""
+	}
+	return params.Orchestrator.Transcoder
+}
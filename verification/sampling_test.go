@@ -0,0 +1,143 @@
+package verification
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/lpms/stream"
+)
+
+func sampleParams(mid string, seqNo uint64, orch string) *Params {
+	return &Params{
+		ManifestID:   core.ManifestID(mid),
+		Source:       &stream.HLSSegment{SeqNo: seqNo, Data: []byte(fmt.Sprintf("segment %d", seqNo))},
+		Orchestrator: &net.OrchestratorInfo{Transcoder: orch},
+	}
+}
+
+func TestPolicy_Sample(t *testing.T) {
+	assert := assert.New(t)
+
+	// Every segment is verified by default
+	p := &Policy{}
+	for i := uint64(0); i < 100; i++ {
+		assert.True(p.sample(sampleParams("mid", i, "o1")))
+	}
+
+	p = &Policy{SampleRate: 0.25}
+	sampled := 0
+	for i := uint64(0); i < 1000; i++ {
+		params := sampleParams("mid", i, "o1")
+		s := p.sample(params)
+		if s {
+			sampled++
+		}
+		// Sampling is repeatable
+		assert.Equal(s, p.sample(params))
+	}
+	assert.InDelta(250, sampled, 50)
+
+	// Streams are sampled independently
+	same := 0
+	for i := uint64(0); i < 1000; i++ {
+		if p.sample(sampleParams("mid", i, "o1")) == p.sample(sampleParams("other", i, "o1")) {
+			same++
+		}
+	}
+	assert.True(same < 1000)
+
+	// Samples depend on the secret key
+	key := sampleKey
+	defer SetSampleKey(key)
+	var samples []bool
+	for i := uint64(0); i < 1000; i++ {
+		samples = append(samples, p.sample(sampleParams("mid", i, "o1")))
+	}
+	SetSampleKey([]byte("another key"))
+	same = 0
+	for i := uint64(0); i < 1000; i++ {
+		if p.sample(sampleParams("mid", i, "o1")) == samples[i] {
+			same++
+		}
+	}
+	assert.True(same < 1000)
+}
+
+func TestPolicy_SampleRate(t *testing.T) {
+	assert := assert.New(t)
+
+	p := &Policy{SampleRate: 0.1, UntrustedSampleRate: 0.5, TrustThreshold: 2}
+	assert.Equal(0.5, p.sampleRate("o1"))
+
+	p.trust.record("o1", nil)
+	assert.Equal(0.5, p.sampleRate("o1"))
+	p.trust.record("o1", nil)
+	assert.Equal(0.1, p.sampleRate("o1"))
+	assert.Equal(0.5, p.sampleRate("o2"))
+
+	// Errors that are not caused by the orchestrator are ignored
+	p.trust.record("o1", ErrVerifierStatus)
+	assert.Equal(0.1, p.sampleRate("o1"))
+
+	// Failing verification loses the trust
+	p.trust.record("o1", ErrTampered)
+	assert.Equal(0.5, p.sampleRate("o1"))
+
+	// The untrusted rate never lowers the sample rate
+	p = &Policy{SampleRate: 0.5, UntrustedSampleRate: 0.1, TrustThreshold: 2}
+	assert.Equal(0.5, p.sampleRate("o1"))
+
+	p = &Policy{SampleRate: 0.5, UntrustedSampleRate: 2, TrustThreshold: 1}
+	assert.Equal(1.0, p.sampleRate("o1"))
+}
+
+func TestSegmentVerifier_Sampling(t *testing.T) {
+	assert := assert.New(t)
+
+	verifier := &stubVerifier{err: ErrTampered}
+	p := &Policy{Verifier: verifier, Retries: 2, SampleRate: 0.000001}
+
+	// Segments that are not sampled are accepted without running the verifier
+	params := sampleParams("mid", 0, "o1")
+	sv := NewSegmentVerifier(p)
+	res, err := sv.Verify(params)
+	assert.Nil(err)
+	assert.Equal(params, res)
+
+	// Retries are always verified
+	sv.count = 1
+	res, err = sv.Verify(params)
+	assert.Nil(res)
+	assert.Equal(ErrTampered, err)
+
+	// Orchestrators gain trust with successful verifications
+	p = &Policy{Verifier: &stubVerifier{results: &Results{}}, Retries: 2, SampleRate: 0.000001, UntrustedSampleRate: 1, TrustThreshold: 2}
+	for i := 0; i < 2; i++ {
+		res, err = NewSegmentVerifier(p).Verify(sampleParams("mid", uint64(i), "o1"))
+		assert.Nil(err)
+		assert.NotNil(res)
+	}
+	assert.True(p.trust.trusted("o1", 2))
+	assert.False(p.trust.trusted("o2", 2))
+
+	// Verifier errors do not affect the trust
+	p.Verifier = &stubVerifier{err: errors.New("Stub Verifier Error")}
+	sv = NewSegmentVerifier(p)
+	sv.count = 1
+	_, err = sv.Verify(sampleParams("mid", 2, "o1"))
+	assert.NotNil(err)
+	assert.True(p.trust.trusted("o1", 2))
+
+	// Failed verifications do
+	p.Verifier = &stubVerifier{err: ErrTampered}
+	sv = NewSegmentVerifier(p)
+	sv.count = 1
+	_, err = sv.Verify(sampleParams("mid", 3, "o1"))
+	assert.Equal(ErrTampered, err)
+	assert.False(p.trust.trusted("o1", 2))
+}
@@ -0,0 +1,99 @@
+package verification
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/lpms/ffmpeg"
+)
+
+var ErrRenditionMismatch = Retryable{errors.New("RenditionMismatch")}
+
+// Tolerances of the sanity checks of renditions
+const (
+	// Maximum relative difference between the duration of a rendition and the duration
+	// of the source segment
+	maxDurationDifference = 0.25
+	// Maximum relative excess of the pixels reported for a rendition over the pixels of
+	// its frames at the resolution of the profile
+	maxPixelsExcess = 0.1
+	// Maximum Hamming distance, out of 64 bits, between the perceptual hashes of two
+	// renditions of the same segment
+	maxRenditionHashDistance = 16
+)
+
+// CheckRenditions runs cheap sanity checks of the metadata that the orchestrator
+// reported for the renditions of a segment, without downloading or decoding them: the
+// duration of every rendition is close to the duration of the source, the pixels
+// reported for a rendition don't exceed its duration at the resolution and frame rate of
+// its profile, and the perceptual hashes of the renditions are close to each other.
+// Checks are skipped for the metadata that the orchestrator didn't report
+func CheckRenditions(params *Params) error {
+	if params.Results == nil {
+		return nil
+	}
+	var srcDur float64
+	if params.Source != nil {
+		srcDur = params.Source.Duration
+	}
+
+	var firstHash uint64
+	hashed := false
+	for i, seg := range params.Results.Segments {
+		if i >= len(params.Profiles) {
+			break
+		}
+		profile := params.Profiles[i]
+
+		if seg.Duration > 0 {
+			dur := float64(seg.Duration) / 1000
+			if srcDur > 0 && math.Abs(dur-srcDur)/srcDur > maxDurationDifference {
+				glog.Errorf("Rendition duration does not match the source manifestID=%s profile=%s duration=%v source=%v",
+					params.ManifestID, profile.Name, dur, srcDur)
+				return ErrRenditionMismatch
+			}
+			if max := maxPixels(profile, dur); max > 0 && float64(seg.Pixels) > max*(1+maxPixelsExcess) {
+				glog.Errorf("Rendition pixels exceed its resolution and duration manifestID=%s profile=%s pixels=%v max=%v",
+					params.ManifestID, profile.Name, seg.Pixels, int64(max))
+				return ErrRenditionMismatch
+			}
+		}
+
+		if len(seg.PerceptualHash) != 8 {
+			continue
+		}
+		hash := binary.BigEndian.Uint64(seg.PerceptualHash)
+		if !hashed {
+			firstHash, hashed = hash, true
+			continue
+		}
+		if distance := bits.OnesCount64(hash ^ firstHash); distance > maxRenditionHashDistance {
+			glog.Errorf("Rendition does not look like the other renditions manifestID=%s profile=%s distance=%v",
+				params.ManifestID, profile.Name, distance)
+			return ErrRenditionMismatch
+		}
+	}
+	return nil
+}
+
+// maxPixels returns the number of pixels of dur seconds of video at the resolution and
+// frame rate of profile, or 0 if the profile has no fixed frame rate
+func maxPixels(profile ffmpeg.VideoProfile, dur float64) float64 {
+	if profile.Framerate == 0 {
+		return 0
+	}
+	w, h, err := ffmpeg.VideoProfileResolution(profile)
+	if err != nil {
+		return 0
+	}
+	den := profile.FramerateDen
+	if den == 0 {
+		den = 1
+	}
+	frames := math.Ceil(dur * float64(profile.Framerate) / float64(den))
+	return float64(w) * float64(h) * frames
+}
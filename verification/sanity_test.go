@@ -0,0 +1,47 @@
+package verification
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/lpms/ffmpeg"
+)
+
+func TestCheckRenditions(t *testing.T) {
+	assert := assert.New(t)
+
+	// 426x240 at 30fps for 2 seconds
+	const pixels240p = 426 * 240 * 60
+	params := func(segs ...*net.TranscodedSegmentData) *Params {
+		p := sampleParams("mid", 1, "o1")
+		p.Source.Duration = 2.0
+		p.Profiles = []ffmpeg.VideoProfile{ffmpeg.P240p30fps16x9, ffmpeg.P240p30fps16x9}
+		p.Results = &net.TranscodeData{Segments: segs}
+		return p
+	}
+	hash := func(b ...byte) []byte { return append(make([]byte, 8-len(b)), b...) }
+
+	assert.Nil(CheckRenditions(params(
+		&net.TranscodedSegmentData{Pixels: pixels240p, Duration: 2000, PerceptualHash: hash(0xff)},
+		&net.TranscodedSegmentData{Pixels: pixels240p, Duration: 1900, PerceptualHash: hash(0xf0)},
+	)))
+
+	// Metadata that isn't reported is not checked
+	assert.Nil(CheckRenditions(params(&net.TranscodedSegmentData{Pixels: 10 * pixels240p})))
+	assert.Nil(CheckRenditions(&Params{}))
+
+	// Durations must be close to the duration of the source
+	assert.Equal(ErrRenditionMismatch, CheckRenditions(params(&net.TranscodedSegmentData{Pixels: pixels240p, Duration: 1000})))
+
+	// Pixels can't exceed the resolution and frame rate of the profile
+	assert.Equal(ErrRenditionMismatch, CheckRenditions(params(&net.TranscodedSegmentData{Pixels: 2 * pixels240p, Duration: 2000})))
+
+	// Renditions must look alike
+	assert.Equal(ErrRenditionMismatch, CheckRenditions(params(
+		&net.TranscodedSegmentData{Duration: 2000, PerceptualHash: hash(0xff, 0xff, 0xff)},
+		&net.TranscodedSegmentData{Duration: 2000, PerceptualHash: hash(0)},
+	)))
+	assert.True(IsRetryable(ErrRenditionMismatch))
+}
@@ -0,0 +1,246 @@
+package verification
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/golang/glog"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+)
+
+var ErrVideoSignatureMismatch = Retryable{errors.New("VideoSignatureMismatch")}
+
+var errSignatureFormat = errors.New("invalid video signature")
+
+// Tolerances of the comparison of video signatures, the defaults of the signature
+// filter of ffmpeg
+const (
+	// Maximum L1 distance between the frame signatures of two frames that match
+	maxFrameDistance = 116
+	// Minimum ratio of the frames of a rendition that match the frames of the source
+	minMatchingFrames = 0.5
+)
+
+// Sizes of the fields of the binary format of MPEG-7 video signatures, in bits
+const (
+	signatureHeaderBits = 274
+	coarseSegmentBits   = 1344
+	fineFrameBits       = 689
+	// Offset of the number of coarse segments in the header
+	numSegmentsOffset = signatureHeaderBits - 32
+	// Frame signatures are 380 ternary elements, packed by 5 into a byte
+	frameSignatureSize = 76
+)
+
+// frameSignature is the fine signature of a frame
+type frameSignature [frameSignatureSize]byte
+
+// ternaryDistances are the L1 distances between the bytes of frame signatures, each
+// byte being 5 ternary elements
+var ternaryDistances = func() [243][243]uint8 {
+	var d [243][243]uint8
+	for a := 0; a < 243; a++ {
+		for b := 0; b < 243; b++ {
+			x, y := a, b
+			for i := 0; i < 5; i++ {
+				diff := x%3 - y%3
+				if diff < 0 {
+					diff = -diff
+				}
+				d[a][b] += uint8(diff)
+				x, y = x/3, y/3
+			}
+		}
+	}
+	return d
+}()
+
+func (f *frameSignature) distance(g *frameSignature) int {
+	dist := 0
+	for i := range f {
+		dist += int(ternaryDistances[f[i]][g[i]])
+	}
+	return dist
+}
+
+// parseVideoSignature returns the frame signatures of a video signature in the binary
+// format of the signature filter of ffmpeg. The coarse signatures of the segments of
+// the video are skipped.
+func parseVideoSignature(data []byte) ([]frameSignature, error) {
+	r := &bitReader{data: data}
+	r.skip(numSegmentsOffset)
+	numSegments, ok := r.read(32)
+	if !ok || uint64(len(data))*8 < signatureHeaderBits+numSegments*coarseSegmentBits {
+		return nil, errSignatureFormat
+	}
+	r.skip(int(numSegments) * coarseSegmentBits)
+	// compression flag, only uncompressed signatures are supported
+	if compressed, ok := r.read(1); !ok || compressed != 0 {
+		return nil, errSignatureFormat
+	}
+	var frames []frameSignature
+	for r.remaining() >= fineFrameBits {
+		// media time flag and media time, confidence and words of the frame
+		r.skip(1 + 32 + 8 + 5*8)
+		var f frameSignature
+		for i := range f {
+			v, _ := r.read(8)
+			if v >= 243 {
+				return nil, errSignatureFormat
+			}
+			f[i] = byte(v)
+		}
+		frames = append(frames, f)
+	}
+	if len(frames) == 0 {
+		return nil, errSignatureFormat
+	}
+	return frames, nil
+}
+
+// CompareVideoSignatures returns the ratio of the frames of a rendition whose
+// signatures match the frames of the source at the same relative position. Frames are
+// matched by position rather than timestamp so that renditions at another frame rate
+// are compared to the frames of the source they were encoded from, with a frame of
+// tolerance either side.
+func CompareVideoSignatures(source, rendition []byte) (float64, error) {
+	src, err := parseVideoSignature(source)
+	if err != nil {
+		return 0, err
+	}
+	frames, err := parseVideoSignature(rendition)
+	if err != nil {
+		return 0, err
+	}
+	return matchingFrames(src, frames), nil
+}
+
+func matchingFrames(src, frames []frameSignature) float64 {
+	matching := 0
+	for i := range frames {
+		pos := i * len(src) / len(frames)
+		for j := pos - 1; j <= pos+1; j++ {
+			if j >= 0 && j < len(src) && frames[i].distance(&src[j]) <= maxFrameDistance {
+				matching++
+				break
+			}
+		}
+	}
+	return float64(matching) / float64(len(frames))
+}
+
+// SignatureChecker checks the renditions of sampled segments against their source with
+// MPEG-7 video signatures, which are robust to scaling and re-encoding, so it's cheaper
+// than verification and can run on most segments. The signatures of the source and of
+// the renditions are computed by the broadcaster with the signature filter of the ffmpeg
+// binary, which must be in the PATH
+type SignatureChecker struct {
+	// Fraction of segments, between 0 and 1, that are checked
+	SampleRate float64
+
+	sign func(data []byte) ([]byte, error)
+}
+
+func NewSignatureChecker(sampleRate float64) *SignatureChecker {
+	return &SignatureChecker{
+		SampleRate: sampleRate,
+		sign:       func(data []byte) ([]byte, error) { return core.VideoSignature(core.WorkDir, data) },
+	}
+}
+
+// Sample returns whether the renditions of a segment should be checked
+func (sc *SignatureChecker) Sample(params *Params) bool {
+	return sampled(params, sc.SampleRate)
+}
+
+// Check signs the source and the renditions of a segment, and compares the signatures of
+// the renditions against the signature of the source. A rendition with video that can't
+// be signed, or whose frames mostly don't match the source, fails the check. The
+// renditions are not checked against a source that can't be signed
+func (sc *SignatureChecker) Check(params *Params) error {
+	if params.Source == nil {
+		return nil
+	}
+	// the source and the renditions are signed in parallel
+	sigs := make([][]byte, len(params.Renditions)+1)
+	errs := make([]error, len(sigs))
+	var wg sync.WaitGroup
+	sign := func(i int, data []byte) {
+		defer wg.Done()
+		sigs[i], errs[i] = sc.sign(data)
+	}
+	wg.Add(1)
+	go sign(0, params.Source.Data)
+	for i, data := range params.Renditions {
+		if i >= len(params.Profiles) || common.ProfileCodec(params.Profiles[i].Profile) == common.NoVideo {
+			continue
+		}
+		wg.Add(1)
+		go sign(i+1, data)
+	}
+	wg.Wait()
+
+	if errs[0] != nil {
+		glog.Errorf("Unable to sign the source manifestID=%s err=%v", params.ManifestID, errs[0])
+		return nil
+	}
+	src, err := parseVideoSignature(sigs[0])
+	if err != nil {
+		glog.Errorf("Invalid video signature of the source manifestID=%s err=%v", params.ManifestID, err)
+		return nil
+	}
+	for i := range params.Renditions {
+		if i >= len(params.Profiles) {
+			break
+		}
+		profile := params.Profiles[i]
+		if common.ProfileCodec(profile.Profile) == common.NoVideo {
+			continue
+		}
+		if errs[i+1] != nil {
+			glog.Errorf("Unable to sign the rendition manifestID=%s profile=%s err=%v", params.ManifestID, profile.Name, errs[i+1])
+			return ErrVideoSignatureMismatch
+		}
+		frames, err := parseVideoSignature(sigs[i+1])
+		if err != nil {
+			glog.Errorf("Invalid video signature of the rendition manifestID=%s profile=%s err=%v", params.ManifestID, profile.Name, err)
+			return ErrVideoSignatureMismatch
+		}
+		ratio := matchingFrames(src, frames)
+		if ratio < minMatchingFrames {
+			glog.Errorf("Rendition video signature does not match the source manifestID=%s profile=%s matching=%.2f",
+				params.ManifestID, profile.Name, ratio)
+			return ErrVideoSignatureMismatch
+		}
+	}
+	return nil
+}
+
+// bitReader reads the big-endian bit fields of video signatures
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) remaining() int {
+	return len(r.data)*8 - r.pos
+}
+
+func (r *bitReader) skip(n int) {
+	r.pos += n
+}
+
+func (r *bitReader) read(n int) (uint64, bool) {
+	if n > r.remaining() {
+		return 0, false
+	}
+	var v uint64
+	for i := 0; i < n; i++ {
+		bit := r.data[(r.pos+i)/8] >> (7 - uint((r.pos+i)%8)) & 1
+		v = v<<1 | uint64(bit)
+	}
+	r.pos += n
+	return v, true
+}
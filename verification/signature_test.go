@@ -0,0 +1,160 @@
+package verification
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/livepeer/lpms/stream"
+)
+
+type bitWriter struct {
+	data []byte
+	pos  int
+}
+
+func (w *bitWriter) write(n int, v uint64) {
+	for i := n - 1; i >= 0; i-- {
+		if w.pos%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		w.data[w.pos/8] |= byte(v>>uint(i)&1) << (7 - uint(w.pos%8))
+		w.pos++
+	}
+}
+
+// videoSignature writes a binary video signature with a coarse segment and the frame
+// signatures of the frames, whose elements are all set to the ternary digits of a frame
+func videoSignature(frames ...byte) []byte {
+	w := &bitWriter{}
+	w.write(numSegmentsOffset, 0)
+	w.write(32, 1)
+	w.write(coarseSegmentBits, 0)
+	w.write(1, 0)
+	for _, f := range frames {
+		w.write(1+32+8+5*8, 0)
+		for i := 0; i < frameSignatureSize; i++ {
+			w.write(8, uint64(f))
+		}
+	}
+	return w.data
+}
+
+func TestParseVideoSignature(t *testing.T) {
+	assert := assert.New(t)
+
+	frames, err := parseVideoSignature(videoSignature(0, 121, 242))
+	assert.Nil(err)
+	assert.Len(frames, 3)
+	assert.Equal(byte(121), frames[1][0])
+	assert.Equal(byte(242), frames[2][frameSignatureSize-1])
+
+	_, err = parseVideoSignature(nil)
+	assert.Equal(errSignatureFormat, err)
+	// truncated coarse segments
+	sig := videoSignature(0)
+	_, err = parseVideoSignature(sig[:signatureHeaderBits/8+1])
+	assert.Equal(errSignatureFormat, err)
+	// no frames
+	_, err = parseVideoSignature(videoSignature())
+	assert.Equal(errSignatureFormat, err)
+	// invalid ternary elements
+	_, err = parseVideoSignature(videoSignature(243))
+	assert.Equal(errSignatureFormat, err)
+	// compressed signatures
+	w := &bitWriter{}
+	w.write(numSegmentsOffset, 0)
+	w.write(32, 0)
+	w.write(1, 1)
+	w.write(fineFrameBits, 0)
+	_, err = parseVideoSignature(w.data)
+	assert.Equal(errSignatureFormat, err)
+}
+
+func TestCompareVideoSignatures(t *testing.T) {
+	assert := assert.New(t)
+
+	// 0 and 242 differ by 2 in each of the 380 elements, 0 and 1 by 1 in 76
+	src := videoSignature(0, 0, 242, 242)
+	ratio, err := CompareVideoSignatures(src, src)
+	assert.Nil(err)
+	assert.Equal(1.0, ratio)
+	ratio, err = CompareVideoSignatures(src, videoSignature(1, 1, 242, 242))
+	assert.Nil(err)
+	assert.Equal(1.0, ratio)
+	ratio, err = CompareVideoSignatures(src, videoSignature(242, 242, 242, 242))
+	assert.Nil(err)
+	assert.Equal(0.75, ratio)
+	ratio, err = CompareVideoSignatures(src, videoSignature(242, 242, 0, 0))
+	assert.Nil(err)
+	assert.Equal(0.5, ratio)
+
+	// renditions at half the frame rate are compared to the frames they were encoded from
+	ratio, err = CompareVideoSignatures(src, videoSignature(0, 242))
+	assert.Nil(err)
+	assert.Equal(1.0, ratio)
+
+	_, err = CompareVideoSignatures(nil, src)
+	assert.Equal(errSignatureFormat, err)
+	_, err = CompareVideoSignatures(src, nil)
+	assert.Equal(errSignatureFormat, err)
+}
+
+func TestSignatureChecker(t *testing.T) {
+	assert := assert.New(t)
+
+	sigs := map[string][]byte{
+		"source":   videoSignature(0, 0, 242, 242),
+		"close":    videoSignature(1, 1, 242, 242),
+		"tampered": videoSignature(121, 121, 121, 121),
+		"invalid":  []byte("invalid"),
+	}
+	sc := NewSignatureChecker(1)
+	var signed []string
+	var mu sync.Mutex
+	sc.sign = func(data []byte) ([]byte, error) {
+		mu.Lock()
+		signed = append(signed, string(data))
+		mu.Unlock()
+		if sig, ok := sigs[string(data)]; ok {
+			return sig, nil
+		}
+		return nil, errors.New("signature: exit status 1")
+	}
+	params := func(source string, renditions ...string) *Params {
+		p := &Params{
+			ManifestID: "mid",
+			Source:     &stream.HLSSegment{Data: []byte(source)},
+			Profiles:   []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9, ffmpeg.P240p30fps16x9},
+		}
+		for _, r := range renditions {
+			p.Renditions = append(p.Renditions, []byte(r))
+		}
+		return p
+	}
+
+	assert.True(sc.Sample(params("source")))
+	assert.Nil(sc.Check(params("source", "source", "close")))
+	assert.Nil(sc.Check(&Params{}))
+	// renditions aren't checked against a source that couldn't be signed
+	assert.Nil(sc.Check(params("garbage", "garbage", "garbage")))
+	assert.Nil(sc.Check(params("invalid", "garbage", "garbage")))
+
+	// renditions that can't be signed, e.g. garbage, fail the check
+	assert.Equal(ErrVideoSignatureMismatch, sc.Check(params("source", "source", "garbage")))
+	assert.Equal(ErrVideoSignatureMismatch, sc.Check(params("source", "source", "invalid")))
+	// 121 differs from 0 and 242 by 1 in all the elements
+	assert.Equal(ErrVideoSignatureMismatch, sc.Check(params("source", "tampered", "source")))
+
+	// renditions without video aren't signed
+	signed = nil
+	p := params("source", "close", "audio")
+	p.Profiles[1].Profile = common.ProfileAudioOnly
+	assert.Nil(sc.Check(p))
+	assert.ElementsMatch([]string{"source", "close"}, signed)
+	assert.True(IsRetryable(ErrVideoSignatureMismatch))
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"sort"
+	"time"
 
 	"github.com/golang/glog"
 
@@ -71,6 +72,12 @@ type Verifier interface {
 	Verify(params *Params) (*Results, error)
 }
 
+// ResultStore persists the outcomes of verifications so that they can be audited
+type ResultStore interface {
+	InsertVerificationResult(res *common.DBVerificationResult) error
+	DeleteVerificationResults(before time.Time) (int64, error)
+}
+
 type Policy struct {
 
 	// Verification function to run
@@ -79,11 +86,26 @@ type Policy struct {
 	// Maximum number of retries until the policy chooses a winner
 	Retries int
 
-	// How often to invoke the verifier, on a per-segment basis
-	SampleRate float64 // XXX for later
+	// Fraction of segments, between 0 and 1, that are verified with the verifier
+	// and pixel counts. Every segment is verified if 0
+	SampleRate float64
+
+	// Fraction of segments that are verified for orchestrators that are not trusted yet.
+	// Orchestrators are trusted after TrustThreshold consecutive successful verifications
+	// and lose their trust when a verification fails
+	UntrustedSampleRate float64
+	TrustThreshold      int
+
+	// Store of the verification outcomes, if any
+	Store ResultStore
+	// How long stored outcomes are kept, forever if 0
+	Retention time.Duration
 
 	// How many parallel transcodes to support
 	Redundancy int // XXX for later
+
+	trust orchTrust
+	sweep resultSweep
 }
 
 type SegmentVerifierResults struct {
@@ -119,11 +141,14 @@ func (sv *SegmentVerifier) Verify(params *Params) (*Params, error) {
 		return nil, err
 	}
 
+	// Always verify the retries of a segment that failed verification
+	if sv.count == 0 && !sv.policy.sample(params) {
+		return params, nil
+	}
+
 	var err error
 	res := &Results{}
 
-	// TODO Use policy sampling rate to determine whether to invoke verifier.
-	//      If not, exit early. Seed sample using source data for repeatability!
 	if sv.policy.Verifier != nil {
 		res, err = sv.policy.Verifier.Verify(params)
 	}
@@ -143,6 +168,8 @@ func (sv *SegmentVerifier) Verify(params *Params) (*Params, error) {
 		}
 	}
 
+	sv.policy.trust.record(orchKey(params), err)
+	sv.policy.storeResult(params, res, err)
 	if err == nil {
 		// Verification passed successfully, so use this set of params
 		return params, nil
@@ -229,36 +256,49 @@ func countPixelParams(params *Params) ([]int64, error) {
 }
 
 func countPixels(data []byte) (int64, error) {
+	info, err := decodeSegment(data)
+	if err != nil {
+		return 0, err
+	}
+	return info.Pixels, nil
+}
+
+// decodeSegment decodes the segment data and returns the decoded media info
+func decodeSegment(data []byte) (*ffmpeg.MediaInfo, error) {
 	// write the data to a temp file
 	tempfile, err := ioutil.TempFile("", common.RandName())
 	if err != nil {
-		return 0, fmt.Errorf("error creating temp file for pixels verification: %w", err)
+		return nil, fmt.Errorf("error creating temp file for pixels verification: %w", err)
 	}
 	defer os.Remove(tempfile.Name())
 
 	if _, err := tempfile.Write(data); err != nil {
 		tempfile.Close()
-		return 0, fmt.Errorf("error writing temp file for pixels verification: %w", err)
+		return nil, fmt.Errorf("error writing temp file for pixels verification: %w", err)
 	}
 
 	if err = tempfile.Close(); err != nil {
-		return 0, fmt.Errorf("error closing temp file for pixels verification: %w", err)
+		return nil, fmt.Errorf("error closing temp file for pixels verification: %w", err)
 	}
 
-	p, err := pixels(tempfile.Name())
+	return decode(tempfile.Name())
+}
+
+func pixels(fname string) (int64, error) {
+	info, err := decode(fname)
 	if err != nil {
 		return 0, err
 	}
 
-	return p, nil
+	return info.Pixels, nil
 }
 
-func pixels(fname string) (int64, error) {
+func decode(fname string) (*ffmpeg.MediaInfo, error) {
 	in := &ffmpeg.TranscodeOptionsIn{Fname: fname}
 	res, err := ffmpeg.Transcode3(in, nil)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return res.Decoded.Pixels, nil
+	return &res.Decoded, nil
 }
@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// ErrNoDeadLetterQueue is returned when replaying deliveries of a dispatcher without a dead-letter queue
+var ErrNoDeadLetterQueue = errors.New("no dead-letter queue")
+
+// ErrDeadLetterNotFound is returned for unknown dead letter IDs
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+var deadLetterID = regexp.MustCompile(`^[0-9]+-[0-9a-f]+$`)
+
+// DeadLetter is a webhook delivery that failed all of its attempts
+type DeadLetter struct {
+	ID        string
+	Webhook   string
+	URL       string
+	Headers   map[string]string `json:",omitempty"`
+	Body      string
+	Attempts  int
+	Error     string
+	CreatedAt time.Time
+}
+
+// DeadLetterQueue persists failed webhook deliveries as one JSON file per delivery in a directory.
+// The queue holds at most maxEntries dead letters, the oldest ones are dropped to make room for new ones
+type DeadLetterQueue struct {
+	mu         sync.Mutex
+	dir        string
+	maxEntries int
+}
+
+// NewDeadLetterQueue creates a DeadLetterQueue in dir holding at most maxEntries dead letters,
+// creating the directory if needed
+func NewDeadLetterQueue(dir string, maxEntries int) (*DeadLetterQueue, error) {
+	if maxEntries < 1 {
+		return nil, fmt.Errorf("invalid maximum number of dead letters %v", maxEntries)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DeadLetterQueue{dir: dir, maxEntries: maxEntries}, nil
+}
+
+// Add persists a dead letter, assigning its ID and creation time
+func (q *DeadLetterQueue) Add(dl *DeadLetter) error {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	now := time.Now()
+	dl.ID = fmt.Sprintf("%d-%s", now.UnixNano(), hex.EncodeToString(b))
+	dl.CreatedAt = now
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err := q.evict(q.maxEntries - 1); err != nil {
+		return err
+	}
+	return q.write(dl)
+}
+
+// update rewrites an existing dead letter
+func (q *DeadLetterQueue) update(dl *DeadLetter) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.write(dl)
+}
+
+// Caller of this function should hold the lock
+func (q *DeadLetterQueue) evict(keep int) error {
+	ids, err := q.ids()
+	if err != nil {
+		return err
+	}
+	for len(ids) > keep {
+		glog.Warningf("Dropping oldest dead letter id=%s, the dead-letter queue is full", ids[0])
+		if err := os.Remove(q.path(ids[0])); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+// ids returns the IDs of the dead letters, oldest first. Caller of this function should hold the lock
+func (q *DeadLetterQueue) ids() ([]string, error) {
+	files, err := ioutil.ReadDir(q.dir)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, f := range files {
+		id := strings.TrimSuffix(f.Name(), ".json")
+		if f.IsDir() || id == f.Name() || !deadLetterID.MatchString(id) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	// IDs start with the creation time in nanoseconds, which has the same number of digits
+	// for any time in this era
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// write stores a dead letter atomically so that a crash never leaves a partial file.
+// Caller of this function should hold the lock
+func (q *DeadLetterQueue) write(dl *DeadLetter) error {
+	data, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+
+	tmp := q.path(dl.ID) + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.path(dl.ID))
+}
+
+// Get returns the dead letter with the provided ID
+func (q *DeadLetterQueue) Get(id string) (*DeadLetter, error) {
+	if !deadLetterID.MatchString(id) {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.read(q.path(id))
+}
+
+// List returns all the dead letters, oldest first
+func (q *DeadLetterQueue) List() ([]*DeadLetter, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ids, err := q.ids()
+	if err != nil {
+		return nil, err
+	}
+	res := []*DeadLetter{}
+	for _, id := range ids {
+		dl, err := q.read(q.path(id))
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, dl)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].CreatedAt.Before(res[j].CreatedAt) })
+	return res, nil
+}
+
+// Remove deletes the dead letter with the provided ID
+func (q *DeadLetterQueue) Remove(id string) error {
+	if !deadLetterID.MatchString(id) {
+		return ErrDeadLetterNotFound
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	err := os.Remove(q.path(id))
+	if os.IsNotExist(err) {
+		return ErrDeadLetterNotFound
+	}
+	return err
+}
+
+func (q *DeadLetterQueue) path(id string) string {
+	return filepath.Join(q.dir, id+".json")
+}
+
+// Caller of this function should hold the lock
+func (q *DeadLetterQueue) read(path string) (*DeadLetter, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	dl := &DeadLetter{}
+	if err := json.Unmarshal(data, dl); err != nil {
+		return nil, fmt.Errorf("invalid dead letter %v: %v", path, err)
+	}
+	return dl, nil
+}
@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetterQueue(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "webhooks")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+	dlq, err := NewDeadLetterQueue(filepath.Join(dir, "dlq"), 100)
+	require.Nil(err)
+
+	deadLetters, err := dlq.List()
+	require.Nil(err)
+	assert.Empty(deadLetters)
+
+	first := &DeadLetter{Webhook: "auth", URL: "http://foo.com", Body: "{}", Attempts: 3, Error: "boom"}
+	require.Nil(dlq.Add(first))
+	assert.Regexp(`^[0-9]+-[0-9a-f]{8}$`, first.ID)
+	assert.False(first.CreatedAt.IsZero())
+	second := &DeadLetter{Webhook: "crash", URL: "http://bar.com"}
+	require.Nil(dlq.Add(second))
+	assert.NotEqual(first.ID, second.ID)
+
+	dl, err := dlq.Get(first.ID)
+	require.Nil(err)
+	assert.Equal("auth", dl.Webhook)
+	assert.Equal("http://foo.com", dl.URL)
+	assert.Equal("{}", dl.Body)
+	assert.Equal(3, dl.Attempts)
+	assert.Equal("boom", dl.Error)
+
+	// Dead letters are persisted and listed oldest first
+	dlq, err = NewDeadLetterQueue(filepath.Join(dir, "dlq"), 100)
+	require.Nil(err)
+	deadLetters, err = dlq.List()
+	require.Nil(err)
+	require.Len(deadLetters, 2)
+	assert.Equal(first.ID, deadLetters[0].ID)
+	assert.Equal(second.ID, deadLetters[1].ID)
+
+	require.Nil(dlq.Remove(first.ID))
+	assert.Equal(ErrDeadLetterNotFound, dlq.Remove(first.ID))
+	_, err = dlq.Get(first.ID)
+	assert.Equal(ErrDeadLetterNotFound, err)
+	deadLetters, err = dlq.List()
+	require.Nil(err)
+	assert.Len(deadLetters, 1)
+}
+
+func TestDeadLetterQueue_InvalidID(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "webhooks")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	dlq, err := NewDeadLetterQueue(dir, 100)
+	require.Nil(t, err)
+
+	// IDs are never used as paths outside of the queue directory
+	_, err = dlq.Get("../foo")
+	assert.Equal(ErrDeadLetterNotFound, err)
+	assert.Equal(ErrDeadLetterNotFound, dlq.Remove("../foo"))
+	assert.Equal(ErrDeadLetterNotFound, dlq.Remove(""))
+}
+
+func TestDeadLetterQueue_MaxEntries(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "webhooks")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+	_, err = NewDeadLetterQueue(dir, 0)
+	assert.EqualError(err, "invalid maximum number of dead letters 0")
+	dlq, err := NewDeadLetterQueue(dir, 2)
+	require.Nil(err)
+
+	// The oldest dead letters are dropped when the queue is full
+	var added []*DeadLetter
+	for i := 0; i < 4; i++ {
+		dl := &DeadLetter{Webhook: "crash"}
+		require.Nil(dlq.Add(dl))
+		added = append(added, dl)
+	}
+	deadLetters, err := dlq.List()
+	require.Nil(err)
+	require.Len(deadLetters, 2)
+	assert.Equal(added[2].ID, deadLetters[0].ID)
+	assert.Equal(added[3].ID, deadLetters[1].ID)
+	_, err = dlq.Get(added[0].ID)
+	assert.Equal(ErrDeadLetterNotFound, err)
+}
@@ -0,0 +1,254 @@
+// Package webhook delivers outbound webhooks with retries and a persistent dead-letter queue
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/golang/glog"
+)
+
+var errRetryableStatus = errors.New("retryable status")
+
+// Options controls the retries of a Dispatcher
+type Options struct {
+	// MaxAttempts is the total number of attempts of a delivery, including the first one
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, it grows exponentially for later retries
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries
+	MaxBackoff time.Duration
+}
+
+// DefaultOptions retries a delivery twice within a couple of seconds, so that
+// webhooks on the stream setup path do not delay it for long
+var DefaultOptions = Options{
+	MaxAttempts:    3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+// DeliveryStats holds the delivery counters of a webhook
+type DeliveryStats struct {
+	Webhook     string
+	Delivered   int64
+	Retried     int64
+	Failed      int64
+	LastSuccess time.Time
+	LastFailure time.Time
+	LastError   string `json:",omitempty"`
+}
+
+// Dispatcher posts webhooks, retrying transport errors, 5xx and 429 responses with an
+// exponential backoff. Deliveries that fail all attempts are added to the dead-letter
+// queue, if any, so that they can be inspected and replayed
+type Dispatcher struct {
+	client *http.Client
+	opts   Options
+	dlq    *DeadLetterQueue
+
+	mu    sync.Mutex
+	stats map[string]*DeliveryStats
+}
+
+// NewDispatcher creates a Dispatcher. The dead-letter queue may be nil
+func NewDispatcher(client *http.Client, opts Options, dlq *DeadLetterQueue) *Dispatcher {
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 1
+	}
+	return &Dispatcher{
+		client: client,
+		opts:   opts,
+		dlq:    dlq,
+		stats:  make(map[string]*DeliveryStats),
+	}
+}
+
+// DeadLetterQueue returns the dead-letter queue of the dispatcher, which may be nil
+func (d *Dispatcher) DeadLetterQueue() *DeadLetterQueue {
+	return d.dlq
+}
+
+// Post delivers body to url for the named webhook. Like http.Client.Post, a response is
+// returned for any status, and its body must be closed by the caller. The response of
+// the last attempt is returned if all attempts fail with a retryable status
+func (d *Dispatcher) Post(webhook, url string, headers map[string]string, body []byte) (*http.Response, error) {
+	return d.deliver(webhook, url, headers, body, true)
+}
+
+// PostNoDeadLetter is like Post, but a delivery that fails all attempts is not added to the
+// dead-letter queue. It is meant for webhooks whose response is only meaningful at the time
+// of the call, like the auth webhook, which would have nothing to replay
+func (d *Dispatcher) PostNoDeadLetter(webhook, url string, headers map[string]string, body []byte) (*http.Response, error) {
+	return d.deliver(webhook, url, headers, body, false)
+}
+
+func (d *Dispatcher) deliver(webhook, url string, headers map[string]string, body []byte, deadLetter bool) (*http.Response, error) {
+	resp, attempts, err := d.post(url, headers, body)
+	if err == nil && !retryable(resp.StatusCode) {
+		d.recordSuccess(webhook, attempts)
+		return resp, nil
+	}
+
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	} else {
+		errMsg = fmt.Sprintf("unexpected status %v", resp.Status)
+	}
+	d.recordFailure(webhook, attempts, errMsg)
+	glog.Errorf("Webhook delivery failed webhook=%s url=%s attempts=%d err=%s", webhook, url, attempts, errMsg)
+
+	if deadLetter && d.dlq != nil {
+		dl := &DeadLetter{
+			Webhook:  webhook,
+			URL:      url,
+			Headers:  headers,
+			Body:     string(body),
+			Attempts: attempts,
+			Error:    errMsg,
+		}
+		if err := d.dlq.Add(dl); err != nil {
+			glog.Errorf("Error adding webhook delivery to dead-letter queue webhook=%s err=%v", webhook, err)
+		}
+	}
+	return resp, err
+}
+
+func (d *Dispatcher) post(url string, headers map[string]string, body []byte) (*http.Response, int, error) {
+	// WithMaxRetries does not limit retries when the maximum is 0
+	var b backoff.BackOff = &backoff.StopBackOff{}
+	if d.opts.MaxAttempts > 1 {
+		expb := backoff.NewExponentialBackOff()
+		expb.InitialInterval = d.opts.InitialBackoff
+		expb.MaxInterval = d.opts.MaxBackoff
+		expb.MaxElapsedTime = 0
+		b = backoff.WithMaxRetries(expb, uint64(d.opts.MaxAttempts-1))
+	}
+
+	var resp *http.Response
+	attempts := 0
+	err := backoff.Retry(func() error {
+		if resp != nil {
+			// Discard the response of the previous attempt
+			resp.Body.Close()
+			resp = nil
+		}
+		attempts++
+
+		req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err = d.client.Do(req)
+		if err != nil {
+			return err
+		}
+		if retryable(resp.StatusCode) {
+			return errRetryableStatus
+		}
+		return nil
+	}, b)
+
+	if err == errRetryableStatus {
+		// Let the caller handle the status of the last response
+		return resp, attempts, nil
+	}
+	if permanent, ok := err.(*backoff.PermanentError); ok {
+		err = permanent.Err
+	}
+	return resp, attempts, err
+}
+
+func retryable(status int) bool {
+	return status >= 500 || status == http.StatusTooManyRequests
+}
+
+// Replay delivers a dead letter again and removes it from the queue if the delivery succeeds.
+// A failed replay stays in the queue with an updated error
+func (d *Dispatcher) Replay(id string) error {
+	if d.dlq == nil {
+		return ErrNoDeadLetterQueue
+	}
+	dl, err := d.dlq.Get(id)
+	if err != nil {
+		return err
+	}
+
+	resp, attempts, err := d.post(dl.URL, dl.Headers, []byte(dl.Body))
+	if err == nil {
+		resp.Body.Close()
+		if retryable(resp.StatusCode) {
+			err = fmt.Errorf("unexpected status %v", resp.Status)
+		}
+	}
+	if err != nil {
+		d.recordFailure(dl.Webhook, attempts, err.Error())
+		dl.Attempts += attempts
+		dl.Error = err.Error()
+		if uerr := d.dlq.update(dl); uerr != nil {
+			glog.Errorf("Error updating dead letter id=%s err=%v", id, uerr)
+		}
+		return err
+	}
+
+	d.recordSuccess(dl.Webhook, attempts)
+	return d.dlq.Remove(id)
+}
+
+// Stats returns the delivery counters of all webhooks ordered by name
+func (d *Dispatcher) Stats() []*DeliveryStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	res := make([]*DeliveryStats, 0, len(d.stats))
+	for _, s := range d.stats {
+		c := *s
+		res = append(res, &c)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Webhook < res[j].Webhook })
+	return res
+}
+
+func (d *Dispatcher) recordSuccess(webhook string, attempts int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s := d.getStats(webhook)
+	s.Delivered++
+	s.Retried += int64(attempts - 1)
+	s.LastSuccess = time.Now()
+}
+
+func (d *Dispatcher) recordFailure(webhook string, attempts int, errMsg string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s := d.getStats(webhook)
+	s.Failed++
+	if attempts > 0 {
+		s.Retried += int64(attempts - 1)
+	}
+	s.LastFailure = time.Now()
+	s.LastError = errMsg
+}
+
+// Caller of this function should hold the lock
+func (d *Dispatcher) getStats(webhook string) *DeliveryStats {
+	s, ok := d.stats[webhook]
+	if !ok {
+		s = &DeliveryStats{Webhook: webhook}
+		d.stats[webhook] = s
+	}
+	return s
+}
@@ -0,0 +1,253 @@
+package webhook
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testOptions = Options{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     5 * time.Millisecond,
+}
+
+// statusServer responds with the provided statuses in order and then with 200
+func statusServer(statuses ...int) (*httptest.Server, *int32) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if int(n) <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	return ts, &calls
+}
+
+func tempDeadLetterQueue(t *testing.T) (*DeadLetterQueue, func()) {
+	dir, err := ioutil.TempDir("", "webhooks")
+	require.Nil(t, err)
+	dlq, err := NewDeadLetterQueue(dir, 100)
+	require.Nil(t, err)
+	return dlq, func() { os.RemoveAll(dir) }
+}
+
+func TestPost_Success(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var headers http.Header
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer ts.Close()
+
+	d := NewDispatcher(http.DefaultClient, testOptions, nil)
+	resp, err := d.Post("auth", ts.URL, map[string]string{"X-Foo": "bar"}, []byte(`{"url":"foo"}`))
+	require.Nil(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("application/json", headers.Get("Content-Type"))
+	assert.Equal("bar", headers.Get("X-Foo"))
+	assert.Equal(`{"url":"foo"}`, string(body))
+
+	stats := d.Stats()
+	require.Len(stats, 1)
+	assert.Equal("auth", stats[0].Webhook)
+	assert.Equal(int64(1), stats[0].Delivered)
+	assert.Equal(int64(0), stats[0].Retried)
+	assert.False(stats[0].LastSuccess.IsZero())
+}
+
+func TestPost_RetriesRetryableStatus(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ts, calls := statusServer(http.StatusInternalServerError, http.StatusTooManyRequests)
+	defer ts.Close()
+
+	dlq, cleanup := tempDeadLetterQueue(t)
+	defer cleanup()
+	d := NewDispatcher(http.DefaultClient, testOptions, dlq)
+	resp, err := d.Post("auth", ts.URL, nil, []byte("{}"))
+	require.Nil(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal(int32(3), atomic.LoadInt32(calls))
+
+	stats := d.Stats()
+	require.Len(stats, 1)
+	assert.Equal(int64(1), stats[0].Delivered)
+	assert.Equal(int64(2), stats[0].Retried)
+
+	deadLetters, err := dlq.List()
+	require.Nil(err)
+	assert.Empty(deadLetters)
+}
+
+func TestPost_DoesNotRetryClientErrors(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ts, calls := statusServer(http.StatusForbidden)
+	defer ts.Close()
+
+	dlq, cleanup := tempDeadLetterQueue(t)
+	defer cleanup()
+	d := NewDispatcher(http.DefaultClient, testOptions, dlq)
+	resp, err := d.Post("auth", ts.URL, nil, []byte("{}"))
+	require.Nil(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusForbidden, resp.StatusCode)
+	assert.Equal(int32(1), atomic.LoadInt32(calls))
+
+	// The webhook answered, so the delivery is not dead-lettered
+	deadLetters, err := dlq.List()
+	require.Nil(err)
+	assert.Empty(deadLetters)
+}
+
+func TestPost_SingleAttempt(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ts, calls := statusServer(http.StatusInternalServerError)
+	defer ts.Close()
+
+	d := NewDispatcher(http.DefaultClient, Options{MaxAttempts: 1}, nil)
+	resp, err := d.Post("auth", ts.URL, nil, []byte("{}"))
+	require.Nil(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(int32(1), atomic.LoadInt32(calls))
+}
+
+func TestPost_DeadLettersFailedDeliveries(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ts, calls := statusServer(http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	defer ts.Close()
+
+	dlq, cleanup := tempDeadLetterQueue(t)
+	defer cleanup()
+	d := NewDispatcher(http.DefaultClient, testOptions, dlq)
+	resp, err := d.Post("crash", ts.URL, map[string]string{"X-Foo": "bar"}, []byte(`{"message":"boom"}`))
+	require.Nil(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(int32(3), atomic.LoadInt32(calls))
+
+	deadLetters, err := dlq.List()
+	require.Nil(err)
+	require.Len(deadLetters, 1)
+	dl := deadLetters[0]
+	assert.Equal("crash", dl.Webhook)
+	assert.Equal(ts.URL, dl.URL)
+	assert.Equal("bar", dl.Headers["X-Foo"])
+	assert.Equal(`{"message":"boom"}`, dl.Body)
+	assert.Equal(3, dl.Attempts)
+	assert.Equal("unexpected status 502 Bad Gateway", dl.Error)
+
+	stats := d.Stats()
+	require.Len(stats, 1)
+	assert.Equal(int64(1), stats[0].Failed)
+	assert.Equal(int64(2), stats[0].Retried)
+	assert.Equal("unexpected status 502 Bad Gateway", stats[0].LastError)
+
+	// Transport errors are dead-lettered too
+	ts.Close()
+	_, err = d.Post("crash", ts.URL, nil, nil)
+	assert.NotNil(err)
+	deadLetters, err = dlq.List()
+	require.Nil(err)
+	assert.Len(deadLetters, 2)
+
+	// Deliveries of webhooks that can't be replayed aren't dead-lettered
+	_, err = d.PostNoDeadLetter("auth", ts.URL, nil, nil)
+	assert.NotNil(err)
+	deadLetters, err = dlq.List()
+	require.Nil(err)
+	assert.Len(deadLetters, 2)
+}
+
+func TestReplay(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ts, calls := statusServer(http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	defer ts.Close()
+
+	dlq, cleanup := tempDeadLetterQueue(t)
+	defer cleanup()
+	d := NewDispatcher(http.DefaultClient, testOptions, dlq)
+	resp, err := d.Post("auth", ts.URL, nil, []byte("{}"))
+	require.Nil(err)
+	resp.Body.Close()
+
+	deadLetters, err := dlq.List()
+	require.Nil(err)
+	require.Len(deadLetters, 1)
+	id := deadLetters[0].ID
+
+	// The webhook is back
+	require.Nil(d.Replay(id))
+	assert.Equal(int32(4), atomic.LoadInt32(calls))
+	_, err = dlq.Get(id)
+	assert.Equal(ErrDeadLetterNotFound, err)
+
+	stats := d.Stats()
+	require.Len(stats, 1)
+	assert.Equal(int64(1), stats[0].Delivered)
+	assert.Equal(int64(1), stats[0].Failed)
+
+	assert.Equal(ErrDeadLetterNotFound, d.Replay(id))
+}
+
+func TestReplay_Failure(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ts, _ := statusServer(http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError,
+		http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	defer ts.Close()
+
+	dlq, cleanup := tempDeadLetterQueue(t)
+	defer cleanup()
+	d := NewDispatcher(http.DefaultClient, testOptions, dlq)
+	resp, err := d.Post("auth", ts.URL, nil, []byte("{}"))
+	require.Nil(err)
+	resp.Body.Close()
+
+	deadLetters, err := dlq.List()
+	require.Nil(err)
+	require.Len(deadLetters, 1)
+	id := deadLetters[0].ID
+
+	// A failed replay stays in the queue
+	assert.EqualError(d.Replay(id), "unexpected status 500 Internal Server Error")
+	dl, err := dlq.Get(id)
+	require.Nil(err)
+	assert.Equal(6, dl.Attempts)
+
+	deadLetters, err = dlq.List()
+	require.Nil(err)
+	assert.Len(deadLetters, 1)
+}
+
+func TestReplay_NoDeadLetterQueue(t *testing.T) {
+	d := NewDispatcher(http.DefaultClient, testOptions, nil)
+	assert.Equal(t, ErrNoDeadLetterQueue, d.Replay("1-ab"))
+	assert.Nil(t, d.DeadLetterQueue())
+}